@@ -7,27 +7,17 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/creack/pty"
+	"github.com/vanpelt/catnip/internal/services"
 	"golang.org/x/term"
 )
 
-// CaptureMetadata matches the Swift MockPTYDataSource structure
-type CaptureMetadata struct {
-	CaptureDate     time.Time      `json:"captureDate"`
-	TotalBytes      int            `json:"totalBytes"`
-	DurationSeconds float64        `json:"durationSeconds"`
-	Events          []CaptureEvent `json:"events"`
-}
-
-// CaptureEvent matches the Swift MockPTYDataSource structure
-type CaptureEvent struct {
-	TimestampMs int    `json:"timestampMs"`
-	Data        []byte `json:"data"`
-}
-
 // Terminal dimensions presets
 const (
 	// Portrait mode (minimum for Claude TUI from TerminalView.swift)
@@ -39,6 +29,14 @@ const (
 	landscapeRows = 30
 )
 
+// Output formats
+const (
+	formatJSON      = "json"
+	formatAsciicast = "asciicast"
+)
+
+const defaultOutputFile = "pty-capture.json"
+
 // findClaude looks for the claude executable in common locations
 func findClaude() string {
 	// Try PATH first
@@ -67,10 +65,23 @@ func findClaude() string {
 }
 
 func main() {
-	outputFile := flag.String("output", "pty-capture.json", "Output JSON file for captured PTY data")
+	outputFile := flag.String("output", defaultOutputFile, "Output file for captured PTY data")
 	landscape := flag.Bool("landscape", false, "Use landscape dimensions (120x30) instead of portrait (65x15)")
+	format := flag.String("format", "", "Output format: json or asciicast (defaults to asciicast for .cast files)")
+	convert := flag.String("convert", "", "Convert an existing JSON capture to asciicast instead of recording")
+	diff := flag.Bool("diff", false, "Compare two captures (JSON or asciicast) given as arguments instead of recording")
+	idleGap := flag.Duration("idle-gap", services.DefaultCaptureIdleGap, "Pause in output that separates prompts when diffing")
+	ignore := flag.String("ignore", "", "Regular expression for lines to leave out when diffing, e.g. spinners")
 	flag.Parse()
 
+	if *diff {
+		if err := runDiff(flag.Args(), *idleGap, *ignore, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Determine terminal size
 	cols := portraitCols
 	rows := portraitRows
@@ -81,9 +92,29 @@ func main() {
 		orientation = "landscape"
 	}
 
+	if *convert != "" {
+		output := *outputFile
+		if output == defaultOutputFile {
+			output = strings.TrimSuffix(*convert, filepath.Ext(*convert)) + ".cast"
+		}
+		if err := convertCapture(*convert, output, cols, rows); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to convert capture: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ Converted %s to %s (%dx%d)\n", *convert, output, cols, rows)
+		return
+	}
+
+	outputFormat, err := resolveFormat(*format, *outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
 	fmt.Printf("🎬 Interactive PTY Capture Tool\n")
 	fmt.Printf("📝 Output file: %s\n", *outputFile)
 	fmt.Printf("📐 Dimensions: %dx%d (%s)\n", cols, rows, orientation)
+	fmt.Printf("🎞️  Format: %s\n", outputFormat)
 	fmt.Println()
 
 	// Find claude executable - check common locations
@@ -140,7 +171,7 @@ func main() {
 
 	// Capture metadata
 	startTime := time.Now()
-	var events []CaptureEvent
+	var events []services.CaptureEvent
 	totalBytes := 0
 
 	// Set up signal handling for graceful shutdown
@@ -222,7 +253,7 @@ func main() {
 				data := make([]byte, n)
 				copy(data, buf[:n])
 
-				event := CaptureEvent{
+				event := services.CaptureEvent{
 					TimestampMs: timestampMs,
 					Data:        data,
 				}
@@ -265,7 +296,7 @@ func main() {
 
 	// Create metadata
 	duration := time.Since(startTime)
-	metadata := CaptureMetadata{
+	metadata := services.CaptureMetadata{
 		CaptureDate:     startTime,
 		TotalBytes:      totalBytes,
 		DurationSeconds: duration.Seconds(),
@@ -281,10 +312,15 @@ func main() {
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(metadata); err != nil {
-		fmt.Fprintf(os.Stderr, "❌ Failed to encode JSON: %v\n", err)
+	if outputFormat == formatAsciicast {
+		err = writeAsciicast(file, metadata, cols, rows)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(metadata)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to encode %s: %v\n", outputFormat, err)
 		os.Exit(1)
 	}
 
@@ -295,8 +331,147 @@ func main() {
 	fmt.Printf("   - Events: %d\n", len(events))
 	fmt.Printf("   - Duration: %.2fs\n", duration.Seconds())
 	fmt.Println()
+	if outputFormat == formatAsciicast {
+		fmt.Printf("🎯 To play it back:\n")
+		fmt.Printf("   asciinema play %s\n", *outputFile)
+		return
+	}
 	fmt.Printf("🎯 To use in Xcode:\n")
 	fmt.Printf("   1. cp %s ../xcode/catnip/PTYCapture/\n", *outputFile)
 	fmt.Printf("   2. Add to Xcode project (if not already)\n")
 	fmt.Printf("   3. Rebuild and view canvas!\n")
 }
+
+// resolveFormat picks the output format from the -format flag or, when unset,
+// the output file's extension
+func resolveFormat(format, outputFile string) (string, error) {
+	switch format {
+	case formatJSON, formatAsciicast:
+		return format, nil
+	case "":
+		if filepath.Ext(outputFile) == ".cast" {
+			return formatAsciicast, nil
+		}
+		return formatJSON, nil
+	default:
+		return "", fmt.Errorf("unknown format %q (expected %s or %s)", format, formatJSON, formatAsciicast)
+	}
+}
+
+// writeAsciicast writes a capture as an asciicast v2 recording
+func writeAsciicast(file *os.File, metadata services.CaptureMetadata, cols, rows int) error {
+	return metadata.WriteAsciicast(file, services.AsciicastHeader{
+		Width:  cols,
+		Height: rows,
+		Env:    captureEnv(),
+	})
+}
+
+// captureEnv records the terminal environment for players that use it
+func captureEnv() map[string]string {
+	env := make(map[string]string)
+	for _, key := range []string{"TERM", "SHELL"} {
+		if value := os.Getenv(key); value != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// convertCapture rewrites an existing JSON capture as an asciicast v2 file
+func convertCapture(inputFile, outputFile string, cols, rows int) error {
+	data, err := os.ReadFile(inputFile)
+	if err != nil {
+		return err
+	}
+	var metadata services.CaptureMetadata
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", inputFile, err)
+	}
+
+	file, err := os.Create(outputFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return writeAsciicast(file, metadata, cols, rows)
+}
+
+// runDiff compares two captures and prints where their output diverges
+func runDiff(files []string, idleGap time.Duration, ignore, format string) error {
+	if len(files) != 2 {
+		return fmt.Errorf("-diff needs two capture files, got %d", len(files))
+	}
+	opts := services.CaptureDiffOptions{IdleGap: idleGap}
+	if ignore != "" {
+		pattern, err := regexp.Compile(ignore)
+		if err != nil {
+			return fmt.Errorf("invalid -ignore pattern: %w", err)
+		}
+		opts.Ignore = pattern
+	}
+
+	var captures [2]*services.CaptureMetadata
+	for i, file := range files {
+		capture, err := services.LoadCapture(file)
+		if err != nil {
+			return err
+		}
+		captures[i] = capture
+	}
+	result := services.DiffCaptures(captures[0], captures[1], opts)
+
+	if format == formatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	printDiff(files, result)
+	return nil
+}
+
+// printDiff prints a capture diff for reading in a terminal
+func printDiff(files []string, result *services.CaptureDiff) {
+	fmt.Printf("🔍 A: %s (%d segments, %.2fs)\n", files[0], result.SegmentsA, float64(result.DurationMsA)/1000)
+	fmt.Printf("🔍 B: %s (%d segments, %.2fs)\n", files[1], result.SegmentsB, float64(result.DurationMsB)/1000)
+	fmt.Println()
+
+	for i, segment := range result.Segments {
+		switch segment.Status {
+		case "same":
+			fmt.Printf("   %2d  ✅ same  (%d lines, %+.2fs)\n", i, segment.A.Lines, float64(segment.DurationDeltaMs)/1000)
+		case "changed":
+			fmt.Printf("   %2d  ⚠️  changed  (%.0f%% similar, %+.2fs)\n", i, segment.Similarity*100, float64(segment.DurationDeltaMs)/1000)
+			for _, line := range segment.Removed {
+				fmt.Printf("         - %s\n", line)
+			}
+			for _, line := range segment.Added {
+				fmt.Printf("         + %s\n", line)
+			}
+		case "only_a":
+			fmt.Printf("   %2d  ➖ only in A at %.2fs (%d lines)\n", i, float64(segment.A.StartMs)/1000, segment.A.Lines)
+		case "only_b":
+			fmt.Printf("   %2d  ➕ only in B at %.2fs (%d lines)\n", i, float64(segment.B.StartMs)/1000, segment.B.Lines)
+		}
+	}
+	fmt.Println()
+
+	if result.Identical {
+		fmt.Println("✅ Captures render the same output")
+		return
+	}
+	d := result.FirstDivergence
+	fmt.Printf("🔀 First divergence in segment %d (A at %.2fs, B at %.2fs)\n", d.Segment, float64(d.TimestampMsA)/1000, float64(d.TimestampMsB)/1000)
+	for _, line := range d.Context {
+		fmt.Printf("     %s\n", line)
+	}
+	fmt.Printf("   A %s\n", diffLine(d.LineA))
+	fmt.Printf("   B %s\n", diffLine(d.LineB))
+}
+
+func diffLine(line string) string {
+	if line == "" {
+		return "(no output)"
+	}
+	return line
+}
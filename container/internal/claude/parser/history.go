@@ -3,7 +3,6 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 
@@ -73,15 +72,10 @@ func (h *HistoryReader) readFromHistoryJSONL(projectPath string) ([]models.Claud
 	defer file.Close()
 
 	var entries []models.ClaudeHistoryEntry
-	decoder := json.NewDecoder(file)
-
-	for {
+	_, err = readJSONLines(file, 0, func(line []byte) {
 		var entry HistoryEntry
-		if err := decoder.Decode(&entry); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip invalid JSON lines
-			continue
+		if json.Unmarshal(line, &entry) != nil {
+			return
 		}
 
 		// Only include entries for this project
@@ -91,6 +85,9 @@ func (h *HistoryReader) readFromHistoryJSONL(projectPath string) ([]models.Claud
 				PastedContents: entry.PastedContents,
 			})
 		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history.jsonl: %w", err)
 	}
 
 	return entries, nil
@@ -136,18 +133,17 @@ func (h *HistoryReader) GetAllHistory() ([]HistoryEntry, error) {
 	defer file.Close()
 
 	var entries []HistoryEntry
-	decoder := json.NewDecoder(file)
-
-	for {
+	_, err = readJSONLines(file, 0, func(line []byte) {
 		var entry HistoryEntry
-		if err := decoder.Decode(&entry); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip invalid JSON lines but continue reading
-			continue
+		if json.Unmarshal(line, &entry) != nil {
+			// Skip entries that don't match the expected shape
+			return
 		}
 
 		entries = append(entries, entry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history.jsonl: %w", err)
 	}
 
 	return entries, nil
@@ -186,19 +182,18 @@ func (h *HistoryReader) readFromHistoryJSONLFull(projectPath string) ([]HistoryE
 	defer file.Close()
 
 	var entries []HistoryEntry
-	decoder := json.NewDecoder(file)
-
-	for {
+	_, err = readJSONLines(file, 0, func(line []byte) {
 		var entry HistoryEntry
-		if err := decoder.Decode(&entry); err == io.EOF {
-			break
-		} else if err != nil {
-			continue
+		if json.Unmarshal(line, &entry) != nil {
+			return
 		}
 
 		if entry.Project == projectPath {
 			entries = append(entries, entry)
 		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history.jsonl: %w", err)
 	}
 
 	return entries, nil
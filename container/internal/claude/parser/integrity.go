@@ -0,0 +1,134 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// IntegrityReport describes the malformed content of a session JSONL file
+type IntegrityReport struct {
+	Path      string `json:"path"`
+	SessionID string `json:"session_id"`
+	// Lines read, including malformed ones
+	Lines     int             `json:"lines"`
+	Malformed []MalformedLine `json:"malformed"`
+	// Whether the file ends in a line with no newline that isn't valid JSON
+	// (a write cut off by a crash, or one still in progress)
+	PartialTail bool `json:"partial_tail"`
+	// Bytes in malformed lines and the partial tail
+	LostBytes int64 `json:"lost_bytes"`
+	// Set when the malformed content was moved to QuarantinePath
+	Repaired       bool      `json:"repaired"`
+	QuarantinePath string    `json:"quarantine_path,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// Healthy reports whether the file had nothing to repair
+func (r *IntegrityReport) Healthy() bool {
+	return len(r.Malformed) == 0 && !r.PartialTail
+}
+
+// CheckSessionFile reads a session file and reports its malformed lines
+// without changing it
+func CheckSessionFile(path string) (*IntegrityReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	report, _, _ := checkSessionData(path, data)
+	return report, nil
+}
+
+// checkSessionData checks file contents and returns the valid lines and
+// where the unconsumed partial tail (if any) starts
+func checkSessionData(path string, data []byte) (*IntegrityReport, [][]byte, int64) {
+	report := &IntegrityReport{
+		Path:      path,
+		SessionID: strings.TrimSuffix(filepath.Base(path), ".jsonl"),
+		Malformed: []MalformedLine{},
+		CheckedAt: time.Now(),
+	}
+
+	var valid [][]byte
+	result, _ := readJSONLines(bytes.NewReader(data), 0, func(line []byte) {
+		valid = append(valid, line)
+	})
+	report.Lines = result.Lines
+	report.Malformed = append(report.Malformed, result.Malformed...)
+	for _, line := range result.Malformed {
+		report.LostBytes += int64(line.Length)
+	}
+	if result.PartialTail {
+		report.PartialTail = true
+		report.LostBytes += int64(len(data)) - result.End
+	}
+	return report, valid, result.End
+}
+
+// RepairSessionFile moves a session file's malformed lines and partial tail
+// into a quarantine file under quarantineDir and rewrites the session with
+// only its valid lines. It refuses if the file changes while being repaired,
+// since Claude may still be appending to it.
+func RepairSessionFile(path, quarantineDir string) (*IntegrityReport, error) {
+	before, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	report, valid, tailStart := checkSessionData(path, data)
+	if report.Healthy() {
+		return report, nil
+	}
+
+	// Keep exactly what was removed so nothing is lost for good
+	var quarantined bytes.Buffer
+	for _, line := range report.Malformed {
+		quarantined.Write(data[line.Offset : line.Offset+int64(line.Length)])
+		quarantined.WriteByte('\n')
+	}
+	if report.PartialTail {
+		quarantined.Write(data[tailStart:])
+		quarantined.WriteByte('\n')
+	}
+
+	if err := os.MkdirAll(quarantineDir, 0755); err != nil {
+		return nil, err
+	}
+	quarantinePath := filepath.Join(quarantineDir, fmt.Sprintf("%s.%d.quarantine.jsonl", report.SessionID, report.CheckedAt.Unix()))
+	if err := os.WriteFile(quarantinePath, quarantined.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write quarantine file: %w", err)
+	}
+
+	var repaired bytes.Buffer
+	for _, line := range valid {
+		repaired.Write(line)
+		repaired.WriteByte('\n')
+	}
+	tmpPath := path + ".repair.tmp"
+	if err := os.WriteFile(tmpPath, repaired.Bytes(), before.Mode().Perm()); err != nil {
+		return nil, err
+	}
+
+	after, err := os.Stat(path)
+	if err != nil || after.Size() != before.Size() || !after.ModTime().Equal(before.ModTime()) {
+		os.Remove(tmpPath)
+		os.Remove(quarantinePath)
+		return nil, fmt.Errorf("%s changed during repair; try again once the session is idle", filepath.Base(path))
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+
+	report.Repaired = true
+	report.QuarantinePath = quarantinePath
+	return report, nil
+}
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	integrityLine1 = `{"type":"user","sessionId":"s1","message":{"role":"user","content":"first"},"uuid":"msg-001","timestamp":"2025-11-21T10:00:00.000Z"}`
+	integrityLine2 = `{"type":"user","sessionId":"s1","message":{"role":"user","content":"second"},"uuid":"msg-002","timestamp":"2025-11-21T10:00:01.000Z"}`
+	integrityBad   = `{"type":"assistant","message":{"role":"assis`
+)
+
+func writeSessionFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cf568042-7147-4fba-a2ca-c6a646581260.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write session file: %v", err)
+	}
+	return path
+}
+
+func TestReadFull_SkipsMalformedLines(t *testing.T) {
+	path := writeSessionFile(t, integrityLine1+"\n"+integrityBad+"\n"+integrityLine2+"\n")
+	reader := NewSessionFileReader(path)
+
+	done := make(chan error, 1)
+	go func() { done <- reader.ReadFull() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("ReadFull failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadFull did not return on a malformed line")
+	}
+
+	if got := reader.GetStats().TotalMessages; got != 2 {
+		t.Errorf("Expected 2 messages around the malformed line, got %d", got)
+	}
+	malformed := reader.GetMalformedLines()
+	if len(malformed) != 1 || malformed[0].Line != 2 {
+		t.Fatalf("Expected line 2 to be reported as malformed, got %+v", malformed)
+	}
+	if malformed[0].Offset != int64(len(integrityLine1)+1) {
+		t.Errorf("Expected offset %d, got %d", len(integrityLine1)+1, malformed[0].Offset)
+	}
+}
+
+func TestReadIncremental_WaitsForPartialTail(t *testing.T) {
+	path := writeSessionFile(t, integrityLine1+"\n"+integrityLine2[:40])
+	reader := NewSessionFileReader(path)
+
+	messages, err := reader.ReadIncremental()
+	if err != nil {
+		t.Fatalf("ReadIncremental failed: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message before the partial tail, got %d", len(messages))
+	}
+	if !reader.HasPartialTail() || len(reader.GetMalformedLines()) != 0 {
+		t.Fatal("Expected a partial tail and no malformed lines")
+	}
+
+	// The rest of the line arrives and is read as a whole
+	if err := os.WriteFile(path, []byte(integrityLine1+"\n"+integrityLine2+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	messages, err = reader.ReadIncremental()
+	if err != nil {
+		t.Fatalf("ReadIncremental failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Uuid != "msg-002" {
+		t.Fatalf("Expected the completed second message, got %+v", messages)
+	}
+	if reader.HasPartialTail() {
+		t.Error("Expected partial tail to clear once the line is complete")
+	}
+}
+
+func TestCheckSessionFile(t *testing.T) {
+	path := writeSessionFile(t, integrityLine1+"\n\n"+integrityBad+"\n"+integrityLine2+"\n")
+
+	report, err := CheckSessionFile(path)
+	if err != nil {
+		t.Fatalf("CheckSessionFile failed: %v", err)
+	}
+	if report.SessionID != "cf568042-7147-4fba-a2ca-c6a646581260" {
+		t.Errorf("Unexpected session ID %q", report.SessionID)
+	}
+	if report.Healthy() || len(report.Malformed) != 1 || report.PartialTail {
+		t.Fatalf("Expected one malformed line, got %+v", report)
+	}
+	if report.LostBytes != int64(len(integrityBad)) {
+		t.Errorf("Expected %d lost bytes, got %d", len(integrityBad), report.LostBytes)
+	}
+
+	healthy, err := CheckSessionFile("testdata/minimal.jsonl")
+	if err != nil {
+		t.Fatalf("CheckSessionFile failed: %v", err)
+	}
+	if !healthy.Healthy() {
+		t.Errorf("Expected minimal.jsonl to be healthy, got %+v", healthy)
+	}
+}
+
+func TestRepairSessionFile(t *testing.T) {
+	tail := integrityLine2[:30]
+	path := writeSessionFile(t, integrityLine1+"\n"+integrityBad+"\n"+integrityLine2+"\n"+tail)
+	quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+
+	report, err := RepairSessionFile(path, quarantineDir)
+	if err != nil {
+		t.Fatalf("RepairSessionFile failed: %v", err)
+	}
+	if !report.Repaired || !report.PartialTail || len(report.Malformed) != 1 {
+		t.Fatalf("Expected a repaired malformed line and tail, got %+v", report)
+	}
+	if report.LostBytes != int64(len(integrityBad)+len(tail)) {
+		t.Errorf("Expected %d lost bytes, got %d", len(integrityBad)+len(tail), report.LostBytes)
+	}
+
+	repaired, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(repaired) != integrityLine1+"\n"+integrityLine2+"\n" {
+		t.Errorf("Unexpected repaired content:\n%s", repaired)
+	}
+
+	quarantined, err := os.ReadFile(report.QuarantinePath)
+	if err != nil {
+		t.Fatalf("Failed to read quarantine file: %v", err)
+	}
+	if string(quarantined) != integrityBad+"\n"+tail+"\n" {
+		t.Errorf("Unexpected quarantined content:\n%s", quarantined)
+	}
+	if !strings.HasPrefix(filepath.Base(report.QuarantinePath), report.SessionID+".") {
+		t.Errorf("Expected quarantine file named after the session, got %s", report.QuarantinePath)
+	}
+
+	// A second pass has nothing left to do
+	again, err := RepairSessionFile(path, quarantineDir)
+	if err != nil {
+		t.Fatalf("RepairSessionFile failed: %v", err)
+	}
+	if !again.Healthy() || again.Repaired {
+		t.Errorf("Expected repaired file to be healthy, got %+v", again)
+	}
+}
@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// maxMalformedPreview caps how much of a malformed line is kept for reports
+const maxMalformedPreview = 200
+
+// MalformedLine describes a session file line that isn't valid JSON
+type MalformedLine struct {
+	// 1-based line number, relative to where reading started
+	Line int `json:"line"`
+	// Byte offset of the line in the file
+	Offset int64 `json:"offset"`
+	// Length of the line in bytes, without the newline
+	Length int    `json:"length"`
+	Error  string `json:"error"`
+	// Start of the line, for deciding whether it mattered
+	Preview string `json:"preview"`
+}
+
+// jsonLinesResult summarizes a readJSONLines pass
+type jsonLinesResult struct {
+	// Offset just past the last line consumed
+	End       int64
+	Lines     int
+	Malformed []MalformedLine
+	// Whether the last line has no newline and isn't valid JSON yet (likely
+	// still being written, or cut off by a crash). It isn't consumed.
+	PartialTail bool
+}
+
+// readJSONLines calls fn with each valid JSON line of r, which starts at byte
+// offset start of the file. Blank lines are ignored and malformed lines are
+// reported and skipped, so one bad line can't stop a read the way it does
+// with json.Decoder (whose errors are sticky).
+func readJSONLines(r io.Reader, start int64, fn func(line []byte)) (jsonLinesResult, error) {
+	result := jsonLinesResult{End: start}
+	reader := bufio.NewReaderSize(r, 64*1024)
+
+	for {
+		raw, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return result, err
+		}
+		if len(raw) == 0 {
+			return result, nil
+		}
+
+		complete := raw[len(raw)-1] == '\n'
+		line := bytes.TrimRight(raw, "\r\n")
+		trimmed := bytes.TrimSpace(line)
+
+		if !complete && len(trimmed) > 0 && !json.Valid(trimmed) {
+			result.PartialTail = true
+			return result, nil
+		}
+
+		result.Lines++
+		offset := result.End
+		result.End += int64(len(raw))
+
+		if len(trimmed) > 0 {
+			if json.Valid(trimmed) {
+				fn(trimmed)
+			} else {
+				result.Malformed = append(result.Malformed, malformedLine(result.Lines, offset, line))
+			}
+		}
+
+		if !complete {
+			return result, nil
+		}
+	}
+}
+
+func malformedLine(lineNo int, offset int64, line []byte) MalformedLine {
+	var probe interface{}
+	errText := "invalid JSON"
+	if err := json.Unmarshal(line, &probe); err != nil {
+		errText = err.Error()
+	}
+	preview := line
+	if len(preview) > maxMalformedPreview {
+		preview = preview[:maxMalformedPreview]
+	}
+	return MalformedLine{
+		Line:    lineNo,
+		Offset:  offset,
+		Length:  len(line),
+		Error:   errText,
+		Preview: string(bytes.ToValidUTF8(preview, []byte("?"))),
+	}
+}
@@ -2,7 +2,6 @@ package parser
 
 import (
 	"encoding/json"
-	"io"
 	"os"
 	"sync"
 	"time"
@@ -25,6 +24,8 @@ type SessionFileReader struct {
 	thinking       []ThinkingBlock
 	subAgents      map[string]*SubAgentInfo
 	userMessageMap map[string]string // For automated prompt detection
+	malformed      []MalformedLine   // Lines skipped because they aren't valid JSON
+	partialTail    bool              // Last line is incomplete and not valid JSON
 
 	// Shared resources (injected, not owned)
 	historyReader *HistoryReader // Optional: for accessing user prompt history
@@ -83,30 +84,27 @@ func (r *SessionFileReader) ReadIncremental() ([]models.ClaudeSessionMessage, er
 		}
 	}
 
-	// Read and parse new messages
-	decoder := json.NewDecoder(file)
+	// Read and parse new messages, stopping before a partially written last line
 	var newMessages []models.ClaudeSessionMessage
-
-	for {
+	result, err := readJSONLines(file, r.lastOffset, func(line []byte) {
 		var msg models.ClaudeSessionMessage
-		if err := decoder.Decode(&msg); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip invalid JSON lines - just continue to next line
-			continue
+		if json.Unmarshal(line, &msg) != nil {
+			return
 		}
 
 		// Process the message to update cached state
 		r.processMessage(&msg)
 		newMessages = append(newMessages, msg)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Update position tracking
-	newOffset, err := file.Seek(0, io.SeekCurrent)
-	if err == nil {
-		r.lastOffset = newOffset
-	}
+	r.lastOffset = result.End
 	r.lastModTime = info.ModTime()
+	r.malformed = append(r.malformed, result.Malformed...)
+	r.partialTail = result.PartialTail
 
 	return newMessages, nil
 }
@@ -137,27 +135,24 @@ func (r *SessionFileReader) ReadFull() error {
 	}
 
 	// Read and parse all messages
-	decoder := json.NewDecoder(file)
-
-	for {
+	result, err := readJSONLines(file, 0, func(line []byte) {
 		var msg models.ClaudeSessionMessage
-		if err := decoder.Decode(&msg); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip invalid JSON lines
-			continue
+		if json.Unmarshal(line, &msg) != nil {
+			return
 		}
 
 		// Process the message
 		r.processMessage(&msg)
+	})
+	if err != nil {
+		return err
 	}
 
 	// Update position tracking
-	newOffset, err := file.Seek(0, io.SeekCurrent)
-	if err == nil {
-		r.lastOffset = newOffset
-	}
+	r.lastOffset = result.End
 	r.lastModTime = info.ModTime()
+	r.malformed = result.Malformed
+	r.partialTail = result.PartialTail
 
 	return nil
 }
@@ -305,15 +300,10 @@ func (r *SessionFileReader) GetAllMessages(filter MessageFilter) ([]models.Claud
 
 	// Build user message map for filtering (first pass)
 	userMsgMap := make(map[string]string)
-	decoder := json.NewDecoder(file)
-
-	for {
+	_, err = readJSONLines(file, 0, func(line []byte) {
 		var msg models.ClaudeSessionMessage
-		if err := decoder.Decode(&msg); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip invalid JSON lines
-			continue
+		if json.Unmarshal(line, &msg) != nil {
+			return
 		}
 
 		if msg.Type == "user" && msg.Message != nil {
@@ -323,6 +313,9 @@ func (r *SessionFileReader) GetAllMessages(filter MessageFilter) ([]models.Claud
 				}
 			}
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Reset file to beginning for second pass
@@ -332,25 +325,37 @@ func (r *SessionFileReader) GetAllMessages(filter MessageFilter) ([]models.Claud
 
 	// Second pass: collect filtered messages
 	var filtered []models.ClaudeSessionMessage
-	decoder = json.NewDecoder(file)
-
-	for {
+	_, err = readJSONLines(file, 0, func(line []byte) {
 		var msg models.ClaudeSessionMessage
-		if err := decoder.Decode(&msg); err == io.EOF {
-			break
-		} else if err != nil {
-			// Skip invalid JSON lines
-			continue
+		if json.Unmarshal(line, &msg) != nil {
+			return
 		}
 
 		if !ShouldSkipMessage(msg, filter, userMsgMap) {
 			filtered = append(filtered, msg)
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return filtered, nil
 }
 
+// GetMalformedLines returns the lines skipped as invalid JSON since the last full read
+func (r *SessionFileReader) GetMalformedLines() []MalformedLine {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]MalformedLine(nil), r.malformed...)
+}
+
+// HasPartialTail reports whether the file ended in an incomplete line at the last read
+func (r *SessionFileReader) HasPartialTail() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.partialTail
+}
+
 // GetStats returns the current session statistics
 func (r *SessionFileReader) GetStats() SessionStats {
 	r.mu.RLock()
@@ -394,6 +399,8 @@ func (r *SessionFileReader) Reset() {
 	r.thinking = nil
 	r.subAgents = make(map[string]*SubAgentInfo)
 	r.userMessageMap = make(map[string]string)
+	r.malformed = nil
+	r.partialTail = false
 }
 
 // GetFilePath returns the file path being monitored
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var backupRestoreForce bool
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "💾 Back up and restore volume state",
+	Long: `# 💾 Volume Backups
+
+**Snapshot catnip state to object storage and restore it onto a fresh volume.**
+
+Snapshots contain state.json, settings, session metadata and the refs (plus
+remotes) of every bare repository. Configure the destination with:
+
+- **CATNIP_BACKUP_URL** - s3://bucket/prefix, gs://bucket/prefix or file:///path
+- **CATNIP_BACKUP_INTERVAL** - How often ` + "`catnip serve`" + ` backs up (default 6h)
+- **CATNIP_BACKUP_RETAIN** - Snapshots to keep (default 14, 0 keeps all)
+
+S3 and GCS uploads use the ` + "`aws`" + ` and ` + "`gcloud`" + ` CLIs and their configured credentials.`,
+}
+
+var backupNowCmd = &cobra.Command{
+	Use:   "now",
+	Short: "Upload a snapshot immediately",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := services.NewBackupService()
+		if err != nil {
+			return err
+		}
+		key, err := service.Backup()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("✅ Uploaded %s\n", key)
+		return nil
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots, newest first",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := services.NewBackupService()
+		if err != nil {
+			return err
+		}
+		keys, err := service.List()
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			fmt.Println(key)
+		}
+		return nil
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [snapshot]",
+	Short: "Restore a snapshot (the newest by default)",
+	Long: `Restore a snapshot onto this volume. Run it while catnip serve is stopped.
+
+Existing files are kept unless --force is given. Missing bare repositories are
+recreated from their saved remotes and refs are reset to the snapshot; refs
+whose commits only existed locally are reported as missing.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, err := services.NewBackupService()
+		if err != nil {
+			return err
+		}
+		key := ""
+		if len(args) == 1 {
+			key = args[0]
+		}
+
+		result, err := service.Restore(key, backupRestoreForce)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("✅ Restored %s\n", result.Key)
+		fmt.Printf("   %d files, %d repositories, %d refs\n", len(result.Files), len(result.Repositories), result.RefsRestored)
+		for _, name := range result.SkippedExists {
+			fmt.Fprintf(os.Stderr, "⚠️  Kept existing %s (use --force to overwrite)\n", name)
+		}
+		for _, ref := range result.RefsMissing {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not restore %s (commit not available from remote)\n", ref)
+		}
+		return nil
+	},
+}
+
+func init() {
+	backupRestoreCmd.Flags().BoolVar(&backupRestoreForce, "force", false, "Overwrite existing files")
+	backupCmd.AddCommand(backupNowCmd, backupListCmd, backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var (
+	doctorFix    bool
+	doctorDryRun bool
+	doctorChecks []string
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "🩺 Diagnose and repair catnip's worktree and terminal state",
+	Long: `# 🩺 Doctor
+
+Ask the catnip server to check its state against git and the filesystem:
+
+- **gitdir_links** worktrees whose .git link to their metadata is broken
+- **missing_worktrees** worktrees catnip tracks that are gone from disk
+- **orphaned_worktrees** worktrees git still has registered that no longer exist
+- **stale_ports** ports allocated to terminal sessions that are gone
+- **circuit_breakers** terminals whose recreation is paused after repeated failures
+
+With --fix the issues are repaired and the actions taken are summarized.
+Add --dry-run to see what --fix would do without changing anything.
+Exits with an error while issues remain.`,
+	Example: `  catnip doctor
+  catnip doctor --fix --dry-run
+  catnip doctor --fix --check gitdir_links --check missing_worktrees`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		report := &services.DiagnosticsReport{}
+		if doctorFix || doctorDryRun {
+			var result services.DiagnosticsFixResult
+			err := doctorRequest("POST", "/v1/diagnostics/fix", map[string]interface{}{
+				"checks":  doctorChecks,
+				"dry_run": doctorDryRun,
+			}, &result)
+			if err != nil {
+				return err
+			}
+			printDoctorActions(&result)
+			report = result.Remaining
+			if result.DryRun {
+				return nil
+			}
+		} else {
+			query := ""
+			if len(doctorChecks) > 0 {
+				query = "?checks=" + url.QueryEscape(strings.Join(doctorChecks, ","))
+			}
+			if err := doctorRequest("GET", "/v1/diagnostics"+query, nil, report); err != nil {
+				return err
+			}
+		}
+
+		if len(report.Issues) == 0 {
+			fmt.Printf("✅ No issues found (%s)\n", strings.Join(report.Checks, ", "))
+			return nil
+		}
+		fmt.Printf("\n%d issue(s) found:\n", len(report.Issues))
+		fixable := 0
+		for _, issue := range report.Issues {
+			fmt.Printf("  ⚠️  [%s] %s: %s\n", issue.Check, issue.Target, issue.Message)
+			if issue.Fix != "" {
+				fixable++
+				fmt.Printf("      fix: %s\n", issue.Fix)
+			}
+		}
+		if fixable > 0 && !doctorFix {
+			fmt.Printf("\nRun catnip doctor --fix to repair %d of them\n", fixable)
+		}
+		return fmt.Errorf("%d issue(s) remain", len(report.Issues))
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "repair the issues found")
+	doctorCmd.Flags().BoolVar(&doctorDryRun, "dry-run", false, "with --fix, show the repairs without making them")
+	doctorCmd.Flags().StringSliceVar(&doctorChecks, "check", nil, "only run these checks ("+strings.Join(services.DiagnosticChecks, ", ")+")")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// printDoctorActions summarizes the fixes applied, or planned in a dry run
+func printDoctorActions(result *services.DiagnosticsFixResult) {
+	if len(result.Actions) == 0 {
+		fmt.Println("Nothing to fix")
+		return
+	}
+	applied, failed := 0, 0
+	for _, action := range result.Actions {
+		switch {
+		case result.DryRun:
+			fmt.Printf("  🔎 would %s for %s (%s)\n", action.Fix, action.Target, action.Check)
+		case action.Applied:
+			applied++
+			fmt.Printf("  ✅ %s for %s (%s)\n", action.Fix, action.Target, action.Check)
+		default:
+			failed++
+			fmt.Printf("  ❌ %s for %s (%s): %s\n", action.Fix, action.Target, action.Check, action.Error)
+		}
+	}
+	if result.DryRun {
+		fmt.Printf("\nDry run: %d fix(es) would be applied\n", len(result.Actions))
+	} else {
+		fmt.Printf("\n🩺 Applied %d fix(es), %d failed\n", applied, failed)
+	}
+}
+
+// doctorRequest calls a diagnostics endpoint on the catnip server, decoding
+// the response into out
+func doctorRequest(method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, catnipServerURL(path), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("catnip server is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s", apiErr.Error)
+		}
+		return fmt.Errorf("catnip server returned %s", resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+var gitCredentialCmd = &cobra.Command{
+	Use:    "git-credential <get|store|erase>",
+	Short:  "🔐 Git credential helper for GitHub",
+	Hidden: true, // Invoked by git, configured as credential.https://github.com.helper
+	Long: `# 🔐 Git Credential Helper
+
+Answers git's credential requests for github.com. Repositories set to
+authenticate as the GitHub App get an installation token from the catnip
+server; everything else is handed to "gh auth git-credential".`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGitCredential,
+}
+
+func init() {
+	rootCmd.AddCommand(gitCredentialCmd)
+}
+
+func runGitCredential(cmd *cobra.Command, args []string) error {
+	input, err := readAllStdin()
+	if err != nil {
+		return err
+	}
+
+	if args[0] == "get" {
+		request := parseCredentialRequest(input)
+		if request["protocol"] == "https" && request["host"] == "github.com" && request["path"] != "" {
+			if username, password, ok := appCredential(request["path"]); ok {
+				fmt.Printf("username=%s\npassword=%s\n", username, password)
+				return nil
+			}
+		}
+	}
+
+	gh := exec.Command("gh", "auth", "git-credential", args[0])
+	gh.Stdin = bytes.NewReader(input)
+	gh.Stdout = os.Stdout
+	gh.Stderr = os.Stderr
+	return gh.Run()
+}
+
+func readAllStdin() ([]byte, error) {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		buf.Write(scanner.Bytes())
+		buf.WriteByte('\n')
+		if scanner.Text() == "" {
+			break
+		}
+	}
+	return buf.Bytes(), scanner.Err()
+}
+
+// parseCredentialRequest reads git's key=value credential description
+func parseCredentialRequest(input []byte) map[string]string {
+	request := make(map[string]string)
+	for _, line := range strings.Split(string(input), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			request[key] = value
+		}
+	}
+	return request
+}
+
+// appCredential asks the server for a GitHub App token for the repository
+// at path (owner/repo.git). It fails quietly so git falls back to gh.
+func appCredential(path string) (string, string, bool) {
+	catnipHost := os.Getenv("CATNIP_HOST")
+	if catnipHost == "" {
+		catnipHost = "localhost:" + config.DefaultPort
+	}
+	repo := strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	endpoint := fmt.Sprintf("http://%s%s/v1/github/app/credential?repo=%s", catnipHost, config.Runtime.BasePath, url.QueryEscape(repo))
+
+	client := &http.Client{Timeout: 20 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return "", "", false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", false
+	}
+	var credential struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&credential); err != nil || credential.Password == "" {
+		return "", "", false
+	}
+	return credential.Username, credential.Password, true
+}
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
 )
 
@@ -32,14 +33,76 @@ type HookSpec struct {
 
 // ClaudeHookEvent represents Claude hook event payload
 type ClaudeHookEvent struct {
-	HookEventName string `json:"hook_event_name"`
-	CWD           string `json:"cwd"`
+	HookEventName string          `json:"hook_event_name"`
+	CWD           string          `json:"cwd"`
+	SessionID     string          `json:"session_id"`
+	ToolName      string          `json:"tool_name"`
+	ToolUseID     string          `json:"tool_use_id"`
+	ToolResponse  json.RawMessage `json:"tool_response"`
+	ToolInput     json.RawMessage `json:"tool_input"`
 }
 
 // CatnipHookPayload represents Catnip hook API payload
 type CatnipHookPayload struct {
-	EventType        string `json:"event_type"`
-	WorkingDirectory string `json:"working_directory"`
+	EventType        string                 `json:"event_type"`
+	WorkingDirectory string                 `json:"working_directory"`
+	SessionID        string                 `json:"session_id,omitempty"`
+	Data             map[string]interface{} `json:"data,omitempty"`
+}
+
+// toolOutcome trims a tool response down to the fields that tell whether the
+// tool failed, so large outputs aren't forwarded
+func toolOutcome(response json.RawMessage) interface{} {
+	var fields map[string]interface{}
+	if json.Unmarshal(response, &fields) == nil {
+		outcome := make(map[string]interface{})
+		for _, key := range []string{"success", "is_error", "isError", "interrupted", "error"} {
+			if value, ok := fields[key]; ok {
+				outcome[key] = value
+			}
+		}
+		return outcome
+	}
+	var text string
+	if json.Unmarshal(response, &text) == nil {
+		if len(text) > 64 {
+			text = text[:64]
+		}
+		return text
+	}
+	return nil
+}
+
+// maxForwardedCommand caps the shell command forwarded for lock checks
+const maxForwardedCommand = 4096
+
+// toolTargets picks the paths a tool is about to touch out of its input, so
+// catnip can check them against locked paths without receiving file contents
+func toolTargets(input json.RawMessage) map[string]interface{} {
+	var fields map[string]interface{}
+	if json.Unmarshal(input, &fields) != nil {
+		return nil
+	}
+	targets := make(map[string]interface{})
+	for _, key := range []string{"file_path", "notebook_path", "command"} {
+		value, ok := fields[key].(string)
+		if !ok || value == "" {
+			continue
+		}
+		if len(value) > maxForwardedCommand {
+			value = value[:maxForwardedCommand]
+		}
+		targets[key] = value
+	}
+	return targets
+}
+
+// CatnipHookOutput is the part of the catnip hook response that is handed to Claude
+type CatnipHookOutput struct {
+	Decision           string          `json:"decision,omitempty"`
+	Reason             string          `json:"reason,omitempty"`
+	HookSpecificOutput json.RawMessage `json:"hookSpecificOutput,omitempty"`
+	SystemMessage      string          `json:"systemMessage,omitempty"`
 }
 
 var installHooksCmd = &cobra.Command{
@@ -52,7 +115,7 @@ Install and configure Claude Code hooks to enable enhanced activity tracking in
 This command will:
 - Create the Claude settings directory if it doesn't exist
 - Configure Claude Code to send activity events to catnip
-- Set up hooks for UserPromptSubmit, PostToolUse, and Stop events
+- Set up hooks for UserPromptSubmit, PreToolUse, PostToolUse, and Stop events
 
 ## ✨ Features
 - **Automatic port detection** - No manual configuration needed
@@ -82,6 +145,7 @@ It reads JSON event data from stdin and sends it to the catnip server for activi
 
 ## 📋 Supported Events
 - **UserPromptSubmit** - User submitted a prompt to Claude
+- **PreToolUse** - Claude started using a tool
 - **PostToolUse** - Claude finished using a tool
 - **Stop** - Claude finished generating a response`,
 	Example: `  # Process a hook event (typically called by Claude Code)
@@ -170,7 +234,7 @@ func runInstallHooks(cmd *cobra.Command, args []string) error {
 	hookCommand := catnipPath + " hook"
 
 	// Define the hook events we want to track
-	events := []string{"SessionStart", "UserPromptSubmit", "PostToolUse", "Stop"}
+	events := []string{"SessionStart", "UserPromptSubmit", "PreToolUse", "PostToolUse", "Stop"}
 
 	for _, event := range events {
 		settings.Hooks[event] = []HookMatcher{
@@ -238,7 +302,7 @@ func runHook(cmd *cobra.Command, args []string) error {
 
 	// Only handle the events we care about for activity tracking
 	switch event.HookEventName {
-	case "SessionStart", "UserPromptSubmit", "PostToolUse", "Stop":
+	case "SessionStart", "UserPromptSubmit", "PreToolUse", "PostToolUse", "Stop":
 		// Good, we want to track these events
 	default:
 		// For other events, exit silently
@@ -265,6 +329,22 @@ func runHook(cmd *cobra.Command, args []string) error {
 	payload := CatnipHookPayload{
 		EventType:        event.HookEventName,
 		WorkingDirectory: event.CWD,
+		SessionID:        event.SessionID,
+	}
+	// Tool events carry what catnip needs to annotate the terminal output
+	if event.ToolName != "" {
+		payload.Data = map[string]interface{}{"tool_name": event.ToolName}
+		if event.ToolUseID != "" {
+			payload.Data["tool_use_id"] = event.ToolUseID
+		}
+		if event.HookEventName == "PostToolUse" {
+			payload.Data["tool_response"] = toolOutcome(event.ToolResponse)
+		}
+		if event.HookEventName == "PreToolUse" {
+			if targets := toolTargets(event.ToolInput); len(targets) > 0 {
+				payload.Data["tool_input"] = targets
+			}
+		}
 	}
 
 	payloadData, err := json.Marshal(payload)
@@ -274,7 +354,7 @@ func runHook(cmd *cobra.Command, args []string) error {
 	}
 
 	// Send the hook event to catnip server
-	url := fmt.Sprintf("http://%s/v1/claude/hooks", catnipHost)
+	url := fmt.Sprintf("http://%s%s/v1/claude/hooks", catnipHost, config.Runtime.BasePath)
 	client := &http.Client{
 		Timeout: 5 * time.Second,
 	}
@@ -296,7 +376,16 @@ func runHook(cmd *cobra.Command, args []string) error {
 
 	// Read response to avoid connection leaks, but don't check status
 	// We exit successfully regardless to avoid breaking Claude
-	_, _ = io.ReadAll(resp.Body)
+	body, _ := io.ReadAll(resp.Body)
+
+	// Pass a decision from catnip (e.g. a time-boxed session asking Claude to
+	// wrap up, or a locked path) through to Claude as hook output
+	var output CatnipHookOutput
+	if json.Unmarshal(body, &output) == nil && (output.Decision != "" || len(output.HookSpecificOutput) > 0 || output.SystemMessage != "") {
+		if data, err := json.Marshal(output); err == nil {
+			fmt.Println(string(data))
+		}
+	}
 
 	return nil
 }
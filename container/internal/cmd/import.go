@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+var (
+	importRepo string
+	importBase string
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <branch>",
+	Short: "📥 Work on an existing branch in a new worktree",
+	Long: `# 📥 Import
+
+Attach an existing branch, like a teammate's feature/foo, to a new catnip
+worktree instead of starting a new catnip/ branch. Commits made in the
+worktree land on the branch and pushes go back to it. The worktree is tracked,
+monitored and runs setup.sh like any other, and deleting it keeps the branch.
+
+The branch is imported into the repository of the worktree you run this in
+unless --repo says otherwise.`,
+	Example: `  catnip import feature/foo
+  catnip import origin/fix/login --base develop
+  catnip import feature/foo --repo acme/app`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		repoID := importRepo
+		if repoID == "" {
+			worktreeID, err := currentWorktreeID()
+			if err != nil {
+				return fmt.Errorf("%w\nPass --repo to choose the repository", err)
+			}
+			worktree, err := findCatnipWorktree(worktreeID)
+			if err != nil {
+				return err
+			}
+			repoID = worktree.RepoID
+		}
+
+		var response struct {
+			Worktree models.Worktree `json:"worktree"`
+		}
+		body := map[string]string{"branch": args[0], "base": importBase}
+		if err := catnipRequest("POST", "/v1/git/repositories/"+url.PathEscape(repoID)+"/worktrees/from-branch", body, &response); err != nil {
+			return err
+		}
+
+		worktree := response.Worktree
+		fmt.Printf("📥 %s is checked out in %s (diffs against %s)\n", worktree.Branch, worktree.Name, worktree.SourceBranch)
+		fmt.Printf("   cd %s\n", worktree.Path)
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVar(&importRepo, "repo", "", "repository to import into (defaults to the current worktree's)")
+	importCmd.Flags().StringVar(&importBase, "base", "", "branch to diff against (defaults to the repository's default branch)")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/services"
+	"golang.org/x/term"
+)
+
+var (
+	kubeExecWorkspace string
+	kubeExecDir       string
+	kubeExecPorts     []int
+	kubeExecEnv       []string
+)
+
+var kubeExecCmd = &cobra.Command{
+	Use:    "kube-exec --workspace <name> --dir <path> [flags] -- command [args...]",
+	Short:  "☸️ Run a workspace session in its Kubernetes pod",
+	Hidden: true, // Started by the server in place of session commands with CATNIP_WORKSPACE_RUNTIME=kubernetes
+	Long: `# ☸️ Kubernetes Exec
+
+Starts the workspace's pod if needed, exposes the session's ports through
+the workspace's service and attaches the terminal to the command in the pod,
+exiting with its exit code. Window size changes are passed on.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runKubeExec,
+}
+
+func init() {
+	kubeExecCmd.Flags().StringVar(&kubeExecWorkspace, "workspace", "", "Workspace whose pod runs the command")
+	kubeExecCmd.Flags().StringVar(&kubeExecDir, "dir", "", "Working directory in the pod")
+	kubeExecCmd.Flags().IntSliceVar(&kubeExecPorts, "port", nil, "Port to expose through the workspace's service (repeatable)")
+	kubeExecCmd.Flags().StringArrayVar(&kubeExecEnv, "env", nil, "NAME=value to set for the command (repeatable)")
+	_ = kubeExecCmd.MarkFlagRequired("workspace")
+	_ = kubeExecCmd.MarkFlagRequired("dir")
+	rootCmd.AddCommand(kubeExecCmd)
+}
+
+func runKubeExec(cmd *cobra.Command, args []string) error {
+	runtime, err := services.NewKubernetesRuntime()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	fmt.Fprintf(os.Stderr, "☸️  Waiting for the %s pod...\r\n", kubeExecWorkspace)
+	pod, err := runtime.EnsurePod(ctx, kubeExecWorkspace)
+	if err != nil {
+		return err
+	}
+	if err := runtime.ExposePorts(ctx, kubeExecWorkspace, kubeExecPorts); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️ %v\r\n", err)
+	}
+
+	env := append([]string{"CATNIP_HOST=" + services.KubernetesServerHost()}, kubeExecEnv...)
+	stream, err := runtime.Exec(ctx, pod, services.KubernetesExecCommand(kubeExecDir, env, args))
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	restore := func() {}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return fmt.Errorf("failed to make stdin raw: %w", err)
+		}
+		restore = func() {
+			_ = term.Restore(int(os.Stdin.Fd()), oldState)
+		}
+
+		// Pass window size changes on to the pod's terminal
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, syscall.SIGWINCH)
+		defer signal.Stop(ch)
+		go func() {
+			for range ch {
+				if rows, cols, err := pty.Getsize(os.Stdin); err == nil {
+					_ = stream.Resize(cols, rows)
+				}
+			}
+		}()
+		ch <- syscall.SIGWINCH // Initial size
+	}
+
+	go func() {
+		_, _ = io.Copy(stream, os.Stdin)
+	}()
+
+	exitCode, err := stream.Wait(os.Stdout)
+	restore()
+	if err != nil {
+		return err
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	return nil
+}
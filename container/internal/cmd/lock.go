@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+var (
+	lockReason string
+	lockOwner  string
+	lockTTL    time.Duration
+)
+
+var lockCmd = &cobra.Command{
+	Use:   "lock",
+	Short: "🔒 Keep Claude away from files you're editing",
+	Long: `# 🔒 Locks
+
+Lock paths in the current worktree while you work on them by hand. Claude
+sessions in the worktree are told about the locks with every prompt, and
+edits to locked paths are warned about, or denied when CATNIP_PATH_LOCK_MODE
+is "block".
+
+Paths are globs relative to the worktree root; "**" matches any number of
+directories and a directory covers everything in it.`,
+	Example: `  catnip lock add src/auth --reason "Refactoring the middleware by hand"
+  catnip lock add 'docs/**/*.md' go.mod --reason "Release prep" --ttl 2h
+  catnip lock list
+  catnip lock rm 0b6e2f4a-9c1d-4e8b-a7f3-2d5c8e1b9a40`,
+}
+
+var lockAddCmd = &cobra.Command{
+	Use:   "add <path>...",
+	Short: "Lock paths in this worktree",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lockTTL > 0 && lockTTL < time.Minute {
+			return fmt.Errorf("--ttl must be at least 1m")
+		}
+		owner := lockOwner
+		if owner == "" {
+			owner = os.Getenv("USER")
+		}
+		var lock models.WorktreeLock
+		err := worktreeRequest("POST", "/locks", map[string]interface{}{
+			"paths":       args,
+			"reason":      lockReason,
+			"owner":       owner,
+			"ttl_minutes": int(lockTTL.Minutes()),
+		}, &lock)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔒 Locked %s (%s)\n", strings.Join(lock.Paths, ", "), lock.ID)
+		return nil
+	},
+}
+
+var lockListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the locks in this worktree",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var locks []models.WorktreeLock
+		if err := worktreeRequest("GET", "/locks", nil, &locks); err != nil {
+			return err
+		}
+		if len(locks) == 0 {
+			fmt.Println("Nothing is locked in this worktree")
+			return nil
+		}
+		for _, lock := range locks {
+			expires := "never"
+			if lock.ExpiresAt != nil {
+				expires = lock.ExpiresAt.Local().Format("Jan 2 15:04")
+			}
+			fmt.Printf("%s  %-12s  expires %-12s  %s: %s\n", lock.ID, lock.Owner, expires, strings.Join(lock.Paths, ", "), lock.Reason)
+		}
+		return nil
+	},
+}
+
+var lockRemoveCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Release a lock",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := worktreeRequest("DELETE", "/locks/"+url.PathEscape(args[0]), nil, nil); err != nil {
+			return err
+		}
+		fmt.Println("🔓 Released")
+		return nil
+	},
+}
+
+func init() {
+	lockAddCmd.Flags().StringVar(&lockReason, "reason", "", "why the paths are locked (shown to Claude)")
+	lockAddCmd.Flags().StringVar(&lockOwner, "owner", "", "who holds the lock (default $USER)")
+	lockAddCmd.Flags().DurationVar(&lockTTL, "ttl", 0, "release the lock after this long, e.g. 2h (default never)")
+	_ = lockAddCmd.MarkFlagRequired("reason")
+	lockCmd.AddCommand(lockAddCmd, lockListCmd, lockRemoveCmd)
+	rootCmd.AddCommand(lockCmd)
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var memoryKind string
+
+var memoryCmd = &cobra.Command{
+	Use:   "memory",
+	Short: "🧠 Remember facts and conventions across Claude sessions",
+	Long: `# 🧠 Memory
+
+Keep facts, decisions and conventions for the current worktree. Every new
+Claude session in the worktree gets them as context, so corrections like
+"we use pnpm, not npm" survive session resets and terminal restarts.
+
+Claude is told about this command and uses it when you correct it.`,
+	Example: `  catnip memory add --kind convention "Use pnpm, not npm"
+  catnip memory add --kind decision "Keep the v1 API until the mobile app migrates"
+  catnip memory list
+  catnip memory rm 6f1c2b9e-3a8d-4c7f-9e21-5b0a4d3c2e1f`,
+}
+
+var memoryAddCmd = &cobra.Command{
+	Use:   "add <text>",
+	Short: "Remember something in this worktree",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source := services.MemorySourceUser
+		// Claude Code sets CLAUDECODE in the environment of commands it runs
+		if os.Getenv("CLAUDECODE") != "" {
+			source = services.MemorySourceClaude
+		}
+		var memory models.WorktreeMemory
+		err := worktreeRequest("POST", "/memory", map[string]string{
+			"text":   strings.Join(args, " "),
+			"kind":   memoryKind,
+			"source": source,
+		}, &memory)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🧠 Remembered %s: %s\n", memory.Kind, memory.Text)
+		return nil
+	},
+}
+
+var memoryListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show what this worktree remembers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var memories []models.WorktreeMemory
+		if err := worktreeRequest("GET", "/memory", nil, &memories); err != nil {
+			return err
+		}
+		if len(memories) == 0 {
+			fmt.Println("Nothing remembered in this worktree yet")
+			return nil
+		}
+		for _, memory := range memories {
+			fmt.Printf("%s  %-10s  %s\n", memory.ID, memory.Kind, memory.Text)
+		}
+		return nil
+	},
+}
+
+var memoryRemoveCmd = &cobra.Command{
+	Use:   "rm <id>",
+	Short: "Forget a memory",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := worktreeRequest("DELETE", "/memory/"+url.PathEscape(args[0]), nil, nil); err != nil {
+			return err
+		}
+		fmt.Println("🧠 Forgotten")
+		return nil
+	},
+}
+
+func init() {
+	memoryAddCmd.Flags().StringVar(&memoryKind, "kind", services.MemoryKindFact, "fact, decision or convention")
+	memoryCmd.AddCommand(memoryAddCmd, memoryListCmd, memoryRemoveCmd)
+	rootCmd.AddCommand(memoryCmd)
+}
+
+// catnipServerURL returns the URL of an API path on the local catnip server
+func catnipServerURL(path string) string {
+	catnipHost := os.Getenv("CATNIP_HOST")
+	if catnipHost == "" {
+		catnipHost = "localhost:" + config.DefaultPort
+	}
+	return fmt.Sprintf("http://%s%s%s", catnipHost, config.Runtime.BasePath, path)
+}
+
+// worktreeRequest calls an endpoint under the worktree containing the current
+// directory, decoding the response into out when it's non-nil
+func worktreeRequest(method, suffix string, body interface{}, out interface{}) error {
+	worktreeID, err := currentWorktreeID()
+	if err != nil {
+		return err
+	}
+	return worktreeIDRequest(worktreeID, method, suffix, body, out)
+}
+
+// worktreeIDRequest calls an endpoint under a worktree, decoding the response
+// into out when it's non-nil
+func worktreeIDRequest(worktreeID, method, suffix string, body interface{}, out interface{}) error {
+	return catnipRequest(method, "/v1/git/worktrees/"+url.PathEscape(worktreeID)+suffix, body, out)
+}
+
+// catnipRequest calls an API path on the catnip server, decoding the
+// response into out when it's non-nil
+func catnipRequest(method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, catnipServerURL(path), reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("catnip server is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s", apiErr.Error)
+		}
+		return fmt.Errorf("catnip server returned %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// listCatnipWorktrees fetches the worktrees known to the catnip server
+func listCatnipWorktrees() ([]models.Worktree, error) {
+	resp, err := http.Get(catnipServerURL("/v1/git/worktrees"))
+	if err != nil {
+		return nil, fmt.Errorf("catnip server is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var worktrees []models.Worktree
+	if err := json.NewDecoder(resp.Body).Decode(&worktrees); err != nil {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	return worktrees, nil
+}
+
+// currentWorktreeID finds the catnip worktree containing the current directory
+func currentWorktreeID() (string, error) {
+	worktrees, err := listCatnipWorktrees()
+	if err != nil {
+		return "", err
+	}
+	root := worktreeRoot()
+	for _, worktree := range worktrees {
+		if worktree.Path == root {
+			return worktree.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a catnip worktree", root)
+}
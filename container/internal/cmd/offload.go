@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var (
+	offloadIfMatches bool
+	offloadRunner    string
+)
+
+var offloadCmd = &cobra.Command{
+	Use:   "offload [flags] -- command [args...]",
+	Short: "🚀 Run a heavy command on a remote runner",
+	Long: `# 🚀 Offload
+
+Run a command on a remote runner configured in the worktree's .catnip.yaml,
+streaming its output back and exiting with its exit code.
+
+    offload:
+      runners:
+        gpu:
+          ssh: ubuntu@gpu-box
+      rules:
+        - pattern: "make train*"
+          runner: gpu
+
+Terminals wrap the programs named by rules (here, make) so matching commands
+are offloaded transparently; with --if-matches anything else runs locally.`,
+	Example: `  catnip offload -- make train EPOCHS=3
+  catnip offload --runner gpu -- python bench.py`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runOffload,
+}
+
+func init() {
+	offloadCmd.Flags().BoolVar(&offloadIfMatches, "if-matches", false, "Run the command locally unless an offload rule matches it")
+	offloadCmd.Flags().StringVar(&offloadRunner, "runner", "", "Runner to use instead of the matching rule's")
+	rootCmd.AddCommand(offloadCmd)
+}
+
+func runOffload(cmd *cobra.Command, args []string) error {
+	command := shellJoin(args)
+	workDir := worktreeRoot()
+
+	if offloadIfMatches && offloadRunner == "" {
+		cfg, err := services.LoadOffloadConfig(workDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ catnip offload: %v\n", err)
+			return execLocal(args)
+		}
+		if _, ok := cfg.Match(command); !ok {
+			return execLocal(args)
+		}
+	}
+
+	exitCode, err := runOffloadOnServer(workDir, command)
+	if err != nil {
+		if offloadIfMatches {
+			fmt.Fprintf(os.Stderr, "⚠️ catnip offload failed, running locally: %v\n", err)
+			return execLocal(args)
+		}
+		return err
+	}
+	os.Exit(exitCode)
+	return nil
+}
+
+// runOffloadOnServer asks the catnip server to run the command and copies its
+// streamed output to this terminal
+func runOffloadOnServer(workDir, command string) (int, error) {
+	catnipHost := os.Getenv("CATNIP_HOST")
+	if catnipHost == "" {
+		catnipHost = "localhost:" + config.DefaultPort
+	}
+	body, _ := json.Marshal(map[string]string{
+		"worktree_path": workDir,
+		"command":       command,
+		"runner":        offloadRunner,
+	})
+	url := fmt.Sprintf("http://%s%s/v1/offload/run", catnipHost, config.Runtime.BasePath)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("catnip server returned %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var line struct {
+			Data     string `json:"data"`
+			Stderr   bool   `json:"stderr"`
+			ExitCode *int   `json:"exit_code"`
+			Error    string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		if line.ExitCode != nil {
+			if line.Error != "" {
+				return -1, fmt.Errorf("%s", line.Error)
+			}
+			return *line.ExitCode, nil
+		}
+		if line.Stderr {
+			fmt.Fprint(os.Stderr, line.Data)
+		} else {
+			fmt.Fprint(os.Stdout, line.Data)
+		}
+	}
+	return -1, fmt.Errorf("offload stream ended without an exit code")
+}
+
+// execLocal replaces this process with the command, so signals and the
+// terminal behave as if it had been run directly
+func execLocal(args []string) error {
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, args, os.Environ())
+}
+
+// worktreeRoot returns the top of the git worktree containing the current directory
+func worktreeRoot() string {
+	if output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output(); err == nil {
+		return strings.TrimSpace(string(output))
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// shellJoin quotes arguments so the remote shell sees them unchanged
+func shellJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && strings.IndexFunc(arg, func(r rune) bool {
+			return !(r == '-' || r == '_' || r == '.' || r == '/' || r == '=' || r == ':' || r == ',' || r == '+' || r == '@' ||
+				(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+		}) < 0 {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
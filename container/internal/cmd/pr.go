@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+var (
+	prFeedbackRefresh bool
+	prFeedbackJSON    bool
+)
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "🔍 Check on the current worktree's pull request",
+	Long: `# 🔍 Pull Request
+
+Show what reviewers and CI said about the current worktree's pull request:
+requested changes, unresolved review comments and failing checks. Claude can
+run this to pick up review feedback and fix it.`,
+	Example: `  catnip pr feedback
+  catnip pr feedback --refresh
+  catnip pr feedback --json`,
+}
+
+var prFeedbackCmd = &cobra.Command{
+	Use:   "feedback",
+	Short: "Show reviews, unresolved comments and CI status",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		suffix := "/pr/feedback"
+		if prFeedbackRefresh {
+			suffix += "?refresh=true"
+		}
+		var feedback models.PullRequestFeedback
+		if err := worktreeRequest("GET", suffix, nil, &feedback); err != nil {
+			return err
+		}
+		if prFeedbackJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(feedback)
+		}
+		printPRFeedback(&feedback)
+		return nil
+	},
+}
+
+func init() {
+	prFeedbackCmd.Flags().BoolVar(&prFeedbackRefresh, "refresh", false, "fetch from GitHub instead of using the last sync")
+	prFeedbackCmd.Flags().BoolVar(&prFeedbackJSON, "json", false, "print the feedback as JSON")
+	prCmd.AddCommand(prFeedbackCmd)
+	rootCmd.AddCommand(prCmd)
+}
+
+func printPRFeedback(feedback *models.PullRequestFeedback) {
+	fmt.Printf("🔍 %s\n", feedback.Summary)
+	if len(feedback.ChangesRequestedBy) > 0 {
+		fmt.Printf("   Changes requested by %s\n", strings.Join(feedback.ChangesRequestedBy, ", "))
+	}
+	if len(feedback.ApprovedBy) > 0 {
+		fmt.Printf("   Approved by %s\n", strings.Join(feedback.ApprovedBy, ", "))
+	}
+
+	checks := feedback.Checks
+	if checks.Total > 0 {
+		fmt.Printf("\nChecks: %d passed, %d failed, %d pending\n", checks.Passed, checks.Failed, checks.Pending)
+		for _, name := range checks.Failing {
+			fmt.Printf("   ❌ %s\n", name)
+		}
+	}
+
+	if len(feedback.Comments) > 0 {
+		fmt.Printf("\nUnresolved comments:\n")
+		for _, comment := range feedback.Comments {
+			location := comment.Path
+			if comment.Line > 0 {
+				location = fmt.Sprintf("%s:%d", comment.Path, comment.Line)
+			}
+			fmt.Printf("\n💬 %s on %s\n", comment.Author, location)
+			for _, line := range strings.Split(strings.TrimSpace(comment.Body), "\n") {
+				fmt.Printf("   %s\n", line)
+			}
+		}
+		if hidden := feedback.UnresolvedThreads - len(feedback.Comments); hidden > 0 {
+			fmt.Printf("\n(%d more unresolved threads on GitHub)\n", hidden)
+		}
+	}
+}
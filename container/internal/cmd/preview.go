@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+var (
+	previewSquash     bool
+	previewKeepBranch bool
+	previewMessage    string
+	previewYes        bool
+	previewNoRefresh  bool
+	previewPlanOnly   bool
+	previewContinue   bool
+	previewAbort      bool
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "🔀 Bring a worktree's changes into your own checkout",
+	Long: `# 🔀 Preview
+
+Run these in your own checkout of the repository, outside the container.
+
+Catnip pushes a worktree's changes, uncommitted ones included, to a
+catnip/<name> preview branch in your checkout. "preview merge" refreshes that
+branch, fetches it from the catnip server when your checkout doesn't already
+have it, shows what it would bring in and merges it into the branch you have
+checked out. When the merge stops on conflicts, resolve them in your editor
+and run it again with --continue, or back out with --abort.
+
+The preview branch is deleted once it's merged.`,
+	Example: `  catnip preview merge milo
+  catnip preview merge milo --plan
+  catnip preview merge milo --squash --message "Add the login redirect"
+  catnip preview merge milo --continue
+  catnip preview clean milo`,
+}
+
+var previewMergeCmd = &cobra.Command{
+	Use:   "merge <worktree>",
+	Short: "Merge a worktree's preview branch into the current branch",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if previewContinue && previewAbort {
+			return fmt.Errorf("--continue and --abort can't be combined")
+		}
+		worktree, err := findCatnipWorktree(args[0])
+		if err != nil {
+			return err
+		}
+		repoPath := worktreeRoot()
+		previewBranch := services.PreviewBranchName(worktree.Branch)
+		merger := services.NewPreviewMerger(git.NewOperations())
+		opts := services.PreviewMergeOptions{Squash: previewSquash, KeepBranch: previewKeepBranch, Message: previewMessage}
+
+		switch {
+		case previewAbort:
+			if err := merger.Abort(repoPath); err != nil {
+				return withHint(err)
+			}
+			fmt.Printf("↩️  Aborted the merge of %s\n", previewBranch)
+			return nil
+		case previewContinue:
+			result, err := merger.Continue(repoPath, previewBranch, opts)
+			if err != nil {
+				return withHint(err)
+			}
+			printPreviewMergeResult(previewBranch, args[0], result)
+			return nil
+		}
+
+		if !previewNoRefresh {
+			if err := worktreeIDRequest(worktree.ID, "POST", "/preview", nil, nil); err != nil {
+				return fmt.Errorf("failed to refresh %s: %w", previewBranch, err)
+			}
+		}
+		if err := fetchPreviewBranch(repoPath, worktree, previewBranch); err != nil {
+			return err
+		}
+
+		plan, err := merger.Plan(repoPath, previewBranch)
+		if err != nil {
+			return withHint(err)
+		}
+		printPreviewMergePlan(plan)
+		if previewPlanOnly || plan.UpToDate && previewKeepBranch {
+			return nil
+		}
+		if !previewYes && !plan.UpToDate && !confirm(fmt.Sprintf("Merge %s into %s?", previewBranch, plan.TargetBranch)) {
+			return nil
+		}
+
+		result, err := merger.Apply(repoPath, previewBranch, opts)
+		if err != nil {
+			return withHint(err)
+		}
+		printPreviewMergeResult(previewBranch, args[0], result)
+		return nil
+	},
+}
+
+var previewCleanCmd = &cobra.Command{
+	Use:   "clean <worktree>",
+	Short: "Delete a worktree's preview branch",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		worktree, err := findCatnipWorktree(args[0])
+		if err != nil {
+			return err
+		}
+		repoPath := worktreeRoot()
+		previewBranch := services.PreviewBranchName(worktree.Branch)
+		operations := git.NewOperations()
+
+		deleted := false
+		if operations.BranchExists(repoPath, previewBranch, false) {
+			if err := services.NewPreviewMerger(operations).DeleteBranch(repoPath, previewBranch); err != nil {
+				return withHint(err)
+			}
+			deleted = true
+		}
+		// The server's copy is in another checkout when the branch was fetched
+		var response struct {
+			Deleted bool `json:"deleted"`
+		}
+		if err := worktreeIDRequest(worktree.ID, "DELETE", "/preview", nil, &response); err != nil {
+			return err
+		}
+		if !deleted && !response.Deleted {
+			fmt.Printf("There's no %s branch to delete\n", previewBranch)
+			return nil
+		}
+		fmt.Printf("🗑️  Deleted %s\n", previewBranch)
+		return nil
+	},
+}
+
+func init() {
+	previewMergeCmd.Flags().BoolVar(&previewSquash, "squash", false, "apply the preview as one commit instead of a merge")
+	previewMergeCmd.Flags().BoolVar(&previewKeepBranch, "keep-branch", false, "keep the preview branch after merging")
+	previewMergeCmd.Flags().StringVarP(&previewMessage, "message", "m", "", `commit message (default "Merge preview catnip/<name>")`)
+	previewMergeCmd.Flags().BoolVarP(&previewYes, "yes", "y", false, "merge without asking")
+	previewMergeCmd.Flags().BoolVar(&previewNoRefresh, "no-refresh", false, "use the preview branch as it is instead of updating it from the worktree")
+	previewMergeCmd.Flags().BoolVar(&previewPlanOnly, "plan", false, "only show what the merge would do")
+	previewMergeCmd.Flags().BoolVar(&previewContinue, "continue", false, "commit the merge once its conflicts are resolved")
+	previewMergeCmd.Flags().BoolVar(&previewAbort, "abort", false, "undo a merge that stopped on conflicts")
+	previewCmd.AddCommand(previewMergeCmd, previewCleanCmd)
+	rootCmd.AddCommand(previewCmd)
+}
+
+// findCatnipWorktree finds a worktree by ID, name, branch or workspace name
+func findCatnipWorktree(ref string) (*models.Worktree, error) {
+	worktrees, err := listCatnipWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	for i, worktree := range worktrees {
+		if ref == worktree.ID || ref == worktree.Name || ref == worktree.Branch ||
+			ref == git.ExtractWorkspaceName(worktree.Branch) || ref == services.PreviewBranchName(worktree.Branch) {
+			return &worktrees[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no catnip worktree named %s", ref)
+}
+
+// fetchPreviewBranch fetches the preview branch from the catnip server's git
+// endpoint. When catnip mounts this checkout the branch is already here and
+// the fetch only fails if it's checked out, so failures are only fatal when
+// the branch is missing.
+func fetchPreviewBranch(repoPath string, worktree *models.Worktree, previewBranch string) error {
+	repoName := worktree.RepoID[strings.LastIndex(worktree.RepoID, "/")+1:]
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/heads/%s", previewBranch, previewBranch)
+	output, err := exec.Command("git", "-C", repoPath, "fetch", "--quiet", catnipServerURL("/"+repoName+".git"), refspec).CombinedOutput()
+	if err == nil || git.NewOperations().BranchExists(repoPath, previewBranch, false) {
+		return nil
+	}
+	return fmt.Errorf("%s isn't in %s and couldn't be fetched from catnip: %s\nRun this in your checkout of %s", previewBranch, repoPath, strings.TrimSpace(string(output)), worktree.RepoID)
+}
+
+// withHint appends an API error's hint to its message
+func withHint(err error) error {
+	if apiErr, ok := models.AsAPIError(err); ok && apiErr.Hint != "" {
+		return fmt.Errorf("%s\n%s", apiErr.Message, apiErr.Hint)
+	}
+	return err
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func printPreviewMergePlan(plan *services.PreviewMergePlan) {
+	target := plan.TargetBranch
+	if target == "" {
+		target = "HEAD (detached)"
+	}
+	if plan.UpToDate {
+		fmt.Printf("✅ %s already has everything in %s\n", target, plan.PreviewBranch)
+		return
+	}
+
+	fmt.Printf("🔀 %s → %s: %d commit(s), %d file(s)", plan.PreviewBranch, target, len(plan.Commits), len(plan.Files))
+	if plan.Behind > 0 {
+		fmt.Printf(", %s is %d commit(s) ahead", target, plan.Behind)
+	}
+	fmt.Println()
+	for _, commit := range plan.Commits {
+		fmt.Printf("   %s\n", commit)
+	}
+
+	switch {
+	case plan.FastForward:
+		fmt.Println("   Fast-forward, no conflicts")
+	case len(plan.Conflicts) > 0:
+		fmt.Printf("⚠️  %d file(s) will conflict:\n", len(plan.Conflicts))
+		for _, file := range plan.Conflicts {
+			fmt.Printf("   %s\n", file)
+		}
+	default:
+		fmt.Println("   Merges cleanly")
+	}
+	if plan.MergeInProgress {
+		fmt.Println("⚠️  A merge is in progress: finish it with --continue or undo it with --abort")
+	} else if plan.DirtyCheckout {
+		fmt.Println("⚠️  Your checkout has uncommitted changes: commit or stash them first")
+	}
+}
+
+func printPreviewMergeResult(previewBranch, worktreeRef string, result *services.PreviewMergeResult) {
+	switch result.Status {
+	case services.PreviewMergeConflicts:
+		fmt.Printf("⚠️  Merging %s stopped on conflicts in:\n", previewBranch)
+		for _, file := range result.Conflicts {
+			fmt.Printf("   %s\n", file)
+		}
+		fmt.Println("\nResolve them, then run:")
+		fmt.Printf("   catnip preview merge %s --continue\n", worktreeRef)
+		fmt.Println("or undo the merge with:")
+		fmt.Printf("   catnip preview merge %s --abort\n", worktreeRef)
+		return
+	case services.PreviewMergeUpToDate:
+		fmt.Println("✅ Nothing to merge")
+	default:
+		fmt.Printf("✅ Merged %s (%s)\n", previewBranch, result.Commit)
+	}
+	if result.BranchDeleted {
+		fmt.Printf("🗑️  Deleted %s\n", previewBranch)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/creack/pty"
 	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/services"
 	"golang.org/x/term"
 )
 
@@ -30,7 +31,8 @@ The title log format is: timestamp|pid|cwd|title
 
 Environment variables:
 - CATNIP_TITLE_LOG: Path to title log file (default: ~/.catnip/title_events.log)
-- CATNIP_DISABLE_PTY_INTERCEPTOR: Set to "1" or "true" to disable interception`,
+- CATNIP_DISABLE_PTY_INTERCEPTOR: Set to "1" or "true" to disable interception
+- CATNIP_APPEND_SYSTEM_PROMPT: Layered system prompt passed to claude as --append-system-prompt`,
 	Example: `  catnip purr claude --version
   catnip purr /home/vscode/.local/bin/claude-real chat
   CATNIP_TITLE_LOG=/tmp/titles.log catnip purr some-command`,
@@ -45,6 +47,8 @@ func init() {
 }
 
 func runPurr(cmd *cobra.Command, args []string) error {
+	args = withLayeredSystemPrompt(args)
+
 	// Check if interceptor is disabled
 	if disabled := os.Getenv("CATNIP_DISABLE_PTY_INTERCEPTOR"); disabled == "1" || disabled == "true" {
 		// Just execute the command directly
@@ -70,6 +74,24 @@ func runPurr(cmd *cobra.Command, args []string) error {
 	return execWithTitleInterception(args, titleLogPath)
 }
 
+// withLayeredSystemPrompt adds the org/repo/workspace system prompt catnip
+// puts in the environment to claude invocations, unless the caller already
+// set a system prompt
+func withLayeredSystemPrompt(args []string) []string {
+	prompt := os.Getenv(services.AppendSystemPromptEnv)
+	// Don't layer it again into claude processes started by this one
+	os.Unsetenv(services.AppendSystemPromptEnv)
+	if prompt == "" || !strings.Contains(filepath.Base(args[0]), "claude") {
+		return args
+	}
+	for _, arg := range args[1:] {
+		if arg == "--system-prompt" || arg == "--append-system-prompt" {
+			return args
+		}
+	}
+	return append([]string{args[0], "--append-system-prompt", prompt}, args[1:]...)
+}
+
 func execDirect(args []string) error {
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Stdin = os.Stdin
@@ -0,0 +1,423 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/spf13/cobra"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+	"golang.org/x/term"
+)
+
+// Exit codes of catnip run task, so scripts can tell outcomes apart. Errors
+// reaching catnip or starting the task exit with 1.
+const (
+	runTaskExitAgentFailed   = 2
+	runTaskExitTestsFailed   = 3
+	runTaskExitNoPullRequest = 4
+	runTaskExitInterrupted   = 130
+)
+
+// Statuses reported by catnip run task
+const (
+	RunTaskSucceeded     = "succeeded"
+	RunTaskAgentFailed   = "agent_failed"
+	RunTaskTestsFailed   = "tests_failed"
+	RunTaskNoPullRequest = "no_pull_request"
+	RunTaskInterrupted   = "interrupted"
+)
+
+var runTaskExitCodes = map[string]int{
+	RunTaskSucceeded:     0,
+	RunTaskAgentFailed:   runTaskExitAgentFailed,
+	RunTaskTestsFailed:   runTaskExitTestsFailed,
+	RunTaskNoPullRequest: runTaskExitNoPullRequest,
+	RunTaskInterrupted:   runTaskExitInterrupted,
+}
+
+var (
+	runTaskWorkspace  string
+	runTaskModel      string
+	runTaskMaxTurns   int
+	runTaskMaxMinutes int
+	runTaskResume     bool
+	runTaskTest       string
+	runTaskRequirePR  bool
+	runTaskJSON       bool
+)
+
+var runTaskCmd = &cobra.Command{
+	Use:   "task [prompt]",
+	Short: "🤖 Give Claude a task in a workspace and wait for the outcome",
+	Long: `# 🤖 Run a Task
+
+Send a prompt to Claude in a catnip workspace, stream its progress and exit
+with a status code describing the outcome, so agents can be driven from
+scripts and CI pipelines. The prompt is read from stdin when none is given.
+
+## 🚦 Exit Codes
+- **0** Claude finished, and tests passed and a pull request exists if asked for
+- **1** catnip couldn't be reached or the task couldn't start
+- **2** Claude failed (error, turn or time limit)
+- **3** the --test command failed
+- **4** --require-pr was set and the branch has no pull request
+- **130** interrupted; Claude keeps working in the workspace
+
+Progress goes to stderr. With **--json** stdout gets a single JSON object
+with the outcome, otherwise a short summary.
+
+**--test** runs in the workspace directory on this machine, so use it where
+the workspace is mounted (e.g. inside the container).`,
+	Example: `  catnip run task "Fix the failing date parsing tests" --test "go test ./..."
+  catnip run task -w catnip/felix --require-pr "Open a PR for the docs update"
+  echo "Upgrade vitest" | catnip run task --json --max-minutes 30 | jq .status`,
+	RunE: runTask,
+}
+
+func init() {
+	runTaskCmd.Flags().StringVarP(&runTaskWorkspace, "workspace", "w", "", "Workspace name (e.g. catnip/felix or felix), ID or path (default: the one containing the current directory)")
+	runTaskCmd.Flags().StringVar(&runTaskModel, "model", "", "Claude model to use")
+	runTaskCmd.Flags().IntVar(&runTaskMaxTurns, "max-turns", 0, "Stop after this many turns")
+	runTaskCmd.Flags().IntVar(&runTaskMaxMinutes, "max-minutes", 0, "Tell Claude to wrap up and stop after this many minutes")
+	runTaskCmd.Flags().BoolVar(&runTaskResume, "resume", false, "Continue the workspace's latest Claude session")
+	runTaskCmd.Flags().StringVar(&runTaskTest, "test", "", "Command that must pass in the workspace once Claude is done")
+	runTaskCmd.Flags().BoolVar(&runTaskRequirePR, "require-pr", false, "Fail unless the workspace's branch has a pull request once Claude is done")
+	runTaskCmd.Flags().BoolVar(&runTaskJSON, "json", false, "Print the outcome as JSON")
+	runCmd.AddCommand(runTaskCmd)
+}
+
+// RunTaskResult is the outcome of catnip run task, printed with --json
+type RunTaskResult struct {
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	WorktreeID string `json:"worktree_id"`
+	Worktree   string `json:"worktree"`
+	Branch     string `json:"branch"`
+	// Claude's last message
+	Response string                   `json:"response,omitempty"`
+	Result   *models.CompletionResult `json:"result,omitempty"`
+	Error    string                   `json:"error,omitempty"`
+	Tests    *RunTaskTests            `json:"tests,omitempty"`
+	// Pull request of the workspace's branch, when --require-pr was set
+	PullRequestURL string `json:"pull_request_url,omitempty"`
+}
+
+// RunTaskTests is the outcome of the --test command
+type RunTaskTests struct {
+	Command    string `json:"command"`
+	Passed     bool   `json:"passed"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+func runTask(cmd *cobra.Command, args []string) error {
+	prompt, err := runTaskPrompt(args)
+	if err != nil {
+		return err
+	}
+	worktree, err := findRunTaskWorktree(runTaskWorkspace)
+	if err != nil {
+		return err
+	}
+	if runTaskTest != "" {
+		if _, err := os.Stat(worktree.Path); err != nil {
+			return fmt.Errorf("--test runs in %s, which isn't available here; run catnip run task where the workspace is mounted", worktree.Path)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result := &RunTaskResult{WorktreeID: worktree.ID, Worktree: worktree.Name, Branch: worktree.Branch}
+	fmt.Fprintf(os.Stderr, "🤖 Running task in %s (%s)\n", worktree.Name, worktree.Branch)
+	if err := streamRunTask(ctx, worktree, prompt, result); err != nil {
+		return err
+	}
+
+	if result.Status == "" && runTaskTest != "" {
+		fmt.Fprintf(os.Stderr, "🧪 %s\n", runTaskTest)
+		result.Tests = runTaskTests(ctx, worktree.Path, runTaskTest)
+		switch {
+		case ctx.Err() != nil:
+			result.Status = RunTaskInterrupted
+		case !result.Tests.Passed:
+			result.Status = RunTaskTestsFailed
+		}
+	}
+	if result.Status == "" && runTaskRequirePR {
+		var pr models.PullRequestInfo
+		if err := getCatnipJSON("/v1/git/worktrees/"+url.PathEscape(worktree.ID)+"/pr", &pr); err != nil {
+			return fmt.Errorf("failed to check for a pull request: %w", err)
+		}
+		result.PullRequestURL = pr.URL
+		if !pr.Exists {
+			result.Status = RunTaskNoPullRequest
+		}
+	}
+	if result.Status == "" {
+		result.Status = RunTaskSucceeded
+	}
+	result.ExitCode = runTaskExitCodes[result.Status]
+
+	if runTaskJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(result)
+	} else {
+		printRunTaskSummary(result)
+	}
+	if result.ExitCode != 0 {
+		os.Exit(result.ExitCode)
+	}
+	return nil
+}
+
+// runTaskPrompt joins the prompt arguments, or reads the prompt from stdin
+// when there are none (or the only one is "-")
+func runTaskPrompt(args []string) (string, error) {
+	prompt := strings.Join(args, " ")
+	if prompt == "" || prompt == "-" {
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			return "", fmt.Errorf("no prompt given; pass it as an argument or on stdin")
+		}
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read prompt from stdin: %w", err)
+		}
+		prompt = string(data)
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return "", fmt.Errorf("the prompt is empty")
+	}
+	return prompt, nil
+}
+
+// findRunTaskWorktree finds a workspace by ID, name, path or the last part
+// of its name, defaulting to the one containing the current directory
+func findRunTaskWorktree(ref string) (*models.Worktree, error) {
+	worktrees, err := listCatnipWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" {
+		root := worktreeRoot()
+		for i := range worktrees {
+			if worktrees[i].Path == root {
+				return &worktrees[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%s is not a catnip worktree; pick one with --workspace", root)
+	}
+
+	var matches []*models.Worktree
+	for i := range worktrees {
+		worktree := &worktrees[i]
+		if worktree.ID == ref || worktree.Name == ref || worktree.Path == ref {
+			return worktree, nil
+		}
+		if strings.HasSuffix(worktree.Name, "/"+ref) {
+			matches = append(matches, worktree)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no workspace named %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, worktree := range matches {
+			names[i] = worktree.Name
+		}
+		return nil, fmt.Errorf("%s matches several workspaces: %s", ref, strings.Join(names, ", "))
+	}
+}
+
+// streamRunTask sends the task over the completion WebSocket and prints
+// Claude's messages to stderr until it's done. It sets a failure status on
+// result when Claude failed or the run was interrupted, and only returns an
+// error when the task couldn't start.
+func streamRunTask(ctx context.Context, worktree *models.Worktree, prompt string, result *RunTaskResult) error {
+	socketURL := "ws" + strings.TrimPrefix(catnipServerURL("/v1/claude/messages/ws"), "http")
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, socketURL, nil)
+	if err != nil {
+		return fmt.Errorf("catnip server is not reachable: %w", err)
+	}
+	defer conn.Close()
+
+	req := models.CreateCompletionRequest{
+		Prompt:           prompt,
+		Model:            runTaskModel,
+		MaxTurns:         runTaskMaxTurns,
+		WorkingDirectory: worktree.Path,
+		Resume:           runTaskResume,
+	}
+	if runTaskMaxMinutes > 0 {
+		req.Budget = &models.SessionBudget{MaxMinutes: runTaskMaxMinutes}
+	}
+	if err := conn.WriteJSON(req); err != nil {
+		return fmt.Errorf("failed to send the task: %w", err)
+	}
+
+	// Closing the socket unblocks the read below; Claude keeps running
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	started, finished := false, false
+	for !finished {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil {
+				result.Status = RunTaskInterrupted
+				fmt.Fprintln(os.Stderr, "\n🛑 Interrupted; Claude keeps working in the workspace")
+				return nil
+			}
+			if !started {
+				return fmt.Errorf("task stream closed before it started: %w", err)
+			}
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				result.Error = err.Error()
+			}
+			break
+		}
+
+		var event struct {
+			Type string `json:"type"`
+			models.CreateCompletionResponse
+		}
+		if err := json.Unmarshal(data, &event); err != nil {
+			continue
+		}
+		switch event.Type {
+		case "completion_start":
+			started = true
+			for _, warning := range event.Warnings {
+				fmt.Fprintf(os.Stderr, "⚠️ %s\n", warning.Message)
+			}
+			continue
+		case "error":
+			if !started {
+				return fmt.Errorf("%s", event.Error)
+			}
+			result.Error = event.Error
+			finished = true
+			continue
+		}
+
+		switch {
+		case event.Permission != nil:
+			fmt.Fprintf(os.Stderr, "⏸️ Waiting for permission to use %s\n", event.Permission.ToolName)
+		case event.Response == services.NoAssistantText:
+			fmt.Fprintln(os.Stderr, "🔧 …")
+		case event.Response != "":
+			result.Response = event.Response
+			fmt.Fprintf(os.Stderr, "💬 %s\n", event.Response)
+		}
+		if event.IsLast {
+			finished = true
+			result.Result = event.Result
+			if event.Error != "" {
+				result.Error = event.Error
+			}
+		}
+	}
+
+	if !finished && result.Error == "" {
+		result.Error = "Claude stopped without reporting a result"
+	}
+	if result.Error != "" || (result.Result != nil && result.Result.IsError) {
+		result.Status = RunTaskAgentFailed
+	}
+	return nil
+}
+
+// runTaskTests runs the --test command in the workspace, streaming its
+// output to stderr
+func runTaskTests(ctx context.Context, dir, command string) *RunTaskTests {
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+
+	started := time.Now()
+	err := cmd.Run()
+	tests := &RunTaskTests{
+		Command:    command,
+		Passed:     err == nil,
+		DurationMS: time.Since(started).Milliseconds(),
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		tests.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		tests.ExitCode = -1
+	}
+	return tests
+}
+
+// getCatnipJSON fetches an API path from the catnip server into out
+func getCatnipJSON(path string, out interface{}) error {
+	resp, err := http.Get(catnipServerURL(path))
+	if err != nil {
+		return fmt.Errorf("catnip server is not reachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s", apiErr.Error)
+		}
+		return fmt.Errorf("catnip server returned %s", resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+func printRunTaskSummary(result *RunTaskResult) {
+	switch result.Status {
+	case RunTaskSucceeded:
+		fmt.Printf("✅ Task finished in %s", result.Worktree)
+	case RunTaskAgentFailed:
+		fmt.Printf("❌ Claude failed in %s", result.Worktree)
+	case RunTaskTestsFailed:
+		fmt.Printf("❌ Tests failed in %s", result.Worktree)
+	case RunTaskNoPullRequest:
+		fmt.Printf("❌ No pull request for %s", result.Branch)
+	case RunTaskInterrupted:
+		fmt.Printf("🛑 Stopped waiting for %s", result.Worktree)
+	}
+	if r := result.Result; r != nil && r.NumTurns > 0 {
+		fmt.Printf(" (%d turns, %s, $%.2f)", r.NumTurns, (time.Duration(r.DurationMS) * time.Millisecond).Round(time.Second), r.TotalCostUSD)
+	}
+	fmt.Println()
+	if result.Error != "" {
+		fmt.Printf("   %s\n", result.Error)
+	}
+	if result.Tests != nil {
+		fmt.Printf("🧪 %s exited with %d\n", result.Tests.Command, result.Tests.ExitCode)
+	}
+	if result.PullRequestURL != "" {
+		fmt.Printf("🔗 %s\n", result.PullRequestURL)
+	}
+}
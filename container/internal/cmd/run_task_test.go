@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// fakeTaskServer serves the worktree list and a completion WebSocket that
+// replies to any task with the given messages, passing on the task it got
+func fakeTaskServer(t *testing.T, messages ...string) <-chan models.CreateCompletionRequest {
+	t.Helper()
+	received := make(chan models.CreateCompletionRequest, 1)
+	upgrader := websocket.Upgrader{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/git/worktrees", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]models.Worktree{
+			{ID: "wt-1", Name: "catnip/felix", Branch: "feature/docs", Path: "/worktrees/catnip/felix"},
+			{ID: "wt-2", Name: "api/felix", Path: "/worktrees/api/felix"},
+			{ID: "wt-3", Name: "api/luna", Path: "/worktrees/api/luna"},
+		})
+	})
+	mux.HandleFunc("/v1/claude/messages/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		var req models.CreateCompletionRequest
+		require.NoError(t, conn.ReadJSON(&req))
+		received <- req
+		for _, message := range messages {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(message)))
+		}
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	t.Setenv("CATNIP_HOST", strings.TrimPrefix(server.URL, "http://"))
+	return received
+}
+
+func TestFindRunTaskWorktree(t *testing.T) {
+	fakeTaskServer(t)
+
+	for ref, want := range map[string]string{
+		"wt-3":                 "wt-3",
+		"catnip/felix":         "wt-1",
+		"/worktrees/api/felix": "wt-2",
+		"luna":                 "wt-3",
+	} {
+		worktree, err := findRunTaskWorktree(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, worktree.ID, ref)
+	}
+
+	_, err := findRunTaskWorktree("felix")
+	assert.ErrorContains(t, err, "matches several workspaces: catnip/felix, api/felix")
+	_, err = findRunTaskWorktree("oscar")
+	assert.ErrorContains(t, err, "no workspace named oscar")
+}
+
+func TestStreamRunTask(t *testing.T) {
+	worktree := &models.Worktree{ID: "wt-1", Name: "catnip/felix", Path: "/worktrees/catnip/felix"}
+
+	received := fakeTaskServer(t,
+		`{"type":"completion_start","completion_id":"c1"}`,
+		`{"response":"Looking at the tests","is_chunk":true}`,
+		`{"response":"`+services.NoAssistantText+`","is_chunk":true}`,
+		`{"response":"Fixed the date parsing","is_chunk":true}`,
+		`{"is_chunk":true,"is_last":true,"result":{"subtype":"success","num_turns":4,"duration_ms":5000,"total_cost_usd":0.12}}`,
+	)
+	runTaskMaxMinutes = 15
+	t.Cleanup(func() { runTaskMaxMinutes = 0 })
+	result := &RunTaskResult{}
+	require.NoError(t, streamRunTask(context.Background(), worktree, "Fix the tests", result))
+	req := <-received
+	assert.Equal(t, "Fix the tests", req.Prompt)
+	assert.Equal(t, worktree.Path, req.WorkingDirectory)
+	assert.Equal(t, 15, req.Budget.MaxMinutes)
+	assert.Empty(t, result.Status, "success leaves the status to the later checks")
+	assert.Equal(t, "Fixed the date parsing", result.Response)
+	require.NotNil(t, result.Result)
+	assert.Equal(t, 4, result.Result.NumTurns)
+
+	// Claude reporting an error
+	fakeTaskServer(t,
+		`{"type":"completion_start"}`,
+		`{"is_chunk":true,"is_last":true,"error":"Claude stopped: error_max_turns","result":{"subtype":"error_max_turns","is_error":true}}`,
+	)
+	result = &RunTaskResult{}
+	require.NoError(t, streamRunTask(context.Background(), worktree, "Fix the tests", result))
+	assert.Equal(t, RunTaskAgentFailed, result.Status)
+	assert.Equal(t, "Claude stopped: error_max_turns", result.Error)
+
+	// The stream ending without a result, e.g. a budget stop
+	fakeTaskServer(t, `{"type":"completion_start"}`, `{"response":"Working","is_chunk":true}`)
+	result = &RunTaskResult{}
+	require.NoError(t, streamRunTask(context.Background(), worktree, "Fix the tests", result))
+	assert.Equal(t, RunTaskAgentFailed, result.Status)
+
+	// A rejected task is an error, not an agent outcome
+	fakeTaskServer(t, `{"type":"error","status":422,"error":"Prompt blocked by pre-flight linting"}`)
+	err := streamRunTask(context.Background(), worktree, "Fix the tests", &RunTaskResult{})
+	assert.ErrorContains(t, err, "Prompt blocked")
+}
+
+func TestRunTaskTests(t *testing.T) {
+	dir := t.TempDir()
+	tests := runTaskTests(context.Background(), dir, "test -d .")
+	assert.True(t, tests.Passed)
+	tests = runTaskTests(context.Background(), dir, "exit 7")
+	assert.False(t, tests.Passed)
+	assert.Equal(t, 7, tests.ExitCode)
+}
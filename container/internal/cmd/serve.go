@@ -4,6 +4,8 @@ import (
 	"net/http/pprof"
 	"os"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/adaptor"
@@ -13,6 +15,7 @@ import (
 	"github.com/spf13/cobra"
 	_ "github.com/vanpelt/catnip/docs" // This will be generated by swag
 	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
 	"github.com/vanpelt/catnip/internal/handlers"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
@@ -43,6 +46,9 @@ func init() {
 
 	// Add port flag
 	serveCmd.Flags().StringP("port", "p", "6369", "Port to listen on")
+	serveCmd.Flags().String("base-path", "", "Path prefix to serve under behind a reverse proxy, e.g. /catnip (default $CATNIP_BASE_PATH)")
+	serveCmd.Flags().String("external-url", "", "URL users reach catnip at, used for generated links (default $CATNIP_EXTERNAL_URL)")
+	serveCmd.Flags().String("profile", "", "Settings profile to apply, e.g. strict-safety, demo or power-user (default $CATNIP_PROFILE)")
 }
 
 // @title Catnip Container API
@@ -57,6 +63,15 @@ func startServer(cmd *cobra.Command) {
 	logLevel := logger.GetLogLevelFromEnv(isDevMode)
 	logger.Configure(logLevel, true) // Always use formatted output
 
+	// Apply the settings profile and CATNIP_* overrides from the volume before services read their settings
+	if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+		_ = os.Setenv(services.ProfileSetting, profile)
+	}
+	configManager := services.NewConfigManager()
+	if err := configManager.Load(); err != nil {
+		logger.Warnf("⚠️ Failed to load configuration overrides: %v", err)
+	}
+
 	// Send codespace credentials to worker if we're in a codespace (once on startup)
 	go updateCodespaceCredentials()
 
@@ -70,6 +85,21 @@ func startServer(cmd *cobra.Command) {
 		}
 	}
 
+	// A state purge removes the state files on the way out, after the deferred
+	// Stop of every service below, so nothing writes its state back
+	var purgeState atomic.Bool
+	var dataExport *services.DataExportService
+	defer func() {
+		if purgeState.Load() {
+			result := dataExport.Purge([]string{services.DataState})
+			if len(result.Failed) > 0 {
+				logger.Warnf("⚠️ Failed to purge %d state files: %s", len(result.Failed), strings.Join(result.Failed, ", "))
+			} else {
+				logger.Infof("🗑️ State purged")
+			}
+		}
+	}()
+
 	// Start settings persistence manager in containerized environments
 	var settings *models.Settings
 	if config.Runtime.IsContainerized() {
@@ -93,7 +123,8 @@ func startServer(cmd *cobra.Command) {
 	if catnipRepo := os.Getenv("CATNIP_REPO"); catnipRepo != "" {
 		logger.Infof("🌟 CATNIP_REPO detected: %s", catnipRepo)
 		go func() {
-			// Parse CATNIP_REPO format: github.com/owner/repo[@branch]
+			// Parse CATNIP_REPO format: github.com/owner/repo[@branch]. GitLab and
+			// Bitbucket repos keep their host: gitlab.com/group/project[@branch]
 			parts := strings.Split(catnipRepo, "@")
 			repoParts := strings.Split(strings.TrimPrefix(parts[0], "github.com/"), "/")
 			if len(repoParts) >= 2 {
@@ -126,6 +157,23 @@ func startServer(cmd *cobra.Command) {
 		DisableStartupMessage: false,
 		AppName:               "Catnip Container v1.0.0",
 	})
+	// Only voice uploads may exceed the default body limit, with room for
+	// their multipart framing
+	app.Server().HeaderReceived = handlers.RouteBodyLimits(map[string]int{
+		"/v1/voice/transcribe": services.MaxVoiceAudioBytes + 1<<20,
+	})
+
+	// Serve under a path prefix when deployed behind a reverse proxy
+	if basePath, _ := cmd.Flags().GetString("base-path"); basePath != "" {
+		config.Runtime.SetPublicAddress(basePath, config.Runtime.ExternalURL)
+	}
+	if externalURL, _ := cmd.Flags().GetString("external-url"); externalURL != "" {
+		config.Runtime.SetPublicAddress(config.Runtime.BasePath, externalURL)
+	}
+	if config.Runtime.BasePath != "" {
+		logger.Infof("🔀 Serving under base path %s", config.Runtime.BasePath)
+		app.Use(handlers.StripBasePath(config.Runtime.BasePath))
+	}
 
 	// Middleware
 	app.Use(handlers.SamplingLogger())
@@ -157,6 +205,18 @@ func startServer(cmd *cobra.Command) {
 		app.Get("/debug/pprof/threadcreate", adaptor.HTTPHandler(pprof.Handler("threadcreate")))
 	}
 
+	// Get port from flag or environment variable
+	port, _ := cmd.Flags().GetString("port")
+	if envPort := os.Getenv("PORT"); envPort != "" {
+		port = envPort
+	}
+	config.Runtime.Port = port
+
+	// Reverse tunnel so instances behind NAT are reachable from the mobile app
+	tunnelService := services.NewTunnelService(port)
+	tunnelService.Start()
+	defer tunnelService.Stop()
+
 	// Settings endpoint - returns environment configuration
 	app.Get("/v1/settings", func(c *fiber.Ctx) error {
 		catnipProxy := os.Getenv("CATNIP_PROXY")
@@ -166,6 +226,9 @@ func startServer(cmd *cobra.Command) {
 			"authRequired":  catnipProxy != "",
 			"codespaceName": codespaceName,
 			"isCodespace":   codespaceName != "",
+			"tunnel":        tunnelService.Status(),
+			"basePath":      config.Runtime.BasePath,
+			"externalURL":   config.Runtime.ExternalURL,
 		})
 	})
 
@@ -182,11 +245,21 @@ func startServer(cmd *cobra.Command) {
 	claudeService := services.NewClaudeService()
 	sessionService := services.NewSessionService()
 	parserService := services.NewParserService()
+	sessionIntegrity := services.NewSessionIntegrityService()
+	transcriptIndex := services.NewTranscriptIndex()
 
 	// Wire up services
-	claudeService.SetSessionService(sessionService) // For best session file selection
-	claudeService.SetParserService(parserService)   // For centralized session parsing
-	parserService.SetClaudeService(claudeService)   // For finding project directories
+	claudeService.SetSessionService(sessionService)     // For best session file selection
+	claudeService.SetParserService(parserService)       // For centralized session parsing
+	claudeService.SetGitService(gitService)             // For worktree Claude tool policies
+	parserService.SetClaudeService(claudeService)       // For finding project directories
+	parserService.SetIntegrityService(sessionIntegrity) // Quarantines malformed session lines on access
+	parserService.SetTranscriptIndex(transcriptIndex)   // Keeps transcript search current as sessions are read
+
+	// Snapshot Claude's project metadata; ~/.claude.json itself is only ever read
+	if err := claudeService.SyncClaudeProjects(); err != nil {
+		logger.Warnf("⚠️ Failed to sync Claude project metadata: %v", err)
+	}
 
 	// Start parser service
 	parserService.Start()
@@ -196,6 +269,17 @@ func startServer(cmd *cobra.Command) {
 
 	// Initialize handlers
 	ptyHandler := handlers.NewPTYHandler(gitService, claudeMonitor, sessionService, portMonitor)
+	// PTY chaos testing (latency, dropped frames, forced disconnects) for
+	// hardening client reconnect logic
+	if ptyChaos := services.NewPTYChaosFromEnv(); ptyChaos != nil {
+		logger.Warnf("🐒 PTY chaos mode enabled, configure it at /debug/pty/chaos")
+		ptyHandler.WithChaos(ptyChaos)
+		app.Get("/debug/pty/chaos", ptyHandler.HandleGetChaos)
+		app.Put("/debug/pty/chaos", ptyHandler.HandleUpdateChaos)
+		app.Post("/debug/pty/chaos/disconnect", ptyHandler.HandleChaosDisconnect)
+	}
+	// Terminal connection lifecycle events, to measure multi-tab conflicts
+	app.Get("/debug/pty/connections", ptyHandler.HandleGetConnectionLog)
 
 	// Initialize Claude onboarding service (after ptyHandler so it can restart sessions after auth)
 	claudeOnboardingService := services.NewClaudeOnboardingService(ptyHandler)
@@ -205,9 +289,25 @@ func startServer(cmd *cobra.Command) {
 	gitService.SetSetupExecutor(ptyHandler)
 	logger.Debugf("✅ setupExecutor configured successfully")
 
+	// Sandboxes close the worktree's terminals before unmounting
+	gitService.SetWorkspaceSessions(ptyHandler)
+
 	// Wire up the claude monitor to git service
 	gitService.SetClaudeMonitor(claudeMonitor)
 
+	// Mirror configured repositories so checkouts work offline
+	mirrorService := services.NewMirrorService()
+	gitService.SetMirrorService(mirrorService)
+	mirrorService.Start()
+	defer mirrorService.Stop()
+
+	// Sync the user's dotfiles from the volume's manifest before any worktree or terminal starts
+	dotfileSync := services.NewDotfileSyncService()
+	dotfileSync.Start()
+	defer dotfileSync.Stop()
+	gitService.SetDotfileSync(dotfileSync)
+	dotfilesHandler := handlers.NewDotfilesHandler(dotfileSync)
+
 	// Restore state from persistent storage before initializing repos
 	logger.Debugf("🔄 Restoring worktree state from persistent storage")
 	if err := gitService.RestoreState(); err != nil {
@@ -238,10 +338,124 @@ func startServer(cmd *cobra.Command) {
 	gitHandler := handlers.NewGitHandler(gitService, gitHTTPService, sessionService, claudeMonitor)
 	sessionHandler := handlers.NewSessionsHandler(sessionService, claudeService, gitService)
 	eventsHandler := handlers.NewEventsHandler(portMonitor, gitService)
-	claudeHandler := handlers.NewClaudeHandler(claudeService, gitService).WithEvents(eventsHandler).WithOnboardingService(claudeOnboardingService).WithPTYHandler(ptyHandler)
+	ptyHandler.WithEvents(eventsHandler)
+	sshAgentService := services.NewSSHAgentService()
+	defer sshAgentService.Stop()
+	ptyHandler.WithSSHAgent(sshAgentService).WithClaudeService(claudeService).WithMacros(services.NewPTYMacroStore()).WithSummarizer(services.NewTerminalSummarizer(claudeService))
+	composites := services.NewCompositeWorkspaceService(gitService)
+	ptyHandler.WithComposites(composites)
+	if services.KubernetesRuntimeEnabled() {
+		if kubernetesRuntime, err := services.NewKubernetesRuntime(); err != nil {
+			logger.Errorf("❌ Kubernetes workspace runtime unavailable, running sessions locally: %v", err)
+		} else {
+			ptyHandler.WithKubernetes(kubernetesRuntime)
+			logger.Infof("☸️ Workspace sessions run in Kubernetes pods")
+		}
+	}
+	compositeHandler := handlers.NewCompositeHandler(composites)
+	sshAgentHandler := handlers.NewSSHAgentHandler(sshAgentService, gitService)
+
+	// Repositories can authenticate to GitHub as a GitHub App instead of the gh login
+	secrets := services.NewSecretStore()
+	githubApp := services.NewGitHubAppService(secrets)
+	gitService.SetGitHubApp(githubApp)
+	githubAppHandler := handlers.NewGitHubAppHandler(githubApp, secrets, gitService)
+	voiceHandler := handlers.NewVoiceHandler(services.NewVoiceService(secrets), claudeService, gitService)
+
+	// Queue pull requests and fetches while GitHub is unreachable, running them when it's back
+	outbox := services.NewOutboxService().WithEvents(eventsHandler)
+	outbox.RegisterExecutor(services.OutboxCreatePullRequest, services.PullRequestOutboxExecutor(gitService))
+	outbox.RegisterExecutor(services.OutboxFetch, services.FetchOutboxExecutor(gitService))
+	outbox.Start()
+	defer outbox.Stop()
+	diskLayout := services.NewDiskLayoutScanner()
+	gitHandler.WithOutbox(outbox).WithDiskLayout(diskLayout).WithComposites(composites).
+		WithSummaries(services.NewWorktreeSummarizer(gitService, claudeService))
+	outboxHandler := handlers.NewOutboxHandler(outbox)
+	offloadHandler := handlers.NewOffloadHandler(services.NewOffloadService())
+	feedbackService := services.NewFeedbackService()
+	claudeService.GetProcessRegistry().Budgets().WithEvents(eventsHandler)
+	claudeService.GetProcessRegistry().Permissions().WithEvents(eventsHandler)
+	automationJobs := services.NewAutomationJobService().WithEvents(eventsHandler)
+	automationJobs.RegisterResumer(services.AutomationJobCompletion, automationJobs.CompletionJobResumer(claudeService))
+	automationJobsHandler := handlers.NewAutomationJobsHandler(automationJobs)
+	claudeHandler := handlers.NewClaudeHandler(claudeService, gitService).WithEvents(eventsHandler).WithOnboardingService(claudeOnboardingService).WithPTYHandler(ptyHandler).WithFeedbackService(feedbackService).WithPromptLinter(services.NewPromptLinter(git.NewOperations())).WithAutomationJobs(automationJobs).WithSessionIntegrity(sessionIntegrity).WithTranscriptIndex(transcriptIndex)
 	defer eventsHandler.Stop()
-	portsHandler := handlers.NewPortsHandler(portMonitor).WithEvents(eventsHandler)
+	portPublisher := services.NewPortPublishService(gitService.ListWorktrees).WithEvents(eventsHandler)
+	portsHandler := handlers.NewPortsHandler(portMonitor).WithEvents(eventsHandler).WithPublisher(portPublisher)
 	proxyHandler := handlers.NewProxyHandler(portMonitor)
+	mdnsService := services.NewMDNSService(gitService.ListWorktrees, portMonitor.GetServices)
+	mdnsService.Start()
+	defer mdnsService.Stop()
+
+	// Publish a preview environment once a worktree's setup succeeded and its dev server is up
+	previews := services.NewPreviewService(gitService, ptyHandler.GetPTYService().SetupState, portMonitor.GetServices)
+	gitService.SetPreviewService(previews)
+	gitHandler.WithPreviews(previews)
+	previews.Start()
+	defer previews.Stop()
+	mdnsHandler := handlers.NewMDNSHandler(mdnsService, gitService)
+
+	// Keep worktrees checked out from pull requests up to date with new pushes
+	prWorktreeSync := services.NewPullRequestWorktreeSync(gitService)
+	prWorktreeSync.Start()
+	defer prWorktreeSync.Stop()
+
+	// Sync shared project templates from CATNIP_TEMPLATE_REPO if configured
+	templateSync := services.NewTemplateSyncService()
+	templateSync.Start()
+	defer templateSync.Stop()
+	templatesHandler := handlers.NewTemplatesHandler(templateSync)
+	onboardingHandler := handlers.NewOnboardingHandler(services.NewOnboardingService(gitService))
+
+	// Back up volume state to object storage if CATNIP_BACKUP_URL is configured
+	backupService, err := services.NewBackupService()
+	if err != nil {
+		logger.Warnf("⚠️ Backups disabled: %v", err)
+		backupService = services.NewBackupServiceWithOptions(nil, nil, "", "", 0, 0)
+	}
+	backupService.Start()
+	defer backupService.Stop()
+	backupHandler := handlers.NewBackupHandler(backupService)
+	mirrorHandler := handlers.NewMirrorHandler(mirrorService)
+	hygieneReports := services.NewHygieneReportService(services.HygieneReportSources{
+		Worktrees:     gitService.ListWorktrees,
+		SetupFailures: ptyHandler.GetPTYService().SetupFailures,
+		DiskLayout:    func(path string) (*services.DiskLayout, error) { return diskLayout.Layout(path, false) },
+		AgentUsage:    services.CollectAgentUsage,
+	}).WithEvents(eventsHandler)
+	hygieneReports.Start()
+	defer hygieneReports.Stop()
+	hygieneHandler := handlers.NewHygieneHandler(hygieneReports)
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(services.NewDiagnosticsService(services.DiagnosticsSources{
+		Git:                  gitService,
+		StalePortAllocations: ptyHandler.StalePortAllocations,
+		ReleasePorts:         ptyHandler.ReleasePorts,
+		CircuitBreakers:      ptyHandler.OpenCircuitBreakers,
+		ResetCircuitBreaker:  ptyHandler.ResetCircuitBreaker,
+	}))
+	issueWebhookHandler := handlers.NewIssueWebhookHandler(
+		services.NewIssueWebhookService(gitService, secrets).WithSessionStarter(ptyHandler.StartAgentSession))
+
+	// Services that pick up setting changes on POST /v1/admin/reload
+	configManager.Subscribe("repository operations", []string{"CATNIP_REPO_CONCURRENCY"}, gitService.RepoLimiter().ReloadConfig)
+	configManager.Subscribe("backups", []string{"CATNIP_BACKUP_INTERVAL", "CATNIP_BACKUP_RETAIN"}, backupService.ReloadConfig)
+	configManager.Subscribe("hygiene reports", []string{"CATNIP_HYGIENE_REPORT_HOUR", "CATNIP_HYGIENE_STALE_DAYS", "CATNIP_HYGIENE_REPORT_NOTIFY", services.TimeZoneSetting}, hygieneReports.ReloadConfig)
+	adminHandler := handlers.NewAdminHandler(configManager)
+	dataExport = services.NewDataExportService(ptyHandler.GetRecordings(), secrets)
+	dataExportHandler := handlers.NewDataExportHandler(dataExport).
+		WithShutdown(func() {
+			logger.Infof("🛑 Stopping to purge state")
+			purgeState.Store(true)
+			if err := app.ShutdownWithTimeout(10 * time.Second); err != nil {
+				logger.Warnf("⚠️ Server did not stop cleanly: %v", err)
+			}
+		})
+	dependencyUpdateHandler := handlers.NewDependencyUpdateHandler(services.NewDependencyUpdateService(gitService, claudeService).WithEvents(eventsHandler).WithAutomationJobs(automationJobs))
+	actionsHandler := handlers.NewActionsHandler(gitService)
+
+	// Resume Claude automations interrupted by the last shutdown, now that every resumer is registered
+	go automationJobs.Recover()
 
 	// Connect events handler to GitService for worktree status events
 	gitService.SetEventsHandler(eventsHandler)
@@ -263,50 +477,244 @@ func startServer(cmd *cobra.Command) {
 	v1.Get("/pty", ptyHandler.HandleWebSocket)
 	v1.Post("/pty/start", ptyHandler.HandlePTYStart)
 	v1.Post("/pty/prompt", ptyHandler.HandlePTYPrompt)
+	v1.Get("/pty/composer", ptyHandler.HandlePromptComposer)
 	v1.Get("/pty/status", ptyHandler.HandlePTYStatus)
+	v1.Get("/pty/agents", ptyHandler.HandleListAgents)
+	v1.Get("/pty/instances", ptyHandler.HandleListInstances)
+	v1.Post("/pty/instances", ptyHandler.HandleCreateInstance)
+	v1.Delete("/pty/instances", ptyHandler.HandleDeleteInstance)
+	v1.Get("/pty/orphans", ptyHandler.HandleListOrphans)
+	v1.Get("/pty/watches", ptyHandler.HandleListWatches)
+	v1.Post("/pty/watches", ptyHandler.HandleAddWatch)
+	v1.Delete("/pty/watches/:id", ptyHandler.HandleDeleteWatch)
+	v1.Get("/pty/attention", ptyHandler.HandleGetAttention)
+	v1.Put("/pty/attention", ptyHandler.HandleUpdateAttention)
+	v1.Post("/pty/attention/ack", ptyHandler.HandleAcknowledgeAttention)
+	v1.Get("/pty/recording", ptyHandler.HandleGetRecording)
+	v1.Post("/pty/recording", ptyHandler.HandleStartRecording)
+	v1.Post("/pty/recording/stop", ptyHandler.HandleStopRecording)
+	v1.Get("/pty/recording/live", ptyHandler.HandleStreamRecording)
+	v1.Get("/pty/annotations", ptyHandler.HandleListAnnotations)
+	v1.Get("/pty/captures", ptyHandler.HandleListCaptures)
+	v1.Get("/pty/captures/:id", ptyHandler.HandleGetCapture)
+	v1.Get("/pty/captures/:id/replay", ptyHandler.HandleReplayCapture)
+	v1.Delete("/pty/captures/:id", ptyHandler.HandleDeleteCapture)
+	v1.Post("/pty/macros/:name/run", ptyHandler.HandleRunMacro)
+	v1.Post("/pty/summarize", ptyHandler.HandleSummarizeOutput)
 
 	// Auth routes
 	v1.Post("/auth/github/start", authHandler.StartGitHubAuth)
 	v1.Get("/auth/github/status", authHandler.GetAuthStatus)
 	v1.Post("/auth/github/reset", authHandler.ResetAuthState)
 
+	// First-run setup routes
+	v1.Get("/onboarding", onboardingHandler.GetOnboardingStatus)
+	v1.Post("/onboarding/steps/:id/skip", onboardingHandler.SkipOnboardingStep)
+	v1.Post("/onboarding/reset", onboardingHandler.ResetOnboarding)
+
+	// Dotfile sync routes
+	v1.Get("/dotfiles", dotfilesHandler.GetStatus)
+	v1.Post("/dotfiles/sync", dotfilesHandler.Sync)
+
+	// Backup routes
+	v1.Get("/backups", backupHandler.ListBackups)
+	v1.Post("/backups", backupHandler.CreateBackup)
+
 	// Upload routes
 	v1.Post("/upload", uploadHandler.UploadFile)
 
 	// Git routes
 	v1.Post("/git/checkout/:org/:repo", gitHandler.CheckoutRepository)
 	v1.Get("/git/status", gitHandler.GetStatus)
+	v1.Get("/git/recovery", gitHandler.GetStateRecovery)
+	v1.Get("/git/network", gitHandler.GetNetworkPolicy)
+	v1.Get("/git/operations", gitHandler.GetOperationQueues)
+	v1.Get("/git/progress", gitHandler.StreamGitProgress)
 	v1.Get("/git/worktrees", gitHandler.ListWorktrees)
+	v1.Get("/git/worktrees/summaries", gitHandler.GetWorktreeSummaries)
 	v1.Patch("/git/worktrees/:id", gitHandler.UpdateWorktree)
 	v1.Delete("/git/worktrees/:id", gitHandler.DeleteWorktree)
 	v1.Post("/git/worktrees/cleanup", gitHandler.CleanupMergedWorktrees)
+	v1.Post("/git/worktrees/bulk", gitHandler.BulkWorktrees)
+	v1.Get("/git/labels", gitHandler.ListWorktreeLabels)
 	v1.Post("/git/worktrees/:id/sync", gitHandler.SyncWorktree)
+	v1.Post("/git/worktrees/:id/sync/undo", gitHandler.UndoSync)
+	v1.Post("/git/worktrees/:id/fetch", gitHandler.FetchWorktree)
 	v1.Get("/git/worktrees/:id/sync/check", gitHandler.CheckSyncConflicts)
 	v1.Post("/git/worktrees/:id/merge", gitHandler.MergeWorktreeToMain)
 	v1.Get("/git/worktrees/:id/merge/check", gitHandler.CheckMergeConflicts)
 	v1.Get("/git/worktrees/:id/diff", gitHandler.GetWorktreeDiff)
+	v1.Get("/git/worktrees/:id/test-impact", gitHandler.GetWorktreeTestImpact)
+	v1.Get("/git/worktrees/:id/status", eventsHandler.PollWorktreeStatus)
+	v1.Get("/git/worktrees/:id/status/ws", eventsHandler.WatchWorktreeStatus)
+	v1.Put("/git/worktrees/:id/review", gitHandler.UpdateFileReview)
+	v1.Get("/git/worktrees/:id/hunks", gitHandler.GetUnstagedHunks)
+	v1.Post("/git/worktrees/:id/stage", gitHandler.StageHunks)
+	v1.Post("/git/worktrees/:id/unstage", gitHandler.UnstageFile)
+	v1.Get("/git/worktrees/:id/staged", gitHandler.GetStagedFiles)
+	v1.Post("/git/worktrees/:id/commit", gitHandler.CommitStaged)
+	v1.Post("/git/worktrees/:id/setup/rerun", gitHandler.RerunWorktreeSetup)
+	v1.Get("/git/worktrees/:id/devcontainer", gitHandler.GetWorktreeDevcontainer)
+	v1.Put("/git/worktrees/:id/issue", gitHandler.LinkWorktreeIssue)
+	v1.Delete("/git/worktrees/:id/issue", gitHandler.UnlinkWorktreeIssue)
+	v1.Put("/git/worktrees/:id/ssh-agent", sshAgentHandler.UpdateWorktreeSSHAgent)
+	v1.Put("/git/worktrees/:id/mdns", mdnsHandler.UpdateWorktreeMDNS)
+	v1.Get("/git/worktrees/:id/claude-tools", gitHandler.GetWorktreeClaudeTools)
+	v1.Put("/git/worktrees/:id/claude-tools", gitHandler.UpdateWorktreeClaudeTools)
+	v1.Put("/git/worktrees/:id/labels", gitHandler.UpdateWorktreeLabels)
+	v1.Get("/git/worktrees/:id/memory", gitHandler.GetWorktreeMemory)
+	v1.Post("/git/worktrees/:id/memory", gitHandler.AddWorktreeMemory)
+	v1.Delete("/git/worktrees/:id/memory/:memory_id", gitHandler.DeleteWorktreeMemory)
+	v1.Get("/git/worktrees/:id/locks", gitHandler.GetWorktreeLocks)
+	v1.Post("/git/worktrees/:id/locks", gitHandler.AddWorktreeLock)
+	v1.Delete("/git/worktrees/:id/locks/:lock_id", gitHandler.DeleteWorktreeLock)
+	v1.Get("/git/worktrees/:id/graph", gitHandler.GetWorktreeGraph)
+	v1.Get("/git/worktrees/:id/agent-commits", gitHandler.GetWorktreeAgentCommits)
+	v1.Get("/git/worktrees/:id/layout", gitHandler.GetWorktreeLayout)
+	v1.Get("/git/worktrees/:id/identity", gitHandler.GetWorktreeIdentity)
+	v1.Put("/git/worktrees/:id/identity", gitHandler.UpdateWorktreeIdentity)
+	v1.Get("/git/worktrees/:id/checkpoints", gitHandler.GetWorktreeCheckpointPolicy)
+	v1.Put("/git/worktrees/:id/checkpoints", gitHandler.UpdateWorktreeCheckpointPolicy)
 	v1.Post("/git/worktrees/:id/preview", gitHandler.CreateWorktreePreview)
+	v1.Delete("/git/worktrees/:id/preview", gitHandler.DeleteWorktreePreview)
+	v1.Get("/git/worktrees/:id/preview/merge", gitHandler.PlanWorktreePreviewMerge)
+	v1.Post("/git/worktrees/:id/preview/merge", gitHandler.MergeWorktreePreview)
+	v1.Post("/git/worktrees/:id/preview/merge/continue", gitHandler.ContinueWorktreePreviewMerge)
+	v1.Post("/git/worktrees/:id/preview/merge/abort", gitHandler.AbortWorktreePreviewMerge)
+	v1.Post("/git/worktrees/:id/preview/deployment", gitHandler.DeployWorktreePreview)
+	v1.Delete("/git/worktrees/:id/preview/deployment", gitHandler.TeardownWorktreePreview)
+	v1.Post("/git/worktrees/:id/standby", gitHandler.CreateStandbyWorktree)
+	v1.Post("/git/worktrees/:id/hibernate", gitHandler.HibernateWorktree)
+	v1.Post("/git/worktrees/:id/rehydrate", gitHandler.RehydrateWorktree)
+	v1.Post("/git/worktrees/:id/sandbox", gitHandler.StartWorktreeSandbox)
+	v1.Get("/git/worktrees/:id/sandbox", gitHandler.GetWorktreeSandbox)
+	v1.Post("/git/worktrees/:id/sandbox/promote", gitHandler.PromoteWorktreeSandbox)
+	v1.Post("/git/worktrees/:id/sandbox/discard", gitHandler.DiscardWorktreeSandbox)
 	v1.Post("/git/worktrees/:id/pr", gitHandler.CreatePullRequest)
 	v1.Put("/git/worktrees/:id/pr", gitHandler.UpdatePullRequest)
 	v1.Get("/git/worktrees/:id/pr", gitHandler.GetPullRequestInfo)
+	v1.Post("/git/worktrees/:id/pr/sync", gitHandler.SyncWorktreePullRequest)
+	v1.Get("/git/worktrees/:id/pr/feedback", gitHandler.GetPullRequestFeedback)
 	v1.Post("/git/worktrees/:id/graduate", gitHandler.GraduateBranch)
 	v1.Post("/git/worktrees/:id/refresh", gitHandler.RefreshWorktreeStatus)
+	v1.Get("/git/composites", compositeHandler.ListComposites)
+	v1.Post("/git/composites", compositeHandler.CreateComposite)
+	v1.Get("/git/composites/:id", compositeHandler.GetComposite)
+	v1.Patch("/git/composites/:id", compositeHandler.UpdateComposite)
+	v1.Delete("/git/composites/:id", compositeHandler.DeleteComposite)
+	v1.Get("/git/composites/:id/status", compositeHandler.GetCompositeStatus)
+	v1.Post("/git/composites/:id/setup", compositeHandler.RunCompositeSetup)
+	v1.Post("/git/composites/:id/members", compositeHandler.AddCompositeMember)
+	v1.Delete("/git/composites/:id/members/:worktree_id", compositeHandler.RemoveCompositeMember)
 	v1.Get("/git/github/repos", gitHandler.ListGitHubRepositories)
 	v1.Post("/git/repositories/:id/github", gitHandler.CreateGitHubRepository)
+	v1.Put("/git/repositories/:id/identity", gitHandler.UpdateRepositoryIdentity)
+	v1.Post("/git/repositories/:id/worktrees/from-pr", gitHandler.CreateWorktreeFromPullRequest)
+	v1.Post("/git/repositories/:id/worktrees/from-branch", gitHandler.ImportBranch)
+	v1.Get("/git/repositories/:id/macros", ptyHandler.HandleListMacros)
+	v1.Put("/git/repositories/:id/macros/:name", ptyHandler.HandleSaveMacro)
+	v1.Delete("/git/repositories/:id/macros/:name", ptyHandler.HandleDeleteMacro)
+	v1.Get("/git/repositories/:id/network", gitHandler.GetRepositoryNetworkPolicy)
+	v1.Put("/git/repositories/:id/network", gitHandler.UpdateRepositoryNetworkPolicy)
+	v1.Get("/git/repositories/:id/checkpoints", gitHandler.GetRepositoryCheckpointPolicy)
+	v1.Put("/git/repositories/:id/checkpoints", gitHandler.UpdateRepositoryCheckpointPolicy)
+	v1.Get("/git/repositories/:id/fetch", gitHandler.GetRepositoryFetchSettings)
+	v1.Put("/git/repositories/:id/fetch", gitHandler.UpdateRepositoryFetchSettings)
+	v1.Get("/git/repositories/:id/branch-naming", gitHandler.GetRepositoryBranchNaming)
+	v1.Put("/git/repositories/:id/branch-naming", gitHandler.UpdateRepositoryBranchNaming)
+	v1.Get("/git/repositories/:id/branch-naming/check", gitHandler.CheckBranchName)
+	v1.Get("/git/repositories/:id/code-owner-reviews", gitHandler.GetRepositoryCodeOwnerReviews)
+	v1.Put("/git/repositories/:id/code-owner-reviews", gitHandler.UpdateRepositoryCodeOwnerReviews)
+	v1.Get("/git/repositories/:id/costs", gitHandler.GetRepositoryAgentCosts)
+	v1.Put("/git/repositories/:id/github-auth", githubAppHandler.UpdateRepositoryGitHubAuth)
+	v1.Post("/git/repositories/:id/dependency-updates", dependencyUpdateHandler.StartDependencyUpdate)
+	v1.Get("/git/dependency-updates", dependencyUpdateHandler.ListDependencyUpdates)
+	v1.Get("/git/dependency-updates/:id", dependencyUpdateHandler.GetDependencyUpdate)
+	v1.Get("/git/mirrors", mirrorHandler.GetMirrorStatus)
+	v1.Post("/git/mirrors/sync", mirrorHandler.SyncMirrors)
+
+	// GitHub App authentication and secrets
+	v1.Get("/github/app", githubAppHandler.GetGitHubApp)
+	v1.Put("/github/app", githubAppHandler.UpdateGitHubApp)
+	v1.Get("/github/app/credential", githubAppHandler.GetGitHubCredential)
+	v1.Get("/secrets", githubAppHandler.ListSecrets)
+	v1.Put("/secrets/:name", githubAppHandler.SetSecret)
+	v1.Delete("/secrets/:name", githubAppHandler.DeleteSecret)
+
+	// Voice input and output for workspace agents
+	v1.Get("/voice", voiceHandler.GetVoice)
+	v1.Put("/voice", voiceHandler.UpdateVoice)
+	v1.Post("/voice/transcribe", voiceHandler.Transcribe)
+	v1.Get("/voice/transcribe/ws", voiceHandler.TranscribeWebSocket)
+	v1.Post("/voice/speak", voiceHandler.Speak)
+
+	// SSH agent forwarding
+	v1.Get("/ssh-agent", sshAgentHandler.GetSSHAgent)
+	v1.Get("/mdns", mdnsHandler.GetMDNS)
 	v1.Delete("/git/repositories/:id", gitHandler.DeleteRepository)
 	v1.Get("/git/branches/:repo_id", gitHandler.GetRepositoryBranches)
 	v1.Post("/git/template", gitHandler.CreateFromTemplate)
+	v1.Get("/git/templates", templatesHandler.ListTemplates)
+	v1.Post("/git/templates/sync", templatesHandler.SyncTemplates)
 
 	// Claude routes
 	v1.Get("/claude/session", claudeHandler.GetWorktreeSessionSummary)
 	v1.Get("/claude/session/:uuid", claudeHandler.GetSessionByUUID)
 	v1.Get("/claude/sessions", claudeHandler.GetAllWorktreeSessionSummaries)
+	v1.Get("/claude/search", claudeHandler.SearchTranscripts)
 	v1.Get("/claude/todos", claudeHandler.GetWorktreeTodos)
 	v1.Get("/claude/latest-message", claudeHandler.GetWorktreeLatestAssistantMessage)
 	v1.Post("/claude/messages", claudeHandler.CreateCompletion)
+	v1.Post("/claude/messages/lint", claudeHandler.LintPrompt)
+	v1.Get("/claude/messages/ws", claudeHandler.CreateCompletionWebSocket)
+	v1.Get("/claude/automations", automationJobsHandler.ListAutomationJobs)
+	v1.Get("/claude/budgets", claudeHandler.ListSessionBudgets)
+	v1.Get("/claude/permissions", claudeHandler.ListPermissionRequests)
+	v1.Get("/claude/integrity", claudeHandler.CheckSessionIntegrity)
+	v1.Post("/claude/integrity/repair", claudeHandler.RepairSessionIntegrity)
+	v1.Post("/claude/permissions/:id", claudeHandler.DecidePermissionRequest)
+	v1.Get("/claude/automations/:id", automationJobsHandler.GetAutomationJob)
+
+	// Offline outbox
+	v1.Get("/outbox", outboxHandler.GetOutbox)
+	v1.Post("/outbox/flush", outboxHandler.FlushOutbox)
+	v1.Post("/outbox/:id/retry", outboxHandler.RetryOutboxOperation)
+	v1.Delete("/outbox/:id", outboxHandler.DeleteOutboxOperation)
+
+	// Admin routes
+	v1.Get("/admin/config", adminHandler.GetConfig)
+	v1.Post("/admin/reload", adminHandler.ReloadConfig)
+	v1.Get("/admin/locale", adminHandler.GetLocale)
+	v1.Put("/admin/locale", adminHandler.UpdateLocale)
+	v1.Get("/admin/profiles", adminHandler.GetProfiles)
+	v1.Put("/admin/profile", adminHandler.UpdateProfile)
+	v1.Get("/admin/export", dataExportHandler.ExportData)
+	v1.Get("/admin/export/manifest", dataExportHandler.GetExportManifest)
+	v1.Post("/admin/purge", dataExportHandler.PurgeData)
+
+	// Workspace hygiene reports
+	v1.Get("/hygiene/reports", hygieneHandler.ListHygieneReports)
+	v1.Post("/hygiene/reports", hygieneHandler.CreateHygieneReport)
+	v1.Get("/hygiene/reports/:id", hygieneHandler.GetHygieneReport)
+
+	// State diagnostics and repair (catnip doctor)
+	v1.Get("/diagnostics", diagnosticsHandler.GetDiagnostics)
+	v1.Post("/diagnostics/fix", diagnosticsHandler.FixDiagnostics)
+
+	// Workspaces provisioned from labeled GitHub issues
+	v1.Post("/webhooks/github", issueWebhookHandler.HandleGitHubWebhook)
+	v1.Get("/webhooks/github/runs", issueWebhookHandler.ListIssueWorkspaceRuns)
+
+	// Heavy command offload to remote runners
+	v1.Get("/offload", offloadHandler.GetOffload)
+	v1.Post("/offload/run", offloadHandler.RunOffload)
 	v1.Get("/claude/settings", claudeHandler.GetClaudeSettings)
 	v1.Put("/claude/settings", claudeHandler.UpdateClaudeSettings)
+	v1.Get("/claude/system-prompt", claudeHandler.GetSystemPrompt)
 	v1.Post("/claude/hooks", claudeHandler.HandleClaudeHook)
+	v1.Post("/claude/feedback", claudeHandler.SubmitFeedback)
+	v1.Get("/claude/feedback", claudeHandler.ListFeedback)
+	v1.Get("/claude/feedback/export", claudeHandler.ExportFeedback)
 
 	// Claude onboarding routes
 	v1.Post("/claude/onboarding/start", claudeHandler.StartOnboarding)
@@ -324,10 +732,17 @@ func startServer(cmd *cobra.Command) {
 
 	// Port monitoring routes
 	v1.Get("/ports", portsHandler.GetPorts)
+	v1.Get("/ports/publish", portsHandler.GetPublishRules)
+	v1.Post("/ports/publish", portsHandler.AddPublishRule)
+	v1.Delete("/ports/publish/:id", portsHandler.DeletePublishRule)
+	v1.Post("/ports/publish/:id/status", portsHandler.ReportPublishStatus)
 	v1.Get("/ports/:port", portsHandler.GetPortInfo)
 	v1.Post("/ports/mappings", portsHandler.SetPortMapping)
 	v1.Delete("/ports/mappings/:port", portsHandler.DeletePortMapping)
 
+	// Command palette actions
+	v1.Get("/actions", actionsHandler.ListActions)
+
 	// Server info route
 	v1.Get("/info", func(c *fiber.Ctx) error {
 		commit, date, builtBy := GetBuildInfo()
@@ -348,6 +763,9 @@ func startServer(cmd *cobra.Command) {
 	notificationHandler := handlers.NewNotificationHandler(eventsHandler)
 	v1.Post("/notifications", notificationHandler.HandleNotification)
 
+	// Every API route is registered; make sure the palette doesn't offer dead actions
+	actionsHandler.VerifyRoutes(app.GetRoutes())
+
 	// Proxy routes for detected services (must be before dev middleware)
 	// Will validate port numbers in handler and call Next() if invalid
 	app.All("/:port", proxyHandler.ProxyToPort)
@@ -387,21 +805,15 @@ func startServer(cmd *cobra.Command) {
 				staticPath = "./dist"
 			}
 
+			// index.html is rewritten for the base path, so it isn't served by Static
+			app.Get("/", handlers.ServeStaticIndex(staticPath))
 			app.Static("/", staticPath)
 
 			// Fallback to index.html for SPA routing
-			app.Get("/*", func(c *fiber.Ctx) error {
-				return c.SendFile(staticPath + "/index.html")
-			})
+			app.Get("/*", handlers.ServeStaticIndex(staticPath))
 		}
 	}
 
-	// Get port from flag or environment variable
-	port, _ := cmd.Flags().GetString("port")
-	if envPort := os.Getenv("PORT"); envPort != "" {
-		port = envPort
-	}
-
 	logger.Infof("🚀 Catnip server starting on port %s", port)
 	if err := app.Listen(":" + port); err != nil {
 		logger.Fatalf("Server failed to start on port %s: %v", port, err)
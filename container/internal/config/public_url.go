@@ -0,0 +1,60 @@
+package config
+
+import (
+	"net/url"
+	"path"
+	"strings"
+)
+
+// DefaultPort is the port catnip serves on unless PORT or --port says otherwise
+const DefaultPort = "6369"
+
+// NormalizeBasePath turns a configured base path into the form used for
+// routing: a leading slash, no trailing slash, and "" for the root
+func NormalizeBasePath(basePath string) string {
+	basePath = strings.TrimSpace(basePath)
+	if basePath == "" {
+		return ""
+	}
+	basePath = path.Clean("/" + basePath)
+	if basePath == "/" {
+		return ""
+	}
+	return basePath
+}
+
+// SetPublicAddress configures where catnip is reachable when it's deployed
+// behind a reverse proxy. externalURL is the full URL of catnip's root as
+// users see it (e.g. https://dev.example.com/catnip); basePath is the path
+// prefix the proxy forwards, and defaults to externalURL's path.
+func (rc *RuntimeConfig) SetPublicAddress(basePath, externalURL string) {
+	rc.ExternalURL = strings.TrimRight(strings.TrimSpace(externalURL), "/")
+	if basePath == "" && rc.ExternalURL != "" {
+		if parsed, err := url.Parse(rc.ExternalURL); err == nil {
+			basePath = parsed.Path
+		}
+	}
+	rc.BasePath = NormalizeBasePath(basePath)
+}
+
+// PublicURL returns an absolute link to a catnip path (e.g. "/workspace/foo")
+// for use outside the browser: notifications, PR comments, share links
+func (rc *RuntimeConfig) PublicURL(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	if rc.ExternalURL != "" {
+		return rc.ExternalURL + p
+	}
+	port := rc.Port
+	if port == "" {
+		port = DefaultPort
+	}
+	return "http://localhost:" + port + rc.BasePath + p
+}
+
+// HasExternalURL reports whether links built by PublicURL are reachable
+// from outside this machine
+func (rc *RuntimeConfig) HasExternalURL() bool {
+	return rc.ExternalURL != ""
+}
@@ -34,6 +34,9 @@ type RuntimeConfig struct {
 	CurrentRepo        string // For native mode, the git repo we're running from
 	SyncEnabled        bool   // Whether to sync settings to volume
 	PortMonitorEnabled bool   // Whether to use /proc for port monitoring
+	Port               string // Port the server listens on
+	BasePath           string // Path prefix when served behind a reverse proxy (e.g. "/catnip"), "" for the root
+	ExternalURL        string // URL users reach catnip's root at, for generated links ("" for localhost)
 }
 
 var (
@@ -59,7 +62,9 @@ func DetectRuntime() *RuntimeConfig {
 
 	config := &RuntimeConfig{
 		Mode: mode,
+		Port: getEnvOrDefault("PORT", DefaultPort),
 	}
+	config.SetPublicAddress(os.Getenv("CATNIP_BASE_PATH"), os.Getenv("CATNIP_EXTERNAL_URL"))
 
 	// Get user's home directory for defaults
 	homeDir, err := os.UserHomeDir()
@@ -0,0 +1,222 @@
+package git
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// bitbucketHost is the host of Bitbucket Cloud, the only Bitbucket catnip
+// talks to (Bitbucket Data Center has a different API)
+const bitbucketHost = "bitbucket.org"
+
+// BitbucketProvider opens pull requests through the Bitbucket Cloud REST API
+// (2.0)
+type BitbucketProvider struct {
+	token       string
+	username    string
+	appPassword string
+	api         *hostingAPI
+}
+
+// NewBitbucketProvider creates a provider authenticated with an access token,
+// or else a username and app password (anonymous if neither is set)
+func NewBitbucketProvider(token, username, appPassword string) *BitbucketProvider {
+	return newBitbucketProvider(token, username, appPassword, "https://api.bitbucket.org/2.0")
+}
+
+func newBitbucketProvider(token, username, appPassword, baseURL string) *BitbucketProvider {
+	p := &BitbucketProvider{token: token, username: username, appPassword: appPassword}
+	p.api = newHostingAPI("bitbucket", baseURL, func(req *http.Request) bool {
+		switch {
+		case p.token != "":
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		case p.username != "" && p.appPassword != "":
+			req.SetBasicAuth(p.username, p.appPassword)
+		default:
+			return false
+		}
+		return true
+	})
+	return p
+}
+
+// Name implements HostingProvider
+func (p *BitbucketProvider) Name() string {
+	return "bitbucket"
+}
+
+// Host implements HostingProvider
+func (p *BitbucketProvider) Host() string {
+	return bitbucketHost
+}
+
+// CloneURL implements HostingProvider
+func (p *BitbucketProvider) CloneURL(ownerRepo string) string {
+	return fmt.Sprintf("https://%s/%s.git", bitbucketHost, ownerRepo)
+}
+
+// CredentialHelper implements HostingProvider. Access tokens authenticate git
+// as x-token-auth.
+func (p *BitbucketProvider) CredentialHelper() string {
+	switch {
+	case p.token != "":
+		return `!f() { test "$1" = get && echo username=x-token-auth && echo "password=$BITBUCKET_TOKEN"; }; f`
+	case p.username != "" && p.appPassword != "":
+		return `!f() { test "$1" = get && echo "username=$BITBUCKET_USERNAME" && echo "password=$BITBUCKET_APP_PASSWORD"; }; f`
+	}
+	return ""
+}
+
+// repositoryPath returns the API path of a workspace/repo
+func (p *BitbucketProvider) repositoryPath(ownerRepo string) (string, error) {
+	workspace, repo, ok := strings.Cut(ownerRepo, "/")
+	if !ok || workspace == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", fmt.Errorf("invalid Bitbucket repository %s (expected workspace/repo)", ownerRepo)
+	}
+	return "/repositories/" + url.PathEscape(workspace) + "/" + url.PathEscape(repo), nil
+}
+
+// ListBranches implements HostingProvider
+func (p *BitbucketProvider) ListBranches(ownerRepo string) ([]string, error) {
+	repoPath, err := p.repositoryPath(ownerRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	var branches []string
+	path, query := repoPath+"/refs/branches", url.Values{"pagelen": {"100"}}
+	for path != "" {
+		var result struct {
+			Values []struct {
+				Name string `json:"name"`
+			} `json:"values"`
+			Next string `json:"next"`
+		}
+		if _, err := p.api.do("GET", path, query, nil, &result); err != nil {
+			return nil, err
+		}
+		for _, branch := range result.Values {
+			branches = append(branches, branch.Name)
+		}
+
+		// The next page is a full URL carrying its own query
+		path, query = result.Next, nil
+	}
+	return branches, nil
+}
+
+// bitbucketPullRequest is a pull request as the Bitbucket API returns it
+type bitbucketPullRequest struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Links       struct {
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+	Source      bitbucketBranchRef `json:"source"`
+	Destination bitbucketBranchRef `json:"destination"`
+}
+
+type bitbucketBranchRef struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+}
+
+func newBitbucketBranchRef(name string) bitbucketBranchRef {
+	var ref bitbucketBranchRef
+	ref.Branch.Name = name
+	return ref
+}
+
+func (pr bitbucketPullRequest) response() *models.PullRequestResponse {
+	return &models.PullRequestResponse{
+		Number:     pr.ID,
+		URL:        pr.Links.HTML.Href,
+		Title:      pr.Title,
+		Body:       pr.Description,
+		HeadBranch: pr.Source.Branch.Name,
+		BaseBranch: pr.Destination.Branch.Name,
+	}
+}
+
+// FindPullRequest implements HostingProvider
+func (p *BitbucketProvider) FindPullRequest(ownerRepo, head string) (*models.PullRequestResponse, error) {
+	repoPath, err := p.repositoryPath(ownerRepo)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Values []bitbucketPullRequest `json:"values"`
+	}
+	query := url.Values{
+		"state": {"OPEN"},
+		"q":     {fmt.Sprintf("source.branch.name = %s", strconv.Quote(head))},
+	}
+	if _, err := p.api.do("GET", repoPath+"/pullrequests", query, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Values) == 0 {
+		return nil, nil
+	}
+	return result.Values[0].response(), nil
+}
+
+// CreatePullRequest implements HostingProvider
+func (p *BitbucketProvider) CreatePullRequest(ownerRepo string, spec PullRequestSpec) (*models.PullRequestResponse, error) {
+	repoPath, err := p.repositoryPath(ownerRepo)
+	if err != nil {
+		return nil, err
+	}
+	body := bitbucketPullRequest{
+		Title:       spec.Title,
+		Description: spec.Body,
+		Source:      newBitbucketBranchRef(spec.Head),
+		Destination: newBitbucketBranchRef(spec.Base),
+	}
+	var result bitbucketPullRequest
+	if _, err := p.api.do("POST", repoPath+"/pullrequests", nil, bitbucketPullRequestBody(body), &result); err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return result.response(), nil
+}
+
+// UpdatePullRequest implements HostingProvider
+func (p *BitbucketProvider) UpdatePullRequest(ownerRepo string, number int, spec PullRequestSpec) (*models.PullRequestResponse, error) {
+	repoPath, err := p.repositoryPath(ownerRepo)
+	if err != nil {
+		return nil, err
+	}
+	body := bitbucketPullRequest{
+		Title:       spec.Title,
+		Description: spec.Body,
+		Destination: newBitbucketBranchRef(spec.Base),
+	}
+	var result bitbucketPullRequest
+	path := repoPath + "/pullrequests/" + strconv.Itoa(number)
+	if _, err := p.api.do("PUT", path, nil, bitbucketPullRequestBody(body), &result); err != nil {
+		return nil, fmt.Errorf("failed to update pull request: %w", err)
+	}
+	return result.response(), nil
+}
+
+// bitbucketPullRequestBody builds a create or update request, leaving out the
+// fields that aren't set
+func bitbucketPullRequestBody(pr bitbucketPullRequest) map[string]interface{} {
+	body := map[string]interface{}{
+		"title":       pr.Title,
+		"description": pr.Description,
+		"destination": pr.Destination,
+	}
+	if pr.Source.Branch.Name != "" {
+		body["source"] = pr.Source
+	}
+	return body
+}
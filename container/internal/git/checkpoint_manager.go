@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
 )
 
 // DefaultCheckpointTimeoutSeconds is the default checkpoint timeout in seconds
@@ -23,6 +25,67 @@ func GetCheckpointTimeout() time.Duration {
 	return DefaultCheckpointTimeoutSeconds * time.Second
 }
 
+// DefaultCheckpointMessageTemplate is the checkpoint commit message used
+// unless a repository or worktree overrides it
+const DefaultCheckpointMessageTemplate = "{title} checkpoint: {n}"
+
+// CheckpointPolicy controls when checkpoint commits are made and how they're titled
+type CheckpointPolicy struct {
+	Disabled        bool
+	Interval        time.Duration
+	MaxDirtyFiles   int // 0 for no limit
+	MessageTemplate string
+}
+
+// DefaultCheckpointPolicy returns the policy used without overrides, with the
+// interval from CATNIP_COMMIT_TIMEOUT_SECONDS
+func DefaultCheckpointPolicy() CheckpointPolicy {
+	return CheckpointPolicy{
+		Interval:        GetCheckpointTimeout(),
+		MessageTemplate: DefaultCheckpointMessageTemplate,
+	}
+}
+
+// WithOverrides returns a copy of the policy with any non-zero settings applied
+func (p CheckpointPolicy) WithOverrides(settings *models.CheckpointSettings) CheckpointPolicy {
+	if settings == nil {
+		return p
+	}
+	if settings.Disabled != nil {
+		p.Disabled = *settings.Disabled
+	}
+	if settings.IntervalSeconds > 0 {
+		p.Interval = time.Duration(settings.IntervalSeconds) * time.Second
+	}
+	if settings.MaxDirtyFiles > 0 {
+		p.MaxDirtyFiles = settings.MaxDirtyFiles
+	}
+	if settings.MessageTemplate != "" {
+		p.MessageTemplate = settings.MessageTemplate
+	}
+	return p
+}
+
+// Settings returns the policy in the same shape as a repository or worktree override
+func (p CheckpointPolicy) Settings() models.CheckpointSettings {
+	disabled := p.Disabled
+	return models.CheckpointSettings{
+		Disabled:        &disabled,
+		IntervalSeconds: int(p.Interval / time.Second),
+		MaxDirtyFiles:   p.MaxDirtyFiles,
+		MessageTemplate: p.MessageTemplate,
+	}
+}
+
+// Message renders the commit message for the nth checkpoint of a session title
+func (p CheckpointPolicy) Message(title string, n int) string {
+	template := p.MessageTemplate
+	if template == "" {
+		template = DefaultCheckpointMessageTemplate
+	}
+	return strings.NewReplacer("{title}", title, "{n}", strconv.Itoa(n)).Replace(template)
+}
+
 // CheckpointManager handles checkpoint functionality for sessions
 type CheckpointManager interface {
 	ShouldCreateCheckpoint() bool
@@ -35,6 +98,8 @@ type CheckpointManager interface {
 type Service interface {
 	GitAddCommitGetHash(workDir, title string) (string, error)
 	RefreshWorktreeStatus(workDir string) error
+	CheckpointPolicy(workDir string) CheckpointPolicy
+	CountDirtyFiles(workDir string) (int, error)
 }
 
 // SessionServiceInterface defines the session operations needed by checkpoint manager
@@ -69,9 +134,22 @@ func NewSessionCheckpointManager(workDir string, gitService Service, sessionServ
 
 // ShouldCreateCheckpoint returns true if a checkpoint should be created
 func (cm *SessionCheckpointManager) ShouldCreateCheckpoint() bool {
+	policy := cm.policy()
+	if policy.Disabled {
+		return false
+	}
+
 	cm.checkpointMutex.RLock()
 	defer cm.checkpointMutex.RUnlock()
-	return time.Since(cm.lastCommitTime) >= GetCheckpointTimeout()
+	return time.Since(cm.lastCommitTime) >= policy.Interval
+}
+
+// policy returns the checkpoint policy that applies to the manager's worktree
+func (cm *SessionCheckpointManager) policy() CheckpointPolicy {
+	if cm.gitService == nil {
+		return DefaultCheckpointPolicy()
+	}
+	return cm.gitService.CheckpointPolicy(cm.workDir)
 }
 
 // CreateCheckpoint creates a checkpoint commit
@@ -80,10 +158,28 @@ func (cm *SessionCheckpointManager) CreateCheckpoint(title string) error {
 		return fmt.Errorf("git service not available")
 	}
 
+	policy := cm.policy()
+	if policy.Disabled {
+		return nil
+	}
+
 	cm.checkpointMutex.Lock()
 	defer cm.checkpointMutex.Unlock()
 
-	checkpointTitle := fmt.Sprintf("%s checkpoint: %d", title, cm.checkpointCount+1)
+	if policy.MaxDirtyFiles > 0 {
+		dirtyFiles, err := cm.gitService.CountDirtyFiles(cm.workDir)
+		if err != nil {
+			return err
+		}
+		if dirtyFiles > policy.MaxDirtyFiles {
+			logger.Debugf("⏭️  Skipping checkpoint in %s: %d changed files exceeds the limit of %d", cm.workDir, dirtyFiles, policy.MaxDirtyFiles)
+			// Wait a full interval before looking at the diff again
+			cm.lastCommitTime = time.Now()
+			return nil
+		}
+	}
+
+	checkpointTitle := policy.Message(title, cm.checkpointCount+1)
 	commitHash, err := cm.gitService.GitAddCommitGetHash(cm.workDir, checkpointTitle)
 	if err != nil {
 		return err
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
 )
 
 // MockGitService is a mock implementation of GitService for testing
@@ -15,6 +16,8 @@ type MockGitService struct {
 	lastCommitTitle string
 	returnHash      string
 	returnError     error
+	policy          *CheckpointPolicy
+	dirtyFiles      int
 }
 
 func (m *MockGitService) GitAddCommitGetHash(workDir, title string) (string, error) {
@@ -27,6 +30,17 @@ func (m *MockGitService) RefreshWorktreeStatus(workDir string) error {
 	return nil
 }
 
+func (m *MockGitService) CheckpointPolicy(workDir string) CheckpointPolicy {
+	if m.policy != nil {
+		return *m.policy
+	}
+	return DefaultCheckpointPolicy()
+}
+
+func (m *MockGitService) CountDirtyFiles(workDir string) (int, error) {
+	return m.dirtyFiles, nil
+}
+
 // MockSessionService is a mock implementation of SessionService for testing
 type MockSessionService struct {
 	addToHistoryCalled bool
@@ -220,3 +234,61 @@ func TestConcurrentAccess(t *testing.T) {
 
 	// If we get here without panicking, the concurrent access is safe
 }
+
+func TestCheckpointPolicyWithOverrides(t *testing.T) {
+	_ = os.Unsetenv("CATNIP_COMMIT_TIMEOUT_SECONDS")
+	disabled, enabled := true, false
+
+	repo := DefaultCheckpointPolicy().WithOverrides(&models.CheckpointSettings{
+		Disabled:        &disabled,
+		IntervalSeconds: 300,
+		MessageTemplate: "wip: {title} ({n})",
+	})
+	assert.True(t, repo.Disabled)
+	assert.Equal(t, 5*time.Minute, repo.Interval)
+	assert.Equal(t, "wip: Add login (3)", repo.Message("Add login", 3))
+
+	// A worktree can turn checkpoints back on and keeps the repository's other settings
+	worktree := repo.WithOverrides(&models.CheckpointSettings{Disabled: &enabled, MaxDirtyFiles: 100})
+	assert.False(t, worktree.Disabled)
+	assert.Equal(t, 5*time.Minute, worktree.Interval)
+	assert.Equal(t, 100, worktree.MaxDirtyFiles)
+	assert.Equal(t, "wip: {title} ({n})", worktree.MessageTemplate)
+
+	assert.Equal(t, "Add login checkpoint: 1", DefaultCheckpointPolicy().WithOverrides(nil).Message("Add login", 1))
+}
+
+func TestCreateCheckpointPolicy(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		mockGit := &MockGitService{returnHash: "abc123", policy: &CheckpointPolicy{Disabled: true, Interval: time.Second}}
+		cm := NewSessionCheckpointManager("/test/workspace", mockGit, &MockSessionService{})
+		cm.lastCommitTime = time.Now().Add(-time.Hour)
+
+		assert.False(t, cm.ShouldCreateCheckpoint())
+		require.NoError(t, cm.CreateCheckpoint("Test Title"))
+		assert.False(t, mockGit.addCommitCalled)
+	})
+
+	t.Run("too many dirty files", func(t *testing.T) {
+		mockGit := &MockGitService{returnHash: "abc123", dirtyFiles: 501, policy: &CheckpointPolicy{Interval: time.Minute, MaxDirtyFiles: 500}}
+		cm := NewSessionCheckpointManager("/test/workspace", mockGit, &MockSessionService{})
+		cm.lastCommitTime = time.Now().Add(-time.Hour)
+
+		require.NoError(t, cm.CreateCheckpoint("Test Title"))
+		assert.False(t, mockGit.addCommitCalled)
+		assert.False(t, cm.ShouldCreateCheckpoint(), "waits another interval before retrying")
+
+		mockGit.dirtyFiles = 500
+		require.NoError(t, cm.CreateCheckpoint("Test Title"))
+		assert.True(t, mockGit.addCommitCalled)
+	})
+
+	t.Run("message template", func(t *testing.T) {
+		mockGit := &MockGitService{returnHash: "abc123", policy: &CheckpointPolicy{Interval: time.Minute, MessageTemplate: "wip({n}): {title}"}}
+		cm := NewSessionCheckpointManager("/test/workspace", mockGit, &MockSessionService{})
+
+		require.NoError(t, cm.CreateCheckpoint("Test Title"))
+		require.NoError(t, cm.CreateCheckpoint("Test Title"))
+		assert.Equal(t, "wip(2): Test Title", mockGit.lastCommitTitle)
+	})
+}
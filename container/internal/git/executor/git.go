@@ -1,6 +1,7 @@
 package executor
 
 import (
+	"context"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -518,6 +519,15 @@ func (e *GitExecutor) getCurrentBranch(repo *gogit.Repository) ([]byte, error) {
 	return []byte(head.Name().Short() + "\n"), nil
 }
 
+// ExecuteGitWithContext runs a cancellable git command - go-git has no
+// cancellation for the network commands this is used for, so always use shell
+func (e *GitExecutor) ExecuteGitWithContext(ctx context.Context, workingDir string, args ...string) ([]byte, error) {
+	if ctxExec, ok := e.fallbackExecutor.(ContextExecutor); ok {
+		return ctxExec.ExecuteGitWithContext(ctx, workingDir, args...)
+	}
+	return e.fallbackExecutor.ExecuteGitWithWorkingDir(workingDir, args...)
+}
+
 // ExecuteWithEnvAndTimeout runs a command with timeout - delegates to fallback executor
 func (e *GitExecutor) ExecuteWithEnvAndTimeout(dir string, env []string, timeout time.Duration, args ...string) ([]byte, error) {
 	return e.fallbackExecutor.ExecuteWithEnvAndTimeout(dir, env, timeout, args...)
@@ -1,6 +1,9 @@
 package executor
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // CommandExecutor abstracts Git command execution
 type CommandExecutor interface {
@@ -13,3 +16,9 @@ type CommandExecutor interface {
 	// ExecuteWithEnvAndTimeout runs commands with timeout for network operations
 	ExecuteWithEnvAndTimeout(dir string, env []string, timeout time.Duration, args ...string) ([]byte, error)
 }
+
+// ContextExecutor is implemented by executors that can cancel a running git
+// command when its context is done
+type ContextExecutor interface {
+	ExecuteGitWithContext(ctx context.Context, workingDir string, args ...string) ([]byte, error)
+}
@@ -86,6 +86,33 @@ func (e *ShellExecutor) ExecuteGitWithWorkingDir(workingDir string, args ...stri
 	return e.Execute("", args...)
 }
 
+// ExecuteGitWithContext runs a git command with -C flag, killing it if ctx is done.
+// On failure the error includes stderr so callers can classify network errors.
+func (e *ShellExecutor) ExecuteGitWithContext(ctx context.Context, workingDir string, args ...string) ([]byte, error) {
+	if workingDir != "" {
+		args = append([]string{"-C", workingDir}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(cmd.Environ(), e.defaultEnv...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return stdout.Bytes(), fmt.Errorf("git %s timed out: %w", strings.Join(args, " "), ctx.Err())
+		case context.Canceled:
+			return stdout.Bytes(), fmt.Errorf("git %s canceled: %w", strings.Join(args, " "), ctx.Err())
+		}
+		return stdout.Bytes(), fmt.Errorf("git %s failed: %v\nstderr: %s", strings.Join(args, " "), err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
 // ExecuteCommand runs any command (not just git) with standard environment
 func (e *ShellExecutor) ExecuteCommand(command string, args ...string) ([]byte, error) {
 	cmd := exec.Command(command, args...)
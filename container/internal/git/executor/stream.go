@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// maxStreamLine bounds a single line of streamed output
+const maxStreamLine = 1024 * 1024
+
+// OutputLine is one line of output from a streamed git command. Progress
+// updates that git redraws with \r are delivered as separate lines.
+type OutputLine struct {
+	Text   string `json:"text"`
+	Stderr bool   `json:"stderr,omitempty"`
+}
+
+// LineHandler receives output as a streamed command produces it. Calls are
+// serialized even though stdout and stderr are read concurrently.
+type LineHandler func(OutputLine)
+
+// StreamExecutor is implemented by executors that can deliver a git
+// command's output while it runs instead of once it has finished
+type StreamExecutor interface {
+	ExecuteGitStream(ctx context.Context, workingDir string, onLine LineHandler, args ...string) ([]byte, error)
+}
+
+// ScanOutputLines splits on \n, \r\n and bare \r, so each progress redraw
+// ("Receiving objects:  42%\r") becomes its own line
+func ScanOutputLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		if data[i] == '\r' && i+1 == len(data) && !atEOF {
+			// Might be the first half of \r\n
+			return 0, nil, nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// EmitOutputLines delivers already-buffered output as lines, for executors
+// that can't stream
+func EmitOutputLines(output []byte, stderr bool, onLine LineHandler) {
+	scanOutput(bytes.NewReader(output), stderr, onLine)
+}
+
+func scanOutput(r io.Reader, stderr bool, onLine LineHandler) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxStreamLine)
+	scanner.Split(ScanOutputLines)
+	for scanner.Scan() {
+		if text := strings.TrimRight(scanner.Text(), " \t"); text != "" {
+			onLine(OutputLine{Text: text, Stderr: stderr})
+		}
+	}
+	// Keep draining after an oversized line so git never blocks on a full pipe
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// ExecuteGitStream runs a git command with -C flag, passing stdout and stderr
+// lines to onLine as they arrive. Like ExecuteGitWithContext it returns the
+// full stdout, and the error includes stderr so callers can classify it.
+func (e *ShellExecutor) ExecuteGitStream(ctx context.Context, workingDir string, onLine LineHandler, args ...string) ([]byte, error) {
+	if workingDir != "" {
+		args = append([]string{"-C", workingDir}, args...)
+	}
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(cmd.Environ(), e.defaultEnv...)
+
+	stdoutPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git %s failed to start: %v", strings.Join(args, " "), err)
+	}
+
+	var mu sync.Mutex
+	emit := func(line OutputLine) {
+		mu.Lock()
+		defer mu.Unlock()
+		onLine(line)
+	}
+
+	var stdout, stderr bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		scanOutput(io.TeeReader(stdoutPipe, &stdout), false, emit)
+	}()
+	go func() {
+		defer wg.Done()
+		scanOutput(io.TeeReader(stderrPipe, &stderr), true, emit)
+	}()
+	// Pipes must be fully read before Wait closes them
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return stdout.Bytes(), fmt.Errorf("git %s timed out: %w", strings.Join(args, " "), ctx.Err())
+		case context.Canceled:
+			return stdout.Bytes(), fmt.Errorf("git %s canceled: %w", strings.Join(args, " "), ctx.Err())
+		}
+		return stdout.Bytes(), fmt.Errorf("git %s failed: %v\nstderr: %s", strings.Join(args, " "), err, stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// ExecuteGitStream streams a git command - go-git has no progressive output
+// for the long network commands this is used for, so always use shell
+func (e *GitExecutor) ExecuteGitStream(ctx context.Context, workingDir string, onLine LineHandler, args ...string) ([]byte, error) {
+	if streamExec, ok := e.fallbackExecutor.(StreamExecutor); ok {
+		return streamExec.ExecuteGitStream(ctx, workingDir, onLine, args...)
+	}
+	output, err := e.ExecuteGitWithContext(ctx, workingDir, args...)
+	EmitOutputLines(output, false, onLine)
+	return output, err
+}
@@ -3,9 +3,11 @@ package git
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
@@ -15,9 +17,14 @@ import (
 // GitHubManager handles all GitHub CLI operations (auth, repos, pull requests, etc.)
 // nolint:revive
 type GitHubManager struct {
-	operations Operations
+	operations  Operations
+	tokenSource TokenSource
 }
 
+// TokenSource returns a GitHub token to use for ownerRepo instead of the gh
+// login, or ok=false to use the login
+type TokenSource func(ownerRepo string) (token string, ok bool)
+
 // NewGitHubManager creates a new GitHub manager
 func NewGitHubManager(operations Operations) *GitHubManager {
 	return &GitHubManager{
@@ -49,12 +56,40 @@ func (g *GitHubManager) extractGitHubRepoFromURL(remoteURL string) string {
 	return ""
 }
 
+// SetTokenSource sets where per-repository tokens (e.g. GitHub App
+// installation tokens) come from
+func (g *GitHubManager) SetTokenSource(source TokenSource) {
+	g.tokenSource = source
+}
+
 // execCommand creates a command with proper environment
 func (g *GitHubManager) execCommand(command string, args ...string) *exec.Cmd {
 	cmd := exec.Command(command, args...)
 	return cmd
 }
 
+// ghCommand creates a gh command acting on ownerRepo, authenticated with the
+// repository's token when it has one
+func (g *GitHubManager) ghCommand(ownerRepo string, args ...string) *exec.Cmd {
+	cmd := g.execCommand("gh", args...)
+	if g.tokenSource != nil {
+		if token, ok := g.tokenSource(ownerRepo); ok {
+			cmd.Env = append(os.Environ(), "GH_TOKEN="+token)
+		}
+	}
+	return cmd
+}
+
+// isGHAuthError reports whether a gh command failed because of missing credentials
+func isGHAuthError(err error) bool {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return false
+	}
+	stderr := strings.ToLower(string(exitErr.Stderr))
+	return strings.Contains(stderr, "gh auth login") || strings.Contains(stderr, "not logged in")
+}
+
 // CreatePullRequestRequest contains parameters for PR creation
 type CreatePullRequestRequest struct {
 	Worktree         *models.Worktree
@@ -70,64 +105,179 @@ type CreatePullRequestRequest struct {
 
 // CreatePullRequest creates or updates a GitHub pull request
 func (g *GitHubManager) CreatePullRequest(req CreatePullRequestRequest) (*models.PullRequestResponse, error) {
-	// Ensure we have full git history for accurate commit tracking
-	req.FetchFullHistory(req.Worktree)
-
-	// For GitHub repos, check uncommitted changes and create temporary commit if needed
-	var tempCommitHash string
-	if !strings.HasPrefix(req.Repository.ID, "local/") {
-		if hasChanges, err := g.operations.HasUncommittedChanges(req.Worktree.Path); err != nil {
-			logger.Warnf("⚠️ Failed to check uncommitted changes for %s: %v", req.Worktree.Name, err)
-		} else if hasChanges {
-			logger.Debugf("📝 Worktree %s has uncommitted changes, creating temporary commit for PR", req.Worktree.Name)
-			if hash, err := req.CreateTempCommit(req.Worktree.Path); err != nil {
-				logger.Warnf("⚠️ Failed to create temporary commit for PR: %v", err)
-			} else {
-				tempCommitHash = hash
-			}
-		}
-	}
+	// Commit uncommitted changes for the PR, undoing it when done
+	cleanup := req.prepare(g.operations)
+	defer cleanup()
 
-	// Cleanup temp commit when done
-	defer func() {
-		if tempCommitHash != "" {
-			req.RevertTempCommit(req.Worktree.Path, tempCommitHash)
-		}
-	}()
+	ownerRepo, err := g.ResolveOwnerRepo(req.Worktree, req.Repository)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create PR: %v", err)
+	}
 
-	// Always try to get the GitHub owner/repo from the origin remote URL first
-	var ownerRepo string
+	if req.IsUpdate {
+		return g.updatePullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush)
+	} else {
+		return g.createPullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush)
+	}
+}
 
+// ResolveOwnerRepo returns the GitHub owner/repo for a worktree, preferring
+// the origin remote URL over the repository ID
+func (g *GitHubManager) ResolveOwnerRepo(worktree *models.Worktree, repository *models.Repository) (string, error) {
 	// Get the remote URL from the worktree to ensure we use the correct GitHub repo name
-	remoteURL, err := g.operations.GetRemoteURL(req.Worktree.Path)
-	if err == nil {
+	if remoteURL, err := g.operations.GetRemoteURL(worktree.Path); err == nil {
 		// Extract owner/repo from URL (e.g., git@github.com:owner/repo.git -> owner/repo)
-		ownerRepo = g.extractGitHubRepoFromURL(remoteURL)
-		if ownerRepo != "" {
-			logger.Debugf("🔄 Using GitHub repo %s from origin remote for repository %s", ownerRepo, req.Repository.ID)
+		if ownerRepo := g.extractGitHubRepoFromURL(remoteURL); ownerRepo != "" {
+			logger.Debugf("🔄 Using GitHub repo %s from origin remote for repository %s", ownerRepo, repository.ID)
+			return ownerRepo, nil
 		}
 	}
 
 	// Fallback to repository ID if we couldn't extract from remote URL
-	if ownerRepo == "" {
-		if strings.HasPrefix(req.Repository.ID, "local/") {
-			return nil, fmt.Errorf("cannot create PR: no GitHub remote configured for local repository")
+	if strings.HasPrefix(repository.ID, "local/") {
+		return "", fmt.Errorf("no GitHub remote configured for local repository")
+	}
+
+	// For non-local repos, use repository ID and validate format
+	parts := strings.Split(repository.ID, "/")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("invalid repository ID format: %s (expected owner/repo)", repository.ID)
+	}
+	logger.Debugf("🔄 Using repository ID %s as fallback for GitHub repo", repository.ID)
+	return repository.ID, nil
+}
+
+// GetIssue fetches an issue with its most recent comments (up to maxComments)
+func (g *GitHubManager) GetIssue(ownerRepo string, number, maxComments int) (*models.LinkedIssue, error) {
+	cmd := g.ghCommand(ownerRepo, "issue", "view", strconv.Itoa(number),
+		"--repo", ownerRepo,
+		"--json", "number,title,body,state,url,comments")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to fetch issue #%d: %v\nStderr: %s", number, err, string(exitErr.Stderr))
 		}
+		return nil, fmt.Errorf("failed to fetch issue #%d: %v", number, err)
+	}
+
+	issue, err := parseIssueJSON(output, maxComments)
+	if err != nil {
+		return nil, err
+	}
+	issue.Repository = ownerRepo
+	return issue, nil
+}
 
-		// For non-local repos, use repository ID and validate format
-		parts := strings.Split(req.Repository.ID, "/")
-		if len(parts) != 2 {
-			return nil, fmt.Errorf("invalid repository ID format: %s (expected owner/repo)", req.Repository.ID)
+// parseIssueJSON converts `gh issue view --json` output, keeping the last maxComments comments
+func parseIssueJSON(data []byte, maxComments int) (*models.LinkedIssue, error) {
+	var raw struct {
+		Number   int    `json:"number"`
+		Title    string `json:"title"`
+		Body     string `json:"body"`
+		State    string `json:"state"`
+		URL      string `json:"url"`
+		Comments []struct {
+			Author struct {
+				Login string `json:"login"`
+			} `json:"author"`
+			Body      string    `json:"body"`
+			CreatedAt time.Time `json:"createdAt"`
+		} `json:"comments"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse issue: %v", err)
+	}
+
+	issue := &models.LinkedIssue{
+		Number:    raw.Number,
+		Title:     raw.Title,
+		Body:      raw.Body,
+		State:     raw.State,
+		URL:       raw.URL,
+		FetchedAt: time.Now(),
+	}
+	comments := raw.Comments
+	if maxComments >= 0 && len(comments) > maxComments {
+		comments = comments[len(comments)-maxComments:]
+	}
+	for _, comment := range comments {
+		issue.Comments = append(issue.Comments, models.IssueComment{
+			Author:    comment.Author.Login,
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+		})
+	}
+	return issue, nil
+}
+
+// GetPullRequest fetches where a pull request's commits come from, including
+// the fork owning its head branch
+func (g *GitHubManager) GetPullRequest(ownerRepo string, number int) (*models.SourcePullRequest, error) {
+	cmd := g.ghCommand(ownerRepo, "pr", "view", strconv.Itoa(number),
+		"--repo", ownerRepo,
+		"--json", "number,title,url,state,author,baseRefName,headRefName,headRefOid,headRepository,headRepositoryOwner,isCrossRepository")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("failed to fetch pull request #%d: %v\nStderr: %s", number, err, string(exitErr.Stderr))
 		}
-		ownerRepo = req.Repository.ID
-		logger.Debugf("🔄 Using repository ID %s as fallback for GitHub repo", ownerRepo)
+		return nil, fmt.Errorf("failed to fetch pull request #%d: %v", number, err)
 	}
 
-	if req.IsUpdate {
-		return g.updatePullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush)
-	} else {
-		return g.createPullRequestWithGH(req.Worktree, ownerRepo, req.Title, req.Body, req.ForcePush)
+	pr, state, err := parsePullRequestJSON(output)
+	if err != nil {
+		return nil, err
+	}
+	if state != "OPEN" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "pull request #%d is %s", number, strings.ToLower(state))
 	}
+	pr.Repository = ownerRepo
+	if !pr.IsFork || pr.HeadRepository == "" {
+		pr.HeadRepository = ownerRepo
+	}
+	return pr, nil
+}
+
+// parsePullRequestJSON converts `gh pr view --json` output, returning the PR's state separately
+func parsePullRequestJSON(data []byte) (*models.SourcePullRequest, string, error) {
+	var raw struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		URL    string `json:"url"`
+		State  string `json:"state"`
+		Author struct {
+			Login string `json:"login"`
+		} `json:"author"`
+		BaseRefName    string `json:"baseRefName"`
+		HeadRefName    string `json:"headRefName"`
+		HeadRefOid     string `json:"headRefOid"`
+		HeadRepository struct {
+			Name string `json:"name"`
+		} `json:"headRepository"`
+		HeadRepositoryOwner struct {
+			Login string `json:"login"`
+		} `json:"headRepositoryOwner"`
+		IsCrossRepository bool `json:"isCrossRepository"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, "", fmt.Errorf("failed to parse pull request: %v", err)
+	}
+
+	pr := &models.SourcePullRequest{
+		Number:     raw.Number,
+		Title:      raw.Title,
+		URL:        raw.URL,
+		Author:     raw.Author.Login,
+		BaseBranch: raw.BaseRefName,
+		HeadBranch: raw.HeadRefName,
+		HeadSHA:    raw.HeadRefOid,
+		IsFork:     raw.IsCrossRepository,
+	}
+	// The head repository is empty when the fork has been deleted
+	if raw.HeadRepositoryOwner.Login != "" && raw.HeadRepository.Name != "" {
+		pr.HeadRepository = raw.HeadRepositoryOwner.Login + "/" + raw.HeadRepository.Name
+	}
+	return pr, raw.State, nil
 }
 
 // GetPullRequestInfo retrieves PR information for a worktree
@@ -185,7 +335,7 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 	}
 
 	// Update the PR
-	cmd := g.execCommand("gh", "pr", "edit", branchToPush,
+	cmd := g.ghCommand(ownerRepo, "pr", "edit", branchToPush,
 		"--repo", ownerRepo,
 		"--title", title,
 		"--body", body)
@@ -202,7 +352,7 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 	logger.Infof("✅ Updated PR for branch %s", worktree.Branch)
 
 	// Get the PR details
-	cmd = g.execCommand("gh", "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body")
+	cmd = g.ghCommand(ownerRepo, "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body")
 	output, err := cmd.Output()
 	if err != nil {
 		logger.Warnf("⚠️ Could not get PR details: %v", err)
@@ -237,6 +387,7 @@ func (g *GitHubManager) updatePullRequestWithGH(worktree *models.Worktree, owner
 		Body:       result.Body,
 		HeadBranch: branchToPush,
 		BaseBranch: worktree.SourceBranch,
+		Repository: ownerRepo,
 	}, nil
 }
 
@@ -305,7 +456,7 @@ func (g *GitHubManager) createPullRequestWithGH(worktree *models.Worktree, owner
 
 	// Create the PR
 	logger.Debugf("🔍 PR Creation: About to create PR with gh pr create --repo %s", ownerRepo)
-	cmd := g.execCommand("gh", "pr", "create",
+	cmd := g.ghCommand(ownerRepo, "pr", "create",
 		"--repo", ownerRepo,
 		"--base", worktree.SourceBranch,
 		"--head", branchToPush,
@@ -365,13 +516,14 @@ func (g *GitHubManager) createPullRequestWithGH(worktree *models.Worktree, owner
 		Body:       body,
 		HeadBranch: branchToPush,
 		BaseBranch: worktree.SourceBranch,
+		Repository: ownerRepo,
 	}, nil
 }
 
 // checkExistingPR checks if a PR already exists for the branch
 func (g *GitHubManager) checkExistingPR(worktree *models.Worktree, ownerRepo string, prInfo *models.PullRequestInfo) error {
 	// Use GitHub CLI to check for existing PR
-	cmd := g.execCommand("gh", "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body")
+	cmd := g.ghCommand(ownerRepo, "pr", "view", worktree.Branch, "--repo", ownerRepo, "--json", "number,url,title,body")
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -405,6 +557,79 @@ func (g *GitHubManager) checkExistingPR(worktree *models.Worktree, ownerRepo str
 	return nil
 }
 
+// UpsertPullRequestComment posts a comment on a PR, or edits the existing
+// comment containing marker so repeated updates don't pile up comments
+func (g *GitHubManager) UpsertPullRequestComment(ownerRepo string, prNumber int, marker, body string) error {
+	if !strings.Contains(body, marker) {
+		body = marker + "\n" + body
+	}
+
+	cmd := g.ghCommand(ownerRepo, "api", "--paginate",
+		fmt.Sprintf("repos/%s/issues/%d/comments", ownerRepo, prNumber),
+		"--jq", fmt.Sprintf(".[] | select(.body | contains(%q)) | .id", marker))
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("failed to list PR comments: %v\nStderr: %s", err, string(exitErr.Stderr))
+		}
+		return fmt.Errorf("failed to list PR comments: %v", err)
+	}
+
+	if ids := strings.Fields(string(output)); len(ids) > 0 {
+		cmd = g.ghCommand(ownerRepo, "api", "-X", "PATCH",
+			fmt.Sprintf("repos/%s/issues/comments/%s", ownerRepo, ids[0]),
+			"-f", "body="+body)
+	} else {
+		cmd = g.ghCommand(ownerRepo, "pr", "comment", strconv.Itoa(prNumber),
+			"--repo", ownerRepo,
+			"--body", body)
+	}
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("failed to write PR comment: %v\nStderr: %s", err, string(exitErr.Stderr))
+		}
+		return fmt.Errorf("failed to write PR comment: %v", err)
+	}
+	return nil
+}
+
+// CommentOnIssue posts a comment on an issue
+func (g *GitHubManager) CommentOnIssue(ownerRepo string, number int, body string) error {
+	cmd := g.ghCommand(ownerRepo, "issue", "comment", strconv.Itoa(number),
+		"--repo", ownerRepo,
+		"--body", body)
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("failed to comment on issue #%d: %v\nStderr: %s", number, err, string(exitErr.Stderr))
+		}
+		return fmt.Errorf("failed to comment on issue #%d: %v", number, err)
+	}
+	return nil
+}
+
+// RequestReviewers requests reviews on a pull request from users and org/team slugs
+func (g *GitHubManager) RequestReviewers(ownerRepo string, prNumber int, reviewers []string) error {
+	cmd := g.ghCommand(ownerRepo, "pr", "edit", strconv.Itoa(prNumber),
+		"--repo", ownerRepo,
+		"--add-reviewer", strings.Join(reviewers, ","))
+	if _, err := cmd.Output(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("failed to request reviewers on PR #%d: %v\nStderr: %s", prNumber, err, string(exitErr.Stderr))
+		}
+		return fmt.Errorf("failed to request reviewers on PR #%d: %v", prNumber, err)
+	}
+	return nil
+}
+
+// CurrentUser returns the login gh acts as for a repository
+func (g *GitHubManager) CurrentUser(ownerRepo string) (string, error) {
+	output, err := g.ghCommand(ownerRepo, "api", "user", "--jq", ".login").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the GitHub user: %v", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
 // IsAuthenticated checks if GitHub CLI is authenticated
 func (g *GitHubManager) IsAuthenticated() bool {
 	cmd := g.execCommand("gh", "auth", "status")
@@ -420,7 +645,7 @@ func (g *GitHubManager) ConfigureGitCredentials() error {
 
 	if !g.IsAuthenticated() {
 		logger.Warnf("ℹ️ GitHub CLI not authenticated, Git operations will only work with public repositories")
-		return fmt.Errorf("GitHub CLI not authenticated")
+		return models.NewGitHubNotAuthenticatedError()
 	}
 
 	logger.Debugf("🔐 Configuring Git to use GitHub CLI for authentication")
@@ -429,6 +654,20 @@ func (g *GitHubManager) ConfigureGitCredentials() error {
 	return g.operations.SetGlobalConfig("credential.https://github.com.helper", "!gh auth git-credential")
 }
 
+// ConfigureAppCredentials routes GitHub git credentials through catnip's
+// helper, which returns GitHub App tokens for repositories in app mode and
+// defers to gh for the rest. The repository path is needed to tell them apart.
+func (g *GitHubManager) ConfigureAppCredentials() error {
+	if config.Runtime.IsNative() {
+		logger.Debugf("ℹ️ Running in native mode - skipping git credential configuration")
+		return nil
+	}
+	if err := g.operations.SetGlobalConfig("credential.https://github.com.useHttpPath", "true"); err != nil {
+		return err
+	}
+	return g.operations.SetGlobalConfig("credential.https://github.com.helper", "!catnip git-credential")
+}
+
 // GitHubRepository represents a GitHub repository from the API
 // nolint:revive
 type GitHubRepository struct {
@@ -445,6 +684,9 @@ func (g *GitHubManager) ListRepositories() ([]GitHubRepository, error) {
 
 	output, err := cmd.Output()
 	if err != nil {
+		if isGHAuthError(err) {
+			return nil, models.NewGitHubNotAuthenticatedError().WithCause(err)
+		}
 		return nil, fmt.Errorf("failed to list GitHub repositories: %w", err)
 	}
 
@@ -469,6 +711,9 @@ func (g *GitHubManager) CreateRepository(name, description string, isPrivate boo
 	cmd := g.execCommand("gh", args...)
 	output, err := cmd.Output()
 	if err != nil {
+		if isGHAuthError(err) {
+			return "", models.NewGitHubNotAuthenticatedError().WithCause(err)
+		}
 		// For error reporting, capture stderr if available
 		if exitErr, ok := err.(*exec.ExitError); ok {
 			return "", fmt.Errorf("failed to create GitHub repository: %v\nStderr: %s", err, string(exitErr.Stderr))
@@ -0,0 +1,150 @@
+package git
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// GitLabProvider opens merge requests through the GitLab REST API (v4) of
+// gitlab.com or a self-hosted instance
+type GitLabProvider struct {
+	host  string
+	token string
+	api   *hostingAPI
+}
+
+// NewGitLabProvider creates a provider for the GitLab instance at host,
+// authenticated with a personal access token (anonymous if empty)
+func NewGitLabProvider(host, token string) *GitLabProvider {
+	return newGitLabProvider(host, token, "https://"+host+"/api/v4")
+}
+
+func newGitLabProvider(host, token, baseURL string) *GitLabProvider {
+	p := &GitLabProvider{host: host, token: token}
+	p.api = newHostingAPI("gitlab", baseURL, func(req *http.Request) bool {
+		if p.token == "" {
+			return false
+		}
+		req.Header.Set("PRIVATE-TOKEN", p.token)
+		return true
+	})
+	return p
+}
+
+// Name implements HostingProvider
+func (p *GitLabProvider) Name() string {
+	return "gitlab"
+}
+
+// Host implements HostingProvider
+func (p *GitLabProvider) Host() string {
+	return p.host
+}
+
+// CloneURL implements HostingProvider
+func (p *GitLabProvider) CloneURL(ownerRepo string) string {
+	return fmt.Sprintf("https://%s/%s.git", p.host, ownerRepo)
+}
+
+// CredentialHelper implements HostingProvider. GitLab takes any username
+// alongside a personal access token.
+func (p *GitLabProvider) CredentialHelper() string {
+	if p.token == "" {
+		return ""
+	}
+	return `!f() { test "$1" = get && echo username=oauth2 && echo "password=$GITLAB_TOKEN"; }; f`
+}
+
+// projectPath returns the API path of a project, addressed by its URL-encoded
+// path
+func (p *GitLabProvider) projectPath(ownerRepo string) string {
+	return "/projects/" + url.PathEscape(ownerRepo)
+}
+
+// ListBranches implements HostingProvider
+func (p *GitLabProvider) ListBranches(ownerRepo string) ([]string, error) {
+	var branches []string
+	for page := "1"; page != ""; {
+		var result []struct {
+			Name string `json:"name"`
+		}
+		query := url.Values{"per_page": {"100"}, "page": {page}}
+		header, err := p.api.do("GET", p.projectPath(ownerRepo)+"/repository/branches", query, nil, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, branch := range result {
+			branches = append(branches, branch.Name)
+		}
+		page = header.Get("X-Next-Page")
+	}
+	return branches, nil
+}
+
+// gitlabMergeRequest is a merge request as the GitLab API returns it
+type gitlabMergeRequest struct {
+	IID          int    `json:"iid"`
+	WebURL       string `json:"web_url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+func (mr gitlabMergeRequest) response() *models.PullRequestResponse {
+	return &models.PullRequestResponse{
+		Number:     mr.IID,
+		URL:        mr.WebURL,
+		Title:      mr.Title,
+		Body:       mr.Description,
+		HeadBranch: mr.SourceBranch,
+		BaseBranch: mr.TargetBranch,
+	}
+}
+
+// FindPullRequest implements HostingProvider
+func (p *GitLabProvider) FindPullRequest(ownerRepo, head string) (*models.PullRequestResponse, error) {
+	var result []gitlabMergeRequest
+	query := url.Values{"state": {"opened"}, "source_branch": {head}}
+	if _, err := p.api.do("GET", p.projectPath(ownerRepo)+"/merge_requests", query, nil, &result); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return result[0].response(), nil
+}
+
+// CreatePullRequest implements HostingProvider
+func (p *GitLabProvider) CreatePullRequest(ownerRepo string, spec PullRequestSpec) (*models.PullRequestResponse, error) {
+	body := map[string]string{
+		"source_branch": spec.Head,
+		"target_branch": spec.Base,
+		"title":         spec.Title,
+		"description":   spec.Body,
+	}
+	var result gitlabMergeRequest
+	if _, err := p.api.do("POST", p.projectPath(ownerRepo)+"/merge_requests", nil, body, &result); err != nil {
+		return nil, fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return result.response(), nil
+}
+
+// UpdatePullRequest implements HostingProvider
+func (p *GitLabProvider) UpdatePullRequest(ownerRepo string, number int, spec PullRequestSpec) (*models.PullRequestResponse, error) {
+	body := map[string]string{
+		"target_branch": spec.Base,
+		"title":         spec.Title,
+		"description":   spec.Body,
+	}
+	var result gitlabMergeRequest
+	path := p.projectPath(ownerRepo) + "/merge_requests/" + strconv.Itoa(number)
+	if _, err := p.api.do("PUT", path, nil, body, &result); err != nil {
+		return nil, fmt.Errorf("failed to update merge request: %w", err)
+	}
+	return result.response(), nil
+}
@@ -0,0 +1,198 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// defaultGraphLimit is the page size used when the caller doesn't specify one
+	defaultGraphLimit = 100
+	// maxGraphLimit caps page size to keep responses small for the UI
+	maxGraphLimit = 500
+)
+
+// graphLogFormat separates fields with \x1f and records with \x1e so commit
+// subjects containing newlines or tabs can't break parsing
+const graphLogFormat = "%H%x1f%P%x1f%an%x1f%ae%x1f%at%x1f%s%x1e"
+
+// CommitNode is a single commit in a worktree's commit graph
+type CommitNode struct {
+	Hash        string    `json:"hash"`
+	ShortHash   string    `json:"short_hash"`
+	Parents     []string  `json:"parents"`
+	Author      string    `json:"author"`
+	AuthorEmail string    `json:"author_email"`
+	Timestamp   time.Time `json:"timestamp"`
+	Message     string    `json:"message"`
+	IsMerge     bool      `json:"is_merge"`
+	OnWorktree  bool      `json:"on_worktree"` // Reachable from the worktree HEAD
+	OnSource    bool      `json:"on_source"`   // Reachable from the source branch
+	IsForkPoint bool      `json:"is_fork_point"`
+}
+
+// CommitEdge links a commit to one of its parents
+type CommitEdge struct {
+	From string `json:"from"` // Child commit hash
+	To   string `json:"to"`   // Parent commit hash
+}
+
+// CommitGraphResponse is the commit DAG between a worktree and its source branch
+type CommitGraphResponse struct {
+	WorktreeID   string       `json:"worktree_id"`
+	SourceBranch string       `json:"source_branch"`
+	Head         string       `json:"head"`
+	ForkCommit   string       `json:"fork_commit"`
+	Nodes        []CommitNode `json:"nodes"`
+	Edges        []CommitEdge `json:"edges"`
+	Offset       int          `json:"offset"`
+	Limit        int          `json:"limit"`
+	HasMore      bool         `json:"has_more"`
+}
+
+// ClampGraphLimit normalizes a requested page size
+func ClampGraphLimit(limit int) int {
+	if limit <= 0 {
+		return defaultGraphLimit
+	}
+	if limit > maxGraphLimit {
+		return maxGraphLimit
+	}
+	return limit
+}
+
+// ParseCommitGraphLog parses `git log` output produced with graphLogFormat
+func ParseCommitGraphLog(output string) []CommitNode {
+	var nodes []CommitNode
+	for _, record := range strings.Split(output, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+
+		fields := strings.Split(record, "\x1f")
+		if len(fields) < 6 {
+			continue
+		}
+
+		node := CommitNode{
+			Hash:        fields[0],
+			Author:      fields[2],
+			AuthorEmail: fields[3],
+			Message:     fields[5],
+			Parents:     []string{},
+		}
+		if len(node.Hash) >= 7 {
+			node.ShortHash = node.Hash[:7]
+		}
+		if fields[1] != "" {
+			node.Parents = strings.Fields(fields[1])
+		}
+		node.IsMerge = len(node.Parents) > 1
+		if unix, err := strconv.ParseInt(fields[4], 10, 64); err == nil {
+			node.Timestamp = time.Unix(unix, 0).UTC()
+		}
+
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// BuildCommitEdges returns parent edges whose endpoints are both present in nodes
+func BuildCommitEdges(nodes []CommitNode) []CommitEdge {
+	present := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		present[node.Hash] = true
+	}
+
+	edges := []CommitEdge{}
+	for _, node := range nodes {
+		for _, parent := range node.Parents {
+			if present[parent] {
+				edges = append(edges, CommitEdge{From: node.Hash, To: parent})
+			}
+		}
+	}
+	return edges
+}
+
+// GetCommitGraph returns the commit DAG from the fork point with sourceRef up to
+// both the worktree HEAD and sourceRef, newest first, paginated by offset/limit
+func (w *WorktreeManager) GetCommitGraph(worktree *models.Worktree, sourceRef string, offset, limit int) (*CommitGraphResponse, error) {
+	limit = ClampGraphLimit(limit)
+	if offset < 0 {
+		offset = 0
+	}
+
+	headOutput, err := w.safeExecuteGit(worktree.Path, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %v", err)
+	}
+	head := strings.TrimSpace(string(headOutput))
+
+	mergeBaseOutput, err := w.safeExecuteGit(worktree.Path, "merge-base", "HEAD", sourceRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find merge base: %v", err)
+	}
+	forkCommit := strings.TrimSpace(string(mergeBaseOutput))
+
+	// Include the fork commit itself by excluding only its parents
+	exclude := []string{"--not", forkCommit + "^@"}
+
+	// Fetch one extra commit to know whether another page exists
+	args := []string{"log", "--topo-order", "--format=" + graphLogFormat,
+		fmt.Sprintf("--skip=%d", offset), fmt.Sprintf("--max-count=%d", limit+1),
+		"HEAD", sourceRef}
+	args = append(args, exclude...)
+
+	output, err := w.safeExecuteGit(worktree.Path, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit graph: %v", err)
+	}
+
+	nodes := ParseCommitGraphLog(string(output))
+	hasMore := len(nodes) > limit
+	if hasMore {
+		nodes = nodes[:limit]
+	}
+
+	worktreeSide := w.revListSet(worktree.Path, append([]string{"HEAD"}, exclude...))
+	sourceSide := w.revListSet(worktree.Path, append([]string{sourceRef}, exclude...))
+	for i := range nodes {
+		nodes[i].OnWorktree = worktreeSide[nodes[i].Hash]
+		nodes[i].OnSource = sourceSide[nodes[i].Hash]
+		nodes[i].IsForkPoint = nodes[i].Hash == forkCommit
+	}
+
+	if nodes == nil {
+		nodes = []CommitNode{}
+	}
+
+	return &CommitGraphResponse{
+		SourceBranch: worktree.SourceBranch,
+		Head:         head,
+		ForkCommit:   forkCommit,
+		Nodes:        nodes,
+		Edges:        BuildCommitEdges(nodes),
+		Offset:       offset,
+		Limit:        limit,
+		HasMore:      hasMore,
+	}, nil
+}
+
+// revListSet returns the set of commits listed by `git rev-list <args>`
+func (w *WorktreeManager) revListSet(worktreePath string, args []string) map[string]bool {
+	set := make(map[string]bool)
+	output, err := w.safeExecuteGit(worktreePath, append([]string{"rev-list"}, args...)...)
+	if err != nil {
+		return set
+	}
+	for _, hash := range strings.Fields(string(output)) {
+		set[hash] = true
+	}
+	return set
+}
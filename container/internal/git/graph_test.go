@@ -0,0 +1,101 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git/executor"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestParseCommitGraphLog(t *testing.T) {
+	output := "aaaaaaaaaa\x1fbbbbbbbbbb cccccccccc\x1fAlice\x1falice@example.com\x1f1700000000\x1fMerge branch 'main'\x1e\n" +
+		"bbbbbbbbbb\x1f\x1fBob\x1fbob@example.com\x1f1699999999\x1fInitial commit\x1e\n"
+
+	nodes := ParseCommitGraphLog(output)
+	require.Len(t, nodes, 2)
+
+	assert.Equal(t, "aaaaaaa", nodes[0].ShortHash)
+	assert.True(t, nodes[0].IsMerge)
+	assert.Equal(t, []string{"bbbbbbbbbb", "cccccccccc"}, nodes[0].Parents)
+	assert.Equal(t, "Alice", nodes[0].Author)
+	assert.Equal(t, int64(1700000000), nodes[0].Timestamp.Unix())
+
+	assert.False(t, nodes[1].IsMerge)
+	assert.Empty(t, nodes[1].Parents)
+
+	// Only edges to commits present in the page are returned
+	edges := BuildCommitEdges(nodes)
+	assert.Equal(t, []CommitEdge{{From: "aaaaaaaaaa", To: "bbbbbbbbbb"}}, edges)
+}
+
+func TestClampGraphLimit(t *testing.T) {
+	assert.Equal(t, defaultGraphLimit, ClampGraphLimit(0))
+	assert.Equal(t, 10, ClampGraphLimit(10))
+	assert.Equal(t, maxGraphLimit, ClampGraphLimit(10000))
+}
+
+func TestGetCommitGraph(t *testing.T) {
+	repo := filepath.Join(t.TempDir(), "repo")
+	require.NoError(t, os.MkdirAll(repo, 0755))
+
+	exec := executor.NewShellExecutor()
+	run := func(args ...string) {
+		_, err := exec.ExecuteGitWithWorkingDir(repo, args...)
+		require.NoError(t, err, "git %v", args)
+	}
+	commit := func(file, message string) {
+		require.NoError(t, os.WriteFile(filepath.Join(repo, file), []byte(message), 0644))
+		run("add", file)
+		run("commit", "-m", message)
+	}
+
+	run("init", "-b", "main")
+	run("config", "user.name", "Test User")
+	run("config", "user.email", "test@example.com")
+	commit("base.txt", "base")
+	run("checkout", "-b", "feature")
+	commit("feature.txt", "feature work")
+	run("checkout", "main")
+	commit("main.txt", "main work")
+	run("checkout", "feature")
+
+	manager := NewWorktreeManager(NewOperationsWithExecutor(exec))
+	worktree := &models.Worktree{Path: repo, SourceBranch: "main"}
+
+	graph, err := manager.GetCommitGraph(worktree, "main", 0, 0)
+	require.NoError(t, err)
+
+	// feature work + main work + fork point (base)
+	require.Len(t, graph.Nodes, 3)
+	assert.False(t, graph.HasMore)
+
+	sides := map[string]CommitNode{}
+	for _, node := range graph.Nodes {
+		sides[node.Message] = node
+	}
+	assert.True(t, sides["feature work"].OnWorktree)
+	assert.False(t, sides["feature work"].OnSource)
+	assert.False(t, sides["main work"].OnWorktree)
+	assert.True(t, sides["main work"].OnSource)
+
+	var fork *CommitNode
+	for i := range graph.Nodes {
+		if graph.Nodes[i].IsForkPoint {
+			fork = &graph.Nodes[i]
+		}
+	}
+	require.NotNil(t, fork)
+	assert.Equal(t, "base", fork.Message)
+	assert.True(t, fork.OnWorktree)
+	assert.True(t, fork.OnSource)
+	assert.Len(t, graph.Edges, 2)
+
+	page, err := manager.GetCommitGraph(worktree, "main", 0, 2)
+	require.NoError(t, err)
+	assert.Len(t, page.Nodes, 2)
+	assert.True(t, page.HasMore)
+}
@@ -0,0 +1,393 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// HostingProvider is a git host other than GitHub (which is driven through
+// the gh CLI by GitHubManager) whose REST API lists branches and opens pull
+// requests. ownerRepo is the repository's path on the host, e.g.
+// "group/subgroup/project" on GitLab or "workspace/repo" on Bitbucket.
+type HostingProvider interface {
+	// Name identifies the provider, e.g. "gitlab"
+	Name() string
+	// Host is the hostname the provider serves, e.g. "gitlab.com"
+	Host() string
+	// CloneURL returns the HTTPS URL repositories are cloned from
+	CloneURL(ownerRepo string) string
+	// CredentialHelper returns a git credential helper answering with the
+	// provider's token, or "" when there is no token
+	CredentialHelper() string
+	// ListBranches lists the repository's branches
+	ListBranches(ownerRepo string) ([]string, error)
+	// FindPullRequest returns the open pull request from head, or nil
+	FindPullRequest(ownerRepo, head string) (*models.PullRequestResponse, error)
+	// CreatePullRequest opens a pull request
+	CreatePullRequest(ownerRepo string, spec PullRequestSpec) (*models.PullRequestResponse, error)
+	// UpdatePullRequest changes an open pull request's title, body and base
+	UpdatePullRequest(ownerRepo string, number int, spec PullRequestSpec) (*models.PullRequestResponse, error)
+}
+
+// PullRequestSpec describes a pull request (a merge request on GitLab)
+type PullRequestSpec struct {
+	Head  string
+	Base  string
+	Title string
+	Body  string
+}
+
+// ParseRemoteURL splits a remote URL into its host and repository path,
+// handling https://host/path.git, ssh://git@host:port/path.git and
+// git@host:path.git. ok is false for local paths and file:// URLs.
+func ParseRemoteURL(remoteURL string) (host, repoPath string, ok bool) {
+	remoteURL = strings.TrimSpace(remoteURL)
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil || u.Host == "" {
+			return "", "", false
+		}
+		switch u.Scheme {
+		case "https", "http":
+			host = u.Host
+		case "ssh", "git":
+			host = u.Hostname()
+		default:
+			return "", "", false
+		}
+		repoPath = u.Path
+	} else {
+		// scp-like syntax: [user@]host:path
+		userHost, path, found := strings.Cut(remoteURL, ":")
+		if !found || strings.Contains(userHost, "/") {
+			return "", "", false
+		}
+		if _, h, hasUser := strings.Cut(userHost, "@"); hasUser {
+			userHost = h
+		}
+		host, repoPath = userHost, path
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	if host == "" || !strings.Contains(repoPath, "/") {
+		return "", "", false
+	}
+	return strings.ToLower(host), repoPath, true
+}
+
+// SplitHostedRepoID splits a repository ID for a host other than GitHub,
+// e.g. "gitlab.com/group/project", into the host and the repository path.
+// GitHub IDs ("owner/repo") have no host: GitHub owners can't contain dots.
+func SplitHostedRepoID(repoID string) (host, ownerRepo string, ok bool) {
+	host, ownerRepo, found := strings.Cut(repoID, "/")
+	if !found || !strings.Contains(host, ".") || !strings.Contains(ownerRepo, "/") {
+		return "", "", false
+	}
+	return host, ownerRepo, true
+}
+
+// HostingManager picks the hosting provider for a repository from its remote
+// URL and opens pull requests through it
+type HostingManager struct {
+	operations Operations
+	providers  map[string]HostingProvider
+}
+
+// NewHostingManager creates a manager with GitLab (gitlab.com and the hosts
+// in CATNIP_GITLAB_HOSTS) and Bitbucket Cloud, authenticated with the tokens
+// in the environment
+func NewHostingManager(operations Operations) *HostingManager {
+	m := &HostingManager{
+		operations: operations,
+		providers:  make(map[string]HostingProvider),
+	}
+	gitlabToken := os.Getenv("GITLAB_TOKEN")
+	m.Register(NewGitLabProvider("gitlab.com", gitlabToken))
+	for _, host := range strings.Split(os.Getenv("CATNIP_GITLAB_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			m.Register(NewGitLabProvider(host, gitlabToken))
+		}
+	}
+	m.Register(NewBitbucketProvider(os.Getenv("BITBUCKET_TOKEN"), os.Getenv("BITBUCKET_USERNAME"), os.Getenv("BITBUCKET_APP_PASSWORD")))
+	return m
+}
+
+// Register adds a provider, replacing any for the same host
+func (m *HostingManager) Register(provider HostingProvider) {
+	m.providers[strings.ToLower(provider.Host())] = provider
+}
+
+// ForHost returns the provider serving host
+func (m *HostingManager) ForHost(host string) (HostingProvider, bool) {
+	provider, ok := m.providers[strings.ToLower(host)]
+	return provider, ok
+}
+
+// ForURL returns the provider for a remote URL and the repository's path on it
+func (m *HostingManager) ForURL(remoteURL string) (HostingProvider, string, bool) {
+	host, repoPath, ok := ParseRemoteURL(remoteURL)
+	if !ok {
+		return nil, "", false
+	}
+	provider, ok := m.ForHost(host)
+	return provider, repoPath, ok
+}
+
+// ForRepoID returns the provider for a host-prefixed repository ID
+func (m *HostingManager) ForRepoID(repoID string) (HostingProvider, string, bool) {
+	host, ownerRepo, ok := SplitHostedRepoID(repoID)
+	if !ok {
+		return nil, "", false
+	}
+	provider, ok := m.ForHost(host)
+	return provider, ownerRepo, ok
+}
+
+// Resolve returns the provider hosting a worktree's repository, preferring
+// the origin remote URL over the repository's URL and ID. ok is false for
+// GitHub and local-only repositories.
+func (m *HostingManager) Resolve(worktree *models.Worktree, repository *models.Repository) (HostingProvider, string, bool) {
+	if remoteURL, err := m.operations.GetRemoteURL(worktree.Path); err == nil {
+		if _, _, parsed := ParseRemoteURL(remoteURL); parsed {
+			return m.ForURL(remoteURL)
+		}
+	}
+	if provider, ownerRepo, ok := m.ForURL(repository.URL); ok {
+		return provider, ownerRepo, true
+	}
+	return m.ForRepoID(repository.ID)
+}
+
+// ConfigureGitCredentials points git at the tokens of the providers that have
+// one, so clones and pushes of private repositories authenticate
+func (m *HostingManager) ConfigureGitCredentials() error {
+	if config.Runtime.IsNative() {
+		logger.Debugf("ℹ️ Running in native mode - skipping git credential configuration")
+		return nil
+	}
+	for host, provider := range m.providers {
+		helper := provider.CredentialHelper()
+		if helper == "" {
+			continue
+		}
+		logger.Debugf("🔐 Configuring Git credentials for %s", host)
+		if err := m.operations.SetGlobalConfig(fmt.Sprintf("credential.https://%s.helper", host), helper); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreatePullRequest pushes the worktree's branch and opens or updates its
+// pull request on provider
+func (m *HostingManager) CreatePullRequest(provider HostingProvider, ownerRepo string, req CreatePullRequestRequest) (*models.PullRequestResponse, error) {
+	cleanup := req.prepare(m.operations)
+	defer cleanup()
+
+	branch, err := pushPullRequestBranch(m.operations, req.Worktree, req.ForcePush)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := provider.FindPullRequest(ownerRepo, branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for existing PR: %w", err)
+	}
+
+	spec := PullRequestSpec{
+		Head:  branch,
+		Base:  req.Worktree.SourceBranch,
+		Title: req.Title,
+		Body:  req.Body,
+	}
+	var pr *models.PullRequestResponse
+	switch {
+	case req.IsUpdate && existing == nil:
+		return nil, fmt.Errorf("failed to update PR: no open pull request for branch %s on %s", branch, provider.Host())
+	case req.IsUpdate:
+		pr, err = provider.UpdatePullRequest(ownerRepo, existing.Number, spec)
+	case existing != nil:
+		return nil, fmt.Errorf("PR_ALREADY_EXISTS: A pull request for this branch already exists")
+	default:
+		pr, err = provider.CreatePullRequest(ownerRepo, spec)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pr.HeadBranch = branch
+	pr.BaseBranch = req.Worktree.SourceBranch
+	pr.Repository = ownerRepo
+	logger.Infof("✅ Opened %s pull request #%d for branch %s", provider.Name(), pr.Number, branch)
+	return pr, nil
+}
+
+// GetPullRequestInfo retrieves the open pull request for a worktree's branch
+func (m *HostingManager) GetPullRequestInfo(provider HostingProvider, ownerRepo string, worktree *models.Worktree) (*models.PullRequestInfo, error) {
+	prInfo := &models.PullRequestInfo{
+		HasCommitsAhead: worktree.CommitCount > 0,
+	}
+	pr, err := provider.FindPullRequest(ownerRepo, pullRequestHead(m.operations, worktree))
+	if err != nil || pr == nil {
+		return prInfo, err
+	}
+	prInfo.Exists = true
+	prInfo.Number = pr.Number
+	prInfo.URL = pr.URL
+	prInfo.Title = pr.Title
+	prInfo.Body = pr.Body
+	return prInfo, nil
+}
+
+// hostingAPI is a JSON REST API client for a hosting provider
+type hostingAPI struct {
+	name      string
+	baseURL   string
+	client    *http.Client
+	authorize func(req *http.Request) bool
+}
+
+func newHostingAPI(name, baseURL string, authorize func(req *http.Request) bool) *hostingAPI {
+	return &hostingAPI{
+		name:      name,
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		client:    &http.Client{Timeout: 30 * time.Second},
+		authorize: authorize,
+	}
+}
+
+// do sends a request to path, relative to the API's base URL unless it's a
+// full URL (as pagination links are), and decodes the JSON response into out,
+// returning the response headers for pagination
+func (a *hostingAPI) do(method, path string, query url.Values, body, out interface{}) (http.Header, error) {
+	endpoint := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		endpoint = a.baseURL + path
+	}
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	authenticated := a.authorize(req)
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s API request failed: %w", a.name, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden ||
+		(resp.StatusCode == http.StatusNotFound && !authenticated) {
+		// Private repositories look missing to anonymous requests
+		return nil, models.NewHostNotAuthenticatedError(a.name)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s API %s %s returned %d: %s", a.name, method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return nil, fmt.Errorf("failed to parse %s API response: %w", a.name, err)
+		}
+	}
+	return resp.Header, nil
+}
+
+// prepare fetches the worktree's history and commits uncommitted changes for
+// the pull request, returning the function that undoes the temporary commit
+func (req CreatePullRequestRequest) prepare(operations Operations) func() {
+	// Ensure we have full git history for accurate commit tracking
+	req.FetchFullHistory(req.Worktree)
+
+	// For remote repos, check uncommitted changes and create temporary commit if needed
+	var tempCommitHash string
+	if !strings.HasPrefix(req.Repository.ID, "local/") {
+		if hasChanges, err := operations.HasUncommittedChanges(req.Worktree.Path); err != nil {
+			logger.Warnf("⚠️ Failed to check uncommitted changes for %s: %v", req.Worktree.Name, err)
+		} else if hasChanges {
+			logger.Debugf("📝 Worktree %s has uncommitted changes, creating temporary commit for PR", req.Worktree.Name)
+			if hash, err := req.CreateTempCommit(req.Worktree.Path); err != nil {
+				logger.Warnf("⚠️ Failed to create temporary commit for PR: %v", err)
+			} else {
+				tempCommitHash = hash
+			}
+		}
+	}
+
+	return func() {
+		if tempCommitHash != "" {
+			req.RevertTempCommit(req.Worktree.Path, tempCommitHash)
+		}
+	}
+}
+
+// pullRequestHead returns the branch a worktree's pull request is opened
+// from: the nice branch mapped to a custom ref (e.g. refs/catnip/ninja), or
+// the ref's simple name
+func pullRequestHead(operations Operations, worktree *models.Worktree) string {
+	if !strings.HasPrefix(worktree.Branch, "refs/catnip/") {
+		return worktree.Branch
+	}
+	configKey := fmt.Sprintf("catnip.branch-map.%s", strings.ReplaceAll(worktree.Branch, "/", "."))
+	if niceBranch, err := operations.GetConfig(worktree.Path, configKey); err == nil && strings.TrimSpace(niceBranch) != "" {
+		return strings.TrimSpace(niceBranch)
+	}
+	return strings.TrimPrefix(worktree.Branch, "refs/catnip/")
+}
+
+// pushPullRequestBranch points the pull request's branch at the worktree's
+// HEAD, without switching the worktree off a custom ref, and pushes it
+func pushPullRequestBranch(operations Operations, worktree *models.Worktree, forcePush bool) (string, error) {
+	branch := pullRequestHead(operations, worktree)
+	if branch != worktree.Branch {
+		if currentCommit, _ := operations.GetCommitHash(worktree.Path, "HEAD"); currentCommit != "" {
+			if !operations.BranchExists(worktree.Path, branch, false) {
+				if err := operations.CreateBranch(worktree.Path, branch, currentCommit); err != nil {
+					return "", fmt.Errorf("failed to create branch from custom ref: %v", err)
+				}
+			} else if _, err := operations.ExecuteGit(worktree.Path, "branch", "-f", branch, currentCommit); err != nil {
+				logger.Warnf("⚠️ Failed to update branch to current commit: %v", err)
+			}
+		}
+	}
+
+	if err := operations.PushBranch(worktree.Path, PushStrategy{
+		Branch:      branch,
+		Remote:      "origin",
+		SetUpstream: true,
+		Force:       forcePush,
+	}); err != nil {
+		return "", fmt.Errorf("failed to push branch before PR creation: %v", err)
+	}
+	return branch, nil
+}
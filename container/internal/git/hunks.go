@@ -0,0 +1,147 @@
+package git
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern matches "@@ -old[,count] +new[,count] @@ section"
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)$`)
+
+// DiffHunk is one hunk of a file's unstaged changes
+type DiffHunk struct {
+	// Content hash of the hunk; staging by ID fails if the file changed since it was listed
+	ID       string   `json:"id" example:"3f2a9c1b7d4e"`
+	Index    int      `json:"index" example:"0"`
+	Header   string   `json:"header" example:"@@ -10,6 +10,8 @@ func main() {"`
+	OldStart int      `json:"old_start" example:"10"`
+	OldLines int      `json:"old_lines" example:"6"`
+	NewStart int      `json:"new_start" example:"10"`
+	NewLines int      `json:"new_lines" example:"8"`
+	Lines    []string `json:"lines"`
+	// Section text git shows after the range (usually the enclosing function)
+	section string
+}
+
+// FileHunks is a single file's unstaged diff split into hunks
+type FileHunks struct {
+	Path   string     `json:"path" example:"src/main.go"`
+	Binary bool       `json:"binary"`
+	Hunks  []DiffHunk `json:"hunks"`
+	// Lines before the first hunk (diff --git, index, ---/+++), needed to rebuild a patch
+	header []string
+}
+
+// ParseFileDiff splits `git diff` output for a single file into hunks
+func ParseFileDiff(path, output string) (*FileHunks, error) {
+	diff := &FileHunks{Path: path, Hunks: []DiffHunk{}}
+	if strings.TrimSpace(output) == "" {
+		return diff, nil
+	}
+
+	var current *DiffHunk
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if strings.HasPrefix(line, "@@") {
+			match := hunkHeaderPattern.FindStringSubmatch(line)
+			if match == nil {
+				return nil, fmt.Errorf("malformed hunk header %q", line)
+			}
+			diff.Hunks = append(diff.Hunks, DiffHunk{
+				Index:    len(diff.Hunks),
+				Header:   line,
+				OldStart: atoiDefault(match[1], 0),
+				OldLines: atoiDefault(match[2], 1),
+				NewStart: atoiDefault(match[3], 0),
+				NewLines: atoiDefault(match[4], 1),
+				Lines:    []string{},
+				section:  match[5],
+			})
+			current = &diff.Hunks[len(diff.Hunks)-1]
+			continue
+		}
+		if current == nil {
+			if strings.HasPrefix(line, "Binary files ") || strings.HasPrefix(line, "GIT binary patch") {
+				diff.Binary = true
+			}
+			diff.header = append(diff.header, line)
+			continue
+		}
+		current.Lines = append(current.Lines, line)
+	}
+
+	for i := range diff.Hunks {
+		diff.Hunks[i].ID = hunkID(&diff.Hunks[i])
+	}
+	return diff, nil
+}
+
+// hunkID hashes a hunk's position and content
+func hunkID(hunk *DiffHunk) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d,%d\n%s", hunk.OldStart, hunk.OldLines, strings.Join(hunk.Lines, "\n"))))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+func atoiDefault(s string, fallback int) int {
+	if s == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// BuildHunkPatch builds a patch containing only the selected hunks, suitable
+// for `git apply --cached`. New-side line numbers are recomputed because
+// unselected hunks earlier in the file are left out.
+func BuildHunkPatch(diff *FileHunks, hunkIDs []string) (string, error) {
+	if diff.Binary {
+		return "", fmt.Errorf("%s is a binary file; stage it whole", diff.Path)
+	}
+
+	selected := make(map[string]bool, len(hunkIDs))
+	for _, id := range hunkIDs {
+		selected[id] = true
+	}
+
+	var b strings.Builder
+	for _, line := range diff.header {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	found, offset := 0, 0
+	for _, hunk := range diff.Hunks {
+		if !selected[hunk.ID] {
+			continue
+		}
+		found++
+
+		newStart := hunk.OldStart + offset
+		switch {
+		case hunk.OldLines == 0:
+			newStart++
+		case hunk.NewLines == 0:
+			newStart--
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@%s\n", hunk.OldStart, hunk.OldLines, newStart, hunk.NewLines, hunk.section)
+		for _, line := range hunk.Lines {
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+		offset += hunk.NewLines - hunk.OldLines
+	}
+
+	if found == 0 {
+		return "", fmt.Errorf("no hunks selected")
+	}
+	if found != len(selected) {
+		return "", fmt.Errorf("%d of the selected hunks no longer exist in %s; reload its hunks", len(selected)-found, diff.Path)
+	}
+	return b.String(), nil
+}
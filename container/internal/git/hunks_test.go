@@ -0,0 +1,64 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const twoHunkDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,5 @@
+ package main
++
++import "fmt"
+ 
+ func a() {}
+@@ -20,4 +22,3 @@ func b() {
+ 	x := 1
+-	y := 2
+ 	return
+ }
+`
+
+func TestParseFileDiff(t *testing.T) {
+	diff, err := ParseFileDiff("main.go", twoHunkDiff)
+	require.NoError(t, err)
+	require.Len(t, diff.Hunks, 2)
+
+	assert.Equal(t, 1, diff.Hunks[0].OldStart)
+	assert.Equal(t, 5, diff.Hunks[0].NewLines)
+	assert.Len(t, diff.Hunks[0].Lines, 5)
+	assert.Equal(t, 20, diff.Hunks[1].OldStart)
+	assert.Equal(t, "@@ -20,4 +22,3 @@ func b() {", diff.Hunks[1].Header)
+	assert.NotEqual(t, diff.Hunks[0].ID, diff.Hunks[1].ID)
+	assert.False(t, diff.Binary)
+
+	binary, err := ParseFileDiff("logo.png", "diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\n")
+	require.NoError(t, err)
+	assert.True(t, binary.Binary)
+	assert.Empty(t, binary.Hunks)
+}
+
+func TestBuildHunkPatch(t *testing.T) {
+	diff, err := ParseFileDiff("main.go", twoHunkDiff)
+	require.NoError(t, err)
+
+	// Leaving out the first hunk shifts the second back by the two lines it added
+	patch, err := BuildHunkPatch(diff, []string{diff.Hunks[1].ID})
+	require.NoError(t, err)
+	assert.Contains(t, patch, "+++ b/main.go\n@@ -20,4 +20,3 @@ func b() {\n \tx := 1\n-\ty := 2\n")
+	assert.NotContains(t, patch, "import")
+
+	patch, err = BuildHunkPatch(diff, []string{diff.Hunks[0].ID, diff.Hunks[1].ID})
+	require.NoError(t, err)
+	assert.Contains(t, patch, "@@ -20,4 +22,3 @@")
+
+	_, err = BuildHunkPatch(diff, []string{"stale"})
+	assert.Error(t, err)
+	_, err = BuildHunkPatch(diff, []string{diff.Hunks[0].ID, "stale"})
+	assert.Error(t, err)
+}
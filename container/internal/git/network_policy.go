@@ -0,0 +1,268 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Default timeouts and retry policy for network git commands
+const (
+	DefaultFetchTimeout    = 2 * time.Minute
+	DefaultPushTimeout     = 2 * time.Minute
+	DefaultCloneTimeout    = 10 * time.Minute
+	DefaultLsRemoteTimeout = 30 * time.Second
+	DefaultMaxRetries      = 2
+	DefaultRetryBackoff    = time.Second
+	DefaultMaxRetryBackoff = 15 * time.Second
+)
+
+// NetworkPolicy controls timeouts and retries for git commands that talk to a remote
+type NetworkPolicy struct {
+	FetchTimeout    time.Duration // Also used for pull
+	PushTimeout     time.Duration
+	CloneTimeout    time.Duration
+	LsRemoteTimeout time.Duration
+	MaxRetries      int           // Retries after a transient network error
+	RetryBackoff    time.Duration // Delay before the first retry, doubled for each retry after it
+	MaxRetryBackoff time.Duration
+}
+
+// NetworkOverrideResolver returns the network settings override for the
+// repository a git command runs in, or nil to use the defaults
+type NetworkOverrideResolver func(workingDir string) *models.GitNetworkSettings
+
+// DefaultNetworkPolicy returns the built-in network policy
+func DefaultNetworkPolicy() NetworkPolicy {
+	return NetworkPolicy{
+		FetchTimeout:    DefaultFetchTimeout,
+		PushTimeout:     DefaultPushTimeout,
+		CloneTimeout:    DefaultCloneTimeout,
+		LsRemoteTimeout: DefaultLsRemoteTimeout,
+		MaxRetries:      DefaultMaxRetries,
+		RetryBackoff:    DefaultRetryBackoff,
+		MaxRetryBackoff: DefaultMaxRetryBackoff,
+	}
+}
+
+// NetworkPolicyFromEnv returns the default network policy adjusted by
+// CATNIP_GIT_{FETCH,PUSH,CLONE,LS_REMOTE}_TIMEOUT_SECONDS and CATNIP_GIT_MAX_RETRIES
+func NetworkPolicyFromEnv() NetworkPolicy {
+	envInt := func(name string) int {
+		raw := os.Getenv(name)
+		if raw == "" {
+			return 0
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			logger.Warnf("⚠️ Ignoring invalid %s=%q", name, raw)
+			return 0
+		}
+		return value
+	}
+
+	settings := &models.GitNetworkSettings{
+		FetchTimeoutSeconds:    envInt("CATNIP_GIT_FETCH_TIMEOUT_SECONDS"),
+		PushTimeoutSeconds:     envInt("CATNIP_GIT_PUSH_TIMEOUT_SECONDS"),
+		CloneTimeoutSeconds:    envInt("CATNIP_GIT_CLONE_TIMEOUT_SECONDS"),
+		LsRemoteTimeoutSeconds: envInt("CATNIP_GIT_LS_REMOTE_TIMEOUT_SECONDS"),
+	}
+	if os.Getenv("CATNIP_GIT_MAX_RETRIES") != "" {
+		retries := envInt("CATNIP_GIT_MAX_RETRIES")
+		settings.MaxRetries = &retries
+	}
+	return DefaultNetworkPolicy().WithOverrides(settings)
+}
+
+// WithOverrides returns a copy of the policy with any non-zero settings applied
+func (p NetworkPolicy) WithOverrides(settings *models.GitNetworkSettings) NetworkPolicy {
+	if settings == nil {
+		return p
+	}
+	seconds := func(value int, fallback time.Duration) time.Duration {
+		if value > 0 {
+			return time.Duration(value) * time.Second
+		}
+		return fallback
+	}
+
+	p.FetchTimeout = seconds(settings.FetchTimeoutSeconds, p.FetchTimeout)
+	p.PushTimeout = seconds(settings.PushTimeoutSeconds, p.PushTimeout)
+	p.CloneTimeout = seconds(settings.CloneTimeoutSeconds, p.CloneTimeout)
+	p.LsRemoteTimeout = seconds(settings.LsRemoteTimeoutSeconds, p.LsRemoteTimeout)
+	if settings.MaxRetries != nil {
+		p.MaxRetries = *settings.MaxRetries
+	}
+	return p
+}
+
+// Settings returns the policy in the same shape as a per-repository override
+func (p NetworkPolicy) Settings() models.GitNetworkSettings {
+	retries := p.MaxRetries
+	return models.GitNetworkSettings{
+		FetchTimeoutSeconds:    int(p.FetchTimeout / time.Second),
+		PushTimeoutSeconds:     int(p.PushTimeout / time.Second),
+		CloneTimeoutSeconds:    int(p.CloneTimeout / time.Second),
+		LsRemoteTimeoutSeconds: int(p.LsRemoteTimeout / time.Second),
+		MaxRetries:             &retries,
+	}
+}
+
+// TimeoutFor returns the timeout for a network git command
+func (p NetworkPolicy) TimeoutFor(command string) time.Duration {
+	switch command {
+	case "fetch", "pull":
+		return p.FetchTimeout
+	case "push":
+		return p.PushTimeout
+	case "clone":
+		return p.CloneTimeout
+	case "ls-remote":
+		return p.LsRemoteTimeout
+	}
+	return 0
+}
+
+// backoff returns the delay before the given retry (1-based)
+func (p NetworkPolicy) backoff(retry int) time.Duration {
+	delay := p.RetryBackoff
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxRetryBackoff > 0 && delay >= p.MaxRetryBackoff {
+			return p.MaxRetryBackoff
+		}
+	}
+	return delay
+}
+
+// NetworkCommand returns the git subcommand in args if it talks to a remote,
+// or "" for local commands. Leading -c and -C options are skipped.
+func NetworkCommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "-c" || args[i] == "-C":
+			i++
+		case strings.HasPrefix(args[i], "-"):
+		default:
+			switch args[i] {
+			case "fetch", "pull", "push", "clone", "ls-remote":
+				return args[i]
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+var transientNetworkErrors = []string{
+	"could not resolve host",
+	"temporary failure in name resolution",
+	"connection timed out",
+	"operation timed out",
+	"connection reset",
+	"connection refused",
+	"network is unreachable",
+	"early eof",
+	"rpc failed",
+	"the remote end hung up unexpectedly",
+	"unexpected disconnect",
+	"gnutls_handshake",
+	"ssl_read",
+	"tls handshake timeout",
+	"http2 stream",
+	"shallow.lock", // Concurrent or crashed fetch still holds the shallow lock
+}
+
+var serverErrorPattern = regexp.MustCompile(`returned error: 5\d\d`)
+
+// IsTransientNetworkError reports whether a failed git command is worth
+// retrying: per-attempt timeouts, DNS/connection failures, dropped transfers
+// and 5xx responses from the server. Auth failures, rejected pushes and
+// missing refs are not retried.
+func IsTransientNetworkError(err error, output string) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	message := strings.ToLower(err.Error() + "\n" + output)
+	for _, pattern := range transientNetworkErrors {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return serverErrorPattern.MatchString(message)
+}
+
+// runWithNetworkPolicy runs a network git command with the policy's timeout,
+// retrying transient failures with exponential backoff until ctx is done
+func runWithNetworkPolicy(ctx context.Context, policy NetworkPolicy, command string, run func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	timeout := policy.TimeoutFor(command)
+
+	var output []byte
+	var err error
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := policy.backoff(attempt)
+			logger.Debugf("🔄 git %s attempt %d/%d failed, retrying in %v: %v", command, attempt, policy.MaxRetries+1, delay, err)
+			select {
+			case <-ctx.Done():
+				return output, fmt.Errorf("git %s canceled while waiting to retry: %w (last error: %v)", command, ctx.Err(), err)
+			case <-time.After(delay):
+			}
+		}
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		}
+		output, err = run(attemptCtx)
+		timedOut := attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+		cancel()
+
+		if err == nil {
+			return output, nil
+		}
+		if ctx.Err() != nil {
+			return output, err
+		}
+		if timedOut && !errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("%v: %w", err, context.DeadlineExceeded)
+		}
+		if !IsTransientNetworkError(err, string(output)) {
+			return output, err
+		}
+	}
+
+	if policy.MaxRetries > 0 {
+		return output, fmt.Errorf("git %s failed after %d attempts: %w", command, policy.MaxRetries+1, err)
+	}
+	return output, err
+}
+
+// cloneDestination returns the directory a clone writes to, assuming the
+// repo's convention of passing the destination as the last argument
+func cloneDestination(workingDir string, args []string) string {
+	if len(args) < 2 || strings.HasPrefix(args[len(args)-1], "-") {
+		return ""
+	}
+	dest := args[len(args)-1]
+	if !filepath.IsAbs(dest) && workingDir != "" {
+		dest = filepath.Join(workingDir, dest)
+	}
+	return dest
+}
@@ -0,0 +1,160 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func testNetworkPolicy(retries int) NetworkPolicy {
+	policy := DefaultNetworkPolicy()
+	policy.MaxRetries = retries
+	policy.RetryBackoff = time.Millisecond
+	policy.MaxRetryBackoff = 4 * time.Millisecond
+	return policy
+}
+
+func TestNetworkCommand(t *testing.T) {
+	assert.Equal(t, "fetch", NetworkCommand([]string{"fetch", "origin", "main"}))
+	assert.Equal(t, "push", NetworkCommand([]string{"-c", "url.https://github.com/.insteadOf=git@github.com:", "push", "origin", "main"}))
+	assert.Equal(t, "ls-remote", NetworkCommand([]string{"-C", "/repo", "ls-remote", "--heads", "origin"}))
+	assert.Equal(t, "clone", NetworkCommand([]string{"clone", "--bare", "url", "dest"}))
+	assert.Equal(t, "", NetworkCommand([]string{"rev-parse", "HEAD"}))
+	assert.Equal(t, "", NetworkCommand([]string{"-c", "user.name=fetch", "commit", "-m", "push"}))
+	assert.Equal(t, "", NetworkCommand(nil))
+}
+
+func TestIsTransientNetworkError(t *testing.T) {
+	transient := []string{
+		"fatal: unable to access 'https://github.com/a/b.git/': Could not resolve host: github.com",
+		"error: RPC failed; curl 56 GnuTLS recv error (-9)",
+		"fatal: the remote end hung up unexpectedly",
+		"fatal: early EOF",
+		"The requested URL returned error: 502",
+		"fatal: Unable to create '/repo/shallow.lock': File exists.",
+	}
+	for _, message := range transient {
+		assert.True(t, IsTransientNetworkError(errors.New("git fetch failed"), message), message)
+	}
+
+	permanent := []string{
+		"fatal: Authentication failed for 'https://github.com/a/b.git/'",
+		"! [rejected] main -> main (non-fast-forward)",
+		"fatal: couldn't find remote ref refs/heads/missing",
+		"The requested URL returned error: 404",
+	}
+	for _, message := range permanent {
+		assert.False(t, IsTransientNetworkError(errors.New("git fetch failed"), message), message)
+	}
+
+	assert.True(t, IsTransientNetworkError(context.DeadlineExceeded, ""))
+	assert.False(t, IsTransientNetworkError(context.Canceled, ""))
+	assert.False(t, IsTransientNetworkError(nil, "could not resolve host"))
+}
+
+func TestNetworkPolicyWithOverrides(t *testing.T) {
+	retries := 0
+	policy := DefaultNetworkPolicy().WithOverrides(&models.GitNetworkSettings{
+		PushTimeoutSeconds: 5,
+		MaxRetries:         &retries,
+	})
+
+	assert.Equal(t, 5*time.Second, policy.TimeoutFor("push"))
+	assert.Equal(t, DefaultFetchTimeout, policy.TimeoutFor("fetch"))
+	assert.Equal(t, DefaultFetchTimeout, policy.TimeoutFor("pull"))
+	assert.Equal(t, 0, policy.MaxRetries)
+	assert.Equal(t, DefaultNetworkPolicy(), DefaultNetworkPolicy().WithOverrides(nil))
+
+	settings := policy.Settings()
+	assert.Equal(t, 5, settings.PushTimeoutSeconds)
+	require.NotNil(t, settings.MaxRetries)
+	assert.Equal(t, 0, *settings.MaxRetries)
+}
+
+func TestNetworkPolicyFromEnv(t *testing.T) {
+	t.Setenv("CATNIP_GIT_FETCH_TIMEOUT_SECONDS", "7")
+	t.Setenv("CATNIP_GIT_MAX_RETRIES", "4")
+	t.Setenv("CATNIP_GIT_PUSH_TIMEOUT_SECONDS", "bogus")
+
+	policy := NetworkPolicyFromEnv()
+	assert.Equal(t, 7*time.Second, policy.FetchTimeout)
+	assert.Equal(t, DefaultPushTimeout, policy.PushTimeout)
+	assert.Equal(t, 4, policy.MaxRetries)
+}
+
+func TestNetworkPolicyBackoff(t *testing.T) {
+	policy := NetworkPolicy{RetryBackoff: time.Second, MaxRetryBackoff: 5 * time.Second}
+	assert.Equal(t, time.Second, policy.backoff(1))
+	assert.Equal(t, 2*time.Second, policy.backoff(2))
+	assert.Equal(t, 4*time.Second, policy.backoff(3))
+	assert.Equal(t, 5*time.Second, policy.backoff(4))
+}
+
+func TestRunWithNetworkPolicy(t *testing.T) {
+	t.Run("retries transient errors until success", func(t *testing.T) {
+		attempts := 0
+		output, err := runWithNetworkPolicy(context.Background(), testNetworkPolicy(2), "fetch", func(ctx context.Context) ([]byte, error) {
+			attempts++
+			if attempts < 3 {
+				return nil, errors.New("fatal: the remote end hung up unexpectedly")
+			}
+			return []byte("ok"), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", string(output))
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		attempts := 0
+		_, err := runWithNetworkPolicy(context.Background(), testNetworkPolicy(2), "push", func(ctx context.Context) ([]byte, error) {
+			attempts++
+			return nil, errors.New("Could not resolve host: github.com")
+		})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "after 3 attempts")
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		attempts := 0
+		_, err := runWithNetworkPolicy(context.Background(), testNetworkPolicy(2), "push", func(ctx context.Context) ([]byte, error) {
+			attempts++
+			return nil, errors.New("! [rejected] main -> main (non-fast-forward)")
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("times out each attempt and retries", func(t *testing.T) {
+		policy := testNetworkPolicy(1)
+		policy.FetchTimeout = 20 * time.Millisecond
+
+		attempts := 0
+		_, err := runWithNetworkPolicy(context.Background(), policy, "fetch", func(ctx context.Context) ([]byte, error) {
+			attempts++
+			<-ctx.Done()
+			return nil, errors.New("signal: killed")
+		})
+		require.Error(t, err)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("stops when the caller cancels", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		_, err := runWithNetworkPolicy(ctx, testNetworkPolicy(5), "fetch", func(ctx context.Context) ([]byte, error) {
+			attempts++
+			cancel()
+			return nil, errors.New("fatal: early EOF")
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
@@ -1,6 +1,9 @@
 package git
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 // WorktreeStatus represents the status of a worktree
 type WorktreeStatus struct {
@@ -18,8 +21,20 @@ type Operations interface {
 	// Core command execution
 	ExecuteGit(workingDir string, args ...string) ([]byte, error)
 	ExecuteGitWithTimeout(workingDir string, timeout time.Duration, args ...string) ([]byte, error)
+	// ExecuteGitContext is ExecuteGit with cancellation; network commands get the
+	// network policy's timeout and retries either way
+	ExecuteGitContext(ctx context.Context, workingDir string, args ...string) ([]byte, error)
+	// ExecuteGitStream runs a git command in the background, delivering its
+	// output line by line while it runs (for long fetches and clones)
+	ExecuteGitStream(ctx context.Context, workingDir string, args ...string) *GitStream
 	ExecuteCommand(command string, args ...string) ([]byte, error)
 
+	// Network policy
+	GetNetworkPolicy() NetworkPolicy
+	SetNetworkPolicy(policy NetworkPolicy)
+	SetNetworkOverrideResolver(resolver NetworkOverrideResolver)
+	EffectiveNetworkPolicy(workingDir string) NetworkPolicy
+
 	// Branch operations
 	BranchExists(repoPath, branch string, isRemote bool) bool
 	GetCommitCount(repoPath, fromRef, toRef string) (int, error)
@@ -1,12 +1,14 @@
 package git
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/vanpelt/catnip/internal/config"
@@ -22,37 +24,119 @@ type OperationsImpl struct {
 	pushExecutor  *PushExecutor
 	statusChecker *StatusChecker
 	urlManager    *URLManager
+
+	networkMu       sync.RWMutex
+	networkPolicy   NetworkPolicy
+	networkResolver NetworkOverrideResolver
+}
+
+// policyExecutor routes the fetch/push executors' git commands through
+// ExecuteGit so they get the network policy too
+type policyExecutor struct {
+	executor.CommandExecutor
+	ops *OperationsImpl
+}
+
+func (p *policyExecutor) ExecuteGitWithWorkingDir(workingDir string, args ...string) ([]byte, error) {
+	return p.ops.ExecuteGit(workingDir, args...)
 }
 
 // NewOperations creates a new Operations implementation using gogit by default
 func NewOperations() Operations {
-	exec := executor.NewGitExecutor() // Use gogit by default
-	return &OperationsImpl{
-		executor:      exec,
-		branchOps:     NewBranchOperations(exec),
-		fetchExecutor: NewFetchExecutor(exec),
-		pushExecutor:  NewPushExecutor(exec),
-		statusChecker: NewStatusChecker(exec),
-		urlManager:    NewURLManager(exec),
-	}
+	return NewOperationsWithExecutor(executor.NewGitExecutor()) // Use gogit by default
 }
 
 // NewOperationsWithExecutor creates Operations with a specific executor (for testing)
 func NewOperationsWithExecutor(exec executor.CommandExecutor) Operations {
-	return &OperationsImpl{
+	ops := &OperationsImpl{
 		executor:      exec,
 		branchOps:     NewBranchOperations(exec),
-		fetchExecutor: NewFetchExecutor(exec),
-		pushExecutor:  NewPushExecutor(exec),
 		statusChecker: NewStatusChecker(exec),
 		urlManager:    NewURLManager(exec),
+		networkPolicy: NetworkPolicyFromEnv(),
 	}
+	networkExec := &policyExecutor{CommandExecutor: exec, ops: ops}
+	ops.fetchExecutor = NewFetchExecutor(networkExec)
+	ops.pushExecutor = NewPushExecutor(networkExec)
+	return ops
 }
 
 // Core command execution
 
 func (o *OperationsImpl) ExecuteGit(workingDir string, args ...string) ([]byte, error) {
-	return o.executor.ExecuteGitWithWorkingDir(workingDir, args...)
+	return o.ExecuteGitContext(context.Background(), workingDir, args...)
+}
+
+func (o *OperationsImpl) ExecuteGitContext(ctx context.Context, workingDir string, args ...string) ([]byte, error) {
+	command := NetworkCommand(args)
+	if command == "" {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return o.executor.ExecuteGitWithWorkingDir(workingDir, args...)
+	}
+
+	cloneDest := newCloneDestination(workingDir, command, args)
+	policy := o.EffectiveNetworkPolicy(workingDir)
+	return runWithNetworkPolicy(ctx, policy, command, func(attemptCtx context.Context) ([]byte, error) {
+		if cloneDest != "" {
+			_ = os.RemoveAll(cloneDest)
+		}
+		if ctxExec, ok := o.executor.(executor.ContextExecutor); ok {
+			return ctxExec.ExecuteGitWithContext(attemptCtx, workingDir, args...)
+		}
+		return o.executor.ExecuteGitWithWorkingDir(workingDir, args...)
+	})
+}
+
+// newCloneDestination returns the destination of a clone that doesn't exist
+// yet. A failed clone leaves a partial destination that would make the retry
+// fail, so it is removed before each attempt.
+func newCloneDestination(workingDir, command string, args []string) string {
+	if command != "clone" {
+		return ""
+	}
+	dest := cloneDestination(workingDir, args)
+	if dest == "" {
+		return ""
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		return ""
+	}
+	return dest
+}
+
+// Network policy
+
+func (o *OperationsImpl) GetNetworkPolicy() NetworkPolicy {
+	o.networkMu.RLock()
+	defer o.networkMu.RUnlock()
+	return o.networkPolicy
+}
+
+func (o *OperationsImpl) SetNetworkPolicy(policy NetworkPolicy) {
+	o.networkMu.Lock()
+	defer o.networkMu.Unlock()
+	o.networkPolicy = policy
+}
+
+func (o *OperationsImpl) SetNetworkOverrideResolver(resolver NetworkOverrideResolver) {
+	o.networkMu.Lock()
+	defer o.networkMu.Unlock()
+	o.networkResolver = resolver
+}
+
+// EffectiveNetworkPolicy returns the default policy with any override for the
+// repository at workingDir applied
+func (o *OperationsImpl) EffectiveNetworkPolicy(workingDir string) NetworkPolicy {
+	o.networkMu.RLock()
+	policy, resolver := o.networkPolicy, o.networkResolver
+	o.networkMu.RUnlock()
+
+	if resolver != nil && workingDir != "" {
+		policy = policy.WithOverrides(resolver(workingDir))
+	}
+	return policy
 }
 
 func (o *OperationsImpl) ExecuteGitWithTimeout(workingDir string, timeout time.Duration, args ...string) ([]byte, error) {
@@ -755,8 +839,10 @@ func (o *OperationsImpl) ShowRef(repoPath, ref string, options ShowRefOptions) e
 
 // Garbage collection
 
+// GarbageCollect prunes unreachable objects. Refs are left loose: their
+// modification times are what ref cleanup's grace period goes by.
 func (o *OperationsImpl) GarbageCollect(repoPath string) error {
-	_, err := o.ExecuteGit(repoPath, "gc", "--prune=now")
+	_, err := o.ExecuteGit(repoPath, "-c", "gc.packRefs=false", "gc", "--prune=now")
 	return err
 }
 
@@ -2,7 +2,6 @@ package git
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/git/executor"
@@ -18,6 +17,10 @@ type FetchStrategy struct {
 	Depth          int    // Fetch depth (0 = no depth limit)
 	UpdateLocalRef bool   // Whether to update local refs after fetch
 	RefSpec        string // Custom refspec (optional)
+	Filter         string // Object filter for partial clones, e.g. "blob:none" (optional)
+	Deepen         int    // Commits to add to shallow history (--deepen)
+	Unshallow      bool   // Fetch the rest of shallow history (--unshallow)
+	Lean           bool   // Skip tags and submodules and reduce output, like FetchBranchFast
 }
 
 // PushStrategy defines the strategy for pushing branches
@@ -78,6 +81,16 @@ func (f *FetchExecutor) FetchBranch(repoPath string, strategy FetchStrategy) err
 	// Add depth if specified
 	if strategy.Depth > 0 {
 		args = append(args, "--depth", fmt.Sprintf("%d", strategy.Depth))
+	} else if strategy.Unshallow {
+		args = append(args, "--unshallow")
+	} else if strategy.Deepen > 0 {
+		args = append(args, fmt.Sprintf("--deepen=%d", strategy.Deepen))
+	}
+	if strategy.Filter != "" {
+		args = append(args, "--filter="+strategy.Filter)
+	}
+	if strategy.Lean {
+		args = append(args, "--no-tags", "--quiet", "--no-recurse-submodules")
 	}
 
 	// Execute fetch
@@ -120,41 +133,23 @@ func (f *FetchExecutor) FetchBranchFast(repoPath, branch string) error {
 		"--no-recurse-submodules", // Skip submodules
 	}
 
-	// Retry up to 3 times with exponential backoff for resilience against temporary issues
-	// (e.g., shallow.lock conflicts from concurrent/crashed git processes)
-	maxRetries := 3
-	var lastErr error
-	var lastOutput []byte
-
-	for attempt := 0; attempt < maxRetries; attempt++ {
-		output, err := f.executor.ExecuteGitWithWorkingDir(repoPath, args...)
-		if err == nil {
-			// Fetch succeeded - now create local branch ref from remote tracking branch
-			// This mirrors the logic in FetchBranch's UpdateLocalRef
-			_, updateErr := f.executor.ExecuteGitWithWorkingDir(repoPath, "update-ref",
-				fmt.Sprintf("refs/heads/%s", branch),
-				fmt.Sprintf("refs/remotes/origin/%s", branch))
-			if updateErr != nil {
-				logger.Debugf("⚠️ Could not update local branch ref for %s: %v", branch, updateErr)
-				// Don't fail the fetch operation if ref update fails - the remote tracking branch is still updated
-			}
-			return nil // Success!
-		}
-
-		lastErr = err
-		lastOutput = output
-
-		// If this wasn't the last attempt, wait before retrying
-		if attempt < maxRetries-1 {
-			// Exponential backoff: 1s, 2s
-			waitTime := time.Duration(1<<attempt) * time.Second
-			logger.Debugf("🔄 Fetch attempt %d/%d failed, retrying in %v: %v", attempt+1, maxRetries, waitTime, err)
-			time.Sleep(waitTime)
-		}
+	// Transient failures (including shallow.lock conflicts from concurrent/crashed
+	// git processes) are retried with backoff by the operations network policy
+	output, err := f.executor.ExecuteGitWithWorkingDir(repoPath, args...)
+	if err != nil {
+		return fmt.Errorf("failed to fetch branch optimized: %v\n%s", err, output)
 	}
 
-	// All retries exhausted
-	return fmt.Errorf("failed to fetch branch optimized after %d attempts: %v\n%s", maxRetries, lastErr, lastOutput)
+	// Fetch succeeded - now create local branch ref from remote tracking branch
+	// This mirrors the logic in FetchBranch's UpdateLocalRef
+	_, updateErr := f.executor.ExecuteGitWithWorkingDir(repoPath, "update-ref",
+		fmt.Sprintf("refs/heads/%s", branch),
+		fmt.Sprintf("refs/remotes/origin/%s", branch))
+	if updateErr != nil {
+		logger.Debugf("⚠️ Could not update local branch ref for %s: %v", branch, updateErr)
+		// Don't fail the fetch operation if ref update fails - the remote tracking branch is still updated
+	}
+	return nil
 }
 
 // FetchBranchFull performs a full fetch for operations that need complete history
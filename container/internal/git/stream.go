@@ -0,0 +1,90 @@
+package git
+
+import (
+	"context"
+	"os"
+
+	"github.com/vanpelt/catnip/internal/git/executor"
+)
+
+// OutputLine is one line of progressive output from a streamed git command
+type OutputLine = executor.OutputLine
+
+// GitStream is a git command running in the background whose output is
+// delivered line by line. Every stream has its own pipes and channel, so any
+// number can run in parallel.
+// nolint:revive
+type GitStream struct {
+	lines  chan OutputLine
+	done   chan struct{}
+	output []byte
+	err    error
+}
+
+func newGitStream() *GitStream {
+	return &GitStream{
+		lines: make(chan OutputLine, 64),
+		done:  make(chan struct{}),
+	}
+}
+
+// Lines returns the command's stdout and stderr lines in arrival order. The
+// channel is closed when the command exits. Readers must keep up: the
+// command blocks while the channel is full.
+func (s *GitStream) Lines() <-chan OutputLine {
+	return s.lines
+}
+
+// Wait waits for the command to exit, discarding lines nobody has read, and
+// returns its full stdout and error like ExecuteGit
+func (s *GitStream) Wait() ([]byte, error) {
+	for range s.lines {
+	}
+	<-s.done
+	return s.output, s.err
+}
+
+func (s *GitStream) finish(output []byte, err error) {
+	s.output, s.err = output, err
+	close(s.lines)
+	close(s.done)
+}
+
+// ExecuteGitStream starts a git command and streams its output. Network
+// commands get the network policy's timeout and retries; output from failed
+// attempts is streamed too. Add --progress to fetch/clone to get progress
+// lines, since git only reports progress to terminals by default.
+func (o *OperationsImpl) ExecuteGitStream(ctx context.Context, workingDir string, args ...string) *GitStream {
+	stream := newGitStream()
+	onLine := func(line OutputLine) {
+		stream.lines <- line
+	}
+	go func() {
+		stream.finish(o.executeGitStreaming(ctx, workingDir, onLine, args...))
+	}()
+	return stream
+}
+
+func (o *OperationsImpl) executeGitStreaming(ctx context.Context, workingDir string, onLine executor.LineHandler, args ...string) ([]byte, error) {
+	streamExec, ok := o.executor.(executor.StreamExecutor)
+	if !ok {
+		// Executors that can't stream deliver the whole output once the command finishes
+		output, err := o.ExecuteGitContext(ctx, workingDir, args...)
+		executor.EmitOutputLines(output, false, onLine)
+		return output, err
+	}
+
+	command := NetworkCommand(args)
+	if command == "" {
+		return streamExec.ExecuteGitStream(ctx, workingDir, onLine, args...)
+	}
+
+	cloneDest := newCloneDestination(workingDir, command, args)
+	policy := o.EffectiveNetworkPolicy(workingDir)
+	return runWithNetworkPolicy(ctx, policy, command, func(attemptCtx context.Context) ([]byte, error) {
+		if cloneDest != "" {
+			_ = os.RemoveAll(cloneDest)
+		}
+		return streamExec.ExecuteGitStream(attemptCtx, workingDir, onLine, args...)
+	})
+}
@@ -0,0 +1,88 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git/executor"
+)
+
+func TestScanOutputLines(t *testing.T) {
+	input := "Receiving objects:  10%\rReceiving objects:  50%\rReceiving objects: 100%, done.\r\nResolving deltas\nlast"
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Split(executor.ScanOutputLines)
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Equal(t, []string{
+		"Receiving objects:  10%",
+		"Receiving objects:  50%",
+		"Receiving objects: 100%, done.",
+		"Resolving deltas",
+		"last",
+	}, lines)
+}
+
+func TestExecuteGitStreamParallel(t *testing.T) {
+	ops := NewOperationsWithExecutor(executor.NewShellExecutor())
+
+	// Several repositories created concurrently, each stream only sees its own output
+	var wg sync.WaitGroup
+	results := make([][]OutputLine, 4)
+	dirs := make([]string, 4)
+	for i := range dirs {
+		dirs[i] = t.TempDir()
+	}
+	for i := range dirs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			stream := ops.ExecuteGitStream(context.Background(), dirs[i], "init", "-b", fmt.Sprintf("branch-%d", i))
+			for line := range stream.Lines() {
+				results[i] = append(results[i], line)
+			}
+			_, err := stream.Wait()
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, lines := range results {
+		require.NotEmpty(t, lines)
+		assert.Contains(t, lines[0].Text, dirs[i])
+		assert.False(t, lines[0].Stderr)
+	}
+
+	// Failures report stderr as a line and in the error, and Wait works without reading lines
+	stream := ops.ExecuteGitStream(context.Background(), dirs[0], "rev-parse", "--verify", "no-such-ref")
+	_, err := stream.Wait()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stderr:")
+
+	stream = ops.ExecuteGitStream(context.Background(), dirs[0], "rev-parse", "--verify", "no-such-ref")
+	var stderr []string
+	for line := range stream.Lines() {
+		if line.Stderr {
+			stderr = append(stderr, line.Text)
+		}
+	}
+	_, _ = stream.Wait()
+	assert.NotEmpty(t, stderr)
+}
+
+func TestExecuteGitStreamCanceled(t *testing.T) {
+	ops := NewOperationsWithExecutor(executor.NewShellExecutor())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ops.ExecuteGitStream(ctx, t.TempDir(), "init").Wait()
+	assert.Error(t, err)
+}
@@ -0,0 +1,223 @@
+package templates
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFileName is the file that marks a directory in a template repository as a template
+const ManifestFileName = "catnip-template.yaml"
+
+const (
+	// SourceBuiltin marks templates shipped with catnip
+	SourceBuiltin = "builtin"
+	// SourceRemote marks templates synced from a template repository
+	SourceRemote = "remote"
+)
+
+var templateIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// TemplateInfo describes a template for the template picker
+type TemplateInfo struct {
+	ID          string   `json:"id" yaml:"id"`
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Category    string   `json:"category" yaml:"category"`
+	Language    string   `json:"language" yaml:"language"`
+	Framework   string   `json:"framework,omitempty" yaml:"framework"`
+	Icon        string   `json:"icon,omitempty" yaml:"icon"`
+	Tags        []string `json:"tags,omitempty" yaml:"tags"`
+	Source      string   `json:"source" yaml:"-"`
+}
+
+// RemoteTemplate is a template loaded from a synced template repository
+type RemoteTemplate struct {
+	TemplateInfo
+	Dir string // Directory holding the manifest and template files
+}
+
+var builtinTemplates = []TemplateInfo{
+	{ID: "basic", Name: "Basic README", Description: "Simple empty repo with a basic README.md", Category: "basic", Language: "Markdown", Icon: "📝"},
+	{ID: "react-vite", Name: "React + Vite", Description: "Modern React app with Vite, TypeScript, and Tailwind CSS", Category: "frontend", Language: "TypeScript", Framework: "React", Icon: "⚛️"},
+	{ID: "node-express", Name: "Node.js + Express API", Description: "RESTful API with Express, TypeScript, and basic middleware", Category: "backend", Language: "TypeScript", Framework: "Express", Icon: "🚀"},
+	{ID: "vue-vite", Name: "Vue 3 + Vite", Description: "Vue 3 app with Composition API, TypeScript, and Tailwind CSS", Category: "frontend", Language: "TypeScript", Framework: "Vue", Icon: "💚"},
+	{ID: "python-fastapi", Name: "Python FastAPI", Description: "Modern Python API with FastAPI, async support, and auto-documentation", Category: "backend", Language: "Python", Framework: "FastAPI", Icon: "🐍"},
+	{ID: "nextjs-app", Name: "Next.js App", Description: "Full-stack React framework with App Router, TypeScript, and Tailwind CSS", Category: "fullstack", Language: "TypeScript", Framework: "Next.js", Icon: "▲"},
+}
+
+var (
+	remoteMutex     sync.RWMutex
+	remoteTemplates = map[string]*RemoteTemplate{}
+)
+
+// IsBuiltinTemplate reports whether templateID is one of the templates shipped with catnip
+func IsBuiltinTemplate(templateID string) bool {
+	for _, info := range builtinTemplates {
+		if info.ID == templateID {
+			return true
+		}
+	}
+	return false
+}
+
+// ListTemplates returns the builtin templates followed by synced remote templates
+func ListTemplates() []TemplateInfo {
+	result := make([]TemplateInfo, 0, len(builtinTemplates))
+	for _, info := range builtinTemplates {
+		info.Source = SourceBuiltin
+		result = append(result, info)
+	}
+
+	remoteMutex.RLock()
+	defer remoteMutex.RUnlock()
+
+	remote := make([]TemplateInfo, 0, len(remoteTemplates))
+	for _, tmpl := range remoteTemplates {
+		remote = append(remote, tmpl.TemplateInfo)
+	}
+	sort.Slice(remote, func(i, j int) bool { return remote[i].ID < remote[j].ID })
+
+	return append(result, remote...)
+}
+
+// GetRemoteTemplate returns the synced remote template with the given ID
+func GetRemoteTemplate(templateID string) (*RemoteTemplate, bool) {
+	remoteMutex.RLock()
+	defer remoteMutex.RUnlock()
+	tmpl, exists := remoteTemplates[templateID]
+	return tmpl, exists
+}
+
+// SetRemoteTemplates replaces the set of synced remote templates
+func SetRemoteTemplates(list []*RemoteTemplate) {
+	next := make(map[string]*RemoteTemplate, len(list))
+	for _, tmpl := range list {
+		next[tmpl.ID] = tmpl
+	}
+
+	remoteMutex.Lock()
+	defer remoteMutex.Unlock()
+	remoteTemplates = next
+}
+
+// ValidateManifest checks that a template manifest is usable
+func ValidateManifest(info *TemplateInfo) error {
+	if info.ID == "" {
+		return fmt.Errorf("id is required")
+	}
+	if !templateIDPattern.MatchString(info.ID) {
+		return fmt.Errorf("id %q must contain only lowercase letters, digits and dashes", info.ID)
+	}
+	if IsBuiltinTemplate(info.ID) {
+		return fmt.Errorf("id %q conflicts with a builtin template", info.ID)
+	}
+	if info.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	return nil
+}
+
+// LoadTemplatesFromDir scans the top-level directories of a template repository
+// for manifests. Invalid templates are skipped and reported in the returned errors.
+func LoadTemplatesFromDir(root string) ([]*RemoteTemplate, []error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	var (
+		result []*RemoteTemplate
+		errs   []error
+		seen   = make(map[string]string)
+	)
+
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == ".git" {
+			continue
+		}
+
+		dir := filepath.Join(root, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, ManifestFileName))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			}
+			continue
+		}
+
+		var info TemplateInfo
+		if err := yaml.Unmarshal(data, &info); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid manifest: %w", entry.Name(), err))
+			continue
+		}
+		if err := ValidateManifest(&info); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", entry.Name(), err))
+			continue
+		}
+		if other, exists := seen[info.ID]; exists {
+			errs = append(errs, fmt.Errorf("%s: id %q already used by %s", entry.Name(), info.ID, other))
+			continue
+		}
+		seen[info.ID] = entry.Name()
+
+		info.Source = SourceRemote
+		if info.Category == "" {
+			info.Category = "custom"
+		}
+		result = append(result, &RemoteTemplate{TemplateInfo: info, Dir: dir})
+	}
+
+	return result, errs
+}
+
+// CopyRemoteTemplate copies a remote template's files (minus its manifest) into projectPath
+func CopyRemoteTemplate(tmpl *RemoteTemplate, projectPath string) error {
+	return filepath.Walk(tmpl.Dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(tmpl.Dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == ManifestFileName {
+			return nil
+		}
+
+		target := filepath.Join(projectPath, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Skip symlinks and special files so a template can't reach outside its directory
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", dst, err)
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,69 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTemplate(t *testing.T, root, dir, manifest string, files map[string]string) {
+	t.Helper()
+	base := filepath.Join(root, dir)
+	require.NoError(t, os.MkdirAll(base, 0755))
+	if manifest != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(base, ManifestFileName), []byte(manifest), 0644))
+	}
+	for path, content := range files {
+		full := filepath.Join(base, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(full), 0755))
+		require.NoError(t, os.WriteFile(full, []byte(content), 0644))
+	}
+}
+
+func TestLoadTemplatesFromDir(t *testing.T) {
+	root := t.TempDir()
+	writeTemplate(t, root, "go-service", "id: go-service\nname: Go Service\nlanguage: Go\n", map[string]string{"main.go": "package main\n"})
+	writeTemplate(t, root, "dupe", "id: go-service\nname: Duplicate\n", nil)
+	writeTemplate(t, root, "builtin-clash", "id: basic\nname: Basic\n", nil)
+	writeTemplate(t, root, "bad-id", "id: Bad_ID\nname: Bad\n", nil)
+	writeTemplate(t, root, "no-manifest", "", map[string]string{"README.md": "hi"})
+
+	loaded, errs := LoadTemplatesFromDir(root)
+
+	require.Len(t, loaded, 1)
+	assert.Equal(t, "go-service", loaded[0].ID)
+	assert.Equal(t, SourceRemote, loaded[0].Source)
+	assert.Equal(t, "custom", loaded[0].Category)
+	assert.Len(t, errs, 3)
+}
+
+func TestRemoteTemplatesInRegistry(t *testing.T) {
+	root := t.TempDir()
+	writeTemplate(t, root, "go-service", "id: go-service\nname: Go Service\n", map[string]string{
+		"main.go":        "package main\n",
+		"cmd/tool/a.txt": "nested",
+	})
+	loaded, _ := LoadTemplatesFromDir(root)
+
+	SetRemoteTemplates(loaded)
+	defer SetRemoteTemplates(nil)
+
+	list := ListTemplates()
+	assert.Equal(t, "basic", list[0].ID)
+	assert.Equal(t, SourceBuiltin, list[0].Source)
+	assert.Equal(t, "go-service", list[len(list)-1].ID)
+
+	tmpl, exists := GetRemoteTemplate("go-service")
+	require.True(t, exists)
+
+	project := t.TempDir()
+	require.NoError(t, CopyRemoteTemplate(tmpl, project))
+
+	data, err := os.ReadFile(filepath.Join(project, "cmd", "tool", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "nested", string(data))
+	assert.NoFileExists(t, filepath.Join(project, ManifestFileName))
+}
@@ -376,6 +376,27 @@ func ExtractWorkspaceName(branchName string) string {
 	return branchName
 }
 
+// CatnipKeepRefPrefix is the namespace of keep markers. A marker at
+// refs/catnip-keep/<name> stops cleanup from deleting the workspace ref
+// refs/catnip/<name> (or legacy branch catnip/<name>), and since it points at
+// the same commit, keeps that commit from being garbage collected.
+const CatnipKeepRefPrefix = "refs/catnip-keep/"
+
+// KeepRefName returns the keep marker for a catnip ref or branch
+// Example: "refs/catnip/felix" -> "refs/catnip-keep/felix"
+func KeepRefName(branchName string) string {
+	return CatnipKeepRefPrefix + ExtractWorkspaceName(branchName)
+}
+
+// CatnipHibernateRefPrefix is the namespace holding the uncommitted changes of
+// hibernated worktrees, as stash commits at refs/catnip-hibernate/<worktree id>
+const CatnipHibernateRefPrefix = "refs/catnip-hibernate/"
+
+// HibernateRefName returns the ref holding a hibernated worktree's changes
+func HibernateRefName(worktreeID string) string {
+	return CatnipHibernateRefPrefix + worktreeID
+}
+
 func CleanBranchName(branchName string) string {
 	branchName = strings.TrimSpace(branchName)
 	branchName = strings.TrimPrefix(branchName, "*") // Current branch indicator
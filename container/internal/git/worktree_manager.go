@@ -228,8 +228,8 @@ func (w *WorktreeManager) DeleteWorktree(worktree *models.Worktree, repo *models
 		logger.Debugf("✅ Removed worktree directory: %s", worktree.Path)
 	}
 
-	// Step 2: Remove the worktree branch
-	if worktree.Branch != "" && worktree.Branch != worktree.SourceBranch {
+	// Step 2: Remove the worktree branch, unless it existed before the worktree
+	if worktree.Branch != "" && worktree.Branch != worktree.SourceBranch && worktree.Branch != worktree.ImportedBranch {
 		if err := w.operations.DeleteBranch(repo.Path, worktree.Branch, true); err != nil {
 			logger.Warnf("⚠️ Failed to remove branch %s (may not exist or be in use): %v", worktree.Branch, err)
 		} else {
@@ -253,6 +253,10 @@ func (w *WorktreeManager) DeleteWorktree(worktree *models.Worktree, repo *models
 	} else {
 		logger.Debugf("ℹ️ No catnip ref to remove: %s", catnipRef)
 	}
+	// A deleted workspace no longer needs protecting from cleanup
+	_, _ = w.operations.ExecuteGit(repo.Path, "update-ref", "-d", KeepRefName(catnipRef))
+	// ...nor do the changes it was hibernated with
+	_, _ = w.operations.ExecuteGit(repo.Path, "update-ref", "-d", HibernateRefName(worktree.ID))
 
 	// Step 4: Remove preview branch if it exists
 	previewBranchName := fmt.Sprintf("catnip/%s", workspaceName)
@@ -498,6 +502,12 @@ type FileDiff struct {
 	NewContent string `json:"new_content,omitempty"`
 	DiffText   string `json:"diff_text,omitempty"`
 	IsExpanded bool   `json:"is_expanded"` // Default expansion state
+	// Review state: blob hash of the current contents and who has reviewed them
+	BlobHash   string   `json:"blob_hash,omitempty"`
+	Reviewed   bool     `json:"reviewed"`
+	ReviewedBy []string `json:"reviewed_by,omitempty"`
+	// Diff ignore rule matching the file (e.g. a lockfile or generated code)
+	IgnoredBy string `json:"ignored_by,omitempty"`
 }
 
 // WorktreeDiffResponse represents the diff response for a worktree
@@ -509,6 +519,35 @@ type WorktreeDiffResponse struct {
 	FileDiffs    []FileDiff `json:"file_diffs"`
 	TotalFiles   int        `json:"total_files"`
 	Summary      string     `json:"summary"`
+	// Changed files hidden by diff ignore rules, without their contents
+	IgnoredFiles []FileDiff `json:"ignored_files,omitempty"`
+	// Review progress of the requesting reviewer
+	Review *models.ReviewProgress `json:"review,omitempty"`
+	// Tests likely affected by the changes, when requested
+	TestImpact *models.TestImpact `json:"test_impact,omitempty"`
+	// Whether the file list hit the diff size limit
+	truncated bool
+}
+
+// Summarize sets TotalFiles and Summary from the file lists
+func (d *WorktreeDiffResponse) Summarize() {
+	d.TotalFiles = len(d.FileDiffs)
+	switch d.TotalFiles {
+	case 0:
+		d.Summary = "No changes"
+	case 1:
+		d.Summary = "1 file changed"
+	default:
+		d.Summary = fmt.Sprintf("%d files changed", d.TotalFiles)
+	}
+	if len(d.IgnoredFiles) > 0 {
+		d.Summary += fmt.Sprintf(", %d ignored", len(d.IgnoredFiles))
+	}
+
+	// Add warning if we hit the file limit
+	if d.truncated {
+		d.Summary += fmt.Sprintf(" (showing first %d files)", maxDiffFiles)
+	}
 }
 
 // GetWorktreeDiff calculates diff for a worktree against its source branch
@@ -749,29 +788,13 @@ func (w *WorktreeManager) GetWorktreeDiff(worktree *models.Worktree, sourceRef s
 		}
 	}
 
-	// Generate summary
-	var summary string
-	totalFiles := len(fileDiffs)
-	switch totalFiles {
-	case 0:
-		summary = "No changes"
-	case 1:
-		summary = "1 file changed"
-	default:
-		summary = fmt.Sprintf("%d files changed", totalFiles)
-	}
-
-	// Add warning if we hit the file limit
-	if totalFiles >= maxDiffFiles {
-		summary += fmt.Sprintf(" (showing first %d files)", maxDiffFiles)
-	}
-
-	return &WorktreeDiffResponse{
+	diff := &WorktreeDiffResponse{
 		WorktreeName: worktree.Name,
 		SourceBranch: worktree.SourceBranch,
 		ForkCommit:   forkCommit,
 		FileDiffs:    fileDiffs,
-		TotalFiles:   totalFiles,
-		Summary:      summary,
-	}, nil
+		truncated:    len(fileDiffs) >= maxDiffFiles,
+	}
+	diff.Summarize()
+	return diff, nil
 }
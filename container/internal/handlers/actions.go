@@ -0,0 +1,641 @@
+package handlers
+
+import (
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Action contexts: the kind of object an action operates on
+const (
+	ActionContextGlobal     = "global"
+	ActionContextWorktree   = "worktree"
+	ActionContextRepository = "repository"
+)
+
+// ActionParam describes one input to an action
+type ActionParam struct {
+	Name string `json:"name" example:"strategy"`
+	// Where the value goes: path, query or body
+	In          string   `json:"in" example:"body" enums:"path,query,body"`
+	Type        string   `json:"type" example:"string" enums:"string,integer,boolean,array"`
+	Required    bool     `json:"required"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	Default     string   `json:"default,omitempty"`
+}
+
+// Action is an operation the command palette can offer. Method and Path name
+// the API endpoint that performs it; {id} in the path is the worktree or
+// repository the action runs on.
+type Action struct {
+	ID          string        `json:"id" example:"worktree.sync"`
+	Title       string        `json:"title" example:"Sync with source branch"`
+	Description string        `json:"description,omitempty"`
+	Category    string        `json:"category" example:"git"`
+	Context     string        `json:"context" example:"worktree" enums:"global,worktree,repository"`
+	Method      string        `json:"method" example:"POST"`
+	Path        string        `json:"path" example:"/v1/git/worktrees/{id}/sync"`
+	Params      []ActionParam `json:"params,omitempty"`
+	Keywords    []string      `json:"keywords,omitempty"`
+	// Dangerous actions should be confirmed before running
+	Dangerous bool `json:"dangerous,omitempty"`
+
+	// available reports whether the action applies to the selected object,
+	// and why not when it doesn't. Nil means always available.
+	available func(ActionTarget) (bool, string)
+}
+
+// ActionTarget is the worktree or repository actions are listed for
+type ActionTarget struct {
+	Worktree   *models.Worktree
+	Repository *models.Repository
+}
+
+// AvailableAction is an action as listed for a target
+type AvailableAction struct {
+	Action
+	Available bool `json:"available"`
+	// Why the action is unavailable
+	Reason string `json:"reason,omitempty" example:"No pull request to update"`
+	// Path with {id} filled in from the target
+	Href string `json:"href,omitempty" example:"/v1/git/worktrees/abc123/sync"`
+}
+
+// ActionsResponse lists the actions for a target
+type ActionsResponse struct {
+	WorktreeID   string            `json:"worktree_id,omitempty"`
+	RepositoryID string            `json:"repository_id,omitempty"`
+	Actions      []AvailableAction `json:"actions"`
+}
+
+// actionTargetSource looks up the objects actions are listed for
+type actionTargetSource interface {
+	GetWorktree(worktreeID string) (*models.Worktree, bool)
+	GetRepositoryByID(repoID string) *models.Repository
+}
+
+// ActionsHandler serves the action registry the command palette and the
+// desktop quick-switcher are built from
+type ActionsHandler struct {
+	targets actionTargetSource
+	mu      sync.RWMutex
+	actions []Action
+}
+
+// NewActionsHandler creates an actions handler with the built-in actions registered
+func NewActionsHandler(targets actionTargetSource) *ActionsHandler {
+	h := &ActionsHandler{targets: targets}
+	h.Register(builtinActions()...)
+	return h
+}
+
+// Register adds actions to the registry, replacing any with the same ID
+func (h *ActionsHandler) Register(actions ...Action) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, action := range actions {
+		replaced := false
+		for i := range h.actions {
+			if h.actions[i].ID == action.ID {
+				h.actions[i] = action
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			h.actions = append(h.actions, action)
+		}
+	}
+}
+
+// actionPathParam matches {name} placeholders in action paths
+var actionPathParam = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// VerifyRoutes warns about registered actions whose endpoint isn't routed, so
+// a renamed endpoint doesn't leave a palette entry that always 404s. It
+// returns the IDs of those actions.
+func (h *ActionsHandler) VerifyRoutes(routes []fiber.Route) []string {
+	routed := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		routed[route.Method+" "+route.Path] = true
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var missing []string
+	for _, action := range h.actions {
+		path := actionPathParam.ReplaceAllString(action.Path, ":$1")
+		if !routed[action.Method+" "+path] {
+			logger.Warnf("⚠️ Action %s points at %s %s, which has no route", action.ID, action.Method, action.Path)
+			missing = append(missing, action.ID)
+		}
+	}
+	return missing
+}
+
+// ListActions returns the actions the command palette can offer
+// @Summary List command palette actions
+// @Description Returns the registered actions with their endpoints and parameter schemas. Without a target only global actions are listed; with worktree_id or repo_id the actions for that worktree or repository are listed too, each marked available or not with the reason.
+// @Tags actions
+// @Produce json
+// @Param worktree_id query string false "List actions for this worktree"
+// @Param repo_id query string false "List actions for this repository (defaults to the worktree's repository)"
+// @Param context query string false "Only list actions for this context" Enums(global,worktree,repository)
+// @Success 200 {object} ActionsResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/actions [get]
+func (h *ActionsHandler) ListActions(c *fiber.Ctx) error {
+	var target ActionTarget
+	response := ActionsResponse{Actions: []AvailableAction{}}
+
+	if worktreeID := c.Query("worktree_id"); worktreeID != "" {
+		worktree, exists := h.targets.GetWorktree(worktreeID)
+		if !exists {
+			return respondError(c, 404, models.NewWorktreeNotFoundError(worktreeID))
+		}
+		target.Worktree = worktree
+		response.WorktreeID = worktree.ID
+	}
+	repoID := c.Query("repo_id")
+	if repoID == "" && target.Worktree != nil {
+		repoID = target.Worktree.RepoID
+	}
+	if repoID != "" {
+		repo := h.targets.GetRepositoryByID(repoID)
+		if repo == nil {
+			return respondError(c, 404, models.NewRepositoryNotFoundError(repoID))
+		}
+		target.Repository = repo
+		response.RepositoryID = repo.ID
+	}
+
+	contextFilter := c.Query("context")
+	h.mu.RLock()
+	for _, action := range h.actions {
+		if contextFilter != "" && action.Context != contextFilter {
+			continue
+		}
+		if listed, ok := listAction(action, target); ok {
+			response.Actions = append(response.Actions, listed)
+		}
+	}
+	h.mu.RUnlock()
+
+	sort.SliceStable(response.Actions, func(i, j int) bool {
+		return response.Actions[i].Category < response.Actions[j].Category
+	})
+	return c.JSON(response)
+}
+
+// listAction resolves an action against a target. Actions for a context
+// without a selected object aren't listed.
+func listAction(action Action, target ActionTarget) (AvailableAction, bool) {
+	listed := AvailableAction{Action: action, Available: true}
+	switch action.Context {
+	case ActionContextWorktree:
+		if target.Worktree == nil {
+			return listed, false
+		}
+		listed.Href = strings.Replace(action.Path, "{id}", url.PathEscape(target.Worktree.ID), 1)
+	case ActionContextRepository:
+		if target.Repository == nil {
+			return listed, false
+		}
+		listed.Href = strings.Replace(action.Path, "{id}", url.QueryEscape(target.Repository.ID), 1)
+	default:
+		if !actionPathParam.MatchString(action.Path) {
+			listed.Href = action.Path
+		}
+	}
+	if action.available != nil {
+		listed.Available, listed.Reason = action.available(target)
+	}
+	return listed, true
+}
+
+// actionIDParam is the path parameter of worktree and repository actions
+func actionIDParam(description string) ActionParam {
+	return ActionParam{Name: "id", In: "path", Type: "string", Required: true, Description: description}
+}
+
+// builtinActions are the actions backed by catnip's own endpoints
+func builtinActions() []Action {
+	worktreeID := actionIDParam("Worktree ID")
+	repoID := actionIDParam("Repository ID")
+
+	return []Action{
+		// Global
+		{
+			ID:          "repository.checkout",
+			Title:       "Check out repository",
+			Description: "Clone a GitHub repository and create a worktree for it",
+			Category:    "git",
+			Context:     ActionContextGlobal,
+			Method:      "POST",
+			Path:        "/v1/git/checkout/{org}/{repo}",
+			Params: []ActionParam{
+				{Name: "org", In: "path", Type: "string", Required: true, Description: "GitHub organization or user"},
+				{Name: "repo", In: "path", Type: "string", Required: true, Description: "Repository name"},
+				{Name: "branch", In: "query", Type: "string", Description: "Branch to check out"},
+			},
+			Keywords: []string{"clone", "new", "open"},
+		},
+		{
+			ID:          "worktrees.cleanup",
+			Title:       "Clean up merged worktrees",
+			Description: "Delete worktrees whose branches were merged",
+			Category:    "git",
+			Context:     ActionContextGlobal,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/cleanup",
+			Params: []ActionParam{
+				{Name: "label", In: "query", Type: "string", Description: "Label selector, e.g. experiment,!keep"},
+				{Name: "standby", In: "query", Type: "boolean", Description: "Replace each removed worktree with a fresh one"},
+			},
+			Keywords:  []string{"prune", "delete", "merged"},
+			Dangerous: true,
+		},
+		{
+			ID:       "mirrors.sync",
+			Title:    "Sync repository mirrors",
+			Category: "git",
+			Context:  ActionContextGlobal,
+			Method:   "POST",
+			Path:     "/v1/git/mirrors/sync",
+			Keywords: []string{"fetch", "offline"},
+		},
+		{
+			ID:       "templates.sync",
+			Title:    "Sync templates",
+			Category: "git",
+			Context:  ActionContextGlobal,
+			Method:   "POST",
+			Path:     "/v1/git/templates/sync",
+		},
+		{
+			ID:          "backups.create",
+			Title:       "Back up volume state",
+			Description: "Snapshot state, settings and repository refs to CATNIP_BACKUP_URL",
+			Category:    "workspace",
+			Context:     ActionContextGlobal,
+			Method:      "POST",
+			Path:        "/v1/backups",
+			Keywords:    []string{"snapshot", "save"},
+		},
+		{
+			ID:       "outbox.flush",
+			Title:    "Retry queued GitHub operations",
+			Category: "workspace",
+			Context:  ActionContextGlobal,
+			Method:   "POST",
+			Path:     "/v1/outbox/flush",
+			Keywords: []string{"outbox", "offline", "retry"},
+		},
+		{
+			ID:          "admin.reload",
+			Title:       "Reload settings",
+			Description: "Re-read CATNIP_* settings without restarting",
+			Category:    "workspace",
+			Context:     ActionContextGlobal,
+			Method:      "POST",
+			Path:        "/v1/admin/reload",
+			Keywords:    []string{"config", "env"},
+		},
+
+		// Worktree
+		{
+			ID:          "worktree.sync",
+			Title:       "Sync with source branch",
+			Description: "Bring in new commits from the branch the worktree was created from",
+			Category:    "git",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/sync",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "strategy", In: "body", Type: "string", Enum: []string{"rebase", "merge"}, Default: "rebase"},
+			},
+			Keywords: []string{"rebase", "update", "pull"},
+		},
+		{
+			ID:          "worktree.sync.undo",
+			Title:       "Undo last sync",
+			Description: "Reset the worktree to where it was before the last sync",
+			Category:    "git",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/sync/undo",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "force", In: "body", Type: "boolean", Description: "Undo even if commits were made after the sync"},
+			},
+			Keywords: []string{"revert", "rollback"},
+			available: func(target ActionTarget) (bool, string) {
+				if target.Worktree.LastSync == nil {
+					return false, "No sync to undo"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:       "worktree.fetch",
+			Title:    "Fetch source branch",
+			Category: "git",
+			Context:  ActionContextWorktree,
+			Method:   "POST",
+			Path:     "/v1/git/worktrees/{id}/fetch",
+			Params:   []ActionParam{worktreeID},
+		},
+		{
+			ID:          "worktree.merge",
+			Title:       "Merge into source branch",
+			Description: "Merge the worktree's commits into the branch it was created from",
+			Category:    "git",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/merge",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "squash", In: "body", Type: "boolean", Description: "Squash the commits into one"},
+				{Name: "auto_cleanup", In: "query", Type: "boolean", Description: "Delete the worktree after merging"},
+			},
+			Keywords: []string{"land", "ship"},
+			available: func(target ActionTarget) (bool, string) {
+				switch {
+				case target.Worktree.CommitCount == 0:
+					return false, "No commits to merge"
+				case target.Worktree.HasConflicts:
+					return false, "Resolve conflicts first"
+				case target.Worktree.IsDirty:
+					return false, "Commit or stash changes first"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:       "worktree.pr.create",
+			Title:    "Create pull request",
+			Category: "github",
+			Context:  ActionContextWorktree,
+			Method:   "POST",
+			Path:     "/v1/git/worktrees/{id}/pr",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "title", In: "body", Type: "string", Required: true},
+				{Name: "body", In: "body", Type: "string"},
+				{Name: "force_push", In: "body", Type: "boolean"},
+			},
+			Keywords: []string{"pr", "review"},
+			available: func(target ActionTarget) (bool, string) {
+				switch {
+				case target.Worktree.PullRequestURL != "":
+					return false, "Worktree already has a pull request"
+				case target.Worktree.CommitCount == 0:
+					return false, "No commits to open a pull request for"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:       "worktree.pr.update",
+			Title:    "Update pull request",
+			Category: "github",
+			Context:  ActionContextWorktree,
+			Method:   "PUT",
+			Path:     "/v1/git/worktrees/{id}/pr",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "title", In: "body", Type: "string"},
+				{Name: "body", In: "body", Type: "string"},
+				{Name: "force_push", In: "body", Type: "boolean"},
+			},
+			Keywords: []string{"pr", "push"},
+			available: func(target ActionTarget) (bool, string) {
+				if target.Worktree.PullRequestURL == "" {
+					return false, "No pull request to update"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:          "worktree.pr.sync",
+			Title:       "Pull new pushes to the pull request",
+			Description: "Move the worktree to the pull request's latest head",
+			Category:    "github",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/pr/sync",
+			Params:      []ActionParam{worktreeID},
+			Keywords:    []string{"pr", "review", "update"},
+			available: func(target ActionTarget) (bool, string) {
+				if target.Worktree.SourcePullRequest == nil {
+					return false, "Worktree was not created from a pull request"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:       "worktree.issue.link",
+			Title:    "Link GitHub issue",
+			Category: "github",
+			Context:  ActionContextWorktree,
+			Method:   "PUT",
+			Path:     "/v1/git/worktrees/{id}/issue",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "number", In: "body", Type: "integer", Required: true, Description: "Issue number"},
+			},
+			Keywords: []string{"issue", "ticket"},
+		},
+		{
+			ID:       "worktree.issue.unlink",
+			Title:    "Unlink GitHub issue",
+			Category: "github",
+			Context:  ActionContextWorktree,
+			Method:   "DELETE",
+			Path:     "/v1/git/worktrees/{id}/issue",
+			Params:   []ActionParam{worktreeID},
+			available: func(target ActionTarget) (bool, string) {
+				if target.Worktree.LinkedIssue == nil {
+					return false, "No issue is linked"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:          "worktree.graduate",
+			Title:       "Rename branch",
+			Description: "Give the worktree's branch a descriptive name",
+			Category:    "git",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/graduate",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "branch_name", In: "body", Type: "string", Description: "Branch name; generated when empty"},
+			},
+			Keywords: []string{"branch", "name", "graduate"},
+		},
+		{
+			ID:       "worktree.labels",
+			Title:    "Set labels",
+			Category: "workspace",
+			Context:  ActionContextWorktree,
+			Method:   "PUT",
+			Path:     "/v1/git/worktrees/{id}/labels",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "labels", In: "body", Type: "array", Required: true},
+			},
+			Keywords: []string{"tag"},
+		},
+		{
+			ID:          "worktree.memory.add",
+			Title:       "Remember for future sessions",
+			Description: "Give every new Claude session in the worktree a fact, decision or convention",
+			Category:    "claude",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/memory",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "text", In: "body", Type: "string", Required: true},
+				{Name: "kind", In: "body", Type: "string", Enum: []string{"fact", "decision", "convention"}, Default: "fact"},
+			},
+			Keywords: []string{"memory", "remember", "note"},
+		},
+		{
+			ID:          "worktree.locks.add",
+			Title:       "Lock paths",
+			Description: "Keep Claude from modifying files you're working on in the worktree",
+			Category:    "claude",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/locks",
+			Params: []ActionParam{
+				worktreeID,
+				{Name: "paths", In: "body", Type: "array", Required: true},
+				{Name: "reason", In: "body", Type: "string", Required: true},
+				{Name: "ttl_minutes", In: "body", Type: "integer"},
+			},
+			Keywords: []string{"lock", "protect", "freeze", "editing"},
+		},
+		{
+			ID:       "worktree.setup.rerun",
+			Title:    "Re-run setup",
+			Category: "workspace",
+			Context:  ActionContextWorktree,
+			Method:   "POST",
+			Path:     "/v1/git/worktrees/{id}/setup/rerun",
+			Params:   []ActionParam{worktreeID},
+			Keywords: []string{"setup.sh", "install"},
+		},
+		{
+			ID:          "worktree.preview",
+			Title:       "Create preview",
+			Description: "Create a preview branch in the main repository to view changes outside the container",
+			Category:    "git",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/preview",
+			Params:      []ActionParam{worktreeID},
+		},
+		{
+			ID:          "worktree.standby",
+			Title:       "Create standby worktree",
+			Description: "Start a fresh worktree from the same source branch",
+			Category:    "workspace",
+			Context:     ActionContextWorktree,
+			Method:      "POST",
+			Path:        "/v1/git/worktrees/{id}/standby",
+			Params:      []ActionParam{worktreeID},
+			Keywords:    []string{"new", "fresh"},
+		},
+		{
+			ID:       "worktree.delete",
+			Title:    "Delete worktree",
+			Category: "workspace",
+			Context:  ActionContextWorktree,
+			Method:   "DELETE",
+			Path:     "/v1/git/worktrees/{id}",
+			Params:   []ActionParam{worktreeID},
+			Keywords: []string{"remove", "close"},
+			// Deleting discards uncommitted changes and unmerged commits
+			Dangerous: true,
+		},
+
+		// Repository
+		{
+			ID:          "repository.worktree.from_pr",
+			Title:       "Check out pull request",
+			Description: "Create a worktree from a pull request and follow its pushes",
+			Category:    "github",
+			Context:     ActionContextRepository,
+			Method:      "POST",
+			Path:        "/v1/git/repositories/{id}/worktrees/from-pr",
+			Params: []ActionParam{
+				repoID,
+				{Name: "number", In: "body", Type: "integer", Required: true, Description: "Pull request number"},
+			},
+			Keywords: []string{"pr", "review"},
+			available: func(target ActionTarget) (bool, string) {
+				if !target.Repository.HasGitHubRemote {
+					return false, "Repository has no GitHub remote"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:          "repository.github.create",
+			Title:       "Publish to GitHub",
+			Description: "Create a GitHub repository for a local repository and push it",
+			Category:    "github",
+			Context:     ActionContextRepository,
+			Method:      "POST",
+			Path:        "/v1/git/repositories/{id}/github",
+			Params: []ActionParam{
+				repoID,
+				{Name: "name", In: "body", Type: "string", Required: true},
+				{Name: "description", In: "body", Type: "string"},
+				{Name: "is_private", In: "body", Type: "boolean"},
+			},
+			Keywords: []string{"publish", "push", "remote"},
+			available: func(target ActionTarget) (bool, string) {
+				if target.Repository.HasGitHubRemote {
+					return false, "Repository is already on GitHub"
+				}
+				return true, ""
+			},
+		},
+		{
+			ID:          "repository.dependency_updates",
+			Title:       "Update dependencies",
+			Description: "Have Claude update dependencies in a new worktree",
+			Category:    "claude",
+			Context:     ActionContextRepository,
+			Method:      "POST",
+			Path:        "/v1/git/repositories/{id}/dependency-updates",
+			Params:      []ActionParam{repoID},
+			Keywords:    []string{"deps", "upgrade", "bump"},
+		},
+		{
+			ID:       "repository.delete",
+			Title:    "Delete repository",
+			Category: "workspace",
+			Context:  ActionContextRepository,
+			Method:   "DELETE",
+			Path:     "/v1/git/repositories/{id}",
+			Params:   []ActionParam{repoID},
+			Keywords: []string{"remove"},
+			// Deleting a repository removes all its worktrees
+			Dangerous: true,
+		},
+	}
+}
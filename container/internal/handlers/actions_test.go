@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type fakeActionTargets struct {
+	worktrees    map[string]*models.Worktree
+	repositories map[string]*models.Repository
+}
+
+func (f *fakeActionTargets) GetWorktree(worktreeID string) (*models.Worktree, bool) {
+	worktree, exists := f.worktrees[worktreeID]
+	return worktree, exists
+}
+
+func (f *fakeActionTargets) GetRepositoryByID(repoID string) *models.Repository {
+	return f.repositories[repoID]
+}
+
+func listTestActions(t *testing.T, handler *ActionsHandler, query string) (int, map[string]AvailableAction) {
+	t.Helper()
+	app := fiber.New()
+	app.Get("/v1/actions", handler.ListActions)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/v1/actions"+query, nil))
+	require.NoError(t, err)
+	if resp.StatusCode != 200 {
+		return resp.StatusCode, nil
+	}
+	var response ActionsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	actions := make(map[string]AvailableAction, len(response.Actions))
+	for _, action := range response.Actions {
+		actions[action.ID] = action
+	}
+	return resp.StatusCode, actions
+}
+
+func TestListActions(t *testing.T) {
+	handler := NewActionsHandler(&fakeActionTargets{
+		worktrees: map[string]*models.Worktree{
+			"wt-1": {ID: "wt-1", RepoID: "local/app", CommitCount: 2, PullRequestURL: "https://github.com/acme/app/pull/3"},
+		},
+		repositories: map[string]*models.Repository{
+			"local/app": {ID: "local/app"},
+		},
+	})
+
+	_, actions := listTestActions(t, handler, "")
+	assert.Contains(t, actions, "worktrees.cleanup")
+	assert.NotContains(t, actions, "worktree.sync", "worktree actions need a worktree")
+	assert.Equal(t, "/v1/git/worktrees/cleanup", actions["worktrees.cleanup"].Href)
+	assert.Empty(t, actions["repository.checkout"].Href, "global actions with path params have no href")
+
+	_, actions = listTestActions(t, handler, "?worktree_id=wt-1")
+	sync := actions["worktree.sync"]
+	assert.True(t, sync.Available)
+	assert.Equal(t, "/v1/git/worktrees/wt-1/sync", sync.Href)
+	assert.Equal(t, []string{"rebase", "merge"}, sync.Params[1].Enum)
+
+	assert.False(t, actions["worktree.pr.create"].Available)
+	assert.True(t, actions["worktree.pr.update"].Available)
+	assert.False(t, actions["worktree.sync.undo"].Available)
+	assert.Equal(t, "No sync to undo", actions["worktree.sync.undo"].Reason)
+	assert.True(t, actions["worktree.delete"].Dangerous)
+
+	// The worktree's repository is the repository target
+	publish := actions["repository.github.create"]
+	assert.True(t, publish.Available)
+	assert.Equal(t, "/v1/git/repositories/local%2Fapp/github", publish.Href)
+
+	_, actions = listTestActions(t, handler, "?worktree_id=wt-1&context=repository")
+	assert.NotContains(t, actions, "worktree.sync")
+	assert.Contains(t, actions, "repository.delete")
+
+	status, _ := listTestActions(t, handler, "?worktree_id=missing")
+	assert.Equal(t, 404, status)
+}
+
+func TestActionsHandlerRegister(t *testing.T) {
+	handler := NewActionsHandler(&fakeActionTargets{})
+	handler.Register(
+		Action{ID: "worktrees.cleanup", Title: "Prune", Context: ActionContextGlobal, Method: "POST", Path: "/v1/git/worktrees/cleanup"},
+		Action{ID: "voice.speak", Title: "Read reply aloud", Context: ActionContextGlobal, Method: "POST", Path: "/v1/voice/speak"},
+	)
+
+	_, actions := listTestActions(t, handler, "?context=global")
+	assert.Equal(t, "Prune", actions["worktrees.cleanup"].Title, "registering an existing ID replaces it")
+	assert.Contains(t, actions, "voice.speak")
+}
+
+func TestActionsHandlerVerifyRoutes(t *testing.T) {
+	handler := &ActionsHandler{}
+	handler.Register(
+		Action{ID: "worktree.sync", Context: ActionContextWorktree, Method: "POST", Path: "/v1/git/worktrees/{id}/sync"},
+		Action{ID: "worktree.rename", Context: ActionContextWorktree, Method: "POST", Path: "/v1/git/worktrees/{id}/rename"},
+	)
+
+	app := fiber.New()
+	app.Post("/v1/git/worktrees/:id/sync", func(c *fiber.Ctx) error { return nil })
+	assert.Equal(t, []string{"worktree.rename"}, handler.VerifyRoutes(app.GetRoutes()))
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// AdminHandler handles operator endpoints for a running instance
+type AdminHandler struct {
+	configManager *services.ConfigManager
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(configManager *services.ConfigManager) *AdminHandler {
+	return &AdminHandler{
+		configManager: configManager,
+	}
+}
+
+// GetConfig reports the configuration overrides in effect
+// @Summary Get configuration overrides
+// @Description Returns the path of the overrides file (catnip.env in the volume), which CATNIP_* settings it currently overrides, the settings that apply without a restart, and the result of the last reload. Values are never returned.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} services.ConfigStatus
+// @Router /v1/admin/config [get]
+func (h *AdminHandler) GetConfig(c *fiber.Ctx) error {
+	return c.JSON(h.configManager.Status())
+}
+
+// ReloadConfig re-reads the configuration overrides
+// @Summary Reload configuration
+// @Description Re-reads catnip.env from the volume (KEY=VALUE lines of CATNIP_* settings) and applies it on top of the process environment. Services that support it, such as the repository concurrency limit, backup schedule and hygiene reports, are reconfigured in place; changed settings that are only read at startup are listed under restart_required. Removing a line restores the value from the environment. Settings read before the file is loaded, such as CATNIP_DEV and the workspace paths, must stay in the environment.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} services.ConfigReload
+// @Failure 500 {object} map[string]string
+// @Router /v1/admin/reload [post]
+func (h *AdminHandler) ReloadConfig(c *fiber.Ctx) error {
+	reload, err := h.configManager.Reload()
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(reload)
+}
+
+// GetLocale reports the time zone and locale schedules and reports use
+// @Summary Get time zone and locale
+// @Description Returns the configured time zone and locale (CATNIP_TIMEZONE and CATNIP_LOCALE) and the ones in effect. The nightly hygiene report runs at its hour in this time zone, agent cost reports bucket months in it, and report summaries format numbers in the locale. Unset values fall back to the container's TZ and LC_ALL/LANG.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} services.LocaleStatus
+// @Router /v1/admin/locale [get]
+func (h *AdminHandler) GetLocale(c *fiber.Ctx) error {
+	return c.JSON(services.CurrentLocale())
+}
+
+// UpdateLocale sets the time zone and locale
+// @Summary Set time zone and locale
+// @Description Stores the time zone (IANA name) and locale (BCP 47 tag) in catnip.env and reloads the configuration, which reschedules the nightly hygiene report. Empty values remove the setting, falling back to the container's TZ and LC_ALL/LANG.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param settings body services.LocaleSettings true "Time zone and locale"
+// @Success 200 {object} services.LocaleStatus
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/admin/locale [put]
+func (h *AdminHandler) UpdateLocale(c *fiber.Ctx) error {
+	var settings services.LocaleSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+	if err := settings.Validate(); err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+
+	if _, err := h.configManager.Set(map[string]string{
+		services.TimeZoneSetting: settings.TimeZone,
+		services.LocaleSetting:   settings.Locale,
+	}); err != nil {
+		return respondError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.JSON(services.CurrentLocale())
+}
+
+// ProfilesResponse lists the settings profiles
+type ProfilesResponse struct {
+	// Active profile, empty if none
+	Active   string                     `json:"active" example:"strict-safety"`
+	Profiles []services.SettingsProfile `json:"profiles"`
+}
+
+// ProfileRequest selects a settings profile
+type ProfileRequest struct {
+	// Profile name; empty clears the profile
+	Name string `json:"name" example:"strict-safety"`
+}
+
+// GetProfiles lists the settings profiles
+// @Summary List settings profiles
+// @Description Returns the built-in settings profiles (strict-safety, demo, power-user) and those in the profiles directory next to catnip.env (<name>.env files in the same format, whose leading comment is the description), with the settings each applies and which one is active. A profile's settings apply on top of the process environment and under catnip.env.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} ProfilesResponse
+// @Failure 500 {object} map[string]string
+// @Router /v1/admin/profiles [get]
+func (h *AdminHandler) GetProfiles(c *fiber.Ctx) error {
+	profiles, err := h.configManager.ListProfiles()
+	if err != nil {
+		return respondError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.JSON(ProfilesResponse{Active: h.configManager.Status().Profile, Profiles: profiles})
+}
+
+// UpdateProfile switches the settings profile
+// @Summary Switch settings profile
+// @Description Stores CATNIP_PROFILE in catnip.env and reloads the configuration, applying the profile's settings (checkpoint interval, Claude tool allowlist, cleanup rules, notification defaults) to the services that support reloading. The choice outlives restarts and wins over the --profile flag. An empty name clears the profile.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param profile body ProfileRequest true "Profile to use"
+// @Success 200 {object} services.ConfigReload
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/admin/profile [put]
+func (h *AdminHandler) UpdateProfile(c *fiber.Ctx) error {
+	var req ProfileRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+	if req.Name != "" {
+		if _, err := h.configManager.Profile(req.Name); err != nil {
+			return respondError(c, fiber.StatusBadRequest, err)
+		}
+	}
+
+	reload, err := h.configManager.Set(map[string]string{services.ProfileSetting: req.Name})
+	if err != nil {
+		return respondError(c, fiber.StatusInternalServerError, err)
+	}
+	return c.JSON(reload)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetWorktreeAgentCommits lists a worktree's commits that carry agent notes
+// @Summary List agent-authored commits
+// @Description Returns commits on the worktree's branch that catnip made for an agent (checkpoint and title commits), newest first, each with the note attached under refs/notes/catnip: agent and version, session ID, SHA-256 of the latest prompt, estimated session cost and the kind of commit. Commits without a note were made by a human. With commit set, only that commit is returned (or nothing if it has no note). Set CATNIP_PUSH_AGENT_NOTES=true to push the notes ref along with branches.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param commit query string false "Only look at this commit"
+// @Param limit query int false "Number of recent commits to search (default 50)"
+// @Success 200 {array} models.AgentCommit
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/agent-commits [get]
+func (h *GitHandler) GetWorktreeAgentCommits(c *fiber.Ctx) error {
+	commits, err := h.gitService.GetAgentCommits(c.Params("id"), c.Query("commit"), c.QueryInt("limit", 0))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(commits)
+}
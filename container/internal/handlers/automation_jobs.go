@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// AutomationJobsHandler handles endpoints for persisted Claude automations
+type AutomationJobsHandler struct {
+	jobs *services.AutomationJobService
+}
+
+// NewAutomationJobsHandler creates a new automation jobs handler
+func NewAutomationJobsHandler(jobs *services.AutomationJobService) *AutomationJobsHandler {
+	return &AutomationJobsHandler{
+		jobs: jobs,
+	}
+}
+
+// ListAutomationJobs returns running and recently finished automations
+// @Summary List Claude automations
+// @Description Returns running and recently finished Claude automations (API completions, dependency updates), newest first. Jobs interrupted by a restart are resumed on boot or marked abandoned.
+// @Tags claude
+// @Produce json
+// @Success 200 {array} services.AutomationJob
+// @Router /v1/claude/automations [get]
+func (h *AutomationJobsHandler) ListAutomationJobs(c *fiber.Ctx) error {
+	return c.JSON(h.jobs.List())
+}
+
+// GetAutomationJob returns one automation, including its result once finished
+// @Summary Get a Claude automation
+// @Description Returns an automation's status and result. Use the X-Automation-Job-ID header from /v1/claude/messages to collect a result after reconnecting.
+// @Tags claude
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} services.AutomationJob
+// @Failure 404 {object} map[string]string
+// @Router /v1/claude/automations/{id} [get]
+func (h *AutomationJobsHandler) GetAutomationJob(c *fiber.Ctx) error {
+	job, exists := h.jobs.Get(c.Params("id"))
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Automation job not found",
+		})
+	}
+	return c.JSON(job)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// BackupHandler handles remote backup endpoints
+type BackupHandler struct {
+	backupService *services.BackupService
+}
+
+// BackupListResponse reports backup status and the snapshots in the bucket
+type BackupListResponse struct {
+	Status    services.BackupStatus `json:"status"`
+	Snapshots []string              `json:"snapshots"`
+	Error     string                `json:"error,omitempty"` // Set when the bucket couldn't be listed
+}
+
+// NewBackupHandler creates a new backup handler
+func NewBackupHandler(backupService *services.BackupService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+	}
+}
+
+// ListBackups returns backup status and available snapshots
+// @Summary List volume backups
+// @Description Returns the backup schedule status and the snapshots stored at CATNIP_BACKUP_URL, newest first
+// @Tags backup
+// @Produce json
+// @Success 200 {object} BackupListResponse
+// @Router /v1/backups [get]
+func (h *BackupHandler) ListBackups(c *fiber.Ctx) error {
+	response := BackupListResponse{
+		Status:    h.backupService.Status(),
+		Snapshots: []string{},
+	}
+	if !h.backupService.Enabled() {
+		return c.JSON(response)
+	}
+
+	snapshots, err := h.backupService.List()
+	if err != nil {
+		response.Error = err.Error()
+	} else if snapshots != nil {
+		response.Snapshots = snapshots
+	}
+	return c.JSON(response)
+}
+
+// CreateBackup uploads a snapshot immediately
+// @Summary Back up volume state now
+// @Description Snapshots state.json, settings, session metadata and bare repository refs and uploads them to CATNIP_BACKUP_URL
+// @Tags backup
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string "Backups not configured"
+// @Failure 500 {object} map[string]string "Backup failed"
+// @Router /v1/backups [post]
+func (h *BackupHandler) CreateBackup(c *fiber.Ctx) error {
+	if !h.backupService.Enabled() {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Backups are not configured (set CATNIP_BACKUP_URL)",
+		})
+	}
+
+	key, err := h.backupService.Backup()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"key": key,
+	})
+}
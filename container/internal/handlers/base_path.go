@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StripBasePath lets catnip run behind a reverse proxy that forwards a path
+// prefix (e.g. /catnip/) without rewriting it. Requests under basePath are
+// routed as if they came in at the root, so API routes, WebSocket upgrades
+// and assets all work unchanged; "/health" is still answered at the root for
+// the proxy's health checks.
+func StripBasePath(basePath string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		path := c.Path()
+		switch {
+		case path == basePath:
+			target := basePath + "/"
+			if query := string(c.Request().URI().QueryString()); query != "" {
+				target += "?" + query
+			}
+			return c.Redirect(target, fiber.StatusMovedPermanently)
+		case strings.HasPrefix(path, basePath+"/"):
+			c.Path(strings.TrimPrefix(path, basePath))
+			return c.Next()
+		case path == "/health":
+			return c.Next()
+		}
+		return c.Status(fiber.StatusNotFound).SendString(fmt.Sprintf("Not found - catnip is served under %s/", basePath))
+	}
+}
+
+// rootRelativeAttr matches src/href attributes with root-relative URLs,
+// skipping protocol-relative ones ("//cdn...")
+var rootRelativeAttr = regexp.MustCompile(`\b(src|href)="/([^/"][^"]*)?"`)
+
+// rewriteIndexHTML prefixes the frontend's root-relative asset URLs with
+// basePath and tells the app where it is mounted via window.__CATNIP_BASE_PATH__
+func rewriteIndexHTML(data []byte, basePath string) []byte {
+	if basePath == "" {
+		return data
+	}
+	data = rootRelativeAttr.ReplaceAll(data, []byte(`$1="`+basePath+`/$2"`))
+
+	script := []byte(fmt.Sprintf(`<script>window.__CATNIP_BASE_PATH__ = %q;</script>`, basePath))
+	if i := bytes.Index(data, []byte("<head>")); i >= 0 {
+		i += len("<head>")
+		return append(data[:i:i], append(script, data[i:]...)...)
+	}
+	return append(script, data...)
+}
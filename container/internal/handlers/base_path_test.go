@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+func TestStripBasePath(t *testing.T) {
+	app := fiber.New()
+	app.Use(StripBasePath("/catnip"))
+	app.Get("/health", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	app.Get("/v1/info", func(c *fiber.Ctx) error { return c.SendString("info " + c.Path()) })
+	app.Get("/", func(c *fiber.Ctx) error { return c.SendString("index") })
+
+	get := func(path string) (int, string, string) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), resp.Header.Get("Location")
+	}
+
+	status, body, _ := get("/catnip/v1/info")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "info /v1/info", body)
+
+	status, body, _ = get("/catnip/")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "index", body)
+
+	status, _, location := get("/catnip?x=1")
+	assert.Equal(t, 301, status)
+	assert.Equal(t, "/catnip/?x=1", location)
+
+	status, _, _ = get("/v1/info")
+	assert.Equal(t, 404, status)
+	status, _, _ = get("/catnipper/v1/info")
+	assert.Equal(t, 404, status)
+
+	status, body, _ = get("/health")
+	assert.Equal(t, 200, status)
+	assert.Equal(t, "ok", body)
+}
+
+func TestRewriteIndexHTML(t *testing.T) {
+	html := []byte(`<html><head><link rel="icon" href="/favicon.ico"><script type="module" src="/assets/index.js"></script><link href="//cdn.example.com/x.css"></head><body><a href="https://example.com/">x</a></body></html>`)
+
+	assert.Equal(t, html, rewriteIndexHTML(html, ""))
+
+	rewritten := string(rewriteIndexHTML(html, "/catnip"))
+	assert.Contains(t, rewritten, `<head><script>window.__CATNIP_BASE_PATH__ = "/catnip";</script>`)
+	assert.Contains(t, rewritten, `href="/catnip/favicon.ico"`)
+	assert.Contains(t, rewritten, `src="/catnip/assets/index.js"`)
+	assert.Contains(t, rewritten, `href="//cdn.example.com/x.css"`)
+	assert.Contains(t, rewritten, `href="https://example.com/"`)
+}
+
+func TestPublicURL(t *testing.T) {
+	rc := &config.RuntimeConfig{Port: "8080"}
+	rc.SetPublicAddress("catnip/", "")
+	assert.Equal(t, "/catnip", rc.BasePath)
+	assert.Equal(t, "http://localhost:8080/catnip/workspace/app/main", rc.PublicURL("/workspace/app/main"))
+	assert.False(t, rc.HasExternalURL())
+
+	// The base path defaults to the external URL's path
+	rc = &config.RuntimeConfig{}
+	rc.SetPublicAddress("", "https://dev.example.com/tools/catnip/")
+	assert.Equal(t, "/tools/catnip", rc.BasePath)
+	assert.Equal(t, "https://dev.example.com/tools/catnip/workspace/app/main", rc.PublicURL("workspace/app/main"))
+
+	rc.SetPublicAddress("/", "")
+	assert.Equal(t, "", rc.BasePath)
+	assert.Equal(t, "http://localhost:6369/", rc.PublicURL("/"))
+}
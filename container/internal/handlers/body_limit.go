@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RouteBodyLimits raises the request body limit for specific routes, such as
+// audio uploads, while every other request keeps the server's default.
+// Routes are matched by path suffix so they still apply under a base path.
+// Set it as the server's HeaderReceived hook: the limit has to be chosen
+// before the body is read.
+func RouteBodyLimits(limits map[string]int) func(*fasthttp.RequestHeader) fasthttp.RequestConfig {
+	return func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		path, _, _ := strings.Cut(string(header.RequestURI()), "?")
+		for route, limit := range limits {
+			if strings.HasSuffix(path, route) {
+				return fasthttp.RequestConfig{MaxRequestBodySize: limit}
+			}
+		}
+		return fasthttp.RequestConfig{}
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+)
+
+func TestRouteBodyLimits(t *testing.T) {
+	app := fiber.New(fiber.Config{BodyLimit: 1024})
+	app.Server().HeaderReceived = RouteBodyLimits(map[string]int{"/v1/voice/transcribe": 4096})
+	app.Post("/*", func(c *fiber.Ctx) error {
+		return c.SendString(c.Path())
+	})
+
+	post := func(path string, size int) error {
+		req := httptest.NewRequest("POST", path, bytes.NewReader(make([]byte, size)))
+		resp, err := app.Test(req)
+		if err == nil {
+			assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+		}
+		return err
+	}
+
+	require.NoError(t, post("/v1/voice/transcribe?language=en", 2048))
+	require.NoError(t, post("/catnip/v1/voice/transcribe", 2048), "routes match under a base path")
+	assert.ErrorIs(t, post("/v1/voice/transcribe", 8192), fasthttp.ErrBodyTooLarge)
+	require.NoError(t, post("/v1/git/checkout", 512))
+	assert.ErrorIs(t, post("/v1/git/checkout", 2048), fasthttp.ErrBodyTooLarge, "other routes keep the default limit")
+}
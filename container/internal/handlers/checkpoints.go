@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// CheckpointPolicyResponse describes the automatic checkpoint settings of a
+// repository or worktree
+type CheckpointPolicyResponse struct {
+	// Built-in defaults (interval from CATNIP_COMMIT_TIMEOUT_SECONDS)
+	Defaults models.CheckpointSettings `json:"defaults"`
+	// Repository override (null if the repository uses the defaults)
+	Repository *models.CheckpointSettings `json:"repository"`
+	// Worktree override (null if the worktree inherits the repository's settings)
+	Worktree *models.CheckpointSettings `json:"worktree,omitempty"`
+	// Settings applied to checkpoint commits
+	Effective models.CheckpointSettings `json:"effective"`
+}
+
+// GetWorktreeCheckpointPolicy returns the checkpoint settings that apply to a worktree
+// @Summary Get worktree checkpoint policy
+// @Description Returns the default, repository, worktree and effective settings for the automatic checkpoint commits made while an agent works in the worktree
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} CheckpointPolicyResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/checkpoints [get]
+func (h *GitHandler) GetWorktreeCheckpointPolicy(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		return respondError(c, 404, models.NewWorktreeNotFoundError(worktreeID))
+	}
+
+	return c.JSON(h.worktreeCheckpointPolicyResponse(worktree, h.gitService.EffectiveCheckpointPolicy(worktree)))
+}
+
+// UpdateWorktreeCheckpointPolicy overrides checkpoint settings for a worktree
+// @Summary Set worktree checkpoint policy
+// @Description Overrides the checkpoint interval, dirty file limit, commit message template or disables checkpoints for one worktree. Omitted fields inherit the repository's settings; an empty body clears the override.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param checkpoints body models.CheckpointSettings true "Checkpoint settings"
+// @Success 200 {object} CheckpointPolicyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/checkpoints [put]
+func (h *GitHandler) UpdateWorktreeCheckpointPolicy(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	override, err := parseCheckpointSettings(c)
+	if err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body: %v", err))
+	}
+
+	effective, err := h.gitService.SetWorktreeCheckpointSettings(worktreeID, override)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		return respondError(c, 404, models.NewWorktreeNotFoundError(worktreeID))
+	}
+	return c.JSON(h.worktreeCheckpointPolicyResponse(worktree, effective))
+}
+
+func (h *GitHandler) worktreeCheckpointPolicyResponse(worktree *models.Worktree, effective git.CheckpointPolicy) CheckpointPolicyResponse {
+	repoOverride, _, _ := h.gitService.GetRepositoryCheckpointSettings(worktree.RepoID)
+	return CheckpointPolicyResponse{
+		Defaults:   git.DefaultCheckpointPolicy().Settings(),
+		Repository: repoOverride,
+		Worktree:   worktree.Checkpoints,
+		Effective:  effective.Settings(),
+	}
+}
+
+// GetRepositoryCheckpointPolicy returns a repository's checkpoint settings
+// @Summary Get repository checkpoint policy
+// @Description Returns the default, overridden and effective checkpoint settings inherited by a repository's worktrees
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Success 200 {object} CheckpointPolicyResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/checkpoints [get]
+func (h *GitHandler) GetRepositoryCheckpointPolicy(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid repository ID: %v", err))
+	}
+
+	override, effective, err := h.gitService.GetRepositoryCheckpointSettings(repoID)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(CheckpointPolicyResponse{
+		Defaults:   git.DefaultCheckpointPolicy().Settings(),
+		Repository: override,
+		Effective:  effective.Settings(),
+	})
+}
+
+// UpdateRepositoryCheckpointPolicy overrides checkpoint settings for a repository
+// @Summary Set repository checkpoint policy
+// @Description Overrides the checkpoint interval, dirty file limit, commit message template or disables checkpoints for a repository's worktrees, so projects with slow test suites or huge diffs can checkpoint less often. Omitted fields inherit the defaults; an empty body clears the override.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param checkpoints body models.CheckpointSettings true "Checkpoint settings"
+// @Success 200 {object} CheckpointPolicyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/checkpoints [put]
+func (h *GitHandler) UpdateRepositoryCheckpointPolicy(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid repository ID: %v", err))
+	}
+
+	override, err := parseCheckpointSettings(c)
+	if err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body: %v", err))
+	}
+
+	effective, err := h.gitService.SetRepositoryCheckpointSettings(repoID, override)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(CheckpointPolicyResponse{
+		Defaults:   git.DefaultCheckpointPolicy().Settings(),
+		Repository: override,
+		Effective:  effective.Settings(),
+	})
+}
+
+// parseCheckpointSettings reads checkpoint settings from the request body,
+// returning nil for an empty object so the override is cleared
+func parseCheckpointSettings(c *fiber.Ctx) (*models.CheckpointSettings, error) {
+	var settings models.CheckpointSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return nil, err
+	}
+	if settings == (models.CheckpointSettings{}) {
+		return nil, nil
+	}
+	return &settings, nil
+}
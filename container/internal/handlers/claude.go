@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -18,6 +19,11 @@ type ClaudeHandler struct {
 	eventsHandler           *EventsHandler
 	claudeOnboardingService *services.ClaudeOnboardingService
 	ptyHandler              *PTYHandler
+	feedbackService         *services.FeedbackService
+	promptLinter            *services.PromptLinter
+	automationJobs          *services.AutomationJobService
+	sessionIntegrity        *services.SessionIntegrityService
+	transcripts             *services.TranscriptIndex
 }
 
 // NewClaudeHandler creates a new Claude handler
@@ -46,6 +52,31 @@ func (h *ClaudeHandler) WithPTYHandler(ptyHandler *PTYHandler) *ClaudeHandler {
 	return h
 }
 
+// WithFeedbackService adds the feedback service for rating completions
+func (h *ClaudeHandler) WithFeedbackService(feedbackService *services.FeedbackService) *ClaudeHandler {
+	h.feedbackService = feedbackService
+	return h
+}
+
+// WithPromptLinter adds pre-flight prompt checks to completions
+func (h *ClaudeHandler) WithPromptLinter(promptLinter *services.PromptLinter) *ClaudeHandler {
+	h.promptLinter = promptLinter
+	return h
+}
+
+// WithAutomationJobs persists non-streaming completions so they can be
+// resumed after a restart
+func (h *ClaudeHandler) WithAutomationJobs(automationJobs *services.AutomationJobService) *ClaudeHandler {
+	h.automationJobs = automationJobs
+	return h
+}
+
+// WithSessionIntegrity enables the session file integrity check and repair endpoints
+func (h *ClaudeHandler) WithSessionIntegrity(sessionIntegrity *services.SessionIntegrityService) *ClaudeHandler {
+	h.sessionIntegrity = sessionIntegrity
+	return h
+}
+
 // GetWorktreeSessionSummary returns Claude session information for a specific worktree
 // @Summary Get worktree session summary
 // @Description Returns Claude Code session metadata for a specific worktree
@@ -57,22 +88,16 @@ func (h *ClaudeHandler) WithPTYHandler(ptyHandler *PTYHandler) *ClaudeHandler {
 func (h *ClaudeHandler) GetWorktreeSessionSummary(c *fiber.Ctx) error {
 	worktreePath := c.Query("worktree_path")
 	if worktreePath == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "worktree_path query parameter is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree_path query parameter is required"))
 	}
 
 	summary, err := h.claudeService.GetWorktreeSessionSummary(worktreePath)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	if summary == nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": "No Claude session found for this worktree",
-		})
+		return respondError(c, 404, models.NewAPIError(models.ErrCodeSessionNotFound, "No Claude session found for this worktree"))
 	}
 
 	return c.JSON(summary)
@@ -88,9 +113,7 @@ func (h *ClaudeHandler) GetWorktreeSessionSummary(c *fiber.Ctx) error {
 func (h *ClaudeHandler) GetAllWorktreeSessionSummaries(c *fiber.Ctx) error {
 	summaries, err := h.claudeService.GetAllWorktreeSessionSummaries()
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(summaries)
@@ -107,23 +130,15 @@ func (h *ClaudeHandler) GetAllWorktreeSessionSummaries(c *fiber.Ctx) error {
 func (h *ClaudeHandler) GetSessionByUUID(c *fiber.Ctx) error {
 	sessionUUID := c.Params("uuid")
 	if sessionUUID == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "session UUID is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "session UUID is required"))
 	}
 
 	sessionData, err := h.claudeService.GetSessionByUUID(sessionUUID)
 	if err != nil {
-		if err.Error() == "session not found: "+sessionUUID {
-			return c.Status(404).JSON(fiber.Map{
-				"error": "Session not found",
-				"uuid":  sessionUUID,
-			})
+		if models.ErrorCodeOf(err) == models.ErrCodeSessionNotFound {
+			return respondError(c, 404, err)
 		}
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to get session data",
-			"details": err.Error(),
-		})
+		return respondError(c, 500, fmt.Errorf("failed to get session data: %w", err))
 	}
 
 	return c.JSON(sessionData)
@@ -138,6 +153,7 @@ func (h *ClaudeHandler) GetSessionByUUID(c *fiber.Ctx) error {
 // @Param request body github_com_vanpelt_catnip_internal_models.CreateCompletionRequest true "Create completion request"
 // @Success 200 {object} github_com_vanpelt_catnip_internal_models.CreateCompletionResponse
 // @Failure 400 {object} map[string]string
+// @Failure 422 {object} map[string]interface{} "Prompt blocked by pre-flight linting; resend with ignore_lint_warnings to override"
 // @Failure 500 {object} map[string]string
 // @Router /v1/claude/messages [post]
 func (h *ClaudeHandler) CreateCompletion(c *fiber.Ctx) error {
@@ -145,32 +161,15 @@ func (h *ClaudeHandler) CreateCompletion(c *fiber.Ctx) error {
 
 	// Parse the request body
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
-	}
-
-	// Validate required fields
-	if req.Prompt == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Prompt is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
 	}
 
-	// Default fork=true when resuming (unless explicitly set to false)
-	// This ensures forked sessions don't pollute original session history
-	if req.Resume && req.Fork == nil {
-		forkTrue := true
-		req.Fork = &forkTrue
-		logger.Debug("🔀 Resuming session, defaulting to fork=true")
+	lintWarnings, completionID, rejection := h.prepareCompletion(&req)
+	if rejection != nil {
+		return c.Status(rejection.status).JSON(rejection.body)
 	}
-
-	// When fork is requested, automatically use haiku model for fast, cheap responses
-	// Fork is used for automated operations (PR summaries, branch names) that don't need
-	// the full power of larger models
-	if req.Fork != nil && *req.Fork && req.Model == "" {
-		req.Model = "claude-haiku-4-5"
-		logger.Debugf("🔀 Fork requested, auto-selecting haiku model for fast response")
+	if completionID != "" {
+		c.Set("X-Completion-ID", completionID)
 	}
 
 	// Create context for the request
@@ -182,40 +181,137 @@ func (h *ClaudeHandler) CreateCompletion(c *fiber.Ctx) error {
 		c.Set("Content-Type", "application/json")
 		c.Set("Cache-Control", "no-cache")
 		c.Set("Connection", "keep-alive")
+		if len(lintWarnings) > 0 {
+			if encoded, err := json.Marshal(lintWarnings); err == nil {
+				c.Set("X-Prompt-Lint-Warnings", string(encoded))
+			}
+		}
 
 		// Use the streaming method
 		return h.claudeService.CreateStreamingCompletion(ctx, &req, c.Response().BodyWriter())
 	}
 
+	// Track non-streaming completions (PR bodies, summaries) so they can be
+	// rerun if catnip restarts before they finish
+	jobID := ""
+	if h.automationJobs != nil {
+		description := fmt.Sprintf("Completion: %.80s", req.Prompt)
+		if id, err := h.automationJobs.Begin(services.AutomationJobCompletion, description, &req); err == nil {
+			jobID = id
+			c.Set("X-Automation-Job-ID", jobID)
+		}
+	}
+
 	// Handle non-streaming response
 	logger.Infof("🔍 Creating Claude completion for prompt: %.100s...", req.Prompt)
 	resp, err := h.claudeService.CreateCompletion(ctx, &req)
+	if jobID != "" {
+		result := ""
+		if resp != nil {
+			result = resp.Response
+		}
+		h.automationJobs.Finish(jobID, result, err)
+	}
 	if err != nil {
 		logger.Errorf("❌ Claude completion failed: %v", err)
 		// Handle specific error types
 		if strings.Contains(err.Error(), "prompt is required") {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Prompt is required",
-			})
-		}
-
-		if strings.Contains(err.Error(), "claude command failed") {
-			return c.Status(500).JSON(fiber.Map{
-				"error":   "Claude CLI execution failed",
-				"details": err.Error(),
-			})
+			return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Prompt is required"))
 		}
 
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	logger.Infof("✅ Claude completion successful. Response length: %d chars", len(resp.Response))
 	logger.Debugf("📝 Claude response content: %s", resp.Response)
+	resp.CompletionID = completionID
+	if len(lintWarnings) > 0 {
+		resp.Warnings = lintWarnings
+	}
 	return c.JSON(resp)
 }
 
+// completionRejection is an error response for a completion refused before it runs
+type completionRejection struct {
+	status int
+	body   fiber.Map
+}
+
+// prepareCompletion validates a completion request and applies the defaults
+// shared by the HTTP and WebSocket transports. It returns the prompt lint
+// warnings and the completion ID feedback can be attached to.
+func (h *ClaudeHandler) prepareCompletion(req *models.CreateCompletionRequest) (lintWarnings []models.PromptLintWarning, completionID string, rejection *completionRejection) {
+	// Validate required fields
+	if req.Prompt == "" {
+		return nil, "", &completionRejection{400, errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, "Prompt is required"))}
+	}
+
+	if req.Budget != nil && !req.Stream {
+		return nil, "", &completionRejection{400, errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, "budget requires stream to be true"))}
+	}
+
+	if req.RelayPermissions && !req.Stream {
+		return nil, "", &completionRejection{400, errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, "relay_permissions requires stream to be true"))}
+	}
+
+	// Check the prompt before spending a Claude call on it
+	if h.promptLinter != nil {
+		lint := h.promptLinter.Lint(req.Prompt, req.WorkingDirectory)
+		if lint.Blocked && !req.IgnoreLintWarnings {
+			body := errorBody(models.NewAPIError(models.ErrCodePromptBlocked, "Prompt blocked by pre-flight checks").
+				WithHint("Fix the prompt or resend with ignore_lint_warnings set"))
+			body["warnings"] = lint.Warnings
+			return nil, "", &completionRejection{fiber.StatusUnprocessableEntity, body}
+		}
+		lintWarnings = lint.Warnings
+		if len(lintWarnings) > 0 {
+			logger.Debugf("⚠️ Prompt lint found %d warning(s)", len(lintWarnings))
+		}
+	}
+
+	// Default fork=true when resuming (unless explicitly set to false)
+	// This ensures forked sessions don't pollute original session history
+	if req.Resume && req.Fork == nil {
+		forkTrue := true
+		req.Fork = &forkTrue
+		logger.Debug("🔀 Resuming session, defaulting to fork=true")
+	}
+
+	// When fork is requested, automatically use haiku model for fast, cheap responses
+	// Fork is used for automated operations (PR summaries, branch names) that don't need
+	// the full power of larger models
+	if req.Fork != nil && *req.Fork && req.Model == "" {
+		req.Model = "claude-haiku-4-5"
+		logger.Debugf("🔀 Fork requested, auto-selecting haiku model for fast response")
+	}
+
+	// Assign a completion ID so clients can attach feedback to this response
+	if h.feedbackService != nil {
+		completionID = h.feedbackService.RecordCompletion(req)
+	}
+	return lintWarnings, completionID, nil
+}
+
+// LintPrompt runs the pre-flight prompt checks without sending the prompt
+// @Summary Lint a Claude prompt
+// @Description Checks a prompt for references to files missing from the worktree, excessive length and likely secrets. Checks are configured under prompt_lint in .catnip.yaml.
+// @Tags claude
+// @Accept json
+// @Produce json
+// @Param request body github_com_vanpelt_catnip_internal_models.CreateCompletionRequest true "Prompt and working directory to check"
+// @Success 200 {object} github_com_vanpelt_catnip_internal_models.PromptLintResponse
+// @Router /v1/claude/messages/lint [post]
+func (h *ClaudeHandler) LintPrompt(c *fiber.Ctx) error {
+	var req models.CreateCompletionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+	if h.promptLinter == nil {
+		return c.JSON(&models.PromptLintResponse{Warnings: []models.PromptLintWarning{}})
+	}
+	return c.JSON(h.promptLinter.Lint(req.Prompt, req.WorkingDirectory))
+}
+
 // GetWorktreeTodos returns the most recent Todo structure from the session history for a specific worktree
 // @Summary Get worktree todos
 // @Description Returns the most recent TodoWrite structure from Claude Code session for a specific worktree
@@ -227,16 +323,12 @@ func (h *ClaudeHandler) CreateCompletion(c *fiber.Ctx) error {
 func (h *ClaudeHandler) GetWorktreeTodos(c *fiber.Ctx) error {
 	worktreePath := c.Query("worktree_path")
 	if worktreePath == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "worktree_path query parameter is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree_path query parameter is required"))
 	}
 
 	todos, err := h.claudeService.GetLatestTodos(worktreePath)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	// Return empty array if no todos found instead of null
@@ -258,21 +350,12 @@ func (h *ClaudeHandler) GetWorktreeTodos(c *fiber.Ctx) error {
 func (h *ClaudeHandler) GetWorktreeLatestAssistantMessage(c *fiber.Ctx) error {
 	worktreePath := c.Query("worktree_path")
 	if worktreePath == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "worktree_path query parameter is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree_path query parameter is required"))
 	}
 
 	message, isError, err := h.claudeService.GetLatestAssistantMessageOrError(worktreePath)
 	if err != nil {
-		if strings.Contains(err.Error(), "project directory not found") {
-			return c.Status(404).JSON(fiber.Map{
-				"error": err.Error(),
-			})
-		}
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -291,9 +374,7 @@ func (h *ClaudeHandler) GetWorktreeLatestAssistantMessage(c *fiber.Ctx) error {
 func (h *ClaudeHandler) GetClaudeSettings(c *fiber.Ctx) error {
 	settings, err := h.claudeService.GetClaudeSettings()
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(settings)
@@ -301,7 +382,7 @@ func (h *ClaudeHandler) GetClaudeSettings(c *fiber.Ctx) error {
 
 // UpdateClaudeSettings updates Claude configuration settings in ~/.claude.json and volume settings.json
 // @Summary Update Claude settings
-// @Description Updates Claude Code configuration settings (theme and notifications)
+// @Description Updates Claude Code configuration settings (theme, notifications and the org-wide system prompt)
 // @Tags claude
 // @Accept json
 // @Produce json
@@ -313,9 +394,7 @@ func (h *ClaudeHandler) UpdateClaudeSettings(c *fiber.Ctx) error {
 
 	// Parse the request body
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
 	}
 
 	// Validate theme if provided
@@ -329,30 +408,51 @@ func (h *ClaudeHandler) UpdateClaudeSettings(c *fiber.Ctx) error {
 			}
 		}
 		if !valid {
-			return c.Status(400).JSON(fiber.Map{
-				"error": "Invalid theme value. Must be one of: dark, light, dark-daltonized, light-daltonized, dark-ansi, light-ansi",
-			})
+			return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid theme value. Must be one of: dark, light, dark-daltonized, light-daltonized, dark-ansi, light-ansi"))
 		}
 	}
 
 	// Validate that at least one field is provided
-	if req.Theme == "" && req.NotificationsEnabled == nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "At least one setting must be provided (theme or notificationsEnabled)",
-		})
+	if req.Theme == "" && req.NotificationsEnabled == nil && req.SystemPrompt == nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "At least one setting must be provided (theme, notificationsEnabled or systemPrompt)"))
 	}
 
 	// Update settings
 	settings, err := h.claudeService.UpdateClaudeSettings(&req)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(settings)
 }
 
+// GetSystemPrompt previews the layered system prompt for a directory
+// @Summary Preview effective system prompt
+// @Description Returns the system prompt layers appended to Claude's default prompt in a worktree: the org-wide prompt from settings, the repository prompt from .catnip.yaml (claude.system_prompt), the linked issue, worktree memory and branch summary (new interactive sessions only) and an optional per-request addition, and their concatenation.
+// @Tags claude
+// @Produce json
+// @Param worktree_path query string true "Worktree path"
+// @Param warm_context query bool false "Include the branch summary new sessions get when claude.warm_context is enabled (runs the configured test command)"
+// @Param append query string false "Per-request addition to preview"
+// @Success 200 {object} models.EffectiveSystemPrompt
+// @Router /v1/claude/system-prompt [get]
+func (h *ClaudeHandler) GetSystemPrompt(c *fiber.Ctx) error {
+	worktreePath := c.Query("worktree_path")
+	if worktreePath == "" {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree_path query parameter is required"))
+	}
+	worktreePath = config.Runtime.ResolvePath(worktreePath)
+
+	workspaceContext := ""
+	if h.gitService != nil {
+		workspaceContext = joinNonEmpty(h.gitService.IssueContextForPath(worktreePath), h.gitService.MemoryContextForPath(worktreePath))
+		if c.QueryBool("warm_context") {
+			workspaceContext = joinNonEmpty(workspaceContext, h.gitService.WarmContextForPath(worktreePath))
+		}
+	}
+	return c.JSON(h.claudeService.LayeredSystemPrompt(worktreePath, workspaceContext, c.Query("append")))
+}
+
 // HandleClaudeHook handles Claude Code hook notifications
 // @Summary Handle Claude hook events
 // @Description Receives hook notifications from Claude Code for activity tracking
@@ -372,9 +472,7 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 	// Parse the request body
 	if err := c.BodyParser(&req); err != nil {
 		logger.Debugf("❌ Hook parsing error: %v", err)
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
 	}
 
 	// Log the parsed hook event
@@ -382,24 +480,32 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 
 	// Validate required fields
 	if req.EventType == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "event_type is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "event_type is required"))
 	}
 
 	if req.WorkingDirectory == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "working_directory is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "working_directory is required"))
 	}
 
 	// Handle the hook event
 	err := h.claudeService.HandleHookEvent(&req)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to handle hook event",
-			"details": err.Error(),
-		})
+		return respondError(c, 500, fmt.Errorf("failed to handle hook event: %w", err))
+	}
+
+	// Mark which terminal output came from which tool invocation
+	if h.ptyHandler != nil {
+		h.ptyHandler.AnnotateToolUse(&req)
+	}
+
+	// Time-boxed sessions: ask Claude to wrap up once its budget is spent
+	budgetResponse := h.claudeService.BudgetHookResponse(&req)
+
+	// Locked paths: the worktree's locks as context, and a warning or denial
+	// before Claude edits one
+	var lockResponse *models.ClaudeHookResponse
+	if budgetResponse == nil {
+		lockResponse = h.gitService.PathLockHookResponse(&req)
 	}
 
 	// Trigger immediate Claude activity state sync for activity-related events
@@ -532,7 +638,7 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 
 				// Generate workspace URL - remove workspace prefix if present
 				workspacePath := strings.TrimPrefix(workspaceDir, config.Runtime.WorkspaceDir)
-				workspaceURL := fmt.Sprintf("http://localhost:6369/workspace%s", workspacePath)
+				workspaceURL := config.Runtime.PublicURL("/workspace" + workspacePath)
 
 				h.eventsHandler.broadcastEvent(AppEvent{
 					Type: NotificationEvent,
@@ -553,12 +659,75 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 		}
 	}
 
+	if budgetResponse != nil {
+		budgetResponse.Status = "success"
+		budgetResponse.Message = "Hook event processed successfully (session budget spent)"
+		return c.JSON(budgetResponse)
+	}
+
+	if lockResponse != nil {
+		lockResponse.Status = "success"
+		lockResponse.Message = "Hook event processed successfully (locked paths)"
+		return c.JSON(lockResponse)
+	}
+
 	return c.JSON(fiber.Map{
 		"status":  "success",
 		"message": "Hook event processed successfully",
 	})
 }
 
+// ListSessionBudgets returns the budgets of time-boxed Claude tasks
+// @Summary List session budgets
+// @Description Returns time/token budgets of Claude tasks started with a budget, with usage and wrap-up phase, newest first
+// @Tags claude
+// @Produce json
+// @Success 200 {array} services.SessionBudgetStatus
+// @Router /v1/claude/budgets [get]
+func (h *ClaudeHandler) ListSessionBudgets(c *fiber.Ctx) error {
+	return c.JSON(h.claudeService.GetProcessRegistry().Budgets().List())
+}
+
+// ListPermissionRequests returns relayed permission prompts
+// @Summary List Claude permission requests
+// @Description Returns permission prompts from completions started with relay_permissions: pending ones first, then recently answered or expired ones, newest first. Prompts are also sent as claude:permission events.
+// @Tags claude
+// @Produce json
+// @Param working_directory query string false "Only show prompts from this working directory"
+// @Success 200 {array} models.ClaudePermissionRequest
+// @Router /v1/claude/permissions [get]
+func (h *ClaudeHandler) ListPermissionRequests(c *fiber.Ctx) error {
+	workingDir := c.Query("working_directory")
+	if workingDir != "" {
+		workingDir = config.Runtime.ResolvePath(workingDir)
+	}
+	return c.JSON(h.claudeService.GetProcessRegistry().Permissions().List(workingDir))
+}
+
+// DecidePermissionRequest approves or denies a relayed permission prompt
+// @Summary Answer Claude permission request
+// @Description Allows or denies a tool use Claude is waiting on. The answer is written back to the Claude process; unanswered prompts are denied after 5 minutes.
+// @Tags claude
+// @Accept json
+// @Produce json
+// @Param id path string true "Permission request ID"
+// @Param decision body models.ClaudePermissionDecision true "Decision"
+// @Success 200 {object} models.ClaudePermissionRequest
+// @Failure 400 {object} map[string]string
+// @Router /v1/claude/permissions/{id} [post]
+func (h *ClaudeHandler) DecidePermissionRequest(c *fiber.Ctx) error {
+	var decision models.ClaudePermissionDecision
+	if err := c.BodyParser(&decision); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	request, err := h.claudeService.GetProcessRegistry().Permissions().Decide(c.Params("id"), decision)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(request)
+}
+
 // StartOnboarding starts the automated Claude Code onboarding process
 // @Summary Start onboarding
 // @Description Starts the automated Claude Code login/onboarding flow
@@ -568,9 +737,7 @@ func (h *ClaudeHandler) HandleClaudeHook(c *fiber.Ctx) error {
 // @Router /v1/claude/onboarding/start [post]
 func (h *ClaudeHandler) StartOnboarding(c *fiber.Ctx) error {
 	if h.claudeOnboardingService == nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Onboarding service not initialized",
-		})
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Onboarding service not initialized"))
 	}
 
 	if h.claudeOnboardingService.IsRunning() {
@@ -604,10 +771,7 @@ func (h *ClaudeHandler) StartOnboarding(c *fiber.Ctx) error {
 	err := h.claudeOnboardingService.Start()
 
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error":   "Failed to start onboarding",
-			"details": err.Error(),
-		})
+		return respondError(c, 500, fmt.Errorf("failed to start onboarding: %w", err))
 	}
 
 	return c.JSON(fiber.Map{
@@ -626,9 +790,7 @@ func (h *ClaudeHandler) StartOnboarding(c *fiber.Ctx) error {
 // @Router /v1/claude/onboarding/status [get]
 func (h *ClaudeHandler) GetOnboardingStatus(c *fiber.Ctx) error {
 	if h.claudeOnboardingService == nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Onboarding service not initialized",
-		})
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Onboarding service not initialized"))
 	}
 
 	status := h.claudeOnboardingService.GetStatus()
@@ -660,30 +822,21 @@ func (h *ClaudeHandler) GetOnboardingStatus(c *fiber.Ctx) error {
 // @Router /v1/claude/onboarding/submit-code [post]
 func (h *ClaudeHandler) SubmitOnboardingCode(c *fiber.Ctx) error {
 	if h.claudeOnboardingService == nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Onboarding service not initialized",
-		})
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Onboarding service not initialized"))
 	}
 
 	var req models.ClaudeOnboardingSubmitCodeRequest
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
 	}
 
 	if req.Code == "" {
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Code is required",
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Code is required"))
 	}
 
 	err := h.claudeOnboardingService.SubmitCode(req.Code)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "Failed to submit code",
-			"details": err.Error(),
-		})
+		return respondError(c, 400, fmt.Errorf("failed to submit code: %w", err))
 	}
 
 	return c.JSON(fiber.Map{
@@ -701,17 +854,12 @@ func (h *ClaudeHandler) SubmitOnboardingCode(c *fiber.Ctx) error {
 // @Router /v1/claude/onboarding/cancel [post]
 func (h *ClaudeHandler) CancelOnboarding(c *fiber.Ctx) error {
 	if h.claudeOnboardingService == nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Onboarding service not initialized",
-		})
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Onboarding service not initialized"))
 	}
 
 	err := h.claudeOnboardingService.Stop()
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "Failed to cancel onboarding",
-			"details": err.Error(),
-		})
+		return respondError(c, 400, fmt.Errorf("failed to cancel onboarding: %w", err))
 	}
 
 	return c.JSON(fiber.Map{
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/claude/parser"
+)
+
+// RepairSessionIntegrityRequest selects a session to repair, or all when empty
+type RepairSessionIntegrityRequest struct {
+	SessionID string `json:"session_id,omitempty" example:"cf568042-7147-4fba-a2ca-c6a646581260"`
+}
+
+// RepairSessionIntegrityResponse lists what was quarantined by a repair
+type RepairSessionIntegrityResponse struct {
+	Reports []*parser.IntegrityReport `json:"reports"`
+	// Sessions with problems left alone because they're in use or couldn't be rewritten
+	Skipped []string `json:"skipped,omitempty"`
+}
+
+// CheckSessionIntegrity scans Claude session files for malformed lines
+// @Summary Check Claude session integrity
+// @Description Scans every Claude session JSONL file for malformed lines and truncated writes without changing anything, along with the repairs made since startup. Reads skip bad lines instead of failing; this reports what they skipped.
+// @Tags claude
+// @Produce json
+// @Success 200 {object} services.SessionIntegrityScan
+// @Failure 500 {object} map[string]string
+// @Router /v1/claude/integrity [get]
+func (h *ClaudeHandler) CheckSessionIntegrity(c *fiber.Ctx) error {
+	if h.sessionIntegrity == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Session integrity service not initialized",
+		})
+	}
+
+	scan, err := h.sessionIntegrity.Scan(false)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(scan)
+}
+
+// RepairSessionIntegrity quarantines malformed lines from Claude session files
+// @Summary Repair Claude session files
+// @Description Moves malformed lines and truncated trailing writes into a quarantine file and rewrites the session with its valid lines, so it can be read and resumed. With a session_id the session is repaired even if recently modified; otherwise only idle sessions are repaired.
+// @Tags claude
+// @Accept json
+// @Produce json
+// @Param request body RepairSessionIntegrityRequest false "Session to repair"
+// @Success 200 {object} RepairSessionIntegrityResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/claude/integrity/repair [post]
+func (h *ClaudeHandler) RepairSessionIntegrity(c *fiber.Ctx) error {
+	if h.sessionIntegrity == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Session integrity service not initialized",
+		})
+	}
+
+	var req RepairSessionIntegrityRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if req.SessionID != "" {
+		report, err := h.sessionIntegrity.Repair(req.SessionID)
+		if err != nil {
+			return respondError(c, 500, err)
+		}
+		return c.JSON(RepairSessionIntegrityResponse{
+			Reports: []*parser.IntegrityReport{report},
+		})
+	}
+
+	scan, err := h.sessionIntegrity.Scan(true)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(RepairSessionIntegrityResponse{
+		Reports: scan.Problems,
+		Skipped: scan.Skipped,
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// WithTranscriptIndex enables searching Claude transcripts across workspaces
+func (h *ClaudeHandler) WithTranscriptIndex(transcripts *services.TranscriptIndex) *ClaudeHandler {
+	h.transcripts = transcripts
+	return h
+}
+
+// parseTranscriptSearchQuery builds a transcript search from query parameters
+func parseTranscriptSearchQuery(c *fiber.Ctx) (services.TranscriptSearchQuery, error) {
+	query := services.TranscriptSearchQuery{
+		Text:     c.Query("q"),
+		Worktree: c.Query("worktree"),
+		Model:    c.Query("model"),
+		Role:     c.Query("role"),
+		Limit:    c.QueryInt("limit", services.DefaultTranscriptSearchLimit),
+		Offset:   c.QueryInt("offset", 0),
+	}
+	for name, target := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, models.NewAPIError(models.ErrCodeInvalidRequest, "%s must be an RFC3339 timestamp", name)
+			}
+			*target = t
+		}
+	}
+	return query, nil
+}
+
+// SearchTranscripts searches every stored Claude session transcript
+// @Summary Search Claude transcripts
+// @Description Full-text search over the user prompts and assistant replies of every Claude session, across all workspaces. Every word of q must appear in a message, and a word ending in * matches words it starts, so "migrat* strategy" finds "migration strategy". Messages with the most matches come first, newest first among equals. The index is built at startup and updated incrementally as sessions are written.
+// @Tags claude
+// @Produce json
+// @Param q query string true "Words to search for"
+// @Param worktree query string false "Only this worktree: its path, or trailing path components such as felix or catnip/felix"
+// @Param model query string false "Only messages from models whose name contains this, e.g. opus"
+// @Param role query string false "Only user or assistant messages"
+// @Param since query string false "Only messages after this RFC3339 timestamp"
+// @Param until query string false "Only messages before this RFC3339 timestamp"
+// @Param limit query int false "Maximum hits to return (default 20, max 100)"
+// @Param offset query int false "Hits to skip, for paging"
+// @Success 200 {object} services.TranscriptSearchResult
+// @Failure 400 {object} map[string]string
+// @Router /v1/claude/search [get]
+func (h *ClaudeHandler) SearchTranscripts(c *fiber.Ctx) error {
+	if h.transcripts == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Transcript index not initialized",
+		})
+	}
+
+	query, err := parseTranscriptSearchQuery(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	result, err := h.transcripts.Search(query)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(result)
+}
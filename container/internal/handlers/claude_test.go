@@ -3,11 +3,13 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"net"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	gorillaws "github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vanpelt/catnip/internal/models"
@@ -195,3 +197,66 @@ func TestClaudeHandler_CreateCompletion_MissingContentType(t *testing.T) {
 	// With mock, should succeed or fail validation, but not call real CLI
 	assert.True(t, resp.StatusCode == 200 || resp.StatusCode == 400)
 }
+
+func startCompletionSocketServer(t *testing.T, handler *ClaudeHandler) string {
+	t.Helper()
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", handler.CreateCompletionWebSocket)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return "ws://" + ln.Addr().String() + "/ws"
+}
+
+func TestClaudeHandler_CreateCompletionWebSocket(t *testing.T) {
+	mockWrapper := services.NewMockClaudeSubprocessWrapper()
+	handler := NewClaudeHandler(services.NewClaudeServiceWithWrapper(mockWrapper), nil)
+	url := startCompletionSocketServer(t, handler)
+
+	conn, _, err := gorillaws.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(models.CreateCompletionRequest{
+		Prompt:           "Stream this response",
+		WorkingDirectory: "/tmp",
+	}))
+
+	var start CompletionSocketStart
+	require.NoError(t, conn.ReadJSON(&start))
+	assert.Equal(t, "completion_start", start.Type)
+
+	// Stream events arrive as one message each, then the server closes normally
+	var event models.CreateCompletionResponse
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, "Mock streaming response", event.Response)
+	var final models.CreateCompletionResponse
+	require.NoError(t, conn.ReadJSON(&final))
+	assert.True(t, final.IsLast)
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, gorillaws.IsCloseError(err, gorillaws.CloseNormalClosure), "unexpected error: %v", err)
+}
+
+func TestClaudeHandler_CreateCompletionWebSocket_Rejected(t *testing.T) {
+	mockWrapper := services.NewMockClaudeSubprocessWrapper()
+	handler := NewClaudeHandler(services.NewClaudeServiceWithWrapper(mockWrapper), nil)
+	url := startCompletionSocketServer(t, handler)
+
+	conn, _, err := gorillaws.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteJSON(models.CreateCompletionRequest{}))
+
+	var message map[string]interface{}
+	require.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, "error", message["type"])
+	assert.Equal(t, float64(400), message["status"])
+	assert.Equal(t, "Prompt is required", message["error"])
+
+	_, _, err = conn.ReadMessage()
+	assert.True(t, gorillaws.IsCloseError(err, gorillaws.ClosePolicyViolation), "unexpected error: %v", err)
+}
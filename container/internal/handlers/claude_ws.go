@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// completionSocketRequestTimeout is how long a client has to send its request after connecting
+const completionSocketRequestTimeout = 30 * time.Second
+
+// CompletionSocketStart is the first message on a completion WebSocket,
+// carrying what the HTTP transport sends as response headers
+type CompletionSocketStart struct {
+	Type         string                     `json:"type" example:"completion_start"`
+	CompletionID string                     `json:"completion_id,omitempty"`
+	Warnings     []models.PromptLintWarning `json:"warnings,omitempty"`
+}
+
+// CreateCompletionWebSocket streams a completion over a WebSocket
+// @Summary Stream Claude messages over WebSocket
+// @Description WebSocket alternative to streaming POST /v1/claude/messages for clients whose proxies break long-lived HTTP responses. Send a CreateCompletionRequest as the first text message (stream is implied). The server replies with a completion_start message, then one text message per stream event (the same JSON objects as the HTTP stream), and closes the socket when the completion ends. Rejected requests get a single {type: error} message with the HTTP status and error body. Disconnecting leaves the Claude process running, like the HTTP stream.
+// @Tags claude
+// @Success 101 {string} string "Switching Protocols"
+// @Router /v1/claude/messages/ws [get]
+func (h *ClaudeHandler) CreateCompletionWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	return websocket.New(h.handleCompletionSocket)(c)
+}
+
+func (h *ClaudeHandler) handleCompletionSocket(conn *websocket.Conn) {
+	defer conn.Close()
+	out := &completionSocketWriter{conn: conn}
+
+	var req models.CreateCompletionRequest
+	_ = conn.SetReadDeadline(time.Now().Add(completionSocketRequestTimeout))
+	if _, data, err := conn.ReadMessage(); err != nil || json.Unmarshal(data, &req) != nil {
+		out.closeWithError(400, fiber.Map{"error": "Invalid request body"})
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	req.Stream = true
+
+	lintWarnings, completionID, rejection := h.prepareCompletion(&req)
+	if rejection != nil {
+		out.closeWithError(rejection.status, rejection.body)
+		return
+	}
+	if err := out.sendJSON(CompletionSocketStart{
+		Type:         "completion_start",
+		CompletionID: completionID,
+		Warnings:     lintWarnings,
+	}); err != nil {
+		return
+	}
+
+	// The client closing its end is the only thing we read for; it cancels
+	// the stream (the process keeps running for other clients)
+	ctx, cancel := context.WithCancel(context.Background())
+	readerDone := make(chan struct{})
+	defer func() {
+		cancel()
+		// The connection is recycled once this handler returns, so the reader must be gone first
+		_ = conn.Close()
+		<-readerDone
+	}()
+	go func() {
+		defer close(readerDone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				cancel()
+				return
+			}
+		}
+	}()
+
+	err := h.claudeService.CreateStreamingCompletion(ctx, &req, out)
+	out.flush()
+	if err != nil && ctx.Err() == nil {
+		logger.Warnf("⚠️ WebSocket completion failed: %v", err)
+		out.closeWithError(500, errorBody(&models.APIError{
+			Code:      models.ErrCodeClaudeFailed,
+			Message:   err.Error(),
+			Retryable: true,
+		}))
+		return
+	}
+	out.close(websocket.CloseNormalClosure, "")
+}
+
+// completionSocketWriter turns the newline-delimited JSON written by the
+// streaming completion into one WebSocket text message per event
+type completionSocketWriter struct {
+	conn    *websocket.Conn
+	mu      sync.Mutex
+	pending []byte
+}
+
+func (w *completionSocketWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		line := bytes.TrimSpace(w.pending[:idx])
+		w.pending = w.pending[idx+1:]
+		if len(line) == 0 {
+			continue
+		}
+		if err := w.conn.WriteMessage(websocket.TextMessage, line); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// flush sends an event left without a trailing newline
+func (w *completionSocketWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if line := bytes.TrimSpace(w.pending); len(line) > 0 {
+		_ = w.conn.WriteMessage(websocket.TextMessage, line)
+	}
+	w.pending = nil
+}
+
+func (w *completionSocketWriter) sendJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// closeWithError sends an error message shaped like the HTTP error response and closes the socket
+func (w *completionSocketWriter) closeWithError(status int, body fiber.Map) {
+	message := fiber.Map{"type": "error", "status": status}
+	for key, value := range body {
+		message[key] = value
+	}
+	_ = w.sendJSON(message)
+
+	code := websocket.CloseInternalServerErr
+	if status < 500 {
+		code = websocket.ClosePolicyViolation
+	}
+	w.close(code, http.StatusText(status))
+}
+
+func (w *completionSocketWriter) close(code int, reason string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_ = w.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
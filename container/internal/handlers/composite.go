@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// CompositeHandler handles composite (multi-repository) workspace endpoints
+type CompositeHandler struct {
+	composites *services.CompositeWorkspaceService
+}
+
+// NewCompositeHandler creates a new composite workspace handler
+func NewCompositeHandler(composites *services.CompositeWorkspaceService) *CompositeHandler {
+	return &CompositeHandler{
+		composites: composites,
+	}
+}
+
+// ListComposites returns all composite workspaces
+// @Summary List composite workspaces
+// @Description Returns the composite workspaces that group worktrees from several repositories, sorted by name
+// @Tags git
+// @Produce json
+// @Success 200 {array} services.CompositeWorkspace
+// @Router /v1/git/composites [get]
+func (h *CompositeHandler) ListComposites(c *fiber.Ctx) error {
+	return c.JSON(h.composites.List())
+}
+
+// CreateComposite creates a composite workspace from existing worktrees
+// @Summary Create composite workspace
+// @Description Creates a parent directory with a link to each member worktree (named after its repository unless an alias is given). Terminals and Claude sessions can be opened in the parent directory with the session ID composite/<name>; they and the members' setup.sh runs get the workspace's env vars, and Claude is told about every member.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param request body services.CreateCompositeWorkspaceRequest true "Composite workspace"
+// @Success 200 {object} services.CompositeWorkspace
+// @Failure 400 {object} map[string]string
+// @Router /v1/git/composites [post]
+func (h *CompositeHandler) CreateComposite(c *fiber.Ctx) error {
+	var req services.CreateCompositeWorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	workspace, err := h.composites.Create(req)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(workspace)
+}
+
+// GetComposite returns one composite workspace
+// @Summary Get composite workspace
+// @Description Returns a composite workspace by ID or name
+// @Tags git
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Success 200 {object} services.CompositeWorkspace
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/composites/{id} [get]
+func (h *CompositeHandler) GetComposite(c *fiber.Ctx) error {
+	workspace, err := h.composites.Get(c.Params("id"))
+	if err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(workspace)
+}
+
+// UpdateComposite changes a composite workspace's env vars or Claude instructions
+// @Summary Update composite workspace
+// @Description Replaces the workspace's env vars and/or Claude instructions. New terminals and Claude sessions pick up the changes.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Param request body services.UpdateCompositeWorkspaceRequest true "Settings to change"
+// @Success 200 {object} services.CompositeWorkspace
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/composites/{id} [patch]
+func (h *CompositeHandler) UpdateComposite(c *fiber.Ctx) error {
+	var req services.UpdateCompositeWorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	workspace, err := h.composites.Update(c.Params("id"), req)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(workspace)
+}
+
+// DeleteComposite removes a composite workspace
+// @Summary Delete composite workspace
+// @Description Removes the workspace's parent directory. Member worktrees are kept unless delete_worktrees is true.
+// @Tags git
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Param delete_worktrees query bool false "Also delete the member worktrees"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/composites/{id} [delete]
+func (h *CompositeHandler) DeleteComposite(c *fiber.Ctx) error {
+	if err := h.composites.Delete(c.Params("id"), c.QueryBool("delete_worktrees")); err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Composite workspace deleted",
+	})
+}
+
+// GetCompositeStatus aggregates member status across a composite workspace
+// @Summary Get composite workspace status
+// @Description Returns each member worktree's branch, dirty/conflict state, commits ahead/behind, PR and Claude activity, plus totals across members
+// @Tags git
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Success 200 {object} services.CompositeWorkspaceStatus
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/composites/{id}/status [get]
+func (h *CompositeHandler) GetCompositeStatus(c *fiber.Ctx) error {
+	status, err := h.composites.Status(c.Params("id"))
+	if err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(status)
+}
+
+// RunCompositeSetup re-runs setup.sh in every member
+// @Summary Run composite workspace setup
+// @Description Starts setup.sh in each member worktree that has one, with the workspace's env vars. Members without a setup.sh report an error and the rest still run.
+// @Tags git
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Success 202 {array} services.CompositeSetupResult
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/composites/{id}/setup [post]
+func (h *CompositeHandler) RunCompositeSetup(c *fiber.Ctx) error {
+	results, err := h.composites.RunSetup(c.Params("id"))
+	if err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.Status(202).JSON(results)
+}
+
+// AddCompositeMember links another worktree into a composite workspace
+// @Summary Add composite workspace member
+// @Description Links an existing worktree into the workspace under its alias (the repository name by default)
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Param request body services.CompositeMember true "Member to add"
+// @Success 200 {object} services.CompositeWorkspace
+// @Failure 400 {object} map[string]string
+// @Router /v1/git/composites/{id}/members [post]
+func (h *CompositeHandler) AddCompositeMember(c *fiber.Ctx) error {
+	var member services.CompositeMember
+	if err := c.BodyParser(&member); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	workspace, err := h.composites.AddMember(c.Params("id"), member)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(workspace)
+}
+
+// RemoveCompositeMember unlinks a worktree from a composite workspace
+// @Summary Remove composite workspace member
+// @Description Removes the member's link from the workspace. The worktree itself is kept.
+// @Tags git
+// @Produce json
+// @Param id path string true "Composite workspace ID or name"
+// @Param worktree_id path string true "Member worktree ID"
+// @Success 200 {object} services.CompositeWorkspace
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/composites/{id}/members/{worktree_id} [delete]
+func (h *CompositeHandler) RemoveCompositeMember(c *fiber.Ctx) error {
+	workspace, err := h.composites.RemoveMember(c.Params("id"), c.Params("worktree_id"))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(workspace)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// DataExportHandler handles exporting and purging the instance's data
+type DataExportHandler struct {
+	dataExport *services.DataExportService
+	shutdown   func()
+}
+
+// DataPurgeRequest selects what to purge
+type DataPurgeRequest struct {
+	// Must be "purge"
+	Confirm string `json:"confirm" example:"purge"`
+	// Categories to purge (state, audit, sessions, transcripts, captures); empty purges all
+	Categories []string `json:"categories" example:"transcripts,captures"`
+}
+
+// DataPurgeResponse reports a purge
+type DataPurgeResponse struct {
+	*services.DataPurgeResult
+	// Set when state is purged: the server stops its services, removes the
+	// state files and exits once the response is sent
+	ShuttingDown bool `json:"shutting_down"`
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(dataExport *services.DataExportService) *DataExportHandler {
+	return &DataExportHandler{
+		dataExport: dataExport,
+	}
+}
+
+// WithShutdown sets how the server stops to purge its state. It must stop
+// the running services before removing the state files, since they hold
+// state in memory and would write it back.
+func (h *DataExportHandler) WithShutdown(shutdown func()) *DataExportHandler {
+	h.shutdown = shutdown
+	return h
+}
+
+// ExportData downloads everything catnip stores about the instance
+// @Summary Export instance data
+// @Description Streams a .tar.gz of everything catnip stores about the instance and its user, grouped by category: state (worktrees, settings, jobs, reports and config overrides in the volume), audit (SSH agent audit log and state journal), sessions (Claude session metadata), transcripts (Claude session logs and prompt history) and captures (terminal recordings). The last entry, manifest.json, lists every file with its size and SHA-256, the data left out (secret values, git repositories, mirrors, sandboxes) and the names of stored secrets.
+// @Tags admin
+// @Produce application/gzip
+// @Param categories query string false "Comma-separated categories to export (default all)"
+// @Success 200 {file} file "Export archive"
+// @Failure 400 {object} map[string]string
+// @Router /v1/admin/export [get]
+func (h *DataExportHandler) ExportData(c *fiber.Ctx) error {
+	var requested []string
+	if raw := c.Query("categories"); raw != "" {
+		requested = strings.Split(raw, ",")
+	}
+	categories, err := services.ParseDataCategories(requested)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+
+	filename := fmt.Sprintf("catnip-export-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	c.Set("Content-Type", "application/gzip")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	logger.Infof("📦 Exporting %v", categories)
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		if err := h.dataExport.Export(w, categories); err != nil {
+			logger.Warnf("⚠️ Data export failed: %v", err)
+			return
+		}
+		_ = w.Flush()
+	}))
+	return nil
+}
+
+// GetExportManifest previews what an export contains
+// @Summary Preview instance data export
+// @Description Returns the manifest an export of the categories would have, without file checksums, to see what an export or purge covers.
+// @Tags admin
+// @Produce json
+// @Param categories query string false "Comma-separated categories (default all)"
+// @Success 200 {object} services.DataExportManifest
+// @Failure 400 {object} map[string]string
+// @Router /v1/admin/export/manifest [get]
+func (h *DataExportHandler) GetExportManifest(c *fiber.Ctx) error {
+	var requested []string
+	if raw := c.Query("categories"); raw != "" {
+		requested = strings.Split(raw, ",")
+	}
+	categories, err := services.ParseDataCategories(requested)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(h.dataExport.Inventory(categories))
+}
+
+// PurgeData deletes the instance's data
+// @Summary Purge instance data
+// @Description Deletes the files of the given categories, the same ones an export contains; secrets, git repositories and mirrors are left alone. Requires {"confirm": "purge"}. Purging state stops the server once the response is sent: running services hold state in memory and would write it back, so they are stopped first and the state files removed last.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body DataPurgeRequest true "Purge confirmation and categories"
+// @Success 200 {object} DataPurgeResponse
+// @Success 202 {object} DataPurgeResponse "State purge scheduled, server stopping"
+// @Failure 400 {object} map[string]string
+// @Router /v1/admin/purge [post]
+func (h *DataExportHandler) PurgeData(c *fiber.Ctx) error {
+	var req DataPurgeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+	if req.Confirm != "purge" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": `Set "confirm" to "purge" to delete data`,
+		})
+	}
+	categories, err := services.ParseDataCategories(req.Categories)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+
+	var immediate []string
+	purgeState := false
+	for _, category := range categories {
+		if category == services.DataState {
+			purgeState = true
+		} else {
+			immediate = append(immediate, category)
+		}
+	}
+	if purgeState && h.shutdown == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "State can't be purged while the server is running",
+		})
+	}
+
+	result := h.dataExport.Purge(immediate)
+	if !purgeState {
+		return c.JSON(DataPurgeResponse{DataPurgeResult: result})
+	}
+
+	// State is removed by the shutdown, after the services stop
+	inventory := h.dataExport.Inventory([]string{services.DataState})
+	result.Categories = append(inventory.Categories, result.Categories...)
+	logger.Warnf("🗑️ Purging state and stopping the server")
+	// Stopping waits for this response to be sent, so it can't block the handler
+	go h.shutdown()
+	return c.Status(fiber.StatusAccepted).JSON(DataPurgeResponse{DataPurgeResult: result, ShuttingDown: true})
+}
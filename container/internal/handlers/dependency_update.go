@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// DependencyUpdateHandler handles dependency update workflow endpoints
+type DependencyUpdateHandler struct {
+	dependencyService *services.DependencyUpdateService
+}
+
+// NewDependencyUpdateHandler creates a new dependency update handler
+func NewDependencyUpdateHandler(dependencyService *services.DependencyUpdateService) *DependencyUpdateHandler {
+	return &DependencyUpdateHandler{
+		dependencyService: dependencyService,
+	}
+}
+
+// StartDependencyUpdate starts a dependency update run for a repository
+// @Summary Start a dependency update
+// @Description Creates a worktree, runs dependency update commands (from the request, .catnip.yaml or lockfile detection), runs tests, lets Claude fix failures and opens a PR. Progress is streamed as dependency_update:progress events.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param request body services.DependencyUpdateRequest false "Overrides for commands, test and fix attempts"
+// @Success 202 {object} services.DependencyUpdateRun
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/dependency-updates [post]
+func (h *DependencyUpdateHandler) StartDependencyUpdate(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req services.DependencyUpdateRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	run, err := h.dependencyService.Start(repoID, req)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.Status(202).JSON(run)
+}
+
+// ListDependencyUpdates returns recent dependency update runs
+// @Summary List dependency updates
+// @Description Returns recent dependency update runs, newest first
+// @Tags git
+// @Produce json
+// @Success 200 {array} services.DependencyUpdateRun
+// @Router /v1/git/dependency-updates [get]
+func (h *DependencyUpdateHandler) ListDependencyUpdates(c *fiber.Ctx) error {
+	return c.JSON(h.dependencyService.List())
+}
+
+// GetDependencyUpdate returns a dependency update run
+// @Summary Get a dependency update
+// @Description Returns the steps, output and outcome of a dependency update run
+// @Tags git
+// @Produce json
+// @Param id path string true "Run ID"
+// @Success 200 {object} services.DependencyUpdateRun
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/dependency-updates/{id} [get]
+func (h *DependencyUpdateHandler) GetDependencyUpdate(c *fiber.Ctx) error {
+	run, exists := h.dependencyService.Get(c.Params("id"))
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Dependency update not found",
+		})
+	}
+	return c.JSON(run)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// DiagnosticsHandler handles state diagnostics endpoints
+type DiagnosticsHandler struct {
+	diagnostics *services.DiagnosticsService
+}
+
+// NewDiagnosticsHandler creates a new diagnostics handler
+func NewDiagnosticsHandler(diagnostics *services.DiagnosticsService) *DiagnosticsHandler {
+	return &DiagnosticsHandler{
+		diagnostics: diagnostics,
+	}
+}
+
+// DiagnosticsFixRequest selects the checks to fix
+type DiagnosticsFixRequest struct {
+	// Checks to fix, all when empty
+	Checks []string `json:"checks,omitempty"`
+	// Report the fixes without applying them
+	DryRun bool `json:"dry_run"`
+}
+
+// GetDiagnostics runs the diagnostic checks
+// @Summary Diagnose catnip's state
+// @Description Checks catnip's state against git and the filesystem: worktrees whose .git link to their metadata is broken (gitdir_links), worktrees in state missing on disk (missing_worktrees), worktrees git still has registered that no longer exist (orphaned_worktrees), port allocations of terminal sessions that are gone (stale_ports) and terminals whose recreation is paused by a circuit breaker (circuit_breakers). Issues with a fix can be repaired with POST /v1/diagnostics/fix.
+// @Tags diagnostics
+// @Produce json
+// @Param checks query string false "Comma-separated checks to run (default all)"
+// @Success 200 {object} services.DiagnosticsReport
+// @Failure 400 {object} map[string]string
+// @Router /v1/diagnostics [get]
+func (h *DiagnosticsHandler) GetDiagnostics(c *fiber.Ctx) error {
+	var checks []string
+	if query := c.Query("checks"); query != "" {
+		checks = strings.Split(query, ",")
+	}
+	report, err := h.diagnostics.Run(checks)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(report)
+}
+
+// FixDiagnostics repairs the issues diagnostics finds
+// @Summary Fix diagnosed issues
+// @Description Runs the diagnostic checks and applies every available fix: rewriting broken .git links and running git worktree repair, recreating missing worktrees from their branches, pruning orphaned worktree registrations, releasing stale port allocations and resetting circuit breakers. Returns the actions taken and the issues that remain. With dry_run the fixes are only listed.
+// @Tags diagnostics
+// @Accept json
+// @Produce json
+// @Param request body DiagnosticsFixRequest false "Checks to fix and dry run"
+// @Success 200 {object} services.DiagnosticsFixResult
+// @Failure 400 {object} map[string]string
+// @Router /v1/diagnostics/fix [post]
+func (h *DiagnosticsHandler) FixDiagnostics(c *fiber.Ctx) error {
+	var req DiagnosticsFixRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	result, err := h.diagnostics.Fix(req.Checks, req.DryRun)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(result)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// DotfilesHandler handles dotfile sync endpoints
+type DotfilesHandler struct {
+	syncService *services.DotfileSyncService
+}
+
+// NewDotfilesHandler creates a new dotfiles handler
+func NewDotfilesHandler(syncService *services.DotfileSyncService) *DotfilesHandler {
+	return &DotfilesHandler{
+		syncService: syncService,
+	}
+}
+
+// GetStatus returns the dotfile sync status
+// @Summary Get dotfile sync status
+// @Description Returns the entries of the dotfile manifest in the volume and what the last sync restored into the environment, saved to the volume or found changed on both sides
+// @Tags dotfiles
+// @Produce json
+// @Success 200 {object} services.DotfileSyncStatus
+// @Router /v1/dotfiles [get]
+func (h *DotfilesHandler) GetStatus(c *fiber.Ctx) error {
+	return c.JSON(h.syncService.Status())
+}
+
+// Sync reconciles dotfiles immediately
+// @Summary Sync dotfiles
+// @Description Copies dotfiles listed in the manifest between the home directory and the volume in whichever direction they changed
+// @Tags dotfiles
+// @Produce json
+// @Success 200 {object} services.DotfileSyncStatus
+// @Failure 400 {object} map[string]string "No dotfile manifest"
+// @Failure 500 {object} map[string]string "Sync failed"
+// @Router /v1/dotfiles/sync [post]
+func (h *DotfilesHandler) Sync(c *fiber.Ctx) error {
+	if err := h.syncService.Sync(); err != nil {
+		return respondError(c, 500, err)
+	}
+
+	status := h.syncService.Status()
+	if !status.Enabled {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No dotfile manifest at " + status.Manifest,
+		})
+	}
+	return c.JSON(status)
+}
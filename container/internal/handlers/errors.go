@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// statusForErrorCode maps a typed error code to its HTTP status
+func statusForErrorCode(code models.ErrorCode, fallback int) int {
+	switch code {
+	case models.ErrCodeInvalidRequest, models.ErrCodeBranchNameRejected:
+		return fiber.StatusBadRequest
+	case models.ErrCodeWorktreeNotFound, models.ErrCodeRepositoryNotFound, models.ErrCodeSessionNotFound, models.ErrCodeCompositeNotFound, models.ErrCodeNotFound:
+		return fiber.StatusNotFound
+	case models.ErrCodeMergeConflict, models.ErrCodeConflict:
+		return fiber.StatusConflict
+	case models.ErrCodeGitHubNotAuthenticated, models.ErrCodeHostNotAuthenticated, models.ErrCodeInvalidSignature:
+		return fiber.StatusUnauthorized
+	case models.ErrCodePromptBlocked:
+		return fiber.StatusUnprocessableEntity
+	case models.ErrCodeGitCommandFailed, models.ErrCodeClaudeFailed:
+		return fiber.StatusInternalServerError
+	case models.ErrCodeTimeout:
+		return fiber.StatusRequestTimeout
+	case models.ErrCodeServiceUnavailable:
+		return fiber.StatusServiceUnavailable
+	default:
+		return fallback
+	}
+}
+
+// errorBody builds the JSON error envelope shared by all handlers. The "error"
+// field keeps the human-readable message for backwards compatibility.
+func errorBody(apiErr *models.APIError) fiber.Map {
+	body := fiber.Map{
+		"error":     apiErr.Message,
+		"code":      apiErr.Code,
+		"retryable": apiErr.Retryable,
+	}
+	if apiErr.Hint != "" {
+		body["hint"] = apiErr.Hint
+	}
+	return body
+}
+
+// toAPIError converts err for an error response, keeping any wrapping context
+// from the service layer in the message. Untyped errors get fallbackCode.
+func toAPIError(err error, fallbackCode models.ErrorCode) *models.APIError {
+	apiErr := &models.APIError{Code: fallbackCode}
+	if typed, ok := models.AsAPIError(err); ok {
+		*apiErr = *typed
+	}
+	apiErr.Message = err.Error()
+	return apiErr
+}
+
+// respondError writes err as a structured error response. Typed errors pick
+// their own status; untyped errors use the fallback status and INTERNAL_ERROR.
+func respondError(c *fiber.Ctx, fallbackStatus int, err error) error {
+	fallbackCode := models.ErrCodeInternal
+	if fallbackStatus == fiber.StatusBadRequest {
+		fallbackCode = models.ErrCodeInvalidRequest
+	}
+	apiErr := toAPIError(err, fallbackCode)
+	return c.Status(statusForErrorCode(apiErr.Code, fallbackStatus)).JSON(errorBody(apiErr))
+}
+
+// respondMergeConflict writes the 409 response for a merge conflict error,
+// preserving the legacy "merge_conflict" error field used by the frontend
+func respondMergeConflict(c *fiber.Ctx, err *models.MergeConflictError) error {
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":          "merge_conflict",
+		"code":           models.ErrCodeMergeConflict,
+		"retryable":      false,
+		"message":        err.Message,
+		"operation":      err.Operation,
+		"worktree_name":  err.WorktreeName,
+		"worktree_path":  err.WorktreePath,
+		"conflict_files": err.ConflictFiles,
+	})
+}
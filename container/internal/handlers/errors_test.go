@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func performErrorRequest(t *testing.T, fallback int, err error) (int, map[string]interface{}) {
+	app := fiber.New()
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return respondError(c, fallback, err)
+	})
+
+	resp, testErr := app.Test(httptest.NewRequest("GET", "/test", nil))
+	require.NoError(t, testErr)
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestRespondError_TypedWorktreeNotFound(t *testing.T) {
+	status, body := performErrorRequest(t, 400, models.NewWorktreeNotFoundError("abc"))
+
+	assert.Equal(t, 404, status)
+	assert.Equal(t, "WORKTREE_NOT_FOUND", body["code"])
+	assert.Equal(t, "worktree abc not found", body["error"])
+	assert.Equal(t, false, body["retryable"])
+	assert.NotEmpty(t, body["hint"])
+}
+
+func TestRespondError_WrappedTypedErrorKeepsContext(t *testing.T) {
+	err := fmt.Errorf("failed to list GitHub repositories: %w", models.NewGitHubNotAuthenticatedError())
+	status, body := performErrorRequest(t, 500, err)
+
+	assert.Equal(t, 401, status)
+	assert.Equal(t, "GITHUB_NOT_AUTHENTICATED", body["code"])
+	assert.Equal(t, err.Error(), body["error"])
+}
+
+func TestRespondError_UntypedUsesFallback(t *testing.T) {
+	status, body := performErrorRequest(t, 500, fmt.Errorf("boom"))
+	assert.Equal(t, 500, status)
+	assert.Equal(t, "INTERNAL_ERROR", body["code"])
+
+	status, body = performErrorRequest(t, 400, fmt.Errorf("bad input"))
+	assert.Equal(t, 400, status)
+	assert.Equal(t, "INVALID_REQUEST", body["code"])
+}
+
+func TestRespondError_RetryableFlag(t *testing.T) {
+	err := models.NewRetryableAPIError(models.ErrCodeGitCommandFailed, "fetch timed out")
+	status, body := performErrorRequest(t, 500, err)
+
+	assert.Equal(t, 500, status)
+	assert.Equal(t, true, body["retryable"])
+}
+
+func TestRespondError_SubprocessFailuresAreServerErrors(t *testing.T) {
+	// A 400 fallback must not turn a failed git or claude command into INVALID_REQUEST
+	status, body := performErrorRequest(t, 400, models.NewAPIError(models.ErrCodeGitCommandFailed, "failed to rebase"))
+	assert.Equal(t, 500, status)
+	assert.Equal(t, "GIT_COMMAND_FAILED", body["code"])
+
+	status, body = performErrorRequest(t, 400, models.NewRetryableAPIError(models.ErrCodeClaudeFailed, "claude command failed: overloaded"))
+	assert.Equal(t, 500, status)
+	assert.Equal(t, "CLAUDE_FAILED", body["code"])
+	assert.Equal(t, true, body["retryable"])
+}
+
+func TestRespondError_InvalidSignatureIsUnauthorized(t *testing.T) {
+	status, body := performErrorRequest(t, 400, models.NewAPIError(models.ErrCodeInvalidSignature, "webhook signature doesn't match"))
+	assert.Equal(t, 401, status)
+	assert.Equal(t, "INVALID_SIGNATURE", body["code"])
+}
+
+func TestRespondError_ServiceUnavailable(t *testing.T) {
+	status, body := performErrorRequest(t, 500, models.NewAPIError(models.ErrCodeServiceUnavailable, "onboarding service not initialized"))
+	assert.Equal(t, 503, status)
+	assert.Equal(t, "SERVICE_UNAVAILABLE", body["code"])
+}
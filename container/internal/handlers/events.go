@@ -40,10 +40,22 @@ const (
 	WorktreeCreatedEvent       EventType = "worktree:created"
 	WorktreeDeletedEvent       EventType = "worktree:deleted"
 	WorktreeTodosUpdatedEvent  EventType = "worktree:todos_updated"
+	WorktreeSetupStaleEvent    EventType = "worktree:setup_stale"
 	SessionTitleUpdatedEvent   EventType = "session:title_updated"
 	SessionStoppedEvent        EventType = "session:stopped"
 	NotificationEvent          EventType = "notification:show"
 	ClaudeMessageEvent         EventType = "claude:message"
+	SessionWatchMatchedEvent   EventType = "session:watch_matched"
+	SessionAttentionEvent      EventType = "session:attention"
+	SessionBudgetEvent         EventType = "session:budget"
+	DependencyUpdateEvent      EventType = "dependency_update:progress"
+	AutomationRecoveredEvent   EventType = "automation:recovered"
+	AutomationAbandonedEvent   EventType = "automation:abandoned"
+	OutboxOperationEvent       EventType = "outbox:finished"
+	PortPublishRulesEvent      EventType = "port:publish_rules"
+	ClaudePermissionEvent      EventType = "claude:permission"
+	HygieneReportEvent         EventType = "hygiene:report"
+	RepositoryAddedEvent       EventType = "repository:added"
 )
 
 type AppEvent struct {
@@ -109,6 +121,14 @@ type WorktreeDirtyPayload struct {
 	Files        []string `json:"files,omitempty"`
 }
 
+type WorktreeSetupStalePayload struct {
+	WorktreeID   string   `json:"worktree_id"`
+	WorktreeName string   `json:"worktree_name"`
+	Files        []string `json:"files"`
+	// Whether setup.sh was re-run automatically; otherwise the UI should offer it
+	Rerun bool `json:"rerun"`
+}
+
 type WorktreeUpdatedPayload struct {
 	WorktreeID string                 `json:"worktree_id"`
 	Updates    map[string]interface{} `json:"updates"`
@@ -151,6 +171,16 @@ type ClaudeMessagePayload struct {
 	Timestamp    int64  `json:"timestamp"`
 }
 
+type SessionWatchMatchedPayload struct {
+	SessionID string `json:"session_id"`
+	WatchID   string `json:"watch_id"`
+	Pattern   string `json:"pattern"`
+	Label     string `json:"label,omitempty"`
+	Match     string `json:"match"`
+	Line      string `json:"line"`
+	Timestamp int64  `json:"timestamp"`
+}
+
 type SSEMessage struct {
 	Event     AppEvent `json:"event"`
 	Timestamp int64    `json:"timestamp"`
@@ -230,6 +260,9 @@ func NewEventsHandler(portMonitor *services.PortMonitor, gitService *services.Gi
 // @Description   - `timestamp` (int64): Current timestamp in milliseconds
 // @Description   - `uptime` (int64): Server uptime in milliseconds
 // @Description
+// @Description ## Filtering
+// @Description Pass `label` (e.g. `experiment,!keep`) to only receive worktree events for worktrees matching the label selector. Events not tied to a worktree are always sent.
+// @Description
 // @Description ## Message Format
 // @Description Each SSE message is a JSON object with:
 // @Description - `event`: Event object containing `type` and `payload`
@@ -244,6 +277,7 @@ func NewEventsHandler(portMonitor *services.PortMonitor, gitService *services.Gi
 // @Tags events
 // @Accept text/event-stream
 // @Produce text/event-stream
+// @Param label query string false "Label selector for worktree events"
 // @Success 200 {object} SSEMessage "SSE stream of events"
 // @Router /v1/events [get]
 // HandleSSE streams container / port / git / process events to the browser.
@@ -257,6 +291,10 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 			"error": "This endpoint only accepts Server-Sent Events (text/event-stream)",
 		})
 	}
+	labels, err := services.ParseLabelSelector(c.Query("label"))
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err))
+	}
 
 	//--------------------------------------------------------------------
 	// 2.  Prepare HTTP headers once
@@ -292,6 +330,12 @@ func (h *EventsHandler) HandleSSE(c *fiber.Ctx) error {
 			if msg.Event.Type == "" { // guard against empty events
 				return true
 			}
+			if !labels.Empty() {
+				var ok bool
+				if msg, ok = h.filterByLabels(msg, labels); !ok {
+					return true
+				}
+			}
 
 			// Debug session:stopped events specifically
 			if msg.Event.Type == SessionStoppedEvent {
@@ -386,6 +430,52 @@ func (h *EventsHandler) removeClient(id string) {
 	h.clientsMux.Unlock()
 }
 
+// filterByLabels drops worktree events for worktrees the selector doesn't
+// match and trims batch updates to matching worktrees. Events that aren't about
+// a worktree, and deletions (the worktree is already gone), always pass.
+func (h *EventsHandler) filterByLabels(msg SSEMessage, selector services.LabelSelector) (SSEMessage, bool) {
+	var worktreeID string
+	switch payload := msg.Event.Payload.(type) {
+	case WorktreeBatchPayload:
+		updates := make(map[string]*services.CachedWorktreeStatus)
+		for id, status := range payload.Updates {
+			if h.gitService.WorktreeMatchesLabels(id, selector) {
+				updates[id] = status
+			}
+		}
+		if len(updates) == 0 {
+			return msg, false
+		}
+		msg.Event.Payload = WorktreeBatchPayload{Updates: updates}
+		return msg, true
+	case WorktreeCreatedPayload:
+		worktree, ok := payload.Worktree.(*models.Worktree)
+		return msg, ok && selector.Matches(worktree.Labels)
+	case WorktreeStatusPayload:
+		worktreeID = payload.WorktreeID
+	case WorktreeDirtyPayload:
+		worktreeID = payload.WorktreeID
+	case WorktreeSetupStalePayload:
+		worktreeID = payload.WorktreeID
+	case WorktreeUpdatedPayload:
+		worktreeID = payload.WorktreeID
+	case WorktreeTodosUpdatedPayload:
+		worktreeID = payload.WorktreeID
+	case SessionTitleUpdatedPayload:
+		worktreeID = payload.WorktreeID
+	case ClaudeMessagePayload:
+		worktreeID = payload.WorktreeID
+	case SessionStoppedPayload:
+		if payload.WorktreeID != nil {
+			worktreeID = *payload.WorktreeID
+		}
+	}
+	if worktreeID == "" {
+		return msg, true
+	}
+	return msg, h.gitService.WorktreeMatchesLabels(worktreeID, selector)
+}
+
 // --- small builders to keep main handler tiny ---
 func (h *EventsHandler) makeHeartbeat() SSEMessage {
 	return SSEMessage{
@@ -704,6 +794,19 @@ func (h *EventsHandler) EmitWorktreeClean(worktreeID, worktreeName string) {
 	})
 }
 
+// EmitWorktreeSetupStale broadcasts that a sync changed files setup.sh depends on
+func (h *EventsHandler) EmitWorktreeSetupStale(worktreeID, worktreeName string, files []string, rerun bool) {
+	h.broadcastEvent(AppEvent{
+		Type: WorktreeSetupStaleEvent,
+		Payload: WorktreeSetupStalePayload{
+			WorktreeID:   worktreeID,
+			WorktreeName: worktreeName,
+			Files:        files,
+			Rerun:        rerun,
+		},
+	})
+}
+
 // EmitWorktreeUpdated broadcasts a worktree updated event to all connected clients
 func (h *EventsHandler) EmitWorktreeUpdated(worktreeID string, updates map[string]interface{}) {
 	h.broadcastEvent(AppEvent{
@@ -789,6 +892,227 @@ func (h *EventsHandler) EmitClaudeMessage(workspaceDir, worktreeID, message, mes
 	})
 }
 
+// EmitSessionWatchMatched broadcasts a PTY watch hit, plus a notification so
+// the TUI/native notification relay can surface it
+func (h *EventsHandler) EmitSessionWatchMatched(match services.WatchMatch) {
+	h.broadcastEvent(AppEvent{
+		Type: SessionWatchMatchedEvent,
+		Payload: SessionWatchMatchedPayload{
+			SessionID: match.SessionID,
+			WatchID:   match.Watch.ID,
+			Pattern:   match.Watch.Pattern,
+			Label:     match.Watch.Label,
+			Match:     match.Match,
+			Line:      match.Line,
+			Timestamp: time.Now().UnixMilli(),
+		},
+	})
+
+	title := match.Watch.Label
+	if title == "" {
+		title = match.Watch.Pattern
+	}
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    title,
+			Body:     match.Line,
+			Subtitle: match.SessionID,
+		},
+	})
+}
+
+// EmitSessionAttention broadcasts a bell or OSC 777 notification from a PTY
+// session, plus a notification for the native relay when the signal asks for one
+func (h *EventsHandler) EmitSessionAttention(signal services.AttentionSignal) {
+	h.broadcastEvent(AppEvent{
+		Type:    SessionAttentionEvent,
+		Payload: signal,
+	})
+
+	if !signal.Notify {
+		return
+	}
+	title := signal.Title
+	if title == "" {
+		title = "Terminal needs attention"
+	}
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    title,
+			Body:     signal.Body,
+			Subtitle: signal.SessionID,
+		},
+	})
+}
+
+// EmitSessionBudget broadcasts a time-boxed session running out of budget or
+// ending after it was asked to wrap up
+func (h *EventsHandler) EmitSessionBudget(status services.SessionBudgetStatus) {
+	h.broadcastEvent(AppEvent{
+		Type:    SessionBudgetEvent,
+		Payload: status,
+	})
+
+	var title string
+	switch status.Phase {
+	case services.SessionBudgetWrappingUp:
+		title = "Session budget spent, wrapping up"
+	case services.SessionBudgetStopped:
+		title = "Session stopped after wrap-up"
+	default:
+		return
+	}
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    title,
+			Body:     fmt.Sprintf("%s budget exceeded", status.Reason),
+			Subtitle: status.WorkingDirectory,
+		},
+	})
+}
+
+// EmitClaudePermission broadcasts a relayed permission prompt when it's raised
+// and again when it's answered or expires
+func (h *EventsHandler) EmitClaudePermission(request models.ClaudePermissionRequest) {
+	h.broadcastEvent(AppEvent{
+		Type:    ClaudePermissionEvent,
+		Payload: request,
+	})
+
+	if request.Status != models.ClaudePermissionPending {
+		return
+	}
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    "Claude needs permission",
+			Body:     fmt.Sprintf("Allow %s?", request.ToolName),
+			Subtitle: request.WorkingDirectory,
+		},
+	})
+}
+
+// EmitDependencyUpdateProgress broadcasts the latest state of a dependency update run
+func (h *EventsHandler) EmitDependencyUpdateProgress(run *services.DependencyUpdateRun) {
+	h.broadcastEvent(AppEvent{
+		Type:    DependencyUpdateEvent,
+		Payload: run,
+	})
+
+	if run.FinishedAt == nil {
+		return
+	}
+	body := fmt.Sprintf("Dependency update for %s: %s", run.RepoID, run.Status)
+	if run.PullRequestURL != "" {
+		body = "Opened " + run.PullRequestURL
+	} else if run.Error != "" {
+		body = run.Error
+	}
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    "Dependency update " + string(run.Status),
+			Body:     body,
+			Subtitle: run.WorktreeName,
+			URL:      run.PullRequestURL,
+		},
+	})
+}
+
+// EmitAutomationJobRecovered broadcasts that an automation interrupted by a
+// restart was resumed
+func (h *EventsHandler) EmitAutomationJobRecovered(job *services.AutomationJob) {
+	h.broadcastEvent(AppEvent{
+		Type:    AutomationRecoveredEvent,
+		Payload: job,
+	})
+}
+
+// EmitAutomationJobAbandoned broadcasts that an automation interrupted by a
+// restart could not be resumed
+func (h *EventsHandler) EmitAutomationJobAbandoned(job *services.AutomationJob) {
+	h.broadcastEvent(AppEvent{
+		Type:    AutomationAbandonedEvent,
+		Payload: job,
+	})
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    "Automation interrupted",
+			Body:     fmt.Sprintf("%s was abandoned after a restart: %s", job.Description, job.Error),
+			Subtitle: string(job.Kind),
+		},
+	})
+}
+
+// EmitOutboxOperationFinished broadcasts that an operation queued while
+// offline ran after connectivity returned
+func (h *EventsHandler) EmitOutboxOperationFinished(op *services.OutboxOperation) {
+	h.broadcastEvent(AppEvent{
+		Type:    OutboxOperationEvent,
+		Payload: op,
+	})
+	title, body := "Queued operation completed", fmt.Sprintf("Ran %s", op.Description)
+	if op.Status == services.OutboxFailed {
+		title, body = "Queued operation failed", fmt.Sprintf("%s failed: %s", op.Description, op.LastError)
+	} else if op.Result != "" {
+		body = fmt.Sprintf("Ran %s: %s", op.Description, op.Result)
+	}
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    title,
+			Body:     body,
+			Subtitle: string(op.Kind),
+		},
+	})
+}
+
+// EmitPortPublishRulesChanged broadcasts the host publish rules so the host
+// helper can bind or release listeners
+func (h *EventsHandler) EmitPortPublishRulesChanged(rules []services.PortPublishRule) {
+	h.broadcastEvent(AppEvent{
+		Type:    PortPublishRulesEvent,
+		Payload: fiber.Map{"rules": rules},
+	})
+}
+
+// EmitHygieneReport broadcasts a finished workspace hygiene report, plus a
+// notification summarizing it
+func (h *EventsHandler) EmitHygieneReport(report *services.HygieneReport) {
+	h.broadcastEvent(AppEvent{
+		Type:    HygieneReportEvent,
+		Payload: report,
+	})
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title: "Workspace hygiene report",
+			Body:  report.Summary(),
+		},
+	})
+}
+
+// EmitRepositoryAdded broadcasts a local repository mounted while catnip was
+// running, plus a notification so it's noticed
+func (h *EventsHandler) EmitRepositoryAdded(repo *models.Repository) {
+	h.broadcastEvent(AppEvent{
+		Type:    RepositoryAddedEvent,
+		Payload: repo,
+	})
+	h.broadcastEvent(AppEvent{
+		Type: NotificationEvent,
+		Payload: NotificationPayload{
+			Title:    "Repository mounted",
+			Body:     fmt.Sprintf("%s is ready to use", repo.ID),
+			Subtitle: repo.Path,
+		},
+	})
+}
+
 // Stop stops the events handler and cleans up resources
 func (h *EventsHandler) Stop() {
 	close(h.stopChan)
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// SubmitFeedback records thumbs-up/down feedback for a Claude response
+// @Summary Submit feedback on a Claude response
+// @Description Records a rating for a completion (by completion_id) or a session message (by session_id/message_id), joined with the prompt, model and workspace when known
+// @Tags claude
+// @Accept json
+// @Produce json
+// @Param request body models.ClaudeFeedbackRequest true "Feedback"
+// @Success 200 {object} models.ClaudeFeedback
+// @Failure 400 {object} map[string]string
+// @Router /v1/claude/feedback [post]
+func (h *ClaudeHandler) SubmitFeedback(c *fiber.Ctx) error {
+	if h.feedbackService == nil {
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Feedback service not initialized"))
+	}
+
+	var req models.ClaudeFeedbackRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	if req.WorkingDirectory != "" {
+		req.WorkingDirectory = config.Runtime.ResolvePath(req.WorkingDirectory)
+	}
+
+	feedback, err := h.feedbackService.SubmitFeedback(&req)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	logger.Debugf("👍 Recorded %s feedback (completion: %s, session: %s)", feedback.Rating, feedback.CompletionID, feedback.SessionID)
+	return c.JSON(feedback)
+}
+
+// parseFeedbackFilter builds a feedback filter from query parameters
+func parseFeedbackFilter(c *fiber.Ctx) (services.FeedbackFilter, error) {
+	filter := services.FeedbackFilter{
+		Rating:           models.FeedbackRating(c.Query("rating")),
+		WorkingDirectory: c.Query("working_directory"),
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return filter, models.NewAPIError(models.ErrCodeInvalidRequest, "since must be an RFC3339 timestamp")
+		}
+		filter.Since = t
+	}
+	return filter, nil
+}
+
+// ListFeedback returns stored feedback records
+// @Summary List Claude feedback
+// @Description Returns stored feedback, optionally filtered by rating, workspace and time
+// @Tags claude
+// @Produce json
+// @Param rating query string false "Filter by rating (up/down)"
+// @Param working_directory query string false "Filter by workspace path"
+// @Param since query string false "Only include feedback after this RFC3339 timestamp"
+// @Success 200 {array} models.ClaudeFeedback
+// @Router /v1/claude/feedback [get]
+func (h *ClaudeHandler) ListFeedback(c *fiber.Ctx) error {
+	if h.feedbackService == nil {
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Feedback service not initialized"))
+	}
+
+	filter, err := parseFeedbackFilter(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	feedback, err := h.feedbackService.ListFeedback(filter)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(feedback)
+}
+
+// ExportFeedback downloads stored feedback as JSONL or CSV
+// @Summary Export Claude feedback
+// @Description Downloads stored feedback for offline analysis as JSONL (default) or CSV
+// @Tags claude
+// @Produce plain
+// @Param format query string false "Export format (jsonl or csv)"
+// @Param rating query string false "Filter by rating (up/down)"
+// @Param working_directory query string false "Filter by workspace path"
+// @Param since query string false "Only include feedback after this RFC3339 timestamp"
+// @Success 200 {string} string "Feedback export"
+// @Router /v1/claude/feedback/export [get]
+func (h *ClaudeHandler) ExportFeedback(c *fiber.Ctx) error {
+	if h.feedbackService == nil {
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "Feedback service not initialized"))
+	}
+
+	filter, err := parseFeedbackFilter(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	feedback, err := h.feedbackService.ListFeedback(filter)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	format := c.Query("format", "jsonl")
+	switch format {
+	case "jsonl":
+		c.Set("Content-Type", "application/x-ndjson")
+		c.Set("Content-Disposition", `attachment; filename="claude-feedback.jsonl"`)
+		encoder := json.NewEncoder(c.Response().BodyWriter())
+		for _, record := range feedback {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "csv":
+		c.Set("Content-Type", "text/csv")
+		c.Set("Content-Disposition", `attachment; filename="claude-feedback.csv"`)
+		writer := csv.NewWriter(c.Response().BodyWriter())
+		_ = writer.Write([]string{"id", "created_at", "rating", "completion_id", "session_id", "message_id", "model", "working_directory", "prompt", "comment"})
+		for _, record := range feedback {
+			_ = writer.Write([]string{
+				record.ID,
+				record.CreatedAt.Format(time.RFC3339),
+				string(record.Rating),
+				record.CompletionID,
+				record.SessionID,
+				record.MessageID,
+				record.Model,
+				record.WorkingDirectory,
+				record.Prompt,
+				record.Comment,
+			})
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default:
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "unsupported export format: %s", format))
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bufio"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -8,8 +9,10 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
 	"github.com/vanpelt/catnip/internal/logger"
 	"github.com/vanpelt/catnip/internal/models"
 	"github.com/vanpelt/catnip/internal/services"
@@ -21,6 +24,11 @@ type GitHandler struct {
 	gitHTTPService *services.GitHTTPService
 	sessionService *services.SessionService
 	claudeMonitor  *services.ClaudeMonitorService
+	outbox         *services.OutboxService
+	diskLayout     *services.DiskLayoutScanner
+	composites     *services.CompositeWorkspaceService
+	previews       *services.PreviewService
+	summaries      *services.WorktreeSummarizer
 }
 
 // CheckoutResponse represents the response when checking out a repository
@@ -124,6 +132,41 @@ func NewGitHandler(gitService *services.GitService, gitHTTPService *services.Git
 	}
 }
 
+// WithOutbox queues pull requests and fetches while GitHub is unreachable
+// instead of failing them
+func (h *GitHandler) WithOutbox(outbox *services.OutboxService) *GitHandler {
+	h.outbox = outbox
+	return h
+}
+
+// WithDiskLayout sets the scanner behind the worktree layout endpoint
+func (h *GitHandler) WithDiskLayout(scanner *services.DiskLayoutScanner) *GitHandler {
+	h.diskLayout = scanner
+	return h
+}
+
+// WithComposites lets standby worktrees take a merged worktree's place in composite workspaces
+func (h *GitHandler) WithComposites(composites *services.CompositeWorkspaceService) *GitHandler {
+	h.composites = composites
+	return h
+}
+
+// queueIfOffline queues an operation that failed (or would fail) because
+// GitHub is unreachable. It returns false if the operation should fail as usual.
+func (h *GitHandler) queueIfOffline(c *fiber.Ctx, err error, kind services.OutboxOperationKind, worktreeID, description string, params any) (bool, error) {
+	if h.outbox == nil || (err != nil && !services.IsOfflineError(err)) {
+		return false, nil
+	}
+	if err != nil {
+		h.outbox.MarkOffline()
+	}
+	op, queueErr := h.outbox.Enqueue(kind, worktreeID, description, params)
+	if queueErr != nil {
+		return true, respondError(c, 500, queueErr)
+	}
+	return true, c.Status(202).JSON(op)
+}
+
 // generateWorktreesETag generates an ETag hash from worktrees data
 func generateWorktreesETag(worktrees []*EnhancedWorktree) (string, error) {
 	// Marshal the worktrees to JSON for consistent hashing
@@ -138,29 +181,62 @@ func generateWorktreesETag(worktrees []*EnhancedWorktree) (string, error) {
 }
 
 // CheckoutRepository handles repository checkout requests
-// @Summary Checkout a GitHub repository
-// @Description Clones a GitHub repository as a bare repo and creates initial worktree
+// @Summary Checkout a GitHub, GitLab or Bitbucket repository
+// @Description Clones a repository as a bare repo and creates initial worktree. Repositories are on GitHub unless host names gitlab.com, a self-hosted GitLab listed in CATNIP_GITLAB_HOSTS or bitbucket.org; their IDs then start with the host (e.g. gitlab.com/group/project).
 // @Tags git
 // @Accept json
 // @Produce json
-// @Param org path string true "Organization name"
+// @Param org path string true "Organization name (GitLab subgroups URL-encoded, e.g. group%2Fsubgroup)"
 // @Param repo path string true "Repository name"
+// @Param host query string false "Git host (optional, default github.com)"
 // @Param branch query string false "Branch name (optional)"
+// @Param tag query string false "Tag to create the worktree from (optional, exclusive with branch/commit)"
+// @Param commit query string false "Commit SHA to create the worktree from (optional, exclusive with branch/tag)"
 // @Success 200 {object} CheckoutResponse
 // @Router /v1/git/checkout/{org}/{repo} [post]
 func (h *GitHandler) CheckoutRepository(c *fiber.Ctx) error {
 	org := c.Params("org")
 	repo := c.Params("repo")
+	if host := strings.ToLower(c.Query("host")); host != "" && host != "github.com" {
+		if unescaped, err := url.PathUnescape(org); err == nil {
+			org = unescaped
+		}
+		org = host + "/" + org
+	}
 	branch := c.Query("branch", "")
+	tag := c.Query("tag", "")
+	commit := c.Query("commit", "")
 
-	logger.Infof("📦 Checkout request: %s/%s (branch: %s)", org, repo, branch)
+	selected := 0
+	for _, v := range []string{branch, tag, commit} {
+		if v != "" {
+			selected++
+		}
+	}
+	if selected > 1 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Only one of branch, tag or commit may be specified",
+		})
+	}
 
-	repository, worktree, err := h.gitService.CheckoutRepository(org, repo, branch)
+	logger.Infof("📦 Checkout request: %s/%s (branch: %s, tag: %s, commit: %s)", org, repo, branch, tag, commit)
+
+	var (
+		repository *models.Repository
+		worktree   *models.Worktree
+		err        error
+	)
+	switch {
+	case tag != "":
+		repository, worktree, err = h.gitService.CheckoutRepositoryAtRef(org, repo, models.SourceRefTag, tag)
+	case commit != "":
+		repository, worktree, err = h.gitService.CheckoutRepositoryAtRef(org, repo, models.SourceRefCommit, commit)
+	default:
+		repository, worktree, err = h.gitService.CheckoutRepository(org, repo, branch)
+	}
 	if err != nil {
 		logger.Errorf("❌ Checkout failed: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -201,14 +277,25 @@ type WorktreeCacheStatus struct {
 // @Tags git
 // @Produce json
 // @Param If-None-Match header string false "ETag from previous request"
+// @Param label query string false "Label selector, e.g. experiment,!keep (a leading ! excludes)"
 // @Success 200 {array} EnhancedWorktree
 // @Success 304 "Not Modified - content unchanged"
+// @Failure 400 {object} map[string]string
 // @Router /v1/git/worktrees [get]
 func (h *GitHandler) ListWorktrees(c *fiber.Ctx) error {
+	selector, err := services.ParseLabelSelector(c.Query("label"))
+	if err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err))
+	}
+
 	worktrees := h.gitService.ListWorktrees()
 	enhancedWorktrees := make([]*EnhancedWorktree, 0, len(worktrees))
 
 	for _, worktree := range worktrees {
+		if !selector.Matches(worktree.Labels) {
+			continue
+		}
+
 		// Enhance worktrees with session information
 		if sessionInfo, exists := h.sessionService.GetActiveSession(worktree.Path); exists {
 			// Convert services.TitleEntry to models.TitleEntry
@@ -336,6 +423,502 @@ func (h *GitHandler) UpdateWorktree(c *fiber.Ctx) error {
 	return c.JSON(worktree)
 }
 
+// GetWorktreeIdentity returns the commit identities that apply to a worktree
+// @Summary Get worktree git identity
+// @Description Returns the worktree's own identity overrides and the effective identities after falling back to the repository's settings
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} WorktreeIdentityResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/identity [get]
+func (h *GitHandler) GetWorktreeIdentity(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		return respondError(c, 404, models.NewWorktreeNotFoundError(worktreeID))
+	}
+
+	return c.JSON(WorktreeIdentityResponse{
+		Worktree:  worktree.Identity,
+		Effective: h.gitService.EffectiveIdentity(worktree),
+	})
+}
+
+// WorktreeIdentityResponse describes a worktree's commit identities
+type WorktreeIdentityResponse struct {
+	// Identity overrides set on the worktree itself (null if it inherits the repository's)
+	Worktree *models.GitIdentitySettings `json:"worktree"`
+	// Identities actually used for commits in this worktree
+	Effective *models.GitIdentitySettings `json:"effective"`
+}
+
+// UpdateWorktreeIdentity sets the commit identities for a worktree
+// @Summary Set worktree git identity
+// @Description Sets the user identity (applied via worktree git config) and optional bot identity (used for automated checkpoint commits). Send null fields to inherit from the repository.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param identity body models.GitIdentitySettings true "Identity settings"
+// @Success 200 {object} WorktreeIdentityResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/identity [put]
+func (h *GitHandler) UpdateWorktreeIdentity(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var settings models.GitIdentitySettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+
+	var override *models.GitIdentitySettings
+	if settings.User != nil || settings.Bot != nil {
+		override = &settings
+	}
+
+	effective, err := h.gitService.SetWorktreeIdentity(worktreeID, override)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(WorktreeIdentityResponse{
+		Worktree:  override,
+		Effective: effective,
+	})
+}
+
+// UpdateRepositoryIdentity sets the default commit identities for a repository's worktrees
+// @Summary Set repository git identity
+// @Description Sets the default user and bot identities for all worktrees of a repository that don't override them
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param identity body models.GitIdentitySettings true "Identity settings"
+// @Success 200 {object} models.GitIdentitySettings
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/identity [put]
+func (h *GitHandler) UpdateRepositoryIdentity(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var settings models.GitIdentitySettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+
+	var identity *models.GitIdentitySettings
+	if settings.User != nil || settings.Bot != nil {
+		identity = &settings
+	}
+
+	if err := h.gitService.SetRepositoryIdentity(repoID, identity); err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(settings)
+}
+
+// NetworkPolicyResponse describes git network timeouts and retries
+type NetworkPolicyResponse struct {
+	// Global defaults (from CATNIP_GIT_* environment variables)
+	Defaults models.GitNetworkSettings `json:"defaults"`
+	// Repository override (null if the repository uses the defaults)
+	Repository *models.GitNetworkSettings `json:"repository,omitempty"`
+	// Settings applied to the repository's git commands
+	Effective *models.GitNetworkSettings `json:"effective,omitempty"`
+}
+
+// GetNetworkPolicy returns the default git network timeouts and retries
+// @Summary Get git network policy
+// @Description Returns the default timeouts and retry count for fetch, pull, push, clone and ls-remote
+// @Tags git
+// @Produce json
+// @Success 200 {object} NetworkPolicyResponse
+// @Router /v1/git/network [get]
+func (h *GitHandler) GetNetworkPolicy(c *fiber.Ctx) error {
+	return c.JSON(NetworkPolicyResponse{
+		Defaults: h.gitService.GetNetworkPolicy().Settings(),
+	})
+}
+
+// GetOperationQueues returns per-repository operation queue metrics
+// @Summary Get repository operation queues
+// @Description Returns, per repository, the concurrency limit (CATNIP_REPO_CONCURRENCY), running and queued worktree operations, and wait time statistics
+// @Tags git
+// @Produce json
+// @Success 200 {array} services.RepoQueueMetrics
+// @Router /v1/git/operations [get]
+func (h *GitHandler) GetOperationQueues(c *fiber.Ctx) error {
+	return c.JSON(h.gitService.GetRepoQueueMetrics())
+}
+
+// GetRepositoryNetworkPolicy returns a repository's git network settings
+// @Summary Get repository git network policy
+// @Description Returns the default, overridden and effective git network timeouts and retries for a repository
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Success 200 {object} NetworkPolicyResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/network [get]
+func (h *GitHandler) GetRepositoryNetworkPolicy(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	override, effective, err := h.gitService.GetRepositoryNetworkPolicy(repoID)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	effectiveSettings := effective.Settings()
+	return c.JSON(NetworkPolicyResponse{
+		Defaults:   h.gitService.GetNetworkPolicy().Settings(),
+		Repository: override,
+		Effective:  &effectiveSettings,
+	})
+}
+
+// UpdateRepositoryNetworkPolicy overrides git network settings for a repository
+// @Summary Set repository git network policy
+// @Description Overrides timeouts and retries for network git commands run against a repository. Omitted fields inherit the defaults; an empty body clears the override.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param network body models.GitNetworkSettings true "Network settings"
+// @Success 200 {object} NetworkPolicyResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/network [put]
+func (h *GitHandler) UpdateRepositoryNetworkPolicy(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var settings models.GitNetworkSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+
+	var override *models.GitNetworkSettings
+	if settings != (models.GitNetworkSettings{}) {
+		override = &settings
+	}
+
+	effective, err := h.gitService.SetRepositoryNetworkPolicy(repoID, override)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	effectiveSettings := effective.Settings()
+	return c.JSON(NetworkPolicyResponse{
+		Defaults:   h.gitService.GetNetworkPolicy().Settings(),
+		Repository: override,
+		Effective:  &effectiveSettings,
+	})
+}
+
+// FetchSettingsResponse is a repository's fetch override and telemetry
+type FetchSettingsResponse struct {
+	// Repository override (null if the repository uses the adaptive defaults)
+	Repository *models.FetchSettings `json:"repository,omitempty"`
+	// Recent fetches and the strategies the next ones would use
+	Telemetry models.FetchTelemetry `json:"telemetry"`
+}
+
+// GetRepositoryFetchSettings returns how a repository's branches are fetched
+// @Summary Get repository fetch settings
+// @Description Returns a repository's fetch override and telemetry: the moving average of fetch durations, repository size, shallowness, background deepening and the depth/filter the next status refresh and sync would use. Without an override, status refreshes skip --depth on fast links, use shallow fetches (deepened in the background) on slow links or large repositories, and add a blob filter to partial clones.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Success 200 {object} FetchSettingsResponse
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/fetch [get]
+func (h *GitHandler) GetRepositoryFetchSettings(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	override, telemetry, err := h.gitService.GetRepositoryFetchTelemetry(repoID)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(FetchSettingsResponse{Repository: override, Telemetry: telemetry})
+}
+
+// UpdateRepositoryFetchSettings overrides how a repository's branches are fetched
+// @Summary Set repository fetch settings
+// @Description Pins a repository's fetch mode (auto, shallow or full), the depth of shallow fetches and whether shallow history is deepened in the background. An empty body restores the adaptive defaults.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param fetch body models.FetchSettings true "Fetch settings"
+// @Success 200 {object} FetchSettingsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/fetch [put]
+func (h *GitHandler) UpdateRepositoryFetchSettings(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var settings models.FetchSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+
+	var override *models.FetchSettings
+	if settings.Mode != "" || settings.Depth != 0 || settings.BackgroundDeepen != nil {
+		override = &settings
+	}
+	if err := h.gitService.SetRepositoryFetchSettings(repoID, override); err != nil {
+		return respondError(c, 500, err)
+	}
+
+	_, telemetry, err := h.gitService.GetRepositoryFetchTelemetry(repoID)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(FetchSettingsResponse{Repository: override, Telemetry: telemetry})
+}
+
+// GetRepositoryBranchNaming returns a repository's branch naming policy
+// @Summary Get repository branch naming policy
+// @Description Returns the prefixes, pattern and length limit branch names in a repository must follow. An empty object means names aren't restricted.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Success 200 {object} models.BranchNamingPolicy
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/branch-naming [get]
+func (h *GitHandler) GetRepositoryBranchNaming(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	policy, err := h.gitService.GetRepositoryBranchNaming(repoID)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	if policy == nil {
+		policy = &models.BranchNamingPolicy{}
+	}
+	return c.JSON(policy)
+}
+
+// UpdateRepositoryBranchNaming sets a repository's branch naming policy
+// @Summary Set repository branch naming policy
+// @Description Restricts the names catnip branches are renamed to and users give branches in a repository: required prefixes (any one of them), a regular expression and a maximum length. Claude's branch name suggestions are adjusted to the policy; custom names that break it are rejected. An empty body lifts the restrictions.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param policy body models.BranchNamingPolicy true "Branch naming policy"
+// @Success 200 {object} models.BranchNamingPolicy
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/branch-naming [put]
+func (h *GitHandler) UpdateRepositoryBranchNaming(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var policy models.BranchNamingPolicy
+	if err := c.BodyParser(&policy); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+
+	var override *models.BranchNamingPolicy
+	if len(policy.Prefixes) > 0 || policy.Pattern != "" || policy.MaxLength > 0 {
+		override = &policy
+	}
+	if err := h.gitService.SetRepositoryBranchNaming(repoID, override); err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(policy)
+}
+
+// CheckBranchName checks a branch name against a repository's naming policy
+// @Summary Check a branch name
+// @Description Reports whether a branch name is valid in a repository and, if not, why and a compliant name derived from it: invalid characters become dashes, a missing prefix replaces the name's own type segment (feat/login becomes feature/login when feature/ is required) and long names are shortened.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param name query string true "Branch name to check"
+// @Success 200 {object} services.BranchNameCheck
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/branch-naming/check [get]
+func (h *GitHandler) CheckBranchName(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	check, err := h.gitService.CheckBranchName(repoID, c.Query("name"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(check)
+}
+
+// CodeOwnerReviewsSetting turns code owner review requests on or off
+type CodeOwnerReviewsSetting struct {
+	// Whether pull requests catnip opens request reviews from the CODEOWNERS of the changed files
+	Enabled bool `json:"enabled" example:"true"`
+}
+
+// GetRepositoryCodeOwnerReviews returns whether new pull requests request code owner reviews
+// @Summary Get repository code owner reviews setting
+// @Description Returns whether pull requests catnip opens for a repository request reviews from the code owners of the files they change. The reviewers a branch's pull request would get are previewed in its PR info.
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Success 200 {object} CodeOwnerReviewsSetting
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/code-owner-reviews [get]
+func (h *GitHandler) GetRepositoryCodeOwnerReviews(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	enabled, err := h.gitService.GetRepositoryCodeOwnerReviews(repoID)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(CodeOwnerReviewsSetting{Enabled: enabled})
+}
+
+// UpdateRepositoryCodeOwnerReviews turns code owner review requests on or off
+// @Summary Set repository code owner reviews setting
+// @Description Turns requesting reviews from code owners on or off for pull requests catnip opens for a repository. When on (the default), the owners of the changed files in the base branch's CODEOWNERS (.github/, the root or docs/) are requested as reviewers, except the pull request's author. Owners given by email are skipped.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param setting body CodeOwnerReviewsSetting true "Code owner reviews setting"
+// @Success 200 {object} CodeOwnerReviewsSetting
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/code-owner-reviews [put]
+func (h *GitHandler) UpdateRepositoryCodeOwnerReviews(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var setting CodeOwnerReviewsSetting
+	if err := c.BodyParser(&setting); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+	}
+
+	if err := h.gitService.SetRepositoryCodeOwnerReviews(repoID, setting.Enabled); err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(setting)
+}
+
+// GetRepositoryAgentCosts reports what each pull request cost in agent time
+// @Summary Get agent cost report
+// @Description Returns Claude token usage, estimated cost and time per pull request for a repository, for PRs created or updated in the given month
+// @Tags git
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param month query string false "Month in YYYY-MM format (defaults to the current month)"
+// @Param tz query string false "IANA time zone months are bucketed in (defaults to CATNIP_TIMEZONE)"
+// @Success 200 {object} models.AgentCostReport
+// @Failure 400 {object} map[string]string
+// @Router /v1/git/repositories/{id}/costs [get]
+func (h *GitHandler) GetRepositoryAgentCosts(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	report, err := h.gitService.GetAgentCostReport(repoID, c.Query("month"), c.Query("tz"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(report)
+}
+
+// StateRecoveryResponse reports crash recovery of interrupted multi-step operations
+type StateRecoveryResponse struct {
+	// Actions taken by the recovery pass at startup
+	Recovered []services.RecoveryAction `json:"recovered"`
+	// Operations currently in flight
+	Pending []services.JournalEntry `json:"pending"`
+}
+
+// GetStateRecovery returns what the startup journal recovery fixed
+// @Summary Get state recovery report
+// @Description Returns the interrupted operations (worktree creation, branch rename) that were completed or rolled back on startup, and any operations currently in flight
+// @Tags git
+// @Produce json
+// @Success 200 {object} StateRecoveryResponse
+// @Router /v1/git/recovery [get]
+func (h *GitHandler) GetStateRecovery(c *fiber.Ctx) error {
+	recovered, pending := h.gitService.GetRecoveryReport()
+	if recovered == nil {
+		recovered = []services.RecoveryAction{}
+	}
+	if pending == nil {
+		pending = []services.JournalEntry{}
+	}
+	return c.JSON(StateRecoveryResponse{Recovered: recovered, Pending: pending})
+}
+
 // ListGitHubRepositories returns user's GitHub repositories
 // @Summary List GitHub repositories
 // @Description Returns a list of GitHub repositories accessible to the authenticated user
@@ -346,9 +929,7 @@ func (h *GitHandler) UpdateWorktree(c *fiber.Ctx) error {
 func (h *GitHandler) ListGitHubRepositories(c *fiber.Ctx) error {
 	repos, err := h.gitService.ListGitHubRepositories()
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(repos)
@@ -375,9 +956,7 @@ func (h *GitHandler) GetRepositoryBranches(c *fiber.Ctx) error {
 
 	branches, err := h.gitService.GetRepositoryBranches(decodedRepoID)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 404, err)
 	}
 
 	return c.JSON(branches)
@@ -396,9 +975,7 @@ func (h *GitHandler) DeleteWorktree(c *fiber.Ctx) error {
 
 	_, err := h.gitService.DeleteWorktree(worktreeID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -439,24 +1016,80 @@ func (h *GitHandler) SyncWorktree(c *fiber.Ctx) error {
 		// Check if this is a merge conflict error
 		var mergeConflictErr *models.MergeConflictError
 		if errors.As(err, &mergeConflictErr) {
-			return c.Status(409).JSON(fiber.Map{
-				"error":          "merge_conflict",
-				"message":        mergeConflictErr.Message,
-				"operation":      mergeConflictErr.Operation,
-				"worktree_name":  mergeConflictErr.WorktreeName,
-				"worktree_path":  mergeConflictErr.WorktreePath,
-				"conflict_files": mergeConflictErr.ConflictFiles,
+			return respondMergeConflict(c, mergeConflictErr)
+		}
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Worktree synced successfully",
+		"id":       worktreeID,
+		"strategy": syncRequest.Strategy,
+	})
+}
+
+// UndoSync rolls a worktree back to its state before the last sync
+// @Summary Undo worktree sync
+// @Description Resets the worktree to its HEAD before the last merge/rebase sync, aborting a sync stopped on conflicts and restoring uncommitted changes auto-stashed for it. Changes made since the sync are stashed first. Only available within CATNIP_SYNC_UNDO_WINDOW (default 1h) of the sync; refuses if commits were made after it unless force is set.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param body body map[string]bool false "Undo options (force)"
+// @Success 200 {object} models.SyncUndoResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/sync/undo [post]
+func (h *GitHandler) UndoSync(c *fiber.Ctx) error {
+	var req struct {
+		Force bool `json:"force"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
 			})
 		}
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+	}
+
+	result, err := h.gitService.UndoSync(c.Params("id"), req.Force)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(result)
+}
+
+// FetchWorktree fetches a worktree's source branch from origin
+// @Summary Fetch worktree source branch
+// @Description Fetches the worktree's source branch from origin and refreshes its status. While GitHub is unreachable the fetch is queued and 202 returns the queued outbox operation.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} map[string]string
+// @Success 202 {object} services.OutboxOperation
+// @Router /v1/git/worktrees/{id}/fetch [post]
+func (h *GitHandler) FetchWorktree(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+	description := "fetch of " + worktreeID
+	if worktree, exists := h.gitService.GetWorktree(worktreeID); exists {
+		description = fmt.Sprintf("fetch of %s", worktree.Name)
+	}
+
+	if h.outbox != nil && !h.outbox.Online() {
+		_, err := h.queueIfOffline(c, nil, services.OutboxFetch, worktreeID, description, nil)
+		return err
+	}
+
+	if err := h.gitService.FetchWorktree(worktreeID); err != nil {
+		if queued, queueErr := h.queueIfOffline(c, err, services.OutboxFetch, worktreeID, description, nil); queued {
+			return queueErr
+		}
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(fiber.Map{
-		"message":  "Worktree synced successfully",
-		"id":       worktreeID,
-		"strategy": syncRequest.Strategy,
+		"message": "Worktree fetched successfully",
+		"id":      worktreeID,
 	})
 }
 
@@ -468,10 +1101,13 @@ func (h *GitHandler) SyncWorktree(c *fiber.Ctx) error {
 // @Produce json
 // @Param id path string true "Worktree ID"
 // @Param body body map[string]string false "Merge options"
+// @Param auto_cleanup query bool false "Delete the worktree after a successful merge"
+// @Param standby query bool false "With auto_cleanup, replace the worktree with a fresh one from the updated source branch"
 // @Success 200 {object} WorktreeOperationResponse
 // @Router /v1/git/worktrees/{id}/merge [post]
 func (h *GitHandler) MergeWorktreeToMain(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
+	snapshot := h.snapshotWorktree(worktreeID)
 
 	var mergeRequest struct {
 		Squash bool `json:"squash"`
@@ -484,18 +1120,9 @@ func (h *GitHandler) MergeWorktreeToMain(c *fiber.Ctx) error {
 		// Check if this is a merge conflict error
 		var mergeConflictErr *models.MergeConflictError
 		if errors.As(err, &mergeConflictErr) {
-			return c.Status(409).JSON(fiber.Map{
-				"error":          "merge_conflict",
-				"message":        mergeConflictErr.Message,
-				"operation":      mergeConflictErr.Operation,
-				"worktree_name":  mergeConflictErr.WorktreeName,
-				"worktree_path":  mergeConflictErr.WorktreePath,
-				"conflict_files": mergeConflictErr.ConflictFiles,
-			})
+			return respondMergeConflict(c, mergeConflictErr)
 		}
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	// Parse auto_cleanup parameter (default to false for safety)
@@ -514,34 +1141,130 @@ func (h *GitHandler) MergeWorktreeToMain(c *fiber.Ctx) error {
 			response["cleanup_warning"] = "Merge succeeded but worktree cleanup failed: " + cleanupErr.Error()
 		} else {
 			response["cleanup"] = "Worktree automatically deleted after successful merge"
+			if c.QueryBool("standby", false) && snapshot != nil {
+				standby, err := h.createStandby(snapshot)
+				if err != nil {
+					response["standby_warning"] = "Merge succeeded but the standby worktree could not be created: " + err.Error()
+				} else {
+					response["standby"] = standby
+				}
+			}
 		}
 	}
 
 	return c.JSON(response)
 }
 
+// snapshotWorktree copies a worktree's state so a standby can be created after it's deleted
+func (h *GitHandler) snapshotWorktree(worktreeID string) *models.Worktree {
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		return nil
+	}
+	snapshot := *worktree
+	return &snapshot
+}
+
+// createStandby creates a fresh worktree to replace a merged one, including
+// its place in composite workspaces
+func (h *GitHandler) createStandby(merged *models.Worktree) (*models.Worktree, error) {
+	standby, err := h.gitService.CreateStandbyWorktree(merged)
+	if err != nil {
+		return nil, err
+	}
+	if h.composites != nil {
+		if names, err := h.composites.ReplaceWorktree(merged.ID, standby.ID); err != nil {
+			logger.Warnf("⚠️ Failed to move standby worktree %s into composite workspaces: %v", standby.Name, err)
+		} else if len(names) > 0 {
+			logger.Infof("🔗 Standby worktree %s replaced %s in composite workspaces: %s", standby.Name, merged.Name, strings.Join(names, ", "))
+		}
+	}
+	return standby, nil
+}
+
+// CreateStandbyWorktree creates a fresh worktree like an existing one
+// @Summary Create standby worktree
+// @Description Creates a new worktree from the latest source branch of the given worktree (the default branch for worktrees created from a tag or commit), copying its labels, git identity and SSH agent setting. Use it to start the next task on the same service before cleaning up a merged worktree.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/standby [post]
+func (h *GitHandler) CreateStandbyWorktree(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		return respondError(c, 404, models.NewWorktreeNotFoundError(worktreeID))
+	}
+
+	standby, err := h.gitService.CreateStandbyWorktree(worktree)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(standby)
+}
+
 // CleanupMergedWorktrees removes worktrees that have been fully merged
 // @Summary Cleanup merged worktrees
-// @Description Removes worktrees that have been fully merged into their source branch
+// @Description Removes worktrees that have been fully merged into their source branch. A label selector limits cleanup to matching worktrees, e.g. "experiment" or "!keep".
 // @Tags git
 // @Produce json
+// @Param label query string false "Label selector, e.g. experiment,!keep (a leading ! excludes)"
+// @Param standby query bool false "Replace each removed worktree with a fresh one from its updated source branch"
 // @Success 200 {object} map[string]interface{}
 // @Router /v1/git/worktrees/cleanup [post]
 func (h *GitHandler) CleanupMergedWorktrees(c *fiber.Ctx) error {
-	cleanedCount, cleanedNames, err := h.gitService.CleanupMergedWorktrees()
+	selector, err := services.ParseLabelSelector(c.Query("label"))
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error":         err.Error(),
-			"cleaned_count": cleanedCount,
-			"cleaned_names": cleanedNames,
-		})
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err))
 	}
 
-	return c.JSON(fiber.Map{
+	// Cleanup reports names, so snapshot by name for standby creation
+	snapshots := make(map[string]*models.Worktree)
+	standby := c.QueryBool("standby", false)
+	if standby {
+		for _, worktree := range h.gitService.ListWorktrees() {
+			if selector.Matches(worktree.Labels) {
+				snapshots[worktree.Name] = worktree
+			}
+		}
+	}
+
+	cleanedCount, cleanedNames, err := h.gitService.CleanupMergedWorktrees(selector)
+
+	response := fiber.Map{
 		"message":       "Merged worktrees cleanup completed successfully",
 		"cleaned_count": cleanedCount,
 		"cleaned_names": cleanedNames,
-	})
+	}
+	if standby {
+		standbys := []*models.Worktree{}
+		var warnings []string
+		for _, name := range cleanedNames {
+			snapshot, ok := snapshots[name]
+			if !ok {
+				continue
+			}
+			created, standbyErr := h.createStandby(snapshot)
+			if standbyErr != nil {
+				warnings = append(warnings, fmt.Sprintf("%s: %v", name, standbyErr))
+				continue
+			}
+			standbys = append(standbys, created)
+		}
+		response["standby"] = standbys
+		if len(warnings) > 0 {
+			response["standby_warnings"] = warnings
+		}
+	}
+
+	if err != nil {
+		response["error"] = err.Error()
+		delete(response, "message")
+		return c.Status(400).JSON(response)
+	}
+	return c.JSON(response)
 }
 
 // CreateWorktreePreview creates a preview branch for viewing changes outside container
@@ -556,9 +1279,7 @@ func (h *GitHandler) CreateWorktreePreview(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
 	if err := h.gitService.CreateWorktreePreview(worktreeID); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -580,9 +1301,7 @@ func (h *GitHandler) CheckSyncConflicts(c *fiber.Ctx) error {
 
 	conflictErr, err := h.gitService.CheckSyncConflicts(worktreeID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	if conflictErr != nil {
@@ -615,9 +1334,7 @@ func (h *GitHandler) CheckMergeConflicts(c *fiber.Ctx) error {
 	conflictErr, err := h.gitService.CheckMergeConflicts(worktreeID)
 	if err != nil {
 		logger.Errorf("❌ CheckMergeConflicts failed for worktree %s: %v", worktreeID, err)
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	if conflictErr != nil {
@@ -638,23 +1355,369 @@ func (h *GitHandler) CheckMergeConflicts(c *fiber.Ctx) error {
 
 // GetWorktreeDiff returns the diff for a worktree against its source branch
 // @Summary Get worktree diff
-// @Description Returns the diff for a worktree against its source branch, including all staged/unstaged changes
+// @Description Returns the diff for a worktree against its source branch, including all staged/unstaged changes. Lockfiles, files matched by the worktree's .catnip-diffignore (.gitignore syntax, "!" re-includes) and files marked linguist-generated in .gitattributes are listed under ignored_files without their contents and left out of review progress.
 // @Tags git
 // @Produce json
 // @Param id path string true "Worktree ID"
+// @Param reviewer query string false "Reviewer whose review marks to include (default \"default\")"
+// @Param include_ignored query bool false "Keep ignored files in file_diffs, labeled with ignored_by"
+// @Param test_impact query bool false "Include test_impact, the tests likely affected by the changes"
 // @Success 200 {object} WorktreeDiffResponse
 // @Router /v1/git/worktrees/{id}/diff [get]
 func (h *GitHandler) GetWorktreeDiff(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
 
-	diff, err := h.gitService.GetWorktreeDiff(worktreeID)
+	diff, err := h.gitService.GetWorktreeDiffWithOptions(worktreeID, services.WorktreeDiffOptions{
+		Reviewer:       c.Query("reviewer", models.DefaultReviewer),
+		IncludeIgnored: c.QueryBool("include_ignored"),
+		TestImpact:     c.QueryBool("test_impact"),
+	})
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(diff)
+}
+
+// GetWorktreeTestImpact maps a worktree's changes to the tests likely affected
+// @Summary Get test impact
+// @Description Maps the files changed in a worktree against its source branch to the test files likely affected, so a test run can start with the impacted subset. Go changes follow the package import graph of every go.mod in the worktree; JavaScript and TypeScript changes follow relative imports and tsconfig paths aliases to *.test.*, *.spec.* and __tests__ files. A changed go.mod or package.json impacts every test of its language. Changed source files no test could be traced to are listed under unmapped. Also available on the diff with ?test_impact=true.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.TestImpact
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/test-impact [get]
+func (h *GitHandler) GetWorktreeTestImpact(c *fiber.Ctx) error {
+	impact, err := h.gitService.WorktreeTestImpact(c.Params("id"))
 	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(impact)
+}
+
+// UpdateFileReview marks files in a worktree diff as reviewed or unreviewed
+// @Summary Update file review marks
+// @Description Marks files of a worktree diff as reviewed (or clears the marks) for a reviewer. Marks are tied to each file's current blob hash and lapse when the file changes again.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body models.UpdateFileReviewRequest true "Files to mark"
+// @Success 200 {object} models.ReviewProgress
+// @Router /v1/git/worktrees/{id}/review [put]
+func (h *GitHandler) UpdateFileReview(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	var req models.UpdateFileReviewRequest
+	if err := c.BodyParser(&req); err != nil {
 		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
+			"error": "Invalid request body",
 		})
 	}
 
-	return c.JSON(diff)
+	progress, err := h.gitService.UpdateFileReview(worktreeID, req)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(progress)
+}
+
+// RerunWorktreeSetup re-runs setup.sh for a worktree
+// @Summary Re-run worktree setup
+// @Description Re-runs setup.sh in the worktree's setup terminal, e.g. after a sync changed package.json or a lockfile, and clears the worktree's setup_changed_files.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/setup/rerun [post]
+func (h *GitHandler) RerunWorktreeSetup(c *fiber.Ctx) error {
+	if err := h.gitService.RerunSetup(c.Params("id")); err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Setup re-run started",
+	})
+}
+
+// GetWorktreeDevcontainer reports how a worktree's devcontainer.json is imported
+// @Summary Get devcontainer.json import
+// @Description Shows how the worktree's devcontainer.json (.devcontainer/devcontainer.json, .devcontainer.json or .devcontainer/<name>/devcontainer.json) maps onto catnip. Without a setup.sh, its onCreate, updateContent, postCreate and postStart commands run as setup with containerEnv and remoteEnv exported. Numeric forwardPorts are published on the host at 127.0.0.1 unless .catnip.yaml declares them. Features can't be installed, so each reports whether its tool is already available. Set devcontainer.import to false in .catnip.yaml to turn the import off.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} services.DevcontainerImport
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/devcontainer [get]
+func (h *GitHandler) GetWorktreeDevcontainer(c *fiber.Ctx) error {
+	result, err := h.gitService.WorktreeDevcontainer(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	if result == nil {
+		return c.Status(404).JSON(fiber.Map{"error": "No devcontainer.json found"})
+	}
+
+	return c.JSON(result)
+}
+
+// StreamGitProgress streams the output of long git operations over SSE
+// @Summary Stream git operation progress
+// @Description Server-Sent Events stream of output from long git operations (clones on checkout, fetches on sync). Running operations are sent first as "operation" events with their recent lines, then each update as a "progress" event.
+// @Tags git
+// @Produce text/event-stream
+// @Param repo query string false "Only stream operations on this repository ID"
+// @Success 200 {object} services.GitProgressEvent
+// @Router /v1/git/progress [get]
+func (h *GitHandler) StreamGitProgress(c *fiber.Ctx) error {
+	running, events, unsubscribe := h.gitService.GetGitProgress().Subscribe(c.Query("repo"))
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		send := func(event string, payload interface{}) bool {
+			b, _ := json.Marshal(payload)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+				return false
+			}
+			return w.Flush() == nil
+		}
+
+		for _, op := range running {
+			if !send("operation", op) {
+				return
+			}
+		}
+
+		tick := time.NewTicker(15 * time.Second)
+		defer tick.Stop()
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok || !send("progress", event) {
+					return
+				}
+			case <-tick.C:
+				if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil || w.Flush() != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
+}
+
+// LinkIssueRequest represents a request to link a GitHub issue to a worktree
+type LinkIssueRequest struct {
+	Number int `json:"number" example:"42"`
+}
+
+// LinkWorktreeIssue links a GitHub issue to a worktree
+// @Summary Link GitHub issue
+// @Description Fetches a GitHub issue and its recent comments with gh and links it to the worktree. New Claude sessions in the worktree get the issue as context, and pull requests created from it include "Fixes #N".
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body LinkIssueRequest true "Issue to link"
+// @Success 200 {object} models.LinkedIssue
+// @Router /v1/git/worktrees/{id}/issue [put]
+func (h *GitHandler) LinkWorktreeIssue(c *fiber.Ctx) error {
+	var req LinkIssueRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	issue, err := h.gitService.LinkIssue(c.Params("id"), req.Number)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(issue)
+}
+
+// UnlinkWorktreeIssue removes the GitHub issue linked to a worktree
+// @Summary Unlink GitHub issue
+// @Description Removes the GitHub issue linked to a worktree
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/issue [delete]
+func (h *GitHandler) UnlinkWorktreeIssue(c *fiber.Ctx) error {
+	if err := h.gitService.UnlinkIssue(c.Params("id")); err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Issue unlinked",
+	})
+}
+
+// CreateWorktreeFromPRRequest selects the pull request to check out
+type CreateWorktreeFromPRRequest struct {
+	Number int `json:"number" example:"123"`
+}
+
+// CreateWorktreeFromPullRequest checks out a pull request into a new worktree
+// @Summary Create worktree from pull request
+// @Description Fetches an open pull request's head (including heads in forks) and creates a worktree at it, diffing against the PR's base branch. The PR is linked to the worktree, and the worktree follows new pushes to the PR while it has no uncommitted changes or local commits of its own. If a worktree already tracks the PR it is returned instead.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param request body CreateWorktreeFromPRRequest true "Pull request to check out"
+// @Success 200 {object} CheckoutResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/worktrees/from-pr [post]
+func (h *GitHandler) CreateWorktreeFromPullRequest(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req CreateWorktreeFromPRRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	worktree, err := h.gitService.CreateWorktreeFromPullRequest(repoID, req.Number)
+	if err != nil {
+		logger.Errorf("❌ Checkout of pull request #%d failed: %v", req.Number, err)
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"repository": h.gitService.GetRepositoryByID(repoID),
+		"worktree":   worktree,
+		"message":    "Pull request checked out successfully",
+	})
+}
+
+// ImportBranchRequest selects the existing branch to attach to a worktree
+type ImportBranchRequest struct {
+	// Branch to import, e.g. a teammate's feature branch ("origin/" is optional)
+	Branch string `json:"branch" example:"feature/foo"`
+	// Branch the worktree diffs against (defaults to the repository's default branch)
+	Base string `json:"base,omitempty" example:"main"`
+}
+
+// ImportBranch attaches an existing branch to a new worktree
+// @Summary Import an existing branch
+// @Description Fetches an existing branch (for example a teammate's feature/foo) and creates a worktree on it instead of a new catnip branch. Commits made in the worktree land on the branch and pushes go back to it; the branch is never renamed and is kept when the worktree is deleted. The worktree is tracked, monitored and runs setup.sh like any other. If a worktree is already on the branch it is returned instead.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param request body ImportBranchRequest true "Branch to import"
+// @Success 200 {object} CheckoutResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/worktrees/from-branch [post]
+func (h *GitHandler) ImportBranch(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid repository ID: " + err.Error(),
+		})
+	}
+
+	var req ImportBranchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	worktree, err := h.gitService.ImportBranch(repoID, req.Branch, req.Base)
+	if err != nil {
+		logger.Errorf("❌ Import of branch %s failed: %v", req.Branch, err)
+		return respondError(c, 500, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"repository": h.gitService.GetRepositoryByID(repoID),
+		"worktree":   worktree,
+		"message":    "Branch imported successfully",
+	})
+}
+
+// SyncWorktreePullRequest pulls new pushes to a worktree's pull request
+// @Summary Sync worktree with its pull request
+// @Description Fetches the pull request a worktree was created from and moves the worktree to its new head, as the background sync does. The result is "updated", "up_to_date", "dirty" (uncommitted changes, nothing was changed) or "diverged" (local commits, nothing was changed).
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.SourcePullRequest
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/pr/sync [post]
+func (h *GitHandler) SyncWorktreePullRequest(c *fiber.Ctx) error {
+	pr, err := h.gitService.SyncPullRequestWorktree(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(pr)
+}
+
+// GetWorktreeGraph returns the commit graph for a worktree
+// @Summary Get worktree commit graph
+// @Description Returns the commit DAG between the worktree's source branch and its HEAD (nodes, parent edges, merge points), newest first and paginated
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param limit query int false "Maximum commits to return (default 100, max 500)"
+// @Param offset query int false "Number of commits to skip"
+// @Success 200 {object} git.CommitGraphResponse
+// @Router /v1/git/worktrees/{id}/graph [get]
+func (h *GitHandler) GetWorktreeGraph(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+
+	graph, err := h.gitService.GetWorktreeCommitGraph(worktreeID, c.QueryInt("offset", 0), c.QueryInt("limit", 0))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	return c.JSON(graph)
+}
+
+// GetWorktreeLayout reports where a worktree's disk space goes
+// @Summary Get worktree disk layout
+// @Description Returns the largest directories and files in a worktree, detected build artifact directories (node_modules, target, dist, ...) and suggested cleanup actions. Scans are cached for 10 minutes.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param refresh query bool false "Rescan instead of using the cached result"
+// @Success 200 {object} services.DiskLayout
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/layout [get]
+func (h *GitHandler) GetWorktreeLayout(c *fiber.Ctx) error {
+	worktree, exists := h.gitService.GetWorktree(c.Params("id"))
+	if !exists {
+		return respondError(c, 404, models.NewWorktreeNotFoundError(c.Params("id")))
+	}
+
+	layout, err := h.diskLayout.Layout(worktree.Path, c.QueryBool("refresh"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(layout)
 }
 
 // CreatePullRequestRequest represents a request to create a pull request
@@ -666,13 +1729,14 @@ type CreatePullRequestRequest struct {
 
 // CreatePullRequest creates a pull request for a worktree
 // @Summary Create pull request
-// @Description Creates a pull request for a worktree branch
+// @Description Creates a pull request for a worktree branch. While GitHub is unreachable the pull request is queued and 202 returns the queued outbox operation.
 // @Tags git
 // @Accept json
 // @Produce json
 // @Param id path string true "Worktree ID"
 // @Param request body CreatePullRequestRequest true "Pull request details"
 // @Success 200 {object} models.PullRequestResponse
+// @Success 202 {object} services.OutboxOperation
 // @Router /v1/git/worktrees/{id}/pr [post]
 func (h *GitHandler) CreatePullRequest(c *fiber.Ctx) error {
 	worktreeID := c.Params("id")
@@ -684,11 +1748,19 @@ func (h *GitHandler) CreatePullRequest(c *fiber.Ctx) error {
 		})
 	}
 
+	params := services.OutboxPullRequestParams{Title: req.Title, Body: req.Body, ForcePush: req.ForcePush}
+	description := fmt.Sprintf("pull request %q", req.Title)
+	if h.outbox != nil && !h.outbox.Online() {
+		_, err := h.queueIfOffline(c, nil, services.OutboxCreatePullRequest, worktreeID, description, params)
+		return err
+	}
+
 	pr, err := h.gitService.CreatePullRequest(worktreeID, req.Title, req.Body, req.ForcePush)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		if queued, queueErr := h.queueIfOffline(c, err, services.OutboxCreatePullRequest, worktreeID, description, params); queued {
+			return queueErr
+		}
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(pr)
@@ -716,9 +1788,7 @@ func (h *GitHandler) UpdatePullRequest(c *fiber.Ctx) error {
 
 	pr, err := h.gitService.UpdatePullRequest(worktreeID, req.Title, req.Body, req.ForcePush)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(pr)
@@ -737,14 +1807,31 @@ func (h *GitHandler) GetPullRequestInfo(c *fiber.Ctx) error {
 
 	prInfo, err := h.gitService.GetPullRequestInfo(worktreeID)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(prInfo)
 }
 
+// GetPullRequestFeedback returns reviews and CI status of a worktree's pull request
+// @Summary Get pull request feedback
+// @Description Returns the review decision, reviewers requesting changes or approving, unresolved review comments and CI check status of a worktree's GitHub pull request, with a one-line summary such as "2 requested changes, CI failing". The PR sync refreshes it every minute and emits worktree:updated events with pull_request_feedback when it changes; refresh fetches it now. Claude reads it with `catnip pr feedback`.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param refresh query bool false "Fetch the feedback from GitHub instead of using the last sync"
+// @Success 200 {object} models.PullRequestFeedback
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/pr/feedback [get]
+func (h *GitHandler) GetPullRequestFeedback(c *fiber.Ctx) error {
+	feedback, err := h.gitService.GetPullRequestFeedback(c.Params("id"), c.QueryBool("refresh"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(feedback)
+}
+
 // GraduateBranchRequest represents the request to graduate a branch
 type GraduateBranchRequest struct {
 	// Optional custom branch name to graduate to
@@ -753,7 +1840,7 @@ type GraduateBranchRequest struct {
 
 // GraduateBranch manually triggers renaming of a branch to a semantic name
 // @Summary Rename branch
-// @Description Triggers renaming of any branch to a semantic name using Claude or a custom name
+// @Description Triggers renaming of any branch to a semantic name using Claude or a custom name. Names follow the repository's branch naming policy: Claude's suggestions are adjusted to it, and custom names that break it are rejected with code BRANCH_NAME_REJECTED and a compliant suggestion in the hint.
 // @Tags git
 // @Accept json
 // @Produce json
@@ -805,6 +1892,9 @@ func (h *GitHandler) GraduateBranch(c *fiber.Ctx) error {
 				"error": "Invalid branch name: " + req.BranchName,
 			})
 		}
+		if err := h.gitService.ValidateWorktreeBranchName(worktreeID, req.BranchName); err != nil {
+			return respondError(c, 400, err)
+		}
 
 		// Check if the new branch already exists
 		if h.gitService.BranchExists(workDir, req.BranchName, false) {
@@ -889,9 +1979,7 @@ func (h *GitHandler) RefreshWorktreeStatus(c *fiber.Ctx) error {
 
 	// Force refresh by calling the git service method that recalculates status
 	if err := h.gitService.RefreshWorktreeStatusByID(worktreeID); err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -942,9 +2030,7 @@ func (h *GitHandler) CreateFromTemplate(c *fiber.Ctx) error {
 	// Create project from template
 	repo, worktree, err := h.gitService.CreateFromTemplate(req.TemplateID, req.ProjectName)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	// Return success response with repository information
@@ -1014,9 +2100,7 @@ func (h *GitHandler) CreateGitHubRepository(c *fiber.Ctx) error {
 	logger.Infof("🚀 Calling CreateGitHubRepositoryAndSetOrigin with repoID: '%s'", repoID)
 	repoURL, err := h.gitService.CreateGitHubRepositoryAndSetOrigin(repoID, req.Name, req.Description, req.IsPrivate)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(CreateGitHubRepositoryResponse{
@@ -1053,9 +2137,7 @@ func (h *GitHandler) DeleteRepository(c *fiber.Ctx) error {
 	// Delete the repository
 	if err := h.gitService.DeleteRepository(repoID); err != nil {
 		logger.Errorf("❌ Failed to delete repository '%s': %v", repoID, err)
-		return c.Status(400).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 400, err)
 	}
 
 	return c.JSON(fiber.Map{
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// GitHubAppHandler handles GitHub App authentication and the secret store
+type GitHubAppHandler struct {
+	app        *services.GitHubAppService
+	secrets    *services.SecretStore
+	gitService *services.GitService
+}
+
+// GitHubAppConfigRequest configures the GitHub App
+type GitHubAppConfigRequest struct {
+	// App ID from the app's settings page
+	AppID string `json:"app_id" example:"123456"`
+	// PEM private key; omit to keep the stored key
+	PrivateKey string `json:"private_key,omitempty"`
+	// Secret to store the key in (defaults to github-app-private-key)
+	PrivateKeySecret string `json:"private_key_secret,omitempty" example:"github-app-private-key"`
+}
+
+// GitHubCredentialResponse is a git credential for one repository
+type GitHubCredentialResponse struct {
+	Username  string    `json:"username" example:"x-access-token"`
+	Password  string    `json:"password"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// RepositoryGitHubAuthRequest selects how a repository authenticates to GitHub
+type RepositoryGitHubAuthRequest struct {
+	// "user" (the gh login) or "app" (GitHub App installation tokens)
+	Mode models.GitHubAuthMode `json:"mode" example:"app"`
+}
+
+// SecretRequest sets a secret's value
+type SecretRequest struct {
+	Value string `json:"value"`
+}
+
+// NewGitHubAppHandler creates a new GitHub App handler
+func NewGitHubAppHandler(app *services.GitHubAppService, secrets *services.SecretStore, gitService *services.GitService) *GitHubAppHandler {
+	return &GitHubAppHandler{
+		app:        app,
+		secrets:    secrets,
+		gitService: gitService,
+	}
+}
+
+// GetGitHubApp returns the GitHub App configuration
+// @Summary Get GitHub App configuration
+// @Description Returns the configured app ID and whether its private key is in the secret store. The key itself is never returned.
+// @Tags github
+// @Produce json
+// @Success 200 {object} services.GitHubAppStatus
+// @Router /v1/github/app [get]
+func (h *GitHubAppHandler) GetGitHubApp(c *fiber.Ctx) error {
+	return c.JSON(h.app.Status())
+}
+
+// UpdateGitHubApp configures the GitHub App
+// @Summary Configure GitHub App
+// @Description Sets the app ID and stores the private key in the secret store. Repositories switched to app mode then push, pull and open pull requests with the app's installation tokens.
+// @Tags github
+// @Accept json
+// @Produce json
+// @Param request body GitHubAppConfigRequest true "App configuration"
+// @Success 200 {object} services.GitHubAppStatus
+// @Failure 400 {object} map[string]string
+// @Router /v1/github/app [put]
+func (h *GitHubAppHandler) UpdateGitHubApp(c *fiber.Ctx) error {
+	var req GitHubAppConfigRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	status, err := h.app.Configure(services.GitHubAppConfig{
+		AppID:            req.AppID,
+		PrivateKeySecret: req.PrivateKeySecret,
+	}, req.PrivateKey)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	if status.Configured && config.Runtime.IsContainerized() {
+		h.gitService.EnableGitHubAppCredentials()
+	}
+	return c.JSON(status)
+}
+
+// GetGitHubCredential returns a git credential for a repository in app mode
+// @Summary Get GitHub App git credential
+// @Description Returns an installation token for a repository that authenticates as the GitHub App. Used by the `catnip git-credential` helper; repositories using the gh login get a 400.
+// @Tags github
+// @Produce json
+// @Param repo query string true "Repository (owner/repo)"
+// @Success 200 {object} GitHubCredentialResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/github/app/credential [get]
+func (h *GitHubAppHandler) GetGitHubCredential(c *fiber.Ctx) error {
+	repo := c.Query("repo")
+	if repo == "" {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "repo query parameter is required"))
+	}
+
+	token, err := h.gitService.GitHubAppTokenFor(repo)
+	if err != nil {
+		return respondError(c, 502, err)
+	}
+	return c.JSON(GitHubCredentialResponse{
+		Username:  services.GitHubAppTokenUsername,
+		Password:  token.Token,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+// UpdateRepositoryGitHubAuth selects how a repository authenticates to GitHub
+// @Summary Set repository GitHub auth mode
+// @Description Switches a repository between the gh login ("user") and the GitHub App ("app"). App mode requires a configured app installed on the repository.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param request body RepositoryGitHubAuthRequest true "Auth mode"
+// @Success 200 {object} models.Repository
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/github-auth [put]
+func (h *GitHubAppHandler) UpdateRepositoryGitHubAuth(c *fiber.Ctx) error {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid repository ID: %v", err))
+	}
+
+	var req RepositoryGitHubAuthRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	repo, err := h.gitService.SetRepositoryGitHubAuth(repoID, req.Mode)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(repo)
+}
+
+// ListSecrets returns the names of stored secrets
+// @Summary List secrets
+// @Description Returns the names of secrets in the secret store. Values are never returned.
+// @Tags secrets
+// @Produce json
+// @Success 200 {array} string
+// @Router /v1/secrets [get]
+func (h *GitHubAppHandler) ListSecrets(c *fiber.Ctx) error {
+	return c.JSON(h.secrets.Names())
+}
+
+// SetSecret stores a secret
+// @Summary Set secret
+// @Description Stores a secret as an owner-only file in the volume, replacing any previous value
+// @Tags secrets
+// @Accept json
+// @Param name path string true "Secret name"
+// @Param request body SecretRequest true "Secret value"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /v1/secrets/{name} [put]
+func (h *GitHubAppHandler) SetSecret(c *fiber.Ctx) error {
+	var req SecretRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+	if err := h.secrets.Set(c.Params("name"), req.Value); err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// DeleteSecret removes a secret
+// @Summary Delete secret
+// @Tags secrets
+// @Param name path string true "Secret name"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Router /v1/secrets/{name} [delete]
+func (h *GitHubAppHandler) DeleteSecret(c *fiber.Ctx) error {
+	if err := h.secrets.Delete(c.Params("name")); err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// HygieneHandler handles workspace hygiene report endpoints
+type HygieneHandler struct {
+	reports *services.HygieneReportService
+}
+
+// NewHygieneHandler creates a new hygiene report handler
+func NewHygieneHandler(reports *services.HygieneReportService) *HygieneHandler {
+	return &HygieneHandler{
+		reports: reports,
+	}
+}
+
+// ListHygieneReports returns stored hygiene reports
+// @Summary List workspace hygiene reports
+// @Description Returns the nightly and on-demand hygiene reports kept on the volume (stale worktrees, failing setups, old unmerged branches, disk usage and Claude spend), newest first
+// @Tags hygiene
+// @Produce json
+// @Success 200 {array} services.HygieneReport
+// @Router /v1/hygiene/reports [get]
+func (h *HygieneHandler) ListHygieneReports(c *fiber.Ctx) error {
+	return c.JSON(h.reports.List())
+}
+
+// GetHygieneReport returns one hygiene report
+// @Summary Get a workspace hygiene report
+// @Description Returns a stored hygiene report by ID, or the newest one for "latest"
+// @Tags hygiene
+// @Produce json
+// @Param id path string true "Report ID or latest"
+// @Success 200 {object} services.HygieneReport
+// @Failure 404 {object} map[string]string
+// @Router /v1/hygiene/reports/{id} [get]
+func (h *HygieneHandler) GetHygieneReport(c *fiber.Ctx) error {
+	report, exists := h.reports.Get(c.Params("id"))
+	if !exists {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Hygiene report not found",
+		})
+	}
+	return c.JSON(report)
+}
+
+// CreateHygieneReport generates a hygiene report now
+// @Summary Generate a workspace hygiene report
+// @Description Builds a hygiene report immediately instead of waiting for the nightly run, stores it and delivers it when notifications are enabled (CATNIP_HYGIENE_REPORT_NOTIFY=true). Worktree disk usage comes from the cached disk layout scanner.
+// @Tags hygiene
+// @Produce json
+// @Success 200 {object} services.HygieneReport
+// @Router /v1/hygiene/reports [post]
+func (h *HygieneHandler) CreateHygieneReport(c *fiber.Ctx) error {
+	return c.JSON(h.reports.Generate())
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// IssueWebhookHandler handles GitHub webhook deliveries that provision
+// workspaces for issues
+type IssueWebhookHandler struct {
+	webhooks *services.IssueWebhookService
+}
+
+// NewIssueWebhookHandler creates a new issue webhook handler
+func NewIssueWebhookHandler(webhooks *services.IssueWebhookService) *IssueWebhookHandler {
+	return &IssueWebhookHandler{
+		webhooks: webhooks,
+	}
+}
+
+// HandleGitHubWebhook receives a GitHub webhook delivery
+// @Summary Receive a GitHub webhook
+// @Description Receives deliveries from a GitHub repository or organization webhook sending "issues" events. When an open issue gets the agent:fix label (or CATNIP_ISSUE_WEBHOOK_LABEL), a worktree is created for the repository, the issue is linked to it, Claude is started on the issue and a comment linking the workspace is posted on the issue. Provisioning continues in the background; follow it with GET /v1/webhooks/github/runs. Deliveries must be signed with the secret stored as github-webhook-secret (or CATNIP_GITHUB_WEBHOOK_SECRET). Other events and labels are acknowledged and ignored.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param X-GitHub-Event header string true "Event type"
+// @Param X-GitHub-Delivery header string false "Delivery ID"
+// @Param X-Hub-Signature-256 header string true "HMAC-SHA256 signature of the payload"
+// @Success 200 {object} services.IssueWebhookResult "Delivery ignored"
+// @Success 202 {object} services.IssueWebhookResult "Workspace provisioning started"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /v1/webhooks/github [post]
+func (h *IssueWebhookHandler) HandleGitHubWebhook(c *fiber.Ctx) error {
+	if err := h.webhooks.VerifySignature(c.Get("X-Hub-Signature-256"), c.Body()); err != nil {
+		return respondError(c, fiber.StatusUnauthorized, err)
+	}
+
+	result, err := h.webhooks.HandleDelivery(c.Get("X-GitHub-Event"), c.Get("X-GitHub-Delivery"), c.Body())
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	if result.Ignored != "" {
+		return c.JSON(result)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(result)
+}
+
+// ListIssueWorkspaceRuns lists workspace provisioning runs
+// @Summary List issue workspace runs
+// @Description Lists the workspaces provisioned for labeled GitHub issues, newest first, with their worktree, workspace link and whether Claude was started and the issue commented on.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} services.IssueWorkspaceRun
+// @Router /v1/webhooks/github/runs [get]
+func (h *IssueWebhookHandler) ListIssueWorkspaceRuns(c *fiber.Ctx) error {
+	return c.JSON(h.webhooks.Runs())
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// MDNSHandler handles LAN advertising of dev servers over mDNS
+type MDNSHandler struct {
+	mdns       *services.MDNSService
+	gitService *services.GitService
+}
+
+// WorktreeMDNSRequest sets whether a worktree is advertised; null restores the default
+type WorktreeMDNSRequest struct {
+	Enabled *bool `json:"enabled" example:"false"`
+}
+
+// NewMDNSHandler creates a new mDNS handler
+func NewMDNSHandler(mdns *services.MDNSService, gitService *services.GitService) *MDNSHandler {
+	return &MDNSHandler{
+		mdns:       mdns,
+		gitService: gitService,
+	}
+}
+
+// GetMDNS returns the mDNS responder status and current advertisements
+// @Summary Get mDNS advertising status
+// @Description Returns whether dev servers are advertised on the local network, the LAN addresses their <workspace>.local names resolve to, and the URLs other devices can open. Enabled by default in native mode; set CATNIP_MDNS to override and CATNIP_MDNS_TTL to change the record TTL.
+// @Tags mdns
+// @Produce json
+// @Success 200 {object} services.MDNSStatus
+// @Router /v1/mdns [get]
+func (h *MDNSHandler) GetMDNS(c *fiber.Ctx) error {
+	return c.JSON(h.mdns.Status())
+}
+
+// UpdateWorktreeMDNS turns mDNS advertising on or off for a worktree
+// @Summary Configure mDNS advertising for a worktree
+// @Description Sets whether the worktree's running dev servers are advertised on the local network. Send null to use the server default. Turning it off withdraws the worktree's records from LAN caches immediately.
+// @Tags mdns
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body WorktreeMDNSRequest true "Advertising setting"
+// @Success 200 {object} services.MDNSStatus
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/mdns [put]
+func (h *MDNSHandler) UpdateWorktreeMDNS(c *fiber.Ctx) error {
+	var req WorktreeMDNSRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := h.gitService.SetWorktreeMDNS(c.Params("id"), req.Enabled); err != nil {
+		return respondError(c, 400, err)
+	}
+	h.mdns.Refresh()
+
+	return c.JSON(h.mdns.Status())
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// MirrorHandler handles repository mirror endpoints
+type MirrorHandler struct {
+	mirrorService *services.MirrorService
+}
+
+// NewMirrorHandler creates a new mirror handler
+func NewMirrorHandler(mirrorService *services.MirrorService) *MirrorHandler {
+	return &MirrorHandler{
+		mirrorService: mirrorService,
+	}
+}
+
+// GetMirrorStatus returns the state of repository mirrors
+// @Summary Get repository mirror status
+// @Description Returns the mirrored repositories configured with CATNIP_MIRROR_REPOS, when each was last synced, and whether offline mode is on
+// @Tags git
+// @Produce json
+// @Success 200 {object} services.MirrorStatus
+// @Router /v1/git/mirrors [get]
+func (h *MirrorHandler) GetMirrorStatus(c *fiber.Ctx) error {
+	return c.JSON(h.mirrorService.Status())
+}
+
+// SyncMirrors starts a sync of every repository mirror
+// @Summary Sync repository mirrors
+// @Description Fetches all refs for every mirrored repository in the background
+// @Tags git
+// @Produce json
+// @Success 202 {object} services.MirrorStatus
+// @Failure 400 {object} map[string]string
+// @Router /v1/git/mirrors/sync [post]
+func (h *MirrorHandler) SyncMirrors(c *fiber.Ctx) error {
+	if !h.mirrorService.Enabled() {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No repositories are mirrored; set CATNIP_MIRROR_REPOS",
+		})
+	}
+	go func() {
+		_ = h.mirrorService.SyncAll()
+	}()
+	return c.Status(202).JSON(h.mirrorService.Status())
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// OffloadHandler handles endpoints for running heavy commands on remote runners
+type OffloadHandler struct {
+	offload *services.OffloadService
+}
+
+// OffloadRunRequest is a command to run on a remote runner
+type OffloadRunRequest struct {
+	WorktreePath string `json:"worktree_path" example:"/workspace/my-project"`
+	Command      string `json:"command" example:"make train EPOCHS=3"`
+	// Runner to use; defaults to the runner of the first matching rule
+	Runner string `json:"runner,omitempty" example:"gpu"`
+}
+
+// OffloadResult is the last NDJSON line of an offloaded run
+type OffloadResult struct {
+	ExitCode int                  `json:"exit_code"`
+	Error    string               `json:"error,omitempty"`
+	Job      *services.OffloadJob `json:"job,omitempty"`
+}
+
+// OffloadStatusResponse describes the offload configuration for a worktree
+type OffloadStatusResponse struct {
+	Config services.OffloadConfig `json:"config"`
+	// Programs terminals wrap so matching commands are offloaded
+	Commands []string               `json:"commands"`
+	Jobs     []*services.OffloadJob `json:"jobs"`
+}
+
+// NewOffloadHandler creates a new offload handler
+func NewOffloadHandler(offload *services.OffloadService) *OffloadHandler {
+	return &OffloadHandler{
+		offload: offload,
+	}
+}
+
+// GetOffload returns a worktree's offload rules and recent offloaded jobs
+// @Summary Get command offload configuration
+// @Description Returns the runners and rules from the worktree's .catnip.yaml (offload section), the programs terminals wrap so matching commands run remotely, and recently offloaded jobs
+// @Tags offload
+// @Produce json
+// @Param worktree_path query string false "Worktree path"
+// @Success 200 {object} OffloadStatusResponse
+// @Router /v1/offload [get]
+func (h *OffloadHandler) GetOffload(c *fiber.Ctx) error {
+	response := OffloadStatusResponse{Commands: []string{}, Jobs: h.offload.Jobs()}
+	if worktreePath := c.Query("worktree_path"); worktreePath != "" {
+		cfg, err := services.LoadOffloadConfig(config.Runtime.ResolvePath(worktreePath))
+		if err != nil {
+			return respondError(c, 400, err)
+		}
+		response.Config = cfg
+		if commands := cfg.Commands(); commands != nil {
+			response.Commands = commands
+		}
+	}
+	return c.JSON(response)
+}
+
+// RunOffload runs a command on a remote runner, streaming its output
+// @Summary Run command on a remote runner
+// @Description Dispatches a command to the runner configured for it in the worktree's .catnip.yaml and streams output back as NDJSON: {"data","stderr"} chunks followed by a final {"exit_code","error","job"} line. Disconnecting cancels the remote command.
+// @Tags offload
+// @Accept json
+// @Produce application/x-ndjson
+// @Param request body OffloadRunRequest true "Command to run"
+// @Success 200 {object} OffloadResult
+// @Router /v1/offload/run [post]
+func (h *OffloadHandler) RunOffload(c *fiber.Ctx) error {
+	var req OffloadRunRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.WorktreePath == "" || req.Command == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "worktree_path and command are required",
+		})
+	}
+
+	c.Set("Content-Type", "application/x-ndjson")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("X-Accel-Buffering", "no")
+
+	offloadReq := services.OffloadRequest{
+		WorkDir: config.Runtime.ResolvePath(req.WorktreePath),
+		Command: req.Command,
+		Runner:  req.Runner,
+	}
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		send := func(payload interface{}) {
+			b, _ := json.Marshal(payload)
+			if _, err := w.Write(append(b, '\n')); err != nil || w.Flush() != nil {
+				// Client went away; stop the remote command
+				cancel()
+			}
+		}
+
+		job, err := h.offload.Run(ctx, offloadReq, func(output services.OffloadOutput) {
+			send(output)
+		})
+		result := OffloadResult{ExitCode: -1, Job: job}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.ExitCode = job.ExitCode
+		}
+		send(result)
+	}))
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// OnboardingHandler handles first-run setup endpoints
+type OnboardingHandler struct {
+	onboardingService *services.OnboardingService
+}
+
+// NewOnboardingHandler creates a new onboarding handler
+func NewOnboardingHandler(onboardingService *services.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{
+		onboardingService: onboardingService,
+	}
+}
+
+// GetOnboardingStatus returns the first-run setup steps and what to do next
+// @Summary Get first-run setup status
+// @Description Evaluates GitHub auth, Claude auth, first repository and first worktree, returning each step's status with an action or deep link to resolve it
+// @Tags onboarding
+// @Produce json
+// @Success 200 {object} services.SetupStatus
+// @Router /v1/onboarding [get]
+func (h *OnboardingHandler) GetOnboardingStatus(c *fiber.Ctx) error {
+	return c.JSON(h.onboardingService.Status())
+}
+
+// SkipOnboardingStep marks an optional setup step as skipped
+// @Summary Skip a setup step
+// @Description Skips an optional first-run setup step (e.g. GitHub auth when only using local repositories)
+// @Tags onboarding
+// @Produce json
+// @Param id path string true "Step ID"
+// @Success 200 {object} services.SetupStatus
+// @Failure 400 {object} map[string]string "Unknown or required step"
+// @Router /v1/onboarding/steps/{id}/skip [post]
+func (h *OnboardingHandler) SkipOnboardingStep(c *fiber.Ctx) error {
+	status, err := h.onboardingService.SkipStep(services.SetupStepID(c.Params("id")))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(status)
+}
+
+// ResetOnboarding clears skipped setup steps
+// @Summary Reset first-run setup
+// @Description Clears skipped steps so the guided setup is offered again
+// @Tags onboarding
+// @Produce json
+// @Success 200 {object} services.SetupStatus
+// @Failure 500 {object} map[string]string
+// @Router /v1/onboarding/reset [post]
+func (h *OnboardingHandler) ResetOnboarding(c *fiber.Ctx) error {
+	status, err := h.onboardingService.Reset()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(status)
+}
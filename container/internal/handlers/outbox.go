@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// OutboxHandler handles endpoints for operations queued while offline
+type OutboxHandler struct {
+	outbox *services.OutboxService
+}
+
+// NewOutboxHandler creates a new outbox handler
+func NewOutboxHandler(outbox *services.OutboxService) *OutboxHandler {
+	return &OutboxHandler{
+		outbox: outbox,
+	}
+}
+
+// GetOutbox returns connectivity and queued operations
+// @Summary Get offline outbox
+// @Description Returns whether GitHub is reachable and the operations (pull requests, fetches) queued while it wasn't, newest first. Queued operations run in order automatically when connectivity returns.
+// @Tags outbox
+// @Produce json
+// @Success 200 {object} services.OutboxStatus
+// @Router /v1/outbox [get]
+func (h *OutboxHandler) GetOutbox(c *fiber.Ctx) error {
+	return c.JSON(h.outbox.Status())
+}
+
+// FlushOutbox checks connectivity and runs queued operations now
+// @Summary Flush offline outbox
+// @Description Checks whether GitHub is reachable and, if so, runs queued operations in the background
+// @Tags outbox
+// @Produce json
+// @Success 202 {object} services.OutboxStatus
+// @Router /v1/outbox/flush [post]
+func (h *OutboxHandler) FlushOutbox(c *fiber.Ctx) error {
+	h.outbox.Wake()
+	return c.Status(202).JSON(h.outbox.Status())
+}
+
+// RetryOutboxOperation requeues a failed operation
+// @Summary Retry outbox operation
+// @Description Requeues a failed operation and runs it as soon as GitHub is reachable
+// @Tags outbox
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 202 {object} services.OutboxOperation
+// @Failure 400 {object} map[string]string
+// @Router /v1/outbox/{id}/retry [post]
+func (h *OutboxHandler) RetryOutboxOperation(c *fiber.Ctx) error {
+	op, err := h.outbox.Retry(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.Status(202).JSON(op)
+}
+
+// DeleteOutboxOperation discards an operation
+// @Summary Discard outbox operation
+// @Description Removes a queued or finished operation from the outbox
+// @Tags outbox
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /v1/outbox/{id} [delete]
+func (h *OutboxHandler) DeleteOutboxOperation(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.outbox.Remove(id); err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Operation removed",
+		"id":      id,
+	})
+}
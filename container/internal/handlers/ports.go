@@ -2,13 +2,15 @@ package handlers
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/models"
 	"github.com/vanpelt/catnip/internal/services"
 )
 
 // PortsHandler handles port-related API endpoints
 type PortsHandler struct {
-	monitor *services.PortMonitor
-	events  *EventsHandler
+	monitor   *services.PortMonitor
+	events    *EventsHandler
+	publisher *services.PortPublishService
 }
 
 // NewPortsHandler creates a new ports handler
@@ -24,6 +26,12 @@ func (h *PortsHandler) WithEvents(events *EventsHandler) *PortsHandler {
 	return h
 }
 
+// WithPublisher attaches the store of ports published on the host
+func (h *PortsHandler) WithPublisher(publisher *services.PortPublishService) *PortsHandler {
+	h.publisher = publisher
+	return h
+}
+
 // GetPorts returns all detected ports and their service information
 // @Summary Get detected ports
 // @Description Returns a list of all currently detected ports with their service information
@@ -56,9 +64,7 @@ func (h *PortsHandler) GetPorts(c *fiber.Ctx) error {
 func (h *PortsHandler) GetPortInfo(c *fiber.Ctx) error {
 	port, err := c.ParamsInt("port")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid port number",
-		})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid port number"))
 	}
 
 	services := h.monitor.GetServices()
@@ -66,9 +72,7 @@ func (h *PortsHandler) GetPortInfo(c *fiber.Ctx) error {
 		return c.JSON(service)
 	}
 
-	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-		"error": "Port not found",
-	})
+	return respondError(c, fiber.StatusNotFound, models.NewAPIError(models.ErrCodeNotFound, "Port not found"))
 }
 
 // SetPortMapping sets or updates a host port mapping for a container port
@@ -83,7 +87,7 @@ func (h *PortsHandler) GetPortInfo(c *fiber.Ctx) error {
 // @Router /v1/ports/mappings [post]
 func (h *PortsHandler) SetPortMapping(c *fiber.Ctx) error {
 	if h.events == nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "events handler not configured"})
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "events handler not configured"))
 	}
 
 	var payload struct {
@@ -91,10 +95,10 @@ func (h *PortsHandler) SetPortMapping(c *fiber.Ctx) error {
 		HostPort int `json:"host_port"`
 	}
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid json"})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid json"))
 	}
 	if payload.Port <= 0 || payload.HostPort <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "port and host_port must be > 0"})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "port and host_port must be > 0"))
 	}
 
 	h.events.SetPortMapping(payload.Port, payload.HostPort)
@@ -113,12 +117,105 @@ func (h *PortsHandler) SetPortMapping(c *fiber.Ctx) error {
 // @Router /v1/ports/mappings/{port} [delete]
 func (h *PortsHandler) DeletePortMapping(c *fiber.Ctx) error {
 	if h.events == nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "events handler not configured"})
+		return respondError(c, fiber.StatusServiceUnavailable, models.NewAPIError(models.ErrCodeServiceUnavailable, "events handler not configured"))
 	}
 	port, err := c.ParamsInt("port")
 	if err != nil || port <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid port"})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid port"))
 	}
 	h.events.ClearPortMapping(port)
 	return c.JSON(fiber.Map{"status": "ok"})
 }
+
+// GetPublishRules lists the ports published directly on the host
+// @Summary List host publish rules
+// @Description Returns the workspace ports published on the host network by the host-side helper (catnip run), from each worktree's .catnip.yaml (ports.publish) and from the API, with their host status. Rules clashing with an earlier rule or a reserved catnip port are reported as conflicts.
+// @Tags ports
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Publish rules"
+// @Router /v1/ports/publish [get]
+func (h *PortsHandler) GetPublishRules(c *fiber.Ctx) error {
+	rules := h.publisher.Rules()
+	if rules == nil {
+		rules = []services.PortPublishRule{}
+	}
+	return c.JSON(fiber.Map{"rules": rules})
+}
+
+// AddPublishRule publishes a worktree's port on the host
+// @Summary Publish a workspace port on the host
+// @Description Adds a rule publishing a worktree's port on the host network. host_port defaults to the container port and host_address to 127.0.0.1. The rule is removed with its worktree.
+// @Tags ports
+// @Accept json
+// @Produce json
+// @Param rule body services.PortPublishRule true "Rule with worktree_id, port and optional host_port and host_address"
+// @Success 201 {object} services.PortPublishRule
+// @Failure 400 {object} map[string]string "Invalid rule or host port conflict"
+// @Failure 404 {object} map[string]string "Worktree not found"
+// @Router /v1/ports/publish [post]
+func (h *PortsHandler) AddPublishRule(c *fiber.Ctx) error {
+	var req struct {
+		WorktreeID  string `json:"worktree_id"`
+		Port        int    `json:"port"`
+		HostPort    int    `json:"host_port"`
+		HostAddress string `json:"host_address"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid json"))
+	}
+	if req.WorktreeID == "" {
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree_id is required"))
+	}
+
+	rule, err := h.publisher.Add(services.PortPublishRule{
+		WorktreeID:  req.WorktreeID,
+		Port:        req.Port,
+		HostPort:    req.HostPort,
+		HostAddress: req.HostAddress,
+	})
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(rule)
+}
+
+// DeletePublishRule stops publishing a port on the host
+// @Summary Remove a host publish rule
+// @Description Removes a rule added through the API. Rules declared in .catnip.yaml must be removed there.
+// @Tags ports
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Success 200 {object} map[string]string "Rule removed"
+// @Failure 400 {object} map[string]string "Unknown or config-declared rule"
+// @Router /v1/ports/publish/{id} [delete]
+func (h *PortsHandler) DeletePublishRule(c *fiber.Ctx) error {
+	if err := h.publisher.Remove(c.Params("id")); err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
+
+// ReportPublishStatus records the host helper's result for a rule
+// @Summary Report host publish status
+// @Description Called by the host-side helper after binding a rule: published, conflict (the host port is in use) or error
+// @Tags ports
+// @Accept json
+// @Produce json
+// @Param id path string true "Rule ID"
+// @Param status body map[string]string true "Object with 'status' and optional 'error'"
+// @Success 200 {object} map[string]string "Status recorded"
+// @Failure 400 {object} map[string]string "Unknown rule or status"
+// @Router /v1/ports/publish/{id}/status [post]
+func (h *PortsHandler) ReportPublishStatus(c *fiber.Ctx) error {
+	var req struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid json"))
+	}
+	if err := h.publisher.ReportStatus(c.Params("id"), services.PortPublishStatus(req.Status), req.Error); err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+	return c.JSON(fiber.Map{"status": "ok"})
+}
@@ -56,11 +56,12 @@ func TestPortsHandler_GetPortInfo_PortNotFound(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 404, resp.StatusCode)
 
-	var response map[string]string
+	var response map[string]interface{}
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	require.NoError(t, err)
 
 	assert.Equal(t, "Port not found", response["error"])
+	assert.Equal(t, "NOT_FOUND", response["code"])
 }
 
 func TestPortsHandler_GetPortInfo_InvalidPort(t *testing.T) {
@@ -77,9 +78,10 @@ func TestPortsHandler_GetPortInfo_InvalidPort(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 400, resp.StatusCode)
 
-	var response map[string]string
+	var response map[string]interface{}
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	require.NoError(t, err)
 
 	assert.Equal(t, "Invalid port number", response["error"])
+	assert.Equal(t, "INVALID_REQUEST", response["code"])
 }
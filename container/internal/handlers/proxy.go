@@ -23,6 +23,7 @@ import (
 	"github.com/gofiber/websocket/v2"
 	gorilla_websocket "github.com/gorilla/websocket"
 	"github.com/vanpelt/catnip/internal/assets"
+	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/recovery"
 	"github.com/vanpelt/catnip/internal/services"
 )
@@ -281,7 +282,7 @@ func (h *ProxyHandler) ProxyToPort(c *fiber.Ctx) error {
 	if strings.Contains(strings.ToLower(contentType), "javascript") ||
 		strings.Contains(strings.ToLower(contentType), "application/javascript") ||
 		strings.Contains(strings.ToLower(contentType), "text/javascript") {
-		c.Response().Header.Set("Service-Worker-Allowed", fmt.Sprintf("/%d/", port))
+		c.Response().Header.Set("Service-Worker-Allowed", proxyBasePath(port)+"/")
 	}
 
 	// Read response body and handle decompression if needed
@@ -339,9 +340,15 @@ func (h *ProxyHandler) ProxyToPort(c *fiber.Ctx) error {
 	return c.Send(body)
 }
 
+// proxyBasePath is the path a proxied port is served under, including
+// catnip's own base path when it runs behind a reverse proxy
+func proxyBasePath(port int) string {
+	return fmt.Sprintf("%s/%d", config.Runtime.BasePath, port)
+}
+
 // modifyHTMLContent injects base tag and JavaScript to handle SPA routing
 func (h *ProxyHandler) modifyHTMLContent(content string, port int) string {
-	basePath := fmt.Sprintf("/%d/", port)
+	basePath := proxyBasePath(port) + "/"
 
 	// Rewrite absolute paths in HTML content
 	content = rewriteHTMLAbsolutePaths(content, basePath)
@@ -407,7 +414,7 @@ func (h *ProxyHandler) modifyHTMLContent(content string, port int) string {
 
 // modifyJavaScriptContent rewrites import paths and other absolute paths in JavaScript content
 func (h *ProxyHandler) modifyJavaScriptContent(content string, port int) string {
-	basePath := fmt.Sprintf("/%d", port)
+	basePath := proxyBasePath(port)
 
 	// Regex patterns to match various import and path patterns in JavaScript
 	patterns := []struct {
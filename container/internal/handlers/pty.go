@@ -24,11 +24,13 @@ import (
 	"github.com/creack/pty"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
 	"github.com/vanpelt/catnip/internal/claude/paths"
 	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/git"
 	"github.com/vanpelt/catnip/internal/models"
 	"github.com/vanpelt/catnip/internal/services"
+	"github.com/vanpelt/catnip/internal/tui"
 )
 
 // PTYConnection represents an abstract connection transport for PTY sessions
@@ -51,6 +53,15 @@ type ControlMessage struct {
 	Cols    uint16 `json:"cols,omitempty"`
 	Rows    uint16 `json:"rows,omitempty"`
 	Focused bool   `json:"focused,omitempty"`
+	// Macro to record or replay, and the parameters to replay it with
+	Name   string            `json:"name,omitempty"`
+	Params map[string]string `json:"params,omitempty"`
+	// How much recent output to summarize when no selection is sent
+	LastKB int `json:"last_kb,omitempty"`
+	// Sent with ready by clients that kept their scrollback: the buffer_id and
+	// offset of the last buffer-complete plus the output bytes received since
+	BufferID string `json:"buffer_id,omitempty"`
+	Offset   int64  `json:"offset,omitempty"`
 }
 
 // WebSocketConnection implements PTYConnection for WebSocket connections
@@ -137,6 +148,7 @@ type WorkspaceFailureTracker struct {
 type PTYHandler struct {
 	sessions       map[string]*Session
 	sessionMutex   sync.RWMutex
+	instanceMutex  sync.Mutex // Serializes picking agent instance numbers
 	failureTracker map[string]*WorkspaceFailureTracker
 	failureMutex   sync.RWMutex
 	gitService     *services.GitService
@@ -145,6 +157,25 @@ type PTYHandler struct {
 	portMonitor    *services.PortMonitor
 	ptyService     *services.PTYService
 	claudeMonitor  *services.ClaudeMonitorService
+	procInspector  *services.ProcessTreeInspector
+	watches        *services.PTYWatchRegistry
+	attention      *services.PTYAttentionTracker
+	recordings     *services.PTYRecorder
+	annotations    *services.PTYAnnotationRegistry
+	scrollback     *services.PTYScrollbackStore
+	agents         *services.AgentRegistry
+	events         *EventsHandler
+	sshAgent       *services.SSHAgentService
+	claudeService  *services.ClaudeService
+	composites     *services.CompositeWorkspaceService
+	kubernetes     *services.KubernetesRuntime
+	chaos          *services.PTYChaos
+	macros         *services.PTYMacroStore
+	summarizer     *services.TerminalSummarizer
+	composer       *services.PromptComposer
+	connLog        *services.PTYConnectionLog
+	orphans        []SessionOrphan // Processes that survived session termination
+	orphanMutex    sync.Mutex
 }
 
 // ConnectionInfo tracks metadata for each connection
@@ -155,6 +186,10 @@ type ConnectionInfo struct {
 	IsReadOnly  bool
 	IsFocused   bool
 	ConnType    string // "websocket" or "sse"
+	// Screen-reader transform for connections in accessible output mode
+	Accessible *services.AccessibleTransformer
+	// Output offset the connection has received up to, once its buffer was replayed
+	Offset int64
 }
 
 // Session represents a PTY session
@@ -175,6 +210,10 @@ type Session struct {
 	outputBuffer  []byte
 	bufferMutex   sync.RWMutex
 	maxBufferSize int
+	// Total bytes of output buffered, i.e. the offset of the buffer's end, and
+	// an ID that changes whenever the buffer is cleared
+	outputOffset int64
+	bufferID     string
 	// Terminal dimensions
 	cols uint16
 	rows uint16
@@ -195,13 +234,20 @@ type Session struct {
 	// Recreation protection - prevents concurrent recreation attempts
 	recreationInProgress bool
 	recreationMutex      sync.Mutex
+	// Held while a macro is typed into the session, one at a time
+	macroMutex sync.Mutex
 	// External workspace read-only protection
 	IsReadOnlyWorkspace bool
 	// PTY readiness tracking - indicates if PTY is ready to accept user input
 	IsReady    bool
 	readyAt    time.Time
 	readyMutex sync.RWMutex
-	// Terminal emulator for Claude sessions (server-side terminal state)
+	// Last output recorded as activity of an agent without hooks, only
+	// touched by the PTY reader
+	lastAgentOutput time.Time
+	// Terminal emulator for Claude sessions (server-side terminal state),
+	// guarded by bufferMutex. Reconnecting clients get a snapshot of it.
+	screen *tui.TerminalEmulator
 }
 
 // ResizeMsg represents terminal resize message
@@ -307,6 +353,28 @@ func (h *PTYHandler) findWorktreeByName(name string) *models.Worktree {
 	return nil
 }
 
+// injectPrompt types a prompt into a session's TUI. With submit, a carriage
+// return follows after a small delay, mimicking how a user would type and
+// then press Enter.
+func injectPrompt(session *Session, prompt string, submit bool) error {
+	logger.Infof("📝 Injecting prompt into PTY: %q (submit: %v)", prompt, submit)
+	if _, err := session.PTY.Write([]byte(prompt)); err != nil {
+		return err
+	}
+
+	if submit {
+		go func() {
+			// Delay to let the TUI process the prompt text before submitting
+			time.Sleep(1 * time.Second)
+			logger.Infof("↩️ Sending carriage return (\\r) to execute prompt")
+			if _, err := session.PTY.Write([]byte("\r")); err != nil {
+				logger.Warnf("❌ Failed to write carriage return to PTY: %v", err)
+			}
+		}()
+	}
+	return nil
+}
+
 // NewPTYHandler creates a new PTY handler
 func NewPTYHandler(gitService *services.GitService, claudeMonitor *services.ClaudeMonitorService, sessionService *services.SessionService, portMonitor *services.PortMonitor) *PTYHandler {
 	h := &PTYHandler{
@@ -318,21 +386,133 @@ func NewPTYHandler(gitService *services.GitService, claudeMonitor *services.Clau
 		portMonitor:    portMonitor, // Use the provided portMonitor instead of creating new one
 		ptyService:     services.NewPTYService(),
 		claudeMonitor:  claudeMonitor,
+		procInspector:  services.NewProcessTreeInspector(),
+		watches:        services.NewPTYWatchRegistry(),
+		attention:      services.NewPTYAttentionTracker(),
+		recordings:     services.NewPTYRecorder(),
+		annotations:    services.NewPTYAnnotationRegistry(),
+		scrollback:     services.NewPTYScrollbackStore(),
+		agents:         services.NewAgentRegistry(),
+		composer:       services.NewPromptComposer(),
+		connLog:        services.NewPTYConnectionLog(),
 	}
 
+	h.agents.Register(claudeAgent{h: h})
+
 	// Start periodic cleanup routine for non-existent workspaces
 	go h.periodicWorkspaceCleanup()
 
+	// Keep shell scrollback on the volume so it survives container restarts
+	go h.periodicScrollbackSave()
+
+	return h
+}
+
+// WithEvents attaches an events handler for broadcasting watch notifications
+func (h *PTYHandler) WithEvents(events *EventsHandler) *PTYHandler {
+	h.events = events
+	return h
+}
+
+// WithSSHAgent forwards the user's SSH agent into workspace terminals
+func (h *PTYHandler) WithSSHAgent(sshAgent *services.SSHAgentService) *PTYHandler {
+	h.sshAgent = sshAgent
+	return h
+}
+
+// WithClaudeService layers the org, repository and workspace system prompts
+// into Claude sessions
+func (h *PTYHandler) WithClaudeService(claudeService *services.ClaudeService) *PTYHandler {
+	h.claudeService = claudeService
+	return h
+}
+
+// WithComposites opens sessions in composite workspaces and gives sessions
+// and setup runs in them the workspace's env vars and context
+func (h *PTYHandler) WithComposites(composites *services.CompositeWorkspaceService) *PTYHandler {
+	h.composites = composites
+	h.ptyService.WithSetupEnv(composites.EnvForPath)
 	return h
 }
 
+// findCompositeBySessionID finds the composite workspace for a
+// "composite/<name>" session
+func (h *PTYHandler) findCompositeBySessionID(sessionID string) *services.CompositeWorkspace {
+	if h.composites == nil {
+		return nil
+	}
+	for _, workspace := range h.composites.List() {
+		if workspace.SessionID() == sessionID {
+			return workspace
+		}
+	}
+	return nil
+}
+
+// systemPrompt returns the layered system prompt for Claude in workDir.
+// Workspace context (the linked issue and worktree memory) only goes to new
+// sessions, and the branch summary only to new sessions started directly by
+// the server.
+func (h *PTYHandler) systemPrompt(workDir string, includeWorkspace, warmContext bool) string {
+	workspaceContext := ""
+	if includeWorkspace && h.gitService != nil {
+		workspaceContext = joinNonEmpty(h.gitService.IssueContextForPath(workDir), h.gitService.MemoryContextForPath(workDir))
+		if warmContext {
+			workspaceContext = joinNonEmpty(workspaceContext, h.gitService.WarmContextForPath(workDir))
+		}
+	}
+	if includeWorkspace && h.composites != nil {
+		workspaceContext = joinNonEmpty(workspaceContext, h.composites.ContextForPath(workDir))
+	}
+	if h.claudeService == nil {
+		return workspaceContext
+	}
+	return h.claudeService.LayeredSystemPrompt(workDir, workspaceContext, "").Prompt
+}
+
+// joinNonEmpty joins prompt sections with blank lines, skipping empty ones
+func joinNonEmpty(sections ...string) string {
+	parts := make([]string, 0, len(sections))
+	for _, section := range sections {
+		if section = strings.TrimSpace(section); section != "" {
+			parts = append(parts, section)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// sshAgentEnv points SSH_AUTH_SOCK at the workspace's agent proxy, or clears
+// it when the workspace has forwarding turned off
+func (h *PTYHandler) sshAgentEnv(workDir string) []string {
+	if h.sshAgent == nil || h.gitService == nil {
+		return nil
+	}
+	for _, worktree := range h.gitService.GetStateManager().GetAllWorktrees() {
+		if worktree.Path != workDir {
+			continue
+		}
+		if !h.sshAgent.Enabled(worktree.SSHAgentForwarding) {
+			return []string{"SSH_AUTH_SOCK="}
+		}
+		socket, err := h.sshAgent.SocketFor(worktree.ID, worktree.Name)
+		if err != nil {
+			logger.Debugf("🔑 Not forwarding SSH agent to %s: %v", worktree.Name, err)
+			return nil
+		}
+		return []string{"SSH_AUTH_SOCK=" + socket}
+	}
+	return nil
+}
+
+// catnipClaudeWrapperPath runs claude through `catnip purr`
+const catnipClaudeWrapperPath = "/opt/catnip/bin/claude"
+
 // findClaudeExecutable finds the claude executable using robust path lookup
 func (h *PTYHandler) findClaudeExecutable() string {
 	// PRIORITY 1: Try Catnip's wrapper script first (for title interception)
-	catnipClaudePath := "/opt/catnip/bin/claude"
-	if _, err := os.Stat(catnipClaudePath); err == nil {
-		logger.Debugf("Found Catnip claude wrapper: %s", catnipClaudePath)
-		return catnipClaudePath
+	if _, err := os.Stat(catnipClaudeWrapperPath); err == nil {
+		logger.Debugf("Found Catnip claude wrapper: %s", catnipClaudeWrapperPath)
+		return catnipClaudeWrapperPath
 	}
 
 	// PRIORITY 2: Try standard PATH lookup
@@ -384,6 +564,7 @@ func (h *PTYHandler) findClaudeExecutable() string {
 // @Description Establishes a WebSocket connection for terminal access
 // @Tags pty
 // @Param session query string true "Session ID"
+// @Param mode query string false "Output mode: raw (default) or accessible, which sends screen-reader friendly {type: a11y, kind, text} JSON messages instead of terminal bytes"
 // @Success 101 {string} string "Switching Protocols"
 // @Router /v1/pty [get]
 func (h *PTYHandler) HandleWebSocket(c *fiber.Ctx) error {
@@ -397,18 +578,16 @@ func (h *PTYHandler) HandleWebSocket(c *fiber.Ctx) error {
 		sessionID := c.Query("session", defaultSession)
 		agent := c.Query("agent", "")
 		reset := c.Query("reset", "false") == "true"
+		outputMode := c.Query("mode", services.PTYOutputRaw)
 
 		// Debug logging to understand what session ID we're actually receiving
 		logger.Debugf("🔍 WebSocket PTY request - Raw session param: %q, Default session: %q, Final sessionID: %q", c.Query("session"), defaultSession, sessionID)
 
-		// Create composite session key: path + agent
-		compositeSessionID := sessionID
-		if agent != "" {
-			compositeSessionID = fmt.Sprintf("%s:%s", sessionID, agent)
-		}
+		// Create composite session key: path + agent (+ instance)
+		compositeSessionID := sessionKey(sessionID, agent, queryInstance(c))
 
 		return websocket.New(func(conn *websocket.Conn) {
-			h.handlePTYConnection(conn, compositeSessionID, agent, reset)
+			h.handlePTYConnection(conn, compositeSessionID, agent, reset, outputMode)
 		})(c)
 	}
 	return fiber.ErrUpgradeRequired
@@ -420,8 +599,11 @@ func (h *PTYHandler) HandleWebSocket(c *fiber.Ctx) error {
 // @Tags pty
 // @Param session query string true "Session ID (workspace name)"
 // @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param sandbox query bool false "Mount an overlay sandbox over the worktree first, so the session's changes can be promoted or discarded (containerized mode only)"
 // @Success 200 {object} map[string]interface{} "Session started or already exists"
 // @Failure 400 {object} map[string]interface{} "Invalid parameters"
+// @Failure 404 {object} map[string]interface{} "Sandboxed workspace not found"
 // @Failure 500 {object} map[string]interface{} "Failed to create session"
 // @Router /v1/pty/start [post]
 func (h *PTYHandler) HandlePTYStart(c *fiber.Ctx) error {
@@ -434,27 +616,27 @@ func (h *PTYHandler) HandlePTYStart(c *fiber.Ctx) error {
 	agent := c.Query("agent", "")
 
 	if sessionID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "session parameter is required",
-		})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "session parameter is required"))
 	}
 
 	logger.Infof("🚀 Starting PTY session - session: %s, agent: %s", sessionID, agent)
 
-	// Create composite session key: path + agent
-	compositeSessionID := sessionID
-	if agent != "" {
-		compositeSessionID = fmt.Sprintf("%s:%s", sessionID, agent)
+	// Create composite session key: path + agent (+ instance)
+	compositeSessionID := sessionKey(sessionID, agent, queryInstance(c))
+
+	// The sandbox has to be mounted before the session's shell enters the worktree
+	sandbox := c.QueryBool("sandbox", false)
+	if sandbox {
+		if err := h.startSandbox(sessionID); err != nil {
+			return respondError(c, fiber.StatusInternalServerError, err)
+		}
 	}
 
 	// Get or create session (returns immediately after starting)
 	session := h.getOrCreateSession(compositeSessionID, agent, false)
 	if session == nil {
 		logger.Errorf("❌ Failed to create session: %s", compositeSessionID)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create session",
-			"session": compositeSessionID,
-		})
+		return respondError(c, fiber.StatusInternalServerError, models.NewAPIError(models.ErrCodeInternal, "Failed to create session %s", compositeSessionID))
 	}
 
 	logger.Infof("✅ PTY session started successfully: %s", compositeSessionID)
@@ -474,6 +656,7 @@ func (h *PTYHandler) HandlePTYStart(c *fiber.Ctx) error {
 		"session_id": compositeSessionID,
 		"is_ready":   isReady,
 		"ready_at":   readyAt,
+		"sandbox":    sandbox,
 	})
 }
 
@@ -483,6 +666,7 @@ func (h *PTYHandler) HandlePTYStart(c *fiber.Ctx) error {
 // @Tags pty
 // @Param session query string true "Session ID (workspace name)"
 // @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
 // @Param prompt body string true "Prompt text to send"
 // @Success 200 {object} map[string]interface{} "Prompt sent successfully"
 // @Failure 400 {object} map[string]interface{} "Invalid parameters"
@@ -500,9 +684,7 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 	agent := c.Query("agent", "")
 
 	if sessionID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "session parameter is required",
-		})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "session parameter is required"))
 	}
 
 	// Read and parse JSON body to extract prompt
@@ -510,25 +692,18 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 		Prompt string `json:"prompt"`
 	}
 	if err := c.BodyParser(&requestBody); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid JSON body",
-		})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid JSON body"))
 	}
 
 	prompt := requestBody.Prompt
 	if prompt == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "prompt is required in request body",
-		})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "prompt is required in request body"))
 	}
 
 	logger.Infof("📝 Sending prompt to PTY - session: %s, agent: %s, prompt length: %d", sessionID, agent, len(prompt))
 
-	// Create composite session key: path + agent
-	compositeSessionID := sessionID
-	if agent != "" {
-		compositeSessionID = fmt.Sprintf("%s:%s", sessionID, agent)
-	}
+	// Create composite session key: path + agent (+ instance)
+	compositeSessionID := sessionKey(sessionID, agent, queryInstance(c))
 
 	// Get session from sessions map
 	h.sessionMutex.RLock()
@@ -537,10 +712,7 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 
 	if !exists || session == nil {
 		logger.Errorf("❌ Session not found: %s", compositeSessionID)
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   "Session not found",
-			"session": compositeSessionID,
-		})
+		return respondError(c, fiber.StatusNotFound, models.NewAPIError(models.ErrCodeSessionNotFound, "Session %s not found", compositeSessionID))
 	}
 
 	// Wait for PTY to be ready (up to 15 seconds)
@@ -550,11 +722,7 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 
 	if !h.waitForPTYReady(session, timeout) {
 		logger.Warnf("⏰ PTY not ready within timeout for session: %s", compositeSessionID)
-		return c.Status(fiber.StatusRequestTimeout).JSON(fiber.Map{
-			"error":           "PTY not ready",
-			"timeout_seconds": int(timeout.Seconds()),
-			"session":         compositeSessionID,
-		})
+		return respondError(c, fiber.StatusRequestTimeout, models.NewRetryableAPIError(models.ErrCodeTimeout, "PTY for session %s not ready after %v", compositeSessionID, timeout))
 	}
 
 	// PTY is ready, inject the prompt
@@ -563,10 +731,7 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 	// Write prompt text first
 	if _, err := session.PTY.Write([]byte(prompt)); err != nil {
 		logger.Errorf("❌ Failed to write prompt to PTY: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to write prompt to PTY",
-			"session": compositeSessionID,
-		})
+		return respondError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to write prompt to PTY of session %s: %w", compositeSessionID, err))
 	}
 
 	// Wait 1 second before sending carriage return to ensure PTY is ready to process it
@@ -575,10 +740,7 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 	// Send carriage return to submit the prompt
 	if _, err := session.PTY.Write([]byte("\r")); err != nil {
 		logger.Errorf("❌ Failed to write carriage return to PTY: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to submit prompt to PTY",
-			"session": compositeSessionID,
-		})
+		return respondError(c, fiber.StatusInternalServerError, fmt.Errorf("failed to submit prompt to PTY of session %s: %w", compositeSessionID, err))
 	}
 
 	logger.Infof("✅ Prompt sent successfully to session: %s", compositeSessionID)
@@ -595,6 +757,7 @@ func (h *PTYHandler) HandlePTYPrompt(c *fiber.Ctx) error {
 // @Tags pty
 // @Param session query string true "Session ID (workspace name)"
 // @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
 // @Success 200 {object} map[string]interface{} "Session status"
 // @Failure 400 {object} map[string]interface{} "Invalid parameters"
 // @Failure 404 {object} map[string]interface{} "Session not found"
@@ -609,16 +772,11 @@ func (h *PTYHandler) HandlePTYStatus(c *fiber.Ctx) error {
 	agent := c.Query("agent", "")
 
 	if sessionID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "session parameter is required",
-		})
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "session parameter is required"))
 	}
 
-	// Create composite session key: path + agent
-	compositeSessionID := sessionID
-	if agent != "" {
-		compositeSessionID = fmt.Sprintf("%s:%s", sessionID, agent)
-	}
+	// Create composite session key: path + agent (+ instance)
+	compositeSessionID := sessionKey(sessionID, agent, queryInstance(c))
 
 	// Get session from sessions map
 	h.sessionMutex.RLock()
@@ -626,10 +784,7 @@ func (h *PTYHandler) HandlePTYStatus(c *fiber.Ctx) error {
 	h.sessionMutex.RUnlock()
 
 	if !exists || session == nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   "Session not found",
-			"session": compositeSessionID,
-		})
+		return respondError(c, fiber.StatusNotFound, models.NewAPIError(models.ErrCodeSessionNotFound, "Session %s not found", compositeSessionID))
 	}
 
 	// Get readiness state
@@ -667,15 +822,140 @@ func (h *PTYHandler) HandlePTYStatus(c *fiber.Ctx) error {
 	})
 }
 
-func (h *PTYHandler) handlePTYConnection(conn *websocket.Conn, sessionID, agent string, reset bool) {
+// sessionKeyFromQuery builds the composite session key from the session, agent and instance query parameters
+func sessionKeyFromQuery(c *fiber.Ctx) string {
+	defaultSession := os.Getenv("CATNIP_SESSION")
+	if defaultSession == "" {
+		defaultSession = "default"
+	}
+	return sessionKey(c.Query("session", defaultSession), c.Query("agent", ""), queryInstance(c))
+}
+
+// HandleListWatches lists output watches registered for a PTY session
+// @Summary List PTY session watches
+// @Description Returns the output patterns being watched in a PTY session
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {array} services.SessionWatch
+// @Router /v1/pty/watches [get]
+func (h *PTYHandler) HandleListWatches(c *fiber.Ctx) error {
+	return c.JSON(h.watches.List(sessionKeyFromQuery(c)))
+}
+
+// HandleAddWatch registers an output watch on a PTY session
+// @Summary Watch PTY session output
+// @Description Registers a string or regex that triggers a session:watch_matched event and a notification when it appears in the session's output. Notifications for a watch are rate limited by its cooldown.
+// @Tags pty
+// @Accept json
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param watch body services.SessionWatch true "Watch definition (pattern, regex, label, once, cooldown_seconds)"
+// @Success 201 {object} services.SessionWatch
+// @Failure 400 {object} map[string]string
+// @Router /v1/pty/watches [post]
+func (h *PTYHandler) HandleAddWatch(c *fiber.Ctx) error {
+	var watch services.SessionWatch
+	if err := c.BodyParser(&watch); err != nil {
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body: %v", err))
+	}
+
+	sessionID := sessionKeyFromQuery(c)
+	created, err := h.watches.Add(sessionID, watch)
+	if err != nil {
+		return respondError(c, fiber.StatusBadRequest, err)
+	}
+
+	logger.Infof("👀 Watching session %s for %q", sessionID, created.Pattern)
+	return c.Status(fiber.StatusCreated).JSON(created)
+}
+
+// HandleDeleteWatch removes an output watch from a PTY session
+// @Summary Remove PTY session watch
+// @Tags pty
+// @Produce json
+// @Param id path string true "Watch ID"
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/watches/{id} [delete]
+func (h *PTYHandler) HandleDeleteWatch(c *fiber.Ctx) error {
+	if !h.watches.Remove(sessionKeyFromQuery(c), c.Params("id")) {
+		return respondError(c, fiber.StatusNotFound, models.NewAPIError(models.ErrCodeNotFound, "Watch not found"))
+	}
+	return c.JSON(fiber.Map{
+		"status": "deleted",
+	})
+}
+
+// HandleGetAttention returns a PTY session's attention state
+// @Summary Get PTY session attention state
+// @Description Returns pending bell/OSC 777 attention signals for the session badge, the last signal and the session's mute settings
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {object} services.SessionAttention
+// @Router /v1/pty/attention [get]
+func (h *PTYHandler) HandleGetAttention(c *fiber.Ctx) error {
+	return c.JSON(h.attention.Get(sessionKeyFromQuery(c)))
+}
+
+// HandleUpdateAttention updates a PTY session's attention mute controls
+// @Summary Configure PTY session attention signals
+// @Description Mutes a session's bell and OSC 777 signals, or opts plain bells into native notifications
+// @Tags pty
+// @Accept json
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param settings body services.SessionAttentionSettings true "Mute settings"
+// @Success 200 {object} services.SessionAttention
+// @Failure 400 {object} map[string]string
+// @Router /v1/pty/attention [put]
+func (h *PTYHandler) HandleUpdateAttention(c *fiber.Ctx) error {
+	var settings services.SessionAttentionSettings
+	if err := c.BodyParser(&settings); err != nil {
+		return respondError(c, fiber.StatusBadRequest, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body: %v", err))
+	}
+	return c.JSON(h.attention.Configure(sessionKeyFromQuery(c), settings))
+}
+
+// HandleAcknowledgeAttention clears a PTY session's attention badge
+// @Summary Acknowledge PTY session attention signals
+// @Description Clears pending attention signals once the user has looked at the session
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {object} services.SessionAttention
+// @Router /v1/pty/attention/ack [post]
+func (h *PTYHandler) HandleAcknowledgeAttention(c *fiber.Ctx) error {
+	return c.JSON(h.attention.Acknowledge(sessionKeyFromQuery(c)))
+}
+
+func (h *PTYHandler) handlePTYConnection(conn *websocket.Conn, sessionID, agent string, reset bool, outputMode string) {
 	// Wrap WebSocket connection in transport abstraction
 	wsConn := NewWebSocketConnection(context.Background(), conn)
 
+	// Inject failures when chaos testing is enabled
+	ptyConn, untrack := h.wrapChaos(wsConn)
+	defer untrack()
+
 	// Use the unified handler with the wrapped connection
-	h.handleConnection(wsConn, sessionID, agent, reset)
+	h.handleConnection(ptyConn, sessionID, agent, reset, outputMode)
 }
 
-func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent string, reset bool) {
+func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent string, reset bool, outputMode string) {
 	// Generate unique connection ID for logging and tracking
 	connID := fmt.Sprintf("%p", conn)
 
@@ -691,13 +971,13 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 	if reset && agent == "claude" {
 		logger.Infof("🔄 Reset requested for Claude session: %s", sessionID)
 		// Shutdown any existing PTY session for this sessionID
-		h.sessionMutex.Lock()
-		if existingSession, exists := h.sessions[sessionID]; exists {
+		h.sessionMutex.RLock()
+		existingSession, exists := h.sessions[sessionID]
+		h.sessionMutex.RUnlock()
+		if exists {
 			logger.Infof("🛑 Shutting down existing session: %s", sessionID)
 			h.cleanupSession(existingSession)
-			delete(h.sessions, sessionID)
 		}
-		h.sessionMutex.Unlock()
 	}
 
 	// Get or create session
@@ -712,25 +992,21 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		}
 
 		// Send error message to WebSocket client before closing
+		apiErr := models.NewWorktreeNotFoundError(sessionID)
 		errorMsg := struct {
-			Type      string `json:"type"`
-			Error     string `json:"error"`
-			Message   string `json:"message"`
-			Code      string `json:"code"`
-			Retryable bool   `json:"retryable"`
+			Type  string `json:"type"`
+			Error string `json:"error"`
+			*models.APIError
 		}{
-			Type:      "error",
-			Error:     "Worktree not found",
-			Message:   fmt.Sprintf("The worktree '%s' does not exist", sessionID),
-			Code:      "WORKTREE_NOT_FOUND",
-			Retryable: false, // This error is not retryable - workspace doesn't exist
+			Type:     "error",
+			Error:    "Worktree not found",
+			APIError: apiErr,
 		}
+		errorMsg.Message = fmt.Sprintf("The worktree '%s' does not exist", sessionID)
 
 		if data, err := json.Marshal(errorMsg); err == nil && conn.Type() == "websocket" {
 			// For WebSocket, we need to write as text message
-			if wsConn, ok := conn.(*WebSocketConnection); ok {
-				_ = wsConn.conn.WriteMessage(websocket.TextMessage, data)
-			}
+			_ = conn.WriteJSONMessage(data)
 		}
 
 		conn.Close()
@@ -778,12 +1054,16 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		}
 
 		// Close them outside the range loop to avoid map modification during iteration
+		var closedRemotes []string
 		for _, existingConn := range connectionsToClose {
-			if _, exists := session.connections[existingConn]; exists {
+			if existingInfo, exists := session.connections[existingConn]; exists {
 				delete(session.connections, existingConn)
 				existingConn.Close()
+				closedRemotes = append(closedRemotes, existingInfo.RemoteAddr)
+				h.logConnectionEvent(session, existingInfo, services.PTYConnectionForceClose, services.PTYReasonNuclearCleanup)
 			}
 		}
+		h.connLog.RecordNuclearCleanup(sessionID, remoteAddr, closedRemotes)
 
 		logger.Debugf("✅ Cleared all %d existing connections from session %s", len(connectionsToClose), sessionID)
 	}
@@ -810,8 +1090,13 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		ConnType:    conn.Type(),
 	}
 	newConnectionCount := len(session.connections)
+	h.logConnectionEvent(session, session.connections[conn], services.PTYConnectionConnect, "")
 	session.connMutex.Unlock()
 
+	if outputMode == services.PTYOutputAccessible {
+		h.setOutputMode(session, conn, outputMode)
+	}
+
 	if isReadOnly {
 		logger.Debugf("🔗 Added READ-ONLY connection [%s] to session %s (connections: %d → %d)", connID, sessionID, connectionCount, newConnectionCount)
 
@@ -870,6 +1155,9 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		delete(session.connections, conn)
 		connectionCount := len(session.connections)
 		logger.Debugf("🔍 Connection count for session %s: %d (after removal)", session.ID, connectionCount)
+		if exists {
+			h.logConnectionEvent(session, connInfo, services.PTYConnectionDisconnect, "")
+		}
 
 		// If the write connection disconnected, promote the oldest read-only connection
 		if wasWriteConnection && connectionCount > 0 {
@@ -886,6 +1174,7 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 			if oldestConn != nil {
 				session.connections[oldestConn].IsReadOnly = false
 				promotedConnID := session.connections[oldestConn].ConnID
+				h.logConnectionEvent(session, session.connections[oldestConn], services.PTYConnectionPromote, services.PTYReasonWriterLeft)
 				logger.Debugf("🔄 Promoted connection [%s] to WRITE access in session %s", promotedConnID, sessionID)
 
 				// Notify the promoted connection about write access
@@ -916,6 +1205,9 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 		}
 	}()
 
+	// Input from this connection is recorded here while recording a macro
+	var macroRecorder *services.PTYMacroRecorder
+
 	// Read from connection and write to PTY
 	for {
 		controlMsg, err := conn.ReadControlMessage()
@@ -940,117 +1232,21 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 			case "ready":
 				logger.Infof("🔧 Client ready signal received for session: %s", sessionID)
 
-				// For Claude sessions, just send buffer-complete and let frontend resize trigger redraw
+				// Claude sessions get a snapshot of their emulated screen, other
+				// sessions a replay of their output buffer. Both send buffer-complete.
 				if session.Agent == "claude" {
-					logger.Debugf("🔄 Claude session reconnected - frontend resize will trigger redraw")
-
-					// Just send buffer-complete - frontend will send resize which triggers SIGWINCH at right time
-					completeMsg := struct {
-						Type string `json:"type"`
-					}{
-						Type: "buffer-complete",
-					}
-
-					if data, err := json.Marshal(completeMsg); err == nil {
-						logger.Infof("🔧 Sending buffer-complete to Claude session")
-						_ = session.writeJSONToConnection(conn, data)
-					}
+					logger.Debugf("🔄 Claude session reconnected - sending screen snapshot")
+					h.replayScreen(session, conn)
 				} else {
-					// Traditional buffer replay for non-Claude sessions
-					session.bufferMutex.RLock()
-					hasBuffer := len(session.outputBuffer) > 0
-					bufferCols := session.bufferedCols
-					bufferRows := session.bufferedRows
-					session.bufferMutex.RUnlock()
-
-					if hasBuffer && bufferCols > 0 && bufferRows > 0 {
-						// First, resize PTY to match buffered dimensions
-						logger.Infof("📐 Resizing PTY to buffered dimensions %dx%d before replay", bufferCols, bufferRows)
-						_ = h.resizePTY(session.PTY, bufferCols, bufferRows)
-
-						// Tell client what size to use for replay
-						sizeMsg := struct {
-							Type string `json:"type"`
-							Cols uint16 `json:"cols"`
-							Rows uint16 `json:"rows"`
-						}{
-							Type: "buffer-size",
-							Cols: bufferCols,
-							Rows: bufferRows,
-						}
-
-						if data, err := json.Marshal(sizeMsg); err == nil {
-							_ = session.writeJSONToConnection(conn, data)
-						}
-
-						// Then replay the buffer (filter TUI content if alternate screen is active)
-						session.bufferMutex.RLock()
-						var bufferToReplay []byte
-
-						if session.AlternateScreenActive && session.LastNonTUIBufferSize > 0 {
-							// Only replay content up to where alternate screen was entered
-							bufferToReplay = make([]byte, session.LastNonTUIBufferSize)
-							copy(bufferToReplay, session.outputBuffer[:session.LastNonTUIBufferSize])
-							logger.Debugf("📋 Replaying %d bytes (filtered from %d) - excluding TUI content", len(bufferToReplay), len(session.outputBuffer))
-						} else {
-							// Replay entire buffer for non-TUI sessions
-							bufferToReplay = make([]byte, len(session.outputBuffer))
-							copy(bufferToReplay, session.outputBuffer)
-							logger.Debugf("📋 Replaying %d bytes of buffered output at %dx%d", len(bufferToReplay), bufferCols, bufferRows)
-						}
-						session.bufferMutex.RUnlock()
-
-						if err := session.writeToConnection(conn, bufferToReplay); err != nil {
-							logger.Warnf("❌ Failed to replay buffer: %v", err)
-						}
-
-						// If we filtered TUI content, send a refresh signal to trigger TUI repaint
-						if session.AlternateScreenActive && session.LastNonTUIBufferSize > 0 {
-							go func() {
-								time.Sleep(100 * time.Millisecond)
-								logger.Infof("🔄 Sending Ctrl+L to refresh TUI after filtered buffer replay")
-								if _, err := session.PTY.Write([]byte("\x0c")); err != nil {
-									logger.Warnf("❌ Failed to send refresh signal: %v", err)
-								}
-							}()
-						}
-					} else {
-						logger.Debugf("📋 No buffer to replay or dimensions not captured")
-					}
-				}
-
-				// Always send buffer complete signal
-				completeMsg := struct {
-					Type string `json:"type"`
-				}{
-					Type: "buffer-complete",
-				}
-
-				if data, err := json.Marshal(completeMsg); err == nil {
-					logger.Infof("🔧 Sending final buffer-complete signal")
-					_ = session.writeJSONToConnection(conn, data)
+					h.replayBuffer(session, conn, controlMsg)
 				}
 				continue
 			case "prompt":
 				// Handle prompt injection for Claude TUI
 				if controlMsg.Data != "" {
-					logger.Infof("📝 Injecting prompt into PTY: %q (submit: %v)", controlMsg.Data, controlMsg.Submit)
-					if _, err := session.PTY.Write([]byte(controlMsg.Data)); err != nil {
+					if err := injectPrompt(session, controlMsg.Data, controlMsg.Submit); err != nil {
 						logger.Warnf("❌ Failed to write prompt to PTY: %v", err)
 					}
-
-					// If submit is true, send a carriage return after a small delay
-					// This mimics how a user would type and then press Enter
-					if controlMsg.Submit {
-						go func() {
-							// Delay to let the TUI process the prompt text before submitting
-							time.Sleep(1 * time.Second)
-							logger.Infof("↩️ Sending carriage return (\\r) to execute prompt")
-							if _, err := session.PTY.Write([]byte("\r")); err != nil {
-								logger.Warnf("❌ Failed to write carriage return to PTY: %v", err)
-							}
-						}()
-					}
 				}
 				continue
 			case "promote":
@@ -1062,25 +1258,41 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 				// Handle focus state change
 				h.handleFocusChange(session, conn, controlMsg.Focused)
 				continue
+			case "mode":
+				// Switch between raw terminal output and the screen-reader stream
+				h.setOutputMode(session, conn, controlMsg.Data)
+				continue
+			case "macro-record":
+				// Start, stop (saving as controlMsg.Name) or cancel recording this connection's input
+				if h.macros != nil && !conn.IsReadOnly() {
+					macroRecorder = h.handleMacroRecord(session, conn, macroRecorder, controlMsg)
+				}
+				continue
+			case "macro":
+				// Replay a macro of the session's repository
+				if h.macros != nil && !conn.IsReadOnly() {
+					h.runMacroMessage(session, conn, controlMsg)
+				}
+				continue
+			case "summarize":
+				// Explain the selected output (controlMsg.Data) or the last controlMsg.LastKB KB
+				if h.summarizer != nil {
+					go h.summarizeMessage(session, conn, controlMsg)
+				}
+				continue
 			case "resize":
 				// Handle resize
 				logger.Infof("🔧 Received resize message: %dx%d", controlMsg.Cols, controlMsg.Rows)
 				if controlMsg.Cols > 0 && controlMsg.Rows > 0 {
-					// For Claude sessions, always force dimension change to ensure SIGWINCH triggers redraw
-					if session.Agent == "claude" {
-						// First resize to slightly different dimensions to force a change
-						_ = h.resizePTY(session.PTY, controlMsg.Cols-1, controlMsg.Rows-1)
-						// Small delay to ensure the change is processed
-						time.Sleep(10 * time.Millisecond)
-						// Then resize to the target dimensions
+					// Only resize if dimensions actually changed; reconnecting Claude
+					// clients are repainted from the emulated screen instead
+					if session.cols != controlMsg.Cols || session.rows != controlMsg.Rows {
 						_ = h.resizePTY(session.PTY, controlMsg.Cols, controlMsg.Rows)
-						logger.Debugf("📐 Resized Claude PTY to %dx%d", controlMsg.Cols, controlMsg.Rows)
-					} else {
-						// For non-Claude sessions, only resize if dimensions actually changed
-						if session.cols != controlMsg.Cols || session.rows != controlMsg.Rows {
-							_ = h.resizePTY(session.PTY, controlMsg.Cols, controlMsg.Rows)
-							logger.Debugf("📐 Resized PTY to %dx%d", controlMsg.Cols, controlMsg.Rows)
+						if session.Agent == "claude" {
+							session.resizeScreen(controlMsg.Cols, controlMsg.Rows)
 						}
+						h.recordings.Resize(session.ID, int(controlMsg.Cols), int(controlMsg.Rows))
+						logger.Debugf("📐 Resized PTY to %dx%d", controlMsg.Cols, controlMsg.Rows)
 					}
 					session.cols = controlMsg.Cols
 					session.rows = controlMsg.Rows
@@ -1099,6 +1311,12 @@ func (h *PTYHandler) handleConnection(conn PTYConnection, sessionID, agent strin
 						logger.Errorf("❌ Failed to write to PTY: %v", err)
 						break
 					}
+					if macroRecorder != nil {
+						macroRecorder.Record(controlMsg.Data)
+					}
+					if strings.ContainsAny(controlMsg.Data, "\r\n") {
+						h.recordings.Boundary(session.ID)
+					}
 				}
 				continue
 			}
@@ -1146,11 +1364,11 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 	// Set workspace directory with validation
 	var workDir string
 
-	// Extract base session ID without agent suffix for worktree lookups
-	baseSessionID := sessionID
-	if idx := strings.LastIndex(sessionID, ":"); idx != -1 {
-		baseSessionID = sessionID[:idx]
-	}
+	// Extract base session ID without agent and instance suffixes for worktree lookups
+	baseSessionID, _, instance := parseSessionKey(sessionID)
+	// Later instances of an agent run alongside the first, so they neither
+	// resume nor take over the workspace's Claude session tracking
+	primary := instance == 1
 
 	logger.Infof("🐱 [getOrCreateSession] sessionID: %s, baseSessionID: %s", sessionID, baseSessionID)
 	logger.Infof("🐱 [getOrCreateSession] WorkspaceDir: %s", config.Runtime.WorkspaceDir)
@@ -1180,6 +1398,9 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 			// Use the path from the worktree state
 			workDir = worktree.Path
 			logger.Infof("📁 Using worktree from state for session %s: %s (name: %s)", baseSessionID, workDir, worktree.Name)
+		} else if composite := h.findCompositeBySessionID(baseSessionID); composite != nil {
+			workDir = composite.Path
+			logger.Infof("📁 Using composite workspace for session %s: %s", baseSessionID, workDir)
 		} else {
 			// Fallback to legacy directory-based lookup for backward compatibility
 			if strings.Contains(baseSessionID, "/") {
@@ -1234,7 +1455,12 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 	// Check for existing Claude session in this directory for auto-resume
 	var resumeSessionID string
 	var useContinue bool
-	if agent == "claude" && !reset {
+	registered, isAgent := h.agents.Lookup(agent, workDir)
+	if isAgent && agent != "claude" && !reset && primary {
+		// Other agents continue their latest conversation themselves
+		useContinue = h.resumesAgentSession(registered, sessionID, workDir)
+	}
+	if agent == "claude" && !reset && primary {
 		// Try to find existing session with valid content (not Warmup sessions)
 		if existingState, err := h.sessionService.FindSessionByDirectory(workDir); err == nil && existingState != nil {
 			// Use --resume with specific session ID to preserve conversation history
@@ -1284,6 +1510,7 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 		connections:   make(map[PTYConnection]*ConnectionInfo),
 		outputBuffer:  make([]byte, 0),
 		maxBufferSize: 5 * 1024 * 1024, // 5MB buffer
+		bufferID:      uuid.NewString(),
 		cols:          80,
 		rows:          24,
 		bufferedCols:  80,
@@ -1305,6 +1532,10 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 		IsReadOnlyWorkspace: agent == "claude" && h.isExternalWorkspace(workDir),
 	}
 
+	if agent != "claude" && !reset {
+		h.restoreScrollback(session)
+	}
+
 	h.sessions[sessionID] = session
 	logger.Debugf("✅ Created new PTY session: %s in %s with agent: %s", sessionID, workDir, agent)
 
@@ -1314,7 +1545,7 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 	}
 
 	// Track active session for this workspace
-	if agent == "claude" {
+	if agent == "claude" && primary {
 		// Start or resume session tracking - we'll update with actual Claude session UUID later
 		if activeSession, err := h.sessionService.StartOrResumeActiveSession(workDir, ""); err != nil {
 			logger.Infof("⚠️  Failed to start/resume session tracking for %s: %v", workDir, err)
@@ -1328,7 +1559,7 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 	}
 
 	// Save initial session state for persistence
-	if agent == "claude" {
+	if isAgent && primary {
 		go h.saveSessionState(session)
 	}
 
@@ -1337,7 +1568,9 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 
 	// Start Claude session ID monitoring for claude sessions
 	if agent == "claude" {
-		go h.monitorClaudeSession(session)
+		if primary {
+			go h.monitorClaudeSession(session)
+		}
 
 		// Trigger immediate Claude activity state sync to update frontend quickly
 		if h.gitService != nil {
@@ -1353,6 +1586,11 @@ func (h *PTYHandler) getOrCreateSession(sessionID, agent string, reset bool) *Se
 	// Start checkpoint monitoring for all sessions
 	go h.monitorCheckpoints(session)
 
+	// Pick up ports published by docker compose services in this workspace
+	if h.portMonitor != nil {
+		h.portMonitor.WatchComposeProject(session.WorkDir)
+	}
+
 	// Start session-level PTY reading to prevent Claude from being blocked
 	go h.readPTYContinuously(session)
 
@@ -1481,7 +1719,25 @@ func (h *PTYHandler) readPTYContinuously(session *Session) {
 
 		// PTY output alone doesn't indicate Claude activity - rely on hooks and JSONL activity instead
 
+		h.recordings.Output(session.ID, buf[:n])
+		h.trackAgentOutput(session)
+
+		// Notify registered output watches (e.g. "BUILD FAILED", "listening on")
+		if h.events != nil {
+			for _, match := range h.watches.Scan(session.ID, buf[:n]) {
+				logger.Debugf("👀 Watch %q matched in session %s", match.Watch.Pattern, session.ID)
+				h.events.EmitSessionWatchMatched(match)
+			}
+
+			// Bells and OSC 777 notifications from long-running commands
+			for _, signal := range h.attention.Scan(session.ID, buf[:n]) {
+				logger.Debugf("🔔 Attention %s in session %s", signal.Kind, session.ID)
+				h.events.EmitSessionAttention(signal)
+			}
+		}
+
 		var outputData []byte
+		var outputEnd int64 // Offset of the output's end in the buffer, if buffered
 
 		// Extract title from PTY data for Claude sessions
 		if session.Agent == "claude" {
@@ -1540,19 +1796,22 @@ func (h *PTYHandler) readPTYContinuously(session *Session) {
 			}
 
 			session.outputBuffer = append(session.outputBuffer, outputData...)
+			session.outputOffset += int64(len(outputData))
+			outputEnd = session.outputOffset
 			// Update buffered dimensions to current terminal size
 			session.bufferedCols = session.cols
 			session.bufferedRows = session.rows
 
 			session.bufferMutex.Unlock()
 		} else {
-			// Claude sessions: send raw data with no processing or buffering
+			// Claude sessions: send raw data, keeping only the emulated screen
 			outputData = buf[:n]
+			outputEnd = session.writeScreen(outputData)
 		}
 
 		// Send to connections based on type (SSE gets errors only, WebSocket gets all data)
 		if len(outputData) > 0 {
-			h.broadcastToConnectionsSelective(session, websocket.BinaryMessage, outputData)
+			h.broadcastToConnectionsSelective(session, websocket.BinaryMessage, outputData, outputEnd)
 		}
 	}
 }
@@ -1630,12 +1889,28 @@ func (h *PTYHandler) monitorSession(session *Session) {
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
+	// Last time the PTY's process tree was busy (running a foreground job or
+	// burning CPU), so long-running builds push back idle cleanup
+	var lastProcessActivity time.Time
+
 	for range ticker.C {
 		session.connMutex.RLock()
 		connectionCount := len(session.connections)
 		hasFocusedConnection := h.hasFocusedConnection(session)
 		session.connMutex.RUnlock()
 
+		if activity := h.inspectSessionProcesses(session); activity.Busy() {
+			lastProcessActivity = time.Now()
+			logger.Debugf("⚙️ Session %s process tree is busy (foreground job: %v, cpu: %.2f cores, %d processes), keeping PTY alive",
+				session.ID, activity.ForegroundJob, activity.CPUUsage, activity.Processes)
+			continue
+		}
+
+		idleSince := session.LastAccess
+		if lastProcessActivity.After(idleSince) {
+			idleSince = lastProcessActivity
+		}
+
 		// Use Claude activity-based cleanup logic for Claude sessions
 		if session.Agent == "claude" {
 			// NEVER cleanup Claude sessions that have focused connections
@@ -1686,7 +1961,7 @@ func (h *PTYHandler) monitorSession(session *Session) {
 				// active processes when users switch between views.
 
 				// Only cleanup if no connections AND it's been inactive for a very long time
-				if connectionCount == 0 && time.Since(session.LastAccess) > 10*time.Minute {
+				if connectionCount == 0 && time.Since(idleSince) > 10*time.Minute {
 					logger.Infof("🧹 Claude session running with no connections for >10min, cleaning up PTY session: %s", session.ID)
 					h.cleanupSession(session)
 					return
@@ -1696,7 +1971,7 @@ func (h *PTYHandler) monitorSession(session *Session) {
 			}
 		} else {
 			// For non-Claude sessions, use the old logic (cleanup after 10 minutes with no connections)
-			if connectionCount == 0 && time.Since(session.LastAccess) > 10*time.Minute {
+			if connectionCount == 0 && time.Since(idleSince) > 10*time.Minute {
 				h.cleanupSession(session)
 				return
 			}
@@ -1704,6 +1979,14 @@ func (h *PTYHandler) monitorSession(session *Session) {
 	}
 }
 
+// inspectSessionProcesses samples the process tree of the session's PTY command
+func (h *PTYHandler) inspectSessionProcesses(session *Session) services.ProcessActivity {
+	if h.procInspector == nil || session.Cmd == nil || session.Cmd.Process == nil {
+		return services.ProcessActivity{}
+	}
+	return h.procInspector.Inspect(session.Cmd.Process.Pid)
+}
+
 func (h *PTYHandler) monitorCheckpoints(session *Session) {
 	logger.Debugf("🔍 Starting checkpoint monitoring for session %s", session.ID)
 	// Monitor session for checkpoint opportunities
@@ -1745,36 +2028,16 @@ func (h *PTYHandler) createCommand(sessionID, agent, workDir, resumeSessionID st
 		portEnvVars = []string{} // fallback to empty
 	}
 
-	switch agent {
-	case "claude":
-		// Build Claude command with optional continue or resume flag
-		args := []string{"--dangerously-skip-permissions"}
-
-		// Note: External workspace read-only mode is handled at the WebSocket/PTY level,
-		// not via Claude command flags. See Session.IsReadOnlyWorkspace field.
-
-		if useContinue {
-			args = append(args, "--continue")
-			logger.Infof("🔄 Starting Claude Code with --continue for session: %s", sessionID)
-		} else if resumeSessionID != "" {
-			args = append(args, "--resume", resumeSessionID)
-			logger.Infof("🔄 Starting Claude Code with resume for session: %s (resuming: %s)", sessionID, resumeSessionID)
-		} else {
-			logger.Debugf("🤖 Starting new Claude Code session: %s", sessionID)
-		}
-
-		// Find claude executable using robust path lookup
-		claudePath := h.findClaudeExecutable()
-		cmd = exec.Command(claudePath, args...)
-		cmd.Env = append(os.Environ(),
-			fmt.Sprintf("SESSION_ID=%s", sessionID),
-			"HOME="+config.Runtime.HomeDir,
-			"TERM=xterm-direct",
-			"COLORTERM=truecolor",
-		)
-		// Add port environment variables
-		cmd.Env = append(cmd.Env, portEnvVars...)
-	case "setup":
+	registered, isAgent := h.agents.Lookup(agent, workDir)
+	switch {
+	case isAgent:
+		cmd = h.agentCommand(registered, services.AgentLaunch{
+			SessionID: sessionID,
+			WorkDir:   workDir,
+			Resume:    useContinue || resumeSessionID != "",
+			ResumeID:  resumeSessionID,
+		}, portEnvVars)
+	case agent == "setup":
 		// For setup sessions, run bash that cats the setup log file
 		// Replace slashes in sessionID with underscores for valid filename
 		safeSessionID := strings.ReplaceAll(sessionID, "/", "_")
@@ -1795,6 +2058,8 @@ func (h *PTYHandler) createCommand(sessionID, agent, workDir, resumeSessionID st
 			"HOME="+config.Runtime.HomeDir,
 			"TERM=xterm-direct",
 			"COLORTERM=truecolor",
+			// Picked up by the claude wrapper when Claude is started from the shell
+			services.AppendSystemPromptEnv+"="+h.systemPrompt(workDir, true, false),
 		)
 		// Add port environment variables
 		cmd.Env = append(cmd.Env, portEnvVars...)
@@ -1802,6 +2067,18 @@ func (h *PTYHandler) createCommand(sessionID, agent, workDir, resumeSessionID st
 	}
 	if cmd != nil {
 		cmd.Dir = workDir
+		if agent != "setup" {
+			// Later entries win, so this overrides any inherited SSH_AUTH_SOCK
+			cmd.Env = append(cmd.Env, h.sshAgentEnv(workDir)...)
+			// Wrap commands with offload rules so matching runs go to remote runners
+			cmd.Env = append(cmd.Env, services.OffloadShellEnv(workDir)...)
+			if h.composites != nil {
+				cmd.Env = append(cmd.Env, h.composites.EnvForPath(workDir)...)
+			}
+			if h.kubernetes != nil {
+				cmd = h.runInKubernetes(cmd, sessionID, workDir, ports)
+			}
+		}
 	}
 	return cmd
 }
@@ -1991,11 +2268,9 @@ func (h *PTYHandler) recreateSession(session *Session) {
 		session.PTY.Close()
 	}
 
-	// Terminate old process
-	if session.Cmd != nil && session.Cmd.Process != nil {
-		_ = session.Cmd.Process.Kill()
-		_ = session.Cmd.Wait()
-	}
+	// Terminate the old process tree in the background; its grace period
+	// shouldn't hold up the new shell
+	go h.terminateSessionProcess(session.ID, session.Cmd)
 
 	// Close all WebSocket connections to force frontend reconnection and terminal clear
 	// First, collect connections while holding lock, then send messages without lock
@@ -2029,10 +2304,11 @@ func (h *PTYHandler) recreateSession(session *Session) {
 
 		// Now close the connections and clear map
 		session.connMutex.Lock()
-		for conn := range session.connections {
+		for conn, connInfo := range session.connections {
 			if err := conn.Close(); err != nil {
 				logger.Warnf("❌ Error closing WebSocket connection during recreation: %v", err)
 			}
+			h.logConnectionEvent(session, connInfo, services.PTYConnectionForceClose, services.PTYReasonSessionRecreated)
 		}
 		// Clear the connections map
 		session.connections = make(map[PTYConnection]*ConnectionInfo)
@@ -2057,7 +2333,7 @@ func (h *PTYHandler) recreateSession(session *Session) {
 	// Create new command using the same agent (use --resume for Claude recreations to preserve history)
 	resumeSessionID = ""
 	useContinue := false
-	if session.Agent == "claude" {
+	if session.Agent == "claude" && session.instance() == 1 {
 		// Try to find existing session with valid content (not Warmup sessions)
 		if existingState, err := h.sessionService.FindSessionByDirectory(session.WorkDir); err == nil && existingState != nil {
 			// Use --resume with specific session ID to preserve conversation history
@@ -2096,10 +2372,13 @@ func (h *PTYHandler) recreateSession(session *Session) {
 	session.ptyReadMutex.Unlock()
 
 	// Clear the output buffer on shell restart - no history between restarts
-	// (only for non-Claude sessions since Claude sessions don't buffer)
+	// (Claude sessions keep an emulated screen instead, which starts blank)
 	session.bufferMutex.Lock()
 	if session.Agent != "claude" {
 		session.outputBuffer = make([]byte, 0)
+		session.bufferID = uuid.NewString()
+	} else {
+		session.screen = nil
 	}
 	// Reset alternate screen buffer detection state
 	session.AlternateScreenActive = false
@@ -2168,13 +2447,38 @@ func (h *PTYHandler) RestartClaudeSessions() {
 }
 
 func (h *PTYHandler) cleanupSession(session *Session) {
+	// Only the map is changed under sessionMutex; terminating the process
+	// tree can take seconds and mustn't block every other session
 	h.sessionMutex.Lock()
-	defer h.sessionMutex.Unlock()
+	if h.sessions[session.ID] != session {
+		h.sessionMutex.Unlock()
+		return
+	}
+	delete(h.sessions, session.ID)
+	h.sessionMutex.Unlock()
 
 	logger.Infof("🧹 Cleaning up idle session: %s", session.ID)
 
 	// Stop the continuous PTY reader
 	session.safeClosePTYReadDone()
+	h.watches.Forget(session.ID)
+	h.attention.Forget(session.ID)
+	h.annotations.Forget(session.ID)
+	if h.recordings.Recording(session.ID) {
+		if capture, err := h.recordings.Stop(session.ID); err != nil {
+			logger.Warnf("⚠️ Failed to save recording of session %s: %v", session.ID, err)
+		} else {
+			logger.Infof("📼 Saved capture %s of session %s", capture.ID, session.ID)
+		}
+	}
+	if h.workspaceExists(extractWorkspaceFromSessionID(session.ID)) {
+		h.saveScrollback(session)
+	} else {
+		h.scrollback.Forget(session.ID)
+		if h.kubernetes != nil {
+			go h.releaseKubernetesWorkspace(extractWorkspaceFromSessionID(session.ID))
+		}
+	}
 
 	// Perform final git add to catch any uncommitted changes before cleanup
 	if h.gitService != nil {
@@ -2185,7 +2489,7 @@ func (h *PTYHandler) cleanupSession(session *Session) {
 	}
 
 	// End session tracking if it's a claude session
-	if session.Agent == "claude" {
+	if session.Agent == "claude" && session.instance() == 1 {
 		if err := h.sessionService.EndActiveSession(session.WorkDir); err != nil {
 			logger.Infof("⚠️  Failed to end session tracking for %s: %v", session.WorkDir, err)
 		}
@@ -2196,21 +2500,21 @@ func (h *PTYHandler) cleanupSession(session *Session) {
 		session.PTY.Close()
 	}
 
-	// Terminate process
-	if session.Cmd != nil && session.Cmd.Process != nil {
-		_ = session.Cmd.Process.Kill()
-		_ = session.Cmd.Wait()
-	}
+	// Terminate the process and everything it started (dev servers etc.)
+	h.terminateSessionProcess(session.ID, session.Cmd)
 
-	// Release ports for this session
+	// Release ports for this session, unless a new session with the same ID
+	// has taken them over in the meantime
+	h.sessionMutex.Lock()
+	defer h.sessionMutex.Unlock()
+	if _, replaced := h.sessions[session.ID]; replaced {
+		return
+	}
 	if err := h.portService.ReleasePortsForSession(session.ID); err != nil {
 		logger.Infof("⚠️  Failed to release ports for session %s: %v", session.ID, err)
 	} else {
 		logger.Infof("🔗 Released ports for session: %s", session.ID)
 	}
-
-	// Remove from sessions map
-	delete(h.sessions, session.ID)
 }
 
 // NOTE: cleanupStaleConnections function was removed and replaced with nuclear approach
@@ -2275,13 +2579,17 @@ func (s *Session) writeToConnection(conn PTYConnection, data []byte) error {
 
 	// Check if connection is still in our connections map
 	s.connMutex.RLock()
-	_, exists := s.connections[conn]
+	info, exists := s.connections[conn]
 	s.connMutex.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("connection no longer exists")
 	}
 
+	if info.Accessible != nil {
+		return writeAccessibleEvents(conn, info.Accessible.Transform(data))
+	}
+
 	// Send all data to connections - the frontend will handle JSON message processing
 	return conn.WriteMessage(data)
 }
@@ -2475,7 +2783,7 @@ func (h *PTYHandler) commitPreviousWork(session *Session, previousTitle string)
 		return
 	}
 
-	commitHash, err := h.gitService.GitAddCommitGetHash(session.WorkDir, previousTitle)
+	commitHash, err := h.gitService.CommitAgentWork(session.WorkDir, previousTitle, models.AgentCommitTitle)
 	if err != nil {
 		logger.Infof("⚠️  Git operations failed for previous title '%s': %v", previousTitle, err)
 		return
@@ -2512,11 +2820,21 @@ func (h *PTYHandler) ExecuteSetupScript(worktreePath string) {
 	h.ptyService.ExecuteSetupScript(worktreePath)
 }
 
+// RerunSetupScript implements the services.SetupRerunner interface
+func (h *PTYHandler) RerunSetupScript(worktreePath string) error {
+	return h.ptyService.RerunSetupScript(worktreePath)
+}
+
 // GetPTYService returns the PTY service for external access
 func (h *PTYHandler) GetPTYService() *services.PTYService {
 	return h.ptyService
 }
 
+// GetRecordings returns the terminal recordings for external access
+func (h *PTYHandler) GetRecordings() *services.PTYRecorder {
+	return h.recordings
+}
+
 // promoteConnection promotes a read-only connection to write access and demotes the current write connection
 func (h *PTYHandler) promoteConnection(session *Session, requestingConn PTYConnection) {
 	session.connMutex.Lock()
@@ -2555,6 +2873,7 @@ func (h *PTYHandler) promoteConnection(session *Session, requestingConn PTYConne
 	if currentWriteConn != nil && currentWriteConnInfo != nil {
 		currentWriteConnInfo.IsReadOnly = true
 		logger.Infof("🔒 Demoted connection [%s] to read-only mode", currentWriteConnInfo.ConnID)
+		h.logConnectionEvent(session, currentWriteConnInfo, services.PTYConnectionDemote, services.PTYReasonPromoteRequest)
 
 		// Notify the demoted connection
 		readOnlyMsg := struct {
@@ -2572,6 +2891,7 @@ func (h *PTYHandler) promoteConnection(session *Session, requestingConn PTYConne
 	// Promote the requesting connection to write access
 	requestingConnInfo.IsReadOnly = false
 	logger.Infof("✍️ Promoted connection [%s] to write mode", requestingConnInfo.ConnID)
+	h.logConnectionEvent(session, requestingConnInfo, services.PTYConnectionPromote, services.PTYReasonPromoteRequest)
 
 	// Notify the promoted connection
 	writeAccessMsg := struct {
@@ -2634,6 +2954,11 @@ func (h *PTYHandler) handleFocusChange(session *Session, conn PTYConnection, foc
 	// Update focus state
 	connInfo.IsFocused = focused
 	connID := connInfo.ConnID
+	if focused {
+		h.logConnectionEvent(session, connInfo, services.PTYConnectionFocus, "")
+	} else {
+		h.logConnectionEvent(session, connInfo, services.PTYConnectionBlur, "")
+	}
 
 	if focused {
 		logger.Infof("🎯 Connection [%s] gained focus in session %s", connID, session.ID)
@@ -2658,6 +2983,7 @@ func (h *PTYHandler) handleFocusChange(session *Session, conn PTYConnection, foc
 			if currentWriteConn != nil && currentWriteConnInfo != nil {
 				currentWriteConnInfo.IsReadOnly = true
 				logger.Infof("🔒 Auto-demoted connection [%s] to read-only (focus lost)", currentWriteConnInfo.ConnID)
+				h.logConnectionEvent(session, currentWriteConnInfo, services.PTYConnectionDemote, services.PTYReasonFocus)
 
 				// Notify the demoted connection
 				readOnlyMsg := struct {
@@ -2675,6 +3001,7 @@ func (h *PTYHandler) handleFocusChange(session *Session, conn PTYConnection, foc
 			// Promote the focused connection
 			connInfo.IsReadOnly = false
 			logger.Infof("✍️ Auto-promoted focused connection [%s] to write mode", connID)
+			h.logConnectionEvent(session, connInfo, services.PTYConnectionPromote, services.PTYReasonFocus)
 
 			// Notify the promoted connection
 			writeAccessMsg := struct {
@@ -2793,8 +3120,10 @@ func (h *PTYHandler) extractRecentErrors(filePath string) []string {
 	return errors
 }
 
-// broadcastToConnectionsSelective sends data to connections based on connection type
-func (h *PTYHandler) broadcastToConnectionsSelective(session *Session, messageType int, data []byte) {
+// broadcastToConnectionsSelective sends data to connections based on connection type.
+// outputEnd is the buffer offset data ends at, or 0 for output that isn't buffered;
+// connections already replayed past part of it only get the rest.
+func (h *PTYHandler) broadcastToConnectionsSelective(session *Session, messageType int, data []byte, outputEnd int64) {
 	session.writeMutex.Lock()
 	defer session.writeMutex.Unlock()
 
@@ -2857,6 +3186,26 @@ func (h *PTYHandler) broadcastToConnectionsSelective(session *Session, messageTy
 			// For WebSocket connections, send all data as before
 			shouldSend = true
 			dataToSend = data
+			if outputEnd > 0 && connInfo.Offset > 0 {
+				// Skip what the connection's buffer replay already covered
+				skip := connInfo.Offset - (outputEnd - int64(len(data)))
+				if skip >= int64(len(data)) {
+					continue
+				}
+				if skip > 0 {
+					dataToSend = data[skip:]
+				}
+				connInfo.Offset = outputEnd
+			}
+		}
+
+		// Accessible connections get announcements instead of terminal bytes
+		if shouldSend && conn.Type() != "sse" && connInfo.Accessible != nil {
+			if err := writeAccessibleEvents(conn, connInfo.Accessible.Transform(dataToSend)); err != nil {
+				logger.Warnf("❌ Connection write error for [%s] (%s) in session %s: %v", connInfo.ConnID, conn.Type(), session.ID, err)
+				disconnectedConns = append(disconnectedConns, conn)
+			}
+			continue
 		}
 
 		if shouldSend {
@@ -2881,8 +3230,12 @@ func (h *PTYHandler) broadcastToConnectionsSelective(session *Session, messageTy
 		session.connMutex.RUnlock()
 		session.connMutex.Lock()
 		for _, conn := range disconnectedConns {
+			connInfo, exists := session.connections[conn]
 			delete(session.connections, conn)
 			conn.Close()
+			if exists {
+				h.logConnectionEvent(session, connInfo, services.PTYConnectionForceClose, services.PTYReasonWriteError)
+			}
 		}
 		session.connMutex.Unlock()
 		session.connMutex.RLock()
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// setOutputMode switches a connection between raw terminal output and the
+// screen-reader stream, and confirms the mode to the client with a
+// {"type":"mode","data":...} message
+func (h *PTYHandler) setOutputMode(session *Session, conn PTYConnection, mode string) {
+	if conn.Type() == "sse" {
+		return
+	}
+	if mode != services.PTYOutputAccessible {
+		mode = services.PTYOutputRaw
+	}
+
+	session.connMutex.Lock()
+	info, exists := session.connections[conn]
+	var flushed []services.AccessibleEvent
+	if exists {
+		switch {
+		case mode == services.PTYOutputAccessible && info.Accessible == nil:
+			info.Accessible = services.NewAccessibleTransformer()
+		case mode == services.PTYOutputRaw && info.Accessible != nil:
+			flushed = info.Accessible.Flush()
+			info.Accessible = nil
+		}
+	}
+	session.connMutex.Unlock()
+	if !exists {
+		return
+	}
+
+	logger.Debugf("♿ Connection [%s] in session %s switched to %s output", info.ConnID, session.ID, mode)
+
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+	_ = writeAccessibleEvents(conn, flushed)
+	if data, err := json.Marshal(struct {
+		Type string `json:"type"`
+		Data string `json:"data"`
+	}{Type: "mode", Data: mode}); err == nil {
+		_ = conn.WriteJSONMessage(data)
+	}
+}
+
+// writeAccessibleEvents sends each announcement as a JSON text message. The
+// caller holds the session's write mutex.
+func writeAccessibleEvents(conn PTYConnection, events []services.AccessibleEvent) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		if err := conn.WriteJSONMessage(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// agentOutputInterval throttles how often output from agents without hooks
+// is recorded as activity
+const agentOutputInterval = time.Second
+
+// claudeAgent runs Claude Code. It resumes conversations by ID, takes the
+// layered system prompt and reports its activity through catnip's hooks.
+type claudeAgent struct {
+	h *PTYHandler
+}
+
+// Name implements services.Agent
+func (a claudeAgent) Name() string {
+	return "claude"
+}
+
+// Executable implements services.Agent, preferring catnip's wrapper, which
+// intercepts title changes
+func (a claudeAgent) Executable() (string, error) {
+	path := a.h.findClaudeExecutable()
+	if _, err := exec.LookPath(path); err != nil {
+		return "", fmt.Errorf("claude isn't installed (npm install -g @anthropic-ai/claude-code)")
+	}
+	return path, nil
+}
+
+// Args implements services.Agent
+func (a claudeAgent) Args(launch services.AgentLaunch) []string {
+	args := []string{"--dangerously-skip-permissions"}
+
+	// Note: External workspace read-only mode is handled at the WebSocket/PTY level,
+	// not via Claude command flags. See Session.IsReadOnlyWorkspace field.
+
+	if launch.Resume && launch.ResumeID == "" {
+		args = append(args, "--continue")
+		logger.Infof("🔄 Starting Claude Code with --continue for session: %s", launch.SessionID)
+	} else if launch.Resume {
+		args = append(args, "--resume", launch.ResumeID)
+		logger.Infof("🔄 Starting Claude Code with resume for session: %s (resuming: %s)", launch.SessionID, launch.ResumeID)
+	} else {
+		logger.Debugf("🤖 Starting new Claude Code session: %s", launch.SessionID)
+	}
+
+	if a.h.gitService != nil {
+		if disallowed := a.h.gitService.DisallowedClaudeTools(launch.WorkDir); len(disallowed) > 0 {
+			args = append(args, "--disallowedTools", strings.Join(disallowed, ","))
+			logger.Infof("🧰 Withholding Claude tools in %s: %s", launch.WorkDir, strings.Join(disallowed, ", "))
+		}
+	}
+
+	if launch.SystemPrompt != "" && a.h.findClaudeExecutable() != catnipClaudeWrapperPath {
+		// Only the wrapper reads the prompt from the environment
+		args = append(args, "--append-system-prompt", launch.SystemPrompt)
+	}
+	return args
+}
+
+// Env implements services.Agent
+func (a claudeAgent) Env(launch services.AgentLaunch) []string {
+	return []string{
+		// Hooks post back to the server, which may be mounted under a path prefix
+		"CATNIP_BASE_PATH=" + config.Runtime.BasePath,
+		services.AppendSystemPromptEnv + "=" + launch.SystemPrompt,
+	}
+}
+
+// CanResume implements services.Agent
+func (a claudeAgent) CanResume() bool {
+	return true
+}
+
+// Activity implements services.Agent
+func (a claudeAgent) Activity() services.AgentActivity {
+	return services.AgentActivityHooks
+}
+
+// agentCommand builds the command running an agent in a session
+func (h *PTYHandler) agentCommand(agent services.Agent, launch services.AgentLaunch, portEnvVars []string) *exec.Cmd {
+	// Layer the org, repo and (for fresh sessions) linked issue, memory and branch summary prompts
+	launch.SystemPrompt = h.systemPrompt(launch.WorkDir, !launch.Resume, !launch.Resume)
+
+	path, err := agent.Executable()
+	if err != nil && h.kubernetes != nil {
+		// The agent runs in the workspace's pod, which has its own PATH
+		path, err = agent.Name(), nil
+	}
+	if err != nil {
+		// Let the session show why the agent didn't start
+		logger.Warnf("⚠️ Can't start %s for session %s: %v", agent.Name(), launch.SessionID, err)
+		return exec.Command("bash", "-c", fmt.Sprintf("echo %s; exit 127", shellQuote(err.Error())))
+	}
+
+	cmd := exec.Command(path, agent.Args(launch)...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("SESSION_ID=%s", launch.SessionID),
+		"HOME="+config.Runtime.HomeDir,
+		"TERM=xterm-direct",
+		"COLORTERM=truecolor",
+	)
+	cmd.Env = append(cmd.Env, agent.Env(launch)...)
+	cmd.Env = append(cmd.Env, portEnvVars...)
+	if agent.Name() != "claude" {
+		logger.Infof("🤖 Starting %s for session: %s (resume: %t)", agent.Name(), launch.SessionID, launch.Resume)
+	}
+	return cmd
+}
+
+// shellQuote single-quotes s for bash
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// resumesAgentSession reports whether a new session of an agent other than
+// Claude should pick up its previous conversation: the same session ran the
+// agent in the same directory before (Claude's conversations are found by ID)
+func (h *PTYHandler) resumesAgentSession(agent services.Agent, sessionID, workDir string) bool {
+	if !agent.CanResume() || h.sessionService == nil {
+		return false
+	}
+	state, err := h.sessionService.LoadSessionState(sessionID)
+	return err == nil && state != nil && state.Agent == agent.Name() && state.WorkingDirectory == workDir
+}
+
+// trackAgentOutput marks an agent session ready on its first output and
+// records output from agents without hooks as worktree activity
+func (h *PTYHandler) trackAgentOutput(session *Session) {
+	agent, ok := h.agents.Get(session.Agent)
+	if !ok || agent.Activity() != services.AgentActivityOutput {
+		return
+	}
+
+	session.readyMutex.Lock()
+	if !session.IsReady {
+		session.IsReady = true
+		session.readyAt = time.Now()
+	}
+	session.readyMutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(session.lastAgentOutput) < agentOutputInterval {
+		return
+	}
+	session.lastAgentOutput = now
+	if h.claudeMonitor != nil {
+		h.claudeMonitor.GetClaudeService().RecordAgentOutput(session.WorkDir)
+	}
+}
+
+// AgentInfo describes an agent sessions can run
+type AgentInfo struct {
+	Name string `json:"name" example:"aider"`
+	// Path of the executable, when it's installed
+	Executable string `json:"executable,omitempty" example:"/usr/local/bin/aider"`
+	Installed  bool   `json:"installed" example:"true"`
+	// Why it can't start, when it isn't installed
+	Error     string                 `json:"error,omitempty" example:"aider isn't installed (pip install aider-chat)"`
+	CanResume bool                   `json:"can_resume" example:"true"`
+	Activity  services.AgentActivity `json:"activity" example:"output"`
+}
+
+// HandleListAgents lists the agents sessions can run
+// @Summary List agents
+// @Description Lists the coding agents sessions can run with the agent query parameter of /v1/pty, and whether each is installed. Agents other than Claude are configured, and new ones added, under agents in the repository's .catnip.yaml. Omitting agent opens a bash shell.
+// @Tags pty
+// @Produce json
+// @Param workspace query string false "Workspace whose .catnip.yaml agents are included"
+// @Success 200 {array} AgentInfo
+// @Router /v1/pty/agents [get]
+func (h *PTYHandler) HandleListAgents(c *fiber.Ctx) error {
+	workDir := ""
+	if workspace := c.Query("workspace"); workspace != "" {
+		if worktree := h.findWorktreeByName(workspace); worktree != nil {
+			workDir = worktree.Path
+		}
+	}
+
+	names := h.agents.Names()
+	if workDir != "" {
+		if cfg, err := services.LoadCatnipConfig(workDir); err == nil {
+			for name := range cfg.Agents {
+				if _, registered := h.agents.Get(name); !registered {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	agents := make([]AgentInfo, 0, len(names))
+	for _, name := range names {
+		agent, ok := h.agents.Lookup(name, workDir)
+		if !ok {
+			continue
+		}
+		info := AgentInfo{Name: name, CanResume: agent.CanResume(), Activity: agent.Activity()}
+		if path, err := agent.Executable(); err != nil {
+			info.Error = err.Error()
+		} else {
+			info.Executable, info.Installed = path, true
+		}
+		agents = append(agents, info)
+	}
+	return c.JSON(agents)
+}
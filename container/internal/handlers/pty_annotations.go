@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// annotationMsg pushes a tool annotation to a session's terminals as it
+// starts and finishes
+type annotationMsg struct {
+	Type       string                      `json:"type"`
+	Annotation services.TerminalAnnotation `json:"annotation"`
+}
+
+// HandleListAnnotations lists the tool annotations of a PTY session
+// @Summary List PTY session tool annotations
+// @Description Returns the Claude tool invocations reported by PreToolUse and PostToolUse hooks, each with the output offsets it spans (positions in the session's output stream, as reported by buffer-complete), tool name, duration and success. Connected terminals also receive them live as {"type":"annotation"} messages.
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {array} services.TerminalAnnotation
+// @Router /v1/pty/annotations [get]
+func (h *PTYHandler) HandleListAnnotations(c *fiber.Ctx) error {
+	return c.JSON(h.annotations.List(sessionKeyFromQuery(c)))
+}
+
+// AnnotateToolUse marks the output of the Claude sessions running in a hook
+// event's working directory with the tool that produced it. PreToolUse opens
+// an annotation and PostToolUse closes it; other events are ignored.
+func (h *PTYHandler) AnnotateToolUse(event *models.ClaudeHookEvent) {
+	if event.EventType != "PreToolUse" && event.EventType != "PostToolUse" {
+		return
+	}
+	toolName, _ := event.Data["tool_name"].(string)
+	if toolName == "" {
+		return
+	}
+	toolUseID, _ := event.Data["tool_use_id"].(string)
+
+	for _, session := range h.claudeSessionsIn(event.WorkingDirectory, event.SessionID) {
+		session.bufferMutex.RLock()
+		bufferID, offset := session.bufferID, session.outputOffset
+		session.bufferMutex.RUnlock()
+
+		var annotation services.TerminalAnnotation
+		if event.EventType == "PreToolUse" {
+			annotation = h.annotations.Begin(session.ID, toolUseID, toolName, bufferID, offset)
+		} else {
+			recordingTime := h.recordings.Marker(session.ID, toolName)
+			annotation = h.annotations.End(session.ID, toolUseID, toolName, bufferID, offset, toolSucceeded(event.Data["tool_response"]), recordingTime)
+			logger.Debugf("🏷️ %s ran %dms in session %s (output %d-%d)", toolName, annotation.DurationMs, session.ID, annotation.StartOffset, annotation.EndOffset)
+		}
+		h.broadcastAnnotation(session, annotation)
+	}
+}
+
+// claudeSessionsIn returns the Claude sessions whose workspace contains dir,
+// preferring the one running claudeSessionID if it is known
+func (h *PTYHandler) claudeSessionsIn(dir, claudeSessionID string) []*Session {
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+
+	dir = filepath.Clean(dir)
+	var matches []*Session
+	longest := -1
+	for _, session := range h.sessions {
+		if session.Agent != "claude" || session.WorkDir == "" {
+			continue
+		}
+		workDir := filepath.Clean(session.WorkDir)
+		if dir != workDir && !strings.HasPrefix(dir, workDir+string(filepath.Separator)) {
+			continue
+		}
+		switch {
+		case len(workDir) > longest:
+			matches, longest = []*Session{session}, len(workDir)
+		case len(workDir) == longest:
+			matches = append(matches, session)
+		}
+	}
+
+	if claudeSessionID != "" && len(matches) > 1 {
+		for _, session := range matches {
+			if session.ClaudeSessionID == claudeSessionID {
+				return []*Session{session}
+			}
+		}
+	}
+	return matches
+}
+
+func (h *PTYHandler) broadcastAnnotation(session *Session, annotation services.TerminalAnnotation) {
+	data, err := json.Marshal(annotationMsg{Type: "annotation", Annotation: annotation})
+	if err != nil {
+		return
+	}
+
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+	session.connMutex.RLock()
+	defer session.connMutex.RUnlock()
+	for conn, info := range session.connections {
+		if conn.Type() != "websocket" || info.Accessible != nil {
+			continue
+		}
+		_ = conn.WriteJSONMessage(data)
+	}
+}
+
+// toolSucceeded reads the outcome from a PostToolUse tool_response. Tools
+// report failure differently, so nil is returned when it can't be told.
+func toolSucceeded(response interface{}) *bool {
+	result := func(ok bool) *bool { return &ok }
+	switch value := response.(type) {
+	case map[string]interface{}:
+		if success, ok := value["success"].(bool); ok {
+			return result(success)
+		}
+		for _, key := range []string{"is_error", "isError", "interrupted"} {
+			if failed, ok := value[key].(bool); ok && failed {
+				return result(false)
+			}
+		}
+		if errText, ok := value["error"].(string); ok && errText != "" {
+			return result(false)
+		}
+		return result(true)
+	case string:
+		return result(!strings.HasPrefix(strings.TrimSpace(value), "Error"))
+	case nil:
+		return nil
+	default:
+		logger.Debugf("🏷️ Unrecognized tool response type %T", value)
+		return nil
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+func TestAnnotateToolUse(t *testing.T) {
+	claude := &Session{ID: "app:claude", Agent: "claude", WorkDir: "/workspace/app", cols: 80, rows: 24, bufferID: "b1"}
+	shell := &Session{ID: "app", WorkDir: "/workspace/app", bufferID: "b2"}
+	other := &Session{ID: "web:claude", Agent: "claude", WorkDir: "/workspace/web", bufferID: "b3"}
+	h := &PTYHandler{
+		sessions:    map[string]*Session{claude.ID: claude, shell.ID: shell, other.ID: other},
+		recordings:  services.NewPTYRecorder(),
+		annotations: services.NewPTYAnnotationRegistry(),
+	}
+
+	claude.writeScreen([]byte("thinking...\r\n"))
+	start := claude.outputOffset
+	h.AnnotateToolUse(&models.ClaudeHookEvent{
+		EventType:        "PreToolUse",
+		WorkingDirectory: "/workspace/app/src",
+		Data:             map[string]interface{}{"tool_name": "Bash", "tool_use_id": "toolu_1"},
+	})
+	claude.writeScreen([]byte("$ go test ./...\r\nFAIL\r\n"))
+	h.AnnotateToolUse(&models.ClaudeHookEvent{
+		EventType:        "PostToolUse",
+		WorkingDirectory: "/workspace/app/src",
+		Data: map[string]interface{}{
+			"tool_name":     "Bash",
+			"tool_use_id":   "toolu_1",
+			"tool_response": map[string]interface{}{"interrupted": true},
+		},
+	})
+
+	annotations := h.annotations.List(claude.ID)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, "Bash", annotations[0].ToolName)
+	assert.Equal(t, "b1", annotations[0].BufferID)
+	assert.Equal(t, start, annotations[0].StartOffset)
+	assert.Equal(t, claude.outputOffset, annotations[0].EndOffset)
+	require.NotNil(t, annotations[0].Success)
+	assert.False(t, *annotations[0].Success)
+
+	assert.Empty(t, h.annotations.List(shell.ID), "only Claude sessions are annotated")
+	assert.Empty(t, h.annotations.List(other.ID), "only the hook's workspace is annotated")
+}
+
+func TestToolSucceeded(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{}
+		want     *bool
+	}{
+		{"explicit success", map[string]interface{}{"success": true}, boolPtr(true)},
+		{"explicit failure", map[string]interface{}{"success": false}, boolPtr(false)},
+		{"error flag", map[string]interface{}{"is_error": true}, boolPtr(false)},
+		{"interrupted", map[string]interface{}{"interrupted": true}, boolPtr(false)},
+		{"error text", map[string]interface{}{"error": "file not found"}, boolPtr(false)},
+		{"plain output", map[string]interface{}{"stdout": "ok", "interrupted": false}, boolPtr(true)},
+		{"error string", "Error: no such file", boolPtr(false)},
+		{"unknown", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, toolSucceeded(tt.response))
+		})
+	}
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// Replay speed limits
+const (
+	minReplaySpeed = 0.1
+	maxReplaySpeed = 20.0
+)
+
+// HandleListCaptures lists PTY session captures
+// @Summary List PTY session captures
+// @Description Returns running recordings and saved captures, newest first. Without a session query every session's captures are listed.
+// @Tags pty
+// @Produce json
+// @Param session query string false "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {array} services.PTYCapture
+// @Router /v1/pty/captures [get]
+func (h *PTYHandler) HandleListCaptures(c *fiber.Ctx) error {
+	sessionID := ""
+	if c.Query("session") != "" {
+		sessionID = sessionKeyFromQuery(c)
+	}
+	return c.JSON(h.recordings.List(sessionID))
+}
+
+// HandleGetCapture downloads a saved capture
+// @Summary Download PTY session capture
+// @Description Returns the capture in the CaptureMetadata JSON format (loadable by capture-pty and the Swift MockPTYDataSource), or as an asciicast v2 file with format=asciicast, where prompt boundaries become markers.
+// @Tags pty
+// @Produce json
+// @Produce application/x-asciicast
+// @Param id path string true "Capture ID"
+// @Param format query string false "json (default) or asciicast"
+// @Success 200 {object} services.CaptureMetadata
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/captures/{id} [get]
+func (h *PTYHandler) HandleGetCapture(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "asciicast" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Unknown format %q (expected json or asciicast)", format),
+		})
+	}
+	info, capture, err := h.recordings.Get(c.Params("id"))
+	if err != nil {
+		return captureError(c, err)
+	}
+
+	name := strings.TrimSuffix(recordingFilename(info.SessionID), ".cast") + "-" + info.ID[:8]
+	if format == "json" {
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".json"))
+		return c.JSON(capture)
+	}
+
+	var buf bytes.Buffer
+	if err := capture.WriteAsciicast(&buf, captureHeader(info)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	c.Set("Content-Type", services.AsciicastContentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".cast"))
+	return c.Send(buf.Bytes())
+}
+
+// HandleReplayCapture replays a saved capture in real time
+// @Summary Replay PTY session capture
+// @Description Streams the capture as asciicast v2 with each event sent when it happened in the original session, scaled by speed, so a terminal or player renders it as it was recorded. Pauses longer than max_idle seconds are shortened. Clients sending Accept: text/event-stream receive one event per SSE message; others receive newline-delimited asciicast lines.
+// @Tags pty
+// @Produce application/x-asciicast
+// @Produce text/event-stream
+// @Param id path string true "Capture ID"
+// @Param speed query number false "Playback speed multiplier (0.1-20, default 1)"
+// @Param max_idle query number false "Longest pause in seconds (default unlimited)"
+// @Success 200 {string} string "asciicast v2 stream"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/captures/{id}/replay [get]
+func (h *PTYHandler) HandleReplayCapture(c *fiber.Ctx) error {
+	speed := c.QueryFloat("speed", 1)
+	if speed < minReplaySpeed || speed > maxReplaySpeed {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("speed must be between %g and %g", minReplaySpeed, maxReplaySpeed),
+		})
+	}
+	maxIdle := c.QueryFloat("max_idle", 0)
+	if maxIdle < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "max_idle can't be negative",
+		})
+	}
+	info, capture, err := h.recordings.Get(c.Params("id"))
+	if err != nil {
+		return captureError(c, err)
+	}
+
+	header := captureHeader(info)
+	header.Version = 2
+	events := replayTimeline(capture.AsciicastEvents(), speed, maxIdle)
+	if len(events) > 0 {
+		header.Duration = events[len(events)-1].Time
+	}
+
+	sse := strings.Contains(c.Get("Accept"), "text/event-stream")
+	if sse {
+		c.Set("Content-Type", "text/event-stream")
+	} else {
+		c.Set("Content-Type", services.AsciicastContentType)
+	}
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	logger.Debugf("📼 Replaying capture %s at %gx", info.ID, speed)
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		send := func(v interface{}) bool {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return true
+			}
+			if sse {
+				_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", b)
+			}
+			return err == nil && w.Flush() == nil
+		}
+
+		if !send(header) {
+			return
+		}
+		start := time.Now()
+		for _, event := range events {
+			if wait := time.Duration(event.Time*float64(time.Second)) - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+			if !send(event) {
+				return
+			}
+		}
+	}))
+	return nil
+}
+
+// HandleDeleteCapture deletes a saved capture
+// @Summary Delete PTY session capture
+// @Tags pty
+// @Produce json
+// @Param id path string true "Capture ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/captures/{id} [delete]
+func (h *PTYHandler) HandleDeleteCapture(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.recordings.Delete(id); err != nil {
+		return captureError(c, err)
+	}
+	logger.Infof("🗑️ Deleted capture %s", id)
+	return c.JSON(fiber.Map{
+		"status": "deleted",
+	})
+}
+
+// captureError responds to a failed capture lookup
+func captureError(c *fiber.Ctx, err error) error {
+	status := fiber.StatusInternalServerError
+	if errors.Is(err, services.ErrCaptureNotFound) {
+		status = fiber.StatusNotFound
+	}
+	return c.Status(status).JSON(fiber.Map{
+		"error": err.Error(),
+	})
+}
+
+// captureHeader is the asciicast header for a saved capture
+func captureHeader(info *services.PTYCapture) services.AsciicastHeader {
+	title := info.SessionID
+	if info.Label != "" {
+		title = info.Label
+	}
+	return services.AsciicastHeader{
+		Width:  info.Cols,
+		Height: info.Rows,
+		Title:  title,
+		Env:    map[string]string{"TERM": "xterm-direct"},
+	}
+}
+
+// replayTimeline rescales event times by speed and shortens pauses longer
+// than maxIdle seconds (0 keeps them)
+func replayTimeline(events []services.AsciicastEvent, speed, maxIdle float64) []services.AsciicastEvent {
+	replay := make([]services.AsciicastEvent, len(events))
+	var previous, elapsed float64
+	for i, event := range events {
+		gap := (event.Time - previous) / speed
+		if gap < 0 {
+			gap = 0
+		}
+		if maxIdle > 0 && gap > maxIdle {
+			gap = maxIdle
+		}
+		previous = event.Time
+		elapsed += gap
+		event.Time = elapsed
+		replay[i] = event
+	}
+	return replay
+}
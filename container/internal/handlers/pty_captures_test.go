@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+func TestReplayTimeline(t *testing.T) {
+	events := []services.AsciicastEvent{
+		{Time: 0.5, Type: services.AsciicastOutput, Data: "a"},
+		{Time: 1, Type: services.AsciicastOutput, Data: "b"},
+		{Time: 31, Type: services.AsciicastMarker, Data: "prompt"},
+		{Time: 32, Type: services.AsciicastOutput, Data: "c"},
+	}
+
+	times := func(events []services.AsciicastEvent) []float64 {
+		var out []float64
+		for _, event := range events {
+			out = append(out, event.Time)
+		}
+		return out
+	}
+	assert.Equal(t, []float64{0.5, 1, 31, 32}, times(replayTimeline(events, 1, 0)))
+	assert.Equal(t, []float64{0.25, 0.5, 15.5, 16}, times(replayTimeline(events, 2, 0)))
+	assert.Equal(t, []float64{0.5, 1, 3, 4}, times(replayTimeline(events, 1, 2)), "long pauses are shortened")
+	assert.Equal(t, "prompt", replayTimeline(events, 1, 2)[2].Data)
+	assert.Equal(t, 31.0, events[2].Time, "input is left alone")
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// errChaosDisconnect is returned by writes on a connection chaos mode closed
+var errChaosDisconnect = errors.New("connection closed by PTY chaos mode")
+
+// chaosConnection injects latency, dropped output frames and disconnects
+// into a PTY connection
+type chaosConnection struct {
+	PTYConnection
+	chaos       *services.PTYChaos
+	connectedAt time.Time
+}
+
+func (c *chaosConnection) WriteMessage(data []byte) error {
+	action := c.chaos.OutputFrame(c.connectedAt)
+	if action.Disconnect {
+		logger.Debugf("🐒 Chaos mode disconnecting %s", c.RemoteAddr())
+		_ = c.PTYConnection.Close()
+		return errChaosDisconnect
+	}
+	time.Sleep(action.Delay)
+	if action.Drop {
+		return nil
+	}
+	return c.PTYConnection.WriteMessage(data)
+}
+
+func (c *chaosConnection) WriteJSONMessage(data []byte) error {
+	time.Sleep(c.chaos.InputDelay())
+	return c.PTYConnection.WriteJSONMessage(data)
+}
+
+func (c *chaosConnection) ReadControlMessage() (*ControlMessage, error) {
+	msg, err := c.PTYConnection.ReadControlMessage()
+	if err == nil {
+		time.Sleep(c.chaos.InputDelay())
+	}
+	return msg, err
+}
+
+// WithChaos injects failures from the chaos tester into WebSocket PTY connections
+func (h *PTYHandler) WithChaos(chaos *services.PTYChaos) *PTYHandler {
+	h.chaos = chaos
+	return h
+}
+
+// wrapChaos wraps a connection for chaos testing when it's enabled. The
+// returned function must be called when the connection ends.
+func (h *PTYHandler) wrapChaos(conn PTYConnection) (PTYConnection, func()) {
+	if h.chaos == nil {
+		return conn, func() {}
+	}
+	untrack := h.chaos.Track(conn)
+	return &chaosConnection{PTYConnection: conn, chaos: h.chaos, connectedAt: time.Now()}, untrack
+}
+
+// HandleGetChaos returns the PTY chaos configuration
+// @Summary Get PTY chaos mode
+// @Description Returns the latency, frame drops and disconnects injected into PTY WebSocket connections, and how many have been injected. Only available when CATNIP_PTY_CHAOS=true.
+// @Tags debug
+// @Produce json
+// @Success 200 {object} services.PTYChaosStatus
+// @Router /debug/pty/chaos [get]
+func (h *PTYHandler) HandleGetChaos(c *fiber.Ctx) error {
+	return c.JSON(h.chaos.Status())
+}
+
+// HandleUpdateChaos changes the failures injected into PTY connections
+// @Summary Configure PTY chaos mode
+// @Description Sets the latency, jitter, output frame drop rate and forced disconnects injected into PTY WebSocket connections. Changes apply to open connections immediately; send an all-zero config to stop injecting.
+// @Tags debug
+// @Accept json
+// @Produce json
+// @Param config body services.PTYChaosConfig true "Failures to inject"
+// @Success 200 {object} services.PTYChaosStatus
+// @Failure 400 {object} map[string]string
+// @Router /debug/pty/chaos [put]
+func (h *PTYHandler) HandleUpdateChaos(c *fiber.Ctx) error {
+	var config services.PTYChaosConfig
+	if err := c.BodyParser(&config); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := h.chaos.Configure(config); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.Infof("🐒 PTY chaos mode configured: %+v", config)
+	return c.JSON(h.chaos.Status())
+}
+
+// HandleChaosDisconnect closes every PTY WebSocket connection now
+// @Summary Force PTY disconnects
+// @Description Closes every open PTY WebSocket connection so clients have to reconnect. PTY sessions keep running.
+// @Tags debug
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Router /debug/pty/chaos/disconnect [post]
+func (h *PTYHandler) HandleChaosDisconnect(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"disconnected": h.chaos.DisconnectAll(),
+	})
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// composerClientMessage is sent by composer clients: an op made against
+// Version, or a submit of the draft
+type composerClientMessage struct {
+	Type    string               `json:"type"`
+	Version int                  `json:"version"`
+	Op      *services.ComposerOp `json:"op,omitempty"`
+}
+
+// HandlePromptComposer joins a session's shared prompt draft over WebSocket
+// @Summary Compose a prompt together
+// @Description Shares a prompt draft between everyone connected for a PTY session, for pairing on a prompt before it goes to Claude. The first message is a snapshot with the draft's text and version and the client's client_id. Send {"type":"op","version":N,"op":{"pos":P,"delete":D,"insert":"text"}} to replace D characters (Unicode code points) at P in version N; the server transforms it past concurrent edits and broadcasts it to all participants as an op message with the new version, which the author takes as the acknowledgement. Clients transform incoming ops against their unacknowledged ones, letting the server's op win ties. Send {"type":"submit"} to type the draft into the session like a prompt control message and press Enter; participants get a submitted message and the draft is cleared. Participants messages list who is connected. A client whose edits fall too far behind gets an error with resync and should reconnect.
+// @Tags pty
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent of the session prompts are submitted to (default claude)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param name query string false "Name shown to other participants"
+// @Success 101 {string} string "Switching Protocols"
+// @Router /v1/pty/composer [get]
+func (h *PTYHandler) HandlePromptComposer(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	defaultSession := os.Getenv("CATNIP_SESSION")
+	if defaultSession == "" {
+		defaultSession = "default"
+	}
+	sessionID := sessionKey(c.Query("session", defaultSession), c.Query("agent", "claude"), queryInstance(c))
+	name := c.Query("name")
+	return websocket.New(func(conn *websocket.Conn) {
+		h.serveComposer(conn, sessionID, name)
+	})(c)
+}
+
+func (h *PTYHandler) serveComposer(conn *websocket.Conn, sessionID, name string) {
+	clientID, messages, leave := h.composer.Join(sessionID, name)
+	defer leave()
+	logger.Debugf("✍️ %s joined the prompt composer of %s", clientID, sessionID)
+
+	// Replies only for this client; all writes happen below
+	replies := make(chan services.ComposerMessage, 16)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var msg composerClientMessage
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if err := h.handleComposerMessage(sessionID, clientID, msg); err != nil {
+				reply := services.ComposerMessage{Type: services.ComposerError, Error: err.Error()}
+				_, reply.Version = h.composer.Snapshot(sessionID)
+				select {
+				case replies <- reply:
+				default:
+				}
+			}
+		}
+	}()
+	defer func() {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_ = conn.Close()
+		<-closed
+	}()
+
+	ping := time.NewTicker(statusSocketPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case msg, ok := <-messages:
+			if !ok {
+				// Dropped for falling behind
+				_ = conn.WriteJSON(services.ComposerMessage{Type: services.ComposerError, Error: "too far behind, resync"})
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case reply := <-replies:
+			if err := conn.WriteJSON(reply); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (h *PTYHandler) handleComposerMessage(sessionID, clientID string, msg composerClientMessage) error {
+	switch msg.Type {
+	case "op":
+		if msg.Op == nil {
+			return fmt.Errorf("op is required")
+		}
+		return h.composer.Apply(sessionID, clientID, msg.Version, *msg.Op)
+	case "submit":
+		h.sessionMutex.RLock()
+		session, exists := h.sessions[sessionID]
+		h.sessionMutex.RUnlock()
+		if !exists || session == nil || session.PTY == nil {
+			return fmt.Errorf("no terminal is open for %s", sessionID)
+		}
+		prompt, err := h.composer.Submit(sessionID, clientID, func(prompt string) error {
+			return injectPrompt(session, prompt, true)
+		})
+		if err != nil {
+			return err
+		}
+		logger.Infof("✍️ %s submitted a %d character prompt composed together to %s", clientID, len(prompt), sessionID)
+		return nil
+	default:
+		return fmt.Errorf("unknown message type %q", msg.Type)
+	}
+}
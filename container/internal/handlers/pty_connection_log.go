@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// PTYConnectionLogResponse is a session's connection events, or the counts
+// for every session when no session is given
+type PTYConnectionLogResponse struct {
+	Stats  services.PTYConnectionStats   `json:"stats"`
+	Events []services.PTYConnectionEvent `json:"events,omitempty"`
+}
+
+// logConnectionEvent records a lifecycle event of one of a session's
+// connections. The caller must hold the session's connMutex.
+func (h *PTYHandler) logConnectionEvent(session *Session, info *ConnectionInfo, eventType, reason string) {
+	h.connLog.Record(services.PTYConnectionEvent{
+		Session:     session.ID,
+		ConnID:      info.ConnID,
+		Type:        eventType,
+		Reason:      reason,
+		ConnType:    info.ConnType,
+		Remote:      info.RemoteAddr,
+		ReadOnly:    info.IsReadOnly,
+		Connections: len(session.connections),
+	})
+}
+
+// HandleGetConnectionLog returns the terminal connection lifecycle log
+// @Summary Get PTY connection lifecycle log
+// @Description Returns counts of terminal connection events (connect, disconnect, promote, demote, focus, blur, force_close) since startup, overall and per session, and how often a new connection force-closed a session's existing ones ("nuclear cleanup"), including how many of those closed a connection from another address (another tab or device). With a session, also returns that session's recent events, oldest first.
+// @Tags debug
+// @Produce json
+// @Param session query string false "Session ID (workspace name)"
+// @Param agent query string false "Agent of the session (e.g. claude)"
+// @Success 200 {object} PTYConnectionLogResponse
+// @Router /debug/pty/connections [get]
+func (h *PTYHandler) HandleGetConnectionLog(c *fiber.Ctx) error {
+	response := PTYConnectionLogResponse{Stats: h.connLog.Stats()}
+	if c.Query("session") != "" {
+		response.Events = h.connLog.Events(sessionKeyFromQuery(c))
+	}
+	return c.JSON(response)
+}
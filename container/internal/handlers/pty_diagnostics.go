@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// StalePortAllocations returns the sessions holding port allocations
+// without a terminal session, left behind by sessions that didn't clean up
+func (h *PTYHandler) StalePortAllocations() []string {
+	allocations := h.portService.ListAllAllocatedPorts()
+
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+	var stale []string
+	for sessionID := range allocations {
+		if _, exists := h.sessions[sessionID]; !exists {
+			stale = append(stale, sessionID)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// ReleasePorts releases a session's port allocation
+func (h *PTYHandler) ReleasePorts(sessionID string) error {
+	return h.portService.ReleasePortsForSession(sessionID)
+}
+
+// OpenCircuitBreakers returns the workspaces whose terminal recreation is
+// backing off after repeated failures
+func (h *PTYHandler) OpenCircuitBreakers() []services.PTYCircuitBreaker {
+	now := time.Now()
+	h.failureMutex.RLock()
+	defer h.failureMutex.RUnlock()
+	var breakers []services.PTYCircuitBreaker
+	for workspaceID, tracker := range h.failureTracker {
+		if now.Before(tracker.BackoffUntil) {
+			breakers = append(breakers, services.PTYCircuitBreaker{
+				Workspace:    workspaceID,
+				FailureCount: tracker.FailureCount,
+				BackoffUntil: tracker.BackoffUntil,
+			})
+		}
+	}
+	return breakers
+}
+
+// ResetCircuitBreaker forgets a workspace's recreation failures so its
+// terminal can be recreated right away
+func (h *PTYHandler) ResetCircuitBreaker(workspaceID string) {
+	h.failureMutex.Lock()
+	defer h.failureMutex.Unlock()
+	delete(h.failureTracker, workspaceID)
+	logger.Infof("🔌 Reset terminal circuit breaker for workspace %s", workspaceID)
+}
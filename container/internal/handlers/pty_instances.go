@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// AgentInstance describes one of the sessions running in a workspace
+type AgentInstance struct {
+	SessionID       string    `json:"session_id" example:"catnip/milo:claude:2"`
+	Agent           string    `json:"agent" example:"claude"`
+	Instance        int       `json:"instance" example:"2"`
+	Title           string    `json:"title,omitempty" example:"Fix login redirect"`
+	IsReady         bool      `json:"is_ready" example:"true"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastAccess      time.Time `json:"last_access"`
+	ConnectionCount int       `json:"connection_count" example:"1"`
+	PID             int       `json:"pid,omitempty" example:"4242"`
+}
+
+// sessionKey builds the composite session key: the workspace, then the
+// agent and, past the first instance, the instance number
+// (e.g. "catnip/milo:claude", "catnip/milo:claude:2")
+func sessionKey(workspace, agent string, instance int) string {
+	if instance > 1 {
+		return fmt.Sprintf("%s:%s:%d", workspace, agent, instance)
+	}
+	if agent != "" {
+		return workspace + ":" + agent
+	}
+	return workspace
+}
+
+// parseSessionKey splits a composite session key into its workspace, agent
+// and instance number (1 when the key has none)
+func parseSessionKey(key string) (workspace, agent string, instance int) {
+	parts := strings.SplitN(key, ":", 3)
+	workspace, instance = parts[0], 1
+	if len(parts) > 1 {
+		agent = parts[1]
+	}
+	if len(parts) > 2 {
+		if n, err := strconv.Atoi(parts[2]); err == nil && n > 1 {
+			instance = n
+		}
+	}
+	return workspace, agent, instance
+}
+
+// queryInstance reads the instance query parameter, defaulting to the first
+func queryInstance(c *fiber.Ctx) int {
+	if instance := c.QueryInt("instance", 1); instance > 1 {
+		return instance
+	}
+	return 1
+}
+
+// instance is the session's instance number among its agent's sessions in
+// the workspace
+func (s *Session) instance() int {
+	_, _, instance := parseSessionKey(s.ID)
+	return instance
+}
+
+// agentInstance describes a running session
+func agentInstance(session *Session) AgentInstance {
+	session.readyMutex.RLock()
+	isReady := session.IsReady
+	session.readyMutex.RUnlock()
+	session.connMutex.RLock()
+	connectionCount := len(session.connections)
+	session.connMutex.RUnlock()
+
+	info := AgentInstance{
+		SessionID:       session.ID,
+		Agent:           session.Agent,
+		Instance:        session.instance(),
+		Title:           session.Title,
+		IsReady:         isReady,
+		CreatedAt:       session.CreatedAt,
+		LastAccess:      session.LastAccess,
+		ConnectionCount: connectionCount,
+	}
+	if session.Cmd != nil && session.Cmd.Process != nil {
+		info.PID = session.Cmd.Process.Pid
+	}
+	return info
+}
+
+// workspaceInstances lists the sessions running in a workspace, by agent and
+// then instance
+func (h *PTYHandler) workspaceInstances(workspace string) []AgentInstance {
+	h.sessionMutex.RLock()
+	var sessions []*Session
+	for key, session := range h.sessions {
+		if ws, _, _ := parseSessionKey(key); ws == workspace {
+			sessions = append(sessions, session)
+		}
+	}
+	h.sessionMutex.RUnlock()
+
+	instances := make([]AgentInstance, 0, len(sessions))
+	for _, session := range sessions {
+		instances = append(instances, agentInstance(session))
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].Agent != instances[j].Agent {
+			return instances[i].Agent < instances[j].Agent
+		}
+		return instances[i].Instance < instances[j].Instance
+	})
+	return instances
+}
+
+// HandleListInstances lists the sessions running in a workspace
+// @Summary List agent instances
+// @Description Returns every PTY session running in the workspace: each agent's instances, shells and setup logs.
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Success 200 {array} AgentInstance
+// @Failure 400 {object} map[string]string
+// @Router /v1/pty/instances [get]
+func (h *PTYHandler) HandleListInstances(c *fiber.Ctx) error {
+	workspace := c.Query("session")
+	if workspace == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "session parameter is required",
+		})
+	}
+	return c.JSON(h.workspaceInstances(workspace))
+}
+
+// HandleCreateInstance starts another instance of an agent in a workspace
+// @Summary Start agent instance
+// @Description Starts a new session of the agent alongside any already running in the workspace, using the lowest free instance number. Instances past the first start fresh rather than resuming the workspace's Claude conversation. Connect to it with the instance query parameter of /v1/pty.
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent to start: claude (default), bash or another agent listed by /v1/pty/agents"
+// @Success 201 {object} AgentInstance
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} models.APIError
+// @Failure 500 {object} map[string]string
+// @Router /v1/pty/instances [post]
+func (h *PTYHandler) HandleCreateInstance(c *fiber.Ctx) error {
+	workspace := c.Query("session")
+	if workspace == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "session parameter is required",
+		})
+	}
+	agent := c.Query("agent", "claude")
+	if agent == "setup" || strings.ContainsAny(agent, ":/") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Can't start %q instances", agent),
+		})
+	}
+	workDir := ""
+	if worktree := h.findWorktreeByName(workspace); worktree != nil {
+		workDir = worktree.Path
+	}
+	if registered, ok := h.agents.Lookup(agent, workDir); ok {
+		if _, err := registered.Executable(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	// Held from picking the instance number until the session exists, so
+	// concurrent requests don't share one
+	h.instanceMutex.Lock()
+	defer h.instanceMutex.Unlock()
+
+	instance := 1
+	h.sessionMutex.RLock()
+	for {
+		if _, exists := h.sessions[h.sanitizeSessionID(sessionKey(workspace, agent, instance))]; !exists {
+			break
+		}
+		instance++
+	}
+	h.sessionMutex.RUnlock()
+
+	session := h.getOrCreateSession(sessionKey(workspace, agent, instance), agent, false)
+	if session == nil {
+		if !h.workspaceExists(workspace) && h.findWorktreeByName(workspace) == nil {
+			return c.Status(fiber.StatusNotFound).JSON(models.NewWorktreeNotFoundError(workspace))
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "Failed to create session",
+			"session": sessionKey(workspace, agent, instance),
+		})
+	}
+	logger.Infof("🤖 Started %s instance %d in %s", agent, instance, workspace)
+	return c.Status(fiber.StatusCreated).JSON(agentInstance(session))
+}
+
+// HandleDeleteInstance stops an agent instance
+// @Summary Stop agent instance
+// @Description Terminates the session's process and everything it started, and closes its connections.
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance (default 1)"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/instances [delete]
+func (h *PTYHandler) HandleDeleteInstance(c *fiber.Ctx) error {
+	sessionID := h.sanitizeSessionID(sessionKeyFromQuery(c))
+	h.sessionMutex.RLock()
+	session, exists := h.sessions[sessionID]
+	h.sessionMutex.RUnlock()
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Session not found",
+			"session": sessionID,
+		})
+	}
+
+	h.closeSessionConnections(session)
+	h.cleanupSession(session)
+	logger.Infof("🛑 Stopped session %s", sessionID)
+	return c.JSON(fiber.Map{
+		"status":     "stopped",
+		"session_id": sessionID,
+	})
+}
+
+// closeSessionConnections disconnects every client of a session
+func (h *PTYHandler) closeSessionConnections(session *Session) {
+	session.connMutex.Lock()
+	conns := make([]PTYConnection, 0, len(session.connections))
+	for conn := range session.connections {
+		conns = append(conns, conn)
+	}
+	session.connections = make(map[PTYConnection]*ConnectionInfo)
+	session.connMutex.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
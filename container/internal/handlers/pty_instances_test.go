@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionKey(t *testing.T) {
+	tests := []struct {
+		workspace string
+		agent     string
+		instance  int
+		key       string
+	}{
+		{"catnip/milo", "", 1, "catnip/milo"},
+		{"catnip/milo", "claude", 1, "catnip/milo:claude"},
+		{"catnip/milo", "claude", 0, "catnip/milo:claude"},
+		{"catnip/milo", "claude", 2, "catnip/milo:claude:2"},
+		{"catnip/milo", "", 3, "catnip/milo::3"},
+	}
+	for _, tt := range tests {
+		key := sessionKey(tt.workspace, tt.agent, tt.instance)
+		assert.Equal(t, tt.key, key)
+
+		workspace, agent, instance := parseSessionKey(key)
+		assert.Equal(t, tt.workspace, workspace, key)
+		assert.Equal(t, tt.agent, agent, key)
+		assert.Equal(t, max(tt.instance, 1), instance, key)
+	}
+
+	_, _, instance := parseSessionKey("catnip/milo:claude:bogus")
+	assert.Equal(t, 1, instance)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// WithKubernetes runs sessions in each workspace's Kubernetes pod instead of
+// the catnip container
+func (h *PTYHandler) WithKubernetes(runtime *services.KubernetesRuntime) *PTYHandler {
+	h.kubernetes = runtime
+	return h
+}
+
+// runInKubernetes wraps a session command in `catnip kube-exec`, which
+// attaches the session's PTY to the command running in the workspace's pod.
+// Only the environment the session adds is passed on; the pod keeps its own
+// PATH, HOME and the like.
+func (h *PTYHandler) runInKubernetes(cmd *exec.Cmd, sessionID, workDir string, ports *services.SessionPorts) *exec.Cmd {
+	self, err := os.Executable()
+	if err != nil {
+		logger.Warnf("⚠️ Can't find the catnip binary, running session %s locally: %v", sessionID, err)
+		return cmd
+	}
+
+	args := []string{"kube-exec", "--workspace", extractWorkspaceFromSessionID(sessionID), "--dir", workDir}
+	if ports != nil {
+		for _, port := range append([]int{ports.PORT}, ports.PORTZ...) {
+			if port > 0 {
+				args = append(args, "--port", strconv.Itoa(port))
+			}
+		}
+	}
+	for _, env := range sessionEnvAdditions(cmd.Env, os.Environ()) {
+		args = append(args, "--env", env)
+	}
+	args = append(args, "--")
+	args = append(args, cmd.Args...)
+
+	wrapped := exec.Command(self, args...)
+	wrapped.Env = append(os.Environ(), "TERM=xterm-direct")
+	wrapped.Dir = cmd.Dir
+	logger.Infof("☸️ Running session %s in the Kubernetes pod for %s", sessionID, extractWorkspaceFromSessionID(sessionID))
+	return wrapped
+}
+
+// sessionEnvAdditions returns the entries of env that aren't inherited from
+// base, i.e. what a session command sets on top of the server's environment
+func sessionEnvAdditions(env, base []string) []string {
+	inherited := make(map[string]bool, len(base))
+	for _, entry := range base {
+		inherited[entry] = true
+	}
+	var additions []string
+	for _, entry := range env {
+		if !inherited[entry] {
+			additions = append(additions, entry)
+		}
+	}
+	return additions
+}
+
+// releaseKubernetesWorkspace deletes the pod and service of a workspace that
+// no longer exists
+func (h *PTYHandler) releaseKubernetesWorkspace(workspace string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := h.kubernetes.DeleteWorkspace(ctx, workspace); err != nil {
+		logger.Warnf("⚠️ Failed to delete Kubernetes pod for %s: %v", workspace, err)
+		return
+	}
+	logger.Infof("☸️ Deleted Kubernetes pod for removed workspace %s", workspace)
+}
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// PTYMacroRequest creates or replaces a terminal macro
+type PTYMacroRequest struct {
+	Description string                   `json:"description,omitempty" example:"Reset the database, seed it and start the server"`
+	Steps       []services.PTYMacroStep  `json:"steps"`
+	Params      []services.PTYMacroParam `json:"params,omitempty"`
+}
+
+// RunPTYMacroRequest sets the parameters a macro is replayed with
+type RunPTYMacroRequest struct {
+	Params map[string]string `json:"params,omitempty"`
+}
+
+// WithMacros enables recording and replaying terminal macros
+func (h *PTYHandler) WithMacros(macros *services.PTYMacroStore) *PTYHandler {
+	h.macros = macros
+	return h
+}
+
+// macroRepoID decodes the repository ID of a macro route and checks it exists
+func (h *PTYHandler) macroRepoID(c *fiber.Ctx) (string, error) {
+	repoID, err := url.QueryUnescape(c.Params("id"))
+	if err != nil {
+		return "", models.NewAPIError(models.ErrCodeInvalidRequest, "invalid repository ID: %v", err)
+	}
+	if h.gitService.GetRepositoryByID(repoID) == nil {
+		return "", models.NewRepositoryNotFoundError(repoID)
+	}
+	return repoID, nil
+}
+
+// sessionRepoID returns the repository of the worktree a session runs in
+func (h *PTYHandler) sessionRepoID(session *Session) (string, bool) {
+	for _, worktree := range h.gitService.ListWorktrees() {
+		if worktree.Path == session.WorkDir {
+			return worktree.RepoID, true
+		}
+	}
+	return "", false
+}
+
+// HandleListMacros lists a repository's terminal macros
+// @Summary List terminal macros
+// @Description Returns the terminal macros saved for a repository, sorted by name
+// @Tags pty
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Success 200 {array} services.PTYMacro
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/macros [get]
+func (h *PTYHandler) HandleListMacros(c *fiber.Ctx) error {
+	repoID, err := h.macroRepoID(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(h.macros.List(repoID))
+}
+
+// HandleSaveMacro creates or replaces a terminal macro
+// @Summary Save terminal macro
+// @Description Creates or replaces a named sequence of terminal inputs for a repository. Step inputs may contain {{name}} placeholders, filled in when the macro is replayed; the macro's parameters are derived from them, and defaults given in params are kept. Macros can also be recorded from a terminal with the macro-record control message.
+// @Tags pty
+// @Accept json
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param name path string true "Macro name"
+// @Param request body PTYMacroRequest true "Macro steps"
+// @Success 200 {object} services.PTYMacro
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/macros/{name} [put]
+func (h *PTYHandler) HandleSaveMacro(c *fiber.Ctx) error {
+	repoID, err := h.macroRepoID(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	var req PTYMacroRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	macro, err := h.macros.Save(services.PTYMacro{
+		Name:        c.Params("name"),
+		RepoID:      repoID,
+		Description: req.Description,
+		Steps:       req.Steps,
+		Params:      req.Params,
+	})
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	logger.Infof("⏺️ Saved terminal macro %s for %s (%d steps)", macro.Name, repoID, len(macro.Steps))
+	return c.JSON(macro)
+}
+
+// HandleDeleteMacro deletes a terminal macro
+// @Summary Delete terminal macro
+// @Tags pty
+// @Produce json
+// @Param id path string true "Repository ID (URL encoded)"
+// @Param name path string true "Macro name"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/repositories/{id}/macros/{name} [delete]
+func (h *PTYHandler) HandleDeleteMacro(c *fiber.Ctx) error {
+	repoID, err := h.macroRepoID(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	if err := h.macros.Delete(repoID, c.Params("name")); err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Macro deleted",
+	})
+}
+
+// HandleRunMacro replays a terminal macro into a PTY session
+// @Summary Replay terminal macro
+// @Description Types a macro of the session's repository into the session with its parameters filled in, pausing between steps as recorded. Playback runs in the background; only one macro plays in a session at a time.
+// @Tags pty
+// @Accept json
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param name path string true "Macro name"
+// @Param request body RunPTYMacroRequest false "Parameter values"
+// @Success 202 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string "Another macro is playing"
+// @Router /v1/pty/macros/{name}/run [post]
+func (h *PTYHandler) HandleRunMacro(c *fiber.Ctx) error {
+	sessionID := sessionKeyFromQuery(c)
+	var req RunPTYMacroRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+		}
+	}
+
+	h.sessionMutex.RLock()
+	session, exists := h.sessions[sessionID]
+	h.sessionMutex.RUnlock()
+	if !exists || session == nil {
+		return respondError(c, fiber.StatusNotFound, models.NewAPIError(models.ErrCodeSessionNotFound, "Session %s not found", sessionID))
+	}
+
+	steps, err := h.startMacro(session, c.Params("name"), req.Params)
+	if errors.Is(err, errMacroPlaying) {
+		return respondError(c, fiber.StatusConflict, models.NewAPIError(models.ErrCodeConflict, "%v", err).WithCause(err))
+	}
+	if err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"status":  "playing",
+		"steps":   steps,
+		"session": sessionID,
+	})
+}
+
+// errMacroPlaying is returned when a session is already playing a macro
+var errMacroPlaying = errors.New("a macro is already playing in this session")
+
+// startMacro looks up a macro of the session's repository and starts typing
+// it into the session, returning how many steps it has
+func (h *PTYHandler) startMacro(session *Session, name string, params map[string]string) (int, error) {
+	repoID, ok := h.sessionRepoID(session)
+	if !ok {
+		return 0, models.NewAPIError(models.ErrCodeInvalidRequest, "session %s is not in a repository worktree", session.ID)
+	}
+	macro, exists := h.macros.Get(repoID, name)
+	if !exists {
+		return 0, fmt.Errorf("repository %s has no macro %q", repoID, name)
+	}
+	steps, err := macro.Expand(params)
+	if err != nil {
+		return 0, err
+	}
+	if !session.macroMutex.TryLock() {
+		return 0, errMacroPlaying
+	}
+
+	logger.Infof("▶️ Playing terminal macro %s (%d steps) in session %s", macro.Name, len(steps), session.ID)
+	go func() {
+		defer session.macroMutex.Unlock()
+		h.playMacro(session, steps)
+	}()
+	return len(steps), nil
+}
+
+// playMacro types macro steps into a session. Claude's TUI needs a moment to
+// take in the text before Enter submits it, as with prompt injection.
+func (h *PTYHandler) playMacro(session *Session, steps []services.PTYMacroStep) {
+	submitDelay := time.Duration(0)
+	if session.Agent == "claude" {
+		submitDelay = time.Second
+	}
+	for i, step := range steps {
+		time.Sleep(time.Duration(step.DelayMs) * time.Millisecond)
+		if step.Input != "" {
+			if _, err := session.PTY.Write([]byte(step.Input)); err != nil {
+				logger.Warnf("❌ Macro stopped at step %d in session %s: %v", i+1, session.ID, err)
+				return
+			}
+		}
+		if step.Submit {
+			time.Sleep(submitDelay)
+			if _, err := session.PTY.Write([]byte("\r")); err != nil {
+				logger.Warnf("❌ Macro stopped at step %d in session %s: %v", i+1, session.ID, err)
+				return
+			}
+		}
+	}
+}
+
+// handleMacroRecord starts, stops or cancels recording a connection's input
+// as a macro. Stopping saves the macro under the message's name for the
+// session's repository. It returns the connection's recorder, nil when not
+// recording.
+func (h *PTYHandler) handleMacroRecord(session *Session, conn PTYConnection, recorder *services.PTYMacroRecorder, msg *ControlMessage) *services.PTYMacroRecorder {
+	switch msg.Data {
+	case "start":
+		logger.Infof("⏺️ Recording terminal macro in session %s", session.ID)
+		return services.NewPTYMacroRecorder()
+	case "stop":
+		if recorder == nil {
+			h.sendControlReply(session, conn, "macro-error", errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, "Not recording a macro")))
+			return nil
+		}
+		repoID, ok := h.sessionRepoID(session)
+		if !ok {
+			h.sendControlReply(session, conn, "macro-error", errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, "Session is not in a repository worktree")))
+			return nil
+		}
+		macro, err := h.macros.Save(services.PTYMacro{Name: msg.Name, RepoID: repoID, Steps: recorder.Steps()})
+		if err != nil {
+			// Keep recording so the client can retry with a valid name
+			h.sendControlReply(session, conn, "macro-error", errorBody(toAPIError(err, models.ErrCodeInvalidRequest)))
+			return recorder
+		}
+		logger.Infof("⏺️ Recorded terminal macro %s for %s (%d steps)", macro.Name, repoID, len(macro.Steps))
+		h.sendControlReply(session, conn, "macro-recorded", fiber.Map{"macro": macro})
+		return nil
+	default:
+		return nil
+	}
+}
+
+// runMacroMessage replays the macro named in a control message
+func (h *PTYHandler) runMacroMessage(session *Session, conn PTYConnection, msg *ControlMessage) {
+	if _, err := h.startMacro(session, msg.Name, msg.Params); err != nil {
+		h.sendControlReply(session, conn, "macro-error", errorBody(toAPIError(err, models.ErrCodeInvalidRequest)))
+	}
+}
+
+// sendControlReply sends a typed JSON reply to the connection that sent a
+// control message
+func (h *PTYHandler) sendControlReply(session *Session, conn PTYConnection, msgType string, fields fiber.Map) {
+	fields["type"] = msgType
+	if data, err := json.Marshal(fields); err == nil {
+		_ = session.writeJSONToConnection(conn, data)
+	}
+}
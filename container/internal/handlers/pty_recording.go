@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// StartRecordingRequest labels a new recording
+type StartRecordingRequest struct {
+	// Free-form note, e.g. what the recording reproduces
+	Label string `json:"label" example:"login bug repro"`
+}
+
+// HandleStartRecording starts recording a PTY session
+// @Summary Start PTY session recording
+// @Description Starts recording the session's terminal output, with a prompt marker wherever input is submitted. Sessions aren't recorded unless asked. The recording can be downloaded or streamed as asciicast v2 while it runs, and is saved as a capture when stopped or when the session ends. Recording stops growing past 50MB.
+// @Tags pty
+// @Accept json
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param request body StartRecordingRequest false "Recording label"
+// @Success 201 {object} services.PTYCapture
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /v1/pty/recording [post]
+func (h *PTYHandler) HandleStartRecording(c *fiber.Ctx) error {
+	var req StartRecordingRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+		}
+	}
+
+	sessionID := sessionKeyFromQuery(c)
+	h.sessionMutex.RLock()
+	session, exists := h.sessions[sessionID]
+	h.sessionMutex.RUnlock()
+	if !exists {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Session not found",
+			"session": sessionID,
+		})
+	}
+
+	capture, err := h.recordings.Start(sessionID, req.Label, int(session.cols), int(session.rows), map[string]string{"TERM": "xterm-direct"})
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.Infof("📼 Started recording %s of session %s", capture.ID, sessionID)
+	return c.Status(fiber.StatusCreated).JSON(capture)
+}
+
+// HandleStopRecording stops recording a PTY session and saves the capture
+// @Summary Stop PTY session recording
+// @Description Stops recording the session and saves it as a capture, available from /v1/pty/captures/{id}.
+// @Tags pty
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {object} services.PTYCapture
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/pty/recording/stop [post]
+func (h *PTYHandler) HandleStopRecording(c *fiber.Ctx) error {
+	sessionID := sessionKeyFromQuery(c)
+	if !h.recordings.Recording(sessionID) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Session is not being recorded",
+			"session": sessionID,
+		})
+	}
+	capture, err := h.recordings.Stop(sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	logger.Infof("📼 Saved recording %s of session %s (%d bytes)", capture.ID, sessionID, capture.TotalBytes)
+	return c.JSON(capture)
+}
+
+// HandleGetRecording exports a PTY session's recording as an asciicast v2 file
+// @Summary Download PTY session recording
+// @Description Returns the session's recording so far as an asciicast v2 file, playable with asciinema or any compatible player. Once stopped, the recording is downloaded from /v1/pty/captures/{id}.
+// @Tags pty
+// @Produce application/x-asciicast
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {string} string "asciicast v2 recording"
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/recording [get]
+func (h *PTYHandler) HandleGetRecording(c *fiber.Ctx) error {
+	sessionID := sessionKeyFromQuery(c)
+
+	var buf bytes.Buffer
+	if err := h.recordings.Export(sessionID, &buf); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   err.Error(),
+			"session": sessionID,
+		})
+	}
+
+	c.Set("Content-Type", services.AsciicastContentType)
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", recordingFilename(sessionID)))
+	return c.Send(buf.Bytes())
+}
+
+// HandleStreamRecording streams a PTY session's recording live
+// @Summary Stream PTY session recording
+// @Description Streams a session being recorded as asciicast v2: the header, the output recorded so far, then new events as they happen until recording stops. Clients sending Accept: text/event-stream (such as asciinema-player's eventsource driver) receive one event per SSE message; others receive newline-delimited asciicast lines.
+// @Tags pty
+// @Produce application/x-asciicast
+// @Produce text/event-stream
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Success 200 {string} string "asciicast v2 stream"
+// @Failure 404 {object} map[string]string
+// @Router /v1/pty/recording/live [get]
+func (h *PTYHandler) HandleStreamRecording(c *fiber.Ctx) error {
+	sessionID := sessionKeyFromQuery(c)
+	header, backlog, events, cancel, ok := h.recordings.Subscribe(sessionID)
+	if !ok {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Session is not being recorded",
+			"session": sessionID,
+		})
+	}
+
+	sse := strings.Contains(c.Get("Accept"), "text/event-stream")
+	if sse {
+		c.Set("Content-Type", "text/event-stream")
+	} else {
+		c.Set("Content-Type", services.AsciicastContentType)
+	}
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	logger.Debugf("📼 Streaming recording for session %s", sessionID)
+
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		send := func(v interface{}) bool {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return true
+			}
+			if sse {
+				_, err = fmt.Fprintf(w, "data: %s\n\n", b)
+			} else {
+				_, err = fmt.Fprintf(w, "%s\n", b)
+			}
+			return err == nil
+		}
+
+		if !send(header) {
+			return
+		}
+		for _, event := range backlog {
+			if !send(event) {
+				return
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for event := range events {
+			if !send(event) {
+				return
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+	return nil
+}
+
+// recordingFilename derives a download name like "catnip-main-claude.cast"
+func recordingFilename(sessionID string) string {
+	name := strings.NewReplacer("/", "-", ":", "-").Replace(filepath.Clean(sessionID))
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		name = "session"
+	}
+	return name + ".cast"
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// bufferCompleteMsg ends a buffer replay. BufferID and Offset give the
+// position in the session's output the client is at; counting the output
+// bytes received after it, a client can send them back with its next ready
+// to only get what it missed.
+type bufferCompleteMsg struct {
+	Type     string `json:"type"`
+	BufferID string `json:"buffer_id,omitempty"`
+	Offset   int64  `json:"offset,omitempty"`
+}
+
+// outputSince returns the buffered output after offset, or the whole buffer
+// if offset isn't a position in it (e.g. the buffer was cleared since, or the
+// client is new). While a TUI holds the alternate screen only output before it
+// is replayed, which filtered reports. end is the offset of the buffer's end.
+// The caller must hold bufferMutex.
+func (s *Session) outputSince(bufferID string, offset int64) (replay []byte, end int64, filtered bool) {
+	start := s.outputOffset - int64(len(s.outputBuffer))
+	limit := len(s.outputBuffer)
+	filtered = s.AlternateScreenActive && s.LastNonTUIBufferSize > 0
+	if filtered {
+		limit = s.LastNonTUIBufferSize
+	}
+
+	from := 0
+	if bufferID != "" && bufferID == s.bufferID && offset >= start && offset <= s.outputOffset {
+		from = int(offset - start)
+	}
+	if from > limit {
+		from = limit
+	}
+	return bytes.Clone(s.outputBuffer[from:limit]), s.outputOffset, filtered
+}
+
+// replayBuffer sends a connection the buffered output it is missing, then
+// buffer-complete. Writes to the session's connections are held meanwhile, so
+// live output is neither lost nor sent twice around the replay.
+func (h *PTYHandler) replayBuffer(session *Session, conn PTYConnection, ready *ControlMessage) {
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+
+	session.connMutex.RLock()
+	info, exists := session.connections[conn]
+	session.connMutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	session.bufferMutex.RLock()
+	replay, end, filtered := session.outputSince(ready.BufferID, ready.Offset)
+	bufferID := session.bufferID
+	bufferCols := session.bufferedCols
+	bufferRows := session.bufferedRows
+	session.bufferMutex.RUnlock()
+
+	if len(replay) > 0 && bufferCols > 0 && bufferRows > 0 {
+		// First, resize PTY to match buffered dimensions
+		logger.Infof("📐 Resizing PTY to buffered dimensions %dx%d before replay", bufferCols, bufferRows)
+		_ = h.resizePTY(session.PTY, bufferCols, bufferRows)
+
+		// Tell client what size to use for replay
+		sizeMsg := struct {
+			Type string `json:"type"`
+			Cols uint16 `json:"cols"`
+			Rows uint16 `json:"rows"`
+		}{
+			Type: "buffer-size",
+			Cols: bufferCols,
+			Rows: bufferRows,
+		}
+		if data, err := json.Marshal(sizeMsg); err == nil {
+			_ = conn.WriteJSONMessage(data)
+		}
+
+		logger.Debugf("📋 Replaying %d bytes of buffered output up to offset %d at %dx%d", len(replay), end, bufferCols, bufferRows)
+		var err error
+		if info.Accessible != nil {
+			err = writeAccessibleEvents(conn, info.Accessible.Transform(replay))
+		} else {
+			err = conn.WriteMessage(replay)
+		}
+		if err != nil {
+			logger.Warnf("❌ Failed to replay buffer: %v", err)
+		}
+	} else {
+		logger.Debugf("📋 No buffer to replay or dimensions not captured")
+	}
+	info.Offset = end
+
+	if data, err := json.Marshal(bufferCompleteMsg{Type: "buffer-complete", BufferID: bufferID, Offset: end}); err == nil {
+		logger.Infof("🔧 Sending final buffer-complete signal")
+		_ = conn.WriteJSONMessage(data)
+	}
+
+	// If we filtered TUI content, send a refresh signal to trigger TUI repaint
+	if filtered {
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			logger.Infof("🔄 Sending Ctrl+L to refresh TUI after filtered buffer replay")
+			if _, err := session.PTY.Write([]byte("\x0c")); err != nil {
+				logger.Warnf("❌ Failed to send refresh signal: %v", err)
+			}
+		}()
+	}
+}
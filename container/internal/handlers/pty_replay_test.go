@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionOutputSince(t *testing.T) {
+	// 10 bytes were buffered before the last clear
+	session := &Session{outputBuffer: []byte("hello world"), outputOffset: 21, bufferID: "b2"}
+
+	replay, end, filtered := session.outputSince("b2", 16)
+	assert.Equal(t, "world", string(replay), "only the missing suffix")
+	assert.Equal(t, int64(21), end)
+	assert.False(t, filtered)
+
+	replay, _, _ = session.outputSince("b2", 21)
+	assert.Empty(t, replay, "nothing missed")
+
+	for _, tc := range []struct {
+		name     string
+		bufferID string
+		offset   int64
+	}{
+		{"new client", "", 0},
+		{"buffer cleared since", "b1", 16},
+		{"before the buffer", "b2", 4},
+		{"past the end", "b2", 30},
+	} {
+		replay, _, _ = session.outputSince(tc.bufferID, tc.offset)
+		assert.Equal(t, "hello world", string(replay), tc.name)
+	}
+
+	// Output after a TUI took the alternate screen isn't replayed
+	session.AlternateScreenActive = true
+	session.LastNonTUIBufferSize = 5
+	replay, end, filtered = session.outputSince("", 0)
+	assert.Equal(t, "hello", string(replay))
+	assert.Equal(t, int64(21), end)
+	assert.True(t, filtered)
+	replay, _, _ = session.outputSince("b2", 18)
+	assert.Empty(t, replay)
+}
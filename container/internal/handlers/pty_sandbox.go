@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// WorkspaceSessionCount counts the terminal sessions running in a workspace
+func (h *PTYHandler) WorkspaceSessionCount(workDir string) int {
+	return len(h.workspaceSessions(workDir))
+}
+
+// CloseWorkspaceSessions ends every terminal session running in a workspace,
+// so nothing keeps its directory busy
+func (h *PTYHandler) CloseWorkspaceSessions(workDir string) int {
+	sessions := h.workspaceSessions(workDir)
+	for _, session := range sessions {
+		logger.Infof("🧪 Closing session %s in %s", session.ID, workDir)
+		h.cleanupSession(session)
+	}
+	return len(sessions)
+}
+
+func (h *PTYHandler) workspaceSessions(workDir string) []*Session {
+	h.sessionMutex.RLock()
+	defer h.sessionMutex.RUnlock()
+
+	var sessions []*Session
+	for _, session := range h.sessions {
+		if session.WorkDir == workDir {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// startSandbox mounts a sandbox over the named workspace's worktree before a
+// session is started in it
+func (h *PTYHandler) startSandbox(workspace string) error {
+	worktree := h.findWorktreeByName(workspace)
+	if worktree == nil {
+		return models.NewAPIError(models.ErrCodeWorktreeNotFound, "no worktree named %s", workspace).
+			WithHint("Sandboxes are only available for worktrees")
+	}
+	_, err := h.gitService.StartSandbox(worktree.ID)
+	return err
+}
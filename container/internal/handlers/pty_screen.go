@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/tui"
+)
+
+// writeScreen feeds Claude's output to the session's terminal emulator and
+// returns the offset of the output's end. Claude redraws its screen in place,
+// so the emulated screen rather than a byte buffer is what a reconnecting
+// client needs.
+func (s *Session) writeScreen(data []byte) int64 {
+	s.bufferMutex.Lock()
+	defer s.bufferMutex.Unlock()
+
+	if s.screen == nil {
+		s.screen = tui.NewTerminalEmulator(int(s.cols), int(s.rows))
+	}
+	s.screen.Write(data)
+	s.outputOffset += int64(len(data))
+	return s.outputOffset
+}
+
+// resizeScreen resizes the terminal emulator along with the PTY
+func (s *Session) resizeScreen(cols, rows uint16) {
+	s.bufferMutex.Lock()
+	defer s.bufferMutex.Unlock()
+	if s.screen != nil {
+		s.screen.Resize(int(cols), int(rows))
+	}
+}
+
+// replayScreen sends a connection a snapshot of the session's emulated screen,
+// then buffer-complete. Writes to the session's connections are held
+// meanwhile, so output the snapshot already shows isn't sent again.
+func (h *PTYHandler) replayScreen(session *Session, conn PTYConnection) {
+	session.writeMutex.Lock()
+	defer session.writeMutex.Unlock()
+
+	session.connMutex.RLock()
+	info, exists := session.connections[conn]
+	session.connMutex.RUnlock()
+	if !exists {
+		return
+	}
+
+	session.bufferMutex.RLock()
+	var snapshot []byte
+	var cols, rows int
+	if session.screen != nil {
+		snapshot = session.screen.Snapshot()
+		cols, rows = session.screen.Size()
+	}
+	bufferID := session.bufferID
+	end := session.outputOffset
+	session.bufferMutex.RUnlock()
+
+	if snapshot != nil {
+		// Tell client what size the snapshot was rendered at
+		sizeMsg := struct {
+			Type string `json:"type"`
+			Cols int    `json:"cols"`
+			Rows int    `json:"rows"`
+		}{
+			Type: "buffer-size",
+			Cols: cols,
+			Rows: rows,
+		}
+		if data, err := json.Marshal(sizeMsg); err == nil {
+			_ = conn.WriteJSONMessage(data)
+		}
+
+		logger.Debugf("🖼️ Sending %d byte screen snapshot at %dx%d up to offset %d", len(snapshot), cols, rows, end)
+		var err error
+		if info.Accessible != nil {
+			err = writeAccessibleEvents(conn, info.Accessible.Transform(snapshot))
+		} else {
+			err = conn.WriteMessage(snapshot)
+		}
+		if err != nil {
+			logger.Warnf("❌ Failed to send screen snapshot: %v", err)
+		}
+	} else {
+		logger.Debugf("🖼️ No screen to replay yet")
+	}
+	info.Offset = end
+
+	if data, err := json.Marshal(bufferCompleteMsg{Type: "buffer-complete", BufferID: bufferID, Offset: end}); err == nil {
+		logger.Infof("🔧 Sending buffer-complete after screen snapshot")
+		_ = conn.WriteJSONMessage(data)
+	}
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionScreenSnapshot(t *testing.T) {
+	session := &Session{cols: 20, rows: 5}
+	end := session.writeScreen([]byte("\x1b[2J\x1b[Hold prompt"))
+	// Claude redraws in place, so only the latest frame should survive
+	end = session.writeScreen([]byte("\x1b[H\x1b[2K> fix it\r\n\x1b[1mready\x1b[0m"))
+	assert.Equal(t, int64(len("\x1b[2J\x1b[Hold prompt")+len("\x1b[H\x1b[2K> fix it\r\n\x1b[1mready\x1b[0m")), end)
+
+	snapshot := string(session.screen.Snapshot())
+	assert.True(t, strings.HasPrefix(snapshot, "\x1b[H\x1b[2J"), "clears the client's screen first")
+	assert.Contains(t, snapshot, "> fix it")
+	assert.Contains(t, snapshot, "\r\n")
+	assert.Contains(t, snapshot, "\x1b[1mready")
+	assert.NotContains(t, snapshot, "old prompt")
+	assert.True(t, strings.HasSuffix(snapshot, "\x1b[2;6H\x1b[?25h"), "restores the cursor after %q", snapshot)
+
+	session.resizeScreen(40, 10)
+	cols, rows := session.screen.Size()
+	assert.Equal(t, 40, cols)
+	assert.Equal(t, 10, rows)
+}
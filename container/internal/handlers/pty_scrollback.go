@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// scrollbackSaveInterval is how often changed session buffers are written to
+// the volume. A container restart loses at most this much output.
+const scrollbackSaveInterval = 5 * time.Second
+
+// periodicScrollbackSave writes the buffers of shell sessions to the volume
+// as they change, so a container restart doesn't lose terminal history.
+// Claude sessions aren't saved: they resume their conversation on restart
+// and redraw it themselves.
+func (h *PTYHandler) periodicScrollbackSave() {
+	if removed := h.scrollback.Prune(services.ScrollbackRetention); removed > 0 {
+		logger.Infof("🧹 Removed %d expired terminal scrollback files", removed)
+	}
+
+	ticker := time.NewTicker(scrollbackSaveInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.sessionMutex.RLock()
+		sessions := make([]*Session, 0, len(h.sessions))
+		for _, session := range h.sessions {
+			sessions = append(sessions, session)
+		}
+		h.sessionMutex.RUnlock()
+
+		for _, session := range sessions {
+			h.saveScrollback(session)
+		}
+	}
+}
+
+// saveScrollback writes a shell session's buffer to the volume if it changed
+// since the last save. Output from a full-screen program still running is
+// left out, since replaying it would leave the terminal in its alternate
+// screen.
+func (h *PTYHandler) saveScrollback(session *Session) {
+	if session.Agent == "claude" {
+		return
+	}
+
+	session.bufferMutex.RLock()
+	output := session.outputBuffer
+	if session.AlternateScreenActive && session.LastNonTUIBufferSize <= len(output) {
+		output = output[:session.LastNonTUIBufferSize]
+	}
+	scrollback := services.PTYScrollback{
+		SessionID: session.ID,
+		WorkDir:   session.WorkDir,
+		Agent:     session.Agent,
+		Cols:      int(session.bufferedCols),
+		Rows:      int(session.bufferedRows),
+		Output:    bytes.Clone(output),
+	}
+	version := fmt.Sprintf("%s:%d:%d", session.bufferID, session.outputOffset, len(output))
+	session.bufferMutex.RUnlock()
+
+	if _, err := h.scrollback.Save(scrollback, version); err != nil {
+		logger.Warnf("⚠️ %v", err)
+	}
+}
+
+// restoreScrollback seeds a new shell session's buffer with the output saved
+// before the container last stopped, so reconnecting clients replay it ahead
+// of the re-spawned shell's output. Scrollback saved for another directory
+// belongs to a workspace that has since been replaced and is dropped.
+func (h *PTYHandler) restoreScrollback(session *Session) {
+	saved, ok := h.scrollback.Load(session.ID)
+	if !ok {
+		return
+	}
+	if saved.WorkDir != session.WorkDir {
+		h.scrollback.Forget(session.ID)
+		return
+	}
+
+	restored := saved.Restored()
+	session.bufferMutex.Lock()
+	session.outputBuffer = append(restored, session.outputBuffer...)
+	session.outputOffset += int64(len(restored))
+	if saved.Cols > 0 && saved.Rows > 0 {
+		session.bufferedCols = uint16(saved.Cols)
+		session.bufferedRows = uint16(saved.Rows)
+	}
+	session.bufferMutex.Unlock()
+	logger.Infof("♻️ Restored %d bytes of scrollback for session %s (saved %s)", len(saved.Output), session.ID, saved.SavedAt.Format(time.RFC3339))
+}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// Time an agent started in the background gets to become ready for its first prompt
+const agentStartTimeout = 30 * time.Second
+
+// StartAgentSession starts an agent in a workspace without a client attached
+// and submits prompt once the agent is ready. It blocks until the prompt is
+// sent, so callers provisioning in the background can report failures.
+func (h *PTYHandler) StartAgentSession(workspace, agent, prompt string) error {
+	sessionID := fmt.Sprintf("%s:%s", workspace, agent)
+	session := h.getOrCreateSession(sessionID, agent, false)
+	if session == nil {
+		return fmt.Errorf("failed to create session %s", sessionID)
+	}
+
+	if !h.waitForPTYReady(session, agentStartTimeout) {
+		return fmt.Errorf("%s wasn't ready within %v", sessionID, agentStartTimeout)
+	}
+	logger.Infof("🚀 Started %s in the background, sending its first prompt", sessionID)
+	return injectPrompt(session, prompt, true)
+}
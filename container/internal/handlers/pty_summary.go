@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// WithSummarizer enables summarizing terminal output with Claude
+func (h *PTYHandler) WithSummarizer(summarizer *services.TerminalSummarizer) *PTYHandler {
+	h.summarizer = summarizer
+	return h
+}
+
+// HandleSummarizeOutput explains a stretch of a PTY session's output
+// @Summary Summarize terminal output
+// @Description Sends the selected output, or the session's most recent output, to a small Claude model and returns a short explanation: what ran, whether it failed, the root cause and the likely fix. Escape sequences are stripped first and at most 64KB is sent, keeping the end of the output.
+// @Tags pty
+// @Accept json
+// @Produce json
+// @Param session query string true "Session ID (workspace name)"
+// @Param agent query string false "Agent type (claude, bash, etc)"
+// @Param instance query int false "Agent instance, for running several of one agent in a workspace (default 1)"
+// @Param request body services.TerminalSummaryRequest false "Output to summarize"
+// @Success 200 {object} services.TerminalSummary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string "Summaries are unavailable"
+// @Router /v1/pty/summarize [post]
+func (h *PTYHandler) HandleSummarizeOutput(c *fiber.Ctx) error {
+	sessionID := sessionKeyFromQuery(c)
+	var req services.TerminalSummaryRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if h.summarizer == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Terminal summaries are not available",
+		})
+	}
+
+	h.sessionMutex.RLock()
+	session, exists := h.sessions[sessionID]
+	h.sessionMutex.RUnlock()
+	if !exists || session == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   "Session not found",
+			"session": sessionID,
+		})
+	}
+
+	summary, err := h.summarizeSession(c.Context(), session, req)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(summary)
+}
+
+// summarizeMessage answers a summarize control message on the connection
+// that sent it
+func (h *PTYHandler) summarizeMessage(session *Session, conn PTYConnection, msg *ControlMessage) {
+	summary, err := h.summarizeSession(context.Background(), session, services.TerminalSummaryRequest{
+		Text:   msg.Data,
+		LastKB: msg.LastKB,
+	})
+	if err != nil {
+		h.sendControlReply(session, conn, "summary-error", fiber.Map{"error": err.Error()})
+		return
+	}
+	h.sendControlReply(session, conn, "summary", fiber.Map{"summary": summary})
+}
+
+// summarizeSession summarizes the requested text, or the tail of the
+// session's output buffer when none was selected
+func (h *PTYHandler) summarizeSession(ctx context.Context, session *Session, req services.TerminalSummaryRequest) (*services.TerminalSummary, error) {
+	output := []byte(req.Text)
+	if len(output) == 0 {
+		kb := req.LastKB
+		if kb <= 0 {
+			kb = services.DefaultTerminalSummaryKB
+		}
+		if kb > services.MaxTerminalSummaryKB {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "last_kb can be at most %d", services.MaxTerminalSummaryKB)
+		}
+		session.bufferMutex.RLock()
+		output = append([]byte(nil), services.TerminalOutputTail(session.outputBuffer, kb)...)
+		session.bufferMutex.RUnlock()
+	}
+
+	logger.Infof("📝 Summarizing %d bytes of output from session %s", len(output), session.ID)
+	return h.summarizer.Summarize(ctx, output, session.WorkDir, req.Question)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// maxSessionOrphans caps how many surviving processes are remembered
+const maxSessionOrphans = 100
+
+// SessionOrphan is a process that outlived the PTY session that started it
+type SessionOrphan struct {
+	services.OrphanedProcess
+	SessionID    string    `json:"session_id"`
+	TerminatedAt time.Time `json:"terminated_at"`
+}
+
+// terminateSessionProcess stops a session's command along with its process
+// tree (SIGTERM, then SIGKILL after a grace period) and records anything that
+// survived. It blocks for up to the grace period, so callers must not hold
+// sessionMutex.
+func (h *PTYHandler) terminateSessionProcess(sessionID string, cmd *exec.Cmd) {
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+
+	inspector := h.procInspector
+	if inspector == nil {
+		inspector = services.NewProcessTreeInspectorWithDir("")
+	}
+	inspector.Forget(cmd.Process.Pid)
+
+	result := inspector.Terminate(cmd, services.ProcessTerminationGrace)
+	if result.Killed {
+		logger.Warnf("⚠️  Session %s process tree (%d processes) ignored SIGTERM, sent SIGKILL", sessionID, result.Processes)
+	} else {
+		logger.Debugf("🛑 Terminated session %s process tree (%d processes)", sessionID, result.Processes)
+	}
+	if len(result.Orphans) == 0 {
+		return
+	}
+
+	h.orphanMutex.Lock()
+	defer h.orphanMutex.Unlock()
+	now := time.Now()
+	for _, orphan := range result.Orphans {
+		logger.Warnf("👻 Process %d (%s, state %s) from session %s survived SIGKILL", orphan.PID, orphan.Command, orphan.State, sessionID)
+		h.orphans = append(h.orphans, SessionOrphan{
+			OrphanedProcess: orphan,
+			SessionID:       sessionID,
+			TerminatedAt:    now,
+		})
+	}
+	if len(h.orphans) > maxSessionOrphans {
+		h.orphans = h.orphans[len(h.orphans)-maxSessionOrphans:]
+	}
+}
+
+// HandleListOrphans lists processes that survived PTY session termination
+// @Summary List orphaned session processes
+// @Description Returns processes still running after their PTY session was terminated, even after SIGKILL (e.g. stuck in uninterruptible I/O). Processes that have since exited are dropped.
+// @Tags pty
+// @Produce json
+// @Success 200 {array} SessionOrphan
+// @Router /v1/pty/orphans [get]
+func (h *PTYHandler) HandleListOrphans(c *fiber.Ctx) error {
+	h.orphanMutex.Lock()
+	defer h.orphanMutex.Unlock()
+
+	alive := h.orphans[:0]
+	for _, orphan := range h.orphans {
+		if syscall.Kill(orphan.PID, 0) == nil {
+			alive = append(alive, orphan)
+		}
+	}
+	h.orphans = alive
+
+	return c.JSON(append([]SessionOrphan{}, alive...))
+}
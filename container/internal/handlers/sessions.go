@@ -177,9 +177,7 @@ func (h *SessionsHandler) DeleteSession(c *fiber.Ctx) error {
 	workspace := c.Params("workspace")
 
 	if err := h.sessionService.RemoveActiveSession(workspace); err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": err.Error(),
-		})
+		return respondError(c, 500, err)
 	}
 
 	return c.JSON(fiber.Map{
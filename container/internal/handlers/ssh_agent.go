@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// SSHAgentHandler handles SSH agent forwarding endpoints
+type SSHAgentHandler struct {
+	sshAgent   *services.SSHAgentService
+	gitService *services.GitService
+}
+
+// SSHAgentResponse is the agent forwarding status with recent key usage
+type SSHAgentResponse struct {
+	services.SSHAgentStatus
+	Usage []services.SSHAgentUsage `json:"usage"`
+}
+
+// SSHAgentForwardingRequest sets a worktree's agent forwarding; null restores the default
+type SSHAgentForwardingRequest struct {
+	Enabled *bool `json:"enabled" example:"true"`
+}
+
+// NewSSHAgentHandler creates a new SSH agent handler
+func NewSSHAgentHandler(sshAgent *services.SSHAgentService, gitService *services.GitService) *SSHAgentHandler {
+	return &SSHAgentHandler{
+		sshAgent:   sshAgent,
+		gitService: gitService,
+	}
+}
+
+// GetSSHAgent returns SSH agent forwarding status and recent key usage
+// @Summary Get SSH agent forwarding status
+// @Description Returns whether an SSH agent is available to forward, whether workspaces forward it by default, and recent signatures made through it (newest first). The full audit trail is written to ssh_agent_audit.jsonl in the volume directory.
+// @Tags ssh-agent
+// @Produce json
+// @Param worktree query string false "Only show key usage from this worktree ID"
+// @Success 200 {object} SSHAgentResponse
+// @Router /v1/ssh-agent [get]
+func (h *SSHAgentHandler) GetSSHAgent(c *fiber.Ctx) error {
+	return c.JSON(SSHAgentResponse{
+		SSHAgentStatus: h.sshAgent.Status(),
+		Usage:          h.sshAgent.Usage(c.Query("worktree")),
+	})
+}
+
+// UpdateWorktreeSSHAgent turns SSH agent forwarding on or off for a worktree
+// @Summary Configure SSH agent forwarding for a worktree
+// @Description Sets whether terminals in the worktree get the user's SSH agent. Send null to use the server default. Applies to terminals started afterwards.
+// @Tags ssh-agent
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body SSHAgentForwardingRequest true "Forwarding setting"
+// @Success 200 {object} map[string]interface{}
+// @Router /v1/git/worktrees/{id}/ssh-agent [put]
+func (h *SSHAgentHandler) UpdateWorktreeSSHAgent(c *fiber.Ctx) error {
+	var req SSHAgentForwardingRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	id := c.Params("id")
+	if err := h.gitService.SetSSHAgentForwarding(id, req.Enabled); err != nil {
+		return respondError(c, 400, err)
+	}
+	if !h.sshAgent.Enabled(req.Enabled) {
+		h.sshAgent.Close(id)
+	}
+
+	return c.JSON(fiber.Map{
+		"enabled": h.sshAgent.Enabled(req.Enabled),
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// StageHunksRequest selects hunks of a file to stage
+type StageHunksRequest struct {
+	Path  string   `json:"path" example:"src/main.go"`
+	Hunks []string `json:"hunks" example:"3f2a9c1b7d4e"`
+}
+
+// UnstageRequest unstages a file, or everything when path is empty
+type UnstageRequest struct {
+	Path string `json:"path,omitempty" example:"src/main.go"`
+}
+
+// CommitStagedRequest commits the staged changes
+type CommitStagedRequest struct {
+	Message string `json:"message" example:"Add retry logic to the client"`
+}
+
+// GetUnstagedHunks returns a file's unstaged changes split into hunks
+// @Summary Get unstaged hunks
+// @Description Returns the file's changes that aren't staged yet, split into hunks with content-hash IDs for selective staging. Untracked files have no hunks until they're tracked.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param path query string true "File path relative to the worktree"
+// @Success 200 {object} git.FileHunks
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/hunks [get]
+func (h *GitHandler) GetUnstagedHunks(c *fiber.Ctx) error {
+	hunks, err := h.gitService.GetUnstagedHunks(c.Params("id"), c.Query("path"))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(hunks)
+}
+
+// StageHunks stages selected hunks of a file
+// @Summary Stage hunks
+// @Description Stages the selected hunks of a file (like `git add -p`) via `git apply --cached` and returns the hunks left unstaged. Fails if the file changed since the hunks were listed.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body StageHunksRequest true "File and hunk IDs"
+// @Success 200 {object} git.FileHunks
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/stage [post]
+func (h *GitHandler) StageHunks(c *fiber.Ctx) error {
+	var req StageHunksRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	remaining, err := h.gitService.StageHunks(c.Params("id"), req.Path, req.Hunks)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(remaining)
+}
+
+// UnstageFile removes staged changes from the index
+// @Summary Unstage changes
+// @Description Unstages a file's changes, or all staged changes when no path is given. The working tree is left untouched.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body UnstageRequest false "File to unstage"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/unstage [post]
+func (h *GitHandler) UnstageFile(c *fiber.Ctx) error {
+	var req UnstageRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	id := c.Params("id")
+	if err := h.gitService.UnstageFile(id, req.Path); err != nil {
+		return respondError(c, 400, err)
+	}
+	files, err := h.gitService.StagedFiles(id)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(fiber.Map{
+		"staged": files,
+	})
+}
+
+// GetStagedFiles lists files with staged changes
+// @Summary List staged files
+// @Description Returns the files with changes staged for the next commit
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/staged [get]
+func (h *GitHandler) GetStagedFiles(c *fiber.Ctx) error {
+	files, err := h.gitService.StagedFiles(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(fiber.Map{
+		"staged": files,
+	})
+}
+
+// CommitStaged commits the staged subset of a worktree's changes
+// @Summary Commit staged changes
+// @Description Commits only what's staged, leaving the rest of the changes in the working tree so a large change can be split into reviewable commits
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body CommitStagedRequest true "Commit message"
+// @Success 200 {object} services.StagedCommit
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/commit [post]
+func (h *GitHandler) CommitStaged(c *fiber.Ctx) error {
+	var req CommitStagedRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	commit, err := h.gitService.CommitStaged(c.Params("id"), req.Message)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(commit)
+}
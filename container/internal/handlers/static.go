@@ -4,12 +4,14 @@ import (
 	"io/fs"
 	"mime"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/filesystem"
 	"github.com/vanpelt/catnip/internal/assets"
+	"github.com/vanpelt/catnip/internal/config"
 )
 
 // HasEmbeddedAssets returns true if frontend assets are embedded
@@ -58,6 +60,9 @@ func ServeEmbeddedSPA(c *fiber.Ctx) error {
 
 	// Check if file exists
 	if data, err := fs.ReadFile(embeddedFS, path); err == nil {
+		if path == "index.html" {
+			data = rewriteIndexHTML(data, config.Runtime.BasePath)
+		}
 		// File exists, serve it with appropriate content type
 		contentType := getContentType(path)
 		c.Set("Content-Type", contentType)
@@ -67,12 +72,25 @@ func ServeEmbeddedSPA(c *fiber.Ctx) error {
 	// File doesn't exist, serve index.html for SPA routing
 	if data, err := fs.ReadFile(embeddedFS, "index.html"); err == nil {
 		c.Set("Content-Type", "text/html; charset=utf-8")
-		return c.Send(data)
+		return c.Send(rewriteIndexHTML(data, config.Runtime.BasePath))
 	}
 
 	return c.Status(404).SendString("Asset not found")
 }
 
+// ServeStaticIndex serves index.html from a directory of built frontend
+// assets, pointing its asset URLs at the configured base path
+func ServeStaticIndex(staticPath string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		data, err := os.ReadFile(filepath.Join(staticPath, "index.html"))
+		if err != nil {
+			return c.Status(404).SendString("Asset not found")
+		}
+		c.Set("Content-Type", "text/html; charset=utf-8")
+		return c.Send(rewriteIndexHTML(data, config.Runtime.BasePath))
+	}
+}
+
 // getContentType returns the appropriate content type for a file based on its extension
 func getContentType(path string) string {
 	ext := filepath.Ext(path)
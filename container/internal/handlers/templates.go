@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/git/templates"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// TemplatesHandler handles project template registry endpoints
+type TemplatesHandler struct {
+	syncService *services.TemplateSyncService
+}
+
+// TemplateListResponse lists available project templates and the remote sync status
+type TemplateListResponse struct {
+	Templates []templates.TemplateInfo    `json:"templates"`
+	Sync      services.TemplateSyncStatus `json:"sync"`
+}
+
+// NewTemplatesHandler creates a new templates handler
+func NewTemplatesHandler(syncService *services.TemplateSyncService) *TemplatesHandler {
+	return &TemplatesHandler{
+		syncService: syncService,
+	}
+}
+
+// ListTemplates returns builtin templates plus any synced from the template repository
+// @Summary List project templates
+// @Description Returns builtin project templates and templates synced from the git repository configured with CATNIP_TEMPLATE_REPO
+// @Tags git
+// @Produce json
+// @Success 200 {object} TemplateListResponse
+// @Router /v1/git/templates [get]
+func (h *TemplatesHandler) ListTemplates(c *fiber.Ctx) error {
+	return c.JSON(TemplateListResponse{
+		Templates: templates.ListTemplates(),
+		Sync:      h.syncService.Status(),
+	})
+}
+
+// SyncTemplates pulls the template repository immediately
+// @Summary Sync project templates
+// @Description Clones or pulls the configured template repository and reloads its template manifests
+// @Tags git
+// @Produce json
+// @Success 200 {object} TemplateListResponse
+// @Failure 400 {object} map[string]string "No template repository configured"
+// @Failure 500 {object} map[string]string "Sync failed"
+// @Router /v1/git/templates/sync [post]
+func (h *TemplatesHandler) SyncTemplates(c *fiber.Ctx) error {
+	if !h.syncService.Status().Enabled {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No template repository configured (set CATNIP_TEMPLATE_REPO)",
+		})
+	}
+
+	if err := h.syncService.Sync(); err != nil {
+		return respondError(c, 500, err)
+	}
+
+	return h.ListTemplates(c)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// voiceSocketIdleTimeout is how long a streaming client may go without sending audio
+const voiceSocketIdleTimeout = 30 * time.Second
+
+// VoiceHandler handles voice input and output for workspace agents
+type VoiceHandler struct {
+	voice         *services.VoiceService
+	claudeService *services.ClaudeService
+	gitService    *services.GitService
+}
+
+// SpeakRequest selects the text to synthesize
+type SpeakRequest struct {
+	// Text to speak; markdown is read as plain text
+	Text string `json:"text,omitempty" example:"All tests pass."`
+	// Speak the latest assistant reply in this worktree instead of text
+	WorktreeID string `json:"worktree_id,omitempty" example:"abc123-def456-ghi789"`
+	// Voice override for this request
+	Voice string `json:"voice,omitempty" example:"alloy"`
+}
+
+// NewVoiceHandler creates a new voice handler
+func NewVoiceHandler(voice *services.VoiceService, claudeService *services.ClaudeService, gitService *services.GitService) *VoiceHandler {
+	return &VoiceHandler{
+		voice:         voice,
+		claudeService: claudeService,
+		gitService:    gitService,
+	}
+}
+
+// GetVoice returns the voice configuration
+// @Summary Get voice configuration
+// @Description Returns the selected transcription and speech providers, the registered providers and whether their API keys are in the secret store.
+// @Tags voice
+// @Produce json
+// @Success 200 {object} services.VoiceStatus
+// @Router /v1/voice [get]
+func (h *VoiceHandler) GetVoice(c *fiber.Ctx) error {
+	return c.JSON(h.voice.Status())
+}
+
+// UpdateVoice configures voice providers
+// @Summary Configure voice providers
+// @Description Selects the transcription and speech providers, models, default voice and language. Provider API keys are stored separately with PUT /v1/secrets/{name}, using the key_secret each provider reports.
+// @Tags voice
+// @Accept json
+// @Produce json
+// @Param request body services.VoiceConfig true "Voice configuration"
+// @Success 200 {object} services.VoiceStatus
+// @Failure 400 {object} map[string]string
+// @Router /v1/voice [put]
+func (h *VoiceHandler) UpdateVoice(c *fiber.Ctx) error {
+	var req services.VoiceConfig
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	status, err := h.voice.Configure(req)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(status)
+}
+
+// Transcribe converts uploaded audio to text
+// @Summary Transcribe audio
+// @Description Transcribes a spoken prompt. Send the audio as the raw request body with its Content-Type (webm, ogg, m4a, mp3, wav or flac), or as the "audio" field of a multipart form. Audio is limited to 25 MB.
+// @Tags voice
+// @Accept audio/webm,audio/mp4,audio/mpeg,audio/wav,multipart/form-data
+// @Produce json
+// @Param language query string false "ISO-639-1 language hint"
+// @Success 200 {object} services.Transcription
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/voice/transcribe [post]
+func (h *VoiceHandler) Transcribe(c *fiber.Ctx) error {
+	audio, contentType, err := voiceUpload(c)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+
+	result, err := h.voice.Transcribe(c.UserContext(), audio, contentType, c.Query("language"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(result)
+}
+
+// voiceUpload returns the audio in a raw or multipart request body
+func voiceUpload(c *fiber.Ctx) ([]byte, string, error) {
+	if !strings.HasPrefix(c.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		return c.Body(), c.Get(fiber.HeaderContentType), nil
+	}
+
+	file, err := c.FormFile("audio")
+	if err != nil {
+		return nil, "", models.NewAPIError(models.ErrCodeInvalidRequest, "multipart uploads need an audio field")
+	}
+	if file.Size > services.MaxVoiceAudioBytes {
+		return nil, "", models.NewAPIError(models.ErrCodeInvalidRequest, "audio is larger than %d MB", services.MaxVoiceAudioBytes>>20)
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	audio, err := io.ReadAll(f)
+	if err != nil {
+		return nil, "", err
+	}
+	return audio, file.Header.Get(fiber.HeaderContentType), nil
+}
+
+// TranscribeWebSocket transcribes audio streamed over a WebSocket
+// @Summary Stream audio for transcription
+// @Description WebSocket alternative to POST /v1/voice/transcribe for push-to-talk clients that send audio while recording. Connect with ?format=<content type> (e.g. audio/webm), send the recording as binary messages, then send the text message "end". The server replies with one {type: transcript} message carrying the Transcription fields and closes the socket. Errors get a single {type: error} message with the HTTP status and error body.
+// @Tags voice
+// @Param format query string true "Audio content type"
+// @Param language query string false "ISO-639-1 language hint"
+// @Success 101 {string} string "Switching Protocols"
+// @Router /v1/voice/transcribe/ws [get]
+func (h *VoiceHandler) TranscribeWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	contentType := c.Query("format")
+	if _, ok := services.VoiceAudioExtension(contentType); !ok {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "format query parameter must be a supported audio content type"))
+	}
+	language := c.Query("language")
+	return websocket.New(func(conn *websocket.Conn) {
+		h.handleTranscribeSocket(conn, contentType, language)
+	})(c)
+}
+
+func (h *VoiceHandler) handleTranscribeSocket(conn *websocket.Conn, contentType, language string) {
+	defer conn.Close()
+	out := &completionSocketWriter{conn: conn}
+
+	var audio []byte
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(voiceSocketIdleTimeout))
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			// The client went away or stalled before finishing the recording
+			return
+		}
+		if messageType == websocket.TextMessage {
+			if strings.TrimSpace(string(data)) == "end" {
+				break
+			}
+			out.closeWithError(400, errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, `Send audio as binary messages and "end" when done`)))
+			return
+		}
+		if len(audio)+len(data) > services.MaxVoiceAudioBytes {
+			out.closeWithError(400, errorBody(models.NewAPIError(models.ErrCodeInvalidRequest, "Audio is larger than 25 MB")))
+			return
+		}
+		audio = append(audio, data...)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	result, err := h.voice.Transcribe(context.Background(), audio, contentType, language)
+	if err != nil {
+		logger.Warnf("⚠️ Streamed transcription failed: %v", err)
+		apiErr, ok := models.AsAPIError(err)
+		if !ok {
+			apiErr = &models.APIError{Code: models.ErrCodeInternal, Message: err.Error()}
+		}
+		out.closeWithError(statusForErrorCode(apiErr.Code, 500), errorBody(apiErr))
+		return
+	}
+	if err := out.sendJSON(fiber.Map{
+		"type":     "transcript",
+		"text":     result.Text,
+		"language": result.Language,
+		"provider": result.Provider,
+	}); err != nil {
+		return
+	}
+	out.close(websocket.CloseNormalClosure, "")
+}
+
+// Speak synthesizes speech
+// @Summary Synthesize speech
+// @Description Returns spoken audio for the given text, or for the latest assistant reply in a worktree so a client can read Claude's answer aloud. Markdown is converted to plain speech and code blocks are skipped. Long text is cut at a sentence boundary.
+// @Tags voice
+// @Accept json
+// @Produce audio/mpeg
+// @Param request body SpeakRequest true "What to say"
+// @Success 200 {file} binary "Audio"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /v1/voice/speak [post]
+func (h *VoiceHandler) Speak(c *fiber.Ctx) error {
+	var req SpeakRequest
+	if err := c.BodyParser(&req); err != nil {
+		return respondError(c, 400, models.NewAPIError(models.ErrCodeInvalidRequest, "Invalid request body"))
+	}
+
+	text := req.Text
+	if req.WorktreeID != "" {
+		worktree, exists := h.gitService.GetWorktree(req.WorktreeID)
+		if !exists {
+			return respondError(c, 404, models.NewWorktreeNotFoundError(req.WorktreeID))
+		}
+		message, err := h.claudeService.GetLatestAssistantMessage(worktree.Path)
+		if err != nil || strings.TrimSpace(message) == "" {
+			return respondError(c, 404, models.NewAPIError(models.ErrCodeNotFound, "No assistant reply found for this worktree"))
+		}
+		text = message
+	}
+
+	speech, err := h.voice.Synthesize(c.UserContext(), services.SpeakableText(text), req.Voice)
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	c.Set(fiber.HeaderContentType, speech.ContentType)
+	c.Set("X-Voice-Provider", speech.Provider)
+	return c.Send(speech.Audio)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// GetWorktreeClaudeTools returns the tools Claude may use in a worktree
+// @Summary Get worktree Claude tools
+// @Description Returns the tools catnip-launched Claude processes (terminals and completions) may use in the worktree, those withheld from them, and where the policy comes from: the worktree's own setting, the repository's claude.allowed_tools in .catnip.yaml, or the default of all tools.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} services.EffectiveClaudeTools
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/claude-tools [get]
+func (h *GitHandler) GetWorktreeClaudeTools(c *fiber.Ctx) error {
+	worktree, exists := h.gitService.GetWorktree(c.Params("id"))
+	if !exists {
+		return respondError(c, 404, models.NewWorktreeNotFoundError(c.Params("id")))
+	}
+	return c.JSON(h.gitService.ClaudeTools(worktree.Path))
+}
+
+// UpdateWorktreeClaudeTools sets the tools Claude may use in a worktree
+// @Summary Set worktree Claude tools
+// @Description Limits catnip-launched Claude processes in the worktree to the allowed built-in tools; every other tool is passed to Claude as --disallowedTools. For example, allowing only Read, Glob, Grep, Edit and Write keeps file edits but removes shell and web access. Send null for allowed to fall back to the repository's policy. Applies to Claude processes started afterwards.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body models.ClaudeToolPolicy true "Allowed tools"
+// @Success 200 {object} services.EffectiveClaudeTools
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/claude-tools [put]
+func (h *GitHandler) UpdateWorktreeClaudeTools(c *fiber.Ctx) error {
+	var req models.ClaudeToolPolicy
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var policy *models.ClaudeToolPolicy
+	if req.Allowed != nil {
+		policy = &req
+	}
+	effective, err := h.gitService.SetClaudeToolPolicy(c.Params("id"), policy)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(effective)
+}
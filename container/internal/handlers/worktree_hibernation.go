@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// HibernateWorktree removes a dormant worktree's files to reclaim disk
+// @Summary Hibernate worktree
+// @Description Removes the worktree's files from disk while keeping its branch, its state and its uncommitted changes, untracked files included, which are saved as a stash commit at refs/catnip-hibernate/{id}. Ignored files such as .env and build caches are archived in the volume and restored on rehydrate. The worktree is listed with a hibernation entry until it is rehydrated. Refused while a Claude session or any terminal is open in the worktree or it has unresolved conflicts.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/hibernate [post]
+func (h *GitHandler) HibernateWorktree(c *fiber.Ctx) error {
+	worktree, err := h.gitService.HibernateWorktree(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	if h.diskLayout != nil {
+		h.diskLayout.Forget(worktree.Path)
+	}
+	return c.JSON(worktree)
+}
+
+// RehydrateWorktree restores a hibernated worktree's files
+// @Summary Rehydrate worktree
+// @Description Checks a hibernated worktree out again at the branch it was hibernated on (or its last commit if the branch is gone) and re-applies its saved uncommitted changes. If the changes no longer apply cleanly the worktree is checked out and they stay at refs/catnip-hibernate/{id}.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/rehydrate [post]
+func (h *GitHandler) RehydrateWorktree(c *fiber.Ctx) error {
+	worktree, err := h.gitService.RehydrateWorktree(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(worktree)
+}
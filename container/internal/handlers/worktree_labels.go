@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// WorktreeLabelsRequest replaces a worktree's labels
+type WorktreeLabelsRequest struct {
+	Labels []string `json:"labels" example:"experiment,customer-x"`
+}
+
+// BulkWorktreeResponse lists the worktrees a bulk action touched
+type BulkWorktreeResponse struct {
+	Action  string                        `json:"action"`
+	Results []services.BulkWorktreeResult `json:"results"`
+}
+
+// UpdateWorktreeLabels replaces the labels on a worktree
+// @Summary Set worktree labels
+// @Description Replaces the worktree's labels. Labels are lowercased, de-duplicated and sorted; each may contain letters, digits, '.', '_' and '-'. Send an empty list to clear them.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body WorktreeLabelsRequest true "Labels"
+// @Success 200 {object} WorktreeLabelsRequest
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/labels [put]
+func (h *GitHandler) UpdateWorktreeLabels(c *fiber.Ctx) error {
+	var req WorktreeLabelsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	labels, err := h.gitService.SetWorktreeLabels(c.Params("id"), req.Labels)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(WorktreeLabelsRequest{Labels: labels})
+}
+
+// ListWorktreeLabels returns the labels in use
+// @Summary List worktree labels
+// @Description Returns every label assigned to a worktree with the number of worktrees carrying it
+// @Tags git
+// @Produce json
+// @Success 200 {object} map[string]int
+// @Router /v1/git/labels [get]
+func (h *GitHandler) ListWorktreeLabels(c *fiber.Ctx) error {
+	return c.JSON(h.gitService.WorktreeLabels())
+}
+
+// BulkWorktrees applies an action to every worktree matching a label selector
+// @Summary Bulk worktree action by label
+// @Description Deletes, syncs, labels or unlabels every worktree matching the selector (e.g. "experiment,!keep"). The selector must include at least one label. Failures are reported per worktree.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param request body services.BulkWorktreeRequest true "Selector and action"
+// @Success 200 {object} BulkWorktreeResponse
+// @Failure 400 {object} map[string]string
+// @Router /v1/git/worktrees/bulk [post]
+func (h *GitHandler) BulkWorktrees(c *fiber.Ctx) error {
+	var req services.BulkWorktreeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	results, err := h.gitService.BulkWorktrees(req)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(BulkWorktreeResponse{Action: req.Action, Results: results})
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// WorktreeLockRequest locks paths in a worktree
+type WorktreeLockRequest struct {
+	// Globs relative to the worktree root; "**" matches any number of directories
+	Paths []string `json:"paths" example:"src/auth/**,go.mod"`
+	// Why the paths are locked, shown to Claude
+	Reason string `json:"reason" example:"Refactoring the auth middleware by hand"`
+	// Who holds the lock
+	Owner string `json:"owner,omitempty" example:"alice"`
+	// Minutes until the lock lapses (0 keeps it until it's removed)
+	TTLMinutes int `json:"ttl_minutes,omitempty" example:"120"`
+}
+
+// GetWorktreeLocks returns a worktree's locked paths
+// @Summary List worktree locks
+// @Description Returns the unexpired advisory locks of a worktree, newest first
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {array} models.WorktreeLock
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/locks [get]
+func (h *GitHandler) GetWorktreeLocks(c *fiber.Ctx) error {
+	locks, err := h.gitService.WorktreeLocks(c.Params("id"))
+	if err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(locks)
+}
+
+// AddWorktreeLock locks paths in a worktree
+// @Summary Lock worktree paths
+// @Description Takes an advisory lock on paths someone is working on, so Claude doesn't clobber them. Claude sessions in the worktree get the active locks as context with every prompt, and edits to locked paths are warned about or denied depending on CATNIP_PATH_LOCK_MODE (warn or block). Shell commands mentioning a locked path are only warned about.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body WorktreeLockRequest true "Lock"
+// @Success 200 {object} models.WorktreeLock
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/locks [post]
+func (h *GitHandler) AddWorktreeLock(c *fiber.Ctx) error {
+	var req WorktreeLockRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	lock, err := h.gitService.AddWorktreeLock(c.Params("id"), models.WorktreeLock{
+		Paths:  req.Paths,
+		Reason: req.Reason,
+		Owner:  req.Owner,
+	}, time.Duration(req.TTLMinutes)*time.Minute)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(lock)
+}
+
+// DeleteWorktreeLock releases a lock
+// @Summary Release worktree lock
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param lock_id path string true "Lock ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/locks/{lock_id} [delete]
+func (h *GitHandler) DeleteWorktreeLock(c *fiber.Ctx) error {
+	if err := h.gitService.RemoveWorktreeLock(c.Params("id"), c.Params("lock_id")); err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Lock released",
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// WorktreeMemoryRequest adds a memory to a worktree
+type WorktreeMemoryRequest struct {
+	// What to remember
+	Text string `json:"text" example:"Use pnpm, not npm"`
+	// fact (default), decision or convention
+	Kind string `json:"kind,omitempty" example:"convention" enums:"fact,decision,convention"`
+	// Who is adding it: user (default) or claude
+	Source string `json:"source,omitempty" example:"claude" enums:"claude,user"`
+}
+
+// GetWorktreeMemory returns what a worktree remembers
+// @Summary List worktree memory
+// @Description Returns the facts, decisions and conventions remembered in a worktree, most recent first. New Claude sessions in the worktree get them as context.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {array} models.WorktreeMemory
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/memory [get]
+func (h *GitHandler) GetWorktreeMemory(c *fiber.Ctx) error {
+	memories, err := h.gitService.WorktreeMemory(c.Params("id"))
+	if err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(memories)
+}
+
+// AddWorktreeMemory remembers something in a worktree
+// @Summary Add worktree memory
+// @Description Remembers a fact, decision or convention in a worktree so every new Claude session there gets it as context, surviving session resets and terminal restarts. Restating an existing memory updates it instead of adding a duplicate. Claude adds memories with `catnip memory add`.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body WorktreeMemoryRequest true "Memory"
+// @Success 200 {object} models.WorktreeMemory
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/memory [post]
+func (h *GitHandler) AddWorktreeMemory(c *fiber.Ctx) error {
+	var req WorktreeMemoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	memory, err := h.gitService.AddWorktreeMemory(c.Params("id"), models.WorktreeMemory{
+		Text:   req.Text,
+		Kind:   req.Kind,
+		Source: req.Source,
+	})
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(memory)
+}
+
+// DeleteWorktreeMemory forgets a memory
+// @Summary Remove worktree memory
+// @Description Removes a memory so new Claude sessions in the worktree no longer get it
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param memory_id path string true "Memory ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/memory/{memory_id} [delete]
+func (h *GitHandler) DeleteWorktreeMemory(c *fiber.Ctx) error {
+	if err := h.gitService.RemoveWorktreeMemory(c.Params("id"), c.Params("memory_id")); err != nil {
+		return respondError(c, 404, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Memory removed",
+	})
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// WithPreviews enables deploying and tearing down preview environments
+func (h *GitHandler) WithPreviews(previews *services.PreviewService) *GitHandler {
+	h.previews = previews
+	return h
+}
+
+// DeployWorktreePreview publishes a preview environment for a worktree
+// @Summary Deploy worktree preview
+// @Description Runs the deploy hook from the worktree's .catnip.yaml (preview.deploy script or preview.webhook) now, without waiting for setup and port readiness. Previews are otherwise deployed automatically once setup succeeded and a dev server is up. The hook runs in the background; the worktree's preview field moves from deploying to deployed with the published URL, or to failed with the hook's output.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 202 {object} models.PreviewDeployment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview/deployment [post]
+func (h *GitHandler) DeployWorktreePreview(c *fiber.Ctx) error {
+	if h.previews == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Preview service not initialized",
+		})
+	}
+
+	preview, err := h.previews.Deploy(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.Status(202).JSON(preview)
+}
+
+// TeardownWorktreePreview removes a worktree's preview environment
+// @Summary Tear down worktree preview
+// @Description Runs the teardown hook (preview.teardown script or preview.webhook) in the background and clears the worktree's preview once it succeeds. Previews are also torn down when their worktree is deleted.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview/deployment [delete]
+func (h *GitHandler) TeardownWorktreePreview(c *fiber.Ctx) error {
+	if h.previews == nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Preview service not initialized",
+		})
+	}
+
+	if err := h.previews.Teardown(c.Params("id")); err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.Status(202).JSON(fiber.Map{
+		"message": "Preview teardown started",
+	})
+}
+
+// parsePreviewMergeOptions reads the optional preview merge options body
+func parsePreviewMergeOptions(c *fiber.Ctx) (services.PreviewMergeOptions, error) {
+	var opts services.PreviewMergeOptions
+	if len(c.Body()) == 0 {
+		return opts, nil
+	}
+	err := c.BodyParser(&opts)
+	return opts, err
+}
+
+// PlanWorktreePreviewMerge describes merging a worktree's preview branch
+// @Summary Plan preview merge
+// @Description Compares the worktree's preview branch (catnip/<name>, created with POST /v1/git/worktrees/{id}/preview) with the branch checked out in its local repository: the commits and files it would bring in, whether it fast-forwards, and the files a three-way merge would conflict on. Local repositories only.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} services.PreviewMergePlan
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview/merge [get]
+func (h *GitHandler) PlanWorktreePreviewMerge(c *fiber.Ctx) error {
+	plan, err := h.gitService.PlanPreviewMerge(c.Params("id"))
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(plan)
+}
+
+// MergeWorktreePreview merges a worktree's preview branch into the local checkout
+// @Summary Merge preview
+// @Description Refreshes the worktree's preview branch, then merges it into the branch checked out in its local repository with a three-way merge, or as one commit with squash. The checkout must be clean. When the merge stops on conflicts, status is conflicts and they're left in the checkout: resolve them there, then continue or abort. The preview branch is deleted after a clean merge unless keep_branch is set.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body services.PreviewMergeOptions false "Merge options"
+// @Success 200 {object} services.PreviewMergeResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview/merge [post]
+func (h *GitHandler) MergeWorktreePreview(c *fiber.Ctx) error {
+	opts, err := parsePreviewMergeOptions(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	result, err := h.gitService.ApplyPreviewMerge(c.Params("id"), opts)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(result)
+}
+
+// ContinueWorktreePreviewMerge finishes a preview merge after its conflicts are resolved
+// @Summary Continue preview merge
+// @Description Stages the conflicted files whose conflict markers were removed and commits the merge. While files still have conflict markers, status stays conflicts and they're listed.
+// @Tags git
+// @Accept json
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param request body services.PreviewMergeOptions false "Merge options (message is used for squash merges)"
+// @Success 200 {object} services.PreviewMergeResult
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview/merge/continue [post]
+func (h *GitHandler) ContinueWorktreePreviewMerge(c *fiber.Ctx) error {
+	opts, err := parsePreviewMergeOptions(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	result, err := h.gitService.ContinuePreviewMerge(c.Params("id"), opts)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(result)
+}
+
+// AbortWorktreePreviewMerge undoes a preview merge that stopped on conflicts
+// @Summary Abort preview merge
+// @Description Restores the local checkout to where it was before the preview merge. The preview branch is kept.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} WorktreeOperationResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview/merge/abort [post]
+func (h *GitHandler) AbortWorktreePreviewMerge(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+	if err := h.gitService.AbortPreviewMerge(worktreeID); err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(fiber.Map{
+		"message": "Preview merge aborted",
+		"id":      worktreeID,
+	})
+}
+
+// DeleteWorktreePreview removes a worktree's preview branch
+// @Summary Delete worktree preview
+// @Description Deletes the worktree's preview branch from its local repository. deleted is false when there was none.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/preview [delete]
+func (h *GitHandler) DeleteWorktreePreview(c *fiber.Ctx) error {
+	worktreeID := c.Params("id")
+	deleted, err := h.gitService.DeleteWorktreePreview(worktreeID)
+	if err != nil {
+		return respondError(c, 400, err)
+	}
+	return c.JSON(fiber.Map{
+		"deleted": deleted,
+		"id":      worktreeID,
+	})
+}
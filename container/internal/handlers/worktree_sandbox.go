@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// StartWorktreeSandbox mounts an overlay sandbox over a worktree
+// @Summary Start worktree sandbox
+// @Description Mounts an overlayfs layer over the worktree so every filesystem change made in it, including untracked and ignored files, is kept apart until the sandbox is promoted or discarded. The branch, HEAD and index are recorded so discarding also undoes commits made in the sandbox. Refused while the worktree has open terminal sessions, since they would write around the overlay; start a sandboxed session with POST /v1/pty/start?sandbox=true instead. Starting an existing sandbox mounts it again after a container restart. Containerized mode only.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/sandbox [post]
+func (h *GitHandler) StartWorktreeSandbox(c *fiber.Ctx) error {
+	worktree, err := h.gitService.StartSandbox(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(worktree)
+}
+
+// GetWorktreeSandbox reports a worktree's sandbox and its changes
+// @Summary Get worktree sandbox
+// @Description Returns the worktree's sandbox, whether its overlay is mounted, and the paths changed and deleted in it so far.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} services.SandboxStatus
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/sandbox [get]
+func (h *GitHandler) GetWorktreeSandbox(c *fiber.Ctx) error {
+	status, err := h.gitService.GetSandbox(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(status)
+}
+
+// PromoteWorktreeSandbox applies a sandbox's changes to its worktree
+// @Summary Promote worktree sandbox
+// @Description Closes the worktree's terminal sessions, unmounts the sandbox and applies its changes to the worktree: written files replace the originals and deleted paths are removed. Commits made in the sandbox are kept. If applying fails partway the sandbox is kept and promoting again finishes it.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/sandbox/promote [post]
+func (h *GitHandler) PromoteWorktreeSandbox(c *fiber.Ctx) error {
+	worktree, err := h.gitService.PromoteSandbox(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(worktree)
+}
+
+// DiscardWorktreeSandbox drops a sandbox's changes
+// @Summary Discard worktree sandbox
+// @Description Closes the worktree's terminal sessions, unmounts the sandbox and deletes its changes, leaving the worktree as it was when the sandbox started. The branch (or detached HEAD) and index are reset too, so commits made in the sandbox are dropped; they remain reachable from the reflog.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Success 200 {object} models.Worktree
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/sandbox/discard [post]
+func (h *GitHandler) DiscardWorktreeSandbox(c *fiber.Ctx) error {
+	worktree, err := h.gitService.DiscardSandbox(c.Params("id"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(worktree)
+}
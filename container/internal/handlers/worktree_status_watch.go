@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+const (
+	// defaultStatusPollTimeout is how long a status long-poll waits for a change
+	defaultStatusPollTimeout = 30 * time.Second
+	// maxStatusPollTimeout caps the timeout a client may ask for
+	maxStatusPollTimeout = 120 * time.Second
+	// statusSocketPingInterval keeps idle status sockets alive through proxies
+	statusSocketPingInterval = 30 * time.Second
+)
+
+// WorktreeStatusSummary is the part of a worktree's status shown in editor
+// status bars: whether it has uncommitted changes and its commit count
+type WorktreeStatusSummary struct {
+	WorktreeID  string `json:"worktree_id" example:"a1b2c3d4"`
+	IsDirty     bool   `json:"is_dirty" example:"true"`
+	CommitCount int    `json:"commit_count" example:"3"`
+	// Set once the worktree is deleted; nothing follows it
+	Deleted bool `json:"deleted,omitempty" example:"false"`
+}
+
+// ETag identifies the summary's state for If-None-Match long-polls
+func (s WorktreeStatusSummary) ETag() string {
+	if s.Deleted {
+		return `"deleted"`
+	}
+	return fmt.Sprintf(`"%t-%d"`, s.IsDirty, s.CommitCount)
+}
+
+// applyWorktreeStatusEvent folds a broadcast event into the summary,
+// reporting whether it changed
+func applyWorktreeStatusEvent(summary *WorktreeStatusSummary, event AppEvent) bool {
+	before := *summary
+	switch payload := event.Payload.(type) {
+	case WorktreeBatchPayload:
+		applyCachedWorktreeStatus(summary, payload.Updates[summary.WorktreeID])
+	case WorktreeStatusPayload:
+		if payload.WorktreeID == summary.WorktreeID {
+			applyCachedWorktreeStatus(summary, payload.Status)
+		}
+	case WorktreeDirtyPayload:
+		if payload.WorktreeID == summary.WorktreeID {
+			summary.IsDirty = event.Type == WorktreeDirtyEvent
+		}
+	case WorktreeUpdatedPayload:
+		if payload.WorktreeID == summary.WorktreeID {
+			if dirty, ok := payload.Updates["is_dirty"].(bool); ok {
+				summary.IsDirty = dirty
+			}
+			if count, ok := payload.Updates["commit_count"].(int); ok {
+				summary.CommitCount = count
+			}
+		}
+	case WorktreeDeletedPayload:
+		if payload.WorktreeID == summary.WorktreeID {
+			summary.Deleted = true
+		}
+	}
+	return *summary != before
+}
+
+func applyCachedWorktreeStatus(summary *WorktreeStatusSummary, status *services.CachedWorktreeStatus) {
+	if status == nil {
+		return
+	}
+	if status.IsDirty != nil {
+		summary.IsDirty = *status.IsDirty
+	}
+	if status.CommitCount != nil {
+		summary.CommitCount = *status.CommitCount
+	}
+}
+
+// watchWorktreeStatus subscribes to broadcast events and reads the
+// worktree's current summary. Subscribing first means no change is missed
+// in between; call the returned function to unsubscribe.
+func (h *EventsHandler) watchWorktreeStatus(worktreeID string) (WorktreeStatusSummary, <-chan SSEMessage, func(), error) {
+	clientID := uuid.New().String()
+	ch := make(chan SSEMessage, 100)
+	h.addClient(clientID, ch)
+	unsubscribe := func() { h.removeClient(clientID) }
+
+	worktree, exists := h.gitService.GetWorktree(worktreeID)
+	if !exists {
+		unsubscribe()
+		return WorktreeStatusSummary{}, nil, nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	summary := WorktreeStatusSummary{
+		WorktreeID:  worktree.ID,
+		IsDirty:     worktree.IsDirty,
+		CommitCount: worktree.CommitCount,
+	}
+	return summary, ch, unsubscribe, nil
+}
+
+// PollWorktreeStatus long-polls a worktree's dirty state and commit count
+// @Summary Long-poll worktree status
+// @Description Returns whether the worktree has uncommitted changes and its commit count, for editor status bars. Send the ETag of the last response as If-None-Match to wait until either changes: the response comes as soon as the status cache sees the change, or with 304 after the timeout. Without If-None-Match it answers immediately.
+// @Tags git
+// @Produce json
+// @Param id path string true "Worktree ID"
+// @Param timeout query int false "Seconds to wait for a change (default 30, max 120)"
+// @Param If-None-Match header string false "ETag of the last status seen"
+// @Success 200 {object} WorktreeStatusSummary
+// @Success 304 "No change before the timeout"
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/status [get]
+func (h *EventsHandler) PollWorktreeStatus(c *fiber.Ctx) error {
+	timeout := time.Duration(c.QueryInt("timeout", int(defaultStatusPollTimeout/time.Second))) * time.Second
+	if timeout <= 0 || timeout > maxStatusPollTimeout {
+		timeout = maxStatusPollTimeout
+	}
+
+	summary, events, unsubscribe, err := h.watchWorktreeStatus(c.Params("id"))
+	if err != nil {
+		return respondError(c, fiber.StatusNotFound, err)
+	}
+	defer unsubscribe()
+
+	seen := c.Get(fiber.HeaderIfNoneMatch)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for seen == summary.ETag() {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				return c.SendStatus(fiber.StatusNotModified)
+			}
+			applyWorktreeStatusEvent(&summary, msg.Event)
+		case <-timer.C:
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+	}
+
+	c.Set(fiber.HeaderETag, summary.ETag())
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	return c.JSON(summary)
+}
+
+// WatchWorktreeStatus streams a worktree's dirty state and commit count over a WebSocket
+// @Summary Stream worktree status over WebSocket
+// @Description Sends a WorktreeStatusSummary on connect and again whenever the worktree's dirty state or commit count changes, straight from status cache events, so editor status bars update within a second of a file save or commit. Nothing else about the worktree is sent. A summary with deleted set ends the stream.
+// @Tags git
+// @Param id path string true "Worktree ID"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 404 {object} map[string]string
+// @Router /v1/git/worktrees/{id}/status/ws [get]
+func (h *EventsHandler) WatchWorktreeStatus(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	worktreeID := c.Params("id")
+	if _, exists := h.gitService.GetWorktree(worktreeID); !exists {
+		return respondError(c, fiber.StatusNotFound, models.NewWorktreeNotFoundError(worktreeID))
+	}
+	return websocket.New(func(conn *websocket.Conn) {
+		h.streamWorktreeStatus(conn, worktreeID)
+	})(c)
+}
+
+func (h *EventsHandler) streamWorktreeStatus(conn *websocket.Conn, worktreeID string) {
+	summary, events, unsubscribe, err := h.watchWorktreeStatus(worktreeID)
+	if err != nil {
+		// Deleted since the upgrade was accepted
+		_ = conn.WriteJSON(WorktreeStatusSummary{WorktreeID: worktreeID, Deleted: true})
+		_ = conn.Close()
+		return
+	}
+	defer unsubscribe()
+
+	// Nothing is read from clients; reading notices when they go away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	defer func() {
+		// The client answering the close frame ends the reader; the
+		// connection is recycled once the handler returns, so it must be gone first
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		_ = conn.Close()
+		<-closed
+	}()
+
+	if err := conn.WriteJSON(summary); err != nil {
+		return
+	}
+	ping := time.NewTicker(statusSocketPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				return
+			}
+			if !applyWorktreeStatusEvent(&summary, msg.Event) {
+				continue
+			}
+			if err := conn.WriteJSON(summary); err != nil || summary.Deleted {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	gorillaws "github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// startStatusWatchServer serves the status endpoints for a worktree that
+// is clean with 3 commits
+func startStatusWatchServer(t *testing.T) (*EventsHandler, string) {
+	t.Helper()
+	gitService := services.NewGitServiceWithStateDir(git.NewOperations(), t.TempDir())
+	require.NoError(t, gitService.GetStateManager().AddRepository(&models.Repository{ID: "vanpelt/catnip"}))
+	require.NoError(t, gitService.GetStateManager().AddWorktree(&models.Worktree{
+		ID: "wt-1", RepoID: "vanpelt/catnip", Name: "catnip/felix", CommitCount: 3,
+	}))
+	h := &EventsHandler{
+		gitService:         gitService,
+		clients:            make(map[string]chan SSEMessage),
+		clientConnectTimes: make(map[string]time.Time),
+	}
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/worktrees/:id/status", h.PollWorktreeStatus)
+	app.Get("/worktrees/:id/status/ws", h.WatchWorktreeStatus)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+	return h, ln.Addr().String()
+}
+
+// broadcastWhenWatched broadcasts the events once a status watcher subscribed
+func broadcastWhenWatched(t *testing.T, h *EventsHandler, events ...AppEvent) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		h.clientsMux.RLock()
+		defer h.clientsMux.RUnlock()
+		return len(h.clients) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	for _, event := range events {
+		h.broadcastEvent(event)
+	}
+}
+
+func TestApplyWorktreeStatusEvent(t *testing.T) {
+	dirty, clean, five := true, false, 5
+	summary := WorktreeStatusSummary{WorktreeID: "wt-1", CommitCount: 3}
+
+	assert.False(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeBatchUpdatedEvent, Payload: WorktreeBatchPayload{
+		Updates: map[string]*services.CachedWorktreeStatus{"wt-2": {IsDirty: &dirty}},
+	}}), "other worktrees are ignored")
+	assert.False(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeBatchUpdatedEvent, Payload: WorktreeBatchPayload{
+		Updates: map[string]*services.CachedWorktreeStatus{"wt-1": {IsDirty: &clean}},
+	}}), "unchanged status isn't a change")
+	assert.True(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeBatchUpdatedEvent, Payload: WorktreeBatchPayload{
+		Updates: map[string]*services.CachedWorktreeStatus{"wt-1": {IsDirty: &dirty}},
+	}}))
+	assert.True(t, summary.IsDirty)
+
+	assert.True(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeCleanEvent, Payload: WorktreeDirtyPayload{WorktreeID: "wt-1"}}))
+	assert.False(t, summary.IsDirty)
+	assert.True(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeStatusUpdatedEvent, Payload: WorktreeStatusPayload{
+		WorktreeID: "wt-1", Status: &services.CachedWorktreeStatus{CommitCount: &five},
+	}}))
+	assert.Equal(t, 5, summary.CommitCount)
+	assert.False(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeUpdatedEvent, Payload: WorktreeUpdatedPayload{
+		WorktreeID: "wt-1", Updates: map[string]interface{}{"branch": "feature/x"},
+	}}), "other fields aren't tracked")
+	assert.True(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeUpdatedEvent, Payload: WorktreeUpdatedPayload{
+		WorktreeID: "wt-1", Updates: map[string]interface{}{"commit_count": 6},
+	}}))
+	assert.Equal(t, `"false-6"`, summary.ETag())
+
+	assert.True(t, applyWorktreeStatusEvent(&summary, AppEvent{Type: WorktreeDeletedEvent, Payload: WorktreeDeletedPayload{WorktreeID: "wt-1"}}))
+	assert.Equal(t, `"deleted"`, summary.ETag())
+}
+
+func TestPollWorktreeStatus(t *testing.T) {
+	h, addr := startStatusWatchServer(t)
+	url := "http://" + addr + "/worktrees/wt-1/status"
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	etag := resp.Header.Get("ETag")
+	assert.Equal(t, `"false-3"`, etag)
+
+	// Waits for a change, answering as soon as one arrives
+	dirty := true
+	go broadcastWhenWatched(t, h,
+		AppEvent{Type: WorktreeTodosUpdatedEvent, Payload: WorktreeTodosUpdatedPayload{WorktreeID: "wt-1"}},
+		AppEvent{Type: WorktreeBatchUpdatedEvent, Payload: WorktreeBatchPayload{
+			Updates: map[string]*services.CachedWorktreeStatus{"wt-1": {IsDirty: &dirty}},
+		}},
+	)
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("If-None-Match", etag)
+	started := time.Now()
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var summary WorktreeStatusSummary
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&summary))
+	resp.Body.Close()
+	assert.Equal(t, WorktreeStatusSummary{WorktreeID: "wt-1", IsDirty: true, CommitCount: 3}, summary)
+	assert.Less(t, time.Since(started), 5*time.Second)
+
+	// No change before the timeout
+	req, _ = http.NewRequest("GET", url+"?timeout=1", nil)
+	req.Header.Set("If-None-Match", `"false-3"`)
+	h.gitService.GetStateManager().UpdateWorktree("wt-1", map[string]interface{}{"is_dirty": false})
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+
+	resp, err = http.Get("http://" + addr + "/worktrees/missing/status")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestWatchWorktreeStatus(t *testing.T) {
+	h, addr := startStatusWatchServer(t)
+	conn, _, err := gorillaws.DefaultDialer.Dial("ws://"+addr+"/worktrees/wt-1/status/ws", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var summary WorktreeStatusSummary
+	require.NoError(t, conn.ReadJSON(&summary))
+	assert.Equal(t, WorktreeStatusSummary{WorktreeID: "wt-1", CommitCount: 3}, summary)
+
+	four := 4
+	broadcastWhenWatched(t, h,
+		AppEvent{Type: WorktreeDirtyEvent, Payload: WorktreeDirtyPayload{WorktreeID: "wt-2"}},
+		AppEvent{Type: WorktreeBatchUpdatedEvent, Payload: WorktreeBatchPayload{
+			Updates: map[string]*services.CachedWorktreeStatus{"wt-1": {CommitCount: &four}},
+		}},
+		AppEvent{Type: WorktreeDeletedEvent, Payload: WorktreeDeletedPayload{WorktreeID: "wt-1"}},
+	)
+	require.NoError(t, conn.ReadJSON(&summary))
+	assert.Equal(t, 4, summary.CommitCount)
+	require.NoError(t, conn.ReadJSON(&summary))
+	assert.True(t, summary.Deleted)
+	_, _, err = conn.ReadMessage()
+	assert.Error(t, err, "the stream ends after a deletion")
+
+	_, resp, err := gorillaws.DefaultDialer.Dial("ws://"+addr+"/worktrees/missing/status/ws", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
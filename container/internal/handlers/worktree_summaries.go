@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/vanpelt/catnip/internal/services"
+)
+
+// WithSummaries enables summarizing the work in flight in dirty worktrees
+func (h *GitHandler) WithSummaries(summaries *services.WorktreeSummarizer) *GitHandler {
+	h.summaries = summaries
+	return h
+}
+
+// GetWorktreeSummaries summarizes every worktree with uncommitted changes
+// @Summary Summarize dirty worktrees
+// @Description Returns a one-paragraph summary of what is in flight in each worktree with uncommitted changes, written by a small Claude model from the branch's commits, the dirty files and the diff, for triaging open branches at a glance. Summaries are cached until the worktree's HEAD or set of dirty files changes, so repeated calls are cheap; refresh regenerates them all. A summary that couldn't be generated carries an error and the last summary made, if any. Hibernated worktrees are skipped.
+// @Tags git
+// @Produce json
+// @Param refresh query bool false "Regenerate cached summaries"
+// @Success 200 {object} services.WorktreeSummaryReport
+// @Failure 503 {object} map[string]string "Summaries are unavailable"
+// @Router /v1/git/worktrees/summaries [get]
+func (h *GitHandler) GetWorktreeSummaries(c *fiber.Ctx) error {
+	if h.summaries == nil {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Worktree summaries are not available",
+		})
+	}
+	report, err := h.summaries.Summaries(c.Context(), c.QueryBool("refresh"))
+	if err != nil {
+		return respondError(c, 500, err)
+	}
+	return c.JSON(report)
+}
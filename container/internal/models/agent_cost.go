@@ -0,0 +1,72 @@
+package models
+
+import "time"
+
+// AgentUsage aggregates Claude usage across a worktree's sessions
+// @Description Claude token usage, estimated cost and time spent on a worktree
+type AgentUsage struct {
+	// Number of Claude sessions that made API calls
+	Sessions int `json:"sessions" example:"3"`
+	// Input tokens (excluding cache reads/writes)
+	InputTokens int64 `json:"input_tokens" example:"150000"`
+	// Output tokens
+	OutputTokens int64 `json:"output_tokens" example:"85000"`
+	// Tokens read from the prompt cache
+	CacheReadTokens int64 `json:"cache_read_tokens" example:"1200000"`
+	// Tokens written to the prompt cache
+	CacheCreationTokens int64 `json:"cache_creation_tokens" example:"300000"`
+	// Estimated cost in USD based on list token prices
+	EstimatedCostUSD float64 `json:"estimated_cost_usd" example:"4.32"`
+	// Wall-clock time from first to last message, summed across sessions
+	WallTimeSeconds float64 `json:"wall_time_seconds" example:"5400"`
+	// Time Claude was actually working, summed across sessions
+	ActiveTimeSeconds float64 `json:"active_time_seconds" example:"2700"`
+}
+
+// Add accumulates other into u
+func (u *AgentUsage) Add(other AgentUsage) {
+	u.Sessions += other.Sessions
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheCreationTokens += other.CacheCreationTokens
+	u.EstimatedCostUSD += other.EstimatedCostUSD
+	u.WallTimeSeconds += other.WallTimeSeconds
+	u.ActiveTimeSeconds += other.ActiveTimeSeconds
+}
+
+// AgentCostRecord attributes a worktree's agent usage to the pull request it produced
+// @Description Agent usage snapshot recorded when a pull request was created or updated
+type AgentCostRecord struct {
+	// Repository identifier in owner/repo format
+	RepoID string `json:"repo_id" example:"anthropics/claude-code"`
+	// Worktree the usage was collected from
+	WorktreeID string `json:"worktree_id" example:"abc123-def456-ghi789"`
+	// Worktree name
+	WorktreeName string `json:"worktree_name" example:"feature-api-docs"`
+	// Branch the pull request was opened from
+	Branch string `json:"branch" example:"feature/api-docs"`
+	// Pull request number
+	PRNumber int `json:"pr_number" example:"123"`
+	// Pull request URL
+	PRURL string `json:"pr_url" example:"https://github.com/owner/repo/pull/123"`
+	// Usage at the time the pull request was last created/updated
+	Usage AgentUsage `json:"usage"`
+	// When the usage was last recorded
+	RecordedAt time.Time `json:"recorded_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// AgentCostReport summarizes agent usage for a repository's pull requests in a month
+// @Description Monthly agent cost report for a repository
+type AgentCostReport struct {
+	// Repository identifier in owner/repo format
+	RepoID string `json:"repo_id" example:"anthropics/claude-code"`
+	// Month in YYYY-MM format
+	Month string `json:"month" example:"2024-01"`
+	// Time zone the month is bucketed in
+	TimeZone string `json:"time_zone" example:"Europe/Berlin"`
+	// Usage per pull request
+	PullRequests []AgentCostRecord `json:"pull_requests"`
+	// Total usage across all pull requests
+	Total AgentUsage `json:"total"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Kinds of commits catnip makes on an agent's behalf
+const (
+	// AgentCommitCheckpoint is a periodic commit of work in progress under the current title
+	AgentCommitCheckpoint = "checkpoint"
+	// AgentCommitTitle commits the work done under a title when the title changes
+	AgentCommitTitle = "title"
+)
+
+// AgentCommitNote is the metadata attached as a git note to commits catnip
+// makes for an agent, so tooling can tell them apart from human commits
+// @Description Agent metadata stored in refs/notes/catnip for an agent-authored commit
+type AgentCommitNote struct {
+	// Agent that did the work
+	Agent string `json:"agent" example:"claude"`
+	// Agent CLI version recorded in the session log
+	AgentVersion string `json:"agent_version,omitempty" example:"1.0.58"`
+	// Agent session the work came from
+	SessionID string `json:"session_id,omitempty" example:"cf568042-7147-4fba-a2ca-c6a646581260"`
+	// SHA-256 of the latest user prompt, so prompts can be correlated without storing them
+	PromptHash string `json:"prompt_hash,omitempty" example:"sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+	// Estimated session cost in USD when the commit was made
+	CostUSD float64 `json:"cost_usd" example:"1.27"`
+	// Why the commit was made: checkpoint or title
+	Kind string `json:"kind" example:"checkpoint"`
+	// When the commit was made
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// AgentCommit is a commit and the agent note attached to it
+// @Description Agent-authored commit with its note
+type AgentCommit struct {
+	// Commit hash
+	Commit string `json:"commit" example:"abc123def456"`
+	// Commit subject line
+	Subject string `json:"subject" example:"Add login form checkpoint: 2"`
+	// Agent metadata attached to the commit
+	Note AgentCommitNote `json:"note"`
+}
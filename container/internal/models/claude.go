@@ -186,8 +186,10 @@ type CreateCompletionRequest struct {
 	Prompt string `json:"prompt" example:"Help me debug this error"`
 	// Whether to stream the response
 	Stream bool `json:"stream,omitempty" example:"true"`
-	// Optional system prompt override
+	// Optional system prompt override; replaces Claude's default prompt and skips the org/repo layers
 	SystemPrompt string `json:"system_prompt,omitempty" example:"You are a helpful coding assistant"`
+	// Optional addition appended after the org and repository system prompt layers
+	AppendSystemPrompt string `json:"append_system_prompt,omitempty" example:"Keep answers short"`
 	// Optional model override
 	Model string `json:"model,omitempty" example:"claude-3-5-sonnet-20241022"`
 	// Maximum number of turns in the conversation
@@ -203,6 +205,50 @@ type CreateCompletionRequest struct {
 	SuppressEvents bool `json:"suppress_events,omitempty" example:"true"`
 	// Whether to disable all tools (Claude will only use context, no tool calls)
 	DisableTools bool `json:"disable_tools,omitempty" example:"true"`
+	// Send the prompt even if pre-flight linting found blocking problems
+	IgnoreLintWarnings bool `json:"ignore_lint_warnings,omitempty" example:"false"`
+	// Optional time/token budget; when exceeded Claude is told to wrap up and then stopped (streaming only)
+	Budget *SessionBudget `json:"budget,omitempty"`
+	// Relay Claude's permission prompts for approval instead of skipping permissions (streaming only)
+	RelayPermissions bool `json:"relay_permissions,omitempty" example:"false"`
+}
+
+// SessionBudget limits how long a Claude task may run before it must wrap up
+// @Description Time and token limits for a Claude task
+type SessionBudget struct {
+	// Wall-clock limit in minutes (0 = unlimited)
+	MaxMinutes int `json:"max_minutes,omitempty" example:"30"`
+	// Input + output token limit, excluding cache reads (0 = unlimited)
+	MaxTokens int `json:"max_tokens,omitempty" example:"200000"`
+}
+
+// PromptLintSeverity is how a prompt lint finding is handled
+type PromptLintSeverity string
+
+const (
+	// PromptLintWarn findings are returned alongside the completion
+	PromptLintWarn PromptLintSeverity = "warn"
+	// PromptLintBlock findings stop the prompt from being sent unless ignore_lint_warnings is set
+	PromptLintBlock PromptLintSeverity = "block"
+)
+
+// PromptLintWarning is one problem found while linting a prompt before sending it
+// @Description Pre-flight prompt check finding
+type PromptLintWarning struct {
+	// Check that produced the finding: missing_file, length or secret
+	Check    string             `json:"check" example:"missing_file"`
+	Severity PromptLintSeverity `json:"severity" example:"warn"`
+	Message  string             `json:"message" example:"src/old.go does not exist in the worktree"`
+	// The offending text, redacted for secrets
+	Match string `json:"match,omitempty" example:"src/old.go"`
+}
+
+// PromptLintResponse is the result of linting a prompt
+// @Description Pre-flight prompt check results
+type PromptLintResponse struct {
+	Warnings []PromptLintWarning `json:"warnings"`
+	// Whether any finding would stop the prompt from being sent
+	Blocked bool `json:"blocked" example:"false"`
 }
 
 // CreateCompletionResponse represents a response from claude CLI completion
@@ -216,6 +262,122 @@ type CreateCompletionResponse struct {
 	IsLast bool `json:"is_last,omitempty" example:"true"`
 	// Any error that occurred
 	Error string `json:"error,omitempty"`
+	// Identifier for this completion, used to attach feedback
+	CompletionID string `json:"completion_id,omitempty" example:"4f9c2e0a-1b2c-4d5e-8f90-123456789abc"`
+	// Non-blocking pre-flight prompt lint findings
+	Warnings []PromptLintWarning `json:"warnings,omitempty"`
+	// Permission prompt waiting for approval (relay_permissions streams only)
+	Permission *ClaudePermissionRequest `json:"permission,omitempty"`
+	// How Claude finished, on the last chunk of a stream
+	Result *CompletionResult `json:"result,omitempty"`
+}
+
+// CompletionResult summarizes how a streamed Claude task ended
+// @Description Outcome reported by Claude at the end of a task
+type CompletionResult struct {
+	// Claude's result subtype: success, error_max_turns or error_during_execution
+	Subtype string `json:"subtype" example:"success"`
+	// Whether the task ended in an error
+	IsError bool `json:"is_error" example:"false"`
+	// Claude session the task ran in
+	SessionID string `json:"session_id,omitempty" example:"0c5d9c7e-2f43-4a61-9d0e-8f2b6a1c3d4e"`
+	// Number of conversation turns taken
+	NumTurns int `json:"num_turns,omitempty" example:"12"`
+	// Wall-clock duration in milliseconds
+	DurationMS int64 `json:"duration_ms,omitempty" example:"84210"`
+	// Cost of the task in US dollars
+	TotalCostUSD float64 `json:"total_cost_usd,omitempty" example:"0.42"`
+}
+
+// ClaudePermissionStatus is the state of a relayed permission prompt
+type ClaudePermissionStatus string
+
+const (
+	// ClaudePermissionPending is waiting for a decision
+	ClaudePermissionPending ClaudePermissionStatus = "pending"
+	// ClaudePermissionAllowed let the tool run
+	ClaudePermissionAllowed ClaudePermissionStatus = "allowed"
+	// ClaudePermissionDenied stopped the tool from running
+	ClaudePermissionDenied ClaudePermissionStatus = "denied"
+	// ClaudePermissionExpired was denied because nobody answered in time or Claude exited
+	ClaudePermissionExpired ClaudePermissionStatus = "expired"
+)
+
+// ClaudePermissionRequest is a tool use Claude asked permission for
+// @Description A permission prompt relayed from a Claude completion
+type ClaudePermissionRequest struct {
+	ID               string                 `json:"id" example:"perm-1a2b3c"`
+	WorkingDirectory string                 `json:"working_directory" example:"/workspace/my-project"`
+	ToolName         string                 `json:"tool_name" example:"Bash"`
+	Input            map[string]interface{} `json:"input"`
+	Status           ClaudePermissionStatus `json:"status" example:"pending"`
+	// Reason given to Claude when the tool use was denied
+	Message   string     `json:"message,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// ClaudePermissionDecision answers a relayed permission prompt
+type ClaudePermissionDecision struct {
+	// Whether the tool may run
+	Allow bool `json:"allow" example:"true"`
+	// Reason passed to Claude when denying
+	Message string `json:"message,omitempty" example:"Don't touch production"`
+	// Replacement tool input when allowing (defaults to the requested input)
+	UpdatedInput map[string]interface{} `json:"updated_input,omitempty"`
+}
+
+// FeedbackRating is a thumbs-up/down rating for a Claude response
+type FeedbackRating string
+
+const (
+	// FeedbackPositive marks a response as helpful
+	FeedbackPositive FeedbackRating = "up"
+	// FeedbackNegative marks a response as unhelpful
+	FeedbackNegative FeedbackRating = "down"
+)
+
+// ClaudeFeedbackRequest represents a request to rate a Claude response
+// @Description Thumbs-up/down feedback for a completion or a session message
+type ClaudeFeedbackRequest struct {
+	// Completion ID returned by /v1/claude/messages (either this or session_id is required)
+	CompletionID string `json:"completion_id,omitempty" example:"4f9c2e0a-1b2c-4d5e-8f90-123456789abc"`
+	// Claude session ID the rated message belongs to
+	SessionID string `json:"session_id,omitempty" example:"abc123-def456-ghi789"`
+	// UUID of the rated message within the session
+	MessageID string `json:"message_id,omitempty" example:"msg-123"`
+	// Rating of the response
+	Rating FeedbackRating `json:"rating" example:"down" enums:"up,down"`
+	// Optional free-form comment
+	Comment string `json:"comment,omitempty" example:"Ignored the failing test"`
+	// Workspace the response was produced in (filled from the completion if omitted)
+	WorkingDirectory string `json:"working_directory,omitempty" example:"/workspace/my-project"`
+}
+
+// ClaudeFeedback is a persisted feedback record joined with completion metadata
+// @Description Stored feedback with the prompt, model and workspace it applies to
+type ClaudeFeedback struct {
+	// Unique feedback ID
+	ID string `json:"id" example:"fb-123"`
+	// Completion ID the feedback refers to
+	CompletionID string `json:"completion_id,omitempty"`
+	// Claude session ID the feedback refers to
+	SessionID string `json:"session_id,omitempty"`
+	// Message UUID the feedback refers to
+	MessageID string `json:"message_id,omitempty"`
+	// Rating of the response
+	Rating FeedbackRating `json:"rating" enums:"up,down"`
+	// Optional free-form comment
+	Comment string `json:"comment,omitempty"`
+	// Prompt that produced the response (when known)
+	Prompt string `json:"prompt,omitempty"`
+	// Model that produced the response (when known)
+	Model string `json:"model,omitempty"`
+	// Workspace the response was produced in
+	WorkingDirectory string `json:"working_directory,omitempty"`
+	// When the feedback was recorded
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // Todo represents a single todo item from the TodoWrite tool
@@ -246,6 +408,8 @@ type ClaudeSettings struct {
 	NumStartups int `json:"numStartups" example:"15"`
 	// Whether notifications are enabled
 	NotificationsEnabled bool `json:"notificationsEnabled" example:"true"`
+	// Org-wide system prompt layered into every Claude session
+	SystemPrompt string `json:"systemPrompt,omitempty" example:"Follow the conventions in CONTRIBUTING.md"`
 }
 
 // ClaudeSettingsUpdateRequest represents a request to update Claude settings
@@ -255,6 +419,8 @@ type ClaudeSettingsUpdateRequest struct {
 	Theme string `json:"theme,omitempty" example:"dark" enums:"dark,light,dark-daltonized,light-daltonized,dark-ansi,light-ansi"`
 	// Whether notifications should be enabled
 	NotificationsEnabled *bool `json:"notificationsEnabled,omitempty" example:"true"`
+	// Org-wide system prompt (empty string clears it)
+	SystemPrompt *string `json:"systemPrompt,omitempty" example:"Follow the conventions in CONTRIBUTING.md"`
 }
 
 // ClaudeHookEvent represents a hook event from Claude Code
@@ -270,6 +436,30 @@ type ClaudeHookEvent struct {
 	Data map[string]interface{} `json:"data,omitempty"`
 }
 
+// ClaudeHookResponse is returned to the hook command. Decision and Reason are
+// passed through to Claude Code as hook output when set.
+type ClaudeHookResponse struct {
+	Status  string `json:"status" example:"success"`
+	Message string `json:"message,omitempty"`
+	// "block" feeds Reason back to Claude (used to ask it to wrap up)
+	Decision string `json:"decision,omitempty" example:"block"`
+	Reason   string `json:"reason,omitempty"`
+	// Event-specific output, such as context for Claude or a tool permission decision
+	HookSpecificOutput *ClaudeHookSpecificOutput `json:"hookSpecificOutput,omitempty"`
+	// Shown to the user in the terminal
+	SystemMessage string `json:"systemMessage,omitempty"`
+}
+
+// ClaudeHookSpecificOutput is the hookSpecificOutput of Claude Code hook output
+type ClaudeHookSpecificOutput struct {
+	HookEventName string `json:"hookEventName" example:"PreToolUse"`
+	// Added to Claude's context (UserPromptSubmit, SessionStart, PreToolUse)
+	AdditionalContext string `json:"additionalContext,omitempty"`
+	// PreToolUse only: allow, deny or ask
+	PermissionDecision       string `json:"permissionDecision,omitempty" example:"deny"`
+	PermissionDecisionReason string `json:"permissionDecisionReason,omitempty"`
+}
+
 // ClaudeOnboardingStatus represents the current status of the onboarding process
 // @Description Status of the automated Claude Code onboarding/login flow
 type ClaudeOnboardingStatus struct {
@@ -291,3 +481,21 @@ type ClaudeOnboardingSubmitCodeRequest struct {
 	// OAuth code obtained from the authentication flow
 	Code string `json:"code" example:"abc123def456" binding:"required"`
 }
+
+// SystemPromptLayer is one source of instructions appended to Claude's system prompt
+// @Description A system prompt layer and where it came from
+type SystemPromptLayer struct {
+	// Where the layer came from: org (settings), repository (.catnip.yaml), workspace (linked issue, branch summary) or request
+	Source string `json:"source" example:"repository" enums:"org,repository,workspace,request"`
+	// Layer content
+	Content string `json:"content" example:"Run pnpm typecheck before committing"`
+}
+
+// EffectiveSystemPrompt is the layered prompt appended to Claude's system prompt
+// @Description The system prompt layers that apply in a directory, and their concatenation
+type EffectiveSystemPrompt struct {
+	// Non-empty layers in the order they are concatenated
+	Layers []SystemPromptLayer `json:"layers"`
+	// Layers joined with blank lines, as passed to --append-system-prompt
+	Prompt string `json:"prompt"`
+}
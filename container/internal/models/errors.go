@@ -0,0 +1,159 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode is a stable, machine-readable identifier for an API error
+type ErrorCode string
+
+const (
+	// ErrCodeInvalidRequest means the request was malformed or missing required fields
+	ErrCodeInvalidRequest ErrorCode = "INVALID_REQUEST"
+	// ErrCodeWorktreeNotFound means the referenced worktree does not exist
+	ErrCodeWorktreeNotFound ErrorCode = "WORKTREE_NOT_FOUND"
+	// ErrCodeRepositoryNotFound means the referenced repository does not exist
+	ErrCodeRepositoryNotFound ErrorCode = "REPOSITORY_NOT_FOUND"
+	// ErrCodeSessionNotFound means the referenced Claude or terminal session does not exist
+	ErrCodeSessionNotFound ErrorCode = "SESSION_NOT_FOUND"
+	// ErrCodeNotFound means another referenced resource, such as a port or a terminal watch, does not exist
+	ErrCodeNotFound ErrorCode = "NOT_FOUND"
+	// ErrCodeCompositeNotFound means the referenced composite workspace does not exist
+	ErrCodeCompositeNotFound ErrorCode = "COMPOSITE_NOT_FOUND"
+	// ErrCodeMergeConflict means a git sync/merge stopped on conflicting files
+	ErrCodeMergeConflict ErrorCode = "MERGE_CONFLICT"
+	// ErrCodeConflict means the request clashes with an operation already in progress
+	ErrCodeConflict ErrorCode = "CONFLICT"
+	// ErrCodeGitHubNotAuthenticated means the GitHub CLI has no valid credentials
+	ErrCodeGitHubNotAuthenticated ErrorCode = "GITHUB_NOT_AUTHENTICATED"
+	// ErrCodeHostNotAuthenticated means a git host other than GitHub refused catnip's credentials
+	ErrCodeHostNotAuthenticated ErrorCode = "HOST_NOT_AUTHENTICATED"
+	// ErrCodeInvalidSignature means a signed request, such as a webhook delivery, was unsigned or its signature didn't verify
+	ErrCodeInvalidSignature ErrorCode = "INVALID_SIGNATURE"
+	// ErrCodeGitCommandFailed means an underlying git command exited with an error
+	ErrCodeGitCommandFailed ErrorCode = "GIT_COMMAND_FAILED"
+	// ErrCodeClaudeFailed means the claude CLI subprocess failed
+	ErrCodeClaudeFailed ErrorCode = "CLAUDE_FAILED"
+	// ErrCodePromptBlocked means pre-flight prompt linting found a blocking problem
+	ErrCodePromptBlocked ErrorCode = "PROMPT_BLOCKED"
+	// ErrCodeBranchNameRejected means a branch name breaks the repository's naming policy
+	ErrCodeBranchNameRejected ErrorCode = "BRANCH_NAME_REJECTED"
+	// ErrCodeTimeout means the operation didn't finish in time; it is safe to retry
+	ErrCodeTimeout ErrorCode = "TIMEOUT"
+	// ErrCodeServiceUnavailable means the service handling the request isn't set up
+	ErrCodeServiceUnavailable ErrorCode = "SERVICE_UNAVAILABLE"
+	// ErrCodeInternal is the fallback for errors without a more specific code
+	ErrCodeInternal ErrorCode = "INTERNAL_ERROR"
+)
+
+// APIError is a typed error carrying a stable code, a retryable flag and an
+// optional remediation hint so clients can react without parsing messages
+type APIError struct {
+	Code      ErrorCode `json:"code"`
+	Message   string    `json:"message"`
+	Retryable bool      `json:"retryable"`
+	Hint      string    `json:"hint,omitempty"`
+	Err       error     `json:"-"` // Underlying cause, if any
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// WithHint returns the error with a remediation hint attached
+func (e *APIError) WithHint(hint string) *APIError {
+	e.Hint = hint
+	return e
+}
+
+// WithCause returns the error with an underlying cause attached
+func (e *APIError) WithCause(err error) *APIError {
+	e.Err = err
+	return e
+}
+
+// NewAPIError creates a non-retryable API error with the given code and message
+func NewAPIError(code ErrorCode, format string, args ...interface{}) *APIError {
+	return &APIError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// NewRetryableAPIError creates an API error that clients may safely retry
+func NewRetryableAPIError(code ErrorCode, format string, args ...interface{}) *APIError {
+	e := NewAPIError(code, format, args...)
+	e.Retryable = true
+	return e
+}
+
+// NewWorktreeNotFoundError creates the standard error for a missing worktree
+func NewWorktreeNotFoundError(worktreeID string) *APIError {
+	return NewAPIError(ErrCodeWorktreeNotFound, "worktree %s not found", worktreeID).
+		WithHint("Refresh the worktree list; the workspace may have been deleted")
+}
+
+// NewRepositoryNotFoundError creates the standard error for a missing repository
+func NewRepositoryNotFoundError(repoID string) *APIError {
+	return NewAPIError(ErrCodeRepositoryNotFound, "repository %s not found", repoID).
+		WithHint("Check out the repository again or verify it is mounted")
+}
+
+// NewCompositeNotFoundError creates the standard error for a missing composite workspace
+func NewCompositeNotFoundError(idOrName string) *APIError {
+	return NewAPIError(ErrCodeCompositeNotFound, "composite workspace %s not found", idOrName)
+}
+
+// NewGitHubNotAuthenticatedError creates the standard error for missing gh credentials
+func NewGitHubNotAuthenticatedError() *APIError {
+	return NewAPIError(ErrCodeGitHubNotAuthenticated, "GitHub CLI not authenticated").
+		WithHint("Run 'gh auth login' or use the GitHub login flow in the UI")
+}
+
+// NewHostNotAuthenticatedError creates the standard error for missing or
+// rejected GitLab and Bitbucket tokens
+func NewHostNotAuthenticatedError(provider string) *APIError {
+	hint := "Set GITLAB_TOKEN to a personal access token with the api scope"
+	if provider == "bitbucket" {
+		hint = "Set BITBUCKET_TOKEN to an access token, or BITBUCKET_USERNAME and BITBUCKET_APP_PASSWORD"
+	}
+	return NewAPIError(ErrCodeHostNotAuthenticated, "%s API not authenticated", provider).WithHint(hint)
+}
+
+// AsAPIError extracts an APIError from an error chain. MergeConflictError is
+// mapped to ErrCodeMergeConflict so callers only need to handle one type.
+func AsAPIError(err error) (*APIError, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	var conflictErr *MergeConflictError
+	if errors.As(err, &conflictErr) {
+		return &APIError{
+			Code:    ErrCodeMergeConflict,
+			Message: conflictErr.Message,
+			Hint:    "Resolve the conflicting files in the worktree and try again",
+			Err:     conflictErr,
+		}, true
+	}
+
+	return nil, false
+}
+
+// ErrorCodeOf returns the code of err, or ErrCodeInternal if it is untyped
+func ErrorCodeOf(err error) ErrorCode {
+	if apiErr, ok := AsAPIError(err); ok {
+		return apiErr.Code
+	}
+	return ErrCodeInternal
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -59,6 +62,247 @@ type Repository struct {
 	RemoteOrigin string `json:"remote_origin,omitempty" example:"https://github.com/anthropics/claude-code.git"`
 	// Whether the remote origin is a GitHub repository
 	HasGitHubRemote bool `json:"has_github_remote" example:"true"`
+	// Commit author identities applied to this repository's worktrees
+	Identity *GitIdentitySettings `json:"identity,omitempty"`
+	// Overrides for git network timeouts and retries
+	Network *GitNetworkSettings `json:"network,omitempty"`
+	// Overrides for how status refreshes and syncs fetch from the remote
+	Fetch *FetchSettings `json:"fetch,omitempty"`
+	// How git and GitHub operations authenticate: user (gh login, the default) or app
+	GitHubAuth GitHubAuthMode `json:"github_auth,omitempty" example:"app" enums:"user,app"`
+	// Rules branch names given to this repository's worktrees must follow
+	BranchNaming *BranchNamingPolicy `json:"branch_naming,omitempty"`
+	// Whether pull requests catnip opens skip requesting reviews from the
+	// CODEOWNERS of the changed files
+	DisableCodeOwnerReviews bool `json:"disable_code_owner_reviews,omitempty" example:"false"`
+	// Overrides for the automatic checkpoint commits made in this repository's worktrees
+	Checkpoints *CheckpointSettings `json:"checkpoints,omitempty"`
+}
+
+// GitHubAuthMode selects the credentials used for a repository's GitHub operations
+type GitHubAuthMode string
+
+const (
+	// GitHubAuthUser uses the server user's gh login
+	GitHubAuthUser GitHubAuthMode = "user"
+	// GitHubAuthApp uses an installation token of the configured GitHub App
+	GitHubAuthApp GitHubAuthMode = "app"
+)
+
+// SourceRefType identifies what kind of non-branch ref a worktree was created from
+type SourceRefType string
+
+const (
+	// SourceRefTag means the worktree was created from a tag
+	SourceRefTag SourceRefType = "tag"
+	// SourceRefCommit means the worktree was created from a specific commit SHA
+	SourceRefCommit SourceRefType = "commit"
+	// SourceRefPullRequest means the worktree was created from a pull request's head
+	SourceRefPullRequest SourceRefType = "pull_request"
+)
+
+// GitIdentity is a git author name and email
+type GitIdentity struct {
+	Name  string `json:"name" example:"Jane Doe"`
+	Email string `json:"email" example:"jane@example.com"`
+}
+
+// String formats the identity as "Name <email>"
+func (i *GitIdentity) String() string {
+	return fmt.Sprintf("%s <%s>", i.Name, i.Email)
+}
+
+// Validate checks that both name and email are set
+func (i *GitIdentity) Validate() error {
+	if strings.TrimSpace(i.Name) == "" || strings.TrimSpace(i.Email) == "" {
+		return fmt.Errorf("identity requires both name and email")
+	}
+	if !strings.Contains(i.Email, "@") {
+		return fmt.Errorf("invalid email %q", i.Email)
+	}
+	return nil
+}
+
+// GitIdentitySettings configures which identities commits are authored with
+// @Description User identity for manual commits and optional bot identity for automated checkpoint/title commits
+type GitIdentitySettings struct {
+	// Identity applied via git config, used for commits made in the terminal
+	User *GitIdentity `json:"user,omitempty"`
+	// Identity for commits catnip makes automatically (falls back to User)
+	Bot *GitIdentity `json:"bot,omitempty"`
+}
+
+// GitNetworkSettings overrides the timeouts and retry policy for network git
+// commands (fetch, push, pull, clone, ls-remote) run against a repository
+// @Description Per-repository git network settings. Omitted or zero fields inherit the global defaults.
+type GitNetworkSettings struct {
+	// Timeout for fetch and pull
+	FetchTimeoutSeconds int `json:"fetch_timeout_seconds,omitempty" example:"120"`
+	// Timeout for push
+	PushTimeoutSeconds int `json:"push_timeout_seconds,omitempty" example:"120"`
+	// Timeout for clone
+	CloneTimeoutSeconds int `json:"clone_timeout_seconds,omitempty" example:"600"`
+	// Timeout for ls-remote
+	LsRemoteTimeoutSeconds int `json:"ls_remote_timeout_seconds,omitempty" example:"30"`
+	// Retries after a transient network error (0 disables retries)
+	MaxRetries *int `json:"max_retries,omitempty" example:"2"`
+}
+
+// Validate checks that timeouts and retries are within sane bounds
+func (n *GitNetworkSettings) Validate() error {
+	for name, value := range map[string]int{
+		"fetch_timeout_seconds":     n.FetchTimeoutSeconds,
+		"push_timeout_seconds":      n.PushTimeoutSeconds,
+		"clone_timeout_seconds":     n.CloneTimeoutSeconds,
+		"ls_remote_timeout_seconds": n.LsRemoteTimeoutSeconds,
+	} {
+		if value < 0 || value > 3600 {
+			return fmt.Errorf("%s must be between 0 and 3600", name)
+		}
+	}
+	if n.MaxRetries != nil && (*n.MaxRetries < 0 || *n.MaxRetries > 10) {
+		return fmt.Errorf("max_retries must be between 0 and 10")
+	}
+	return nil
+}
+
+// FetchMode selects how a repository's branches are fetched
+type FetchMode string
+
+const (
+	// FetchModeAuto picks depth and filters from past fetch durations and the repository's size
+	FetchModeAuto FetchMode = "auto"
+	// FetchModeShallow always fetches status refreshes with a limited depth
+	FetchModeShallow FetchMode = "shallow"
+	// FetchModeFull always fetches complete history
+	FetchModeFull FetchMode = "full"
+)
+
+// MaxFetchDepth bounds the depth of shallow fetches
+const MaxFetchDepth = 10000
+
+// FetchSettings overrides how catnip fetches a repository's branches
+// @Description Per-repository fetch settings. Omitted fields use the adaptive defaults.
+type FetchSettings struct {
+	// auto (default), shallow or full
+	Mode FetchMode `json:"mode,omitempty" example:"auto" enums:"auto,shallow,full"`
+	// Depth of shallow fetches (default 1)
+	Depth int `json:"depth,omitempty" example:"1"`
+	// Whether shallow history is deepened in the background after a shallow
+	// fetch (default true in auto mode)
+	BackgroundDeepen *bool `json:"background_deepen,omitempty" example:"true"`
+}
+
+// Validate checks the mode and depth
+func (f *FetchSettings) Validate() error {
+	switch f.Mode {
+	case "", FetchModeAuto, FetchModeShallow, FetchModeFull:
+	default:
+		return fmt.Errorf("mode must be auto, shallow or full")
+	}
+	if f.Depth < 0 || f.Depth > MaxFetchDepth {
+		return fmt.Errorf("depth must be between 0 and %d", MaxFetchDepth)
+	}
+	return nil
+}
+
+// FetchTelemetry describes a repository's recent fetches and the strategy
+// the adaptive fetcher picks from them
+type FetchTelemetry struct {
+	// Fetches measured since the server started
+	Samples int `json:"samples" example:"12"`
+	// Moving average of fetch durations
+	AverageMs int64 `json:"average_ms" example:"850"`
+	// Duration of the last fetch
+	LastMs int64 `json:"last_ms" example:"640"`
+	// When the last fetch finished
+	LastFetchAt *time.Time `json:"last_fetch_at,omitempty"`
+	// Strategy of the last fetch, e.g. "depth=1 filter=blob:none"
+	LastStrategy string `json:"last_strategy,omitempty" example:"depth=1"`
+	// Strategy the next status refresh would use
+	NextStatusStrategy string `json:"next_status_strategy,omitempty" example:"depth=1"`
+	// Strategy the next sync or push fetch would use
+	NextFullStrategy string `json:"next_full_strategy,omitempty" example:"full"`
+	// Error of the last fetch, if it failed
+	LastError string `json:"last_error,omitempty"`
+	// Failed fetches since the server started
+	Failures int `json:"failures" example:"0"`
+	// Size of the repository's object store
+	RepoSizeBytes int64 `json:"repo_size_bytes" example:"104857600"`
+	// Whether the repository has shallow history
+	Shallow bool `json:"shallow" example:"true"`
+	// Whether the repository is a partial (blobless) clone
+	PartialClone bool `json:"partial_clone" example:"false"`
+	// Whether history is being deepened in the background
+	Deepening bool `json:"deepening" example:"false"`
+	// When history was last deepened in the background
+	LastDeepenedAt *time.Time `json:"last_deepened_at,omitempty"`
+}
+
+// BranchNamingPolicy restricts the names catnip branches are renamed to and
+// users may give branches in a repository
+// @Description Per-repository branch naming rules. Empty fields don't restrict names.
+type BranchNamingPolicy struct {
+	// Names must start with one of these prefixes
+	Prefixes []string `json:"prefixes,omitempty" example:"feature/,fix/,chore/"`
+	// Names must match this regular expression
+	Pattern string `json:"pattern,omitempty" example:"^[a-z]+/[a-z0-9-]+$"`
+	// Longest name allowed
+	MaxLength int `json:"max_length,omitempty" example:"60"`
+}
+
+// Validate checks that the pattern compiles and the limits are sane
+func (p *BranchNamingPolicy) Validate() error {
+	if p.Pattern != "" {
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("pattern is not a valid regular expression: %v", err)
+		}
+	}
+	if p.MaxLength < 0 || p.MaxLength > 255 {
+		return fmt.Errorf("max_length must be between 0 and 255")
+	}
+	for _, prefix := range p.Prefixes {
+		if strings.TrimSpace(prefix) == "" {
+			return fmt.Errorf("prefixes must not be empty")
+		}
+		if p.MaxLength > 0 && len(prefix) >= p.MaxLength {
+			return fmt.Errorf("prefix %q leaves no room within max_length %d", prefix, p.MaxLength)
+		}
+	}
+	return nil
+}
+
+// CheckpointSettings tunes the checkpoint commits catnip makes while an agent
+// works in a worktree
+// @Description Per-repository or per-worktree checkpoint settings. Omitted or zero fields inherit the repository's settings, then the defaults.
+type CheckpointSettings struct {
+	// Turn automatic checkpoints off, or back on for a worktree of a repository that disabled them
+	Disabled *bool `json:"disabled,omitempty" example:"false"`
+	// Minimum time between checkpoints
+	IntervalSeconds int `json:"interval_seconds,omitempty" example:"300"`
+	// Skip checkpoints while more files than this are changed (0 for no limit)
+	MaxDirtyFiles int `json:"max_dirty_files,omitempty" example:"500"`
+	// Commit message; {title} is replaced by the session title and {n} by the checkpoint number
+	MessageTemplate string `json:"message_template,omitempty" example:"wip: {title} ({n})"`
+}
+
+// Validate checks that the interval and limits are sane
+func (s *CheckpointSettings) Validate() error {
+	if s.IntervalSeconds != 0 && (s.IntervalSeconds < 10 || s.IntervalSeconds > 86400) {
+		return fmt.Errorf("interval_seconds must be between 10 and 86400")
+	}
+	if s.MaxDirtyFiles < 0 {
+		return fmt.Errorf("max_dirty_files must not be negative")
+	}
+	if s.MessageTemplate != "" {
+		if strings.TrimSpace(s.MessageTemplate) == "" {
+			return fmt.Errorf("message_template must not be blank")
+		}
+		if strings.ContainsAny(s.MessageTemplate, "\r\n") {
+			return fmt.Errorf("message_template must be a single line")
+		}
+	}
+	return nil
 }
 
 // Worktree represents a Git worktree
@@ -76,8 +320,14 @@ type Worktree struct {
 	Branch string `json:"branch" example:"feature/api-docs"`
 	// Branch this worktree was originally created from
 	SourceBranch string `json:"source_branch" example:"main"`
+	// Tag or commit this worktree was created from, when not created from a branch
+	SourceRef string `json:"source_ref,omitempty" example:"v1.2.0"`
+	// Kind of SourceRef: "tag", "commit" or "pull_request"
+	SourceRefType SourceRefType `json:"source_ref_type,omitempty" example:"tag"`
 	// Whether this worktree's branch has been renamed from its original catnip ref
 	HasBeenRenamed bool `json:"has_been_renamed" example:"true"`
+	// Existing branch the worktree was attached to on import; it's kept when the worktree is deleted
+	ImportedBranch string `json:"imported_branch,omitempty" example:"feature/api-docs"`
 	// Commit hash where this worktree diverged from source branch (updated after merges)
 	CommitHash string `json:"commit_hash" example:"abc123def456"`
 	// Number of commits ahead of the divergence point (CommitHash)
@@ -110,6 +360,8 @@ type Worktree struct {
 	PullRequestState string `json:"pull_request_state,omitempty" example:"open"`
 	// Last time the PR state was synced
 	PullRequestLastSynced *time.Time `json:"pull_request_last_synced,omitempty"`
+	// Reviews, unresolved comments and CI checks of the associated pull request
+	PullRequestFeedback *PullRequestFeedback `json:"pull_request_feedback,omitempty"`
 	// Whether the branch has commits ahead of the remote branch (calculated on list)
 	HasCommitsAheadOfRemote bool `json:"has_commits_ahead_of_remote"`
 	// Current todos from the most recent TodoWrite in Claude session
@@ -122,6 +374,258 @@ type Worktree struct {
 	LatestClaudeMessage string `json:"latest_claude_message,omitempty"`
 	// Type of the latest Claude message ("assistant" or "user")
 	LatestClaudeMessageType string `json:"latest_claude_message_type,omitempty"`
+	// Commit author identities for this worktree (overrides the repository's)
+	Identity *GitIdentitySettings `json:"identity,omitempty"`
+	// Overrides for automatic checkpoint commits (applied over the repository's)
+	Checkpoints *CheckpointSettings `json:"checkpoints,omitempty"`
+	// Whether the worktree was created from a local mirror because the network was unavailable
+	CreatedFromMirror bool `json:"created_from_mirror,omitempty" example:"false"`
+	// When the mirror used to create the worktree was last synced; the source branch may be older than upstream
+	MirrorSyncedAt *time.Time `json:"mirror_synced_at,omitempty"`
+	// Setup-relevant files (setup.sh, manifests, lockfiles) changed by a sync since setup.sh last ran
+	SetupChangedFiles []string `json:"setup_changed_files,omitempty"`
+	// Breakdown of uncommitted changes, stashes and remote divergence (nil until computed)
+	StatusDetail *WorktreeStatusDetail `json:"status_detail,omitempty"`
+	// GitHub issue this worktree is working on (context for Claude, referenced by PRs)
+	LinkedIssue *LinkedIssue `json:"linked_issue,omitempty"`
+	// Pull request this worktree was checked out from, kept in sync with pushes to it
+	SourcePullRequest *SourcePullRequest `json:"source_pull_request,omitempty"`
+	// Whether terminals get the user's SSH agent (nil uses the server default)
+	SSHAgentForwarding *bool `json:"ssh_agent_forwarding,omitempty"`
+	// Whether running dev servers are advertised on the LAN via mDNS (nil uses the server default)
+	MDNS *bool `json:"mdns,omitempty"`
+	// State before the most recent sync, kept while the sync can still be undone
+	LastSync *SyncSnapshot `json:"last_sync,omitempty"`
+	// User-assigned labels (lowercase, sorted) used for filtering and bulk selectors
+	Labels []string `json:"labels,omitempty" example:"experiment,hotfix"`
+	// Facts, decisions and conventions remembered across Claude sessions in this worktree
+	Memory []WorktreeMemory `json:"memory,omitempty"`
+	// Advisory locks on paths a person or automation is working on
+	Locks []WorktreeLock `json:"locks,omitempty"`
+	// Ephemeral preview environment published by the repository's deploy hook
+	Preview *PreviewDeployment `json:"preview,omitempty"`
+	// Claude tools catnip-launched sessions may use (nil uses the repository's .catnip.yaml)
+	ClaudeTools *ClaudeToolPolicy `json:"claude_tools,omitempty"`
+	// Set while the worktree is hibernated: its files are removed until it is rehydrated
+	Hibernation *WorktreeHibernation `json:"hibernation,omitempty"`
+	// Set while an overlay sandbox is mounted over the worktree: changes land in the sandbox until promoted or discarded
+	Sandbox *WorktreeSandbox `json:"sandbox,omitempty"`
+}
+
+// WorktreeSandbox records the overlay layer a sandboxed worktree writes to
+type WorktreeSandbox struct {
+	StartedAt time.Time `json:"started_at"`
+	// Directory holding the overlay's upper and work directories
+	Dir string `json:"dir" example:"/volume/sandboxes/abc123"`
+	// Branch or catnip ref checked out when the sandbox started ("" if HEAD was detached)
+	Ref string `json:"ref,omitempty" example:"refs/catnip/felix"`
+	// Commit checked out when the sandbox started, restored on discard
+	Head string `json:"head" example:"abc123def456"`
+}
+
+// WorktreeHibernation records what a hibernated worktree is restored from
+type WorktreeHibernation struct {
+	HibernatedAt time.Time `json:"hibernated_at"`
+	// Branch or catnip ref that was checked out ("" if HEAD was detached)
+	Ref string `json:"ref,omitempty" example:"refs/catnip/felix"`
+	// Commit that was checked out, used if Ref no longer exists
+	Head string `json:"head" example:"abc123def456"`
+	// Stash commit holding uncommitted changes, including untracked files
+	Stash string `json:"stash,omitempty" example:"789abc012def"`
+	// Archive of the ignored files (.env, local config, build caches), which
+	// a stash doesn't hold
+	IgnoredArchive string `json:"ignored_archive,omitempty" example:"/volume/hibernate/wt-felix.tar.gz"`
+	// Disk space freed by removing the working tree
+	ReclaimedBytes int64 `json:"reclaimed_bytes" example:"524288000"`
+}
+
+// ClaudeToolPolicy limits the tools Claude may use in a worktree
+type ClaudeToolPolicy struct {
+	// Tools Claude may use; any other built-in tool is disallowed
+	Allowed []string `json:"allowed" example:"Read,Glob,Grep,Edit,Write"`
+}
+
+// Preview deployment states
+const (
+	PreviewDeploying   = "deploying"
+	PreviewDeployed    = "deployed"
+	PreviewFailed      = "failed"
+	PreviewTearingDown = "tearing_down"
+)
+
+// PreviewDeployment is a worktree's preview environment
+// @Description Preview environment published for a worktree by the deploy hook in .catnip.yaml
+type PreviewDeployment struct {
+	// deploying, deployed, failed or tearing_down
+	State string `json:"state" example:"deployed" enums:"deploying,deployed,failed,tearing_down"`
+	// Where the preview is served, as reported by the deploy hook
+	URL string `json:"url,omitempty" example:"https://feature-api-docs.fly.dev"`
+	// Local port that was ready when the deploy started
+	Port int `json:"port,omitempty" example:"3000"`
+	// Commit that was deployed
+	Commit string `json:"commit,omitempty" example:"abc123def456"`
+	// When the current deploy or teardown started
+	StartedAt time.Time `json:"started_at" example:"2024-01-15T14:30:00Z"`
+	// When the preview went live
+	DeployedAt *time.Time `json:"deployed_at,omitempty"`
+	// Why the last deploy or teardown failed, with the end of its output
+	Error string `json:"error,omitempty"`
+}
+
+// WorktreeMemory is something Claude or the user asked to remember in a worktree
+// @Description Entry in a worktree's memory, given to every new Claude session there
+type WorktreeMemory struct {
+	// Memory ID
+	ID string `json:"id" example:"6f1c2b9e-3a8d-4c7f-9e21-5b0a4d3c2e1f"`
+	// Kind of memory: fact, decision or convention
+	Kind string `json:"kind" example:"convention" enums:"fact,decision,convention"`
+	// What to remember
+	Text string `json:"text" example:"Use pnpm, not npm"`
+	// Who added it: claude or user
+	Source string `json:"source" example:"claude" enums:"claude,user"`
+	// When it was added or last restated
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorktreeLock is an advisory lock on paths in a worktree
+// @Description Paths someone is working on. Claude sessions in the worktree are told about them and warned or blocked when they modify them.
+type WorktreeLock struct {
+	// Lock ID
+	ID string `json:"id" example:"0b6e2f4a-9c1d-4e8b-a7f3-2d5c8e1b9a40"`
+	// Globs relative to the worktree root; "**" matches any number of directories
+	Paths []string `json:"paths" example:"src/auth/**,go.mod"`
+	// Why the paths are locked, shown to Claude
+	Reason string `json:"reason" example:"Refactoring the auth middleware by hand"`
+	// Who holds the lock
+	Owner     string    `json:"owner,omitempty" example:"alice"`
+	CreatedAt time.Time `json:"created_at"`
+	// When the lock lapses (never when unset)
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// SyncSnapshot records a worktree's state before a merge/rebase sync
+// @Description Pre-sync HEAD, reflog position and auto-stash used to undo a sync
+type SyncSnapshot struct {
+	// Sync strategy (merge or rebase)
+	Strategy string `json:"strategy" example:"rebase"`
+	// Ref the worktree was synced with
+	SourceRef string `json:"source_ref" example:"origin/main"`
+	// HEAD before the sync
+	PreHead string `json:"pre_head" example:"abc123def456"`
+	// HEAD after a successful sync (empty when the sync stopped on an error or conflict)
+	PostHead string `json:"post_head,omitempty" example:"789abc012def"`
+	// Number of HEAD reflog entries before the sync; HEAD@{n} for the pre-sync entry is derived from it
+	ReflogPosition int `json:"reflog_position" example:"12"`
+	// Stash commit holding uncommitted changes set aside for the sync
+	AutoStash string `json:"auto_stash,omitempty"`
+	// Whether the auto-stash was re-applied after the sync
+	AutoStashRestored bool `json:"auto_stash_restored"`
+	// When the sync ran
+	SyncedAt time.Time `json:"synced_at" example:"2024-01-15T14:30:00Z"`
+	// When the sync can no longer be undone
+	UndoExpiresAt time.Time `json:"undo_expires_at" example:"2024-01-15T15:30:00Z"`
+}
+
+// SyncUndoResult describes a rolled back sync
+// @Description Result of undoing a worktree sync
+type SyncUndoResult struct {
+	// The sync that was undone
+	Sync SyncSnapshot `json:"sync"`
+	// Reflog entry the worktree was reset to
+	ReflogEntry string `json:"reflog_entry,omitempty" example:"HEAD@{2}"`
+	// Whether uncommitted changes from before the sync were restored
+	RestoredAutoStash bool `json:"restored_auto_stash"`
+	// Stash commit holding changes made after the sync, set aside before the reset
+	BackupStash string `json:"backup_stash,omitempty"`
+}
+
+// LinkedIssue is a GitHub issue linked to a worktree, as fetched with gh
+// @Description GitHub issue linked to a worktree with its body and most recent comments
+type LinkedIssue struct {
+	// Issue number
+	Number int `json:"number" example:"42"`
+	// Repository the issue belongs to (owner/repo)
+	Repository string `json:"repository" example:"wandb/catnip"`
+	// Issue title
+	Title string `json:"title" example:"Terminal loses focus after reconnect"`
+	// Issue body (markdown)
+	Body string `json:"body"`
+	// Issue state (OPEN, CLOSED)
+	State string `json:"state" example:"OPEN"`
+	// URL of the issue
+	URL string `json:"url" example:"https://github.com/wandb/catnip/issues/42"`
+	// Most recent comments, oldest first
+	Comments []IssueComment `json:"comments,omitempty"`
+	// When the issue was fetched
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// SourcePullRequest is a pull request a worktree was checked out from
+// @Description Pull request head a worktree tracks, and the result of the last check for new pushes
+type SourcePullRequest struct {
+	// Pull request number
+	Number int `json:"number" example:"123"`
+	// Repository the pull request was opened against (owner/repo)
+	Repository string `json:"repository" example:"wandb/catnip"`
+	// URL of the pull request
+	URL string `json:"url" example:"https://github.com/wandb/catnip/pull/123"`
+	// Pull request title
+	Title string `json:"title" example:"Add retries to the uploader"`
+	// Login of the pull request author
+	Author string `json:"author" example:"octocat"`
+	// Branch the pull request merges into
+	BaseBranch string `json:"base_branch" example:"main"`
+	// Branch the pull request's commits are pushed to
+	HeadBranch string `json:"head_branch" example:"retries"`
+	// Repository the head branch lives in (owner/repo); differs from Repository for forks
+	HeadRepository string `json:"head_repository" example:"octocat/catnip"`
+	// Whether the head branch is in a fork
+	IsFork bool `json:"is_fork" example:"true"`
+	// Head commit the worktree was last synced to
+	HeadSHA string `json:"head_sha" example:"abc123def4567890abc123def4567890abc123de"`
+	// When the worktree was last checked for new pushes
+	SyncedAt time.Time `json:"synced_at"`
+	// Result of the last check: "updated", "up_to_date", "dirty" (uncommitted changes kept it back) or "diverged" (local commits)
+	SyncStatus string `json:"sync_status" example:"up_to_date"`
+}
+
+// IssueComment is a comment on a GitHub issue
+type IssueComment struct {
+	// Login of the comment author
+	Author string `json:"author" example:"octocat"`
+	// Comment body (markdown)
+	Body string `json:"body"`
+	// When the comment was posted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorktreeStatusDetail breaks a worktree's git status down for the UI
+// @Description Granular worktree status: file counts by state, stash entries and ahead/behind per remote
+type WorktreeStatusDetail struct {
+	// Files with staged changes
+	StagedCount int `json:"staged_count" example:"2"`
+	// Tracked files with unstaged changes
+	ModifiedCount int `json:"modified_count" example:"3"`
+	// Untracked files (respecting .gitignore)
+	UntrackedCount int `json:"untracked_count" example:"1"`
+	// Files with unresolved merge conflicts
+	ConflictedCount int `json:"conflicted_count" example:"0"`
+	// Stash entries (stashes are shared by all worktrees of a repository)
+	StashCount int `json:"stash_count" example:"1"`
+	// Ahead/behind counts for each remote that has a matching branch
+	Remotes []RemoteAheadBehind `json:"remotes,omitempty"`
+}
+
+// RemoteAheadBehind is how far HEAD has diverged from a remote-tracking branch
+type RemoteAheadBehind struct {
+	// Remote name (origin or upstream)
+	Remote string `json:"remote" example:"origin"`
+	// Remote-tracking ref compared against
+	Ref string `json:"ref" example:"origin/feature/login"`
+	// Commits on HEAD that the remote doesn't have
+	Ahead int `json:"ahead" example:"2"`
+	// Commits on the remote that HEAD doesn't have
+	Behind int `json:"behind" example:"0"`
 }
 
 // WorktreeCreateRequest represents a request to create a new worktree
@@ -163,6 +667,8 @@ type PullRequestResponse struct {
 	BaseBranch string `json:"base_branch" example:"main"`
 	// Repository in owner/repo format
 	Repository string `json:"repository" example:"owner/repo"`
+	// Code owners review was requested from
+	RequestedReviewers []string `json:"requested_reviewers,omitempty" example:"octocat,acme/platform"`
 }
 
 // PullRequestInfo represents information about an existing pull request
@@ -180,6 +686,22 @@ type PullRequestInfo struct {
 	Number int `json:"number,omitempty" example:"123"`
 	// URL to the pull request (if exists)
 	URL string `json:"url,omitempty" example:"https://github.com/owner/repo/pull/123"`
+	// Code owners review will be requested from when the pull request is
+	// created (if it doesn't exist yet)
+	CodeOwners *CodeOwnerReviewers `json:"code_owners,omitempty"`
+}
+
+// CodeOwnerReviewers are the CODEOWNERS of a branch's changed files
+type CodeOwnerReviewers struct {
+	// Whether reviews are requested from them when a pull request is opened
+	Enabled bool `json:"enabled" example:"true"`
+	// CODEOWNERS file the owners come from, empty if the repository has none
+	File string `json:"file,omitempty" example:".github/CODEOWNERS"`
+	// Users and org/team slugs to request reviews from. The pull request's
+	// author is left out when the request is made.
+	Reviewers []string `json:"reviewers" example:"octocat,acme/platform"`
+	// Owners given by email, whom GitHub can't request reviews from
+	Unrequestable []string `json:"unrequestable,omitempty" example:"jane@example.com"`
 }
 
 // PullRequestState represents the cached state of a pull request
@@ -199,6 +721,50 @@ type PullRequestState struct {
 	LastSynced time.Time `json:"last_synced" example:"2024-01-15T16:45:30Z"`
 	// List of worktree IDs that reference this PR
 	WorktreeIDs []string `json:"worktree_ids" example:"[\"abc123-def456\", \"ghi789-jkl012\"]"`
+	// Reviews, unresolved comments and CI checks (nil until synced)
+	Feedback *PullRequestFeedback `json:"feedback,omitempty"`
+}
+
+// PullRequestFeedback is what reviewers and CI have said about a pull request
+// @Description Review decision, requested changes, unresolved review comments and CI check status of a pull request
+type PullRequestFeedback struct {
+	// GitHub's review decision (APPROVED, CHANGES_REQUESTED, REVIEW_REQUIRED), empty when no review is required
+	ReviewDecision string `json:"review_decision,omitempty" example:"CHANGES_REQUESTED"`
+	// Reviewers whose latest review requests changes
+	ChangesRequestedBy []string `json:"changes_requested_by,omitempty" example:"octocat,hubot"`
+	// Reviewers whose latest review approves
+	ApprovedBy []string `json:"approved_by,omitempty" example:"monalisa"`
+	// Number of review threads not yet resolved
+	UnresolvedThreads int `json:"unresolved_threads" example:"3"`
+	// First comment of each unresolved review thread, oldest first
+	Comments []PullRequestComment `json:"comments,omitempty"`
+	// CI check status of the head commit
+	Checks PullRequestChecks `json:"checks"`
+	// One-line summary for lists, e.g. "2 requested changes, CI failing"
+	Summary string `json:"summary" example:"2 requested changes, 3 unresolved comments, CI failing"`
+}
+
+// PullRequestComment is a review comment on a pull request
+type PullRequestComment struct {
+	Author string `json:"author" example:"octocat"`
+	Body   string `json:"body" example:"This should handle the empty case"`
+	// File and line the comment is on
+	Path      string    `json:"path,omitempty" example:"src/api.go"`
+	Line      int       `json:"line,omitempty" example:"42"`
+	URL       string    `json:"url" example:"https://github.com/owner/repo/pull/123#discussion_r1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T16:45:30Z"`
+}
+
+// PullRequestChecks summarizes the CI checks of a pull request's head commit
+type PullRequestChecks struct {
+	// Overall state (success, failure, pending), empty when there are no checks
+	State   string `json:"state,omitempty" example:"failure"`
+	Total   int    `json:"total" example:"5"`
+	Passed  int    `json:"passed" example:"3"`
+	Failed  int    `json:"failed" example:"1"`
+	Pending int    `json:"pending" example:"1"`
+	// Names of the failed checks
+	Failing []string `json:"failing,omitempty" example:"test (ubuntu-latest)"`
 }
 
 // GitState represents the persisted state of repositories and worktrees
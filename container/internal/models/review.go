@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DefaultReviewer is used when a review request doesn't name a reviewer
+const DefaultReviewer = "default"
+
+// FileReviewMark records that a reviewer has seen a file's current contents
+type FileReviewMark struct {
+	// Blob hash of the file when it was reviewed; the mark lapses once the file changes
+	BlobHash   string    `json:"blob_hash" example:"3b18e512dba79e4c8300dd08aeb37f8e728b8dad"`
+	ReviewedAt time.Time `json:"reviewed_at"`
+}
+
+// ReviewProgress summarizes a reviewer's progress through a worktree diff
+// @Description How many changed files a reviewer has marked as reviewed
+type ReviewProgress struct {
+	Reviewer string `json:"reviewer" example:"default"`
+	// Changed files marked reviewed at their current contents
+	Reviewed int `json:"reviewed" example:"3"`
+	// Changed files in the diff
+	Total int `json:"total" example:"10"`
+	// Files whose review lapsed because they changed after being marked
+	Stale int `json:"stale" example:"1"`
+}
+
+// UpdateFileReviewRequest marks files in a worktree diff as reviewed or unreviewed
+// @Description Request to mark or unmark files as reviewed
+type UpdateFileReviewRequest struct {
+	// Paths as they appear in the diff's file_path
+	Files []string `json:"files" example:"[\"src/main.go\"]"`
+	// true to mark reviewed, false to clear the marks
+	Reviewed bool `json:"reviewed" example:"true"`
+	// Reviewer name (defaults to "default")
+	Reviewer string `json:"reviewer,omitempty" example:"alice"`
+	// Count files hidden by diff ignore rules in the returned progress
+	IncludeIgnored bool `json:"include_ignored,omitempty" example:"false"`
+}
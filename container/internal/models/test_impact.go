@@ -0,0 +1,30 @@
+package models
+
+// TestImpact maps a worktree's changed files to the tests likely affected
+// @Description Tests likely affected by a worktree's changes, for running the impacted subset first
+type TestImpact struct {
+	// Changed files the analysis started from
+	ChangedFiles []string `json:"changed_files" example:"internal/services/git.go"`
+	// Test files likely affected, nearest to the changes first
+	Tests []ImpactedTest `json:"tests"`
+	// Go packages containing impacted tests, as ./-relative patterns from the worktree root
+	GoPackages []string `json:"go_packages,omitempty" example:"./internal/services"`
+	// Commands running only the impacted tests
+	Commands []string `json:"commands,omitempty" example:"go test ./internal/services ./internal/handlers"`
+	// Changed source files no test could be traced to; run the full suite to cover them
+	Unmapped []string `json:"unmapped,omitempty" example:"scripts/release.sh"`
+	// Whether the worktree had more files than were analyzed
+	Partial bool `json:"partial,omitempty" example:"false"`
+}
+
+// ImpactedTest is a test file likely affected by a change
+// @Description A test file and why it is affected
+type ImpactedTest struct {
+	Path string `json:"path" example:"internal/handlers/git_test.go"`
+	// "go" or "javascript"
+	Language string `json:"language" example:"go"`
+	// Import steps between the test and the nearest changed file; 0 when the test itself changed
+	Distance int `json:"distance" example:"1"`
+	// Why the test is affected
+	Reason string `json:"reason" example:"imports internal/services"`
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Thresholds the adaptive fetcher picks strategies by
+const (
+	// Status refreshes of repositories that fetch faster than this skip --depth
+	fastFetchThreshold = 2 * time.Second
+	// Repositories that fetch slower than this, or are larger than
+	// largeRepoBytes, get the leanest fetches
+	slowFetchThreshold = 10 * time.Second
+	largeRepoBytes     = 1 << 30
+	// Weight of the newest sample in the moving average of fetch durations
+	fetchDurationWeight = 0.3
+	// How often a repository's size and shallowness are re-measured
+	repoSizeRefreshPeriod = 10 * time.Minute
+	// How often shallow history is deepened in the background, and by how
+	// many commits on slow links (fast links fetch the rest at once)
+	backgroundDeepenInterval = time.Hour
+	backgroundDeepenCommits  = 200
+)
+
+// fetchPlan is how one fetch is run
+type fetchPlan struct {
+	depth  int    // 0 fetches without --depth
+	filter string // Partial clone filter, only for repositories that are partial clones
+	deepen bool   // Deepen shallow history in the background afterwards
+}
+
+// String describes the plan in telemetry
+func (p fetchPlan) String() string {
+	parts := []string{"full"}
+	if p.depth > 0 {
+		parts = []string{fmt.Sprintf("depth=%d", p.depth)}
+	}
+	if p.filter != "" {
+		parts = append(parts, "filter="+p.filter)
+	}
+	return strings.Join(parts, " ")
+}
+
+// repoFetchStats is what the adaptive fetcher knows about a repository
+type repoFetchStats struct {
+	telemetry     models.FetchTelemetry
+	sizeCheckedAt time.Time
+}
+
+// planFetch picks how to fetch a repository from its settings and fetch
+// history. Status refreshes (status true) favor speed; other fetches need
+// the branch's recent history in full.
+func planFetch(settings *models.FetchSettings, stats models.FetchTelemetry, status bool) fetchPlan {
+	var plan fetchPlan
+	mode := models.FetchModeAuto
+	depth := 1
+	if settings != nil {
+		if settings.Mode != "" {
+			mode = settings.Mode
+		}
+		if settings.Depth > 0 {
+			depth = settings.Depth
+		}
+	}
+	average := time.Duration(stats.AverageMs) * time.Millisecond
+	slow := average >= slowFetchThreshold || stats.RepoSizeBytes >= largeRepoBytes
+	if stats.PartialClone && (slow || mode == models.FetchModeShallow) {
+		plan.filter = "blob:none"
+	}
+
+	switch {
+	case !status || mode == models.FetchModeFull:
+		return plan
+	case mode == models.FetchModeAuto && stats.Samples > 0 && average < fastFetchThreshold && !slow:
+		// Fast link and a modest repository: fetching everything new costs
+		// little and keeps later syncs from having to deepen
+		return plan
+	}
+
+	plan.depth = depth
+	plan.deepen = mode == models.FetchModeAuto
+	if settings != nil && settings.BackgroundDeepen != nil {
+		plan.deepen = *settings.BackgroundDeepen
+	}
+	return plan
+}
+
+// adaptiveFetch fetches a worktree's source branch with the strategy its
+// repository's fetch history calls for, recording how long it took
+func (s *GitService) adaptiveFetch(worktree *models.Worktree, status bool) error {
+	var settings *models.FetchSettings
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists {
+		settings = repo.Fetch
+	}
+	stats := s.measureRepository(worktree.RepoID, worktree.Path)
+	plan := planFetch(settings, stats, status)
+
+	start := time.Now()
+	var err error
+	switch plan {
+	case fetchPlan{depth: 1}, fetchPlan{depth: 1, deepen: true}:
+		err = s.operations.FetchBranchFast(worktree.Path, worktree.SourceBranch)
+	case fetchPlan{}:
+		err = s.operations.FetchBranchFull(worktree.Path, worktree.SourceBranch)
+	default:
+		err = s.fetchBranch(worktree.Path, git.FetchStrategy{
+			Branch:         worktree.SourceBranch,
+			Depth:          plan.depth,
+			Filter:         plan.filter,
+			Lean:           true,
+			UpdateLocalRef: status,
+		})
+	}
+	s.recordFetch(worktree.RepoID, plan, time.Since(start), err)
+
+	if err == nil && plan.deepen && stats.Shallow {
+		s.scheduleDeepen(worktree)
+	}
+	return err
+}
+
+// measureRepository returns a repository's fetch stats, re-measuring its
+// size and shallowness when they are stale
+func (s *GitService) measureRepository(repoID, path string) models.FetchTelemetry {
+	s.fetchStatsMu.Lock()
+	stats := s.fetchStatsFor(repoID)
+	stale := time.Since(stats.sizeCheckedAt) > repoSizeRefreshPeriod
+	if stale {
+		// Claim the refresh so concurrent fetches don't measure too
+		stats.sizeCheckedAt = time.Now()
+	}
+	s.fetchStatsMu.Unlock()
+
+	if stale {
+		size, shallow, partial := s.probeRepository(path)
+		s.fetchStatsMu.Lock()
+		stats.telemetry.RepoSizeBytes = size
+		stats.telemetry.Shallow = shallow
+		stats.telemetry.PartialClone = partial
+		s.fetchStatsMu.Unlock()
+	}
+
+	s.fetchStatsMu.Lock()
+	defer s.fetchStatsMu.Unlock()
+	return stats.telemetry
+}
+
+// probeRepository reads the object store size, whether history is shallow
+// and whether the repository is a partial clone
+func (s *GitService) probeRepository(path string) (int64, bool, bool) {
+	var size int64
+	if output, err := s.operations.ExecuteGit(path, "count-objects", "-v"); err == nil {
+		for _, line := range strings.Split(string(output), "\n") {
+			key, value, found := strings.Cut(line, ":")
+			if !found || (key != "size" && key != "size-pack") {
+				continue
+			}
+			if kib, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+				size += kib * 1024
+			}
+		}
+	}
+
+	shallow := false
+	if output, err := s.operations.ExecuteGit(path, "rev-parse", "--is-shallow-repository"); err == nil {
+		shallow = strings.TrimSpace(string(output)) == "true"
+	}
+	partial := false
+	if output, err := s.operations.ExecuteGit(path, "config", "--get", "extensions.partialClone"); err == nil {
+		partial = strings.TrimSpace(string(output)) != ""
+	}
+	return size, shallow, partial
+}
+
+// recordFetch adds a fetch's duration to the repository's moving average
+func (s *GitService) recordFetch(repoID string, plan fetchPlan, duration time.Duration, err error) {
+	s.fetchStatsMu.Lock()
+	defer s.fetchStatsMu.Unlock()
+
+	telemetry := &s.fetchStatsFor(repoID).telemetry
+	now := time.Now()
+	telemetry.LastFetchAt = &now
+	telemetry.LastStrategy = plan.String()
+	if err != nil {
+		// Failures say little about the link's speed, so they don't move the average
+		telemetry.Failures++
+		telemetry.LastError = err.Error()
+		return
+	}
+	telemetry.LastError = ""
+	telemetry.LastMs = duration.Milliseconds()
+	if telemetry.Samples == 0 {
+		telemetry.AverageMs = telemetry.LastMs
+	} else {
+		telemetry.AverageMs = int64(fetchDurationWeight*float64(telemetry.LastMs) + (1-fetchDurationWeight)*float64(telemetry.AverageMs))
+	}
+	telemetry.Samples++
+}
+
+// scheduleDeepen deepens a shallow repository's history in the background,
+// at most once per backgroundDeepenInterval. Fast links fetch the rest of
+// the history; slow ones add backgroundDeepenCommits at a time.
+func (s *GitService) scheduleDeepen(worktree *models.Worktree) {
+	s.fetchStatsMu.Lock()
+	stats := s.fetchStatsFor(worktree.RepoID)
+	telemetry := &stats.telemetry
+	if telemetry.Deepening || (telemetry.LastDeepenedAt != nil && time.Since(*telemetry.LastDeepenedAt) < backgroundDeepenInterval) {
+		s.fetchStatsMu.Unlock()
+		return
+	}
+	telemetry.Deepening = true
+	slow := time.Duration(telemetry.AverageMs)*time.Millisecond >= slowFetchThreshold || telemetry.RepoSizeBytes >= largeRepoBytes
+	s.fetchStatsMu.Unlock()
+
+	go func() {
+		strategy := git.FetchStrategy{Branch: worktree.SourceBranch, Lean: true}
+		if slow {
+			strategy.Deepen = backgroundDeepenCommits
+		} else {
+			strategy.Unshallow = true
+		}
+		err := s.fetchBranch(worktree.Path, strategy)
+		if err != nil {
+			logger.Debugf("⚠️ Background deepening of %s failed: %v", worktree.RepoID, err)
+		} else {
+			logger.Debugf("📚 Deepened history of %s", worktree.RepoID)
+		}
+
+		s.fetchStatsMu.Lock()
+		now := time.Now()
+		telemetry.Deepening = false
+		telemetry.LastDeepenedAt = &now
+		// Re-measure on the next fetch
+		stats.sizeCheckedAt = time.Time{}
+		s.fetchStatsMu.Unlock()
+	}()
+}
+
+// fetchStatsFor returns a repository's stats, creating them. Callers hold fetchStatsMu.
+func (s *GitService) fetchStatsFor(repoID string) *repoFetchStats {
+	stats, exists := s.fetchStats[repoID]
+	if !exists {
+		stats = &repoFetchStats{}
+		s.fetchStats[repoID] = stats
+	}
+	return stats
+}
+
+// GetRepositoryFetchTelemetry returns a repository's fetch override (nil if it
+// uses the adaptive defaults) and its fetch telemetry
+func (s *GitService) GetRepositoryFetchTelemetry(repoID string) (*models.FetchSettings, models.FetchTelemetry, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, models.FetchTelemetry{}, models.NewRepositoryNotFoundError(repoID)
+	}
+
+	s.fetchStatsMu.Lock()
+	telemetry := s.fetchStatsFor(repoID).telemetry
+	s.fetchStatsMu.Unlock()
+
+	telemetry.NextStatusStrategy = planFetch(repo.Fetch, telemetry, true).String()
+	telemetry.NextFullStrategy = planFetch(repo.Fetch, telemetry, false).String()
+	return repo.Fetch, telemetry, nil
+}
+
+// SetRepositoryFetchSettings stores how a repository's branches are fetched.
+// A nil settings value restores the adaptive defaults.
+func (s *GitService) SetRepositoryFetchSettings(repoID string, settings *models.FetchSettings) error {
+	if settings != nil {
+		if err := settings.Validate(); err != nil {
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+		}
+	}
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.Fetch = settings
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return fmt.Errorf("failed to save repository fetch settings: %v", err)
+	}
+	return nil
+}
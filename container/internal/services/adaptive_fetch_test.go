@@ -0,0 +1,88 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestPlanFetch(t *testing.T) {
+	noDeepen := false
+	tests := []struct {
+		name     string
+		settings *models.FetchSettings
+		stats    models.FetchTelemetry
+		status   bool
+		want     fetchPlan
+	}{
+		{"first status fetch is shallow", nil, models.FetchTelemetry{}, true, fetchPlan{depth: 1, deepen: true}},
+		{"fast link fetches everything", nil, models.FetchTelemetry{Samples: 3, AverageMs: 400}, true, fetchPlan{}},
+		{"average link stays shallow", nil, models.FetchTelemetry{Samples: 3, AverageMs: 5000}, true, fetchPlan{depth: 1, deepen: true}},
+		{"large repository stays shallow", nil, models.FetchTelemetry{Samples: 3, AverageMs: 400, RepoSizeBytes: 2 << 30}, true, fetchPlan{depth: 1, deepen: true}},
+		{"slow partial clone filters blobs", nil, models.FetchTelemetry{Samples: 3, AverageMs: 20000, PartialClone: true}, true, fetchPlan{depth: 1, filter: "blob:none", deepen: true}},
+		{"full fetch of a slow partial clone", nil, models.FetchTelemetry{Samples: 3, AverageMs: 20000, PartialClone: true}, false, fetchPlan{filter: "blob:none"}},
+		{"full fetch", nil, models.FetchTelemetry{Samples: 3, AverageMs: 20000}, false, fetchPlan{}},
+		{"pinned shallow", &models.FetchSettings{Mode: models.FetchModeShallow, Depth: 20}, models.FetchTelemetry{Samples: 3, AverageMs: 400}, true, fetchPlan{depth: 20}},
+		{"pinned full", &models.FetchSettings{Mode: models.FetchModeFull}, models.FetchTelemetry{}, true, fetchPlan{}},
+		{"deepening disabled", &models.FetchSettings{BackgroundDeepen: &noDeepen}, models.FetchTelemetry{}, true, fetchPlan{depth: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, planFetch(tt.settings, tt.stats, tt.status))
+		})
+	}
+
+	assert.Equal(t, "depth=1 filter=blob:none", fetchPlan{depth: 1, filter: "blob:none"}.String())
+	assert.Equal(t, "full", fetchPlan{}.String())
+}
+
+func TestAdaptiveFetchDeepensShallowClone(t *testing.T) {
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	origin := filepath.Join(t.TempDir(), "origin")
+	require.NoError(t, os.MkdirAll(origin, 0755))
+	runTestGit(t, origin, "init", "-b", "main")
+	for _, content := range []string{"v1\n", "v2\n", "v3\n"} {
+		require.NoError(t, os.WriteFile(filepath.Join(origin, "app.txt"), []byte(content), 0644))
+		runTestGit(t, origin, "add", ".")
+		runTestGit(t, origin, "commit", "-m", "commit "+content)
+	}
+
+	clone := filepath.Join(t.TempDir(), "clone")
+	runTestGit(t, filepath.Dir(clone), "clone", "--depth", "1", "file://"+origin, clone)
+	runTestGit(t, clone, "checkout", "-b", "catnip/work")
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: clone, DefaultBranch: "main"}))
+	worktree := &models.Worktree{ID: "wt-app", RepoID: "acme/app", Name: "app/work", Path: clone, Branch: "catnip/work", SourceBranch: "main"}
+
+	require.NoError(t, service.adaptiveFetch(worktree, true))
+
+	require.Eventually(t, func() bool {
+		_, telemetry, err := service.GetRepositoryFetchTelemetry("acme/app")
+		return err == nil && telemetry.LastDeepenedAt != nil && !telemetry.Deepening
+	}, 10*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "false", runTestGit(t, clone, "rev-parse", "--is-shallow-repository"), "a fast link fetches the rest of the history")
+
+	_, telemetry, err := service.GetRepositoryFetchTelemetry("acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, 1, telemetry.Samples)
+	assert.Equal(t, "depth=1", telemetry.LastStrategy)
+	assert.True(t, telemetry.Shallow, "measured before the fetch")
+	assert.Positive(t, telemetry.RepoSizeBytes)
+
+	// Pinning full history skips --depth
+	require.NoError(t, service.SetRepositoryFetchSettings("acme/app", &models.FetchSettings{Mode: models.FetchModeFull}))
+	require.NoError(t, service.adaptiveFetch(worktree, true))
+	_, telemetry, err = service.GetRepositoryFetchTelemetry("acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, 2, telemetry.Samples)
+	assert.Equal(t, "full", telemetry.LastStrategy)
+	assert.Equal(t, "full", telemetry.NextStatusStrategy)
+
+	assert.Error(t, service.SetRepositoryFetchSettings("acme/app", &models.FetchSettings{Mode: "sometimes"}))
+}
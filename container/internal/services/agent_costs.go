@@ -0,0 +1,264 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/claude/parser"
+	"github.com/vanpelt/catnip/internal/claude/paths"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// List prices in USD per million tokens used to estimate agent cost. Session
+// files don't record which model answered, so these are Sonnet rates.
+const (
+	inputTokenPricePerMillion         = 3.00
+	outputTokenPricePerMillion        = 15.00
+	cacheReadTokenPricePerMillion     = 0.30
+	cacheCreationTokenPricePerMillion = 3.75
+)
+
+// agentCostCommentMarker identifies the cost summary comment so it can be edited in place
+const agentCostCommentMarker = "<!-- catnip:agent-cost -->"
+
+// EstimateAgentCost estimates the USD cost of the given token usage
+func EstimateAgentCost(usage models.AgentUsage) float64 {
+	return (float64(usage.InputTokens)*inputTokenPricePerMillion +
+		float64(usage.OutputTokens)*outputTokenPricePerMillion +
+		float64(usage.CacheReadTokens)*cacheReadTokenPricePerMillion +
+		float64(usage.CacheCreationTokens)*cacheCreationTokenPricePerMillion) / 1_000_000
+}
+
+// CollectAgentUsage sums token usage and time across all Claude sessions
+// recorded for a worktree
+func CollectAgentUsage(worktreePath string) (models.AgentUsage, error) {
+	projectDir, err := paths.GetProjectDir(worktreePath)
+	if err != nil {
+		return models.AgentUsage{}, err
+	}
+	return collectAgentUsageFromDir(projectDir)
+}
+
+func collectAgentUsageFromDir(projectDir string) (models.AgentUsage, error) {
+	var usage models.AgentUsage
+
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return usage, nil
+		}
+		return usage, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".jsonl") || !paths.IsValidSessionUUID(strings.TrimSuffix(name, ".jsonl")) {
+			continue
+		}
+
+		reader := parser.NewSessionFileReader(filepath.Join(projectDir, name))
+		if err := reader.ReadFull(); err != nil {
+			logger.Debugf("⚠️ Skipping unreadable session file %s: %v", name, err)
+			continue
+		}
+		stats := reader.GetStats()
+		if stats.APICallCount == 0 && stats.TotalOutputTokens == 0 {
+			continue
+		}
+
+		usage.Add(models.AgentUsage{
+			Sessions:            1,
+			InputTokens:         stats.TotalInputTokens,
+			OutputTokens:        stats.TotalOutputTokens,
+			CacheReadTokens:     stats.CacheReadTokens,
+			CacheCreationTokens: stats.CacheCreationTokens,
+			WallTimeSeconds:     stats.SessionDuration.Seconds(),
+			ActiveTimeSeconds:   stats.ActiveDuration.Seconds(),
+		})
+	}
+
+	usage.EstimatedCostUSD = EstimateAgentCost(usage)
+	return usage, nil
+}
+
+// FormatAgentCostComment renders a usage summary as a PR comment body
+func FormatAgentCostComment(usage models.AgentUsage) string {
+	var b strings.Builder
+	b.WriteString(agentCostCommentMarker + "\n")
+	b.WriteString("### 🐱 Agent cost\n\n")
+	b.WriteString("| | |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Estimated cost | $%.2f |\n", usage.EstimatedCostUSD)
+	fmt.Fprintf(&b, "| Sessions | %d |\n", usage.Sessions)
+	fmt.Fprintf(&b, "| Active time | %s |\n", formatAgentDuration(usage.ActiveTimeSeconds))
+	fmt.Fprintf(&b, "| Wall time | %s |\n", formatAgentDuration(usage.WallTimeSeconds))
+	fmt.Fprintf(&b, "| Input / output tokens | %d / %d |\n", usage.InputTokens, usage.OutputTokens)
+	fmt.Fprintf(&b, "| Cache read / write tokens | %d / %d |\n", usage.CacheReadTokens, usage.CacheCreationTokens)
+	b.WriteString("\n<sub>Estimated from Claude session logs at list token prices.</sub>\n")
+	return b.String()
+}
+
+func formatAgentDuration(seconds float64) string {
+	return (time.Duration(seconds) * time.Second).Round(time.Minute).String()
+}
+
+// AgentCostStore persists per-PR agent cost records
+type AgentCostStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewAgentCostStore creates a store backed by the given JSON file
+func NewAgentCostStore(path string) *AgentCostStore {
+	return &AgentCostStore{path: path}
+}
+
+func (s *AgentCostStore) load() ([]models.AgentCostRecord, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var records []models.AgentCostRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("corrupt agent cost records: %v", err)
+	}
+	return records, nil
+}
+
+// Record stores a usage snapshot, replacing any earlier snapshot for the same PR
+func (s *AgentCostStore) Record(record models.AgentCostRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, existing := range records {
+		if existing.RepoID == record.RepoID && existing.PRNumber == record.PRNumber && existing.PRURL == record.PRURL {
+			records[i] = record
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		records = append(records, record)
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// Report returns the PRs of a repository whose usage was last recorded in the
+// given month (YYYY-MM) in location, most expensive first
+func (s *AgentCostStore) Report(repoID, month string, location *time.Location) (*models.AgentCostReport, error) {
+	s.mu.Lock()
+	records, err := s.load()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.AgentCostReport{
+		RepoID:       repoID,
+		Month:        month,
+		TimeZone:     zoneName(location),
+		PullRequests: []models.AgentCostRecord{},
+	}
+	for _, record := range records {
+		if record.RepoID != repoID || record.RecordedAt.In(location).Format("2006-01") != month {
+			continue
+		}
+		report.PullRequests = append(report.PullRequests, record)
+		report.Total.Add(record.Usage)
+	}
+	sort.Slice(report.PullRequests, func(i, j int) bool {
+		return report.PullRequests[i].Usage.EstimatedCostUSD > report.PullRequests[j].Usage.EstimatedCostUSD
+	})
+	return report, nil
+}
+
+// attributeAgentCost records a worktree's agent usage against its PR and
+// posts (or refreshes) a cost summary comment on the PR. Set
+// CATNIP_PR_COST_COMMENT=false to record usage without commenting.
+func (s *GitService) attributeAgentCost(worktree *models.Worktree, pr *models.PullRequestResponse) {
+	if pr == nil || pr.URL == "" {
+		return
+	}
+
+	usage, err := CollectAgentUsage(worktree.Path)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to collect agent usage for %s: %v", worktree.Name, err)
+		return
+	}
+
+	record := models.AgentCostRecord{
+		RepoID:       worktree.RepoID,
+		WorktreeID:   worktree.ID,
+		WorktreeName: worktree.Name,
+		Branch:       pr.HeadBranch,
+		PRNumber:     pr.Number,
+		PRURL:        pr.URL,
+		Usage:        usage,
+		RecordedAt:   time.Now(),
+	}
+	if err := s.agentCosts.Record(record); err != nil {
+		logger.Warnf("⚠️ Failed to record agent cost for %s: %v", worktree.Name, err)
+	}
+
+	// The comment goes through gh, so GitLab and Bitbucket PRs only get the record
+	if usage.Sessions == 0 || pr.Number == 0 || pr.Repository == "" || os.Getenv("CATNIP_PR_COST_COMMENT") == "false" ||
+		!strings.HasPrefix(pr.URL, "https://github.com/") {
+		return
+	}
+	comment := FormatAgentCostComment(usage)
+	// Link back to the workspace when catnip is reachable from outside this machine
+	if config.Runtime.HasExternalURL() {
+		comment += fmt.Sprintf("\n[Open workspace in catnip](%s)\n", config.Runtime.PublicURL("/workspace/"+worktree.Name))
+	}
+	if err := s.githubManager.UpsertPullRequestComment(pr.Repository, pr.Number, agentCostCommentMarker, comment); err != nil {
+		logger.Warnf("⚠️ Failed to post agent cost comment on %s: %v", pr.URL, err)
+	}
+}
+
+// GetAgentCostReport returns the monthly agent cost report for a repository.
+// Months are bucketed in timeZone, or the instance's time zone if empty; an
+// empty month is the current one.
+func (s *GitService) GetAgentCostReport(repoID, month, timeZone string) (*models.AgentCostReport, error) {
+	location := InstanceLocation()
+	if timeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(timeZone); err != nil {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown time zone %q", timeZone)
+		}
+	}
+	if month == "" {
+		month = time.Now().In(location).Format("2006-01")
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid month %q (expected YYYY-MM)", month)
+	}
+	return s.agentCosts.Report(repoID, month, location)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const testSessionLog = `{"type":"user","timestamp":"2024-01-15T10:00:00Z","message":{"role":"user","content":"fix the bug"}}
+{"type":"assistant","timestamp":"2024-01-15T10:05:00Z","message":{"role":"assistant","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":1000,"output_tokens":2000,"cache_read_input_tokens":100000,"cache_creation_input_tokens":10000}}}
+`
+
+func TestCollectAgentUsageFromDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cf568042-7147-4fba-a2ca-c6a646581260.jsonl"), []byte(testSessionLog), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0d1e2f3a-7147-4fba-a2ca-c6a646581260.jsonl"), []byte(testSessionLog), 0644))
+	// Files that aren't session logs are ignored
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.jsonl"), []byte(testSessionLog), 0644))
+
+	usage, err := collectAgentUsageFromDir(dir)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, usage.Sessions)
+	assert.Equal(t, int64(2000), usage.InputTokens)
+	assert.Equal(t, int64(4000), usage.OutputTokens)
+	assert.Equal(t, int64(200000), usage.CacheReadTokens)
+	assert.Equal(t, int64(20000), usage.CacheCreationTokens)
+	assert.Equal(t, 600.0, usage.WallTimeSeconds)
+	assert.Equal(t, 600.0, usage.ActiveTimeSeconds)
+	// 2000*3 + 4000*15 + 200000*0.30 + 20000*3.75 per million
+	assert.InDelta(t, 0.201, usage.EstimatedCostUSD, 0.0001)
+
+	empty, err := collectAgentUsageFromDir(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, empty.Sessions)
+}
+
+func TestFormatAgentCostComment(t *testing.T) {
+	body := FormatAgentCostComment(models.AgentUsage{Sessions: 2, EstimatedCostUSD: 1.234, ActiveTimeSeconds: 5400})
+	assert.Contains(t, body, agentCostCommentMarker)
+	assert.Contains(t, body, "$1.23")
+	assert.Contains(t, body, "1h30m0s")
+}
+
+func TestAgentCostStore(t *testing.T) {
+	store := NewAgentCostStore(filepath.Join(t.TempDir(), "agent_costs.json"))
+	january := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, store.Record(models.AgentCostRecord{
+		RepoID: "owner/repo", PRNumber: 1, PRURL: "https://github.com/owner/repo/pull/1",
+		Usage: models.AgentUsage{Sessions: 1, EstimatedCostUSD: 1}, RecordedAt: january,
+	}))
+	require.NoError(t, store.Record(models.AgentCostRecord{
+		RepoID: "owner/repo", PRNumber: 2, PRURL: "https://github.com/owner/repo/pull/2",
+		Usage: models.AgentUsage{Sessions: 1, EstimatedCostUSD: 2}, RecordedAt: january,
+	}))
+	// Updating a PR replaces its earlier snapshot
+	require.NoError(t, store.Record(models.AgentCostRecord{
+		RepoID: "owner/repo", PRNumber: 1, PRURL: "https://github.com/owner/repo/pull/1",
+		Usage: models.AgentUsage{Sessions: 2, EstimatedCostUSD: 3}, RecordedAt: january,
+	}))
+	require.NoError(t, store.Record(models.AgentCostRecord{
+		RepoID: "owner/other", PRNumber: 1, PRURL: "https://github.com/owner/other/pull/1",
+		Usage: models.AgentUsage{Sessions: 1, EstimatedCostUSD: 5}, RecordedAt: january,
+	}))
+
+	report, err := store.Report("owner/repo", "2024-01", time.UTC)
+	require.NoError(t, err)
+	require.Len(t, report.PullRequests, 2)
+	assert.Equal(t, 1, report.PullRequests[0].PRNumber, "most expensive PR first")
+	assert.Equal(t, 3, report.Total.Sessions)
+	assert.InDelta(t, 5.0, report.Total.EstimatedCostUSD, 0.0001)
+
+	february, err := store.Report("owner/repo", "2024-02", time.UTC)
+	require.NoError(t, err)
+	assert.Empty(t, february.PullRequests)
+
+	// Months are bucketed in the report's time zone
+	require.NoError(t, store.Record(models.AgentCostRecord{
+		RepoID: "owner/repo", PRNumber: 3, PRURL: "https://github.com/owner/repo/pull/3",
+		Usage: models.AgentUsage{Sessions: 1}, RecordedAt: time.Date(2024, 2, 29, 23, 30, 0, 0, time.UTC),
+	}))
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	march, err := store.Report("owner/repo", "2024-03", tokyo)
+	require.NoError(t, err)
+	assert.Len(t, march.PullRequests, 1)
+	assert.Equal(t, "Asia/Tokyo", march.TimeZone)
+	february, err = store.Report("owner/repo", "2024-02", time.UTC)
+	require.NoError(t, err)
+	assert.Len(t, february.PullRequests, 1)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/claude/parser"
+	"github.com/vanpelt/catnip/internal/claude/paths"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// AgentNotesRef holds the notes catnip attaches to agent-authored commits. It
+// is kept apart from refs/notes/commits so it never mixes with the user's notes.
+const AgentNotesRef = "refs/notes/catnip"
+
+// PushAgentNotesSetting makes pushes also push AgentNotesRef when "true"
+const PushAgentNotesSetting = "CATNIP_PUSH_AGENT_NOTES"
+
+// defaultAgentNotesLimit is how many commits are searched for notes by default
+const defaultAgentNotesLimit = 50
+
+// CommitAgentWork commits everything in the workspace like GitAddCommitGetHash
+// and attaches an agent note describing the session that did the work.
+// Failing to attach the note doesn't fail the commit.
+func (s *GitService) CommitAgentWork(workspaceDir, message, kind string) (string, error) {
+	hash, err := s.GitAddCommitGetHash(workspaceDir, message)
+	if err != nil || hash == "" {
+		return hash, err
+	}
+
+	note := collectAgentNote(workspaceDir, kind)
+	if err := s.addAgentNote(workspaceDir, hash, note); err != nil {
+		logger.Warnf("⚠️ Failed to attach agent note to %s: %v", hash, err)
+	}
+	return hash, nil
+}
+
+// GetAgentCommits returns the commits reachable from the worktree's HEAD that
+// carry an agent note, newest first. With commit set only that commit is
+// looked at; otherwise the last limit commits are searched.
+func (s *GitService) GetAgentCommits(worktreeID, commit string, limit int) ([]models.AgentCommit, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+
+	if limit <= 0 {
+		limit = defaultAgentNotesLimit
+	}
+	args := []string{"log", "--notes=" + AgentNotesRef, "--format=%H%x1f%s%x1f%N%x1e"}
+	if commit != "" {
+		if _, err := s.operations.GetCommitHash(worktree.Path, commit+"^{commit}"); err != nil {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown commit %s", commit)
+		}
+		args = append(args, "-1", commit)
+	} else {
+		args = append(args, "-n", strconv.Itoa(limit), "HEAD")
+	}
+
+	output, err := s.operations.ExecuteGit(worktree.Path, args...)
+	if err != nil {
+		return nil, models.NewAPIError(models.ErrCodeGitCommandFailed, "failed to read commits of %s: %v", worktree.Name, err)
+	}
+	return parseAgentCommits(string(output)), nil
+}
+
+// parseAgentCommits parses log records of hash, subject and notes, skipping
+// commits without a valid agent note
+func parseAgentCommits(output string) []models.AgentCommit {
+	commits := []models.AgentCommit{}
+	for _, record := range strings.Split(output, "\x1e") {
+		fields := strings.SplitN(strings.TrimSpace(record), "\x1f", 3)
+		if len(fields) < 3 || strings.TrimSpace(fields[2]) == "" {
+			continue
+		}
+		var note models.AgentCommitNote
+		if err := json.Unmarshal([]byte(strings.TrimSpace(fields[2])), &note); err != nil {
+			logger.Debugf("⚠️ Skipping unreadable agent note on %s: %v", fields[0], err)
+			continue
+		}
+		commits = append(commits, models.AgentCommit{Commit: fields[0], Subject: fields[1], Note: note})
+	}
+	return commits
+}
+
+// addAgentNote attaches a note to a commit under AgentNotesRef, as the bot
+// identity if one is configured
+func (s *GitService) addAgentNote(workspaceDir, hash string, note models.AgentCommitNote) error {
+	data, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+	args := append(s.automatedCommitArgs(workspaceDir), "notes", "--ref", AgentNotesRef, "add", "-f", "-m", string(data), hash)
+	if output, err := s.operations.ExecuteGit(workspaceDir, args...); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// pushAgentNotes pushes AgentNotesRef alongside a branch when
+// CATNIP_PUSH_AGENT_NOTES is enabled. If the remote's notes moved, they are
+// merged in (keeping both sides' lines) and the push retried once.
+func (s *GitService) pushAgentNotes(worktreePath, remote string) {
+	if os.Getenv(PushAgentNotesSetting) != "true" {
+		return
+	}
+	if _, err := s.operations.GetCommitHash(worktreePath, AgentNotesRef); err != nil {
+		return
+	}
+
+	output, err := s.operations.ExecuteGit(worktreePath, "push", remote, AgentNotesRef)
+	if err == nil {
+		return
+	}
+	remoteNotes := "refs/notes/remote/catnip"
+	if _, fetchErr := s.operations.ExecuteGit(worktreePath, "fetch", remote, "+"+AgentNotesRef+":"+remoteNotes); fetchErr == nil {
+		if _, mergeErr := s.operations.ExecuteGit(worktreePath, "notes", "--ref", AgentNotesRef, "merge", "-s", "cat_sort_uniq", remoteNotes); mergeErr == nil {
+			output, err = s.operations.ExecuteGit(worktreePath, "push", remote, AgentNotesRef)
+		}
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to push %s to %s: %v\n%s", AgentNotesRef, remote, err, strings.TrimSpace(string(output)))
+	}
+}
+
+// collectAgentNote describes the newest Claude session in a workspace. Fields
+// that can't be read are left empty rather than failing the commit.
+func collectAgentNote(workspaceDir, kind string) models.AgentCommitNote {
+	note := models.AgentCommitNote{Agent: "claude", Kind: kind, CreatedAt: time.Now()}
+
+	if projectDir, err := paths.GetProjectDir(workspaceDir); err == nil {
+		if sessionFile, err := paths.FindBestSessionFile(projectDir); err == nil {
+			note.SessionID = strings.TrimSuffix(filepath.Base(sessionFile), ".jsonl")
+			reader := parser.NewSessionFileReader(sessionFile)
+			if err := reader.ReadFull(); err == nil {
+				if latest := reader.GetLatestMessage(); latest != nil {
+					note.AgentVersion = latest.Version
+				}
+				stats := reader.GetStats()
+				note.CostUSD = EstimateAgentCost(models.AgentUsage{
+					InputTokens:         stats.TotalInputTokens,
+					OutputTokens:        stats.TotalOutputTokens,
+					CacheReadTokens:     stats.CacheReadTokens,
+					CacheCreationTokens: stats.CacheCreationTokens,
+				})
+			}
+		}
+	}
+
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		if prompt, err := parser.NewHistoryReader(homeDir).GetLatestUserPrompt(workspaceDir); err == nil && prompt != "" {
+			sum := sha256.Sum256([]byte(prompt))
+			note.PromptHash = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return note
+}
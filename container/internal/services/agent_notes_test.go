@@ -0,0 +1,80 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/claude/paths"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCommitAgentWorkAttachesNote(t *testing.T) {
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	repoPath := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	runTestGit(t, repoPath, "init", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v1\n"), 0644))
+	runTestGit(t, repoPath, "add", ".")
+	runTestGit(t, repoPath, "commit", "-m", "human commit")
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: repoPath, DefaultBranch: "main"}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-app", RepoID: "acme/app", Name: "app/main", Path: repoPath, Branch: "main"}))
+
+	// The session log and prompt history Claude leaves behind
+	sessionID := "cf568042-7147-4fba-a2ca-c6a646581260"
+	projectDir, err := paths.GetProjectDir(repoPath)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	sessionLog := fmt.Sprintf(`{"type":"user","sessionId":"%[1]s","version":"1.0.58","timestamp":"2024-01-15T10:00:00Z","message":{"role":"user","content":"add a login form"}}
+{"type":"assistant","sessionId":"%[1]s","version":"1.0.58","timestamp":"2024-01-15T10:05:00Z","message":{"role":"assistant","content":[{"type":"text","text":"done"}],"usage":{"input_tokens":1000,"output_tokens":2000,"cache_read_input_tokens":100000,"cache_creation_input_tokens":10000}}}
+`, sessionID)
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, sessionID+".jsonl"), []byte(sessionLog), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".claude"), 0755))
+	history := fmt.Sprintf(`{"display":"add a login form","project":%q,"sessionId":%q,"timestamp":1705312800000}`+"\n", repoPath, sessionID)
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".claude", "history.jsonl"), []byte(history), 0644))
+
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "login.txt"), []byte("form\n"), 0644))
+	hash, err := service.CommitAgentWork(repoPath, "Add login form checkpoint: 1", models.AgentCommitCheckpoint)
+	require.NoError(t, err)
+	require.NotEmpty(t, hash)
+
+	commits, err := service.GetAgentCommits("wt-app", "", 0)
+	require.NoError(t, err)
+	require.Len(t, commits, 1, "the human commit has no note")
+	assert.Equal(t, hash, commits[0].Commit)
+	assert.Equal(t, "Add login form checkpoint: 1", commits[0].Subject)
+	note := commits[0].Note
+	assert.Equal(t, "claude", note.Agent)
+	assert.Equal(t, "1.0.58", note.AgentVersion)
+	assert.Equal(t, sessionID, note.SessionID)
+	assert.Equal(t, models.AgentCommitCheckpoint, note.Kind)
+	// sha256("add a login form")
+	assert.Equal(t, "sha256:6d2572dd9c90d3d1337c9636f60f78f72f2378e7255d6c4be69fa092580ec1b7", note.PromptHash)
+	// 1000*3 + 2000*15 + 100000*0.30 + 10000*3.75 per million
+	assert.InDelta(t, 0.1005, note.CostUSD, 0.0001)
+
+	single, err := service.GetAgentCommits("wt-app", "HEAD~1", 0)
+	require.NoError(t, err)
+	assert.Empty(t, single)
+	_, err = service.GetAgentCommits("wt-app", "nope", 0)
+	assert.Error(t, err)
+
+	// Nothing to commit means no commit and no note
+	hash, err = service.CommitAgentWork(repoPath, "Add login form", models.AgentCommitTitle)
+	require.NoError(t, err)
+	assert.Empty(t, hash)
+}
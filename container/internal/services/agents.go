@@ -0,0 +1,286 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// AgentActivity is how an agent's activity is tracked for the worktree's
+// activity state
+type AgentActivity string
+
+const (
+	// AgentActivityHooks agents report prompts, tool use and stops through
+	// catnip's hooks (Claude)
+	AgentActivityHooks AgentActivity = "hooks"
+	// AgentActivityOutput agents count as working while they write output
+	AgentActivityOutput AgentActivity = "output"
+)
+
+// AgentLaunch describes the session an agent is started for
+type AgentLaunch struct {
+	SessionID string
+	WorkDir   string
+	// Pick up the workspace's previous conversation
+	Resume bool
+	// Conversation to resume, for agents that track them by ID
+	ResumeID string
+	// Layered system prompt, for agents that take one
+	SystemPrompt string
+}
+
+// Agent is a coding agent run in workspace PTY sessions, selected with the
+// agent query parameter
+type Agent interface {
+	// Name is the agent query parameter value
+	Name() string
+	// Executable finds the agent's binary, failing when it isn't installed
+	Executable() (string, error)
+	// Args returns the arguments the executable is run with
+	Args(launch AgentLaunch) []string
+	// Env returns the variables the agent needs beyond those every session gets
+	Env(launch AgentLaunch) []string
+	// CanResume reports whether the agent can pick up a previous conversation
+	CanResume() bool
+	// Activity reports how the agent's activity is tracked
+	Activity() AgentActivity
+}
+
+// AgentConfig configures an agent run from a command line. Built-in agents
+// can be adjusted and new ones added under "agents" in .catnip.yaml.
+type AgentConfig struct {
+	// Executable and arguments starting a new session
+	Command []string `json:"command,omitempty" yaml:"command"`
+	// Arguments appended to resume the workspace's previous conversation
+	// (unset: sessions always start fresh)
+	Resume []string `json:"resume,omitempty" yaml:"resume"`
+	// Places to look for the executable when it isn't on PATH
+	Paths []string `json:"paths,omitempty" yaml:"paths"`
+	// Extra environment variables
+	Env map[string]string `json:"env,omitempty" yaml:"env"`
+	// Flag passing catnip's system prompt (unset: the prompt isn't passed)
+	PromptFlag string `json:"prompt_flag,omitempty" yaml:"prompt_flag"`
+	// Shown when the executable can't be found
+	InstallHint string `json:"install_hint,omitempty" yaml:"install_hint"`
+}
+
+// merge returns the config with the fields set in override replaced
+func (c AgentConfig) merge(override AgentConfig) AgentConfig {
+	if len(override.Command) > 0 {
+		c.Command = override.Command
+	}
+	if override.Resume != nil {
+		c.Resume = override.Resume
+	}
+	if len(override.Paths) > 0 {
+		c.Paths = append(append([]string{}, override.Paths...), c.Paths...)
+	}
+	if len(override.Env) > 0 {
+		env := make(map[string]string, len(c.Env)+len(override.Env))
+		for key, value := range c.Env {
+			env[key] = value
+		}
+		for key, value := range override.Env {
+			env[key] = value
+		}
+		c.Env = env
+	}
+	if override.PromptFlag != "" {
+		c.PromptFlag = override.PromptFlag
+	}
+	if override.InstallHint != "" {
+		c.InstallHint = override.InstallHint
+	}
+	return c
+}
+
+// builtinAgents are the command line agents catnip knows out of the box
+var builtinAgents = map[string]AgentConfig{
+	"aider": {
+		Command:     []string{"aider"},
+		Resume:      []string{"--restore-chat-history"},
+		InstallHint: "pip install aider-chat",
+	},
+	"codex": {
+		Command:     []string{"codex"},
+		Resume:      []string{"resume", "--last"},
+		InstallHint: "npm install -g @openai/codex",
+	},
+	"goose": {
+		Command:     []string{"goose", "session"},
+		Resume:      []string{"--resume"},
+		InstallHint: "install the goose CLI from github.com/block/goose",
+	},
+}
+
+// CommandAgent is an agent run from a configured command line, whose activity
+// is tracked from its output
+type CommandAgent struct {
+	name   string
+	config AgentConfig
+}
+
+// NewCommandAgent creates an agent from its configuration
+func NewCommandAgent(name string, cfg AgentConfig) *CommandAgent {
+	return &CommandAgent{name: name, config: cfg}
+}
+
+// Name implements Agent
+func (a *CommandAgent) Name() string {
+	return a.name
+}
+
+// Executable looks for the command on PATH, then in the configured paths,
+// ~/.local/bin and NVM_BIN, where npm and pip install for the user
+func (a *CommandAgent) Executable() (string, error) {
+	if len(a.config.Command) == 0 {
+		return "", fmt.Errorf("agent %s has no command", a.name)
+	}
+	command := a.config.Command[0]
+	if filepath.IsAbs(command) {
+		if _, err := os.Stat(command); err == nil {
+			return command, nil
+		}
+	} else if path, err := exec.LookPath(command); err == nil {
+		return path, nil
+	}
+
+	candidates := append([]string{}, a.config.Paths...)
+	name := filepath.Base(command)
+	candidates = append(candidates, filepath.Join(config.Runtime.HomeDir, ".local", "bin", name))
+	if nvmBin := os.Getenv("NVM_BIN"); nvmBin != "" {
+		candidates = append(candidates, filepath.Join(nvmBin, name))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+
+	err := fmt.Errorf("%s isn't installed", name)
+	if a.config.InstallHint != "" {
+		err = fmt.Errorf("%s isn't installed (%s)", name, a.config.InstallHint)
+	}
+	return "", err
+}
+
+// Args implements Agent
+func (a *CommandAgent) Args(launch AgentLaunch) []string {
+	var args []string
+	if len(a.config.Command) > 1 {
+		args = append(args, a.config.Command[1:]...)
+	}
+	if launch.SystemPrompt != "" && a.config.PromptFlag != "" {
+		args = append(args, a.config.PromptFlag, launch.SystemPrompt)
+	}
+	if launch.Resume {
+		args = append(args, a.config.Resume...)
+	}
+	return args
+}
+
+// Env implements Agent
+func (a *CommandAgent) Env(launch AgentLaunch) []string {
+	env := make([]string, 0, len(a.config.Env))
+	for key, value := range a.config.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// CanResume implements Agent
+func (a *CommandAgent) CanResume() bool {
+	return len(a.config.Resume) > 0
+}
+
+// Activity implements Agent
+func (a *CommandAgent) Activity() AgentActivity {
+	return AgentActivityOutput
+}
+
+// AgentRegistry holds the agents sessions can run: the built-in command line
+// agents, those registered by the server (Claude) and those a repository
+// configures in .catnip.yaml
+type AgentRegistry struct {
+	mu     sync.RWMutex
+	agents map[string]Agent
+}
+
+// NewAgentRegistry creates a registry with the built-in command line agents
+func NewAgentRegistry() *AgentRegistry {
+	r := &AgentRegistry{agents: make(map[string]Agent)}
+	for name, cfg := range builtinAgents {
+		r.Register(NewCommandAgent(name, cfg))
+	}
+	return r
+}
+
+// Register adds an agent, replacing any with the same name
+func (r *AgentRegistry) Register(agent Agent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.agents[agent.Name()] = agent
+}
+
+// Get returns a registered agent
+func (r *AgentRegistry) Get(name string) (Agent, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	agent, ok := r.agents[name]
+	return agent, ok
+}
+
+// Names lists the registered agents
+func (r *AgentRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.agents))
+	for name := range r.agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the agent to run in workDir: a registered agent, adjusted or
+// added by the repository's .catnip.yaml. Agents registered by the server
+// other than command line ones (Claude) can't be reconfigured there.
+func (r *AgentRegistry) Lookup(name, workDir string) (Agent, bool) {
+	agent, registered := r.Get(name)
+	override, configured := repoAgentConfig(workDir, name)
+	if !configured {
+		return agent, registered
+	}
+	if !registered {
+		return NewCommandAgent(name, override), true
+	}
+	if command, ok := agent.(*CommandAgent); ok {
+		return NewCommandAgent(name, command.config.merge(override)), true
+	}
+	logger.Warnf("⚠️ Ignoring agents.%s in %s: it can't be reconfigured", name, CatnipConfigFileName)
+	return agent, true
+}
+
+// repoAgentConfig reads an agent's configuration from the .catnip.yaml in
+// workDir
+func repoAgentConfig(workDir, name string) (AgentConfig, bool) {
+	if workDir == "" || name == "" || strings.ContainsAny(name, ":/") {
+		return AgentConfig{}, false
+	}
+	cfg, err := LoadCatnipConfig(workDir)
+	if err != nil {
+		logger.Warnf("⚠️ %v", err)
+		return AgentConfig{}, false
+	}
+	agentCfg, ok := cfg.Agents[name]
+	return agentCfg, ok
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinAgents(t *testing.T) {
+	registry := NewAgentRegistry()
+	assert.Equal(t, []string{"aider", "codex", "goose"}, registry.Names())
+
+	goose, ok := registry.Get("goose")
+	require.True(t, ok)
+	assert.Equal(t, []string{"session"}, goose.Args(AgentLaunch{}))
+	assert.Equal(t, []string{"session", "--resume"}, goose.Args(AgentLaunch{Resume: true}))
+	assert.True(t, goose.CanResume())
+	assert.Equal(t, AgentActivityOutput, goose.Activity())
+
+	codex, _ := registry.Get("codex")
+	assert.Equal(t, []string{"resume", "--last"}, codex.Args(AgentLaunch{Resume: true, SystemPrompt: "be brief"}))
+
+	_, ok = registry.Get("bash")
+	assert.False(t, ok)
+}
+
+func TestCommandAgentExecutable(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("NVM_BIN", "")
+
+	agent := NewCommandAgent("aider", builtinAgents["aider"])
+	_, err := agent.Executable()
+	assert.EqualError(t, err, "aider isn't installed (pip install aider-chat)")
+
+	bin := filepath.Join(t.TempDir(), "aider")
+	require.NoError(t, os.WriteFile(bin, []byte("#!/bin/sh\n"), 0755))
+	agent = NewCommandAgent("aider", builtinAgents["aider"].merge(AgentConfig{Paths: []string{bin}}))
+	path, err := agent.Executable()
+	require.NoError(t, err)
+	assert.Equal(t, bin, path)
+
+	t.Setenv("PATH", filepath.Dir(bin))
+	path, err = NewCommandAgent("aider", builtinAgents["aider"]).Executable()
+	require.NoError(t, err)
+	assert.Equal(t, bin, path)
+}
+
+func TestAgentRegistryLookupRepoConfig(t *testing.T) {
+	workDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, CatnipConfigFileName), []byte(`
+agents:
+  aider:
+    command: [aider, --model, sonnet]
+    env:
+      AIDER_DARK_MODE: "true"
+  reviewer:
+    command: [review-bot, --interactive]
+    prompt_flag: --instructions
+  claude:
+    command: [not-claude]
+`), 0644))
+
+	registry := NewAgentRegistry()
+	registry.Register(stubAgent{name: "claude"})
+
+	aider, ok := registry.Lookup("aider", workDir)
+	require.True(t, ok)
+	assert.Equal(t, []string{"--model", "sonnet", "--restore-chat-history"}, aider.Args(AgentLaunch{Resume: true}), "the built-in resume arguments are kept")
+	assert.Equal(t, []string{"AIDER_DARK_MODE=true"}, aider.Env(AgentLaunch{}))
+
+	reviewer, ok := registry.Lookup("reviewer", workDir)
+	require.True(t, ok)
+	assert.Equal(t, []string{"--interactive", "--instructions", "be brief"}, reviewer.Args(AgentLaunch{Resume: true, SystemPrompt: "be brief"}))
+	assert.False(t, reviewer.CanResume())
+	_, ok = registry.Lookup("reviewer", t.TempDir())
+	assert.False(t, ok, "only configured in the repository")
+
+	claude, ok := registry.Lookup("claude", workDir)
+	require.True(t, ok)
+	assert.Equal(t, stubAgent{name: "claude"}, claude, "agents registered by the server keep their own setup")
+
+	unconfigured, ok := registry.Lookup("aider", t.TempDir())
+	require.True(t, ok)
+	assert.Empty(t, unconfigured.Args(AgentLaunch{}))
+}
+
+type stubAgent struct {
+	name string
+}
+
+func (a stubAgent) Name() string                { return a.name }
+func (a stubAgent) Executable() (string, error) { return a.name, nil }
+func (a stubAgent) Args(AgentLaunch) []string   { return nil }
+func (a stubAgent) Env(AgentLaunch) []string    { return nil }
+func (a stubAgent) CanResume() bool             { return false }
+func (a stubAgent) Activity() AgentActivity     { return AgentActivityHooks }
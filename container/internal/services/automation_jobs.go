@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// A job interrupted more often than this is abandoned instead of resumed
+	maxAutomationJobResumes = 2
+	// Finished jobs kept so clients can collect results after reconnecting
+	maxFinishedAutomationJobs = 100
+	finishedAutomationJobTTL  = 7 * 24 * time.Hour
+	// Timeout for completions restarted after a restart
+	resumedCompletionTimeout = 10 * time.Minute
+)
+
+// AutomationJobKind identifies what kind of Claude automation a job runs
+type AutomationJobKind string
+
+const (
+	// AutomationJobCompletion is a non-streaming API completion (PR bodies, summaries, ...)
+	AutomationJobCompletion AutomationJobKind = "completion"
+	// AutomationJobDependencyUpdate is a dependency update run with its Claude test-fix loop
+	AutomationJobDependencyUpdate AutomationJobKind = "dependency_update"
+)
+
+// AutomationJobStatus is the lifecycle state of an automation job
+type AutomationJobStatus string
+
+const (
+	AutomationJobRunning   AutomationJobStatus = "running"
+	AutomationJobSucceeded AutomationJobStatus = "succeeded"
+	AutomationJobFailed    AutomationJobStatus = "failed"
+	// Interrupted by a restart and not resumed
+	AutomationJobAbandoned AutomationJobStatus = "abandoned"
+)
+
+// AutomationJob is a persisted record of a Claude automation, with enough
+// context to restart it if catnip goes down mid-run
+type AutomationJob struct {
+	ID          string              `json:"id"`
+	Kind        AutomationJobKind   `json:"kind"`
+	Description string              `json:"description,omitempty"`
+	Status      AutomationJobStatus `json:"status"`
+	// Kind-specific parameters used to resume the job
+	Params json.RawMessage `json:"params,omitempty"`
+	// Completion text for completion jobs
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// How many times the job was resumed after a restart
+	Resumes     int        `json:"resumes"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	RecoveredAt *time.Time `json:"recovered_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// AutomationJobResumer restarts an interrupted job. It must eventually call
+// Finish for the job; returning an error abandons it.
+type AutomationJobResumer func(job *AutomationJob) error
+
+// AutomationJobEventsEmitter publishes recovery outcomes
+type AutomationJobEventsEmitter interface {
+	EmitAutomationJobRecovered(job *AutomationJob)
+	EmitAutomationJobAbandoned(job *AutomationJob)
+}
+
+// AutomationJobService persists in-flight Claude automations so they can be
+// resumed or reported as abandoned after a restart
+type AutomationJobService struct {
+	path     string
+	mu       sync.Mutex
+	jobs     map[string]*AutomationJob
+	resumers map[AutomationJobKind]AutomationJobResumer
+	events   AutomationJobEventsEmitter
+}
+
+// NewAutomationJobService creates a service storing jobs in the volume directory
+func NewAutomationJobService() *AutomationJobService {
+	return NewAutomationJobServiceWithPath(filepath.Join(config.Runtime.VolumeDir, "automation_jobs.json"))
+}
+
+// NewAutomationJobServiceWithPath creates a service storing jobs at path (for testing)
+func NewAutomationJobServiceWithPath(path string) *AutomationJobService {
+	s := &AutomationJobService{
+		path:     path,
+		jobs:     make(map[string]*AutomationJob),
+		resumers: make(map[AutomationJobKind]AutomationJobResumer),
+	}
+	if err := s.load(); err != nil {
+		logger.Warnf("⚠️ Failed to load automation jobs: %v", err)
+	}
+	return s
+}
+
+// WithEvents sets the emitter used for recovery events
+func (s *AutomationJobService) WithEvents(events AutomationJobEventsEmitter) *AutomationJobService {
+	s.events = events
+	return s
+}
+
+// RegisterResumer sets how interrupted jobs of a kind are restarted
+func (s *AutomationJobService) RegisterResumer(kind AutomationJobKind, resumer AutomationJobResumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resumers[kind] = resumer
+}
+
+func (s *AutomationJobService) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var jobs []*AutomationJob
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return fmt.Errorf("corrupt automation jobs file: %v", err)
+	}
+	for _, job := range jobs {
+		s.jobs[job.ID] = job
+	}
+	return nil
+}
+
+func (s *AutomationJobService) saveLocked() {
+	s.pruneLocked()
+	jobs := make([]*AutomationJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.path), 0755); err == nil {
+			tmpPath := s.path + ".tmp"
+			if err = os.WriteFile(tmpPath, data, 0644); err == nil {
+				err = os.Rename(tmpPath, s.path)
+			}
+		}
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to persist automation jobs: %v", err)
+	}
+}
+
+// pruneLocked drops old finished jobs
+func (s *AutomationJobService) pruneLocked() {
+	var finished []*AutomationJob
+	for id, job := range s.jobs {
+		if job.FinishedAt == nil {
+			continue
+		}
+		if time.Since(*job.FinishedAt) > finishedAutomationJobTTL {
+			delete(s.jobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+	if len(finished) <= maxFinishedAutomationJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, job := range finished[:len(finished)-maxFinishedAutomationJobs] {
+		delete(s.jobs, job.ID)
+	}
+}
+
+// Begin records a running job and returns its ID
+func (s *AutomationJobService) Begin(kind AutomationJobKind, description string, params any) (string, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode automation job params: %v", err)
+	}
+	now := time.Now()
+	job := &AutomationJob{
+		ID:          uuid.New().String(),
+		Kind:        kind,
+		Description: description,
+		Status:      AutomationJobRunning,
+		Params:      raw,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	s.saveLocked()
+	return job.ID, nil
+}
+
+// UpdateParams replaces a running job's resume parameters, e.g. once a
+// worktree has been created for it
+func (s *AutomationJobService) UpdateParams(id string, params any) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to encode automation job params: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[id]
+	if !exists || job.FinishedAt != nil {
+		return
+	}
+	job.Params = raw
+	job.UpdatedAt = time.Now()
+	s.saveLocked()
+}
+
+// Finish marks a job as done. A nil error means it succeeded.
+func (s *AutomationJobService) Finish(id, result string, jobErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[id]
+	if !exists || job.FinishedAt != nil {
+		return
+	}
+	now := time.Now()
+	job.Status = AutomationJobSucceeded
+	job.Result = result
+	if jobErr != nil {
+		job.Status = AutomationJobFailed
+		job.Error = jobErr.Error()
+	}
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+	s.saveLocked()
+}
+
+// Get returns a copy of a job
+func (s *AutomationJobService) Get(id string) (*AutomationJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, exists := s.jobs[id]
+	if !exists {
+		return nil, false
+	}
+	copied := *job
+	return &copied, true
+}
+
+// List returns copies of all jobs, newest first
+func (s *AutomationJobService) List() []*AutomationJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*AutomationJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		copied := *job
+		jobs = append(jobs, &copied)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// Recover resumes jobs left running by a previous process, or abandons them
+// when they can't be resumed. Call once at startup after registering resumers.
+func (s *AutomationJobService) Recover() (recovered, abandoned []*AutomationJob) {
+	s.mu.Lock()
+	var interrupted []*AutomationJob
+	for _, job := range s.jobs {
+		if job.Status == AutomationJobRunning {
+			interrupted = append(interrupted, job)
+		}
+	}
+	sort.Slice(interrupted, func(i, j int) bool { return interrupted[i].CreatedAt.Before(interrupted[j].CreatedAt) })
+	s.mu.Unlock()
+
+	for _, job := range interrupted {
+		s.mu.Lock()
+		resumer := s.resumers[job.Kind]
+		now := time.Now()
+		reason := ""
+		switch {
+		case resumer == nil:
+			reason = fmt.Sprintf("no way to resume %s jobs", job.Kind)
+		case job.Resumes >= maxAutomationJobResumes:
+			reason = fmt.Sprintf("interrupted %d times, giving up", job.Resumes+1)
+		default:
+			job.Resumes++
+			job.RecoveredAt = &now
+			job.UpdatedAt = now
+		}
+		if reason != "" {
+			s.abandonLocked(job, reason)
+		}
+		s.saveLocked()
+		snapshot := *job
+		s.mu.Unlock()
+
+		if reason == "" {
+			if err := resumer(&snapshot); err != nil {
+				s.mu.Lock()
+				s.abandonLocked(job, fmt.Sprintf("resume failed: %v", err))
+				s.saveLocked()
+				snapshot = *job
+				s.mu.Unlock()
+				reason = snapshot.Error
+			}
+		}
+
+		if reason != "" {
+			logger.Warnf("⚠️ Abandoned interrupted %s job %s: %s", snapshot.Kind, snapshot.ID, reason)
+			abandoned = append(abandoned, &snapshot)
+			if s.events != nil {
+				s.events.EmitAutomationJobAbandoned(&snapshot)
+			}
+			continue
+		}
+		logger.Infof("♻️ Resumed interrupted %s job %s", snapshot.Kind, snapshot.ID)
+		recovered = append(recovered, &snapshot)
+		if s.events != nil {
+			s.events.EmitAutomationJobRecovered(&snapshot)
+		}
+	}
+	return recovered, abandoned
+}
+
+func (s *AutomationJobService) abandonLocked(job *AutomationJob, reason string) {
+	now := time.Now()
+	job.Status = AutomationJobAbandoned
+	job.Error = reason
+	job.UpdatedAt = now
+	job.FinishedAt = &now
+}
+
+// CompletionJobResumer re-runs interrupted completion jobs in the background.
+// The client that asked is gone, so the result is stored on the job.
+func (s *AutomationJobService) CompletionJobResumer(claude completionCreator) AutomationJobResumer {
+	return func(job *AutomationJob) error {
+		var req models.CreateCompletionRequest
+		if err := json.Unmarshal(job.Params, &req); err != nil {
+			return fmt.Errorf("invalid completion params: %v", err)
+		}
+		req.Stream = false
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), resumedCompletionTimeout)
+			defer cancel()
+			resp, err := claude.CreateCompletion(ctx, &req)
+			result := ""
+			if resp != nil {
+				result = resp.Response
+			}
+			s.Finish(job.ID, result, err)
+		}()
+		return nil
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type echoClaude struct{}
+
+func (echoClaude) CreateCompletion(ctx context.Context, req *models.CreateCompletionRequest) (*models.CreateCompletionResponse, error) {
+	return &models.CreateCompletionResponse{Response: "echo: " + req.Prompt}, nil
+}
+
+type recordingJobEvents struct {
+	recovered []string
+	abandoned []string
+}
+
+func (r *recordingJobEvents) EmitAutomationJobRecovered(job *AutomationJob) {
+	r.recovered = append(r.recovered, job.ID)
+}
+
+func (r *recordingJobEvents) EmitAutomationJobAbandoned(job *AutomationJob) {
+	r.abandoned = append(r.abandoned, job.ID)
+}
+
+func TestAutomationJobLifecycle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "automation_jobs.json")
+	jobs := NewAutomationJobServiceWithPath(path)
+
+	id, err := jobs.Begin(AutomationJobCompletion, "Generate PR body", &models.CreateCompletionRequest{Prompt: "summarize"})
+	require.NoError(t, err)
+	job, exists := jobs.Get(id)
+	require.True(t, exists)
+	assert.Equal(t, AutomationJobRunning, job.Status)
+
+	jobs.Finish(id, "done", nil)
+	jobs.Finish(id, "", errors.New("ignored once finished"))
+
+	// A new process sees the finished job
+	reloaded := NewAutomationJobServiceWithPath(path)
+	job, exists = reloaded.Get(id)
+	require.True(t, exists)
+	assert.Equal(t, AutomationJobSucceeded, job.Status)
+	assert.Equal(t, "done", job.Result)
+	assert.Empty(t, job.Error)
+
+	recovered, abandoned := reloaded.Recover()
+	assert.Empty(t, recovered)
+	assert.Empty(t, abandoned)
+}
+
+func TestAutomationJobRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "automation_jobs.json")
+	before := NewAutomationJobServiceWithPath(path)
+
+	completionID, err := before.Begin(AutomationJobCompletion, "PR body", &models.CreateCompletionRequest{Prompt: "summarize"})
+	require.NoError(t, err)
+	unknownID, err := before.Begin(AutomationJobKind("mystery"), "Unknown", nil)
+	require.NoError(t, err)
+	failingID, err := before.Begin(AutomationJobDependencyUpdate, "Deps", dependencyUpdateJobParams{RepoID: "gone/repo"})
+	require.NoError(t, err)
+
+	// Simulate a restart: the process died with all three running
+	events := &recordingJobEvents{}
+	after := NewAutomationJobServiceWithPath(path).WithEvents(events)
+	after.RegisterResumer(AutomationJobCompletion, after.CompletionJobResumer(echoClaude{}))
+	after.RegisterResumer(AutomationJobDependencyUpdate, func(job *AutomationJob) error {
+		return errors.New("repository gone/repo not found")
+	})
+
+	recovered, abandoned := after.Recover()
+	require.Len(t, recovered, 1)
+	assert.Equal(t, completionID, recovered[0].ID)
+	assert.Equal(t, 1, recovered[0].Resumes)
+	assert.Len(t, abandoned, 2)
+	assert.Equal(t, []string{completionID}, events.recovered)
+	assert.ElementsMatch(t, []string{unknownID, failingID}, events.abandoned)
+
+	job, _ := after.Get(failingID)
+	assert.Equal(t, AutomationJobAbandoned, job.Status)
+	assert.Contains(t, job.Error, "resume failed")
+
+	// The resumed completion finishes in the background and stores its result
+	require.Eventually(t, func() bool {
+		job, _ := after.Get(completionID)
+		return job.Status == AutomationJobSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+	job, _ = after.Get(completionID)
+	assert.Equal(t, "echo: summarize", job.Result)
+}
+
+func TestAutomationJobGivesUpAfterRepeatedInterruptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "automation_jobs.json")
+	id, err := NewAutomationJobServiceWithPath(path).Begin(AutomationJobCompletion, "PR body", &models.CreateCompletionRequest{Prompt: "x"})
+	require.NoError(t, err)
+
+	resumes := 0
+	for restart := 0; restart <= maxAutomationJobResumes; restart++ {
+		jobs := NewAutomationJobServiceWithPath(path)
+		// The resumed work never finishes before the next "crash"
+		jobs.RegisterResumer(AutomationJobCompletion, func(job *AutomationJob) error {
+			resumes++
+			return nil
+		})
+		jobs.Recover()
+	}
+
+	assert.Equal(t, maxAutomationJobResumes, resumes)
+	job, _ := NewAutomationJobServiceWithPath(path).Get(id)
+	assert.Equal(t, AutomationJobAbandoned, job.Status)
+}
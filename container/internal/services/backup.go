@@ -0,0 +1,673 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+const (
+	// defaultBackupInterval is how often scheduled backups run
+	defaultBackupInterval = 6 * time.Hour
+	// defaultBackupRetain is how many snapshots are kept in the bucket
+	defaultBackupRetain = 14
+	backupKeyPrefix     = "catnip-backup-"
+	backupKeySuffix     = ".tar.gz"
+	backupKeyTimeFormat = "20060102T150405Z"
+)
+
+// Volume files included in every snapshot (relative to the volume directory)
+var backupVolumeFiles = []string{
+	"state.json",
+	"settings.json",
+	"onboarding.json",
+	"agent_costs.json",
+}
+
+// BackupObjectStore is a bucket that snapshots are uploaded to
+type BackupObjectStore interface {
+	Upload(localPath, key string) error
+	Download(key, localPath string) error
+	List() ([]string, error)
+	Delete(key string) error
+}
+
+// cliObjectStore stores snapshots with the aws or gcloud CLI so no cloud SDK
+// (or its credentials handling) is needed in the binary
+type cliObjectStore struct {
+	baseURL string // s3://bucket/prefix or gs://bucket/prefix, no trailing slash
+	command []string
+}
+
+func (s *cliObjectStore) run(args ...string) ([]byte, error) {
+	fullArgs := append(append([]string{}, s.command[1:]...), args...)
+	output, err := exec.Command(s.command[0], fullArgs...).CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("%s %s failed: %v: %s", s.command[0], strings.Join(fullArgs, " "), err, strings.TrimSpace(string(output)))
+	}
+	return output, nil
+}
+
+func (s *cliObjectStore) Upload(localPath, key string) error {
+	_, err := s.run("cp", localPath, s.baseURL+"/"+key)
+	return err
+}
+
+func (s *cliObjectStore) Download(key, localPath string) error {
+	_, err := s.run("cp", s.baseURL+"/"+key, localPath)
+	return err
+}
+
+func (s *cliObjectStore) List() ([]string, error) {
+	output, err := s.run("ls", s.baseURL+"/")
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// aws prints "date time size name", gcloud prints the full URL
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		key := path.Base(fields[len(fields)-1])
+		if strings.HasPrefix(key, backupKeyPrefix) && strings.HasSuffix(key, backupKeySuffix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (s *cliObjectStore) Delete(key string) error {
+	_, err := s.run("rm", s.baseURL+"/"+key)
+	return err
+}
+
+// dirObjectStore stores snapshots in a local directory (file:// URLs, e.g. a
+// second mounted disk)
+type dirObjectStore struct {
+	dir string
+}
+
+func (s *dirObjectStore) Upload(localPath, key string) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return copyFile(localPath, filepath.Join(s.dir, key))
+}
+
+func (s *dirObjectStore) Download(key, localPath string) error {
+	return copyFile(filepath.Join(s.dir, key), localPath)
+}
+
+func (s *dirObjectStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var keys []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), backupKeyPrefix) && strings.HasSuffix(entry.Name(), backupKeySuffix) {
+			keys = append(keys, entry.Name())
+		}
+	}
+	return keys, nil
+}
+
+func (s *dirObjectStore) Delete(key string) error {
+	return os.Remove(filepath.Join(s.dir, key))
+}
+
+// NewBackupObjectStore returns the object store for an s3://, gs:// or file:// URL
+func NewBackupObjectStore(rawURL string) (BackupObjectStore, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup URL %q: %v", rawURL, err)
+	}
+	base := strings.TrimSuffix(strings.TrimSpace(rawURL), "/")
+	switch parsed.Scheme {
+	case "s3":
+		return &cliObjectStore{baseURL: base, command: []string{"aws", "s3"}}, nil
+	case "gs":
+		return &cliObjectStore{baseURL: base, command: []string{"gcloud", "storage"}}, nil
+	case "file":
+		return &dirObjectStore{dir: parsed.Path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported backup URL %q (expected s3://, gs:// or file://)", rawURL)
+	}
+}
+
+// BackupStatus reports the state of remote backups
+type BackupStatus struct {
+	Enabled    bool          `json:"enabled"`
+	URL        string        `json:"url,omitempty"`
+	Interval   time.Duration `json:"interval_ns,omitempty"`
+	LastBackup time.Time     `json:"last_backup,omitempty"`
+	LastKey    string        `json:"last_key,omitempty"`
+	LastError  string        `json:"last_error,omitempty"`
+}
+
+// BackupRestoreResult summarizes what a restore put back
+type BackupRestoreResult struct {
+	Key           string   `json:"key"`
+	Files         []string `json:"files"`
+	Repositories  []string `json:"repositories"`
+	RefsRestored  int      `json:"refs_restored"`
+	RefsMissing   []string `json:"refs_missing,omitempty"` // Refs whose commits were only local and couldn't be fetched
+	SkippedExists []string `json:"skipped_exists,omitempty"`
+}
+
+// BackupService snapshots volume state (state.json, settings, session
+// metadata and bare repository refs) to object storage on a schedule
+type BackupService struct {
+	store      BackupObjectStore
+	operations git.Operations
+	url        string
+	volumeDir  string
+	sessionDir string
+	interval   time.Duration
+	retain     int
+	now        func() time.Time
+	mu         sync.Mutex // Serializes backups and guards status
+	status     BackupStatus
+	stopChan   chan struct{}
+	reschedule chan struct{}
+	running    bool
+}
+
+// NewBackupService creates a backup service configured from CATNIP_BACKUP_URL,
+// CATNIP_BACKUP_INTERVAL and CATNIP_BACKUP_RETAIN. Backups are disabled when
+// no URL is set.
+func NewBackupService() (*BackupService, error) {
+	rawURL := strings.TrimSpace(os.Getenv("CATNIP_BACKUP_URL"))
+	interval, retain := backupScheduleFromEnv()
+
+	var store BackupObjectStore
+	if rawURL != "" {
+		var err error
+		if store, err = NewBackupObjectStore(rawURL); err != nil {
+			return nil, err
+		}
+	}
+
+	service := NewBackupServiceWithOptions(store, git.NewOperations(), config.Runtime.VolumeDir,
+		filepath.Join(config.Runtime.WorkspaceDir, ".session-state"), interval, retain)
+	service.url = rawURL
+	service.status.URL = rawURL
+	return service, nil
+}
+
+func backupScheduleFromEnv() (time.Duration, int) {
+	interval := defaultBackupInterval
+	if raw := os.Getenv("CATNIP_BACKUP_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= 5*time.Minute {
+			interval = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_BACKUP_INTERVAL %q (minimum 5m)", raw)
+		}
+	}
+
+	retain := defaultBackupRetain
+	if raw := os.Getenv("CATNIP_BACKUP_RETAIN"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed >= 0 {
+			retain = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_BACKUP_RETAIN %q", raw)
+		}
+	}
+	return interval, retain
+}
+
+// NewBackupServiceWithOptions creates a backup service with explicit settings (for testing)
+func NewBackupServiceWithOptions(store BackupObjectStore, operations git.Operations, volumeDir, sessionDir string, interval time.Duration, retain int) *BackupService {
+	return &BackupService{
+		store:      store,
+		operations: operations,
+		volumeDir:  volumeDir,
+		sessionDir: sessionDir,
+		interval:   interval,
+		retain:     retain,
+		now:        time.Now,
+		stopChan:   make(chan struct{}),
+		reschedule: make(chan struct{}, 1),
+		status: BackupStatus{
+			Enabled:  store != nil,
+			Interval: interval,
+		},
+	}
+}
+
+// SetSchedule changes the backup interval and how many backups are kept,
+// waiting for a backup in progress. The next backup is an interval from now.
+func (s *BackupService) SetSchedule(interval time.Duration, retain int) {
+	s.mu.Lock()
+	s.interval = interval
+	s.retain = retain
+	s.status.Interval = interval
+	s.mu.Unlock()
+
+	select {
+	case s.reschedule <- struct{}{}:
+	default:
+	}
+	logger.Infof("💾 Backup schedule changed: every %v, keeping %d", interval, retain)
+}
+
+// ReloadConfig re-reads CATNIP_BACKUP_INTERVAL and CATNIP_BACKUP_RETAIN. A
+// changed CATNIP_BACKUP_URL still needs a restart.
+func (s *BackupService) ReloadConfig() {
+	s.SetSchedule(backupScheduleFromEnv())
+}
+
+// Enabled reports whether a backup destination is configured
+func (s *BackupService) Enabled() bool {
+	return s.store != nil
+}
+
+// Start runs backups periodically. It does nothing when backups are disabled.
+func (s *BackupService) Start() {
+	if s.store == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	interval := s.interval
+	s.mu.Unlock()
+
+	logger.Infof("💾 Backing up volume state to %s every %v", s.url, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-s.reschedule:
+				s.mu.Lock()
+				interval := s.interval
+				s.mu.Unlock()
+				ticker.Reset(interval)
+			case <-ticker.C:
+				if _, err := s.Backup(); err != nil {
+					logger.Warnf("⚠️ Scheduled backup failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled backups
+func (s *BackupService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+}
+
+// Status returns the current backup status
+func (s *BackupService) Status() BackupStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// List returns the snapshot keys in the bucket, newest first
+func (s *BackupService) List() ([]string, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("backups are not configured (set CATNIP_BACKUP_URL)")
+	}
+	keys, err := s.store.List()
+	if err != nil {
+		return nil, err
+	}
+	// Keys embed a UTC timestamp so lexical order is chronological
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	return keys, nil
+}
+
+// Backup writes a snapshot and uploads it, pruning snapshots beyond the
+// retention count. It returns the uploaded key.
+func (s *BackupService) Backup() (string, error) {
+	if s.store == nil {
+		return "", fmt.Errorf("backups are not configured (set CATNIP_BACKUP_URL)")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := backupKeyPrefix + s.now().UTC().Format(backupKeyTimeFormat) + backupKeySuffix
+	err := s.backupLocked(key)
+	if err != nil {
+		s.status.LastError = err.Error()
+		return "", err
+	}
+
+	s.status.LastBackup = s.now()
+	s.status.LastKey = key
+	s.status.LastError = ""
+	logger.Infof("💾 Uploaded backup %s", key)
+
+	if s.retain > 0 {
+		s.pruneLocked()
+	}
+	return key, nil
+}
+
+func (s *BackupService) backupLocked(key string) error {
+	tmp, err := os.CreateTemp("", "catnip-backup-*.tar.gz")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := s.writeSnapshot(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := s.store.Upload(tmp.Name(), key); err != nil {
+		return fmt.Errorf("failed to upload snapshot: %v", err)
+	}
+	return nil
+}
+
+func (s *BackupService) pruneLocked() {
+	keys, err := s.store.List()
+	if err != nil {
+		logger.Warnf("⚠️ Failed to list backups for pruning: %v", err)
+		return
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	for i := s.retain; i < len(keys); i++ {
+		if err := s.store.Delete(keys[i]); err != nil {
+			logger.Warnf("⚠️ Failed to prune backup %s: %v", keys[i], err)
+		}
+	}
+}
+
+// writeSnapshot writes a gzipped tarball laid out as:
+//
+//	volume/<file>              state and settings files
+//	sessions/<file>.json       session metadata
+//	repos/<name>.git/config    bare repository config (remotes)
+//	repos/<name>.git/refs.txt  "<sha> <ref>" for every ref
+func (s *BackupService) writeSnapshot(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range backupVolumeFiles {
+		if err := addFileToTar(tw, filepath.Join(s.volumeDir, name), "volume/"+name); err != nil {
+			return err
+		}
+	}
+
+	if entries, err := os.ReadDir(s.sessionDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+				continue
+			}
+			if err := addFileToTar(tw, filepath.Join(s.sessionDir, entry.Name()), "sessions/"+entry.Name()); err != nil {
+				return err
+			}
+		}
+	}
+
+	reposDir := filepath.Join(s.volumeDir, "repos")
+	if entries, err := os.ReadDir(reposDir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() || !strings.HasSuffix(entry.Name(), ".git") {
+				continue
+			}
+			repoPath := filepath.Join(reposDir, entry.Name())
+			refs, err := s.operations.ExecuteGit(repoPath, "for-each-ref", "--format=%(objectname) %(refname)")
+			if err != nil {
+				logger.Warnf("⚠️ Skipping refs of %s in backup: %v", entry.Name(), err)
+				continue
+			}
+			if err := addFileToTar(tw, filepath.Join(repoPath, "config"), "repos/"+entry.Name()+"/config"); err != nil {
+				return err
+			}
+			if err := addBytesToTar(tw, "repos/"+entry.Name()+"/refs.txt", refs); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// addFileToTar adds a file if it exists; missing files are skipped
+func addFileToTar(tw *tar.Writer, src, name string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return addBytesToTar(tw, name, data)
+}
+
+func addBytesToTar(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// Restore downloads a snapshot (the newest when key is empty) and puts it
+// back. Existing files are left alone unless force is set. Bare repositories
+// that are missing are recreated from their remote and refs are reset to the
+// snapshot wherever the commit could be fetched.
+func (s *BackupService) Restore(key string, force bool) (*BackupRestoreResult, error) {
+	if s.store == nil {
+		return nil, fmt.Errorf("backups are not configured (set CATNIP_BACKUP_URL)")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key == "" {
+		keys, err := s.store.List()
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no backups found")
+		}
+		sort.Strings(keys)
+		key = keys[len(keys)-1]
+	}
+
+	tmp, err := os.CreateTemp("", "catnip-restore-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := s.store.Download(key, tmp.Name()); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", key, err)
+	}
+
+	result := &BackupRestoreResult{Key: key}
+	repos, err := s.extractSnapshot(tmp.Name(), force, result)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := s.restoreRepo(name, repos[name], force, result); err != nil {
+			logger.Warnf("⚠️ Failed to restore refs of %s: %v", name, err)
+		}
+	}
+	return result, nil
+}
+
+// backupRepoSnapshot is a bare repository's config and refs from a snapshot
+type backupRepoSnapshot struct {
+	config []byte
+	refs   []byte
+}
+
+func (s *BackupService) extractSnapshot(archivePath string, force bool, result *BackupRestoreResult) (map[string]*backupRepoSnapshot, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt snapshot: %v", err)
+	}
+	tr := tar.NewReader(gz)
+
+	repos := make(map[string]*backupRepoSnapshot)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("corrupt snapshot: %v", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only plain names below each section are accepted so a crafted
+		// archive can't write outside the volume
+		section, rest, _ := strings.Cut(header.Name, "/")
+		var dest string
+		switch section {
+		case "volume":
+			if rest == filepath.Base(rest) {
+				dest = filepath.Join(s.volumeDir, rest)
+			}
+		case "sessions":
+			if rest == filepath.Base(rest) {
+				dest = filepath.Join(s.sessionDir, rest)
+			}
+		case "repos":
+			repo, file, _ := strings.Cut(rest, "/")
+			if repo != filepath.Base(repo) || !strings.HasSuffix(repo, ".git") {
+				break
+			}
+			snapshot := repos[repo]
+			if snapshot == nil {
+				snapshot = &backupRepoSnapshot{}
+				repos[repo] = snapshot
+			}
+			switch file {
+			case "refs.txt":
+				snapshot.refs = data
+			case "config":
+				snapshot.config = data
+			}
+			continue
+		}
+		if dest == "" || rest == "" || rest == "." || rest == ".." {
+			logger.Warnf("⚠️ Ignoring unexpected snapshot entry %s", header.Name)
+			continue
+		}
+
+		if _, err := os.Stat(dest); err == nil && !force {
+			result.SkippedExists = append(result.SkippedExists, header.Name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(dest, data, 0644); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, header.Name)
+	}
+	return repos, nil
+}
+
+// restoreRepo recreates a missing bare repository from its saved remotes and
+// resets each ref to the snapshot wherever the commit is available. Refs of
+// repositories that still exist are only reset when forced.
+func (s *BackupService) restoreRepo(name string, snapshot *backupRepoSnapshot, force bool, result *BackupRestoreResult) error {
+	repoPath := filepath.Join(s.volumeDir, "repos", name)
+
+	if _, err := os.Stat(repoPath); err == nil && !force {
+		result.SkippedExists = append(result.SkippedExists, "repos/"+name)
+		return nil
+	} else if os.IsNotExist(err) {
+		if _, err := s.operations.ExecuteGit("", "init", "--bare", repoPath); err != nil {
+			return err
+		}
+		if len(snapshot.config) > 0 {
+			if err := os.WriteFile(filepath.Join(repoPath, "config"), snapshot.config, 0644); err != nil {
+				return err
+			}
+		}
+		// Bare clones have no fetch refspec, so fetch remote branches explicitly
+		// to bring in the objects the snapshot's refs point at
+		if output, err := s.operations.ExecuteGit(repoPath, "fetch", "--tags", "origin", "+refs/heads/*:refs/remotes/origin/*"); err != nil {
+			logger.Warnf("⚠️ Fetch while restoring %s failed: %v: %s", name, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(snapshot.refs)), "\n") {
+		sha, ref, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok || !strings.HasPrefix(ref, "refs/") {
+			continue
+		}
+		if _, err := s.operations.ExecuteGit(repoPath, "cat-file", "-e", sha+"^{commit}"); err != nil {
+			result.RefsMissing = append(result.RefsMissing, name+":"+ref)
+			continue
+		}
+		if _, err := s.operations.ExecuteGit(repoPath, "update-ref", ref, sha); err != nil {
+			result.RefsMissing = append(result.RefsMissing, name+":"+ref)
+			continue
+		}
+		result.RefsRestored++
+	}
+	result.Repositories = append(result.Repositories, name)
+	return nil
+}
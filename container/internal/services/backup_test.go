@@ -0,0 +1,93 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=Test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test", "GIT_COMMITTER_EMAIL=test@example.com")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	return strings.TrimSpace(string(output))
+}
+
+func TestNewBackupObjectStore(t *testing.T) {
+	store, err := NewBackupObjectStore("s3://bucket/catnip/")
+	require.NoError(t, err)
+	assert.Equal(t, "s3://bucket/catnip", store.(*cliObjectStore).baseURL)
+
+	_, err = NewBackupObjectStore("gs://bucket")
+	assert.NoError(t, err)
+	_, err = NewBackupObjectStore("ftp://bucket")
+	assert.Error(t, err)
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	// Upstream repository with a bare clone in the volume, like a checked out repo
+	upstream := t.TempDir()
+	runTestGit(t, upstream, "init", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(upstream, "README.md"), []byte("hi"), 0644))
+	runTestGit(t, upstream, "add", ".")
+	runTestGit(t, upstream, "commit", "-m", "initial")
+	pushed := runTestGit(t, upstream, "rev-parse", "HEAD")
+
+	volumeDir := t.TempDir()
+	sessionDir := filepath.Join(t.TempDir(), ".session-state")
+	barePath := filepath.Join(volumeDir, "repos", "upstream.git")
+	runTestGit(t, volumeDir, "clone", "--bare", upstream, barePath)
+	runTestGit(t, barePath, "update-ref", "refs/catnip/felix", pushed)
+
+	require.NoError(t, os.WriteFile(filepath.Join(volumeDir, "state.json"), []byte(`{"worktrees":{}}`), 0644))
+	require.NoError(t, os.MkdirAll(sessionDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(sessionDir, "workspace.json"), []byte(`{}`), 0644))
+
+	store, err := NewBackupObjectStore("file://" + filepath.Join(t.TempDir(), "bucket"))
+	require.NoError(t, err)
+	service := NewBackupServiceWithOptions(store, git.NewOperations(), volumeDir, sessionDir, time.Hour, 1)
+
+	first, err := service.Backup()
+	require.NoError(t, err)
+	assert.Equal(t, first, service.Status().LastKey)
+
+	// Retention keeps only the newest snapshot
+	service.now = func() time.Time { return time.Now().Add(time.Hour) }
+	second, err := service.Backup()
+	require.NoError(t, err)
+	keys, err := service.List()
+	require.NoError(t, err)
+	assert.Equal(t, []string{second}, keys)
+
+	// Restore onto an empty volume
+	freshVolume := t.TempDir()
+	freshSessions := filepath.Join(t.TempDir(), ".session-state")
+	restorer := NewBackupServiceWithOptions(store, git.NewOperations(), freshVolume, freshSessions, time.Hour, 1)
+	result, err := restorer.Restore("", false)
+	require.NoError(t, err)
+
+	assert.Equal(t, second, result.Key)
+	assert.ElementsMatch(t, []string{"volume/state.json", "sessions/workspace.json"}, result.Files)
+	assert.Equal(t, []string{"upstream.git"}, result.Repositories)
+	assert.Empty(t, result.RefsMissing)
+	assert.FileExists(t, filepath.Join(freshVolume, "state.json"))
+	assert.FileExists(t, filepath.Join(freshSessions, "workspace.json"))
+	assert.Equal(t, pushed, runTestGit(t, filepath.Join(freshVolume, "repos", "upstream.git"), "rev-parse", "refs/catnip/felix"))
+
+	// Existing files are kept without force
+	result, err = restorer.Restore(second, false)
+	require.NoError(t, err)
+	assert.Contains(t, result.SkippedExists, "volume/state.json")
+	assert.Contains(t, result.SkippedExists, "repos/upstream.git")
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// ImportBranch attaches an existing branch, such as a teammate's
+// feature/foo, to a new worktree. The worktree works on its own catnip ref
+// like any other, with the branch as its nice name, so commits land on the
+// branch and pushes go back to it. It diffs against base (the repository's
+// default branch when empty), gets Claude monitoring and runs setup.sh. If a
+// worktree is already on the branch it is returned instead.
+func (s *GitService) ImportBranch(repoID, branch, base string) (*models.Worktree, error) {
+	branch = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(branch), "refs/heads/"), "origin/")
+	if violation := s.branchNameViolation(nil, branch); violation != "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "branch %q %s", branch, violation)
+	}
+	if isCatnipBranch(branch) {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s is already a catnip branch", branch).
+			WithHint("Open the worktree it belongs to instead")
+	}
+
+	s.mu.RLock()
+	repo, exists := s.stateManager.GetRepository(repoID)
+	s.mu.RUnlock()
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(repoID)
+	}
+	if base == "" {
+		base = repo.DefaultBranch
+	}
+	if branch == base {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s is the branch worktrees are based on", branch).
+			WithHint("Create a new worktree from it instead of importing it")
+	}
+	if existing := s.branchWorktree(repoID, branch); existing != nil {
+		logger.Infof("🔁 Branch %s is already checked out in worktree %s", branch, existing.Name)
+		return existing, nil
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpCheckout)
+	defer release()
+
+	if s.isLocalRepo(repo.ID) {
+		if !s.branchExists(repo.Path, branch, false) {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "branch %s does not exist in %s", branch, repo.ID)
+		}
+	} else {
+		if err := s.fetchBranch(repo.Path, git.FetchStrategy{Branch: branch, UpdateLocalRef: true}); err != nil {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "could not fetch branch %s of %s", branch, repo.ID).
+				WithHint("Check that the branch has been pushed").
+				WithCause(err)
+		}
+		// Diffs are against the base branch, so make sure it's current
+		if err := s.fetchBranch(repo.Path, git.FetchStrategy{Branch: base, UpdateLocalRef: true}); err != nil {
+			logger.Warnf("⚠️ Could not fetch base branch %s for imported branch %s: %v", base, branch, err)
+		}
+	}
+	commit, err := s.resolveCommit(repo.Path, "refs/heads/"+branch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve branch %s: %v", branch, err)
+	}
+	logger.Infof("📥 Importing branch %s of %s at %s", branch, repo.ID, commit[:7])
+
+	funName := s.generateUniqueSessionName(repo.Path)
+	var worktree *models.Worktree
+	if s.isLocalRepo(repo.ID) {
+		worktree, err = s.createLocalRepoWorktree(repo, commit, funName)
+	} else {
+		worktree, err = s.createWorktreeInternalForRepo(repo, commit, funName, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %v", err)
+	}
+
+	// Attach the branch as the worktree's nice name so commit sync keeps it
+	// current and it isn't renamed after the session title
+	if err := s.stateManager.RenameWorktreeBranch(worktree.ID, branch, s.operations); err != nil {
+		return nil, fmt.Errorf("created worktree %s but failed to attach it to %s: %v", worktree.Name, branch, err)
+	}
+	updates := map[string]interface{}{
+		"source_branch":   base,
+		"imported_branch": branch,
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, updates); err != nil {
+		logger.Warnf("⚠️ Failed to record import of %s in worktree %s: %v", branch, worktree.Name, err)
+	}
+	if s.worktreeCache != nil {
+		s.worktreeCache.ForceRefresh(worktree.ID)
+	}
+	if updated, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		worktree = updated
+	}
+	return worktree, nil
+}
+
+// branchWorktree returns the worktree of a repository that's on branch, if any
+func (s *GitService) branchWorktree(repoID, branch string) *models.Worktree {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID == repoID && worktree.Branch == branch {
+			return worktree
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestImportBranch(t *testing.T) {
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	upstream := t.TempDir()
+	runTestGit(t, upstream, "init", "-b", "main")
+	runTestGit(t, upstream, "commit", "--allow-empty", "-m", "base")
+	clone := filepath.Join(t.TempDir(), "app")
+	runTestGit(t, filepath.Dir(clone), "clone", "-q", upstream, clone)
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: clone, DefaultBranch: "main"}))
+
+	// A teammate pushes their branch after the repository was cloned
+	runTestGit(t, upstream, "checkout", "-b", "feature/foo")
+	require.NoError(t, os.WriteFile(filepath.Join(upstream, "foo.txt"), []byte("foo\n"), 0644))
+	runTestGit(t, upstream, "add", ".")
+	runTestGit(t, upstream, "commit", "-m", "Add foo")
+	head := runTestGit(t, upstream, "rev-parse", "HEAD")
+	runTestGit(t, upstream, "checkout", "main")
+
+	worktree, err := service.ImportBranch("acme/app", "origin/feature/foo", "")
+	require.NoError(t, err)
+	assert.Equal(t, "feature/foo", worktree.Branch)
+	assert.Equal(t, "feature/foo", worktree.ImportedBranch)
+	assert.Equal(t, "main", worktree.SourceBranch)
+	assert.True(t, worktree.HasBeenRenamed)
+	assert.Equal(t, head, runTestGit(t, worktree.Path, "rev-parse", "HEAD"))
+	// The worktree works on its own catnip ref mapped to the branch
+	catnipRef := runTestGit(t, worktree.Path, "symbolic-ref", "HEAD")
+	assert.True(t, strings.HasPrefix(catnipRef, "refs/catnip/"), catnipRef)
+	assert.Equal(t, "feature/foo", runTestGit(t, clone, "config", "catnip.branch-map."+strings.ReplaceAll(catnipRef, "/", ".")))
+
+	t.Run("ReturnsExistingWorktree", func(t *testing.T) {
+		again, err := service.ImportBranch("acme/app", "feature/foo", "")
+		require.NoError(t, err)
+		assert.Equal(t, worktree.ID, again.ID)
+	})
+
+	t.Run("RejectsInvalidBranches", func(t *testing.T) {
+		for _, branch := range []string{"", "main", "refs/catnip/milo", "bad..name"} {
+			_, err := service.ImportBranch("acme/app", branch, "")
+			assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err), branch)
+		}
+		_, err := service.ImportBranch("acme/app", "feature/missing", "")
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+	})
+
+	t.Run("DeletingKeepsTheBranch", func(t *testing.T) {
+		done, err := service.DeleteWorktree(worktree.ID)
+		require.NoError(t, err)
+		require.NoError(t, <-done)
+		assert.Equal(t, head, runTestGit(t, clone, "rev-parse", "refs/heads/feature/foo"))
+	})
+}
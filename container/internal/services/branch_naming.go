@@ -0,0 +1,215 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+var (
+	// Runs of characters git refuses in branch names, or that shells and URLs mangle
+	branchNameInvalidChars = regexp.MustCompile(`[^A-Za-z0-9/._-]+`)
+	branchNameRepeatedDash = regexp.MustCompile(`-{2,}`)
+)
+
+// BranchNameCheck reports whether a name follows a repository's branch naming
+// policy and, if not, a compliant name derived from it
+type BranchNameCheck struct {
+	Name  string `json:"name" example:"Feat/Add Login"`
+	Valid bool   `json:"valid" example:"false"`
+	// Why the name was rejected
+	Reason string `json:"reason,omitempty" example:"must start with one of feature/, fix/"`
+	// A compliant name derived from Name, empty if none could be found
+	Suggestion string `json:"suggestion,omitempty" example:"feature/Add-Login"`
+}
+
+// GetRepositoryBranchNaming returns a repository's branch naming policy, nil
+// if names aren't restricted
+func (s *GitService) GetRepositoryBranchNaming(repoID string) (*models.BranchNamingPolicy, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(repoID)
+	}
+	return repo.BranchNaming, nil
+}
+
+// SetRepositoryBranchNaming stores a repository's branch naming policy. A nil
+// policy lifts the restrictions.
+func (s *GitService) SetRepositoryBranchNaming(repoID string, policy *models.BranchNamingPolicy) error {
+	if policy != nil {
+		if err := policy.Validate(); err != nil {
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+		}
+	}
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.BranchNaming = policy
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return fmt.Errorf("failed to save repository branch naming policy: %v", err)
+	}
+	return nil
+}
+
+// CheckBranchName checks a name against a repository's branch naming policy
+// and git's rules for branch names
+func (s *GitService) CheckBranchName(repoID, name string) (*BranchNameCheck, error) {
+	policy, err := s.GetRepositoryBranchNaming(repoID)
+	if err != nil {
+		return nil, err
+	}
+	return s.checkBranchName(policy, name), nil
+}
+
+// ValidateWorktreeBranchName returns a BRANCH_NAME_REJECTED error, hinting at
+// a compliant name, if name breaks the naming policy of the worktree's repository
+func (s *GitService) ValidateWorktreeBranchName(worktreeID, name string) error {
+	check := s.checkBranchName(s.branchNamingPolicyForWorktree(worktreeID), name)
+	if check.Valid {
+		return nil
+	}
+	apiErr := models.NewAPIError(models.ErrCodeBranchNameRejected, "branch name %q %s", name, check.Reason)
+	if check.Suggestion != "" {
+		apiErr = apiErr.WithHint(fmt.Sprintf("Try %q", check.Suggestion))
+	}
+	return apiErr
+}
+
+// compliantBranchName returns name, or the suggested replacement if name breaks
+// the worktree's naming policy. It returns false if no compliant name is found.
+func (s *GitService) compliantBranchName(worktreeID, name string) (string, bool) {
+	check := s.checkBranchName(s.branchNamingPolicyForWorktree(worktreeID), name)
+	switch {
+	case check.Valid:
+		return name, true
+	case check.Suggestion != "":
+		return check.Suggestion, true
+	default:
+		return "", false
+	}
+}
+
+// branchNamingPolicyForWorktree returns the naming policy of a worktree's repository
+func (s *GitService) branchNamingPolicyForWorktree(worktreeID string) *models.BranchNamingPolicy {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil
+	}
+	return repo.BranchNaming
+}
+
+func (s *GitService) checkBranchName(policy *models.BranchNamingPolicy, name string) *BranchNameCheck {
+	check := &BranchNameCheck{Name: name}
+	check.Reason = s.branchNameViolation(policy, name)
+	if check.Reason == "" {
+		check.Valid = true
+		return check
+	}
+	if suggestion := suggestBranchName(policy, name); suggestion != name && s.branchNameViolation(policy, suggestion) == "" {
+		check.Suggestion = suggestion
+	}
+	return check
+}
+
+// branchNameViolation describes how name breaks git's rules or the policy,
+// or returns "" if it doesn't
+func (s *GitService) branchNameViolation(policy *models.BranchNamingPolicy, name string) string {
+	if name == "" {
+		return "is empty"
+	}
+	if _, err := s.operations.ExecuteGit("", "check-ref-format", "refs/heads/"+name); err != nil {
+		return "is not a valid git branch name"
+	}
+	if policy == nil {
+		return ""
+	}
+	if policy.MaxLength > 0 && len(name) > policy.MaxLength {
+		return fmt.Sprintf("is longer than %d characters", policy.MaxLength)
+	}
+	if len(policy.Prefixes) > 0 && !hasBranchPrefix(policy.Prefixes, name) {
+		return fmt.Sprintf("must start with one of %s", strings.Join(policy.Prefixes, ", "))
+	}
+	if policy.Pattern != "" {
+		if pattern, err := regexp.Compile(policy.Pattern); err == nil && !pattern.MatchString(name) {
+			return fmt.Sprintf("must match %s", policy.Pattern)
+		}
+	}
+	return ""
+}
+
+func hasBranchPrefix(prefixes []string, name string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestBranchName transforms name towards the policy: characters git
+// rejects become dashes, a missing prefix replaces the name's own type
+// segment ("feat/login" becomes "feature/login" when feature/ is allowed),
+// the name is shortened to the length limit and, failing the pattern, tried
+// in lower case. The result may still break the policy.
+func suggestBranchName(policy *models.BranchNamingPolicy, name string) string {
+	suggestion := sanitizeBranchName(name)
+	if policy == nil {
+		return suggestion
+	}
+
+	if len(policy.Prefixes) > 0 && !hasBranchPrefix(policy.Prefixes, suggestion) {
+		prefix := policy.Prefixes[0]
+		rest := suggestion
+		if segment, remainder, found := strings.Cut(suggestion, "/"); found && remainder != "" {
+			rest = remainder
+			segment = strings.ToLower(segment)
+			for _, candidate := range policy.Prefixes {
+				kind := strings.ToLower(strings.Trim(candidate, "/-_"))
+				if kind != "" && (strings.HasPrefix(kind, segment) || strings.HasPrefix(segment, kind)) {
+					prefix = candidate
+					break
+				}
+			}
+		}
+		suggestion = prefix + rest
+	}
+
+	if policy.Pattern != "" {
+		if pattern, err := regexp.Compile(policy.Pattern); err == nil && !pattern.MatchString(suggestion) &&
+			pattern.MatchString(strings.ToLower(suggestion)) {
+			suggestion = strings.ToLower(suggestion)
+		}
+	}
+
+	if policy.MaxLength > 0 && len(suggestion) > policy.MaxLength {
+		suggestion = strings.TrimRight(suggestion[:policy.MaxLength], "-/.")
+	}
+	return suggestion
+}
+
+// sanitizeBranchName replaces what git rejects in branch names with dashes
+// and drops empty or dot-led path segments
+func sanitizeBranchName(name string) string {
+	name = branchNameInvalidChars.ReplaceAllString(strings.TrimSpace(name), "-")
+	name = strings.ReplaceAll(name, "..", "-")
+	name = branchNameRepeatedDash.ReplaceAllString(name, "-")
+
+	var segments []string
+	for _, segment := range strings.Split(name, "/") {
+		segment = strings.TrimSuffix(strings.Trim(segment, "-."), ".lock")
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
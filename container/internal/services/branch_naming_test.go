@@ -0,0 +1,61 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestBranchNamingPolicy(t *testing.T) {
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: t.TempDir(), DefaultBranch: "main"}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "acme/app", Name: "app/felix", Path: t.TempDir(), Branch: "refs/catnip/felix", SourceBranch: "main",
+	}))
+
+	// Without a policy any valid git branch name goes
+	assert.NoError(t, service.ValidateWorktreeBranchName("wt-felix", "Felix/WIP"))
+	check, err := service.CheckBranchName("acme/app", "add login..page")
+	require.NoError(t, err)
+	assert.False(t, check.Valid)
+	assert.Equal(t, "add-login-page", check.Suggestion)
+
+	assert.Error(t, service.SetRepositoryBranchNaming("acme/app", &models.BranchNamingPolicy{Pattern: "(["}))
+	require.NoError(t, service.SetRepositoryBranchNaming("acme/app", &models.BranchNamingPolicy{
+		Prefixes:  []string{"feature/", "fix/"},
+		Pattern:   `^[a-z]+/[a-z0-9-]+$`,
+		MaxLength: 24,
+	}))
+
+	for name, suggestion := range map[string]string{
+		"feature/add-login":                  "",
+		"feat/Add Login":                     "feature/add-login",
+		"bugfix/crash":                       "feature/crash",
+		"fix-crash":                          "feature/fix-crash",
+		"fix/very-long-description-of-a-bug": "fix/very-long-descriptio",
+		"feature/café":                       "feature/caf",
+	} {
+		check, err := service.CheckBranchName("acme/app", name)
+		require.NoError(t, err)
+		assert.Equal(t, suggestion == "", check.Valid, name)
+		assert.Equal(t, suggestion, check.Suggestion, name)
+	}
+
+	err = service.ValidateWorktreeBranchName("wt-felix", "feat/Add Login")
+	apiErr, ok := models.AsAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, models.ErrCodeBranchNameRejected, apiErr.Code)
+	assert.Equal(t, `Try "feature/add-login"`, apiErr.Hint)
+
+	name, ok := service.compliantBranchName("wt-felix", "fix/Crash On Start")
+	assert.True(t, ok)
+	assert.Equal(t, "fix/crash-on-start", name)
+
+	require.NoError(t, service.SetRepositoryBranchNaming("acme/app", nil))
+	assert.NoError(t, service.ValidateWorktreeBranchName("wt-felix", "feat/Add-Login"))
+}
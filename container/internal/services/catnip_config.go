@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// CatnipConfigFileName is the per-repository configuration file
+const CatnipConfigFileName = ".catnip.yaml"
+
+// CatnipConfig is the per-repository configuration read from .catnip.yaml
+type CatnipConfig struct {
+	Dependencies DependencyUpdateConfig   `json:"dependencies" yaml:"dependencies"`
+	PromptLint   PromptLintConfig         `json:"prompt_lint" yaml:"prompt_lint"`
+	Setup        SetupConfig              `json:"setup" yaml:"setup"`
+	Claude       ClaudeRepoConfig         `json:"claude" yaml:"claude"`
+	Offload      OffloadConfig            `json:"offload" yaml:"offload"`
+	Ports        PortsConfig              `json:"ports" yaml:"ports"`
+	Devcontainer DevcontainerImportConfig `json:"devcontainer" yaml:"devcontainer"`
+	Preview      PreviewConfig            `json:"preview" yaml:"preview"`
+	Agents       map[string]AgentConfig   `json:"agents,omitempty" yaml:"agents"`
+}
+
+// ClaudeRepoConfig configures Claude sessions in the repository
+type ClaudeRepoConfig struct {
+	// Instructions appended to Claude's system prompt after the org-wide prompt
+	SystemPrompt string `json:"system_prompt,omitempty" yaml:"system_prompt"`
+	// Summary of the branch's state given to new sessions
+	WarmContext WarmContextConfig `json:"warm_context" yaml:"warm_context"`
+	// Tools Claude may use in the repository's worktrees (unset allows all)
+	AllowedTools []string `json:"allowed_tools,omitempty" yaml:"allowed_tools"`
+}
+
+// DependencyUpdateConfig configures the dependency update workflow
+type DependencyUpdateConfig struct {
+	// Commands that update dependencies, run in order with bash from the worktree root
+	Commands []string `json:"commands,omitempty" yaml:"commands"`
+	// Command that verifies the update (e.g. "pnpm test")
+	Test string `json:"test,omitempty" yaml:"test"`
+	// How many times Claude may try to fix failing tests (default 2, 0 disables)
+	FixAttempts *int `json:"fix_attempts,omitempty" yaml:"fix_attempts"`
+	// Per-command timeout in minutes (default 15)
+	TimeoutMinutes int `json:"timeout_minutes,omitempty" yaml:"timeout_minutes"`
+}
+
+// LoadCatnipConfig reads .catnip.yaml from a directory. A missing file yields
+// an empty config.
+func LoadCatnipConfig(dir string) (*CatnipConfig, error) {
+	cfg := &CatnipConfig{}
+	data, err := os.ReadFile(filepath.Join(dir, CatnipConfigFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", CatnipConfigFileName, err)
+	}
+	return cfg, nil
+}
+
+// DetectDependencyUpdateConfig guesses update and test commands from the
+// lockfiles and manifests in a directory
+func DetectDependencyUpdateConfig(dir string) DependencyUpdateConfig {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	var cfg DependencyUpdateConfig
+	var tests []string
+
+	if exists("package.json") {
+		manager := "npm"
+		switch {
+		case exists("pnpm-lock.yaml"):
+			manager = "pnpm"
+		case exists("yarn.lock"):
+			manager = "yarn"
+		case exists("bun.lockb"), exists("bun.lock"):
+			manager = "bun"
+		}
+		if manager == "yarn" {
+			cfg.Commands = append(cfg.Commands, "yarn upgrade")
+		} else {
+			cfg.Commands = append(cfg.Commands, manager+" update")
+		}
+		if hasPackageScript(filepath.Join(dir, "package.json"), "test") {
+			tests = append(tests, manager+" test")
+		}
+	}
+	if exists("go.mod") {
+		cfg.Commands = append(cfg.Commands, "go get -u ./... && go mod tidy")
+		tests = append(tests, "go test ./...")
+	}
+	if exists("Cargo.toml") {
+		cfg.Commands = append(cfg.Commands, "cargo update")
+		tests = append(tests, "cargo test")
+	}
+	if exists("uv.lock") {
+		cfg.Commands = append(cfg.Commands, "uv lock --upgrade && uv sync")
+	}
+
+	for i, test := range tests {
+		if i > 0 {
+			cfg.Test += " && "
+		}
+		cfg.Test += test
+	}
+	return cfg
+}
+
+func hasPackageScript(packageJSON, script string) bool {
+	data, err := os.ReadFile(packageJSON)
+	if err != nil {
+		return false
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return false
+	}
+	_, ok := pkg.Scripts[script]
+	return ok
+}
@@ -1,6 +1,9 @@
 package services
 
-import "github.com/vanpelt/catnip/internal/git"
+import (
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
 
 // Ensure adapters implement the required interfaces
 var (
@@ -18,9 +21,10 @@ func NewGitServiceAdapter(gs *GitService) *GitServiceAdapter {
 	return &GitServiceAdapter{GitService: gs}
 }
 
-// GitAddCommitGetHash implements git.Service interface
+// GitAddCommitGetHash implements git.Service interface. Checkpoints are
+// agent work, so they get an agent note.
 func (a *GitServiceAdapter) GitAddCommitGetHash(workDir, title string) (string, error) {
-	return a.GitService.GitAddCommitGetHash(workDir, title)
+	return a.GitService.CommitAgentWork(workDir, title, models.AgentCommitCheckpoint)
 }
 
 // RefreshWorktreeStatus implements git.Service interface
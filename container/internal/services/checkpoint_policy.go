@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// EffectiveCheckpointPolicy returns the checkpoint policy for a worktree: the
+// defaults, overridden by its repository's settings, then by its own
+func (s *GitService) EffectiveCheckpointPolicy(worktree *models.Worktree) git.CheckpointPolicy {
+	policy := git.DefaultCheckpointPolicy()
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists {
+		policy = policy.WithOverrides(repo.Checkpoints)
+	}
+	return policy.WithOverrides(worktree.Checkpoints)
+}
+
+// CheckpointPolicy implements git.Service, returning the policy for the
+// worktree that workDir belongs to or the defaults if it isn't one
+func (s *GitService) CheckpointPolicy(workDir string) git.CheckpointPolicy {
+	dir := filepath.Clean(workDir)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		path := filepath.Clean(worktree.Path)
+		if dir == path || strings.HasPrefix(dir, path+string(filepath.Separator)) {
+			return s.EffectiveCheckpointPolicy(worktree)
+		}
+	}
+	return git.DefaultCheckpointPolicy()
+}
+
+// CountDirtyFiles implements git.Service, counting the changed and untracked
+// files a checkpoint in workDir would commit
+func (s *GitService) CountDirtyFiles(workDir string) (int, error) {
+	output, err := s.operations.ExecuteGit(workDir, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get status: %v", err)
+	}
+	count := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// SetWorktreeCheckpointSettings stores checkpoint overrides for a worktree and
+// returns its effective policy. A nil settings value clears the override.
+func (s *GitService) SetWorktreeCheckpointSettings(worktreeID string, settings *models.CheckpointSettings) (git.CheckpointPolicy, error) {
+	if settings != nil {
+		if err := settings.Validate(); err != nil {
+			return git.CheckpointPolicy{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+		}
+	}
+
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return git.CheckpointPolicy{}, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"checkpoints": settings}); err != nil {
+		return git.CheckpointPolicy{}, err
+	}
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return git.CheckpointPolicy{}, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	return s.EffectiveCheckpointPolicy(worktree), nil
+}
+
+// GetRepositoryCheckpointSettings returns a repository's checkpoint override
+// (nil if it uses the defaults) and the policy its worktrees inherit
+func (s *GitService) GetRepositoryCheckpointSettings(repoID string) (*models.CheckpointSettings, git.CheckpointPolicy, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, git.CheckpointPolicy{}, models.NewRepositoryNotFoundError(repoID)
+	}
+	return repo.Checkpoints, git.DefaultCheckpointPolicy().WithOverrides(repo.Checkpoints), nil
+}
+
+// SetRepositoryCheckpointSettings stores checkpoint overrides for a
+// repository. A nil settings value clears the override.
+func (s *GitService) SetRepositoryCheckpointSettings(repoID string, settings *models.CheckpointSettings) (git.CheckpointPolicy, error) {
+	if settings != nil {
+		if err := settings.Validate(); err != nil {
+			return git.CheckpointPolicy{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+		}
+	}
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return git.CheckpointPolicy{}, models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.Checkpoints = settings
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return git.CheckpointPolicy{}, fmt.Errorf("failed to save repository checkpoint settings: %v", err)
+	}
+	return git.DefaultCheckpointPolicy().WithOverrides(settings), nil
+}
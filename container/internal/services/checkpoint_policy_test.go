@@ -0,0 +1,72 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCheckpointPolicy(t *testing.T) {
+	t.Setenv("CATNIP_COMMIT_TIMEOUT_SECONDS", "")
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	tempDir := t.TempDir()
+	firstPath := filepath.Join(tempDir, "first")
+	secondPath := filepath.Join(tempDir, "second")
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "test/repo", Path: filepath.Join(tempDir, "repo")}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-1", RepoID: "test/repo", Path: firstPath}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-2", RepoID: "test/repo", Path: secondPath}))
+
+	t.Run("RejectsInvalidSettings", func(t *testing.T) {
+		_, err := service.SetRepositoryCheckpointSettings("test/repo", &models.CheckpointSettings{IntervalSeconds: 1})
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+		_, err = service.SetWorktreeCheckpointSettings("wt-1", &models.CheckpointSettings{MessageTemplate: "wip\nmore"})
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+	})
+
+	t.Run("WorktreeOverridesRepository", func(t *testing.T) {
+		disabled := true
+		_, err := service.SetRepositoryCheckpointSettings("test/repo", &models.CheckpointSettings{Disabled: &disabled, IntervalSeconds: 600})
+		require.NoError(t, err)
+
+		enabled := false
+		effective, err := service.SetWorktreeCheckpointSettings("wt-1", &models.CheckpointSettings{Disabled: &enabled, MaxDirtyFiles: 200})
+		require.NoError(t, err)
+		assert.False(t, effective.Disabled)
+		assert.Equal(t, 10*time.Minute, effective.Interval)
+		assert.Equal(t, 200, effective.MaxDirtyFiles)
+
+		// Lookups by path match the worktree and its subdirectories
+		assert.Equal(t, effective, service.CheckpointPolicy(filepath.Join(firstPath, "src")))
+		assert.True(t, service.CheckpointPolicy(secondPath).Disabled)
+		assert.Equal(t, git.DefaultCheckpointPolicy(), service.CheckpointPolicy(filepath.Join(tempDir, "elsewhere")))
+	})
+
+	t.Run("ClearingOverrides", func(t *testing.T) {
+		_, err := service.SetWorktreeCheckpointSettings("wt-1", nil)
+		require.NoError(t, err)
+		_, err = service.SetRepositoryCheckpointSettings("test/repo", nil)
+		require.NoError(t, err)
+		assert.Equal(t, git.DefaultCheckpointPolicy(), service.CheckpointPolicy(firstPath))
+	})
+
+	t.Run("CountDirtyFiles", func(t *testing.T) {
+		repoPath := filepath.Join(tempDir, "dirty")
+		require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "nested"), 0755))
+		_, err := service.ExecuteGit(repoPath, "init", "-b", "main")
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "a.txt"), []byte("a"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "nested", "b.txt"), []byte("b"), 0644))
+
+		count, err := service.CountDirtyFiles(repoPath)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+}
@@ -26,16 +26,24 @@ import (
 type ClaudeService struct {
 	claudeConfigPath  string
 	claudeProjectsDir string
-	volumeProjectsDir string
-	settingsPath      string // Path to volume settings.json
-	subprocessWrapper ClaudeSubprocessInterface
-	sessionService    *SessionService // For best session file selection
-	parserService     *ParserService  // Centralized session file parser management
+	// Catnip's snapshot of the project metadata in claudeConfigPath
+	claudeProjectsPath string
+	projectsMutex      sync.Mutex
+	projects           *claudeProjectsSnapshot
+	projectsSyncedAt   time.Time // claudeConfigPath mod time at the last sync
+	volumeProjectsDir  string
+	settingsPath       string // Path to volume settings.json
+	subprocessWrapper  ClaudeSubprocessInterface
+	sessionService     *SessionService // For best session file selection
+	parserService      *ParserService  // Centralized session file parser management
+	gitService         *GitService     // For worktree Claude tool policies
 	// Process registry for persistent streaming processes
 	processRegistry *ClaudeProcessRegistry
 	// Activity tracking for PTY sessions
 	activityMutex sync.RWMutex
 	lastActivity  map[string]time.Time // Map of worktree path to last activity time
+	// Output-based activity tracking for agents without hooks
+	lastAgentOutput map[string]time.Time // Map of worktree path to last agent output time
 	// Hook-based activity tracking
 	lastUserPromptSubmit map[string]time.Time // Map of worktree path to last UserPromptSubmit time
 	lastPostToolUse      map[string]time.Time // Map of worktree path to last PostToolUse time
@@ -71,11 +79,13 @@ func NewClaudeService() *ClaudeService {
 	return &ClaudeService{
 		claudeConfigPath:     filepath.Join(homeDir, ".claude.json"),
 		claudeProjectsDir:    filepath.Join(homeDir, ".claude", "projects"),
+		claudeProjectsPath:   filepath.Join(volumeDir, claudeProjectsFileName),
 		volumeProjectsDir:    filepath.Join(volumeDir, ".claude", ".claude", "projects"),
 		settingsPath:         filepath.Join(volumeDir, "settings.json"),
 		subprocessWrapper:    NewClaudeSubprocessWrapper(),
 		processRegistry:      NewClaudeProcessRegistry(),
 		lastActivity:         make(map[string]time.Time),
+		lastAgentOutput:      make(map[string]time.Time),
 		lastUserPromptSubmit: make(map[string]time.Time),
 		lastPostToolUse:      make(map[string]time.Time),
 		lastStopEvent:        make(map[string]time.Time),
@@ -92,11 +102,13 @@ func NewClaudeServiceWithWrapper(wrapper ClaudeSubprocessInterface) *ClaudeServi
 	return &ClaudeService{
 		claudeConfigPath:     filepath.Join(homeDir, ".claude.json"),
 		claudeProjectsDir:    filepath.Join(homeDir, ".claude", "projects"),
+		claudeProjectsPath:   filepath.Join(volumeDir, claudeProjectsFileName),
 		volumeProjectsDir:    filepath.Join(volumeDir, ".claude", ".claude", "projects"),
 		settingsPath:         filepath.Join(volumeDir, "settings.json"),
 		subprocessWrapper:    wrapper,
 		processRegistry:      NewClaudeProcessRegistry(),
 		lastActivity:         make(map[string]time.Time),
+		lastAgentOutput:      make(map[string]time.Time),
 		lastUserPromptSubmit: make(map[string]time.Time),
 		lastPostToolUse:      make(map[string]time.Time),
 		lastStopEvent:        make(map[string]time.Time),
@@ -115,6 +127,19 @@ func (s *ClaudeService) SetParserService(parserService *ParserService) {
 	s.parserService = parserService
 }
 
+// SetGitService sets the git service whose worktrees' tool policies apply to Claude processes
+func (s *ClaudeService) SetGitService(gitService *GitService) {
+	s.gitService = gitService
+}
+
+// disallowedTools returns the tools withheld from Claude processes in workDir
+func (s *ClaudeService) disallowedTools(workDir string) []string {
+	if s.gitService == nil {
+		return nil
+	}
+	return s.gitService.DisallowedClaudeTools(workDir)
+}
+
 // findProjectDirectory returns the path to the project directory if it exists in either location
 func (s *ClaudeService) findProjectDirectory(projectDirName string) string {
 	// Check local directory first
@@ -257,7 +282,7 @@ func (s *ClaudeService) getSessionTiming(worktreePath string) (*SessionTimingWit
 	projectDir := s.findProjectDirectory(projectDirName)
 
 	if projectDir == "" {
-		return nil, fmt.Errorf("project directory not found for worktree: %s", worktreePath)
+		return nil, models.NewAPIError(models.ErrCodeSessionNotFound, "project directory not found for worktree: %s", worktreePath)
 	}
 
 	// Find the most recent session file
@@ -315,40 +340,6 @@ func (s *ClaudeService) readSessionTiming(sessionFilePath string) (*SessionTimin
 	}, nil
 }
 
-// readClaudeConfig reads and parses the ~/.claude.json file
-func (s *ClaudeService) readClaudeConfig() (map[string]*models.ClaudeProjectMetadata, error) {
-	data, err := os.ReadFile(s.claudeConfigPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// Return empty map if file doesn't exist
-			return make(map[string]*models.ClaudeProjectMetadata), nil
-		}
-		return nil, fmt.Errorf("failed to read claude config file: %w", err)
-	}
-
-	var config struct {
-		Projects map[string]*models.ClaudeProjectMetadata `json:"projects"`
-	}
-
-	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse claude config: %w", err)
-	}
-
-	// Handle case where projects is nil
-	if config.Projects == nil {
-		return make(map[string]*models.ClaudeProjectMetadata), nil
-	}
-
-	// Set the path for each project
-	for path, project := range config.Projects {
-		project.Path = path
-	}
-
-	// Note: History is no longer loaded here - use parser.HistoryReader via parserService instead
-	// This keeps project metadata separate from user prompt history
-	return config.Projects, nil
-}
-
 // GetFullSessionData gets complete session data for a workspace including all messages
 func (s *ClaudeService) GetFullSessionData(worktreePath string, includeFullData bool) (*models.FullSessionData, error) {
 	// Get basic session summary
@@ -571,7 +562,7 @@ func (s *ClaudeService) GetSessionMessages(worktreePath, sessionID string) ([]mo
 	projectDir := s.findProjectDirectory(projectDirName)
 
 	if projectDir == "" {
-		return nil, fmt.Errorf("project directory not found for worktree: %s", worktreePath)
+		return nil, models.NewAPIError(models.ErrCodeSessionNotFound, "project directory not found for worktree: %s", worktreePath)
 	}
 
 	sessionFile := filepath.Join(projectDir, sessionID+".jsonl")
@@ -618,7 +609,7 @@ func (s *ClaudeService) GetSessionByID(worktreePath, sessionID string) (*models.
 	}
 
 	if targetSession == nil {
-		return nil, fmt.Errorf("session not found: %s", sessionID)
+		return nil, models.NewAPIError(models.ErrCodeSessionNotFound, "session not found: %s", sessionID)
 	}
 
 	// Create session summary for this specific session
@@ -681,7 +672,7 @@ func (s *ClaudeService) GetSessionByUUID(sessionUUID string) (*models.FullSessio
 	}
 
 	if targetWorktree == "" {
-		return nil, fmt.Errorf("session not found: %s", sessionUUID)
+		return nil, models.NewAPIError(models.ErrCodeSessionNotFound, "session not found: %s", sessionUUID)
 	}
 
 	// Get the session data using the existing method
@@ -868,16 +859,18 @@ func (s *ClaudeService) CreateCompletion(ctx context.Context, req *models.Create
 
 	// Set up subprocess options
 	opts := &ClaudeSubprocessOptions{
-		Prompt:           req.Prompt,
-		SystemPrompt:     req.SystemPrompt,
-		Model:            req.Model,
-		MaxTurns:         req.MaxTurns,
-		WorkingDirectory: workingDir,
-		Resume:           req.Resume,
-		Fork:             fork,
-		SessionID:        sessionID,
-		SuppressEvents:   suppressEvents,
-		DisableTools:     req.DisableTools,
+		Prompt:             req.Prompt,
+		SystemPrompt:       req.SystemPrompt,
+		AppendSystemPrompt: s.completionAppendPrompt(req, workingDir),
+		Model:              req.Model,
+		MaxTurns:           req.MaxTurns,
+		WorkingDirectory:   workingDir,
+		Resume:             req.Resume,
+		Fork:               fork,
+		SessionID:          sessionID,
+		SuppressEvents:     suppressEvents,
+		DisableTools:       req.DisableTools,
+		DisallowedTools:    s.disallowedTools(workingDir),
 	}
 
 	// Enable event suppression for automated operations
@@ -972,16 +965,20 @@ func (s *ClaudeService) CreateStreamingCompletion(ctx context.Context, req *mode
 
 	// Set up subprocess options for streaming
 	opts := &ClaudeSubprocessOptions{
-		Prompt:           req.Prompt,
-		SystemPrompt:     req.SystemPrompt,
-		Model:            req.Model,
-		MaxTurns:         req.MaxTurns,
-		WorkingDirectory: workingDir,
-		Resume:           req.Resume,
-		Fork:             fork,
-		SessionID:        sessionID,
-		SuppressEvents:   suppressEvents,
-		DisableTools:     req.DisableTools,
+		Prompt:             req.Prompt,
+		SystemPrompt:       req.SystemPrompt,
+		AppendSystemPrompt: s.completionAppendPrompt(req, workingDir),
+		Model:              req.Model,
+		MaxTurns:           req.MaxTurns,
+		WorkingDirectory:   workingDir,
+		Resume:             req.Resume,
+		Fork:               fork,
+		SessionID:          sessionID,
+		SuppressEvents:     suppressEvents,
+		DisableTools:       req.DisableTools,
+		DisallowedTools:    s.disallowedTools(workingDir),
+		Budget:             req.Budget,
+		RelayPermissions:   req.RelayPermissions,
 	}
 
 	// Enable event suppression for automated operations
@@ -1130,6 +1127,7 @@ func (s *ClaudeService) GetClaudeSettings() (*models.ClaudeSettings, error) {
 				HasCompletedOnboarding: false,
 				NumStartups:            0,
 				NotificationsEnabled:   true, // Default to enabled
+				SystemPrompt:           s.orgSystemPrompt(),
 			}, nil
 		}
 		return nil, fmt.Errorf("failed to read claude config file: %w", err)
@@ -1189,6 +1187,7 @@ func (s *ClaudeService) GetClaudeSettings() (*models.ClaudeSettings, error) {
 	if err == nil {
 		settings.NotificationsEnabled = notificationsEnabled
 	}
+	settings.SystemPrompt = s.orgSystemPrompt()
 
 	return settings, nil
 }
@@ -1249,24 +1248,22 @@ func (s *ClaudeService) UpdateClaudeSettings(req *models.ClaudeSettingsUpdateReq
 		}
 	}
 
+	// Handle the org-wide system prompt (update volume settings.json)
+	if req.SystemPrompt != nil {
+		if err := s.writeVolumeSetting("systemPrompt", strings.TrimSpace(*req.SystemPrompt)); err != nil {
+			return nil, fmt.Errorf("failed to update system prompt: %w", err)
+		}
+	}
+
 	// Return updated settings
 	return s.GetClaudeSettings()
 }
 
 // getNotificationsEnabled reads notifications setting from volume settings.json
 func (s *ClaudeService) getNotificationsEnabled() (bool, error) {
-	data, err := os.ReadFile(s.settingsPath)
+	settings, err := s.readVolumeSettings()
 	if err != nil {
-		if os.IsNotExist(err) {
-			// Default to enabled if file doesn't exist
-			return true, nil
-		}
-		return false, fmt.Errorf("failed to read settings file: %w", err)
-	}
-
-	var settings map[string]interface{}
-	if err := json.Unmarshal(data, &settings); err != nil {
-		return false, fmt.Errorf("failed to parse settings file: %w", err)
+		return false, err
 	}
 
 	if notifications, exists := settings["notificationsEnabled"]; exists {
@@ -1281,25 +1278,35 @@ func (s *ClaudeService) getNotificationsEnabled() (bool, error) {
 
 // setNotificationsEnabled writes notifications setting to volume settings.json
 func (s *ClaudeService) setNotificationsEnabled(enabled bool) error {
-	// Read current settings or create new ones
-	var settings map[string]interface{}
+	return s.writeVolumeSetting("notificationsEnabled", enabled)
+}
+
+// readVolumeSettings reads volume settings.json (empty if it doesn't exist)
+func (s *ClaudeService) readVolumeSettings() (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
 
 	data, err := os.ReadFile(s.settingsPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			// Create new settings if file doesn't exist
-			settings = make(map[string]interface{})
-		} else {
-			return fmt.Errorf("failed to read settings file: %w", err)
-		}
-	} else {
-		if err := json.Unmarshal(data, &settings); err != nil {
-			return fmt.Errorf("failed to parse settings file: %w", err)
+			return settings, nil
 		}
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
 	}
 
-	// Update notifications setting
-	settings["notificationsEnabled"] = enabled
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file: %w", err)
+	}
+	return settings, nil
+}
+
+// writeVolumeSetting sets one key in volume settings.json, keeping the others
+func (s *ClaudeService) writeVolumeSetting(key string, value interface{}) error {
+	settings, err := s.readVolumeSettings()
+	if err != nil {
+		return err
+	}
+
+	settings[key] = value
 
 	// Write back to file with proper formatting
 	updatedData, err := json.MarshalIndent(settings, "", "  ")
@@ -1340,6 +1347,24 @@ func (s *ClaudeService) UpdateActivity(worktreePath string) {
 	s.activityMutex.Unlock()
 }
 
+// RecordAgentOutput records output from an agent that doesn't report its
+// activity through hooks (aider, codex, ...), which counts as it working
+func (s *ClaudeService) RecordAgentOutput(worktreePath string) {
+	now := time.Now()
+	s.activityMutex.Lock()
+	s.lastActivity[worktreePath] = now
+	s.lastAgentOutput[worktreePath] = now
+	s.activityMutex.Unlock()
+}
+
+// GetLastAgentOutput returns when an agent without hooks last wrote output in
+// a worktree, or zero time if none has
+func (s *ClaudeService) GetLastAgentOutput(worktreePath string) time.Time {
+	s.activityMutex.RLock()
+	defer s.activityMutex.RUnlock()
+	return s.lastAgentOutput[worktreePath]
+}
+
 // GetLastActivity returns the last activity time for a worktree, or zero time if no activity
 func (s *ClaudeService) GetLastActivity(worktreePath string) time.Time {
 	s.activityMutex.RLock()
@@ -1479,6 +1504,21 @@ func (s *ClaudeService) HandleHookEvent(event *models.ClaudeHookEvent) error {
 	}
 }
 
+// BudgetHookResponse enforces time-boxed sessions from hook events. It returns
+// hook output asking Claude to wrap up once the session's budget is spent, and
+// stops the process after Claude has finished that turn.
+func (s *ClaudeService) BudgetHookResponse(event *models.ClaudeHookEvent) *models.ClaudeHookResponse {
+	if s.processRegistry == nil {
+		return nil
+	}
+	worktreeRoot := s.normalizeToWorktreeRoot(event.WorkingDirectory)
+	response, stop := s.processRegistry.Budgets().HookResponse(worktreeRoot, event.EventType)
+	if stop {
+		s.processRegistry.StopBudgetedProcess(worktreeRoot)
+	}
+	return response
+}
+
 // GetLastSessionStart returns the last SessionStart event time for a worktree
 func (s *ClaudeService) GetLastSessionStart(worktreePath string) time.Time {
 	s.activityMutex.RLock()
@@ -1539,16 +1579,11 @@ func (s *ClaudeService) CleanupWorktreeClaudeFiles(worktreePath string) error {
 		}
 	}
 
-	// TODO: CRITICAL BUG FIX - DO NOT MODIFY CLAUDE.JSON DURING OPERATION!
-	// The removeClaudeConfigEntry function has a catastrophic bug that destroys user authentication.
-	// It only preserves the 'projects' field and nukes all OAuth/auth data when writing back.
-	// We should NEVER modify ~/.claude.json during operation - it should be READ-ONLY.
-	// Consider using a separate metadata file like ~/.catnip-projects.json for runtime tracking.
-	//
-	// DISABLED to prevent auth corruption:
-	// if err := s.removeClaudeConfigEntry(worktreePath); err != nil {
-	// 	cleanupErrors = append(cleanupErrors, fmt.Sprintf("failed to clean claude.json entry: %v", err))
-	// }
+	// Forget the project metadata in catnip's snapshot; ~/.claude.json holds
+	// the user's auth and is never written here
+	if err := s.removeClaudeProjectEntry(worktreePath); err != nil {
+		cleanupErrors = append(cleanupErrors, fmt.Sprintf("failed to remove claude project metadata: %v", err))
+	}
 
 	// Clear in-memory activity tracking for this worktree
 	s.activityMutex.Lock()
@@ -1572,65 +1607,6 @@ func (s *ClaudeService) CleanupWorktreeClaudeFiles(worktreePath string) error {
 	return nil
 }
 
-// removeClaudeConfigEntry removes the claude.json entry for a specific worktree path
-//
-// ⚠️  CRITICAL BUG: This function has a catastrophic bug that destroys user authentication!
-// It only preserves the 'projects' field (lines 1503-1507) and overwrites the entire file,
-// which NUKES all OAuth account data, custom API keys, and other critical auth information.
-// This function should NEVER be called during operation - claude.json should be READ-ONLY.
-//
-// TODO: Replace with separate metadata file like ~/.catnip-projects.json for runtime tracking.
-//
-//nolint:unused // TODO: Remove after claude.json management is refactored
-func (s *ClaudeService) removeClaudeConfigEntry(worktreePath string) error {
-	// Read current config
-	claudeConfig, err := s.readClaudeConfig()
-	if err != nil {
-		return fmt.Errorf("failed to read claude config: %w", err)
-	}
-
-	// Check if entry exists
-	if _, exists := claudeConfig[worktreePath]; !exists {
-		return nil // Nothing to remove
-	}
-
-	// Remove the entry
-	delete(claudeConfig, worktreePath)
-
-	// Write back the config
-	configData := struct {
-		Projects map[string]*models.ClaudeProjectMetadata `json:"projects"`
-	}{
-		Projects: claudeConfig,
-	}
-
-	updatedData, err := json.MarshalIndent(configData, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated config: %w", err)
-	}
-
-	// Create a temporary file first (atomic write)
-	tempFile := s.claudeConfigPath + ".tmp"
-	if err := os.WriteFile(tempFile, updatedData, 0644); err != nil {
-		return fmt.Errorf("failed to write temp config file: %w", err)
-	}
-
-	// Atomically rename temp file to final destination
-	if err := os.Rename(tempFile, s.claudeConfigPath); err != nil {
-		os.Remove(tempFile) // Clean up temp file on error
-		return fmt.Errorf("failed to update config file: %w", err)
-	}
-
-	// Set proper ownership for catnip user
-	if err := os.Chown(s.claudeConfigPath, 1000, 1000); err != nil {
-		// Log but don't fail
-		logger.Warnf("Warning: Failed to chown %s: %v", s.claudeConfigPath, err)
-	}
-
-	logger.Debugf("✅ Removed claude.json entry for worktree: %s", worktreePath)
-	return nil
-}
-
 // GetProcessRegistry returns the process registry for external access
 func (s *ClaudeService) GetProcessRegistry() *ClaudeProcessRegistry {
 	return s.processRegistry
@@ -1737,6 +1713,7 @@ func (m *ClaudePTYManager) createPTY() error {
 	} else {
 		logger.Debugf("🤖 Starting new Claude Code session for PTY streaming")
 	}
+	args = append(args, disallowedToolsArgs(m.claudeService.disallowedTools(m.workingDir))...)
 
 	// Create command
 	m.cmd = exec.Command(claudePath, args...)
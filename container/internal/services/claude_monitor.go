@@ -721,6 +721,9 @@ func (m *WorktreeCheckpointManager) HandleTitleChange(newTitle string) {
 // startCheckpointTimer starts or restarts the checkpoint timer
 func (m *WorktreeCheckpointManager) startCheckpointTimer() {
 	timeout := git.GetCheckpointTimeout()
+	if m.gitService != nil {
+		timeout = m.gitService.CheckpointPolicy(m.workDir).Interval
+	}
 	// Start timer silently
 	m.checkpointTimer = time.AfterFunc(timeout, func() {
 		m.timerMutex.Lock()
@@ -766,7 +769,7 @@ func (m *WorktreeCheckpointManager) commitPreviousWork(title string) {
 		return
 	}
 
-	commitHash, err := m.gitService.GitAddCommitGetHash(m.workDir, title)
+	commitHash, err := m.gitService.CommitAgentWork(m.workDir, title, models.AgentCommitTitle)
 	if err != nil {
 		logger.Warnf("⚠️  Failed to commit previous work: %v", err)
 		return
@@ -840,6 +843,20 @@ func (m *WorktreeCheckpointManager) checkAndRenameBranch(title string) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
+	// Ask for names that already follow the repository's naming policy
+	var policyRules string
+	if policy := m.gitService.branchNamingPolicyForWorktree(m.worktreeID); policy != nil {
+		if len(policy.Prefixes) > 0 {
+			policyRules += fmt.Sprintf("\n5. MUST start with one of: %s", strings.Join(policy.Prefixes, ", "))
+		}
+		if policy.Pattern != "" {
+			policyRules += fmt.Sprintf("\n6. MUST match the regular expression: %s", policy.Pattern)
+		}
+		if policy.MaxLength > 0 {
+			policyRules += fmt.Sprintf("\n7. MUST be at most %d characters", policy.MaxLength)
+		}
+	}
+
 	req := &models.CreateCompletionRequest{
 		Prompt: fmt.Sprintf(`Based on this coding session title: "%s"
 
@@ -847,9 +864,9 @@ Generate a git branch name that:
 1. Follows conventional patterns like: feature/add-auth, chore/update-deps, refactor/cleanup-api, bug/fix-login, docs/update-readme
 2. Uses only lowercase letters, numbers, hyphens, and forward slashes
 3. Is concise but descriptive (max 60 characters)
-4. Common prefixes: feature, chore, refactor, bug, docs, test, style, perf, fix
+4. Common prefixes: feature, chore, refactor, bug, docs, test, style, perf, fix%s
 
-Respond with ONLY the branch name, nothing else.`, cleanedTitle),
+Respond with ONLY the branch name, nothing else.`, cleanedTitle, policyRules),
 		SystemPrompt:     "You are a helpful assistant that generates git branch names. Respond only with the branch name, no explanation or additional text.",
 		MaxTurns:         1,
 		WorkingDirectory: m.workDir,
@@ -880,6 +897,15 @@ Respond with ONLY the branch name, nothing else.`, cleanedTitle),
 		return
 	}
 
+	// Bring the name in line with the repository's naming policy
+	if compliant, ok := m.gitService.compliantBranchName(m.worktreeID, newBranch); !ok {
+		logger.Warnf("⚠️  Claude suggested branch name %q breaks the naming policy and can't be fixed up", newBranch)
+		return
+	} else if compliant != newBranch {
+		logger.Debugf("📏 Adjusted branch name %q to %q to follow the naming policy", newBranch, compliant)
+		newBranch = compliant
+	}
+
 	// Check if the new branch name already exists and append numbers if needed
 	logger.Debugf("🔍 Checking if branch %q exists in %s", newBranch, m.workDir)
 	finalBranch := newBranch
@@ -1058,6 +1084,9 @@ func (s *ClaudeMonitorService) TriggerBranchRename(workDir string, customBranchN
 		if !manager.isValidGitBranchName(customBranchName) {
 			return fmt.Errorf("invalid branch name: %q", customBranchName)
 		}
+		if err := s.gitService.ValidateWorktreeBranchName(manager.worktreeID, customBranchName); err != nil {
+			return err
+		}
 
 		// Check if the branch already exists and append numbers if needed
 		finalBranch := customBranchName
@@ -1512,6 +1541,10 @@ func (s *ClaudeMonitorService) GetClaudeService() *ClaudeService {
 	return s.claudeService
 }
 
+// agentOutputActiveWindow is how long after its last output an agent without
+// hooks still counts as working
+const agentOutputActiveWindow = 15 * time.Second
+
 // GetClaudeActivityState returns the Claude activity state based on hook events and PTY activity tracking
 func (s *ClaudeMonitorService) GetClaudeActivityState(worktreePath string) models.ClaudeActivityState {
 	now := time.Now()
@@ -1548,6 +1581,11 @@ func (s *ClaudeMonitorService) GetClaudeActivityState(worktreePath string) model
 		return models.ClaudeActive
 	}
 
+	// ACTIVE: an agent without hooks is writing output
+	if lastOutput := s.claudeService.GetLastAgentOutput(worktreePath); !lastOutput.IsZero() && now.Sub(lastOutput) <= agentOutputActiveWindow {
+		return models.ClaudeActive
+	}
+
 	// RUNNING: Session active but not generating (PTY activity)
 	// Check if there's an active PTY session - real user interaction
 	if s.sessionService.IsActiveSessionActive(worktreePath) {
@@ -10,6 +10,7 @@ import (
 	"github.com/vanpelt/catnip/internal/claude/paths"
 	"github.com/vanpelt/catnip/internal/config"
 	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
 )
 
 // ParserService manages Claude session file parser instances as a singleton per session file
@@ -17,9 +18,11 @@ import (
 type ParserService struct {
 	parsers       map[string]*parserInstance // key: session file path
 	parsersMutex  sync.RWMutex
-	claudeService *ClaudeService        // For finding project directories
-	historyReader *parser.HistoryReader // Singleton history reader for user prompts
-	maxParsers    int                   // Maximum number of parsers to keep in memory (LRU eviction)
+	claudeService *ClaudeService           // For finding project directories
+	historyReader *parser.HistoryReader    // Singleton history reader for user prompts
+	maxParsers    int                      // Maximum number of parsers to keep in memory (LRU eviction)
+	integrity     *SessionIntegrityService // Repairs session files with malformed lines
+	transcripts   *TranscriptIndex         // Full-text index kept current as sessions are read
 	stopCh        chan struct{}
 }
 
@@ -49,10 +52,29 @@ func (s *ParserService) SetClaudeService(claudeService *ClaudeService) {
 	s.claudeService = claudeService
 }
 
+// SetIntegrityService enables quarantining malformed session lines when a parser first reads a file
+func (s *ParserService) SetIntegrityService(integrity *SessionIntegrityService) {
+	s.parsersMutex.Lock()
+	defer s.parsersMutex.Unlock()
+	s.integrity = integrity
+}
+
+// SetTranscriptIndex keeps a transcript search index current with the session files parsers read
+func (s *ParserService) SetTranscriptIndex(transcripts *TranscriptIndex) {
+	s.parsersMutex.Lock()
+	defer s.parsersMutex.Unlock()
+	s.transcripts = transcripts
+}
+
 // Start begins the parser service lifecycle (periodic cleanup)
 func (s *ParserService) Start() {
 	logger.Info("🔧 Starting Claude session parser service")
 
+	// Build the transcript index in the background; it's updated incrementally after this
+	if s.transcripts != nil {
+		go s.transcripts.Sync()
+	}
+
 	// Start periodic cleanup of stale parsers
 	go s.cleanupLoop()
 }
@@ -101,6 +123,16 @@ func (s *ParserService) GetOrCreateParser(worktreePath string) (*parser.SessionF
 		// Continue anyway - parser will retry on next access
 	}
 
+	// Malformed lines were skipped; quarantine them in the background so the
+	// file (and anything resuming it) is clean on the next read
+	if s.integrity != nil && (len(reader.GetMalformedLines()) > 0 || reader.HasPartialTail()) {
+		logger.Warnf("⚠️  Session file %s has %d malformed lines", sessionFile, len(reader.GetMalformedLines()))
+		go s.integrity.RepairOnAccess(reader)
+	}
+	if s.transcripts != nil {
+		go s.transcripts.Update(sessionFile)
+	}
+
 	instance := &parserInstance{
 		reader:       reader,
 		lastAccess:   time.Now(),
@@ -126,9 +158,13 @@ func (s *ParserService) RefreshParser(worktreePath string) error {
 	}
 
 	// Force an incremental read
-	if _, err := reader.ReadIncremental(); err != nil {
+	newMessages, err := reader.ReadIncremental()
+	if err != nil {
 		return fmt.Errorf("failed to refresh parser: %w", err)
 	}
+	if len(newMessages) > 0 && s.transcripts != nil {
+		go s.transcripts.Update(reader.GetFilePath())
+	}
 
 	return nil
 }
@@ -186,7 +222,7 @@ func (s *ParserService) findSessionFile(worktreePath string) (string, error) {
 		return sessionFile, nil
 	}
 
-	return "", fmt.Errorf("no session file found for worktree: %s", worktreePath)
+	return "", models.NewAPIError(models.ErrCodeSessionNotFound, "no session file found for worktree: %s", worktreePath)
 }
 
 // evictIfNeeded evicts least recently used parsers if we exceed maxParsers
@@ -222,6 +258,10 @@ func (s *ParserService) cleanupLoop() {
 		select {
 		case <-ticker.C:
 			s.cleanupStaleParsers()
+			// Pick up sessions no parser has read, such as other projects'
+			if s.transcripts != nil {
+				s.transcripts.Sync()
+			}
 		case <-s.stopCh:
 			return
 		}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os/exec"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vanpelt/catnip/internal/logger"
@@ -30,6 +31,18 @@ type ActiveClaudeProcess struct {
 	// Client management
 	clientsMutex sync.RWMutex
 	clients      map[string]chan []byte // client ID -> output channel
+
+	// Input management; budgeted processes keep stdin open for the wrap-up prompt
+	stdinMutex  sync.Mutex
+	stdin       io.WriteCloser
+	inputClosed bool
+
+	// Budget tracking (nil when the process has no budget)
+	budgets          *SessionBudgetTracker
+	stoppedForBudget atomic.Bool
+
+	// Permission prompts are relayed here instead of being skipped (nil unless requested)
+	permissions *PermissionRelay
 }
 
 // ClaudeProcessRegistry manages persistent Claude processes
@@ -42,6 +55,13 @@ type ClaudeProcessRegistry struct {
 	processTimeout  time.Duration
 	stopCleanup     chan struct{}
 	cleanupWg       sync.WaitGroup
+
+	// Budget enforcement for time-boxed sessions
+	budgets             *SessionBudgetTracker
+	budgetCheckInterval time.Duration
+
+	// Permission prompts from processes started with RelayPermissions
+	permissions *PermissionRelay
 }
 
 // NewClaudeProcessRegistry creates a new process registry
@@ -51,6 +71,10 @@ func NewClaudeProcessRegistry() *ClaudeProcessRegistry {
 		cleanupInterval: 1 * time.Minute,  // Check every minute
 		processTimeout:  10 * time.Minute, // Kill processes after 10 minutes of inactivity
 		stopCleanup:     make(chan struct{}),
+
+		budgets:             NewSessionBudgetTracker(),
+		budgetCheckInterval: 5 * time.Second,
+		permissions:         NewPermissionRelay(),
 	}
 
 	// Start cleanup goroutine
@@ -107,6 +131,12 @@ func (r *ClaudeProcessRegistry) createProcess(opts *ClaudeSubprocessOptions, wra
 		done:             make(chan struct{}),
 		clients:          make(map[string]chan []byte),
 	}
+	if opts.Budget != nil {
+		process.budgets = r.budgets
+	}
+	if opts.RelayPermissions {
+		process.permissions = r.permissions
+	}
 
 	// Start the Claude process using the existing wrapper logic but with persistent context
 	cmd, err := r.startClaudeProcess(ctx, opts, wrapper, process)
@@ -117,6 +147,11 @@ func (r *ClaudeProcessRegistry) createProcess(opts *ClaudeSubprocessOptions, wra
 
 	process.Process = cmd
 
+	if opts.Budget != nil {
+		r.budgets.Start(opts.WorkingDirectory, *opts.Budget)
+		go r.enforceBudget(process)
+	}
+
 	// Start goroutine to monitor process completion
 	go func() {
 		defer close(process.done)
@@ -127,6 +162,12 @@ func (r *ClaudeProcessRegistry) createProcess(opts *ClaudeSubprocessOptions, wra
 		} else {
 			logger.Debugf("✅ Claude process completed successfully for %s", opts.WorkingDirectory)
 		}
+		if process.budgets != nil {
+			r.budgets.End(opts.WorkingDirectory, process.stoppedForBudget.Load())
+		}
+		if process.permissions != nil {
+			process.permissions.Cancel(opts.WorkingDirectory)
+		}
 
 		// Remove from registry when process completes
 		r.processesMutex.Lock()
@@ -156,11 +197,19 @@ func (r *ClaudeProcessRegistry) startClaudeProcess(ctx context.Context, opts *Cl
 	args = append(args, "--output-format=stream-json")
 	args = append(args, "--input-format=stream-json")
 	args = append(args, "--verbose")
-	args = append(args, "--dangerously-skip-permissions")
+	if opts.RelayPermissions {
+		// Claude sends can_use_tool control requests on stdout and reads the answers on stdin
+		args = append(args, "--permission-prompt-tool", "stdio")
+	} else {
+		args = append(args, "--dangerously-skip-permissions")
+	}
 
 	if opts.SystemPrompt != "" {
 		args = append(args, "--system-prompt", opts.SystemPrompt)
 	}
+	if opts.AppendSystemPrompt != "" {
+		args = append(args, "--append-system-prompt", opts.AppendSystemPrompt)
+	}
 	if opts.Model != "" {
 		args = append(args, "--model", opts.Model)
 	}
@@ -179,6 +228,7 @@ func (r *ClaudeProcessRegistry) startClaudeProcess(ctx context.Context, opts *Cl
 			logger.Debug("🔄 Using --continue for auto-resume")
 		}
 	}
+	args = append(args, disallowedToolsArgs(opts.DisallowedTools)...)
 
 	cmd := exec.CommandContext(ctx, wrapper.claudePath, args...)
 	cmd.Dir = opts.WorkingDirectory
@@ -205,27 +255,16 @@ func (r *ClaudeProcessRegistry) startClaudeProcess(ctx context.Context, opts *Cl
 	}
 
 	// Send initial prompt
+	process.stdin = stdin
 	go func() {
-		defer stdin.Close()
-		message := map[string]interface{}{
-			"type": "user",
-			"message": map[string]string{
-				"role":    "user",
-				"content": opts.Prompt,
-			},
+		if err := process.sendUserMessage(opts.Prompt); err != nil {
+			logger.Errorf("Failed to write message to stdin: %v", err)
 		}
-
-		if messageJSON, err := json.Marshal(message); err == nil {
-			if _, writeErr := stdin.Write(messageJSON); writeErr != nil {
-				logger.Errorf("Failed to write message to stdin: %v", writeErr)
-				return
-			}
-			if _, writeErr := stdin.Write([]byte("\n")); writeErr != nil {
-				logger.Errorf("Failed to write newline to stdin: %v", writeErr)
-				return
-			}
-		} else {
-			logger.Errorf("Failed to marshal message: %v", err)
+		// Budgeted sessions keep stdin open so a wrap-up prompt can be queued,
+		// and relayed sessions so permission answers can be written; they
+		// close it when Claude finishes a turn
+		if opts.Budget == nil && !opts.RelayPermissions {
+			process.closeInput()
 		}
 	}()
 
@@ -241,6 +280,44 @@ func (r *ClaudeProcessRegistry) startClaudeProcess(ctx context.Context, opts *Cl
 	return cmd, nil
 }
 
+// sendUserMessage writes a user message to the process's stream-json input
+func (p *ActiveClaudeProcess) sendUserMessage(content string) error {
+	return p.writeInput(map[string]interface{}{
+		"type": "user",
+		"message": map[string]string{
+			"role":    "user",
+			"content": content,
+		},
+	})
+}
+
+// writeInput writes one stream-json line to the process's input
+func (p *ActiveClaudeProcess) writeInput(message interface{}) error {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	p.stdinMutex.Lock()
+	defer p.stdinMutex.Unlock()
+	if p.inputClosed || p.stdin == nil {
+		return fmt.Errorf("input for %s is closed", p.WorkingDirectory)
+	}
+	_, err = p.stdin.Write(append(messageJSON, '\n'))
+	return err
+}
+
+// closeInput closes stdin so Claude exits once it has handled queued messages
+func (p *ActiveClaudeProcess) closeInput() {
+	p.stdinMutex.Lock()
+	defer p.stdinMutex.Unlock()
+	if p.inputClosed || p.stdin == nil {
+		return
+	}
+	p.inputClosed = true
+	_ = p.stdin.Close()
+}
+
 // AddClient adds a client to receive process output
 func (p *ActiveClaudeProcess) AddClient(clientID string) <-chan []byte {
 	p.clientsMutex.Lock()
@@ -283,8 +360,25 @@ func (p *ActiveClaudeProcess) broadcastOutput(stdout io.Reader) {
 			continue // Skip invalid JSON lines
 		}
 
+		msgType, _ := jsonData["type"].(string)
+		if p.budgets != nil {
+			p.trackBudget(msgType, jsonData)
+		}
+		switch msgType {
+		case "control_request":
+			p.relayControlRequest(jsonData)
+			continue
+		case "result":
+			// Input held open for wrap-up prompts or permission answers isn't needed anymore
+			p.closeInput()
+			if responseJSON, err := json.Marshal(completionResultResponse(jsonData)); err == nil {
+				p.broadcastToClients(append(responseJSON, '\n'))
+			}
+			continue
+		}
+
 		// Look for assistant messages and broadcast them
-		if msgType, ok := jsonData["type"].(string); ok && msgType == "assistant" {
+		if msgType == "assistant" {
 			// Parse and extract just the text content
 			var responseText string
 			if message, ok := jsonData["message"].(map[string]interface{}); ok {
@@ -298,7 +392,7 @@ func (p *ActiveClaudeProcess) broadcastOutput(stdout io.Reader) {
 			}
 
 			if responseText == "" {
-				responseText = "No text content found in assistant response"
+				responseText = NoAssistantText
 			}
 
 			// Create a clean response chunk
@@ -326,6 +420,90 @@ func (p *ActiveClaudeProcess) broadcastOutput(stdout io.Reader) {
 	logger.Debugf("📡 Output broadcaster finished for %s", p.WorkingDirectory)
 }
 
+// completionResultResponse turns Claude's result event into the last chunk
+// of a stream, so clients learn how the task ended
+func completionResultResponse(jsonData map[string]interface{}) *models.CreateCompletionResponse {
+	result := &models.CompletionResult{}
+	result.Subtype, _ = jsonData["subtype"].(string)
+	result.IsError, _ = jsonData["is_error"].(bool)
+	result.SessionID, _ = jsonData["session_id"].(string)
+	if turns, ok := jsonData["num_turns"].(float64); ok {
+		result.NumTurns = int(turns)
+	}
+	if duration, ok := jsonData["duration_ms"].(float64); ok {
+		result.DurationMS = int64(duration)
+	}
+	result.TotalCostUSD, _ = jsonData["total_cost_usd"].(float64)
+
+	response := &models.CreateCompletionResponse{
+		IsChunk: true,
+		IsLast:  true,
+		Result:  result,
+	}
+	if result.IsError || (result.Subtype != "" && result.Subtype != "success") {
+		response.Error, _ = jsonData["result"].(string)
+		if response.Error == "" {
+			response.Error = "Claude stopped: " + result.Subtype
+		}
+	}
+	return response
+}
+
+// trackBudget records token usage of a budgeted process and closes its input
+// when Claude finishes a turn, so any queued wrap-up prompt is the last one
+func (p *ActiveClaudeProcess) trackBudget(msgType string, jsonData map[string]interface{}) {
+	switch msgType {
+	case "assistant":
+		message, ok := jsonData["message"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		usage, ok := message["usage"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		input, _ := usage["input_tokens"].(float64)
+		output, _ := usage["output_tokens"].(float64)
+		messageID, _ := message["id"].(string)
+		p.budgets.RecordUsage(p.WorkingDirectory, messageID, int(input+output))
+		p.budgets.Check(p.WorkingDirectory)
+	}
+}
+
+// relayControlRequest hands a permission prompt to the relay and streams it
+// to clients; the answer is written back to Claude's input when it's decided
+func (p *ActiveClaudeProcess) relayControlRequest(jsonData map[string]interface{}) {
+	requestID, toolName, input, err := parsePermissionControlRequest(jsonData)
+	if err != nil || p.permissions == nil {
+		if err == nil {
+			err = fmt.Errorf("permission relay is not enabled")
+		}
+		logger.Warnf("⚠️ Rejecting control request from Claude in %s: %v", p.WorkingDirectory, err)
+		if requestID != "" {
+			_ = p.writeInput(map[string]interface{}{
+				"type": "control_response",
+				"response": map[string]interface{}{
+					"subtype":    "error",
+					"request_id": requestID,
+					"error":      err.Error(),
+				},
+			})
+		}
+		return
+	}
+
+	request := p.permissions.Request(p.WorkingDirectory, toolName, input, func(decision models.ClaudePermissionDecision) error {
+		return p.writeInput(permissionControlResponse(requestID, decision))
+	})
+	response := &models.CreateCompletionResponse{
+		IsChunk:    true,
+		Permission: &request,
+	}
+	if responseJSON, err := json.Marshal(response); err == nil {
+		p.broadcastToClients(append(responseJSON, '\n'))
+	}
+}
+
 // broadcastToClients sends output to all connected clients
 func (p *ActiveClaudeProcess) broadcastToClients(data []byte) {
 	p.clientsMutex.RLock()
@@ -358,6 +536,59 @@ func (p *ActiveClaudeProcess) Stop() {
 	p.cancel()
 }
 
+// enforceBudget checks a budgeted process until it exits. Once over budget,
+// the wrap-up prompt is queued on its input (unless a hook delivered it
+// mid-turn), and the process is stopped when the grace period runs out.
+func (r *ClaudeProcessRegistry) enforceBudget(process *ActiveClaudeProcess) {
+	ticker := time.NewTicker(r.budgetCheckInterval)
+	defer ticker.Stop()
+
+	workingDir := process.WorkingDirectory
+	for {
+		select {
+		case <-process.done:
+			return
+		case <-ticker.C:
+			r.budgets.Check(workingDir)
+			if prompt, ok := r.budgets.ClaimWrapUp(workingDir, "input"); ok {
+				if err := process.sendUserMessage(prompt); err != nil {
+					logger.Debugf("⏰ Could not queue wrap-up prompt for %s: %v", workingDir, err)
+				}
+			}
+			if r.budgets.GraceExpired(workingDir) {
+				logger.Infof("⏰ %s did not finish wrapping up in time, stopping it", workingDir)
+				process.stoppedForBudget.Store(true)
+				process.Stop()
+				return
+			}
+		}
+	}
+}
+
+// Permissions returns the relay for permission prompts of processes started
+// with RelayPermissions
+func (r *ClaudeProcessRegistry) Permissions() *PermissionRelay {
+	return r.permissions
+}
+
+// Budgets returns the tracker for time-boxed sessions
+func (r *ClaudeProcessRegistry) Budgets() *SessionBudgetTracker {
+	return r.budgets
+}
+
+// StopBudgetedProcess stops a budgeted process that has finished wrapping up
+func (r *ClaudeProcessRegistry) StopBudgetedProcess(workingDir string) {
+	r.processesMutex.RLock()
+	process, exists := r.processes[workingDir]
+	r.processesMutex.RUnlock()
+	if !exists || process.budgets == nil {
+		return
+	}
+	logger.Infof("⏰ %s wrapped up, stopping it", workingDir)
+	process.stoppedForBudget.Store(true)
+	process.Stop()
+}
+
 // cleanupLoop periodically cleans up stale processes
 func (r *ClaudeProcessRegistry) cleanupLoop() {
 	defer r.cleanupWg.Done()
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// claudeProjectsFileName is catnip's copy of the project metadata in ~/.claude.json,
+// kept in the volume directory next to settings.json
+const claudeProjectsFileName = "claude-projects.json"
+
+// claudeProjectsVersion is bumped when the snapshot format changes
+const claudeProjectsVersion = 1
+
+// claudeProjectsSnapshot is the on-disk format of catnip's project metadata.
+//
+// ~/.claude.json belongs to Claude: besides projects it holds OAuth and API key
+// data, and Claude rewrites it while running. Catnip only ever reads it, copying
+// the projects here; everything catnip changes (such as forgetting a deleted
+// worktree) is tracked in this file instead.
+type claudeProjectsSnapshot struct {
+	Version    int                                      `json:"version"`
+	ImportedAt time.Time                                `json:"imported_at"`
+	Projects   map[string]*models.ClaudeProjectMetadata `json:"projects"`
+	// Removed maps worktree paths catnip has forgotten to the fingerprint of
+	// their ~/.claude.json entry at the time. The entry stays forgotten until
+	// Claude changes it, i.e. until a new session runs at that path.
+	Removed map[string]string `json:"removed,omitempty"`
+}
+
+// syncClaudeProjects brings the snapshot up to date with ~/.claude.json if
+// that changed since the last sync. The caller must hold projectsMutex.
+func (s *ClaudeService) syncClaudeProjects() error {
+	if s.projects == nil {
+		snapshot, err := s.readClaudeProjectsFile()
+		if err != nil {
+			return err
+		}
+		s.projects = snapshot
+	}
+
+	info, err := os.Stat(s.claudeConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to stat claude config file: %w", err)
+	}
+	if s.projects.Version == claudeProjectsVersion && info.ModTime().Equal(s.projectsSyncedAt) {
+		return nil
+	}
+
+	source, err := s.readClaudeConfigProjects()
+	if err != nil {
+		return err
+	}
+	if s.projects.Version == 0 {
+		logger.Infof("📦 Importing Claude project metadata from %s into %s", s.claudeConfigPath, s.claudeProjectsPath)
+	}
+	if mergeClaudeProjects(s.projects, source, s.liveWorktreePaths()) {
+		if err := s.writeClaudeProjectsFile(s.projects); err != nil {
+			return err
+		}
+	}
+	s.projectsSyncedAt = info.ModTime()
+	return nil
+}
+
+// SyncClaudeProjects copies project metadata from ~/.claude.json into catnip's
+// snapshot, importing it on the first run of an existing install
+func (s *ClaudeService) SyncClaudeProjects() error {
+	s.projectsMutex.Lock()
+	defer s.projectsMutex.Unlock()
+	return s.syncClaudeProjects()
+}
+
+// readClaudeConfig returns the project metadata Claude has recorded, keyed by
+// worktree path, minus the worktrees catnip has since forgotten
+func (s *ClaudeService) readClaudeConfig() (map[string]*models.ClaudeProjectMetadata, error) {
+	s.projectsMutex.Lock()
+	defer s.projectsMutex.Unlock()
+	if err := s.syncClaudeProjects(); err != nil {
+		return nil, err
+	}
+
+	projects := make(map[string]*models.ClaudeProjectMetadata, len(s.projects.Projects))
+	for path, project := range s.projects.Projects {
+		copied := *project
+		copied.Path = path
+		projects[path] = &copied
+	}
+	return projects, nil
+}
+
+// removeClaudeProjectEntry forgets a worktree's project metadata. Only
+// catnip's snapshot is changed; ~/.claude.json is left alone.
+func (s *ClaudeService) removeClaudeProjectEntry(worktreePath string) error {
+	s.projectsMutex.Lock()
+	defer s.projectsMutex.Unlock()
+	if err := s.syncClaudeProjects(); err != nil {
+		return err
+	}
+
+	project, exists := s.projects.Projects[worktreePath]
+	if !exists {
+		return nil
+	}
+	delete(s.projects.Projects, worktreePath)
+	if s.projects.Removed == nil {
+		s.projects.Removed = make(map[string]string)
+	}
+	s.projects.Removed[worktreePath] = claudeProjectFingerprint(project)
+	if err := s.writeClaudeProjectsFile(s.projects); err != nil {
+		return err
+	}
+
+	logger.Debugf("✅ Removed Claude project metadata for worktree: %s", worktreePath)
+	return nil
+}
+
+// liveWorktreePaths returns the paths of the worktrees catnip manages
+func (s *ClaudeService) liveWorktreePaths() map[string]bool {
+	paths := make(map[string]bool)
+	if s.gitService == nil || s.gitService.stateManager == nil {
+		return paths
+	}
+	for _, worktree := range s.gitService.stateManager.GetAllWorktrees() {
+		paths[worktree.Path] = true
+	}
+	return paths
+}
+
+// mergeClaudeProjects folds the projects read from ~/.claude.json into the
+// snapshot, reporting whether it changed. Projects missing from the source
+// are dropped unless they belong to a live worktree, whose metadata the
+// snapshot keeps when ~/.claude.json is lost, e.g. with a fresh home
+// directory.
+func mergeClaudeProjects(snapshot *claudeProjectsSnapshot, source map[string]*models.ClaudeProjectMetadata, live map[string]bool) bool {
+	changed := false
+	if snapshot.Version != claudeProjectsVersion {
+		snapshot.Version = claudeProjectsVersion
+		snapshot.ImportedAt = time.Now()
+		changed = true
+	}
+	if snapshot.Projects == nil {
+		snapshot.Projects = make(map[string]*models.ClaudeProjectMetadata)
+	}
+
+	for path, project := range source {
+		fingerprint := claudeProjectFingerprint(project)
+		if removed, ok := snapshot.Removed[path]; ok {
+			if removed == fingerprint {
+				continue
+			}
+			// Claude has used the path again since catnip forgot it
+			delete(snapshot.Removed, path)
+			changed = true
+		}
+		if existing, ok := snapshot.Projects[path]; ok && claudeProjectFingerprint(existing) == fingerprint {
+			continue
+		}
+		snapshot.Projects[path] = project
+		changed = true
+	}
+
+	for path := range snapshot.Projects {
+		if _, ok := source[path]; !ok && !live[path] {
+			delete(snapshot.Projects, path)
+			changed = true
+		}
+	}
+	// Forgotten entries only need remembering while Claude still has them
+	for path := range snapshot.Removed {
+		if _, ok := source[path]; !ok {
+			delete(snapshot.Removed, path)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// claudeProjectFingerprint identifies the content of a project entry
+func claudeProjectFingerprint(project *models.ClaudeProjectMetadata) string {
+	data, _ := json.Marshal(project)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// readClaudeConfigProjects reads the projects from ~/.claude.json without ever modifying it
+func (s *ClaudeService) readClaudeConfigProjects() (map[string]*models.ClaudeProjectMetadata, error) {
+	data, err := os.ReadFile(s.claudeConfigPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]*models.ClaudeProjectMetadata), nil
+		}
+		return nil, fmt.Errorf("failed to read claude config file: %w", err)
+	}
+
+	var config struct {
+		Projects map[string]*models.ClaudeProjectMetadata `json:"projects"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse claude config: %w", err)
+	}
+
+	projects := make(map[string]*models.ClaudeProjectMetadata, len(config.Projects))
+	for path, project := range config.Projects {
+		if project == nil {
+			continue
+		}
+		project.Path = path
+		projects[path] = project
+	}
+	return projects, nil
+}
+
+// readClaudeProjectsFile reads catnip's snapshot, returning an empty one
+// (Version 0) when it hasn't been created yet
+func (s *ClaudeService) readClaudeProjectsFile() (*claudeProjectsSnapshot, error) {
+	snapshot := &claudeProjectsSnapshot{}
+	data, err := os.ReadFile(s.claudeProjectsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return snapshot, nil
+		}
+		return nil, fmt.Errorf("failed to read claude projects file: %w", err)
+	}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		// The snapshot can always be rebuilt from ~/.claude.json
+		logger.Warnf("⚠️ Ignoring unreadable Claude projects file %s: %v", s.claudeProjectsPath, err)
+		return &claudeProjectsSnapshot{}, nil
+	}
+	return snapshot, nil
+}
+
+// writeClaudeProjectsFile atomically replaces catnip's snapshot
+func (s *ClaudeService) writeClaudeProjectsFile(snapshot *claudeProjectsSnapshot) error {
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal claude projects: %w", err)
+	}
+
+	tempFile := s.claudeProjectsPath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp claude projects file: %w", err)
+	}
+	if err := os.Rename(tempFile, s.claudeProjectsPath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to update claude projects file: %w", err)
+	}
+
+	// Set proper ownership for catnip user
+	if err := os.Chown(s.claudeProjectsPath, 1000, 1000); err != nil {
+		logger.Debugf("Failed to chown %s: %v", s.claudeProjectsPath, err)
+	}
+	return nil
+}
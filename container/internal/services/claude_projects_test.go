@@ -0,0 +1,99 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestClaudeProjectsSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	s := NewClaudeServiceWithWrapper(NewMockClaudeSubprocessWrapper())
+	s.claudeConfigPath = filepath.Join(dir, ".claude.json")
+	s.claudeProjectsPath = filepath.Join(dir, claudeProjectsFileName)
+
+	claudeJSON := `{"oauthAccount":{"emailAddress":"felix@example.com"},"primaryApiKey":"sk-test","projects":{` +
+		`"/worktrees/catnip/felix":{"lastSessionId":"s1"},"/worktrees/catnip/luna":{"lastSessionId":"s2"}}}`
+	require.NoError(t, os.WriteFile(s.claudeConfigPath, []byte(claudeJSON), 0600))
+
+	// An existing install's projects are imported on first sync
+	require.NoError(t, s.SyncClaudeProjects())
+	require.FileExists(t, s.claudeProjectsPath)
+	projects, err := s.readClaudeConfig()
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	assert.Equal(t, "/worktrees/catnip/felix", projects["/worktrees/catnip/felix"].Path)
+
+	// Forgetting a worktree never touches ~/.claude.json
+	require.NoError(t, s.CleanupWorktreeClaudeFiles("/worktrees/catnip/felix"))
+	data, err := os.ReadFile(s.claudeConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, claudeJSON, string(data))
+	projects, err = s.readClaudeConfig()
+	require.NoError(t, err)
+	assert.NotContains(t, projects, "/worktrees/catnip/felix")
+
+	// ...and it stays forgotten across restarts while Claude's entry is unchanged
+	restarted := NewClaudeServiceWithWrapper(NewMockClaudeSubprocessWrapper())
+	restarted.claudeConfigPath = s.claudeConfigPath
+	restarted.claudeProjectsPath = s.claudeProjectsPath
+	projects, err = restarted.readClaudeConfig()
+	require.NoError(t, err)
+	assert.NotContains(t, projects, "/worktrees/catnip/felix")
+	assert.Contains(t, projects, "/worktrees/catnip/luna")
+
+	// A new session at the path brings it back
+	claudeJSON = `{"oauthAccount":{"emailAddress":"felix@example.com"},"primaryApiKey":"sk-test","projects":{` +
+		`"/worktrees/catnip/felix":{"lastSessionId":"s3"},"/worktrees/catnip/luna":{"lastSessionId":"s2"}}}`
+	require.NoError(t, os.WriteFile(s.claudeConfigPath, []byte(claudeJSON), 0600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(s.claudeConfigPath, later, later))
+	projects, err = restarted.readClaudeConfig()
+	require.NoError(t, err)
+	require.Contains(t, projects, "/worktrees/catnip/felix")
+	assert.Equal(t, "s3", *projects["/worktrees/catnip/felix"].LastSessionId)
+}
+
+func TestClaudeProjectsSnapshotDropsRemovedProjects(t *testing.T) {
+	dir := t.TempDir()
+	wsm := NewWorktreeStateManager(t.TempDir(), nil)
+	t.Cleanup(wsm.Stop)
+	require.NoError(t, wsm.AddRepository(&models.Repository{ID: "catnip", Path: "/repos/catnip.git", Available: true}))
+	require.NoError(t, wsm.AddWorktree(&models.Worktree{ID: "felix", RepoID: "catnip", Path: "/worktrees/catnip/felix"}))
+
+	s := NewClaudeServiceWithWrapper(NewMockClaudeSubprocessWrapper())
+	s.SetGitService(&GitService{stateManager: wsm})
+	s.claudeConfigPath = filepath.Join(dir, ".claude.json")
+	s.claudeProjectsPath = filepath.Join(dir, claudeProjectsFileName)
+
+	writeClaudeJSON := func(projects string, modTime time.Time) {
+		require.NoError(t, os.WriteFile(s.claudeConfigPath, []byte(`{"projects":{`+projects+`}}`), 0600))
+		require.NoError(t, os.Chtimes(s.claudeConfigPath, modTime, modTime))
+	}
+	now := time.Now()
+	writeClaudeJSON(`"/worktrees/catnip/felix":{"lastSessionId":"s1"},"/worktrees/catnip/luna":{"lastSessionId":"s2"},`+
+		`"/worktrees/catnip/tom":{"lastSessionId":"s3"}`, now)
+	require.NoError(t, s.CleanupWorktreeClaudeFiles("/worktrees/catnip/tom"))
+
+	// Claude drops every project: only the live worktree's entry is kept
+	writeClaudeJSON("", now.Add(time.Minute))
+	projects, err := s.readClaudeConfig()
+	require.NoError(t, err)
+	assert.Len(t, projects, 1)
+	assert.Contains(t, projects, "/worktrees/catnip/felix")
+	assert.Empty(t, s.projects.Removed, "forgotten entries Claude no longer has are dropped")
+
+	// The pruned snapshot is what a restart reads
+	restarted := NewClaudeServiceWithWrapper(NewMockClaudeSubprocessWrapper())
+	restarted.claudeConfigPath = s.claudeConfigPath
+	restarted.claudeProjectsPath = s.claudeProjectsPath
+	snapshot, err := restarted.readClaudeProjectsFile()
+	require.NoError(t, err)
+	assert.Len(t, snapshot.Projects, 1)
+	assert.NotContains(t, snapshot.Projects, "/worktrees/catnip/luna")
+}
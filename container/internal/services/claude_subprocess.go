@@ -16,6 +16,10 @@ import (
 	"github.com/vanpelt/catnip/internal/models"
 )
 
+// NoAssistantText is the response streamed for assistant messages without
+// text, such as turns that only use tools
+const NoAssistantText = "No text content found in assistant response"
+
 // ClaudeSubprocessInterface defines the interface for claude CLI subprocess execution
 type ClaudeSubprocessInterface interface {
 	CreateCompletion(ctx context.Context, opts *ClaudeSubprocessOptions) (*models.CreateCompletionResponse, error)
@@ -175,16 +179,21 @@ func (w *ClaudeSubprocessWrapper) retryClaudeCommand(ctx context.Context, origin
 
 // ClaudeSubprocessOptions represents options for the claude subprocess call
 type ClaudeSubprocessOptions struct {
-	Prompt           string
-	SystemPrompt     string
-	Model            string
-	MaxTurns         int
-	WorkingDirectory string
-	Resume           bool
-	Fork             bool   // When true with Resume, adds --fork-session flag (creates new session ID, doesn't pollute original)
-	SessionID        string // Optional: specific session ID to resume (uses --resume). If empty with Resume=true, uses --continue
-	SuppressEvents   bool
-	DisableTools     bool // When true, disables all tools (Claude will only use context, no tool calls)
+	Prompt       string
+	SystemPrompt string
+	// Appended to Claude's default system prompt (the org/repo/request layers)
+	AppendSystemPrompt string
+	Model              string
+	MaxTurns           int
+	WorkingDirectory   string
+	Resume             bool
+	Fork               bool   // When true with Resume, adds --fork-session flag (creates new session ID, doesn't pollute original)
+	SessionID          string // Optional: specific session ID to resume (uses --resume). If empty with Resume=true, uses --continue
+	SuppressEvents     bool
+	DisableTools       bool                  // When true, disables all tools (Claude will only use context, no tool calls)
+	DisallowedTools    []string              // Tools withheld by the worktree's tool policy
+	Budget             *models.SessionBudget // Optional time/token budget, enforced by the process registry
+	RelayPermissions   bool                  // Relay permission prompts through the process registry instead of skipping them
 }
 
 // CreateCompletion executes claude CLI and returns the response (always uses streaming internally)
@@ -208,6 +217,9 @@ func (w *ClaudeSubprocessWrapper) CreateStreamingCompletion(ctx context.Context,
 	if opts.SystemPrompt != "" {
 		args = append(args, "--system-prompt", opts.SystemPrompt)
 	}
+	if opts.AppendSystemPrompt != "" {
+		args = append(args, "--append-system-prompt", opts.AppendSystemPrompt)
+	}
 	if opts.Model != "" {
 		args = append(args, "--model", opts.Model)
 	}
@@ -239,6 +251,7 @@ func (w *ClaudeSubprocessWrapper) CreateStreamingCompletion(ctx context.Context,
 		args = append(args, "--tools", "")
 		logger.Debug("🚫 Disabling all tools")
 	}
+	args = append(args, disallowedToolsArgs(opts.DisallowedTools)...)
 
 	// Note: Prompt is sent via stdin, not as command argument
 
@@ -336,7 +349,7 @@ func (w *ClaudeSubprocessWrapper) CreateStreamingCompletion(ctx context.Context,
 			}
 
 			if responseText == "" {
-				responseText = "No text content found in assistant response"
+				responseText = NoAssistantText
 			}
 
 			// Create a clean response chunk with just the text
@@ -398,7 +411,7 @@ func (w *ClaudeSubprocessWrapper) CreateStreamingCompletion(ctx context.Context,
 			logger.Warnf("[WARNING] Failed to write response: %v", err)
 		}
 
-		return fmt.Errorf("claude command failed: %s", errorMsg)
+		return models.NewRetryableAPIError(models.ErrCodeClaudeFailed, "claude command failed: %s", errorMsg)
 	}
 
 	// Send final "end" chunk
@@ -432,6 +445,9 @@ func (w *ClaudeSubprocessWrapper) createSyncCompletion(ctx context.Context, opts
 	if opts.SystemPrompt != "" {
 		args = append(args, "--system-prompt", opts.SystemPrompt)
 	}
+	if opts.AppendSystemPrompt != "" {
+		args = append(args, "--append-system-prompt", opts.AppendSystemPrompt)
+	}
 	if opts.Model != "" {
 		args = append(args, "--model", opts.Model)
 	}
@@ -463,6 +479,7 @@ func (w *ClaudeSubprocessWrapper) createSyncCompletion(ctx context.Context, opts
 		args = append(args, "--tools", "")
 		logger.Debug("🚫 Disabling all tools")
 	}
+	args = append(args, disallowedToolsArgs(opts.DisallowedTools)...)
 
 	// Note: Prompt is sent via stdin, not as command argument
 
@@ -585,7 +602,7 @@ func (w *ClaudeSubprocessWrapper) createSyncCompletion(ctx context.Context, opts
 
 		return &models.CreateCompletionResponse{
 			Error: errorMsg,
-		}, fmt.Errorf("claude command failed: %s", errorMsg)
+		}, models.NewRetryableAPIError(models.ErrCodeClaudeFailed, "claude command failed: %s", errorMsg)
 	}
 
 	// Check if we found an assistant response
@@ -631,7 +648,7 @@ func (w *ClaudeSubprocessWrapper) createSyncCompletion(ctx context.Context, opts
 
 	if responseText == "" {
 		logger.Errorf("❌ Response text is empty, returning fallback message")
-		responseText = "No text content found in assistant response"
+		responseText = NoAssistantText
 	}
 
 	// Return just the text content
@@ -40,7 +40,7 @@ func (m *MockClaudeSubprocessWrapper) CreateCompletion(ctx context.Context, opts
 		}
 		return &models.CreateCompletionResponse{
 			Error: errorMsg,
-		}, fmt.Errorf("claude command failed: %s", errorMsg)
+		}, models.NewRetryableAPIError(models.ErrCodeClaudeFailed, "claude command failed: %s", errorMsg)
 	}
 
 	// Check if this is a branch naming request
@@ -100,7 +100,7 @@ func (m *MockClaudeSubprocessWrapper) CreateStreamingCompletion(ctx context.Cont
 			return fmt.Errorf("failed to write error response: %w", err)
 		}
 
-		return fmt.Errorf("claude command failed: %s", errorMsg)
+		return models.NewRetryableAPIError(models.ErrCodeClaudeFailed, "claude command failed: %s", errorMsg)
 	}
 
 	// Write successful streaming response
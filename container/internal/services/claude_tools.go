@@ -0,0 +1,157 @@
+package services
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// ClaudeToolNames are Claude's built-in tools that a tool policy can allow.
+// Tools missing from a policy are passed to Claude as --disallowedTools,
+// which holds even with --dangerously-skip-permissions.
+var ClaudeToolNames = []string{
+	"Bash", "BashOutput", "KillShell",
+	"Read", "Glob", "Grep",
+	"Edit", "MultiEdit", "Write", "NotebookEdit",
+	"WebFetch", "WebSearch",
+	"Task", "TodoWrite", "SlashCommand", "ExitPlanMode",
+}
+
+// Sources of a worktree's effective Claude tool policy
+const (
+	ClaudeToolsSourceWorktree   = "worktree"
+	ClaudeToolsSourceRepository = "repository"
+	ClaudeToolsSourceInstance   = "instance"
+	ClaudeToolsSourceDefault    = "default"
+)
+
+// EffectiveClaudeTools is the tool policy catnip-launched Claude processes get in a worktree
+type EffectiveClaudeTools struct {
+	// Tools Claude may use
+	Allowed []string `json:"allowed" example:"Read,Glob,Grep,Edit,Write"`
+	// Tools passed as --disallowedTools
+	Disallowed []string `json:"disallowed" example:"Bash,WebFetch"`
+	// Where the policy comes from: worktree, repository (.catnip.yaml),
+	// instance (CATNIP_CLAUDE_ALLOWED_TOOLS, e.g. from a settings profile) or
+	// default (all tools)
+	Source string `json:"source" example:"worktree"`
+	// Every tool a policy can allow
+	Tools []string `json:"tools"`
+}
+
+// NormalizeClaudeTools validates tool names, fixing their case and ordering
+// them as ClaudeToolNames does
+func NormalizeClaudeTools(tools []string) ([]string, error) {
+	known, unknown := splitClaudeTools(tools)
+	if len(unknown) > 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown Claude tools: %s (known tools: %s)", strings.Join(unknown, ", "), strings.Join(ClaudeToolNames, ", "))
+	}
+	return known, nil
+}
+
+// splitClaudeTools separates known tool names, normalized, from unknown ones
+func splitClaudeTools(tools []string) (known, unknown []string) {
+	known = []string{}
+	for _, name := range ClaudeToolNames {
+		if slices.ContainsFunc(tools, func(tool string) bool { return strings.EqualFold(strings.TrimSpace(tool), name) }) {
+			known = append(known, name)
+		}
+	}
+	for _, tool := range tools {
+		if !slices.ContainsFunc(ClaudeToolNames, func(name string) bool { return strings.EqualFold(strings.TrimSpace(tool), name) }) {
+			unknown = append(unknown, tool)
+		}
+	}
+	return known, unknown
+}
+
+// SetClaudeToolPolicy sets the tools Claude may use in a worktree (nil
+// restores the repository's policy). Running sessions keep their tools.
+func (s *GitService) SetClaudeToolPolicy(worktreeID string, policy *models.ClaudeToolPolicy) (*EffectiveClaudeTools, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if policy != nil {
+		allowed, err := NormalizeClaudeTools(policy.Allowed)
+		if err != nil {
+			return nil, err
+		}
+		policy = &models.ClaudeToolPolicy{Allowed: allowed}
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"claude_tools": policy,
+	}); err != nil {
+		return nil, err
+	}
+	return s.ClaudeTools(worktree.Path), nil
+}
+
+// ClaudeTools returns the tool policy for Claude processes in workDir: the
+// worktree's setting, else the repository's .catnip.yaml, else
+// CATNIP_CLAUDE_ALLOWED_TOOLS, else all tools
+func (s *GitService) ClaudeTools(workDir string) *EffectiveClaudeTools {
+	effective := &EffectiveClaudeTools{
+		Allowed:    ClaudeToolNames,
+		Disallowed: []string{},
+		Source:     ClaudeToolsSourceDefault,
+		Tools:      ClaudeToolNames,
+	}
+
+	var allowed []string
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.Path == workDir && worktree.ClaudeTools != nil {
+			allowed = worktree.ClaudeTools.Allowed
+			effective.Source = ClaudeToolsSourceWorktree
+			break
+		}
+	}
+	if effective.Source == ClaudeToolsSourceDefault && workDir != "" {
+		cfg, err := LoadCatnipConfig(workDir)
+		if err != nil {
+			logger.Warnf("⚠️ Ignoring repository Claude tools in %s: %v", workDir, err)
+		} else if cfg.Claude.AllowedTools != nil {
+			// Unknown names can't widen the policy, so they are only reported
+			var unknown []string
+			if allowed, unknown = splitClaudeTools(cfg.Claude.AllowedTools); len(unknown) > 0 {
+				logger.Warnf("⚠️ Ignoring unknown Claude tools in %s: %s", CatnipConfigFileName, strings.Join(unknown, ", "))
+			}
+			effective.Source = ClaudeToolsSourceRepository
+		}
+	}
+	if raw := os.Getenv(ClaudeAllowedToolsSetting); effective.Source == ClaudeToolsSourceDefault && raw != "" {
+		var unknown []string
+		if allowed, unknown = splitClaudeTools(strings.Split(raw, ",")); len(unknown) > 0 {
+			logger.Warnf("⚠️ Ignoring unknown Claude tools in %s: %s", ClaudeAllowedToolsSetting, strings.Join(unknown, ", "))
+		}
+		effective.Source = ClaudeToolsSourceInstance
+	}
+	if effective.Source == ClaudeToolsSourceDefault {
+		return effective
+	}
+
+	effective.Allowed = allowed
+	for _, name := range ClaudeToolNames {
+		if !slices.Contains(allowed, name) {
+			effective.Disallowed = append(effective.Disallowed, name)
+		}
+	}
+	return effective
+}
+
+// DisallowedClaudeTools returns the tools to pass as --disallowedTools to
+// Claude processes in workDir
+func (s *GitService) DisallowedClaudeTools(workDir string) []string {
+	return s.ClaudeTools(workDir).Disallowed
+}
+
+// disallowedToolsArgs returns the Claude CLI flags that withhold tools
+func disallowedToolsArgs(disallowed []string) []string {
+	if len(disallowed) == 0 {
+		return nil
+	}
+	return []string{"--disallowedTools", strings.Join(disallowed, ",")}
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestClaudeToolPolicy(t *testing.T) {
+	worktreePath := t.TempDir()
+	s := NewGitServiceWithStateDir(git.NewOperations(), t.TempDir())
+	require.NoError(t, s.stateManager.AddRepository(&models.Repository{ID: "vanpelt/catnip"}))
+	require.NoError(t, s.stateManager.AddWorktree(&models.Worktree{ID: "wt-1", RepoID: "vanpelt/catnip", Path: worktreePath}))
+
+	// All tools by default
+	effective := s.ClaudeTools(worktreePath)
+	assert.Equal(t, ClaudeToolsSourceDefault, effective.Source)
+	assert.Empty(t, effective.Disallowed)
+	assert.Nil(t, disallowedToolsArgs(effective.Disallowed))
+
+	// The repository's policy, ignoring names it doesn't know
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, CatnipConfigFileName), []byte(
+		"claude:\n  allowed_tools: [Read, Glob, Grep, Edit, MultiEdit, Write, NotebookEdit, Bash, BashOutput, KillShell, Task, TodoWrite, SlashCommand, ExitPlanMode, Teleport]\n"), 0644))
+	effective = s.ClaudeTools(worktreePath)
+	assert.Equal(t, ClaudeToolsSourceRepository, effective.Source)
+	assert.Equal(t, []string{"WebFetch", "WebSearch"}, effective.Disallowed)
+
+	// The worktree's own setting wins, normalized
+	effective, err := s.SetClaudeToolPolicy("wt-1", &models.ClaudeToolPolicy{Allowed: []string{"write", " edit", "Read"}})
+	require.NoError(t, err)
+	assert.Equal(t, ClaudeToolsSourceWorktree, effective.Source)
+	assert.Equal(t, []string{"Read", "Edit", "Write"}, effective.Allowed)
+	assert.Contains(t, effective.Disallowed, "Bash")
+	assert.NotContains(t, effective.Disallowed, "Edit")
+	assert.Equal(t, effective.Disallowed, s.DisallowedClaudeTools(worktreePath))
+	args := disallowedToolsArgs(effective.Disallowed)
+	require.Len(t, args, 2)
+	assert.Equal(t, "--disallowedTools", args[0])
+	assert.Contains(t, args[1], "Bash,BashOutput,KillShell,Glob")
+
+	// Allowing nothing withholds every tool
+	effective, err = s.SetClaudeToolPolicy("wt-1", &models.ClaudeToolPolicy{Allowed: []string{}})
+	require.NoError(t, err)
+	assert.Empty(t, effective.Allowed)
+	assert.Equal(t, ClaudeToolNames, effective.Disallowed)
+
+	_, err = s.SetClaudeToolPolicy("wt-1", &models.ClaudeToolPolicy{Allowed: []string{"Read", "Teleport"}})
+	assert.ErrorContains(t, err, "unknown Claude tools: Teleport")
+	_, err = s.SetClaudeToolPolicy("missing", nil)
+	assert.Error(t, err)
+
+	// Clearing it falls back to the repository
+	effective, err = s.SetClaudeToolPolicy("wt-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, ClaudeToolsSourceRepository, effective.Source)
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Where GitHub looks for a CODEOWNERS file, in order
+var codeOwnersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeOwnerRule is a CODEOWNERS line: files matching the pattern are owned by the owners
+type codeOwnerRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// parseCodeOwners parses a CODEOWNERS file. Lines that can't be parsed are
+// skipped, as GitHub does.
+func parseCodeOwners(content string) []codeOwnerRule {
+	var rules []codeOwnerRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		pattern, err := codeOwnerPattern(fields[0])
+		if err != nil {
+			logger.Debugf("⚠️ Skipping CODEOWNERS pattern %q: %v", fields[0], err)
+			continue
+		}
+		// A pattern without owners removes the ownership earlier rules gave
+		rules = append(rules, codeOwnerRule{pattern: pattern, owners: fields[1:]})
+	}
+	return rules
+}
+
+// codeOwnerPattern compiles a CODEOWNERS pattern, which follows gitignore
+// rules: patterns with a leading or inner slash are relative to the
+// repository root, others match at any depth, and a pattern matching a
+// directory owns everything in it unless it ends in "/*".
+func codeOwnerPattern(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var expr strings.Builder
+	if anchored {
+		expr.WriteString("^")
+	} else {
+		expr.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			expr.WriteString("[^/]*")
+		case pattern[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	switch {
+	case dirOnly:
+		expr.WriteString("/.*$")
+	case strings.HasSuffix(pattern, "/*"):
+		expr.WriteString("$")
+	default:
+		expr.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(expr.String())
+}
+
+// codeOwnersOf returns the owners of files, the last matching rule winning per file
+func codeOwnersOf(rules []codeOwnerRule, files []string) []string {
+	seen := make(map[string]bool)
+	var owners []string
+	for _, file := range files {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if !rules[i].pattern.MatchString(file) {
+				continue
+			}
+			for _, owner := range rules[i].owners {
+				if !seen[owner] {
+					seen[owner] = true
+					owners = append(owners, owner)
+				}
+			}
+			break
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// GetRepositoryCodeOwnerReviews reports whether pull requests opened for a
+// repository request reviews from code owners
+func (s *GitService) GetRepositoryCodeOwnerReviews(repoID string) (bool, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return false, models.NewRepositoryNotFoundError(repoID)
+	}
+	return !repo.DisableCodeOwnerReviews, nil
+}
+
+// SetRepositoryCodeOwnerReviews turns requesting reviews from code owners on
+// pull requests opened for a repository on or off
+func (s *GitService) SetRepositoryCodeOwnerReviews(repoID string, enabled bool) error {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.DisableCodeOwnerReviews = !enabled
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return fmt.Errorf("failed to save repository code owner reviews setting: %v", err)
+	}
+	return nil
+}
+
+// codeOwnerReviewers finds the code owners of the files a worktree's branch
+// changes, per the CODEOWNERS file of its base branch
+func (s *GitService) codeOwnerReviewers(worktree *models.Worktree, repo *models.Repository) (*models.CodeOwnerReviewers, error) {
+	result := &models.CodeOwnerReviewers{Enabled: !repo.DisableCodeOwnerReviews, Reviewers: []string{}}
+
+	// GitHub reads CODEOWNERS from the base branch, not the pull request
+	baseRef := worktree.SourceBranch
+	if !s.isLocalRepo(worktree.RepoID) {
+		baseRef = "origin/" + worktree.SourceBranch
+	}
+	var rules []codeOwnerRule
+	for _, path := range codeOwnersPaths {
+		if content, err := s.runGitCommand(worktree.Path, "show", baseRef+":"+path); err == nil {
+			result.File = path
+			rules = parseCodeOwners(string(content))
+			break
+		}
+	}
+	if result.File == "" {
+		return result, nil
+	}
+
+	output, err := s.runGitCommand(worktree.Path, "diff", "--name-only", baseRef+"...HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list changed files: %v", err)
+	}
+	var files []string
+	for _, file := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	for _, owner := range codeOwnersOf(rules, files) {
+		if strings.HasPrefix(owner, "@") {
+			result.Reviewers = append(result.Reviewers, strings.TrimPrefix(owner, "@"))
+		} else {
+			result.Unrequestable = append(result.Unrequestable, owner)
+		}
+	}
+	return result, nil
+}
+
+// requestCodeOwnerReviews requests reviews on a newly opened pull request from
+// the code owners of its changes, leaving out its author. Failures are logged;
+// the pull request stands either way.
+func (s *GitService) requestCodeOwnerReviews(worktree *models.Worktree, repo *models.Repository, pr *models.PullRequestResponse) {
+	if repo.DisableCodeOwnerReviews {
+		return
+	}
+	owners, err := s.codeOwnerReviewers(worktree, repo)
+	if err != nil {
+		logger.Warnf("⚠️ Could not find code owners for PR #%d: %v", pr.Number, err)
+		return
+	}
+	if len(owners.Reviewers) == 0 {
+		return
+	}
+
+	ownerRepo := pr.Repository
+	if ownerRepo == "" {
+		if ownerRepo, err = s.githubManager.ResolveOwnerRepo(worktree, repo); err != nil {
+			logger.Warnf("⚠️ Could not request code owner reviews for PR #%d: %v", pr.Number, err)
+			return
+		}
+	}
+	// GitHub refuses review requests from a pull request's author
+	author, _ := s.githubManager.CurrentUser(ownerRepo)
+	var reviewers []string
+	for _, reviewer := range owners.Reviewers {
+		if !strings.EqualFold(reviewer, author) {
+			reviewers = append(reviewers, reviewer)
+		}
+	}
+	if len(reviewers) == 0 {
+		return
+	}
+
+	if err := s.githubManager.RequestReviewers(ownerRepo, pr.Number, reviewers); err != nil {
+		logger.Warnf("⚠️ Could not request code owner reviews for PR #%d: %v", pr.Number, err)
+		return
+	}
+	logger.Infof("👀 Requested reviews on %s#%d from code owners %s", ownerRepo, pr.Number, strings.Join(reviewers, ", "))
+	pr.RequestedReviewers = reviewers
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeOwnerPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"*.js", []string{"app.js", "src/app.js"}, []string{"app.jsx"}},
+		{"/build/logs/", []string{"build/logs/a.log", "build/logs/deep/b.log"}, []string{"src/build/logs/a.log", "build/logs"}},
+		{"docs/*", []string{"docs/intro.md"}, []string{"docs/api/intro.md", "src/docs/intro.md"}},
+		{"apps/", []string{"apps/web/main.go", "src/apps/x.go"}, []string{"apps.go"}},
+		{"**/logs", []string{"logs/a", "build/logs/a"}, []string{"logsx/a"}},
+		{"/docs/**/*.md", []string{"docs/a.md", "docs/api/v1/b.md"}, []string{"docs/a.txt"}},
+		{"Makefile", []string{"Makefile", "tools/Makefile"}, []string{"Makefile.bak"}},
+		{"/scripts", []string{"scripts/run.sh"}, []string{"tools/scripts/run.sh"}},
+	} {
+		pattern, err := codeOwnerPattern(tc.pattern)
+		require.NoError(t, err, tc.pattern)
+		for _, path := range tc.matches {
+			assert.True(t, pattern.MatchString(path), "%s should match %s", tc.pattern, path)
+		}
+		for _, path := range tc.misses {
+			assert.False(t, pattern.MatchString(path), "%s shouldn't match %s", tc.pattern, path)
+		}
+	}
+}
+
+func TestCodeOwnersOf(t *testing.T) {
+	rules := parseCodeOwners(`# Default owners
+*       @acme/core
+
+/docs/  @docs-team jane@example.com   # writers
+*.go    @gophers @octocat
+/vendor/
+`)
+	require.Len(t, rules, 4)
+
+	assert.Equal(t, []string{"@acme/core"}, codeOwnersOf(rules, []string{"README.md"}))
+	assert.Equal(t, []string{"@docs-team", "jane@example.com"}, codeOwnersOf(rules, []string{"docs/setup.md"}))
+	// The last matching rule wins, so Go files aren't also owned by core
+	assert.Equal(t, []string{"@acme/core", "@gophers", "@octocat"}, codeOwnersOf(rules, []string{"main.go", "cmd/run.go", "Makefile"}))
+	// A rule without owners leaves files unowned
+	assert.Empty(t, codeOwnersOf(rules, []string{"vendor/lib/lib.go"}))
+	assert.Empty(t, codeOwnersOf(nil, []string{"main.go"}))
+}
@@ -0,0 +1,23 @@
+package services
+
+import (
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// gitCommandError types a failed git or gh subprocess as GIT_COMMAND_FAILED,
+// retryable when it looks like a transient network failure. Errors that
+// already carry a code, such as merge conflicts or missing credentials, are
+// returned unchanged.
+func gitCommandError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := models.AsAPIError(err); ok {
+		return err
+	}
+
+	apiErr := models.NewAPIError(models.ErrCodeGitCommandFailed, "%s", err.Error()).WithCause(err)
+	apiErr.Retryable = git.IsTransientNetworkError(err, "")
+	return apiErr
+}
@@ -0,0 +1,32 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestGitCommandError(t *testing.T) {
+	assert.NoError(t, gitCommandError(nil))
+
+	err := gitCommandError(fmt.Errorf("failed to fetch main: %w", context.DeadlineExceeded))
+	apiErr, ok := models.AsAPIError(err)
+	require.True(t, ok)
+	assert.Equal(t, models.ErrCodeGitCommandFailed, apiErr.Code)
+	assert.True(t, apiErr.Retryable)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = gitCommandError(fmt.Errorf("failed to push: ! [rejected] main -> main (non-fast-forward)"))
+	apiErr, ok = models.AsAPIError(err)
+	require.True(t, ok)
+	assert.False(t, apiErr.Retryable)
+	assert.Equal(t, "failed to push: ! [rejected] main -> main (non-fast-forward)", apiErr.Message)
+
+	// Errors that already have a code keep it
+	notFound := models.NewWorktreeNotFoundError("abc")
+	assert.Same(t, notFound, gitCommandError(notFound))
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// composeFileNames lists the compose file names docker compose looks for, in priority order
+var composeFileNames = []string{
+	"compose.yaml",
+	"compose.yml",
+	"docker-compose.yaml",
+	"docker-compose.yml",
+}
+
+// ComposeService describes a docker compose service and the host ports it publishes
+type ComposeService struct {
+	Name   string `json:"name"`
+	Ports  []int  `json:"ports"`
+	State  string `json:"state,omitempty"`  // "running", "exited", ... (empty if only parsed from file)
+	Health string `json:"health,omitempty"` // "healthy", "unhealthy", "starting" (empty if no healthcheck)
+}
+
+// FindComposeFile returns the path to the compose file in dir, or "" if there is none
+func FindComposeFile(dir string) string {
+	for _, name := range composeFileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// composeFile is the subset of the compose spec we need for port discovery
+type composeFile struct {
+	Services map[string]struct {
+		Ports []interface{} `yaml:"ports"`
+	} `yaml:"services"`
+}
+
+// ParseComposeFile extracts published host ports per service from a compose file
+func ParseComposeFile(path string) ([]ComposeService, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file composeFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	var result []ComposeService
+	for name, svc := range file.Services {
+		var ports []int
+		for _, entry := range svc.Ports {
+			ports = append(ports, parseComposePortEntry(entry)...)
+		}
+		if len(ports) > 0 {
+			result = append(result, ComposeService{Name: name, Ports: ports})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// parseComposePortEntry handles both the short ("127.0.0.1:8080:80/tcp") and
+// long ({published: 8080, target: 80}) port syntaxes, returning host ports
+func parseComposePortEntry(entry interface{}) []int {
+	switch v := entry.(type) {
+	case int:
+		// Bare container port - docker picks an ephemeral host port we can't know statically
+		return nil
+	case string:
+		return parseComposeShortPort(v)
+	case map[interface{}]interface{}:
+		switch published := v["published"].(type) {
+		case int:
+			return []int{published}
+		case string:
+			return parsePortRange(published)
+		}
+	}
+	return nil
+}
+
+// parseComposeShortPort parses "[ip:]host:container[/proto]" strings
+func parseComposeShortPort(spec string) []int {
+	spec = strings.Trim(spec, "\"' ")
+	if idx := strings.Index(spec, "/"); idx >= 0 {
+		spec = spec[:idx]
+	}
+
+	// IPv6 host IPs are bracketed, e.g. "[::1]:8080:80"
+	if strings.HasPrefix(spec, "[") {
+		if idx := strings.Index(spec, "]:"); idx >= 0 {
+			spec = spec[idx+2:]
+		}
+	}
+
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		// Only a container port was given
+		return nil
+	}
+	return parsePortRange(parts[len(parts)-2])
+}
+
+// parsePortRange parses "8080" or "8080-8082" into a list of ports
+func parsePortRange(spec string) []int {
+	if spec == "" {
+		return nil
+	}
+	if start, end, found := strings.Cut(spec, "-"); found {
+		lo, err1 := strconv.Atoi(start)
+		hi, err2 := strconv.Atoi(end)
+		if err1 != nil || err2 != nil || hi < lo || hi-lo > 100 {
+			return nil
+		}
+		ports := make([]int, 0, hi-lo+1)
+		for p := lo; p <= hi; p++ {
+			ports = append(ports, p)
+		}
+		return ports
+	}
+	if port, err := strconv.Atoi(spec); err == nil && port > 0 {
+		return []int{port}
+	}
+	return nil
+}
+
+// composePSEntry mirrors a row of `docker compose ps --format json`
+type composePSEntry struct {
+	Service    string `json:"Service"`
+	State      string `json:"State"`
+	Health     string `json:"Health"`
+	Publishers []struct {
+		PublishedPort int    `json:"PublishedPort"`
+		TargetPort    int    `json:"TargetPort"`
+		Protocol      string `json:"Protocol"`
+	} `json:"Publishers"`
+}
+
+// ParseComposePSOutput parses `docker compose ps --format json` output, which is
+// a JSON array on older compose versions and newline-delimited JSON on newer ones
+func ParseComposePSOutput(output []byte) ([]ComposeService, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, nil
+	}
+
+	var entries []composePSEntry
+	if output[0] == '[' {
+		if err := json.Unmarshal(output, &entries); err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(output))
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var entry composePSEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	var result []ComposeService
+	for _, entry := range entries {
+		svc := ComposeService{Name: entry.Service, State: entry.State, Health: entry.Health}
+		seen := make(map[int]bool)
+		for _, pub := range entry.Publishers {
+			if pub.PublishedPort > 0 && !seen[pub.PublishedPort] {
+				seen[pub.PublishedPort] = true
+				svc.Ports = append(svc.Ports, pub.PublishedPort)
+			}
+		}
+		if len(svc.Ports) > 0 {
+			result = append(result, svc)
+		}
+	}
+	return result, nil
+}
+
+// DetectComposeServices returns the compose services with published ports for a
+// project directory. Running containers reported by `docker compose ps` win; if
+// docker is unavailable we fall back to statically parsing the compose file.
+func DetectComposeServices(dir string) ([]ComposeService, error) {
+	composePath := FindComposeFile(dir)
+	if composePath == "" {
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath("docker"); err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		cmd := exec.CommandContext(ctx, "docker", "compose", "ps", "--format", "json")
+		cmd.Dir = dir
+		if output, err := cmd.Output(); err == nil {
+			return ParseComposePSOutput(output)
+		}
+	}
+
+	return ParseComposeFile(composePath)
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComposeFile(t *testing.T) {
+	dir := t.TempDir()
+	content := `services:
+  web:
+    image: nginx
+    ports:
+      - "8080:80"
+      - "127.0.0.1:8443:443/tcp"
+  db:
+    image: postgres
+    ports:
+      - target: 5432
+        published: 15432
+  worker:
+    image: busybox
+    ports:
+      - "9000"
+  ranged:
+    image: busybox
+    ports:
+      - "7000-7002:7000-7002"
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte(content), 0644))
+
+	path := FindComposeFile(dir)
+	require.NotEmpty(t, path)
+
+	services, err := ParseComposeFile(path)
+	require.NoError(t, err)
+
+	byName := make(map[string][]int)
+	for _, svc := range services {
+		byName[svc.Name] = svc.Ports
+	}
+
+	assert.Equal(t, []int{8080, 8443}, byName["web"])
+	assert.Equal(t, []int{15432}, byName["db"])
+	assert.Equal(t, []int{7000, 7001, 7002}, byName["ranged"])
+	_, hasWorker := byName["worker"]
+	assert.False(t, hasWorker, "services without a fixed host port should be skipped")
+}
+
+func TestFindComposeFile_None(t *testing.T) {
+	assert.Empty(t, FindComposeFile(t.TempDir()))
+}
+
+func TestParseComposePSOutput(t *testing.T) {
+	t.Run("ndjson", func(t *testing.T) {
+		output := []byte(`{"Service":"web","State":"running","Health":"healthy","Publishers":[{"PublishedPort":8080,"TargetPort":80,"Protocol":"tcp"},{"PublishedPort":8080,"TargetPort":80,"Protocol":"tcp"}]}
+{"Service":"cache","State":"running","Health":"","Publishers":[{"PublishedPort":0,"TargetPort":6379,"Protocol":"tcp"}]}
+`)
+		services, err := ParseComposePSOutput(output)
+		require.NoError(t, err)
+		require.Len(t, services, 1)
+		assert.Equal(t, "web", services[0].Name)
+		assert.Equal(t, []int{8080}, services[0].Ports)
+		assert.Equal(t, "healthy", services[0].Health)
+	})
+
+	t.Run("json array", func(t *testing.T) {
+		output := []byte(`[{"Service":"api","State":"running","Publishers":[{"PublishedPort":3000,"TargetPort":3000,"Protocol":"tcp"}]}]`)
+		services, err := ParseComposePSOutput(output)
+		require.NoError(t, err)
+		require.Len(t, services, 1)
+		assert.Equal(t, []int{3000}, services[0].Ports)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		services, err := ParseComposePSOutput(nil)
+		require.NoError(t, err)
+		assert.Empty(t, services)
+	})
+}
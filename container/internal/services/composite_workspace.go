@@ -0,0 +1,647 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// compositeWorkspaceDirName is the directory below the workspace root that
+// holds composite workspaces
+const compositeWorkspaceDirName = "composite"
+
+// Composite names and member aliases become directory names
+var compositeNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// CompositeMember is a worktree linked into a composite workspace
+type CompositeMember struct {
+	WorktreeID string `json:"worktree_id"`
+	// Directory name of the member inside the workspace (defaults to the repository name)
+	Alias string `json:"alias"`
+}
+
+// CompositeWorkspace groups worktrees from several repositories (e.g. a
+// frontend and a backend) under one parent directory so they can be worked
+// on as one unit
+type CompositeWorkspace struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Parent directory containing a link to each member worktree
+	Path    string            `json:"path"`
+	Members []CompositeMember `json:"members"`
+	// Environment variables for terminals, Claude sessions and setup.sh runs
+	// in the workspace and its members
+	Env map[string]string `json:"env,omitempty"`
+	// Instructions added to Claude's workspace context
+	Instructions string    `json:"instructions,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// SessionID returns the PTY session ID for the workspace's parent directory
+func (w *CompositeWorkspace) SessionID() string {
+	return compositeWorkspaceDirName + "/" + w.Name
+}
+
+// CreateCompositeWorkspaceRequest describes a new composite workspace
+type CreateCompositeWorkspaceRequest struct {
+	Name         string            `json:"name"`
+	Members      []CompositeMember `json:"members"`
+	Env          map[string]string `json:"env,omitempty"`
+	Instructions string            `json:"instructions,omitempty"`
+}
+
+// UpdateCompositeWorkspaceRequest changes a composite workspace's settings.
+// Nil fields are left alone.
+type UpdateCompositeWorkspaceRequest struct {
+	Env          map[string]string `json:"env,omitempty"`
+	Instructions *string           `json:"instructions,omitempty"`
+}
+
+// CompositeMemberStatus is the state of one member worktree
+type CompositeMemberStatus struct {
+	CompositeMember
+	Name   string `json:"name,omitempty"`
+	RepoID string `json:"repo_id,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// The member worktree was deleted outside the composite workspace
+	Missing             bool                       `json:"missing,omitempty"`
+	IsDirty             bool                       `json:"is_dirty"`
+	HasConflicts        bool                       `json:"has_conflicts"`
+	CommitCount         int                        `json:"commit_count"`
+	CommitsBehind       int                        `json:"commits_behind"`
+	PullRequestURL      string                     `json:"pull_request_url,omitempty"`
+	PullRequestState    string                     `json:"pull_request_state,omitempty"`
+	ClaudeActivityState models.ClaudeActivityState `json:"claude_activity_state,omitempty"`
+	SetupChangedFiles   []string                   `json:"setup_changed_files,omitempty"`
+}
+
+// CompositeWorkspaceStatus aggregates member state across a composite workspace
+type CompositeWorkspaceStatus struct {
+	Workspace *CompositeWorkspace     `json:"workspace"`
+	Members   []CompositeMemberStatus `json:"members"`
+	// Any member has uncommitted changes
+	IsDirty bool `json:"is_dirty"`
+	// Any member is mid-merge or mid-rebase with conflicts
+	HasConflicts bool `json:"has_conflicts"`
+	// Commits ahead, summed across members
+	CommitCount int `json:"commit_count"`
+	// Commits behind the source branches, summed across members
+	CommitsBehind int `json:"commits_behind"`
+	// Members with a setup that may be out of date
+	SetupStale     int `json:"setup_stale"`
+	MissingMembers int `json:"missing_members"`
+	// Most active Claude state across members
+	ClaudeActivityState models.ClaudeActivityState `json:"claude_activity_state"`
+}
+
+// CompositeSetupResult is the outcome of starting setup.sh in a member
+type CompositeSetupResult struct {
+	WorktreeID string `json:"worktree_id"`
+	Alias      string `json:"alias"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CompositeWorktrees is the worktree management a composite workspace delegates to
+type CompositeWorktrees interface {
+	ListWorktrees() []*models.Worktree
+	RerunSetup(worktreeID string) error
+	DeleteWorktree(worktreeID string) (<-chan error, error)
+}
+
+// CompositeWorkspaceService manages composite workspaces, persisting them on
+// the volume and keeping each workspace's member links in place
+type CompositeWorkspaceService struct {
+	path      string
+	rootDir   string
+	worktrees CompositeWorktrees
+
+	mu         sync.RWMutex
+	workspaces map[string]*CompositeWorkspace
+}
+
+// NewCompositeWorkspaceService creates a composite workspace service backed by
+// the volume, with workspaces under <workspace>/composite
+func NewCompositeWorkspaceService(worktrees CompositeWorktrees) *CompositeWorkspaceService {
+	return NewCompositeWorkspaceServiceWithOptions(
+		filepath.Join(config.Runtime.VolumeDir, "composite_workspaces.json"),
+		filepath.Join(config.Runtime.WorkspaceDir, compositeWorkspaceDirName),
+		worktrees)
+}
+
+// NewCompositeWorkspaceServiceWithOptions creates a composite workspace service with explicit paths (for testing)
+func NewCompositeWorkspaceServiceWithOptions(path, rootDir string, worktrees CompositeWorktrees) *CompositeWorkspaceService {
+	s := &CompositeWorkspaceService{
+		path:       path,
+		rootDir:    rootDir,
+		worktrees:  worktrees,
+		workspaces: make(map[string]*CompositeWorkspace),
+	}
+	if err := s.load(); err != nil {
+		logger.Warnf("⚠️ Failed to load composite workspaces: %v", err)
+	}
+	return s
+}
+
+func (s *CompositeWorkspaceService) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var workspaces []*CompositeWorkspace
+	if err := json.Unmarshal(data, &workspaces); err != nil {
+		return fmt.Errorf("corrupt composite workspaces file: %v", err)
+	}
+	for _, workspace := range workspaces {
+		s.workspaces[workspace.ID] = workspace
+	}
+	return nil
+}
+
+func (s *CompositeWorkspaceService) saveLocked() {
+	data, err := json.MarshalIndent(s.sortedLocked(), "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.path), 0755); err == nil {
+			tmpPath := s.path + ".tmp"
+			if err = os.WriteFile(tmpPath, data, 0644); err == nil {
+				err = os.Rename(tmpPath, s.path)
+			}
+		}
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to persist composite workspaces: %v", err)
+	}
+}
+
+func (s *CompositeWorkspaceService) sortedLocked() []*CompositeWorkspace {
+	workspaces := make([]*CompositeWorkspace, 0, len(s.workspaces))
+	for _, workspace := range s.workspaces {
+		workspaces = append(workspaces, workspace)
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	return workspaces
+}
+
+// findLocked looks a workspace up by ID or name
+func (s *CompositeWorkspaceService) findLocked(idOrName string) (*CompositeWorkspace, error) {
+	if workspace, ok := s.workspaces[idOrName]; ok {
+		return workspace, nil
+	}
+	for _, workspace := range s.workspaces {
+		if workspace.Name == idOrName {
+			return workspace, nil
+		}
+	}
+	return nil, models.NewCompositeNotFoundError(idOrName)
+}
+
+func (s *CompositeWorkspaceService) worktreesByID() map[string]*models.Worktree {
+	byID := make(map[string]*models.Worktree)
+	for _, worktree := range s.worktrees.ListWorktrees() {
+		byID[worktree.ID] = worktree
+	}
+	return byID
+}
+
+// resolveMember validates a member against the existing members and fills in its alias
+func resolveMember(member CompositeMember, existing []CompositeMember, worktrees map[string]*models.Worktree) (CompositeMember, error) {
+	worktree, ok := worktrees[member.WorktreeID]
+	if !ok {
+		return member, models.NewWorktreeNotFoundError(member.WorktreeID)
+	}
+	if member.Alias == "" {
+		member.Alias = filepath.Base(worktree.RepoID)
+	}
+	if !compositeNamePattern.MatchString(member.Alias) {
+		return member, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid member alias %q", member.Alias)
+	}
+	for _, other := range existing {
+		if other.WorktreeID == member.WorktreeID {
+			return member, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is already a member", worktree.Name)
+		}
+		if other.Alias == member.Alias {
+			return member, models.NewAPIError(models.ErrCodeInvalidRequest, "member alias %q is already used", member.Alias).
+				WithHint("Give members from repositories with the same name distinct aliases")
+		}
+	}
+	return member, nil
+}
+
+// linkMembers points each member's alias at its worktree and removes links
+// left by members that were dropped. Only symlinks are removed so files
+// created in the parent directory survive.
+func (s *CompositeWorkspaceService) linkMembers(workspace *CompositeWorkspace, worktrees map[string]*models.Worktree) error {
+	if err := os.MkdirAll(workspace.Path, 0755); err != nil {
+		return err
+	}
+
+	want := make(map[string]string)
+	for _, member := range workspace.Members {
+		if worktree, ok := worktrees[member.WorktreeID]; ok {
+			want[member.Alias] = worktree.Path
+		}
+	}
+
+	entries, err := os.ReadDir(workspace.Path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		linkPath := filepath.Join(workspace.Path, entry.Name())
+		if entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+		if target, err := os.Readlink(linkPath); err == nil && target == want[entry.Name()] {
+			delete(want, entry.Name())
+			continue
+		}
+		if err := os.Remove(linkPath); err != nil {
+			return err
+		}
+	}
+
+	for alias, target := range want {
+		if err := os.Symlink(target, filepath.Join(workspace.Path, alias)); err != nil {
+			return fmt.Errorf("failed to link %s: %v", alias, err)
+		}
+	}
+	return nil
+}
+
+// Create creates a composite workspace and links its members
+func (s *CompositeWorkspaceService) Create(req CreateCompositeWorkspaceRequest) (*CompositeWorkspace, error) {
+	if !compositeNamePattern.MatchString(req.Name) {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid composite workspace name %q", req.Name).
+			WithHint("Use letters, digits, dots, dashes and underscores")
+	}
+	if len(req.Members) < 2 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "a composite workspace needs at least two member worktrees")
+	}
+
+	worktrees := s.worktreesByID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.findLocked(req.Name); err == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "composite workspace %q already exists", req.Name)
+	}
+
+	var members []CompositeMember
+	for _, member := range req.Members {
+		resolved, err := resolveMember(member, members, worktrees)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, resolved)
+	}
+
+	now := time.Now()
+	workspace := &CompositeWorkspace{
+		ID:           uuid.New().String(),
+		Name:         req.Name,
+		Path:         filepath.Join(s.rootDir, req.Name),
+		Members:      members,
+		Env:          req.Env,
+		Instructions: strings.TrimSpace(req.Instructions),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.linkMembers(workspace, worktrees); err != nil {
+		return nil, fmt.Errorf("failed to create composite workspace directory: %v", err)
+	}
+
+	s.workspaces[workspace.ID] = workspace
+	s.saveLocked()
+	logger.Infof("🧩 Created composite workspace %s with %d members", workspace.Name, len(members))
+	return workspace, nil
+}
+
+// List returns all composite workspaces, sorted by name
+func (s *CompositeWorkspaceService) List() []*CompositeWorkspace {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sortedLocked()
+}
+
+// Get returns a composite workspace by ID or name
+func (s *CompositeWorkspaceService) Get(idOrName string) (*CompositeWorkspace, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.findLocked(idOrName)
+}
+
+// Update changes a composite workspace's env vars or Claude instructions
+func (s *CompositeWorkspaceService) Update(idOrName string, req UpdateCompositeWorkspaceRequest) (*CompositeWorkspace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workspace, err := s.findLocked(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	if req.Env != nil {
+		workspace.Env = req.Env
+	}
+	if req.Instructions != nil {
+		workspace.Instructions = strings.TrimSpace(*req.Instructions)
+	}
+	workspace.UpdatedAt = time.Now()
+	s.saveLocked()
+	return workspace, nil
+}
+
+// AddMember links another worktree into a composite workspace
+func (s *CompositeWorkspaceService) AddMember(idOrName string, member CompositeMember) (*CompositeWorkspace, error) {
+	worktrees := s.worktreesByID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workspace, err := s.findLocked(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := resolveMember(member, workspace.Members, worktrees)
+	if err != nil {
+		return nil, err
+	}
+	workspace.Members = append(workspace.Members, resolved)
+	if err := s.linkMembers(workspace, worktrees); err != nil {
+		workspace.Members = workspace.Members[:len(workspace.Members)-1]
+		return nil, err
+	}
+	workspace.UpdatedAt = time.Now()
+	s.saveLocked()
+	return workspace, nil
+}
+
+// RemoveMember unlinks a worktree from a composite workspace. The worktree
+// itself is kept.
+func (s *CompositeWorkspaceService) RemoveMember(idOrName, worktreeID string) (*CompositeWorkspace, error) {
+	worktrees := s.worktreesByID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workspace, err := s.findLocked(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]CompositeMember, 0, len(workspace.Members))
+	for _, member := range workspace.Members {
+		if member.WorktreeID != worktreeID {
+			members = append(members, member)
+		}
+	}
+	if len(members) == len(workspace.Members) {
+		return nil, models.NewWorktreeNotFoundError(worktreeID).
+			WithHint("The worktree is not a member of this composite workspace")
+	}
+	workspace.Members = members
+	if err := s.linkMembers(workspace, worktrees); err != nil {
+		return nil, err
+	}
+	workspace.UpdatedAt = time.Now()
+	s.saveLocked()
+	return workspace, nil
+}
+
+// ReplaceWorktree swaps a member worktree for another in every composite
+// workspace that contains it, keeping its alias. It returns the names of the
+// workspaces that changed.
+func (s *CompositeWorkspaceService) ReplaceWorktree(oldID, newID string) ([]string, error) {
+	worktrees := s.worktreesByID()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var replaced []string
+	for _, workspace := range s.sortedLocked() {
+		changed := false
+		for i := range workspace.Members {
+			if workspace.Members[i].WorktreeID == oldID {
+				workspace.Members[i].WorktreeID = newID
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := s.linkMembers(workspace, worktrees); err != nil {
+			return replaced, err
+		}
+		workspace.UpdatedAt = time.Now()
+		replaced = append(replaced, workspace.Name)
+	}
+	if len(replaced) > 0 {
+		s.saveLocked()
+	}
+	return replaced, nil
+}
+
+// Delete removes a composite workspace's directory and record. Member
+// worktrees are deleted too when deleteWorktrees is set; otherwise they're
+// left as standalone worktrees.
+func (s *CompositeWorkspaceService) Delete(idOrName string, deleteWorktrees bool) error {
+	s.mu.Lock()
+	workspace, err := s.findLocked(idOrName)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	delete(s.workspaces, workspace.ID)
+	s.saveLocked()
+	s.mu.Unlock()
+
+	// The directory only holds member links and scratch files
+	if err := os.RemoveAll(workspace.Path); err != nil {
+		logger.Warnf("⚠️ Failed to remove composite workspace directory %s: %v", workspace.Path, err)
+	}
+
+	var failed []string
+	if deleteWorktrees {
+		for _, member := range workspace.Members {
+			if _, err := s.worktrees.DeleteWorktree(member.WorktreeID); err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %v", member.Alias, err))
+			}
+		}
+	}
+	logger.Infof("🧩 Deleted composite workspace %s", workspace.Name)
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to delete member worktrees: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Status aggregates the state of a composite workspace's members
+func (s *CompositeWorkspaceService) Status(idOrName string) (*CompositeWorkspaceStatus, error) {
+	workspace, err := s.Get(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	worktrees := s.worktreesByID()
+
+	status := &CompositeWorkspaceStatus{
+		Workspace:           workspace,
+		Members:             make([]CompositeMemberStatus, 0, len(workspace.Members)),
+		ClaudeActivityState: models.ClaudeInactive,
+	}
+	for _, member := range workspace.Members {
+		memberStatus := CompositeMemberStatus{CompositeMember: member}
+		worktree, ok := worktrees[member.WorktreeID]
+		if !ok {
+			memberStatus.Missing = true
+			status.MissingMembers++
+			status.Members = append(status.Members, memberStatus)
+			continue
+		}
+
+		memberStatus.Name = worktree.Name
+		memberStatus.RepoID = worktree.RepoID
+		memberStatus.Branch = worktree.Branch
+		memberStatus.Path = worktree.Path
+		memberStatus.IsDirty = worktree.IsDirty
+		memberStatus.HasConflicts = worktree.HasConflicts
+		memberStatus.CommitCount = worktree.CommitCount
+		memberStatus.CommitsBehind = worktree.CommitsBehind
+		memberStatus.PullRequestURL = worktree.PullRequestURL
+		memberStatus.PullRequestState = worktree.PullRequestState
+		memberStatus.ClaudeActivityState = worktree.ClaudeActivityState
+		memberStatus.SetupChangedFiles = worktree.SetupChangedFiles
+		status.Members = append(status.Members, memberStatus)
+
+		status.IsDirty = status.IsDirty || worktree.IsDirty
+		status.HasConflicts = status.HasConflicts || worktree.HasConflicts
+		status.CommitCount += worktree.CommitCount
+		status.CommitsBehind += worktree.CommitsBehind
+		if len(worktree.SetupChangedFiles) > 0 {
+			status.SetupStale++
+		}
+		if claudeActivityRank(worktree.ClaudeActivityState) > claudeActivityRank(status.ClaudeActivityState) {
+			status.ClaudeActivityState = worktree.ClaudeActivityState
+		}
+	}
+	return status, nil
+}
+
+func claudeActivityRank(state models.ClaudeActivityState) int {
+	switch state {
+	case models.ClaudeActive:
+		return 2
+	case models.ClaudeRunning:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// RunSetup re-runs setup.sh in every member that has one
+func (s *CompositeWorkspaceService) RunSetup(idOrName string) ([]CompositeSetupResult, error) {
+	workspace, err := s.Get(idOrName)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]CompositeSetupResult, 0, len(workspace.Members))
+	for _, member := range workspace.Members {
+		result := CompositeSetupResult{WorktreeID: member.WorktreeID, Alias: member.Alias}
+		if err := s.worktrees.RerunSetup(member.WorktreeID); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// forPath returns the workspace whose parent directory is path, or that has
+// a member worktree at path
+func (s *CompositeWorkspaceService) forPath(path string) *CompositeWorkspace {
+	if path == "" {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var memberOf []*CompositeWorkspace
+	for _, workspace := range s.workspaces {
+		if workspace.Path == path {
+			return workspace
+		}
+		for _, member := range workspace.Members {
+			if target, err := os.Readlink(filepath.Join(workspace.Path, member.Alias)); err == nil && target == path {
+				memberOf = append(memberOf, workspace)
+				break
+			}
+		}
+	}
+	if len(memberOf) == 0 {
+		return nil
+	}
+	// A worktree can be linked into several workspaces; pick one consistently
+	sort.Slice(memberOf, func(i, j int) bool { return memberOf[i].Name < memberOf[j].Name })
+	return memberOf[0]
+}
+
+// EnvForPath returns the composite workspace env vars for a process running
+// in a workspace's parent directory or one of its members
+func (s *CompositeWorkspaceService) EnvForPath(path string) []string {
+	workspace := s.forPath(path)
+	if workspace == nil {
+		return nil
+	}
+	env := []string{"CATNIP_COMPOSITE_WORKSPACE=" + workspace.Name, "CATNIP_COMPOSITE_PATH=" + workspace.Path}
+	keys := make([]string, 0, len(workspace.Env))
+	for key := range workspace.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		env = append(env, key+"="+workspace.Env[key])
+	}
+	return env
+}
+
+// ContextForPath describes the composite workspace to Claude sessions
+// running in its parent directory or one of its members
+func (s *CompositeWorkspaceService) ContextForPath(path string) string {
+	workspace := s.forPath(path)
+	if workspace == nil {
+		return ""
+	}
+	worktrees := s.worktreesByID()
+
+	var b strings.Builder
+	if path == workspace.Path {
+		fmt.Fprintf(&b, "This directory is the composite workspace %q. Each subdirectory below is a git worktree of a separate repository, so commit in each one separately:\n", workspace.Name)
+	} else {
+		fmt.Fprintf(&b, "This worktree is part of the composite workspace %q at %s. Changes may need matching work in the other members:\n", workspace.Name, workspace.Path)
+	}
+	for _, member := range workspace.Members {
+		worktree, ok := worktrees[member.WorktreeID]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "- %s/: %s on branch %s (%s)\n", member.Alias, worktree.RepoID, worktree.Branch, worktree.Path)
+	}
+	if workspace.Instructions != "" {
+		b.WriteString("\n")
+		b.WriteString(workspace.Instructions)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
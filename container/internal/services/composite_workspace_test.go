@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type fakeCompositeWorktrees struct {
+	worktrees []*models.Worktree
+	setupRuns []string
+	deleted   []string
+}
+
+func (f *fakeCompositeWorktrees) ListWorktrees() []*models.Worktree {
+	return f.worktrees
+}
+
+func (f *fakeCompositeWorktrees) RerunSetup(worktreeID string) error {
+	if worktreeID == "api" {
+		return errors.New("no setup.sh found")
+	}
+	f.setupRuns = append(f.setupRuns, worktreeID)
+	return nil
+}
+
+func (f *fakeCompositeWorktrees) DeleteWorktree(worktreeID string) (<-chan error, error) {
+	f.deleted = append(f.deleted, worktreeID)
+	done := make(chan error, 1)
+	close(done)
+	return done, nil
+}
+
+func newTestCompositeService(t *testing.T) (*CompositeWorkspaceService, *fakeCompositeWorktrees, string) {
+	workspaceDir := t.TempDir()
+	worktrees := &fakeCompositeWorktrees{}
+	for _, wt := range []*models.Worktree{
+		{ID: "web", Name: "web/login", RepoID: "acme/web", Branch: "login", CommitCount: 2, ClaudeActivityState: models.ClaudeRunning},
+		{ID: "api", Name: "api/login", RepoID: "acme/api", Branch: "login", CommitCount: 1, CommitsBehind: 3, IsDirty: true, ClaudeActivityState: models.ClaudeActive},
+		{ID: "docs", Name: "docs/login", RepoID: "other/web", Branch: "login", SetupChangedFiles: []string{"package.json"}},
+	} {
+		wt.Path = filepath.Join(workspaceDir, wt.Name)
+		require.NoError(t, os.MkdirAll(wt.Path, 0755))
+		worktrees.worktrees = append(worktrees.worktrees, wt)
+	}
+	rootDir := filepath.Join(workspaceDir, "composite")
+	service := NewCompositeWorkspaceServiceWithOptions(filepath.Join(t.TempDir(), "composite_workspaces.json"), rootDir, worktrees)
+	return service, worktrees, workspaceDir
+}
+
+func TestCompositeWorkspaceLifecycle(t *testing.T) {
+	service, worktrees, workspaceDir := newTestCompositeService(t)
+
+	_, err := service.Create(CreateCompositeWorkspaceRequest{Name: "login", Members: []CompositeMember{{WorktreeID: "web"}}})
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err), "needs two members")
+	_, err = service.Create(CreateCompositeWorkspaceRequest{Name: "login", Members: []CompositeMember{{WorktreeID: "web"}, {WorktreeID: "docs"}}})
+	assert.Error(t, err, "aliases default to the repository name and must be unique")
+	_, err = service.Create(CreateCompositeWorkspaceRequest{Name: "login", Members: []CompositeMember{{WorktreeID: "web"}, {WorktreeID: "gone"}}})
+	assert.Equal(t, models.ErrCodeWorktreeNotFound, models.ErrorCodeOf(err))
+
+	workspace, err := service.Create(CreateCompositeWorkspaceRequest{
+		Name:         "login",
+		Members:      []CompositeMember{{WorktreeID: "web"}, {WorktreeID: "api"}},
+		Env:          map[string]string{"API_URL": "http://localhost:8080"},
+		Instructions: "Keep the API client in sync.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(workspaceDir, "composite", "login"), workspace.Path)
+	assert.Equal(t, "composite/login", workspace.SessionID())
+	target, err := os.Readlink(filepath.Join(workspace.Path, "web"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(workspaceDir, "web/login"), target)
+
+	// Members can be added and removed; links follow
+	_, err = service.AddMember("login", CompositeMember{WorktreeID: "docs", Alias: "docs"})
+	require.NoError(t, err)
+	_, err = os.Readlink(filepath.Join(workspace.Path, "docs"))
+	assert.NoError(t, err)
+	_, err = service.RemoveMember("login", "docs")
+	require.NoError(t, err)
+	_, err = os.Lstat(filepath.Join(workspace.Path, "docs"))
+	assert.True(t, os.IsNotExist(err))
+
+	// Env and context apply to the parent directory and its members
+	env := service.EnvForPath(filepath.Join(workspaceDir, "api/login"))
+	assert.Contains(t, env, "CATNIP_COMPOSITE_WORKSPACE=login")
+	assert.Contains(t, env, "API_URL=http://localhost:8080")
+	assert.Empty(t, service.EnvForPath(filepath.Join(workspaceDir, "docs/login")))
+	context := service.ContextForPath(workspace.Path)
+	assert.Contains(t, context, "- web/: acme/web on branch login")
+	assert.Contains(t, context, "- api/: acme/api on branch login")
+	assert.Contains(t, context, "Keep the API client in sync.")
+
+	status, err := service.Status(workspace.ID)
+	require.NoError(t, err)
+	assert.True(t, status.IsDirty)
+	assert.Equal(t, 3, status.CommitCount)
+	assert.Equal(t, 3, status.CommitsBehind)
+	assert.Equal(t, models.ClaudeActive, status.ClaudeActivityState)
+	assert.Len(t, status.Members, 2)
+
+	results, err := service.RunSetup("login")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, worktrees.setupRuns)
+	assert.Equal(t, "no setup.sh found", results[1].Error)
+
+	// Workspaces survive a restart
+	reloaded := NewCompositeWorkspaceServiceWithOptions(service.path, service.rootDir, worktrees)
+	_, err = reloaded.Get("login")
+	require.NoError(t, err)
+
+	require.NoError(t, reloaded.Delete("login", true))
+	assert.NoDirExists(t, workspace.Path)
+	assert.ElementsMatch(t, []string{"web", "api"}, worktrees.deleted)
+	_, err = reloaded.Get("login")
+	assert.Equal(t, models.ErrCodeCompositeNotFound, models.ErrorCodeOf(err))
+}
+
+func TestCompositeWorkspaceStatusReportsMissingMembers(t *testing.T) {
+	service, worktrees, _ := newTestCompositeService(t)
+	_, err := service.Create(CreateCompositeWorkspaceRequest{Name: "pair", Members: []CompositeMember{{WorktreeID: "web"}, {WorktreeID: "api"}}})
+	require.NoError(t, err)
+
+	worktrees.worktrees = worktrees.worktrees[:1]
+	status, err := service.Status("pair")
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.MissingMembers)
+	assert.True(t, status.Members[1].Missing)
+	assert.False(t, status.IsDirty)
+}
+
+func TestCompositeWorkspaceReplaceWorktree(t *testing.T) {
+	service, worktrees, workspaceDir := newTestCompositeService(t)
+	_, err := service.Create(CreateCompositeWorkspaceRequest{Name: "pair", Members: []CompositeMember{{WorktreeID: "web"}, {WorktreeID: "api"}}})
+	require.NoError(t, err)
+
+	standby := &models.Worktree{ID: "web-2", Name: "web/next", RepoID: "acme/web", Path: filepath.Join(workspaceDir, "web/next")}
+	require.NoError(t, os.MkdirAll(standby.Path, 0755))
+	worktrees.worktrees = append(worktrees.worktrees, standby)
+
+	names, err := service.ReplaceWorktree("web", "web-2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pair"}, names)
+
+	workspace, err := service.Get("pair")
+	require.NoError(t, err)
+	assert.Equal(t, CompositeMember{WorktreeID: "web-2", Alias: "web"}, workspace.Members[0])
+	target, err := os.Readlink(filepath.Join(workspace.Path, "web"))
+	require.NoError(t, err)
+	assert.Equal(t, standby.Path, target)
+
+	names, err = service.ReplaceWorktree("docs", "web-2")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
@@ -0,0 +1,396 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// liveConfigKeys are settings read every time they're used, so a reload
+// applies them without anything subscribing
+var liveConfigKeys = []string{
+	"CATNIP_SYNC_UNDO_WINDOW",
+	"CATNIP_SETUP_AUTO_RERUN",
+	"CATNIP_CLAUDE_SESSION_TIMEOUT_SECONDS",
+	"CATNIP_PR_COST_COMMENT",
+	"CATNIP_LOCALE",
+	"CATNIP_PUSH_AGENT_NOTES",
+	"CATNIP_COMMIT_TIMEOUT_SECONDS",
+	ClaudeAllowedToolsSetting,
+	ProfileSetting,
+	PathLockModeSetting,
+}
+
+// ConfigReload describes what a configuration reload changed
+type ConfigReload struct {
+	ReloadedAt time.Time `json:"reloaded_at"`
+	// Settings whose value changed (values are omitted since some are secrets)
+	Changed []string `json:"changed"`
+	// Services that picked up the changes
+	Applied []string `json:"applied"`
+	// Changed settings that are only read at startup
+	RestartRequired []string `json:"restart_required"`
+	// Lines in the overrides file that were skipped
+	Ignored []string `json:"ignored,omitempty"`
+}
+
+// ConfigStatus describes the overrides file and the last reload
+type ConfigStatus struct {
+	Path string `json:"path"`
+	// Settings currently overridden by the file
+	Overrides []string `json:"overrides"`
+	// Active settings profile (CATNIP_PROFILE) and the settings it applies
+	Profile         string   `json:"profile,omitempty" example:"strict-safety"`
+	ProfileSettings []string `json:"profile_settings,omitempty"`
+	// Settings that can change without a restart
+	Reloadable []string      `json:"reloadable"`
+	LastReload *ConfigReload `json:"last_reload,omitempty"`
+}
+
+type configSubscriber struct {
+	name  string
+	keys  []string
+	apply func()
+}
+
+// ConfigManager applies CATNIP_* settings from an env file in the volume on
+// top of the process environment and the active settings profile, and tells
+// subscribed services when a reload changes a setting they read. Services keep reading settings with
+// os.Getenv, so the file works for every setting; only ones a service
+// subscribes to (or reads on every use) take effect without a restart.
+type ConfigManager struct {
+	path        string
+	profilesDir string
+
+	mu          sync.Mutex
+	overrides   map[string]string  // Values applied from the profile and file
+	fileKeys    []string           // Settings set by the file
+	profile     *SettingsProfile   // Active profile, nil if none
+	original    map[string]*string // Environment before overriding, nil when unset
+	subscribers []configSubscriber
+	lastReload  *ConfigReload
+}
+
+// NewConfigManager reads overrides from catnip.env in the volume directory
+func NewConfigManager() *ConfigManager {
+	return NewConfigManagerWithPath(filepath.Join(config.Runtime.VolumeDir, "catnip.env"))
+}
+
+// NewConfigManagerWithPath reads overrides from an explicit path (for
+// testing), with profiles in the profiles directory next to it
+func NewConfigManagerWithPath(path string) *ConfigManager {
+	return &ConfigManager{
+		path:        path,
+		profilesDir: filepath.Join(filepath.Dir(path), "profiles"),
+		overrides:   make(map[string]string),
+		original:    make(map[string]*string),
+	}
+}
+
+// Load applies the overrides file without notifying subscribers. Call it
+// before creating the services that read the settings.
+func (m *ConfigManager) Load() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	overrides, ignored, err := m.readOverrides()
+	if err != nil {
+		return err
+	}
+	overrides, ignored = m.withProfileLocked(overrides, ignored)
+	for _, line := range ignored {
+		logger.Warnf("⚠️ Ignoring %s: %s", m.path, line)
+	}
+	if changed := m.applyLocked(overrides); len(changed) > 0 {
+		logger.Infof("⚙️ Applied %d settings from %s", len(changed), m.path)
+	}
+	if m.profile != nil {
+		logger.Infof("⚙️ Using settings profile %s", m.profile.Name)
+	}
+	return nil
+}
+
+// Subscribe registers a service to be reconfigured when any of keys changes
+func (m *ConfigManager) Subscribe(name string, keys []string, apply func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, configSubscriber{name: name, keys: keys, apply: apply})
+}
+
+// Reload re-reads the overrides file, updates the environment and
+// reconfigures the services whose settings changed. An unreadable file leaves
+// the current settings in place.
+func (m *ConfigManager) Reload() (*ConfigReload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	overrides, ignored, err := m.readOverrides()
+	if err != nil {
+		return nil, err
+	}
+	overrides, ignored = m.withProfileLocked(overrides, ignored)
+
+	reload := &ConfigReload{
+		ReloadedAt:      time.Now(),
+		Changed:         m.applyLocked(overrides),
+		Applied:         []string{},
+		RestartRequired: []string{},
+		Ignored:         ignored,
+	}
+
+	handled := make(map[string]bool)
+	for _, key := range liveConfigKeys {
+		handled[key] = true
+	}
+	for _, subscriber := range m.subscribers {
+		if !containsAny(reload.Changed, subscriber.keys) {
+			continue
+		}
+		subscriber.apply()
+		reload.Applied = append(reload.Applied, subscriber.name)
+		for _, key := range subscriber.keys {
+			handled[key] = true
+		}
+	}
+	for _, key := range reload.Changed {
+		if !handled[key] {
+			reload.RestartRequired = append(reload.RestartRequired, key)
+		}
+	}
+
+	logger.Infof("⚙️ Reloaded configuration: %d changed, applied to %v, restart required for %v",
+		len(reload.Changed), reload.Applied, reload.RestartRequired)
+	m.lastReload = reload
+	return reload, nil
+}
+
+// Set writes settings to the overrides file and reloads it. An empty value
+// removes the setting's line, restoring the value from the environment.
+// Other lines and comments in the file are kept.
+func (m *ConfigManager) Set(values map[string]string) (*ConfigReload, error) {
+	for key := range values {
+		if !isEnvName(key) || !strings.HasPrefix(key, "CATNIP_") {
+			return nil, fmt.Errorf("only CATNIP_* settings can be set, not %s", key)
+		}
+	}
+
+	m.mu.Lock()
+	data, err := os.ReadFile(m.path)
+	if err != nil && !os.IsNotExist(err) {
+		m.mu.Unlock()
+		return nil, err
+	}
+	var lines []string
+	written := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		key, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		key = strings.TrimSpace(key)
+		value, replaced := values[key]
+		if !replaced {
+			if line != "" || len(lines) > 0 {
+				lines = append(lines, line)
+			}
+			continue
+		}
+		// The setting's first line is replaced in place, any others dropped
+		if value != "" && !written[key] {
+			lines = append(lines, fmt.Sprintf("%s=%s", key, strconv.Quote(value)))
+			written[key] = true
+		}
+	}
+	for _, key := range sortedKeys(values) {
+		if values[key] != "" && !written[key] {
+			lines = append(lines, fmt.Sprintf("%s=%s", key, strconv.Quote(values[key])))
+		}
+	}
+
+	err = os.MkdirAll(filepath.Dir(m.path), 0755)
+	if err == nil {
+		tmpPath := m.path + ".tmp"
+		if err = os.WriteFile(tmpPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err == nil {
+			err = os.Rename(tmpPath, m.path)
+		}
+	}
+	m.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %v", m.path, err)
+	}
+	return m.Reload()
+}
+
+// Status reports the overrides in effect and the last reload
+func (m *ConfigManager) Status() ConfigStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := ConfigStatus{
+		Path:       m.path,
+		Overrides:  append([]string{}, m.fileKeys...),
+		Reloadable: append([]string{}, liveConfigKeys...),
+		LastReload: m.lastReload,
+	}
+	if m.profile != nil {
+		status.Profile = m.profile.Name
+		status.ProfileSettings = sortedKeys(m.profile.Settings)
+	}
+	for _, subscriber := range m.subscribers {
+		status.Reloadable = append(status.Reloadable, subscriber.keys...)
+	}
+	sort.Strings(status.Reloadable)
+	return status
+}
+
+// readOverrides parses the overrides file. Only CATNIP_* settings may be
+// overridden.
+func (m *ConfigManager) readOverrides() (map[string]string, []string, error) {
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			m.fileKeys = []string{}
+			return make(map[string]string), nil, nil
+		}
+		return nil, nil, err
+	}
+	overrides, ignored, err := parseEnvFile(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.fileKeys = sortedKeys(overrides)
+	return overrides, ignored, nil
+}
+
+// withProfileLocked layers the file's overrides on top of the active
+// profile's settings. The profile is CATNIP_PROFILE from the file, else from
+// the environment; an unknown one is reported and skipped.
+func (m *ConfigManager) withProfileLocked(overrides map[string]string, ignored []string) (map[string]string, []string) {
+	name, inFile := overrides[ProfileSetting]
+	if !inFile {
+		name = os.Getenv(ProfileSetting)
+		if original, saved := m.original[ProfileSetting]; saved {
+			name = ""
+			if original != nil {
+				name = *original
+			}
+		}
+	}
+
+	m.profile = nil
+	if name == "" {
+		return overrides, ignored
+	}
+	profile, err := m.Profile(name)
+	if err != nil {
+		return overrides, append(ignored, fmt.Sprintf("%s=%s: %v", ProfileSetting, name, err))
+	}
+	m.profile = profile
+
+	layered := make(map[string]string, len(profile.Settings)+len(overrides))
+	for key, value := range profile.Settings {
+		layered[key] = value
+	}
+	for key, value := range overrides {
+		layered[key] = value
+	}
+	return layered, ignored
+}
+
+// parseEnvFile parses KEY=VALUE lines with optional "export" and quotes, and
+// # comments. Lines that aren't CATNIP_* settings are returned as ignored.
+func parseEnvFile(data []byte) (map[string]string, []string, error) {
+	values := make(map[string]string)
+	var ignored []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isEnvName(key) {
+			ignored = append(ignored, fmt.Sprintf("line %d is not KEY=VALUE", lineNum))
+			continue
+		}
+		if !strings.HasPrefix(key, "CATNIP_") {
+			ignored = append(ignored, fmt.Sprintf("line %d: only CATNIP_* settings can be set, not %s", lineNum, key))
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if unquoted, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+			value = unquoted
+		} else if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	return values, ignored, scanner.Err()
+}
+
+// applyLocked sets the environment to the original values plus overrides and
+// returns the keys whose value changed
+func (m *ConfigManager) applyLocked(overrides map[string]string) []string {
+	keys := make(map[string]bool)
+	for key := range m.overrides {
+		keys[key] = true
+	}
+	for key := range overrides {
+		keys[key] = true
+	}
+
+	changed := []string{}
+	for key := range keys {
+		if _, saved := m.original[key]; !saved {
+			if value, set := os.LookupEnv(key); set {
+				m.original[key] = &value
+			} else {
+				m.original[key] = nil
+			}
+		}
+
+		before, wasSet := os.LookupEnv(key)
+		if value, overridden := overrides[key]; overridden {
+			_ = os.Setenv(key, value)
+		} else if original := m.original[key]; original != nil {
+			_ = os.Setenv(key, *original)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+		if after, isSet := os.LookupEnv(key); after != before || isSet != wasSet {
+			changed = append(changed, key)
+		}
+	}
+	m.overrides = overrides
+	sort.Strings(changed)
+	return changed
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, value := range values {
+		for _, candidate := range candidates {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigManagerReload(t *testing.T) {
+	t.Setenv("CATNIP_REPO_CONCURRENCY", "1")
+	t.Setenv("CATNIP_SYNC_UNDO_WINDOW", "")
+	os.Unsetenv("CATNIP_SYNC_UNDO_WINDOW")
+	t.Setenv("CATNIP_TEMPLATE_REPO", "org/templates")
+
+	path := filepath.Join(t.TempDir(), "catnip.env")
+	manager := NewConfigManagerWithPath(path)
+	require.NoError(t, manager.Load(), "a missing file is fine")
+
+	limiter := NewRepoOperationLimiter()
+	applied := 0
+	manager.Subscribe("repository operations", []string{"CATNIP_REPO_CONCURRENCY"}, func() {
+		applied++
+		limiter.ReloadConfig()
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte(`# tuning
+export CATNIP_REPO_CONCURRENCY=3
+CATNIP_SYNC_UNDO_WINDOW="30m"
+CATNIP_TEMPLATE_REPO='org/other'
+PATH=/nowhere
+not a setting
+`), 0644))
+
+	reload, err := manager.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CATNIP_REPO_CONCURRENCY", "CATNIP_SYNC_UNDO_WINDOW", "CATNIP_TEMPLATE_REPO"}, reload.Changed)
+	assert.Equal(t, []string{"repository operations"}, reload.Applied)
+	assert.Equal(t, []string{"CATNIP_TEMPLATE_REPO"}, reload.RestartRequired)
+	assert.Len(t, reload.Ignored, 2)
+	assert.Equal(t, 1, applied)
+	assert.Equal(t, 3, limiter.limit)
+	assert.Equal(t, "30m", os.Getenv("CATNIP_SYNC_UNDO_WINDOW"))
+	assert.Equal(t, "org/other", os.Getenv("CATNIP_TEMPLATE_REPO"))
+	assert.NotEqual(t, "/nowhere", os.Getenv("PATH"))
+
+	// Reloading an unchanged file notifies nobody
+	reload, err = manager.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, reload.Changed)
+	assert.Equal(t, 1, applied)
+
+	// Removing settings restores the environment they overrode
+	require.NoError(t, os.WriteFile(path, []byte("CATNIP_REPO_CONCURRENCY=3\n"), 0644))
+	reload, err = manager.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CATNIP_SYNC_UNDO_WINDOW", "CATNIP_TEMPLATE_REPO"}, reload.Changed)
+	_, set := os.LookupEnv("CATNIP_SYNC_UNDO_WINDOW")
+	assert.False(t, set)
+	assert.Equal(t, "org/templates", os.Getenv("CATNIP_TEMPLATE_REPO"))
+
+	status := manager.Status()
+	assert.Equal(t, []string{"CATNIP_REPO_CONCURRENCY"}, status.Overrides)
+	assert.Contains(t, status.Reloadable, "CATNIP_REPO_CONCURRENCY")
+	assert.Equal(t, reload, status.LastReload)
+}
+
+func TestConfigManagerSet(t *testing.T) {
+	t.Setenv(TimeZoneSetting, "")
+	t.Setenv(LocaleSetting, "")
+	path := filepath.Join(t.TempDir(), "catnip.env")
+	require.NoError(t, os.WriteFile(path, []byte("# tuning\nCATNIP_TIMEZONE=UTC\nCATNIP_REPO_CONCURRENCY=2\n"), 0644))
+	manager := NewConfigManagerWithPath(path)
+	require.NoError(t, manager.Load())
+
+	rescheduled := 0
+	manager.Subscribe("hygiene reports", []string{TimeZoneSetting}, func() { rescheduled++ })
+	reload, err := manager.Set(map[string]string{TimeZoneSetting: "Europe/Berlin", LocaleSetting: "de-DE"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{LocaleSetting, TimeZoneSetting}, reload.Changed)
+	assert.Empty(t, reload.RestartRequired)
+	assert.Equal(t, 1, rescheduled)
+	assert.Equal(t, "Europe/Berlin", os.Getenv(TimeZoneSetting))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# tuning\nCATNIP_TIMEZONE=\"Europe/Berlin\"\nCATNIP_REPO_CONCURRENCY=2\nCATNIP_LOCALE=\"de-DE\"\n", string(data))
+
+	// Empty values remove the setting
+	_, err = manager.Set(map[string]string{TimeZoneSetting: ""})
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), TimeZoneSetting)
+	assert.Empty(t, os.Getenv(TimeZoneSetting))
+
+	_, err = manager.Set(map[string]string{"PATH": "/nowhere"})
+	assert.Error(t, err)
+}
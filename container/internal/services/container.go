@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	goruntime "runtime"
 	"strings"
 	"syscall"
 	"time"
@@ -204,6 +205,12 @@ func (cs *ContainerService) RunContainer(ctx context.Context, image, name, workD
 		// Note: Windows named pipes are handled differently and would need special handling
 	}
 
+	// Mount the host's SSH agent; the server proxies it into workspaces that enable forwarding
+	if socketPath := hostSSHAgentSocket(cs.runtime); socketPath != "" {
+		args = append(args, "-v", fmt.Sprintf("%s:%s", socketPath, ContainerSSHAgentSocket))
+		args = append(args, "-e", "CATNIP_SSH_AGENT_UPSTREAM="+ContainerSSHAgentSocket)
+	}
+
 	// Environment variables
 	switch cs.runtime {
 	case RuntimeDocker:
@@ -830,3 +837,23 @@ func copyFile(src, dst string) error {
 
 	return os.Chmod(dst, sourceInfo.Mode())
 }
+
+// hostSSHAgentSocket returns the SSH agent socket to mount into the container.
+// Docker Desktop on macOS can't share the host's SSH_AUTH_SOCK directly but
+// exposes the agent at a fixed path inside its VM.
+func hostSSHAgentSocket(runtime ContainerRuntime) string {
+	if runtime != RuntimeDocker {
+		return ""
+	}
+	if goruntime.GOOS == "darwin" {
+		return "/run/host-services/ssh-auth.sock"
+	}
+	socketPath := os.Getenv("SSH_AUTH_SOCK")
+	if socketPath == "" {
+		return ""
+	}
+	if _, err := os.Stat(socketPath); err != nil {
+		return ""
+	}
+	return socketPath
+}
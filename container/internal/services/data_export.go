@@ -0,0 +1,380 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Categories of data an export contains and a purge removes
+const (
+	// DataState is the volume's JSON stores: worktrees, settings, jobs, reports and config overrides
+	DataState = "state"
+	// DataAudit is the SSH agent audit log and the state journal
+	DataAudit = "audit"
+	// DataSessions is per-worktree Claude session metadata
+	DataSessions = "sessions"
+	// DataTranscripts is Claude session logs and prompt history
+	DataTranscripts = "transcripts"
+	// DataCaptures is the terminal recordings held in memory, the saved
+	// session captures and the shell scrollback kept across restarts
+	DataCaptures = "captures"
+)
+
+// DataCategories lists every category, in archive order
+var DataCategories = []string{DataState, DataAudit, DataSessions, DataTranscripts, DataCaptures}
+
+// Volume files that are audit logs rather than state
+var auditVolumeFiles = []string{"ssh_agent_audit.jsonl", "journal.json"}
+
+// Volume files and directories that are never exported or purged, and why
+var excludedVolumePaths = map[string]string{
+	"catnip.env": "configuration overrides, which can hold tunnel tokens, webhook secrets and backup credentials",
+	"secrets":    "secret values are never exported; their names are listed in the manifest",
+	"repos":      "git repositories; push branches to move code",
+	"mirrors":    "repository mirrors, recreated from their remotes",
+	"sandboxes":  "worktree sandbox layers; promote or discard them first",
+	"templates":  "project templates, recreated on sync",
+}
+
+// dataExportManifestVersion is bumped when the archive layout changes
+const dataExportManifestVersion = 1
+
+// DataExportManifest describes an export archive. It is the archive's last
+// entry, manifest.json, since it carries every file's checksum.
+type DataExportManifest struct {
+	Version    int                   `json:"version" example:"1"`
+	CreatedAt  time.Time             `json:"created_at"`
+	Categories []DataCategorySummary `json:"categories"`
+	Files      []DataExportFile      `json:"files"`
+	// Data that exists but isn't in the archive
+	Excluded []DataExportExclusion `json:"excluded"`
+	// Names of stored secrets (values are never exported)
+	SecretNames []string `json:"secret_names"`
+}
+
+// DataCategorySummary counts a category's files
+type DataCategorySummary struct {
+	Name  string `json:"name" example:"transcripts"`
+	Files int    `json:"files" example:"42"`
+	Bytes int64  `json:"bytes" example:"1048576"`
+}
+
+// DataExportFile is a file in an export archive
+type DataExportFile struct {
+	// Path in the archive: <category>/<name>
+	Path     string `json:"path" example:"state/state.json"`
+	Category string `json:"category" example:"state"`
+	Bytes    int64  `json:"bytes" example:"2048"`
+	SHA256   string `json:"sha256,omitempty"`
+}
+
+// DataExportExclusion is data left out of an export
+type DataExportExclusion struct {
+	Path   string `json:"path" example:"/volume/repos"`
+	Reason string `json:"reason"`
+}
+
+// DataPurgeResult reports what a purge removed
+type DataPurgeResult struct {
+	Categories []DataCategorySummary `json:"categories"`
+	// Files that couldn't be removed
+	Failed []string `json:"failed,omitempty"`
+}
+
+// dataSource is a file or directory exported under a category
+type dataSource struct {
+	category string
+	path     string
+	name     string // Path under the category in the archive
+}
+
+// DataExportService packages everything catnip stores about the instance
+// and its user into an archive, and purges it
+type DataExportService struct {
+	volumeDir  string
+	homeDir    string
+	sessionDir string
+	recordings *PTYRecorder
+	secrets    *SecretStore
+	now        func() time.Time
+}
+
+// NewDataExportService exports the volume, session state and Claude files of
+// this instance
+func NewDataExportService(recordings *PTYRecorder, secrets *SecretStore) *DataExportService {
+	return NewDataExportServiceWithDirs(config.Runtime.VolumeDir, config.Runtime.HomeDir,
+		filepath.Join(config.Runtime.WorkspaceDir, ".session-state"), recordings, secrets)
+}
+
+// NewDataExportServiceWithDirs exports from explicit directories (for testing)
+func NewDataExportServiceWithDirs(volumeDir, homeDir, sessionDir string, recordings *PTYRecorder, secrets *SecretStore) *DataExportService {
+	return &DataExportService{
+		volumeDir:  volumeDir,
+		homeDir:    homeDir,
+		sessionDir: sessionDir,
+		recordings: recordings,
+		secrets:    secrets,
+		now:        time.Now,
+	}
+}
+
+// ParseDataCategories validates a list of categories; empty means all
+func ParseDataCategories(categories []string) ([]string, error) {
+	if len(categories) == 0 {
+		return DataCategories, nil
+	}
+	selected := make(map[string]bool)
+	for _, category := range categories {
+		category = strings.TrimSpace(category)
+		if !slices.Contains(DataCategories, category) {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown data category %q", category).
+				WithHint("Use one of " + strings.Join(DataCategories, ", "))
+		}
+		selected[category] = true
+	}
+	var ordered []string
+	for _, category := range DataCategories {
+		if selected[category] {
+			ordered = append(ordered, category)
+		}
+	}
+	return ordered, nil
+}
+
+// Export writes a gzipped tarball of the categories laid out as
+// <category>/<name>, followed by manifest.json
+func (s *DataExportService) Export(w io.Writer, categories []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	manifest := s.newManifest()
+
+	add := func(category, name string, data []byte, modTime time.Time) error {
+		archivePath := path.Join(category, name)
+		if err := tw.WriteHeader(&tar.Header{Name: archivePath, Mode: 0644, Size: int64(len(data)), ModTime: modTime}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files = append(manifest.Files, DataExportFile{
+			Path: archivePath, Category: category, Bytes: int64(len(data)), SHA256: hex.EncodeToString(sum[:]),
+		})
+		return nil
+	}
+
+	for _, file := range s.files(categories) {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			// Files come and go while the server runs
+			logger.Debugf("⚠️ Skipping %s in export: %v", file.path, err)
+			continue
+		}
+		if err := add(file.category, file.name, data, file.modTime); err != nil {
+			return err
+		}
+	}
+	if slices.Contains(categories, DataCaptures) && s.recordings != nil {
+		for _, sessionID := range s.recordings.SessionIDs() {
+			var cast bytes.Buffer
+			if err := s.recordings.Export(sessionID, &cast); err != nil {
+				continue
+			}
+			if err := add(DataCaptures, captureFileName(sessionID), cast.Bytes(), s.now()); err != nil {
+				return err
+			}
+		}
+	}
+
+	manifest.Categories = summarizeDataFiles(categories, manifest.Files)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addBytesToTar(tw, "manifest.json", data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Inventory lists what an export of the categories would contain, without
+// file checksums
+func (s *DataExportService) Inventory(categories []string) *DataExportManifest {
+	manifest := s.newManifest()
+	for _, file := range s.files(categories) {
+		manifest.Files = append(manifest.Files, DataExportFile{
+			Path: path.Join(file.category, file.name), Category: file.category, Bytes: file.size,
+		})
+	}
+	if slices.Contains(categories, DataCaptures) && s.recordings != nil {
+		for _, sessionID := range s.recordings.SessionIDs() {
+			manifest.Files = append(manifest.Files, DataExportFile{
+				Path: path.Join(DataCaptures, captureFileName(sessionID)), Category: DataCaptures,
+			})
+		}
+	}
+	manifest.Categories = summarizeDataFiles(categories, manifest.Files)
+	return manifest
+}
+
+// Purge deletes the categories' files. Directories are emptied rather than
+// removed, and excluded data (secrets, repositories) is left alone. Purging
+// state only sticks once the server stops, since services keep it in memory.
+func (s *DataExportService) Purge(categories []string) *DataPurgeResult {
+	result := &DataPurgeResult{}
+	var removed []DataExportFile
+	for _, file := range s.files(categories) {
+		if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+			result.Failed = append(result.Failed, file.path)
+			continue
+		}
+		removed = append(removed, DataExportFile{Path: path.Join(file.category, file.name), Category: file.category, Bytes: file.size})
+	}
+	if slices.Contains(categories, DataCaptures) && s.recordings != nil {
+		for _, sessionID := range s.recordings.SessionIDs() {
+			s.recordings.Forget(sessionID)
+			removed = append(removed, DataExportFile{Path: path.Join(DataCaptures, captureFileName(sessionID)), Category: DataCaptures})
+		}
+	}
+	result.Categories = summarizeDataFiles(categories, removed)
+	logger.Infof("🗑️ Purged %d files of %v", len(removed), categories)
+	return result
+}
+
+func (s *DataExportService) newManifest() *DataExportManifest {
+	manifest := &DataExportManifest{
+		Version:     dataExportManifestVersion,
+		CreatedAt:   s.now(),
+		Files:       []DataExportFile{},
+		Excluded:    []DataExportExclusion{},
+		SecretNames: []string{},
+	}
+	for _, name := range sortedKeys(excludedVolumePaths) {
+		if path := filepath.Join(s.volumeDir, name); pathExists(path) {
+			manifest.Excluded = append(manifest.Excluded, DataExportExclusion{Path: path, Reason: excludedVolumePaths[name]})
+		}
+	}
+	if s.secrets != nil {
+		manifest.SecretNames = s.secrets.Names()
+	}
+	return manifest
+}
+
+// sources lists where each category's data lives
+func (s *DataExportService) sources() []dataSource {
+	var sources []dataSource
+	if entries, err := os.ReadDir(s.volumeDir); err == nil {
+		for _, entry := range entries {
+			name := entry.Name()
+			_, excluded := excludedVolumePaths[name]
+			if !entry.Type().IsRegular() || excluded || strings.HasSuffix(name, ".tmp") {
+				continue
+			}
+			category := DataState
+			if slices.Contains(auditVolumeFiles, name) {
+				category = DataAudit
+			}
+			sources = append(sources, dataSource{category: category, path: filepath.Join(s.volumeDir, name), name: name})
+		}
+	}
+	sources = append(sources,
+		dataSource{category: DataSessions, path: s.sessionDir},
+		dataSource{category: DataTranscripts, path: filepath.Join(s.homeDir, ".claude", "projects"), name: "projects"},
+		dataSource{category: DataTranscripts, path: filepath.Join(s.homeDir, ".claude", "history.jsonl"), name: "history.jsonl"},
+		dataSource{category: DataTranscripts, path: filepath.Join(s.volumeDir, ".claude", ".claude", "projects"), name: "volume-projects"},
+		dataSource{category: DataTranscripts, path: filepath.Join(s.volumeDir, "claude-quarantine"), name: "quarantine"},
+		dataSource{category: DataCaptures, path: filepath.Join(s.volumeDir, "captures"), name: "saved"},
+		dataSource{category: DataCaptures, path: filepath.Join(s.volumeDir, "scrollback"), name: "scrollback"},
+	)
+	return sources
+}
+
+// dataFile is a file on disk belonging to a category
+type dataFile struct {
+	category string
+	path     string
+	name     string
+	size     int64
+	modTime  time.Time
+}
+
+// files walks the categories' sources. A directory reached through two
+// sources (the home directory's .claude linked into the volume) is listed once.
+func (s *DataExportService) files(categories []string) []dataFile {
+	var files []dataFile
+	seen := make(map[string]bool)
+	for _, source := range s.sources() {
+		if !slices.Contains(categories, source.category) {
+			continue
+		}
+		real, err := filepath.EvalSymlinks(source.path)
+		if err != nil || seen[real] {
+			continue
+		}
+		seen[real] = true
+
+		_ = filepath.WalkDir(real, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil || !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			rel, _ := filepath.Rel(real, file)
+			files = append(files, dataFile{
+				category: source.category,
+				path:     file,
+				name:     path.Join(source.name, filepath.ToSlash(rel)),
+				size:     info.Size(),
+				modTime:  info.ModTime(),
+			})
+			return nil
+		})
+	}
+	return files
+}
+
+func summarizeDataFiles(categories []string, files []DataExportFile) []DataCategorySummary {
+	summaries := make([]DataCategorySummary, 0, len(categories))
+	for _, category := range categories {
+		summary := DataCategorySummary{Name: category}
+		for _, file := range files {
+			if file.Category == category {
+				summary.Files++
+				summary.Bytes += file.Bytes
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// captureFileName names a session's recording in the archive
+func captureFileName(sessionID string) string {
+	return strings.NewReplacer("/", "_", ":", "_").Replace(sessionID) + ".cast"
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDataExport(t *testing.T) (*DataExportService, string, string) {
+	root := t.TempDir()
+	volumeDir := filepath.Join(root, "volume")
+	homeDir := filepath.Join(root, "home")
+	sessionDir := filepath.Join(root, "session-state")
+
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	write(filepath.Join(volumeDir, "state.json"), `{"worktrees":{}}`)
+	write(filepath.Join(volumeDir, "settings.json"), `{"theme":"dark"}`)
+	write(filepath.Join(volumeDir, "catnip.env"), "CATNIP_TUNNEL_TOKEN=tunnel_supersecret\n")
+	write(filepath.Join(volumeDir, "ssh_agent_audit.jsonl"), `{"action":"sign"}`+"\n")
+	write(filepath.Join(volumeDir, "repos", "app.git", "HEAD"), "ref: refs/heads/main\n")
+	write(filepath.Join(sessionDir, "app", "session.json"), `{"id":"abc"}`)
+	write(filepath.Join(homeDir, ".claude", "projects", "-workspace-app", "abc.jsonl"), `{"type":"user"}`+"\n")
+	write(filepath.Join(homeDir, ".claude", "history.jsonl"), `{"display":"fix the bug"}`+"\n")
+
+	secrets := NewSecretStoreWithPath(filepath.Join(volumeDir, "secrets"))
+	require.NoError(t, secrets.Set("GITHUB_TOKEN", "ghp_supersecret"))
+
+	recorder := NewPTYRecorderWithDir(t.TempDir())
+	_, err := recorder.Start("app:main", "app", 80, 24, nil)
+	require.NoError(t, err)
+	recorder.Output("app:main", []byte("hello\r\n"))
+
+	return NewDataExportServiceWithDirs(volumeDir, homeDir, sessionDir, recorder, secrets), volumeDir, homeDir
+}
+
+func readDataExport(t *testing.T, archive []byte) ([]string, map[string][]byte) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	var names []string
+	contents := make(map[string][]byte)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		names = append(names, header.Name)
+		contents[header.Name] = data
+	}
+	return names, contents
+}
+
+func TestDataExport(t *testing.T) {
+	service, volumeDir, _ := newTestDataExport(t)
+
+	var archive bytes.Buffer
+	require.NoError(t, service.Export(&archive, DataCategories))
+	names, contents := readDataExport(t, archive.Bytes())
+
+	require.NotEmpty(t, names)
+	assert.Equal(t, "manifest.json", names[len(names)-1])
+	assert.Contains(t, names, "state/state.json")
+	assert.Contains(t, names, "state/settings.json")
+	assert.Contains(t, names, "audit/ssh_agent_audit.jsonl")
+	assert.Contains(t, names, "sessions/app/session.json")
+	assert.Contains(t, names, "transcripts/projects/-workspace-app/abc.jsonl")
+	assert.Contains(t, names, "transcripts/history.jsonl")
+	assert.Contains(t, names, "captures/app_main.cast")
+	assert.Contains(t, string(contents["captures/app_main.cast"]), "hello")
+
+	for name, data := range contents {
+		assert.NotContains(t, string(data), "ghp_supersecret", name)
+		assert.NotContains(t, string(data), "tunnel_supersecret", name)
+		assert.NotContains(t, name, "repos/")
+	}
+
+	var manifest DataExportManifest
+	require.NoError(t, json.Unmarshal(contents["manifest.json"], &manifest))
+	assert.Equal(t, []string{"GITHUB_TOKEN"}, manifest.SecretNames)
+	assert.Len(t, manifest.Files, len(names)-1)
+	for _, file := range manifest.Files {
+		sum := sha256.Sum256(contents[file.Path])
+		assert.Equal(t, hex.EncodeToString(sum[:]), file.SHA256, file.Path)
+	}
+	var excluded []string
+	for _, exclusion := range manifest.Excluded {
+		excluded = append(excluded, exclusion.Path)
+	}
+	assert.Contains(t, excluded, filepath.Join(volumeDir, "repos"))
+	assert.Contains(t, excluded, filepath.Join(volumeDir, "secrets"))
+	assert.Contains(t, excluded, filepath.Join(volumeDir, "catnip.env"))
+}
+
+func TestDataExportCategories(t *testing.T) {
+	service, _, _ := newTestDataExport(t)
+
+	categories, err := ParseDataCategories([]string{"transcripts", " state"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{DataState, DataTranscripts}, categories)
+
+	_, err = ParseDataCategories([]string{"secrets"})
+	assert.Error(t, err)
+
+	manifest := service.Inventory([]string{DataAudit})
+	require.Len(t, manifest.Categories, 1)
+	assert.Equal(t, DataCategorySummary{Name: DataAudit, Files: 1, Bytes: int64(len(`{"action":"sign"}` + "\n"))}, manifest.Categories[0])
+}
+
+func TestDataPurge(t *testing.T) {
+	service, volumeDir, homeDir := newTestDataExport(t)
+
+	result := service.Purge([]string{DataAudit, DataTranscripts, DataCaptures})
+	assert.Empty(t, result.Failed)
+	require.Len(t, result.Categories, 3)
+	assert.Equal(t, 2, result.Categories[1].Files)
+	assert.Equal(t, 1, result.Categories[2].Files)
+
+	assert.NoFileExists(t, filepath.Join(volumeDir, "ssh_agent_audit.jsonl"))
+	assert.NoFileExists(t, filepath.Join(homeDir, ".claude", "history.jsonl"))
+	assert.NoFileExists(t, filepath.Join(homeDir, ".claude", "projects", "-workspace-app", "abc.jsonl"))
+	assert.Empty(t, service.recordings.SessionIDs())
+
+	// Other categories and excluded data are untouched
+	assert.FileExists(t, filepath.Join(volumeDir, "state.json"))
+	assert.FileExists(t, filepath.Join(volumeDir, "repos", "app.git", "HEAD"))
+	assert.Equal(t, []string{"GITHUB_TOKEN"}, service.secrets.Names())
+}
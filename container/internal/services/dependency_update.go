@@ -0,0 +1,532 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	defaultDependencyFixAttempts    = 2
+	defaultDependencyCommandTimeout = 15 * time.Minute
+	dependencyFixMaxTurns           = 50
+	// Output kept per step and sent to Claude when tests fail
+	maxDependencyStepOutput = 16 * 1024
+	// Finished runs kept in memory for GET requests
+	maxDependencyUpdateRuns = 50
+)
+
+// DependencyUpdateStepID identifies a step of the dependency update workflow
+type DependencyUpdateStepID string
+
+const (
+	DependencyStepCreateWorktree DependencyUpdateStepID = "create_worktree"
+	DependencyStepUpdate         DependencyUpdateStepID = "update_dependencies"
+	DependencyStepTest           DependencyUpdateStepID = "test"
+	DependencyStepFix            DependencyUpdateStepID = "fix"
+	DependencyStepCommit         DependencyUpdateStepID = "commit"
+	DependencyStepPullRequest    DependencyUpdateStepID = "pull_request"
+)
+
+// DependencyUpdateStatus is the state of a run or one of its steps
+type DependencyUpdateStatus string
+
+const (
+	DependencyUpdatePending   DependencyUpdateStatus = "pending"
+	DependencyUpdateRunning   DependencyUpdateStatus = "running"
+	DependencyUpdateSucceeded DependencyUpdateStatus = "succeeded"
+	DependencyUpdateFailed    DependencyUpdateStatus = "failed"
+	DependencyUpdateSkipped   DependencyUpdateStatus = "skipped"
+	DependencyUpdateUpToDate  DependencyUpdateStatus = "up_to_date" // Nothing changed, no PR opened
+)
+
+// DependencyUpdateStep is one step of a dependency update run
+type DependencyUpdateStep struct {
+	ID         DependencyUpdateStepID `json:"id"`
+	Status     DependencyUpdateStatus `json:"status"`
+	Detail     string                 `json:"detail,omitempty"`
+	Output     string                 `json:"output,omitempty"` // Tail of command output
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+}
+
+// DependencyUpdateRun is one API-triggered dependency update workflow
+type DependencyUpdateRun struct {
+	ID             string                 `json:"id"`
+	RepoID         string                 `json:"repo_id"`
+	WorktreeID     string                 `json:"worktree_id,omitempty"`
+	WorktreeName   string                 `json:"worktree_name,omitempty"`
+	Status         DependencyUpdateStatus `json:"status"`
+	Config         DependencyUpdateConfig `json:"config"`
+	Steps          []DependencyUpdateStep `json:"steps"`
+	FixAttempts    int                    `json:"fix_attempts"`
+	CommitHash     string                 `json:"commit_hash,omitempty"`
+	PullRequestURL string                 `json:"pull_request_url,omitempty"`
+	Error          string                 `json:"error,omitempty"`
+	StartedAt      time.Time              `json:"started_at"`
+	FinishedAt     *time.Time             `json:"finished_at,omitempty"`
+
+	jobID string // Persisted automation job, if tracked
+}
+
+// dependencyUpdateJobParams is what's persisted to resume a run after a restart
+type dependencyUpdateJobParams struct {
+	RunID      string                  `json:"run_id"`
+	RepoID     string                  `json:"repo_id"`
+	WorktreeID string                  `json:"worktree_id,omitempty"`
+	Request    DependencyUpdateRequest `json:"request"`
+}
+
+// DependencyUpdateRequest starts a dependency update run. Empty fields fall
+// back to .catnip.yaml and then to lockfile detection.
+type DependencyUpdateRequest struct {
+	Commands    []string `json:"commands,omitempty"`
+	Test        string   `json:"test,omitempty"`
+	FixAttempts *int     `json:"fix_attempts,omitempty"`
+}
+
+// DependencyUpdateEventsEmitter publishes run progress
+type DependencyUpdateEventsEmitter interface {
+	EmitDependencyUpdateProgress(run *DependencyUpdateRun)
+}
+
+// completionCreator runs a Claude completion (implemented by ClaudeService)
+type completionCreator interface {
+	CreateCompletion(ctx context.Context, req *models.CreateCompletionRequest) (*models.CreateCompletionResponse, error)
+}
+
+// DependencyUpdateService runs dependency updates in a fresh worktree, lets
+// Claude fix the fallout, runs tests and opens a PR
+type DependencyUpdateService struct {
+	gitService *GitService
+	claude     completionCreator
+	events     DependencyUpdateEventsEmitter
+	jobs       *AutomationJobService
+	mu         sync.Mutex
+	runs       map[string]*DependencyUpdateRun
+}
+
+// NewDependencyUpdateService creates a dependency update service
+func NewDependencyUpdateService(gitService *GitService, claude completionCreator) *DependencyUpdateService {
+	return &DependencyUpdateService{
+		gitService: gitService,
+		claude:     claude,
+		runs:       make(map[string]*DependencyUpdateRun),
+	}
+}
+
+// WithEvents sets the emitter used for progress events
+func (s *DependencyUpdateService) WithEvents(events DependencyUpdateEventsEmitter) *DependencyUpdateService {
+	s.events = events
+	return s
+}
+
+// WithAutomationJobs persists runs as automation jobs so runs interrupted by
+// a restart are resumed on boot
+func (s *DependencyUpdateService) WithAutomationJobs(jobs *AutomationJobService) *DependencyUpdateService {
+	s.jobs = jobs
+	jobs.RegisterResumer(AutomationJobDependencyUpdate, s.resumeJob)
+	return s
+}
+
+// Start validates the repository and runs the workflow in the background
+func (s *DependencyUpdateService) Start(repoID string, req DependencyUpdateRequest) (*DependencyUpdateRun, error) {
+	repo := s.gitService.GetRepositoryByID(repoID)
+	if repo == nil {
+		return nil, models.NewRepositoryNotFoundError(repoID)
+	}
+	if req.FixAttempts != nil && *req.FixAttempts < 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "fix_attempts must not be negative")
+	}
+
+	run := newDependencyUpdateRun(uuid.New().String(), repoID)
+	if s.jobs != nil {
+		jobID, err := s.jobs.Begin(AutomationJobDependencyUpdate, "Update dependencies in "+repoID, dependencyUpdateJobParams{
+			RunID:   run.ID,
+			RepoID:  repoID,
+			Request: req,
+		})
+		if err != nil {
+			logger.Warnf("⚠️ Dependency update %s won't survive a restart: %v", run.ID, err)
+		}
+		run.jobID = jobID
+	}
+
+	return s.launch(run, req), nil
+}
+
+func newDependencyUpdateRun(id, repoID string) *DependencyUpdateRun {
+	run := &DependencyUpdateRun{
+		ID:        id,
+		RepoID:    repoID,
+		Status:    DependencyUpdateRunning,
+		StartedAt: time.Now(),
+	}
+	for _, id := range []DependencyUpdateStepID{DependencyStepCreateWorktree, DependencyStepUpdate, DependencyStepTest, DependencyStepFix, DependencyStepCommit, DependencyStepPullRequest} {
+		run.Steps = append(run.Steps, DependencyUpdateStep{ID: id, Status: DependencyUpdatePending})
+	}
+	return run
+}
+
+func (s *DependencyUpdateService) launch(run *DependencyUpdateRun, req DependencyUpdateRequest) *DependencyUpdateRun {
+	s.mu.Lock()
+	s.runs[run.ID] = run
+	s.pruneLocked()
+	snapshot := s.snapshotLocked(run)
+	s.mu.Unlock()
+
+	go s.execute(run, req)
+	return snapshot
+}
+
+// resumeJob restarts a run interrupted by a restart, reusing its worktree
+// when one was already created. Update commands are rerun from the start.
+func (s *DependencyUpdateService) resumeJob(job *AutomationJob) error {
+	var params dependencyUpdateJobParams
+	if err := json.Unmarshal(job.Params, &params); err != nil {
+		return fmt.Errorf("invalid dependency update params: %v", err)
+	}
+	if s.gitService.GetRepositoryByID(params.RepoID) == nil {
+		return models.NewRepositoryNotFoundError(params.RepoID)
+	}
+
+	run := newDependencyUpdateRun(params.RunID, params.RepoID)
+	run.WorktreeID = params.WorktreeID
+	run.jobID = job.ID
+	s.launch(run, params.Request)
+	return nil
+}
+
+// Get returns a copy of a run
+func (s *DependencyUpdateService) Get(runID string) (*DependencyUpdateRun, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, exists := s.runs[runID]
+	if !exists {
+		return nil, false
+	}
+	return s.snapshotLocked(run), true
+}
+
+// List returns copies of all runs, newest first
+func (s *DependencyUpdateService) List() []*DependencyUpdateRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := make([]*DependencyUpdateRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, s.snapshotLocked(run))
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return runs
+}
+
+func (s *DependencyUpdateService) snapshotLocked(run *DependencyUpdateRun) *DependencyUpdateRun {
+	copied := *run
+	copied.Steps = append([]DependencyUpdateStep(nil), run.Steps...)
+	copied.Config.Commands = append([]string(nil), run.Config.Commands...)
+	return &copied
+}
+
+// pruneLocked drops the oldest finished runs beyond the retention limit
+func (s *DependencyUpdateService) pruneLocked() {
+	if len(s.runs) <= maxDependencyUpdateRuns {
+		return
+	}
+	var finished []*DependencyUpdateRun
+	for _, run := range s.runs {
+		if run.FinishedAt != nil {
+			finished = append(finished, run)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].StartedAt.Before(finished[j].StartedAt) })
+	for i := 0; i < len(finished) && len(s.runs) > maxDependencyUpdateRuns; i++ {
+		delete(s.runs, finished[i].ID)
+	}
+}
+
+// update mutates a run under the lock and publishes the new state
+func (s *DependencyUpdateService) update(run *DependencyUpdateRun, fn func(run *DependencyUpdateRun)) {
+	s.mu.Lock()
+	fn(run)
+	snapshot := s.snapshotLocked(run)
+	s.mu.Unlock()
+
+	if s.events != nil {
+		s.events.EmitDependencyUpdateProgress(snapshot)
+	}
+}
+
+func (s *DependencyUpdateService) setStep(run *DependencyUpdateRun, id DependencyUpdateStepID, status DependencyUpdateStatus, detail, output string) {
+	s.update(run, func(run *DependencyUpdateRun) {
+		for i := range run.Steps {
+			step := &run.Steps[i]
+			if step.ID != id {
+				continue
+			}
+			now := time.Now()
+			if status == DependencyUpdateRunning && step.StartedAt == nil {
+				step.StartedAt = &now
+			}
+			if status != DependencyUpdateRunning && status != DependencyUpdatePending {
+				step.FinishedAt = &now
+			}
+			step.Status = status
+			if detail != "" {
+				step.Detail = detail
+			}
+			if output != "" {
+				step.Output = tailOutput(output)
+			}
+		}
+	})
+}
+
+func (s *DependencyUpdateService) finish(run *DependencyUpdateRun, status DependencyUpdateStatus, err error) {
+	s.update(run, func(run *DependencyUpdateRun) {
+		now := time.Now()
+		run.Status = status
+		run.FinishedAt = &now
+		if err != nil {
+			run.Error = err.Error()
+		}
+		for i := range run.Steps {
+			if run.Steps[i].Status == DependencyUpdatePending {
+				run.Steps[i].Status = DependencyUpdateSkipped
+			}
+		}
+	})
+	if s.jobs != nil && run.jobID != "" {
+		s.jobs.Finish(run.jobID, string(status), err)
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Dependency update %s for %s failed: %v", run.ID, run.RepoID, err)
+	} else {
+		logger.Infof("📦 Dependency update %s for %s finished: %s", run.ID, run.RepoID, status)
+	}
+}
+
+func (s *DependencyUpdateService) execute(run *DependencyUpdateRun, req DependencyUpdateRequest) {
+	s.setStep(run, DependencyStepCreateWorktree, DependencyUpdateRunning, "", "")
+	worktree, reused := s.gitService.GetWorktree(run.WorktreeID)
+	if !reused {
+		org, name, _ := strings.Cut(run.RepoID, "/")
+		var err error
+		_, worktree, err = s.gitService.CheckoutRepository(org, name, "")
+		if err != nil {
+			s.setStep(run, DependencyStepCreateWorktree, DependencyUpdateFailed, err.Error(), "")
+			s.finish(run, DependencyUpdateFailed, err)
+			return
+		}
+	}
+	s.update(run, func(run *DependencyUpdateRun) {
+		run.WorktreeID = worktree.ID
+		run.WorktreeName = worktree.Name
+	})
+	if s.jobs != nil && run.jobID != "" {
+		s.jobs.UpdateParams(run.jobID, dependencyUpdateJobParams{
+			RunID:      run.ID,
+			RepoID:     run.RepoID,
+			WorktreeID: worktree.ID,
+			Request:    req,
+		})
+	}
+	detail := worktree.Name
+	if reused {
+		detail += " (resumed after restart)"
+	}
+	s.setStep(run, DependencyStepCreateWorktree, DependencyUpdateSucceeded, detail, "")
+
+	cfg, err := resolveDependencyUpdateConfig(worktree.Path, req)
+	if err != nil {
+		s.finish(run, DependencyUpdateFailed, err)
+		return
+	}
+	s.update(run, func(run *DependencyUpdateRun) { run.Config = cfg })
+
+	status, err := s.updateAndVerify(run, worktree.Path, cfg)
+	if err != nil || status == DependencyUpdateUpToDate {
+		s.finish(run, status, err)
+		return
+	}
+
+	s.setStep(run, DependencyStepCommit, DependencyUpdateRunning, "", "")
+	hash, err := s.gitService.GitAddCommitGetHash(worktree.Path, "Update dependencies")
+	if err != nil {
+		s.setStep(run, DependencyStepCommit, DependencyUpdateFailed, err.Error(), "")
+		s.finish(run, DependencyUpdateFailed, err)
+		return
+	}
+	s.update(run, func(run *DependencyUpdateRun) { run.CommitHash = hash })
+	s.setStep(run, DependencyStepCommit, DependencyUpdateSucceeded, hash, "")
+
+	s.setStep(run, DependencyStepPullRequest, DependencyUpdateRunning, "", "")
+	pr, err := s.gitService.CreatePullRequest(worktree.ID, "Update dependencies", dependencyUpdatePRBody(run), false)
+	if err != nil {
+		s.setStep(run, DependencyStepPullRequest, DependencyUpdateFailed, err.Error(), "")
+		s.finish(run, DependencyUpdateFailed, err)
+		return
+	}
+	s.update(run, func(run *DependencyUpdateRun) { run.PullRequestURL = pr.URL })
+	s.setStep(run, DependencyStepPullRequest, DependencyUpdateSucceeded, pr.URL, "")
+	s.finish(run, DependencyUpdateSucceeded, nil)
+}
+
+// updateAndVerify runs the update commands, then tests, asking Claude to fix
+// failures up to the configured number of attempts. It returns
+// DependencyUpdateUpToDate when the update changed nothing.
+func (s *DependencyUpdateService) updateAndVerify(run *DependencyUpdateRun, dir string, cfg DependencyUpdateConfig) (DependencyUpdateStatus, error) {
+	timeout := time.Duration(cfg.TimeoutMinutes) * time.Minute
+
+	s.setStep(run, DependencyStepUpdate, DependencyUpdateRunning, "", "")
+	var updateOutput strings.Builder
+	for _, command := range cfg.Commands {
+		output, err := runDependencyCommand(dir, command, timeout)
+		fmt.Fprintf(&updateOutput, "$ %s\n%s\n", command, output)
+		if err != nil {
+			s.setStep(run, DependencyStepUpdate, DependencyUpdateFailed, fmt.Sprintf("%s: %v", command, err), updateOutput.String())
+			return DependencyUpdateFailed, fmt.Errorf("update command %q failed: %v", command, err)
+		}
+	}
+	s.setStep(run, DependencyStepUpdate, DependencyUpdateSucceeded, strings.Join(cfg.Commands, "; "), updateOutput.String())
+
+	if !s.gitService.operations.IsDirty(dir) {
+		s.setStep(run, DependencyStepTest, DependencyUpdateSkipped, "dependencies already up to date", "")
+		return DependencyUpdateUpToDate, nil
+	}
+
+	if cfg.Test == "" {
+		s.setStep(run, DependencyStepTest, DependencyUpdateSkipped, "no test command configured", "")
+		s.setStep(run, DependencyStepFix, DependencyUpdateSkipped, "", "")
+		return DependencyUpdateSucceeded, nil
+	}
+
+	fixAttempts := defaultDependencyFixAttempts
+	if cfg.FixAttempts != nil {
+		fixAttempts = *cfg.FixAttempts
+	}
+
+	for attempt := 0; ; attempt++ {
+		s.setStep(run, DependencyStepTest, DependencyUpdateRunning, cfg.Test, "")
+		output, err := runDependencyCommand(dir, cfg.Test, timeout)
+		if err == nil {
+			s.setStep(run, DependencyStepTest, DependencyUpdateSucceeded, cfg.Test, output)
+			if attempt == 0 {
+				s.setStep(run, DependencyStepFix, DependencyUpdateSkipped, "tests passed", "")
+			} else {
+				s.setStep(run, DependencyStepFix, DependencyUpdateSucceeded, fmt.Sprintf("fixed after %d attempt(s)", attempt), "")
+			}
+			return DependencyUpdateSucceeded, nil
+		}
+
+		if attempt >= fixAttempts || s.claude == nil {
+			s.setStep(run, DependencyStepTest, DependencyUpdateFailed, err.Error(), output)
+			if attempt > 0 {
+				s.setStep(run, DependencyStepFix, DependencyUpdateFailed, fmt.Sprintf("tests still failing after %d attempt(s)", attempt), "")
+			}
+			return DependencyUpdateFailed, fmt.Errorf("tests failed after dependency update; the worktree was kept for manual fixes")
+		}
+		s.setStep(run, DependencyStepTest, DependencyUpdateRunning, fmt.Sprintf("failed, asking Claude to fix (attempt %d of %d)", attempt+1, fixAttempts), output)
+
+		s.setStep(run, DependencyStepFix, DependencyUpdateRunning, fmt.Sprintf("attempt %d of %d", attempt+1, fixAttempts), "")
+		s.update(run, func(run *DependencyUpdateRun) { run.FixAttempts = attempt + 1 })
+		if _, err := s.claude.CreateCompletion(context.Background(), &models.CreateCompletionRequest{
+			Prompt:           dependencyFixPrompt(cfg, output),
+			WorkingDirectory: dir,
+			MaxTurns:         dependencyFixMaxTurns,
+			SuppressEvents:   true,
+		}); err != nil {
+			s.setStep(run, DependencyStepFix, DependencyUpdateFailed, err.Error(), "")
+			return DependencyUpdateFailed, fmt.Errorf("claude fix attempt failed: %v", err)
+		}
+	}
+}
+
+// resolveDependencyUpdateConfig merges the request over .catnip.yaml over
+// lockfile detection
+func resolveDependencyUpdateConfig(dir string, req DependencyUpdateRequest) (DependencyUpdateConfig, error) {
+	catnipConfig, err := LoadCatnipConfig(dir)
+	if err != nil {
+		return DependencyUpdateConfig{}, err
+	}
+	cfg := catnipConfig.Dependencies
+	detected := DetectDependencyUpdateConfig(dir)
+
+	if len(req.Commands) > 0 {
+		cfg.Commands = req.Commands
+	} else if len(cfg.Commands) == 0 {
+		cfg.Commands = detected.Commands
+	}
+	if req.Test != "" {
+		cfg.Test = req.Test
+	} else if cfg.Test == "" {
+		cfg.Test = detected.Test
+	}
+	if req.FixAttempts != nil {
+		cfg.FixAttempts = req.FixAttempts
+	}
+	if cfg.TimeoutMinutes <= 0 {
+		cfg.TimeoutMinutes = int(defaultDependencyCommandTimeout / time.Minute)
+	}
+
+	if len(cfg.Commands) == 0 {
+		return cfg, models.NewAPIError(models.ErrCodeInvalidRequest, "no dependency update commands found; add dependencies.commands to %s", CatnipConfigFileName)
+	}
+	return cfg, nil
+}
+
+func runDependencyCommand(dir, command string, timeout time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "bash", "-lc", command)
+	cmd.Dir = dir
+	// Don't wait on children still holding the output pipe after a timeout
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(output), fmt.Errorf("timed out after %v", timeout)
+	}
+	return string(output), err
+}
+
+func tailOutput(output string) string {
+	if len(output) <= maxDependencyStepOutput {
+		return output
+	}
+	return "…" + output[len(output)-maxDependencyStepOutput:]
+}
+
+func dependencyFixPrompt(cfg DependencyUpdateConfig, testOutput string) string {
+	return fmt.Sprintf(`Dependencies in this repository were just updated with:
+
+%s
+
+The test command %q now fails. Fix the code so the tests pass with the updated dependencies. Prefer adapting to the new APIs over pinning old versions, don't disable or delete tests, and don't commit. Test output:
+
+%s`, "    "+strings.Join(cfg.Commands, "\n    "), cfg.Test, tailOutput(testOutput))
+}
+
+func dependencyUpdatePRBody(run *DependencyUpdateRun) string {
+	var b strings.Builder
+	b.WriteString("Automated dependency update.\n\n**Update commands**\n\n")
+	for _, command := range run.Config.Commands {
+		fmt.Fprintf(&b, "- `%s`\n", command)
+	}
+	if run.Config.Test != "" {
+		fmt.Fprintf(&b, "\n**Verified with** `%s`", run.Config.Test)
+		if run.FixAttempts > 0 {
+			fmt.Fprintf(&b, " after %d Claude fix attempt(s)", run.FixAttempts)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// fixingClaude "fixes" tests by creating the file the test command checks for
+type fixingClaude struct {
+	calls int
+	fix   bool
+}
+
+func (f *fixingClaude) CreateCompletion(ctx context.Context, req *models.CreateCompletionRequest) (*models.CreateCompletionResponse, error) {
+	f.calls++
+	if f.fix {
+		return &models.CreateCompletionResponse{}, os.WriteFile(filepath.Join(req.WorkingDirectory, "fixed"), []byte("ok"), 0644)
+	}
+	return &models.CreateCompletionResponse{}, nil
+}
+
+func TestResolveDependencyUpdateConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"scripts":{"test":"vitest"}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pnpm-lock.yaml"), []byte(""), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module x"), 0644))
+
+	cfg, err := resolveDependencyUpdateConfig(dir, DependencyUpdateRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pnpm update", "go get -u ./... && go mod tidy"}, cfg.Commands)
+	assert.Equal(t, "pnpm test && go test ./...", cfg.Test)
+	assert.Equal(t, 15, cfg.TimeoutMinutes)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte("dependencies:\n  commands:\n    - pnpm up --latest\n  fix_attempts: 1\n"), 0644))
+	cfg, err = resolveDependencyUpdateConfig(dir, DependencyUpdateRequest{Test: "make check"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pnpm up --latest"}, cfg.Commands)
+	assert.Equal(t, "make check", cfg.Test)
+	require.NotNil(t, cfg.FixAttempts)
+	assert.Equal(t, 1, *cfg.FixAttempts)
+
+	_, err = resolveDependencyUpdateConfig(t.TempDir(), DependencyUpdateRequest{})
+	assert.Error(t, err, "nothing to update without config or lockfiles")
+}
+
+func TestDependencyUpdateVerify(t *testing.T) {
+	newRepo := func(t *testing.T) string {
+		dir := t.TempDir()
+		runTestGit(t, dir, "init", "-b", "main")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "deps.txt"), []byte("v1"), 0644))
+		runTestGit(t, dir, "add", ".")
+		runTestGit(t, dir, "commit", "-m", "initial")
+		return dir
+	}
+	newRun := func() *DependencyUpdateRun {
+		run := &DependencyUpdateRun{ID: "run"}
+		for _, id := range []DependencyUpdateStepID{DependencyStepUpdate, DependencyStepTest, DependencyStepFix} {
+			run.Steps = append(run.Steps, DependencyUpdateStep{ID: id, Status: DependencyUpdatePending})
+		}
+		return run
+	}
+	cfg := DependencyUpdateConfig{
+		Commands:       []string{"echo v2 > deps.txt"},
+		Test:           "test -f fixed",
+		TimeoutMinutes: 1,
+	}
+
+	t.Run("claude fixes failing tests", func(t *testing.T) {
+		claude := &fixingClaude{fix: true}
+		service := NewDependencyUpdateService(createTestGitService(t), claude)
+		run := newRun()
+
+		status, err := service.updateAndVerify(run, newRepo(t), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, DependencyUpdateSucceeded, status)
+		assert.Equal(t, 1, claude.calls)
+		assert.Equal(t, 1, run.FixAttempts)
+		assert.Equal(t, DependencyUpdateSucceeded, run.Steps[2].Status)
+	})
+
+	t.Run("gives up after the configured attempts", func(t *testing.T) {
+		claude := &fixingClaude{}
+		service := NewDependencyUpdateService(createTestGitService(t), claude)
+		run := newRun()
+
+		status, err := service.updateAndVerify(run, newRepo(t), cfg)
+		assert.Error(t, err)
+		assert.Equal(t, DependencyUpdateFailed, status)
+		assert.Equal(t, defaultDependencyFixAttempts, claude.calls)
+		assert.Equal(t, DependencyUpdateFailed, run.Steps[1].Status)
+	})
+
+	t.Run("nothing to do when already up to date", func(t *testing.T) {
+		service := NewDependencyUpdateService(createTestGitService(t), &fixingClaude{})
+		upToDate := cfg
+		upToDate.Commands = []string{"true"}
+
+		status, err := service.updateAndVerify(newRun(), newRepo(t), upToDate)
+		require.NoError(t, err)
+		assert.Equal(t, DependencyUpdateUpToDate, status)
+	})
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// devcontainerPaths are where a devcontainer.json is looked for, in order. A
+// repo with several configurations under .devcontainer/<name>/ uses the first
+// by name.
+var devcontainerPaths = []string{
+	".devcontainer/devcontainer.json",
+	".devcontainer.json",
+	".devcontainer/*/devcontainer.json",
+}
+
+// devcontainerLifecycleStages are the lifecycle commands run as setup, in the
+// order a dev container runs them. initializeCommand runs on the host and
+// postAttachCommand on every attach, so neither has a catnip equivalent.
+var devcontainerLifecycleStages = []string{
+	"onCreateCommand",
+	"updateContentCommand",
+	"postCreateCommand",
+	"postStartCommand",
+}
+
+// devcontainerFeatureTools maps dev container feature names to the command
+// each one installs. Catnip can't install features, so a feature is only
+// usable if the image already provides its tool.
+var devcontainerFeatureTools = map[string]string{
+	"node":                     "node",
+	"python":                   "python3",
+	"go":                       "go",
+	"rust":                     "cargo",
+	"java":                     "java",
+	"ruby":                     "ruby",
+	"php":                      "php",
+	"dotnet":                   "dotnet",
+	"git":                      "git",
+	"github-cli":               "gh",
+	"docker-in-docker":         "docker",
+	"docker-outside-of-docker": "docker",
+	"aws-cli":                  "aws",
+	"azure-cli":                "az",
+	"kubectl-helm-minikube":    "kubectl",
+	"terraform":                "terraform",
+	"conda":                    "conda",
+	"deno":                     "deno",
+	"bun":                      "bun",
+}
+
+// devcontainerIgnoredKeys are settings that describe how to build or run the
+// container itself, which catnip provides
+var devcontainerIgnoredKeys = []string{
+	"image",
+	"build",
+	"dockerFile",
+	"dockerComposeFile",
+	"runArgs",
+	"mounts",
+	"initializeCommand",
+	"postAttachCommand",
+}
+
+// DevcontainerImportConfig configures how a repo's devcontainer.json is used
+type DevcontainerImportConfig struct {
+	// Whether to map devcontainer.json onto setup and ports (default true)
+	Import *bool `json:"import,omitempty" yaml:"import"`
+}
+
+// Devcontainer is the subset of a devcontainer.json that catnip understands
+type Devcontainer struct {
+	// Path of the file relative to the repository root
+	Path            string                             `json:"-"`
+	Name            string                             `json:"name"`
+	Features        map[string]json.RawMessage         `json:"features"`
+	ForwardPorts    []json.RawMessage                  `json:"forwardPorts"`
+	PortsAttributes map[string]DevcontainerPortOptions `json:"portsAttributes"`
+	ContainerEnv    map[string]string                  `json:"containerEnv"`
+	RemoteEnv       map[string]string                  `json:"remoteEnv"`
+
+	// Top-level keys, to report settings that are ignored
+	keys map[string]json.RawMessage
+}
+
+// DevcontainerPortOptions are the portsAttributes catnip uses
+type DevcontainerPortOptions struct {
+	Label string `json:"label"`
+}
+
+// DevcontainerFeature is a feature from devcontainer.json and whether its tool is installed
+type DevcontainerFeature struct {
+	ID   string `json:"id" example:"ghcr.io/devcontainers/features/node:1"`
+	Tool string `json:"tool,omitempty" example:"node"`
+	// available when the tool is on PATH, missing when it isn't, unsupported
+	// for features catnip doesn't know
+	Status string `json:"status" example:"available"`
+}
+
+// DevcontainerPort is a forwarded port and how it was mapped
+type DevcontainerPort struct {
+	Port  int    `json:"port"`
+	Label string `json:"label,omitempty"`
+	// Whether a host publish rule was created for it; ports already declared
+	// in .catnip.yaml keep their configured rule
+	Published bool `json:"published"`
+}
+
+// DevcontainerCommand is one lifecycle command, as run by bash
+type DevcontainerCommand struct {
+	Stage string `json:"stage" example:"postCreateCommand"`
+	// Name of the command when the stage uses the object form
+	Name    string `json:"name,omitempty"`
+	Command string `json:"command" example:"npm install"`
+}
+
+// DevcontainerImport summarizes how a worktree's devcontainer.json maps onto catnip
+type DevcontainerImport struct {
+	Path string `json:"path" example:".devcontainer/devcontainer.json"`
+	Name string `json:"name,omitempty"`
+	// False when .catnip.yaml sets devcontainer.import to false
+	Enabled   bool                  `json:"enabled"`
+	Features  []DevcontainerFeature `json:"features"`
+	Ports     []DevcontainerPort    `json:"ports"`
+	Lifecycle []DevcontainerCommand `json:"lifecycle"`
+	// What setup runs: setup.sh (which takes precedence), devcontainer, or none
+	Setup string `json:"setup" example:"devcontainer"`
+	// Settings that were skipped and why
+	Warnings []string `json:"warnings"`
+}
+
+// LoadDevcontainer finds and parses the devcontainer.json in a directory.
+// It returns nil when there is none.
+func LoadDevcontainer(dir string) (*Devcontainer, error) {
+	for _, pattern := range devcontainerPaths {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil || len(matches) == 0 {
+			continue
+		}
+		sort.Strings(matches)
+		data, err := os.ReadFile(matches[0])
+		if err != nil {
+			return nil, err
+		}
+		rel, _ := filepath.Rel(dir, matches[0])
+		dc, err := parseDevcontainer(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", rel, err)
+		}
+		dc.Path = filepath.ToSlash(rel)
+		return dc, nil
+	}
+	return nil, nil
+}
+
+func parseDevcontainer(data []byte) (*Devcontainer, error) {
+	data = stripJSONC(data)
+	dc := &Devcontainer{}
+	if err := json.Unmarshal(data, dc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &dc.keys); err != nil {
+		return nil, err
+	}
+	return dc, nil
+}
+
+// stripJSONC turns JSON with comments and trailing commas, as devcontainer.json
+// allows, into plain JSON
+func stripJSONC(data []byte) []byte {
+	return stripTrailingCommas(stripJSONComments(data))
+}
+
+func stripJSONComments(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case inString:
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case ch == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				return out
+			}
+			i += end + 3
+			out = append(out, ' ')
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// stripTrailingCommas drops commas followed only by whitespace and a closing bracket
+func stripTrailingCommas(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		switch {
+		case inString:
+			out = append(out, ch)
+			if ch == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+			out = append(out, ch)
+		case ch == ',':
+			rest := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(rest) > 0 && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+			out = append(out, ch)
+		default:
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// LifecycleCommands returns the lifecycle commands to run as setup, in order
+func (dc *Devcontainer) LifecycleCommands() ([]DevcontainerCommand, []string) {
+	var commands []DevcontainerCommand
+	var warnings []string
+	for _, stage := range devcontainerLifecycleStages {
+		raw, exists := dc.keys[stage]
+		if !exists {
+			continue
+		}
+		parsed, err := parseLifecycleCommand(stage, raw)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("%s skipped: %v", stage, err))
+			continue
+		}
+		commands = append(commands, parsed...)
+	}
+	return commands, warnings
+}
+
+// parseLifecycleCommand accepts the three forms a lifecycle command can take:
+// a shell string, an argument array, or an object of named commands. Named
+// commands run in parallel in a dev container; catnip runs them in name order.
+func parseLifecycleCommand(stage string, raw json.RawMessage) ([]DevcontainerCommand, error) {
+	var named map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &named); err == nil {
+		names := make([]string, 0, len(named))
+		for name := range named {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var commands []DevcontainerCommand
+		for _, name := range names {
+			command, err := lifecycleShellCommand(named[name])
+			if err != nil {
+				return nil, fmt.Errorf("command %q: %v", name, err)
+			}
+			if command != "" {
+				commands = append(commands, DevcontainerCommand{Stage: stage, Name: name, Command: command})
+			}
+		}
+		return commands, nil
+	}
+
+	command, err := lifecycleShellCommand(raw)
+	if err != nil || command == "" {
+		return nil, err
+	}
+	return []DevcontainerCommand{{Stage: stage, Command: command}}, nil
+}
+
+func lifecycleShellCommand(raw json.RawMessage) (string, error) {
+	var command string
+	if err := json.Unmarshal(raw, &command); err == nil {
+		return strings.TrimSpace(command), nil
+	}
+	var args []string
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return "", fmt.Errorf("expected a string, an array of strings or an object")
+	}
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(quoted, " "), nil
+}
+
+// SetupScript builds a bash script that exports the container environment and
+// runs the lifecycle commands, stopping at the first failure. It returns ""
+// when there are no commands.
+func (dc *Devcontainer) SetupScript() string {
+	commands, _ := dc.LifecycleCommands()
+	if len(commands) == 0 {
+		return ""
+	}
+
+	var script strings.Builder
+	script.WriteString("set -e\n")
+	for _, line := range dc.envExports() {
+		script.WriteString(line + "\n")
+	}
+	for _, command := range commands {
+		label := command.Stage
+		if command.Name != "" {
+			label += " (" + command.Name + ")"
+		}
+		fmt.Fprintf(&script, "echo %s\n", shellQuote("▶ "+label+": "+command.Command))
+		script.WriteString(command.Command + "\n")
+	}
+	return script.String()
+}
+
+// envExports returns export statements for containerEnv and remoteEnv.
+// Values using ${...} variables are skipped since catnip can't resolve them
+// the way the dev container CLI does.
+func (dc *Devcontainer) envExports() []string {
+	env := make(map[string]string)
+	for key, value := range dc.ContainerEnv {
+		env[key] = value
+	}
+	for key, value := range dc.RemoteEnv {
+		env[key] = value
+	}
+
+	var exports []string
+	for key, value := range env {
+		if strings.Contains(value, "${") || !isEnvName(key) {
+			continue
+		}
+		exports = append(exports, fmt.Sprintf("export %s=%s", key, shellQuote(value)))
+	}
+	sort.Strings(exports)
+	return exports
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, ch := range name {
+		if ch != '_' && !(ch >= 'a' && ch <= 'z') && !(ch >= 'A' && ch <= 'Z') && !(i > 0 && ch >= '0' && ch <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Ports returns the numeric forwarded ports with their labels. Ports given as
+// "service:port" belong to other compose services and are reported as warnings.
+func (dc *Devcontainer) Ports() ([]DevcontainerPort, []string) {
+	var ports []DevcontainerPort
+	var warnings []string
+	seen := make(map[int]bool)
+	for _, raw := range dc.ForwardPorts {
+		var port int
+		if err := json.Unmarshal(raw, &port); err != nil {
+			var value string
+			_ = json.Unmarshal(raw, &value)
+			if port, err = strconv.Atoi(value); err != nil {
+				warnings = append(warnings, fmt.Sprintf("forwardPorts entry %s skipped: only ports in this container can be forwarded", string(raw)))
+				continue
+			}
+		}
+		if port <= 0 || port > 65535 || seen[port] {
+			continue
+		}
+		seen[port] = true
+		ports = append(ports, DevcontainerPort{
+			Port:  port,
+			Label: dc.PortsAttributes[strconv.Itoa(port)].Label,
+		})
+	}
+	return ports, warnings
+}
+
+// FeatureStatus reports which features' tools are installed
+func (dc *Devcontainer) FeatureStatus() []DevcontainerFeature {
+	features := make([]DevcontainerFeature, 0, len(dc.Features))
+	for id := range dc.Features {
+		feature := DevcontainerFeature{ID: id, Status: "unsupported"}
+		if tool, known := devcontainerFeatureTools[devcontainerFeatureName(id)]; known {
+			feature.Tool = tool
+			feature.Status = "missing"
+			if _, err := exec.LookPath(tool); err == nil {
+				feature.Status = "available"
+			}
+		}
+		features = append(features, feature)
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i].ID < features[j].ID })
+	return features
+}
+
+// devcontainerFeatureName extracts the feature name from a reference like
+// ghcr.io/devcontainers/features/node:1
+func devcontainerFeatureName(id string) string {
+	name := path.Base(id)
+	if idx := strings.IndexAny(name, ":@"); idx >= 0 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// devcontainerImportEnabled reports whether .catnip.yaml allows using devcontainer.json
+func devcontainerImportEnabled(cfg *CatnipConfig) bool {
+	return cfg.Devcontainer.Import == nil || *cfg.Devcontainer.Import
+}
+
+// devcontainerSetupScript returns the setup script generated from a
+// worktree's devcontainer.json and the file's path, or "" when it has no
+// lifecycle commands or importing is disabled
+func devcontainerSetupScript(dir string) (string, string) {
+	cfg, err := LoadCatnipConfig(dir)
+	if err != nil || !devcontainerImportEnabled(cfg) {
+		return "", ""
+	}
+	dc, err := LoadDevcontainer(dir)
+	if err != nil || dc == nil {
+		return "", ""
+	}
+	return dc.SetupScript(), dc.Path
+}
+
+// devcontainerPublishRules maps a worktree's forwarded ports onto host
+// publish rules bound to localhost, skipping ports .catnip.yaml declares
+func devcontainerPublishRules(wt *models.Worktree, cfg *CatnipConfig) []PortPublishRule {
+	if !devcontainerImportEnabled(cfg) {
+		return nil
+	}
+	dc, err := LoadDevcontainer(wt.Path)
+	if err != nil || dc == nil {
+		return nil
+	}
+
+	declared := make(map[int]bool)
+	for _, entry := range cfg.Ports.Publish {
+		declared[entry.Port] = true
+	}
+	ports, _ := dc.Ports()
+	var rules []PortPublishRule
+	for _, port := range ports {
+		if declared[port.Port] {
+			continue
+		}
+		rule := PortPublishRule{
+			ID:           fmt.Sprintf("devcontainer-%s-%d", wt.ID, port.Port),
+			WorktreeID:   wt.ID,
+			WorktreeName: wt.Name,
+			Port:         port.Port,
+			Source:       PortPublishFromDevcontainer,
+			CreatedAt:    wt.CreatedAt,
+		}
+		normalizePublishRule(&rule)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ImportDevcontainer reports how the devcontainer.json in dir maps onto
+// catnip's setup and ports. It returns nil when there is none.
+func ImportDevcontainer(dir string) (*DevcontainerImport, error) {
+	cfg, err := LoadCatnipConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	dc, err := LoadDevcontainer(dir)
+	if err != nil || dc == nil {
+		return nil, err
+	}
+
+	result := &DevcontainerImport{
+		Path:      dc.Path,
+		Name:      dc.Name,
+		Enabled:   devcontainerImportEnabled(cfg),
+		Features:  dc.FeatureStatus(),
+		Lifecycle: []DevcontainerCommand{},
+		Warnings:  []string{},
+		Setup:     "none",
+	}
+
+	commands, warnings := dc.LifecycleCommands()
+	if commands != nil {
+		result.Lifecycle = commands
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+	if _, err := os.Stat(filepath.Join(dir, "setup.sh")); err == nil {
+		result.Setup = "setup.sh"
+		if len(commands) > 0 {
+			result.Warnings = append(result.Warnings, "lifecycle commands not run: setup.sh takes precedence")
+		}
+	} else if len(commands) > 0 && result.Enabled {
+		result.Setup = "devcontainer"
+	}
+
+	declared := make(map[int]bool)
+	for _, entry := range cfg.Ports.Publish {
+		declared[entry.Port] = true
+	}
+	ports, warnings := dc.Ports()
+	for i := range ports {
+		ports[i].Published = result.Enabled && !declared[ports[i].Port]
+	}
+	result.Ports = ports
+	if result.Ports == nil {
+		result.Ports = []DevcontainerPort{}
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+
+	for _, feature := range result.Features {
+		switch feature.Status {
+		case "missing":
+			result.Warnings = append(result.Warnings, fmt.Sprintf("feature %s: %s is not installed", feature.ID, feature.Tool))
+		case "unsupported":
+			result.Warnings = append(result.Warnings, fmt.Sprintf("feature %s is not supported", feature.ID))
+		}
+	}
+	for _, key := range devcontainerIgnoredKeys {
+		if _, exists := dc.keys[key]; exists {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s ignored: catnip provides the container", key))
+		}
+	}
+	for key, value := range dc.ContainerEnv {
+		if strings.Contains(value, "${") {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("containerEnv %s skipped: variables aren't substituted", key))
+		}
+	}
+	for key, value := range dc.RemoteEnv {
+		if strings.Contains(value, "${") {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("remoteEnv %s skipped: variables aren't substituted", key))
+		}
+	}
+	return result, nil
+}
+
+// WorktreeDevcontainer reports how a worktree's devcontainer.json is
+// imported, or nil when it has none
+func (s *GitService) WorktreeDevcontainer(worktreeID string) (*DevcontainerImport, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	result, err := ImportDevcontainer(worktree.Path)
+	if err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+	return result, nil
+}
+
+// isDevcontainerFile reports whether a repository path is one LoadDevcontainer reads
+func isDevcontainerFile(file string) bool {
+	for _, pattern := range devcontainerPaths {
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const testDevcontainer = `{
+	// Codespaces configuration
+	"name": "App",
+	"image": "mcr.microsoft.com/devcontainers/typescript-node",
+	"features": {
+		"ghcr.io/devcontainers/features/github-cli:1": {},
+		"ghcr.io/example/features/custom-thing:2": {},
+	},
+	"forwardPorts": [3000, "5432", "db:5432", 3000],
+	"portsAttributes": {
+		"3000": { "label": "Web // UI" }
+	},
+	/* commands */
+	"containerEnv": { "APP_ENV": "development", "TOKEN": "${localEnv:TOKEN}" },
+	"onCreateCommand": ["npm", "config", "set", "fund", "false"],
+	"postCreateCommand": {
+		"server": "npm install",
+		"client": "echo 'it''s fine'"
+	},
+	"postAttachCommand": "npm run dev",
+}`
+
+func writeDevcontainer(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, rel)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, rel), []byte(content), 0644))
+}
+
+func TestStripJSONC(t *testing.T) {
+	input := `{"a": "http://x/*y*/", /* c */ "b": [1, 2,], // tail
+"c": "\"//\"",}`
+	var parsed map[string]interface{}
+	require.NoError(t, json.Unmarshal(stripJSONC([]byte(input)), &parsed))
+	assert.Equal(t, "http://x/*y*/", parsed["a"])
+	assert.Equal(t, []interface{}{1.0, 2.0}, parsed["b"])
+	assert.Equal(t, `"//"`, parsed["c"])
+}
+
+func TestLoadDevcontainer(t *testing.T) {
+	dir := t.TempDir()
+	dc, err := LoadDevcontainer(dir)
+	require.NoError(t, err)
+	assert.Nil(t, dc)
+
+	writeDevcontainer(t, dir, ".devcontainer/devcontainer.json", testDevcontainer)
+	dc, err = LoadDevcontainer(dir)
+	require.NoError(t, err)
+	require.NotNil(t, dc)
+	assert.Equal(t, ".devcontainer/devcontainer.json", dc.Path)
+	assert.Equal(t, "App", dc.Name)
+
+	commands, warnings := dc.LifecycleCommands()
+	assert.Empty(t, warnings)
+	assert.Equal(t, []DevcontainerCommand{
+		{Stage: "onCreateCommand", Command: "'npm' 'config' 'set' 'fund' 'false'"},
+		{Stage: "postCreateCommand", Name: "client", Command: "echo 'it''s fine'"},
+		{Stage: "postCreateCommand", Name: "server", Command: "npm install"},
+	}, commands)
+
+	ports, warnings := dc.Ports()
+	assert.Equal(t, []DevcontainerPort{{Port: 3000, Label: "Web // UI"}, {Port: 5432}}, ports)
+	assert.Len(t, warnings, 1, "ports on other compose services are skipped")
+
+	writeDevcontainer(t, dir, ".devcontainer/devcontainer.json", `{"name": `)
+	_, err = LoadDevcontainer(dir)
+	assert.Error(t, err)
+}
+
+func TestDevcontainerSetupScript(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	writeDevcontainer(t, dir, ".devcontainer.json", `{
+		"remoteEnv": {"GREETING": "hello world"},
+		"onCreateCommand": "echo \"$GREETING\" > out.txt",
+		"postStartCommand": ["sh", "-c", "echo started >> out.txt"]
+	}`)
+
+	command, ok := setupCommand(dir)
+	require.True(t, ok)
+	cmd := exec.Command("bash", "-c", command)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+	assert.Contains(t, string(output), "lifecycle commands from .devcontainer.json")
+
+	data, err := os.ReadFile(filepath.Join(dir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello world\nstarted\n", string(data))
+
+	// A failing command stops setup
+	writeDevcontainer(t, dir, ".devcontainer.json", `{"onCreateCommand": "false", "postCreateCommand": "touch ran"}`)
+	command, _ = setupCommand(dir)
+	cmd = exec.Command("bash", "-c", command)
+	cmd.Dir = dir
+	assert.Error(t, cmd.Run())
+	assert.NoFileExists(t, filepath.Join(dir, "ran"))
+
+	// setup.sh takes precedence
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "setup.sh"), []byte("#!/bin/bash\n"), 0755))
+	command, _ = setupCommand(dir)
+	assert.Contains(t, command, "./setup.sh")
+}
+
+func TestImportDevcontainer(t *testing.T) {
+	dir := t.TempDir()
+	writeDevcontainer(t, dir, ".devcontainer/devcontainer.json", testDevcontainer)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte(`ports:
+  publish:
+    - port: 5432
+      host_port: 15432
+`), 0644))
+
+	result, err := ImportDevcontainer(dir)
+	require.NoError(t, err)
+	assert.True(t, result.Enabled)
+	assert.Equal(t, "devcontainer", result.Setup)
+	assert.Equal(t, []DevcontainerPort{
+		{Port: 3000, Label: "Web // UI", Published: true},
+		{Port: 5432, Published: false},
+	}, result.Ports)
+	require.Len(t, result.Features, 2)
+	assert.Equal(t, "unsupported", result.Features[1].Status)
+	assert.Contains(t, result.Warnings, "image ignored: catnip provides the container")
+	assert.Contains(t, result.Warnings, "containerEnv TOKEN skipped: variables aren't substituted")
+
+	// Forwarded ports become host publish rules after the ones in .catnip.yaml
+	worktrees := []*models.Worktree{{ID: "wt-app", Name: "app/main", Path: dir, CreatedAt: time.Now()}}
+	service := NewPortPublishServiceWithPath(filepath.Join(t.TempDir(), "port_publish.json"), func() []*models.Worktree { return worktrees })
+	rules := service.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, PortPublishFromConfig, rules[0].Source)
+	assert.Equal(t, PortPublishFromDevcontainer, rules[1].Source)
+	assert.Equal(t, 3000, rules[1].Port)
+	assert.Equal(t, "127.0.0.1", rules[1].HostAddress)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte("devcontainer:\n  import: false\n"), 0644))
+	result, err = ImportDevcontainer(dir)
+	require.NoError(t, err)
+	assert.False(t, result.Enabled)
+	assert.Equal(t, "none", result.Setup)
+	assert.Empty(t, service.Rules())
+	_, ok := setupCommand(dir)
+	assert.False(t, ok)
+}
+
+func TestMatchesSetupWatchDevcontainer(t *testing.T) {
+	assert.True(t, matchesSetupWatch(".devcontainer/devcontainer.json", nil))
+	assert.True(t, matchesSetupWatch(".devcontainer/python/devcontainer.json", nil))
+	assert.True(t, matchesSetupWatch(".devcontainer.json", nil))
+	assert.False(t, matchesSetupWatch("docs/devcontainer.json", nil))
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Diagnostic checks, in the order they run and are fixed. Missing worktrees
+// are recreated before orphaned registrations are pruned, since recreating
+// uses the registration's metadata.
+const (
+	DiagnosticGitdirLinks        = "gitdir_links"
+	DiagnosticMissingWorktrees   = "missing_worktrees"
+	DiagnosticOrphanedWorktrees  = "orphaned_worktrees"
+	DiagnosticStalePorts         = "stale_ports"
+	DiagnosticCircuitBreakers    = "circuit_breakers"
+	diagnosticGitdirFilePrefix   = "gitdir: "
+	diagnosticWorktreesDirectory = "worktrees"
+)
+
+// DiagnosticChecks lists every check
+var DiagnosticChecks = []string{
+	DiagnosticGitdirLinks,
+	DiagnosticMissingWorktrees,
+	DiagnosticOrphanedWorktrees,
+	DiagnosticStalePorts,
+	DiagnosticCircuitBreakers,
+}
+
+// DiagnosticIssue is a problem found by a check
+type DiagnosticIssue struct {
+	Check string `json:"check" example:"gitdir_links"`
+	// Worktree, repository or session the issue is about
+	Target  string `json:"target" example:"catnip/felix"`
+	Message string `json:"message" example:".git points to a missing directory"`
+	// What fixing does, empty if the issue needs a person
+	Fix string `json:"fix,omitempty" example:"run git worktree repair"`
+
+	apply func() error
+}
+
+// DiagnosticsReport is the result of running the checks
+type DiagnosticsReport struct {
+	CheckedAt time.Time         `json:"checked_at"`
+	Checks    []string          `json:"checks"`
+	Issues    []DiagnosticIssue `json:"issues"`
+}
+
+// DiagnosticAction is a fix attempted (or, in a dry run, planned) for an issue
+type DiagnosticAction struct {
+	DiagnosticIssue
+	Applied bool   `json:"applied"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DiagnosticsFixResult reports the fixes made and what is left
+type DiagnosticsFixResult struct {
+	DryRun  bool               `json:"dry_run"`
+	Actions []DiagnosticAction `json:"actions"`
+	// Issues found after fixing (before, in a dry run)
+	Remaining *DiagnosticsReport `json:"remaining"`
+}
+
+// PTYCircuitBreaker is a workspace whose terminal recreation is backing off
+// after repeated failures
+type PTYCircuitBreaker struct {
+	Workspace    string    `json:"workspace"`
+	FailureCount int       `json:"failure_count"`
+	BackoffUntil time.Time `json:"backoff_until"`
+}
+
+// DiagnosticsSources supplies the state checks inspect and the fixes they
+// apply. Nil sources skip their checks.
+type DiagnosticsSources struct {
+	Git *GitService
+	// Sessions holding port allocations without a live terminal
+	StalePortAllocations func() []string
+	ReleasePorts         func(sessionID string) error
+	// Open terminal circuit breakers
+	CircuitBreakers     func() []PTYCircuitBreaker
+	ResetCircuitBreaker func(workspace string)
+}
+
+// DiagnosticsService checks catnip's state against git and the filesystem
+// and repairs what it safely can
+type DiagnosticsService struct {
+	sources DiagnosticsSources
+	mu      sync.Mutex // Serializes fixing
+	now     func() time.Time
+}
+
+// NewDiagnosticsService creates a diagnostics service over sources
+func NewDiagnosticsService(sources DiagnosticsSources) *DiagnosticsService {
+	return &DiagnosticsService{sources: sources, now: time.Now}
+}
+
+// Run runs the named checks, or every check when none are named
+func (d *DiagnosticsService) Run(checks []string) (*DiagnosticsReport, error) {
+	if len(checks) == 0 {
+		checks = DiagnosticChecks
+	}
+	for _, check := range checks {
+		if !slices.Contains(DiagnosticChecks, check) {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown check %q (checks: %s)", check, strings.Join(DiagnosticChecks, ", "))
+		}
+	}
+
+	report := &DiagnosticsReport{CheckedAt: d.now(), Checks: []string{}, Issues: []DiagnosticIssue{}}
+	for _, check := range DiagnosticChecks {
+		if !slices.Contains(checks, check) {
+			continue
+		}
+		issues := d.runCheck(check)
+		slices.SortStableFunc(issues, func(a, b DiagnosticIssue) int { return strings.Compare(a.Target, b.Target) })
+		report.Checks = append(report.Checks, check)
+		report.Issues = append(report.Issues, issues...)
+	}
+	return report, nil
+}
+
+// Fix runs the named checks and applies every available fix in check order.
+// A dry run only reports the fixes it would apply.
+func (d *DiagnosticsService) Fix(checks []string, dryRun bool) (*DiagnosticsFixResult, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	report, err := d.Run(checks)
+	if err != nil {
+		return nil, err
+	}
+	result := &DiagnosticsFixResult{DryRun: dryRun, Actions: []DiagnosticAction{}, Remaining: report}
+	for _, issue := range report.Issues {
+		if issue.apply == nil {
+			continue
+		}
+		action := DiagnosticAction{DiagnosticIssue: issue}
+		if !dryRun {
+			if err := issue.apply(); err != nil {
+				action.Error = err.Error()
+				logger.Warnf("🩺 Failed to fix %s (%s): %v", issue.Target, issue.Check, err)
+			} else {
+				action.Applied = true
+				logger.Infof("🩺 Fixed %s (%s): %s", issue.Target, issue.Check, issue.Fix)
+			}
+		}
+		result.Actions = append(result.Actions, action)
+	}
+
+	if !dryRun {
+		result.Remaining, _ = d.Run(report.Checks)
+	}
+	return result, nil
+}
+
+func (d *DiagnosticsService) runCheck(check string) []DiagnosticIssue {
+	switch check {
+	case DiagnosticGitdirLinks:
+		if d.sources.Git != nil {
+			return d.sources.Git.checkGitdirLinks()
+		}
+	case DiagnosticMissingWorktrees:
+		if d.sources.Git != nil {
+			return d.sources.Git.checkMissingWorktrees()
+		}
+	case DiagnosticOrphanedWorktrees:
+		if d.sources.Git != nil {
+			return d.sources.Git.checkOrphanedWorktrees()
+		}
+	case DiagnosticStalePorts:
+		return d.checkStalePorts()
+	case DiagnosticCircuitBreakers:
+		return d.checkCircuitBreakers()
+	}
+	return nil
+}
+
+func (d *DiagnosticsService) checkStalePorts() []DiagnosticIssue {
+	if d.sources.StalePortAllocations == nil || d.sources.ReleasePorts == nil {
+		return nil
+	}
+	var issues []DiagnosticIssue
+	for _, sessionID := range d.sources.StalePortAllocations() {
+		issues = append(issues, DiagnosticIssue{
+			Check:   DiagnosticStalePorts,
+			Target:  sessionID,
+			Message: "ports are allocated to a terminal session that no longer exists",
+			Fix:     "release the ports",
+			apply:   func() error { return d.sources.ReleasePorts(sessionID) },
+		})
+	}
+	return issues
+}
+
+func (d *DiagnosticsService) checkCircuitBreakers() []DiagnosticIssue {
+	if d.sources.CircuitBreakers == nil || d.sources.ResetCircuitBreaker == nil {
+		return nil
+	}
+	var issues []DiagnosticIssue
+	for _, breaker := range d.sources.CircuitBreakers() {
+		issues = append(issues, DiagnosticIssue{
+			Check:  DiagnosticCircuitBreakers,
+			Target: breaker.Workspace,
+			Message: fmt.Sprintf("terminal recreation failed %d times and is paused until %s",
+				breaker.FailureCount, breaker.BackoffUntil.Format(time.RFC3339)),
+			Fix: "reset the circuit breaker",
+			apply: func() error {
+				d.sources.ResetCircuitBreaker(breaker.Workspace)
+				return nil
+			},
+		})
+	}
+	return issues
+}
+
+// diagnosableWorktrees returns the worktrees with files on disk expected,
+// with their repositories: hibernated worktrees, main checkouts and
+// worktrees of unavailable repositories are left out
+func (s *GitService) diagnosableWorktrees() map[*models.Worktree]*models.Repository {
+	worktrees := make(map[*models.Worktree]*models.Repository)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+		if !exists || worktree.Hibernation != nil || worktree.Path == repo.Path {
+			continue
+		}
+		if _, err := os.Stat(repo.Path); err != nil {
+			continue
+		}
+		worktrees[worktree] = repo
+	}
+	return worktrees
+}
+
+// checkGitdirLinks finds worktrees whose .git file doesn't lead to their
+// metadata in the repository, or whose metadata doesn't lead back
+func (s *GitService) checkGitdirLinks() []DiagnosticIssue {
+	var issues []DiagnosticIssue
+	for worktree, repo := range s.diagnosableWorktrees() {
+		gitFile := filepath.Join(worktree.Path, ".git")
+		data, err := os.ReadFile(gitFile)
+		if err != nil {
+			// Missing worktrees are their own check; directories are full clones
+			continue
+		}
+		target := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), diagnosticGitdirFilePrefix))
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(worktree.Path, target)
+		}
+
+		problem := ""
+		if _, err := os.Stat(target); err != nil {
+			problem = fmt.Sprintf(".git points to missing metadata %s", target)
+		} else if back, err := os.ReadFile(filepath.Join(target, "gitdir")); err != nil || filepath.Clean(strings.TrimSpace(string(back))) != gitFile {
+			problem = fmt.Sprintf("metadata %s doesn't point back to the worktree", target)
+		}
+		if problem == "" {
+			continue
+		}
+
+		issue := DiagnosticIssue{Check: DiagnosticGitdirLinks, Target: worktree.Name, Message: problem}
+		metadata := s.findWorktreeMetadata(repo.Path, worktree.Path)
+		if metadata == "" {
+			issue.Message += "; no metadata for it was found in " + repo.Path
+		} else {
+			issue.Fix = fmt.Sprintf("point .git at %s and run git worktree repair", metadata)
+			worktreePath := worktree.Path
+			repoPath := repo.Path
+			issue.apply = func() error {
+				if err := os.WriteFile(gitFile, []byte(diagnosticGitdirFilePrefix+metadata+"\n"), 0644); err != nil {
+					return err
+				}
+				if output, err := s.operations.ExecuteGit(repoPath, "worktree", "repair", worktreePath); err != nil {
+					return fmt.Errorf("git worktree repair failed: %v\n%s", err, strings.TrimSpace(string(output)))
+				}
+				return nil
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues
+}
+
+// findWorktreeMetadata finds the metadata directory in a repository that
+// belongs to a worktree path: the one whose gitdir names it, or else the one
+// named after it, as restoring worktrees assumes
+func (s *GitService) findWorktreeMetadata(repoPath, worktreePath string) string {
+	output, err := s.operations.ExecuteGit(repoPath, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return ""
+	}
+	commonDir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(commonDir) {
+		commonDir = filepath.Join(repoPath, commonDir)
+	}
+	metadataDir := filepath.Join(commonDir, diagnosticWorktreesDirectory)
+
+	entries, err := os.ReadDir(metadataDir)
+	if err != nil {
+		return ""
+	}
+	gitFile := filepath.Join(worktreePath, ".git")
+	for _, entry := range entries {
+		candidate := filepath.Join(metadataDir, entry.Name())
+		if gitdir, err := os.ReadFile(filepath.Join(candidate, "gitdir")); err == nil && filepath.Clean(strings.TrimSpace(string(gitdir))) == gitFile {
+			return candidate
+		}
+	}
+	if candidate := filepath.Join(metadataDir, filepath.Base(worktreePath)); slices.ContainsFunc(entries, func(entry os.DirEntry) bool {
+		return entry.Name() == filepath.Base(worktreePath)
+	}) {
+		return candidate
+	}
+	return ""
+}
+
+// checkMissingWorktrees finds worktrees in state whose directory is gone
+func (s *GitService) checkMissingWorktrees() []DiagnosticIssue {
+	var issues []DiagnosticIssue
+	for worktree, repo := range s.diagnosableWorktrees() {
+		if _, err := os.Stat(worktree.Path); !os.IsNotExist(err) {
+			continue
+		}
+		issues = append(issues, DiagnosticIssue{
+			Check:   DiagnosticMissingWorktrees,
+			Target:  worktree.Name,
+			Message: fmt.Sprintf("%s is in catnip's state but missing on disk", worktree.Path),
+			Fix:     "recreate the worktree from its branch",
+			apply:   func() error { return s.RecreateWorktree(worktree, repo) },
+		})
+	}
+	return issues
+}
+
+// checkOrphanedWorktrees finds worktrees git still has registered whose
+// directory is gone and which catnip doesn't track
+func (s *GitService) checkOrphanedWorktrees() []DiagnosticIssue {
+	tracked := make(map[string]bool)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		tracked[worktree.Path] = true
+	}
+
+	var issues []DiagnosticIssue
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if _, err := os.Stat(repo.Path); err != nil {
+			continue
+		}
+		registered, err := s.operations.ListWorktrees(repo.Path)
+		if err != nil {
+			continue
+		}
+		var orphans []string
+		for _, info := range registered {
+			if info.Bare || info.Path == repo.Path || tracked[info.Path] {
+				continue
+			}
+			if _, err := os.Stat(info.Path); os.IsNotExist(err) {
+				orphans = append(orphans, info.Path)
+			}
+		}
+		if len(orphans) == 0 {
+			continue
+		}
+		repoPath := repo.Path
+		issues = append(issues, DiagnosticIssue{
+			Check:   DiagnosticOrphanedWorktrees,
+			Target:  repo.ID,
+			Message: fmt.Sprintf("git has worktrees registered that no longer exist: %s", strings.Join(orphans, ", ")),
+			Fix:     "run git worktree prune",
+			apply: func() error {
+				if output, err := s.operations.ExecuteGit(repoPath, "worktree", "prune"); err != nil {
+					return fmt.Errorf("git worktree prune failed: %v\n%s", err, strings.TrimSpace(string(output)))
+				}
+				return nil
+			},
+		})
+	}
+	return issues
+}
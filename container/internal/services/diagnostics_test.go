@@ -0,0 +1,89 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDiagnosticsFindsAndFixesIssues(t *testing.T) {
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	repoPath := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	runTestGit(t, repoPath, "init", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v1\n"), 0644))
+	runTestGit(t, repoPath, "add", ".")
+	runTestGit(t, repoPath, "commit", "-m", "base")
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: repoPath, DefaultBranch: "main"}))
+
+	// A worktree moved to another volume behind git's back, and one deleted
+	// without git or catnip knowing
+	oldPath := filepath.Join(t.TempDir(), "felix")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "catnip/felix", oldPath, "main")
+	worktreePath := filepath.Join(t.TempDir(), "felix")
+	require.NoError(t, os.Rename(oldPath, worktreePath))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "acme/app", Name: "app/felix", Path: worktreePath, Branch: "catnip/felix", SourceBranch: "main",
+	}))
+	orphanPath := filepath.Join(t.TempDir(), "luna")
+	runTestGit(t, repoPath, "worktree", "add", "-b", "catnip/luna", orphanPath, "main")
+	require.NoError(t, os.RemoveAll(orphanPath))
+
+	ports := []string{"app/luna:claude"}
+	breakers := []PTYCircuitBreaker{{Workspace: "app/luna", FailureCount: 5, BackoffUntil: time.Now().Add(time.Minute)}}
+	diagnostics := NewDiagnosticsService(DiagnosticsSources{
+		Git:                  service,
+		StalePortAllocations: func() []string { return ports },
+		ReleasePorts:         func(string) error { ports = nil; return nil },
+		CircuitBreakers:      func() []PTYCircuitBreaker { return breakers },
+		ResetCircuitBreaker:  func(string) { breakers = nil },
+	})
+
+	report, err := diagnostics.Run(nil)
+	require.NoError(t, err)
+	assert.Equal(t, DiagnosticChecks, report.Checks)
+	var found []string
+	for _, issue := range report.Issues {
+		found = append(found, issue.Check+" "+issue.Target)
+		assert.NotEmpty(t, issue.Fix, issue.Message)
+	}
+	assert.Equal(t, []string{
+		"gitdir_links app/felix",
+		"orphaned_worktrees acme/app",
+		"stale_ports app/luna:claude",
+		"circuit_breakers app/luna",
+	}, found)
+
+	_, err = diagnostics.Run([]string{"everything"})
+	assert.Error(t, err)
+
+	// A dry run changes nothing
+	result, err := diagnostics.Fix(nil, true)
+	require.NoError(t, err)
+	assert.Len(t, result.Actions, 4)
+	for _, action := range result.Actions {
+		assert.False(t, action.Applied)
+	}
+	assert.Len(t, result.Remaining.Issues, 4)
+	assert.Contains(t, runTestGit(t, repoPath, "worktree", "list"), orphanPath)
+
+	result, err = diagnostics.Fix(nil, false)
+	require.NoError(t, err)
+	for _, action := range result.Actions {
+		assert.True(t, action.Applied, action.Error)
+	}
+	assert.Empty(t, result.Remaining.Issues)
+	assert.Equal(t, "catnip/felix", runTestGit(t, worktreePath, "rev-parse", "--abbrev-ref", "HEAD"))
+	worktrees := runTestGit(t, repoPath, "worktree", "list")
+	assert.Contains(t, worktrees, worktreePath)
+	assert.False(t, strings.Contains(worktrees, orphanPath), "orphan pruned")
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// DiffIgnoreFileName lists files to leave out of worktree diffs, with
+// .gitignore syntax. Its rules apply after the defaults, so a "!" rule can
+// bring a lockfile back.
+const DiffIgnoreFileName = ".catnip-diffignore"
+
+// defaultDiffIgnorePatterns are lockfiles, which change wholesale with any
+// dependency update and are never reviewed line by line
+var defaultDiffIgnorePatterns = []string{
+	"package-lock.json",
+	"npm-shrinkwrap.json",
+	"pnpm-lock.yaml",
+	"yarn.lock",
+	"bun.lockb",
+	"bun.lock",
+	"Cargo.lock",
+	"go.sum",
+	"poetry.lock",
+	"uv.lock",
+	"Pipfile.lock",
+	"Gemfile.lock",
+	"composer.lock",
+}
+
+// diffIgnoreRule is one .gitignore-style pattern
+type diffIgnoreRule struct {
+	source   string // Where the rule came from, for reporting
+	pattern  string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool // Matched from the root rather than against any path component
+}
+
+func parseDiffIgnoreRule(source, line string) (diffIgnoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return diffIgnoreRule{}, false
+	}
+	rule := diffIgnoreRule{source: source, pattern: line}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return diffIgnoreRule{}, false
+	}
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// matches reports whether the rule matches a file or one of its directories
+func (r diffIgnoreRule) matches(file string) bool {
+	parts := strings.Split(file, "/")
+	for n := 1; n <= len(parts); n++ {
+		isDir := n < len(parts)
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.anchored {
+			if matchPathSegments(r.segments, parts[:n]) {
+				return true
+			}
+		} else if ok, _ := path.Match(r.segments[0], parts[n-1]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPathSegments matches glob segments against path segments, with "**"
+// matching any number of directories
+func matchPathSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchPathSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+	return matchPathSegments(pattern[1:], parts[1:])
+}
+
+// DiffIgnoreRules decides which changed files are left out of a diff
+type DiffIgnoreRules struct {
+	rules []diffIgnoreRule
+}
+
+// LoadDiffIgnoreRules returns the default rules followed by the rules in the
+// directory's .catnip-diffignore, if it has one
+func LoadDiffIgnoreRules(dir string) *DiffIgnoreRules {
+	rules := &DiffIgnoreRules{}
+	for _, pattern := range defaultDiffIgnorePatterns {
+		if rule, ok := parseDiffIgnoreRule("default", pattern); ok {
+			rules.rules = append(rules.rules, rule)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, DiffIgnoreFileName))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to read %s: %v", DiffIgnoreFileName, err)
+		}
+		return rules
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if rule, ok := parseDiffIgnoreRule(DiffIgnoreFileName, scanner.Text()); ok {
+			rules.rules = append(rules.rules, rule)
+		}
+	}
+	return rules
+}
+
+// Match returns the rule that ignores a file, like ".catnip-diffignore: dist/".
+// As in .gitignore, the last matching rule wins.
+func (r *DiffIgnoreRules) Match(file string) (string, bool) {
+	reason, ignored := "", false
+	for _, rule := range r.rules {
+		if rule.matches(file) {
+			ignored = !rule.negate
+			reason = rule.source + ": " + rule.pattern
+		}
+	}
+	if !ignored {
+		return "", false
+	}
+	return reason, true
+}
+
+// linguistGeneratedFiles returns which of the files .gitattributes marks as
+// linguist-generated
+func (s *GitService) linguistGeneratedFiles(worktreePath string, files []string) map[string]bool {
+	generated := make(map[string]bool)
+	if len(files) == 0 {
+		return generated
+	}
+	args := append([]string{"check-attr", "-z", "linguist-generated", "--"}, files...)
+	output, err := s.operations.ExecuteGit(worktreePath, args...)
+	if err != nil {
+		logger.Debugf("⚠️ Failed to check linguist-generated attributes: %v", err)
+		return generated
+	}
+	// -z output is path, attribute, value triples
+	fields := strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		if value := fields[i+2]; value == "set" || value == "true" {
+			generated[fields[i]] = true
+		}
+	}
+	return generated
+}
+
+// applyDiffIgnore moves files matched by the worktree's diff ignore rules or
+// marked linguist-generated into IgnoredFiles, dropping their contents. With
+// includeIgnored the files stay in the diff and are only labeled.
+func (s *GitService) applyDiffIgnore(worktree *models.Worktree, diff *git.WorktreeDiffResponse, includeIgnored bool) {
+	rules := LoadDiffIgnoreRules(worktree.Path)
+	files := make([]string, len(diff.FileDiffs))
+	for i, fileDiff := range diff.FileDiffs {
+		files[i] = fileDiff.FilePath
+	}
+	generated := s.linguistGeneratedFiles(worktree.Path, files)
+
+	var kept []git.FileDiff
+	for _, fileDiff := range diff.FileDiffs {
+		reason, ignored := rules.Match(fileDiff.FilePath)
+		if !ignored && generated[fileDiff.FilePath] {
+			reason, ignored = ".gitattributes: linguist-generated", true
+		}
+		if !ignored {
+			kept = append(kept, fileDiff)
+			continue
+		}
+
+		fileDiff.IgnoredBy = reason
+		if includeIgnored {
+			kept = append(kept, fileDiff)
+			continue
+		}
+		fileDiff.OldContent = ""
+		fileDiff.NewContent = ""
+		fileDiff.DiffText = ""
+		diff.IgnoredFiles = append(diff.IgnoredFiles, fileDiff)
+	}
+	if kept == nil {
+		kept = []git.FileDiff{}
+	}
+	diff.FileDiffs = kept
+	diff.Summarize()
+}
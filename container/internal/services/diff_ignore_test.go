@@ -0,0 +1,79 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestDiffIgnoreRules(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DiffIgnoreFileName), []byte(`# generated
+dist/
+/src/gen/**/*.pb.go
+*.snap
+!keep.snap
+!go.sum
+`), 0644))
+	rules := LoadDiffIgnoreRules(dir)
+
+	cases := map[string]string{
+		"package-lock.json":             "default: package-lock.json",
+		"web/pnpm-lock.yaml":            "default: pnpm-lock.yaml",
+		"dist/app.js":                   ".catnip-diffignore: dist/",
+		"packages/ui/dist/index.js":     ".catnip-diffignore: dist/",
+		"src/gen/api/v1/service.pb.go":  ".catnip-diffignore: /src/gen/**/*.pb.go",
+		"src/gen/service.pb.go":         ".catnip-diffignore: /src/gen/**/*.pb.go",
+		"tests/__snapshots__/home.snap": ".catnip-diffignore: *.snap",
+	}
+	for file, want := range cases {
+		reason, ignored := rules.Match(file)
+		assert.True(t, ignored, file)
+		assert.Equal(t, want, reason, file)
+	}
+
+	for _, file := range []string{"main.go", "go.sum", "keep.snap", "lib/src/gen/x.pb.go", "distribution.md", "dist"} {
+		_, ignored := rules.Match(file)
+		assert.False(t, ignored, file)
+	}
+}
+
+func TestApplyDiffIgnore(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitattributes"), []byte("api/client.ts linguist-generated\n"), 0644))
+
+	worktree := &models.Worktree{ID: "wt-ignore", Path: dir}
+	newDiff := func() *git.WorktreeDiffResponse {
+		return &git.WorktreeDiffResponse{FileDiffs: []git.FileDiff{
+			{FilePath: "main.go", ChangeType: "modified", DiffText: "+code"},
+			{FilePath: "yarn.lock", ChangeType: "modified", DiffText: "+lock"},
+			{FilePath: "api/client.ts", ChangeType: "modified", DiffText: "+generated"},
+		}}
+	}
+
+	diff := newDiff()
+	service.applyDiffIgnore(worktree, diff, false)
+	require.Len(t, diff.FileDiffs, 1)
+	assert.Equal(t, "main.go", diff.FileDiffs[0].FilePath)
+	require.Len(t, diff.IgnoredFiles, 2)
+	assert.Equal(t, "default: yarn.lock", diff.IgnoredFiles[0].IgnoredBy)
+	assert.Equal(t, ".gitattributes: linguist-generated", diff.IgnoredFiles[1].IgnoredBy)
+	assert.Empty(t, diff.IgnoredFiles[0].DiffText, "ignored contents are dropped")
+	assert.Equal(t, 1, diff.TotalFiles)
+	assert.Equal(t, "1 file changed, 2 ignored", diff.Summary)
+
+	// The override keeps everything in the diff, labeled
+	diff = newDiff()
+	service.applyDiffIgnore(worktree, diff, true)
+	require.Len(t, diff.FileDiffs, 3)
+	assert.Empty(t, diff.IgnoredFiles)
+	assert.Equal(t, "default: yarn.lock", diff.FileDiffs[1].IgnoredBy)
+	assert.Equal(t, "+lock", diff.FileDiffs[1].DiffText)
+}
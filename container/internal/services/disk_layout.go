@@ -0,0 +1,321 @@
+package services
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Scans are reused for this long unless a refresh is requested
+	diskLayoutCacheTTL = 10 * time.Minute
+	// Entries returned in each "largest" list
+	diskLayoutTopN = 15
+	// Directories deeper than this aren't listed on their own (they still count)
+	diskLayoutMaxDirDepth = 3
+	// Files at least this large get a review suggestion
+	diskLayoutLargeFile = 100 << 20
+)
+
+// Build artifact directories, by name. Names that are common words (build,
+// target) need a marker file next to them to count.
+var diskArtifactDirs = map[string]struct {
+	kind    string
+	markers []string
+	rebuild string
+}{
+	"node_modules":  {kind: "node_modules", rebuild: "reinstall with your package manager (npm/pnpm/yarn install)"},
+	".next":         {kind: "next", rebuild: "rebuilt by next build / next dev"},
+	".turbo":        {kind: "turbo", rebuild: "rebuilt by turbo"},
+	".parcel-cache": {kind: "parcel", rebuild: "rebuilt by parcel"},
+	"__pycache__":   {kind: "pycache", rebuild: "recreated by Python"},
+	".pytest_cache": {kind: "pytest", rebuild: "recreated by pytest"},
+	".mypy_cache":   {kind: "mypy", rebuild: "recreated by mypy"},
+	".venv":         {kind: "venv", rebuild: "recreate the virtualenv (uv sync / pip install)"},
+	"venv":          {kind: "venv", markers: []string{"pyvenv.cfg"}, rebuild: "recreate the virtualenv (uv sync / pip install)"},
+	"target":        {kind: "target", markers: []string{"../Cargo.toml", "../pom.xml"}, rebuild: "rebuilt by cargo / maven"},
+	"dist":          {kind: "dist", markers: []string{"../package.json", "../pyproject.toml", "../setup.py"}, rebuild: "rebuilt by the project's build"},
+	"build":         {kind: "build", markers: []string{"../package.json", "../build.gradle", "../CMakeLists.txt", "../setup.py"}, rebuild: "rebuilt by the project's build"},
+	".gradle":       {kind: "gradle", rebuild: "recreated by gradle"},
+}
+
+// DiskEntry is a file or directory and its size
+type DiskEntry struct {
+	// Path relative to the worktree root
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+	// Files below a directory (1 for files)
+	Files int `json:"files"`
+}
+
+// DiskArtifact is a build artifact directory that can be regenerated
+type DiskArtifact struct {
+	DiskEntry
+	// node_modules, target, dist, ...
+	Kind string `json:"kind"`
+}
+
+// DiskCleanupSuggestion is an action that would free space in a worktree
+type DiskCleanupSuggestion struct {
+	// remove_artifact or review_large_file
+	Action      string `json:"action"`
+	Path        string `json:"path"`
+	Bytes       int64  `json:"bytes"`
+	Description string `json:"description"`
+	// Shell command to run in the worktree, for safe actions
+	Command string `json:"command,omitempty"`
+}
+
+// DiskLayout describes where a worktree's disk space goes
+type DiskLayout struct {
+	Path        string                  `json:"path"`
+	TotalBytes  int64                   `json:"total_bytes"`
+	TotalFiles  int                     `json:"total_files"`
+	Directories []DiskEntry             `json:"directories"`
+	Files       []DiskEntry             `json:"files"`
+	Artifacts   []DiskArtifact          `json:"artifacts"`
+	Suggestions []DiskCleanupSuggestion `json:"suggestions"`
+	// Space freed by removing every artifact directory
+	ReclaimableBytes int64     `json:"reclaimable_bytes"`
+	ScannedAt        time.Time `json:"scanned_at"`
+	// How long the scan took
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// DiskLayoutScanner measures worktrees like du, caching results so repeated
+// requests (and reclaim decisions) don't rescan large trees
+type DiskLayoutScanner struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	cache    map[string]*DiskLayout
+	inflight map[string]chan struct{}
+}
+
+// NewDiskLayoutScanner creates a scanner with the default cache lifetime
+func NewDiskLayoutScanner() *DiskLayoutScanner {
+	return &DiskLayoutScanner{
+		ttl:      diskLayoutCacheTTL,
+		now:      time.Now,
+		cache:    make(map[string]*DiskLayout),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// Layout returns the layout of the directory at path, scanning it if there's
+// no fresh cached result or refresh is set. Concurrent requests for the same
+// path share one scan.
+func (s *DiskLayoutScanner) Layout(path string, refresh bool) (*DiskLayout, error) {
+	for {
+		s.mu.Lock()
+		if cached, ok := s.cache[path]; ok && !refresh && s.now().Sub(cached.ScannedAt) < s.ttl {
+			s.mu.Unlock()
+			return cached, nil
+		}
+		if wait, ok := s.inflight[path]; ok {
+			s.mu.Unlock()
+			<-wait
+			// The scan that just finished is as fresh as a refresh
+			refresh = false
+			continue
+		}
+		done := make(chan struct{})
+		s.inflight[path] = done
+		s.mu.Unlock()
+
+		layout, err := scanDiskLayout(path, s.now)
+
+		s.mu.Lock()
+		delete(s.inflight, path)
+		if err == nil {
+			s.cache[path] = layout
+		}
+		s.mu.Unlock()
+		close(done)
+		return layout, err
+	}
+}
+
+// Reclaimable returns the reclaimable bytes from the last scan of path, for
+// callers deciding what to clean up without triggering a scan
+func (s *DiskLayoutScanner) Reclaimable(path string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cached, ok := s.cache[path]
+	if !ok {
+		return 0, false
+	}
+	return cached.ReclaimableBytes, true
+}
+
+// Forget drops the cached scan of path (after cleanup or deletion)
+func (s *DiskLayoutScanner) Forget(path string) {
+	s.mu.Lock()
+	delete(s.cache, path)
+	s.mu.Unlock()
+}
+
+func scanDiskLayout(root string, now func() time.Time) (*DiskLayout, error) {
+	started := now()
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	dirs := make(map[string]*DiskEntry)
+	artifactKinds := make(map[string]string)
+	var files []DiskEntry
+	var total DiskEntry
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped rather than failing the scan
+			if d != nil && d.IsDir() && path != root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			dirs[rel] = &DiskEntry{Path: rel}
+			if artifactParent(rel, artifactKinds) == "" {
+				if kind := artifactKind(path, d.Name()); kind != "" {
+					artifactKinds[rel] = kind
+				}
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		size := info.Size()
+		total.Bytes += size
+		total.Files++
+		if artifactParent(rel, artifactKinds) == "" {
+			files = append(files, DiskEntry{Path: rel, Bytes: size, Files: 1})
+		}
+		// Add the file to every enclosing directory
+		for dir := filepath.ToSlash(filepath.Dir(rel)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			if entry, ok := dirs[dir]; ok {
+				entry.Bytes += size
+				entry.Files++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	layout := &DiskLayout{
+		Path:        root,
+		TotalBytes:  total.Bytes,
+		TotalFiles:  total.Files,
+		Directories: []DiskEntry{},
+		Files:       []DiskEntry{},
+		Artifacts:   []DiskArtifact{},
+		Suggestions: []DiskCleanupSuggestion{},
+	}
+
+	for rel, entry := range dirs {
+		if kind, ok := artifactKinds[rel]; ok {
+			layout.Artifacts = append(layout.Artifacts, DiskArtifact{DiskEntry: *entry, Kind: kind})
+			layout.ReclaimableBytes += entry.Bytes
+		}
+		// The contents of artifacts would crowd out everything else
+		if strings.Count(rel, "/") < diskLayoutMaxDirDepth && artifactParent(rel, artifactKinds) == "" {
+			layout.Directories = append(layout.Directories, *entry)
+		}
+	}
+	layout.Directories = topDiskEntries(layout.Directories)
+	layout.Files = topDiskEntries(files)
+	sort.Slice(layout.Artifacts, func(i, j int) bool {
+		if layout.Artifacts[i].Bytes != layout.Artifacts[j].Bytes {
+			return layout.Artifacts[i].Bytes > layout.Artifacts[j].Bytes
+		}
+		return layout.Artifacts[i].Path < layout.Artifacts[j].Path
+	})
+
+	for _, artifact := range layout.Artifacts {
+		if artifact.Bytes == 0 {
+			continue
+		}
+		layout.Suggestions = append(layout.Suggestions, DiskCleanupSuggestion{
+			Action:      "remove_artifact",
+			Path:        artifact.Path,
+			Bytes:       artifact.Bytes,
+			Description: "Build artifacts; " + diskArtifactDirs[filepath.Base(artifact.Path)].rebuild,
+			Command:     "rm -rf " + shellQuote(artifact.Path),
+		})
+	}
+	for _, file := range layout.Files {
+		if file.Bytes >= diskLayoutLargeFile && !strings.HasPrefix(file.Path, ".git/") {
+			layout.Suggestions = append(layout.Suggestions, DiskCleanupSuggestion{
+				Action:      "review_large_file",
+				Path:        file.Path,
+				Bytes:       file.Bytes,
+				Description: "Large file; remove it if it's a generated or downloaded copy",
+			})
+		}
+	}
+
+	layout.ScannedAt = now()
+	layout.DurationMs = layout.ScannedAt.Sub(started).Milliseconds()
+	return layout, nil
+}
+
+// artifactKind reports whether the directory at path is a build artifact
+func artifactKind(path, name string) string {
+	artifact, ok := diskArtifactDirs[name]
+	if !ok {
+		return ""
+	}
+	if len(artifact.markers) == 0 {
+		return artifact.kind
+	}
+	for _, marker := range artifact.markers {
+		if _, err := os.Stat(filepath.Join(path, marker)); err == nil {
+			return artifact.kind
+		}
+	}
+	return ""
+}
+
+// artifactParent returns the artifact directory containing rel, if any
+func artifactParent(rel string, artifacts map[string]string) string {
+	for dir := filepath.ToSlash(filepath.Dir(rel)); dir != "." && dir != "/"; dir = filepath.ToSlash(filepath.Dir(dir)) {
+		if _, ok := artifacts[dir]; ok {
+			return dir
+		}
+	}
+	return ""
+}
+
+func topDiskEntries(entries []DiskEntry) []DiskEntry {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Bytes != entries[j].Bytes {
+			return entries[i].Bytes > entries[j].Bytes
+		}
+		return entries[i].Path < entries[j].Path
+	})
+	if len(entries) > diskLayoutTopN {
+		entries = entries[:diskLayoutTopN]
+	}
+	if entries == nil {
+		return []DiskEntry{}
+	}
+	return entries
+}
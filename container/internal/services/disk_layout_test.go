@@ -0,0 +1,86 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSizedFile(t *testing.T, path string, size int) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+}
+
+func TestDiskLayoutScan(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, filepath.Join(dir, "package.json"), 10)
+	writeSizedFile(t, filepath.Join(dir, "src", "app.ts"), 100)
+	writeSizedFile(t, filepath.Join(dir, "node_modules", "react", "index.js"), 5000)
+	writeSizedFile(t, filepath.Join(dir, "node_modules", "react", "node_modules", "x", "y.js"), 1000)
+	writeSizedFile(t, filepath.Join(dir, "dist", "bundle.js"), 2000)
+	// "build" only counts as an artifact next to a project file
+	writeSizedFile(t, filepath.Join(dir, "docs", "build", "notes.md"), 50)
+
+	layout, err := NewDiskLayoutScanner().Layout(dir, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(8160), layout.TotalBytes)
+	assert.Equal(t, 6, layout.TotalFiles)
+
+	require.Len(t, layout.Artifacts, 2, "nested node_modules are part of the outer one")
+	assert.Equal(t, "node_modules", layout.Artifacts[0].Path)
+	assert.Equal(t, "node_modules", layout.Artifacts[0].Kind)
+	assert.Equal(t, int64(6000), layout.Artifacts[0].Bytes)
+	assert.Equal(t, "dist", layout.Artifacts[1].Path)
+	assert.Equal(t, int64(8000), layout.ReclaimableBytes)
+
+	require.Len(t, layout.Suggestions, 2)
+	assert.Equal(t, "remove_artifact", layout.Suggestions[0].Action)
+	assert.Equal(t, "rm -rf 'node_modules'", layout.Suggestions[0].Command)
+
+	var dirs []string
+	for _, entry := range layout.Directories {
+		dirs = append(dirs, entry.Path)
+	}
+	assert.Equal(t, []string{"node_modules", "dist", "src", "docs", "docs/build"}, dirs, "artifact contents aren't listed")
+	assert.Equal(t, "src/app.ts", layout.Files[0].Path, "files inside artifacts are covered by the artifact")
+}
+
+func TestDiskLayoutCache(t *testing.T) {
+	dir := t.TempDir()
+	writeSizedFile(t, filepath.Join(dir, "a.bin"), 10)
+
+	scanner := NewDiskLayoutScanner()
+	now := time.Now()
+	scanner.now = func() time.Time { return now }
+
+	_, ok := scanner.Reclaimable(dir)
+	assert.False(t, ok)
+	first, err := scanner.Layout(dir, false)
+	require.NoError(t, err)
+	reclaimable, ok := scanner.Reclaimable(dir)
+	assert.True(t, ok)
+	assert.Zero(t, reclaimable)
+
+	writeSizedFile(t, filepath.Join(dir, "b.bin"), 10)
+	cached, err := scanner.Layout(dir, false)
+	require.NoError(t, err)
+	assert.Same(t, first, cached)
+
+	refreshed, err := scanner.Layout(dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), refreshed.TotalBytes)
+
+	writeSizedFile(t, filepath.Join(dir, "c.bin"), 10)
+	now = now.Add(diskLayoutCacheTTL)
+	expired, err := scanner.Layout(dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(30), expired.TotalBytes)
+
+	_, err = scanner.Layout(filepath.Join(dir, "missing"), false)
+	assert.Error(t, err)
+}
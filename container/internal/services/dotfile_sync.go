@@ -0,0 +1,551 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// defaultDotfileSyncInterval is how often dotfiles are reconciled
+	defaultDotfileSyncInterval = time.Minute
+	// maxDotfileSize skips files that are too large to be dotfiles, such as
+	// caches picked up by a directory entry
+	maxDotfileSize = 4 << 20
+)
+
+// Results of reconciling one dotfile
+const (
+	dotfileUnchanged = ""
+	dotfileRestored  = "restored"
+	dotfileSaved     = "saved"
+)
+
+// DotfileEntry is a file or directory, relative to the home directory, listed
+// in the dotfile manifest
+type DotfileEntry struct {
+	Path     string `json:"path" yaml:"path"`
+	ReadOnly bool   `json:"readonly,omitempty" yaml:"readonly"` // Edits made in the environment are overwritten instead of saved
+	Worktree bool   `json:"worktree,omitempty" yaml:"worktree"` // Also copied into the root of each new worktree
+}
+
+// Validate checks that the entry stays inside the home directory
+func (e DotfileEntry) Validate() error {
+	path := strings.TrimSpace(e.Path)
+	if path == "" {
+		return fmt.Errorf("path is required")
+	}
+	if filepath.IsAbs(path) {
+		return fmt.Errorf("path must be relative to the home directory")
+	}
+	clean := filepath.Clean(path)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("path must be inside the home directory")
+	}
+	return nil
+}
+
+// DotfileManifest lists the dotfiles to sync
+type DotfileManifest struct {
+	Files []DotfileEntry `yaml:"files"`
+}
+
+// DotfileSyncStatus reports the state of the dotfile sync
+type DotfileSyncStatus struct {
+	Enabled   bool           `json:"enabled"` // A manifest exists
+	Manifest  string         `json:"manifest"`
+	Entries   []DotfileEntry `json:"entries,omitempty"`
+	LastSync  time.Time      `json:"last_sync,omitempty"`
+	LastError string         `json:"last_error,omitempty"`
+	Restored  []string       `json:"restored,omitempty"`  // Files copied into the environment by the last sync
+	Saved     []string       `json:"saved,omitempty"`     // Files saved to the volume by the last sync
+	Conflicts []string       `json:"conflicts,omitempty"` // Files changed on both sides; the newer copy won
+	Invalid   []string       `json:"invalid,omitempty"`   // Entries or files skipped by the last sync
+}
+
+// DotfileSyncService keeps the dotfiles listed in the manifest in sync between
+// the home directory and the volume, so every environment starts with the
+// user's shell rc, git config, tool versions and Claude commands and edits
+// made in one are saved for the next. Files changed on one side since the
+// last sync are copied to the other; a file missing from the home directory
+// is always restored. Deleting a file doesn't propagate: remove it from the
+// manifest or the volume instead.
+type DotfileSyncService struct {
+	operations   git.Operations
+	dir          string // Holds the manifest, stored copies and sync state
+	homeDir      string
+	syncInterval time.Duration
+	mu           sync.Mutex // Serializes syncs and guards status
+	status       DotfileSyncStatus
+	stopChan     chan struct{}
+	running      bool
+}
+
+// NewDotfileSyncService creates a dotfile sync service using the manifest in
+// the volume's dotfiles directory, syncing every CATNIP_DOTFILE_SYNC_INTERVAL
+func NewDotfileSyncService() *DotfileSyncService {
+	interval := defaultDotfileSyncInterval
+	if raw := os.Getenv("CATNIP_DOTFILE_SYNC_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= 10*time.Second {
+			interval = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_DOTFILE_SYNC_INTERVAL %q (minimum 10s)", raw)
+		}
+	}
+
+	return NewDotfileSyncServiceWithOptions(
+		git.NewOperations(),
+		filepath.Join(config.Runtime.VolumeDir, "dotfiles"),
+		config.Runtime.HomeDir,
+		interval,
+	)
+}
+
+// NewDotfileSyncServiceWithOptions creates a dotfile sync service with explicit settings (for testing)
+func NewDotfileSyncServiceWithOptions(operations git.Operations, dir, homeDir string, interval time.Duration) *DotfileSyncService {
+	return &DotfileSyncService{
+		operations:   operations,
+		dir:          dir,
+		homeDir:      homeDir,
+		syncInterval: interval,
+		stopChan:     make(chan struct{}),
+		status: DotfileSyncStatus{
+			Manifest: filepath.Join(dir, "manifest.yaml"),
+		},
+	}
+}
+
+// Start syncs once, so restored dotfiles are in place before any worktree or
+// terminal is created, and then syncs periodically. A manifest added later is
+// picked up by the next sync.
+func (s *DotfileSyncService) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	if err := s.Sync(); err != nil {
+		logger.Warnf("⚠️ Initial dotfile sync failed: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.syncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+				if err := s.Sync(); err != nil {
+					logger.Warnf("⚠️ Dotfile sync failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops periodic syncing
+func (s *DotfileSyncService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+}
+
+// Status returns the current sync status
+func (s *DotfileSyncService) Status() DotfileSyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync reconciles every file in the manifest between the home directory and
+// the volume. It does nothing when there is no manifest.
+func (s *DotfileSyncService) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manifest, err := s.loadManifest()
+	if err != nil {
+		s.status.LastError = err.Error()
+		return err
+	}
+	if manifest == nil {
+		s.status = DotfileSyncStatus{Manifest: s.manifestPath()}
+		return nil
+	}
+
+	base := s.loadBase()
+	status := DotfileSyncStatus{
+		Enabled:  true,
+		Manifest: s.manifestPath(),
+		Entries:  manifest.Files,
+		LastSync: time.Now(),
+	}
+	for _, entry := range manifest.Files {
+		if err := s.validateEntry(entry); err != nil {
+			logger.Warnf("⚠️ Skipping dotfile %q: %v", entry.Path, err)
+			status.Invalid = append(status.Invalid, fmt.Sprintf("%s: %v", entry.Path, err))
+			continue
+		}
+		for _, rel := range s.entryFiles(entry.Path) {
+			action, conflict, err := s.syncFile(rel, entry.ReadOnly, base)
+			if err != nil {
+				logger.Warnf("⚠️ Failed to sync dotfile %s: %v", rel, err)
+				status.Invalid = append(status.Invalid, fmt.Sprintf("%s: %v", rel, err))
+				continue
+			}
+			switch action {
+			case dotfileRestored:
+				status.Restored = append(status.Restored, rel)
+			case dotfileSaved:
+				status.Saved = append(status.Saved, rel)
+			}
+			if conflict {
+				status.Conflicts = append(status.Conflicts, rel)
+			}
+		}
+	}
+
+	if err := s.saveBase(base); err != nil {
+		status.LastError = err.Error()
+		s.status = status
+		return err
+	}
+	s.status = status
+
+	if len(status.Restored) > 0 || len(status.Saved) > 0 {
+		logger.Infof("🏠 Synced dotfiles: %d restored, %d saved", len(status.Restored), len(status.Saved))
+	}
+	return nil
+}
+
+// ApplyToWorktree copies the manifest's worktree entries, such as
+// .tool-versions, into a new worktree before its setup.sh runs. Files the
+// repository already has are left alone, and copied ones are excluded from
+// git so they aren't committed.
+func (s *DotfileSyncService) ApplyToWorktree(worktreePath string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	manifest, err := s.loadManifest()
+	if err != nil || manifest == nil {
+		return
+	}
+
+	var copied []string
+	for _, entry := range manifest.Files {
+		if !entry.Worktree || s.validateEntry(entry) != nil {
+			continue
+		}
+		for _, rel := range s.entryFiles(entry.Path) {
+			dst := filepath.Join(worktreePath, rel)
+			if _, err := os.Lstat(dst); err == nil {
+				continue
+			}
+			src := filepath.Join(s.homeDir, rel)
+			if _, err := os.Stat(src); err != nil {
+				src = filepath.Join(s.filesDir(), rel)
+			}
+			if err := copyDotfile(src, dst); err != nil {
+				logger.Warnf("⚠️ Failed to copy dotfile %s into %s: %v", rel, worktreePath, err)
+				continue
+			}
+			copied = append(copied, rel)
+		}
+	}
+	if len(copied) == 0 {
+		return
+	}
+
+	if err := s.excludeFromGit(worktreePath, copied); err != nil {
+		logger.Warnf("⚠️ Failed to exclude dotfiles from git in %s: %v", worktreePath, err)
+	}
+	logger.Infof("🏠 Copied %d dotfiles into %s", len(copied), worktreePath)
+}
+
+func (s *DotfileSyncService) manifestPath() string {
+	return filepath.Join(s.dir, "manifest.yaml")
+}
+
+// filesDir holds the stored copy of each dotfile at its path relative to home
+func (s *DotfileSyncService) filesDir() string {
+	return filepath.Join(s.dir, "files")
+}
+
+// loadManifest returns nil without an error when there is no manifest
+func (s *DotfileSyncService) loadManifest() (*DotfileManifest, error) {
+	data, err := os.ReadFile(s.manifestPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dotfile manifest: %v", err)
+	}
+
+	var manifest DotfileManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid dotfile manifest %s: %v", s.manifestPath(), err)
+	}
+	return &manifest, nil
+}
+
+// validateEntry also rejects entries containing the dotfiles directory
+// itself, which would sync into itself when the volume is inside home
+func (s *DotfileSyncService) validateEntry(entry DotfileEntry) error {
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+	live := filepath.Join(s.homeDir, filepath.Clean(strings.TrimSpace(entry.Path)))
+	if dir := filepath.Clean(s.dir); dir == live || strings.HasPrefix(dir, live+string(filepath.Separator)) {
+		return fmt.Errorf("path contains the dotfiles directory %s", s.dir)
+	}
+	return nil
+}
+
+// entryFiles lists the regular files of an entry found in the home directory
+// or the volume, relative to home
+func (s *DotfileSyncService) entryFiles(path string) []string {
+	path = filepath.Clean(strings.TrimSpace(path))
+	seen := make(map[string]bool)
+	for _, root := range []string{s.homeDir, s.filesDir()} {
+		entryRoot := filepath.Join(root, path)
+		_ = filepath.WalkDir(entryRoot, func(file string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() {
+				return nil
+			}
+			if rel, err := filepath.Rel(root, file); err == nil {
+				seen[rel] = true
+			}
+			return nil
+		})
+	}
+
+	files := make([]string, 0, len(seen))
+	for rel := range seen {
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// syncFile copies a dotfile in whichever direction it changed since the last
+// sync, recording the synced content's hash in base. When both copies changed
+// the newer one wins and the other is kept in the conflicts directory.
+func (s *DotfileSyncService) syncFile(rel string, readOnly bool, base map[string]string) (string, bool, error) {
+	live := filepath.Join(s.homeDir, rel)
+	stored := filepath.Join(s.filesDir(), rel)
+
+	liveHash, liveTime, err := hashDotfile(live)
+	if err != nil {
+		return dotfileUnchanged, false, err
+	}
+	storedHash, storedTime, err := hashDotfile(stored)
+	if err != nil {
+		return dotfileUnchanged, false, err
+	}
+
+	restore := func() (string, error) {
+		if err := copyDotfile(stored, live); err != nil {
+			return dotfileUnchanged, err
+		}
+		base[rel] = storedHash
+		return dotfileRestored, nil
+	}
+	save := func() (string, error) {
+		if err := copyDotfile(live, stored); err != nil {
+			return dotfileUnchanged, err
+		}
+		base[rel] = liveHash
+		return dotfileSaved, nil
+	}
+
+	var action string
+	switch {
+	case liveHash == storedHash:
+		if liveHash != "" {
+			base[rel] = liveHash
+		}
+		return dotfileUnchanged, false, nil
+	case liveHash == "":
+		action, err = restore()
+	case storedHash == "":
+		if readOnly {
+			return dotfileUnchanged, false, nil
+		}
+		action, err = save()
+	case readOnly || liveHash == base[rel]:
+		action, err = restore()
+	case storedHash == base[rel]:
+		action, err = save()
+	default:
+		loser := live
+		if liveTime.After(storedTime) {
+			loser = stored
+		}
+		if err := copyDotfile(loser, filepath.Join(s.dir, "conflicts", rel)); err != nil {
+			return dotfileUnchanged, true, fmt.Errorf("failed to keep conflicting copy: %v", err)
+		}
+		logger.Warnf("⚠️ Dotfile %s changed in both the environment and the volume, keeping the newer copy", rel)
+		if loser == stored {
+			action, err = save()
+		} else {
+			action, err = restore()
+		}
+		return action, true, err
+	}
+	return action, false, err
+}
+
+// baseStatePath records the hash of each dotfile as of its last sync
+func (s *DotfileSyncService) baseStatePath() string {
+	return filepath.Join(s.dir, "state.json")
+}
+
+func (s *DotfileSyncService) loadBase() map[string]string {
+	base := make(map[string]string)
+	data, err := os.ReadFile(s.baseStatePath())
+	if err != nil {
+		return base
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		logger.Warnf("⚠️ Ignoring invalid dotfile sync state: %v", err)
+		return make(map[string]string)
+	}
+	return base
+}
+
+func (s *DotfileSyncService) saveBase(base map[string]string) error {
+	data, err := json.MarshalIndent(base, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create dotfiles directory: %v", err)
+	}
+	if err := os.WriteFile(s.baseStatePath(), data, 0644); err != nil {
+		return fmt.Errorf("failed to save dotfile sync state: %v", err)
+	}
+	return nil
+}
+
+// excludeFromGit adds files to the repository's info/exclude
+func (s *DotfileSyncService) excludeFromGit(worktreePath string, files []string) error {
+	output, err := s.operations.ExecuteCommand("git", "-C", worktreePath, "rev-parse", "--git-path", "info/exclude")
+	if err != nil {
+		return err
+	}
+	excludePath := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(excludePath) {
+		excludePath = filepath.Join(worktreePath, excludePath)
+	}
+
+	existing, _ := os.ReadFile(excludePath)
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		lines[strings.TrimSpace(line)] = true
+	}
+	var add strings.Builder
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		add.WriteString("\n")
+	}
+	for _, file := range files {
+		pattern := "/" + filepath.ToSlash(file)
+		if !lines[pattern] {
+			add.WriteString(pattern + "\n")
+			lines[pattern] = true
+		}
+	}
+	if add.Len() == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(excludePath), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(excludePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(add.String())
+	return err
+}
+
+// hashDotfile returns the content hash and modification time of a regular
+// file, or an empty hash if it doesn't exist
+func hashDotfile(path string) (string, time.Time, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return "", time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return "", time.Time{}, fmt.Errorf("not a regular file")
+	}
+	if info.Size() > maxDotfileSize {
+		return "", time.Time{}, fmt.Errorf("larger than %d bytes", maxDotfileSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), info.ModTime(), nil
+}
+
+// copyDotfile atomically replaces dst with src, keeping its permissions
+func copyDotfile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".dotfile-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
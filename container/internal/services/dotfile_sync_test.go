@@ -0,0 +1,108 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+const testDotfileManifest = `files:
+  - path: .zshrc
+  - path: .gitconfig
+    readonly: true
+  - path: .tool-versions
+    worktree: true
+  - path: .claude/commands
+  - path: ../outside
+`
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestDotfileSync(t *testing.T) {
+	tempDir := t.TempDir()
+	dir := filepath.Join(tempDir, "volume", "dotfiles")
+	home := filepath.Join(tempDir, "home")
+	files := filepath.Join(dir, "files")
+	service := NewDotfileSyncServiceWithOptions(git.NewOperations(), dir, home, time.Minute)
+
+	t.Run("DisabledWithoutManifest", func(t *testing.T) {
+		require.NoError(t, service.Sync())
+		assert.False(t, service.Status().Enabled)
+	})
+
+	writeTestFile(t, filepath.Join(dir, "manifest.yaml"), testDotfileManifest)
+	writeTestFile(t, filepath.Join(files, ".zshrc"), "export EDITOR=vim\n")
+	writeTestFile(t, filepath.Join(files, ".gitconfig"), "[user]\n\tname = Cat\n")
+	writeTestFile(t, filepath.Join(files, ".tool-versions"), "golang 1.22.0\n")
+	writeTestFile(t, filepath.Join(home, ".claude", "commands", "review.md"), "Review the diff\n")
+
+	t.Run("RestoresAndCaptures", func(t *testing.T) {
+		require.NoError(t, service.Sync())
+		status := service.Status()
+		assert.True(t, status.Enabled)
+		assert.Equal(t, []string{".zshrc", ".gitconfig", ".tool-versions"}, status.Restored)
+		assert.Equal(t, []string{filepath.Join(".claude", "commands", "review.md")}, status.Saved)
+		assert.Len(t, status.Invalid, 1)
+
+		assert.Equal(t, "export EDITOR=vim\n", readTestFile(t, filepath.Join(home, ".zshrc")))
+		assert.Equal(t, "Review the diff\n", readTestFile(t, filepath.Join(files, ".claude", "commands", "review.md")))
+	})
+
+	t.Run("PropagatesChangedSide", func(t *testing.T) {
+		writeTestFile(t, filepath.Join(home, ".zshrc"), "export EDITOR=nvim\n")
+		writeTestFile(t, filepath.Join(files, ".tool-versions"), "golang 1.23.0\n")
+		writeTestFile(t, filepath.Join(home, ".gitconfig"), "[user]\n\tname = Dog\n")
+
+		require.NoError(t, service.Sync())
+		status := service.Status()
+		assert.Equal(t, []string{".zshrc"}, status.Saved)
+		assert.Equal(t, []string{".gitconfig", ".tool-versions"}, status.Restored)
+
+		assert.Equal(t, "export EDITOR=nvim\n", readTestFile(t, filepath.Join(files, ".zshrc")))
+		assert.Equal(t, "golang 1.23.0\n", readTestFile(t, filepath.Join(home, ".tool-versions")))
+		// Read-only entries always take the volume's copy
+		assert.Equal(t, "[user]\n\tname = Cat\n", readTestFile(t, filepath.Join(home, ".gitconfig")))
+
+		require.NoError(t, service.Sync())
+		assert.Empty(t, service.Status().Restored)
+		assert.Empty(t, service.Status().Saved)
+	})
+
+	t.Run("ConflictKeepsNewer", func(t *testing.T) {
+		writeTestFile(t, filepath.Join(files, ".zshrc"), "older\n")
+		writeTestFile(t, filepath.Join(home, ".zshrc"), "newer\n")
+		past := time.Now().Add(-time.Hour)
+		require.NoError(t, os.Chtimes(filepath.Join(files, ".zshrc"), past, past))
+
+		require.NoError(t, service.Sync())
+		assert.Equal(t, []string{".zshrc"}, service.Status().Conflicts)
+		assert.Equal(t, "newer\n", readTestFile(t, filepath.Join(files, ".zshrc")))
+		assert.Equal(t, "older\n", readTestFile(t, filepath.Join(dir, "conflicts", ".zshrc")))
+	})
+
+	t.Run("ApplyToWorktree", func(t *testing.T) {
+		worktree := filepath.Join(tempDir, "worktree")
+		require.NoError(t, os.MkdirAll(worktree, 0755))
+		_, err := git.NewOperations().ExecuteGit(worktree, "init", "-b", "main")
+		require.NoError(t, err)
+
+		service.ApplyToWorktree(worktree)
+		assert.Equal(t, "golang 1.23.0\n", readTestFile(t, filepath.Join(worktree, ".tool-versions")))
+		assert.NoFileExists(t, filepath.Join(worktree, ".zshrc"))
+		assert.Contains(t, readTestFile(t, filepath.Join(worktree, ".git", "info", "exclude")), "/.tool-versions\n")
+
+		// The repository's own copy is never replaced
+		writeTestFile(t, filepath.Join(worktree, ".tool-versions"), "nodejs 20\n")
+		service.ApplyToWorktree(worktree)
+		assert.Equal(t, "nodejs 20\n", readTestFile(t, filepath.Join(worktree, ".tool-versions")))
+	})
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// maxTrackedCompletions bounds how many recent completions we remember for
+// joining feedback with its prompt/model/workspace metadata
+const maxTrackedCompletions = 500
+
+// completionRecord is the metadata kept for a recent completion. Records are
+// appended to claude-completions.jsonl next to the feedback, so feedback sent
+// after a restart is still joined with its completion.
+type completionRecord struct {
+	ID               string    `json:"id"`
+	Prompt           string    `json:"prompt"`
+	Model            string    `json:"model,omitempty"`
+	WorkingDirectory string    `json:"working_directory,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// FeedbackFilter narrows down which feedback records are returned
+type FeedbackFilter struct {
+	Rating           models.FeedbackRating
+	WorkingDirectory string
+	Since            time.Time
+}
+
+// FeedbackService persists thumbs-up/down feedback on Claude responses
+type FeedbackService struct {
+	filePath        string
+	completionsPath string
+	mu              sync.Mutex
+	completions     map[string]*completionRecord
+	order           []string // Completion IDs in insertion order for eviction
+	appended        int      // Records appended to completionsPath since it was last rewritten
+}
+
+// NewFeedbackService creates a feedback service storing records in the volume directory
+func NewFeedbackService() *FeedbackService {
+	return NewFeedbackServiceWithDir(config.Runtime.VolumeDir)
+}
+
+// NewFeedbackServiceWithDir creates a feedback service storing records in dir (for testing)
+func NewFeedbackServiceWithDir(dir string) *FeedbackService {
+	s := &FeedbackService{
+		filePath:        filepath.Join(dir, "claude-feedback.jsonl"),
+		completionsPath: filepath.Join(dir, "claude-completions.jsonl"),
+		completions:     make(map[string]*completionRecord),
+	}
+	s.loadCompletions()
+	return s
+}
+
+// loadCompletions restores the most recent completions saved before a restart
+func (s *FeedbackService) loadCompletions() {
+	file, err := os.Open(s.completionsPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to load completion metadata: %v", err)
+		}
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record completionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.ID == "" {
+			continue
+		}
+		s.trackLocked(&record)
+		s.appended++
+	}
+}
+
+// trackLocked remembers a completion, evicting the oldest beyond the limit.
+// The caller must hold mu.
+func (s *FeedbackService) trackLocked(record *completionRecord) {
+	if _, exists := s.completions[record.ID]; !exists {
+		s.order = append(s.order, record.ID)
+	}
+	s.completions[record.ID] = record
+	for len(s.order) > maxTrackedCompletions {
+		delete(s.completions, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+// saveCompletionLocked appends a completion to completionsPath. Once the file
+// holds twice the tracked limit it is rewritten with just the tracked
+// completions. The caller must hold mu.
+func (s *FeedbackService) saveCompletionLocked(record *completionRecord) error {
+	if err := os.MkdirAll(filepath.Dir(s.completionsPath), 0755); err != nil {
+		return err
+	}
+	if s.appended >= 2*maxTrackedCompletions {
+		var data []byte
+		for _, id := range s.order {
+			line, err := json.Marshal(s.completions[id])
+			if err != nil {
+				return err
+			}
+			data = append(append(data, line...), '\n')
+		}
+		tmpPath := s.completionsPath + ".tmp"
+		if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, s.completionsPath); err != nil {
+			return err
+		}
+		s.appended = len(s.order)
+		return nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.completionsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.appended++
+	return nil
+}
+
+// RecordCompletion remembers a completion's metadata and returns its new ID
+func (s *FeedbackService) RecordCompletion(req *models.CreateCompletionRequest) string {
+	id := uuid.New().String()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := &completionRecord{
+		ID:               id,
+		Prompt:           req.Prompt,
+		Model:            req.Model,
+		WorkingDirectory: req.WorkingDirectory,
+		CreatedAt:        time.Now(),
+	}
+	s.trackLocked(record)
+	if err := s.saveCompletionLocked(record); err != nil {
+		logger.Warnf("⚠️ Failed to save completion metadata: %v", err)
+	}
+
+	return id
+}
+
+// SubmitFeedback validates and persists a feedback record
+func (s *FeedbackService) SubmitFeedback(req *models.ClaudeFeedbackRequest) (*models.ClaudeFeedback, error) {
+	if req.Rating != models.FeedbackPositive && req.Rating != models.FeedbackNegative {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "rating must be 'up' or 'down'")
+	}
+	if req.CompletionID == "" && req.SessionID == "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "completion_id or session_id is required")
+	}
+
+	feedback := &models.ClaudeFeedback{
+		ID:               uuid.New().String(),
+		CompletionID:     req.CompletionID,
+		SessionID:        req.SessionID,
+		MessageID:        req.MessageID,
+		Rating:           req.Rating,
+		Comment:          req.Comment,
+		WorkingDirectory: req.WorkingDirectory,
+		CreatedAt:        time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.CompletionID != "" {
+		if record, exists := s.completions[req.CompletionID]; exists {
+			feedback.Prompt = record.Prompt
+			feedback.Model = record.Model
+			if feedback.WorkingDirectory == "" {
+				feedback.WorkingDirectory = record.WorkingDirectory
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create feedback directory: %w", err)
+	}
+
+	data, err := json.Marshal(feedback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal feedback: %w", err)
+	}
+
+	file, err := os.OpenFile(s.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write feedback: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// ListFeedback returns all stored feedback matching the filter, oldest first
+func (s *FeedbackService) ListFeedback(filter FeedbackFilter) ([]models.ClaudeFeedback, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := []models.ClaudeFeedback{}
+
+	file, err := os.Open(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var feedback models.ClaudeFeedback
+		if err := json.Unmarshal(scanner.Bytes(), &feedback); err != nil {
+			// Skip partially written lines rather than failing the whole export
+			continue
+		}
+		if filter.Rating != "" && feedback.Rating != filter.Rating {
+			continue
+		}
+		if filter.WorkingDirectory != "" && feedback.WorkingDirectory != filter.WorkingDirectory {
+			continue
+		}
+		if !filter.Since.IsZero() && feedback.CreatedAt.Before(filter.Since) {
+			continue
+		}
+		result = append(result, feedback)
+	}
+
+	return result, scanner.Err()
+}
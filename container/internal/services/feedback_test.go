@@ -0,0 +1,104 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestFeedbackService_SubmitJoinsCompletionMetadata(t *testing.T) {
+	service := NewFeedbackServiceWithDir(t.TempDir())
+
+	completionID := service.RecordCompletion(&models.CreateCompletionRequest{
+		Prompt:           "Summarize this diff",
+		Model:            "claude-haiku-4-5",
+		WorkingDirectory: "/workspace/repo/feature",
+	})
+	require.NotEmpty(t, completionID)
+
+	feedback, err := service.SubmitFeedback(&models.ClaudeFeedbackRequest{
+		CompletionID: completionID,
+		Rating:       models.FeedbackNegative,
+		Comment:      "Missed the main change",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Summarize this diff", feedback.Prompt)
+	assert.Equal(t, "claude-haiku-4-5", feedback.Model)
+	assert.Equal(t, "/workspace/repo/feature", feedback.WorkingDirectory)
+
+	_, err = service.SubmitFeedback(&models.ClaudeFeedbackRequest{
+		SessionID: "session-1",
+		MessageID: "msg-1",
+		Rating:    models.FeedbackPositive,
+	})
+	require.NoError(t, err)
+
+	all, err := service.ListFeedback(FeedbackFilter{})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	negative, err := service.ListFeedback(FeedbackFilter{Rating: models.FeedbackNegative})
+	require.NoError(t, err)
+	require.Len(t, negative, 1)
+	assert.Equal(t, completionID, negative[0].CompletionID)
+
+	future, err := service.ListFeedback(FeedbackFilter{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, future)
+}
+
+func TestFeedbackService_Validation(t *testing.T) {
+	service := NewFeedbackServiceWithDir(t.TempDir())
+
+	_, err := service.SubmitFeedback(&models.ClaudeFeedbackRequest{CompletionID: "x", Rating: "meh"})
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+
+	_, err = service.SubmitFeedback(&models.ClaudeFeedbackRequest{Rating: models.FeedbackPositive})
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+}
+
+func TestFeedbackService_EvictsOldCompletions(t *testing.T) {
+	service := NewFeedbackServiceWithDir(t.TempDir())
+
+	first := service.RecordCompletion(&models.CreateCompletionRequest{Prompt: "first"})
+	for i := 0; i < maxTrackedCompletions; i++ {
+		service.RecordCompletion(&models.CreateCompletionRequest{Prompt: "filler"})
+	}
+
+	feedback, err := service.SubmitFeedback(&models.ClaudeFeedbackRequest{CompletionID: first, Rating: models.FeedbackPositive})
+	require.NoError(t, err)
+	assert.Empty(t, feedback.Prompt, "evicted completions should no longer be joined")
+}
+
+func TestFeedbackService_CompletionsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	service := NewFeedbackServiceWithDir(dir)
+
+	completionID := service.RecordCompletion(&models.CreateCompletionRequest{
+		Prompt:           "Name this branch",
+		Model:            "claude-haiku-4-5",
+		WorkingDirectory: "/workspace/repo/feature",
+	})
+
+	restarted := NewFeedbackServiceWithDir(dir)
+	feedback, err := restarted.SubmitFeedback(&models.ClaudeFeedbackRequest{CompletionID: completionID, Rating: models.FeedbackPositive})
+	require.NoError(t, err)
+	assert.Equal(t, "Name this branch", feedback.Prompt)
+	assert.Equal(t, "claude-haiku-4-5", feedback.Model)
+	assert.Equal(t, "/workspace/repo/feature", feedback.WorkingDirectory)
+
+	// The file is compacted to the tracked completions as it grows
+	for i := 0; i < 2*maxTrackedCompletions; i++ {
+		restarted.RecordCompletion(&models.CreateCompletionRequest{Prompt: "filler"})
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "claude-completions.jsonl"))
+	require.NoError(t, err)
+	assert.LessOrEqual(t, strings.Count(string(data), "\n"), 2*maxTrackedCompletions)
+	assert.Len(t, NewFeedbackServiceWithDir(dir).completions, maxTrackedCompletions)
+}
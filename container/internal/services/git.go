@@ -1,11 +1,11 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -82,6 +82,7 @@ func (s *GitService) cleanupUnusedBranches() {
 			continue
 		}
 		deletedInRepo := 0
+		guard := s.newRefCleanupGuard(repo.Path)
 
 		for _, branch := range branches {
 			// Clean up branch name
@@ -95,6 +96,10 @@ func (s *GitService) cleanupUnusedBranches() {
 			if !isCatnipBranch(branchName) {
 				continue
 			}
+			if reason := guard.protects("refs/heads/" + branchName); reason != "" {
+				logger.Debugf("🔒 Preserving branch %s: %s", branchName, reason)
+				continue
+			}
 
 			// Check if branch has any commits different from its parent
 			// First, try to find the merge-base with main/master
@@ -156,7 +161,8 @@ func (s *GitService) cleanupUnusedBranches() {
 	}
 }
 
-// cleanupCatnipRefs provides comprehensive cleanup of refs/catnip/ namespace, checking against state.json
+// cleanupCatnipRefs provides comprehensive cleanup of refs/catnip/ namespace, checking against state.json.
+// Refs with a keep marker, refs of journaled operations and refs updated within the grace period are never deleted.
 func (s *GitService) cleanupCatnipRefs() {
 	logger.Debug("🧹 Starting cleanup of catnip refs namespace...")
 
@@ -218,6 +224,7 @@ func (s *GitService) cleanupCatnipRefs() {
 
 		deletedInRepo := 0
 		refs := strings.Split(strings.TrimSpace(string(output)), "\n")
+		guard := s.newRefCleanupGuard(repo.Path)
 
 		for _, ref := range refs {
 			ref = strings.TrimSpace(ref)
@@ -234,6 +241,13 @@ func (s *GitService) cleanupCatnipRefs() {
 				continue
 			}
 
+			// Keep markers, in-flight operations and recently updated refs
+			// cover worktrees state.json doesn't know about yet
+			if reason := guard.protects(ref); reason != "" {
+				logger.Debugf("🔒 Preserving ref %s: %s", ref, reason)
+				continue
+			}
+
 			// Double-check if there's an active worktree using this ref (fallback safety)
 			worktrees, err := s.operations.ListWorktrees(repo.Path)
 			if err == nil {
@@ -405,6 +419,8 @@ type EventsEmitter interface {
 	EmitWorktreeTodosUpdated(worktreeID string, todos []models.Todo)
 	EmitSessionTitleUpdated(workspaceDir, worktreeID string, sessionTitle *models.TitleEntry, sessionTitleHistory []models.TitleEntry)
 	EmitClaudeMessage(workspaceDir, worktreeID, message, messageType string)
+	EmitWorktreeSetupStale(worktreeID, worktreeName string, files []string, rerun bool)
+	EmitRepositoryAdded(repo *models.Repository)
 }
 
 type GitService struct {
@@ -413,16 +429,31 @@ type GitService struct {
 	gitWorktreeManager  *git.WorktreeManager  // Git layer worktree operations
 	conflictResolver    *git.ConflictResolver // Handles conflict detection/resolution
 	githubManager       *git.GitHubManager    // Handles all GitHub CLI operations
+	hostingManager      *git.HostingManager   // Handles GitLab and Bitbucket repositories
 	localRepoManager    *LocalRepoManager     // Handles local repository detection
 	commitSync          *CommitSyncService    // Handles automatic checkpointing and commit sync
 	setupExecutor       SetupExecutor         // Handles setup.sh execution in PTY sessions
+	workspaceSessions   WorkspaceSessions     // Terminal sessions closed around sandbox changes
 	worktreeCache       *WorktreeStatusCache  // Handles worktree status caching with event updates
 	eventsEmitter       EventsEmitter         // Handles emitting events to connected clients
 	claudeMonitor       *ClaudeMonitorService // Handles Claude session monitoring
+	agentCosts          *AgentCostStore       // Per-PR agent usage records
+	reviews             *ReviewStore          // Per-reviewer file review marks
+	repoLimiter         *RepoOperationLimiter // Serializes mutating operations per repository
+	mirrors             *MirrorService        // Local mirrors for offline checkouts
+	previews            *PreviewService       // Preview deployments torn down with their worktree
+	dotfiles            *DotfileSyncService   // Dotfiles copied into new worktrees
+	gitProgress         *GitProgressHub       // Output of long clones and fetches
+	githubApp           *GitHubAppService     // Installation tokens for repositories in app auth mode
 	mu                  sync.RWMutex
-	lastFetchTimes      map[string]time.Time // Track last fetch time per repo path
-	lastFetchMu         sync.RWMutex         // Protect lastFetchTimes map
-	fetchThrottlePeriod time.Duration        // How long to wait between fetches for same repo
+	lastFetchTimes      map[string]time.Time       // Track last fetch time per repo path
+	lastFetchMu         sync.RWMutex               // Protect lastFetchTimes map
+	memoryMu            sync.Mutex                 // Serializes edits to worktree memory
+	locksMu             sync.Mutex                 // Serializes edits to worktree locks
+	localRepoWatchStop  chan struct{}              // Stops rescanning mount roots for new repositories
+	fetchThrottlePeriod time.Duration              // How long to wait between fetches for same repo
+	fetchStats          map[string]*repoFetchStats // Fetch durations and repository sizes per repo ID
+	fetchStatsMu        sync.Mutex                 // Protect fetchStats
 }
 
 // Helper functions for standardized command execution
@@ -441,6 +472,27 @@ func (s *GitService) SetClaudeMonitor(monitor *ClaudeMonitorService) {
 	s.claudeMonitor = monitor
 }
 
+// SetMirrorService connects the repository mirrors used for offline checkouts
+func (s *GitService) SetMirrorService(mirrors *MirrorService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mirrors = mirrors
+}
+
+// SetPreviewService tears down preview deployments when their worktree is deleted
+func (s *GitService) SetPreviewService(previews *PreviewService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previews = previews
+}
+
+// SetDotfileSync copies the user's worktree dotfiles into new worktrees
+func (s *GitService) SetDotfileSync(dotfiles *DotfileSyncService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dotfiles = dotfiles
+}
+
 // SetEventsEmitter connects the events emitter to the state manager
 func (s *GitService) SetEventsEmitter(emitter EventsEmitter) {
 	s.mu.Lock()
@@ -468,6 +520,53 @@ func (s *GitService) TriggerClaudeActivitySync() {
 func (s *GitService) InitializeLocalRepos() {
 	logger.Debug("🔍 Initializing local repositories with setup executor configured")
 	s.detectLocalRepos()
+	s.watchLocalRepos()
+}
+
+// watchLocalRepos periodically rescans the mount roots so repositories
+// mounted while catnip runs are picked up without a restart
+func (s *GitService) watchLocalRepos() {
+	interval := s.localRepoManager.ScanInterval()
+	if config.Runtime.IsNative() || interval <= 0 || s.localRepoWatchStop != nil {
+		return
+	}
+	stop := make(chan struct{})
+	s.localRepoWatchStop = stop
+
+	recovery.SafeGo("local-repo-watcher", func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.detectNewLocalRepos()
+			}
+		}
+	})
+}
+
+// detectNewLocalRepos adds repositories that appeared under the mount roots
+// since the last scan and announces them to clients
+func (s *GitService) detectNewLocalRepos() []*models.Repository {
+	var added []*models.Repository
+	for repoID, repoPath := range s.localRepoManager.ScanLocalRepoPaths() {
+		if _, exists := s.stateManager.GetRepository(repoID); exists {
+			continue
+		}
+
+		logger.Infof("🔌 New local repository mounted at %s", repoPath)
+		repo := s.localRepoManager.LoadLocalRepo(repoID, repoPath)
+		if !s.registerLocalRepo(repo) {
+			continue
+		}
+		added = append(added, repo)
+		if s.eventsEmitter != nil {
+			s.eventsEmitter.EmitRepositoryAdded(repo)
+		}
+	}
+	return added
 }
 
 // Repository type detection helpers
@@ -548,6 +647,13 @@ func (s *GitService) pushBranch(worktree *models.Worktree, repo *models.Reposito
 
 	// Execute push using operations
 	err := s.operations.PushBranch(worktree.Path, gitStrategy)
+	if err == nil {
+		remote := gitStrategy.Remote
+		if gitStrategy.RemoteURL != "" {
+			remote = gitStrategy.RemoteURL
+		}
+		s.pushAgentNotes(worktree.Path, remote)
+	}
 
 	// Handle push failure with sync retry (if requested)
 	if err != nil && strategy.SyncOnFail && git.IsPushRejected(err, err.Error()) {
@@ -555,7 +661,7 @@ func (s *GitService) pushBranch(worktree *models.Worktree, repo *models.Reposito
 
 		// Sync with upstream
 		if syncErr := s.syncBranchWithUpstream(worktree); syncErr != nil {
-			return fmt.Errorf("failed to sync with upstream: %v", syncErr)
+			return gitCommandError(fmt.Errorf("failed to sync with upstream: %w", syncErr))
 		}
 
 		// Retry the push (without sync this time to avoid infinite loop)
@@ -564,7 +670,7 @@ func (s *GitService) pushBranch(worktree *models.Worktree, repo *models.Reposito
 		return s.pushBranch(worktree, repo, retryStrategy)
 	}
 
-	return err
+	return gitCommandError(err)
 }
 
 // branchExists checks if a branch exists in a repository with configurable options
@@ -584,7 +690,7 @@ func (s *GitService) getDefaultBranch(repoPath string) (string, error) {
 
 // fetchBranch unified fetch method with strategy pattern
 func (s *GitService) fetchBranch(repoPath string, strategy git.FetchStrategy) error {
-	return s.operations.FetchBranch(repoPath, strategy)
+	return gitCommandError(s.operations.FetchBranch(repoPath, strategy))
 }
 
 // NewGitService creates a new Git service instance
@@ -608,11 +714,21 @@ func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitS
 		gitWorktreeManager:  git.NewWorktreeManager(operations),
 		conflictResolver:    git.NewConflictResolver(operations),
 		githubManager:       git.NewGitHubManager(operations),
+		hostingManager:      git.NewHostingManager(operations),
 		localRepoManager:    NewLocalRepoManager(operations),
+		repoLimiter:         NewRepoOperationLimiter(),
+		gitProgress:         NewGitProgressHub(),
 		lastFetchTimes:      make(map[string]time.Time),
 		fetchThrottlePeriod: 5 * time.Second, // Throttle fetches to once per 5 seconds per repo
+		fetchStats:          make(map[string]*repoFetchStats),
 	}
 
+	s.agentCosts = NewAgentCostStore(filepath.Join(stateDir, "agent_costs.json"))
+	s.reviews = NewReviewStore(filepath.Join(stateDir, "reviews.json"))
+
+	// Apply per-repository network timeout/retry overrides to git commands
+	operations.SetNetworkOverrideResolver(s.networkOverrideFor)
+
 	// Initialize CommitSync service
 	s.commitSync = NewCommitSyncServiceWithOperations(s, operations)
 
@@ -670,6 +786,10 @@ func NewGitServiceWithStateDir(operations git.Operations, stateDir string) *GitS
 
 // Stop properly shuts down the git service and its components
 func (s *GitService) Stop() {
+	if s.localRepoWatchStop != nil {
+		close(s.localRepoWatchStop)
+	}
+
 	// Stop CommitSync service
 	if s.commitSync != nil {
 		s.commitSync.Stop()
@@ -693,24 +813,19 @@ func (s *GitService) Stop() {
 
 // CheckoutRepository clones a GitHub repository as a bare repo and creates initial worktree
 func (s *GitService) CheckoutRepository(org, repo, branch string) (*models.Repository, *models.Worktree, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	repoID := fmt.Sprintf("%s/%s", org, repo)
+	release := s.acquireRepoSlot(repoID, repoOpCheckout)
+	defer release()
 
 	// Handle local repo specially
 	if s.isLocalRepo(repoID) {
 		return s.handleLocalRepoWorktree(repoID, branch)
 	}
 
-	var repoURL string
-	if os.Getenv("CATNIP_TEST_MODE") == "1" {
-		// In test mode, use local test repositories
-		repoURL = filepath.Join("/tmp", "test-repos", repo)
-	} else {
-		// In production, use GitHub URLs
-		repoURL = fmt.Sprintf("https://github.com/%s/%s.git", org, repo)
+	if err := s.checkRepoHost(repoID); err != nil {
+		return nil, nil, err
 	}
+	repoURL := checkoutRemoteURL(repoID)
 
 	repoName := strings.ReplaceAll(repo, "/", "-")
 	reposDir := filepath.Join(config.Runtime.VolumeDir, "repos")
@@ -744,6 +859,20 @@ func (s *GitService) CheckoutRepository(org, repo, branch string) (*models.Repos
 	return s.cloneNewRepository(repoID, repoURL, barePath, branch)
 }
 
+// checkRepoHost rejects repository IDs naming a host catnip has no provider
+// for: GitHub IDs have no host, GitLab and Bitbucket ones start with it
+func (s *GitService) checkRepoHost(repoID string) error {
+	host, _, ok := git.SplitHostedRepoID(repoID)
+	if !ok {
+		return nil
+	}
+	if _, supported := s.hostingManager.ForHost(host); !supported {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "unsupported git host %s", host).
+			WithHint("Use github.com, gitlab.com or bitbucket.org, or add a self-hosted GitLab to CATNIP_GITLAB_HOSTS")
+	}
+	return nil
+}
+
 // isRepoMounted checks if a repo directory is already mounted
 func (s *GitService) isRepoMounted(workspaceDir, repoName string) bool {
 	potentialMountPath := filepath.Join(workspaceDir, repoName)
@@ -788,16 +917,23 @@ func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch
 		branch = repo.DefaultBranch
 	}
 
-	// Check if the requested branch exists in the bare repo
-	if !s.branchExists(barePath, branch, true) {
+	// Fetch the requested branch if the bare repo doesn't have it yet
+	mirrorSyncedAt, err := s.fetchCheckoutBranch(repoID, barePath, branch, func() error {
+		if s.branchExists(barePath, branch, true) {
+			return nil
+		}
 		logger.Infof("🔄 Branch %s not found, fetching from remote", branch)
 		if err := s.fetchBranch(barePath, git.FetchStrategy{
 			Branch:         branch,
 			Depth:          1,
 			UpdateLocalRef: true,
 		}); err != nil {
-			return nil, nil, fmt.Errorf("failed to fetch branch %s: %v", branch, err)
+			return fmt.Errorf("failed to fetch branch %s: %v", branch, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Create new worktree with fun name
@@ -806,6 +942,9 @@ func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create worktree: %v", err)
 	}
+	if mirrorSyncedAt != nil {
+		s.markCreatedFromMirror(worktree, *mirrorSyncedAt)
+	}
 
 	// State persistence handled by state manager
 	logger.Infof("✅ Worktree created from existing repository: %s", repoID)
@@ -814,15 +953,32 @@ func (s *GitService) handleExistingRepository(repoID, repoURL, barePath, branch
 
 // cloneNewRepository clones a new bare repository
 func (s *GitService) cloneNewRepository(repoID, repoURL, barePath, branch string) (*models.Repository, *models.Worktree, error) {
-	// Clone as bare repository with shallow depth
-	args := []string{"clone", "--bare", "--depth", "1", "--single-branch"}
-	if branch != "" {
-		args = append(args, "--branch", branch)
-	}
-	args = append(args, repoURL, barePath)
+	mirrorPath, mirrorSyncedAt, hasMirror := s.mirrorForCheckout(repoID)
+	usedMirror := false
+	if s.offline() && hasMirror {
+		if err := s.cloneFromMirror(mirrorPath, repoURL, barePath, branch); err != nil {
+			return nil, nil, err
+		}
+		usedMirror = true
+	} else {
+		// Clone as bare repository with shallow depth
+		args := []string{"clone", "--progress", "--bare", "--depth", "1", "--single-branch"}
+		if branch != "" {
+			args = append(args, "--branch", branch)
+		}
+		args = append(args, repoURL, barePath)
 
-	if _, err := s.runGitCommand("", args...); err != nil {
-		return nil, nil, fmt.Errorf("failed to clone repository: %v", err)
+		if _, err := s.runGitWithProgress(repoID, "", repoOpCheckout, "", args...); err != nil {
+			if !hasMirror {
+				return nil, nil, fmt.Errorf("failed to clone repository: %v", err)
+			}
+			logger.Warnf("⚠️ Cloning %s failed, using mirror synced %s: %v", repoID, mirrorSyncedAt.Format(time.RFC3339), err)
+			_ = os.RemoveAll(barePath)
+			if err := s.cloneFromMirror(mirrorPath, repoURL, barePath, branch); err != nil {
+				return nil, nil, err
+			}
+			usedMirror = true
+		}
 	}
 
 	// Get default branch if not specified
@@ -848,8 +1004,10 @@ func (s *GitService) cloneNewRepository(repoID, repoURL, barePath, branch string
 		logger.Warnf("⚠️ Failed to add repository to state: %v", err)
 	}
 
-	// Start background unshallow process for the requested branch
-	go s.unshallowRepository(barePath, branch)
+	// Start background unshallow process for the requested branch (mirror clones have full history)
+	if !usedMirror {
+		go s.unshallowRepository(barePath, branch)
+	}
 
 	// Create initial worktree with fun name to avoid conflicts with local branches
 	funName := s.generateUniqueSessionName(repository.Path)
@@ -857,6 +1015,9 @@ func (s *GitService) cloneNewRepository(repoID, repoURL, barePath, branch string
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create initial worktree: %v", err)
 	}
+	if usedMirror {
+		s.markCreatedFromMirror(worktree, mirrorSyncedAt)
+	}
 
 	// State persistence handled by state manager
 	logger.Infof("✅ Repository cloned successfully: %s", repository.ID)
@@ -895,15 +1056,8 @@ func (s *GitService) enhanceWorktreeWithPRState(wt *models.Worktree) {
 	}
 
 	// Extract repo ID and PR number from the PR URL
-	prPattern := regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
-	matches := prPattern.FindStringSubmatch(wt.PullRequestURL)
-	if len(matches) != 3 {
-		return
-	}
-
-	repoID := matches[1]
-	prNumber, err := strconv.Atoi(matches[2])
-	if err != nil {
+	repoID, prNumber, ok := parseGitHubPRURL(wt.PullRequestURL)
+	if !ok {
 		return
 	}
 
@@ -912,6 +1066,9 @@ func (s *GitService) enhanceWorktreeWithPRState(wt *models.Worktree) {
 		if prState := prSyncManager.GetPRState(repoID, prNumber); prState != nil {
 			wt.PullRequestState = prState.State
 			wt.PullRequestLastSynced = &prState.LastSynced
+			if prState.Feedback != nil {
+				wt.PullRequestFeedback = prState.Feedback
+			}
 		}
 	}
 
@@ -998,6 +1155,9 @@ func (s *GitService) configureGitCredentials() {
 	} else {
 		logger.Infof("✅ Git credential helper configured successfully")
 	}
+	if err := s.hostingManager.ConfigureGitCredentials(); err != nil {
+		logger.Warnf("❌ Failed to configure GitLab/Bitbucket credentials: %v", err)
+	}
 }
 
 // ListGitHubRepositories returns a list of GitHub repositories accessible to the user
@@ -1057,126 +1217,136 @@ func (s *GitService) detectLocalRepos() {
 	repos := s.localRepoManager.DetectLocalRepos()
 
 	// Add detected repos to our repository map via state manager
-	for repoID, repo := range repos {
-		// Check if repository already exists in state and update fields if needed
-		if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
-			// Always update these fields from fresh detection
-			existingRepo.DefaultBranch = repo.DefaultBranch
-			existingRepo.LastAccessed = repo.LastAccessed
-			existingRepo.HasGitHubRemote = repo.HasGitHubRemote
-			existingRepo.RemoteOrigin = repo.RemoteOrigin
-
-			// Log if GitHub remote detection changed
-			if existingRepo.HasGitHubRemote != repo.HasGitHubRemote {
-				logger.Infof("🔄 Updating GitHub remote status for %s: %v -> %v", repoID, existingRepo.HasGitHubRemote, repo.HasGitHubRemote)
-			}
+	for _, repo := range repos {
+		s.registerLocalRepo(repo)
+	}
 
-			repo = existingRepo // Use the existing repo with updated fields
-		}
+	// Check and update any stale catnip-live remotes in existing worktrees
+	s.updateStaleRemotes()
+}
 
-		if err := s.stateManager.AddRepository(repo); err != nil {
-			logger.Warnf("⚠️ Failed to add repository %s to state: %v", repoID, err)
-			continue
+// registerLocalRepo adds a detected local repository to state, refreshing the
+// fields detection owns if it's already known, and creates its initial
+// worktree if it has none. It reports whether the repository was added.
+func (s *GitService) registerLocalRepo(repo *models.Repository) bool {
+	repoID := repo.ID
+
+	// Check if repository already exists in state and update fields if needed
+	if existingRepo, exists := s.stateManager.GetRepository(repoID); exists {
+		// Always update these fields from fresh detection
+		existingRepo.DefaultBranch = repo.DefaultBranch
+		existingRepo.LastAccessed = repo.LastAccessed
+		existingRepo.HasGitHubRemote = repo.HasGitHubRemote
+		existingRepo.RemoteOrigin = repo.RemoteOrigin
+
+		// Log if GitHub remote detection changed
+		if existingRepo.HasGitHubRemote != repo.HasGitHubRemote {
+			logger.Infof("🔄 Updating GitHub remote status for %s: %v -> %v", repoID, existingRepo.HasGitHubRemote, repo.HasGitHubRemote)
 		}
 
-		// Check if any worktrees exist for this repo
-		if s.shouldCreateInitialWorktree(repoID) {
-			logger.Infof("🌱 Creating initial worktree for %s", repoID)
+		repo = existingRepo // Use the existing repo with updated fields
+	}
 
-			// For shallow clones or when on a non-default branch, we need to ensure
-			// the default branch is fetched before we can create a worktree from it
-			defaultBranch := repo.DefaultBranch
+	if err := s.stateManager.AddRepository(repo); err != nil {
+		logger.Warnf("⚠️ Failed to add repository %s to state: %v", repoID, err)
+		return false
+	}
 
-			// Check if the default branch exists locally
-			if !s.branchExists(repo.Path, defaultBranch, false) {
-				logger.Infof("📥 Default branch '%s' not found locally, fetching from origin...", defaultBranch)
-
-				// Fetch the default branch in the background
-				// Use FetchBranchFast for speed (shallow fetch with depth=1)
-				// FetchBranchFast will also create the local branch ref from the remote tracking branch
-				if err := s.operations.FetchBranchFast(repo.Path, defaultBranch); err != nil {
-					logger.Warnf("⚠️  Failed to fetch default branch '%s': %v", defaultBranch, err)
-					logger.Infof("🔄 Attempting to determine and fetch the correct default branch from remote...")
-
-					// Try to get the actual default branch from the remote
-					if remoteBranch, err := s.operations.GetRemoteDefaultBranch(repo.Path); err == nil && remoteBranch != "" {
-						defaultBranch = remoteBranch
-						logger.Infof("🔍 Remote default branch detected: %s", defaultBranch)
-
-						// Try fetching the actual default branch
-						if err := s.operations.FetchBranchFast(repo.Path, defaultBranch); err != nil {
-							logger.Warnf("⚠️  Failed to fetch remote default branch '%s': %v", defaultBranch, err)
-						} else {
-							logger.Infof("✅ Successfully fetched default branch '%s'", defaultBranch)
-							// Update the repository's default branch if it was detected differently
-							repo.DefaultBranch = defaultBranch
-							if err := s.stateManager.AddRepository(repo); err != nil {
-								logger.Warnf("⚠️ Failed to update repository default branch in state: %v", err)
-							}
+	// Check if any worktrees exist for this repo
+	if s.shouldCreateInitialWorktree(repoID) {
+		logger.Infof("🌱 Creating initial worktree for %s", repoID)
+
+		// For shallow clones or when on a non-default branch, we need to ensure
+		// the default branch is fetched before we can create a worktree from it
+		defaultBranch := repo.DefaultBranch
+
+		// Check if the default branch exists locally
+		if !s.branchExists(repo.Path, defaultBranch, false) {
+			logger.Infof("📥 Default branch '%s' not found locally, fetching from origin...", defaultBranch)
+
+			// Fetch the default branch in the background
+			// Use FetchBranchFast for speed (shallow fetch with depth=1)
+			// FetchBranchFast will also create the local branch ref from the remote tracking branch
+			if err := s.operations.FetchBranchFast(repo.Path, defaultBranch); err != nil {
+				logger.Warnf("⚠️  Failed to fetch default branch '%s': %v", defaultBranch, err)
+				logger.Infof("🔄 Attempting to determine and fetch the correct default branch from remote...")
+
+				// Try to get the actual default branch from the remote
+				if remoteBranch, err := s.operations.GetRemoteDefaultBranch(repo.Path); err == nil && remoteBranch != "" {
+					defaultBranch = remoteBranch
+					logger.Infof("🔍 Remote default branch detected: %s", defaultBranch)
+
+					// Try fetching the actual default branch
+					if err := s.operations.FetchBranchFast(repo.Path, defaultBranch); err != nil {
+						logger.Warnf("⚠️  Failed to fetch remote default branch '%s': %v", defaultBranch, err)
+					} else {
+						logger.Infof("✅ Successfully fetched default branch '%s'", defaultBranch)
+						// Update the repository's default branch if it was detected differently
+						repo.DefaultBranch = defaultBranch
+						if err := s.stateManager.AddRepository(repo); err != nil {
+							logger.Warnf("⚠️ Failed to update repository default branch in state: %v", err)
 						}
 					}
-				} else {
-					logger.Infof("✅ Successfully fetched default branch '%s'", defaultBranch)
 				}
+			} else {
+				logger.Infof("✅ Successfully fetched default branch '%s'", defaultBranch)
+			}
 
-				// If the local branch still doesn't exist (even after fetch), fall back to using any available local branch
-				// Note: A successful fetch might only update the remote tracking branch without creating the local branch
-				if !s.branchExists(repo.Path, defaultBranch, false) {
-					logger.Warnf("⚠️  Default branch '%s' not available locally, checking for available local branches...", defaultBranch)
-
-					// Get list of local branches
-					if localBranches, err := s.operations.GetLocalBranches(repo.Path); err == nil && len(localBranches) > 0 {
-						// Try common branch names in order
-						commonBranches := []string{"main", "master", "develop"}
-						fallbackBranch := ""
-
-						for _, commonBranch := range commonBranches {
-							for _, localBranch := range localBranches {
-								if localBranch == commonBranch {
-									fallbackBranch = commonBranch
-									break
-								}
-							}
-							if fallbackBranch != "" {
+			// If the local branch still doesn't exist (even after fetch), fall back to using any available local branch
+			// Note: A successful fetch might only update the remote tracking branch without creating the local branch
+			if !s.branchExists(repo.Path, defaultBranch, false) {
+				logger.Warnf("⚠️  Default branch '%s' not available locally, checking for available local branches...", defaultBranch)
+
+				// Get list of local branches
+				if localBranches, err := s.operations.GetLocalBranches(repo.Path); err == nil && len(localBranches) > 0 {
+					// Try common branch names in order
+					commonBranches := []string{"main", "master", "develop"}
+					fallbackBranch := ""
+
+					for _, commonBranch := range commonBranches {
+						for _, localBranch := range localBranches {
+							if localBranch == commonBranch {
+								fallbackBranch = commonBranch
 								break
 							}
 						}
-
-						// If no common branch found, use the first available branch
-						if fallbackBranch == "" {
-							fallbackBranch = localBranches[0]
+						if fallbackBranch != "" {
+							break
 						}
+					}
 
-						logger.Warnf("⚠️  Using fallback branch '%s' instead of configured default '%s'", fallbackBranch, defaultBranch)
-						defaultBranch = fallbackBranch
+					// If no common branch found, use the first available branch
+					if fallbackBranch == "" {
+						fallbackBranch = localBranches[0]
+					}
 
-						// Update the repository's default branch
-						repo.DefaultBranch = defaultBranch
-						if err := s.stateManager.AddRepository(repo); err != nil {
-							logger.Warnf("⚠️ Failed to update repository default branch in state: %v", err)
-						}
-					} else {
-						logger.Warnf("⚠️  No local branches found, will attempt to create worktree anyway")
+					logger.Warnf("⚠️  Using fallback branch '%s' instead of configured default '%s'", fallbackBranch, defaultBranch)
+					defaultBranch = fallbackBranch
+
+					// Update the repository's default branch
+					repo.DefaultBranch = defaultBranch
+					if err := s.stateManager.AddRepository(repo); err != nil {
+						logger.Warnf("⚠️ Failed to update repository default branch in state: %v", err)
 					}
+				} else {
+					logger.Warnf("⚠️  No local branches found, will attempt to create worktree anyway")
 				}
 			}
+		}
 
-			// Don't proactively prune during runtime - it can delete workspaces being restored
-			// Pruning should only happen on explicit user request or during shutdown
-			// if pruneErr := s.operations.PruneWorktrees(repo.Path); pruneErr != nil {
-			// 	logger.Warnf("⚠️  Failed to prune worktrees for %s: %v", repoID, pruneErr)
-			// }
+		// Don't proactively prune during runtime - it can delete workspaces being restored
+		// Pruning should only happen on explicit user request or during shutdown
+		// if pruneErr := s.operations.PruneWorktrees(repo.Path); pruneErr != nil {
+		// 	logger.Warnf("⚠️  Failed to prune worktrees for %s: %v", repoID, pruneErr)
+		// }
 
-			if _, worktree, err := s.handleLocalRepoWorktree(repoID, defaultBranch); err != nil {
-				logger.Warnf("❌ Failed to create initial worktree for %s: %v", repoID, err)
-			} else {
-				logger.Infof("✅ Initial worktree created: %s", worktree.Name)
-			}
+		if _, worktree, err := s.handleLocalRepoWorktree(repoID, defaultBranch); err != nil {
+			logger.Warnf("❌ Failed to create initial worktree for %s: %v", repoID, err)
+		} else {
+			logger.Infof("✅ Initial worktree created: %s", worktree.Name)
 		}
 	}
-
-	// Check and update any stale catnip-live remotes in existing worktrees
-	s.updateStaleRemotes()
+	return true
 }
 
 // updateStaleRemotes checks all existing worktrees for stale catnip-live remotes and updates them
@@ -1277,7 +1447,7 @@ func (s *GitService) handleLocalRepoWorktree(repoID, branch string) (*models.Rep
 	// Get the local repo from repositories map
 	localRepo, exists := s.stateManager.GetRepository(repoID)
 	if !exists {
-		return nil, nil, fmt.Errorf("local repository %s not found - it may not be mounted", repoID)
+		return nil, nil, models.NewAPIError(models.ErrCodeRepositoryNotFound, "local repository %s not found - it may not be mounted", repoID)
 	}
 
 	// If no branch specified, use repository's default branch
@@ -1308,6 +1478,8 @@ func (s *GitService) handleLocalRepoWorktree(repoID, branch string) (*models.Rep
 
 // createLocalRepoWorktree creates a worktree for any local repo
 func (s *GitService) createLocalRepoWorktree(repo *models.Repository, branch, name string) (*models.Worktree, error) {
+	journalID := s.beginWorktreeJournal(repo, name)
+
 	// Use git WorktreeManager to create the local worktree
 	worktree, err := s.gitWorktreeManager.CreateLocalWorktree(git.CreateWorktreeRequest{
 		Repository:   repo,
@@ -1316,13 +1488,17 @@ func (s *GitService) createLocalRepoWorktree(repo *models.Repository, branch, na
 		WorkspaceDir: getWorkspaceDir(),
 	})
 	if err != nil {
+		s.stateManager.CompleteOperation(journalID)
 		return nil, err
 	}
 
 	// Store worktree in service map
 	if err := s.stateManager.AddWorktree(worktree); err != nil {
 		logger.Warnf("⚠️ Failed to add worktree to state: %v", err)
+	} else {
+		s.stateManager.CompleteOperation(journalID)
 	}
+	s.applyIdentityOnCreate(worktree)
 
 	// Notify ClaudeMonitor service about the new worktree
 	if s.claudeMonitor != nil {
@@ -1334,6 +1510,11 @@ func (s *GitService) createLocalRepoWorktree(repo *models.Repository, branch, na
 		_ = s.updateCurrentSymlink(worktree.Path)
 	}
 
+	// Copy dotfiles like .tool-versions in before setup.sh needs them
+	if s.dotfiles != nil {
+		s.dotfiles.ApplyToWorktree(worktree.Path)
+	}
+
 	// Execute setup.sh if it exists in the newly created worktree
 	if s.setupExecutor != nil {
 		logger.Infof("🚀 Scheduling setup.sh execution for local worktree: %s", worktree.Path)
@@ -1363,6 +1544,17 @@ func (s *GitService) GetRepositoryBranches(repoID string) ([]string, error) {
 
 	repo, exists := s.stateManager.GetRepository(repoID)
 	if !exists {
+		// GitLab and Bitbucket repos list branches through their API, which
+		// authenticates with the configured token
+		if provider, ownerRepo, ok := s.hostingManager.ForRepoID(repoID); ok {
+			branches, err := provider.ListBranches(ownerRepo)
+			if err == nil {
+				return branches, nil
+			}
+			logger.Warnf("⚠️ Failed to list %s branches through the %s API, falling back to ls-remote: %v", repoID, provider.Name(), err)
+			return s.operations.GetRemoteBranchesFromURL(provider.CloneURL(ownerRepo))
+		}
+
 		// For remote GitHub repos that haven't been checked out yet,
 		// we can still fetch branches using git ls-remote
 		if !strings.HasPrefix(repoID, "local/") {
@@ -1370,7 +1562,7 @@ func (s *GitService) GetRepositoryBranches(repoID string) ([]string, error) {
 			remoteURL := fmt.Sprintf("https://github.com/%s.git", repoID)
 			return s.operations.GetRemoteBranchesFromURL(remoteURL)
 		}
-		return nil, fmt.Errorf("repository %s not found", repoID)
+		return nil, models.NewRepositoryNotFoundError(repoID)
 	}
 
 	// Check if repository is available for local repos only
@@ -1409,30 +1601,29 @@ func (s *GitService) GetRepositoryBranches(repoID string) ([]string, error) {
 	return s.operations.GetRemoteBranches(repo.Path, repo.DefaultBranch)
 }
 
-// DeleteWorktree removes a worktree and returns a channel that signals when cleanup is complete
-// Callers can ignore the channel for async behavior, or wait on it for sync behavior
-func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
+// detachWorktree removes a worktree from state, caches and sessions so the UI
+// updates immediately; its git metadata and directory are removed afterwards
+func (s *GitService) detachWorktree(worktreeID string) (*models.Worktree, *models.Repository, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	if !exists {
-		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, nil, models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Get repository for worktree deletion
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
-		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+		return nil, nil, models.NewRepositoryNotFoundError(worktree.RepoID)
 	}
 
 	// SAFETY CHECK: Refuse to delete worktrees outside our managed workspace directory
 	// This protects against accidentally deleting external repository paths
 	// Exception: Allow deletion during tests (temp directories on Linux/macOS)
 	workspaceDir := config.Runtime.WorkspaceDir
-	isTestPath := s.isTemporaryPath(worktree.Path)
-	if workspaceDir != "" && !strings.HasPrefix(worktree.Path, workspaceDir+"/") && !isTestPath {
-		return nil, fmt.Errorf("cannot delete worktree %s: path %s is outside managed workspace directory %s", worktree.Name, worktree.Path, workspaceDir)
+	if workspaceDir != "" && !strings.HasPrefix(worktree.Path, workspaceDir+"/") && !s.isTemporaryPath(worktree.Path) {
+		return nil, nil, fmt.Errorf("cannot delete worktree %s: path %s is outside managed workspace directory %s", worktree.Name, worktree.Path, workspaceDir)
 	}
 
 	// Clean up any active PTY sessions for this worktree (service-specific)
@@ -1451,16 +1642,31 @@ func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
 		s.claudeMonitor.OnWorktreeDeleted(worktreeID, worktree.Path)
 	}
 
+	if s.reviews != nil {
+		if err := s.reviews.Forget(worktreeID); err != nil {
+			logger.Warnf("⚠️ Failed to clear review marks for worktree %s: %v", worktree.Name, err)
+		}
+	}
+
+	return worktree, repo, nil
+}
+
+// DeleteWorktree removes a worktree and returns a channel that signals when cleanup is complete
+// Callers can ignore the channel for async behavior, or wait on it for sync behavior
+func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
+	worktree, repo, err := s.detachWorktree(worktreeID)
+	if err != nil {
+		return nil, err
+	}
 	// Create a channel to signal completion
 	done := make(chan error, 1)
 
 	// For test environments, run cleanup synchronously to avoid hanging in CI
-	// Note: isTestPath was already declared above for the safety check
-	if isTestPath {
+	if s.isTemporaryPath(worktree.Path) {
 		logger.Debugf("🧪 Running synchronous cleanup for test worktree %s", worktree.Name)
 		cleanupStart := time.Now()
 
-		if err := s.gitWorktreeManager.DeleteWorktree(worktree, repo); err != nil {
+		if err := s.deleteWorktreeFromGit(worktree, repo); err != nil {
 			logger.Warnf("⚠️ Synchronous git cleanup failed for worktree %s: %v", worktree.Name, err)
 			done <- err
 		} else {
@@ -1475,7 +1681,7 @@ func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
 			logger.Debugf("🗑️ Starting background git cleanup for worktree %s", worktree.Name)
 			cleanupStart := time.Now()
 
-			if err := s.gitWorktreeManager.DeleteWorktree(worktree, repo); err != nil {
+			if err := s.deleteWorktreeFromGit(worktree, repo); err != nil {
 				logger.Warnf("⚠️ Background git cleanup failed for worktree %s: %v", worktree.Name, err)
 				done <- err
 			} else {
@@ -1493,6 +1699,37 @@ func (s *GitService) DeleteWorktree(worktreeID string) (<-chan error, error) {
 	return done, nil
 }
 
+// deleteWorktreeFromGit removes a worktree's git metadata and directory while
+// holding the repository's operation slot, so it can't race a concurrent
+// worktree creation on the same bare repository. A preview deployment is torn
+// down first, while its teardown script still exists.
+func (s *GitService) deleteWorktreeFromGit(worktree *models.Worktree, repo *models.Repository) error {
+	if s.previews != nil {
+		s.previews.TeardownDeleted(worktree)
+	}
+	release := s.acquireRepoSlot(repo.ID, repoOpDelete)
+	defer release()
+	s.dropSandbox(worktree)
+	return s.gitWorktreeManager.DeleteWorktree(worktree, repo)
+}
+
+// acquireRepoSlot blocks until an operation may run on the repository and
+// returns the function that releases the slot. Take the slot before s.mu.
+func (s *GitService) acquireRepoSlot(repoID, caller string) func() {
+	release, _ := s.repoLimiter.Acquire(context.Background(), repoID, caller)
+	return release
+}
+
+// GetRepoQueueMetrics reports per-repository operation queueing
+func (s *GitService) GetRepoQueueMetrics() []RepoQueueMetrics {
+	return s.repoLimiter.Metrics()
+}
+
+// RepoLimiter returns the limiter that bounds operations per repository
+func (s *GitService) RepoLimiter() *RepoOperationLimiter {
+	return s.repoLimiter
+}
+
 // UpdateWorktreeBranchName updates the stored branch name for a worktree after a git branch rename
 func (s *GitService) UpdateWorktreeBranchName(worktreePath, newBranchName string) error {
 	s.mu.Lock()
@@ -1508,7 +1745,7 @@ func (s *GitService) UpdateWorktreeBranchName(worktreePath, newBranchName string
 	}
 
 	if targetWorktree == nil {
-		return fmt.Errorf("worktree not found for path: %s", worktreePath)
+		return models.NewAPIError(models.ErrCodeWorktreeNotFound, "worktree not found for path: %s", worktreePath)
 	}
 
 	// Update the branch name
@@ -1528,8 +1765,9 @@ func (s *GitService) UpdateWorktreeBranchName(worktreePath, newBranchName string
 	return nil
 }
 
-// CleanupMergedWorktrees removes worktrees that have been fully merged into their source branch
-func (s *GitService) CleanupMergedWorktrees() (int, []string, error) {
+// CleanupMergedWorktrees removes worktrees that have been fully merged into their
+// source branch, limited to those matching the label selector
+func (s *GitService) CleanupMergedWorktrees(selector LabelSelector) (int, []string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1542,6 +1780,11 @@ func (s *GitService) CleanupMergedWorktrees() (int, []string, error) {
 		logger.Debugf("🔍 Checking worktree %s: dirty=%v, conflicts=%v, commits_ahead=%d, source=%s",
 			worktree.Name, worktree.IsDirty, worktree.HasConflicts, worktree.CommitCount, worktree.SourceBranch)
 
+		if !selector.Matches(worktree.Labels) {
+			logger.Debugf("⏭️  Skipping cleanup of %s: labels %v don't match %q", worktree.Name, worktree.Labels, selector.String())
+			continue
+		}
+
 		// Skip if worktree has uncommitted changes or conflicts
 		if worktree.IsDirty {
 			logger.Warnf("⏭️  Skipping cleanup of dirty worktree: %s", worktree.Name)
@@ -1701,24 +1944,9 @@ func (s *GitService) fetchLatestReferenceWithDepth(worktree *models.Worktree, sh
 		// Local repos: No fetching needed since worktrees share the same .git repository
 		// The source branch is already available locally
 		return
-	} else {
-		// Remote repos: use shallow or full fetch based on need
-		if shallow {
-			_ = s.fetchBranchFast(worktree.Path, worktree.SourceBranch)
-		} else {
-			_ = s.fetchBranchFull(worktree.Path, worktree.SourceBranch)
-		}
 	}
-}
-
-// fetchBranchFast performs a highly optimized fetch for status updates
-func (s *GitService) fetchBranchFast(repoPath, branch string) error {
-	return s.operations.FetchBranchFast(repoPath, branch)
-}
-
-// fetchBranchFull performs a full fetch for operations that need complete history
-func (s *GitService) fetchBranchFull(repoPath, branch string) error {
-	return s.operations.FetchBranchFull(repoPath, branch)
+	// Remote repos: depth and filters adapt to the repository's size and past fetch durations
+	_ = s.adaptiveFetch(worktree, shallow)
 }
 
 // These fetchLocalBranch functions have been removed as they used the deprecated "live" remote approach.
@@ -1731,39 +1959,88 @@ func (s *GitService) SyncWorktree(worktreeID string, strategy string) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("worktree %s not found", worktreeID)
+		return models.NewWorktreeNotFoundError(worktreeID)
 	}
 
+	release := s.acquireRepoSlot(worktree.RepoID, repoOpSync)
+	defer release()
+
 	return s.syncWorktreeInternal(worktree, strategy)
 }
 
+// FetchWorktree fetches a worktree's source branch from origin and refreshes
+// its ahead/behind status
+func (s *GitService) FetchWorktree(worktreeID string) error {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if s.isLocalRepo(worktree.RepoID) {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has no remote to fetch from", worktree.Name)
+	}
+
+	release := s.acquireRepoSlot(worktree.RepoID, repoOpFetch)
+	defer release()
+
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", worktree.SourceBranch, worktree.SourceBranch)
+	if output, err := s.runGitWithProgress(worktree.RepoID, worktree.ID, repoOpFetch, worktree.Path, "fetch", "--progress", "origin", refspec); err != nil {
+		return gitCommandError(fmt.Errorf("failed to fetch %s: %w\n%s", worktree.SourceBranch, err, strings.TrimSpace(string(output))))
+	}
+
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, func(w *models.Worktree) string {
+		return fmt.Sprintf("origin/%s", w.SourceBranch)
+	})
+	return nil
+}
+
 // syncWorktreeInternal consolidated sync logic for both local and regular repos
 func (s *GitService) syncWorktreeInternal(worktree *models.Worktree, strategy string) error {
-	// Ensure we have full history for sync operations
-	s.fetchFullHistory(worktree)
+	// Ensure we have full history for sync operations, streaming fetch progress
+	if !s.isLocalRepo(worktree.RepoID) {
+		refspec := fmt.Sprintf("+refs/heads/%s:refs/remotes/origin/%s", worktree.SourceBranch, worktree.SourceBranch)
+		if _, err := s.runGitWithProgress(worktree.RepoID, worktree.ID, repoOpSync, worktree.Path, "fetch", "--progress", "origin", refspec); err != nil {
+			logger.Debugf("⚠️ Failed to fetch %s before sync: %v", worktree.SourceBranch, err)
+		}
+	}
 
 	// Get the appropriate source reference (fetch already done by fetchFullHistory)
 	sourceRef := s.getSourceRef(worktree)
 
+	// Remember HEAD so we can tell whether the sync touched setup-relevant files
+	beforeHead, _ := s.operations.GetCommitHash(worktree.Path, "HEAD")
+
+	// Record where to roll back to, setting aside uncommitted changes
+	snapshot, err := s.prepareSyncUndo(worktree, strategy, sourceRef, beforeHead)
+	if err != nil {
+		return err
+	}
+
 	// Apply the sync strategy
-	if err := s.applySyncStrategy(worktree, strategy, sourceRef); err != nil {
+	err = s.applySyncStrategy(worktree, strategy, sourceRef)
+	s.finishSyncUndo(worktree, snapshot, err)
+	if err != nil {
 		return err
 	}
 
 	// Update worktree status (no need to fetch since we already did fetchFullHistory)
-	getSourceRef := func(w *models.Worktree) string {
-		if s.isLocalRepo(w.RepoID) {
-			return w.SourceBranch // Local repos use branch directly
-		} else {
-			return fmt.Sprintf("origin/%s", w.SourceBranch) // Remote repos use origin prefix
-		}
-	}
-	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, getSourceRef)
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, s.statusSourceRef)
+	s.checkSetupDrift(worktree, beforeHead)
 
 	logger.Infof("✅ Synced worktree %s with %s strategy", worktree.Name, strategy)
 	return nil
 }
 
+// statusSourceRef is the ref ahead/behind counts are computed against
+func (s *GitService) statusSourceRef(w *models.Worktree) string {
+	if s.isLocalRepo(w.RepoID) {
+		return w.SourceBranch // Local repos use branch directly
+	}
+	return fmt.Sprintf("origin/%s", w.SourceBranch) // Remote repos use origin prefix
+}
+
 // applySyncStrategy applies merge or rebase strategy
 func (s *GitService) applySyncStrategy(worktree *models.Worktree, strategy, sourceRef string) error {
 	var err error
@@ -1774,20 +2051,20 @@ func (s *GitService) applySyncStrategy(worktree *models.Worktree, strategy, sour
 	case "rebase":
 		err = s.operations.Rebase(worktree.Path, sourceRef)
 	default:
-		return fmt.Errorf("unknown sync strategy: %s", strategy)
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "unknown sync strategy: %s", strategy)
 	}
 
 	if err != nil {
 		// Check if this is an uncommitted changes error (not a conflict)
 		if s.isUncommittedChangesError(err.Error()) {
-			return fmt.Errorf("cannot %s: worktree has staged changes. Please commit or unstage your changes first", strategy)
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "cannot %s: worktree has staged changes. Please commit or unstage your changes first", strategy)
 		}
 
 		// Check if this is a merge conflict
 		if s.isMergeConflict(worktree.Path, err.Error()) {
 			return s.createMergeConflictError("sync", worktree, err.Error())
 		}
-		return fmt.Errorf("failed to %s: %v", strategy, err)
+		return gitCommandError(fmt.Errorf("failed to %s: %w", strategy, err))
 	}
 
 	return nil
@@ -1800,7 +2077,7 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("worktree %s not found", worktreeID)
+		return models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Only works for local repos
@@ -1808,10 +2085,13 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 		return fmt.Errorf("merge to main only supported for local repositories")
 	}
 
+	release := s.acquireRepoSlot(worktree.RepoID, repoOpMerge)
+	defer release()
+
 	// Get the local repo
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
-		return fmt.Errorf("local repository %s not found", worktree.RepoID)
+		return models.NewAPIError(models.ErrCodeRepositoryNotFound, "local repository %s not found", worktree.RepoID)
 	}
 
 	logger.Infof("🔄 Merging worktree %s back to main repository", worktree.Name)
@@ -1822,13 +2102,13 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 	// First, push the worktree branch to the main repo
 	output, err := s.runGitCommand(worktree.Path, "push", repo.Path, fmt.Sprintf("%s:%s", worktree.Branch, worktree.Branch))
 	if err != nil {
-		return fmt.Errorf("failed to push worktree branch to main repo: %v\n%s", err, output)
+		return gitCommandError(fmt.Errorf("failed to push worktree branch to main repo: %w\n%s", err, output))
 	}
 
 	// Switch to the source branch in main repo and merge
 	output, err = s.runGitCommand(repo.Path, "checkout", worktree.SourceBranch)
 	if err != nil {
-		return fmt.Errorf("failed to checkout source branch in main repo: %v\n%s", err, output)
+		return gitCommandError(fmt.Errorf("failed to checkout source branch in main repo: %w\n%s", err, output))
 	}
 
 	// Merge the worktree branch
@@ -1844,14 +2124,14 @@ func (s *GitService) MergeWorktreeToMain(worktreeID string, squash bool) error {
 		if s.isMergeConflict(repo.Path, string(output)) {
 			return s.createMergeConflictError("merge", worktree, string(output))
 		}
-		return fmt.Errorf("failed to merge worktree branch: %v\n%s", err, output)
+		return gitCommandError(fmt.Errorf("failed to merge worktree branch: %w\n%s", err, output))
 	}
 
 	// For squash merges, we need to commit the staged changes
 	if squash {
 		_, err = s.runGitCommitWithGPGFallback(repo.Path, "commit", "-m", fmt.Sprintf("Squash merge branch '%s' from worktree", worktree.Branch))
 		if err != nil {
-			return fmt.Errorf("failed to commit squash merge: %v", err)
+			return gitCommandError(fmt.Errorf("failed to commit squash merge: %w", err))
 		}
 	}
 
@@ -1880,7 +2160,7 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("worktree %s not found", worktreeID)
+		return models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Only works for local repos
@@ -1891,7 +2171,7 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 	// Get the local repo
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
-		return fmt.Errorf("local repository %s not found", worktree.RepoID)
+		return models.NewAPIError(models.ErrCodeRepositoryNotFound, "local repository %s not found", worktree.RepoID)
 	}
 
 	// Check if repository is available
@@ -1899,7 +2179,7 @@ func (s *GitService) CreateWorktreePreview(worktreeID string) error {
 		return fmt.Errorf("repository %s is not available", worktree.RepoID)
 	}
 
-	previewBranchName := fmt.Sprintf("catnip/%s", git.ExtractWorkspaceName(worktree.Branch))
+	previewBranchName := PreviewBranchName(worktree.Branch)
 	logger.Debugf("🔍 Creating preview branch %s for worktree %s", previewBranchName, worktree.Name)
 
 	// Check if there are uncommitted changes (staged, unstaged, or untracked)
@@ -2040,7 +2320,7 @@ func (s *GitService) CheckSyncConflicts(worktreeID string) (*models.MergeConflic
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Ensure we have full history for accurate conflict detection
@@ -2059,7 +2339,7 @@ func (s *GitService) CheckMergeConflicts(worktreeID string) (*models.MergeConfli
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Only works for local repos
@@ -2070,7 +2350,7 @@ func (s *GitService) CheckMergeConflicts(worktreeID string) (*models.MergeConfli
 	// Get the local repo
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
-		return nil, fmt.Errorf("local repository %s not found", worktree.RepoID)
+		return nil, models.NewAPIError(models.ErrCodeRepositoryNotFound, "local repository %s not found", worktree.RepoID)
 	}
 
 	return s.conflictResolver.CheckMergeConflicts(repo.Path, worktree.Path, worktree.Branch, worktree.SourceBranch, worktree.Name)
@@ -2122,7 +2402,7 @@ func (s *GitService) RefreshWorktreeStatus(workDir string) error {
 		}
 	}
 
-	return fmt.Errorf("worktree not found for path: %s", workDir)
+	return models.NewAPIError(models.ErrCodeWorktreeNotFound, "worktree not found for path: %s", workDir)
 }
 
 // GitAddCommitGetHash performs git add, commit, and returns the commit hash
@@ -2144,8 +2424,9 @@ func (s *GitService) GitAddCommitGetHash(workspaceDir, message string) (string,
 		return "", nil
 	}
 
-	// Commit with the message (with GPG error handling)
-	if _, err := s.runGitCommitWithGPGFallback(workspaceDir, "commit", "-m", message, "-n"); err != nil {
+	// Commit with the message (with GPG error handling), authored by the bot identity if configured
+	commitArgs := append(s.automatedCommitArgs(workspaceDir), "commit", "-m", message, "-n")
+	if _, err := s.runGitCommitWithGPGFallback(workspaceDir, commitArgs...); err != nil {
 		return "", fmt.Errorf("git commit failed: %v", err)
 	}
 
@@ -2238,11 +2519,14 @@ func (s *GitService) createWorktreeForExistingRepo(repo *models.Repository, bran
 	}
 
 	// Always fetch the latest state for checkout operations (full history)
-	logger.Infof("🔄 Fetching latest state for branch %s", branch)
-	if err := s.fetchBranch(repo.Path, git.FetchStrategy{
-		Branch:         branch,
-		UpdateLocalRef: true,
-	}); err != nil {
+	mirrorSyncedAt, err := s.fetchCheckoutBranch(repo.ID, repo.Path, branch, func() error {
+		logger.Infof("🔄 Fetching latest state for branch %s", branch)
+		return s.fetchBranch(repo.Path, git.FetchStrategy{
+			Branch:         branch,
+			UpdateLocalRef: true,
+		})
+	})
+	if err != nil {
 		// If fetch fails, check if branch exists locally and proceed if so
 		if !s.branchExists(repo.Path, branch, true) {
 			return nil, nil, fmt.Errorf("failed to fetch branch %s: %v", branch, err)
@@ -2257,6 +2541,9 @@ func (s *GitService) createWorktreeForExistingRepo(repo *models.Repository, bran
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create worktree: %v", err)
 	}
+	if mirrorSyncedAt != nil {
+		s.markCreatedFromMirror(worktree, *mirrorSyncedAt)
+	}
 
 	// Save state
 	// State persistence handled by state manager
@@ -2272,6 +2559,9 @@ func (s *GitService) createWorktreeInternalForRepo(repo *models.Repository, sour
 
 // createWorktreeInternalForRepoWithOptions creates a worktree with option to skip Claude cleanup (for restoration)
 func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repository, source, name string, isInitial bool, shouldCleanupClaude bool) (*models.Worktree, error) {
+	// Journal the git + state steps so a crash in between can be repaired on startup
+	journalID := s.beginWorktreeJournal(repo, name)
+
 	// Use git WorktreeManager to create the worktree
 	worktree, err := s.gitWorktreeManager.CreateWorktree(git.CreateWorktreeRequest{
 		Repository:   repo,
@@ -2281,6 +2571,7 @@ func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repos
 		IsInitial:    isInitial,
 	})
 	if err != nil {
+		s.stateManager.CompleteOperation(journalID)
 		// Check if the error is because branch already exists or worktree registration conflict
 		if strings.Contains(err.Error(), "already exists") {
 			logger.Warnf("⚠️  Branch %s already exists, trying a new name...", name)
@@ -2314,7 +2605,10 @@ func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repos
 	// Store worktree in service map
 	if err := s.stateManager.AddWorktree(worktree); err != nil {
 		logger.Warnf("⚠️ Failed to add worktree to state: %v", err)
+	} else {
+		s.stateManager.CompleteOperation(journalID)
 	}
+	s.applyIdentityOnCreate(worktree)
 
 	// Add to cache and start watching
 	s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
@@ -2334,6 +2628,11 @@ func (s *GitService) createWorktreeInternalForRepoWithOptions(repo *models.Repos
 		_ = s.updateCurrentSymlink(worktree.Path)
 	}
 
+	// Copy dotfiles like .tool-versions in before setup.sh needs them
+	if s.dotfiles != nil {
+		s.dotfiles.ApplyToWorktree(worktree.Path)
+	}
+
 	// Execute setup.sh if it exists in the newly created worktree
 	if s.setupExecutor != nil {
 		logger.Infof("🚀 Scheduling setup.sh execution for worktree: %s", worktree.Path)
@@ -2388,12 +2687,35 @@ func (s *GitService) ListRepositories() []*models.Repository {
 
 // GetWorktreeDiff returns the diff for a worktree against its source branch
 func (s *GitService) GetWorktreeDiff(worktreeID string) (*git.WorktreeDiffResponse, error) {
+	return s.GetWorktreeDiffForReviewer(worktreeID, models.DefaultReviewer)
+}
+
+// WorktreeDiffOptions controls what GetWorktreeDiffWithOptions returns
+type WorktreeDiffOptions struct {
+	// Reviewer whose review marks to include
+	Reviewer string
+	// Keep files matched by diff ignore rules in the diff, labeled, instead
+	// of listing them separately
+	IncludeIgnored bool
+	// Map the changed files to the tests likely affected by them
+	TestImpact bool
+}
+
+// GetWorktreeDiffForReviewer returns the worktree diff annotated with the
+// given reviewer's file review marks
+func (s *GitService) GetWorktreeDiffForReviewer(worktreeID, reviewer string) (*git.WorktreeDiffResponse, error) {
+	return s.GetWorktreeDiffWithOptions(worktreeID, WorktreeDiffOptions{Reviewer: reviewer})
+}
+
+// GetWorktreeDiffWithOptions returns the worktree diff with ignored files
+// split out and annotated with a reviewer's file review marks
+func (s *GitService) GetWorktreeDiffWithOptions(worktreeID string, opts WorktreeDiffOptions) (*git.WorktreeDiffResponse, error) {
 	s.mu.RLock()
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("worktree not found: %s", worktreeID)
+		return nil, models.NewAPIError(models.ErrCodeWorktreeNotFound, "worktree not found: %s", worktreeID)
 	}
 
 	// Get source reference and delegate to WorktreeManager
@@ -2411,6 +2733,39 @@ func (s *GitService) GetWorktreeDiff(worktreeID string) (*git.WorktreeDiffRespon
 	}
 
 	// Set the worktreeID since git WorktreeManager doesn't have access to it
+	result.WorktreeID = worktreeID
+
+	s.applyDiffIgnore(worktree, result, opts.IncludeIgnored)
+	if err := s.applyReviewState(worktree, result, opts.Reviewer); err != nil {
+		logger.Warnf("⚠️ Failed to load review marks for worktree %s: %v", worktree.Name, err)
+	}
+	if opts.TestImpact {
+		result.TestImpact = AnalyzeTestImpact(worktree.Path, diffChangedFiles(result))
+	}
+	return result, nil
+}
+
+// GetWorktreeCommitGraph returns the commit DAG between a worktree and its source branch
+func (s *GitService) GetWorktreeCommitGraph(worktreeID string, offset, limit int) (*git.CommitGraphResponse, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+
+	sourceRef := s.getSourceRef(worktree)
+	result, err := s.gitWorktreeManager.GetCommitGraph(worktree, sourceRef, offset, limit)
+	if err != nil {
+		// The source ref may not have been fetched yet; fetch once and retry
+		s.fetchLatestReference(worktree)
+		result, err = s.gitWorktreeManager.GetCommitGraph(worktree, sourceRef, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	result.WorktreeID = worktreeID
 	return result, nil
 }
@@ -2421,29 +2776,33 @@ func (s *GitService) CreatePullRequest(worktreeID, title, body string, forcePush
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	if !exists {
 		s.mu.RUnlock()
-		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
 		s.mu.RUnlock()
-		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
 	}
 	s.mu.RUnlock()
 
-	// Check if this is a local repository without a GitHub remote
-	if strings.HasPrefix(worktree.RepoID, "local/") && !repo.HasGitHubRemote {
+	// Check if this is a local repository without a GitHub, GitLab or Bitbucket remote
+	provider, ownerRepo, hosted := s.hostingManager.Resolve(worktree, repo)
+	if strings.HasPrefix(worktree.RepoID, "local/") && !repo.HasGitHubRemote && !hosted {
 		return nil, fmt.Errorf("NO_GITHUB_REMOTE: this local repository does not have a GitHub remote configured. Please create a GitHub repository first")
 	}
 
 	logger.Infof("🔄 Creating pull request for worktree %s", worktree.Name)
 
+	// Reference the linked issue so merging the PR closes it
+	body = withIssueReference(body, worktree.LinkedIssue)
+
 	// Check if base branch exists on remote and push if needed
 	if err := s.ensureBaseBranchOnRemote(worktree, repo); err != nil {
-		return nil, fmt.Errorf("failed to ensure base branch exists on remote: %v", err)
+		return nil, gitCommandError(fmt.Errorf("failed to ensure base branch exists on remote: %w", err))
 	}
 
-	pr, err := s.githubManager.CreatePullRequest(git.CreatePullRequestRequest{
+	req := git.CreatePullRequestRequest{
 		Worktree:         worktree,
 		Repository:       repo,
 		Title:            title,
@@ -2453,10 +2812,22 @@ func (s *GitService) CreatePullRequest(worktreeID, title, body string, forcePush
 		FetchFullHistory: s.fetchFullHistory,
 		CreateTempCommit: s.createTemporaryCommit,
 		RevertTempCommit: s.revertTemporaryCommit,
-	})
+	}
+	var pr *models.PullRequestResponse
+	var err error
+	if hosted {
+		pr, err = s.hostingManager.CreatePullRequest(provider, ownerRepo, req)
+	} else {
+		pr, err = s.githubManager.CreatePullRequest(req)
+	}
 
 	if err != nil {
-		return nil, err
+		return nil, gitCommandError(err)
+	}
+
+	// Route the new PR to the owners of the code it changes (review requests go through gh)
+	if !hosted {
+		s.requestCodeOwnerReviews(worktree, repo, pr)
 	}
 
 	// Save PR metadata to worktree state and emit events
@@ -2471,6 +2842,9 @@ func (s *GitService) CreatePullRequest(worktreeID, title, body string, forcePush
 	}
 	s.mu.Unlock()
 
+	// Attribute agent usage to the PR in the background - it reads every session log and calls gh
+	go s.attributeAgentCost(worktree, pr)
+
 	return pr, nil
 }
 
@@ -2480,24 +2854,27 @@ func (s *GitService) UpdatePullRequest(worktreeID, title, body string, forcePush
 	worktree, exists := s.stateManager.GetWorktree(worktreeID)
 	if !exists {
 		s.mu.RUnlock()
-		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
 		s.mu.RUnlock()
-		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
 	}
 	s.mu.RUnlock()
 
 	logger.Infof("🔄 Updating pull request for worktree %s", worktree.Name)
 
+	// Reference the linked issue so merging the PR closes it
+	body = withIssueReference(body, worktree.LinkedIssue)
+
 	// Check if base branch exists on remote and push if needed
 	if err := s.ensureBaseBranchOnRemote(worktree, repo); err != nil {
-		return nil, fmt.Errorf("failed to ensure base branch exists on remote: %v", err)
+		return nil, gitCommandError(fmt.Errorf("failed to ensure base branch exists on remote: %w", err))
 	}
 
-	pr, err := s.githubManager.CreatePullRequest(git.CreatePullRequestRequest{
+	req := git.CreatePullRequestRequest{
 		Worktree:         worktree,
 		Repository:       repo,
 		Title:            title,
@@ -2507,10 +2884,17 @@ func (s *GitService) UpdatePullRequest(worktreeID, title, body string, forcePush
 		FetchFullHistory: s.fetchFullHistory,
 		CreateTempCommit: s.createTemporaryCommit,
 		RevertTempCommit: s.revertTemporaryCommit,
-	})
+	}
+	var pr *models.PullRequestResponse
+	var err error
+	if provider, ownerRepo, hosted := s.hostingManager.Resolve(worktree, repo); hosted {
+		pr, err = s.hostingManager.CreatePullRequest(provider, ownerRepo, req)
+	} else {
+		pr, err = s.githubManager.CreatePullRequest(req)
+	}
 
 	if err != nil {
-		return nil, err
+		return nil, gitCommandError(err)
 	}
 
 	// Save PR metadata to worktree state (in case it changed) and emit events
@@ -2525,6 +2909,9 @@ func (s *GitService) UpdatePullRequest(worktreeID, title, body string, forcePush
 	}
 	s.mu.Unlock()
 
+	// Attribute agent usage to the PR in the background - it reads every session log and calls gh
+	go s.attributeAgentCost(worktree, pr)
+
 	return pr, nil
 }
 
@@ -2651,13 +3038,13 @@ func (s *GitService) GetPullRequestInfo(worktreeID string) (*models.PullRequestI
 	s.mu.RUnlock()
 
 	if !exists {
-		return nil, fmt.Errorf("worktree %s not found", worktreeID)
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Get the repository
 	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
 	if !exists {
-		return nil, fmt.Errorf("repository %s not found", worktree.RepoID)
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
 	}
 
 	// Check if branch has commits ahead of the base branch
@@ -2673,10 +3060,15 @@ func (s *GitService) GetPullRequestInfo(worktreeID string) (*models.PullRequestI
 		Exists:          false,
 	}
 
-	// GitHubManager handles URL parsing and PR checking internally
-
-	// Get PR info from GitHub manager (already handles checking existing PR)
-	if ghPrInfo, err := s.githubManager.GetPullRequestInfo(worktree, repo); err != nil {
+	// GitLab and Bitbucket repos are checked through their API, GitHub ones
+	// through gh (the managers handle URL parsing and PR checking internally)
+	var ghPrInfo *models.PullRequestInfo
+	if provider, ownerRepo, hosted := s.hostingManager.Resolve(worktree, repo); hosted {
+		ghPrInfo, err = s.hostingManager.GetPullRequestInfo(provider, ownerRepo, worktree)
+	} else {
+		ghPrInfo, err = s.githubManager.GetPullRequestInfo(worktree, repo)
+	}
+	if err != nil {
 		logger.Warnf("⚠️ Could not check for existing PR: %v", err)
 	} else {
 		prInfo = ghPrInfo
@@ -2705,6 +3097,15 @@ func (s *GitService) GetPullRequestInfo(worktreeID string) (*models.PullRequestI
 		}
 	}
 
+	// Preview the code owners review will be requested from
+	if !prInfo.Exists {
+		if owners, err := s.codeOwnerReviewers(worktree, repo); err != nil {
+			logger.Debugf("⚠️ Could not find code owners: %v", err)
+		} else {
+			prInfo.CodeOwners = owners
+		}
+	}
+
 	return prInfo, nil
 }
 
@@ -2809,7 +3210,7 @@ func (s *GitService) RefreshWorktreeStatusByID(worktreeID string) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("worktree %s not found", worktreeID)
+		return models.NewWorktreeNotFoundError(worktreeID)
 	}
 
 	// Create a function that provides the source reference
@@ -2894,6 +3295,7 @@ func (s *GitService) CreateFromTemplate(templateID, projectName string) (*models
 	logger.Infof("🏗️ Creating project from template %s at %s", templateID, projectPath)
 
 	var cmd *exec.Cmd
+	remoteTemplate, isRemote := templates.GetRemoteTemplate(templateID)
 	switch templateID {
 	case "react-vite":
 		cmd = exec.Command("pnpm", "create", "vite", projectName, "--template", "react-ts", "--yes")
@@ -2915,7 +3317,15 @@ func (s *GitService) CreateFromTemplate(templateID, projectName string) (*models
 			return nil, nil, fmt.Errorf("failed to create project directory: %v", err)
 		}
 	default:
-		return nil, nil, fmt.Errorf("unsupported template: %s", templateID)
+		if !isRemote {
+			return nil, nil, fmt.Errorf("unsupported template: %s", templateID)
+		}
+		if err := os.MkdirAll(projectPath, 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create project directory: %v", err)
+		}
+		if err := templates.CopyRemoteTemplate(remoteTemplate, projectPath); err != nil {
+			return nil, nil, fmt.Errorf("failed to copy template files: %v", err)
+		}
 	}
 
 	// Execute the creation command if one was set
@@ -3034,9 +3444,24 @@ func (s *GitService) CreateFromTemplate(templateID, projectName string) (*models
 // RecreateWorktree implements the WorktreeRestorer interface
 // This method manually restores worktrees by leveraging existing git metadata
 // instead of using `git worktree add` which fails due to registration conflicts
-func (s *GitService) RecreateWorktree(worktree *models.Worktree, repo *models.Repository) error {
+func (s *GitService) RecreateWorktree(worktree *models.Worktree, repo *models.Repository) (err error) {
 	logger.Infof("🔄 Manually restoring worktree %s at %s (from repo %s)", worktree.Name, worktree.Path, repo.Path)
 
+	// Hold a keep marker on the workspace ref while restoring so cleanup can't
+	// delete it mid-restore. A failed restore leaves the marker, keeping the
+	// ref and its commits around for manual recovery.
+	nameParts := strings.Split(worktree.Name, "/")
+	catnipRef := "refs/catnip/" + nameParts[len(nameParts)-1]
+	if s.KeepCatnipRef(repo.Path, catnipRef) == nil {
+		defer func() {
+			if err == nil {
+				_ = s.ReleaseCatnipRef(repo.Path, catnipRef)
+			} else {
+				logger.Warnf("🔒 Keeping %s in %s after the failed restore of %s", catnipRef, repo.Path, worktree.Name)
+			}
+		}()
+	}
+
 	// Step 1: Create the workspace directory
 	if err := os.MkdirAll(worktree.Path, 0755); err != nil {
 		logger.Warnf("❌ Failed to create workspace directory %s: %v", worktree.Path, err)
@@ -3186,11 +3611,32 @@ func (s *GitService) RecreateWorktree(worktree *models.Worktree, repo *models.Re
 	return nil
 }
 
-// RestoreState restores worktree state from persistent storage
+// RestoreState restores worktree state from persistent storage, first repairing
+// any operations the journal shows were interrupted by a crash
 func (s *GitService) RestoreState() error {
+	s.stateManager.RecoverJournal(s.operations)
 	return s.stateManager.RestoreState()
 }
 
+// GetRecoveryReport returns what the startup journal recovery fixed, plus any operations still in flight
+func (s *GitService) GetRecoveryReport() ([]RecoveryAction, []JournalEntry) {
+	return s.stateManager.LastRecovery(), s.stateManager.PendingOperations()
+}
+
+// beginWorktreeJournal records a worktree creation in the state journal
+func (s *GitService) beginWorktreeJournal(repo *models.Repository, branch string) string {
+	id, err := s.stateManager.BeginOperation(JournalEntry{
+		Op:       JournalCreateWorktree,
+		RepoID:   repo.ID,
+		RepoPath: repo.Path,
+		Branch:   branch,
+	})
+	if err != nil {
+		logger.Warnf("⚠️ Failed to journal worktree creation: %v", err)
+	}
+	return id
+}
+
 // CreateGitHubRepositoryAndSetOrigin creates a GitHub repository and sets it as origin for a local repo
 func (s *GitService) CreateGitHubRepositoryAndSetOrigin(repoID, name, description string, isPrivate bool) (string, error) {
 	logger.Infof("🔍 Looking up repository with ID: '%s'", repoID)
@@ -3210,7 +3656,7 @@ func (s *GitService) CreateGitHubRepositoryAndSetOrigin(repoID, name, descriptio
 			logger.Infof("  - '%s'", id)
 		}
 
-		return "", fmt.Errorf("repository %s not found", repoID)
+		return "", models.NewRepositoryNotFoundError(repoID)
 	}
 	s.mu.RUnlock()
 
@@ -3271,13 +3717,17 @@ func (s *GitService) CreateGitHubRepositoryAndSetOrigin(repoID, name, descriptio
 func (s *GitService) DeleteRepository(repoID string) error {
 	logger.Infof("🗑️  Delete repository request: %s", repoID)
 
+	// Wait for in-flight operations on the repository before removing it
+	release := s.acquireRepoSlot(repoID, repoOpRemove)
+	defer release()
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Get the repository
 	repo, exists := s.stateManager.GetRepository(repoID)
 	if !exists {
-		return fmt.Errorf("repository not found: %s", repoID)
+		return models.NewAPIError(models.ErrCodeRepositoryNotFound, "repository not found: %s", repoID)
 	}
 
 	// Get all worktrees for this repository
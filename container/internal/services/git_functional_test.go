@@ -441,7 +441,7 @@ func TestGitServiceCleanupOperationsFunctional(t *testing.T) {
 
 	t.Run("CleanupMergedWorktrees", func(t *testing.T) {
 		// Should not error even with no worktrees
-		_, _, err := service.CleanupMergedWorktrees()
+		_, _, err := service.CleanupMergedWorktrees(LabelSelector{})
 		assert.NoError(t, err)
 
 		// Add some test worktrees
@@ -462,7 +462,7 @@ func TestGitServiceCleanupOperationsFunctional(t *testing.T) {
 		_ = service.stateManager.AddWorktree(worktree2)
 
 		// Should not error with worktrees (though cleanup may not work without real git repos)
-		_, _, err = service.CleanupMergedWorktrees()
+		_, _, err = service.CleanupMergedWorktrees(LabelSelector{})
 		assert.NoError(t, err)
 	})
 
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// SetGitHubApp sets the GitHub App used by repositories in app auth mode.
+// gh commands for those repositories run with the app's installation token,
+// and git credentials go through `catnip git-credential` once the app is
+// configured.
+func (s *GitService) SetGitHubApp(app *GitHubAppService) {
+	s.mu.Lock()
+	s.githubApp = app
+	s.mu.Unlock()
+
+	s.githubManager.SetTokenSource(s.githubAppTokenSource)
+	if app.Configured() && config.Runtime.IsContainerized() {
+		s.EnableGitHubAppCredentials()
+	}
+}
+
+// EnableGitHubAppCredentials points git's GitHub credential helper at catnip
+func (s *GitService) EnableGitHubAppCredentials() {
+	if err := s.githubManager.ConfigureAppCredentials(); err != nil {
+		logger.Warnf("❌ Failed to configure GitHub App credential helper: %v", err)
+	} else {
+		logger.Infof("✅ Git credentials for GitHub go through catnip (GitHub App repositories use installation tokens)")
+	}
+}
+
+// normalizeOwnerRepo turns "/owner/repo.git" (as git passes it) into "owner/repo"
+func normalizeOwnerRepo(ownerRepo string) string {
+	return strings.TrimSuffix(strings.Trim(ownerRepo, "/"), ".git")
+}
+
+// repositoryForGitHub finds the repository whose ID or origin is ownerRepo
+func (s *GitService) repositoryForGitHub(ownerRepo string) *models.Repository {
+	ownerRepo = normalizeOwnerRepo(ownerRepo)
+	for _, repo := range s.stateManager.GetAllRepositories() {
+		if strings.EqualFold(repo.ID, ownerRepo) {
+			return repo
+		}
+		origin := strings.TrimSuffix(repo.RemoteOrigin, ".git")
+		if strings.HasSuffix(strings.ToLower(origin), "github.com/"+strings.ToLower(ownerRepo)) ||
+			strings.HasSuffix(strings.ToLower(origin), "github.com:"+strings.ToLower(ownerRepo)) {
+			return repo
+		}
+	}
+	return nil
+}
+
+// GitHubAppTokenFor returns an installation token for a repository in app
+// auth mode. Repositories using the gh login get an error.
+func (s *GitService) GitHubAppTokenFor(ownerRepo string) (GitHubAppToken, error) {
+	s.mu.RLock()
+	app := s.githubApp
+	s.mu.RUnlock()
+
+	repo := s.repositoryForGitHub(ownerRepo)
+	if repo == nil {
+		return GitHubAppToken{}, models.NewRepositoryNotFoundError(ownerRepo)
+	}
+	if repo.GitHubAuth != models.GitHubAuthApp || app == nil {
+		return GitHubAppToken{}, models.NewAPIError(models.ErrCodeInvalidRequest, "repository %s uses the gh login, not the GitHub App", repo.ID)
+	}
+	return app.InstallationToken(normalizeOwnerRepo(ownerRepo))
+}
+
+// githubAppTokenSource supplies gh commands with app tokens for repositories
+// in app mode; other repositories keep using the gh login
+func (s *GitService) githubAppTokenSource(ownerRepo string) (string, bool) {
+	repo := s.repositoryForGitHub(ownerRepo)
+	if repo == nil || repo.GitHubAuth != models.GitHubAuthApp {
+		return "", false
+	}
+	token, err := s.GitHubAppTokenFor(ownerRepo)
+	if err != nil {
+		logger.Warnf("⚠️ Falling back to the gh login for %s: %v", ownerRepo, err)
+		return "", false
+	}
+	return token.Token, true
+}
+
+// SetRepositoryGitHubAuth selects how a repository authenticates to GitHub
+func (s *GitService) SetRepositoryGitHubAuth(repoID string, mode models.GitHubAuthMode) (*models.Repository, error) {
+	switch mode {
+	case "", models.GitHubAuthUser:
+		mode = ""
+	case models.GitHubAuthApp:
+		s.mu.RLock()
+		app := s.githubApp
+		s.mu.RUnlock()
+		if app == nil || !app.Configured() {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "no GitHub App is configured").
+				WithHint("Set the app ID and private key with PUT /v1/github/app first")
+		}
+	default:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown GitHub auth mode %q (user or app)", mode)
+	}
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.GitHubAuth = mode
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return nil, fmt.Errorf("failed to save repository GitHub auth mode: %v", err)
+	}
+	if mode == models.GitHubAuthApp {
+		logger.Infof("🔐 Repository %s now authenticates to GitHub as the GitHub App", repoID)
+	} else {
+		logger.Infof("🔐 Repository %s now authenticates to GitHub with the gh login", repoID)
+	}
+	return &updated, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// EffectiveIdentity returns the identity settings that apply to a worktree:
+// the worktree's own user/bot identities, falling back to its repository's
+func (s *GitService) EffectiveIdentity(worktree *models.Worktree) *models.GitIdentitySettings {
+	result := &models.GitIdentitySettings{}
+
+	if repo, exists := s.stateManager.GetRepository(worktree.RepoID); exists && repo.Identity != nil {
+		result.User = repo.Identity.User
+		result.Bot = repo.Identity.Bot
+	}
+	if worktree.Identity != nil {
+		if worktree.Identity.User != nil {
+			result.User = worktree.Identity.User
+		}
+		if worktree.Identity.Bot != nil {
+			result.Bot = worktree.Identity.Bot
+		}
+	}
+
+	return result
+}
+
+// SetWorktreeIdentity stores identity settings for a worktree and applies them via git config.
+// A nil settings value clears the worktree override so the repository's identity applies.
+func (s *GitService) SetWorktreeIdentity(worktreeID string, settings *models.GitIdentitySettings) (*models.GitIdentitySettings, error) {
+	if err := validateIdentitySettings(settings); err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"identity": settings}); err != nil {
+		return nil, err
+	}
+	if updated, exists := s.stateManager.GetWorktree(worktreeID); exists {
+		worktree = updated
+	}
+
+	effective := s.EffectiveIdentity(worktree)
+	if err := s.applyWorktreeIdentity(worktree.Path, effective.User); err != nil {
+		return nil, err
+	}
+	return effective, nil
+}
+
+// SetRepositoryIdentity stores identity settings for a repository and re-applies
+// them to each of its worktrees that doesn't override the user identity
+func (s *GitService) SetRepositoryIdentity(repoID string, settings *models.GitIdentitySettings) error {
+	if err := validateIdentitySettings(settings); err != nil {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.Identity = settings
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return fmt.Errorf("failed to save repository identity: %v", err)
+	}
+
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID != repoID {
+			continue
+		}
+		if err := s.applyWorktreeIdentity(worktree.Path, s.EffectiveIdentity(worktree).User); err != nil {
+			logger.Warnf("⚠️ Failed to apply identity to worktree %s: %v", worktree.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyWorktreeIdentity writes user.name/user.email to the worktree-specific git
+// config so commits in one worktree don't change the identity of its siblings.
+// A nil identity removes any override so the global identity applies again.
+func (s *GitService) applyWorktreeIdentity(worktreePath string, identity *models.GitIdentity) error {
+	// Worktree-scoped config requires the extension to be enabled on the shared repo config
+	if output, err := s.runGitCommand(worktreePath, "config", "extensions.worktreeConfig", "true"); err != nil {
+		return fmt.Errorf("failed to enable worktree config: %v\nOutput: %s", err, string(output))
+	}
+
+	if identity == nil {
+		// --unset exits non-zero when the key is already absent, which is fine
+		_, _ = s.runGitCommand(worktreePath, "config", "--worktree", "--unset", "user.name")
+		_, _ = s.runGitCommand(worktreePath, "config", "--worktree", "--unset", "user.email")
+		return nil
+	}
+
+	if output, err := s.runGitCommand(worktreePath, "config", "--worktree", "user.name", identity.Name); err != nil {
+		return fmt.Errorf("failed to set user.name: %v\nOutput: %s", err, string(output))
+	}
+	if output, err := s.runGitCommand(worktreePath, "config", "--worktree", "user.email", identity.Email); err != nil {
+		return fmt.Errorf("failed to set user.email: %v\nOutput: %s", err, string(output))
+	}
+	return nil
+}
+
+// applyIdentityOnCreate configures a freshly created worktree with its repository's user identity
+func (s *GitService) applyIdentityOnCreate(worktree *models.Worktree) {
+	identity := s.EffectiveIdentity(worktree).User
+	if identity == nil {
+		return
+	}
+	if err := s.applyWorktreeIdentity(worktree.Path, identity); err != nil {
+		logger.Warnf("⚠️ Failed to apply git identity to worktree %s: %v", worktree.Name, err)
+	}
+}
+
+// automatedCommitArgs returns `-c` flags that author automated commits in
+// workDir with the bot identity, or nil when no bot identity is configured
+func (s *GitService) automatedCommitArgs(workDir string) []string {
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.Path != workDir {
+			continue
+		}
+		bot := s.EffectiveIdentity(worktree).Bot
+		if bot == nil {
+			return nil
+		}
+		return []string{"-c", "user.name=" + bot.Name, "-c", "user.email=" + bot.Email}
+	}
+	return nil
+}
+
+func validateIdentitySettings(settings *models.GitIdentitySettings) error {
+	if settings == nil {
+		return nil
+	}
+	if settings.User != nil {
+		if err := settings.User.Validate(); err != nil {
+			return fmt.Errorf("user %v", err)
+		}
+	}
+	if settings.Bot != nil {
+		if err := settings.Bot.Validate(); err != nil {
+			return fmt.Errorf("bot %v", err)
+		}
+	}
+	return nil
+}
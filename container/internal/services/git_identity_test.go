@@ -0,0 +1,81 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWorktreeIdentity(t *testing.T) {
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	tempDir := t.TempDir()
+	repoPath := filepath.Join(tempDir, "repo")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+
+	git := func(dir string, args ...string) string {
+		output, err := service.ExecuteGit(dir, args...)
+		require.NoError(t, err, "git %v: %s", args, output)
+		return strings.TrimSpace(string(output))
+	}
+	git(repoPath, "init", "-b", "main")
+	git(repoPath, "config", "user.name", "Global User")
+	git(repoPath, "config", "user.email", "global@example.com")
+	git(repoPath, "commit", "--allow-empty", "-m", "initial")
+
+	firstPath := filepath.Join(tempDir, "first")
+	secondPath := filepath.Join(tempDir, "second")
+	git(repoPath, "worktree", "add", "-b", "first", firstPath)
+	git(repoPath, "worktree", "add", "-b", "second", secondPath)
+
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "test/repo", Path: repoPath}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-1", RepoID: "test/repo", Path: firstPath}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-2", RepoID: "test/repo", Path: secondPath}))
+
+	t.Run("RejectsIncompleteIdentity", func(t *testing.T) {
+		_, err := service.SetWorktreeIdentity("wt-1", &models.GitIdentitySettings{
+			User: &models.GitIdentity{Name: "No Email"},
+		})
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+	})
+
+	t.Run("WorktreeOverrideDoesNotLeak", func(t *testing.T) {
+		effective, err := service.SetWorktreeIdentity("wt-1", &models.GitIdentitySettings{
+			User: &models.GitIdentity{Name: "Jane Doe", Email: "jane@example.com"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Jane Doe", effective.User.Name)
+
+		assert.Equal(t, "Jane Doe", git(firstPath, "config", "user.name"))
+		assert.Equal(t, "Global User", git(secondPath, "config", "user.name"))
+	})
+
+	t.Run("RepositoryBotIdentityForAutomatedCommits", func(t *testing.T) {
+		require.NoError(t, service.SetRepositoryIdentity("test/repo", &models.GitIdentitySettings{
+			Bot: &models.GitIdentity{Name: "catnip-bot", Email: "bot@catnip.local"},
+		}))
+
+		require.NoError(t, os.WriteFile(filepath.Join(firstPath, "file.txt"), []byte("change"), 0644))
+		hash, err := service.GitAddCommitGetHash(firstPath, "checkpoint")
+		require.NoError(t, err)
+		require.NotEmpty(t, hash)
+
+		assert.Equal(t, "catnip-bot <bot@catnip.local>", git(firstPath, "log", "-1", "--format=%an <%ae>"))
+		// Manual commits still use the worktree's user identity
+		assert.Equal(t, "Jane Doe", git(firstPath, "config", "user.name"))
+	})
+
+	t.Run("ClearingOverrideFallsBackToRepository", func(t *testing.T) {
+		effective, err := service.SetWorktreeIdentity("wt-1", nil)
+		require.NoError(t, err)
+		assert.Nil(t, effective.User)
+		assert.Equal(t, "catnip-bot", effective.Bot.Name)
+		assert.Equal(t, "Global User", git(firstPath, "config", "user.name"))
+	})
+}
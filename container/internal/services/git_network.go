@@ -0,0 +1,72 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// networkOverrideFor returns the network settings of the repository that
+// workingDir belongs to, matching its bare repo path or one of its worktrees
+func (s *GitService) networkOverrideFor(workingDir string) *models.GitNetworkSettings {
+	dir := filepath.Clean(workingDir)
+
+	repos := s.stateManager.GetAllRepositories()
+	for _, repo := range repos {
+		if repo.Network != nil && filepath.Clean(repo.Path) == dir {
+			return repo.Network
+		}
+	}
+
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		path := filepath.Clean(worktree.Path)
+		if dir != path && !strings.HasPrefix(dir, path+string(filepath.Separator)) {
+			continue
+		}
+		if repo, exists := repos[worktree.RepoID]; exists {
+			return repo.Network
+		}
+		return nil
+	}
+	return nil
+}
+
+// GetNetworkPolicy returns the default git network policy
+func (s *GitService) GetNetworkPolicy() git.NetworkPolicy {
+	return s.operations.GetNetworkPolicy()
+}
+
+// GetRepositoryNetworkPolicy returns a repository's network override (nil if it
+// uses the defaults) and the effective policy for its git commands
+func (s *GitService) GetRepositoryNetworkPolicy(repoID string) (*models.GitNetworkSettings, git.NetworkPolicy, error) {
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return nil, git.NetworkPolicy{}, models.NewRepositoryNotFoundError(repoID)
+	}
+	return repo.Network, s.operations.GetNetworkPolicy().WithOverrides(repo.Network), nil
+}
+
+// SetRepositoryNetworkPolicy stores network timeout/retry overrides for a
+// repository. A nil settings value clears the override.
+func (s *GitService) SetRepositoryNetworkPolicy(repoID string, settings *models.GitNetworkSettings) (git.NetworkPolicy, error) {
+	if settings != nil {
+		if err := settings.Validate(); err != nil {
+			return git.NetworkPolicy{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+		}
+	}
+
+	repo, exists := s.stateManager.GetRepository(repoID)
+	if !exists {
+		return git.NetworkPolicy{}, models.NewRepositoryNotFoundError(repoID)
+	}
+
+	updated := *repo
+	updated.Network = settings
+	if err := s.stateManager.AddRepository(&updated); err != nil {
+		return git.NetworkPolicy{}, fmt.Errorf("failed to save repository network settings: %v", err)
+	}
+	return s.operations.GetNetworkPolicy().WithOverrides(settings), nil
+}
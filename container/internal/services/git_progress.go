@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+const (
+	// Lines kept per running operation for clients that subscribe mid-way
+	maxGitProgressLines = 100
+	// Buffered events per subscriber; updates beyond that are dropped for slow clients
+	gitProgressSubscriberBuffer = 256
+)
+
+// GitOperationProgress is a long git operation (clone, fetch) running while
+// an operation holds a repository slot
+type GitOperationProgress struct {
+	ID         string           `json:"id"`
+	RepoID     string           `json:"repo_id"`
+	WorktreeID string           `json:"worktree_id,omitempty"`
+	Operation  string           `json:"operation"`
+	StartedAt  time.Time        `json:"started_at"`
+	Lines      []git.OutputLine `json:"lines,omitempty"`
+}
+
+// GitProgressEvent is one update from a long git operation: an output line,
+// or its completion
+type GitProgressEvent struct {
+	OperationID string          `json:"operation_id"`
+	RepoID      string          `json:"repo_id"`
+	WorktreeID  string          `json:"worktree_id,omitempty"`
+	Operation   string          `json:"operation"`
+	Line        *git.OutputLine `json:"line,omitempty"`
+	Done        bool            `json:"done,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	Time        time.Time       `json:"time"`
+}
+
+// GitProgressHub fans out the output of long git operations to subscribers.
+// Publishing never blocks on subscribers, so streams of parallel operations
+// on different repositories don't hold each other up.
+type GitProgressHub struct {
+	mu          sync.Mutex
+	active      map[string]*GitOperationProgress
+	subscribers map[chan GitProgressEvent]string // channel -> repo filter ("" for all)
+}
+
+// NewGitProgressHub creates an empty progress hub
+func NewGitProgressHub() *GitProgressHub {
+	return &GitProgressHub{
+		active:      make(map[string]*GitOperationProgress),
+		subscribers: make(map[chan GitProgressEvent]string),
+	}
+}
+
+// Subscribe returns a snapshot of running operations (optionally only those
+// of repoID) and a channel of later events. Call unsubscribe when done.
+func (h *GitProgressHub) Subscribe(repoID string) ([]GitOperationProgress, <-chan GitProgressEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var running []GitOperationProgress
+	for _, op := range h.active {
+		if repoID == "" || op.RepoID == repoID {
+			snapshot := *op
+			snapshot.Lines = append([]git.OutputLine(nil), op.Lines...)
+			running = append(running, snapshot)
+		}
+	}
+
+	ch := make(chan GitProgressEvent, gitProgressSubscriberBuffer)
+	h.subscribers[ch] = repoID
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+	return running, ch, unsubscribe
+}
+
+func (h *GitProgressHub) begin(repoID, worktreeID, operation string) *GitOperationProgress {
+	op := &GitOperationProgress{
+		ID:         uuid.New().String(),
+		RepoID:     repoID,
+		WorktreeID: worktreeID,
+		Operation:  operation,
+		StartedAt:  time.Now(),
+	}
+	h.mu.Lock()
+	h.active[op.ID] = op
+	h.mu.Unlock()
+	return op
+}
+
+func (h *GitProgressHub) line(op *GitOperationProgress, line git.OutputLine) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	op.Lines = append(op.Lines, line)
+	if len(op.Lines) > maxGitProgressLines {
+		op.Lines = op.Lines[len(op.Lines)-maxGitProgressLines:]
+	}
+	h.publishLocked(op, GitProgressEvent{Line: &line})
+}
+
+func (h *GitProgressHub) finish(op *GitOperationProgress, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.active, op.ID)
+	event := GitProgressEvent{Done: true}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	h.publishLocked(op, event)
+}
+
+func (h *GitProgressHub) publishLocked(op *GitOperationProgress, event GitProgressEvent) {
+	event.OperationID = op.ID
+	event.RepoID = op.RepoID
+	event.WorktreeID = op.WorktreeID
+	event.Operation = op.Operation
+	event.Time = time.Now()
+	for ch, repoID := range h.subscribers {
+		if repoID != "" && repoID != op.RepoID {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// GetGitProgress returns the hub publishing output of long git operations
+func (s *GitService) GetGitProgress() *GitProgressHub {
+	return s.gitProgress
+}
+
+// runGitWithProgress runs a long git command, publishing its output for the
+// repository as it arrives. Pass --progress for fetch/clone progress lines.
+func (s *GitService) runGitWithProgress(repoID, worktreeID, operation, workingDir string, args ...string) ([]byte, error) {
+	op := s.gitProgress.begin(repoID, worktreeID, operation)
+	stream := s.operations.ExecuteGitStream(context.Background(), workingDir, args...)
+	for line := range stream.Lines() {
+		s.gitProgress.line(op, line)
+	}
+	output, err := stream.Wait()
+	s.gitProgress.finish(op, err)
+	return output, err
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunGitWithProgress(t *testing.T) {
+	service := createTestGitService(t)
+	hub := service.GetGitProgress()
+
+	running, events, unsubscribe := hub.Subscribe("acme/app")
+	defer unsubscribe()
+	assert.Empty(t, running)
+	_, otherEvents, unsubscribeOther := hub.Subscribe("acme/other")
+	defer unsubscribeOther()
+
+	dir := t.TempDir()
+	_, err := service.runGitWithProgress("acme/app", "wt-1", repoOpCheckout, dir, "init", "-b", "main")
+	require.NoError(t, err)
+
+	var received []GitProgressEvent
+	for len(received) == 0 || !received[len(received)-1].Done {
+		received = append(received, <-events)
+	}
+	require.Len(t, received, 2)
+	assert.Equal(t, "acme/app", received[0].RepoID)
+	assert.Equal(t, "wt-1", received[0].WorktreeID)
+	assert.Equal(t, repoOpCheckout, received[0].Operation)
+	require.NotNil(t, received[0].Line)
+	assert.Contains(t, received[0].Line.Text, "Initialized empty Git repository")
+	assert.Equal(t, received[0].OperationID, received[1].OperationID)
+	assert.Empty(t, received[1].Error)
+	assert.Empty(t, otherEvents, "subscribers only see their repository")
+
+	// Failures are reported on the completion event
+	_, err = service.runGitWithProgress("acme/app", "", repoOpSync, dir, "rev-parse", "--verify", "no-such-ref")
+	require.Error(t, err)
+	var last GitProgressEvent
+	for !last.Done {
+		last = <-events
+	}
+	assert.NotEmpty(t, last.Error)
+
+	running, _, unsubscribeLate := hub.Subscribe("")
+	defer unsubscribeLate()
+	assert.Empty(t, running, "finished operations aren't reported as running")
+}
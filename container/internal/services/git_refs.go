@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+var (
+	commitSHAPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+	tagNamePattern   = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/+-]*$`)
+)
+
+// CheckoutRepositoryAtRef creates a worktree from a tag or a specific commit
+// instead of a branch. The worktree starts at that commit on its own catnip
+// ref, so commits made while reproducing a bug or bisecting are never left on
+// a detached HEAD. The repository's default branch is used as the source
+// branch for diffs, syncs and PRs.
+func (s *GitService) CheckoutRepositoryAtRef(org, repo string, refType models.SourceRefType, ref string) (*models.Repository, *models.Worktree, error) {
+	ref = strings.TrimSpace(ref)
+	switch refType {
+	case models.SourceRefTag:
+		if !tagNamePattern.MatchString(ref) || strings.Contains(ref, "..") {
+			return nil, nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid tag name: %q", ref)
+		}
+	case models.SourceRefCommit:
+		if !commitSHAPattern.MatchString(ref) {
+			return nil, nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid commit SHA: %q", ref)
+		}
+		ref = strings.ToLower(ref)
+	default:
+		return nil, nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unsupported ref type: %q", refType)
+	}
+
+	repoID := fmt.Sprintf("%s/%s", org, repo)
+	release := s.acquireRepoSlot(repoID, repoOpCheckout)
+	defer release()
+
+	repository, err := s.ensureRepository(org, repo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	commit, err := s.resolveCheckoutRef(repository, refType, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+	logger.Infof("🏷️ Creating worktree for %s at %s %s (%s)", repoID, refType, ref, commit[:7])
+
+	funName := s.generateUniqueSessionName(repository.Path)
+	var worktree *models.Worktree
+	if s.isLocalRepo(repoID) {
+		worktree, err = s.createLocalRepoWorktree(repository, commit, funName)
+	} else {
+		worktree, err = s.createWorktreeInternalForRepo(repository, commit, funName, true)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create worktree: %v", err)
+	}
+
+	updates := map[string]interface{}{
+		"source_branch":   repository.DefaultBranch,
+		"source_ref":      ref,
+		"source_ref_type": refType,
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, updates); err != nil {
+		logger.Warnf("⚠️ Failed to record source ref for worktree %s: %v", worktree.Name, err)
+	}
+	if updated, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		worktree = updated
+	}
+
+	return repository, worktree, nil
+}
+
+// ensureRepository returns the repository for org/repo, loading an existing bare
+// clone or shallow-cloning the default branch if needed (must hold the
+// repository's operation slot)
+func (s *GitService) ensureRepository(org, repo string) (*models.Repository, error) {
+	repoID := fmt.Sprintf("%s/%s", org, repo)
+	if existing, exists := s.stateManager.GetRepository(repoID); exists {
+		return existing, nil
+	}
+	if s.isLocalRepo(repoID) {
+		return nil, models.NewAPIError(models.ErrCodeRepositoryNotFound, "local repository %s not found - it may not be mounted", repoID)
+	}
+
+	if err := s.checkRepoHost(repoID); err != nil {
+		return nil, err
+	}
+	repoURL := checkoutRemoteURL(repoID)
+
+	reposDir := filepath.Join(config.Runtime.VolumeDir, "repos")
+	if err := os.MkdirAll(reposDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create repos directory: %v", err)
+	}
+	barePath := filepath.Join(reposDir, fmt.Sprintf("%s.git", strings.ReplaceAll(repo, "/", "-")))
+
+	if _, err := os.Stat(barePath); os.IsNotExist(err) {
+		logger.Debugf("🔄 Cloning new repository: %s", repoID)
+		if _, err := s.runGitCommand("", "clone", "--bare", "--depth", "1", "--single-branch", repoURL, barePath); err != nil {
+			return nil, fmt.Errorf("failed to clone repository: %v", err)
+		}
+	}
+
+	defaultBranch, err := s.getDefaultBranch(barePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get default branch: %v", err)
+	}
+
+	repository := &models.Repository{
+		ID:            repoID,
+		URL:           repoURL,
+		Path:          barePath,
+		DefaultBranch: defaultBranch,
+		CreatedAt:     time.Now(),
+		LastAccessed:  time.Now(),
+	}
+	if err := s.stateManager.AddRepository(repository); err != nil {
+		logger.Warnf("⚠️ Failed to add repository to state: %v", err)
+	}
+
+	go s.unshallowRepository(barePath, defaultBranch)
+	return repository, nil
+}
+
+// resolveCheckoutRef makes sure a tag or commit is available locally, fetching
+// it from origin if necessary, and returns the full commit SHA
+func (s *GitService) resolveCheckoutRef(repo *models.Repository, refType models.SourceRefType, ref string) (string, error) {
+	revision := ref
+	if refType == models.SourceRefTag {
+		revision = "refs/tags/" + ref
+	}
+
+	if commit, err := s.resolveCommit(repo.Path, revision); err == nil {
+		return commit, nil
+	}
+
+	if s.isLocalRepo(repo.ID) {
+		return "", models.NewAPIError(models.ErrCodeInvalidRequest, "%s %s not found in %s", refType, ref, repo.ID)
+	}
+
+	logger.Infof("🔄 %s %s not found locally, fetching from origin", refType, ref)
+	switch {
+	case refType == models.SourceRefTag:
+		if output, err := s.runGitCommand(repo.Path, "fetch", "origin", fmt.Sprintf("+refs/tags/%s:refs/tags/%s", ref, ref)); err != nil {
+			return "", fmt.Errorf("failed to fetch tag %s: %v\nOutput: %s", ref, err, string(output))
+		}
+	case len(ref) == 40:
+		// Servers only allow fetching reachable commits by their full SHA
+		if output, err := s.runGitCommand(repo.Path, "fetch", "origin", ref); err != nil {
+			return "", fmt.Errorf("failed to fetch commit %s: %v\nOutput: %s", ref, err, string(output))
+		}
+	default:
+		// Abbreviated SHAs can only be resolved against local history, so deepen it first
+		if output, err := s.runGitCommand(repo.Path, "fetch", "--unshallow", "origin"); err != nil && !strings.Contains(string(output)+err.Error(), "complete repository") {
+			return "", fmt.Errorf("failed to fetch history for commit %s: %v\nOutput: %s", ref, err, string(output))
+		}
+	}
+
+	commit, err := s.resolveCommit(repo.Path, revision)
+	if err != nil {
+		return "", models.NewAPIError(models.ErrCodeInvalidRequest, "%s %s not found in %s", refType, ref, repo.ID)
+	}
+	return commit, nil
+}
+
+// resolveCommit returns the full SHA of the commit a revision points to,
+// peeling annotated tags
+func (s *GitService) resolveCommit(repoPath, revision string) (string, error) {
+	output, err := s.runGitCommand(repoPath, "rev-list", "-n", "1", revision, "--")
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(output))
+	if len(commit) != 40 {
+		return "", fmt.Errorf("could not resolve %s", revision)
+	}
+	return commit, nil
+}
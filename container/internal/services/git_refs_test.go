@@ -0,0 +1,68 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCheckoutRepositoryAtRef(t *testing.T) {
+	workspace := t.TempDir()
+	t.Setenv("CATNIP_WORKSPACE_DIR", workspace)
+
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	repoPath := filepath.Join(t.TempDir(), "releases")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	git := func(args ...string) string {
+		output, err := service.ExecuteGit(repoPath, args...)
+		require.NoError(t, err, "git %v: %s", args, output)
+		return strings.TrimSpace(string(output))
+	}
+	git("init", "-b", "main")
+	git("config", "user.name", "Test User")
+	git("config", "user.email", "test@example.com")
+	git("commit", "--allow-empty", "-m", "v1")
+	git("tag", "v1.0.0")
+	v1 := git("rev-parse", "HEAD")
+	git("commit", "--allow-empty", "-m", "v2")
+
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{
+		ID:            "acme/releases",
+		Path:          repoPath,
+		DefaultBranch: "main",
+	}))
+
+	t.Run("Tag", func(t *testing.T) {
+		_, worktree, err := service.CheckoutRepositoryAtRef("acme", "releases", models.SourceRefTag, "v1.0.0")
+		require.NoError(t, err)
+
+		assert.Equal(t, v1, worktree.CommitHash)
+		assert.Equal(t, "v1.0.0", worktree.SourceRef)
+		assert.Equal(t, models.SourceRefTag, worktree.SourceRefType)
+		assert.Equal(t, "main", worktree.SourceBranch)
+		// Work happens on a catnip ref rather than a detached HEAD
+		assert.True(t, strings.HasPrefix(worktree.Branch, "refs/catnip/"))
+	})
+
+	t.Run("AbbreviatedCommit", func(t *testing.T) {
+		_, worktree, err := service.CheckoutRepositoryAtRef("acme", "releases", models.SourceRefCommit, v1[:10])
+		require.NoError(t, err)
+		assert.Equal(t, v1, worktree.CommitHash)
+		assert.Equal(t, models.SourceRefCommit, worktree.SourceRefType)
+	})
+
+	t.Run("InvalidRefs", func(t *testing.T) {
+		_, _, err := service.CheckoutRepositoryAtRef("acme", "releases", models.SourceRefCommit, "not-a-sha")
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+
+		_, _, err = service.CheckoutRepositoryAtRef("acme", "releases", models.SourceRefTag, "../escape")
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+	})
+}
@@ -260,7 +260,7 @@ func TestGitServiceCleanupOperations(t *testing.T) {
 
 	t.Run("CleanupMergedWorktrees", func(t *testing.T) {
 		// Should not error even with no worktrees
-		_, _, err := service.CleanupMergedWorktrees()
+		_, _, err := service.CleanupMergedWorktrees(LabelSelector{})
 		assert.NoError(t, err)
 	})
 
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// DefaultGitHubAppKeySecret is the secret holding the app's private key
+	DefaultGitHubAppKeySecret = "github-app-private-key"
+	// GitHubAppTokenUsername is the git username for installation tokens
+	GitHubAppTokenUsername = "x-access-token"
+
+	defaultGitHubAPIURL = "https://api.github.com"
+	// Installation tokens last an hour; refresh them a little early
+	gitHubAppTokenRefreshMargin = 5 * time.Minute
+)
+
+// GitHubAppConfig identifies the GitHub App used for repositories in app
+// authentication mode
+type GitHubAppConfig struct {
+	// Numeric app ID (or client ID) from the app's settings page
+	AppID string `json:"app_id"`
+	// Secret holding the app's PEM private key
+	PrivateKeySecret string `json:"private_key_secret"`
+}
+
+// GitHubAppStatus reports the app configuration without the key itself
+type GitHubAppStatus struct {
+	Configured       bool   `json:"configured"`
+	AppID            string `json:"app_id,omitempty"`
+	PrivateKeySecret string `json:"private_key_secret,omitempty"`
+	HasPrivateKey    bool   `json:"has_private_key"`
+}
+
+// GitHubAppToken is an installation access token for one repository
+type GitHubAppToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GitHubAppService mints installation tokens for a GitHub App, so team
+// servers can push and open pull requests as the app instead of relying on
+// one user's gh login
+type GitHubAppService struct {
+	path       string
+	secrets    *SecretStore
+	apiURL     string
+	httpClient *http.Client
+	now        func() time.Time
+
+	mu            sync.Mutex
+	config        GitHubAppConfig
+	installations map[string]int64 // owner/repo -> installation ID
+	tokens        map[int64]GitHubAppToken
+}
+
+// NewGitHubAppService creates the app service, reading its configuration
+// from the volume
+func NewGitHubAppService(secrets *SecretStore) *GitHubAppService {
+	return NewGitHubAppServiceWithOptions(filepath.Join(config.Runtime.VolumeDir, "github_app.json"), secrets, defaultGitHubAPIURL)
+}
+
+// NewGitHubAppServiceWithOptions creates the app service with an explicit
+// config path and API URL (for testing and GitHub Enterprise)
+func NewGitHubAppServiceWithOptions(path string, secrets *SecretStore, apiURL string) *GitHubAppService {
+	s := &GitHubAppService{
+		path:          path,
+		secrets:       secrets,
+		apiURL:        strings.TrimSuffix(apiURL, "/"),
+		httpClient:    &http.Client{Timeout: 15 * time.Second},
+		now:           time.Now,
+		installations: make(map[string]int64),
+		tokens:        make(map[int64]GitHubAppToken),
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &s.config); err != nil {
+			logger.Warnf("⚠️ Ignoring invalid GitHub App config %s: %v", path, err)
+		}
+	}
+	return s
+}
+
+// Status reports whether the app is usable
+func (s *GitHubAppService) Status() GitHubAppStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked()
+}
+
+func (s *GitHubAppService) statusLocked() GitHubAppStatus {
+	status := GitHubAppStatus{
+		AppID:            s.config.AppID,
+		PrivateKeySecret: s.config.PrivateKeySecret,
+	}
+	if s.config.PrivateKeySecret != "" {
+		status.HasPrivateKey = s.secrets.Has(s.config.PrivateKeySecret)
+	}
+	status.Configured = status.AppID != "" && status.HasPrivateKey
+	return status
+}
+
+// Configured reports whether tokens can be minted
+func (s *GitHubAppService) Configured() bool {
+	return s.Status().Configured
+}
+
+// Configure sets the app ID and, if given, stores the private key in the
+// secret store. Cached tokens are dropped.
+func (s *GitHubAppService) Configure(cfg GitHubAppConfig, privateKey string) (GitHubAppStatus, error) {
+	cfg.AppID = strings.TrimSpace(cfg.AppID)
+	if cfg.AppID == "" {
+		return GitHubAppStatus{}, models.NewAPIError(models.ErrCodeInvalidRequest, "app_id is required")
+	}
+	if cfg.PrivateKeySecret == "" {
+		cfg.PrivateKeySecret = DefaultGitHubAppKeySecret
+	}
+	if privateKey != "" {
+		if _, err := parseGitHubAppKey(privateKey); err != nil {
+			return GitHubAppStatus{}, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid private key: %v", err)
+		}
+		if err := s.secrets.Set(cfg.PrivateKeySecret, privateKey); err != nil {
+			return GitHubAppStatus{}, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return GitHubAppStatus{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return GitHubAppStatus{}, err
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return GitHubAppStatus{}, err
+	}
+	s.config = cfg
+	s.installations = make(map[string]int64)
+	s.tokens = make(map[int64]GitHubAppToken)
+	logger.Infof("🤖 GitHub App %s configured", cfg.AppID)
+	return s.statusLocked(), nil
+}
+
+// InstallationToken returns a token scoped to the app's installation on
+// ownerRepo, reusing a cached token until shortly before it expires
+func (s *GitHubAppService) InstallationToken(ownerRepo string) (GitHubAppToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if status := s.statusLocked(); !status.Configured {
+		return GitHubAppToken{}, models.NewAPIError(models.ErrCodeGitHubNotAuthenticated, "GitHub App is not configured").
+			WithHint("Set the app ID and private key with PUT /v1/github/app")
+	}
+	key, err := s.privateKeyLocked()
+	if err != nil {
+		return GitHubAppToken{}, err
+	}
+
+	installationID, ok := s.installations[ownerRepo]
+	if !ok {
+		var installation struct {
+			ID int64 `json:"id"`
+		}
+		if err := s.appRequest(key, "GET", "/repos/"+ownerRepo+"/installation", &installation); err != nil {
+			return GitHubAppToken{}, fmt.Errorf("GitHub App %s is not installed on %s: %v", s.config.AppID, ownerRepo, err)
+		}
+		installationID = installation.ID
+		s.installations[ownerRepo] = installationID
+	}
+
+	if token, ok := s.tokens[installationID]; ok && s.now().Add(gitHubAppTokenRefreshMargin).Before(token.ExpiresAt) {
+		return token, nil
+	}
+
+	var token GitHubAppToken
+	if err := s.appRequest(key, "POST", fmt.Sprintf("/app/installations/%d/access_tokens", installationID), &token); err != nil {
+		return GitHubAppToken{}, fmt.Errorf("failed to create installation token for %s: %v", ownerRepo, err)
+	}
+	s.tokens[installationID] = token
+	logger.Debugf("🤖 Minted GitHub App installation token for %s (expires %s)", ownerRepo, token.ExpiresAt.Format(time.RFC3339))
+	return token, nil
+}
+
+func (s *GitHubAppService) privateKeyLocked() (*rsa.PrivateKey, error) {
+	pemData, err := s.secrets.Get(s.config.PrivateKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	return parseGitHubAppKey(pemData)
+}
+
+// appRequest calls the GitHub API authenticated as the app itself
+func (s *GitHubAppService) appRequest(key *rsa.PrivateKey, method, path string, out interface{}) error {
+	jwt, err := gitHubAppJWT(key, s.config.AppID, s.now())
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, s.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(body, &apiErr)
+		if apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(body))
+		}
+		return fmt.Errorf("%s %s: %s", method, path, firstNonEmpty(apiErr.Message, resp.Status))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// gitHubAppJWT signs the short-lived RS256 JWT that authenticates as the app
+func gitHubAppJWT(key *rsa.PrivateKey, appID string, now time.Time) (string, error) {
+	encode := func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(data), nil
+	}
+	header, err := encode(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	// Backdate iat for clock drift; GitHub allows at most 10 minutes
+	claims, err := encode(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + claims
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parseGitHubAppKey parses the PKCS#1 key GitHub generates (or PKCS#8)
+func parseGitHubAppKey(pemData string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(pemData)))
+	if block == nil {
+		return nil, fmt.Errorf("not a PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("GitHub App keys must be RSA")
+	}
+	return key, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
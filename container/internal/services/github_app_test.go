@@ -0,0 +1,174 @@
+package services
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func testGitHubAppKey(t *testing.T) (*rsa.PrivateKey, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pemData := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return key, string(pemData)
+}
+
+// fakeGitHubAppAPI serves the installation and access token endpoints,
+// verifying the app JWT on every request
+func fakeGitHubAppAPI(t *testing.T, key *rsa.PrivateKey, tokenRequests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		parts := strings.Split(jwt, ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature) != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		assert.Contains(t, string(claims), `"iss":"1234"`)
+
+		switch {
+		case r.Method == "GET" && r.URL.Path == "/repos/acme/app/installation":
+			_, _ = w.Write([]byte(`{"id": 99}`))
+		case r.Method == "POST" && r.URL.Path == "/app/installations/99/access_tokens":
+			n := atomic.AddInt32(tokenRequests, 1)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"token":      "ghs_token" + string(rune('0'+n)),
+				"expires_at": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "Not Found"}`))
+		}
+	}))
+}
+
+func TestSecretStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	store := NewSecretStoreWithPath(dir)
+
+	assert.Empty(t, store.Names())
+	assert.False(t, store.Has("token"))
+	require.NoError(t, store.Set("token", "s3cret"))
+	value, err := store.Get("token")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+	assert.Equal(t, []string{"token"}, store.Names())
+
+	info, err := os.Stat(filepath.Join(dir, "token"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.Error(t, store.Set("../escape", "x"), "names can't leave the store")
+	assert.Error(t, store.Set("empty", " "))
+	require.NoError(t, store.Delete("token"))
+	require.NoError(t, store.Delete("token"), "deleting twice is fine")
+	_, err = store.Get("token")
+	assert.Error(t, err)
+}
+
+func TestGitHubAppInstallationToken(t *testing.T) {
+	key, pemData := testGitHubAppKey(t)
+	var tokenRequests int32
+	api := fakeGitHubAppAPI(t, key, &tokenRequests)
+	defer api.Close()
+
+	dir := t.TempDir()
+	secrets := NewSecretStoreWithPath(filepath.Join(dir, "secrets"))
+	app := NewGitHubAppServiceWithOptions(filepath.Join(dir, "github_app.json"), secrets, api.URL)
+	assert.False(t, app.Configured())
+
+	_, err := app.Configure(GitHubAppConfig{AppID: "1234"}, "not a key")
+	assert.Error(t, err)
+	status, err := app.Configure(GitHubAppConfig{AppID: "1234"}, pemData)
+	require.NoError(t, err)
+	assert.True(t, status.Configured)
+	assert.Equal(t, DefaultGitHubAppKeySecret, status.PrivateKeySecret)
+	assert.True(t, secrets.Has(DefaultGitHubAppKeySecret), "the key lives in the secret store")
+
+	token, err := app.InstallationToken("acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token.Token)
+	token, err = app.InstallationToken("acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token.Token, "tokens are reused until they near expiry")
+
+	app.now = func() time.Time { return time.Now().Add(56 * time.Minute) }
+	token, err = app.InstallationToken("acme/app")
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token2", token.Token)
+
+	_, err = app.InstallationToken("acme/other")
+	assert.ErrorContains(t, err, "not installed on acme/other")
+
+	// The configuration survives a restart
+	reloaded := NewGitHubAppServiceWithOptions(filepath.Join(dir, "github_app.json"), secrets, api.URL)
+	assert.True(t, reloaded.Configured())
+}
+
+func TestRepositoryGitHubAuth(t *testing.T) {
+	key, pemData := testGitHubAppKey(t)
+	var tokenRequests int32
+	api := fakeGitHubAppAPI(t, key, &tokenRequests)
+	defer api.Close()
+
+	service := createTestGitService(t)
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{
+		ID:           "acme/app",
+		Path:         t.TempDir(),
+		RemoteOrigin: "https://github.com/acme/app.git",
+	}))
+
+	dir := t.TempDir()
+	app := NewGitHubAppServiceWithOptions(filepath.Join(dir, "github_app.json"), NewSecretStoreWithPath(filepath.Join(dir, "secrets")), api.URL)
+	service.SetGitHubApp(app)
+
+	_, err := service.SetRepositoryGitHubAuth("acme/app", models.GitHubAuthApp)
+	assert.ErrorContains(t, err, "no GitHub App is configured")
+	_, err = app.Configure(GitHubAppConfig{AppID: "1234"}, pemData)
+	require.NoError(t, err)
+
+	_, err = service.GitHubAppTokenFor("acme/app")
+	assert.ErrorContains(t, err, "uses the gh login")
+	_, ok := service.githubAppTokenSource("acme/app")
+	assert.False(t, ok, "gh keeps using the login for user-mode repositories")
+
+	repo, err := service.SetRepositoryGitHubAuth("acme/app", models.GitHubAuthApp)
+	require.NoError(t, err)
+	assert.Equal(t, models.GitHubAuthApp, repo.GitHubAuth)
+
+	token, err := service.GitHubAppTokenFor("acme/app.git")
+	require.NoError(t, err)
+	assert.Equal(t, "ghs_token1", token.Token)
+	ghToken, ok := service.githubAppTokenSource("acme/app")
+	assert.True(t, ok)
+	assert.Equal(t, "ghs_token1", ghToken)
+
+	_, err = service.SetRepositoryGitHubAuth("acme/app", "robot")
+	assert.Error(t, err)
+	repo, err = service.SetRepositoryGitHubAuth("acme/app", models.GitHubAuthUser)
+	require.NoError(t, err)
+	assert.Empty(t, repo.GitHubAuth)
+	_, err = service.GitHubAppTokenFor("unknown/repo")
+	assert.Error(t, err)
+}
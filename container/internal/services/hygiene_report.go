@@ -0,0 +1,466 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// defaultHygieneReportHour is the hour the nightly report runs at in the
+	// instance's time zone
+	defaultHygieneReportHour = 3
+	// defaultHygieneStaleDays is how long a worktree can sit untouched (or a
+	// branch unmerged) before the report flags it
+	defaultHygieneStaleDays = 14
+	// Reports kept on disk, newest first
+	maxHygieneReports = 30
+	// Worktrees listed in the disk and spend breakdowns
+	hygieneReportTopN = 10
+)
+
+// HygieneWorktree is a worktree flagged or ranked by a hygiene report
+type HygieneWorktree struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	RepoID string `json:"repo_id"`
+	Branch string `json:"branch"`
+	Path   string `json:"path"`
+	// Days since last access (stale worktrees) or creation (unmerged branches)
+	AgeDays        int       `json:"age_days,omitempty"`
+	LastAccessed   time.Time `json:"last_accessed"`
+	CommitCount    int       `json:"commit_count,omitempty"`
+	IsDirty        bool      `json:"is_dirty,omitempty"`
+	PullRequestURL string    `json:"pull_request_url,omitempty"`
+	Bytes          int64     `json:"bytes,omitempty"`
+	// Space freed by removing build artifact directories
+	ReclaimableBytes int64   `json:"reclaimable_bytes,omitempty"`
+	EstimatedCostUSD float64 `json:"estimated_cost_usd,omitempty"`
+}
+
+// HygieneDiskUsage sums disk usage across worktrees
+type HygieneDiskUsage struct {
+	TotalBytes       int64 `json:"total_bytes"`
+	ReclaimableBytes int64 `json:"reclaimable_bytes"`
+	// Largest worktrees first
+	Worktrees []HygieneWorktree `json:"worktrees"`
+}
+
+// HygieneClaudeSpend sums Claude usage recorded for the current worktrees
+type HygieneClaudeSpend struct {
+	models.AgentUsage
+	// Most expensive worktrees first
+	Worktrees []HygieneWorktree `json:"worktrees"`
+}
+
+// HygieneReport summarizes workspace clutter and spend at a point in time
+type HygieneReport struct {
+	ID             string    `json:"id"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	StaleAfterDays int       `json:"stale_after_days"`
+	// Scheduled (nightly) or on demand
+	Scheduled        bool               `json:"scheduled"`
+	WorktreeCount    int                `json:"worktree_count"`
+	StaleWorktrees   []HygieneWorktree  `json:"stale_worktrees"`
+	FailingSetups    []SetupFailure     `json:"failing_setups"`
+	UnmergedBranches []HygieneWorktree  `json:"unmerged_branches"`
+	Disk             HygieneDiskUsage   `json:"disk"`
+	ClaudeSpend      HygieneClaudeSpend `json:"claude_spend"`
+	// Problems collecting parts of the report (unreadable worktrees, ...)
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Summary is a one-line description of the report for notifications, with
+// numbers formatted in the instance's locale
+func (r *HygieneReport) Summary() string {
+	return localePrinter().Sprintf("%d stale worktrees, %d failing setups, %d unmerged branches, %s on disk (%s reclaimable), $%.2f Claude spend",
+		len(r.StaleWorktrees), len(r.FailingSetups), len(r.UnmergedBranches),
+		formatHygieneBytes(r.Disk.TotalBytes), formatHygieneBytes(r.Disk.ReclaimableBytes), r.ClaudeSpend.EstimatedCostUSD)
+}
+
+// HygieneReportSources supplies the data a report summarizes. Nil sources
+// leave their section empty.
+type HygieneReportSources struct {
+	Worktrees     func() []*models.Worktree
+	SetupFailures func() []SetupFailure
+	DiskLayout    func(path string) (*DiskLayout, error)
+	AgentUsage    func(worktreePath string) (models.AgentUsage, error)
+}
+
+// HygieneReportEventsEmitter delivers finished reports
+type HygieneReportEventsEmitter interface {
+	EmitHygieneReport(report *HygieneReport)
+}
+
+// HygieneReportService generates a workspace hygiene report every night and
+// keeps recent reports on the volume
+type HygieneReportService struct {
+	path      string
+	sources   HygieneReportSources
+	hour      int
+	staleDays int
+	now       func() time.Time
+	events    HygieneReportEventsEmitter
+	notify    bool
+
+	generating sync.Mutex // Serializes generation
+	mu         sync.Mutex // Guards reports and running
+	reports    []*HygieneReport
+	stopChan   chan struct{}
+	reschedule chan struct{}
+	running    bool
+}
+
+// NewHygieneReportService creates a report service configured from
+// CATNIP_HYGIENE_REPORT_HOUR (hour in CATNIP_TIMEZONE, 0-23), CATNIP_HYGIENE_STALE_DAYS and
+// CATNIP_HYGIENE_REPORT_NOTIFY (deliver reports as notifications when "true")
+func NewHygieneReportService(sources HygieneReportSources) *HygieneReportService {
+	hour, staleDays, notify := hygieneSettingsFromEnv()
+	return NewHygieneReportServiceWithOptions(filepath.Join(config.Runtime.VolumeDir, "hygiene_reports.json"), sources, hour, staleDays).
+		WithNotify(notify)
+}
+
+func hygieneSettingsFromEnv() (hour, staleDays int, notify bool) {
+	hour = defaultHygieneReportHour
+	if raw := os.Getenv("CATNIP_HYGIENE_REPORT_HOUR"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed >= 0 && parsed <= 23 {
+			hour = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_HYGIENE_REPORT_HOUR %q (expected 0-23)", raw)
+		}
+	}
+
+	staleDays = defaultHygieneStaleDays
+	if raw := os.Getenv("CATNIP_HYGIENE_STALE_DAYS"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			staleDays = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_HYGIENE_STALE_DAYS %q", raw)
+		}
+	}
+
+	return hour, staleDays, os.Getenv("CATNIP_HYGIENE_REPORT_NOTIFY") == "true"
+}
+
+// NewHygieneReportServiceWithOptions creates a report service with explicit settings (for testing)
+func NewHygieneReportServiceWithOptions(path string, sources HygieneReportSources, hour, staleDays int) *HygieneReportService {
+	s := &HygieneReportService{
+		path:      path,
+		sources:   sources,
+		hour:      hour,
+		staleDays: staleDays,
+		now:       time.Now,
+		stopChan:  make(chan struct{}),
+		// Buffered so Reconfigure never waits on the scheduler
+		reschedule: make(chan struct{}, 1),
+	}
+	if err := s.load(); err != nil {
+		logger.Warnf("⚠️ Failed to load hygiene reports: %v", err)
+	}
+	return s
+}
+
+// WithEvents sets the emitter reports are delivered through when
+// notifications are enabled
+func (s *HygieneReportService) WithEvents(events HygieneReportEventsEmitter) *HygieneReportService {
+	s.events = events
+	return s
+}
+
+// WithNotify turns delivery of generated reports on or off
+func (s *HygieneReportService) WithNotify(notify bool) *HygieneReportService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notify = notify
+	return s
+}
+
+// Reconfigure changes the report hour, stale threshold and delivery, moving
+// the next scheduled report to the new hour
+func (s *HygieneReportService) Reconfigure(hour, staleDays int, notify bool) {
+	s.mu.Lock()
+	s.hour = hour
+	s.staleDays = staleDays
+	s.notify = notify
+	s.mu.Unlock()
+
+	select {
+	case s.reschedule <- struct{}{}:
+	default:
+	}
+	logger.Infof("🧹 Hygiene report reconfigured: nightly at %02d:00, stale after %d days, notify %v", hour, staleDays, notify)
+}
+
+// ReloadConfig re-reads the CATNIP_HYGIENE_* settings
+func (s *HygieneReportService) ReloadConfig() {
+	s.Reconfigure(hygieneSettingsFromEnv())
+}
+
+func (s *HygieneReportService) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var reports []*HygieneReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return fmt.Errorf("corrupt hygiene reports file: %v", err)
+	}
+	s.reports = reports
+	return nil
+}
+
+func (s *HygieneReportService) saveLocked() {
+	data, err := json.MarshalIndent(s.reports, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.path), 0755); err == nil {
+			tmpPath := s.path + ".tmp"
+			if err = os.WriteFile(tmpPath, data, 0644); err == nil {
+				err = os.Rename(tmpPath, s.path)
+			}
+		}
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to persist hygiene reports: %v", err)
+	}
+}
+
+// nextRun returns the next time the nightly report is due after now, at the
+// report hour in the instance's time zone
+func (s *HygieneReportService) nextRun(now time.Time) time.Time {
+	s.mu.Lock()
+	hour := s.hour
+	s.mu.Unlock()
+
+	now = now.In(InstanceLocation())
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Start generates a report every night at the configured hour
+func (s *HygieneReportService) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	hour := s.hour
+	s.mu.Unlock()
+
+	logger.Infof("🧹 Workspace hygiene report scheduled nightly at %02d:00 %s", hour, zoneName(InstanceLocation()))
+
+	go func() {
+		timer := time.NewTimer(s.nextRun(s.now()).Sub(s.now()))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-timer.C:
+				s.generate(true)
+				timer.Reset(s.nextRun(s.now()).Sub(s.now()))
+			case <-s.reschedule:
+				timer.Reset(s.nextRun(s.now()).Sub(s.now()))
+			}
+		}
+	}()
+}
+
+// Stop stops the nightly schedule
+func (s *HygieneReportService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+}
+
+// Generate builds a report now, stores it and delivers it if enabled
+func (s *HygieneReportService) Generate() *HygieneReport {
+	return s.generate(false)
+}
+
+func (s *HygieneReportService) generate(scheduled bool) *HygieneReport {
+	s.generating.Lock()
+	defer s.generating.Unlock()
+
+	report := s.buildReport(scheduled)
+	s.mu.Lock()
+	s.reports = append([]*HygieneReport{report}, s.reports...)
+	if len(s.reports) > maxHygieneReports {
+		s.reports = s.reports[:maxHygieneReports]
+	}
+	s.saveLocked()
+	notify := s.notify
+	s.mu.Unlock()
+
+	logger.Infof("🧹 Hygiene report %s: %s", report.ID, report.Summary())
+	if notify && s.events != nil {
+		s.events.EmitHygieneReport(report)
+	}
+	return report
+}
+
+func (s *HygieneReportService) buildReport(scheduled bool) *HygieneReport {
+	s.mu.Lock()
+	staleDays := s.staleDays
+	s.mu.Unlock()
+
+	now := s.now().In(InstanceLocation())
+	report := &HygieneReport{
+		ID:               uuid.New().String(),
+		GeneratedAt:      now,
+		StaleAfterDays:   staleDays,
+		Scheduled:        scheduled,
+		StaleWorktrees:   []HygieneWorktree{},
+		FailingSetups:    []SetupFailure{},
+		UnmergedBranches: []HygieneWorktree{},
+		Disk:             HygieneDiskUsage{Worktrees: []HygieneWorktree{}},
+		ClaudeSpend:      HygieneClaudeSpend{Worktrees: []HygieneWorktree{}},
+	}
+	staleAfter := time.Duration(staleDays) * 24 * time.Hour
+
+	var worktrees []*models.Worktree
+	if s.sources.Worktrees != nil {
+		worktrees = s.sources.Worktrees()
+	}
+	report.WorktreeCount = len(worktrees)
+
+	for _, wt := range worktrees {
+		entry := HygieneWorktree{
+			ID:             wt.ID,
+			Name:           wt.Name,
+			RepoID:         wt.RepoID,
+			Branch:         wt.Branch,
+			Path:           wt.Path,
+			LastAccessed:   wt.LastAccessed,
+			CommitCount:    wt.CommitCount,
+			IsDirty:        wt.IsDirty,
+			PullRequestURL: wt.PullRequestURL,
+		}
+
+		lastTouched := wt.LastAccessed
+		if lastTouched.IsZero() {
+			lastTouched = wt.CreatedAt
+		}
+		if idle := now.Sub(lastTouched); !lastTouched.IsZero() && idle >= staleAfter {
+			stale := entry
+			stale.AgeDays = int(idle.Hours() / 24)
+			report.StaleWorktrees = append(report.StaleWorktrees, stale)
+		}
+
+		// Branches with work on them that never made it in
+		if age := now.Sub(wt.CreatedAt); wt.CommitCount > 0 && !wt.CreatedAt.IsZero() && age >= staleAfter &&
+			!strings.EqualFold(wt.PullRequestState, "MERGED") {
+			unmerged := entry
+			unmerged.AgeDays = int(age.Hours() / 24)
+			report.UnmergedBranches = append(report.UnmergedBranches, unmerged)
+		}
+
+		if s.sources.DiskLayout != nil {
+			if layout, err := s.sources.DiskLayout(wt.Path); err != nil {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("disk usage of %s: %v", wt.Name, err))
+			} else {
+				usage := entry
+				usage.Bytes = layout.TotalBytes
+				usage.ReclaimableBytes = layout.ReclaimableBytes
+				report.Disk.TotalBytes += layout.TotalBytes
+				report.Disk.ReclaimableBytes += layout.ReclaimableBytes
+				report.Disk.Worktrees = append(report.Disk.Worktrees, usage)
+			}
+		}
+
+		if s.sources.AgentUsage != nil {
+			if usage, err := s.sources.AgentUsage(wt.Path); err != nil {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("Claude usage of %s: %v", wt.Name, err))
+			} else if usage.Sessions > 0 {
+				report.ClaudeSpend.Add(usage)
+				spend := entry
+				spend.EstimatedCostUSD = usage.EstimatedCostUSD
+				report.ClaudeSpend.Worktrees = append(report.ClaudeSpend.Worktrees, spend)
+			}
+		}
+	}
+
+	if s.sources.SetupFailures != nil {
+		report.FailingSetups = append(report.FailingSetups, s.sources.SetupFailures()...)
+	}
+
+	sort.Slice(report.StaleWorktrees, func(i, j int) bool { return report.StaleWorktrees[i].AgeDays > report.StaleWorktrees[j].AgeDays })
+	sort.Slice(report.UnmergedBranches, func(i, j int) bool {
+		return report.UnmergedBranches[i].AgeDays > report.UnmergedBranches[j].AgeDays
+	})
+	sort.Slice(report.FailingSetups, func(i, j int) bool {
+		return report.FailingSetups[i].FinishedAt.After(report.FailingSetups[j].FinishedAt)
+	})
+	sort.Slice(report.Disk.Worktrees, func(i, j int) bool { return report.Disk.Worktrees[i].Bytes > report.Disk.Worktrees[j].Bytes })
+	if len(report.Disk.Worktrees) > hygieneReportTopN {
+		report.Disk.Worktrees = report.Disk.Worktrees[:hygieneReportTopN]
+	}
+	sort.Slice(report.ClaudeSpend.Worktrees, func(i, j int) bool {
+		return report.ClaudeSpend.Worktrees[i].EstimatedCostUSD > report.ClaudeSpend.Worktrees[j].EstimatedCostUSD
+	})
+	if len(report.ClaudeSpend.Worktrees) > hygieneReportTopN {
+		report.ClaudeSpend.Worktrees = report.ClaudeSpend.Worktrees[:hygieneReportTopN]
+	}
+	return report
+}
+
+// List returns stored reports, newest first
+func (s *HygieneReportService) List() []*HygieneReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*HygieneReport{}, s.reports...)
+}
+
+// Get returns a stored report by ID, or the newest report for "latest"
+func (s *HygieneReportService) Get(id string) (*HygieneReport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id == "latest" {
+		if len(s.reports) == 0 {
+			return nil, false
+		}
+		return s.reports[0], true
+	}
+	for _, report := range s.reports {
+		if report.ID == id {
+			return report, true
+		}
+	}
+	return nil, false
+}
+
+func formatHygieneBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return localePrinter().Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type recordingHygieneEvents struct {
+	reports []*HygieneReport
+}
+
+func (r *recordingHygieneEvents) EmitHygieneReport(report *HygieneReport) {
+	r.reports = append(r.reports, report)
+}
+
+func TestHygieneReportFlagsWorktrees(t *testing.T) {
+	now := time.Date(2026, 3, 20, 3, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	worktrees := []*models.Worktree{
+		{ID: "fresh", Name: "fresh", Path: "/w/fresh", CreatedAt: now.Add(-2 * day), LastAccessed: now.Add(-time.Hour), CommitCount: 3},
+		{ID: "idle", Name: "idle", Path: "/w/idle", CreatedAt: now.Add(-40 * day), LastAccessed: now.Add(-20 * day), CommitCount: 2},
+		{ID: "merged", Name: "merged", Path: "/w/merged", CreatedAt: now.Add(-30 * day), LastAccessed: now.Add(-day), CommitCount: 1, PullRequestState: "MERGED"},
+		{ID: "broken", Name: "broken", Path: "/w/broken", CreatedAt: now.Add(-day), LastAccessed: now},
+	}
+	sizes := map[string]int64{"/w/fresh": 100, "/w/idle": 5000, "/w/merged": 300}
+	costs := map[string]float64{"/w/fresh": 1.5, "/w/idle": 0.25}
+
+	service := NewHygieneReportServiceWithOptions(filepath.Join(t.TempDir(), "hygiene_reports.json"), HygieneReportSources{
+		Worktrees: func() []*models.Worktree { return worktrees },
+		SetupFailures: func() []SetupFailure {
+			return []SetupFailure{{WorkDir: "/w/broken", SessionID: "broken", Error: "exit status 1", FinishedAt: now}}
+		},
+		DiskLayout: func(path string) (*DiskLayout, error) {
+			size, ok := sizes[path]
+			if !ok {
+				return nil, errors.New("no such directory")
+			}
+			return &DiskLayout{Path: path, TotalBytes: size, ReclaimableBytes: size / 2}, nil
+		},
+		AgentUsage: func(path string) (models.AgentUsage, error) {
+			if cost, ok := costs[path]; ok {
+				return models.AgentUsage{Sessions: 1, EstimatedCostUSD: cost}, nil
+			}
+			return models.AgentUsage{}, nil
+		},
+	}, 3, 14)
+	service.now = func() time.Time { return now }
+
+	report := service.Generate()
+	assert.Equal(t, 4, report.WorktreeCount)
+	require.Len(t, report.StaleWorktrees, 1)
+	assert.Equal(t, "idle", report.StaleWorktrees[0].ID)
+	assert.Equal(t, 20, report.StaleWorktrees[0].AgeDays)
+	require.Len(t, report.UnmergedBranches, 1)
+	assert.Equal(t, "idle", report.UnmergedBranches[0].ID)
+	require.Len(t, report.FailingSetups, 1)
+	assert.Equal(t, "/w/broken", report.FailingSetups[0].WorkDir)
+
+	assert.Equal(t, int64(5400), report.Disk.TotalBytes)
+	assert.Equal(t, int64(2700), report.Disk.ReclaimableBytes)
+	assert.Equal(t, "idle", report.Disk.Worktrees[0].ID)
+	assert.Len(t, report.Warnings, 1)
+
+	assert.Equal(t, 2, report.ClaudeSpend.Sessions)
+	assert.InDelta(t, 1.75, report.ClaudeSpend.EstimatedCostUSD, 0.001)
+	assert.Equal(t, "fresh", report.ClaudeSpend.Worktrees[0].ID)
+}
+
+func TestHygieneReportStorageAndDelivery(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hygiene_reports.json")
+	events := &recordingHygieneEvents{}
+	service := NewHygieneReportServiceWithOptions(path, HygieneReportSources{}, 3, 14).WithEvents(events)
+
+	first := service.Generate()
+	assert.Empty(t, events.reports, "reports are only delivered when notifications are enabled")
+
+	service.WithNotify(true)
+	second := service.Generate()
+	require.Len(t, events.reports, 1)
+	assert.Equal(t, second.ID, events.reports[0].ID)
+
+	// Reports survive a restart, newest first
+	reloaded := NewHygieneReportServiceWithOptions(path, HygieneReportSources{}, 3, 14)
+	reports := reloaded.List()
+	require.Len(t, reports, 2)
+	assert.Equal(t, second.ID, reports[0].ID)
+	assert.Equal(t, first.ID, reports[1].ID)
+
+	latest, ok := reloaded.Get("latest")
+	require.True(t, ok)
+	assert.Equal(t, second.ID, latest.ID)
+	_, ok = reloaded.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestHygieneReportNextRun(t *testing.T) {
+	t.Setenv(TimeZoneSetting, "UTC")
+	service := NewHygieneReportServiceWithOptions(filepath.Join(t.TempDir(), "hygiene_reports.json"), HygieneReportSources{}, 3, 14)
+
+	before := time.Date(2026, 3, 20, 1, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 20, 3, 0, 0, 0, time.UTC), service.nextRun(before))
+
+	after := time.Date(2026, 3, 20, 3, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 21, 3, 0, 0, 0, time.UTC), service.nextRun(after))
+
+	// The hour is in the instance's time zone, wherever the clock is
+	t.Setenv(TimeZoneSetting, "America/Los_Angeles")
+	assert.True(t, time.Date(2026, 3, 20, 10, 0, 0, 0, time.UTC).Equal(service.nextRun(before)), "3:00 PDT")
+}
+
+func TestHygieneReportReconfigure(t *testing.T) {
+	t.Setenv("CATNIP_HYGIENE_REPORT_HOUR", "5")
+	t.Setenv("CATNIP_HYGIENE_STALE_DAYS", "bogus")
+	t.Setenv("CATNIP_HYGIENE_REPORT_NOTIFY", "true")
+
+	s := NewHygieneReportServiceWithOptions(filepath.Join(t.TempDir(), "reports.json"), HygieneReportSources{}, 3, 14)
+	s.ReloadConfig()
+	assert.Equal(t, 5, s.hour)
+	assert.Equal(t, defaultHygieneStaleDays, s.staleDays, "invalid values fall back to the default")
+	assert.True(t, s.notify)
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// linkedIssueMaxComments is how many of an issue's latest comments are kept
+	linkedIssueMaxComments = 5
+	// linkedIssueMaxBody caps the issue body and each comment in Claude's context
+	linkedIssueMaxBody = 8000
+)
+
+// LinkIssue fetches a GitHub issue with gh and links it to a worktree. New
+// Claude sessions in the worktree get the issue as context, and pull requests
+// created from it reference the issue so merging closes it.
+func (s *GitService) LinkIssue(worktreeID string, number int) (*models.LinkedIssue, error) {
+	if number <= 0 {
+		return nil, fmt.Errorf("invalid issue number: %d", number)
+	}
+
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		s.mu.RUnlock()
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	s.mu.RUnlock()
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
+	}
+
+	ownerRepo, err := s.githubManager.ResolveOwnerRepo(worktree, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot link issue: %v", err)
+	}
+
+	issue, err := s.githubManager.GetIssue(ownerRepo, number, linkedIssueMaxComments)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"linked_issue": issue,
+	}); err != nil {
+		return nil, err
+	}
+	logger.Infof("🔗 Linked issue %s#%d to worktree %s", ownerRepo, number, worktree.Name)
+	return issue, nil
+}
+
+// UnlinkIssue removes the issue linked to a worktree
+func (s *GitService) UnlinkIssue(worktreeID string) error {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"linked_issue": (*models.LinkedIssue)(nil),
+	})
+}
+
+// IssueContextForPath returns the linked issue of the worktree at path,
+// formatted as context for a new Claude session ("" if none is linked)
+func (s *GitService) IssueContextForPath(worktreePath string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.Path == worktreePath && worktree.LinkedIssue != nil {
+			return issueContextPrompt(worktree.LinkedIssue)
+		}
+	}
+	return ""
+}
+
+// issueContextPrompt formats an issue and its comments for Claude's system prompt
+func issueContextPrompt(issue *models.LinkedIssue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "This worktree is dedicated to GitHub issue %s#%d: %s\n", issue.Repository, issue.Number, issue.Title)
+	if issue.URL != "" {
+		fmt.Fprintf(&b, "%s\n", issue.URL)
+	}
+	b.WriteString("Treat the issue below as the task description.\n\n")
+
+	b.WriteString("<issue>\n")
+	b.WriteString(truncateIssueText(issue.Body))
+	b.WriteString("\n</issue>\n")
+
+	for _, comment := range issue.Comments {
+		fmt.Fprintf(&b, "\n<comment author=%q date=%q>\n%s\n</comment>\n",
+			comment.Author, comment.CreatedAt.Format("2006-01-02"), truncateIssueText(comment.Body))
+	}
+	return b.String()
+}
+
+func truncateIssueText(text string) string {
+	text = strings.TrimSpace(text)
+	if len(text) <= linkedIssueMaxBody {
+		return text
+	}
+	return text[:linkedIssueMaxBody] + "\n[truncated]"
+}
+
+// withIssueReference appends "Fixes #N" to a PR body unless it already
+// references the issue with a closing keyword
+func withIssueReference(body string, issue *models.LinkedIssue) string {
+	if issue == nil {
+		return body
+	}
+	closes := regexp.MustCompile(fmt.Sprintf(`(?i)\b(close[sd]?|fix(e[sd])?|resolve[sd]?)\s+(%s)?#%d\b`,
+		regexp.QuoteMeta(issue.Repository), issue.Number))
+	if closes.MatchString(body) {
+		return body
+	}
+
+	reference := fmt.Sprintf("Fixes #%d", issue.Number)
+	if strings.TrimSpace(body) == "" {
+		return reference
+	}
+	return strings.TrimRight(body, "\n") + "\n\n" + reference
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWithIssueReference(t *testing.T) {
+	issue := &models.LinkedIssue{Number: 42, Repository: "acme/app"}
+
+	assert.Equal(t, "Adds retries\n\nFixes #42", withIssueReference("Adds retries\n", issue))
+	assert.Equal(t, "Fixes #42", withIssueReference("", issue))
+	assert.Equal(t, "Closes #42 by adding retries", withIssueReference("Closes #42 by adding retries", issue))
+	assert.Equal(t, "resolves acme/app#42", withIssueReference("resolves acme/app#42", issue))
+	assert.Equal(t, "Related to #42\n\nFixes #42", withIssueReference("Related to #42", issue), "a plain mention doesn't close the issue")
+	assert.Equal(t, "Fixes #420\n\nFixes #42", withIssueReference("Fixes #420", issue))
+	assert.Equal(t, "Adds retries", withIssueReference("Adds retries", nil))
+}
+
+func TestIssueContext(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-issue", RepoID: "acme/app", Name: "feature", Path: dir}))
+	assert.Empty(t, service.IssueContextForPath(dir))
+
+	issue := &models.LinkedIssue{
+		Number:     42,
+		Repository: "acme/app",
+		Title:      "Retries are missing",
+		Body:       "Requests fail on the first timeout.",
+		URL:        "https://github.com/acme/app/issues/42",
+		Comments: []models.IssueComment{
+			{Author: "octocat", Body: "Happens on mobile too.", CreatedAt: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	require.NoError(t, service.stateManager.UpdateWorktree("wt-issue", map[string]interface{}{"linked_issue": issue}))
+
+	context := service.IssueContextForPath(dir)
+	assert.Contains(t, context, "acme/app#42: Retries are missing")
+	assert.Contains(t, context, "<issue>\nRequests fail on the first timeout.\n</issue>")
+	assert.Contains(t, context, `<comment author="octocat" date="2026-03-01">`)
+	assert.Empty(t, service.IssueContextForPath(t.TempDir()))
+
+	issue.Body = strings.Repeat("x", linkedIssueMaxBody+10)
+	assert.Contains(t, issueContextPrompt(issue), "[truncated]")
+
+	require.NoError(t, service.UnlinkIssue("wt-issue"))
+	assert.Empty(t, service.IssueContextForPath(dir))
+	assert.Error(t, service.UnlinkIssue("missing"))
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// IssueWebhookSecretName is the secret store entry holding the webhook secret
+	IssueWebhookSecretName = "github-webhook-secret"
+	// Label that turns an issue into a workspace, unless CATNIP_ISSUE_WEBHOOK_LABEL says otherwise
+	defaultIssueWebhookLabel = "agent:fix"
+	// Provisioning runs kept in memory for GET requests
+	maxIssueWorkspaceRuns = 50
+)
+
+// Issue workspace provisioning statuses
+const (
+	IssueWorkspaceRunning   = "running"
+	IssueWorkspaceSucceeded = "succeeded"
+	IssueWorkspaceFailed    = "failed"
+)
+
+// AgentSessionStarter opens an agent session in a workspace and submits a
+// first prompt once the agent is ready
+type AgentSessionStarter func(workspace, agent, prompt string) error
+
+// IssueWorkspaceRun is the provisioning of a workspace for a labeled issue
+type IssueWorkspaceRun struct {
+	ID string `json:"id"`
+	// X-GitHub-Delivery of the webhook that triggered the run
+	Delivery     string `json:"delivery,omitempty"`
+	Repository   string `json:"repository" example:"acme/app"`
+	Issue        int    `json:"issue" example:"42"`
+	IssueTitle   string `json:"issue_title"`
+	Status       string `json:"status" example:"succeeded"`
+	WorktreeID   string `json:"worktree_id,omitempty"`
+	WorktreeName string `json:"worktree_name,omitempty"`
+	WorkspaceURL string `json:"workspace_url,omitempty"`
+	// Whether Claude was started with the issue
+	SessionStarted bool `json:"session_started"`
+	// Whether the workspace link was posted on the issue
+	Commented  bool       `json:"commented"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// IssueWebhookResult tells GitHub what a delivery did
+type IssueWebhookResult struct {
+	// Why the delivery was ignored, empty when it started a run
+	Ignored string             `json:"ignored,omitempty"`
+	Run     *IssueWorkspaceRun `json:"run,omitempty"`
+}
+
+// issuesEvent is the part of a GitHub "issues" webhook payload used here
+type issuesEvent struct {
+	Action string `json:"action"`
+	Label  *struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Issue struct {
+		Number      int    `json:"number"`
+		Title       string `json:"title"`
+		State       string `json:"state"`
+		PullRequest any    `json:"pull_request"`
+	} `json:"issue"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// IssueWebhookService turns GitHub issues labeled agent:fix into running
+// agent workspaces: it creates a worktree, links the issue, starts Claude on
+// it and comments on the issue with a link to the workspace
+type IssueWebhookService struct {
+	secrets      *SecretStore
+	startSession AgentSessionStarter
+	mu           sync.Mutex
+	runs         []*IssueWorkspaceRun
+
+	// Hooks into git and GitHub, replaced in tests
+	checkout          func(org, repo string) (*models.Worktree, error)
+	linkIssue         func(worktreeID string, number int) (*models.LinkedIssue, error)
+	findIssueWorktree func(ownerRepo string, number int) *models.Worktree
+	comment           func(ownerRepo string, number int, body string) error
+}
+
+// NewIssueWebhookService creates an issue webhook service provisioning
+// workspaces with gitService
+func NewIssueWebhookService(gitService *GitService, secrets *SecretStore) *IssueWebhookService {
+	return &IssueWebhookService{
+		secrets: secrets,
+		checkout: func(org, repo string) (*models.Worktree, error) {
+			_, worktree, err := gitService.CheckoutRepository(org, repo, "")
+			return worktree, err
+		},
+		linkIssue:         gitService.LinkIssue,
+		findIssueWorktree: gitService.findIssueWorktree,
+		comment:           gitService.githubManager.CommentOnIssue,
+	}
+}
+
+// WithSessionStarter starts Claude in provisioned workspaces
+func (s *IssueWebhookService) WithSessionStarter(starter AgentSessionStarter) *IssueWebhookService {
+	s.startSession = starter
+	return s
+}
+
+// Label returns the issue label that provisions a workspace
+func (s *IssueWebhookService) Label() string {
+	if label := strings.TrimSpace(os.Getenv("CATNIP_ISSUE_WEBHOOK_LABEL")); label != "" {
+		return label
+	}
+	return defaultIssueWebhookLabel
+}
+
+// secret returns the webhook secret from the secret store, or from
+// CATNIP_GITHUB_WEBHOOK_SECRET
+func (s *IssueWebhookService) secret() string {
+	if s.secrets != nil {
+		if secret, err := s.secrets.Get(IssueWebhookSecretName); err == nil && secret != "" {
+			return secret
+		}
+	}
+	return os.Getenv("CATNIP_GITHUB_WEBHOOK_SECRET")
+}
+
+// VerifySignature checks an X-Hub-Signature-256 header against the payload.
+// Deliveries are refused until a webhook secret is configured.
+func (s *IssueWebhookService) VerifySignature(signature string, payload []byte) error {
+	secret := s.secret()
+	if secret == "" {
+		return models.NewAPIError(models.ErrCodeInvalidSignature, "no GitHub webhook secret is configured to verify deliveries").
+			WithHint(fmt.Sprintf("Store the webhook's secret as %s with PUT /v1/secrets/%s", IssueWebhookSecretName, IssueWebhookSecretName))
+	}
+	if signature == "" {
+		return models.NewAPIError(models.ErrCodeInvalidSignature, "webhook delivery is not signed")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return models.NewAPIError(models.ErrCodeInvalidSignature, "webhook signature doesn't match")
+	}
+	return nil
+}
+
+// HandleDelivery handles a verified webhook delivery. Issues that get the
+// provisioning label start a run in the background; everything else is
+// ignored with a reason.
+func (s *IssueWebhookService) HandleDelivery(event, delivery string, payload []byte) (*IssueWebhookResult, error) {
+	switch event {
+	case "ping":
+		return &IssueWebhookResult{Ignored: "ping"}, nil
+	case "issues":
+	default:
+		return &IssueWebhookResult{Ignored: fmt.Sprintf("%s events aren't handled", event)}, nil
+	}
+
+	var payloadEvent issuesEvent
+	if err := json.Unmarshal(payload, &payloadEvent); err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid issues payload: %v", err)
+	}
+	label := s.Label()
+	switch {
+	case payloadEvent.Action != "labeled":
+		return &IssueWebhookResult{Ignored: fmt.Sprintf("issue was %s", payloadEvent.Action)}, nil
+	case payloadEvent.Label == nil || payloadEvent.Label.Name != label:
+		return &IssueWebhookResult{Ignored: fmt.Sprintf("label isn't %s", label)}, nil
+	case payloadEvent.Issue.PullRequest != nil:
+		return &IssueWebhookResult{Ignored: "pull requests aren't provisioned"}, nil
+	case strings.EqualFold(payloadEvent.Issue.State, "closed"):
+		return &IssueWebhookResult{Ignored: "issue is closed"}, nil
+	}
+
+	ownerRepo := payloadEvent.Repository.FullName
+	org, repo, found := strings.Cut(ownerRepo, "/")
+	if !found || org == "" || repo == "" || payloadEvent.Issue.Number <= 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "payload is missing the repository or issue number")
+	}
+
+	// The run reserves the issue before the lock is released, so redeliveries
+	// and deliveries racing for the same issue can't both provision. Failed
+	// runs release the reservation and a retry provisions again.
+	run := &IssueWorkspaceRun{
+		ID:         uuid.New().String(),
+		Delivery:   delivery,
+		Repository: ownerRepo,
+		Issue:      payloadEvent.Issue.Number,
+		IssueTitle: payloadEvent.Issue.Title,
+		Status:     IssueWorkspaceRunning,
+		StartedAt:  time.Now(),
+	}
+	s.mu.Lock()
+	for _, existing := range s.runs {
+		if existing.Status == IssueWorkspaceFailed {
+			continue
+		}
+		if (delivery != "" && existing.Delivery == delivery) ||
+			(existing.Repository == ownerRepo && existing.Issue == run.Issue && existing.Status == IssueWorkspaceRunning) {
+			s.mu.Unlock()
+			return &IssueWebhookResult{Ignored: "already provisioning", Run: existing.snapshot()}, nil
+		}
+	}
+	s.runs = append(s.runs, run)
+	s.pruneLocked()
+	snapshot := run.snapshot()
+	s.mu.Unlock()
+
+	if worktree := s.findIssueWorktree(ownerRepo, run.Issue); worktree != nil {
+		s.release(run)
+		return &IssueWebhookResult{Ignored: fmt.Sprintf("issue already has workspace %s", worktree.Name)}, nil
+	}
+
+	logger.Infof("🎫 Provisioning a workspace for %s#%d (%s)", ownerRepo, run.Issue, run.IssueTitle)
+	go s.provision(run, org, repo)
+	return &IssueWebhookResult{Run: snapshot}, nil
+}
+
+// provision creates the workspace, links the issue, starts Claude and comments on the issue
+func (s *IssueWebhookService) provision(run *IssueWorkspaceRun, org, repo string) {
+	worktree, err := s.checkout(org, repo)
+	if err != nil {
+		s.finish(run, fmt.Errorf("failed to create a worktree: %v", err))
+		return
+	}
+	s.update(run, func(run *IssueWorkspaceRun) {
+		run.WorktreeID = worktree.ID
+		run.WorktreeName = worktree.Name
+		run.WorkspaceURL = config.Runtime.PublicURL("/workspace/" + worktree.Name)
+	})
+
+	issue, err := s.linkIssue(worktree.ID, run.Issue)
+	if err != nil {
+		s.finish(run, fmt.Errorf("failed to link the issue: %v", err))
+		return
+	}
+
+	// The linked issue is in the new session's context, so the prompt only has to point at it
+	if s.startSession != nil {
+		prompt := fmt.Sprintf("Fix GitHub issue #%d: %s. The issue and its latest comments are in your context. Investigate, implement the fix with tests, and commit it.", issue.Number, issue.Title)
+		if err := s.startSession(worktree.Name, "claude", prompt); err != nil {
+			s.finish(run, fmt.Errorf("failed to start Claude: %v", err))
+			return
+		}
+		s.update(run, func(run *IssueWorkspaceRun) { run.SessionStarted = true })
+	}
+
+	if err := s.comment(run.Repository, run.Issue, issueWorkspaceComment(worktree, run.WorkspaceURL)); err != nil {
+		// The workspace is running; only the link back is missing
+		logger.Warnf("⚠️ Failed to comment on %s#%d: %v", run.Repository, run.Issue, err)
+		s.update(run, func(run *IssueWorkspaceRun) { run.Error = err.Error() })
+	} else {
+		s.update(run, func(run *IssueWorkspaceRun) { run.Commented = true })
+	}
+	s.finish(run, nil)
+}
+
+// issueWorkspaceComment is the comment posted on a provisioned issue
+func issueWorkspaceComment(worktree *models.Worktree, workspaceURL string) string {
+	comment := fmt.Sprintf("🐱 Claude is working on this issue in catnip workspace `%s`.\n", worktree.Name)
+	if config.Runtime.HasExternalURL() {
+		comment += fmt.Sprintf("\n[Open workspace in catnip](%s)\n", workspaceURL)
+	}
+	return comment
+}
+
+func (s *IssueWebhookService) update(run *IssueWorkspaceRun, fn func(run *IssueWorkspaceRun)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(run)
+}
+
+func (s *IssueWebhookService) finish(run *IssueWorkspaceRun, err error) {
+	s.update(run, func(run *IssueWorkspaceRun) {
+		now := time.Now()
+		run.FinishedAt = &now
+		run.Status = IssueWorkspaceSucceeded
+		if err != nil {
+			run.Status = IssueWorkspaceFailed
+			run.Error = err.Error()
+		}
+	})
+	if err != nil {
+		logger.Warnf("⚠️ Provisioning a workspace for %s#%d failed: %v", run.Repository, run.Issue, err)
+	} else {
+		logger.Infof("🎫 Workspace %s is working on %s#%d", run.WorktreeName, run.Repository, run.Issue)
+	}
+}
+
+// release drops a run that never started provisioning
+func (s *IssueWebhookService) release(run *IssueWorkspaceRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, existing := range s.runs {
+		if existing == run {
+			s.runs = append(s.runs[:i], s.runs[i+1:]...)
+			return
+		}
+	}
+}
+
+// pruneLocked drops the oldest finished runs beyond the limit. The caller must hold mu.
+func (s *IssueWebhookService) pruneLocked() {
+	for len(s.runs) > maxIssueWorkspaceRuns {
+		pruned := false
+		for i, run := range s.runs {
+			if run.Status != IssueWorkspaceRunning {
+				s.runs = append(s.runs[:i], s.runs[i+1:]...)
+				pruned = true
+				break
+			}
+		}
+		if !pruned {
+			return
+		}
+	}
+}
+
+// Runs returns provisioning runs, newest first
+func (s *IssueWebhookService) Runs() []*IssueWorkspaceRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	runs := make([]*IssueWorkspaceRun, 0, len(s.runs))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		runs = append(runs, s.runs[i].snapshot())
+	}
+	return runs
+}
+
+func (r *IssueWorkspaceRun) snapshot() *IssueWorkspaceRun {
+	copied := *r
+	return &copied
+}
+
+// findIssueWorktree returns the worktree an issue is linked to, if any
+func (s *GitService) findIssueWorktree(ownerRepo string, number int) *models.Worktree {
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if issue := worktree.LinkedIssue; issue != nil && issue.Number == number && strings.EqualFold(issue.Repository, ownerRepo) {
+			return worktree
+		}
+	}
+	return nil
+}
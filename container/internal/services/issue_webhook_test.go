@@ -0,0 +1,172 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func signWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func waitForIssueWorkspaceRun(t *testing.T, s *IssueWebhookService) *IssueWorkspaceRun {
+	t.Helper()
+	var run *IssueWorkspaceRun
+	require.Eventually(t, func() bool {
+		run = s.Runs()[0]
+		return run.Status != IssueWorkspaceRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return run
+}
+
+func TestIssueWebhookVerifiesSignatures(t *testing.T) {
+	t.Setenv("CATNIP_GITHUB_WEBHOOK_SECRET", "")
+	secrets := NewSecretStoreWithPath(t.TempDir())
+	s := &IssueWebhookService{secrets: secrets}
+	payload := []byte(`{"action":"labeled"}`)
+
+	assert.Error(t, s.VerifySignature(signWebhook("", payload), payload), "unsigned deliveries are refused without a secret")
+
+	require.NoError(t, secrets.Set(IssueWebhookSecretName, "hunter2"))
+	assert.NoError(t, s.VerifySignature(signWebhook("hunter2", payload), payload))
+	assert.Equal(t, models.ErrCodeInvalidSignature, models.ErrorCodeOf(s.VerifySignature(signWebhook("wrong", payload), payload)))
+	assert.Equal(t, models.ErrCodeInvalidSignature, models.ErrorCodeOf(s.VerifySignature("", payload)), "unsigned deliveries fail authentication")
+}
+
+func TestIssueWebhookProvisionsLabeledIssues(t *testing.T) {
+	t.Setenv("CATNIP_ISSUE_WEBHOOK_LABEL", "")
+	var linked, prompted, commented string
+	var existing *models.Worktree
+	s := &IssueWebhookService{
+		checkout: func(org, repo string) (*models.Worktree, error) {
+			return &models.Worktree{ID: "wt-1", Name: repo + "/felix"}, nil
+		},
+		linkIssue: func(worktreeID string, number int) (*models.LinkedIssue, error) {
+			linked = worktreeID
+			return &models.LinkedIssue{Number: number, Repository: "acme/app", Title: "Login is broken"}, nil
+		},
+		findIssueWorktree: func(ownerRepo string, number int) *models.Worktree { return existing },
+		comment: func(ownerRepo string, number int, body string) error {
+			commented = body
+			return nil
+		},
+	}
+	s.WithSessionStarter(func(workspace, agent, prompt string) error {
+		prompted = workspace + ":" + agent + " " + prompt
+		return nil
+	})
+
+	labeled := []byte(`{"action":"labeled","label":{"name":"agent:fix"},"issue":{"number":42,"title":"Login is broken","state":"open"},"repository":{"full_name":"acme/app"}}`)
+	for event, payload := range map[string][]byte{
+		"ping":   []byte(`{}`),
+		"push":   labeled,
+		"issues": []byte(`{"action":"labeled","label":{"name":"bug"},"issue":{"number":42},"repository":{"full_name":"acme/app"}}`),
+	} {
+		result, err := s.HandleDelivery(event, "", payload)
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Ignored, event)
+	}
+	assert.Empty(t, s.Runs())
+
+	result, err := s.HandleDelivery("issues", "delivery-1", labeled)
+	require.NoError(t, err)
+	require.NotNil(t, result.Run)
+	assert.Empty(t, result.Ignored)
+
+	run := waitForIssueWorkspaceRun(t, s)
+	assert.Equal(t, IssueWorkspaceSucceeded, run.Status, run.Error)
+	assert.Equal(t, "app/felix", run.WorktreeName)
+	assert.Equal(t, "wt-1", linked)
+	assert.Contains(t, prompted, "app/felix:claude Fix GitHub issue #42: Login is broken")
+	assert.Contains(t, commented, "`app/felix`")
+	assert.True(t, run.SessionStarted)
+	assert.True(t, run.Commented)
+
+	// Redeliveries and issues that already have a workspace don't provision again
+	result, err = s.HandleDelivery("issues", "delivery-1", labeled)
+	require.NoError(t, err)
+	assert.Equal(t, "already provisioning", result.Ignored)
+	existing = &models.Worktree{Name: "app/felix"}
+	result, err = s.HandleDelivery("issues", "delivery-2", labeled)
+	require.NoError(t, err)
+	assert.Contains(t, result.Ignored, "already has workspace")
+	assert.Len(t, s.Runs(), 1)
+
+	// A custom label is honored, and failures are reported on the run
+	t.Setenv("CATNIP_ISSUE_WEBHOOK_LABEL", "catnip")
+	existing = nil
+	s.startSession = func(workspace, agent, prompt string) error { return errors.New("claude isn't installed") }
+	result, err = s.HandleDelivery("issues", "delivery-3", labeled)
+	require.NoError(t, err)
+	assert.Contains(t, result.Ignored, "label isn't catnip")
+	_, err = s.HandleDelivery("issues", "delivery-4", []byte(`{"action":"labeled","label":{"name":"catnip"},"issue":{"number":7,"title":"Crash","state":"open"},"repository":{"full_name":"acme/app"}}`))
+	require.NoError(t, err)
+	run = waitForIssueWorkspaceRun(t, s)
+	assert.Equal(t, IssueWorkspaceFailed, run.Status)
+	assert.Contains(t, run.Error, "claude isn't installed")
+	assert.False(t, run.Commented)
+}
+
+func TestIssueWebhookReservesIssueWhileProvisioning(t *testing.T) {
+	t.Setenv("CATNIP_ISSUE_WEBHOOK_LABEL", "")
+	var checkouts atomic.Int32
+	unblock := make(chan struct{})
+	var fail atomic.Bool
+	s := &IssueWebhookService{
+		checkout: func(org, repo string) (*models.Worktree, error) {
+			checkouts.Add(1)
+			<-unblock
+			if fail.Load() {
+				return nil, errors.New("disk full")
+			}
+			return &models.Worktree{ID: "wt-1", Name: repo + "/felix"}, nil
+		},
+		linkIssue: func(worktreeID string, number int) (*models.LinkedIssue, error) {
+			return &models.LinkedIssue{Number: number}, nil
+		},
+		findIssueWorktree: func(ownerRepo string, number int) *models.Worktree { return nil },
+		comment:           func(ownerRepo string, number int, body string) error { return nil },
+	}
+	s.WithSessionStarter(func(workspace, agent, prompt string) error { return nil })
+	labeled := []byte(`{"action":"labeled","label":{"name":"agent:fix"},"issue":{"number":42,"title":"Login is broken","state":"open"},"repository":{"full_name":"acme/app"}}`)
+
+	// Retries and opened+labeled deliveries arriving together provision once
+	fail.Store(true)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := s.HandleDelivery("issues", fmt.Sprintf("delivery-%d", i%2), labeled)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, s.Runs(), 1)
+	close(unblock)
+	run := waitForIssueWorkspaceRun(t, s)
+	assert.Equal(t, IssueWorkspaceFailed, run.Status)
+	assert.Equal(t, int32(1), checkouts.Load())
+
+	// A failed run releases the issue, so a redelivery provisions again
+	fail.Store(false)
+	result, err := s.HandleDelivery("issues", run.Delivery, labeled)
+	require.NoError(t, err)
+	assert.Empty(t, result.Ignored)
+	run = waitForIssueWorkspaceRun(t, s)
+	assert.Equal(t, IssueWorkspaceSucceeded, run.Status, run.Error)
+	assert.Equal(t, int32(2), checkouts.Load())
+}
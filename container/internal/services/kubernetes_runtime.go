@@ -0,0 +1,711 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vanpelt/catnip/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// WorkspaceRuntimeSetting picks where workspace sessions run: "local"
+// (default) runs them in the catnip container, "kubernetes" runs each
+// workspace's sessions in a pod of its own
+const WorkspaceRuntimeSetting = "CATNIP_WORKSPACE_RUNTIME"
+
+// WorkspaceRuntimeKubernetes runs workspace sessions in Kubernetes pods
+const WorkspaceRuntimeKubernetes = "kubernetes"
+
+// Kubernetes runtime settings
+const (
+	// Namespace for workspace pods (default: catnip's own namespace)
+	KubernetesNamespaceSetting = "CATNIP_K8S_NAMESPACE"
+	// Pod template file (YAML or JSON). Its first container runs the sessions
+	// and must see the workspace at the same path as catnip does.
+	KubernetesPodTemplateSetting = "CATNIP_K8S_POD_TEMPLATE"
+	// Image for the default pod template
+	KubernetesImageSetting = "CATNIP_K8S_IMAGE"
+	// PersistentVolumeClaim holding the workspace, mounted by the default pod
+	// template (it must allow ReadWriteMany to be shared with catnip)
+	KubernetesWorkspaceClaimSetting = "CATNIP_K8S_WORKSPACE_CLAIM"
+	// host:port sessions in pods reach catnip at, for hooks and the catnip CLI
+	// (default: this pod's IP)
+	KubernetesServerHostSetting = "CATNIP_K8S_SERVER_HOST"
+)
+
+// Labels and annotations on the objects catnip creates
+const (
+	kubernetesManagedByLabel      = "app.kubernetes.io/managed-by"
+	kubernetesPodLabel            = "catnip/pod"
+	kubernetesWorkspaceAnnotation = "catnip/workspace"
+)
+
+const (
+	defaultKubernetesImage       = "wandb/catnip:latest"
+	kubernetesServiceAccountDir  = "/var/run/secrets/kubernetes.io/serviceaccount"
+	kubernetesExecProtocol       = "v4.channel.k8s.io"
+	kubernetesPodReadyTimeout    = 3 * time.Minute
+	kubernetesPodPollInterval    = time.Second
+	kubernetesMaxPodNamePrefix   = 40
+	kubernetesExecStdinChannel   = 0
+	kubernetesExecStdoutChannel  = 1
+	kubernetesExecStderrChannel  = 2
+	kubernetesExecStatusChannel  = 3
+	kubernetesExecResizeChannel  = 4
+	kubernetesMergePatchMimeType = "application/merge-patch+json"
+)
+
+// ErrKubernetesNotFound is returned for Kubernetes objects that don't exist
+var ErrKubernetesNotFound = errors.New("not found")
+
+var kubernetesNameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// KubernetesRuntimeEnabled reports whether workspace sessions run in
+// Kubernetes pods
+func KubernetesRuntimeEnabled() bool {
+	return strings.EqualFold(os.Getenv(WorkspaceRuntimeSetting), WorkspaceRuntimeKubernetes)
+}
+
+// KubernetesClient calls the Kubernetes API with a bearer token
+type KubernetesClient struct {
+	baseURL   string
+	token     string
+	tlsConfig *tls.Config
+	http      *http.Client
+}
+
+// NewKubernetesClient creates a client for the API server at baseURL
+func NewKubernetesClient(baseURL, token string, tlsConfig *tls.Config) *KubernetesClient {
+	return &KubernetesClient{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		token:     token,
+		tlsConfig: tlsConfig,
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}
+}
+
+// NewInClusterKubernetesClient creates a client authenticated as the pod's
+// service account
+func NewInClusterKubernetesClient() (*KubernetesClient, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster (KUBERNETES_SERVICE_HOST is unset)")
+	}
+	token, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	ca, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in cluster CA")
+	}
+	baseURL := "https://" + net.JoinHostPort(host, port)
+	return NewKubernetesClient(baseURL, strings.TrimSpace(string(token)), &tls.Config{RootCAs: pool}), nil
+}
+
+// kubernetesStatus is the error body the API server responds with
+type kubernetesStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Reason  string `json:"reason"`
+	Code    int    `json:"code"`
+	Details struct {
+		Causes []struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"causes"`
+	} `json:"details"`
+}
+
+// do sends a request and decodes the response into out. It returns the
+// response status, with an error for anything but 2xx.
+func (c *KubernetesClient) do(ctx context.Context, method, path, contentType string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10*1024*1024))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode == http.StatusNotFound {
+			return resp.StatusCode, ErrKubernetesNotFound
+		}
+		var status kubernetesStatus
+		if json.Unmarshal(data, &status) == nil && status.Message != "" {
+			return resp.StatusCode, fmt.Errorf("kubernetes %s %s: %s", method, path, status.Message)
+		}
+		return resp.StatusCode, fmt.Errorf("kubernetes %s %s: %s", method, path, resp.Status)
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to parse kubernetes response: %w", err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// KubernetesWorkspacePod is the pod a workspace's sessions run in
+type KubernetesWorkspacePod struct {
+	Name      string `json:"name" example:"catnip-catnip-main-1a2b3c4d"`
+	Workspace string `json:"workspace" example:"catnip/main"`
+	Container string `json:"container" example:"workspace"`
+	Phase     string `json:"phase" example:"Running"`
+	PodIP     string `json:"pod_ip,omitempty" example:"10.4.2.17"`
+}
+
+// kubernetesPod is the part of a pod object catnip reads
+type kubernetesPod struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Containers []struct {
+			Name string `json:"name"`
+		} `json:"containers"`
+	} `json:"spec"`
+	Status struct {
+		Phase string `json:"phase"`
+		PodIP string `json:"podIP"`
+	} `json:"status"`
+}
+
+func (p *kubernetesPod) workspacePod() *KubernetesWorkspacePod {
+	pod := &KubernetesWorkspacePod{
+		Name:      p.Metadata.Name,
+		Workspace: p.Metadata.Annotations[kubernetesWorkspaceAnnotation],
+		Phase:     p.Status.Phase,
+		PodIP:     p.Status.PodIP,
+	}
+	if len(p.Spec.Containers) > 0 {
+		pod.Container = p.Spec.Containers[0].Name
+	}
+	return pod
+}
+
+// KubernetesRuntime runs each workspace's sessions in a pod of its own,
+// created from a pod template, with session ports exposed by a service
+// named after the pod
+type KubernetesRuntime struct {
+	client       *KubernetesClient
+	namespace    string
+	template     map[string]interface{}
+	readyTimeout time.Duration
+	pollInterval time.Duration
+	// Serializes pod creation so concurrent sessions share one pod
+	mu sync.Mutex
+}
+
+// NewKubernetesRuntime creates a runtime using the in-cluster service
+// account and the Kubernetes settings
+func NewKubernetesRuntime() (*KubernetesRuntime, error) {
+	client, err := NewInClusterKubernetesClient()
+	if err != nil {
+		return nil, err
+	}
+	namespace := os.Getenv(KubernetesNamespaceSetting)
+	if namespace == "" {
+		if data, err := os.ReadFile(filepath.Join(kubernetesServiceAccountDir, "namespace")); err == nil {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	template := DefaultKubernetesPodTemplate()
+	if path := os.Getenv(KubernetesPodTemplateSetting); path != "" {
+		if template, err = LoadKubernetesPodTemplate(path); err != nil {
+			return nil, err
+		}
+	}
+	return NewKubernetesRuntimeWithClient(client, namespace, template), nil
+}
+
+// NewKubernetesRuntimeWithClient creates a runtime with the given client and
+// pod template (for testing)
+func NewKubernetesRuntimeWithClient(client *KubernetesClient, namespace string, template map[string]interface{}) *KubernetesRuntime {
+	return &KubernetesRuntime{
+		client:       client,
+		namespace:    namespace,
+		template:     template,
+		readyTimeout: kubernetesPodReadyTimeout,
+		pollInterval: kubernetesPodPollInterval,
+	}
+}
+
+// DefaultKubernetesPodTemplate runs CATNIP_K8S_IMAGE with the workspace
+// claim (CATNIP_K8S_WORKSPACE_CLAIM) mounted where catnip keeps workspaces
+func DefaultKubernetesPodTemplate() map[string]interface{} {
+	image := os.Getenv(KubernetesImageSetting)
+	if image == "" {
+		image = defaultKubernetesImage
+	}
+	container := map[string]interface{}{
+		"name":    "workspace",
+		"image":   image,
+		"command": []interface{}{"sleep", "infinity"},
+	}
+	spec := map[string]interface{}{
+		"restartPolicy": "Always",
+		"containers":    []interface{}{container},
+	}
+	if claim := os.Getenv(KubernetesWorkspaceClaimSetting); claim != "" {
+		container["volumeMounts"] = []interface{}{
+			map[string]interface{}{"name": "workspace", "mountPath": config.Runtime.WorkspaceDir},
+		}
+		spec["volumes"] = []interface{}{
+			map[string]interface{}{
+				"name":                  "workspace",
+				"persistentVolumeClaim": map[string]interface{}{"claimName": claim},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"spec":       spec,
+	}
+}
+
+// LoadKubernetesPodTemplate reads a pod template from a YAML or JSON file
+func LoadKubernetesPodTemplate(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pod template: %w", err)
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse pod template %s: %w", path, err)
+	}
+	template, ok := normalizeYAMLValue(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("pod template %s is not an object", path)
+	}
+	spec, _ := template["spec"].(map[string]interface{})
+	if containers, _ := spec["containers"].([]interface{}); len(containers) == 0 {
+		return nil, fmt.Errorf("pod template %s has no containers", path)
+	}
+	return template, nil
+}
+
+// normalizeYAMLValue converts yaml.v2's map[interface{}]interface{} into
+// map[string]interface{} so the value can be encoded as JSON
+func normalizeYAMLValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			m[fmt.Sprint(key)] = normalizeYAMLValue(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range value {
+			value[i] = normalizeYAMLValue(item)
+		}
+		return value
+	}
+	return v
+}
+
+// KubernetesPodName is the pod (and service) name for a workspace: a
+// readable prefix plus a hash, since workspace names aren't valid DNS labels
+func KubernetesPodName(workspace string) string {
+	slug := strings.Trim(kubernetesNameInvalid.ReplaceAllString(strings.ToLower(workspace), "-"), "-")
+	if len(slug) > kubernetesMaxPodNamePrefix {
+		slug = strings.TrimRight(slug[:kubernetesMaxPodNamePrefix], "-")
+	}
+	sum := sha256.Sum256([]byte(workspace))
+	if slug == "" {
+		return "catnip-" + hex.EncodeToString(sum[:4])
+	}
+	return "catnip-" + slug + "-" + hex.EncodeToString(sum[:4])
+}
+
+// podSpec builds a workspace's pod from the template
+func (r *KubernetesRuntime) podSpec(workspace string) (map[string]interface{}, error) {
+	data, err := json.Marshal(r.template)
+	if err != nil {
+		return nil, err
+	}
+	var pod map[string]interface{}
+	if err := json.Unmarshal(data, &pod); err != nil {
+		return nil, err
+	}
+	name := KubernetesPodName(workspace)
+	pod["apiVersion"] = "v1"
+	pod["kind"] = "Pod"
+
+	metadata, _ := pod["metadata"].(map[string]interface{})
+	if metadata == nil {
+		metadata = map[string]interface{}{}
+	}
+	delete(metadata, "generateName")
+	metadata["name"] = name
+	metadata["namespace"] = r.namespace
+	labels, _ := metadata["labels"].(map[string]interface{})
+	if labels == nil {
+		labels = map[string]interface{}{}
+	}
+	labels[kubernetesManagedByLabel] = "catnip"
+	labels[kubernetesPodLabel] = name
+	metadata["labels"] = labels
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	annotations[kubernetesWorkspaceAnnotation] = workspace
+	metadata["annotations"] = annotations
+	pod["metadata"] = metadata
+	return pod, nil
+}
+
+func (r *KubernetesRuntime) podPath(name string) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/pods/%s", url.PathEscape(r.namespace), url.PathEscape(name))
+}
+
+func (r *KubernetesRuntime) servicePath(name string) string {
+	return fmt.Sprintf("/api/v1/namespaces/%s/services/%s", url.PathEscape(r.namespace), url.PathEscape(name))
+}
+
+func (r *KubernetesRuntime) getPod(ctx context.Context, name string) (*kubernetesPod, error) {
+	var pod kubernetesPod
+	if _, err := r.client.do(ctx, http.MethodGet, r.podPath(name), "", nil, &pod); err != nil {
+		return nil, err
+	}
+	return &pod, nil
+}
+
+// EnsurePod returns the workspace's running pod, creating it from the
+// template (and replacing one that has stopped) and waiting for it to start
+func (r *KubernetesRuntime) EnsurePod(ctx context.Context, workspace string) (*KubernetesWorkspacePod, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := KubernetesPodName(workspace)
+	pod, err := r.getPod(ctx, name)
+	if err != nil && !errors.Is(err, ErrKubernetesNotFound) {
+		return nil, err
+	}
+	if pod != nil && (pod.Status.Phase == "Succeeded" || pod.Status.Phase == "Failed") {
+		if err := r.deleteAndWait(ctx, name); err != nil {
+			return nil, err
+		}
+		pod = nil
+	}
+	if pod == nil {
+		spec, err := r.podSpec(workspace)
+		if err != nil {
+			return nil, err
+		}
+		path := fmt.Sprintf("/api/v1/namespaces/%s/pods", url.PathEscape(r.namespace))
+		if status, err := r.client.do(ctx, http.MethodPost, path, "", spec, nil); err != nil && status != http.StatusConflict {
+			return nil, fmt.Errorf("failed to create pod %s: %w", name, err)
+		}
+	}
+	return r.waitForPod(ctx, name)
+}
+
+// waitForPod polls until the pod is running
+func (r *KubernetesRuntime) waitForPod(ctx context.Context, name string) (*KubernetesWorkspacePod, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.readyTimeout)
+	defer cancel()
+	for {
+		pod, err := r.getPod(ctx, name)
+		if err != nil && !errors.Is(err, ErrKubernetesNotFound) {
+			return nil, err
+		}
+		if pod != nil {
+			switch pod.Status.Phase {
+			case "Running":
+				return pod.workspacePod(), nil
+			case "Succeeded", "Failed":
+				return nil, fmt.Errorf("pod %s stopped (%s) before sessions could start", name, pod.Status.Phase)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("pod %s didn't start within %s", name, r.readyTimeout)
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// deleteAndWait deletes a pod and waits until it is gone, so a new one can
+// take its name
+func (r *KubernetesRuntime) deleteAndWait(ctx context.Context, name string) error {
+	if _, err := r.client.do(ctx, http.MethodDelete, r.podPath(name), "", nil, nil); err != nil && !errors.Is(err, ErrKubernetesNotFound) {
+		return fmt.Errorf("failed to delete pod %s: %w", name, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.readyTimeout)
+	defer cancel()
+	for {
+		if _, err := r.getPod(ctx, name); errors.Is(err, ErrKubernetesNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("pod %s wasn't deleted within %s", name, r.readyTimeout)
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// ExposePorts points the workspace's service at its pod's ports, creating
+// the service the first time. The ports are then reachable in the cluster at
+// <pod name>.<namespace>.svc.
+func (r *KubernetesRuntime) ExposePorts(ctx context.Context, workspace string, ports []int) error {
+	if len(ports) == 0 {
+		return nil
+	}
+	name := KubernetesPodName(workspace)
+	servicePorts := make([]interface{}, 0, len(ports))
+	for _, port := range ports {
+		servicePorts = append(servicePorts, map[string]interface{}{
+			"name":       "port-" + strconv.Itoa(port),
+			"port":       port,
+			"targetPort": port,
+			"protocol":   "TCP",
+		})
+	}
+	service := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":        name,
+			"namespace":   r.namespace,
+			"labels":      map[string]interface{}{kubernetesManagedByLabel: "catnip", kubernetesPodLabel: name},
+			"annotations": map[string]interface{}{kubernetesWorkspaceAnnotation: workspace},
+		},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{kubernetesPodLabel: name},
+			"ports":    servicePorts,
+		},
+	}
+
+	path := fmt.Sprintf("/api/v1/namespaces/%s/services", url.PathEscape(r.namespace))
+	status, err := r.client.do(ctx, http.MethodPost, path, "", service, nil)
+	if status == http.StatusConflict {
+		patch := map[string]interface{}{"spec": map[string]interface{}{"ports": servicePorts}}
+		_, err = r.client.do(ctx, http.MethodPatch, r.servicePath(name), kubernetesMergePatchMimeType, patch, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to expose ports of %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteWorkspace removes a workspace's pod and service
+func (r *KubernetesRuntime) DeleteWorkspace(ctx context.Context, workspace string) error {
+	name := KubernetesPodName(workspace)
+	var errs []error
+	for _, path := range []string{r.podPath(name), r.servicePath(name)} {
+		if _, err := r.client.do(ctx, http.MethodDelete, path, "", nil, nil); err != nil && !errors.Is(err, ErrKubernetesNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Pods lists the workspace pods catnip created
+func (r *KubernetesRuntime) Pods(ctx context.Context) ([]KubernetesWorkspacePod, error) {
+	var list struct {
+		Items []kubernetesPod `json:"items"`
+	}
+	path := fmt.Sprintf("/api/v1/namespaces/%s/pods?labelSelector=%s", url.PathEscape(r.namespace),
+		url.QueryEscape(kubernetesManagedByLabel+"=catnip"))
+	if _, err := r.client.do(ctx, http.MethodGet, path, "", nil, &list); err != nil {
+		return nil, err
+	}
+	pods := make([]KubernetesWorkspacePod, 0, len(list.Items))
+	for i := range list.Items {
+		pods = append(pods, *list.Items[i].workspacePod())
+	}
+	return pods, nil
+}
+
+// KubernetesExecCommand is the command that runs args in dir with env set,
+// since the exec API takes neither a working directory nor environment
+func KubernetesExecCommand(dir string, env, args []string) []string {
+	command := []string{"sh", "-c", `cd "$1" && shift && exec env "$@"`, "sh", dir}
+	command = append(command, env...)
+	return append(command, args...)
+}
+
+// Exec attaches to a command running with a TTY in the workspace's pod
+func (r *KubernetesRuntime) Exec(ctx context.Context, pod *KubernetesWorkspacePod, command []string) (*KubernetesExecStream, error) {
+	query := url.Values{}
+	for _, arg := range command {
+		query.Add("command", arg)
+	}
+	if pod.Container != "" {
+		query.Set("container", pod.Container)
+	}
+	query.Set("stdin", "true")
+	query.Set("stdout", "true")
+	query.Set("tty", "true")
+
+	execURL := r.client.baseURL + r.podPath(pod.Name) + "/exec?" + query.Encode()
+	execURL = "ws" + strings.TrimPrefix(execURL, "http")
+	dialer := websocket.Dialer{
+		TLSClientConfig:  r.client.tlsConfig,
+		Subprotocols:     []string{kubernetesExecProtocol},
+		HandshakeTimeout: 30 * time.Second,
+	}
+	header := http.Header{}
+	if r.client.token != "" {
+		header.Set("Authorization", "Bearer "+r.client.token)
+	}
+	conn, resp, err := dialer.DialContext(ctx, execURL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("failed to exec in pod %s: %s", pod.Name, resp.Status)
+		}
+		return nil, fmt.Errorf("failed to exec in pod %s: %w", pod.Name, err)
+	}
+	return &KubernetesExecStream{conn: conn}, nil
+}
+
+// KubernetesExecStream is a command attached over the exec API's
+// channel.k8s.io protocol: each message starts with its channel number
+type KubernetesExecStream struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (s *KubernetesExecStream) send(channel byte, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteMessage(websocket.BinaryMessage, append([]byte{channel}, data...))
+}
+
+// Write sends input to the command
+func (s *KubernetesExecStream) Write(p []byte) (int, error) {
+	if err := s.send(kubernetesExecStdinChannel, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize sets the command's terminal size
+func (s *KubernetesExecStream) Resize(cols, rows int) error {
+	data, _ := json.Marshal(map[string]int{"Width": cols, "Height": rows})
+	return s.send(kubernetesExecResizeChannel, data)
+}
+
+// Wait copies the command's output to w until it exits and returns its exit
+// code
+func (s *KubernetesExecStream) Wait(w io.Writer) (int, error) {
+	for {
+		_, message, err := s.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+				return 0, nil
+			}
+			return -1, fmt.Errorf("exec stream closed: %w", err)
+		}
+		if len(message) == 0 {
+			continue
+		}
+		switch message[0] {
+		case kubernetesExecStdoutChannel, kubernetesExecStderrChannel:
+			if _, err := w.Write(message[1:]); err != nil {
+				return -1, err
+			}
+		case kubernetesExecStatusChannel:
+			return kubernetesExitCode(message[1:])
+		}
+	}
+}
+
+// Close closes the stream
+func (s *KubernetesExecStream) Close() error {
+	return s.conn.Close()
+}
+
+// kubernetesExitCode reads the exit code from the exec API's final status
+func kubernetesExitCode(data []byte) (int, error) {
+	var status kubernetesStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return -1, fmt.Errorf("failed to parse exec status: %w", err)
+	}
+	if status.Status == "Success" {
+		return 0, nil
+	}
+	for _, cause := range status.Details.Causes {
+		if cause.Reason == "ExitCode" {
+			if code, err := strconv.Atoi(cause.Message); err == nil {
+				return code, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("exec failed: %s", status.Message)
+}
+
+// KubernetesServerHost is the host:port sessions in workspace pods reach
+// catnip at
+func KubernetesServerHost() string {
+	if host := os.Getenv(KubernetesServerHostSetting); host != "" {
+		return host
+	}
+	host := os.Getenv("POD_IP")
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	return net.JoinHostPort(host, config.Runtime.Port)
+}
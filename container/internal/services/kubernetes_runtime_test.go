@@ -0,0 +1,227 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKubernetesAPI serves the pod and service endpoints the runtime uses
+type fakeKubernetesAPI struct {
+	mu       sync.Mutex
+	pods     map[string]map[string]interface{}
+	services map[string]map[string]interface{}
+}
+
+func (f *fakeKubernetesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/namespaces/team/"), "/")
+	store := f.pods
+	if parts[0] == "services" {
+		store = f.services
+	}
+	var body map[string]interface{}
+	if r.Body != nil {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch {
+	case r.Method == http.MethodPost:
+		name := body["metadata"].(map[string]interface{})["name"].(string)
+		if _, exists := store[name]; exists {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"status":"Failure","message":"already exists","code":409}`))
+			return
+		}
+		if parts[0] == "pods" {
+			body["status"] = map[string]interface{}{"phase": "Running", "podIP": "10.0.0.7"}
+		}
+		store[name] = body
+		w.WriteHeader(http.StatusCreated)
+	case len(parts) < 2:
+		items := []interface{}{}
+		for _, obj := range store {
+			items = append(items, obj)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items})
+	case r.Method == http.MethodGet:
+		if obj, exists := store[parts[1]]; exists {
+			_ = json.NewEncoder(w).Encode(obj)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	case r.Method == http.MethodPatch:
+		if r.Header.Get("Content-Type") != kubernetesMergePatchMimeType {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		store[parts[1]]["spec"] = body["spec"]
+	case r.Method == http.MethodDelete:
+		if _, exists := store[parts[1]]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(store, parts[1])
+	}
+}
+
+func newTestKubernetesRuntime(t *testing.T) (*KubernetesRuntime, *fakeKubernetesAPI) {
+	api := &fakeKubernetesAPI{pods: map[string]map[string]interface{}{}, services: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	template := map[string]interface{}{
+		"metadata": map[string]interface{}{"labels": map[string]interface{}{"team": "ml"}},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "dev", "image": "catnip:test"}},
+		},
+	}
+	runtime := NewKubernetesRuntimeWithClient(NewKubernetesClient(server.URL, "token", nil), "team", template)
+	runtime.pollInterval = 10 * time.Millisecond
+	runtime.readyTimeout = time.Second
+	return runtime, api
+}
+
+func TestKubernetesPodName(t *testing.T) {
+	assert.Regexp(t, `^catnip-catnip-feature-login-[0-9a-f]{8}$`, KubernetesPodName("catnip/feature_login"))
+	assert.NotEqual(t, KubernetesPodName("a/b"), KubernetesPodName("a-b"), "names that slug the same differ by hash")
+	assert.LessOrEqual(t, len(KubernetesPodName(strings.Repeat("x", 100))), 63)
+	assert.Regexp(t, `^catnip-[0-9a-f]{8}$`, KubernetesPodName("///"))
+}
+
+func TestKubernetesRuntimeEnsurePod(t *testing.T) {
+	runtime, api := newTestKubernetesRuntime(t)
+
+	pod, err := runtime.EnsurePod(t.Context(), "catnip/main")
+	require.NoError(t, err)
+	assert.Equal(t, KubernetesPodName("catnip/main"), pod.Name)
+	assert.Equal(t, "catnip/main", pod.Workspace)
+	assert.Equal(t, "dev", pod.Container)
+	assert.Equal(t, "10.0.0.7", pod.PodIP)
+
+	created := api.pods[pod.Name]
+	metadata := created["metadata"].(map[string]interface{})
+	labels := metadata["labels"].(map[string]interface{})
+	assert.Equal(t, "ml", labels["team"], "template labels are kept")
+	assert.Equal(t, "catnip", labels[kubernetesManagedByLabel])
+	assert.Equal(t, pod.Name, labels[kubernetesPodLabel])
+	assert.Equal(t, "team", metadata["namespace"])
+
+	// A running pod is reused
+	_, err = runtime.EnsurePod(t.Context(), "catnip/main")
+	require.NoError(t, err)
+	assert.Len(t, api.pods, 1)
+
+	// A stopped pod is replaced
+	api.pods[pod.Name]["status"] = map[string]interface{}{"phase": "Failed"}
+	pod, err = runtime.EnsurePod(t.Context(), "catnip/main")
+	require.NoError(t, err)
+	assert.Equal(t, "Running", pod.Phase)
+
+	pods, err := runtime.Pods(t.Context())
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	assert.Equal(t, "catnip/main", pods[0].Workspace)
+}
+
+func TestKubernetesRuntimeExposePortsAndDelete(t *testing.T) {
+	runtime, api := newTestKubernetesRuntime(t)
+	name := KubernetesPodName("catnip/main")
+
+	require.NoError(t, runtime.ExposePorts(t.Context(), "catnip/main", nil))
+	assert.Empty(t, api.services)
+
+	require.NoError(t, runtime.ExposePorts(t.Context(), "catnip/main", []int{3000}))
+	require.NoError(t, runtime.ExposePorts(t.Context(), "catnip/main", []int{3000, 3001}))
+	spec := api.services[name]["spec"].(map[string]interface{})
+	assert.Len(t, spec["ports"], 2, "the second call patches the existing service")
+
+	_, err := runtime.EnsurePod(t.Context(), "catnip/main")
+	require.NoError(t, err)
+	require.NoError(t, runtime.DeleteWorkspace(t.Context(), "catnip/main"))
+	assert.Empty(t, api.pods)
+	assert.Empty(t, api.services)
+	require.NoError(t, runtime.DeleteWorkspace(t.Context(), "catnip/main"), "deleting twice is fine")
+}
+
+func TestKubernetesExecStream(t *testing.T) {
+	upgrader := websocket.Upgrader{Subprotocols: []string{kubernetesExecProtocol}}
+	var command []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		command = r.URL.Query()["command"]
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		_, resize, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{kubernetesExecResizeChannel}, `{"Height":40,"Width":120}`...), resize)
+		_, input, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, append([]byte{kubernetesExecStdinChannel}, "exit 3\r"...), input)
+
+		_ = conn.WriteMessage(websocket.BinaryMessage, append([]byte{kubernetesExecStdoutChannel}, "bye\r\n"...))
+		status := `{"status":"Failure","reason":"NonZeroExitCode","details":{"causes":[{"reason":"ExitCode","message":"3"}]}}`
+		_ = conn.WriteMessage(websocket.BinaryMessage, append([]byte{kubernetesExecStatusChannel}, status...))
+	}))
+	defer server.Close()
+
+	runtime := NewKubernetesRuntimeWithClient(NewKubernetesClient(server.URL, "token", nil), "team", nil)
+	pod := &KubernetesWorkspacePod{Name: "catnip-main", Container: "dev"}
+	stream, err := runtime.Exec(t.Context(), pod, KubernetesExecCommand("/workspace/catnip/main", []string{"PORT=3000"}, []string{"bash", "--login"}))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	require.NoError(t, stream.Resize(120, 40))
+	_, err = io.Copy(stream, strings.NewReader("exit 3\r"))
+	require.NoError(t, err)
+	var output bytes.Buffer
+	exitCode, err := stream.Wait(&output)
+	require.NoError(t, err)
+	assert.Equal(t, 3, exitCode)
+	assert.Equal(t, "bye\r\n", output.String())
+	assert.Equal(t, []string{"sh", "-c", `cd "$1" && shift && exec env "$@"`, "sh", "/workspace/catnip/main", "PORT=3000", "bash", "--login"}, command)
+}
+
+func TestLoadKubernetesPodTemplate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pod.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+metadata:
+  labels:
+    team: ml
+spec:
+  containers:
+    - name: dev
+      image: catnip:test
+      resources:
+        limits:
+          cpu: 2
+`), 0644))
+
+	template, err := LoadKubernetesPodTemplate(path)
+	require.NoError(t, err)
+	_, err = json.Marshal(template)
+	require.NoError(t, err, "YAML maps are converted for JSON")
+	containers := template["spec"].(map[string]interface{})["containers"].([]interface{})
+	assert.Equal(t, "dev", containers[0].(map[string]interface{})["name"])
+
+	require.NoError(t, os.WriteFile(path, []byte("spec: {}\n"), 0644))
+	_, err = LoadKubernetesPodTemplate(path)
+	assert.ErrorContains(t, err, "no containers")
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,21 +18,83 @@ import (
 // LocalRepoManager handles local repository operations
 type LocalRepoManager struct {
 	operations git.Operations
+	scan       LocalRepoScanConfig
 }
 
-// NewLocalRepoManager creates a new local repository manager
+// LocalRepoScanConfig controls where local repositories are detected
+type LocalRepoScanConfig struct {
+	Roots    []string      // Mount roots to scan, the live directory when empty
+	Exclude  []string      // Glob patterns matched against directory names and paths relative to their root
+	MaxDepth int           // Directory levels below a root searched for nested repositories
+	Interval time.Duration // How often roots are rescanned for new mounts, 0 disables rescanning
+}
+
+const (
+	defaultLocalRepoScanDepth    = 3
+	defaultLocalRepoScanInterval = 10 * time.Second
+)
+
+// NewLocalRepoManager creates a new local repository manager configured from
+// CATNIP_LOCAL_REPO_ROOTS, CATNIP_LOCAL_REPO_EXCLUDE, CATNIP_LOCAL_REPO_DEPTH
+// and CATNIP_LOCAL_REPO_SCAN_INTERVAL
 func NewLocalRepoManager(operations git.Operations) *LocalRepoManager {
+	return NewLocalRepoManagerWithScanConfig(operations, localRepoScanConfigFromEnv())
+}
+
+// NewLocalRepoManagerWithScanConfig creates a local repository manager with explicit scan settings (for testing)
+func NewLocalRepoManagerWithScanConfig(operations git.Operations, scan LocalRepoScanConfig) *LocalRepoManager {
+	if scan.MaxDepth <= 0 {
+		scan.MaxDepth = defaultLocalRepoScanDepth
+	}
 	return &LocalRepoManager{
 		operations: operations,
+		scan:       scan,
+	}
+}
+
+func localRepoScanConfigFromEnv() LocalRepoScanConfig {
+	scan := LocalRepoScanConfig{
+		Roots:    splitLocalRepoList(os.Getenv("CATNIP_LOCAL_REPO_ROOTS")),
+		Exclude:  splitLocalRepoList(os.Getenv("CATNIP_LOCAL_REPO_EXCLUDE")),
+		MaxDepth: defaultLocalRepoScanDepth,
+		Interval: defaultLocalRepoScanInterval,
+	}
+	if raw := os.Getenv("CATNIP_LOCAL_REPO_DEPTH"); raw != "" {
+		if depth, err := strconv.Atoi(raw); err == nil && depth > 0 {
+			scan.MaxDepth = depth
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_LOCAL_REPO_DEPTH %q", raw)
+		}
+	}
+	if raw := os.Getenv("CATNIP_LOCAL_REPO_SCAN_INTERVAL"); raw != "" {
+		if interval, err := time.ParseDuration(raw); err == nil && (interval == 0 || interval >= time.Second) {
+			scan.Interval = interval
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_LOCAL_REPO_SCAN_INTERVAL %q (0 or at least 1s)", raw)
+		}
+	}
+	return scan
+}
+
+func splitLocalRepoList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
+}
+
+// ScanInterval returns how often mount roots should be rescanned for new repositories
+func (lrm *LocalRepoManager) ScanInterval() time.Duration {
+	return lrm.scan.Interval
 }
 
-// DetectLocalRepos scans the live directory for any Git repositories and loads them
+// DetectLocalRepos scans the mount roots for any Git repositories and loads them
 func (lrm *LocalRepoManager) DetectLocalRepos() map[string]*models.Repository {
 	repositories := make(map[string]*models.Repository)
 
-	liveDir := config.Runtime.LiveDir
-
 	// In native mode, only detect the current repo to avoid scanning all sibling repos
 	if config.Runtime.IsNative() {
 		if config.Runtime.CurrentRepo != "" {
@@ -42,58 +105,141 @@ func (lrm *LocalRepoManager) DetectLocalRepos() map[string]*models.Repository {
 		return repositories
 	}
 
-	// Docker mode: Check if live directory exists
-	if liveDir == "" {
-		logger.Debug("📁 No live directory configured, skipping local repo detection")
-		return repositories
+	for repoID, repoPath := range lrm.ScanLocalRepoPaths() {
+		repositories[repoID] = lrm.LoadLocalRepo(repoID, repoPath)
+		logger.Debugf("✅ Local repository loaded: %s", repoID)
 	}
 
-	if _, err := os.Stat(liveDir); os.IsNotExist(err) {
-		logger.Debug("📁 Live directory does not exist, skipping local repo detection")
-		return repositories
+	return repositories
+}
+
+// ScanLocalRepoPaths finds the Git repositories under the mount roots without
+// inspecting them, returning their paths by repository ID. Repositories in the
+// first root are named by their path relative to it (local/app,
+// local/monorepo/packages/api); those in other roots are prefixed with the
+// root's name. Native mode only ever has the current repository, so nothing
+// is scanned.
+func (lrm *LocalRepoManager) ScanLocalRepoPaths() map[string]string {
+	paths := make(map[string]string)
+	if config.Runtime.IsNative() {
+		return paths
 	}
 
-	// Read all entries in live directory
-	entries, err := os.ReadDir(liveDir)
-	if err != nil {
-		logger.Errorf("❌ Failed to read live directory: %v", err)
-		return repositories
+	roots := lrm.scan.Roots
+	if len(roots) == 0 {
+		if config.Runtime.LiveDir == "" {
+			logger.Debug("📁 No live directory configured, skipping local repo detection")
+			return paths
+		}
+		roots = []string{config.Runtime.LiveDir}
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	for i, root := range roots {
+		if _, err := os.Stat(root); err != nil {
+			logger.Debugf("📁 Local repo root %s does not exist, skipping", root)
 			continue
 		}
+		prefix := ""
+		if i > 0 {
+			prefix = filepath.Base(root)
+		}
+		for _, repoPath := range lrm.findRepos(root) {
+			repoID := localRepoID(root, prefix, repoPath)
+			if existing, taken := paths[repoID]; taken {
+				logger.Warnf("⚠️ Skipping local repository %s: %s is already detected at %s", repoPath, repoID, existing)
+				continue
+			}
+			paths[repoID] = repoPath
+		}
+	}
+	return paths
+}
 
-		repoPath := filepath.Join(liveDir, entry.Name())
-		gitPath := filepath.Join(repoPath, ".git")
+// findRepos walks a root up to the maximum depth, returning every repository
+// it finds. Repositories are searched too, so embedded repositories inside a
+// monorepo are found alongside it. Hidden directories and node_modules are
+// never searched.
+func (lrm *LocalRepoManager) findRepos(root string) []string {
+	var repos []string
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			logger.Debugf("🔍 Detected local repository at %s", dir)
+			repos = append(repos, dir)
+		}
+		if depth >= lrm.scan.MaxDepth {
+			return
+		}
 
-		// Check if it's a git repository
-		if _, err := os.Stat(gitPath); os.IsNotExist(err) {
-			continue
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			logger.Errorf("❌ Failed to read %s: %v", dir, err)
+			return
 		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() || strings.HasPrefix(name, ".") || name == "node_modules" {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if lrm.excluded(root, path) {
+				logger.Debugf("📁 Excluding %s from local repo detection", path)
+				continue
+			}
+			walk(path, depth+1)
+		}
+	}
+	walk(root, 0)
+	return repos
+}
 
-		logger.Debugf("🔍 Detected local repository at %s", repoPath)
-
-		// Create repository object
-		repoID := fmt.Sprintf("local/%s", entry.Name())
-		remoteOrigin, hasGitHubRemote := lrm.getRemoteOriginInfo(repoPath)
-		repo := &models.Repository{
-			ID:              repoID,
-			URL:             "file://" + repoPath,
-			Path:            repoPath,
-			DefaultBranch:   lrm.getLocalRepoDefaultBranch(repoPath),
-			CreatedAt:       time.Now(),
-			LastAccessed:    time.Now(),
-			RemoteOrigin:    remoteOrigin,
-			HasGitHubRemote: hasGitHubRemote,
+// excluded reports whether a directory matches an exclusion pattern, by its
+// name or by its path relative to the root it was found in
+func (lrm *LocalRepoManager) excluded(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range lrm.scan.Exclude {
+		pattern = strings.TrimSuffix(pattern, "/")
+		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+			return true
 		}
+		if matched, _ := filepath.Match(pattern, rel); matched {
+			return true
+		}
+	}
+	return false
+}
 
-		repositories[repoID] = repo
-		logger.Debugf("✅ Local repository loaded: %s", repoID)
+// localRepoID names a repository found under a mount root
+func localRepoID(root, prefix, repoPath string) string {
+	var parts []string
+	if prefix != "" {
+		parts = append(parts, prefix)
 	}
+	if rel, err := filepath.Rel(root, repoPath); err == nil && rel != "." {
+		parts = append(parts, filepath.ToSlash(rel))
+	} else if prefix == "" {
+		// The root itself is a repository
+		parts = append(parts, filepath.Base(root))
+	}
+	return "local/" + strings.Join(parts, "/")
+}
 
-	return repositories
+// LoadLocalRepo reads the default branch and remote of a detected repository
+func (lrm *LocalRepoManager) LoadLocalRepo(repoID, repoPath string) *models.Repository {
+	remoteOrigin, hasGitHubRemote := lrm.getRemoteOriginInfo(repoPath)
+	return &models.Repository{
+		ID:              repoID,
+		URL:             "file://" + repoPath,
+		Path:            repoPath,
+		DefaultBranch:   lrm.getLocalRepoDefaultBranch(repoPath),
+		CreatedAt:       time.Now(),
+		LastAccessed:    time.Now(),
+		RemoteOrigin:    remoteOrigin,
+		HasGitHubRemote: hasGitHubRemote,
+	}
 }
 
 // detectCurrentRepo handles the case where we're running from within a git repo in native mode
@@ -153,9 +299,7 @@ func (lrm *LocalRepoManager) detectCurrentRepo() map[string]*models.Repository {
 
 // CreateWorktreePreview creates a preview branch in the main repo for viewing changes outside container
 func (lrm *LocalRepoManager) CreateWorktreePreview(repo *models.Repository, worktree *models.Worktree) error {
-	// Extract workspace name for preview branch
-	workspaceName := git.ExtractWorkspaceName(worktree.Branch)
-	previewBranchName := fmt.Sprintf("catnip/%s", workspaceName)
+	previewBranchName := PreviewBranchName(worktree.Branch)
 	logger.Debugf("🔍 Creating preview branch %s for worktree %s", previewBranchName, worktree.Name)
 
 	// Check if there are uncommitted changes (staged, unstaged, or untracked)
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+func TestScanLocalRepoPaths(t *testing.T) {
+	oldMode := config.Runtime.Mode
+	config.Runtime.Mode = config.DockerMode
+	defer func() { config.Runtime.Mode = oldMode }()
+
+	live := t.TempDir()
+	extra := filepath.Join(t.TempDir(), "mnt")
+	for _, dir := range []string{
+		filepath.Join(live, "app"),
+		filepath.Join(live, "monorepo"),
+		filepath.Join(live, "monorepo", "packages", "api"),
+		filepath.Join(live, "monorepo", "node_modules", "dep"),
+		filepath.Join(live, "scratch", "experiment"),
+		filepath.Join(live, "archive", "old"),
+		filepath.Join(live, "deep", "a", "b", "repo"),
+		filepath.Join(extra, "tools"),
+		filepath.Join(extra, "app"),
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, ".git"), 0755))
+	}
+	// Not a repository
+	require.NoError(t, os.MkdirAll(filepath.Join(live, "notes"), 0755))
+
+	manager := NewLocalRepoManagerWithScanConfig(git.NewOperations(), LocalRepoScanConfig{
+		Roots:   []string{live, extra},
+		Exclude: []string{"scratch", "archive/*"},
+	})
+	assert.Equal(t, map[string]string{
+		"local/app":                   filepath.Join(live, "app"),
+		"local/monorepo":              filepath.Join(live, "monorepo"),
+		"local/monorepo/packages/api": filepath.Join(live, "monorepo", "packages", "api"),
+		"local/mnt/tools":             filepath.Join(extra, "tools"),
+		"local/mnt/app":               filepath.Join(extra, "app"),
+	}, manager.ScanLocalRepoPaths())
+
+	// Mounts that appear later are found by the next scan
+	require.NoError(t, os.MkdirAll(filepath.Join(live, "hotplug", ".git"), 0755))
+	assert.Contains(t, manager.ScanLocalRepoPaths(), "local/hotplug")
+
+	// Without configured roots the live directory is scanned, and a root that
+	// is itself a repository is found too
+	oldLiveDir := config.Runtime.LiveDir
+	config.Runtime.LiveDir = filepath.Join(extra, "tools")
+	defer func() { config.Runtime.LiveDir = oldLiveDir }()
+	assert.Equal(t, map[string]string{"local/tools": filepath.Join(extra, "tools")},
+		NewLocalRepoManagerWithScanConfig(git.NewOperations(), LocalRepoScanConfig{}).ScanLocalRepoPaths())
+}
+
+func TestLocalRepoScanConfigFromEnv(t *testing.T) {
+	t.Setenv("CATNIP_LOCAL_REPO_ROOTS", "/live, /mnt/code,,")
+	t.Setenv("CATNIP_LOCAL_REPO_EXCLUDE", "vendor")
+	t.Setenv("CATNIP_LOCAL_REPO_DEPTH", "-1")
+	t.Setenv("CATNIP_LOCAL_REPO_SCAN_INTERVAL", "0")
+
+	scan := localRepoScanConfigFromEnv()
+	assert.Equal(t, []string{"/live", "/mnt/code"}, scan.Roots)
+	assert.Equal(t, []string{"vendor"}, scan.Exclude)
+	assert.Equal(t, defaultLocalRepoScanDepth, scan.MaxDepth, "invalid depths are ignored")
+	assert.Zero(t, scan.Interval, "0 disables rescanning")
+}
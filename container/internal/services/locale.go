@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	// Zone data for containers without /usr/share/zoneinfo
+	_ "time/tzdata"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// Settings for the time zone schedules and reports use and the locale
+// reports are formatted in. Both are read on every use.
+const (
+	TimeZoneSetting = "CATNIP_TIMEZONE"
+	LocaleSetting   = "CATNIP_LOCALE"
+)
+
+// LocaleSettings are the instance's time zone and locale
+type LocaleSettings struct {
+	// IANA time zone name; empty uses the container's zone (TZ)
+	TimeZone string `json:"time_zone" example:"Europe/Berlin"`
+	// BCP 47 language tag; empty uses LC_ALL or LANG
+	Locale string `json:"locale" example:"de-DE"`
+}
+
+// LocaleStatus is the configured and effective time zone and locale
+type LocaleStatus struct {
+	// Values of CATNIP_TIMEZONE and CATNIP_LOCALE
+	Configured LocaleSettings `json:"configured"`
+	// Time zone and locale in use
+	Effective LocaleSettings `json:"effective"`
+	// Current UTC offset of the effective time zone
+	UTCOffset string `json:"utc_offset" example:"+02:00"`
+}
+
+// Validate checks the time zone and locale can be loaded
+func (s LocaleSettings) Validate() error {
+	if s.TimeZone != "" {
+		if _, err := time.LoadLocation(s.TimeZone); err != nil {
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "unknown time zone %q", s.TimeZone).
+				WithHint("Use an IANA zone name such as America/New_York or UTC")
+		}
+	}
+	if s.Locale != "" {
+		if _, err := language.Parse(s.Locale); err != nil {
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "unknown locale %q", s.Locale).
+				WithHint("Use a language tag such as en-US or de-DE")
+		}
+	}
+	return nil
+}
+
+// InstanceLocation returns the time zone schedules run in and reports are
+// bucketed by: CATNIP_TIMEZONE, or the container's zone
+func InstanceLocation() *time.Location {
+	name := strings.TrimSpace(os.Getenv(TimeZoneSetting))
+	if name == "" {
+		return time.Local
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		logger.Warnf("⚠️ Ignoring invalid %s %q: %v", TimeZoneSetting, name, err)
+		return time.Local
+	}
+	return location
+}
+
+// InstanceLanguage returns the locale reports are formatted in:
+// CATNIP_LOCALE, else the LC_ALL or LANG locale, else en-US
+func InstanceLanguage() language.Tag {
+	if raw := strings.TrimSpace(os.Getenv(LocaleSetting)); raw != "" {
+		if tag, err := language.Parse(raw); err == nil {
+			return tag
+		}
+		logger.Warnf("⚠️ Ignoring invalid %s %q", LocaleSetting, raw)
+	}
+	for _, key := range []string{"LC_ALL", "LANG"} {
+		// POSIX locales look like en_US.UTF-8; C and POSIX say nothing about language
+		raw, _, _ := strings.Cut(os.Getenv(key), ".")
+		if raw == "" || raw == "C" || raw == "POSIX" {
+			continue
+		}
+		if tag, err := language.Parse(strings.ReplaceAll(raw, "_", "-")); err == nil {
+			return tag
+		}
+	}
+	return language.AmericanEnglish
+}
+
+// localePrinter formats numbers in the instance's locale
+func localePrinter() *message.Printer {
+	return message.NewPrinter(InstanceLanguage())
+}
+
+// CurrentLocale reports the configured and effective time zone and locale
+func CurrentLocale() LocaleStatus {
+	location := InstanceLocation()
+	_, offset := time.Now().In(location).Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	return LocaleStatus{
+		Configured: LocaleSettings{
+			TimeZone: os.Getenv(TimeZoneSetting),
+			Locale:   os.Getenv(LocaleSetting),
+		},
+		Effective: LocaleSettings{
+			TimeZone: zoneName(location),
+			Locale:   InstanceLanguage().String(),
+		},
+		UTCOffset: fmt.Sprintf("%s%02d:%02d", sign, offset/3600, offset%3600/60),
+	}
+}
+
+// zoneName names a location, resolving the container's zone to TZ when set
+func zoneName(location *time.Location) string {
+	if location == time.Local {
+		if tz := os.Getenv("TZ"); tz != "" {
+			return tz
+		}
+	}
+	return location.String()
+}
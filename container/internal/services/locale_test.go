@@ -0,0 +1,35 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstanceLocale(t *testing.T) {
+	t.Setenv(TimeZoneSetting, "Asia/Tokyo")
+	t.Setenv(LocaleSetting, "de-DE")
+	assert.Equal(t, "Asia/Tokyo", InstanceLocation().String())
+	assert.Equal(t, "de-DE", InstanceLanguage().String())
+	assert.Equal(t, "1.234,50 / 2,0 GiB", localePrinter().Sprintf("%.2f / %s", 1234.5, formatHygieneBytes(2<<30)))
+
+	status := CurrentLocale()
+	assert.Equal(t, "Asia/Tokyo", status.Effective.TimeZone)
+	assert.Equal(t, "+09:00", status.UTCOffset)
+
+	// Invalid settings fall back to the container's zone and locale
+	t.Setenv(TimeZoneSetting, "Mars/Olympus")
+	t.Setenv(LocaleSetting, "")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LANG", "fr_FR.UTF-8")
+	assert.Equal(t, time.Local, InstanceLocation())
+	assert.Equal(t, "fr-FR", InstanceLanguage().String())
+	t.Setenv("LANG", "C.UTF-8")
+	assert.Equal(t, "en-US", InstanceLanguage().String())
+
+	assert.NoError(t, LocaleSettings{TimeZone: "America/New_York", Locale: "en-GB"}.Validate())
+	assert.NoError(t, LocaleSettings{}.Validate())
+	assert.Error(t, LocaleSettings{TimeZone: "Mars/Olympus"}.Validate())
+	assert.Error(t, LocaleSettings{Locale: "not a locale"}.Validate())
+}
@@ -0,0 +1,612 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// defaultMDNSTTL is how long LAN resolvers may cache our records
+	defaultMDNSTTL = 2 * time.Minute
+	// mdnsRefreshInterval is how often running dev servers are re-checked
+	mdnsRefreshInterval = 5 * time.Second
+	// mdnsCacheFlush marks records we're the sole owner of (RFC 6762 §10.2)
+	mdnsCacheFlush = 1 << 15
+	// mdnsUnicastResponse is the QU bit in a question's class (RFC 6762 §5.4)
+	mdnsUnicastResponse = 1 << 15
+	// mdnsHTTPService is the DNS-SD service type dev servers are browsable under
+	mdnsHTTPService = "_http._tcp.local."
+	// mdnsServicesMeta enumerates the service types we advertise (RFC 6763 §9)
+	mdnsServicesMeta = "_services._dns-sd._udp.local."
+)
+
+var (
+	mdnsGroup       = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	mdnsInvalidChar = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// MDNSAdvertisement is a worktree whose dev servers are advertised on the LAN
+type MDNSAdvertisement struct {
+	WorktreeID   string   `json:"worktree_id"`
+	WorktreeName string   `json:"worktree_name"`
+	Hostname     string   `json:"hostname" example:"feature-api-docs.local"`
+	Ports        []int    `json:"ports"`
+	URLs         []string `json:"urls" example:"http://feature-api-docs.local:3000"`
+}
+
+// MDNSStatus describes the mDNS responder and what it advertises
+type MDNSStatus struct {
+	// Whether advertising is on for this server (native mode by default, or CATNIP_MDNS)
+	Enabled bool `json:"enabled"`
+	// Whether the responder is listening
+	Running bool `json:"running"`
+	// Record TTL handed to LAN resolvers
+	TTLSeconds int `json:"ttl_seconds"`
+	// LAN addresses the hostnames resolve to
+	Addresses      []string            `json:"addresses"`
+	Advertisements []MDNSAdvertisement `json:"advertisements"`
+	Error          string              `json:"error,omitempty"`
+}
+
+// MDNSService answers multicast DNS queries for running dev servers so devices
+// on the LAN can open <worktree>.local:<port> instead of hunting for the IP.
+// Each worktree with listening ports gets an A record and a browsable
+// _http._tcp service per port; records are withdrawn with a goodbye packet
+// when the ports close or the worktree opts out.
+type MDNSService struct {
+	enabled   bool
+	ttl       time.Duration
+	worktrees func() []*models.Worktree
+	services  func() map[int]*ServiceInfo
+	addrs     func() []net.IP
+
+	mu            sync.Mutex
+	ads           map[string]MDNSAdvertisement // Keyed by FQDN, e.g. "feature-api-docs.local."
+	lastAnnounced time.Time
+	conn          *net.UDPConn
+	send          func(packet []byte, to *net.UDPAddr) error // Set while running
+	lastErr       string
+	stopCh        chan struct{}
+	running       bool
+}
+
+// NewMDNSService creates a responder that's enabled in native mode unless
+// CATNIP_MDNS says otherwise, with its TTL from CATNIP_MDNS_TTL
+func NewMDNSService(worktrees func() []*models.Worktree, services func() map[int]*ServiceInfo) *MDNSService {
+	enabled := config.Runtime.IsNative()
+	if raw := os.Getenv("CATNIP_MDNS"); raw != "" {
+		if parsed, err := strconv.ParseBool(raw); err == nil {
+			enabled = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_MDNS %q", raw)
+		}
+	}
+
+	ttl := defaultMDNSTTL
+	if raw := os.Getenv("CATNIP_MDNS_TTL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= 10*time.Second && parsed <= time.Hour {
+			ttl = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_MDNS_TTL %q (10s to 1h)", raw)
+		}
+	}
+
+	return NewMDNSServiceWithOptions(enabled, ttl, worktrees, services, lanAddresses)
+}
+
+// NewMDNSServiceWithOptions creates a responder with explicit settings (for testing)
+func NewMDNSServiceWithOptions(enabled bool, ttl time.Duration, worktrees func() []*models.Worktree, services func() map[int]*ServiceInfo, addrs func() []net.IP) *MDNSService {
+	return &MDNSService{
+		enabled:   enabled,
+		ttl:       ttl,
+		worktrees: worktrees,
+		services:  services,
+		addrs:     addrs,
+		ads:       make(map[string]MDNSAdvertisement),
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start joins the mDNS multicast group and begins advertising
+func (s *MDNSService) Start() {
+	if !s.enabled {
+		return
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, mdnsGroup)
+	if err != nil {
+		logger.Warnf("⚠️ mDNS advertising unavailable: %v", err)
+		s.mu.Lock()
+		s.lastErr = err.Error()
+		s.mu.Unlock()
+		return
+	}
+
+	s.mu.Lock()
+	s.conn = conn
+	s.send = func(packet []byte, to *net.UDPAddr) error {
+		_, err := conn.WriteToUDP(packet, to)
+		return err
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	logger.Infof("📡 Advertising dev servers on the LAN via mDNS (TTL %v)", s.ttl)
+	go s.serve(conn)
+	go s.refreshLoop()
+}
+
+// Stop withdraws every advertisement and leaves the multicast group
+func (s *MDNSService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopCh)
+	for name, ad := range s.ads {
+		s.goodbyeLocked(ad)
+		delete(s.ads, name)
+	}
+	conn := s.conn
+	s.conn = nil
+	s.mu.Unlock()
+
+	_ = conn.Close()
+}
+
+func (s *MDNSService) refreshLoop() {
+	s.Refresh()
+	ticker := time.NewTicker(mdnsRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.Refresh()
+		}
+	}
+}
+
+func (s *MDNSService) serve(conn *net.UDPConn) {
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-s.stopCh:
+			default:
+				logger.Warnf("⚠️ mDNS responder stopped: %v", err)
+			}
+			return
+		}
+
+		response, unicast := s.Answer(buf[:n], from.Port != mdnsGroup.Port)
+		if response == nil {
+			continue
+		}
+		to := mdnsGroup
+		if unicast {
+			to = from
+		}
+		if err := s.send(response, to); err != nil {
+			logger.Debugf("⚠️ Failed to send mDNS response to %s: %v", to, err)
+		}
+	}
+}
+
+// Refresh matches listening ports to worktrees, announcing new and changed
+// advertisements and withdrawing stale ones. Everything is re-announced at
+// half the TTL so LAN caches never expire a live record.
+func (s *MDNSService) Refresh() {
+	desired := s.desiredAdvertisements()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reannounce := time.Since(s.lastAnnounced) >= s.ttl/2
+	for name, current := range s.ads {
+		next, exists := desired[name]
+		if !exists {
+			logger.Debugf("📡 Withdrawing mDNS name %s", current.Hostname)
+			s.goodbyeLocked(current)
+			delete(s.ads, name)
+		} else if removed := missingPorts(current.Ports, next.Ports); len(removed) > 0 {
+			// The hostname stays; only the closed ports' services go
+			s.broadcastLocked(s.serviceRecords(current, removed, 0))
+		}
+	}
+	for name, ad := range desired {
+		current, exists := s.ads[name]
+		s.ads[name] = ad
+		if !reannounce && exists && equalPorts(current.Ports, ad.Ports) {
+			continue
+		}
+		if !exists {
+			logger.Infof("📡 Advertising %s on ports %v", ad.Hostname, ad.Ports)
+		}
+		s.announceLocked(ad)
+	}
+	if reannounce {
+		s.lastAnnounced = time.Now()
+	}
+}
+
+// desiredAdvertisements builds an advertisement for every opted-in worktree
+// with a dev server listening inside it
+func (s *MDNSService) desiredAdvertisements() map[string]MDNSAdvertisement {
+	worktrees := s.worktrees()
+	sort.Slice(worktrees, func(i, j int) bool { return worktrees[i].Name < worktrees[j].Name })
+
+	services := s.services()
+	desired := make(map[string]MDNSAdvertisement)
+	for _, wt := range worktrees {
+		if wt.MDNS != nil && !*wt.MDNS {
+			continue
+		}
+		var ports []int
+		for port, service := range services {
+			if service.WorkingDir != "" && pathWithin(service.WorkingDir, wt.Path) {
+				ports = append(ports, port)
+			}
+		}
+		if len(ports) == 0 {
+			continue
+		}
+		sort.Ints(ports)
+
+		label := MDNSLabel(wt.Name)
+		// Worktrees with the same name in different repos get numbered
+		for n := 2; desired[label+".local."].WorktreeID != ""; n++ {
+			label = fmt.Sprintf("%s-%d", MDNSLabel(wt.Name), n)
+		}
+		ad := MDNSAdvertisement{
+			WorktreeID:   wt.ID,
+			WorktreeName: wt.Name,
+			Hostname:     label + ".local",
+			Ports:        ports,
+		}
+		for _, port := range ports {
+			ad.URLs = append(ad.URLs, fmt.Sprintf("http://%s:%d", ad.Hostname, port))
+		}
+		desired[label+".local."] = ad
+	}
+	return desired
+}
+
+// MDNSLabel turns a worktree name into a DNS label, e.g. "catnip/Felix_2" becomes "catnip-felix-2"
+func MDNSLabel(name string) string {
+	label := mdnsInvalidChar.ReplaceAllString(strings.ToLower(name), "-")
+	label = strings.Trim(label, "-")
+	if len(label) > 55 {
+		label = strings.TrimRight(label[:55], "-")
+	}
+	if label == "" {
+		label = "workspace"
+	}
+	return label
+}
+
+// Status reports whether the responder is running and what it advertises
+func (s *MDNSService) Status() MDNSStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := MDNSStatus{
+		Enabled:        s.enabled,
+		Running:        s.running,
+		TTLSeconds:     int(s.ttl / time.Second),
+		Addresses:      []string{},
+		Advertisements: make([]MDNSAdvertisement, 0, len(s.ads)),
+		Error:          s.lastErr,
+	}
+	for _, ip := range s.addrs() {
+		status.Addresses = append(status.Addresses, ip.String())
+	}
+	for _, ad := range s.ads {
+		status.Advertisements = append(status.Advertisements, ad)
+	}
+	sort.Slice(status.Advertisements, func(i, j int) bool {
+		return status.Advertisements[i].Hostname < status.Advertisements[j].Hostname
+	})
+	return status
+}
+
+// Answer builds the response to an mDNS query packet, or nil if none of its
+// questions are about names we advertise. unicast reports whether the reply
+// should go straight back to the sender (a QU question, or a legacy resolver
+// querying from a port other than 5353, which also gets the ID and questions
+// echoed back).
+func (s *MDNSService) Answer(packet []byte, legacy bool) (response []byte, unicast bool) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(packet)
+	if err != nil || header.Response || header.OpCode != 0 {
+		return nil, false
+	}
+	questions, err := parser.AllQuestions()
+	if err != nil {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var answers, additionals []dnsmessage.Resource
+	unicast = legacy
+	for _, q := range questions {
+		found := s.answerLocked(q, s.ttl)
+		if len(found) == 0 {
+			continue
+		}
+		answers = append(answers, found...)
+		if q.Class&mdnsUnicastResponse != 0 {
+			unicast = true
+		}
+		// Spare resolvers a follow-up query for the hostname or service details
+		if q.Type == dnsmessage.TypePTR {
+			for _, ptr := range found {
+				body := ptr.Body.(*dnsmessage.PTRResource)
+				additionals = append(additionals, s.answerLocked(dnsmessage.Question{Name: body.PTR, Type: dnsmessage.TypeALL}, s.ttl)...)
+			}
+		}
+		if q.Type == dnsmessage.TypeSRV {
+			for _, srv := range found {
+				if body, ok := srv.Body.(*dnsmessage.SRVResource); ok {
+					additionals = append(additionals, s.answerLocked(dnsmessage.Question{Name: body.Target, Type: dnsmessage.TypeA}, s.ttl)...)
+				}
+			}
+		}
+	}
+	if len(answers) == 0 {
+		return nil, false
+	}
+
+	msg := dnsmessage.Message{
+		Header:      dnsmessage.Header{Response: true, Authoritative: true},
+		Answers:     answers,
+		Additionals: additionals,
+	}
+	if legacy {
+		msg.Header.ID = header.ID
+		msg.Questions = questions
+	}
+	response, err = msg.Pack()
+	if err != nil {
+		logger.Debugf("⚠️ Failed to pack mDNS response: %v", err)
+		return nil, false
+	}
+	return response, unicast
+}
+
+// answerLocked returns our records matching a question
+func (s *MDNSService) answerLocked(q dnsmessage.Question, ttl time.Duration) []dnsmessage.Resource {
+	name := strings.ToLower(q.Name.String())
+	matches := func(t dnsmessage.Type) bool { return q.Type == t || q.Type == dnsmessage.TypeALL }
+	var records []dnsmessage.Resource
+
+	switch {
+	case name == mdnsServicesMeta:
+		if matches(dnsmessage.TypePTR) && len(s.ads) > 0 {
+			records = append(records, ptrRecord(mdnsServicesMeta, mdnsHTTPService, ttl))
+		}
+	case name == mdnsHTTPService:
+		if matches(dnsmessage.TypePTR) {
+			for _, ad := range s.sortedAdsLocked() {
+				for _, port := range ad.Ports {
+					records = append(records, ptrRecord(mdnsHTTPService, mdnsInstanceName(ad.Hostname, port), ttl))
+				}
+			}
+		}
+	case strings.HasSuffix(name, "."+mdnsHTTPService):
+		for _, ad := range s.ads {
+			for _, port := range ad.Ports {
+				if name == strings.ToLower(mdnsInstanceName(ad.Hostname, port)) {
+					records = append(records, s.instanceRecords(ad, port, ttl, matches)...)
+				}
+			}
+		}
+	default:
+		if ad, exists := s.ads[name]; exists && matches(dnsmessage.TypeA) {
+			records = append(records, s.addressRecords(ad, ttl)...)
+		}
+	}
+	return records
+}
+
+func (s *MDNSService) sortedAdsLocked() []MDNSAdvertisement {
+	ads := make([]MDNSAdvertisement, 0, len(s.ads))
+	for _, ad := range s.ads {
+		ads = append(ads, ad)
+	}
+	sort.Slice(ads, func(i, j int) bool { return ads[i].Hostname < ads[j].Hostname })
+	return ads
+}
+
+// announceLocked multicasts an advertisement's records unsolicited
+func (s *MDNSService) announceLocked(ad MDNSAdvertisement) {
+	s.broadcastLocked(s.advertisementRecords(ad, s.ttl))
+}
+
+// goodbyeLocked multicasts an advertisement's records with a zero TTL so
+// LAN caches drop them immediately (RFC 6762 §10.1)
+func (s *MDNSService) goodbyeLocked(ad MDNSAdvertisement) {
+	s.broadcastLocked(s.advertisementRecords(ad, 0))
+}
+
+func (s *MDNSService) broadcastLocked(records []dnsmessage.Resource) {
+	if !s.running || len(records) == 0 {
+		return
+	}
+	msg := dnsmessage.Message{
+		Header:  dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: records,
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		logger.Debugf("⚠️ Failed to pack mDNS announcement: %v", err)
+		return
+	}
+	if err := s.send(packet, mdnsGroup); err != nil {
+		logger.Debugf("⚠️ Failed to send mDNS announcement: %v", err)
+	}
+}
+
+// advertisementRecords returns every record for an advertisement
+func (s *MDNSService) advertisementRecords(ad MDNSAdvertisement, ttl time.Duration) []dnsmessage.Resource {
+	return append(s.addressRecords(ad, ttl), s.serviceRecords(ad, ad.Ports, ttl)...)
+}
+
+// serviceRecords returns the DNS-SD records for some of an advertisement's ports
+func (s *MDNSService) serviceRecords(ad MDNSAdvertisement, ports []int, ttl time.Duration) []dnsmessage.Resource {
+	all := func(dnsmessage.Type) bool { return true }
+	var records []dnsmessage.Resource
+	for _, port := range ports {
+		records = append(records, ptrRecord(mdnsHTTPService, mdnsInstanceName(ad.Hostname, port), ttl))
+		records = append(records, s.instanceRecords(ad, port, ttl, all)...)
+	}
+	return records
+}
+
+func (s *MDNSService) addressRecords(ad MDNSAdvertisement, ttl time.Duration) []dnsmessage.Resource {
+	var records []dnsmessage.Resource
+	for _, ip := range s.addrs() {
+		v4 := ip.To4()
+		if v4 == nil {
+			continue
+		}
+		var a dnsmessage.AResource
+		copy(a.A[:], v4)
+		records = append(records, dnsmessage.Resource{
+			Header: uniqueHeader(ad.Hostname+".", ttl),
+			Body:   &a,
+		})
+	}
+	return records
+}
+
+func (s *MDNSService) instanceRecords(ad MDNSAdvertisement, port int, ttl time.Duration, matches func(dnsmessage.Type) bool) []dnsmessage.Resource {
+	instance := mdnsInstanceName(ad.Hostname, port)
+	var records []dnsmessage.Resource
+	if matches(dnsmessage.TypeSRV) {
+		records = append(records, dnsmessage.Resource{
+			Header: uniqueHeader(instance, ttl),
+			Body: &dnsmessage.SRVResource{
+				Port:   uint16(port),
+				Target: dnsmessage.MustNewName(ad.Hostname + "."),
+			},
+		})
+	}
+	if matches(dnsmessage.TypeTXT) {
+		records = append(records, dnsmessage.Resource{
+			Header: uniqueHeader(instance, ttl),
+			Body:   &dnsmessage.TXTResource{TXT: []string{"path=/", "worktree=" + ad.WorktreeName}},
+		})
+	}
+	return records
+}
+
+// mdnsInstanceName names a dev server's DNS-SD instance, e.g. "feature-api-docs-3000._http._tcp.local."
+func mdnsInstanceName(hostname string, port int) string {
+	return fmt.Sprintf("%s-%d.%s", strings.TrimSuffix(hostname, ".local"), port, mdnsHTTPService)
+}
+
+func uniqueHeader(name string, ttl time.Duration) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  dnsmessage.MustNewName(name),
+		Class: dnsmessage.ClassINET | mdnsCacheFlush,
+		TTL:   uint32(ttl / time.Second),
+	}
+}
+
+func ptrRecord(name, target string, ttl time.Duration) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName(name),
+			Class: dnsmessage.ClassINET,
+			TTL:   uint32(ttl / time.Second),
+		},
+		Body: &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(target)},
+	}
+}
+
+// lanAddresses returns the machine's IPv4 addresses on up, multicast-capable,
+// non-loopback interfaces
+func lanAddresses() []net.IP {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			ips = append(ips, ipNet.IP.To4())
+		}
+	}
+	return ips
+}
+
+// pathWithin reports whether path is dir or inside it
+func pathWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func missingPorts(before, after []int) []int {
+	var missing []int
+	for _, port := range before {
+		if !containsPort(after, port) {
+			missing = append(missing, port)
+		}
+	}
+	return missing
+}
+
+func equalPorts(a, b []int) bool {
+	return len(a) == len(b) && len(missingPorts(a, b)) == 0
+}
+
+func containsPort(ports []int, port int) bool {
+	for _, p := range ports {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// SetWorktreeMDNS sets whether a worktree's dev servers are advertised on the
+// LAN (nil restores the server default)
+func (s *GitService) SetWorktreeMDNS(worktreeID string, enabled *bool) error {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"mdns": enabled,
+	})
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type mdnsFixture struct {
+	svc       *MDNSService
+	worktrees []*models.Worktree
+	services  map[int]*ServiceInfo
+	sent      []dnsmessage.Message
+}
+
+func newMDNSFixture(t *testing.T) *mdnsFixture {
+	f := &mdnsFixture{
+		worktrees: []*models.Worktree{
+			{ID: "wt-1", Name: "catnip/Felix", Path: "/workspace/catnip/felix"},
+			{ID: "wt-2", Name: "catnip/idle", Path: "/workspace/catnip/idle"},
+		},
+		services: map[int]*ServiceInfo{
+			3000: {Port: 3000, WorkingDir: "/workspace/catnip/felix/web"},
+			8080: {Port: 8080, WorkingDir: "/workspace/catnip/felix"},
+			9000: {Port: 9000, WorkingDir: "/workspace/catnip/felix-other"},
+		},
+	}
+	f.svc = NewMDNSServiceWithOptions(true, time.Minute,
+		func() []*models.Worktree { return f.worktrees },
+		func() map[int]*ServiceInfo { return f.services },
+		func() []net.IP { return []net.IP{net.IPv4(192, 168, 1, 20)} },
+	)
+	// Capture announcements as if the responder were listening
+	f.svc.running = true
+	f.svc.send = func(packet []byte, to *net.UDPAddr) error {
+		var msg dnsmessage.Message
+		require.NoError(t, msg.Unpack(packet))
+		f.sent = append(f.sent, msg)
+		return nil
+	}
+	return f
+}
+
+func mdnsQuery(t *testing.T, name string, qtype dnsmessage.Type) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 42},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packet, err := msg.Pack()
+	require.NoError(t, err)
+	return packet
+}
+
+func unpackMDNS(t *testing.T, packet []byte) dnsmessage.Message {
+	t.Helper()
+	require.NotNil(t, packet)
+	var msg dnsmessage.Message
+	require.NoError(t, msg.Unpack(packet))
+	return msg
+}
+
+func TestMDNSLabel(t *testing.T) {
+	assert.Equal(t, "catnip-felix-2", MDNSLabel("catnip/Felix_2"))
+	assert.Equal(t, "workspace", MDNSLabel("///"))
+	assert.LessOrEqual(t, len(MDNSLabel(string(make([]byte, 100))+"a")), 55)
+}
+
+func TestMDNSAdvertisesWorktreePorts(t *testing.T) {
+	f := newMDNSFixture(t)
+	f.svc.Refresh()
+
+	status := f.svc.Status()
+	require.Len(t, status.Advertisements, 1)
+	ad := status.Advertisements[0]
+	assert.Equal(t, "catnip-felix.local", ad.Hostname)
+	assert.Equal(t, []int{3000, 8080}, ad.Ports)
+	assert.Equal(t, []string{"http://catnip-felix.local:3000", "http://catnip-felix.local:8080"}, ad.URLs)
+	assert.Equal(t, []string{"192.168.1.20"}, status.Addresses)
+
+	// New advertisements are announced unsolicited
+	require.Len(t, f.sent, 1)
+	assert.Equal(t, uint32(60), f.sent[0].Answers[0].Header.TTL)
+
+	response, unicast := f.svc.Answer(mdnsQuery(t, "Catnip-Felix.local.", dnsmessage.TypeA), false)
+	msg := unpackMDNS(t, response)
+	assert.False(t, unicast)
+	require.Len(t, msg.Answers, 1)
+	assert.Equal(t, [4]byte{192, 168, 1, 20}, msg.Answers[0].Body.(*dnsmessage.AResource).A)
+	assert.Empty(t, msg.Questions, "multicast responses don't echo questions")
+
+	response, _ = f.svc.Answer(mdnsQuery(t, "unknown.local.", dnsmessage.TypeA), false)
+	assert.Nil(t, response)
+}
+
+func TestMDNSServiceBrowsing(t *testing.T) {
+	f := newMDNSFixture(t)
+	f.svc.Refresh()
+
+	// A legacy resolver (not on port 5353) gets a unicast reply echoing its query
+	response, unicast := f.svc.Answer(mdnsQuery(t, mdnsHTTPService, dnsmessage.TypePTR), true)
+	msg := unpackMDNS(t, response)
+	assert.True(t, unicast)
+	assert.Equal(t, uint16(42), msg.Header.ID)
+	require.Len(t, msg.Questions, 1)
+
+	require.Len(t, msg.Answers, 2)
+	assert.Equal(t, "catnip-felix-3000._http._tcp.local.", msg.Answers[0].Body.(*dnsmessage.PTRResource).PTR.String())
+
+	var srvPorts []uint16
+	var hasAddress bool
+	for _, record := range msg.Additionals {
+		switch body := record.Body.(type) {
+		case *dnsmessage.SRVResource:
+			assert.Equal(t, "catnip-felix.local.", body.Target.String())
+			srvPorts = append(srvPorts, body.Port)
+		case *dnsmessage.AResource:
+			hasAddress = true
+		}
+	}
+	assert.ElementsMatch(t, []uint16{3000, 8080}, srvPorts)
+	assert.False(t, hasAddress, "PTR additionals carry the instance records")
+}
+
+func TestMDNSWithdrawsStaleRecords(t *testing.T) {
+	f := newMDNSFixture(t)
+	f.svc.Refresh()
+	f.sent = nil
+
+	// A closed port only withdraws its own service, then the rest is re-announced
+	delete(f.services, 8080)
+	f.svc.Refresh()
+	require.Len(t, f.sent, 2)
+	assert.Equal(t, uint32(60), f.sent[1].Answers[0].Header.TTL)
+	for _, record := range f.sent[0].Answers {
+		assert.Zero(t, record.Header.TTL)
+		assert.NotEqual(t, dnsmessage.TypeA, record.Header.Type, "hostname stays while port 3000 is open")
+	}
+
+	// Opting the worktree out withdraws everything
+	f.sent = nil
+	disabled := false
+	f.worktrees[0].MDNS = &disabled
+	f.svc.Refresh()
+	assert.Empty(t, f.svc.Status().Advertisements)
+	require.Len(t, f.sent, 1)
+	var withdrewAddress bool
+	for _, record := range f.sent[0].Answers {
+		assert.Zero(t, record.Header.TTL)
+		withdrewAddress = withdrewAddress || record.Header.Type == dnsmessage.TypeA
+	}
+	assert.True(t, withdrewAddress)
+}
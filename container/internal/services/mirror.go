@@ -0,0 +1,411 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	defaultMirrorInterval = time.Hour
+	minMirrorInterval     = 5 * time.Minute
+	mirrorStatusFile      = "mirrors.json"
+)
+
+// RepoMirrorStatus reports the state of one mirrored repository
+type RepoMirrorStatus struct {
+	RepoID    string     `json:"repo_id"`
+	URL       string     `json:"url"`
+	Path      string     `json:"path"`
+	LastSync  *time.Time `json:"last_sync,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	// Refs in the mirror after the last successful sync
+	RefCount int `json:"ref_count"`
+}
+
+// MirrorStatus reports the mirror configuration and every mirrored repository
+type MirrorStatus struct {
+	Enabled bool `json:"enabled"`
+	// Checkouts use mirrors only, without trying the network
+	Offline      bool               `json:"offline"`
+	IntervalSecs int                `json:"interval_seconds"`
+	Repositories []RepoMirrorStatus `json:"repositories"`
+}
+
+// MirrorService keeps full mirrors (all refs) of configured repositories in
+// the volume so checkouts keep working without network access
+type MirrorService struct {
+	operations git.Operations
+	repos      []string
+	dir        string
+	interval   time.Duration
+	offline    bool
+	syncMu     sync.Mutex // Serializes syncs
+	mu         sync.Mutex // Guards status
+	status     map[string]*RepoMirrorStatus
+	stopChan   chan struct{}
+	running    bool
+}
+
+// NewMirrorService creates a mirror service configured from
+// CATNIP_MIRROR_REPOS (comma separated org/repo), CATNIP_MIRROR_INTERVAL and
+// CATNIP_OFFLINE
+func NewMirrorService() *MirrorService {
+	interval := defaultMirrorInterval
+	if raw := os.Getenv("CATNIP_MIRROR_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= minMirrorInterval {
+			interval = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_MIRROR_INTERVAL %q (minimum %v)", raw, minMirrorInterval)
+		}
+	}
+
+	var repos []string
+	for _, repoID := range strings.Split(os.Getenv("CATNIP_MIRROR_REPOS"), ",") {
+		repoID = strings.TrimSpace(repoID)
+		if repoID == "" {
+			continue
+		}
+		if strings.Count(repoID, "/") != 1 {
+			logger.Warnf("⚠️ Ignoring invalid mirror repository %q (expected org/repo)", repoID)
+			continue
+		}
+		repos = append(repos, repoID)
+	}
+
+	return NewMirrorServiceWithOptions(
+		git.NewOperations(),
+		repos,
+		filepath.Join(config.Runtime.VolumeDir, "mirrors"),
+		interval,
+		os.Getenv("CATNIP_OFFLINE") == "1",
+		checkoutRemoteURL,
+	)
+}
+
+// NewMirrorServiceWithOptions creates a mirror service with explicit settings (for testing)
+func NewMirrorServiceWithOptions(operations git.Operations, repos []string, dir string, interval time.Duration, offline bool, remoteURL func(repoID string) string) *MirrorService {
+	s := &MirrorService{
+		operations: operations,
+		repos:      repos,
+		dir:        dir,
+		interval:   interval,
+		offline:    offline,
+		status:     make(map[string]*RepoMirrorStatus),
+		stopChan:   make(chan struct{}),
+	}
+	s.loadStatus()
+	for _, repoID := range repos {
+		if _, exists := s.status[repoID]; !exists {
+			s.status[repoID] = &RepoMirrorStatus{RepoID: repoID}
+		}
+		s.status[repoID].URL = remoteURL(repoID)
+		s.status[repoID].Path = s.mirrorPath(repoID)
+	}
+	return s
+}
+
+// checkoutRemoteURL is the URL CheckoutRepository clones a repository from
+func checkoutRemoteURL(repoID string) string {
+	if os.Getenv("CATNIP_TEST_MODE") == "1" {
+		// In test mode, use local test repositories
+		_, repo, _ := strings.Cut(repoID, "/")
+		return filepath.Join("/tmp", "test-repos", repo)
+	}
+	// GitLab and Bitbucket repository IDs start with the host
+	if host, ownerRepo, ok := git.SplitHostedRepoID(repoID); ok {
+		return fmt.Sprintf("https://%s/%s.git", host, ownerRepo)
+	}
+	return fmt.Sprintf("https://github.com/%s.git", repoID)
+}
+
+func (s *MirrorService) mirrorPath(repoID string) string {
+	return filepath.Join(s.dir, repoID+".git")
+}
+
+func (s *MirrorService) loadStatus() {
+	data, err := os.ReadFile(filepath.Join(s.dir, mirrorStatusFile))
+	if err != nil {
+		return
+	}
+	var statuses []*RepoMirrorStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		logger.Warnf("⚠️ Ignoring corrupt mirror status: %v", err)
+		return
+	}
+	for _, status := range statuses {
+		s.status[status.RepoID] = status
+	}
+}
+
+func (s *MirrorService) saveStatusLocked() {
+	statuses := make([]*RepoMirrorStatus, 0, len(s.status))
+	for _, status := range s.status {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].RepoID < statuses[j].RepoID })
+
+	data, err := json.MarshalIndent(statuses, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(s.dir, 0755); err == nil {
+			err = os.WriteFile(filepath.Join(s.dir, mirrorStatusFile), data, 0644)
+		}
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to save mirror status: %v", err)
+	}
+}
+
+// Enabled reports whether any repositories are mirrored
+func (s *MirrorService) Enabled() bool {
+	return len(s.repos) > 0
+}
+
+// Offline reports whether checkouts should skip the network entirely
+func (s *MirrorService) Offline() bool {
+	return s.offline
+}
+
+// Start syncs all mirrors in the background and then on the configured interval.
+// It does nothing when no repositories are configured.
+func (s *MirrorService) Start() {
+	if !s.Enabled() {
+		return
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	if s.offline {
+		logger.Infof("🪞 Offline mode: checkouts will use mirrors of %d repositories", len(s.repos))
+		return
+	}
+	logger.Infof("🪞 Mirroring %d repositories every %v", len(s.repos), s.interval)
+
+	go func() {
+		s.SyncAll()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+				s.SyncAll()
+			}
+		}
+	}()
+}
+
+// Stop stops periodic syncing
+func (s *MirrorService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+}
+
+// SyncAll updates every configured mirror, returning the first error
+func (s *MirrorService) SyncAll() error {
+	var firstErr error
+	for _, repoID := range s.repos {
+		if err := s.Sync(repoID); err != nil {
+			logger.Warnf("⚠️ Mirror sync failed for %s: %v", repoID, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Sync clones or updates the mirror of one configured repository
+func (s *MirrorService) Sync(repoID string) error {
+	s.mu.Lock()
+	status, configured := s.status[repoID]
+	s.mu.Unlock()
+	if !configured || !s.isConfigured(repoID) {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "repository %s is not mirrored; add it to CATNIP_MIRROR_REPOS", repoID)
+	}
+
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	path := s.mirrorPath(repoID)
+	var err error
+	if _, statErr := os.Stat(path); statErr == nil {
+		_, err = s.operations.ExecuteGit(path, "fetch", "--prune", "--tags", "origin")
+	} else {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			tmpPath := path + ".tmp"
+			_ = os.RemoveAll(tmpPath)
+			if _, err = s.operations.ExecuteGit("", "clone", "--mirror", status.URL, tmpPath); err == nil {
+				err = os.Rename(tmpPath, path)
+			} else {
+				_ = os.RemoveAll(tmpPath)
+			}
+		}
+	}
+
+	refCount := 0
+	if err == nil {
+		if output, refErr := s.operations.ExecuteGit(path, "for-each-ref", "--format=%(refname)"); refErr == nil {
+			refCount = len(strings.Fields(string(output)))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		status.LastError = err.Error()
+	} else {
+		now := time.Now()
+		status.LastSync = &now
+		status.LastError = ""
+		status.RefCount = refCount
+		logger.Debugf("🪞 Mirrored %s (%d refs)", repoID, refCount)
+	}
+	s.saveStatusLocked()
+	return err
+}
+
+func (s *MirrorService) isConfigured(repoID string) bool {
+	for _, configured := range s.repos {
+		if configured == repoID {
+			return true
+		}
+	}
+	return false
+}
+
+// Mirror returns the path of a repository's mirror and when it was last
+// synced, if a usable mirror exists
+func (s *MirrorService) Mirror(repoID string) (string, time.Time, bool) {
+	s.mu.Lock()
+	status, exists := s.status[repoID]
+	s.mu.Unlock()
+	if !exists || status.LastSync == nil {
+		return "", time.Time{}, false
+	}
+	if _, err := os.Stat(status.Path); err != nil {
+		return "", time.Time{}, false
+	}
+	return status.Path, *status.LastSync, true
+}
+
+// Status returns the mirror configuration and per-repository state
+func (s *MirrorService) Status() MirrorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := MirrorStatus{
+		Enabled:      s.Enabled(),
+		Offline:      s.offline,
+		IntervalSecs: int(s.interval / time.Second),
+		Repositories: []RepoMirrorStatus{},
+	}
+	for _, repoID := range s.repos {
+		if status, exists := s.status[repoID]; exists {
+			result.Repositories = append(result.Repositories, *status)
+		}
+	}
+	return result
+}
+
+// mirrorForCheckout returns a repository's mirror when a checkout should (or
+// has to) use it instead of the network
+func (s *GitService) mirrorForCheckout(repoID string) (string, time.Time, bool) {
+	if s.mirrors == nil {
+		return "", time.Time{}, false
+	}
+	return s.mirrors.Mirror(repoID)
+}
+
+// offline reports whether checkouts should skip the network
+func (s *GitService) offline() bool {
+	return s.mirrors != nil && s.mirrors.Offline()
+}
+
+// cloneFromMirror creates the bare repository from a local mirror, then
+// points origin back at the real remote so fetches and pushes work once the
+// network is back
+func (s *GitService) cloneFromMirror(mirrorPath, repoURL, barePath, branch string) error {
+	args := []string{"clone", "--bare"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	args = append(args, mirrorPath, barePath)
+	if _, err := s.runGitCommand("", args...); err != nil {
+		return fmt.Errorf("failed to clone from mirror: %v", err)
+	}
+	if _, err := s.runGitCommand(barePath, "remote", "set-url", "origin", repoURL); err != nil {
+		return fmt.Errorf("failed to restore origin URL: %v", err)
+	}
+	return nil
+}
+
+// fetchBranchFromMirror updates a branch of a bare repository from its mirror
+func (s *GitService) fetchBranchFromMirror(mirrorPath, barePath, branch string) error {
+	refspec := fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branch, branch)
+	if _, err := s.runGitCommand(barePath, "fetch", mirrorPath, refspec); err != nil {
+		return fmt.Errorf("failed to fetch %s from mirror: %v", branch, err)
+	}
+	return nil
+}
+
+// fetchCheckoutBranch brings a branch up to date for a checkout. Offline it
+// comes straight from the mirror; otherwise fetch runs and the mirror is the
+// fallback when it fails. Returns the mirror's sync time if the mirror was used.
+func (s *GitService) fetchCheckoutBranch(repoID, barePath, branch string, fetch func() error) (*time.Time, error) {
+	mirrorPath, syncedAt, hasMirror := s.mirrorForCheckout(repoID)
+	if s.offline() && hasMirror {
+		if err := s.fetchBranchFromMirror(mirrorPath, barePath, branch); err != nil {
+			return nil, err
+		}
+		return &syncedAt, nil
+	}
+
+	err := fetch()
+	if err == nil || !hasMirror {
+		return nil, err
+	}
+	logger.Warnf("⚠️ Fetching %s failed, using mirror synced %s: %v", branch, syncedAt.Format(time.RFC3339), err)
+	if err := s.fetchBranchFromMirror(mirrorPath, barePath, branch); err != nil {
+		return nil, err
+	}
+	return &syncedAt, nil
+}
+
+// markCreatedFromMirror records that a worktree came from a mirror, and how
+// fresh the mirror was, so the UI can flag it as possibly stale
+func (s *GitService) markCreatedFromMirror(worktree *models.Worktree, syncedAt time.Time) {
+	worktree.CreatedFromMirror = true
+	worktree.MirrorSyncedAt = &syncedAt
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{
+		"created_from_mirror": true,
+		"mirror_synced_at":    &syncedAt,
+	}); err != nil {
+		logger.Warnf("⚠️ Failed to record mirror origin for worktree %s: %v", worktree.Name, err)
+	}
+}
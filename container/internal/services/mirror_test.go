@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+// newMirrorUpstream creates a repository with a main branch to mirror
+func newMirrorUpstream(t *testing.T) string {
+	upstream := t.TempDir()
+	runTestGit(t, upstream, "init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(upstream, "README.md"), []byte("hello\n"), 0644))
+	runTestGit(t, upstream, "add", ".")
+	runTestGit(t, upstream, "commit", "-q", "-m", "initial")
+	return upstream
+}
+
+func TestMirrorServiceSync(t *testing.T) {
+	upstream := newMirrorUpstream(t)
+	dir := t.TempDir()
+	remoteURL := func(string) string { return upstream }
+
+	mirrors := NewMirrorServiceWithOptions(git.NewOperations(), []string{"acme/app"}, dir, time.Hour, false, remoteURL)
+	_, _, ok := mirrors.Mirror("acme/app")
+	assert.False(t, ok, "no mirror before the first sync")
+
+	require.NoError(t, mirrors.Sync("acme/app"))
+	path, syncedAt, ok := mirrors.Mirror("acme/app")
+	require.True(t, ok)
+	assert.False(t, syncedAt.IsZero())
+
+	// New refs upstream show up on the next sync
+	runTestGit(t, upstream, "branch", "feature")
+	runTestGit(t, upstream, "tag", "v1")
+	require.NoError(t, mirrors.Sync("acme/app"))
+	runTestGit(t, path, "rev-parse", "--verify", "refs/heads/feature")
+	runTestGit(t, path, "rev-parse", "--verify", "refs/tags/v1")
+	assert.Equal(t, 3, mirrors.Status().Repositories[0].RefCount)
+
+	assert.Error(t, mirrors.Sync("acme/other"))
+
+	// Status survives a restart
+	reloaded := NewMirrorServiceWithOptions(git.NewOperations(), []string{"acme/app"}, dir, time.Hour, true, remoteURL)
+	_, reloadedSync, ok := reloaded.Mirror("acme/app")
+	require.True(t, ok)
+	assert.True(t, reloadedSync.Equal(syncedAt) || reloadedSync.After(syncedAt))
+	assert.True(t, reloaded.Status().Offline)
+}
+
+func TestCheckoutFallsBackToMirror(t *testing.T) {
+	upstream := newMirrorUpstream(t)
+	mirrors := NewMirrorServiceWithOptions(git.NewOperations(), []string{"acme/app"}, t.TempDir(), time.Hour, false, func(string) string { return upstream })
+	require.NoError(t, mirrors.Sync("acme/app"))
+	mirrorPath, _, _ := mirrors.Mirror("acme/app")
+
+	service := createTestGitService(t)
+	service.SetMirrorService(mirrors)
+
+	barePath := filepath.Join(t.TempDir(), "app.git")
+	require.NoError(t, service.cloneFromMirror(mirrorPath, "https://github.com/acme/app.git", barePath, "main"))
+	assert.Equal(t, "https://github.com/acme/app.git", runTestGit(t, barePath, "remote", "get-url", "origin"))
+
+	// A failed network fetch falls back to the mirror and reports its sync time
+	runTestGit(t, upstream, "branch", "feature")
+	require.NoError(t, mirrors.Sync("acme/app"))
+	syncedAt, err := service.fetchCheckoutBranch("acme/app", barePath, "feature", func() error {
+		return errors.New("could not resolve host: github.com")
+	})
+	require.NoError(t, err)
+	require.NotNil(t, syncedAt)
+	runTestGit(t, barePath, "rev-parse", "--verify", "refs/heads/feature")
+
+	// A successful fetch doesn't touch the mirror
+	syncedAt, err = service.fetchCheckoutBranch("acme/app", barePath, "main", func() error { return nil })
+	require.NoError(t, err)
+	assert.Nil(t, syncedAt)
+
+	// Without a mirror the fetch error is returned
+	_, err = service.fetchCheckoutBranch("acme/unmirrored", barePath, "main", func() error { return errors.New("offline") })
+	assert.EqualError(t, err, "offline")
+}
+
+func TestOfflineCheckoutSkipsNetwork(t *testing.T) {
+	upstream := newMirrorUpstream(t)
+	dir := t.TempDir()
+	remoteURL := func(string) string { return upstream }
+	require.NoError(t, NewMirrorServiceWithOptions(git.NewOperations(), []string{"acme/app"}, dir, time.Hour, false, remoteURL).Sync("acme/app"))
+
+	service := createTestGitService(t)
+	service.SetMirrorService(NewMirrorServiceWithOptions(git.NewOperations(), []string{"acme/app"}, dir, time.Hour, true, remoteURL))
+
+	barePath := filepath.Join(t.TempDir(), "app.git")
+	runTestGit(t, "", "init", "-q", "--bare", barePath)
+	syncedAt, err := service.fetchCheckoutBranch("acme/app", barePath, "main", func() error {
+		t.Fatal("offline checkouts must not fetch from the network")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotNil(t, syncedAt)
+	runTestGit(t, barePath, "rev-parse", "--verify", "refs/heads/main")
+}
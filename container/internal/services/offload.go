@@ -0,0 +1,475 @@
+package services
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// Offloaded jobs kept in memory for the API
+const maxOffloadJobs = 50
+
+// OffloadConfig configures remote runners for heavy commands (.catnip.yaml "offload")
+type OffloadConfig struct {
+	Runners map[string]OffloadRunnerConfig `json:"runners,omitempty" yaml:"runners"`
+	// Checked in order; the first matching rule wins
+	Rules []OffloadRule `json:"rules,omitempty" yaml:"rules"`
+}
+
+// OffloadRunnerConfig describes a remote runner. Set SSH for a machine
+// reachable over ssh, or URL for an HTTP runner API.
+type OffloadRunnerConfig struct {
+	// ssh destination (e.g. ubuntu@gpu-box); the worktree is copied there before each run
+	SSH string `json:"ssh,omitempty" yaml:"ssh"`
+	// Remote directory for the worktree (default ~/catnip-offload/<worktree name>)
+	Dir string `json:"dir,omitempty" yaml:"dir"`
+	// Runner API endpoint; receives the command and commit as JSON and streams NDJSON output back
+	URL string `json:"url,omitempty" yaml:"url"`
+	// Environment variable holding a bearer token for the runner API
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env"`
+}
+
+// OffloadRule sends commands matching Pattern to a runner. Pattern matches
+// the whole command line; * matches any text (e.g. "make train*").
+type OffloadRule struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Runner  string `json:"runner" yaml:"runner"`
+}
+
+// OffloadOutput is a chunk of a remote command's output
+type OffloadOutput struct {
+	Data   string `json:"data"`
+	Stderr bool   `json:"stderr,omitempty"`
+}
+
+// OffloadRequest is a command to run on a remote runner
+type OffloadRequest struct {
+	WorkDir string
+	Command string
+	// Runner to use; empty picks one from the matching rule
+	Runner string
+}
+
+// OffloadJob records a command dispatched to a remote runner
+type OffloadJob struct {
+	ID         string     `json:"id"`
+	Runner     string     `json:"runner"`
+	Command    string     `json:"command"`
+	WorkDir    string     `json:"work_dir"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Remote exit code; -1 until finished or when the runner failed
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// OffloadRunner runs a command somewhere else, streaming its output back,
+// and returns the command's exit code
+type OffloadRunner interface {
+	Run(ctx context.Context, req OffloadRequest, onOutput func(OffloadOutput)) (int, error)
+}
+
+// OffloadService dispatches heavy commands to remote runners configured in
+// the worktree's .catnip.yaml, so the catnip container stays lightweight
+type OffloadService struct {
+	mu   sync.Mutex
+	jobs []*OffloadJob
+	// Builds a runner from its config (replaceable for testing)
+	newRunner func(name string, cfg OffloadRunnerConfig) (OffloadRunner, error)
+}
+
+// NewOffloadService creates an offload service
+func NewOffloadService() *OffloadService {
+	return &OffloadService{newRunner: newOffloadRunner}
+}
+
+// LoadOffloadConfig reads the offload section of .catnip.yaml in dir
+func LoadOffloadConfig(dir string) (OffloadConfig, error) {
+	cfg, err := LoadCatnipConfig(dir)
+	if err != nil {
+		return OffloadConfig{}, err
+	}
+	return cfg.Offload, nil
+}
+
+// Match returns the first rule matching command
+func (c OffloadConfig) Match(command string) (*OffloadRule, bool) {
+	command = strings.Join(strings.Fields(command), " ")
+	for i := range c.Rules {
+		if offloadPatternRegexp(c.Rules[i].Pattern).MatchString(command) {
+			return &c.Rules[i], true
+		}
+	}
+	return nil, false
+}
+
+// Commands returns the programs rules can match (the first word of each
+// pattern), which terminals wrap so matching invocations are offloaded
+func (c OffloadConfig) Commands() []string {
+	seen := make(map[string]bool)
+	var commands []string
+	for _, rule := range c.Rules {
+		fields := strings.Fields(rule.Pattern)
+		if len(fields) == 0 || strings.ContainsAny(fields[0], "*/") || !shellFunctionName.MatchString(fields[0]) {
+			continue
+		}
+		if !seen[fields[0]] {
+			seen[fields[0]] = true
+			commands = append(commands, fields[0])
+		}
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+var shellFunctionName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+func offloadPatternRegexp(pattern string) *regexp.Regexp {
+	parts := strings.Split(strings.Join(strings.Fields(pattern), " "), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// OffloadShellEnv exports bash functions wrapping the commands offload rules
+// match, so `make train` typed in a terminal (or run by Claude's Bash tool)
+// goes through `catnip offload`, which runs anything that doesn't match locally
+func OffloadShellEnv(workDir string) []string {
+	cfg, err := LoadOffloadConfig(workDir)
+	if err != nil {
+		return nil
+	}
+	var env []string
+	for _, command := range cfg.Commands() {
+		env = append(env, fmt.Sprintf(`BASH_FUNC_%s%%%%=() {  catnip offload --if-matches -- %s "$@"
+}`, command, command))
+	}
+	return env
+}
+
+// Run dispatches a command to its runner, streaming output to onOutput, and
+// returns the finished job
+func (s *OffloadService) Run(ctx context.Context, req OffloadRequest, onOutput func(OffloadOutput)) (*OffloadJob, error) {
+	cfg, err := LoadOffloadConfig(req.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+	if req.Runner == "" {
+		rule, ok := cfg.Match(req.Command)
+		if !ok {
+			return nil, fmt.Errorf("no offload rule matches %q", req.Command)
+		}
+		req.Runner = rule.Runner
+	}
+	runnerCfg, ok := cfg.Runners[req.Runner]
+	if !ok {
+		return nil, fmt.Errorf("offload runner %q is not configured", req.Runner)
+	}
+	runner, err := s.newRunner(req.Runner, runnerCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &OffloadJob{
+		ID:        uuid.New().String(),
+		Runner:    req.Runner,
+		Command:   req.Command,
+		WorkDir:   req.WorkDir,
+		StartedAt: time.Now(),
+		ExitCode:  -1,
+	}
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	if len(s.jobs) > maxOffloadJobs {
+		s.jobs = s.jobs[len(s.jobs)-maxOffloadJobs:]
+	}
+	s.mu.Unlock()
+	logger.Infof("🚀 Offloading %q to %s", req.Command, req.Runner)
+
+	exitCode, runErr := runner.Run(ctx, req, onOutput)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	job.FinishedAt = &now
+	if runErr != nil {
+		job.Error = runErr.Error()
+	} else {
+		job.ExitCode = exitCode
+	}
+	copied := *job
+	return &copied, runErr
+}
+
+// Jobs returns recent offloaded jobs, newest first
+func (s *OffloadService) Jobs() []*OffloadJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]*OffloadJob, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		copied := *s.jobs[i]
+		jobs = append(jobs, &copied)
+	}
+	return jobs
+}
+
+func newOffloadRunner(name string, cfg OffloadRunnerConfig) (OffloadRunner, error) {
+	switch {
+	case cfg.SSH != "":
+		return &SSHOffloadRunner{Destination: cfg.SSH, Dir: cfg.Dir}, nil
+	case cfg.URL != "":
+		token := ""
+		if cfg.TokenEnv != "" {
+			token = os.Getenv(cfg.TokenEnv)
+		}
+		return &APIOffloadRunner{URL: cfg.URL, Token: token, Client: &http.Client{}}, nil
+	}
+	return nil, fmt.Errorf("offload runner %q needs ssh or url", name)
+}
+
+// SSHOffloadRunner copies the worktree (tracked and untracked, non-ignored
+// files) to a machine over ssh and runs the command there
+type SSHOffloadRunner struct {
+	Destination string
+	Dir         string
+}
+
+// Run implements OffloadRunner
+func (r *SSHOffloadRunner) Run(ctx context.Context, req OffloadRequest, onOutput func(OffloadOutput)) (int, error) {
+	dir := r.Dir
+	if dir == "" {
+		dir = "catnip-offload/" + filepath.Base(req.WorkDir)
+	}
+
+	copyCmd := exec.CommandContext(ctx, "ssh", r.Destination, fmt.Sprintf("mkdir -p %s && tar -xzf - -C %s", shellQuote(dir), shellQuote(dir)))
+	archive, err := worktreeArchive(req.WorkDir)
+	if err != nil {
+		return -1, err
+	}
+	copyCmd.Stdin = archive
+	if output, err := copyCmd.CombinedOutput(); err != nil {
+		return -1, fmt.Errorf("failed to copy worktree to %s: %v\n%s", r.Destination, err, strings.TrimSpace(string(output)))
+	}
+
+	run := exec.CommandContext(ctx, "ssh", r.Destination, fmt.Sprintf("cd %s && bash -lc %s", shellQuote(dir), shellQuote(req.Command)))
+	return runStreaming(run, onOutput)
+}
+
+// runStreaming runs cmd, passing output chunks to onOutput as they arrive,
+// and returns its exit code
+func runStreaming(cmd *exec.Cmd, onOutput func(OffloadOutput)) (int, error) {
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return -1, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return -1, err
+	}
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	pump := func(r io.Reader, isStderr bool) {
+		defer wg.Done()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				mu.Lock()
+				onOutput(OffloadOutput{Data: string(buf[:n]), Stderr: isStderr})
+				mu.Unlock()
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	wg.Add(2)
+	go pump(stdout, false)
+	go pump(stderr, true)
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() >= 0 {
+			return exitErr.ExitCode(), nil
+		}
+		return -1, err
+	}
+	return 0, nil
+}
+
+// worktreeArchive returns a gzipped tar of the worktree's tracked and
+// untracked, non-ignored files
+func worktreeArchive(workDir string) (io.Reader, error) {
+	output, err := exec.Command("git", "-C", workDir, "ls-files", "-z", "--cached", "--others", "--exclude-standard").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list worktree files: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeFilesArchive(&buf, workDir, strings.Split(string(output), "\x00")); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// writeFilesArchive writes a gzipped tar of the regular files and symlinks
+// named relative to dir. Names that no longer exist are skipped.
+func writeFilesArchive(w io.Writer, dir string, names []string) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		info, err := os.Lstat(path)
+		if err != nil {
+			continue // Deleted but not yet staged
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, _ = os.Readlink(path)
+		} else if !info.Mode().IsRegular() {
+			continue
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func shellQuote(s string) string {
+	if strings.HasPrefix(s, "~/") {
+		// Leave the home directory for the remote shell to expand
+		return "~/" + shellQuote(s[2:])
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// APIOffloadRunner posts the command to a runner API. The runner checks out
+// the commit itself, so only committed and pushed changes are visible to it.
+type APIOffloadRunner struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// OffloadAPIRequest is the body posted to an offload runner API
+type OffloadAPIRequest struct {
+	Command string `json:"command"`
+	Remote  string `json:"remote,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// offloadAPIEvent is one NDJSON line of a runner API response: output, or
+// the final exit code
+type offloadAPIEvent struct {
+	Data     string `json:"data,omitempty"`
+	Stderr   bool   `json:"stderr,omitempty"`
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Run implements OffloadRunner
+func (r *APIOffloadRunner) Run(ctx context.Context, req OffloadRequest, onOutput func(OffloadOutput)) (int, error) {
+	gitValue := func(args ...string) string {
+		output, _ := exec.Command("git", append([]string{"-C", req.WorkDir}, args...)...).Output()
+		return strings.TrimSpace(string(output))
+	}
+	body, err := json.Marshal(OffloadAPIRequest{
+		Command: req.Command,
+		Remote:  gitValue("remote", "get-url", "origin"),
+		Branch:  gitValue("rev-parse", "--abbrev-ref", "HEAD"),
+		Commit:  gitValue("rev-parse", "HEAD"),
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/x-ndjson")
+	if r.Token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+
+	resp, err := r.Client.Do(httpReq)
+	if err != nil {
+		return -1, fmt.Errorf("offload runner request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return -1, fmt.Errorf("offload runner returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var event offloadAPIEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if event.Error != "" {
+			return -1, fmt.Errorf("offload runner failed: %s", event.Error)
+		}
+		if event.Data != "" {
+			onOutput(OffloadOutput{Data: event.Data, Stderr: event.Stderr})
+		}
+		if event.ExitCode != nil {
+			return *event.ExitCode, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return -1, fmt.Errorf("offload runner stream failed: %v", err)
+	}
+	return -1, errors.New("offload runner closed the stream without an exit code")
+}
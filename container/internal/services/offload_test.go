@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOffloadConfig = `offload:
+  runners:
+    gpu:
+      ssh: ubuntu@gpu-box
+  rules:
+    - pattern: "make train*"
+      runner: gpu
+    - pattern: "bazel build *"
+      runner: gpu
+    - pattern: "*/bench.sh"
+      runner: gpu
+`
+
+type fakeOffloadRunner struct {
+	exitCode int
+	err      error
+	got      OffloadRequest
+}
+
+func (f *fakeOffloadRunner) Run(ctx context.Context, req OffloadRequest, onOutput func(OffloadOutput)) (int, error) {
+	f.got = req
+	onOutput(OffloadOutput{Data: "epoch 1\n"})
+	onOutput(OffloadOutput{Data: "warning\n", Stderr: true})
+	return f.exitCode, f.err
+}
+
+func TestOffloadConfigMatch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte(testOffloadConfig), 0644))
+	cfg, err := LoadOffloadConfig(dir)
+	require.NoError(t, err)
+
+	rule, ok := cfg.Match("make   train EPOCHS=3")
+	require.True(t, ok)
+	assert.Equal(t, "gpu", rule.Runner)
+	_, ok = cfg.Match("make train")
+	assert.True(t, ok)
+	_, ok = cfg.Match("make test")
+	assert.False(t, ok)
+	_, ok = cfg.Match("bazel build //...")
+	assert.True(t, ok)
+	_, ok = cfg.Match("bazel test //...")
+	assert.False(t, ok)
+
+	// Patterns starting with a wildcard or path can't be wrapped as shell functions
+	assert.Equal(t, []string{"bazel", "make"}, cfg.Commands())
+	env := OffloadShellEnv(dir)
+	require.Len(t, env, 2)
+	assert.Equal(t, "BASH_FUNC_bazel%%=() {  catnip offload --if-matches -- bazel \"$@\"\n}", env[0])
+
+	assert.Empty(t, OffloadShellEnv(t.TempDir()))
+}
+
+func TestOffloadServiceRun(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte(testOffloadConfig), 0644))
+
+	runner := &fakeOffloadRunner{exitCode: 3}
+	s := NewOffloadService()
+	s.newRunner = func(name string, cfg OffloadRunnerConfig) (OffloadRunner, error) {
+		assert.Equal(t, "ubuntu@gpu-box", cfg.SSH)
+		return runner, nil
+	}
+
+	var output []OffloadOutput
+	job, err := s.Run(context.Background(), OffloadRequest{WorkDir: dir, Command: "make train"}, func(o OffloadOutput) {
+		output = append(output, o)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, job.ExitCode)
+	assert.Equal(t, "gpu", job.Runner)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, []OffloadOutput{{Data: "epoch 1\n"}, {Data: "warning\n", Stderr: true}}, output)
+
+	_, err = s.Run(context.Background(), OffloadRequest{WorkDir: dir, Command: "make test"}, func(OffloadOutput) {})
+	assert.ErrorContains(t, err, "no offload rule matches")
+	_, err = s.Run(context.Background(), OffloadRequest{WorkDir: dir, Command: "make test", Runner: "tpu"}, func(OffloadOutput) {})
+	assert.ErrorContains(t, err, `runner "tpu" is not configured`)
+
+	runner.err = errors.New("ssh: connect to host gpu-box: Connection refused")
+	job, err = s.Run(context.Background(), OffloadRequest{WorkDir: dir, Command: "make train"}, func(OffloadOutput) {})
+	assert.Error(t, err)
+	assert.Equal(t, -1, job.ExitCode)
+
+	jobs := s.Jobs()
+	require.Len(t, jobs, 2)
+	assert.NotEmpty(t, jobs[0].Error)
+	assert.Equal(t, 3, jobs[1].ExitCode)
+}
+
+func TestAPIOffloadRunner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req OffloadAPIRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		fmt.Fprintf(w, `{"data":"running %s\n"}`+"\n", req.Command)
+		fmt.Fprintln(w, `{"data":"oops\n","stderr":true}`)
+		fmt.Fprintln(w, `{"exit_code":1}`)
+	}))
+	defer server.Close()
+
+	runner := &APIOffloadRunner{URL: server.URL, Token: "secret", Client: server.Client()}
+	var out strings.Builder
+	exitCode, err := runner.Run(context.Background(), OffloadRequest{WorkDir: t.TempDir(), Command: "make train"}, func(o OffloadOutput) {
+		out.WriteString(o.Data)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, exitCode)
+	assert.Equal(t, "running make train\noops\n", out.String())
+}
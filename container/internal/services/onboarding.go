@@ -0,0 +1,266 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// SetupStepID identifies a first-run setup step
+type SetupStepID string
+
+const (
+	SetupStepGitHubAuth      SetupStepID = "github_auth"
+	SetupStepClaudeAuth      SetupStepID = "claude_auth"
+	SetupStepFirstRepository SetupStepID = "first_repository"
+	SetupStepFirstWorktree   SetupStepID = "first_worktree"
+)
+
+// SetupStepStatus is where a setup step stands
+type SetupStepStatus string
+
+const (
+	SetupStepComplete SetupStepStatus = "complete"
+	SetupStepCurrent  SetupStepStatus = "current" // First incomplete step - what the user should do next
+	SetupStepPending  SetupStepStatus = "pending" // Waiting on an earlier step
+	SetupStepSkipped  SetupStepStatus = "skipped"
+)
+
+// SetupAction tells a client how to resolve a setup step
+type SetupAction struct {
+	Label string `json:"label"`
+	// API call that starts the step, e.g. "POST /v1/auth/github/start"
+	Method   string `json:"method,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Frontend route where the step can be completed
+	Link string `json:"link,omitempty"`
+}
+
+// SetupStep is one step of first-run setup
+type SetupStep struct {
+	ID          SetupStepID     `json:"id"`
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Status      SetupStepStatus `json:"status"`
+	// Optional steps can be skipped (e.g. GitHub auth when only using local repos)
+	Optional bool         `json:"optional"`
+	Action   *SetupAction `json:"action,omitempty"`
+}
+
+// SetupStatus is the first-run setup state
+type SetupStatus struct {
+	Steps       []SetupStep `json:"steps"`
+	CurrentStep SetupStepID `json:"current_step,omitempty"`
+	Complete    bool        `json:"complete"`
+}
+
+// SetupChecks reports which prerequisites are already satisfied
+type SetupChecks interface {
+	GitHubAuthenticated() bool
+	ClaudeAuthenticated() bool
+	RepositoryCount() int
+	WorktreeCount() int
+}
+
+type setupStepDefinition struct {
+	id          SetupStepID
+	title       string
+	description string
+	optional    bool
+	action      SetupAction
+	done        func(SetupChecks) bool
+}
+
+var setupSteps = []setupStepDefinition{
+	{
+		id:          SetupStepGitHubAuth,
+		title:       "Connect GitHub",
+		description: "Log in with the GitHub CLI to clone private repositories and open pull requests.",
+		optional:    true,
+		action:      SetupAction{Label: "Log in to GitHub", Method: "POST", Endpoint: "/v1/auth/github/start"},
+		done:        func(c SetupChecks) bool { return c.GitHubAuthenticated() },
+	},
+	{
+		id:          SetupStepClaudeAuth,
+		title:       "Connect Claude",
+		description: "Log in to Claude so agents can run in your workspaces.",
+		action:      SetupAction{Label: "Log in to Claude", Method: "POST", Endpoint: "/v1/claude/onboarding/start"},
+		done:        func(c SetupChecks) bool { return c.ClaudeAuthenticated() },
+	},
+	{
+		id:          SetupStepFirstRepository,
+		title:       "Add a repository",
+		description: "Check out a GitHub repository or mount a local one.",
+		action:      SetupAction{Label: "Choose a repository", Link: "/workspace/repos"},
+		done:        func(c SetupChecks) bool { return c.RepositoryCount() > 0 },
+	},
+	{
+		id:          SetupStepFirstWorktree,
+		title:       "Create a workspace",
+		description: "Create your first worktree to start working with an agent.",
+		action:      SetupAction{Label: "New workspace", Link: "/workspace/new"},
+		done:        func(c SetupChecks) bool { return c.WorktreeCount() > 0 },
+	},
+}
+
+// onboardingState is the persisted part of setup (which steps were skipped)
+type onboardingState struct {
+	Skipped map[SetupStepID]time.Time `json:"skipped"`
+}
+
+// OnboardingService tracks first-run setup so clients can present a guided
+// flow instead of users discovering missing prerequisites via errors
+type OnboardingService struct {
+	checks    SetupChecks
+	statePath string
+	mu        sync.Mutex
+}
+
+// gitSetupChecks answers setup checks from the git service and auth files
+type gitSetupChecks struct {
+	gitService *GitService
+}
+
+func (c *gitSetupChecks) GitHubAuthenticated() bool {
+	return c.gitService.githubManager.IsAuthenticated()
+}
+
+func (c *gitSetupChecks) ClaudeAuthenticated() bool {
+	if os.Getenv("ANTHROPIC_API_KEY") != "" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(config.Runtime.HomeDir, ".claude", ".credentials.json"))
+	return err == nil
+}
+
+func (c *gitSetupChecks) RepositoryCount() int {
+	return len(c.gitService.stateManager.GetAllRepositories())
+}
+
+func (c *gitSetupChecks) WorktreeCount() int {
+	return len(c.gitService.stateManager.GetAllWorktrees())
+}
+
+// NewOnboardingService creates an onboarding service backed by the git service
+func NewOnboardingService(gitService *GitService) *OnboardingService {
+	return NewOnboardingServiceWithChecks(&gitSetupChecks{gitService: gitService}, filepath.Join(config.Runtime.VolumeDir, "onboarding.json"))
+}
+
+// NewOnboardingServiceWithChecks creates an onboarding service with custom checks (for testing)
+func NewOnboardingServiceWithChecks(checks SetupChecks, statePath string) *OnboardingService {
+	return &OnboardingService{
+		checks:    checks,
+		statePath: statePath,
+	}
+}
+
+func (s *OnboardingService) loadState() onboardingState {
+	state := onboardingState{Skipped: make(map[SetupStepID]time.Time)}
+	data, err := os.ReadFile(s.statePath)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.Warnf("⚠️ Ignoring corrupt onboarding state: %v", err)
+		return onboardingState{Skipped: make(map[SetupStepID]time.Time)}
+	}
+	if state.Skipped == nil {
+		state.Skipped = make(map[SetupStepID]time.Time)
+	}
+	return state
+}
+
+func (s *OnboardingService) saveState(state onboardingState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.statePath, data, 0644)
+}
+
+// Status evaluates each setup step against the current environment. Steps are
+// completed in order; the first one that is neither complete nor skipped is
+// the current step.
+func (s *OnboardingService) Status() SetupStatus {
+	s.mu.Lock()
+	state := s.loadState()
+	s.mu.Unlock()
+
+	status := SetupStatus{Steps: make([]SetupStep, 0, len(setupSteps))}
+	for _, def := range setupSteps {
+		step := SetupStep{
+			ID:          def.id,
+			Title:       def.title,
+			Description: def.description,
+			Optional:    def.optional,
+		}
+
+		_, skipped := state.Skipped[def.id]
+		switch {
+		case def.done(s.checks):
+			step.Status = SetupStepComplete
+		case skipped:
+			step.Status = SetupStepSkipped
+		case status.CurrentStep == "":
+			step.Status = SetupStepCurrent
+			status.CurrentStep = def.id
+		default:
+			step.Status = SetupStepPending
+		}
+
+		if step.Status != SetupStepComplete {
+			action := def.action
+			step.Action = &action
+		}
+		status.Steps = append(status.Steps, step)
+	}
+
+	status.Complete = status.CurrentStep == ""
+	return status
+}
+
+// SkipStep marks an optional step as skipped
+func (s *OnboardingService) SkipStep(id SetupStepID) (SetupStatus, error) {
+	var def *setupStepDefinition
+	for i := range setupSteps {
+		if setupSteps[i].id == id {
+			def = &setupSteps[i]
+		}
+	}
+	if def == nil {
+		return SetupStatus{}, fmt.Errorf("unknown setup step %q", id)
+	}
+	if !def.optional {
+		return SetupStatus{}, fmt.Errorf("setup step %q is required and can't be skipped", id)
+	}
+
+	s.mu.Lock()
+	state := s.loadState()
+	state.Skipped[id] = time.Now()
+	err := s.saveState(state)
+	s.mu.Unlock()
+	if err != nil {
+		return SetupStatus{}, fmt.Errorf("failed to save onboarding state: %v", err)
+	}
+	return s.Status(), nil
+}
+
+// Reset clears skipped steps so setup is offered again
+func (s *OnboardingService) Reset() (SetupStatus, error) {
+	s.mu.Lock()
+	err := os.Remove(s.statePath)
+	s.mu.Unlock()
+	if err != nil && !os.IsNotExist(err) {
+		return SetupStatus{}, fmt.Errorf("failed to reset onboarding state: %v", err)
+	}
+	return s.Status(), nil
+}
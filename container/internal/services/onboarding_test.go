@@ -0,0 +1,76 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSetupChecks struct {
+	github, claude   bool
+	repos, worktrees int
+}
+
+func (f *fakeSetupChecks) GitHubAuthenticated() bool { return f.github }
+func (f *fakeSetupChecks) ClaudeAuthenticated() bool { return f.claude }
+func (f *fakeSetupChecks) RepositoryCount() int      { return f.repos }
+func (f *fakeSetupChecks) WorktreeCount() int        { return f.worktrees }
+
+func stepStatuses(status SetupStatus) map[SetupStepID]SetupStepStatus {
+	result := make(map[SetupStepID]SetupStepStatus)
+	for _, step := range status.Steps {
+		result[step.ID] = step.Status
+	}
+	return result
+}
+
+func TestOnboardingStatus(t *testing.T) {
+	checks := &fakeSetupChecks{}
+	service := NewOnboardingServiceWithChecks(checks, filepath.Join(t.TempDir(), "onboarding.json"))
+
+	status := service.Status()
+	assert.False(t, status.Complete)
+	assert.Equal(t, SetupStepGitHubAuth, status.CurrentStep)
+	assert.Equal(t, SetupStepPending, stepStatuses(status)[SetupStepClaudeAuth])
+	require.NotNil(t, status.Steps[0].Action)
+	assert.Equal(t, "/v1/auth/github/start", status.Steps[0].Action.Endpoint)
+
+	checks.github = true
+	checks.claude = true
+	status = service.Status()
+	assert.Equal(t, SetupStepFirstRepository, status.CurrentStep)
+	assert.Equal(t, SetupStepComplete, stepStatuses(status)[SetupStepGitHubAuth])
+	assert.Nil(t, status.Steps[0].Action)
+
+	checks.repos = 1
+	checks.worktrees = 1
+	status = service.Status()
+	assert.True(t, status.Complete)
+	assert.Empty(t, status.CurrentStep)
+}
+
+func TestOnboardingSkipStep(t *testing.T) {
+	checks := &fakeSetupChecks{}
+	statePath := filepath.Join(t.TempDir(), "onboarding.json")
+	service := NewOnboardingServiceWithChecks(checks, statePath)
+
+	_, err := service.SkipStep(SetupStepClaudeAuth)
+	assert.Error(t, err, "required steps can't be skipped")
+	_, err = service.SkipStep("bogus")
+	assert.Error(t, err)
+
+	status, err := service.SkipStep(SetupStepGitHubAuth)
+	require.NoError(t, err)
+	assert.Equal(t, SetupStepSkipped, stepStatuses(status)[SetupStepGitHubAuth])
+	assert.Equal(t, SetupStepClaudeAuth, status.CurrentStep)
+
+	// Skips persist across restarts
+	reloaded := NewOnboardingServiceWithChecks(checks, statePath)
+	assert.Equal(t, SetupStepClaudeAuth, reloaded.Status().CurrentStep)
+
+	status, err = reloaded.Reset()
+	require.NoError(t, err)
+	assert.Equal(t, SetupStepGitHubAuth, status.CurrentStep)
+}
@@ -0,0 +1,516 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+const (
+	defaultOutboxInterval = 30 * time.Second
+	// Finished operations kept so clients can see what ran while they were away
+	maxFinishedOutboxOperations = 50
+	outboxProbeAddress          = "github.com:443"
+	outboxProbeTimeout          = 5 * time.Second
+)
+
+// OutboxOperationKind identifies a queued network operation
+type OutboxOperationKind string
+
+const (
+	// OutboxCreatePullRequest pushes a worktree's branch and opens a pull request
+	OutboxCreatePullRequest OutboxOperationKind = "create_pr"
+	// OutboxFetch fetches a worktree's source branch from origin
+	OutboxFetch OutboxOperationKind = "fetch"
+)
+
+// OutboxOperationStatus is the lifecycle state of a queued operation
+type OutboxOperationStatus string
+
+const (
+	OutboxQueued    OutboxOperationStatus = "queued"
+	OutboxRunning   OutboxOperationStatus = "running"
+	OutboxSucceeded OutboxOperationStatus = "succeeded"
+	OutboxFailed    OutboxOperationStatus = "failed"
+)
+
+// OutboxOperation is a network operation deferred until GitHub is reachable
+type OutboxOperation struct {
+	ID          string                `json:"id"`
+	Kind        OutboxOperationKind   `json:"kind"`
+	WorktreeID  string                `json:"worktree_id"`
+	Description string                `json:"description,omitempty"`
+	Status      OutboxOperationStatus `json:"status"`
+	// Kind-specific parameters needed to run the operation
+	Params json.RawMessage `json:"params,omitempty"`
+	// Result of a successful run (e.g. the pull request URL)
+	Result     string     `json:"result,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	Attempts   int        `json:"attempts"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// OutboxStatus reports connectivity and the queued operations
+type OutboxStatus struct {
+	Online    bool       `json:"online"`
+	LastCheck *time.Time `json:"last_check,omitempty"`
+	Queued    int        `json:"queued"`
+	// All operations, newest first
+	Operations []*OutboxOperation `json:"operations"`
+}
+
+// OutboxExecutor runs a queued operation, returning its result
+type OutboxExecutor func(op *OutboxOperation) (string, error)
+
+// OutboxEventsEmitter publishes operations run after connectivity returned
+type OutboxEventsEmitter interface {
+	EmitOutboxOperationFinished(op *OutboxOperation)
+}
+
+// OutboxService queues operations that need GitHub (opening pull requests,
+// fetching) while the machine is offline, persists them in the volume, and
+// runs them in order once GitHub is reachable again
+type OutboxService struct {
+	path      string
+	probe     func() error
+	interval  time.Duration
+	mu        sync.Mutex
+	flushMu   sync.Mutex // Serializes flushes
+	ops       map[string]*OutboxOperation
+	executors map[OutboxOperationKind]OutboxExecutor
+	events    OutboxEventsEmitter
+	online    bool
+	lastCheck *time.Time
+	wake      chan struct{}
+	stopChan  chan struct{}
+	running   bool
+}
+
+// NewOutboxService creates an outbox stored in the volume directory that
+// checks GitHub's reachability every 30 seconds while operations are queued
+func NewOutboxService() *OutboxService {
+	return NewOutboxServiceWithOptions(
+		filepath.Join(config.Runtime.VolumeDir, "outbox.json"),
+		probeGitHub,
+		defaultOutboxInterval,
+	)
+}
+
+// NewOutboxServiceWithOptions creates an outbox with an explicit path and
+// connectivity probe (for testing)
+func NewOutboxServiceWithOptions(path string, probe func() error, interval time.Duration) *OutboxService {
+	s := &OutboxService{
+		path:      path,
+		probe:     probe,
+		interval:  interval,
+		ops:       make(map[string]*OutboxOperation),
+		executors: make(map[OutboxOperationKind]OutboxExecutor),
+		online:    true,
+		wake:      make(chan struct{}, 1),
+		stopChan:  make(chan struct{}),
+	}
+	if err := s.load(); err != nil {
+		logger.Warnf("⚠️ Failed to load outbox: %v", err)
+	}
+	return s
+}
+
+func probeGitHub() error {
+	conn, err := net.DialTimeout("tcp", outboxProbeAddress, outboxProbeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// IsOfflineError reports whether an operation failed because GitHub couldn't
+// be reached, as opposed to being rejected
+func IsOfflineError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if git.IsTransientNetworkError(err, "") {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	for _, pattern := range []string{"error connecting to", "no such host", "dial tcp", "i/o timeout"} {
+		if strings.Contains(message, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithEvents sets the emitter used when queued operations finish
+func (s *OutboxService) WithEvents(events OutboxEventsEmitter) *OutboxService {
+	s.events = events
+	return s
+}
+
+// RegisterExecutor sets how operations of a kind are run
+func (s *OutboxService) RegisterExecutor(kind OutboxOperationKind, executor OutboxExecutor) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executors[kind] = executor
+}
+
+func (s *OutboxService) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var ops []*OutboxOperation
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return fmt.Errorf("corrupt outbox file: %v", err)
+	}
+	for _, op := range ops {
+		// Interrupted mid-run; running it again is safe for every kind
+		if op.Status == OutboxRunning {
+			op.Status = OutboxQueued
+		}
+		s.ops[op.ID] = op
+	}
+	return nil
+}
+
+func (s *OutboxService) saveLocked() {
+	s.pruneLocked()
+	ops := s.sortedLocked()
+
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(s.path), 0755); err == nil {
+			tmpPath := s.path + ".tmp"
+			if err = os.WriteFile(tmpPath, data, 0644); err == nil {
+				err = os.Rename(tmpPath, s.path)
+			}
+		}
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to persist outbox: %v", err)
+	}
+}
+
+// sortedLocked returns operations oldest first, the order they run in
+func (s *OutboxService) sortedLocked() []*OutboxOperation {
+	ops := make([]*OutboxOperation, 0, len(s.ops))
+	for _, op := range s.ops {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].CreatedAt.Before(ops[j].CreatedAt) })
+	return ops
+}
+
+// pruneLocked drops the oldest succeeded operations
+func (s *OutboxService) pruneLocked() {
+	var finished []*OutboxOperation
+	for _, op := range s.ops {
+		if op.Status == OutboxSucceeded {
+			finished = append(finished, op)
+		}
+	}
+	if len(finished) <= maxFinishedOutboxOperations {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].FinishedAt.Before(*finished[j].FinishedAt) })
+	for _, op := range finished[:len(finished)-maxFinishedOutboxOperations] {
+		delete(s.ops, op.ID)
+	}
+}
+
+// Online reports whether GitHub was reachable at the last check
+func (s *OutboxService) Online() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.online
+}
+
+// MarkOffline records that an operation just failed to reach GitHub, so
+// further operations are queued without waiting for another timeout
+func (s *OutboxService) MarkOffline() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.online = false
+}
+
+// Enqueue queues an operation to run once GitHub is reachable
+func (s *OutboxService) Enqueue(kind OutboxOperationKind, worktreeID, description string, params any) (*OutboxOperation, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode outbox params: %v", err)
+	}
+	now := time.Now()
+	op := &OutboxOperation{
+		ID:          uuid.New().String(),
+		Kind:        kind,
+		WorktreeID:  worktreeID,
+		Description: description,
+		Status:      OutboxQueued,
+		Params:      raw,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	s.mu.Lock()
+	s.ops[op.ID] = op
+	s.saveLocked()
+	copied := *op
+	s.mu.Unlock()
+
+	logger.Infof("📮 Queued %s until GitHub is reachable", description)
+	return &copied, nil
+}
+
+// Retry requeues a failed operation and tries to run it now
+func (s *OutboxService) Retry(id string) (*OutboxOperation, error) {
+	s.mu.Lock()
+	op, exists := s.ops[id]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("outbox operation %s not found", id)
+	}
+	if op.Status != OutboxFailed {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("only failed operations can be retried (operation is %s)", op.Status)
+	}
+	op.Status = OutboxQueued
+	op.UpdatedAt = time.Now()
+	op.FinishedAt = nil
+	s.saveLocked()
+	copied := *op
+	s.mu.Unlock()
+
+	s.Wake()
+	return &copied, nil
+}
+
+// Remove discards a queued or finished operation
+func (s *OutboxService) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	op, exists := s.ops[id]
+	if !exists {
+		return fmt.Errorf("outbox operation %s not found", id)
+	}
+	if op.Status == OutboxRunning {
+		return fmt.Errorf("outbox operation %s is running", id)
+	}
+	delete(s.ops, id)
+	s.saveLocked()
+	return nil
+}
+
+// Status returns connectivity and all operations, newest first
+func (s *OutboxService) Status() OutboxStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := OutboxStatus{
+		Online:     s.online,
+		LastCheck:  s.lastCheck,
+		Operations: make([]*OutboxOperation, 0, len(s.ops)),
+	}
+	ops := s.sortedLocked()
+	for i := len(ops) - 1; i >= 0; i-- {
+		copied := *ops[i]
+		status.Operations = append(status.Operations, &copied)
+		if copied.Status == OutboxQueued {
+			status.Queued++
+		}
+	}
+	return status
+}
+
+// Start checks connectivity and drains the queue in the background
+func (s *OutboxService) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		s.Flush()
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+			s.Flush()
+		}
+	}()
+}
+
+// Stop stops the background loop
+func (s *OutboxService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+}
+
+// Wake asks the background loop to check connectivity and drain the queue now
+func (s *OutboxService) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Flush runs queued operations in order if GitHub is reachable. It stops at
+// the first operation that fails to reach GitHub, leaving the rest queued.
+func (s *OutboxService) Flush() {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	var queued []*OutboxOperation
+	for _, op := range s.sortedLocked() {
+		if op.Status == OutboxQueued {
+			queued = append(queued, op)
+		}
+	}
+	s.mu.Unlock()
+	if len(queued) == 0 {
+		return
+	}
+
+	if !s.checkConnectivity() {
+		return
+	}
+
+	for _, op := range queued {
+		s.mu.Lock()
+		executor := s.executors[op.Kind]
+		if op.Status != OutboxQueued {
+			// Removed or changed while earlier operations ran
+			s.mu.Unlock()
+			continue
+		}
+		op.Status = OutboxRunning
+		op.Attempts++
+		op.UpdatedAt = time.Now()
+		s.saveLocked()
+		snapshot := *op
+		s.mu.Unlock()
+
+		var result string
+		var err error
+		if executor == nil {
+			err = fmt.Errorf("no way to run %s operations", op.Kind)
+		} else {
+			result, err = executor(&snapshot)
+		}
+
+		s.mu.Lock()
+		now := time.Now()
+		op.UpdatedAt = now
+		offline := IsOfflineError(err)
+		switch {
+		case err == nil:
+			op.Status = OutboxSucceeded
+			op.Result = result
+			op.LastError = ""
+			op.FinishedAt = &now
+		case offline:
+			op.Status = OutboxQueued
+			op.LastError = err.Error()
+			s.online = false
+		default:
+			op.Status = OutboxFailed
+			op.LastError = err.Error()
+			op.FinishedAt = &now
+		}
+		s.saveLocked()
+		snapshot = *op
+		s.mu.Unlock()
+
+		if offline {
+			logger.Infof("📮 Lost connectivity while running %s, will retry", snapshot.Description)
+			return
+		}
+		if err != nil {
+			logger.Warnf("⚠️ Queued %s failed: %v", snapshot.Description, err)
+		} else {
+			logger.Infof("📮 Ran queued %s", snapshot.Description)
+		}
+		if s.events != nil {
+			s.events.EmitOutboxOperationFinished(&snapshot)
+		}
+	}
+}
+
+func (s *OutboxService) checkConnectivity() bool {
+	err := s.probe()
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wasOnline := s.online
+	s.online = err == nil
+	s.lastCheck = &now
+	if s.online && !wasOnline {
+		logger.Infof("📮 GitHub is reachable again, running queued operations")
+	}
+	return s.online
+}
+
+// OutboxPullRequestParams are the parameters of a queued pull request
+type OutboxPullRequestParams struct {
+	Title     string `json:"title"`
+	Body      string `json:"body"`
+	ForcePush bool   `json:"force_push,omitempty"`
+}
+
+// PullRequestOutboxExecutor opens queued pull requests
+func PullRequestOutboxExecutor(gitService *GitService) OutboxExecutor {
+	return func(op *OutboxOperation) (string, error) {
+		var params OutboxPullRequestParams
+		if err := json.Unmarshal(op.Params, &params); err != nil {
+			return "", fmt.Errorf("invalid pull request params: %v", err)
+		}
+		pr, err := gitService.CreatePullRequest(op.WorktreeID, params.Title, params.Body, params.ForcePush)
+		if err != nil {
+			return "", err
+		}
+		return pr.URL, nil
+	}
+}
+
+// FetchOutboxExecutor runs queued fetches
+func FetchOutboxExecutor(gitService *GitService) OutboxExecutor {
+	return func(op *OutboxOperation) (string, error) {
+		return "", gitService.FetchWorktree(op.WorktreeID)
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingOutboxEvents struct {
+	finished []*OutboxOperation
+}
+
+func (r *recordingOutboxEvents) EmitOutboxOperationFinished(op *OutboxOperation) {
+	r.finished = append(r.finished, op)
+}
+
+func TestOutboxRunsQueuedOperationsWhenOnline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	probeErr := errors.New("dial tcp: lookup github.com: no such host")
+	outbox := NewOutboxServiceWithOptions(path, func() error { return probeErr }, 0)
+	events := &recordingOutboxEvents{}
+	outbox.WithEvents(events)
+
+	var ran []string
+	executorErr := map[string]error{}
+	outbox.RegisterExecutor(OutboxCreatePullRequest, func(op *OutboxOperation) (string, error) {
+		ran = append(ran, op.WorktreeID)
+		if err := executorErr[op.WorktreeID]; err != nil {
+			return "", err
+		}
+		return "https://github.com/org/repo/pull/1", nil
+	})
+
+	first, err := outbox.Enqueue(OutboxCreatePullRequest, "wt-1", "pull request \"one\"", OutboxPullRequestParams{Title: "one"})
+	require.NoError(t, err)
+	second, err := outbox.Enqueue(OutboxCreatePullRequest, "wt-2", "pull request \"two\"", OutboxPullRequestParams{Title: "two"})
+	require.NoError(t, err)
+
+	// Offline: nothing runs
+	outbox.Flush()
+	assert.Empty(t, ran)
+	status := outbox.Status()
+	assert.False(t, status.Online)
+	assert.Equal(t, 2, status.Queued)
+	assert.NotNil(t, status.LastCheck)
+
+	// Back online, but the connection drops during the first operation
+	probeErr = nil
+	executorErr["wt-1"] = errors.New("fatal: unable to access 'https://github.com/org/repo/': Could not resolve host: github.com")
+	outbox.Flush()
+	assert.Equal(t, []string{"wt-1"}, ran)
+	assert.False(t, outbox.Online())
+	assert.Equal(t, 2, outbox.Status().Queued)
+	assert.Empty(t, events.finished)
+
+	// Connectivity returns; a rejected operation fails without blocking the next
+	executorErr["wt-1"] = errors.New("no commits between main and feature")
+	outbox.Flush()
+	assert.Equal(t, []string{"wt-1", "wt-1", "wt-2"}, ran)
+	assert.True(t, outbox.Online())
+	require.Len(t, events.finished, 2)
+	assert.Equal(t, OutboxFailed, events.finished[0].Status)
+	assert.Equal(t, 2, events.finished[0].Attempts)
+	assert.Equal(t, OutboxSucceeded, events.finished[1].Status)
+	assert.Equal(t, "https://github.com/org/repo/pull/1", events.finished[1].Result)
+
+	// Only failed operations can be retried
+	_, err = outbox.Retry(second.ID)
+	assert.Error(t, err)
+	delete(executorErr, "wt-1")
+	retried, err := outbox.Retry(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, OutboxQueued, retried.Status)
+	outbox.Flush()
+	status = outbox.Status()
+	assert.Equal(t, 0, status.Queued)
+	require.Len(t, status.Operations, 2)
+	for _, op := range status.Operations {
+		assert.Equal(t, OutboxSucceeded, op.Status)
+	}
+
+	// Persisted across restarts
+	reloaded := NewOutboxServiceWithOptions(path, func() error { return nil }, 0)
+	assert.Len(t, reloaded.Status().Operations, 2)
+	require.NoError(t, reloaded.Remove(first.ID))
+	assert.Error(t, reloaded.Remove(first.ID))
+	assert.Len(t, reloaded.Status().Operations, 1)
+}
+
+func TestIsOfflineError(t *testing.T) {
+	assert.False(t, IsOfflineError(nil))
+	assert.True(t, IsOfflineError(errors.New("error connecting to api.github.com")))
+	assert.True(t, IsOfflineError(errors.New("ssh: connect to host github.com port 22: Network is unreachable")))
+	assert.False(t, IsOfflineError(errors.New("GraphQL: A pull request already exists for org:feature")))
+}
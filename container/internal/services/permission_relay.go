@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// DefaultPermissionTimeout is how long a relayed prompt waits before it's denied
+	DefaultPermissionTimeout = 5 * time.Minute
+	// Decided prompts kept for the list endpoint
+	maxDecidedPermissions = 100
+)
+
+// PermissionRelayEventsEmitter is notified when permission prompts are raised and answered
+type PermissionRelayEventsEmitter interface {
+	EmitClaudePermission(request models.ClaudePermissionRequest)
+}
+
+// PermissionAnswerFunc delivers a decision back to the Claude process
+type PermissionAnswerFunc func(decision models.ClaudePermissionDecision) error
+
+type pendingPermission struct {
+	request models.ClaudePermissionRequest
+	answer  PermissionAnswerFunc
+	timer   *time.Timer
+}
+
+// PermissionRelay holds permission prompts from Claude completions that run
+// without --dangerously-skip-permissions until a client approves or denies
+// them, so automation doesn't hang on a prompt nobody can see
+type PermissionRelay struct {
+	mu      sync.Mutex
+	pending map[string]*pendingPermission
+	decided []models.ClaudePermissionRequest
+	timeout time.Duration
+	now     func() time.Time
+	events  PermissionRelayEventsEmitter
+}
+
+// NewPermissionRelay creates an empty relay
+func NewPermissionRelay() *PermissionRelay {
+	return &PermissionRelay{
+		pending: make(map[string]*pendingPermission),
+		timeout: DefaultPermissionTimeout,
+		now:     time.Now,
+	}
+}
+
+// WithEvents sets the emitter notified of new and answered prompts
+func (r *PermissionRelay) WithEvents(events PermissionRelayEventsEmitter) *PermissionRelay {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = events
+	return r
+}
+
+// Request registers a permission prompt. answer is called exactly once, with
+// the client's decision or a denial when the prompt times out.
+func (r *PermissionRelay) Request(workingDir, toolName string, input map[string]interface{}, answer PermissionAnswerFunc) models.ClaudePermissionRequest {
+	r.mu.Lock()
+	now := r.now()
+	request := models.ClaudePermissionRequest{
+		ID:               "perm-" + uuid.New().String()[:8],
+		WorkingDirectory: workingDir,
+		ToolName:         toolName,
+		Input:            input,
+		Status:           models.ClaudePermissionPending,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(r.timeout),
+	}
+	entry := &pendingPermission{request: request, answer: answer}
+	r.pending[request.ID] = entry
+	entry.timer = time.AfterFunc(r.timeout, func() {
+		r.resolve(request.ID, models.ClaudePermissionExpired, models.ClaudePermissionDecision{
+			Message: "Permission request timed out without an answer",
+		})
+	})
+	events := r.events
+	r.mu.Unlock()
+
+	logger.Infof("🔐 Claude in %s asks to use %s (%s)", workingDir, toolName, request.ID)
+	if events != nil {
+		events.EmitClaudePermission(request)
+	}
+	return request
+}
+
+// Decide answers a pending prompt
+func (r *PermissionRelay) Decide(id string, decision models.ClaudePermissionDecision) (models.ClaudePermissionRequest, error) {
+	status := models.ClaudePermissionDenied
+	if decision.Allow {
+		status = models.ClaudePermissionAllowed
+	}
+	request, ok := r.resolve(id, status, decision)
+	if !ok {
+		return models.ClaudePermissionRequest{}, models.NewAPIError(models.ErrCodeInvalidRequest, "permission request %s is not pending", id)
+	}
+	return request, nil
+}
+
+// Cancel expires the pending prompts of a working directory whose Claude process exited
+func (r *PermissionRelay) Cancel(workingDir string) {
+	r.mu.Lock()
+	var ids []string
+	for id, entry := range r.pending {
+		if entry.request.WorkingDirectory == workingDir {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.Unlock()
+	for _, id := range ids {
+		r.resolve(id, models.ClaudePermissionExpired, models.ClaudePermissionDecision{Message: "Claude exited"})
+	}
+}
+
+// List returns pending prompts followed by recently decided ones, newest
+// first, optionally for one working directory
+func (r *PermissionRelay) List(workingDir string) []models.ClaudePermissionRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []models.ClaudePermissionRequest{}
+	var pending []models.ClaudePermissionRequest
+	for _, entry := range r.pending {
+		if workingDir == "" || entry.request.WorkingDirectory == workingDir {
+			pending = append(pending, entry.request)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.After(pending[j].CreatedAt) })
+	result = append(result, pending...)
+	for i := len(r.decided) - 1; i >= 0; i-- {
+		if workingDir == "" || r.decided[i].WorkingDirectory == workingDir {
+			result = append(result, r.decided[i])
+		}
+	}
+	return result
+}
+
+func (r *PermissionRelay) resolve(id string, status models.ClaudePermissionStatus, decision models.ClaudePermissionDecision) (models.ClaudePermissionRequest, bool) {
+	r.mu.Lock()
+	entry, ok := r.pending[id]
+	if !ok {
+		r.mu.Unlock()
+		return models.ClaudePermissionRequest{}, false
+	}
+	delete(r.pending, id)
+	entry.timer.Stop()
+	now := r.now()
+	entry.request.Status = status
+	entry.request.DecidedAt = &now
+	if status != models.ClaudePermissionAllowed {
+		entry.request.Message = decision.Message
+		decision.Allow = false
+	}
+	r.decided = append(r.decided, entry.request)
+	if len(r.decided) > maxDecidedPermissions {
+		r.decided = r.decided[len(r.decided)-maxDecidedPermissions:]
+	}
+	events := r.events
+	r.mu.Unlock()
+
+	if decision.Allow && decision.UpdatedInput == nil {
+		decision.UpdatedInput = entry.request.Input
+	}
+	if err := entry.answer(decision); err != nil {
+		logger.Warnf("⚠️ Could not answer permission request %s: %v", id, err)
+	}
+	logger.Infof("🔐 Permission request %s for %s: %s", id, entry.request.ToolName, status)
+	if events != nil {
+		events.EmitClaudePermission(entry.request)
+	}
+	return entry.request, true
+}
+
+// permissionControlResponse builds the stream-json answer to a can_use_tool
+// control request
+func permissionControlResponse(requestID string, decision models.ClaudePermissionDecision) map[string]interface{} {
+	var result map[string]interface{}
+	if decision.Allow {
+		result = map[string]interface{}{"behavior": "allow", "updatedInput": decision.UpdatedInput}
+	} else {
+		message := decision.Message
+		if message == "" {
+			message = "Permission denied"
+		}
+		result = map[string]interface{}{"behavior": "deny", "message": message}
+	}
+	return map[string]interface{}{
+		"type": "control_response",
+		"response": map[string]interface{}{
+			"subtype":    "success",
+			"request_id": requestID,
+			"response":   result,
+		},
+	}
+}
+
+// parsePermissionControlRequest extracts a can_use_tool control request from a stream-json line
+func parsePermissionControlRequest(jsonData map[string]interface{}) (requestID, toolName string, input map[string]interface{}, err error) {
+	requestID, _ = jsonData["request_id"].(string)
+	request, _ := jsonData["request"].(map[string]interface{})
+	if requestID == "" || request == nil {
+		return "", "", nil, fmt.Errorf("malformed control request")
+	}
+	if subtype, _ := request["subtype"].(string); subtype != "can_use_tool" {
+		return "", "", nil, fmt.Errorf("unsupported control request %q", subtype)
+	}
+	toolName, _ = request["tool_name"].(string)
+	input, _ = request["input"].(map[string]interface{})
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	return requestID, toolName, input, nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type recordingPermissionEvents struct {
+	mu       sync.Mutex
+	statuses []models.ClaudePermissionStatus
+}
+
+func (e *recordingPermissionEvents) EmitClaudePermission(request models.ClaudePermissionRequest) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.statuses = append(e.statuses, request.Status)
+}
+
+type bufferCloser struct {
+	mu sync.Mutex
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Buffer.Write(p)
+}
+
+func (b *bufferCloser) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.Buffer.String()
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+func TestPermissionRelayDecide(t *testing.T) {
+	events := &recordingPermissionEvents{}
+	relay := NewPermissionRelay().WithEvents(events)
+
+	var answers []models.ClaudePermissionDecision
+	answer := func(decision models.ClaudePermissionDecision) error {
+		answers = append(answers, decision)
+		return nil
+	}
+	input := map[string]interface{}{"command": "rm -rf build"}
+	allowed := relay.Request("/workspace/app", "Bash", input, answer)
+	denied := relay.Request("/workspace/other", "Write", map[string]interface{}{}, answer)
+
+	assert.Len(t, relay.List(""), 2)
+	assert.Len(t, relay.List("/workspace/app"), 1)
+
+	request, err := relay.Decide(allowed.ID, models.ClaudePermissionDecision{Allow: true})
+	require.NoError(t, err)
+	assert.Equal(t, models.ClaudePermissionAllowed, request.Status)
+	assert.Equal(t, input, answers[0].UpdatedInput, "allowing without changes passes the original input")
+
+	_, err = relay.Decide(allowed.ID, models.ClaudePermissionDecision{Allow: true})
+	assert.Error(t, err, "prompts are answered once")
+
+	request, err = relay.Decide(denied.ID, models.ClaudePermissionDecision{Message: "read-only task"})
+	require.NoError(t, err)
+	assert.Equal(t, models.ClaudePermissionDenied, request.Status)
+	assert.Equal(t, "read-only task", request.Message)
+	assert.False(t, answers[1].Allow)
+
+	list := relay.List("")
+	require.Len(t, list, 2)
+	assert.Equal(t, denied.ID, list[0].ID, "newest first")
+	assert.Equal(t, []models.ClaudePermissionStatus{
+		models.ClaudePermissionPending, models.ClaudePermissionPending,
+		models.ClaudePermissionAllowed, models.ClaudePermissionDenied,
+	}, events.statuses)
+}
+
+func TestPermissionRelayExpires(t *testing.T) {
+	relay := NewPermissionRelay()
+	relay.timeout = 20 * time.Millisecond
+
+	answered := make(chan models.ClaudePermissionDecision, 1)
+	request := relay.Request("/workspace/app", "Bash", nil, func(decision models.ClaudePermissionDecision) error {
+		answered <- decision
+		return nil
+	})
+
+	select {
+	case decision := <-answered:
+		assert.False(t, decision.Allow)
+	case <-time.After(2 * time.Second):
+		t.Fatal("unanswered prompt was not denied")
+	}
+	assert.Equal(t, models.ClaudePermissionExpired, relay.List("")[0].Status)
+	_, err := relay.Decide(request.ID, models.ClaudePermissionDecision{Allow: true})
+	assert.Error(t, err)
+
+	// Prompts of a process that exited are expired too
+	relay.timeout = time.Hour
+	relay.Request("/workspace/app", "Bash", nil, func(models.ClaudePermissionDecision) error { return nil })
+	relay.Cancel("/workspace/app")
+	assert.Equal(t, models.ClaudePermissionExpired, relay.List("")[0].Status)
+}
+
+func TestClaudeProcessRelaysControlRequests(t *testing.T) {
+	stdin := &bufferCloser{}
+	process := &ActiveClaudeProcess{
+		WorkingDirectory: "/workspace/app",
+		clients:          make(map[string]chan []byte),
+		stdin:            stdin,
+		permissions:      NewPermissionRelay(),
+	}
+	output := process.AddClient("test")
+
+	process.broadcastOutput(strings.NewReader(`{"type":"control_request","request_id":"req_1","request":{"subtype":"can_use_tool","tool_name":"Bash","input":{"command":"make deploy"}}}` + "\n"))
+
+	var chunk models.CreateCompletionResponse
+	require.NoError(t, json.Unmarshal(<-output, &chunk))
+	require.NotNil(t, chunk.Permission, "clients streaming the completion see the prompt")
+	assert.Equal(t, "Bash", chunk.Permission.ToolName)
+	assert.Equal(t, "make deploy", chunk.Permission.Input["command"])
+
+	_, err := process.permissions.Decide(chunk.Permission.ID, models.ClaudePermissionDecision{Message: "not today"})
+	require.NoError(t, err)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimSpace(stdin.String())), &response))
+	assert.Equal(t, "control_response", response["type"])
+	inner := response["response"].(map[string]interface{})
+	assert.Equal(t, "req_1", inner["request_id"])
+	assert.Equal(t, map[string]interface{}{"behavior": "deny", "message": "not today"}, inner["response"])
+
+	// The end of the turn closes the input held open for answers
+	process.broadcastOutput(strings.NewReader(`{"type":"result"}` + "\n"))
+	assert.True(t, process.inputClosed)
+}
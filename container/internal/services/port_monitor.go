@@ -29,6 +29,21 @@ type ServiceInfo struct {
 	PID             int       `json:"pid,omitempty"`
 	Command         string    `json:"command,omitempty"`
 	WorkingDir      string    `json:"working_dir,omitempty"`
+	ComposeService  string    `json:"compose_service,omitempty"` // docker compose service name, if detected from compose
+}
+
+const (
+	// detectionSourceTerminal marks ports discovered by parsing terminal output
+	detectionSourceTerminal = "terminal-output"
+	// detectionSourceCompose marks ports published by docker compose services
+	detectionSourceCompose = "docker-compose"
+)
+
+// isExternallyDetected reports whether a service was found by something other
+// than the TCP scan, meaning it may not appear in /proc/net/tcp (e.g. ports
+// published by a docker daemon outside this container)
+func (s *ServiceInfo) isExternallyDetected() bool {
+	return s.DetectionSource == detectionSourceTerminal || s.DetectionSource == detectionSourceCompose
 }
 
 // PortMonitor monitors /proc/net/tcp for port changes and manages service registry
@@ -40,6 +55,8 @@ type PortMonitor struct {
 	inodeCacheTime time.Time   // When the inode cache was last fully rebuilt
 	stopChan       chan bool
 	stopped        bool
+	composeDirs    map[string]bool // Project directories scanned for docker compose services
+	composeMutex   sync.Mutex
 }
 
 // NewPortMonitor creates a new port monitor instance
@@ -49,6 +66,7 @@ func NewPortMonitor() *PortMonitor {
 		lastTcpState: make(map[int]bool),
 		inodeToPID:   make(map[int]int),
 		stopChan:     make(chan bool),
+		composeDirs:  make(map[string]bool),
 	}
 
 	// Start monitoring immediately
@@ -61,6 +79,8 @@ func NewPortMonitor() *PortMonitor {
 func (pm *PortMonitor) Start() {
 	ticker := time.NewTicker(500 * time.Millisecond) // Check every 500ms for fast detection
 	defer ticker.Stop()
+	composeTicker := time.NewTicker(15 * time.Second) // docker compose ps is comparatively expensive
+	defer composeTicker.Stop()
 
 	var method string
 	if config.Runtime.PortMonitorEnabled {
@@ -74,6 +94,8 @@ func (pm *PortMonitor) Start() {
 		select {
 		case <-ticker.C:
 			pm.checkPortChanges()
+		case <-composeTicker.C:
+			go pm.scanComposeProjects()
 		case <-pm.stopChan:
 			logger.Debug("🛑 Stopped port monitoring")
 			pm.stopped = true
@@ -144,7 +166,7 @@ func (pm *PortMonitor) checkPortChanges() {
 	for port := range pm.lastTcpState {
 		if _, exists := currentPorts[port]; !exists {
 			// Check if this is a terminal-output detected port before removing it
-			if service, hasService := pm.services[port]; hasService && service.DetectionSource == "terminal-output" {
+			if service, hasService := pm.services[port]; hasService && service.isExternallyDetected() {
 				// For terminal-output detected ports, only remove them if they've been unhealthy for more than 30 seconds
 				// This prevents temporary network issues or scanning delays from removing valid ports
 				if service.Health == "unhealthy" && time.Since(service.LastSeen) > 30*time.Second {
@@ -175,9 +197,9 @@ func (pm *PortMonitor) checkPortChanges() {
 	for port := range currentPorts {
 		lastTcpState[port] = true
 	}
-	// Preserve terminal-output detected ports that are still active in services
+	// Preserve terminal-output and compose detected ports that are still active in services
 	for port, service := range pm.services {
-		if service.DetectionSource == "terminal-output" {
+		if service.isExternallyDetected() {
 			lastTcpState[port] = true
 		}
 	}
@@ -741,7 +763,7 @@ func (pm *PortMonitor) RegisterPortFromTerminalOutput(port int, workingDir strin
 	service := &ServiceInfo{
 		Port:            port,
 		ServiceType:     "unknown",
-		DetectionSource: "terminal-output",
+		DetectionSource: detectionSourceTerminal,
 		Health:          "unknown",
 		LastSeen:        time.Now(),
 		PID:             0, // Will be resolved later if possible
@@ -802,3 +824,98 @@ func (pm *PortMonitor) extractTitle(service *ServiceInfo, url string) {
 		}
 	}
 }
+
+// WatchComposeProject adds a project directory to the set scanned for docker
+// compose services. Directories without a compose file are ignored.
+func (pm *PortMonitor) WatchComposeProject(dir string) {
+	if dir == "" || FindComposeFile(dir) == "" {
+		return
+	}
+
+	pm.composeMutex.Lock()
+	alreadyWatched := pm.composeDirs[dir]
+	pm.composeDirs[dir] = true
+	pm.composeMutex.Unlock()
+
+	if !alreadyWatched {
+		logger.Debugf("🐳 Watching docker compose project in %s", dir)
+		go pm.RegisterComposeServices(dir)
+	}
+}
+
+// UnwatchComposeProject stops scanning a project directory for compose services
+func (pm *PortMonitor) UnwatchComposeProject(dir string) {
+	pm.composeMutex.Lock()
+	defer pm.composeMutex.Unlock()
+	delete(pm.composeDirs, dir)
+}
+
+// scanComposeProjects refreshes compose services for all watched directories
+func (pm *PortMonitor) scanComposeProjects() {
+	pm.composeMutex.Lock()
+	dirs := make([]string, 0, len(pm.composeDirs))
+	for dir := range pm.composeDirs {
+		dirs = append(dirs, dir)
+	}
+	pm.composeMutex.Unlock()
+
+	for _, dir := range dirs {
+		// Worktrees get deleted; stop scanning once the compose file is gone
+		if FindComposeFile(dir) == "" {
+			pm.UnwatchComposeProject(dir)
+			continue
+		}
+		pm.RegisterComposeServices(dir)
+	}
+}
+
+// RegisterComposeServices detects docker compose services in dir and registers
+// their published ports, tagged with the compose service name and health
+func (pm *PortMonitor) RegisterComposeServices(dir string) {
+	composeServices, err := DetectComposeServices(dir)
+	if err != nil {
+		logger.Debugf("⚠️  Failed to detect docker compose services in %s: %v", dir, err)
+		return
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	for _, composeService := range composeServices {
+		for _, port := range composeService.Ports {
+			if port == 6369 {
+				continue
+			}
+
+			service, exists := pm.services[port]
+			if !exists {
+				logger.Debugf("🐳 Port %d published by docker compose service %q in %s", port, composeService.Name, dir)
+				service = &ServiceInfo{
+					Port:            port,
+					ServiceType:     "unknown",
+					DetectionSource: detectionSourceCompose,
+					Health:          "unknown",
+					WorkingDir:      dir,
+				}
+				pm.services[port] = service
+				pm.lastTcpState[port] = true
+			}
+
+			service.ComposeService = composeService.Name
+			service.LastSeen = time.Now()
+			if service.Title == "" {
+				service.Title = composeService.Name
+			}
+
+			// Prefer docker's own healthcheck result when the service defines one
+			switch composeService.Health {
+			case "healthy", "unhealthy":
+				service.Health = composeService.Health
+			default:
+				if !exists {
+					go pm.healthCheckService(service)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const defaultPublishHostAddress = "127.0.0.1"
+
+// Host ports the catnip container itself publishes (web UI, SSH, dev server)
+var reservedHostPorts = map[int]string{
+	6369: "the catnip server",
+	2222: "catnip SSH",
+	5173: "the catnip dev server",
+}
+
+// PortPublishSource says where a publish rule was declared
+type PortPublishSource string
+
+const (
+	// PortPublishFromConfig rules come from the worktree's .catnip.yaml
+	PortPublishFromConfig PortPublishSource = "config"
+	// PortPublishFromDevcontainer rules come from forwardPorts in the worktree's devcontainer.json
+	PortPublishFromDevcontainer PortPublishSource = "devcontainer"
+	// PortPublishFromAPI rules were added through the API
+	PortPublishFromAPI PortPublishSource = "api"
+)
+
+// PortPublishStatus is the state of a rule on the host
+type PortPublishStatus string
+
+const (
+	// PortPublishPending rules are waiting for the host helper to bind them
+	PortPublishPending PortPublishStatus = "pending"
+	// PortPublishPublished rules are listening on the host
+	PortPublishPublished PortPublishStatus = "published"
+	// PortPublishConflict rules clash with another rule or a port in use on the host
+	PortPublishConflict PortPublishStatus = "conflict"
+	// PortPublishError rules failed on the host for another reason
+	PortPublishError PortPublishStatus = "error"
+)
+
+// PortsConfig configures workspace ports in .catnip.yaml
+type PortsConfig struct {
+	// Ports published directly on the host network while the worktree exists
+	Publish []PortPublishConfig `json:"publish,omitempty" yaml:"publish"`
+}
+
+// PortPublishConfig declares a port to publish on the host
+type PortPublishConfig struct {
+	// Port inside the container
+	Port int `json:"port" yaml:"port"`
+	// Port on the host (defaults to the container port)
+	HostPort int `json:"host_port,omitempty" yaml:"host_port"`
+	// Host address to bind (defaults to 127.0.0.1; 0.0.0.0 exposes the port on the network)
+	HostAddress string `json:"host_address,omitempty" yaml:"host_address"`
+}
+
+// PortPublishRule publishes a workspace port on the host network through the
+// host-side helper (catnip run), instead of only through the server's proxy
+type PortPublishRule struct {
+	ID           string            `json:"id"`
+	WorktreeID   string            `json:"worktree_id"`
+	WorktreeName string            `json:"worktree_name,omitempty"`
+	Port         int               `json:"port"`
+	HostPort     int               `json:"host_port"`
+	HostAddress  string            `json:"host_address"`
+	Source       PortPublishSource `json:"source"`
+	Status       PortPublishStatus `json:"status"`
+	// Why the rule is in conflict or failed
+	Error     string     `json:"error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// PortPublishEventsEmitter announces rule changes so the host helper can
+// reconcile its listeners
+type PortPublishEventsEmitter interface {
+	EmitPortPublishRulesChanged(rules []PortPublishRule)
+}
+
+// portPublishReport is the last status the host helper reported for a rule
+type portPublishReport struct {
+	status PortPublishStatus
+	err    string
+	at     time.Time
+}
+
+// PortPublishService keeps the host publish rules: rules declared in each
+// worktree's .catnip.yaml plus rules added through the API, which are
+// persisted in the volume. Rules only live as long as their worktree.
+type PortPublishService struct {
+	path      string
+	worktrees func() []*models.Worktree
+	events    PortPublishEventsEmitter
+	mu        sync.Mutex
+	rules     map[string]*PortPublishRule // API rules by ID
+	reports   map[string]portPublishReport
+}
+
+// NewPortPublishService creates a publish rule store in the volume directory
+func NewPortPublishService(worktrees func() []*models.Worktree) *PortPublishService {
+	return NewPortPublishServiceWithPath(filepath.Join(config.Runtime.VolumeDir, "port_publish.json"), worktrees)
+}
+
+// NewPortPublishServiceWithPath creates a publish rule store at an explicit
+// path (for testing)
+func NewPortPublishServiceWithPath(path string, worktrees func() []*models.Worktree) *PortPublishService {
+	s := &PortPublishService{
+		path:      path,
+		worktrees: worktrees,
+		rules:     make(map[string]*PortPublishRule),
+		reports:   make(map[string]portPublishReport),
+	}
+	if err := s.load(); err != nil {
+		logger.Warnf("⚠️ Failed to load port publish rules: %v", err)
+	}
+	return s
+}
+
+// WithEvents sets the emitter used when rules change
+func (s *PortPublishService) WithEvents(events PortPublishEventsEmitter) *PortPublishService {
+	s.events = events
+	return s
+}
+
+// Rules returns every active rule with its host status. Rules whose worktree
+// is gone are dropped, and rules clashing with an earlier rule's host address
+// and port are marked as conflicts.
+func (s *PortPublishService) Rules() []PortPublishRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rulesLocked()
+}
+
+func (s *PortPublishService) rulesLocked() []PortPublishRule {
+	worktrees := make(map[string]*models.Worktree)
+	for _, wt := range s.worktrees() {
+		worktrees[wt.ID] = wt
+	}
+
+	var rules []PortPublishRule
+	pruned := false
+	for id, rule := range s.rules {
+		wt, exists := worktrees[rule.WorktreeID]
+		if !exists {
+			logger.Infof("🔌 Dropping host publish rule %s: worktree %s is gone", id, rule.WorktreeID)
+			delete(s.rules, id)
+			delete(s.reports, id)
+			pruned = true
+			continue
+		}
+		r := *rule
+		r.WorktreeName = wt.Name
+		rules = append(rules, r)
+	}
+	if pruned {
+		if err := s.saveLocked(); err != nil {
+			logger.Warnf("⚠️ Failed to save port publish rules: %v", err)
+		}
+	}
+
+	for _, wt := range worktrees {
+		rules = append(rules, configPublishRules(wt)...)
+	}
+
+	// Config rules first, then devcontainer rules, then API rules in the order
+	// they were added
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Source != rules[j].Source {
+			return publishSourceRank[rules[i].Source] < publishSourceRank[rules[j].Source]
+		}
+		if !rules[i].CreatedAt.Equal(rules[j].CreatedAt) {
+			return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+		}
+		return rules[i].ID < rules[j].ID
+	})
+
+	for i := range rules {
+		rule := &rules[i]
+		rule.Status = PortPublishPending
+		if conflict := publishConflict(*rule, rules[:i]); conflict != "" {
+			rule.Status = PortPublishConflict
+			rule.Error = conflict
+			continue
+		}
+		if report, ok := s.reports[rule.ID]; ok {
+			rule.Status = report.status
+			rule.Error = report.err
+			at := report.at
+			rule.UpdatedAt = &at
+		}
+	}
+	return rules
+}
+
+// publishSourceRank orders rules by source, so declared rules win conflicts
+var publishSourceRank = map[PortPublishSource]int{
+	PortPublishFromConfig:       0,
+	PortPublishFromDevcontainer: 1,
+	PortPublishFromAPI:          2,
+}
+
+// configPublishRules reads the publish rules from a worktree's .catnip.yaml
+// and the ports forwarded by its devcontainer.json
+func configPublishRules(wt *models.Worktree) []PortPublishRule {
+	if wt.Path == "" {
+		return nil
+	}
+	cfg, err := LoadCatnipConfig(wt.Path)
+	if err != nil {
+		logger.Warnf("⚠️ Ignoring port publish rules in %s: %v", wt.Path, err)
+		return nil
+	}
+
+	var rules []PortPublishRule
+	for _, entry := range cfg.Ports.Publish {
+		rule := PortPublishRule{
+			ID:           fmt.Sprintf("config-%s-%d", wt.ID, entry.Port),
+			WorktreeID:   wt.ID,
+			WorktreeName: wt.Name,
+			Port:         entry.Port,
+			HostPort:     entry.HostPort,
+			HostAddress:  entry.HostAddress,
+			Source:       PortPublishFromConfig,
+			CreatedAt:    wt.CreatedAt,
+		}
+		normalizePublishRule(&rule)
+		if err := validatePublishRule(rule); err != nil {
+			logger.Warnf("⚠️ Ignoring port publish rule in %s: %v", wt.Path, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return append(rules, devcontainerPublishRules(wt, cfg)...)
+}
+
+func normalizePublishRule(rule *PortPublishRule) {
+	if rule.HostPort == 0 {
+		rule.HostPort = rule.Port
+	}
+	if rule.HostAddress == "" {
+		rule.HostAddress = defaultPublishHostAddress
+	}
+}
+
+func validatePublishRule(rule PortPublishRule) error {
+	if rule.Port <= 0 || rule.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if rule.HostPort <= 0 || rule.HostPort > 65535 {
+		return fmt.Errorf("host_port must be between 1 and 65535")
+	}
+	return nil
+}
+
+// publishConflict explains why rule can't bind next to the earlier rules
+func publishConflict(rule PortPublishRule, earlier []PortPublishRule) string {
+	if owner, reserved := reservedHostPorts[rule.HostPort]; reserved {
+		return fmt.Sprintf("host port %d is used by %s", rule.HostPort, owner)
+	}
+	for _, other := range earlier {
+		if other.Status == PortPublishConflict || other.HostPort != rule.HostPort {
+			continue
+		}
+		if other.HostAddress == rule.HostAddress || other.HostAddress == "0.0.0.0" || rule.HostAddress == "0.0.0.0" {
+			return fmt.Sprintf("host port %d is already published for port %d of %s", rule.HostPort, other.Port, other.WorktreeName)
+		}
+	}
+	return ""
+}
+
+// Add creates a rule publishing a worktree's port on the host. Rules that
+// clash with an existing rule are rejected.
+func (s *PortPublishService) Add(rule PortPublishRule) (PortPublishRule, error) {
+	normalizePublishRule(&rule)
+	if err := validatePublishRule(rule); err != nil {
+		return PortPublishRule{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var worktree *models.Worktree
+	for _, wt := range s.worktrees() {
+		if wt.ID == rule.WorktreeID {
+			worktree = wt
+		}
+	}
+	if worktree == nil {
+		return PortPublishRule{}, models.NewWorktreeNotFoundError(rule.WorktreeID)
+	}
+	rule.WorktreeName = worktree.Name
+
+	existing := s.rulesLocked()
+	for _, other := range existing {
+		if other.WorktreeID == rule.WorktreeID && other.Port == rule.Port {
+			return PortPublishRule{}, models.NewAPIError(models.ErrCodeInvalidRequest,
+				"port %d of %s is already published on host port %d", rule.Port, worktree.Name, other.HostPort)
+		}
+	}
+	if conflict := publishConflict(rule, existing); conflict != "" {
+		return PortPublishRule{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%s", conflict).
+			WithHint("Choose a different host_port")
+	}
+
+	rule.ID = uuid.New().String()
+	rule.Source = PortPublishFromAPI
+	rule.Status = PortPublishPending
+	rule.Error = ""
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = nil
+	stored := rule
+	s.rules[rule.ID] = &stored
+	if err := s.saveLocked(); err != nil {
+		delete(s.rules, rule.ID)
+		return PortPublishRule{}, err
+	}
+
+	logger.Infof("🔌 Publishing port %d of %s on host %s:%d", rule.Port, worktree.Name, rule.HostAddress, rule.HostPort)
+	s.emitLocked()
+	return rule, nil
+}
+
+// Remove deletes an API rule. Rules from .catnip.yaml can't be removed here.
+func (s *PortPublishService) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.rules[id]; !exists {
+		for _, rule := range s.rulesLocked() {
+			if rule.ID == id {
+				return models.NewAPIError(models.ErrCodeInvalidRequest, "rule %s is declared in %s", id, CatnipConfigFileName).
+					WithHint("Remove it from the worktree's " + CatnipConfigFileName)
+			}
+		}
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "port publish rule %s not found", id)
+	}
+
+	delete(s.rules, id)
+	delete(s.reports, id)
+	if err := s.saveLocked(); err != nil {
+		return err
+	}
+	s.emitLocked()
+	return nil
+}
+
+// ReportStatus records what happened when the host helper applied a rule
+func (s *PortPublishService) ReportStatus(id string, status PortPublishStatus, message string) error {
+	switch status {
+	case PortPublishPublished, PortPublishConflict, PortPublishError, PortPublishPending:
+	default:
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "unknown status %q", status)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := false
+	for _, rule := range s.rulesLocked() {
+		if rule.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "port publish rule %s not found", id)
+	}
+
+	previous, had := s.reports[id]
+	if had && previous.status == status && previous.err == message {
+		return nil
+	}
+	if status == PortPublishPending {
+		delete(s.reports, id)
+	} else {
+		s.reports[id] = portPublishReport{status: status, err: message, at: time.Now()}
+	}
+	s.emitLocked()
+	return nil
+}
+
+func (s *PortPublishService) emitLocked() {
+	if s.events != nil {
+		s.events.EmitPortPublishRulesChanged(s.rulesLocked())
+	}
+}
+
+func (s *PortPublishService) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var rules []*PortPublishRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		s.rules[rule.ID] = rule
+	}
+	return nil
+}
+
+func (s *PortPublishService) saveLocked() error {
+	rules := make([]*PortPublishRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		stored := *rule
+		stored.Status = PortPublishPending
+		stored.Error = ""
+		stored.UpdatedAt = nil
+		rules = append(rules, &stored)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].CreatedAt.Before(rules[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type recordingPublishEvents struct {
+	changes [][]PortPublishRule
+}
+
+func (r *recordingPublishEvents) EmitPortPublishRulesChanged(rules []PortPublishRule) {
+	r.changes = append(r.changes, rules)
+}
+
+func TestPortPublishRules(t *testing.T) {
+	appDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(appDir, CatnipConfigFileName), []byte(`ports:
+  publish:
+    - port: 3000
+    - port: 5432
+      host_port: 15432
+      host_address: 0.0.0.0
+    - port: 0
+`), 0644))
+
+	worktrees := []*models.Worktree{
+		{ID: "wt-app", Name: "app/main", Path: appDir, CreatedAt: time.Now().Add(-time.Hour)},
+		{ID: "wt-api", Name: "api/main", Path: t.TempDir(), CreatedAt: time.Now()},
+	}
+	list := func() []*models.Worktree { return worktrees }
+	path := filepath.Join(t.TempDir(), "port_publish.json")
+	events := &recordingPublishEvents{}
+	service := NewPortPublishServiceWithPath(path, list).WithEvents(events)
+
+	rules := service.Rules()
+	require.Len(t, rules, 2, "the invalid config entry is skipped")
+	assert.Equal(t, 3000, rules[0].HostPort)
+	assert.Equal(t, "127.0.0.1", rules[0].HostAddress)
+	assert.Equal(t, PortPublishFromConfig, rules[0].Source)
+	assert.Equal(t, PortPublishPending, rules[0].Status)
+
+	// Host ports already taken by another rule or by catnip are rejected
+	_, err := service.Add(PortPublishRule{WorktreeID: "wt-api", Port: 3000})
+	assert.Error(t, err)
+	_, err = service.Add(PortPublishRule{WorktreeID: "wt-api", Port: 8000, HostPort: 15432, HostAddress: "127.0.0.1"})
+	assert.Error(t, err, "0.0.0.0 overlaps every address")
+	_, err = service.Add(PortPublishRule{WorktreeID: "wt-api", Port: 8000, HostPort: 6369})
+	assert.Error(t, err)
+	_, err = service.Add(PortPublishRule{WorktreeID: "missing", Port: 8000})
+	assert.Equal(t, models.ErrCodeWorktreeNotFound, models.ErrorCodeOf(err))
+	assert.Empty(t, events.changes)
+
+	rule, err := service.Add(PortPublishRule{WorktreeID: "wt-api", Port: 3000, HostPort: 3001})
+	require.NoError(t, err)
+	assert.Equal(t, PortPublishFromAPI, rule.Source)
+	assert.Equal(t, "api/main", rule.WorktreeName)
+	require.Len(t, events.changes, 1)
+	assert.Len(t, events.changes[0], 3)
+
+	// The host helper reports what happened when binding
+	require.NoError(t, service.ReportStatus(rule.ID, PortPublishConflict, "host port 127.0.0.1:3001 is already in use"))
+	require.NoError(t, service.ReportStatus(rule.ID, PortPublishConflict, "host port 127.0.0.1:3001 is already in use"))
+	assert.Len(t, events.changes, 2, "repeated reports are not re-announced")
+	assert.Error(t, service.ReportStatus(rule.ID, "bogus", ""))
+	rules = service.Rules()
+	assert.Equal(t, PortPublishConflict, rules[2].Status)
+	assert.NotNil(t, rules[2].UpdatedAt)
+
+	// API rules survive a restart; config rules can only be removed from the config
+	reloaded := NewPortPublishServiceWithPath(path, list)
+	rules = reloaded.Rules()
+	require.Len(t, rules, 3)
+	assert.Equal(t, rule.ID, rules[2].ID)
+	assert.Equal(t, PortPublishPending, rules[2].Status)
+	assert.Error(t, reloaded.Remove(rules[0].ID))
+
+	// Rules go away with their worktree
+	worktrees = worktrees[:1]
+	assert.Len(t, reloaded.Rules(), 2)
+	assert.Error(t, reloaded.Remove(rule.ID))
+	assert.Len(t, NewPortPublishServiceWithPath(path, list).Rules(), 2)
+}
+
+func TestPortPublishConflictsBetweenConfigRules(t *testing.T) {
+	var worktrees []*models.Worktree
+	for i, name := range []string{"first", "second"} {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte("ports:\n  publish:\n    - port: 8080\n"), 0644))
+		worktrees = append(worktrees, &models.Worktree{ID: name, Name: name, Path: dir, CreatedAt: time.Now().Add(time.Duration(i) * time.Minute)})
+	}
+	service := NewPortPublishServiceWithPath(filepath.Join(t.TempDir(), "port_publish.json"), func() []*models.Worktree { return worktrees })
+
+	rules := service.Rules()
+	require.Len(t, rules, 2)
+	assert.Equal(t, "first", rules[0].WorktreeID)
+	assert.Equal(t, PortPublishPending, rules[0].Status)
+	assert.Equal(t, PortPublishConflict, rules[1].Status)
+	assert.Contains(t, rules[1].Error, "already published for port 8080 of first")
+	assert.Nil(t, rules[1].UpdatedAt)
+}
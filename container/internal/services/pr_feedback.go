@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// prFeedbackMaxComments caps the unresolved review comments kept per PR
+	prFeedbackMaxComments = 20
+	// prFeedbackMaxCommentLength caps each comment body
+	prFeedbackMaxCommentLength = 1000
+)
+
+// prFeedbackFields are the GraphQL fields fetched for each pull request on
+// top of its state: the review decision, each reviewer's latest review, the
+// review threads and the head commit's check rollup
+const prFeedbackFields = `reviewDecision ` +
+	`latestReviews(first: 20) { nodes { state author { login } } } ` +
+	`reviewThreads(first: 50) { nodes { isResolved path line comments(first: 1) { nodes { author { login } body url createdAt } } } } ` +
+	`commits(last: 1) { nodes { commit { statusCheckRollup { state contexts(first: 50) { nodes { __typename ... on CheckRun { name status conclusion } ... on StatusContext { context state } } } } } } }`
+
+// githubPRFeedback is the GraphQL shape of prFeedbackFields
+type githubPRFeedback struct {
+	ReviewDecision string `json:"reviewDecision"`
+	LatestReviews  struct {
+		Nodes []struct {
+			State  string      `json:"state"`
+			Author githubActor `json:"author"`
+		} `json:"nodes"`
+	} `json:"latestReviews"`
+	ReviewThreads struct {
+		Nodes []struct {
+			IsResolved bool   `json:"isResolved"`
+			Path       string `json:"path"`
+			Line       int    `json:"line"`
+			Comments   struct {
+				Nodes []struct {
+					Author    githubActor `json:"author"`
+					Body      string      `json:"body"`
+					URL       string      `json:"url"`
+					CreatedAt time.Time   `json:"createdAt"`
+				} `json:"nodes"`
+			} `json:"comments"`
+		} `json:"nodes"`
+	} `json:"reviewThreads"`
+	Commits struct {
+		Nodes []struct {
+			Commit struct {
+				StatusCheckRollup *struct {
+					State    string `json:"state"`
+					Contexts struct {
+						Nodes []githubCheckContext `json:"nodes"`
+					} `json:"contexts"`
+				} `json:"statusCheckRollup"`
+			} `json:"commit"`
+		} `json:"nodes"`
+	} `json:"commits"`
+}
+
+// githubActor is a GraphQL author, null for deleted accounts
+type githubActor *struct {
+	Login string `json:"login"`
+}
+
+func actorLogin(actor githubActor) string {
+	if actor == nil || actor.Login == "" {
+		return "ghost"
+	}
+	return actor.Login
+}
+
+// githubCheckContext is a check run (GitHub Actions, apps) or a commit status
+type githubCheckContext struct {
+	Typename string `json:"__typename"`
+	// CheckRun
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	// StatusContext
+	Context string `json:"context"`
+	State   string `json:"state"`
+}
+
+// result classifies the context as passed, failed or pending
+func (c githubCheckContext) result() string {
+	if c.Typename == "StatusContext" {
+		switch c.State {
+		case "SUCCESS":
+			return "passed"
+		case "FAILURE", "ERROR":
+			return "failed"
+		}
+		return "pending"
+	}
+	if c.Status != "COMPLETED" {
+		return "pending"
+	}
+	switch c.Conclusion {
+	case "SUCCESS", "NEUTRAL", "SKIPPED":
+		return "passed"
+	}
+	return "failed"
+}
+
+func (c githubCheckContext) name() string {
+	if c.Typename == "StatusContext" {
+		return c.Context
+	}
+	return c.Name
+}
+
+// feedback converts the GraphQL response into the stored feedback
+func (raw githubPRFeedback) feedback() *models.PullRequestFeedback {
+	feedback := &models.PullRequestFeedback{ReviewDecision: raw.ReviewDecision}
+
+	for _, review := range raw.LatestReviews.Nodes {
+		switch review.State {
+		case "CHANGES_REQUESTED":
+			feedback.ChangesRequestedBy = append(feedback.ChangesRequestedBy, actorLogin(review.Author))
+		case "APPROVED":
+			feedback.ApprovedBy = append(feedback.ApprovedBy, actorLogin(review.Author))
+		}
+	}
+
+	for _, thread := range raw.ReviewThreads.Nodes {
+		if thread.IsResolved {
+			continue
+		}
+		feedback.UnresolvedThreads++
+		if len(thread.Comments.Nodes) == 0 || len(feedback.Comments) >= prFeedbackMaxComments {
+			continue
+		}
+		comment := thread.Comments.Nodes[0]
+		feedback.Comments = append(feedback.Comments, models.PullRequestComment{
+			Author:    actorLogin(comment.Author),
+			Body:      truncate(comment.Body, prFeedbackMaxCommentLength),
+			Path:      thread.Path,
+			Line:      thread.Line,
+			URL:       comment.URL,
+			CreatedAt: comment.CreatedAt,
+		})
+	}
+
+	if len(raw.Commits.Nodes) > 0 {
+		if rollup := raw.Commits.Nodes[0].Commit.StatusCheckRollup; rollup != nil {
+			checks := &feedback.Checks
+			checks.State = checkRollupState(rollup.State)
+			for _, context := range rollup.Contexts.Nodes {
+				checks.Total++
+				switch context.result() {
+				case "passed":
+					checks.Passed++
+				case "failed":
+					checks.Failed++
+					checks.Failing = append(checks.Failing, context.name())
+				default:
+					checks.Pending++
+				}
+			}
+		}
+	}
+
+	feedback.Summary = summarizePRFeedback(feedback)
+	return feedback
+}
+
+// checkRollupState maps GitHub's rollup state to success, failure or pending
+func checkRollupState(state string) string {
+	switch state {
+	case "SUCCESS":
+		return "success"
+	case "FAILURE", "ERROR":
+		return "failure"
+	case "PENDING", "EXPECTED":
+		return "pending"
+	}
+	return ""
+}
+
+// summarizePRFeedback describes feedback in one line, e.g. "2 requested
+// changes, 3 unresolved comments, CI failing"
+func summarizePRFeedback(feedback *models.PullRequestFeedback) string {
+	var parts []string
+	switch {
+	case len(feedback.ChangesRequestedBy) > 0:
+		parts = append(parts, pluralize(len(feedback.ChangesRequestedBy), "requested change", "requested changes"))
+	case feedback.ReviewDecision == "APPROVED":
+		parts = append(parts, "approved")
+	case feedback.ReviewDecision == "REVIEW_REQUIRED":
+		parts = append(parts, "review required")
+	}
+	if feedback.UnresolvedThreads > 0 {
+		parts = append(parts, pluralize(feedback.UnresolvedThreads, "unresolved comment", "unresolved comments"))
+	}
+	switch feedback.Checks.State {
+	case "failure":
+		parts = append(parts, "CI failing")
+	case "pending":
+		parts = append(parts, "CI running")
+	case "success":
+		parts = append(parts, "CI passing")
+	}
+	if len(parts) == 0 {
+		return "no reviews yet"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", singular)
+	}
+	return fmt.Sprintf("%d %s", n, plural)
+}
+
+// githubPRPattern extracts owner/repo and the number from a GitHub PR URL
+var githubPRPattern = regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
+
+// parseGitHubPRURL returns the owner/repo and number of a GitHub PR URL
+func parseGitHubPRURL(prURL string) (string, int, bool) {
+	matches := githubPRPattern.FindStringSubmatch(prURL)
+	if len(matches) != 3 {
+		return "", 0, false
+	}
+	number, err := strconv.Atoi(matches[2])
+	if err != nil {
+		return "", 0, false
+	}
+	return matches[1], number, true
+}
+
+// GetPullRequestFeedback returns the reviews, unresolved comments and CI
+// status of a worktree's pull request, fetching them first when refresh is
+// set or they haven't been synced yet
+func (s *GitService) GetPullRequestFeedback(worktreeID string, refresh bool) (*models.PullRequestFeedback, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if worktree.PullRequestURL == "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has no pull request", worktree.Name).
+			WithHint("Create one with POST /v1/git/worktrees/{id}/pr")
+	}
+	repoID, number, ok := parseGitHubPRURL(worktree.PullRequestURL)
+	if !ok {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "review feedback is only synced for GitHub pull requests, not %s", worktree.PullRequestURL)
+	}
+
+	prSyncManager := GetPRSyncManager(nil)
+	if !refresh {
+		if state := prSyncManager.GetPRState(repoID, number); state != nil && state.Feedback != nil {
+			return state.Feedback, nil
+		}
+	}
+	state, err := prSyncManager.SyncPullRequest(repoID, number)
+	if err != nil {
+		return nil, err
+	}
+	if state.Feedback == nil {
+		return &models.PullRequestFeedback{Summary: summarizePRFeedback(&models.PullRequestFeedback{})}, nil
+	}
+	return state.Feedback, nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const testPRFeedbackResponse = `{"data": {"repository": {
+	"pr12": {
+		"number": 12, "title": "Add login", "state": "OPEN", "url": "https://github.com/acme/app/pull/12",
+		"reviewDecision": "CHANGES_REQUESTED",
+		"latestReviews": {"nodes": [
+			{"state": "CHANGES_REQUESTED", "author": {"login": "octocat"}},
+			{"state": "APPROVED", "author": {"login": "monalisa"}},
+			{"state": "CHANGES_REQUESTED", "author": null},
+			{"state": "COMMENTED", "author": {"login": "hubot"}}
+		]},
+		"reviewThreads": {"nodes": [
+			{"isResolved": false, "path": "api.go", "line": 42, "comments": {"nodes": [
+				{"author": {"login": "octocat"}, "body": "Handle the empty case", "url": "https://github.com/acme/app/pull/12#discussion_r1", "createdAt": "2024-01-15T16:45:30Z"}
+			]}},
+			{"isResolved": true, "path": "api.go", "line": 7, "comments": {"nodes": [
+				{"author": {"login": "octocat"}, "body": "Fixed", "url": "https://github.com/acme/app/pull/12#discussion_r2", "createdAt": "2024-01-15T16:40:00Z"}
+			]}}
+		]},
+		"commits": {"nodes": [{"commit": {"statusCheckRollup": {"state": "FAILURE", "contexts": {"nodes": [
+			{"__typename": "CheckRun", "name": "test", "status": "COMPLETED", "conclusion": "FAILURE"},
+			{"__typename": "CheckRun", "name": "lint", "status": "COMPLETED", "conclusion": "SUCCESS"},
+			{"__typename": "CheckRun", "name": "e2e", "status": "IN_PROGRESS", "conclusion": null},
+			{"__typename": "StatusContext", "context": "ci/deploy", "state": "ERROR"}
+		]}}}}]}
+	},
+	"pr13": {
+		"number": 13, "title": "Docs", "state": "MERGED", "url": "https://github.com/acme/app/pull/13",
+		"reviewDecision": "APPROVED",
+		"latestReviews": {"nodes": []},
+		"reviewThreads": {"nodes": []},
+		"commits": {"nodes": [{"commit": {"statusCheckRollup": null}}]}
+	},
+	"pr14": null
+}}}`
+
+func TestParseBatchPRResponseFeedback(t *testing.T) {
+	pm := &PRSyncManager{prStateCache: make(map[string]*models.PullRequestState)}
+	states, err := pm.parseBatchPRResponse([]byte(testPRFeedbackResponse), "acme/app", []int{12, 13, 14})
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+
+	feedback := states["acme/app#12"].Feedback
+	require.NotNil(t, feedback)
+	assert.Equal(t, "CHANGES_REQUESTED", feedback.ReviewDecision)
+	assert.Equal(t, []string{"octocat", "ghost"}, feedback.ChangesRequestedBy)
+	assert.Equal(t, []string{"monalisa"}, feedback.ApprovedBy)
+	assert.Equal(t, 1, feedback.UnresolvedThreads)
+	require.Len(t, feedback.Comments, 1)
+	assert.Equal(t, models.PullRequestComment{
+		Author:    "octocat",
+		Body:      "Handle the empty case",
+		Path:      "api.go",
+		Line:      42,
+		URL:       "https://github.com/acme/app/pull/12#discussion_r1",
+		CreatedAt: feedback.Comments[0].CreatedAt,
+	}, feedback.Comments[0])
+	assert.Equal(t, models.PullRequestChecks{
+		State:   "failure",
+		Total:   4,
+		Passed:  1,
+		Failed:  2,
+		Pending: 1,
+		Failing: []string{"test", "ci/deploy"},
+	}, feedback.Checks)
+	assert.Equal(t, "2 requested changes, 1 unresolved comment, CI failing", feedback.Summary)
+
+	merged := states["acme/app#13"].Feedback
+	require.NotNil(t, merged)
+	assert.Empty(t, merged.Checks.State)
+	assert.Equal(t, "approved", merged.Summary)
+}
+
+func TestSummarizePRFeedback(t *testing.T) {
+	assert.Equal(t, "no reviews yet", summarizePRFeedback(&models.PullRequestFeedback{}))
+	assert.Equal(t, "review required, 3 unresolved comments, CI running", summarizePRFeedback(&models.PullRequestFeedback{
+		ReviewDecision:    "REVIEW_REQUIRED",
+		UnresolvedThreads: 3,
+		Checks:            models.PullRequestChecks{State: "pending"},
+	}))
+	assert.Equal(t, "1 requested change, CI passing", summarizePRFeedback(&models.PullRequestFeedback{
+		ReviewDecision:     "CHANGES_REQUESTED",
+		ChangesRequestedBy: []string{"octocat"},
+		Checks:             models.PullRequestChecks{State: "success"},
+	}))
+}
+
+func TestParseGitHubPRURL(t *testing.T) {
+	repoID, number, ok := parseGitHubPRURL("https://github.com/acme/app/pull/12")
+	assert.True(t, ok)
+	assert.Equal(t, "acme/app", repoID)
+	assert.Equal(t, 12, number)
+
+	_, _, ok = parseGitHubPRURL("https://gitlab.com/acme/app/-/merge_requests/12")
+	assert.False(t, ok)
+}
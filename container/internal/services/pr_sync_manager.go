@@ -4,8 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
-	"regexp"
-	"strconv"
+	"reflect"
 	"strings"
 	"sync"
 	"time"
@@ -141,7 +140,6 @@ func (pm *PRSyncManager) collectPRRequests() map[string][]int {
 	}
 
 	prRequests := make(map[string][]int)
-	prPattern := regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
 
 	// Get all worktrees from state manager
 	allWorktrees := pm.stateManager.GetAllWorktrees()
@@ -150,15 +148,8 @@ func (pm *PRSyncManager) collectPRRequests() map[string][]int {
 			continue
 		}
 
-		matches := prPattern.FindStringSubmatch(worktree.PullRequestURL)
-		if len(matches) != 3 {
-			continue
-		}
-
-		repoID := matches[1]
-		prNumber, err := strconv.Atoi(matches[2])
-		if err != nil {
-			logger.Warnf("Invalid PR number in URL %s: %v", worktree.PullRequestURL, err)
+		repoID, prNumber, ok := parseGitHubPRURL(worktree.PullRequestURL)
+		if !ok {
 			continue
 		}
 
@@ -217,7 +208,7 @@ func (pm *PRSyncManager) buildBatchPRQuery(repoID string, prNumbers []int) strin
 
 	var aliases []string
 	for _, num := range prNumbers {
-		aliases = append(aliases, fmt.Sprintf("pr%d: pullRequest(number: %d) { number title state url }", num, num))
+		aliases = append(aliases, fmt.Sprintf("pr%d: pullRequest(number: %d) { number title state url %s }", num, num, prFeedbackFields))
 	}
 
 	return fmt.Sprintf(`query { repository(owner: "%s", name: "%s") { %s } }`,
@@ -228,11 +219,12 @@ func (pm *PRSyncManager) buildBatchPRQuery(repoID string, prNumbers []int) strin
 func (pm *PRSyncManager) parseBatchPRResponse(output []byte, repoID string, prNumbers []int) (map[string]*models.PullRequestState, error) {
 	var response struct {
 		Data struct {
-			Repository map[string]struct {
+			Repository map[string]*struct {
 				Number int    `json:"number"`
 				Title  string `json:"title"`
 				State  string `json:"state"`
 				URL    string `json:"url"`
+				githubPRFeedback
 			} `json:"repository"`
 		} `json:"data"`
 	}
@@ -245,7 +237,7 @@ func (pm *PRSyncManager) parseBatchPRResponse(output []byte, repoID string, prNu
 	now := time.Now()
 
 	for _, pr := range response.Data.Repository {
-		if pr.Number == 0 {
+		if pr == nil || pr.Number == 0 {
 			continue // PR not found or error
 		}
 
@@ -258,6 +250,7 @@ func (pm *PRSyncManager) parseBatchPRResponse(output []byte, repoID string, prNu
 			Title:       pr.Title,
 			LastSynced:  now,
 			WorktreeIDs: pm.getWorktreeIDsForPR(repoID, pr.Number),
+			Feedback:    pr.feedback(),
 		}
 	}
 
@@ -294,7 +287,7 @@ func (pm *PRSyncManager) updateCache(states map[string]*models.PullRequestState)
 	// Update in-memory cache and detect changes
 	for key, newState := range states {
 		oldState := pm.prStateCache[key]
-		if oldState == nil || oldState.State != newState.State {
+		if oldState == nil || oldState.State != newState.State || !reflect.DeepEqual(oldState.Feedback, newState.Feedback) {
 			changedStates[key] = newState
 			logger.Debugf("PR state changed for %s: %s -> %s", key,
 				func() string {
@@ -335,8 +328,6 @@ func (pm *PRSyncManager) triggerWorktreeUpdatesForPRChanges(changedStates map[st
 		return
 	}
 
-	prPattern := regexp.MustCompile(`github\.com/([^/]+/[^/]+)/pull/(\d+)`)
-
 	// Get all worktrees to find which ones are affected by the changed PRs
 	allWorktrees := pm.stateManager.GetAllWorktrees()
 	updateCount := 0
@@ -346,14 +337,8 @@ func (pm *PRSyncManager) triggerWorktreeUpdatesForPRChanges(changedStates map[st
 			continue
 		}
 
-		matches := prPattern.FindStringSubmatch(worktree.PullRequestURL)
-		if len(matches) != 3 {
-			continue
-		}
-
-		repoID := matches[1]
-		prNumber, err := strconv.Atoi(matches[2])
-		if err != nil {
+		repoID, prNumber, ok := parseGitHubPRURL(worktree.PullRequestURL)
+		if !ok {
 			continue
 		}
 
@@ -361,7 +346,7 @@ func (pm *PRSyncManager) triggerWorktreeUpdatesForPRChanges(changedStates map[st
 		prKey := fmt.Sprintf("%s#%d", repoID, prNumber)
 		if changedState, exists := changedStates[prKey]; exists {
 			logger.Debugf("Sending PR state update for worktree %s: %s", worktree.ID, changedState.State)
-			pm.stateManager.SendPRStateUpdate(worktree.ID, changedState.State)
+			pm.stateManager.SendPRStateUpdate(worktree.ID, changedState.State, changedState.Feedback)
 			updateCount++
 		}
 	}
@@ -371,6 +356,22 @@ func (pm *PRSyncManager) triggerWorktreeUpdatesForPRChanges(changedStates map[st
 	}
 }
 
+// SyncPullRequest fetches a single PR's state and feedback now instead of
+// waiting for the next sync cycle
+func (pm *PRSyncManager) SyncPullRequest(repoID string, prNumber int) (*models.PullRequestState, error) {
+	states, err := pm.syncRepositoryPRs(repoID, []int{prNumber})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync PR %s#%d: %w", repoID, prNumber, err)
+	}
+	key := fmt.Sprintf("%s#%d", repoID, prNumber)
+	state, ok := states[key]
+	if !ok {
+		return nil, fmt.Errorf("PR %s#%d not found", repoID, prNumber)
+	}
+	pm.updateCache(states)
+	return state, nil
+}
+
 // GetPRState returns the cached state for a specific PR
 func (pm *PRSyncManager) GetPRState(repoID string, prNumber int) *models.PullRequestState {
 	pm.mutex.RLock()
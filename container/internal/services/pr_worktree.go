@@ -0,0 +1,291 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// defaultPullRequestSyncInterval is how often worktrees created from pull
+// requests are checked for new pushes
+const defaultPullRequestSyncInterval = 2 * time.Minute
+
+// Pull request worktree sync results
+const (
+	PullRequestSyncUpdated  = "updated"
+	PullRequestSyncUpToDate = "up_to_date"
+	PullRequestSyncDirty    = "dirty"
+	PullRequestSyncDiverged = "diverged"
+)
+
+// pullRequestRef is where a pull request's head is fetched to. GitHub
+// publishes refs/pull/N/head in the base repository for every PR, so heads
+// in forks are fetched without adding the fork as a remote.
+func pullRequestRef(number int) string {
+	return fmt.Sprintf("refs/remotes/origin/pr/%d", number)
+}
+
+// CreateWorktreeFromPullRequest checks out a pull request into a new
+// worktree. The worktree starts at the PR's head on its own catnip ref, diffs
+// against the PR's base branch, and has the PR linked so its state is
+// tracked. If a worktree already tracks the PR it is returned instead.
+func (s *GitService) CreateWorktreeFromPullRequest(repoID string, number int) (*models.Worktree, error) {
+	if number <= 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid pull request number: %d", number)
+	}
+
+	s.mu.RLock()
+	repo, exists := s.stateManager.GetRepository(repoID)
+	s.mu.RUnlock()
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(repoID)
+	}
+	if existing := s.pullRequestWorktree(repoID, number); existing != nil {
+		logger.Infof("🔁 Pull request #%d is already checked out in worktree %s", number, existing.Name)
+		return existing, nil
+	}
+
+	ownerRepo, err := s.githubManager.ResolveOwnerRepo(&models.Worktree{Path: repo.Path}, repo)
+	if err != nil {
+		return nil, fmt.Errorf("cannot check out pull request: %v", err)
+	}
+	pr, err := s.githubManager.GetPullRequest(ownerRepo, number)
+	if err != nil {
+		return nil, err
+	}
+	return s.createPullRequestWorktree(repo, pr)
+}
+
+// pullRequestWorktree returns the worktree tracking a pull request, if any
+func (s *GitService) pullRequestWorktree(repoID string, number int) *models.Worktree {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.RepoID == repoID && worktree.SourcePullRequest != nil && worktree.SourcePullRequest.Number == number {
+			return worktree
+		}
+	}
+	return nil
+}
+
+// createPullRequestWorktree fetches a pull request's head and creates the worktree for it
+func (s *GitService) createPullRequestWorktree(repo *models.Repository, pr *models.SourcePullRequest) (*models.Worktree, error) {
+	release := s.acquireRepoSlot(repo.ID, repoOpCheckout)
+	defer release()
+
+	commit, err := s.fetchPullRequestHead(repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+	if pr.BaseBranch == "" {
+		pr.BaseBranch = repo.DefaultBranch
+	}
+	if !s.isLocalRepo(repo.ID) {
+		// Diffs are against the base branch, so make sure it's current
+		if err := s.fetchBranch(repo.Path, git.FetchStrategy{Branch: pr.BaseBranch, UpdateLocalRef: true}); err != nil {
+			logger.Warnf("⚠️ Could not fetch base branch %s of pull request #%d: %v", pr.BaseBranch, pr.Number, err)
+		}
+	}
+	logger.Infof("🔀 Creating worktree for %s#%d (%s:%s at %s)", repo.ID, pr.Number, pr.HeadRepository, pr.HeadBranch, commit[:7])
+
+	funName := s.generateUniqueSessionName(repo.Path)
+	var worktree *models.Worktree
+	if s.isLocalRepo(repo.ID) {
+		worktree, err = s.createLocalRepoWorktree(repo, commit, funName)
+	} else {
+		worktree, err = s.createWorktreeInternalForRepo(repo, commit, funName, true)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create worktree: %v", err)
+	}
+
+	pr.HeadSHA = commit
+	pr.SyncedAt = time.Now()
+	pr.SyncStatus = PullRequestSyncUpToDate
+	updates := map[string]interface{}{
+		"source_branch":       pr.BaseBranch,
+		"source_ref":          fmt.Sprintf("pull/%d", pr.Number),
+		"source_ref_type":     models.SourceRefPullRequest,
+		"source_pull_request": pr,
+		"pull_request_url":    pr.URL,
+		"pull_request_title":  pr.Title,
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, updates); err != nil {
+		logger.Warnf("⚠️ Failed to link pull request #%d to worktree %s: %v", pr.Number, worktree.Name, err)
+	}
+	if updated, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		worktree = updated
+	}
+	return worktree, nil
+}
+
+// fetchPullRequestHead fetches a pull request's head commit into the
+// repository and returns its SHA
+func (s *GitService) fetchPullRequestHead(repo *models.Repository, number int) (string, error) {
+	ref := pullRequestRef(number)
+	refspec := fmt.Sprintf("+refs/pull/%d/head:%s", number, ref)
+	if output, err := s.runGitCommand(repo.Path, "fetch", "origin", refspec); err != nil {
+		return "", fmt.Errorf("failed to fetch pull request #%d: %v\nOutput: %s", number, err, string(output))
+	}
+	return s.resolveCommit(repo.Path, ref)
+}
+
+// SyncPullRequestWorktree brings a worktree created from a pull request up to
+// date with new pushes to it. The worktree moves to the new head only when
+// that loses nothing: it must have no uncommitted changes, and any local
+// commits must already be part of the new head. A force-pushed head replaces
+// the old one only when there are no local commits.
+func (s *GitService) SyncPullRequestWorktree(worktreeID string) (*models.SourcePullRequest, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	var repo *models.Repository
+	if exists {
+		repo, _ = s.stateManager.GetRepository(worktree.RepoID)
+	}
+	s.mu.RUnlock()
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if worktree.SourcePullRequest == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s was not created from a pull request", worktree.Name)
+	}
+	if repo == nil {
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpSync)
+	defer release()
+
+	pr := *worktree.SourcePullRequest
+	newHead, err := s.fetchPullRequestHead(repo, pr.Number)
+	if err != nil {
+		return nil, err
+	}
+	pr.SyncedAt = time.Now()
+
+	head, err := s.resolveCommit(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of %s: %v", worktree.Name, err)
+	}
+	switch {
+	case newHead == pr.HeadSHA || newHead == head:
+		pr.HeadSHA = newHead
+		pr.SyncStatus = PullRequestSyncUpToDate
+	case head != pr.HeadSHA && !s.isAncestor(worktree.Path, head, newHead):
+		pr.SyncStatus = PullRequestSyncDiverged
+	default:
+		if dirty, err := s.operations.HasUncommittedChanges(worktree.Path); err != nil || dirty {
+			pr.SyncStatus = PullRequestSyncDirty
+			break
+		}
+		// Fast-forward, or take a force-pushed head when HEAD has nothing of its own
+		args := []string{"merge", "--ff-only", newHead}
+		if !s.isAncestor(worktree.Path, head, newHead) {
+			args = []string{"reset", "--hard", newHead}
+		}
+		if output, err := s.runGitCommand(worktree.Path, args...); err != nil {
+			return nil, fmt.Errorf("failed to update %s to %s: %v\nOutput: %s", worktree.Name, newHead[:7], err, string(output))
+		}
+		logger.Infof("🔀 Updated worktree %s to the new head of pull request #%d (%s)", worktree.Name, pr.Number, newHead[:7])
+		pr.HeadSHA = newHead
+		pr.SyncStatus = PullRequestSyncUpdated
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"source_pull_request": &pr}); err != nil {
+		return nil, err
+	}
+	if pr.SyncStatus == PullRequestSyncUpdated && s.worktreeCache != nil {
+		s.worktreeCache.ForceRefresh(worktreeID)
+	}
+	return &pr, nil
+}
+
+// isAncestor reports whether commit is reachable from descendant
+func (s *GitService) isAncestor(dir, commit, descendant string) bool {
+	_, err := s.runGitCommand(dir, "merge-base", "--is-ancestor", commit, descendant)
+	return err == nil
+}
+
+// PullRequestWorktreeSync periodically syncs worktrees created from open pull
+// requests with new pushes to them
+type PullRequestWorktreeSync struct {
+	gitService *GitService
+	interval   time.Duration
+	mu         sync.Mutex
+	stopChan   chan struct{}
+	running    bool
+}
+
+// NewPullRequestWorktreeSync creates the sync loop, checking every
+// CATNIP_PR_WORKTREE_SYNC_INTERVAL (default 2m)
+func NewPullRequestWorktreeSync(gitService *GitService) *PullRequestWorktreeSync {
+	interval := defaultPullRequestSyncInterval
+	if raw := os.Getenv("CATNIP_PR_WORKTREE_SYNC_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= 30*time.Second {
+			interval = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_PR_WORKTREE_SYNC_INTERVAL %q (minimum 30s)", raw)
+		}
+	}
+	return &PullRequestWorktreeSync{
+		gitService: gitService,
+		interval:   interval,
+		stopChan:   make(chan struct{}),
+	}
+}
+
+// Start syncs pull request worktrees periodically in the background
+func (p *PullRequestWorktreeSync) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running {
+		return
+	}
+	p.running = true
+
+	go func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stopChan:
+				return
+			case <-ticker.C:
+				p.SyncAll()
+			}
+		}
+	}()
+}
+
+// Stop stops periodic syncing
+func (p *PullRequestWorktreeSync) Stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.running {
+		return
+	}
+	p.running = false
+	close(p.stopChan)
+}
+
+// SyncAll syncs every worktree whose pull request is still open
+func (p *PullRequestWorktreeSync) SyncAll() {
+	for _, worktree := range p.gitService.ListWorktrees() {
+		if worktree.SourcePullRequest == nil {
+			continue
+		}
+		// PRSyncManager keeps the state current; closed and merged PRs get no new pushes
+		if state := strings.ToUpper(worktree.PullRequestState); state == "CLOSED" || state == "MERGED" {
+			continue
+		}
+		if _, err := p.gitService.SyncPullRequestWorktree(worktree.ID); err != nil {
+			logger.Warnf("⚠️ Failed to sync worktree %s with pull request #%d: %v", worktree.Name, worktree.SourcePullRequest.Number, err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestPullRequestWorktree(t *testing.T) {
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	// Upstream publishes the PR head as refs/pull/7/head, like GitHub
+	upstream := t.TempDir()
+	runTestGit(t, upstream, "init", "-b", "main")
+	runTestGit(t, upstream, "commit", "--allow-empty", "-m", "base")
+	runTestGit(t, upstream, "checkout", "-b", "contributor")
+	require.NoError(t, os.WriteFile(filepath.Join(upstream, "feature.txt"), []byte("v1\n"), 0644))
+	runTestGit(t, upstream, "add", ".")
+	runTestGit(t, upstream, "commit", "-m", "feature v1")
+	runTestGit(t, upstream, "update-ref", "refs/pull/7/head", "HEAD")
+	prHead := runTestGit(t, upstream, "rev-parse", "HEAD")
+
+	clone := filepath.Join(t.TempDir(), "app")
+	runTestGit(t, filepath.Dir(clone), "clone", "-q", upstream, clone)
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: clone, DefaultBranch: "main"}))
+	repo, _ := service.stateManager.GetRepository("acme/app")
+
+	worktree, err := service.createPullRequestWorktree(repo, &models.SourcePullRequest{
+		Number:         7,
+		Repository:     "acme/app",
+		URL:            "https://github.com/acme/app/pull/7",
+		Title:          "Add feature",
+		BaseBranch:     "main",
+		HeadBranch:     "contributor",
+		HeadRepository: "someone/app",
+		IsFork:         true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "main", worktree.SourceBranch)
+	assert.Equal(t, models.SourceRefPullRequest, worktree.SourceRefType)
+	assert.Equal(t, "pull/7", worktree.SourceRef)
+	assert.Equal(t, "https://github.com/acme/app/pull/7", worktree.PullRequestURL)
+	assert.True(t, strings.HasPrefix(worktree.Branch, "refs/catnip/"))
+	require.NotNil(t, worktree.SourcePullRequest)
+	assert.Equal(t, prHead, worktree.SourcePullRequest.HeadSHA)
+	assert.Equal(t, prHead, runTestGit(t, worktree.Path, "rev-parse", "HEAD"))
+	assert.Equal(t, worktree.ID, service.pullRequestWorktree("acme/app", 7).ID)
+
+	pushToPR := func(content string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(upstream, "feature.txt"), []byte(content), 0644))
+		runTestGit(t, upstream, "commit", "-am", content)
+		runTestGit(t, upstream, "update-ref", "refs/pull/7/head", "HEAD")
+		return runTestGit(t, upstream, "rev-parse", "HEAD")
+	}
+
+	t.Run("FollowsPushes", func(t *testing.T) {
+		newHead := pushToPR("v2\n")
+		pr, err := service.SyncPullRequestWorktree(worktree.ID)
+		require.NoError(t, err)
+		assert.Equal(t, PullRequestSyncUpdated, pr.SyncStatus)
+		assert.Equal(t, newHead, pr.HeadSHA)
+		assert.Equal(t, newHead, runTestGit(t, worktree.Path, "rev-parse", "HEAD"))
+
+		pr, err = service.SyncPullRequestWorktree(worktree.ID)
+		require.NoError(t, err)
+		assert.Equal(t, PullRequestSyncUpToDate, pr.SyncStatus)
+	})
+
+	t.Run("KeepsUncommittedChanges", func(t *testing.T) {
+		before := runTestGit(t, worktree.Path, "rev-parse", "HEAD")
+		require.NoError(t, os.WriteFile(filepath.Join(worktree.Path, "notes.txt"), []byte("wip\n"), 0644))
+		pushToPR("v3\n")
+
+		pr, err := service.SyncPullRequestWorktree(worktree.ID)
+		require.NoError(t, err)
+		assert.Equal(t, PullRequestSyncDirty, pr.SyncStatus)
+		assert.Equal(t, before, runTestGit(t, worktree.Path, "rev-parse", "HEAD"))
+		require.NoError(t, os.Remove(filepath.Join(worktree.Path, "notes.txt")))
+	})
+
+	t.Run("KeepsLocalCommits", func(t *testing.T) {
+		runTestGit(t, worktree.Path, "commit", "--allow-empty", "-m", "local review fix")
+		local := runTestGit(t, worktree.Path, "rev-parse", "HEAD")
+		pushToPR("v4\n")
+
+		pr, err := service.SyncPullRequestWorktree(worktree.ID)
+		require.NoError(t, err)
+		assert.Equal(t, PullRequestSyncDiverged, pr.SyncStatus)
+		assert.Equal(t, local, runTestGit(t, worktree.Path, "rev-parse", "HEAD"))
+	})
+
+	_, err = service.SyncPullRequestWorktree("missing")
+	assert.Error(t, err)
+}
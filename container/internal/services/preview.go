@@ -0,0 +1,465 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// previewCheckInterval is how often worktrees are checked for setup and
+	// port readiness
+	previewCheckInterval = 5 * time.Second
+	// defaultPreviewTimeout bounds each deploy or teardown hook
+	defaultPreviewTimeout = 10 * time.Minute
+	// previewErrorLines is how much of a failed hook's output is kept
+	previewErrorLines = 20
+)
+
+// PreviewConfig configures preview deployments (.catnip.yaml "preview"). Set
+// Deploy (and optionally Teardown) to run scripts, or Webhook to call an API.
+type PreviewConfig struct {
+	// Command run with bash from the worktree root once setup succeeded and a
+	// port is ready; the last http(s) URL it prints is recorded as the preview
+	Deploy string `json:"deploy,omitempty" yaml:"deploy"`
+	// Command run when the worktree is deleted; gets the URL as CATNIP_PREVIEW_URL
+	Teardown string `json:"teardown,omitempty" yaml:"teardown"`
+	// Endpoint receiving deploy and teardown requests as JSON; deploys answer with {"url": "..."}
+	Webhook string `json:"webhook,omitempty" yaml:"webhook"`
+	// Environment variable holding a bearer token for the webhook
+	TokenEnv string `json:"token_env,omitempty" yaml:"token_env"`
+	// Port that must be ready (default: any healthy port served from the worktree)
+	Port int `json:"port,omitempty" yaml:"port"`
+	// Per-hook timeout in minutes (default 10)
+	TimeoutMinutes int `json:"timeout_minutes,omitempty" yaml:"timeout_minutes"`
+}
+
+// Enabled reports whether a deploy hook is configured
+func (c PreviewConfig) Enabled() bool {
+	return c.Deploy != "" || c.Webhook != ""
+}
+
+func (c PreviewConfig) timeout() time.Duration {
+	if c.TimeoutMinutes > 0 {
+		return time.Duration(c.TimeoutMinutes) * time.Minute
+	}
+	return defaultPreviewTimeout
+}
+
+// PreviewHookRequest is the JSON body sent to a preview webhook
+type PreviewHookRequest struct {
+	// "deploy" or "teardown"
+	Action     string `json:"action" example:"deploy"`
+	Repository string `json:"repository" example:"vanpelt/catnip"`
+	Worktree   string `json:"worktree" example:"catnip/felix"`
+	Branch     string `json:"branch" example:"feature/api-docs"`
+	Commit     string `json:"commit" example:"abc123def456"`
+	Port       int    `json:"port,omitempty" example:"3000"`
+	// Preview being torn down
+	URL string `json:"url,omitempty" example:"https://feature-api-docs.fly.dev"`
+}
+
+// PreviewService publishes a preview environment for each worktree once its
+// setup succeeded and a dev server is up, using the deploy hook in the
+// worktree's .catnip.yaml, and tears it down when the worktree is deleted
+type PreviewService struct {
+	gitService *GitService
+	setupState func(workDir string) string
+	services   func() map[int]*ServiceInfo
+	client     *http.Client
+
+	mu      sync.Mutex
+	running map[string]bool // Worktree IDs with a hook in flight
+	stopCh  chan struct{}
+}
+
+// NewPreviewService creates a preview service watching setup results and
+// detected ports
+func NewPreviewService(gitService *GitService, setupState func(workDir string) string, services func() map[int]*ServiceInfo) *PreviewService {
+	return &PreviewService{
+		gitService: gitService,
+		setupState: setupState,
+		services:   services,
+		client:     &http.Client{},
+		running:    make(map[string]bool),
+		stopCh:     make(chan struct{}),
+	}
+}
+
+// LoadPreviewConfig reads the preview section of .catnip.yaml in dir
+func LoadPreviewConfig(dir string) (PreviewConfig, error) {
+	cfg, err := LoadCatnipConfig(dir)
+	if err != nil {
+		return PreviewConfig{}, err
+	}
+	return cfg.Preview, nil
+}
+
+// Start begins watching worktrees for previews to deploy
+func (p *PreviewService) Start() {
+	go func() {
+		ticker := time.NewTicker(previewCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.deployReady()
+			case <-p.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops watching worktrees
+func (p *PreviewService) Stop() {
+	close(p.stopCh)
+}
+
+// deployReady starts a deploy for every worktree without a preview whose
+// setup succeeded (or that has none) and that serves a ready port
+func (p *PreviewService) deployReady() {
+	for _, worktree := range p.gitService.ListWorktrees() {
+		if worktree.Preview != nil || p.isRunning(worktree.ID) {
+			continue
+		}
+		if state := p.setupState(worktree.Path); state != SetupStateSucceeded && state != SetupStateNone {
+			continue
+		}
+		cfg, err := LoadPreviewConfig(worktree.Path)
+		if err != nil || !cfg.Enabled() {
+			continue
+		}
+		port := p.readyPort(worktree.Path, cfg.Port)
+		if port == 0 {
+			continue
+		}
+
+		started, release, err := p.startDeploy(worktree, port)
+		if err != nil {
+			continue
+		}
+		logger.Infof("🚀 Setup done and port %d ready, deploying preview for %s", port, worktree.Name)
+		go func(worktree *models.Worktree, cfg PreviewConfig) {
+			if err := p.finishDeploy(worktree, cfg, started, release); err != nil {
+				logger.Warnf("⚠️ Preview deploy failed for %s: %v", worktree.Name, err)
+			}
+		}(worktree, cfg)
+	}
+}
+
+// readyPort returns the lowest healthy port served from a worktree, or the
+// wanted port when it's healthy; 0 when none is ready
+func (p *PreviewService) readyPort(worktreePath string, want int) int {
+	var ports []int
+	for port, service := range p.services() {
+		if service.Health == "healthy" && service.WorkingDir != "" && pathWithin(service.WorkingDir, worktreePath) {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	for _, port := range ports {
+		if want == 0 || port == want {
+			return port
+		}
+	}
+	return 0
+}
+
+// Deploy starts publishing (or republishing) a worktree's preview now,
+// using the worktree's lowest ready port if any, and returns the deploying
+// state. The outcome is recorded on the worktree.
+func (p *PreviewService) Deploy(worktreeID string) (*models.PreviewDeployment, error) {
+	worktree, err := p.worktree(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := LoadPreviewConfig(worktree.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled() {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "no preview deploy hook configured in %s", CatnipConfigFileName)
+	}
+	started, release, err := p.startDeploy(worktree, p.readyPort(worktree.Path, cfg.Port))
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := p.finishDeploy(worktree, cfg, started, release); err != nil {
+			logger.Warnf("⚠️ Preview deploy failed for %s: %v", worktree.Name, err)
+		}
+	}()
+	return started, nil
+}
+
+// Teardown starts removing a worktree's preview. The preview is cleared
+// once the teardown hook succeeds.
+func (p *PreviewService) Teardown(worktreeID string) error {
+	worktree, err := p.worktree(worktreeID)
+	if err != nil {
+		return err
+	}
+	if worktree.Preview == nil {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has no preview", worktree.Name)
+	}
+	cfg, err := LoadPreviewConfig(worktree.Path)
+	if err != nil {
+		return err
+	}
+	preview, release, err := p.startTeardown(worktree, true)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := p.finishTeardown(worktree, cfg, preview, true, release); err != nil {
+			logger.Warnf("⚠️ Preview teardown failed for %s: %v", worktree.Name, err)
+		}
+	}()
+	return nil
+}
+
+// TeardownDeleted removes the preview of a worktree that is being deleted.
+// It runs before the worktree's directory is removed, so teardown scripts
+// are still there.
+func (p *PreviewService) TeardownDeleted(worktree *models.Worktree) {
+	if worktree.Preview == nil {
+		return
+	}
+	cfg, err := LoadPreviewConfig(worktree.Path)
+	if err != nil {
+		logger.Warnf("⚠️ Can't tear down preview of deleted worktree %s: %v", worktree.Name, err)
+		return
+	}
+	preview, release, err := p.startTeardown(worktree, false)
+	if err == nil {
+		err = p.finishTeardown(worktree, cfg, preview, false, release)
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Preview teardown failed for deleted worktree %s: %v", worktree.Name, err)
+	}
+}
+
+func (p *PreviewService) worktree(worktreeID string) (*models.Worktree, error) {
+	worktree, exists := p.gitService.GetStateManager().GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	return worktree, nil
+}
+
+func (p *PreviewService) isRunning(worktreeID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.running[worktreeID]
+}
+
+// claim marks a hook as running for a worktree, failing if one already is
+func (p *PreviewService) claim(worktree *models.Worktree) (func(), error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running[worktree.ID] {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "a preview hook is already running for %s", worktree.Name)
+	}
+	p.running[worktree.ID] = true
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.running, worktree.ID)
+	}, nil
+}
+
+// record stores a worktree's preview state
+func (p *PreviewService) record(worktree *models.Worktree, preview *models.PreviewDeployment) {
+	if err := p.gitService.GetStateManager().UpdateWorktree(worktree.ID, map[string]interface{}{
+		"preview": preview,
+	}); err != nil {
+		logger.Warnf("⚠️ Failed to record preview state for %s: %v", worktree.Name, err)
+	}
+}
+
+// startDeploy claims a worktree for a deploy and records it as started
+func (p *PreviewService) startDeploy(worktree *models.Worktree, port int) (*models.PreviewDeployment, func(), error) {
+	release, err := p.claim(worktree)
+	if err != nil {
+		return nil, nil, err
+	}
+	preview := &models.PreviewDeployment{
+		State:     models.PreviewDeploying,
+		Port:      port,
+		Commit:    worktree.CommitHash,
+		StartedAt: time.Now(),
+	}
+	p.record(worktree, preview)
+	return preview, release, nil
+}
+
+// finishDeploy runs the deploy hook and records the outcome
+func (p *PreviewService) finishDeploy(worktree *models.Worktree, cfg PreviewConfig, started *models.PreviewDeployment, release func()) error {
+	defer release()
+
+	url, err := p.runHook(worktree, cfg, PreviewHookRequest{Action: "deploy", Port: started.Port}, cfg.Deploy)
+	done := *started
+	if err != nil {
+		done.State = models.PreviewFailed
+		done.Error = err.Error()
+		p.record(worktree, &done)
+		return err
+	}
+
+	now := time.Now()
+	done.State = models.PreviewDeployed
+	done.URL = url
+	done.DeployedAt = &now
+	p.record(worktree, &done)
+	logger.Infof("🌐 Preview for %s is live at %s", worktree.Name, url)
+	return nil
+}
+
+// startTeardown claims a worktree for a teardown and, for worktrees that
+// still exist, records it as started
+func (p *PreviewService) startTeardown(worktree *models.Worktree, record bool) (models.PreviewDeployment, func(), error) {
+	release, err := p.claim(worktree)
+	if err != nil {
+		return models.PreviewDeployment{}, nil, err
+	}
+	preview := *worktree.Preview
+	if record {
+		tearingDown := preview
+		tearingDown.State = models.PreviewTearingDown
+		tearingDown.StartedAt = time.Now()
+		p.record(worktree, &tearingDown)
+	}
+	return preview, release, nil
+}
+
+// finishTeardown runs the teardown hook, if any, and clears the preview. A
+// failed teardown keeps the preview so it can be retried.
+func (p *PreviewService) finishTeardown(worktree *models.Worktree, cfg PreviewConfig, preview models.PreviewDeployment, record bool, release func()) error {
+	defer release()
+
+	var err error
+	if cfg.Teardown != "" || cfg.Webhook != "" {
+		_, err = p.runHook(worktree, cfg, PreviewHookRequest{Action: "teardown", Port: preview.Port, URL: preview.URL}, cfg.Teardown)
+	}
+	if err != nil {
+		if record {
+			preview.State = models.PreviewFailed
+			preview.Error = err.Error()
+			p.record(worktree, &preview)
+		}
+		return err
+	}
+
+	if record {
+		p.record(worktree, nil)
+	}
+	logger.Infof("🧹 Tore down preview for %s", worktree.Name)
+	return nil
+}
+
+// runHook runs a deploy or teardown script, or calls the webhook when no
+// script is set, and returns the preview URL it reported
+func (p *PreviewService) runHook(worktree *models.Worktree, cfg PreviewConfig, req PreviewHookRequest, script string) (string, error) {
+	req.Repository = worktree.RepoID
+	req.Worktree = worktree.Name
+	req.Branch = worktree.Branch
+	req.Commit = worktree.CommitHash
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout())
+	defer cancel()
+	if script != "" {
+		return p.runScript(ctx, worktree.Path, script, req)
+	}
+	return p.callWebhook(ctx, cfg, req)
+}
+
+func (p *PreviewService) runScript(ctx context.Context, dir, script string, req PreviewHookRequest) (string, error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", script)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"CATNIP_PREVIEW_ACTION="+req.Action,
+		"CATNIP_REPOSITORY="+req.Repository,
+		"CATNIP_WORKTREE="+req.Worktree,
+		"CATNIP_BRANCH="+req.Branch,
+		"CATNIP_COMMIT="+req.Commit,
+		"CATNIP_PREVIEW_PORT="+strconv.Itoa(req.Port),
+		"CATNIP_PREVIEW_URL="+req.URL,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s hook failed: %v\n%s", req.Action, err, lastLines(strings.TrimSpace(output.String()), previewErrorLines))
+	}
+	if req.Action != "deploy" {
+		return "", nil
+	}
+
+	url := lastPreviewURL(output.String())
+	if url == "" {
+		return "", fmt.Errorf("deploy hook didn't print a preview URL\n%s", lastLines(strings.TrimSpace(output.String()), previewErrorLines))
+	}
+	return url, nil
+}
+
+func (p *PreviewService) callWebhook(ctx context.Context, cfg PreviewConfig, req PreviewHookRequest) (string, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if cfg.TokenEnv != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+os.Getenv(cfg.TokenEnv))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("%s webhook failed: %v", req.Action, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s webhook returned %s: %s", req.Action, resp.Status, lastLines(strings.TrimSpace(string(data)), previewErrorLines))
+	}
+	if req.Action != "deploy" {
+		return "", nil
+	}
+
+	var result struct {
+		URL string `json:"url"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.URL == "" {
+		return "", fmt.Errorf("deploy webhook didn't return a preview URL")
+	}
+	return result.URL, nil
+}
+
+// lastPreviewURL returns the last http(s) URL in a hook's output
+func lastPreviewURL(output string) string {
+	fields := strings.Fields(output)
+	for i := len(fields) - 1; i >= 0; i-- {
+		field := strings.TrimLeft(fields[i], "(<\"'")
+		if strings.HasPrefix(field, "https://") || strings.HasPrefix(field, "http://") {
+			return strings.TrimRight(field, ".,;)>\"'")
+		}
+	}
+	return ""
+}
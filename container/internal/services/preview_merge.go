@@ -0,0 +1,406 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Outcomes of applying a preview branch
+const (
+	PreviewMergeMerged    = "merged"
+	PreviewMergeUpToDate  = "up_to_date"
+	PreviewMergeConflicts = "conflicts"
+)
+
+// PreviewBranchName is the branch CreateWorktreePreview pushes a worktree
+// branch to in the local repository
+func PreviewBranchName(worktreeBranch string) string {
+	return "catnip/" + git.ExtractWorkspaceName(worktreeBranch)
+}
+
+// PreviewMergePlan describes what merging a preview branch into a checkout's
+// current branch would do
+type PreviewMergePlan struct {
+	PreviewBranch string `json:"preview_branch" example:"catnip/milo"`
+	// Branch checked out in the checkout; empty when HEAD is detached
+	TargetBranch string `json:"target_branch" example:"main"`
+	// Commits the preview has that the target lacks, newest first
+	Commits []string `json:"commits"`
+	// Commits the target has that the preview lacks
+	Behind int `json:"behind" example:"2"`
+	// Files the preview changes since the branches diverged
+	Files []string `json:"files"`
+	// Files both sides changed in ways git can't reconcile
+	Conflicts []string `json:"conflicts,omitempty"`
+	// The target can simply move to the preview
+	FastForward bool `json:"fast_forward"`
+	// The target already contains the preview
+	UpToDate bool `json:"up_to_date"`
+	// The checkout has uncommitted changes, so nothing can be applied yet
+	DirtyCheckout bool `json:"dirty_checkout"`
+	// A preview merge is waiting for its conflicts to be resolved
+	MergeInProgress bool `json:"merge_in_progress"`
+}
+
+// PreviewMergeOptions controls how a preview branch is applied
+type PreviewMergeOptions struct {
+	// Apply the preview as one commit instead of a merge
+	Squash bool `json:"squash"`
+	// Keep the preview branch once it is merged
+	KeepBranch bool `json:"keep_branch"`
+	// Commit message (default "Merge preview <branch>")
+	Message string `json:"message,omitempty"`
+}
+
+func (o PreviewMergeOptions) message(previewBranch string) string {
+	if o.Message != "" {
+		return o.Message
+	}
+	return "Merge preview " + previewBranch
+}
+
+// PreviewMergeResult is the outcome of applying a preview branch
+type PreviewMergeResult struct {
+	// merged, up_to_date or conflicts
+	Status string `json:"status" example:"merged"`
+	// The target branch's new commit
+	Commit string `json:"commit,omitempty" example:"3f2c1a9"`
+	// Files left with conflict markers to resolve before continuing
+	Conflicts []string `json:"conflicts,omitempty"`
+	// The preview branch was deleted
+	BranchDeleted bool `json:"branch_deleted"`
+}
+
+// PreviewMerger brings a worktree's preview branch into a checkout of its
+// repository: the user's original checkout, whether reached from the host or
+// through the container's mount of it
+type PreviewMerger struct {
+	operations git.Operations
+}
+
+// NewPreviewMerger creates a merger running git through operations
+func NewPreviewMerger(operations git.Operations) *PreviewMerger {
+	return &PreviewMerger{operations: operations}
+}
+
+func (m *PreviewMerger) git(repoPath string, args ...string) (string, error) {
+	output, err := m.operations.ExecuteGit(repoPath, args...)
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v\n%s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func (m *PreviewMerger) requireBranch(repoPath, previewBranch string) error {
+	if !m.operations.BranchExists(repoPath, previewBranch, false) {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "preview branch %s doesn't exist in %s", previewBranch, repoPath).
+			WithHint("Create it first with POST /v1/git/worktrees/{id}/preview, and run this in the checkout catnip mounts")
+	}
+	return nil
+}
+
+// hasMergeState reports whether the checkout's git directory holds a file of
+// an unfinished merge
+func (m *PreviewMerger) hasMergeState(repoPath, name string) bool {
+	gitDir, err := m.git(repoPath, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(filepath.Join(gitDir, name))
+	return err == nil
+}
+
+// hasMergeHead reports whether a merge is waiting to be committed
+func (m *PreviewMerger) hasMergeHead(repoPath string) bool {
+	return m.hasMergeState(repoPath, "MERGE_HEAD")
+}
+
+// hasSquashMessage reports whether a squash merge is waiting to be committed.
+// Squash merges leave no MERGE_HEAD, only the message they prepared.
+func (m *PreviewMerger) hasSquashMessage(repoPath string) bool {
+	return m.hasMergeState(repoPath, "SQUASH_MSG")
+}
+
+// mergeInProgress reports whether a merge or squash merge stopped on
+// conflicts in the checkout
+func (m *PreviewMerger) mergeInProgress(repoPath string) bool {
+	return m.hasMergeHead(repoPath) || m.hasSquashMessage(repoPath)
+}
+
+// Plan describes merging previewBranch into the checkout's current branch
+func (m *PreviewMerger) Plan(repoPath, previewBranch string) (*PreviewMergePlan, error) {
+	if err := m.requireBranch(repoPath, previewBranch); err != nil {
+		return nil, err
+	}
+	plan := &PreviewMergePlan{PreviewBranch: previewBranch, Commits: []string{}, Files: []string{}}
+	if branch, err := m.git(repoPath, "symbolic-ref", "--quiet", "--short", "HEAD"); err == nil {
+		plan.TargetBranch = branch
+	}
+
+	commits, err := m.git(repoPath, "log", "--oneline", "--no-decorate", "HEAD.."+previewBranch)
+	if err != nil {
+		return nil, err
+	}
+	if commits != "" {
+		plan.Commits = strings.Split(commits, "\n")
+	}
+	if plan.Behind, err = m.operations.GetCommitCount(repoPath, previewBranch, "HEAD"); err != nil {
+		return nil, err
+	}
+	files, err := m.git(repoPath, "diff", "--name-only", "HEAD..."+previewBranch)
+	if err != nil {
+		return nil, err
+	}
+	if files != "" {
+		plan.Files = strings.Split(files, "\n")
+	}
+
+	plan.UpToDate = len(plan.Commits) == 0
+	plan.FastForward = !plan.UpToDate && plan.Behind == 0
+	if !plan.UpToDate && !plan.FastForward {
+		output, err := m.operations.MergeTree(repoPath, "HEAD", previewBranch)
+		if err != nil {
+			return nil, err
+		}
+		if git.HasConflictMarkers(output) {
+			plan.Conflicts = git.ExtractConflictFiles(output)
+		}
+	}
+	plan.MergeInProgress = m.mergeInProgress(repoPath)
+	if !plan.MergeInProgress {
+		dirty, err := m.operations.HasUncommittedChanges(repoPath)
+		if err != nil {
+			return nil, err
+		}
+		plan.DirtyCheckout = dirty
+	}
+	return plan, nil
+}
+
+// Apply merges previewBranch into the checkout's current branch with a
+// three-way merge (or as one squashed commit). When the merge stops on
+// conflicts they're left in the checkout to resolve, then Continue or Abort.
+// The preview branch is deleted after a clean merge unless KeepBranch is set.
+func (m *PreviewMerger) Apply(repoPath, previewBranch string, opts PreviewMergeOptions) (*PreviewMergeResult, error) {
+	plan, err := m.Plan(repoPath, previewBranch)
+	if err != nil {
+		return nil, err
+	}
+	if plan.MergeInProgress {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "a merge is already in progress in %s", repoPath).
+			WithHint("Resolve its conflicts and continue, or abort it")
+	}
+	if plan.UpToDate {
+		return m.finish(repoPath, previewBranch, opts, &PreviewMergeResult{Status: PreviewMergeUpToDate})
+	}
+	if plan.DirtyCheckout {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s has uncommitted changes", repoPath).
+			WithHint("Commit or stash them before applying the preview")
+	}
+	if plan.TargetBranch == "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s has no branch checked out", repoPath).
+			WithHint("Check out the branch the preview should be applied to")
+	}
+
+	args := []string{"merge", "--no-edit", "-m", opts.message(previewBranch)}
+	if opts.Squash {
+		args = []string{"merge", "--squash"}
+	}
+	if _, err := m.operations.ExecuteGit(repoPath, append(args, previewBranch)...); err != nil {
+		conflicts, _ := m.operations.GetConflictedFiles(repoPath)
+		if len(conflicts) > 0 {
+			return &PreviewMergeResult{Status: PreviewMergeConflicts, Conflicts: conflicts}, nil
+		}
+		return nil, models.NewAPIError(models.ErrCodeGitCommandFailed, "failed to merge %s: %v", previewBranch, err)
+	}
+	if opts.Squash {
+		// A squash merge records no ancestry, so a preview squashed in before
+		// still has "new" commits but stages nothing
+		if _, err := m.operations.ExecuteGit(repoPath, "diff", "--cached", "--quiet"); err == nil {
+			if _, err := m.git(repoPath, "reset", "--merge"); err != nil {
+				return nil, err
+			}
+			return m.finish(repoPath, previewBranch, opts, &PreviewMergeResult{Status: PreviewMergeUpToDate})
+		}
+		if _, err := m.git(repoPath, "commit", "--no-verify", "-m", opts.message(previewBranch)); err != nil {
+			return nil, err
+		}
+	}
+	return m.finish(repoPath, previewBranch, opts, &PreviewMergeResult{Status: PreviewMergeMerged})
+}
+
+// Continue commits a preview merge once its conflicts are resolved. Conflicted
+// files edited in place to remove the conflict markers are staged first.
+func (m *PreviewMerger) Continue(repoPath, previewBranch string, opts PreviewMergeOptions) (*PreviewMergeResult, error) {
+	if !m.mergeInProgress(repoPath) {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "no merge in progress in %s", repoPath)
+	}
+	conflicts, err := m.stageResolved(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return &PreviewMergeResult{Status: PreviewMergeConflicts, Conflicts: conflicts}, nil
+	}
+	args := []string{"commit", "--no-verify", "--no-edit"}
+	if !m.hasMergeHead(repoPath) {
+		args = []string{"commit", "--no-verify", "-m", opts.message(previewBranch)}
+	}
+	if _, err := m.git(repoPath, args...); err != nil {
+		return nil, err
+	}
+	return m.finish(repoPath, previewBranch, opts, &PreviewMergeResult{Status: PreviewMergeMerged})
+}
+
+// stageResolved stages the conflicted files that no longer contain conflict
+// markers and returns those that still do
+func (m *PreviewMerger) stageResolved(repoPath string) ([]string, error) {
+	conflicted, err := m.operations.GetConflictedFiles(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	var remaining, resolved []string
+	for _, file := range conflicted {
+		content, err := os.ReadFile(filepath.Join(repoPath, file))
+		if err != nil || bytes.Contains(content, []byte("<<<<<<< ")) {
+			remaining = append(remaining, file)
+			continue
+		}
+		resolved = append(resolved, file)
+	}
+	if len(resolved) > 0 {
+		if _, err := m.git(repoPath, append([]string{"add", "--"}, resolved...)...); err != nil {
+			return nil, err
+		}
+	}
+	return remaining, nil
+}
+
+// Abort undoes a preview merge that stopped on conflicts
+func (m *PreviewMerger) Abort(repoPath string) error {
+	if m.hasMergeHead(repoPath) {
+		_, err := m.git(repoPath, "merge", "--abort")
+		return err
+	}
+	if !m.hasSquashMessage(repoPath) {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "no merge in progress in %s", repoPath)
+	}
+	_, err := m.git(repoPath, "reset", "--merge")
+	return err
+}
+
+// DeleteBranch removes a preview branch from the checkout
+func (m *PreviewMerger) DeleteBranch(repoPath, previewBranch string) error {
+	if err := m.requireBranch(repoPath, previewBranch); err != nil {
+		return err
+	}
+	if branch, _ := m.git(repoPath, "symbolic-ref", "--quiet", "--short", "HEAD"); branch == previewBranch {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "%s is checked out in %s", previewBranch, repoPath).
+			WithHint("Switch to another branch first")
+	}
+	return m.operations.DeleteBranch(repoPath, previewBranch, true)
+}
+
+// finish records the new commit and deletes the merged preview branch
+func (m *PreviewMerger) finish(repoPath, previewBranch string, opts PreviewMergeOptions, result *PreviewMergeResult) (*PreviewMergeResult, error) {
+	if commit, err := m.git(repoPath, "log", "-1", "--format=%h"); err == nil {
+		result.Commit = commit
+	}
+	if !opts.KeepBranch {
+		if err := m.operations.DeleteBranch(repoPath, previewBranch, true); err != nil {
+			return nil, fmt.Errorf("merged, but failed to delete %s: %w", previewBranch, err)
+		}
+		result.BranchDeleted = true
+	}
+	return result, nil
+}
+
+// previewCheckout finds the checkout a worktree's preview branch is pushed to
+func (s *GitService) previewCheckout(worktreeID string) (repoPath, previewBranch string, err error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+	if !exists {
+		return "", "", models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if !s.isLocalRepo(worktree.RepoID) {
+		return "", "", models.NewAPIError(models.ErrCodeInvalidRequest, "preview only supported for local repositories")
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return "", "", models.NewAPIError(models.ErrCodeRepositoryNotFound, "local repository %s not found", worktree.RepoID)
+	}
+	if !repo.Available {
+		return "", "", fmt.Errorf("repository %s is not available", worktree.RepoID)
+	}
+	return repo.Path, PreviewBranchName(worktree.Branch), nil
+}
+
+// PlanPreviewMerge describes merging a worktree's preview branch into the
+// branch checked out in its local repository
+func (s *GitService) PlanPreviewMerge(worktreeID string) (*PreviewMergePlan, error) {
+	repoPath, previewBranch, err := s.previewCheckout(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	return NewPreviewMerger(s.operations).Plan(repoPath, previewBranch)
+}
+
+// ApplyPreviewMerge refreshes a worktree's preview branch and merges it into
+// the branch checked out in its local repository
+func (s *GitService) ApplyPreviewMerge(worktreeID string, opts PreviewMergeOptions) (*PreviewMergeResult, error) {
+	repoPath, previewBranch, err := s.previewCheckout(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.CreateWorktreePreview(worktreeID); err != nil {
+		return nil, err
+	}
+	result, err := NewPreviewMerger(s.operations).Apply(repoPath, previewBranch, opts)
+	if err == nil {
+		logger.Infof("🔀 Preview %s of worktree %s: %s", previewBranch, worktreeID, result.Status)
+	}
+	return result, err
+}
+
+// ContinuePreviewMerge commits a preview merge once its conflicts are resolved
+func (s *GitService) ContinuePreviewMerge(worktreeID string, opts PreviewMergeOptions) (*PreviewMergeResult, error) {
+	repoPath, previewBranch, err := s.previewCheckout(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	return NewPreviewMerger(s.operations).Continue(repoPath, previewBranch, opts)
+}
+
+// AbortPreviewMerge undoes a preview merge that stopped on conflicts
+func (s *GitService) AbortPreviewMerge(worktreeID string) error {
+	repoPath, _, err := s.previewCheckout(worktreeID)
+	if err != nil {
+		return err
+	}
+	return NewPreviewMerger(s.operations).Abort(repoPath)
+}
+
+// DeleteWorktreePreview removes a worktree's preview branch from its local
+// repository, reporting whether there was one
+func (s *GitService) DeleteWorktreePreview(worktreeID string) (bool, error) {
+	repoPath, previewBranch, err := s.previewCheckout(worktreeID)
+	if err != nil {
+		return false, err
+	}
+	if !s.operations.BranchExists(repoPath, previewBranch, false) {
+		return false, nil
+	}
+	if err := NewPreviewMerger(s.operations).DeleteBranch(repoPath, previewBranch); err != nil {
+		return false, err
+	}
+	logger.Infof("🗑️ Deleted preview branch %s", previewBranch)
+	return true, nil
+}
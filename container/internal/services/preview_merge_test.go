@@ -0,0 +1,178 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+)
+
+// setupPreviewRepo creates a checkout on main with a catnip/milo preview
+// branch that diverged from it
+func setupPreviewRepo(t *testing.T, conflicting bool) (string, func(file, content string)) {
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+
+	dir := t.TempDir()
+	write := func(file, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+	}
+	commit := func(message string) {
+		runTestGit(t, dir, "add", ".")
+		runTestGit(t, dir, "commit", "-q", "-m", message)
+	}
+
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	write("a.txt", "base\n")
+	commit("initial")
+	runTestGit(t, dir, "checkout", "-q", "-b", "catnip/milo")
+	write("a.txt", "preview\n")
+	write("b.txt", "preview\n")
+	commit("preview work")
+	runTestGit(t, dir, "checkout", "-q", "main")
+	if conflicting {
+		write("a.txt", "main\n")
+	} else {
+		write("c.txt", "main\n")
+	}
+	commit("main work")
+	return dir, write
+}
+
+func TestPreviewBranchName(t *testing.T) {
+	assert.Equal(t, "catnip/milo", PreviewBranchName("refs/catnip/milo"))
+	assert.Equal(t, "catnip/milo", PreviewBranchName("catnip/milo"))
+}
+
+func TestPreviewMergerPlan(t *testing.T) {
+	merger := NewPreviewMerger(git.NewOperations())
+
+	dir, _ := setupPreviewRepo(t, false)
+	plan, err := merger.Plan(dir, "catnip/milo")
+	require.NoError(t, err)
+	assert.Equal(t, "main", plan.TargetBranch)
+	assert.Len(t, plan.Commits, 1)
+	assert.Contains(t, plan.Commits[0], "preview work")
+	assert.Equal(t, 1, plan.Behind)
+	assert.ElementsMatch(t, []string{"a.txt", "b.txt"}, plan.Files)
+	assert.Empty(t, plan.Conflicts)
+	assert.False(t, plan.FastForward)
+	assert.False(t, plan.UpToDate)
+	assert.False(t, plan.DirtyCheckout)
+
+	conflicting, write := setupPreviewRepo(t, true)
+	write("d.txt", "untracked\n")
+	plan, err = merger.Plan(conflicting, "catnip/milo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt"}, plan.Conflicts)
+	assert.True(t, plan.DirtyCheckout)
+
+	_, err = merger.Plan(dir, "catnip/felix")
+	assert.Error(t, err)
+}
+
+func TestPreviewMergerApply(t *testing.T) {
+	merger := NewPreviewMerger(git.NewOperations())
+
+	dir, _ := setupPreviewRepo(t, false)
+	result, err := merger.Apply(dir, "catnip/milo", PreviewMergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, PreviewMergeMerged, result.Status)
+	assert.True(t, result.BranchDeleted)
+	assert.Equal(t, runTestGit(t, dir, "rev-parse", "--short", "HEAD"), result.Commit)
+	assert.Equal(t, "Merge preview catnip/milo", runTestGit(t, dir, "log", "-1", "--format=%s"))
+	assert.Equal(t, "preview\n", readTestFile(t, filepath.Join(dir, "b.txt")))
+	assert.False(t, git.NewOperations().BranchExists(dir, "catnip/milo", false))
+
+	squashed, write := setupPreviewRepo(t, false)
+	write("c.txt", "dirty\n")
+	_, err = merger.Apply(squashed, "catnip/milo", PreviewMergeOptions{Squash: true})
+	assert.Error(t, err, "a dirty checkout is refused")
+	runTestGit(t, squashed, "checkout", "--", "c.txt")
+
+	result, err = merger.Apply(squashed, "catnip/milo", PreviewMergeOptions{Squash: true, KeepBranch: true, Message: "Add b"})
+	require.NoError(t, err)
+	assert.Equal(t, PreviewMergeMerged, result.Status)
+	assert.False(t, result.BranchDeleted)
+	assert.Len(t, strings.Fields(runTestGit(t, squashed, "rev-list", "--parents", "-n", "1", "HEAD")), 2, "squash makes a single-parent commit")
+	assert.Equal(t, "Add b", runTestGit(t, squashed, "log", "-1", "--format=%s"))
+
+	// Squashing it again finds nothing new
+	result, err = merger.Apply(squashed, "catnip/milo", PreviewMergeOptions{Squash: true, KeepBranch: true})
+	require.NoError(t, err)
+	assert.Equal(t, PreviewMergeUpToDate, result.Status)
+	plan, err := merger.Plan(squashed, "catnip/milo")
+	require.NoError(t, err)
+	assert.False(t, plan.MergeInProgress)
+
+	_, err = merger.Apply(dir, "catnip/milo", PreviewMergeOptions{})
+	assert.Error(t, err, "the merged branch is gone")
+	runTestGit(t, dir, "branch", "catnip/milo", "HEAD~1")
+	result, err = merger.Apply(dir, "catnip/milo", PreviewMergeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, PreviewMergeUpToDate, result.Status)
+	assert.True(t, result.BranchDeleted)
+}
+
+func TestPreviewMergerConflicts(t *testing.T) {
+	merger := NewPreviewMerger(git.NewOperations())
+
+	for _, squash := range []bool{false, true} {
+		dir, write := setupPreviewRepo(t, true)
+		head := runTestGit(t, dir, "rev-parse", "HEAD")
+		opts := PreviewMergeOptions{Squash: squash}
+
+		result, err := merger.Apply(dir, "catnip/milo", opts)
+		require.NoError(t, err)
+		assert.Equal(t, PreviewMergeConflicts, result.Status)
+		assert.Equal(t, []string{"a.txt"}, result.Conflicts)
+
+		plan, err := merger.Plan(dir, "catnip/milo")
+		require.NoError(t, err)
+		assert.True(t, plan.MergeInProgress)
+		_, err = merger.Apply(dir, "catnip/milo", opts)
+		assert.Error(t, err, "a merge in progress is refused")
+
+		// Still conflicted until the markers are gone
+		result, err = merger.Continue(dir, "catnip/milo", opts)
+		require.NoError(t, err)
+		assert.Equal(t, PreviewMergeConflicts, result.Status)
+
+		require.NoError(t, merger.Abort(dir))
+		assert.Equal(t, head, runTestGit(t, dir, "rev-parse", "HEAD"))
+		assert.Empty(t, runTestGit(t, dir, "status", "--porcelain"))
+		_, err = merger.Continue(dir, "catnip/milo", opts)
+		assert.Error(t, err, "nothing to continue after aborting")
+
+		_, err = merger.Apply(dir, "catnip/milo", opts)
+		require.NoError(t, err)
+		write("a.txt", "resolved\n")
+		result, err = merger.Continue(dir, "catnip/milo", opts)
+		require.NoError(t, err)
+		assert.Equal(t, PreviewMergeMerged, result.Status)
+		assert.True(t, result.BranchDeleted)
+		assert.Equal(t, "resolved\n", readTestFile(t, filepath.Join(dir, "a.txt")))
+		assert.Equal(t, "Merge preview catnip/milo", runTestGit(t, dir, "log", "-1", "--format=%s"))
+		assert.Empty(t, runTestGit(t, dir, "status", "--porcelain"))
+	}
+}
+
+func TestPreviewMergerDeleteBranch(t *testing.T) {
+	merger := NewPreviewMerger(git.NewOperations())
+	dir, _ := setupPreviewRepo(t, false)
+
+	runTestGit(t, dir, "checkout", "-q", "catnip/milo")
+	assert.Error(t, merger.DeleteBranch(dir, "catnip/milo"), "the checked out branch is kept")
+
+	runTestGit(t, dir, "checkout", "-q", "main")
+	require.NoError(t, merger.DeleteBranch(dir, "catnip/milo"))
+	assert.Error(t, merger.DeleteBranch(dir, "catnip/milo"))
+}
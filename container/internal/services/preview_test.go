@@ -0,0 +1,160 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func setupPreviewWorktree(t *testing.T, config string) (*GitService, *models.Worktree) {
+	t.Helper()
+	gitService := createTestGitService(t)
+	worktree := &models.Worktree{
+		ID:         "wt-preview",
+		RepoID:     "vanpelt/catnip",
+		Name:       "catnip/felix",
+		Branch:     "feature/api-docs",
+		CommitHash: "abc123",
+		Path:       t.TempDir(),
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(worktree.Path, CatnipConfigFileName), []byte(config), 0644))
+	require.NoError(t, gitService.GetStateManager().AddRepository(&models.Repository{ID: worktree.RepoID}))
+	require.NoError(t, gitService.GetStateManager().AddWorktree(worktree))
+	return gitService, worktree
+}
+
+func waitForPreview(t *testing.T, gitService *GitService, worktreeID string, done func(*models.PreviewDeployment) bool) *models.PreviewDeployment {
+	t.Helper()
+	var preview *models.PreviewDeployment
+	require.Eventually(t, func() bool {
+		preview = gitService.GetStateManager().GetAllWorktrees()[worktreeID].Preview
+		return done(preview)
+	}, 10*time.Second, 20*time.Millisecond)
+	return preview
+}
+
+func TestPreviewServiceDeploysReadyWorktrees(t *testing.T) {
+	gitService, worktree := setupPreviewWorktree(t, `preview:
+  deploy: |
+    echo "deploying $CATNIP_BRANCH@$CATNIP_COMMIT from port $CATNIP_PREVIEW_PORT"
+    echo "Preview ready: https://$CATNIP_PREVIEW_PORT.preview.example.com."
+  teardown: echo "$CATNIP_PREVIEW_URL" > torn-down
+`)
+	setupState := SetupStateRunning
+	services := map[int]*ServiceInfo{
+		8080: {Port: 8080, Health: "healthy", WorkingDir: t.TempDir()},
+		5173: {Port: 5173, Health: "unhealthy", WorkingDir: worktree.Path},
+		3000: {Port: 3000, Health: "healthy", WorkingDir: filepath.Join(worktree.Path, "web")},
+	}
+	previews := NewPreviewService(gitService,
+		func(string) string { return setupState },
+		func() map[int]*ServiceInfo { return services },
+	)
+
+	// Nothing deploys while setup is still running
+	previews.deployReady()
+	assert.Nil(t, gitService.GetStateManager().GetAllWorktrees()[worktree.ID].Preview)
+
+	setupState = SetupStateSucceeded
+	previews.deployReady()
+	preview := waitForPreview(t, gitService, worktree.ID, func(p *models.PreviewDeployment) bool {
+		return p != nil && p.State != models.PreviewDeploying
+	})
+	assert.Equal(t, models.PreviewDeployed, preview.State, preview.Error)
+	assert.Equal(t, "https://3000.preview.example.com", preview.URL)
+	assert.Equal(t, 3000, preview.Port)
+	assert.Equal(t, "abc123", preview.Commit)
+	assert.NotNil(t, preview.DeployedAt)
+
+	// Deployed worktrees aren't redeployed automatically
+	previews.deployReady()
+	assert.False(t, previews.isRunning(worktree.ID))
+
+	require.NoError(t, previews.Teardown(worktree.ID))
+	waitForPreview(t, gitService, worktree.ID, func(p *models.PreviewDeployment) bool { return p == nil })
+	torn, err := os.ReadFile(filepath.Join(worktree.Path, "torn-down"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://3000.preview.example.com\n", string(torn))
+
+	err = previews.Teardown(worktree.ID)
+	assert.ErrorContains(t, err, "has no preview")
+	_, err = previews.Deploy("missing")
+	assert.Error(t, err)
+}
+
+func TestPreviewServiceFailedDeploy(t *testing.T) {
+	gitService, worktree := setupPreviewWorktree(t, "preview:\n  deploy: echo 'no url here'; echo boom >&2; exit 3\n")
+	previews := NewPreviewService(gitService,
+		func(string) string { return SetupStateNone },
+		func() map[int]*ServiceInfo { return nil },
+	)
+
+	started, err := previews.Deploy(worktree.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PreviewDeploying, started.State)
+	assert.Zero(t, started.Port, "manual deploys don't need a ready port")
+
+	preview := waitForPreview(t, gitService, worktree.ID, func(p *models.PreviewDeployment) bool {
+		return p != nil && p.State == models.PreviewFailed
+	})
+	assert.Contains(t, preview.Error, "deploy hook failed")
+	assert.Contains(t, preview.Error, "boom")
+	assert.Empty(t, preview.URL)
+}
+
+func TestPreviewServiceWebhook(t *testing.T) {
+	var requests []PreviewHookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer s3cret", r.Header.Get("Authorization"))
+		var req PreviewHookRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		requests = append(requests, req)
+		if req.Action == "deploy" {
+			_ = json.NewEncoder(w).Encode(map[string]string{"url": "https://felix.fly.dev"})
+		}
+	}))
+	defer server.Close()
+	t.Setenv("PREVIEW_TOKEN", "s3cret")
+
+	gitService, worktree := setupPreviewWorktree(t, "preview:\n  webhook: "+server.URL+"\n  token_env: PREVIEW_TOKEN\n  port: 3000\n")
+	previews := NewPreviewService(gitService,
+		func(string) string { return SetupStateSucceeded },
+		func() map[int]*ServiceInfo {
+			return map[int]*ServiceInfo{
+				3000: {Port: 3000, Health: "healthy", WorkingDir: worktree.Path},
+				2999: {Port: 2999, Health: "healthy", WorkingDir: worktree.Path},
+			}
+		},
+	)
+
+	previews.deployReady()
+	preview := waitForPreview(t, gitService, worktree.ID, func(p *models.PreviewDeployment) bool {
+		return p != nil && p.State == models.PreviewDeployed
+	})
+	assert.Equal(t, "https://felix.fly.dev", preview.URL)
+	assert.Equal(t, 3000, preview.Port)
+
+	// Deleted worktrees are torn down synchronously
+	deleted, _ := gitService.GetStateManager().GetWorktree(worktree.ID)
+	previews.TeardownDeleted(deleted)
+	require.Len(t, requests, 2)
+	assert.Equal(t, PreviewHookRequest{
+		Action: "deploy", Repository: "vanpelt/catnip", Worktree: "catnip/felix",
+		Branch: "feature/api-docs", Commit: "abc123", Port: 3000,
+	}, requests[0])
+	assert.Equal(t, "teardown", requests[1].Action)
+	assert.Equal(t, "https://felix.fly.dev", requests[1].URL)
+}
+
+func TestLastPreviewURL(t *testing.T) {
+	assert.Equal(t, "https://b.example.com/x", lastPreviewURL("see http://a.example.com\nDeployed to (https://b.example.com/x).\n"))
+	assert.Empty(t, lastPreviewURL("done, no url"))
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+const (
+	// clockTicksPerSecond is USER_HZ, which is 100 on every Linux platform we ship on
+	clockTicksPerSecond = 100
+	// cpuActiveThreshold is the fraction of a core a process tree must use to count as busy
+	cpuActiveThreshold = 0.05
+)
+
+// ProcStat is the subset of /proc/<pid>/stat needed for activity detection
+type ProcStat struct {
+	PID     int
+	PPID    int
+	PGRP    int
+	Session int    // Session ID (the PID of the session leader)
+	TPGID   int    // Foreground process group of the controlling terminal
+	State   string // R, S, D, Z, ...
+	Ticks   uint64 // utime + stime in clock ticks
+	Comm    string // Executable name, truncated by the kernel to 15 bytes
+}
+
+// ProcessActivity describes what a session's process tree is currently doing
+type ProcessActivity struct {
+	Supported     bool    // False when /proc is unavailable (e.g. macOS)
+	Processes     int     // Number of processes in the tree, including the root
+	ForegroundJob bool    // A job other than the root owns the terminal
+	CPUUsage      float64 // Cores used since the previous sample
+	CPUActive     bool    // CPUUsage exceeded cpuActiveThreshold
+}
+
+// Busy reports whether the process tree is doing work that should keep the session alive
+func (a ProcessActivity) Busy() bool {
+	return a.ForegroundJob || a.CPUActive
+}
+
+// cpuSample is a previous CPU reading for a process tree
+type cpuSample struct {
+	ticks uint64
+	at    time.Time
+}
+
+// ProcessTreeInspector samples the CPU usage and foreground jobs of PTY process trees
+type ProcessTreeInspector struct {
+	procDir string
+	mu      sync.Mutex
+	samples map[int]cpuSample // Keyed by root PID
+}
+
+// NewProcessTreeInspector creates an inspector reading from /proc. Where /proc
+// isn't available the inspector reports every tree as unsupported.
+func NewProcessTreeInspector() *ProcessTreeInspector {
+	procDir := ""
+	if config.Runtime.PortMonitorEnabled {
+		procDir = "/proc"
+	}
+	return NewProcessTreeInspectorWithDir(procDir)
+}
+
+// NewProcessTreeInspectorWithDir creates an inspector reading from procDir (for testing)
+func NewProcessTreeInspectorWithDir(procDir string) *ProcessTreeInspector {
+	return &ProcessTreeInspector{
+		procDir: procDir,
+		samples: make(map[int]cpuSample),
+	}
+}
+
+// Inspect returns the activity of the process tree rooted at rootPID. CPU usage
+// is measured against the previous call for the same root, so the first call
+// only establishes a baseline.
+func (i *ProcessTreeInspector) Inspect(rootPID int) ProcessActivity {
+	if rootPID <= 0 || i.procDir == "" {
+		return ProcessActivity{}
+	}
+
+	stats := i.readAllStats()
+	root, exists := stats[rootPID]
+	if !exists {
+		i.Forget(rootPID)
+		return ProcessActivity{}
+	}
+
+	tree := collectProcessTree(stats, rootPID)
+	activity := ProcessActivity{
+		Supported: true,
+		Processes: len(tree),
+		// The shell owns the terminal while idle at a prompt; anything else is a foreground job
+		ForegroundJob: root.TPGID > 0 && root.TPGID != root.PGRP,
+	}
+
+	var ticks uint64
+	for _, stat := range tree {
+		ticks += stat.Ticks
+	}
+
+	now := time.Now()
+	i.mu.Lock()
+	previous, hasPrevious := i.samples[rootPID]
+	i.samples[rootPID] = cpuSample{ticks: ticks, at: now}
+	i.mu.Unlock()
+
+	// Ticks can drop when a child exits; treat that as no measurable usage
+	if hasPrevious && ticks > previous.ticks {
+		elapsed := now.Sub(previous.at).Seconds()
+		if elapsed > 0 {
+			activity.CPUUsage = float64(ticks-previous.ticks) / clockTicksPerSecond / elapsed
+		}
+	}
+	activity.CPUActive = activity.CPUUsage >= cpuActiveThreshold
+
+	return activity
+}
+
+// Forget drops the stored CPU sample for a root PID once its session is gone
+func (i *ProcessTreeInspector) Forget(rootPID int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.samples, rootPID)
+}
+
+// readAllStats reads the stat file of every process in procDir
+func (i *ProcessTreeInspector) readAllStats() map[int]ProcStat {
+	stats := make(map[int]ProcStat)
+
+	entries, err := os.ReadDir(i.procDir)
+	if err != nil {
+		return stats
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(i.procDir, entry.Name(), "stat"))
+		if err != nil {
+			// Process exited while we were scanning
+			continue
+		}
+		if stat, ok := ParseProcStat(string(data)); ok && stat.PID == pid {
+			stats[pid] = stat
+		}
+	}
+
+	return stats
+}
+
+// collectProcessTree returns rootPID and all of its descendants
+func collectProcessTree(stats map[int]ProcStat, rootPID int) []ProcStat {
+	children := make(map[int][]int)
+	for pid, stat := range stats {
+		children[stat.PPID] = append(children[stat.PPID], pid)
+	}
+
+	var tree []ProcStat
+	queue := []int{rootPID}
+	visited := make(map[int]bool)
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		if visited[pid] {
+			continue
+		}
+		visited[pid] = true
+		if stat, exists := stats[pid]; exists {
+			tree = append(tree, stat)
+		}
+		queue = append(queue, children[pid]...)
+	}
+	return tree
+}
+
+// ParseProcStat parses the contents of /proc/<pid>/stat. The command name is
+// wrapped in parentheses and may itself contain spaces or parentheses, so
+// fields are split after the last closing paren.
+func ParseProcStat(data string) (ProcStat, bool) {
+	openIdx := strings.Index(data, "(")
+	closeIdx := strings.LastIndex(data, ")")
+	if openIdx < 0 || closeIdx < openIdx {
+		return ProcStat{}, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(data[:openIdx]))
+	if err != nil {
+		return ProcStat{}, false
+	}
+
+	// Fields after comm: state ppid pgrp session tty_nr tpgid flags minflt
+	// cminflt majflt cmajflt utime stime ...
+	fields := strings.Fields(data[closeIdx+1:])
+	if len(fields) < 13 {
+		return ProcStat{}, false
+	}
+
+	stat := ProcStat{PID: pid, State: fields[0], Comm: data[openIdx+1 : closeIdx]}
+	stat.PPID, _ = strconv.Atoi(fields[1])
+	stat.PGRP, _ = strconv.Atoi(fields[2])
+	stat.Session, _ = strconv.Atoi(fields[3])
+	stat.TPGID, _ = strconv.Atoi(fields[5])
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	stat.Ticks = utime + stime
+
+	return stat, true
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProcStat(t *testing.T, procDir string, pid, ppid, pgrp, tpgid int, ticks uint64) {
+	t.Helper()
+	dir := filepath.Join(procDir, fmt.Sprint(pid))
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	stat := fmt.Sprintf("%d (some (weird) cmd) S %d %d %d 34816 %d 4194304 100 0 0 0 %d 0 0 0 20 0 1 0",
+		pid, ppid, pgrp, pgrp, tpgid, ticks)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644))
+}
+
+func TestParseProcStat(t *testing.T) {
+	stat, ok := ParseProcStat("42 (my (cmd)) R 1 42 42 34816 50 4194304 100 0 0 0 7 3 0 0 20 0")
+	require.True(t, ok)
+	assert.Equal(t, 42, stat.PID)
+	assert.Equal(t, 1, stat.PPID)
+	assert.Equal(t, 42, stat.PGRP)
+	assert.Equal(t, 42, stat.Session)
+	assert.Equal(t, 50, stat.TPGID)
+	assert.Equal(t, "my (cmd)", stat.Comm)
+	assert.Equal(t, "R", stat.State)
+	assert.Equal(t, uint64(10), stat.Ticks)
+
+	_, ok = ParseProcStat("garbage")
+	assert.False(t, ok)
+}
+
+func TestProcessTreeInspector_IdleShell(t *testing.T) {
+	procDir := t.TempDir()
+	writeProcStat(t, procDir, 100, 1, 100, 100, 5)
+
+	inspector := NewProcessTreeInspectorWithDir(procDir)
+	activity := inspector.Inspect(100)
+
+	assert.True(t, activity.Supported)
+	assert.Equal(t, 1, activity.Processes)
+	assert.False(t, activity.Busy())
+}
+
+func TestProcessTreeInspector_ForegroundJob(t *testing.T) {
+	procDir := t.TempDir()
+	// Shell 100 has handed the terminal to job 200, which spawned 201
+	writeProcStat(t, procDir, 100, 1, 100, 200, 5)
+	writeProcStat(t, procDir, 200, 100, 200, 200, 0)
+	writeProcStat(t, procDir, 201, 200, 200, 200, 0)
+	// Unrelated process
+	writeProcStat(t, procDir, 300, 1, 300, 0, 1000)
+
+	activity := NewProcessTreeInspectorWithDir(procDir).Inspect(100)
+
+	assert.True(t, activity.ForegroundJob)
+	assert.Equal(t, 3, activity.Processes)
+	assert.True(t, activity.Busy())
+}
+
+func TestProcessTreeInspector_CPUActivity(t *testing.T) {
+	procDir := t.TempDir()
+	writeProcStat(t, procDir, 100, 1, 100, 100, 5)
+	writeProcStat(t, procDir, 150, 100, 150, 100, 0)
+
+	inspector := NewProcessTreeInspectorWithDir(procDir)
+	// First sample only establishes a baseline
+	assert.False(t, inspector.Inspect(100).CPUActive)
+
+	// Background child burned a lot of CPU since the last sample
+	writeProcStat(t, procDir, 150, 100, 150, 100, 100000)
+	activity := inspector.Inspect(100)
+	assert.True(t, activity.CPUActive)
+	assert.True(t, activity.Busy())
+
+	// Missing root resets the baseline
+	assert.False(t, inspector.Inspect(999).Supported)
+}
+
+func TestProcessTreeInspector_Unsupported(t *testing.T) {
+	assert.False(t, NewProcessTreeInspectorWithDir("").Inspect(100).Supported)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	// ProcessTerminationGrace is how long a process tree gets to exit after SIGTERM
+	ProcessTerminationGrace = 3 * time.Second
+	// processKillWait is how long to wait for processes to disappear after SIGKILL
+	processKillWait = time.Second
+	// processPollInterval is how often termination checks for surviving processes
+	processPollInterval = 50 * time.Millisecond
+)
+
+// OrphanedProcess is a process that was still alive after its tree was killed
+type OrphanedProcess struct {
+	PID     int    `json:"pid"`
+	PGRP    int    `json:"pgrp"`
+	State   string `json:"state,omitempty"` // Empty when /proc is unavailable
+	Command string `json:"command,omitempty"`
+}
+
+// ProcessTermination describes how a process tree was shut down
+type ProcessTermination struct {
+	RootPID int `json:"root_pid"`
+	// Processes found in the tree when termination started, including the root
+	Processes int `json:"processes"`
+	// Whether anything outlived the grace period and needed SIGKILL
+	Killed  bool              `json:"killed"`
+	Orphans []OrphanedProcess `json:"orphans,omitempty"`
+}
+
+// Terminate shuts down cmd together with everything it started and reaps it.
+// cmd must lead its own process group, which pty.Start guarantees by making it
+// a session leader. The tree is sent SIGTERM and, if anything is left after
+// grace, SIGKILL. Besides the root's group it signals background jobs that
+// shells moved into their own groups (same session) and descendants that
+// started new sessions, so dev servers launched from a shell don't outlive it
+// holding their ports. Processes that survive even SIGKILL are returned as
+// orphans. Without /proc only the root's process group can be signaled.
+func (i *ProcessTreeInspector) Terminate(cmd *exec.Cmd, grace time.Duration) ProcessTermination {
+	rootPID := cmd.Process.Pid
+	result := ProcessTermination{RootPID: rootPID}
+
+	reaped := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(reaped)
+	}()
+
+	targets := i.treeProcesses(rootPID)
+	result.Processes = len(targets)
+	groups := map[int]bool{rootPID: true}
+	ownGroup := syscall.Getpgrp()
+	for _, stat := range targets {
+		// A root that didn't get its own group would otherwise take catnip down with it
+		if stat.PGRP != ownGroup {
+			groups[stat.PGRP] = true
+		}
+	}
+
+	signal := func(sig syscall.Signal, pids []int) {
+		for pgid := range groups {
+			_ = syscall.Kill(-pgid, sig)
+		}
+		// Catch processes that have since left their group
+		for _, pid := range pids {
+			_ = syscall.Kill(pid, sig)
+		}
+	}
+
+	signal(syscall.SIGTERM, processIDs(targets))
+	survivors := i.waitForExit(rootPID, targets, reaped, grace)
+	if len(survivors) == 0 {
+		return result
+	}
+
+	result.Killed = true
+	signal(syscall.SIGKILL, survivors)
+	for _, pid := range i.waitForExit(rootPID, targets, reaped, processKillWait) {
+		orphan := OrphanedProcess{PID: pid, PGRP: rootPID}
+		if stat, exists := targets[pid]; exists {
+			orphan.PGRP = stat.PGRP
+			orphan.Command = stat.Comm
+			if current, ok := i.readStat(pid); ok {
+				orphan.State = current.State
+			}
+		}
+		result.Orphans = append(result.Orphans, orphan)
+	}
+	return result
+}
+
+// treeProcesses snapshots the processes belonging to the tree rooted at
+// rootPID: its descendants plus, if it leads a session, every process in that
+// session (shell jobs get their own group but stay in the session)
+func (i *ProcessTreeInspector) treeProcesses(rootPID int) map[int]ProcStat {
+	targets := make(map[int]ProcStat)
+	if i.procDir == "" {
+		return targets
+	}
+
+	stats := i.readAllStats()
+	root, exists := stats[rootPID]
+	if !exists {
+		return targets
+	}
+	for _, stat := range collectProcessTree(stats, rootPID) {
+		targets[stat.PID] = stat
+	}
+	// Never widen to a session the root merely belongs to, e.g. catnip's own
+	if root.Session == rootPID {
+		for pid, stat := range stats {
+			if stat.Session == rootPID {
+				targets[pid] = stat
+			}
+		}
+	}
+	return targets
+}
+
+// waitForExit polls until the root is reaped and the targets are gone, or the
+// timeout passes, and returns the PIDs still alive. Zombies count as exited.
+func (i *ProcessTreeInspector) waitForExit(rootPID int, targets map[int]ProcStat, reaped <-chan struct{}, timeout time.Duration) []int {
+	deadline := time.Now().Add(timeout)
+	for {
+		var alive []int
+		select {
+		case <-reaped:
+		default:
+			alive = append(alive, rootPID)
+		}
+
+		if i.procDir == "" {
+			// Without /proc all we can see is whether the root's group still exists
+			if len(alive) == 0 && syscall.Kill(-rootPID, 0) == nil {
+				alive = append(alive, rootPID)
+			}
+		} else {
+			for pid := range targets {
+				if pid == rootPID {
+					continue
+				}
+				if stat, ok := i.readStat(pid); ok && stat.State != "Z" {
+					alive = append(alive, pid)
+				}
+			}
+		}
+
+		if len(alive) == 0 || time.Now().After(deadline) {
+			return alive
+		}
+		time.Sleep(processPollInterval)
+	}
+}
+
+// readStat reads the current stat of a single process
+func (i *ProcessTreeInspector) readStat(pid int) (ProcStat, bool) {
+	data, err := os.ReadFile(filepath.Join(i.procDir, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return ProcStat{}, false
+	}
+	stat, ok := ParseProcStat(string(data))
+	return stat, ok && stat.PID == pid
+}
+
+func processIDs(stats map[int]ProcStat) []int {
+	pids := make([]int, 0, len(stats))
+	for pid := range stats {
+		pids = append(pids, pid)
+	}
+	return pids
+}
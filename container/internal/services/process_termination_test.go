@@ -0,0 +1,96 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startSessionLeader starts a bash script in a new session, as pty.Start does
+func startSessionLeader(t *testing.T, script string) *exec.Cmd {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("process tree termination tests need /proc")
+	}
+	cmd := exec.Command("bash", "-c", script)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	require.NoError(t, cmd.Start())
+	t.Cleanup(func() { _ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) })
+	return cmd
+}
+
+// waitForPIDFile waits for a script to report a background PID
+func waitForPIDFile(t *testing.T, path string) int {
+	t.Helper()
+	var pid int
+	require.Eventually(t, func() bool {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	return pid
+}
+
+func processAlive(pid int) bool {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return false
+	}
+	stat, ok := ParseProcStat(string(data))
+	return ok && stat.State != "Z"
+}
+
+func TestTerminateKillsBackgroundJobs(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// set -m gives the background job its own process group, like an interactive shell
+	cmd := startSessionLeader(t, "set -m; sleep 300 & echo $! > "+pidFile+"; wait")
+	jobPID := waitForPIDFile(t, pidFile)
+
+	inspector := NewProcessTreeInspectorWithDir("/proc")
+	result := inspector.Terminate(cmd, 2*time.Second)
+
+	assert.Equal(t, cmd.Process.Pid, result.RootPID)
+	assert.GreaterOrEqual(t, result.Processes, 2)
+	assert.False(t, result.Killed)
+	assert.Empty(t, result.Orphans)
+	assert.False(t, processAlive(jobPID), "background job outlived its session")
+}
+
+func TestTerminateEscalatesToSIGKILL(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "pid")
+	// Ignored signals are inherited, so the child ignores SIGTERM too
+	cmd := startSessionLeader(t, "trap '' TERM; sleep 300 & echo $! > "+pidFile+"; wait")
+	childPID := waitForPIDFile(t, pidFile)
+
+	inspector := NewProcessTreeInspectorWithDir("/proc")
+	start := time.Now()
+	result := inspector.Terminate(cmd, 200*time.Millisecond)
+
+	assert.True(t, result.Killed)
+	assert.Empty(t, result.Orphans)
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	assert.False(t, processAlive(childPID))
+}
+
+func TestTerminateWithoutProc(t *testing.T) {
+	cmd := startSessionLeader(t, "sleep 300")
+
+	inspector := NewProcessTreeInspectorWithDir("")
+	result := inspector.Terminate(cmd, 2*time.Second)
+
+	assert.Equal(t, 0, result.Processes)
+	assert.False(t, result.Killed)
+	assert.NotNil(t, cmd.ProcessState, "root should be reaped")
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Longest prompt a shared draft may grow to, in characters
+	maxComposerLength = 100_000
+	// Applied ops kept to transform late ops against; clients further behind resync
+	maxComposerHistory = 1000
+	// Messages buffered per participant before a slow one is dropped
+	composerBufferSize = 256
+)
+
+// Composer message types
+const (
+	ComposerSnapshot     = "snapshot"
+	ComposerOpApplied    = "op"
+	ComposerParticipants = "participants"
+	ComposerSubmitted    = "submitted"
+	ComposerError        = "error"
+)
+
+// ComposerOp replaces Delete characters at Pos with Insert. Positions and
+// lengths count Unicode code points.
+type ComposerOp struct {
+	Pos    int    `json:"pos"`
+	Delete int    `json:"delete,omitempty"`
+	Insert string `json:"insert,omitempty"`
+}
+
+// ComposerParticipant is someone connected to a shared draft
+type ComposerParticipant struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`
+}
+
+// ComposerMessage is sent to a draft's participants
+type ComposerMessage struct {
+	Type string `json:"type"`
+	// Draft version after the message
+	Version int `json:"version"`
+	// The participant the snapshot is for, or who applied the op or submitted
+	ClientID     string                `json:"client_id,omitempty"`
+	Text         string                `json:"text,omitempty"`
+	Op           *ComposerOp           `json:"op,omitempty"`
+	Participants []ComposerParticipant `json:"participants,omitempty"`
+	Error        string                `json:"error,omitempty"`
+}
+
+// ComposerStaleError reports an op based on a version too old to transform;
+// the client must resync from a fresh snapshot
+type ComposerStaleError struct {
+	Version int
+	Oldest  int
+}
+
+func (e *ComposerStaleError) Error() string {
+	return fmt.Sprintf("version %d is too old to merge (oldest is %d), resync", e.Version, e.Oldest)
+}
+
+type composerDraft struct {
+	text    []rune
+	version int
+	// history[i] took the draft from version historyBase+i to historyBase+i+1
+	history      []ComposerOp
+	historyBase  int
+	participants []ComposerParticipant
+	channels     map[string]chan ComposerMessage
+}
+
+// PromptComposer holds a shared prompt draft per PTY session so several
+// people can write a prompt together before it is sent to Claude.
+//
+// Concurrent edits are merged with operational transformation around a
+// single authority: each op names the draft version it was made against,
+// and is transformed past every op applied since before it is applied and
+// broadcast, with the new version, to all participants (its author takes
+// that as the acknowledgement). Clients transform incoming ops against
+// their own unacknowledged ones, letting the op already applied here win
+// ties at the same position.
+type PromptComposer struct {
+	mu     sync.Mutex
+	drafts map[string]*composerDraft
+}
+
+// NewPromptComposer creates a composer without drafts
+func NewPromptComposer() *PromptComposer {
+	return &PromptComposer{drafts: make(map[string]*composerDraft)}
+}
+
+// Join adds a participant to a session's draft. The first message on the
+// returned channel is a snapshot of the draft; the channel is closed when
+// the participant leaves or falls too far behind. Call leave when done.
+func (c *PromptComposer) Join(sessionID, name string) (clientID string, messages <-chan ComposerMessage, leave func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	draft, exists := c.drafts[sessionID]
+	if !exists {
+		draft = &composerDraft{channels: make(map[string]chan ComposerMessage)}
+		c.drafts[sessionID] = draft
+	}
+
+	clientID = uuid.New().String()[:8]
+	if name == "" {
+		name = "guest-" + clientID[:4]
+	}
+	ch := make(chan ComposerMessage, composerBufferSize)
+	ch <- ComposerMessage{Type: ComposerSnapshot, Version: draft.version, ClientID: clientID, Text: string(draft.text)}
+	draft.channels[clientID] = ch
+	draft.participants = append(draft.participants, ComposerParticipant{ClientID: clientID, Name: name})
+	c.broadcastParticipants(sessionID, draft)
+
+	return clientID, ch, func() { c.leave(sessionID, clientID) }
+}
+
+func (c *PromptComposer) leave(sessionID, clientID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	draft, exists := c.drafts[sessionID]
+	if !exists {
+		return
+	}
+	c.drop(draft, clientID)
+	c.broadcastParticipants(sessionID, draft)
+}
+
+// drop disconnects a participant. The caller must hold mu.
+func (c *PromptComposer) drop(draft *composerDraft, clientID string) {
+	ch, exists := draft.channels[clientID]
+	if !exists {
+		return
+	}
+	close(ch)
+	delete(draft.channels, clientID)
+	for i, participant := range draft.participants {
+		if participant.ClientID == clientID {
+			draft.participants = append(draft.participants[:i], draft.participants[i+1:]...)
+			break
+		}
+	}
+}
+
+// Snapshot returns a session's draft and its version
+func (c *PromptComposer) Snapshot(sessionID string) (string, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	draft, exists := c.drafts[sessionID]
+	if !exists {
+		return "", 0
+	}
+	return string(draft.text), draft.version
+}
+
+// Apply merges an op a participant made against version into the draft and
+// broadcasts it. Ops based on versions no longer in history fail with
+// ComposerStaleError.
+func (c *PromptComposer) Apply(sessionID, clientID string, version int, op ComposerOp) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	draft, exists := c.drafts[sessionID]
+	if !exists || draft.channels[clientID] == nil {
+		return fmt.Errorf("not a participant of %s", sessionID)
+	}
+	if version > draft.version || version < 0 {
+		return fmt.Errorf("unknown version %d (current is %d)", version, draft.version)
+	}
+	if version < draft.historyBase {
+		return &ComposerStaleError{Version: version, Oldest: draft.historyBase}
+	}
+	if op.Pos < 0 || op.Delete < 0 {
+		return fmt.Errorf("invalid op at %d deleting %d", op.Pos, op.Delete)
+	}
+
+	for _, applied := range draft.history[version-draft.historyBase:] {
+		op = transformComposerOp(op, applied)
+	}
+	if op.Pos+op.Delete > len(draft.text) {
+		return fmt.Errorf("op at %d deleting %d is past the end of the draft (%d)", op.Pos, op.Delete, len(draft.text))
+	}
+	insert := []rune(op.Insert)
+	if len(draft.text)-op.Delete+len(insert) > maxComposerLength {
+		return fmt.Errorf("prompts are limited to %d characters", maxComposerLength)
+	}
+	if op.Delete == 0 && len(insert) == 0 {
+		return nil
+	}
+
+	text := make([]rune, 0, len(draft.text)-op.Delete+len(insert))
+	text = append(text, draft.text[:op.Pos]...)
+	text = append(text, insert...)
+	draft.text = append(text, draft.text[op.Pos+op.Delete:]...)
+	c.record(draft, op)
+	c.broadcast(sessionID, draft, ComposerMessage{Type: ComposerOpApplied, Version: draft.version, ClientID: clientID, Op: &op})
+	return nil
+}
+
+// Submit sends the draft with send and, if that succeeds, clears it for the
+// next prompt. No edit can land between the two.
+func (c *PromptComposer) Submit(sessionID, clientID string, send func(prompt string) error) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	draft, exists := c.drafts[sessionID]
+	if !exists || draft.channels[clientID] == nil {
+		return "", fmt.Errorf("not a participant of %s", sessionID)
+	}
+	prompt := string(draft.text)
+	if prompt == "" {
+		return "", fmt.Errorf("the draft is empty")
+	}
+	if err := send(prompt); err != nil {
+		return "", err
+	}
+
+	c.record(draft, ComposerOp{Pos: 0, Delete: len(draft.text)})
+	draft.text = nil
+	c.broadcast(sessionID, draft, ComposerMessage{Type: ComposerSubmitted, Version: draft.version, ClientID: clientID, Text: prompt})
+	return prompt, nil
+}
+
+// record appends an applied op to the history. The caller must hold mu.
+func (c *PromptComposer) record(draft *composerDraft, op ComposerOp) {
+	draft.history = append(draft.history, op)
+	draft.version++
+	if len(draft.history) > maxComposerHistory {
+		trim := len(draft.history) - maxComposerHistory
+		draft.history = append([]ComposerOp(nil), draft.history[trim:]...)
+		draft.historyBase += trim
+	}
+}
+
+// broadcast sends a message to every participant, dropping those whose
+// buffer is full so one stalled client can't hold up the others. Drafts
+// nobody is connected to are forgotten once empty. The caller must hold mu.
+func (c *PromptComposer) broadcast(sessionID string, draft *composerDraft, msg ComposerMessage) {
+	var stalled []string
+	for clientID, ch := range draft.channels {
+		select {
+		case ch <- msg:
+		default:
+			stalled = append(stalled, clientID)
+		}
+	}
+	for _, clientID := range stalled {
+		c.drop(draft, clientID)
+	}
+	if len(stalled) > 0 {
+		c.broadcastParticipants(sessionID, draft)
+	}
+	if len(draft.channels) == 0 && len(draft.text) == 0 {
+		delete(c.drafts, sessionID)
+	}
+}
+
+// broadcastParticipants tells participants who is connected. The caller must hold mu.
+func (c *PromptComposer) broadcastParticipants(sessionID string, draft *composerDraft) {
+	participants := append([]ComposerParticipant{}, draft.participants...)
+	c.broadcast(sessionID, draft, ComposerMessage{Type: ComposerParticipants, Version: draft.version, Participants: participants})
+}
+
+// transformComposerOp rewrites op, made concurrently with applied, to apply
+// after it. Text applied inserted where op starts stays before op, and
+// whatever op deletes that applied already deleted is left alone.
+func transformComposerOp(op, applied ComposerOp) ComposerOp {
+	start := mapComposerPos(op.Pos, applied, true)
+	end := mapComposerPos(op.Pos+op.Delete, applied, false)
+	if end < start {
+		end = start
+	}
+	op.Pos, op.Delete = start, end-start
+	return op
+}
+
+// mapComposerPos moves a position in the draft before applied to the draft
+// after it. A position at applied's insertion moves past the inserted text
+// when after is set.
+func mapComposerPos(pos int, applied ComposerOp, after bool) int {
+	inserted := utf8.RuneCountInString(applied.Insert)
+	switch {
+	case pos < applied.Pos || (pos == applied.Pos && !after):
+		return pos
+	case pos >= applied.Pos+applied.Delete && pos > applied.Pos:
+		return pos - applied.Delete + inserted
+	case after:
+		return applied.Pos + inserted
+	default:
+		return applied.Pos
+	}
+}
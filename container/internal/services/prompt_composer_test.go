@@ -0,0 +1,116 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nextComposerMessage(t *testing.T, messages <-chan ComposerMessage, msgType string) ComposerMessage {
+	t.Helper()
+	for {
+		select {
+		case msg := <-messages:
+			if msg.Type == msgType {
+				return msg
+			}
+		default:
+			t.Fatalf("no %s message", msgType)
+		}
+	}
+}
+
+func TestPromptComposerMergesConcurrentEdits(t *testing.T) {
+	composer := NewPromptComposer()
+	felix, felixMessages, leaveFelix := composer.Join("app/felix:claude", "felix")
+	defer leaveFelix()
+	snapshot := nextComposerMessage(t, felixMessages, ComposerSnapshot)
+	assert.Equal(t, felix, snapshot.ClientID)
+	assert.Equal(t, 0, snapshot.Version)
+
+	require.NoError(t, composer.Apply("app/felix:claude", felix, 0, ComposerOp{Pos: 0, Insert: "fix the tests"}))
+
+	luna, lunaMessages, leaveLuna := composer.Join("app/felix:claude", "luna")
+	defer leaveLuna()
+	snapshot = nextComposerMessage(t, lunaMessages, ComposerSnapshot)
+	assert.Equal(t, "fix the tests", snapshot.Text)
+	assert.Equal(t, 1, snapshot.Version)
+	participants := nextComposerMessage(t, lunaMessages, ComposerParticipants)
+	assert.Len(t, participants.Participants, 2)
+
+	// Both edit version 1 at once: felix replaces "the", luna appends
+	require.NoError(t, composer.Apply("app/felix:claude", felix, 1, ComposerOp{Pos: 4, Delete: 3, Insert: "the flaky"}))
+	require.NoError(t, composer.Apply("app/felix:claude", luna, 1, ComposerOp{Pos: 13, Insert: " in ci"}))
+	op := nextComposerMessage(t, lunaMessages, ComposerOpApplied)
+	assert.Equal(t, felix, op.ClientID)
+	op = nextComposerMessage(t, lunaMessages, ComposerOpApplied)
+	assert.Equal(t, luna, op.ClientID)
+	assert.Equal(t, ComposerOp{Pos: 19, Insert: " in ci"}, *op.Op, "transformed past felix's edit")
+	assert.Equal(t, 3, op.Version)
+
+	// Concurrent inserts at one position keep the order they arrived in, and
+	// deleting text someone else already deleted is a no-op
+	require.NoError(t, composer.Apply("app/felix:claude", felix, 3, ComposerOp{Pos: 0, Insert: "please "}))
+	require.NoError(t, composer.Apply("app/felix:claude", luna, 3, ComposerOp{Pos: 0, Insert: "now "}))
+	require.NoError(t, composer.Apply("app/felix:claude", luna, 3, ComposerOp{Pos: 4, Delete: 4}))
+	text, version := composer.Snapshot("app/felix:claude")
+	assert.Equal(t, "please now fix flaky tests in ci", text)
+	assert.Equal(t, 6, version)
+	require.NoError(t, composer.Apply("app/felix:claude", felix, 5, ComposerOp{Pos: 15, Delete: 10}))
+	text, _ = composer.Snapshot("app/felix:claude")
+	assert.Equal(t, "please now fix tests in ci", text)
+
+	assert.Error(t, composer.Apply("app/felix:claude", felix, 99, ComposerOp{Insert: "x"}))
+	assert.Error(t, composer.Apply("app/felix:claude", "stranger", 0, ComposerOp{Insert: "x"}))
+
+	// Submitting sends the draft and clears it for everyone
+	var sent string
+	_, err := composer.Submit("app/felix:claude", luna, func(prompt string) error { return errors.New("no terminal") })
+	assert.Error(t, err)
+	prompt, err := composer.Submit("app/felix:claude", luna, func(prompt string) error { sent = prompt; return nil })
+	require.NoError(t, err)
+	assert.Equal(t, "please now fix tests in ci", prompt)
+	assert.Equal(t, prompt, sent)
+	submitted := nextComposerMessage(t, felixMessages, ComposerSubmitted)
+	assert.Equal(t, luna, submitted.ClientID)
+	text, _ = composer.Snapshot("app/felix:claude")
+	assert.Empty(t, text)
+}
+
+func TestPromptComposerResyncsStaleClients(t *testing.T) {
+	composer := NewPromptComposer()
+	felix, felixMessages, leave := composer.Join("app/felix:claude", "felix")
+	apply := func(version int, op ComposerOp) error {
+		err := composer.Apply("app/felix:claude", felix, version, op)
+		for len(felixMessages) > 0 {
+			<-felixMessages
+		}
+		return err
+	}
+
+	for i := 0; i <= maxComposerHistory; i++ {
+		require.NoError(t, apply(i, ComposerOp{Pos: i, Insert: "a"}))
+	}
+	var stale *ComposerStaleError
+	require.ErrorAs(t, apply(0, ComposerOp{Insert: "b"}), &stale)
+	assert.Equal(t, 1, stale.Oldest)
+
+	// A participant that stopped reading is dropped instead of blocking others
+	_, stalled, leaveStalled := composer.Join("app/felix:claude", "luna")
+	defer leaveStalled()
+	for i := 0; i < composerBufferSize; i++ {
+		require.NoError(t, apply(maxComposerHistory+1+i, ComposerOp{Insert: "c"}))
+	}
+	drained := 0
+	for range stalled {
+		drained++
+	}
+	assert.Equal(t, composerBufferSize, drained, "the channel is closed once full")
+
+	// The draft outlives its participants until it's submitted
+	leave()
+	text, _ := composer.Snapshot("app/felix:claude")
+	assert.NotEmpty(t, text)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	defaultPromptMaxChars = 50000
+	// Missing file findings reported per prompt
+	maxPromptMissingFiles = 20
+
+	promptLintOff = "off"
+)
+
+// Prompt lint check names
+const (
+	PromptCheckMissingFile = "missing_file"
+	PromptCheckLength      = "length"
+	PromptCheckSecret      = "secret"
+)
+
+// PromptLintConfig configures pre-flight prompt checks. Each check is "warn",
+// "block" or "off".
+type PromptLintConfig struct {
+	// Files referenced in the prompt that don't exist (default warn)
+	MissingFiles string `json:"missing_files,omitempty" yaml:"missing_files"`
+	// Prompts longer than max_chars (default warn)
+	Length string `json:"length,omitempty" yaml:"length"`
+	// Prompts containing API keys, tokens or private keys (default block)
+	Secrets string `json:"secrets,omitempty" yaml:"secrets"`
+	// Length threshold in characters (default 50000)
+	MaxChars int `json:"max_chars,omitempty" yaml:"max_chars"`
+}
+
+func (c PromptLintConfig) withDefaults() PromptLintConfig {
+	if c.MissingFiles == "" {
+		c.MissingFiles = string(models.PromptLintWarn)
+	}
+	if c.Length == "" {
+		c.Length = string(models.PromptLintWarn)
+	}
+	if c.Secrets == "" {
+		c.Secrets = string(models.PromptLintBlock)
+	}
+	if c.MaxChars <= 0 {
+		c.MaxChars = defaultPromptMaxChars
+	}
+	return c
+}
+
+var promptSecretPatterns = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"Anthropic API key", regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"OpenAI API key", regexp.MustCompile(`sk-(?:proj-)?[A-Za-z0-9]{32,}`)},
+	{"GitHub token", regexp.MustCompile(`(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,})`)},
+	{"AWS access key", regexp.MustCompile(`(?:AKIA|ASIA)[0-9A-Z]{16}`)},
+	{"Slack token", regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----`)},
+	{"credential assignment", regexp.MustCompile(`(?i)\b(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token)\s*[:=]\s*["']?[^\s"']{12,}`)},
+}
+
+// promptPathPattern matches tokens shaped like file paths with an extension
+var promptPathPattern = regexp.MustCompile(`^(?:\.{1,2}/|/)?(?:[\w.-]+/)*[\w-][\w.-]*\.[A-Za-z][A-Za-z0-9]{0,7}$`)
+
+// promptLineSuffix strips ":12" or ":12:3" location suffixes from paths
+var promptLineSuffix = regexp.MustCompile(`(?::\d+){1,2}$`)
+
+// Extensions treated as file references even without a directory in the path
+var promptFileExtensions = map[string]bool{
+	"go": true, "ts": true, "tsx": true, "js": true, "jsx": true, "mjs": true, "py": true,
+	"rs": true, "rb": true, "java": true, "kt": true, "swift": true, "c": true, "h": true,
+	"cpp": true, "cs": true, "md": true, "json": true, "yaml": true, "yml": true, "toml": true,
+	"sh": true, "css": true, "scss": true, "html": true, "sql": true, "vue": true, "svelte": true,
+	"proto": true, "txt": true, "lock": true,
+}
+
+// PromptLinter checks prompts before they're sent to Claude
+type PromptLinter struct {
+	operations git.Operations
+}
+
+// NewPromptLinter creates a prompt linter
+func NewPromptLinter(operations git.Operations) *PromptLinter {
+	return &PromptLinter{operations: operations}
+}
+
+// Lint runs the checks configured in the working directory's .catnip.yaml
+func (l *PromptLinter) Lint(prompt, workingDir string) *models.PromptLintResponse {
+	if workingDir == "" {
+		workingDir = filepath.Join(config.Runtime.WorkspaceDir, "current")
+	} else {
+		workingDir = config.Runtime.ResolvePath(workingDir)
+	}
+
+	var cfg PromptLintConfig
+	if catnipConfig, err := LoadCatnipConfig(workingDir); err != nil {
+		logger.Warnf("⚠️ Using default prompt lint settings: %v", err)
+	} else {
+		cfg = catnipConfig.PromptLint
+	}
+	return l.LintWithConfig(prompt, workingDir, cfg)
+}
+
+// LintWithConfig runs the checks with an explicit configuration
+func (l *PromptLinter) LintWithConfig(prompt, workingDir string, cfg PromptLintConfig) *models.PromptLintResponse {
+	cfg = cfg.withDefaults()
+	result := &models.PromptLintResponse{Warnings: []models.PromptLintWarning{}}
+	add := func(action string, warning models.PromptLintWarning) {
+		warning.Severity = models.PromptLintSeverity(action)
+		if warning.Severity == models.PromptLintBlock {
+			result.Blocked = true
+		}
+		result.Warnings = append(result.Warnings, warning)
+	}
+
+	if cfg.Length != promptLintOff {
+		if chars := utf8.RuneCountInString(prompt); chars > cfg.MaxChars {
+			add(cfg.Length, models.PromptLintWarning{
+				Check:   PromptCheckLength,
+				Message: fmt.Sprintf("Prompt is %d characters, over the %d character limit", chars, cfg.MaxChars),
+			})
+		}
+	}
+
+	if cfg.Secrets != promptLintOff {
+		for _, secret := range promptSecretPatterns {
+			for _, match := range secret.pattern.FindAllString(prompt, -1) {
+				add(cfg.Secrets, models.PromptLintWarning{
+					Check:   PromptCheckSecret,
+					Message: fmt.Sprintf("Prompt appears to contain a %s", secret.name),
+					Match:   redactSecret(match),
+				})
+			}
+		}
+	}
+
+	if cfg.MissingFiles != promptLintOff && workingDir != "" {
+		for _, path := range l.missingFiles(prompt, workingDir) {
+			add(cfg.MissingFiles, models.PromptLintWarning{
+				Check:   PromptCheckMissingFile,
+				Message: fmt.Sprintf("%s does not exist in the worktree", path),
+				Match:   path,
+			})
+		}
+	}
+
+	return result
+}
+
+// promptFileReferences extracts tokens that look like file paths
+func promptFileReferences(prompt string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, token := range strings.Fields(prompt) {
+		token = strings.Trim(token, "\"'`()[]{}<>,;!?*")
+		mention := strings.HasPrefix(token, "@")
+		token = strings.TrimPrefix(token, "@")
+		token = strings.TrimRight(token, ".:")
+		token = promptLineSuffix.ReplaceAllString(token, "")
+		if token == "" || strings.Contains(token, "://") || !promptPathPattern.MatchString(token) {
+			continue
+		}
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(token), "."))
+		if !mention && !strings.Contains(token, "/") && !promptFileExtensions[ext] {
+			continue
+		}
+		if !seen[token] {
+			seen[token] = true
+			refs = append(refs, token)
+		}
+	}
+	return refs
+}
+
+// missingFiles returns referenced paths that exist neither relative to the
+// working directory nor as the suffix of any file in the repository
+func (l *PromptLinter) missingFiles(prompt, workingDir string) []string {
+	refs := promptFileReferences(prompt)
+	if len(refs) == 0 {
+		return nil
+	}
+	if _, err := os.Stat(workingDir); err != nil {
+		return nil
+	}
+
+	var files []string
+	filesLoaded := false
+	var missing []string
+	for _, ref := range refs {
+		path := ref
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, ref)
+		}
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if filepath.IsAbs(ref) {
+			missing = append(missing, ref)
+		} else {
+			if !filesLoaded {
+				files = l.repositoryFiles(workingDir)
+				filesLoaded = true
+			}
+			if !hasPathSuffix(files, filepath.Clean(ref)) {
+				missing = append(missing, ref)
+			}
+		}
+		if len(missing) >= maxPromptMissingFiles {
+			break
+		}
+	}
+	return missing
+}
+
+func (l *PromptLinter) repositoryFiles(workingDir string) []string {
+	if l.operations == nil {
+		return nil
+	}
+	output, err := l.operations.ExecuteGit(workingDir, "ls-files", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSpace(string(output)), "\n")
+}
+
+func hasPathSuffix(files []string, ref string) bool {
+	for _, file := range files {
+		if file == ref || strings.HasSuffix(file, "/"+ref) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactSecret(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:4] + "…" + strings.Repeat("*", 4)
+}
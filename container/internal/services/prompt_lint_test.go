@@ -0,0 +1,83 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func lintChecks(result *models.PromptLintResponse, check string) []models.PromptLintWarning {
+	var warnings []models.PromptLintWarning
+	for _, warning := range result.Warnings {
+		if warning.Check == check {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
+func TestPromptFileReferences(t *testing.T) {
+	refs := promptFileReferences("Look at @src/app.tsx:12, `main.go` and ./docs/guide.md. " +
+		"Call fmt.Println, see https://example.com/a.go, e.g. v1.2.3 or internal/handlers")
+	assert.Equal(t, []string{"src/app.tsx", "main.go", "./docs/guide.md"}, refs)
+}
+
+func TestPromptLinterMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "internal", "services"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "internal", "services", "git.go"), []byte("package services\n"), 0644))
+
+	linter := NewPromptLinter(git.NewOperations())
+	result := linter.LintWithConfig("Fix main.go, services/git.go and git.go but not old/legacy.go or gone.py", dir, PromptLintConfig{})
+
+	missing := lintChecks(result, PromptCheckMissingFile)
+	require.Len(t, missing, 2)
+	assert.Equal(t, "old/legacy.go", missing[0].Match)
+	assert.Equal(t, "gone.py", missing[1].Match)
+	assert.Equal(t, models.PromptLintWarn, missing[0].Severity)
+	assert.False(t, result.Blocked)
+
+	result = linter.LintWithConfig("Fix gone.py", dir, PromptLintConfig{MissingFiles: "off"})
+	assert.Empty(t, result.Warnings)
+}
+
+func TestPromptLinterSecretsAndLength(t *testing.T) {
+	linter := NewPromptLinter(nil)
+	dir := t.TempDir()
+
+	result := linter.LintWithConfig("Use ghp_"+strings.Repeat("a", 36)+" to push", dir, PromptLintConfig{})
+	secrets := lintChecks(result, PromptCheckSecret)
+	require.Len(t, secrets, 1)
+	assert.True(t, result.Blocked)
+	assert.Equal(t, models.PromptLintBlock, secrets[0].Severity)
+	assert.NotContains(t, secrets[0].Match, strings.Repeat("a", 10))
+
+	result = linter.LintWithConfig("password = hunter2hunter2hunter2", dir, PromptLintConfig{Secrets: "warn"})
+	assert.Len(t, lintChecks(result, PromptCheckSecret), 1)
+	assert.False(t, result.Blocked)
+
+	result = linter.LintWithConfig(strings.Repeat("x", 101), dir, PromptLintConfig{MaxChars: 100, Length: "block"})
+	assert.Len(t, lintChecks(result, PromptCheckLength), 1)
+	assert.True(t, result.Blocked)
+
+	result = linter.LintWithConfig("Explain how the retry loop works", dir, PromptLintConfig{})
+	assert.Empty(t, result.Warnings)
+	assert.False(t, result.Blocked)
+}
+
+func TestPromptLinterReadsCatnipConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte("prompt_lint:\n  max_chars: 10\n  length: block\n"), 0644))
+
+	result := NewPromptLinter(nil).Lint("this prompt is too long", dir)
+	assert.True(t, result.Blocked)
+	assert.Len(t, lintChecks(result, PromptCheckLength), 1)
+}
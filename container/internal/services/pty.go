@@ -17,6 +17,8 @@ import (
 type PTYService struct {
 	sessions     map[string]*SetupSession
 	sessionMutex sync.RWMutex
+	// Extra environment for setup.sh in a worktree
+	setupEnv func(workDir string) []string
 }
 
 // SetupSession represents a PTY session used for setup script execution
@@ -28,6 +30,19 @@ type SetupSession struct {
 	CreatedAt   time.Time
 	Buffer      []byte
 	BufferMutex sync.RWMutex
+
+	// Outcome of setup.sh, set when it exits
+	resultMutex sync.Mutex
+	finishedAt  *time.Time
+	failure     string
+}
+
+// SetupFailure is a worktree whose most recent setup.sh run failed
+type SetupFailure struct {
+	WorkDir    string    `json:"work_dir"`
+	SessionID  string    `json:"session_id"`
+	Error      string    `json:"error"`
+	FinishedAt time.Time `json:"finished_at"`
 }
 
 // NewPTYService creates a new PTY service instance
@@ -37,37 +52,88 @@ func NewPTYService() *PTYService {
 	}
 }
 
+// WithSetupEnv adds environment variables, looked up by worktree path, to
+// setup.sh runs
+func (s *PTYService) WithSetupEnv(setupEnv func(workDir string) []string) *PTYService {
+	s.setupEnv = setupEnv
+	return s
+}
+
 // ExecuteSetupScript checks for and executes setup.sh in a worktree's PTY session
 func (s *PTYService) ExecuteSetupScript(worktreePath string) {
-	setupScriptPath := filepath.Join(worktreePath, "setup.sh")
+	compositeSessionID, ok := s.setupSessionID(worktreePath)
+	if !ok {
+		return
+	}
 
-	// Check if setup.sh exists and is executable
-	if _, err := os.Stat(setupScriptPath); os.IsNotExist(err) {
-		logger.Debugf("📄 No setup.sh found in %s, skipping setup", worktreePath)
+	// Create or get existing session for this worktree
+	session := s.getOrCreateSetupSession(compositeSessionID, worktreePath)
+	if session == nil {
+		logger.Errorf("❌ Failed to create/get session for setup.sh execution: %s", compositeSessionID)
 		return
 	}
 
-	logger.Debugf("🔧 Found setup.sh in %s, executing in terminal", worktreePath)
+	logger.Debugf("✅ Started setup.sh execution in PTY session %s for worktree %s", compositeSessionID, worktreePath)
+}
+
+// RerunSetupScript replaces a worktree's setup session with a fresh run of
+// setup.sh, stopping the previous run if it is still going
+func (s *PTYService) RerunSetupScript(worktreePath string) error {
+	compositeSessionID, ok := s.setupSessionID(worktreePath)
+	if !ok {
+		return fmt.Errorf("no setup.sh or devcontainer.json lifecycle commands found in %s", worktreePath)
+	}
+
+	s.CleanupSession(compositeSessionID)
+	if session := s.getOrCreateSetupSession(compositeSessionID, worktreePath); session == nil {
+		return fmt.Errorf("failed to start setup session %s", compositeSessionID)
+	}
+
+	logger.Infof("🔁 Re-running setup.sh in PTY session %s", compositeSessionID)
+	return nil
+}
+
+// setupCommand returns the bash command that sets up a worktree: its
+// setup.sh, or else the lifecycle commands from its devcontainer.json
+func setupCommand(worktreePath string) (string, bool) {
+	if _, err := os.Stat(filepath.Join(worktreePath, "setup.sh")); err == nil {
+		return "chmod +x setup.sh && echo '🔧 Running setup.sh...' && ./setup.sh && echo '\n✅ Setup completed'", true
+	}
+	script, source := devcontainerSetupScript(worktreePath)
+	if script == "" {
+		return "", false
+	}
+	// A separate bash so set -e in the script isn't ignored by the && chain
+	return fmt.Sprintf("echo %s && bash -c %s && echo '\n✅ Setup completed'",
+		shellQuote("🔧 Running lifecycle commands from "+source+"..."), shellQuote(script)), true
+}
+
+// hasSetupCommand reports whether a worktree has setup to run
+func hasSetupCommand(worktreePath string) bool {
+	_, ok := setupCommand(worktreePath)
+	return ok
+}
+
+// setupSessionID returns the setup session ID for a worktree with a setup.sh
+// or devcontainer.json lifecycle commands
+func (s *PTYService) setupSessionID(worktreePath string) (string, bool) {
+	if !hasSetupCommand(worktreePath) {
+		logger.Debugf("📄 No setup.sh or devcontainer.json lifecycle commands found in %s, skipping setup", worktreePath)
+		return "", false
+	}
+
+	logger.Debugf("🔧 Found setup for %s, executing in terminal", worktreePath)
 
 	// Extract workspace name from worktree path for session ID
 	// Format: workspace/repo/branch -> repo/branch
 	parts := strings.Split(strings.TrimPrefix(worktreePath, config.Runtime.WorkspaceDir+"/"), "/")
 	if len(parts) < 2 {
 		logger.Warnf("⚠️ Cannot determine session ID from worktree path: %s", worktreePath)
-		return
+		return "", false
 	}
 	sessionID := strings.Join(parts, "/")
 	// Add :setup suffix to match the composite session ID used in PTYHandler
-	compositeSessionID := fmt.Sprintf("%s:setup", sessionID)
-
-	// Create or get existing session for this worktree
-	session := s.getOrCreateSetupSession(compositeSessionID, worktreePath)
-	if session == nil {
-		logger.Errorf("❌ Failed to create/get session for setup.sh execution: %s", compositeSessionID)
-		return
-	}
-
-	logger.Debugf("✅ Started setup.sh execution in PTY session %s for worktree %s", compositeSessionID, worktreePath)
+	return fmt.Sprintf("%s:setup", sessionID), true
 }
 
 // getOrCreateSetupSession creates or retrieves a setup session for the given session ID
@@ -101,8 +167,15 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 		return nil
 	}
 
+	command, ok := setupCommand(workDir)
+	if !ok {
+		logFile.Close()
+		logger.Warnf("⚠️ Setup for %s disappeared before it could start", workDir)
+		return nil
+	}
+
 	// Create command to run setup script and capture output to file
-	cmd := exec.Command("bash", "-c", "chmod +x setup.sh && echo '🔧 Running setup.sh...' && ./setup.sh && echo '\n✅ Setup completed'")
+	cmd := exec.Command("bash", "-c", command)
 	// Set environment for setup script execution
 	cmd.Env = append(os.Environ(),
 		fmt.Sprintf("SESSION_ID=%s", sessionID),
@@ -110,6 +183,9 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 		"TERM=xterm-direct",
 		"COLORTERM=truecolor",
 	)
+	if s.setupEnv != nil {
+		cmd.Env = append(cmd.Env, s.setupEnv(workDir)...)
+	}
 	cmd.Dir = workDir
 	cmd.Stdout = logFile
 	cmd.Stderr = logFile
@@ -122,7 +198,9 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 		defer logFile.Close()
 
 		logger.Debugf("🔧 Starting setup script execution for session: %s", sessionID)
-		if err := cmd.Run(); err != nil {
+		err := cmd.Run()
+		session.recordResult(err)
+		if err != nil {
 			logger.Errorf("❌ Setup script failed for session %s: %v", sessionID, err)
 			// Write error to log file
 			if _, writeErr := fmt.Fprintf(logFile, "\n❌ Setup script failed: %v\n", err); writeErr != nil {
@@ -140,6 +218,78 @@ func (s *PTYService) getOrCreateSetupSession(sessionID, workDir string) *SetupSe
 	return session
 }
 
+func (session *SetupSession) recordResult(err error) {
+	session.resultMutex.Lock()
+	defer session.resultMutex.Unlock()
+	now := time.Now()
+	session.finishedAt = &now
+	session.failure = ""
+	if err != nil {
+		session.failure = err.Error()
+	}
+}
+
+// SetupFailures returns the worktrees whose latest setup.sh run failed
+func (s *PTYService) SetupFailures() []SetupFailure {
+	s.sessionMutex.RLock()
+	defer s.sessionMutex.RUnlock()
+
+	failures := []SetupFailure{}
+	for id, session := range s.sessions {
+		session.resultMutex.Lock()
+		if session.finishedAt != nil && session.failure != "" {
+			failures = append(failures, SetupFailure{
+				WorkDir:    session.WorkDir,
+				SessionID:  id,
+				Error:      session.failure,
+				FinishedAt: *session.finishedAt,
+			})
+		}
+		session.resultMutex.Unlock()
+	}
+	return failures
+}
+
+// Setup states reported by SetupState
+const (
+	SetupStateNone      = "none"
+	SetupStatePending   = "pending"
+	SetupStateRunning   = "running"
+	SetupStateSucceeded = "succeeded"
+	SetupStateFailed    = "failed"
+)
+
+// SetupState reports how the latest setup run in a worktree went. Worktrees
+// with setup that hasn't started yet are pending; those without setup are none.
+func (s *PTYService) SetupState(workDir string) string {
+	s.sessionMutex.RLock()
+	var session *SetupSession
+	for _, candidate := range s.sessions {
+		if candidate.WorkDir == workDir {
+			session = candidate
+			break
+		}
+	}
+	s.sessionMutex.RUnlock()
+
+	if session == nil {
+		if hasSetupCommand(workDir) {
+			return SetupStatePending
+		}
+		return SetupStateNone
+	}
+	session.resultMutex.Lock()
+	defer session.resultMutex.Unlock()
+	switch {
+	case session.finishedAt == nil:
+		return SetupStateRunning
+	case session.failure != "":
+		return SetupStateFailed
+	default:
+		return SetupStateSucceeded
+	}
+}
+
 // GetSetupSession retrieves a setup session by ID
 func (s *PTYService) GetSetupSession(sessionID string) (*SetupSession, bool) {
 	s.sessionMutex.RLock()
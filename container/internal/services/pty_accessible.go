@@ -0,0 +1,296 @@
+package services
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PTY output modes a terminal connection can negotiate
+const (
+	// PTYOutputRaw sends the PTY byte stream unchanged (the default)
+	PTYOutputRaw = "raw"
+	// PTYOutputAccessible sends AccessibleEvents suited to screen readers
+	PTYOutputAccessible = "accessible"
+)
+
+// Kinds of AccessibleEvent
+const (
+	// AccessibleText is a new line of output
+	AccessibleText = "text"
+	// AccessibleUpdate is a line that appeared while a full-screen program redrew
+	AccessibleUpdate = "update"
+	// AccessiblePrompt is output waiting for input (a shell prompt or a question)
+	AccessiblePrompt = "prompt"
+	// AccessibleTitle is a new terminal title
+	AccessibleTitle = "title"
+	// AccessibleAlert is a terminal bell
+	AccessibleAlert = "alert"
+	// AccessibleScreen announces entering or leaving a full-screen program
+	AccessibleScreen = "screen"
+)
+
+const (
+	// Announced lines remembered so redraws don't repeat them
+	accessibleRecentLines = 256
+	// Longest partial line held back waiting for its newline
+	maxAccessiblePending = 4096
+)
+
+// AccessibleEvent is one announcement for a screen reader
+type AccessibleEvent struct {
+	Type string `json:"type"` // Always "a11y"
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+var (
+	// Prompts end with a shell prompt character or ask a question
+	accessiblePromptPattern = regexp.MustCompile(`(?i)([$#%>❯›»]|\?|:|\(y/n\)|\[y/n\]|\[Y/n\]|\[y/N\])\s*$`)
+	// Lines of only borders, spinners and punctuation carry nothing to read
+	accessibleDecorationPattern = regexp.MustCompile(`^[\s\p{P}\p{S}\x{2500}-\x{259F}\x{2800}-\x{28FF}]*$`)
+)
+
+// AccessibleTransformer turns a PTY byte stream into AccessibleEvents:
+// escape sequences are stripped, cursor-addressed redraws only announce lines
+// that weren't read out already, and prompts, titles and bells are announced
+// on their own. A transformer keeps state across reads, so each connection
+// needs its own.
+type AccessibleTransformer struct {
+	pending    []byte // Output after the last newline
+	escape     []byte // Incomplete escape sequence from the previous read
+	title      string
+	fullScreen bool
+	recent     map[string]struct{}
+	recentList []string
+}
+
+// NewAccessibleTransformer creates a transformer for one connection
+func NewAccessibleTransformer() *AccessibleTransformer {
+	return &AccessibleTransformer{recent: make(map[string]struct{})}
+}
+
+// Transform converts a chunk of PTY output into announcements
+func (t *AccessibleTransformer) Transform(data []byte) []AccessibleEvent {
+	if len(t.escape) > 0 {
+		data = append(t.escape, data...)
+		t.escape = nil
+	}
+
+	var events []AccessibleEvent
+	var line []byte
+	redraw := false
+
+	// Lines are finished by newlines, and during redraws also by cursor moves
+	flush := func(kind string) {
+		text := accessibleLine(line)
+		line = line[:0]
+		if text == "" {
+			return
+		}
+		if kind == AccessibleUpdate && t.seen(text) {
+			return
+		}
+		t.remember(text)
+		events = append(events, AccessibleEvent{Type: "a11y", Kind: kind, Text: text})
+	}
+	lineKind := func() string {
+		if redraw || t.fullScreen {
+			return AccessibleUpdate
+		}
+		return AccessibleText
+	}
+
+	line = append(line, t.pending...)
+	t.pending = nil
+
+	for i := 0; i < len(data); {
+		b := data[i]
+		switch {
+		case b == 0x1b:
+			end, complete := escapeSequenceEnd(data[i:])
+			if !complete {
+				// Finish the sequence with the next read, unless it never ends
+				if len(data)-i <= maxAccessiblePending {
+					t.escape = append([]byte(nil), data[i:]...)
+				}
+				i = len(data)
+				continue
+			}
+			seq := data[i : i+end]
+			i += end
+			switch {
+			case isOSCTitle(seq):
+				if title := oscPayload(seq); title != "" && title != t.title {
+					t.title = title
+					events = append(events, AccessibleEvent{Type: "a11y", Kind: AccessibleTitle, Text: title})
+				}
+			case isAlternateScreen(seq):
+				enter := seq[len(seq)-1] == 'h'
+				if enter != t.fullScreen {
+					flush(lineKind())
+					t.fullScreen = enter
+					text := "Full-screen program started"
+					if !enter {
+						text = "Full-screen program ended"
+					}
+					events = append(events, AccessibleEvent{Type: "a11y", Kind: AccessibleScreen, Text: text})
+				}
+			case isCursorMove(seq):
+				// Text at a new position is a separate line of the redraw
+				redraw = true
+				flush(lineKind())
+			}
+		case b == '\n':
+			flush(lineKind())
+			i++
+		case b == '\r':
+			// A lone carriage return rewrites the line in place (progress bars)
+			if i+1 < len(data) && data[i+1] != '\n' {
+				line = line[:0]
+			}
+			i++
+		case b == 0x07:
+			events = append(events, AccessibleEvent{Type: "a11y", Kind: AccessibleAlert, Text: "Bell"})
+			i++
+		case b == '\b':
+			if len(line) > 0 {
+				_, size := utf8.DecodeLastRune(line)
+				line = line[:len(line)-size]
+			}
+			i++
+		case b < 0x20 && b != '\t':
+			i++
+		default:
+			line = append(line, b)
+			i++
+		}
+	}
+
+	// A trailing partial line is announced now if it asks for input or is
+	// part of a redraw, otherwise held until its newline arrives
+	if text := accessibleLine(line); text != "" {
+		switch {
+		case accessiblePromptPattern.MatchString(text):
+			// Redraws repeat the same prompt; only announce it when it changes
+			if !(redraw || t.fullScreen) || !t.seen(text) {
+				if redraw || t.fullScreen {
+					t.remember(text)
+				}
+				events = append(events, AccessibleEvent{Type: "a11y", Kind: AccessiblePrompt, Text: text})
+			}
+		case redraw || t.fullScreen:
+			flush(AccessibleUpdate)
+		case len(line) <= maxAccessiblePending:
+			t.pending = append([]byte(nil), line...)
+		default:
+			flush(AccessibleText)
+		}
+	}
+	return events
+}
+
+// Flush announces any held partial line
+func (t *AccessibleTransformer) Flush() []AccessibleEvent {
+	text := accessibleLine(t.pending)
+	t.pending = nil
+	if text == "" {
+		return nil
+	}
+	return []AccessibleEvent{{Type: "a11y", Kind: AccessibleText, Text: text}}
+}
+
+func (t *AccessibleTransformer) seen(text string) bool {
+	_, ok := t.recent[text]
+	return ok
+}
+
+func (t *AccessibleTransformer) remember(text string) {
+	if _, ok := t.recent[text]; ok {
+		return
+	}
+	t.recent[text] = struct{}{}
+	t.recentList = append(t.recentList, text)
+	if len(t.recentList) > accessibleRecentLines {
+		delete(t.recent, t.recentList[0])
+		t.recentList = t.recentList[1:]
+	}
+}
+
+// accessibleLine cleans a line for reading: invalid UTF-8 is dropped,
+// whitespace collapsed and pure decoration (borders, spinners) discarded
+func accessibleLine(line []byte) string {
+	text := strings.ToValidUTF8(string(line), "")
+	text = strings.Join(strings.FieldsFunc(text, unicode.IsSpace), " ")
+	// Box-drawing borders around TUI panes and braille spinners
+	text = strings.TrimFunc(text, func(r rune) bool {
+		return (r >= 0x2500 && r <= 0x259F) || (r >= 0x2800 && r <= 0x28FF) || unicode.IsSpace(r)
+	})
+	if accessibleDecorationPattern.MatchString(text) {
+		return ""
+	}
+	return text
+}
+
+// escapeSequenceEnd returns the length of the escape sequence at the start of
+// data, or false if it continues past the end of data
+func escapeSequenceEnd(data []byte) (int, bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+	switch data[1] {
+	case '[': // CSI: parameters then a final byte in @..~
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1, true
+			}
+		}
+		return 0, false
+	case ']', 'P', '_', '^': // OSC/DCS/APC/PM: ended by BEL or ST
+		for i := 2; i < len(data); i++ {
+			if data[i] == 0x07 {
+				return i + 1, true
+			}
+			if data[i] == 0x1b && i+1 < len(data) && data[i+1] == '\\' {
+				return i + 2, true
+			}
+		}
+		return 0, false
+	case '(', ')', '*', '+', '#', '%': // Charset designation: one more byte
+		if len(data) < 3 {
+			return 0, false
+		}
+		return 3, true
+	default:
+		return 2, true
+	}
+}
+
+func isOSCTitle(seq []byte) bool {
+	return len(seq) > 4 && seq[1] == ']' && (seq[2] == '0' || seq[2] == '2') && seq[3] == ';'
+}
+
+func oscPayload(seq []byte) string {
+	payload := seq[4:]
+	payload = []byte(strings.TrimSuffix(strings.TrimSuffix(string(payload), "\x07"), "\x1b\\"))
+	return accessibleLine(payload)
+}
+
+func isAlternateScreen(seq []byte) bool {
+	s := string(seq)
+	return s == "\x1b[?1049h" || s == "\x1b[?1049l" || s == "\x1b[?47h" || s == "\x1b[?47l" || s == "\x1b[?1047h" || s == "\x1b[?1047l"
+}
+
+// isCursorMove reports CSI sequences that position the cursor or clear the
+// screen, which full-screen programs use to redraw
+func isCursorMove(seq []byte) bool {
+	if len(seq) < 3 || seq[1] != '[' || seq[2] == '?' {
+		return false
+	}
+	switch seq[len(seq)-1] {
+	case 'H', 'f', 'A', 'B', 'E', 'F', 'd', 'J':
+		return true
+	}
+	return false
+}
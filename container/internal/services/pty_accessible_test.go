@@ -0,0 +1,63 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func accessibleTexts(events []AccessibleEvent) []string {
+	var texts []string
+	for _, event := range events {
+		texts = append(texts, event.Kind+": "+event.Text)
+	}
+	return texts
+}
+
+func TestAccessibleTransformerStream(t *testing.T) {
+	transformer := NewAccessibleTransformer()
+
+	// Colors are stripped and partial lines wait for their newline
+	assert.Empty(t, transformer.Transform([]byte("\x1b[32mok\x1b[0m  github.com/app")))
+	assert.Equal(t, []string{"text: ok github.com/app 0.4s"},
+		accessibleTexts(transformer.Transform([]byte(" 0.4s\r\n"))))
+
+	// Progress bars rewritten with carriage returns only announce the final state
+	assert.Equal(t, []string{"text: 100% done"},
+		accessibleTexts(transformer.Transform([]byte("10%\r50%\r100% done\n"))))
+
+	// Prompts, titles and bells are announced immediately
+	assert.Equal(t, []string{"title: catnip: main", "alert: Bell", "prompt: user@catnip:~/app$"},
+		accessibleTexts(transformer.Transform([]byte("\x1b]0;catnip: main\x07\x07user@catnip:~/app$ "))))
+
+	// An escape sequence split across reads is still stripped
+	assert.Equal(t, []string{"text: ls"}, accessibleTexts(transformer.Transform([]byte("ls\r\n\x1b[3"))))
+	assert.Equal(t, []string{"text: README.md"},
+		accessibleTexts(transformer.Transform([]byte("1mREADME.md\x1b[0m\n"))))
+
+	assert.Empty(t, transformer.Transform([]byte("partial")))
+	assert.Equal(t, []string{"text: partial"}, accessibleTexts(transformer.Flush()))
+}
+
+func TestAccessibleTransformerRedraws(t *testing.T) {
+	transformer := NewAccessibleTransformer()
+
+	frame := func(status string) []byte {
+		return []byte("\x1b[2J\x1b[1;1H╭──────────╮\x1b[2;1H│ > fix the tests │\x1b[3;1H╰──────────╯\x1b[4;1H⠋ " + status + "\x1b[5;1HAllow this edit? (y/n)")
+	}
+
+	// The first frame is read out without borders or spinners
+	assert.Equal(t, []string{"update: > fix the tests", "update: Thinking", "prompt: Allow this edit? (y/n)"},
+		accessibleTexts(transformer.Transform(frame("Thinking"))))
+
+	// Redrawing the same screen with one changed line only announces the change
+	assert.Equal(t, []string{"update: Running tests"},
+		accessibleTexts(transformer.Transform(frame("Running tests"))))
+	assert.Empty(t, transformer.Transform(frame("Running tests")))
+
+	// Entering and leaving full-screen programs is announced
+	assert.Equal(t, []string{"screen: Full-screen program started", "update: GNU nano"},
+		accessibleTexts(transformer.Transform([]byte("\x1b[?1049hGNU nano\n"))))
+	assert.Equal(t, []string{"screen: Full-screen program ended"},
+		accessibleTexts(transformer.Transform([]byte("\x1b[?1049l"))))
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxAnnotationsPerSession caps how many tool annotations a PTY session keeps;
+// the oldest are dropped first
+const MaxAnnotationsPerSession = 500
+
+// TerminalAnnotation marks the stretch of a PTY session's output produced
+// while Claude ran one tool. Offsets are positions in the session's output
+// stream, the same ones buffer-complete reports, within the buffer BufferID.
+type TerminalAnnotation struct {
+	ID        string `json:"id"`
+	ToolName  string `json:"tool_name" example:"Bash"`
+	ToolUseID string `json:"tool_use_id,omitempty" example:"toolu_01ABC"`
+	BufferID  string `json:"buffer_id"`
+	// Output offset when the tool started, or when the previous tool ended if
+	// the start wasn't reported
+	StartOffset int64 `json:"start_offset" example:"10240"`
+	// Output offset when the tool finished (0 while it runs)
+	EndOffset  int64      `json:"end_offset" example:"12800"`
+	StartedAt  time.Time  `json:"started_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+	DurationMs int64      `json:"duration_ms" example:"1850"`
+	// Whether the tool succeeded (absent while it runs or if unknown)
+	Success *bool `json:"success,omitempty" example:"true"`
+	// Time of the finish in the session's recording, in seconds
+	RecordingTime float64 `json:"recording_time,omitempty" example:"42.5"`
+}
+
+// Running reports whether the tool hasn't finished yet
+func (a TerminalAnnotation) Running() bool {
+	return a.EndedAt == nil
+}
+
+// PTYAnnotationRegistry holds the tool annotations of each PTY session
+type PTYAnnotationRegistry struct {
+	mu       sync.Mutex
+	sessions map[string][]*TerminalAnnotation
+	now      func() time.Time
+}
+
+// NewPTYAnnotationRegistry creates an empty registry
+func NewPTYAnnotationRegistry() *PTYAnnotationRegistry {
+	return &PTYAnnotationRegistry{
+		sessions: make(map[string][]*TerminalAnnotation),
+		now:      time.Now,
+	}
+}
+
+// Begin records that a tool started at an output position
+func (r *PTYAnnotationRegistry) Begin(sessionID, toolUseID, toolName, bufferID string, offset int64) TerminalAnnotation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	annotation := &TerminalAnnotation{
+		ID:          uuid.NewString(),
+		ToolName:    toolName,
+		ToolUseID:   toolUseID,
+		BufferID:    bufferID,
+		StartOffset: offset,
+		StartedAt:   r.now(),
+	}
+	r.add(sessionID, annotation)
+	return *annotation
+}
+
+// End records that a tool finished at an output position. It closes the
+// running annotation with the same tool use ID, or else the oldest running one
+// for the same tool; without one (the start wasn't reported) the annotation
+// starts where the previous one ended.
+func (r *PTYAnnotationRegistry) End(sessionID, toolUseID, toolName, bufferID string, offset int64, success *bool, recordingTime float64) TerminalAnnotation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	annotation := r.running(sessionID, toolUseID, toolName)
+	if annotation == nil {
+		annotation = &TerminalAnnotation{
+			ID:          uuid.NewString(),
+			ToolName:    toolName,
+			ToolUseID:   toolUseID,
+			BufferID:    bufferID,
+			StartOffset: offset,
+			StartedAt:   now,
+		}
+		if previous := r.lastEnded(sessionID); previous != nil && previous.BufferID == bufferID {
+			annotation.StartOffset = previous.EndOffset
+			annotation.StartedAt = *previous.EndedAt
+		}
+		r.add(sessionID, annotation)
+	}
+	if annotation.BufferID != bufferID {
+		// The output was cleared while the tool ran
+		annotation.BufferID = bufferID
+		annotation.StartOffset = 0
+	}
+
+	annotation.EndOffset = offset
+	annotation.EndedAt = &now
+	annotation.DurationMs = now.Sub(annotation.StartedAt).Milliseconds()
+	annotation.Success = success
+	annotation.RecordingTime = recordingTime
+	return *annotation
+}
+
+// List returns a session's annotations, oldest first
+func (r *PTYAnnotationRegistry) List(sessionID string) []TerminalAnnotation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	annotations := make([]TerminalAnnotation, 0, len(r.sessions[sessionID]))
+	for _, annotation := range r.sessions[sessionID] {
+		annotations = append(annotations, *annotation)
+	}
+	return annotations
+}
+
+// Forget drops a session's annotations
+func (r *PTYAnnotationRegistry) Forget(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, sessionID)
+}
+
+func (r *PTYAnnotationRegistry) add(sessionID string, annotation *TerminalAnnotation) {
+	annotations := append(r.sessions[sessionID], annotation)
+	if len(annotations) > MaxAnnotationsPerSession {
+		annotations = append([]*TerminalAnnotation(nil), annotations[len(annotations)-MaxAnnotationsPerSession:]...)
+	}
+	r.sessions[sessionID] = annotations
+}
+
+func (r *PTYAnnotationRegistry) running(sessionID, toolUseID, toolName string) *TerminalAnnotation {
+	var byName *TerminalAnnotation
+	for _, annotation := range r.sessions[sessionID] {
+		if !annotation.Running() {
+			continue
+		}
+		if toolUseID != "" && annotation.ToolUseID == toolUseID {
+			return annotation
+		}
+		if byName == nil && annotation.ToolName == toolName && (toolUseID == "" || annotation.ToolUseID == "") {
+			byName = annotation
+		}
+	}
+	return byName
+}
+
+// lastEnded returns the annotation that finished last. Tools can finish out
+// of order, so this isn't necessarily the newest one.
+func (r *PTYAnnotationRegistry) lastEnded(sessionID string) *TerminalAnnotation {
+	var last *TerminalAnnotation
+	for _, annotation := range r.sessions[sessionID] {
+		if annotation.Running() {
+			continue
+		}
+		if last == nil || annotation.EndedAt.After(*last.EndedAt) ||
+			(annotation.EndedAt.Equal(*last.EndedAt) && annotation.EndOffset > last.EndOffset) {
+			last = annotation
+		}
+	}
+	return last
+}
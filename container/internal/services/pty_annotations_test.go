@@ -0,0 +1,61 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTYAnnotationRegistry(t *testing.T) {
+	registry := NewPTYAnnotationRegistry()
+	clock := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	registry.now = func() time.Time { return clock }
+	ok := true
+
+	registry.Begin("app:claude", "toolu_1", "Read", "b1", 100)
+	registry.Begin("app:claude", "toolu_2", "Bash", "b1", 120)
+	running := registry.List("app:claude")
+	require.Len(t, running, 2)
+	assert.True(t, running[1].Running())
+
+	clock = clock.Add(1500 * time.Millisecond)
+	bash := registry.End("app:claude", "toolu_2", "Bash", "b1", 400, &ok, 12.5)
+	assert.Equal(t, int64(120), bash.StartOffset)
+	assert.Equal(t, int64(400), bash.EndOffset)
+	assert.Equal(t, int64(1500), bash.DurationMs)
+	assert.Equal(t, 12.5, bash.RecordingTime)
+
+	// Without a tool use ID the oldest running annotation of the tool closes
+	read := registry.End("app:claude", "", "Read", "b1", 450, nil, 0)
+	assert.Equal(t, int64(100), read.StartOffset)
+	assert.False(t, read.Running())
+
+	// An unreported start begins where the previous tool ended
+	clock = clock.Add(time.Second)
+	edit := registry.End("app:claude", "toolu_3", "Edit", "b1", 600, &ok, 0)
+	assert.Equal(t, int64(450), edit.StartOffset)
+	assert.Equal(t, int64(1000), edit.DurationMs)
+	assert.Len(t, registry.List("app:claude"), 3)
+
+	// The output was cleared while the tool ran
+	registry.Begin("app:claude", "toolu_4", "Bash", "b1", 700)
+	cleared := registry.End("app:claude", "toolu_4", "Bash", "b2", 30, &ok, 0)
+	assert.Equal(t, "b2", cleared.BufferID)
+	assert.Equal(t, int64(0), cleared.StartOffset)
+
+	registry.Forget("app:claude")
+	assert.Empty(t, registry.List("app:claude"))
+}
+
+func TestPTYAnnotationRegistryCap(t *testing.T) {
+	registry := NewPTYAnnotationRegistry()
+	for i := 0; i < MaxAnnotationsPerSession+10; i++ {
+		registry.Begin("app:claude", fmt.Sprintf("toolu_%d", i), "Read", "b1", int64(i))
+	}
+	annotations := registry.List("app:claude")
+	require.Len(t, annotations, MaxAnnotationsPerSession)
+	assert.Equal(t, "toolu_10", annotations[0].ToolUseID)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBellCooldown is the minimum time between attention events for
+	// plain bells, which shells ring freely (e.g. on failed tab completion)
+	DefaultBellCooldown = 5 * time.Second
+	// Longest OSC sequence buffered while waiting for its terminator
+	maxOSCLength = 4096
+)
+
+// AttentionKind is what kind of attention signal a session emitted
+type AttentionKind string
+
+const (
+	// AttentionBell is a BEL character outside of an escape sequence
+	AttentionBell AttentionKind = "bell"
+	// AttentionNotification is an OSC 777 desktop notification
+	AttentionNotification AttentionKind = "notification"
+)
+
+// AttentionSignal is an attention request read from a PTY session's output
+type AttentionSignal struct {
+	SessionID string        `json:"session_id"`
+	Kind      AttentionKind `json:"kind"`
+	Title     string        `json:"title,omitempty"`
+	Body      string        `json:"body,omitempty"`
+	// Unacknowledged signals for the session, for the UI badge
+	Pending int `json:"pending"`
+	// Whether the signal should also raise a native notification
+	Notify    bool      `json:"notify"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SessionAttentionSettings are the per-session mute controls
+type SessionAttentionSettings struct {
+	// Ignore all attention signals from the session
+	Muted bool `json:"muted"`
+	// Raise native notifications for plain bells, not just OSC 777
+	NotifyOnBell bool `json:"notify_on_bell"`
+}
+
+// SessionAttention is a session's attention state
+type SessionAttention struct {
+	SessionID string `json:"session_id"`
+	SessionAttentionSettings
+	Pending    int              `json:"pending"`
+	LastSignal *AttentionSignal `json:"last_signal,omitempty"`
+}
+
+type oscState int
+
+const (
+	oscNone oscState = iota
+	oscEscape
+	oscBody
+	oscBodyEscape
+)
+
+type sessionAttentionState struct {
+	settings   SessionAttentionSettings
+	pending    int
+	lastSignal *AttentionSignal
+	lastBellAt time.Time
+
+	// Escape sequence parser state, carried across reads
+	state oscState
+	osc   strings.Builder
+}
+
+// PTYAttentionTracker turns BEL characters and OSC 777 notifications in PTY
+// output into attention signals
+type PTYAttentionTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionAttentionState
+	now      func() time.Time
+}
+
+// NewPTYAttentionTracker creates an empty attention tracker
+func NewPTYAttentionTracker() *PTYAttentionTracker {
+	return &PTYAttentionTracker{
+		sessions: make(map[string]*sessionAttentionState),
+		now:      time.Now,
+	}
+}
+
+func (t *PTYAttentionTracker) sessionLocked(sessionID string) *sessionAttentionState {
+	state, exists := t.sessions[sessionID]
+	if !exists {
+		state = &sessionAttentionState{}
+		t.sessions[sessionID] = state
+	}
+	return state
+}
+
+// Scan parses a chunk of PTY output and returns the signals to publish
+func (t *PTYAttentionTracker) Scan(sessionID string, data []byte) []AttentionSignal {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state := t.sessionLocked(sessionID)
+	var signals []AttentionSignal
+	for _, b := range data {
+		switch state.state {
+		case oscNone:
+			switch b {
+			case 0x1b:
+				state.state = oscEscape
+			case 0x07:
+				if signal, ok := t.bellLocked(sessionID, state); ok {
+					signals = append(signals, signal)
+				}
+			}
+		case oscEscape:
+			if b == ']' {
+				state.state = oscBody
+				state.osc.Reset()
+			} else {
+				state.state = oscNone
+			}
+		case oscBody:
+			switch b {
+			case 0x07:
+				if signal, ok := t.oscLocked(sessionID, state); ok {
+					signals = append(signals, signal)
+				}
+			case 0x1b:
+				state.state = oscBodyEscape
+			default:
+				if state.osc.Len() >= maxOSCLength {
+					state.state = oscNone
+					state.osc.Reset()
+					continue
+				}
+				state.osc.WriteByte(b)
+			}
+		case oscBodyEscape:
+			// ESC \ (string terminator) ends the sequence; anything else aborts it
+			if b == '\\' {
+				if signal, ok := t.oscLocked(sessionID, state); ok {
+					signals = append(signals, signal)
+				}
+			} else {
+				state.state = oscNone
+				state.osc.Reset()
+			}
+		}
+	}
+	return signals
+}
+
+func (t *PTYAttentionTracker) bellLocked(sessionID string, state *sessionAttentionState) (AttentionSignal, bool) {
+	if state.settings.Muted {
+		return AttentionSignal{}, false
+	}
+	now := t.now()
+	if now.Sub(state.lastBellAt) < DefaultBellCooldown {
+		return AttentionSignal{}, false
+	}
+	state.lastBellAt = now
+	return t.recordLocked(state, AttentionSignal{
+		SessionID: sessionID,
+		Kind:      AttentionBell,
+		Notify:    state.settings.NotifyOnBell,
+	}), true
+}
+
+// oscLocked handles a completed OSC sequence. Only "777;notify;title;body"
+// is an attention signal; titles, hyperlinks etc. are ignored.
+func (t *PTYAttentionTracker) oscLocked(sessionID string, state *sessionAttentionState) (AttentionSignal, bool) {
+	body := state.osc.String()
+	state.state = oscNone
+	state.osc.Reset()
+
+	parts := strings.SplitN(body, ";", 4)
+	if len(parts) < 3 || parts[0] != "777" || parts[1] != "notify" || state.settings.Muted {
+		return AttentionSignal{}, false
+	}
+	signal := AttentionSignal{
+		SessionID: sessionID,
+		Kind:      AttentionNotification,
+		Title:     parts[2],
+		Notify:    true,
+	}
+	if len(parts) == 4 {
+		signal.Body = parts[3]
+	}
+	return t.recordLocked(state, signal), true
+}
+
+func (t *PTYAttentionTracker) recordLocked(state *sessionAttentionState, signal AttentionSignal) AttentionSignal {
+	state.pending++
+	signal.Pending = state.pending
+	signal.Timestamp = t.now()
+	state.lastSignal = &signal
+	return signal
+}
+
+// Get returns a session's attention state
+func (t *PTYAttentionTracker) Get(sessionID string) SessionAttention {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshotLocked(sessionID)
+}
+
+func (t *PTYAttentionTracker) snapshotLocked(sessionID string) SessionAttention {
+	attention := SessionAttention{SessionID: sessionID}
+	if state, exists := t.sessions[sessionID]; exists {
+		attention.SessionAttentionSettings = state.settings
+		attention.Pending = state.pending
+		if state.lastSignal != nil {
+			last := *state.lastSignal
+			attention.LastSignal = &last
+		}
+	}
+	return attention
+}
+
+// Configure updates a session's mute controls. Muting also clears the badge.
+func (t *PTYAttentionTracker) Configure(sessionID string, settings SessionAttentionSettings) SessionAttention {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.sessionLocked(sessionID)
+	state.settings = settings
+	if settings.Muted {
+		state.pending = 0
+	}
+	return t.snapshotLocked(sessionID)
+}
+
+// Acknowledge clears a session's pending signals once the user has looked at it
+func (t *PTYAttentionTracker) Acknowledge(sessionID string) SessionAttention {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state, exists := t.sessions[sessionID]; exists {
+		state.pending = 0
+	}
+	return t.snapshotLocked(sessionID)
+}
+
+// Forget drops the state of a session that has been cleaned up
+func (t *PTYAttentionTracker) Forget(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sessions, sessionID)
+}
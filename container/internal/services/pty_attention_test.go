@@ -0,0 +1,62 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTYAttentionTrackerScan(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := NewPTYAttentionTracker()
+	tracker.now = func() time.Time { return now }
+
+	// BEL that terminates a title OSC is not a bell
+	assert.Empty(t, tracker.Scan("s1", []byte("\x1b]0;my title\x07prompt$ ")))
+
+	signals := tracker.Scan("s1", []byte("build done\x07"))
+	require.Len(t, signals, 1)
+	assert.Equal(t, AttentionBell, signals[0].Kind)
+	assert.False(t, signals[0].Notify)
+	assert.Equal(t, 1, signals[0].Pending)
+
+	// Bells within the cooldown are dropped
+	assert.Empty(t, tracker.Scan("s1", []byte("\x07\x07")))
+
+	// OSC 777 split across reads, terminated with ST
+	assert.Empty(t, tracker.Scan("s1", []byte("\x1b]777;notify;Tests")))
+	signals = tracker.Scan("s1", []byte(";all 42 passed\x1b\\"))
+	require.Len(t, signals, 1)
+	assert.Equal(t, AttentionNotification, signals[0].Kind)
+	assert.Equal(t, "Tests", signals[0].Title)
+	assert.Equal(t, "all 42 passed", signals[0].Body)
+	assert.True(t, signals[0].Notify)
+	assert.Equal(t, 2, signals[0].Pending)
+
+	now = now.Add(DefaultBellCooldown)
+	assert.Len(t, tracker.Scan("s1", []byte("\x07")), 1)
+
+	state := tracker.Acknowledge("s1")
+	assert.Equal(t, 0, state.Pending)
+	require.NotNil(t, state.LastSignal)
+	assert.Equal(t, AttentionBell, state.LastSignal.Kind)
+}
+
+func TestPTYAttentionTrackerMute(t *testing.T) {
+	tracker := NewPTYAttentionTracker()
+
+	tracker.Configure("s1", SessionAttentionSettings{Muted: true})
+	assert.Empty(t, tracker.Scan("s1", []byte("\x07\x1b]777;notify;Done;ok\x07")))
+	assert.Equal(t, 0, tracker.Get("s1").Pending)
+
+	// Other sessions are unaffected, and bells can opt into native notifications
+	tracker.Configure("s2", SessionAttentionSettings{NotifyOnBell: true})
+	signals := tracker.Scan("s2", []byte("\x07"))
+	require.Len(t, signals, 1)
+	assert.True(t, signals[0].Notify)
+
+	tracker.Forget("s2")
+	assert.False(t, tracker.Get("s2").NotifyOnBell)
+}
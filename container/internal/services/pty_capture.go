@@ -0,0 +1,504 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultCaptureIdleGap is the pause in output that ends a segment of a
+// capture: the agent has finished responding and is waiting for a prompt
+const DefaultCaptureIdleGap = 2 * time.Second
+
+// Lines listed per side of a changed segment
+const maxCaptureDiffLines = 20
+
+// CaptureMetadata is a PTY capture in the JSON format of the Swift
+// MockPTYDataSource
+type CaptureMetadata struct {
+	CaptureDate     time.Time      `json:"captureDate"`
+	TotalBytes      int            `json:"totalBytes"`
+	DurationSeconds float64        `json:"durationSeconds"`
+	Events          []CaptureEvent `json:"events"`
+}
+
+// CaptureEvent is a chunk of terminal output. Boundary events carry no
+// output and mark where the user submitted a prompt.
+type CaptureEvent struct {
+	TimestampMs int    `json:"timestampMs"`
+	Data        []byte `json:"data"`
+	Boundary    bool   `json:"boundary,omitempty"`
+}
+
+// LoadCapture reads a JSON capture or an asciicast v2 recording. Asciicast
+// input and marker events become prompt boundaries.
+func LoadCapture(path string) (*CaptureMetadata, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var header AsciicastHeader
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	if json.Unmarshal(firstLine, &header) == nil && header.Version == 2 {
+		return parseAsciicastCapture(data, header)
+	}
+
+	var capture CaptureMetadata
+	if err := json.Unmarshal(data, &capture); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &capture, nil
+}
+
+func parseAsciicastCapture(data []byte, header AsciicastHeader) (*CaptureMetadata, error) {
+	capture := &CaptureMetadata{DurationSeconds: header.Duration}
+	if header.Timestamp != 0 {
+		capture.CaptureDate = time.Unix(header.Timestamp, 0)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxRecordingBytes)
+	scanner.Scan() // Header
+	for lineNum := 2; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event AsciicastEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		timestampMs := int(event.Time * 1000)
+		switch event.Type {
+		case AsciicastOutput:
+			capture.Events = append(capture.Events, CaptureEvent{TimestampMs: timestampMs, Data: []byte(event.Data)})
+			capture.TotalBytes += len(event.Data)
+		case AsciicastInput, AsciicastMarker:
+			capture.Events = append(capture.Events, CaptureEvent{TimestampMs: timestampMs, Boundary: true})
+		}
+		if capture.DurationSeconds < event.Time {
+			capture.DurationSeconds = event.Time
+		}
+	}
+	return capture, scanner.Err()
+}
+
+// CaptureDiffOptions controls how captures are segmented and compared
+type CaptureDiffOptions struct {
+	// Pause in output that starts a new segment (default DefaultCaptureIdleGap)
+	IdleGap time.Duration
+	// Lines matching this are left out of the comparison, e.g. spinners and timers
+	Ignore *regexp.Regexp
+}
+
+// CaptureSegment is the output between two prompt boundaries
+type CaptureSegment struct {
+	StartMs int `json:"start_ms"`
+	EndMs   int `json:"end_ms"`
+	Lines   int `json:"lines"`
+	Bytes   int `json:"bytes"`
+
+	text       []string
+	timestamps []int
+}
+
+// CaptureSegmentDiff compares aligned segments of two captures
+type CaptureSegmentDiff struct {
+	// "same", "changed", "only_a" or "only_b"
+	Status string          `json:"status"`
+	A      *CaptureSegment `json:"a,omitempty"`
+	B      *CaptureSegment `json:"b,omitempty"`
+	// Share of lines the segments have in common, from 0 to 1
+	Similarity float64 `json:"similarity"`
+	// Lines only in A or only in B (the first few)
+	Removed []string `json:"removed,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	// Difference in how long the segment took (B minus A)
+	DurationDeltaMs int `json:"duration_delta_ms"`
+}
+
+// CaptureDivergence is the first point where two captures' output differs
+type CaptureDivergence struct {
+	// Index into CaptureDiff.Segments
+	Segment      int    `json:"segment"`
+	TimestampMsA int    `json:"timestamp_ms_a"`
+	TimestampMsB int    `json:"timestamp_ms_b"`
+	LineA        string `json:"line_a"`
+	LineB        string `json:"line_b"`
+	// Last lines both captures printed before diverging
+	Context []string `json:"context,omitempty"`
+}
+
+// CaptureDiff reports how two captured runs differ
+type CaptureDiff struct {
+	Identical       bool                 `json:"identical"`
+	SegmentsA       int                  `json:"segments_a"`
+	SegmentsB       int                  `json:"segments_b"`
+	DurationMsA     int                  `json:"duration_ms_a"`
+	DurationMsB     int                  `json:"duration_ms_b"`
+	Segments        []CaptureSegmentDiff `json:"segments"`
+	FirstDivergence *CaptureDivergence   `json:"first_divergence,omitempty"`
+}
+
+// DiffCaptures splits two captures into segments at prompt boundaries and
+// idle gaps, aligns the segments and reports where the rendered output
+// diverges. Escape sequences are stripped and repeated redraws of a line are
+// collapsed, so only the text differences remain.
+func DiffCaptures(a, b *CaptureMetadata, opts CaptureDiffOptions) *CaptureDiff {
+	if opts.IdleGap <= 0 {
+		opts.IdleGap = DefaultCaptureIdleGap
+	}
+	segmentsA := segmentCapture(a, opts)
+	segmentsB := segmentCapture(b, opts)
+
+	diff := &CaptureDiff{
+		Identical:   true,
+		SegmentsA:   len(segmentsA),
+		SegmentsB:   len(segmentsB),
+		DurationMsA: captureDurationMs(a),
+		DurationMsB: captureDurationMs(b),
+		Segments:    []CaptureSegmentDiff{},
+	}
+	for _, pair := range alignCaptureSegments(segmentsA, segmentsB) {
+		segmentDiff, divergence := compareCaptureSegments(pair[0], pair[1])
+		if segmentDiff.Status != "same" {
+			diff.Identical = false
+			if diff.FirstDivergence == nil {
+				divergence.Segment = len(diff.Segments)
+				diff.FirstDivergence = divergence
+			}
+		}
+		diff.Segments = append(diff.Segments, segmentDiff)
+	}
+	return diff
+}
+
+func captureDurationMs(capture *CaptureMetadata) int {
+	if capture.DurationSeconds > 0 {
+		return int(capture.DurationSeconds * 1000)
+	}
+	if n := len(capture.Events); n > 0 {
+		return capture.Events[n-1].TimestampMs
+	}
+	return 0
+}
+
+// segmentCapture renders a capture's output into text lines, split into
+// segments at boundary events and idle gaps
+func segmentCapture(capture *CaptureMetadata, opts CaptureDiffOptions) []*CaptureSegment {
+	var segments []*CaptureSegment
+	var current *CaptureSegment
+	screen := &captureTextWriter{}
+	lastMs := 0
+
+	endSegment := func() {
+		if current == nil {
+			return
+		}
+		screen.flush(current, opts.Ignore)
+		if len(current.text) > 0 {
+			current.Lines = len(current.text)
+			segments = append(segments, current)
+		}
+		current = nil
+		screen.last = ""
+	}
+
+	for _, event := range capture.Events {
+		if event.Boundary || (current != nil && time.Duration(event.TimestampMs-lastMs)*time.Millisecond >= opts.IdleGap) {
+			endSegment()
+		}
+		if event.Boundary {
+			continue
+		}
+		if current == nil {
+			current = &CaptureSegment{StartMs: event.TimestampMs}
+		}
+		lastMs = event.TimestampMs
+		current.EndMs = event.TimestampMs
+		current.Bytes += len(event.Data)
+		screen.write(current, event, opts.Ignore)
+	}
+	endSegment()
+	return segments
+}
+
+// captureTextWriter turns terminal output into lines of text: escape
+// sequences are dropped, and cursor jumps and carriage returns end a line,
+// since TUIs redraw by positioning the cursor rather than printing newlines
+type captureTextWriter struct {
+	line    []byte
+	lineMs  int    // When the line's first character was printed
+	started bool   // Whether the line has any characters
+	now     int    // Timestamp of the event being written
+	escape  []byte // Pending escape sequence, including the ESC
+	inOSC   bool
+	last    string // Last line kept, to collapse redraws
+}
+
+func (w *captureTextWriter) write(segment *CaptureSegment, event CaptureEvent, ignore *regexp.Regexp) {
+	w.now = event.TimestampMs
+	for _, c := range event.Data {
+		if w.escape != nil {
+			w.escape = append(w.escape, c)
+			if w.endEscape(c) {
+				w.applyEscape(segment, ignore)
+				w.escape = nil
+				w.inOSC = false
+			}
+			continue
+		}
+		switch {
+		case c == 0x1b:
+			w.escape = []byte{c}
+		case c == '\n' || c == '\r':
+			w.flush(segment, ignore)
+		case c == '\t':
+			w.add(' ')
+		case c < 0x20 || c == 0x7f:
+			// Bell, backspace and other controls don't print
+		default:
+			w.add(c)
+		}
+	}
+}
+
+func (w *captureTextWriter) add(c byte) {
+	if !w.started {
+		w.lineMs = w.now
+		w.started = true
+	}
+	w.line = append(w.line, c)
+}
+
+// endEscape reports whether c completes the pending escape sequence
+func (w *captureTextWriter) endEscape(c byte) bool {
+	if len(w.escape) == 2 {
+		switch c {
+		case '[':
+			return false
+		case ']', 'P', '_', '^':
+			w.inOSC = true
+			return false
+		}
+		// Intermediate bytes, as in ESC ( B, are followed by one more
+		return c < 0x20 || c > 0x2f
+	}
+	if w.inOSC {
+		// OSC and other strings end with BEL or ESC \
+		return c == 0x07 || (c == '\\' && w.escape[len(w.escape)-2] == 0x1b)
+	}
+	if w.escape[1] != '[' {
+		return c >= 0x30
+	}
+	return c >= 0x40 && c <= 0x7e
+}
+
+// applyEscape breaks the line on cursor movements that leave it, and turns
+// cursor-forward into a space so words stay apart
+func (w *captureTextWriter) applyEscape(segment *CaptureSegment, ignore *regexp.Regexp) {
+	if len(w.escape) < 3 || w.escape[1] != '[' {
+		return
+	}
+	switch w.escape[len(w.escape)-1] {
+	case 'H', 'f', 'A', 'B', 'E', 'F', 'd':
+		w.flush(segment, ignore)
+	case 'C':
+		if len(w.line) > 0 {
+			w.line = append(w.line, ' ')
+		}
+	}
+}
+
+// flush ends the current line, keeping it if it has text, isn't ignored and
+// isn't a redraw of the previous line
+func (w *captureTextWriter) flush(segment *CaptureSegment, ignore *regexp.Regexp) {
+	text := strings.Join(strings.Fields(string(w.line)), " ")
+	lineMs := w.lineMs
+	w.line = w.line[:0]
+	w.started = false
+	if text == "" || text == w.last || (ignore != nil && ignore.MatchString(text)) {
+		return
+	}
+	w.last = text
+	segment.text = append(segment.text, text)
+	segment.timestamps = append(segment.timestamps, lineMs)
+}
+
+// alignCaptureSegments pairs up segments of two captures, allowing segments
+// that only one capture has. It maximizes the total similarity of the pairs,
+// so an extra pause in one run doesn't misalign everything after it.
+func alignCaptureSegments(a, b []*CaptureSegment) [][2]*CaptureSegment {
+	// Segments less alike than this are reported as unmatched
+	const minSimilarity = 0.3
+
+	similarity := make([][]float64, len(a))
+	for i := range a {
+		similarity[i] = make([]float64, len(b))
+		for j := range b {
+			similarity[i][j] = captureLineSimilarity(a[i].text, b[j].text)
+		}
+	}
+
+	// score[i][j] is the best alignment of a[i:] and b[j:]
+	score := make([][]float64, len(a)+1)
+	for i := range score {
+		score[i] = make([]float64, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			best := max(score[i+1][j], score[i][j+1])
+			if similarity[i][j] >= minSimilarity {
+				best = max(best, similarity[i][j]+score[i+1][j+1])
+			}
+			score[i][j] = best
+		}
+	}
+
+	var pairs [][2]*CaptureSegment
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case similarity[i][j] >= minSimilarity && score[i][j] == similarity[i][j]+score[i+1][j+1]:
+			pairs = append(pairs, [2]*CaptureSegment{a[i], b[j]})
+			i++
+			j++
+		case score[i][j] == score[i+1][j]:
+			pairs = append(pairs, [2]*CaptureSegment{a[i], nil})
+			i++
+		default:
+			pairs = append(pairs, [2]*CaptureSegment{nil, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		pairs = append(pairs, [2]*CaptureSegment{a[i], nil})
+	}
+	for ; j < len(b); j++ {
+		pairs = append(pairs, [2]*CaptureSegment{nil, b[j]})
+	}
+	return pairUnmatchedSegments(pairs)
+}
+
+// pairUnmatchedSegments pairs up segments each capture has in the same place
+// but that don't look alike, so a rewritten response shows as changed rather
+// than as one removed and one added segment
+func pairUnmatchedSegments(pairs [][2]*CaptureSegment) [][2]*CaptureSegment {
+	var result [][2]*CaptureSegment
+	for start := 0; start < len(pairs); {
+		if pairs[start][0] != nil && pairs[start][1] != nil {
+			result = append(result, pairs[start])
+			start++
+			continue
+		}
+		end := start
+		var onlyA, onlyB []*CaptureSegment
+		for ; end < len(pairs) && (pairs[end][0] == nil || pairs[end][1] == nil); end++ {
+			if pairs[end][0] != nil {
+				onlyA = append(onlyA, pairs[end][0])
+			} else {
+				onlyB = append(onlyB, pairs[end][1])
+			}
+		}
+		for k := 0; k < max(len(onlyA), len(onlyB)); k++ {
+			var pair [2]*CaptureSegment
+			if k < len(onlyA) {
+				pair[0] = onlyA[k]
+			}
+			if k < len(onlyB) {
+				pair[1] = onlyB[k]
+			}
+			result = append(result, pair)
+		}
+		start = end
+	}
+	return result
+}
+
+// captureLineSimilarity is the share of lines two segments have in common,
+// counting repeated lines as often as both contain them
+func captureLineSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+	counts := make(map[string]int, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	common := 0
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+			common++
+		}
+	}
+	return float64(2*common) / float64(len(a)+len(b))
+}
+
+func compareCaptureSegments(a, b *CaptureSegment) (CaptureSegmentDiff, *CaptureDivergence) {
+	diff := CaptureSegmentDiff{A: a, B: b}
+	switch {
+	case b == nil:
+		diff.Status = "only_a"
+		return diff, &CaptureDivergence{TimestampMsA: a.StartMs, LineA: a.text[0]}
+	case a == nil:
+		diff.Status = "only_b"
+		return diff, &CaptureDivergence{TimestampMsB: b.StartMs, LineB: b.text[0]}
+	}
+
+	diff.Similarity = captureLineSimilarity(a.text, b.text)
+	diff.DurationDeltaMs = (b.EndMs - b.StartMs) - (a.EndMs - a.StartMs)
+	diff.Removed = captureLinesNotIn(a.text, b.text)
+	diff.Added = captureLinesNotIn(b.text, a.text)
+
+	prefix := 0
+	for prefix < len(a.text) && prefix < len(b.text) && a.text[prefix] == b.text[prefix] {
+		prefix++
+	}
+	if prefix == len(a.text) && prefix == len(b.text) {
+		diff.Status = "same"
+		return diff, nil
+	}
+
+	diff.Status = "changed"
+	divergence := &CaptureDivergence{
+		TimestampMsA: a.EndMs,
+		TimestampMsB: b.EndMs,
+		Context:      a.text[max(prefix-3, 0):prefix],
+	}
+	if prefix < len(a.text) {
+		divergence.LineA = a.text[prefix]
+		divergence.TimestampMsA = a.timestamps[prefix]
+	}
+	if prefix < len(b.text) {
+		divergence.LineB = b.text[prefix]
+		divergence.TimestampMsB = b.timestamps[prefix]
+	}
+	return diff, divergence
+}
+
+// captureLinesNotIn returns the first lines of a that b doesn't have
+func captureLinesNotIn(a, b []string) []string {
+	counts := make(map[string]int, len(b))
+	for _, line := range b {
+		counts[line]++
+	}
+	var missing []string
+	for _, line := range a {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+		if len(missing) == maxCaptureDiffLines {
+			break
+		}
+		missing = append(missing, line)
+	}
+	return missing
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCapture builds a capture from output chunks at the given times
+func testCapture(chunks ...interface{}) *CaptureMetadata {
+	capture := &CaptureMetadata{}
+	for i := 0; i+1 < len(chunks); i += 2 {
+		ms := chunks[i].(int)
+		data := chunks[i+1].(string)
+		capture.Events = append(capture.Events, CaptureEvent{TimestampMs: ms, Data: []byte(data)})
+		capture.TotalBytes += len(data)
+	}
+	return capture
+}
+
+func TestSegmentCaptureRendersText(t *testing.T) {
+	capture := testCapture(
+		0, "\x1b[?25l\x1b]0;claude\x07\x1b[1;1H\x1b[1mWelcome\x1b[0m to\x1b[1Cclaude\r\n",
+		100, "\x1b(B> \x1b[2;1H> ",
+		150, "\x1b[2;1H> ", // Redraw of the same prompt
+		5000, "Thinking…\r\nDone\n",
+	)
+	segments := segmentCapture(capture, CaptureDiffOptions{IdleGap: DefaultCaptureIdleGap})
+	require.Len(t, segments, 2)
+	assert.Equal(t, []string{"Welcome to claude", ">"}, segments[0].text)
+	assert.Equal(t, []int{0, 100}, segments[0].timestamps)
+	assert.Equal(t, 0, segments[0].StartMs)
+	assert.Equal(t, 150, segments[0].EndMs)
+	assert.Equal(t, []string{"Thinking…", "Done"}, segments[1].text)
+}
+
+func TestDiffCapturesIdentical(t *testing.T) {
+	a := testCapture(0, "hello\n", 3000, "world\n")
+	b := testCapture(0, "\x1b[32mhello\x1b[0m\n", 4000, "world\n")
+	diff := DiffCaptures(a, b, CaptureDiffOptions{})
+	assert.True(t, diff.Identical)
+	assert.Nil(t, diff.FirstDivergence)
+	require.Len(t, diff.Segments, 2)
+	assert.Equal(t, "same", diff.Segments[1].Status)
+}
+
+func TestDiffCapturesDivergence(t *testing.T) {
+	a := testCapture(
+		0, "> fix the bug\nReading main.go\n",
+		3000, "Editing main.go\nRan tests: 3 passed\nDone\n",
+	)
+	b := testCapture(
+		0, "> fix the bug\nReading main.go\n",
+		// An extra pause splits a segment that run A doesn't have
+		2500, "Searching for callers\n",
+		6000, "Editing main.go\nRan tests: 2 passed, 1 failed\nDone\n",
+	)
+
+	diff := DiffCaptures(a, b, CaptureDiffOptions{})
+	assert.False(t, diff.Identical)
+	assert.Equal(t, 2, diff.SegmentsA)
+	assert.Equal(t, 3, diff.SegmentsB)
+
+	statuses := []string{}
+	for _, segment := range diff.Segments {
+		statuses = append(statuses, segment.Status)
+	}
+	assert.Equal(t, []string{"same", "only_b", "changed"}, statuses, "the extra segment doesn't shift the alignment")
+
+	changed := diff.Segments[2]
+	assert.Equal(t, []string{"Ran tests: 3 passed"}, changed.Removed)
+	assert.Equal(t, []string{"Ran tests: 2 passed, 1 failed"}, changed.Added)
+
+	require.NotNil(t, diff.FirstDivergence)
+	assert.Equal(t, 1, diff.FirstDivergence.Segment)
+	assert.Equal(t, "Searching for callers", diff.FirstDivergence.LineB)
+	assert.Equal(t, 2500, diff.FirstDivergence.TimestampMsB)
+
+	// Ignoring the test summary leaves only the extra segment
+	diff = DiffCaptures(a, b, CaptureDiffOptions{Ignore: regexp.MustCompile(`^Ran tests`)})
+	assert.Equal(t, "same", diff.Segments[2].Status)
+}
+
+func TestLoadCaptureAsciicast(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.cast")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 2, "width": 80, "height": 24, "timestamp": 1700000000}
+[0.5, "o", "hello\r\n"]
+[1.0, "i", "fix it\r"]
+[1.25, "o", "fixed\r\n"]
+`), 0644))
+
+	capture, err := LoadCapture(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000), capture.CaptureDate.Unix())
+	assert.Equal(t, 1.25, capture.DurationSeconds)
+	assert.Equal(t, 14, capture.TotalBytes)
+	require.Len(t, capture.Events, 3)
+	assert.True(t, capture.Events[1].Boundary)
+
+	// Input splits segments even without a pause
+	segments := segmentCapture(capture, CaptureDiffOptions{IdleGap: DefaultCaptureIdleGap})
+	require.Len(t, segments, 2)
+	assert.Equal(t, []string{"fixed"}, segments[1].text)
+}
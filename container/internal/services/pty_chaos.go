@@ -0,0 +1,177 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// PTYChaosEnv enables the PTY chaos testing mode when set to "true"
+const PTYChaosEnv = "CATNIP_PTY_CHAOS"
+
+// PTYChaosConfig describes the failures injected into PTY WebSocket
+// connections. The zero value injects nothing.
+type PTYChaosConfig struct {
+	// Delay added to every frame in either direction
+	LatencyMs int `json:"latency_ms"`
+	// Random extra delay, up to this many milliseconds
+	JitterMs int `json:"jitter_ms"`
+	// Fraction (0-1) of terminal output frames silently dropped
+	DropRate float64 `json:"drop_rate"`
+	// Fraction (0-1) of terminal output frames that close the connection instead
+	DisconnectRate float64 `json:"disconnect_rate"`
+	// Close every connection this many seconds after it connects (0 disables)
+	DisconnectAfterSeconds int `json:"disconnect_after_seconds"`
+}
+
+// PTYChaosStatus is the current chaos configuration and what it has done
+type PTYChaosStatus struct {
+	Config            PTYChaosConfig `json:"config"`
+	Connections       int            `json:"connections"`
+	DelayedFrames     int64          `json:"delayed_frames"`
+	DroppedFrames     int64          `json:"dropped_frames"`
+	ForcedDisconnects int64          `json:"forced_disconnects"`
+}
+
+// PTYChaosAction is what to do with one frame
+type PTYChaosAction struct {
+	Delay      time.Duration
+	Drop       bool
+	Disconnect bool
+}
+
+// PTYChaos injects latency, dropped frames and disconnects into PTY
+// connections so clients' reconnect logic can be exercised. It's a developer
+// tool and is only created when CATNIP_PTY_CHAOS=true.
+type PTYChaos struct {
+	mu          sync.Mutex
+	config      PTYChaosConfig
+	rand        *rand.Rand
+	connections map[int]io.Closer
+	nextID      int
+	delayed     int64
+	dropped     int64
+	disconnects int64
+}
+
+// NewPTYChaosFromEnv returns a chaos injector when CATNIP_PTY_CHAOS=true, or nil
+func NewPTYChaosFromEnv() *PTYChaos {
+	if os.Getenv(PTYChaosEnv) != "true" {
+		return nil
+	}
+	return NewPTYChaos(rand.NewSource(time.Now().UnixNano()))
+}
+
+// NewPTYChaos creates a chaos injector with no failures configured
+func NewPTYChaos(source rand.Source) *PTYChaos {
+	return &PTYChaos{
+		rand:        rand.New(source),
+		connections: make(map[int]io.Closer),
+	}
+}
+
+// Configure replaces the injected failures
+func (c *PTYChaos) Configure(config PTYChaosConfig) error {
+	if config.LatencyMs < 0 || config.JitterMs < 0 || config.DisconnectAfterSeconds < 0 {
+		return fmt.Errorf("latency, jitter and disconnect delay must not be negative")
+	}
+	if config.DropRate < 0 || config.DropRate > 1 || config.DisconnectRate < 0 || config.DisconnectRate > 1 {
+		return fmt.Errorf("drop and disconnect rates must be between 0 and 1")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = config
+	return nil
+}
+
+// Status returns the configuration and injection counters
+func (c *PTYChaos) Status() PTYChaosStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return PTYChaosStatus{
+		Config:            c.config,
+		Connections:       len(c.connections),
+		DelayedFrames:     c.delayed,
+		DroppedFrames:     c.dropped,
+		ForcedDisconnects: c.disconnects,
+	}
+}
+
+// delayLocked picks the latency for one frame
+func (c *PTYChaos) delayLocked() time.Duration {
+	delay := time.Duration(c.config.LatencyMs) * time.Millisecond
+	if c.config.JitterMs > 0 {
+		delay += time.Duration(c.rand.Intn(c.config.JitterMs+1)) * time.Millisecond
+	}
+	if delay > 0 {
+		c.delayed++
+	}
+	return delay
+}
+
+// InputDelay returns the latency to add to a frame from the client
+func (c *PTYChaos) InputDelay() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.delayLocked()
+}
+
+// OutputFrame decides the fate of a terminal output frame on a connection
+// that connected at connectedAt. Control messages should only be delayed.
+func (c *PTYChaos) OutputFrame(connectedAt time.Time) PTYChaosAction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if after := c.config.DisconnectAfterSeconds; after > 0 && time.Since(connectedAt) >= time.Duration(after)*time.Second {
+		c.disconnects++
+		return PTYChaosAction{Disconnect: true}
+	}
+	if c.config.DisconnectRate > 0 && c.rand.Float64() < c.config.DisconnectRate {
+		c.disconnects++
+		return PTYChaosAction{Disconnect: true}
+	}
+
+	action := PTYChaosAction{Delay: c.delayLocked()}
+	if c.config.DropRate > 0 && c.rand.Float64() < c.config.DropRate {
+		c.dropped++
+		action.Drop = true
+	}
+	return action
+}
+
+// Track registers a connection so DisconnectAll can reach it. Call the
+// returned function when the connection ends.
+func (c *PTYChaos) Track(conn io.Closer) func() {
+	c.mu.Lock()
+	id := c.nextID
+	c.nextID++
+	c.connections[id] = conn
+	c.mu.Unlock()
+
+	return func() {
+		c.mu.Lock()
+		delete(c.connections, id)
+		c.mu.Unlock()
+	}
+}
+
+// DisconnectAll closes every tracked connection now and returns how many were closed
+func (c *PTYChaos) DisconnectAll() int {
+	c.mu.Lock()
+	conns := make([]io.Closer, 0, len(c.connections))
+	for id, conn := range c.connections {
+		conns = append(conns, conn)
+		delete(c.connections, id)
+	}
+	c.disconnects += int64(len(conns))
+	c.mu.Unlock()
+
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	return len(conns)
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeCounter struct {
+	closed int
+}
+
+func (c *closeCounter) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestPTYChaosInjectsNothingByDefault(t *testing.T) {
+	chaos := NewPTYChaos(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, PTYChaosAction{}, chaos.OutputFrame(time.Now()))
+	}
+	assert.Zero(t, chaos.InputDelay())
+	assert.Equal(t, PTYChaosStatus{}, chaos.Status())
+}
+
+func TestPTYChaosConfigure(t *testing.T) {
+	chaos := NewPTYChaos(rand.NewSource(1))
+	assert.Error(t, chaos.Configure(PTYChaosConfig{DropRate: 1.5}))
+	assert.Error(t, chaos.Configure(PTYChaosConfig{LatencyMs: -1}))
+
+	require.NoError(t, chaos.Configure(PTYChaosConfig{LatencyMs: 50, JitterMs: 10, DropRate: 0.5}))
+	dropped := 0
+	for i := 0; i < 200; i++ {
+		action := chaos.OutputFrame(time.Now())
+		assert.False(t, action.Disconnect)
+		assert.GreaterOrEqual(t, action.Delay, 50*time.Millisecond)
+		assert.LessOrEqual(t, action.Delay, 60*time.Millisecond)
+		if action.Drop {
+			dropped++
+		}
+	}
+	assert.InDelta(t, 100, dropped, 30)
+
+	status := chaos.Status()
+	assert.Equal(t, int64(200), status.DelayedFrames)
+	assert.Equal(t, int64(dropped), status.DroppedFrames)
+}
+
+func TestPTYChaosDisconnects(t *testing.T) {
+	chaos := NewPTYChaos(rand.NewSource(1))
+	require.NoError(t, chaos.Configure(PTYChaosConfig{DisconnectAfterSeconds: 30}))
+	assert.False(t, chaos.OutputFrame(time.Now()).Disconnect)
+	assert.True(t, chaos.OutputFrame(time.Now().Add(-time.Minute)).Disconnect)
+
+	require.NoError(t, chaos.Configure(PTYChaosConfig{DisconnectRate: 1}))
+	assert.True(t, chaos.OutputFrame(time.Now()).Disconnect)
+
+	first, second := &closeCounter{}, &closeCounter{}
+	chaos.Track(first)
+	untrack := chaos.Track(second)
+	untrack()
+	assert.Equal(t, 1, chaos.Status().Connections)
+
+	assert.Equal(t, 1, chaos.DisconnectAll())
+	assert.Equal(t, 1, first.closed)
+	assert.Equal(t, 0, second.closed)
+	assert.Equal(t, 0, chaos.Status().Connections)
+	assert.Equal(t, int64(3), chaos.Status().ForcedDisconnects)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Lifecycle events kept per session
+	maxPTYConnectionEvents = 200
+	// Sessions with events kept; the least recently active are forgotten first
+	maxPTYConnectionLogSessions = 500
+)
+
+// PTY connection lifecycle event types
+const (
+	PTYConnectionConnect    = "connect"
+	PTYConnectionDisconnect = "disconnect"
+	PTYConnectionPromote    = "promote"
+	PTYConnectionDemote     = "demote"
+	PTYConnectionFocus      = "focus"
+	PTYConnectionBlur       = "blur"
+	// A connection the server closed itself
+	PTYConnectionForceClose = "force_close"
+)
+
+// Reasons attached to PTY connection lifecycle events
+const (
+	// Closed by the cleanup that drops every existing connection when a new one arrives
+	PTYReasonNuclearCleanup = "nuclear_cleanup"
+	// Promoted because the write connection went away
+	PTYReasonWriterLeft = "writer_disconnected"
+	// The client asked for write access
+	PTYReasonPromoteRequest = "promote_request"
+	// Another connection gained focus and took write access
+	PTYReasonFocus = "focus"
+	// Closed because the session's terminal was recreated
+	PTYReasonSessionRecreated = "session_recreated"
+	// Closed after terminal output couldn't be written to it
+	PTYReasonWriteError = "write_error"
+)
+
+// PTYConnectionEvent is one step in the life of a terminal connection
+type PTYConnectionEvent struct {
+	At       time.Time `json:"at"`
+	Session  string    `json:"session" example:"app/felix:claude"`
+	ConnID   string    `json:"conn_id" example:"0xc000412000"`
+	Type     string    `json:"type" example:"force_close"`
+	Reason   string    `json:"reason,omitempty" example:"nuclear_cleanup"`
+	ConnType string    `json:"conn_type,omitempty" example:"websocket"`
+	Remote   string    `json:"remote,omitempty" example:"127.0.0.1:53122"`
+	ReadOnly bool      `json:"read_only"`
+	// Connections open in the session after the event
+	Connections int `json:"connections"`
+}
+
+// PTYNuclearCleanupStats counts the times a new connection closed every
+// existing connection of a session
+type PTYNuclearCleanupStats struct {
+	Cleanups int64 `json:"cleanups"`
+	// Connections closed by them
+	ClosedConnections int64 `json:"closed_connections"`
+	// Cleanups where a closed connection came from another address, i.e. most
+	// likely another tab or device rather than a reconnect
+	CrossClientCleanups int64      `json:"cross_client_cleanups"`
+	Last                *time.Time `json:"last,omitempty"`
+}
+
+// PTYConnectionSessionStats summarizes one session's connection lifecycle
+type PTYConnectionSessionStats struct {
+	Session   string                 `json:"session"`
+	Counts    map[string]int64       `json:"counts"`
+	Nuclear   PTYNuclearCleanupStats `json:"nuclear"`
+	LastEvent time.Time              `json:"last_event"`
+}
+
+// PTYConnectionStats summarizes connection lifecycle events since startup
+type PTYConnectionStats struct {
+	Since    time.Time                   `json:"since"`
+	Counts   map[string]int64            `json:"counts"`
+	Nuclear  PTYNuclearCleanupStats      `json:"nuclear"`
+	Sessions []PTYConnectionSessionStats `json:"sessions"`
+}
+
+type ptyConnectionSessionLog struct {
+	events []PTYConnectionEvent
+	stats  PTYConnectionSessionStats
+}
+
+// PTYConnectionLog keeps a structured log of terminal connection lifecycle
+// events per session, and counts them, so multi-tab conflicts can be
+// measured. Only recent events are kept; counts cover the process lifetime.
+type PTYConnectionLog struct {
+	mu       sync.Mutex
+	since    time.Time
+	counts   map[string]int64
+	nuclear  PTYNuclearCleanupStats
+	sessions map[string]*ptyConnectionSessionLog
+	now      func() time.Time
+}
+
+// NewPTYConnectionLog creates an empty connection log
+func NewPTYConnectionLog() *PTYConnectionLog {
+	return &PTYConnectionLog{
+		since:    time.Now(),
+		counts:   make(map[string]int64),
+		sessions: make(map[string]*ptyConnectionSessionLog),
+		now:      time.Now,
+	}
+}
+
+// Record adds an event to its session's log
+func (l *PTYConnectionLog) Record(event PTYConnectionEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if event.At.IsZero() {
+		event.At = l.now()
+	}
+	session := l.sessionLocked(event.Session)
+	session.events = append(session.events, event)
+	if len(session.events) > maxPTYConnectionEvents {
+		session.events = append([]PTYConnectionEvent(nil), session.events[len(session.events)-maxPTYConnectionEvents:]...)
+	}
+	session.stats.Counts[event.Type]++
+	session.stats.LastEvent = event.At
+	l.counts[event.Type]++
+}
+
+// RecordNuclearCleanup counts a cleanup in which the connection newConnRemote
+// closed the session's existing connections, whose remote addresses are given
+func (l *PTYConnectionLog) RecordNuclearCleanup(sessionID, newConnRemote string, closedRemotes []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	crossClient := false
+	for _, remote := range closedRemotes {
+		if remote != newConnRemote {
+			crossClient = true
+			break
+		}
+	}
+	at := l.now()
+	for _, stats := range []*PTYNuclearCleanupStats{&l.nuclear, &l.sessionLocked(sessionID).stats.Nuclear} {
+		stats.Cleanups++
+		stats.ClosedConnections += int64(len(closedRemotes))
+		if crossClient {
+			stats.CrossClientCleanups++
+		}
+		stats.Last = &at
+	}
+}
+
+// sessionLocked returns a session's log, creating it and forgetting the
+// least recently active session if there are too many. The caller must hold mu.
+func (l *PTYConnectionLog) sessionLocked(sessionID string) *ptyConnectionSessionLog {
+	session, exists := l.sessions[sessionID]
+	if exists {
+		return session
+	}
+	if len(l.sessions) >= maxPTYConnectionLogSessions {
+		oldest := ""
+		for id, candidate := range l.sessions {
+			if oldest == "" || candidate.stats.LastEvent.Before(l.sessions[oldest].stats.LastEvent) {
+				oldest = id
+			}
+		}
+		delete(l.sessions, oldest)
+	}
+	session = &ptyConnectionSessionLog{stats: PTYConnectionSessionStats{
+		Session: sessionID,
+		Counts:  make(map[string]int64),
+	}}
+	l.sessions[sessionID] = session
+	return session
+}
+
+// Events returns a session's recent events, oldest first
+func (l *PTYConnectionLog) Events(sessionID string) []PTYConnectionEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	session, exists := l.sessions[sessionID]
+	if !exists {
+		return []PTYConnectionEvent{}
+	}
+	return append([]PTYConnectionEvent{}, session.events...)
+}
+
+// Stats returns event counts overall and per session, most recently active first
+func (l *PTYConnectionLog) Stats() PTYConnectionStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := PTYConnectionStats{
+		Since:    l.since,
+		Counts:   copyCounts(l.counts),
+		Nuclear:  l.nuclear,
+		Sessions: make([]PTYConnectionSessionStats, 0, len(l.sessions)),
+	}
+	for _, session := range l.sessions {
+		sessionStats := session.stats
+		sessionStats.Counts = copyCounts(session.stats.Counts)
+		stats.Sessions = append(stats.Sessions, sessionStats)
+	}
+	sort.Slice(stats.Sessions, func(i, j int) bool {
+		return stats.Sessions[i].LastEvent.After(stats.Sessions[j].LastEvent)
+	})
+	return stats
+}
+
+func copyCounts(counts map[string]int64) map[string]int64 {
+	copied := make(map[string]int64, len(counts))
+	for key, value := range counts {
+		copied[key] = value
+	}
+	return copied
+}
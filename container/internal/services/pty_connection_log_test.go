@@ -0,0 +1,51 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTYConnectionLog(t *testing.T) {
+	log := NewPTYConnectionLog()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	log.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	log.Record(PTYConnectionEvent{Session: "app/felix:claude", ConnID: "a", Type: PTYConnectionConnect, Remote: "10.0.0.1:1", Connections: 1})
+	log.Record(PTYConnectionEvent{Session: "app/felix:claude", ConnID: "a", Type: PTYConnectionFocus, Connections: 1})
+	// A reconnect from the same tab, then a second tab taking over
+	log.Record(PTYConnectionEvent{Session: "app/felix:claude", ConnID: "a", Type: PTYConnectionForceClose, Reason: PTYReasonNuclearCleanup})
+	log.RecordNuclearCleanup("app/felix:claude", "10.0.0.1:1", []string{"10.0.0.1:1"})
+	log.RecordNuclearCleanup("app/felix:claude", "10.0.0.2:1", []string{"10.0.0.1:1", "10.0.0.1:1"})
+	log.Record(PTYConnectionEvent{Session: "app/luna:bash", ConnID: "b", Type: PTYConnectionConnect})
+
+	events := log.Events("app/felix:claude")
+	require.Len(t, events, 3)
+	assert.Equal(t, PTYConnectionConnect, events[0].Type)
+	assert.Equal(t, PTYReasonNuclearCleanup, events[2].Reason)
+	assert.True(t, events[0].At.Before(events[2].At))
+	assert.Empty(t, log.Events("app/nobody"))
+
+	stats := log.Stats()
+	assert.Equal(t, int64(2), stats.Counts[PTYConnectionConnect])
+	assert.Equal(t, int64(1), stats.Counts[PTYConnectionForceClose])
+	assert.Equal(t, int64(2), stats.Nuclear.Cleanups)
+	assert.Equal(t, int64(3), stats.Nuclear.ClosedConnections)
+	assert.Equal(t, int64(1), stats.Nuclear.CrossClientCleanups)
+	require.Len(t, stats.Sessions, 2)
+	assert.Equal(t, "app/luna:bash", stats.Sessions[0].Session, "most recently active first")
+	assert.Equal(t, int64(2), stats.Sessions[1].Nuclear.Cleanups)
+	assert.Zero(t, stats.Sessions[0].Nuclear.Cleanups)
+
+	// Only recent events are kept, but counts cover everything
+	for i := 0; i < maxPTYConnectionEvents+10; i++ {
+		log.Record(PTYConnectionEvent{Session: "app/luna:bash", ConnID: fmt.Sprint(i), Type: PTYConnectionBlur})
+	}
+	events = log.Events("app/luna:bash")
+	assert.Len(t, events, maxPTYConnectionEvents)
+	assert.Equal(t, fmt.Sprint(maxPTYConnectionEvents+9), events[len(events)-1].ConnID)
+	assert.Equal(t, int64(maxPTYConnectionEvents+10), log.Stats().Counts[PTYConnectionBlur])
+}
@@ -0,0 +1,391 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// maxMacrosPerRepo caps how many macros a repository can have
+	maxMacrosPerRepo = 100
+	// maxMacroSteps caps how many inputs a macro replays
+	maxMacroSteps = 50
+	// maxMacroStepInput caps the text typed by a single step
+	maxMacroStepInput = 4096
+	// MaxMacroStepDelay caps the pause before a step, recorded or configured
+	MaxMacroStepDelay = 5 * time.Second
+	// minRecordedMacroDelay is the shortest pause a recording keeps; shorter
+	// ones are just typing
+	minRecordedMacroDelay = 500 * time.Millisecond
+)
+
+// macroNamePattern allows short slug-like macro names ("reset-db", "seed.dev")
+var macroNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// macroParamPattern matches {{name}} placeholders in step inputs
+var macroParamPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// PTYMacroStep is one input a macro types into a terminal
+type PTYMacroStep struct {
+	// Text typed into the terminal; {{name}} placeholders are replaced by parameters
+	Input string `json:"input" example:"bin/rails db:seed SEED={{seed}}"`
+	// Press Enter after the input
+	Submit bool `json:"submit" example:"true"`
+	// Pause before typing the input, in milliseconds (at most 5000)
+	DelayMs int `json:"delay_ms,omitempty" example:"1000"`
+}
+
+// PTYMacroParam is a placeholder used by a macro's steps
+type PTYMacroParam struct {
+	Name string `json:"name" example:"seed"`
+	// Value used when replay doesn't set the parameter; without one the parameter is required
+	Default string `json:"default,omitempty" example:"demo"`
+}
+
+// PTYMacro is a named sequence of terminal inputs stored for a repository and
+// replayed into any of its sessions
+type PTYMacro struct {
+	Name        string          `json:"name" example:"reset-db"`
+	RepoID      string          `json:"repo_id" example:"acme/app"`
+	Description string          `json:"description,omitempty" example:"Reset the database, seed it and start the server"`
+	Steps       []PTYMacroStep  `json:"steps"`
+	Params      []PTYMacroParam `json:"params,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// Expand substitutes parameter values into the macro's steps. Values may not
+// contain line breaks, which would submit input the macro doesn't contain.
+func (m PTYMacro) Expand(values map[string]string) ([]PTYMacroStep, error) {
+	resolved := make(map[string]string, len(m.Params))
+	for _, param := range m.Params {
+		value, ok := values[param.Name]
+		if !ok {
+			if param.Default == "" {
+				return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "macro %s needs parameter %q", m.Name, param.Name)
+			}
+			value = param.Default
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "parameter %q may not contain line breaks", param.Name)
+		}
+		resolved[param.Name] = value
+	}
+
+	steps := make([]PTYMacroStep, len(m.Steps))
+	for i, step := range m.Steps {
+		step.Input = macroParamPattern.ReplaceAllStringFunc(step.Input, func(placeholder string) string {
+			return resolved[macroParamPattern.FindStringSubmatch(placeholder)[1]]
+		})
+		steps[i] = step
+	}
+	return steps, nil
+}
+
+// PTYMacroStore keeps terminal macros per repository, persisted in the volume
+type PTYMacroStore struct {
+	path   string
+	mu     sync.Mutex
+	macros map[string]map[string]*PTYMacro // repo ID -> name -> macro
+}
+
+// NewPTYMacroStore creates a macro store in the volume directory
+func NewPTYMacroStore() *PTYMacroStore {
+	return NewPTYMacroStoreWithPath(filepath.Join(config.Runtime.VolumeDir, "pty_macros.json"))
+}
+
+// NewPTYMacroStoreWithPath creates a macro store at an explicit path (for testing)
+func NewPTYMacroStoreWithPath(path string) *PTYMacroStore {
+	s := &PTYMacroStore{
+		path:   path,
+		macros: make(map[string]map[string]*PTYMacro),
+	}
+	if err := s.load(); err != nil {
+		logger.Warnf("⚠️ Failed to load terminal macros: %v", err)
+	}
+	return s
+}
+
+// List returns a repository's macros sorted by name
+func (s *PTYMacroStore) List(repoID string) []PTYMacro {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	macros := make([]PTYMacro, 0, len(s.macros[repoID]))
+	for _, macro := range s.macros[repoID] {
+		macros = append(macros, *macro)
+	}
+	sort.Slice(macros, func(i, j int) bool { return macros[i].Name < macros[j].Name })
+	return macros
+}
+
+// Get returns a repository's macro by name
+func (s *PTYMacroStore) Get(repoID, name string) (PTYMacro, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	macro, exists := s.macros[repoID][name]
+	if !exists {
+		return PTYMacro{}, false
+	}
+	return *macro, true
+}
+
+// Save validates a macro and creates or replaces it. Its parameters are the
+// placeholders in its steps; defaults given for them are kept.
+func (s *PTYMacroStore) Save(macro PTYMacro) (PTYMacro, error) {
+	macro.Name = strings.ToLower(strings.TrimSpace(macro.Name))
+	macro.Description = strings.TrimSpace(macro.Description)
+	if !macroNamePattern.MatchString(macro.Name) {
+		return PTYMacro{}, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid macro name %q: use up to 63 lowercase letters, digits, '.', '_' or '-'", macro.Name)
+	}
+	if macro.RepoID == "" {
+		return PTYMacro{}, models.NewAPIError(models.ErrCodeInvalidRequest, "macro %s has no repository", macro.Name)
+	}
+	if err := validateMacroSteps(macro.Steps); err != nil {
+		return PTYMacro{}, err
+	}
+	macro.Params = macroParams(macro.Steps, macro.Params)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repoMacros := s.macros[macro.RepoID]
+	if repoMacros == nil {
+		repoMacros = make(map[string]*PTYMacro)
+		s.macros[macro.RepoID] = repoMacros
+	}
+	now := time.Now()
+	macro.CreatedAt = now
+	if existing, exists := repoMacros[macro.Name]; exists {
+		macro.CreatedAt = existing.CreatedAt
+	} else if len(repoMacros) >= maxMacrosPerRepo {
+		return PTYMacro{}, models.NewAPIError(models.ErrCodeInvalidRequest, "repository %s already has %d macros", macro.RepoID, maxMacrosPerRepo)
+	}
+	macro.UpdatedAt = now
+
+	previous := repoMacros[macro.Name]
+	repoMacros[macro.Name] = &macro
+	if err := s.saveLocked(); err != nil {
+		if previous != nil {
+			repoMacros[macro.Name] = previous
+		} else {
+			delete(repoMacros, macro.Name)
+		}
+		return PTYMacro{}, err
+	}
+	return macro, nil
+}
+
+// Delete removes a repository's macro
+func (s *PTYMacroStore) Delete(repoID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	macro, exists := s.macros[repoID][name]
+	if !exists {
+		return fmt.Errorf("repository %s has no macro %q", repoID, name)
+	}
+	delete(s.macros[repoID], name)
+	if len(s.macros[repoID]) == 0 {
+		delete(s.macros, repoID)
+	}
+	if err := s.saveLocked(); err != nil {
+		if s.macros[repoID] == nil {
+			s.macros[repoID] = make(map[string]*PTYMacro)
+		}
+		s.macros[repoID][name] = macro
+		return err
+	}
+	return nil
+}
+
+func validateMacroSteps(steps []PTYMacroStep) error {
+	if len(steps) == 0 {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "a macro needs at least one step")
+	}
+	if len(steps) > maxMacroSteps {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "a macro can have at most %d steps", maxMacroSteps)
+	}
+	for i, step := range steps {
+		switch {
+		case step.Input == "" && !step.Submit:
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "step %d types nothing", i+1)
+		case len(step.Input) > maxMacroStepInput:
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "step %d is longer than %d bytes", i+1, maxMacroStepInput)
+		case step.DelayMs < 0 || time.Duration(step.DelayMs)*time.Millisecond > MaxMacroStepDelay:
+			return models.NewAPIError(models.ErrCodeInvalidRequest, "step %d delay must be between 0 and %d ms", i+1, MaxMacroStepDelay.Milliseconds())
+		}
+	}
+	return nil
+}
+
+// macroParams lists the placeholders used by steps in order of first use,
+// keeping the defaults of previously declared parameters
+func macroParams(steps []PTYMacroStep, declared []PTYMacroParam) []PTYMacroParam {
+	defaults := make(map[string]string, len(declared))
+	for _, param := range declared {
+		defaults[param.Name] = param.Default
+	}
+	seen := make(map[string]bool)
+	var params []PTYMacroParam
+	for _, step := range steps {
+		for _, match := range macroParamPattern.FindAllStringSubmatch(step.Input, -1) {
+			if name := match[1]; !seen[name] {
+				seen[name] = true
+				params = append(params, PTYMacroParam{Name: name, Default: defaults[name]})
+			}
+		}
+	}
+	return params
+}
+
+func (s *PTYMacroStore) load() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var macros []*PTYMacro
+	if err := json.Unmarshal(data, &macros); err != nil {
+		return err
+	}
+	for _, macro := range macros {
+		if s.macros[macro.RepoID] == nil {
+			s.macros[macro.RepoID] = make(map[string]*PTYMacro)
+		}
+		s.macros[macro.RepoID][macro.Name] = macro
+	}
+	return nil
+}
+
+func (s *PTYMacroStore) saveLocked() error {
+	var macros []*PTYMacro
+	for _, repoMacros := range s.macros {
+		for _, macro := range repoMacros {
+			macros = append(macros, macro)
+		}
+	}
+	sort.Slice(macros, func(i, j int) bool {
+		if macros[i].RepoID != macros[j].RepoID {
+			return macros[i].RepoID < macros[j].RepoID
+		}
+		return macros[i].Name < macros[j].Name
+	})
+
+	data, err := json.MarshalIndent(macros, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// PTYMacroRecorder turns terminal input into macro steps. Each line submitted
+// with Enter becomes a step, with backspaces applied so the step holds what
+// was typed rather than the keystrokes. Cursor and other escape sequences are
+// dropped, and control keys such as Ctrl-C end a step without submitting it.
+type PTYMacroRecorder struct {
+	steps    []PTYMacroStep
+	line     []rune
+	delay    time.Duration // Pause before the current line's first keystroke
+	lastStep time.Time
+	escape   int // 0 outside an escape sequence, 1 after ESC, 2 inside CSI/SS3
+	now      func() time.Time
+}
+
+// NewPTYMacroRecorder starts recording
+func NewPTYMacroRecorder() *PTYMacroRecorder {
+	return newPTYMacroRecorder(time.Now)
+}
+
+func newPTYMacroRecorder(now func() time.Time) *PTYMacroRecorder {
+	return &PTYMacroRecorder{now: now, lastStep: now()}
+}
+
+// Record adds terminal input
+func (r *PTYMacroRecorder) Record(data string) {
+	for len(data) > 0 {
+		c, size := utf8.DecodeRuneInString(data)
+		data = data[size:]
+
+		switch r.escape {
+		case 1:
+			r.escape = 0
+			if c == '[' || c == 'O' {
+				r.escape = 2
+			}
+			continue
+		case 2:
+			if c >= 0x40 && c <= 0x7e {
+				r.escape = 0
+			}
+			continue
+		}
+
+		switch {
+		case c == 0x1b:
+			r.escape = 1
+		case c == '\r' || c == '\n':
+			r.endStep("", true)
+		case c == 0x7f || c == '\b':
+			if len(r.line) > 0 {
+				r.line = r.line[:len(r.line)-1]
+			}
+		case c < 0x20 && c != '\t':
+			r.endStep(string(c), false)
+		default:
+			if len(r.line) == 0 {
+				r.delay = r.now().Sub(r.lastStep)
+			}
+			r.line = append(r.line, c)
+		}
+	}
+}
+
+// endStep finishes the current line as a step, followed by suffix
+func (r *PTYMacroRecorder) endStep(suffix string, submit bool) {
+	if len(r.line) == 0 {
+		r.delay = r.now().Sub(r.lastStep)
+	}
+	step := PTYMacroStep{Input: string(r.line) + suffix, Submit: submit}
+	if r.delay >= minRecordedMacroDelay {
+		step.DelayMs = int(min(r.delay, MaxMacroStepDelay).Round(100 * time.Millisecond).Milliseconds())
+	}
+	if len(r.steps) == 0 {
+		step.DelayMs = 0
+	}
+	r.steps = append(r.steps, step)
+	r.line = nil
+	r.lastStep = r.now()
+}
+
+// Steps returns the recorded steps; a line typed without pressing Enter is
+// kept as a final step that isn't submitted
+func (r *PTYMacroRecorder) Steps() []PTYMacroStep {
+	steps := append([]PTYMacroStep{}, r.steps...)
+	if len(r.line) > 0 {
+		steps = append(steps, PTYMacroStep{Input: string(r.line)})
+	}
+	return steps
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestPTYMacroStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pty_macros.json")
+	store := NewPTYMacroStoreWithPath(path)
+
+	macro, err := store.Save(PTYMacro{
+		Name:   "Reset-DB",
+		RepoID: "acme/app",
+		Steps: []PTYMacroStep{
+			{Input: "bin/rails db:reset", Submit: true},
+			{Input: "bin/rails db:seed SEED={{ seed }} SCALE={{scale}}", Submit: true, DelayMs: 1000},
+			{Input: "bin/dev --port {{scale}}", Submit: true},
+		},
+		Params: []PTYMacroParam{{Name: "scale", Default: "1"}, {Name: "unused", Default: "x"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "reset-db", macro.Name)
+	assert.Equal(t, []PTYMacroParam{{Name: "seed"}, {Name: "scale", Default: "1"}}, macro.Params)
+
+	_, err = store.Save(PTYMacro{Name: "other", RepoID: "acme/api", Steps: []PTYMacroStep{{Input: "make", Submit: true}}})
+	require.NoError(t, err)
+	assert.Len(t, store.List("acme/app"), 1, "macros are per repository")
+
+	// Macros survive a restart
+	reloaded := NewPTYMacroStoreWithPath(path)
+	stored, exists := reloaded.Get("acme/app", "reset-db")
+	require.True(t, exists)
+	assert.Equal(t, macro.CreatedAt.Unix(), stored.CreatedAt.Unix())
+
+	require.NoError(t, reloaded.Delete("acme/app", "reset-db"))
+	assert.Error(t, reloaded.Delete("acme/app", "reset-db"))
+	assert.Empty(t, NewPTYMacroStoreWithPath(path).List("acme/app"))
+}
+
+func TestPTYMacroStoreValidation(t *testing.T) {
+	store := NewPTYMacroStoreWithPath(filepath.Join(t.TempDir(), "pty_macros.json"))
+	for _, invalid := range []PTYMacro{
+		{Name: "has space", RepoID: "acme/app", Steps: []PTYMacroStep{{Input: "ls", Submit: true}}},
+		{Name: "empty", RepoID: "acme/app"},
+		{Name: "blank-step", RepoID: "acme/app", Steps: []PTYMacroStep{{Input: ""}}},
+		{Name: "slow", RepoID: "acme/app", Steps: []PTYMacroStep{{Input: "ls", DelayMs: 60000}}},
+		{Name: "no-repo", Steps: []PTYMacroStep{{Input: "ls", Submit: true}}},
+	} {
+		_, err := store.Save(invalid)
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err), invalid.Name)
+	}
+}
+
+func TestPTYMacroExpand(t *testing.T) {
+	macro := PTYMacro{
+		Name: "seed",
+		Steps: []PTYMacroStep{
+			{Input: "SEED={{seed}} SCALE={{ scale }} bin/seed", Submit: true},
+		},
+		Params: []PTYMacroParam{{Name: "seed"}, {Name: "scale", Default: "1"}},
+	}
+
+	steps, err := macro.Expand(map[string]string{"seed": "demo"})
+	require.NoError(t, err)
+	assert.Equal(t, "SEED=demo SCALE=1 bin/seed", steps[0].Input)
+	assert.Equal(t, "SEED={{seed}} SCALE={{ scale }} bin/seed", macro.Steps[0].Input, "the macro itself is unchanged")
+
+	_, err = macro.Expand(nil)
+	assert.ErrorContains(t, err, `needs parameter "seed"`)
+	_, err = macro.Expand(map[string]string{"seed": "demo\rrm -rf /"})
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+}
+
+func TestPTYMacroRecorder(t *testing.T) {
+	now := time.Unix(0, 0)
+	recorder := newPTYMacroRecorder(func() time.Time { return now })
+
+	recorder.Record("bin/rails db:rest\x7f\x7fset\r")
+	now = now.Add(3 * time.Second)
+	// Arrow keys are dropped; Ctrl-C ends a step without submitting it
+	recorder.Record("bin/dev\x1b[D\x1b[C")
+	recorder.Record("\x03")
+	now = now.Add(20 * time.Second)
+	recorder.Record("tail -f log/\tdev.log")
+
+	assert.Equal(t, []PTYMacroStep{
+		{Input: "bin/rails db:reset", Submit: true},
+		{Input: "bin/dev\x03", DelayMs: 3000},
+		{Input: "tail -f log/\tdev.log"},
+	}, recorder.Steps())
+
+	recorder.Record("\r")
+	steps := recorder.Steps()
+	require.Len(t, steps, 3)
+	assert.Equal(t, PTYMacroStep{Input: "tail -f log/\tdev.log", Submit: true, DelayMs: 5000}, steps[2], "long pauses are capped")
+}
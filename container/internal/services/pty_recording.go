@@ -0,0 +1,436 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+const (
+	// AsciicastContentType is the media type of asciicast v2 recordings
+	AsciicastContentType = "application/x-asciicast"
+	// MaxRecordingBytes caps the data kept in one recording; events past it are
+	// dropped and the recording marked truncated
+	MaxRecordingBytes = 50 * 1024 * 1024
+	// Events buffered per live subscriber before it is disconnected
+	recordingSubscriberBuffer = 256
+)
+
+// Asciicast v2 event types
+const (
+	AsciicastOutput = "o"
+	AsciicastInput  = "i"
+	AsciicastResize = "r"
+	AsciicastMarker = "m"
+)
+
+// AsciicastPromptMarker labels the markers recorded where input was
+// submitted, the prompt boundaries of a capture
+const AsciicastPromptMarker = "prompt"
+
+// AsciicastHeader is the first line of an asciicast v2 recording
+type AsciicastHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Duration  float64           `json:"duration,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// AsciicastEvent is one line of an asciicast v2 recording, encoded as
+// [time, type, data]
+type AsciicastEvent struct {
+	Time float64 // Seconds since the start of the recording
+	Type string
+	Data string
+}
+
+// MarshalJSON encodes the event as an asciicast v2 event array
+func (e AsciicastEvent) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode([]interface{}{roundEventTime(e.Time), e.Type, e.Data}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// UnmarshalJSON decodes an asciicast v2 event array
+func (e *AsciicastEvent) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("asciicast event must have 3 elements, got %d", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &e.Time); err != nil {
+		return fmt.Errorf("invalid event time: %w", err)
+	}
+	if err := json.Unmarshal(raw[1], &e.Type); err != nil {
+		return fmt.Errorf("invalid event type: %w", err)
+	}
+	if err := json.Unmarshal(raw[2], &e.Data); err != nil {
+		return fmt.Errorf("invalid event data: %w", err)
+	}
+	return nil
+}
+
+// roundEventTime keeps microsecond precision, like asciinema itself
+func roundEventTime(t float64) float64 {
+	return float64(int64(t*1e6+0.5)) / 1e6
+}
+
+// AsciicastResizeData formats a terminal size as resize event data
+func AsciicastResizeData(cols, rows int) string {
+	return fmt.Sprintf("%dx%d", cols, rows)
+}
+
+// splitUTF8 returns the longest prefix of b that does not end in a truncated
+// UTF-8 sequence, and the truncated remainder
+func splitUTF8(b []byte) ([]byte, []byte) {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i], b[i:]
+			}
+			break
+		}
+	}
+	return b, nil
+}
+
+// AsciicastWriter writes an asciicast v2 recording. Output split in the middle
+// of a UTF-8 sequence is held back until the rest of the sequence arrives.
+type AsciicastWriter struct {
+	enc   *json.Encoder
+	carry []byte
+}
+
+// NewAsciicastWriter writes the header and returns a writer for the events
+func NewAsciicastWriter(w io.Writer, header AsciicastHeader) (*AsciicastWriter, error) {
+	header.Version = 2
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+	return &AsciicastWriter{enc: enc}, nil
+}
+
+// WriteEvent writes a single event line
+func (w *AsciicastWriter) WriteEvent(event AsciicastEvent) error {
+	return w.enc.Encode(event)
+}
+
+// WriteOutput writes terminal output captured elapsed seconds into the recording
+func (w *AsciicastWriter) WriteOutput(elapsed float64, data []byte) error {
+	complete, rest := splitUTF8(append(w.carry, data...))
+	w.carry = append([]byte(nil), rest...)
+	if len(complete) == 0 {
+		return nil
+	}
+	return w.WriteEvent(AsciicastEvent{Time: elapsed, Type: AsciicastOutput, Data: string(complete)})
+}
+
+// sessionRecording is a recording in progress. It is kept in memory as
+// asciicast events and saved as a capture when it stops.
+type sessionRecording struct {
+	info        PTYCapture
+	header      AsciicastHeader
+	start       time.Time
+	events      []AsciicastEvent
+	size        int
+	carry       []byte
+	subscribers map[chan AsciicastEvent]struct{}
+}
+
+// PTYRecorder records PTY sessions on request. A recording can be exported
+// or streamed as asciicast v2 while it runs, and is saved to disk as a
+// capture when it stops: <id>.json (the CaptureMetadata) and <id>.info.json
+// (its PTYCapture).
+type PTYRecorder struct {
+	dir      string
+	mu       sync.Mutex
+	sessions map[string]*sessionRecording
+	maxBytes int
+	now      func() time.Time
+}
+
+// NewPTYRecorder saves recordings in the volume's captures directory
+func NewPTYRecorder() *PTYRecorder {
+	return NewPTYRecorderWithDir(filepath.Join(config.Runtime.VolumeDir, "captures"))
+}
+
+// NewPTYRecorderWithDir saves recordings in dir (for testing)
+func NewPTYRecorderWithDir(dir string) *PTYRecorder {
+	return &PTYRecorder{
+		dir:      dir,
+		sessions: make(map[string]*sessionRecording),
+		maxBytes: MaxRecordingBytes,
+		now:      time.Now,
+	}
+}
+
+// Start begins recording a session. A session has at most one recording
+// running at a time.
+func (r *PTYRecorder) Start(sessionID, label string, cols, rows int, env map[string]string) (*PTYCapture, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.sessions[sessionID]; exists {
+		return nil, fmt.Errorf("session %s is already being recorded (capture %s)", sessionID, existing.info.ID)
+	}
+	now := r.now()
+	label = strings.TrimSpace(label)
+	title := sessionID
+	if label != "" {
+		title = label
+	}
+	rec := &sessionRecording{
+		info: PTYCapture{
+			ID:        uuid.New().String(),
+			SessionID: sessionID,
+			Label:     label,
+			Cols:      cols,
+			Rows:      rows,
+			StartedAt: now,
+			Active:    true,
+		},
+		header: AsciicastHeader{
+			Version:   2,
+			Width:     cols,
+			Height:    rows,
+			Timestamp: now.Unix(),
+			Title:     title,
+			Env:       env,
+		},
+		start:       now,
+		subscribers: make(map[chan AsciicastEvent]struct{}),
+	}
+	r.sessions[sessionID] = rec
+	info := rec.info
+	return &info, nil
+}
+
+// Recording reports whether a session is being recorded
+func (r *PTYRecorder) Recording(sessionID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exists := r.sessions[sessionID]
+	return exists
+}
+
+// Output records terminal output for a session
+func (r *PTYRecorder) Output(sessionID string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, exists := r.sessions[sessionID]
+	if !exists || rec.info.Truncated {
+		return
+	}
+	complete, rest := splitUTF8(append(rec.carry, data...))
+	rec.carry = append([]byte(nil), rest...)
+	if len(complete) == 0 {
+		return
+	}
+	r.append(rec, AsciicastOutput, string(complete))
+}
+
+// Resize records a terminal size change for a session
+func (r *PTYRecorder) Resize(sessionID string, cols, rows int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, exists := r.sessions[sessionID]; exists && !rec.info.Truncated {
+		r.append(rec, AsciicastResize, AsciicastResizeData(cols, rows))
+	}
+}
+
+// Boundary marks where the user submitted input in a session
+func (r *PTYRecorder) Boundary(sessionID string) {
+	r.Marker(sessionID, AsciicastPromptMarker)
+}
+
+// Marker records a labelled marker for a session and returns its time in
+// the recording, or 0 if the session isn't being recorded
+func (r *PTYRecorder) Marker(sessionID, label string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, exists := r.sessions[sessionID]
+	if !exists || rec.info.Truncated {
+		return 0
+	}
+	r.append(rec, AsciicastMarker, label)
+	return rec.events[len(rec.events)-1].Time
+}
+
+func (r *PTYRecorder) append(rec *sessionRecording, eventType, data string) {
+	// Past the cap the recording is marked truncated and stops growing; what
+	// it holds so far is still saved
+	if rec.size+len(data) > r.maxBytes {
+		rec.info.Truncated = true
+		return
+	}
+	event := AsciicastEvent{
+		Time: r.now().Sub(rec.start).Seconds(),
+		Type: eventType,
+		Data: data,
+	}
+	rec.events = append(rec.events, event)
+	rec.size += len(data)
+
+	for ch := range rec.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// A dropped event would corrupt the player's screen, so disconnect
+			// slow subscribers and let them reload the recording
+			delete(rec.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Stop ends a session's recording and saves it as a capture. Live
+// subscribers are disconnected.
+func (r *PTYRecorder) Stop(sessionID string) (*PTYCapture, error) {
+	r.mu.Lock()
+	rec, exists := r.sessions[sessionID]
+	if !exists {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("session %s is not being recorded", sessionID)
+	}
+	delete(r.sessions, sessionID)
+	rec.closeSubscribers()
+	stopped := r.now()
+	r.mu.Unlock()
+
+	capture := rec.captureMetadata()
+	capture.DurationSeconds = roundEventTime(stopped.Sub(rec.start).Seconds())
+	info := rec.info
+	info.StoppedAt = &stopped
+	info.Active = false
+	info.TotalBytes = capture.TotalBytes
+	info.Events = len(capture.Events)
+	info.DurationSeconds = capture.DurationSeconds
+
+	if err := r.save(&info, capture); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// captureMetadata converts the recording to a capture: output events keep
+// their bytes and prompt markers become boundaries
+func (rec *sessionRecording) captureMetadata() *CaptureMetadata {
+	capture := &CaptureMetadata{CaptureDate: rec.start, Events: []CaptureEvent{}}
+	for _, event := range rec.events {
+		timestampMs := int(event.Time * 1000)
+		switch {
+		case event.Type == AsciicastOutput:
+			capture.Events = append(capture.Events, CaptureEvent{TimestampMs: timestampMs, Data: []byte(event.Data)})
+			capture.TotalBytes += len(event.Data)
+		case event.Type == AsciicastMarker && event.Data == AsciicastPromptMarker:
+			capture.Events = append(capture.Events, CaptureEvent{TimestampMs: timestampMs, Boundary: true})
+		}
+	}
+	return capture
+}
+
+// Export writes a session's recording so far as an asciicast v2 file
+func (r *PTYRecorder) Export(sessionID string, w io.Writer) error {
+	r.mu.Lock()
+	rec, exists := r.sessions[sessionID]
+	if !exists {
+		r.mu.Unlock()
+		return fmt.Errorf("no recording for session %s", sessionID)
+	}
+	header := rec.header
+	header.Duration = roundEventTime(r.now().Sub(rec.start).Seconds())
+	events := append([]AsciicastEvent(nil), rec.events...)
+	r.mu.Unlock()
+
+	writer, err := NewAsciicastWriter(w, header)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := writer.WriteEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe returns the recording so far and a channel receiving new events.
+// The channel is closed when the recording stops or the subscriber falls
+// behind; cancel must be called once the subscriber is done.
+func (r *PTYRecorder) Subscribe(sessionID string) (AsciicastHeader, []AsciicastEvent, <-chan AsciicastEvent, func(), bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, exists := r.sessions[sessionID]
+	if !exists {
+		return AsciicastHeader{}, nil, nil, func() {}, false
+	}
+	ch := make(chan AsciicastEvent, recordingSubscriberBuffer)
+	rec.subscribers[ch] = struct{}{}
+	cancel := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if _, ok := rec.subscribers[ch]; ok {
+			delete(rec.subscribers, ch)
+			close(ch)
+		}
+	}
+	return rec.header, append([]AsciicastEvent(nil), rec.events...), ch, cancel, true
+}
+
+// SessionIDs lists the sessions being recorded, sorted
+func (r *PTYRecorder) SessionIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Forget drops a session's recording without saving it and disconnects its
+// live subscribers
+func (r *PTYRecorder) Forget(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, exists := r.sessions[sessionID]; exists {
+		rec.closeSubscribers()
+		delete(r.sessions, sessionID)
+	}
+}
+
+func (rec *sessionRecording) closeSubscribers() {
+	for ch := range rec.subscribers {
+		delete(rec.subscribers, ch)
+		close(ch)
+	}
+}
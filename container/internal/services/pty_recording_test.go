@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRecorder(t *testing.T) (*PTYRecorder, *time.Time) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	recorder := NewPTYRecorderWithDir(t.TempDir())
+	recorder.now = func() time.Time { return now }
+	return recorder, &now
+}
+
+func parseAsciicast(t *testing.T, data []byte) (AsciicastHeader, []AsciicastEvent) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	require.True(t, scanner.Scan())
+	var header AsciicastHeader
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &header))
+
+	var events []AsciicastEvent
+	for scanner.Scan() {
+		var event AsciicastEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return header, events
+}
+
+func TestPTYRecorderExport(t *testing.T) {
+	recorder, now := newTestRecorder(t)
+	_, err := recorder.Start("ws:claude", "", 80, 24, map[string]string{"TERM": "xterm-direct"})
+	require.NoError(t, err)
+
+	recorder.Output("ws:claude", []byte("hello "))
+	*now = now.Add(1500 * time.Millisecond)
+	// A multi-byte rune split across reads is held until it is complete
+	recorder.Output("ws:claude", []byte("caf\xc3"))
+	recorder.Output("ws:claude", []byte("\xa9 <ok>\r\n"))
+	*now = now.Add(time.Second)
+	recorder.Resize("ws:claude", 120, 30)
+	recorder.Output("unknown", []byte("ignored"))
+
+	var buf bytes.Buffer
+	require.NoError(t, recorder.Export("ws:claude", &buf))
+	assert.Contains(t, buf.String(), ` <ok>`)
+
+	header, events := parseAsciicast(t, buf.Bytes())
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, 80, header.Width)
+	assert.Equal(t, 24, header.Height)
+	assert.Equal(t, "ws:claude", header.Title)
+	assert.Equal(t, now.Add(-2500*time.Millisecond).Unix(), header.Timestamp)
+	assert.Equal(t, 2.5, header.Duration)
+	assert.Equal(t, "xterm-direct", header.Env["TERM"])
+
+	assert.Equal(t, []AsciicastEvent{
+		{Time: 0, Type: AsciicastOutput, Data: "hello "},
+		{Time: 1.5, Type: AsciicastOutput, Data: "caf"},
+		{Time: 1.5, Type: AsciicastOutput, Data: "é <ok>\r\n"},
+		{Time: 2.5, Type: AsciicastResize, Data: "120x30"},
+	}, events)
+
+	recorder.Forget("ws:claude")
+	assert.Error(t, recorder.Export("ws:claude", &buf))
+}
+
+func TestPTYRecorderStartStop(t *testing.T) {
+	recorder, now := newTestRecorder(t)
+
+	// Nothing is recorded until asked
+	recorder.Output("ws", []byte("unrecorded"))
+	assert.False(t, recorder.Recording("ws"))
+	assert.Error(t, recorder.Export("ws", &bytes.Buffer{}))
+	_, err := recorder.Stop("ws")
+	assert.Error(t, err)
+
+	_, err = recorder.Start("ws", "", 80, 24, nil)
+	require.NoError(t, err)
+	_, err = recorder.Start("ws", "", 80, 24, nil)
+	assert.Error(t, err, "one recording per session")
+	assert.True(t, recorder.Recording("ws"))
+	recorder.Output("ws", []byte("recorded"))
+	_, _, events, _, _ := recorder.Subscribe("ws")
+
+	*now = now.Add(2 * time.Second)
+	_, err = recorder.Stop("ws")
+	require.NoError(t, err)
+	assert.False(t, recorder.Recording("ws"))
+	_, open := <-events
+	assert.False(t, open, "stopping disconnects live subscribers")
+	recorder.Output("ws", []byte("after stop"))
+	assert.Empty(t, recorder.SessionIDs())
+}
+
+func TestPTYRecorderTruncates(t *testing.T) {
+	recorder, _ := newTestRecorder(t)
+	recorder.maxBytes = 10
+
+	_, err := recorder.Start("ws", "", 80, 24, nil)
+	require.NoError(t, err)
+	recorder.Output("ws", []byte("12345678"))
+	recorder.Output("ws", []byte("too much"))
+	recorder.Output("ws", []byte("ok"))
+
+	stopped, err := recorder.Stop("ws")
+	require.NoError(t, err)
+	assert.True(t, stopped.Truncated)
+	assert.Equal(t, 8, stopped.TotalBytes, "nothing is kept once truncated")
+	assert.Equal(t, 1, stopped.Events)
+}
+
+func TestPTYRecorderSubscribe(t *testing.T) {
+	recorder, _ := newTestRecorder(t)
+	_, _, _, _, ok := recorder.Subscribe("ws")
+	assert.False(t, ok)
+
+	_, err := recorder.Start("ws", "", 80, 24, nil)
+	require.NoError(t, err)
+	recorder.Output("ws", []byte("before"))
+
+	header, backlog, events, cancel, ok := recorder.Subscribe("ws")
+	require.True(t, ok)
+	assert.Equal(t, 80, header.Width)
+	require.Len(t, backlog, 1)
+	assert.Equal(t, "before", backlog[0].Data)
+
+	recorder.Output("ws", []byte("after"))
+	assert.Equal(t, "after", (<-events).Data)
+
+	cancel()
+	_, open := <-events
+	assert.False(t, open)
+	cancel()
+
+	// Ending the session disconnects live subscribers
+	_, _, events, _, _ = recorder.Subscribe("ws")
+	recorder.Forget("ws")
+	_, open = <-events
+	assert.False(t, open)
+}
+
+func TestAsciicastWriter(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := NewAsciicastWriter(&buf, AsciicastHeader{Width: 65, Height: 15})
+	require.NoError(t, err)
+	require.NoError(t, writer.WriteOutput(0.25, []byte("\x1b[31m\xe2\x9c")))
+	require.NoError(t, writer.WriteOutput(0.5, []byte("\x93 done")))
+
+	header, events := parseAsciicast(t, buf.Bytes())
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, []AsciicastEvent{
+		{Time: 0.25, Type: AsciicastOutput, Data: "\x1b[31m"},
+		{Time: 0.5, Type: AsciicastOutput, Data: "✓ done"},
+	}, events)
+}
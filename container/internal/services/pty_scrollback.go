@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+)
+
+// MaxScrollbackBytes caps the output saved per session; older output is
+// dropped from the front
+const MaxScrollbackBytes = 1024 * 1024
+
+// ScrollbackRetention is how long a saved scrollback is kept once its
+// session stops updating it
+const ScrollbackRetention = 7 * 24 * time.Hour
+
+// PTYScrollback is a terminal session's output saved to the volume, so a
+// session re-spawned after a container restart starts with its history
+type PTYScrollback struct {
+	SessionID string    `json:"session_id"`
+	WorkDir   string    `json:"work_dir"`
+	Agent     string    `json:"agent,omitempty"`
+	Cols      int       `json:"cols"`
+	Rows      int       `json:"rows"`
+	SavedAt   time.Time `json:"saved_at"`
+	Output    []byte    `json:"output"`
+}
+
+// Restored is the saved output followed by a notice that the process behind
+// it was restarted, ready to seed a new session's buffer
+func (sb *PTYScrollback) Restored() []byte {
+	var buf bytes.Buffer
+	buf.Write(sb.Output)
+	if len(sb.Output) > 0 && !bytes.HasSuffix(sb.Output, []byte("\n")) {
+		buf.WriteString("\r\n")
+	}
+	fmt.Fprintf(&buf, "\x1b[0m\x1b[2m── session restored from %s, the shell was restarted ──\x1b[0m\r\n",
+		sb.SavedAt.Local().Format("Jan 2 15:04:05"))
+	return buf.Bytes()
+}
+
+// PTYScrollbackStore keeps the scrollback of terminal sessions on disk, one
+// <escaped session id>.json file per session
+type PTYScrollbackStore struct {
+	dir      string
+	mu       sync.Mutex
+	versions map[string]string
+	maxBytes int
+	now      func() time.Time
+}
+
+// NewPTYScrollbackStore keeps scrollback in the volume's scrollback directory
+func NewPTYScrollbackStore() *PTYScrollbackStore {
+	return NewPTYScrollbackStoreWithDir(filepath.Join(config.Runtime.VolumeDir, "scrollback"))
+}
+
+// NewPTYScrollbackStoreWithDir keeps scrollback in dir (for testing)
+func NewPTYScrollbackStoreWithDir(dir string) *PTYScrollbackStore {
+	return &PTYScrollbackStore{
+		dir:      dir,
+		versions: make(map[string]string),
+		maxBytes: MaxScrollbackBytes,
+		now:      time.Now,
+	}
+}
+
+// Save writes a session's scrollback. version identifies the buffer's
+// contents (e.g. its ID and output offset); a scrollback already saved at
+// the same version isn't written again. It reports whether it wrote.
+func (s *PTYScrollbackStore) Save(sb PTYScrollback, version string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.versions[sb.SessionID] == version {
+		return false, nil
+	}
+	sb.Output = trimScrollback(sb.Output, s.maxBytes)
+	sb.SavedAt = s.now()
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return false, err
+	}
+	if err := writeJSONFile(s.path(sb.SessionID), sb); err != nil {
+		return false, fmt.Errorf("failed to save scrollback of %s: %w", sb.SessionID, err)
+	}
+	s.versions[sb.SessionID] = version
+	return true, nil
+}
+
+// Load returns a session's saved scrollback
+func (s *PTYScrollbackStore) Load(sessionID string) (*PTYScrollback, bool) {
+	data, err := os.ReadFile(s.path(sessionID))
+	if err != nil {
+		return nil, false
+	}
+	var sb PTYScrollback
+	if err := json.Unmarshal(data, &sb); err != nil || sb.SessionID != sessionID {
+		return nil, false
+	}
+	return &sb, true
+}
+
+// Forget removes a session's saved scrollback
+func (s *PTYScrollbackStore) Forget(sessionID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.versions, sessionID)
+	_ = os.Remove(s.path(sessionID))
+}
+
+// Prune removes scrollback not saved within maxAge and returns how many
+// sessions' scrollback it removed
+func (s *PTYScrollbackStore) Prune(maxAge time.Duration) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	cutoff := s.now().Add(-maxAge)
+	removed := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+func (s *PTYScrollbackStore) path(sessionID string) string {
+	return filepath.Join(s.dir, url.PathEscape(sessionID)+".json")
+}
+
+// trimScrollback keeps the last maxBytes of output, starting at a line so
+// the replay doesn't open inside an escape sequence
+func trimScrollback(output []byte, maxBytes int) []byte {
+	if len(output) <= maxBytes {
+		return output
+	}
+	tail := output[len(output)-maxBytes:]
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return bytes.Clone(tail)
+}
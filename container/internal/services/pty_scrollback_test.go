@@ -0,0 +1,79 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTYScrollbackStore(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	store := NewPTYScrollbackStoreWithDir(dir)
+	store.now = func() time.Time { return now }
+
+	scrollback := PTYScrollback{SessionID: "catnip/main:bash", WorkDir: "/workspace/catnip/main", Cols: 120, Rows: 40, Output: []byte("$ ls\r\nREADME.md\r\n")}
+	wrote, err := store.Save(scrollback, "buf:18")
+	require.NoError(t, err)
+	assert.True(t, wrote)
+	assert.FileExists(t, filepath.Join(dir, "catnip%2Fmain:bash.json"))
+
+	wrote, err = store.Save(scrollback, "buf:18")
+	require.NoError(t, err)
+	assert.False(t, wrote, "unchanged buffers aren't rewritten")
+
+	loaded, ok := store.Load("catnip/main:bash")
+	require.True(t, ok)
+	assert.Equal(t, "/workspace/catnip/main", loaded.WorkDir)
+	assert.Equal(t, 120, loaded.Cols)
+	assert.Equal(t, now, loaded.SavedAt.UTC())
+	assert.Equal(t, "$ ls\r\nREADME.md\r\n", string(loaded.Output))
+
+	restored := string(loaded.Restored())
+	assert.True(t, strings.HasPrefix(restored, "$ ls\r\nREADME.md\r\n\x1b[0m"))
+	assert.Contains(t, restored, "session restored")
+
+	_, ok = store.Load("catnip/other:bash")
+	assert.False(t, ok)
+
+	store.Forget("catnip/main:bash")
+	_, ok = store.Load("catnip/main:bash")
+	assert.False(t, ok)
+	wrote, err = store.Save(scrollback, "buf:18")
+	require.NoError(t, err)
+	assert.True(t, wrote, "forgotten scrollback is saved again")
+}
+
+func TestPTYScrollbackStoreTrimsToLines(t *testing.T) {
+	store := NewPTYScrollbackStoreWithDir(t.TempDir())
+	store.maxBytes = 16
+
+	_, err := store.Save(PTYScrollback{SessionID: "ws:bash", Output: []byte("first line\r\nsecond\r\nthird\r\n")}, "v1")
+	require.NoError(t, err)
+	loaded, ok := store.Load("ws:bash")
+	require.True(t, ok)
+	assert.Equal(t, "second\r\nthird\r\n", string(loaded.Output))
+}
+
+func TestPTYScrollbackStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	store := NewPTYScrollbackStoreWithDir(dir)
+
+	_, err := store.Save(PTYScrollback{SessionID: "old:bash", Output: []byte("old")}, "v1")
+	require.NoError(t, err)
+	_, err = store.Save(PTYScrollback{SessionID: "new:bash", Output: []byte("new")}, "v1")
+	require.NoError(t, err)
+	stale := time.Now().Add(-8 * 24 * time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "old:bash.json"), stale, stale))
+
+	assert.Equal(t, 1, store.Prune(ScrollbackRetention))
+	_, ok := store.Load("old:bash")
+	assert.False(t, ok)
+	_, ok = store.Load("new:bash")
+	assert.True(t, ok)
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// ErrCaptureNotFound is returned for captures that don't exist
+var ErrCaptureNotFound = errors.New("capture not found")
+
+var captureIDPattern = regexp.MustCompile(`^[0-9a-f-]{36}$`)
+
+// PTYCapture describes a recording of a PTY session saved in the
+// CaptureMetadata format
+type PTYCapture struct {
+	ID              string     `json:"id" example:"3f0c2a9e-6a51-4a43-9d0e-2b8c6f1e7a10"`
+	SessionID       string     `json:"session_id" example:"catnip/main:claude"`
+	Label           string     `json:"label,omitempty" example:"login bug repro"`
+	Cols            int        `json:"cols" example:"120"`
+	Rows            int        `json:"rows" example:"40"`
+	StartedAt       time.Time  `json:"started_at"`
+	StoppedAt       *time.Time `json:"stopped_at,omitempty"`
+	TotalBytes      int        `json:"total_bytes" example:"48213"`
+	Events          int        `json:"events" example:"312"`
+	DurationSeconds float64    `json:"duration_seconds" example:"94.2"`
+	// Events past MaxRecordingBytes were dropped
+	Truncated bool `json:"truncated,omitempty"`
+	// Still recording
+	Active bool `json:"active"`
+}
+
+// save writes a stopped recording's capture and description
+func (r *PTYRecorder) save(info *PTYCapture, capture *CaptureMetadata) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	if err := writeJSONFile(r.capturePath(info.ID), capture); err != nil {
+		return fmt.Errorf("failed to save capture %s: %w", info.ID, err)
+	}
+	if err := writeJSONFile(r.infoPath(info.ID), info); err != nil {
+		_ = os.Remove(r.capturePath(info.ID))
+		return fmt.Errorf("failed to save capture %s: %w", info.ID, err)
+	}
+	return nil
+}
+
+// snapshot describes a recording in progress
+func (r *PTYRecorder) snapshot(rec *sessionRecording) PTYCapture {
+	info := rec.info
+	for _, event := range rec.events {
+		switch {
+		case event.Type == AsciicastOutput:
+			info.TotalBytes += len(event.Data)
+			info.Events++
+		case event.Type == AsciicastMarker && event.Data == AsciicastPromptMarker:
+			info.Events++
+		}
+	}
+	info.DurationSeconds = roundEventTime(r.now().Sub(rec.start).Seconds())
+	return info
+}
+
+// List returns the running and saved captures, newest first. With sessionID
+// set only that session's captures are listed.
+func (r *PTYRecorder) List(sessionID string) []PTYCapture {
+	captures := []PTYCapture{}
+	r.mu.Lock()
+	for _, rec := range r.sessions {
+		if sessionID == "" || rec.info.SessionID == sessionID {
+			captures = append(captures, r.snapshot(rec))
+		}
+	}
+	r.mu.Unlock()
+
+	paths, _ := filepath.Glob(filepath.Join(r.dir, "*.info.json"))
+	for _, path := range paths {
+		var info PTYCapture
+		data, err := os.ReadFile(path)
+		if err != nil || json.Unmarshal(data, &info) != nil {
+			continue
+		}
+		if sessionID == "" || info.SessionID == sessionID {
+			captures = append(captures, info)
+		}
+	}
+	sort.Slice(captures, func(i, j int) bool {
+		return captures[i].StartedAt.After(captures[j].StartedAt)
+	})
+	return captures
+}
+
+// Get returns a saved capture and its description
+func (r *PTYRecorder) Get(id string) (*PTYCapture, *CaptureMetadata, error) {
+	if !captureIDPattern.MatchString(id) {
+		return nil, nil, ErrCaptureNotFound
+	}
+	data, err := os.ReadFile(r.infoPath(id))
+	if os.IsNotExist(err) {
+		return nil, nil, ErrCaptureNotFound
+	} else if err != nil {
+		return nil, nil, err
+	}
+	var info PTYCapture
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse capture %s: %w", id, err)
+	}
+	capture, err := LoadCapture(r.capturePath(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &info, capture, nil
+}
+
+// Delete removes a saved capture
+func (r *PTYRecorder) Delete(id string) error {
+	if !captureIDPattern.MatchString(id) {
+		return ErrCaptureNotFound
+	}
+	if err := os.Remove(r.infoPath(id)); os.IsNotExist(err) {
+		return ErrCaptureNotFound
+	} else if err != nil {
+		return err
+	}
+	if err := os.Remove(r.capturePath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *PTYRecorder) capturePath(id string) string {
+	return filepath.Join(r.dir, id+".json")
+}
+
+func (r *PTYRecorder) infoPath(id string) string {
+	return filepath.Join(r.dir, id+".info.json")
+}
+
+// writeJSONFile writes v to path through a temporary file
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// AsciicastEvents converts a capture's events to asciicast v2. Output split
+// inside a UTF-8 sequence is joined, and prompt boundaries become markers.
+func (m *CaptureMetadata) AsciicastEvents() []AsciicastEvent {
+	events := make([]AsciicastEvent, 0, len(m.Events))
+	var carry []byte
+	for _, event := range m.Events {
+		elapsed := float64(event.TimestampMs) / 1000
+		if event.Boundary {
+			events = append(events, AsciicastEvent{Time: elapsed, Type: AsciicastMarker, Data: AsciicastPromptMarker})
+			continue
+		}
+		complete, rest := splitUTF8(append(carry, event.Data...))
+		carry = append([]byte(nil), rest...)
+		if len(complete) > 0 {
+			events = append(events, AsciicastEvent{Time: elapsed, Type: AsciicastOutput, Data: string(complete)})
+		}
+	}
+	return events
+}
+
+// WriteAsciicast writes the capture as an asciicast v2 recording. The
+// header's timestamp and duration default to the capture's.
+func (m *CaptureMetadata) WriteAsciicast(w io.Writer, header AsciicastHeader) error {
+	if header.Timestamp == 0 && !m.CaptureDate.IsZero() {
+		header.Timestamp = m.CaptureDate.Unix()
+	}
+	if header.Duration == 0 {
+		header.Duration = m.DurationSeconds
+	}
+	writer, err := NewAsciicastWriter(w, header)
+	if err != nil {
+		return err
+	}
+	for _, event := range m.AsciicastEvents() {
+		if err := writer.WriteEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPTYRecorderSavesCapture(t *testing.T) {
+	recorder, now := newTestRecorder(t)
+
+	started, err := recorder.Start("ws:claude", " repro ", 80, 24, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "repro", started.Label)
+	assert.True(t, started.Active)
+
+	recorder.Output("ws:claude", []byte("$ "))
+	*now = now.Add(1500 * time.Millisecond)
+	recorder.Boundary("ws:claude")
+	recorder.Output("ws:claude", []byte("ls\r\n"))
+	recorder.Resize("ws:claude", 100, 30)
+	recorder.Marker("ws:claude", "Bash")
+	recorder.Output("ws:other", []byte("not captured"))
+
+	// The live recording and the saved capture come from the same events
+	var buf bytes.Buffer
+	require.NoError(t, recorder.Export("ws:claude", &buf))
+	header, events := parseAsciicast(t, buf.Bytes())
+	assert.Equal(t, "repro", header.Title)
+	require.Len(t, events, 5)
+	assert.Equal(t, AsciicastEvent{Time: 1.5, Type: AsciicastMarker, Data: AsciicastPromptMarker}, events[1])
+
+	list := recorder.List("")
+	require.Len(t, list, 1)
+	assert.Equal(t, 3, list[0].Events)
+	assert.Equal(t, 6, list[0].TotalBytes)
+
+	*now = now.Add(time.Second)
+	stopped, err := recorder.Stop("ws:claude")
+	require.NoError(t, err)
+	assert.False(t, stopped.Active)
+	assert.Equal(t, 6, stopped.TotalBytes)
+	assert.Equal(t, 2.5, stopped.DurationSeconds)
+	assert.Error(t, recorder.Export("ws:claude", &buf))
+
+	// The saved file is a capture LoadCapture and capture-pty read
+	capture, err := LoadCapture(filepath.Join(recorder.dir, stopped.ID+".json"))
+	require.NoError(t, err)
+	assert.Equal(t, []CaptureEvent{
+		{TimestampMs: 0, Data: []byte("$ ")},
+		{TimestampMs: 1500, Boundary: true},
+		{TimestampMs: 1500, Data: []byte("ls\r\n")},
+	}, capture.Events)
+	assert.Equal(t, 6, capture.TotalBytes)
+
+	info, loaded, err := recorder.Get(stopped.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "ws:claude", info.SessionID)
+	assert.Equal(t, capture.Events, loaded.Events)
+
+	// A second recording of the session lists first
+	*now = now.Add(time.Minute)
+	_, err = recorder.Start("ws:claude", "", 80, 24, nil)
+	require.NoError(t, err)
+	list = recorder.List("ws:claude")
+	require.Len(t, list, 2)
+	assert.True(t, list[0].Active)
+	assert.Equal(t, stopped.ID, list[1].ID)
+	assert.Empty(t, recorder.List("ws:other"))
+	recorder.Forget("ws:claude")
+
+	require.NoError(t, recorder.Delete(stopped.ID))
+	assert.ErrorIs(t, recorder.Delete(stopped.ID), ErrCaptureNotFound)
+	_, _, err = recorder.Get(stopped.ID)
+	assert.ErrorIs(t, err, ErrCaptureNotFound)
+	_, _, err = recorder.Get("../../etc/passwd")
+	assert.ErrorIs(t, err, ErrCaptureNotFound)
+	entries, err := os.ReadDir(recorder.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCaptureMetadataWriteAsciicast(t *testing.T) {
+	capture := &CaptureMetadata{
+		CaptureDate:     time.Unix(1700000000, 0),
+		DurationSeconds: 2,
+		Events: []CaptureEvent{
+			{TimestampMs: 0, Data: []byte("caf\xc3")},
+			{TimestampMs: 250, Data: []byte("\xa9\r\n")},
+			{TimestampMs: 1000, Boundary: true},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, capture.WriteAsciicast(&buf, AsciicastHeader{Width: 100, Height: 30}))
+	header, events := parseAsciicast(t, buf.Bytes())
+	assert.Equal(t, 2, header.Version)
+	assert.Equal(t, 100, header.Width)
+	assert.Equal(t, int64(1700000000), header.Timestamp)
+	assert.Equal(t, 2.0, header.Duration)
+	assert.Equal(t, []AsciicastEvent{
+		{Time: 0, Type: AsciicastOutput, Data: "caf"},
+		{Time: 0.25, Type: AsciicastOutput, Data: "é\r\n"},
+		{Time: 1, Type: AsciicastMarker, Data: AsciicastPromptMarker},
+	}, events)
+
+	// Converting back keeps the boundary
+	path := filepath.Join(t.TempDir(), "capture.cast")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+	loaded, err := LoadCapture(path)
+	require.NoError(t, err)
+	require.Len(t, loaded.Events, 3)
+	assert.True(t, loaded.Events[2].Boundary)
+
+	data, err := json.Marshal(capture)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"captureDate"`)
+}
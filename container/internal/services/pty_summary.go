@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// TerminalSummaryModel is fast and cheap enough to run on every wall of
+	// build errors
+	TerminalSummaryModel = "claude-haiku-4-5"
+	// DefaultTerminalSummaryKB is how much recent output is summarized when
+	// no selection is given
+	DefaultTerminalSummaryKB = 16
+	// MaxTerminalSummaryKB caps the output sent to Claude; longer output
+	// keeps its end, where errors usually are
+	MaxTerminalSummaryKB = 64
+
+	terminalSummaryTimeout = 90 * time.Second
+)
+
+// TerminalSummaryRequest selects the terminal output to summarize
+type TerminalSummaryRequest struct {
+	// Selected output to summarize; the session's recent output when empty
+	Text string `json:"text,omitempty" example:"npm ERR! code ERESOLVE"`
+	// How many KB of recent output to summarize when no text is selected
+	LastKB int `json:"last_kb,omitempty" example:"16"`
+	// Optional question to focus the summary on
+	Question string `json:"question,omitempty" example:"Why did the build fail?"`
+}
+
+// TerminalSummary explains a stretch of terminal output
+type TerminalSummary struct {
+	Summary   string `json:"summary" example:"The build failed: src/app.ts:12 imports a module that doesn't exist."`
+	Model     string `json:"model" example:"claude-haiku-4-5"`
+	Lines     int    `json:"lines" example:"412"`
+	Truncated bool   `json:"truncated,omitempty" example:"false"`
+}
+
+// TerminalSummarizer explains terminal output with a small Claude model
+type TerminalSummarizer struct {
+	claude completionCreator
+}
+
+// NewTerminalSummarizer creates a summarizer backed by Claude
+func NewTerminalSummarizer(claude completionCreator) *TerminalSummarizer {
+	return &TerminalSummarizer{claude: claude}
+}
+
+// Summarize explains raw PTY output. Escape sequences are stripped and
+// redrawn lines collapsed before the text is sent; output beyond
+// MaxTerminalSummaryKB is cut from the front.
+func (s *TerminalSummarizer) Summarize(ctx context.Context, output []byte, workDir, question string) (*TerminalSummary, error) {
+	truncated := false
+	if len(output) > MaxTerminalSummaryKB*1024 {
+		output = TerminalOutputTail(output, MaxTerminalSummaryKB)
+		truncated = true
+	}
+	lines := TerminalOutputLines(output)
+	if len(lines) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "there is no output to summarize")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, terminalSummaryTimeout)
+	defer cancel()
+	response, err := s.claude.CreateCompletion(ctx, &models.CreateCompletionRequest{
+		Prompt:           terminalSummaryPrompt(lines, question),
+		SystemPrompt:     "You explain terminal output to developers. Be brief and concrete, and never invent details that aren't in the output.",
+		Model:            TerminalSummaryModel,
+		MaxTurns:         1,
+		WorkingDirectory: workDir,
+		SuppressEvents:   true,
+		DisableTools:     true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize output: %w", err)
+	}
+	summary := strings.TrimSpace(response.Response)
+	if summary == "" {
+		return nil, fmt.Errorf("claude returned an empty summary")
+	}
+
+	return &TerminalSummary{
+		Summary:   summary,
+		Model:     TerminalSummaryModel,
+		Lines:     len(lines),
+		Truncated: truncated,
+	}, nil
+}
+
+// TerminalOutputTail returns the last kb KB of output, starting at a line
+// boundary so the first line isn't cut off partway
+func TerminalOutputTail(output []byte, kb int) []byte {
+	limit := kb * 1024
+	if kb <= 0 || len(output) <= limit {
+		return output
+	}
+	tail := output[len(output)-limit:]
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return tail
+}
+
+// TerminalOutputLines turns raw PTY output into plain lines of text, the way
+// captures are compared: escape sequences and control characters are
+// dropped and consecutive redraws of a line are kept once
+func TerminalOutputLines(output []byte) []string {
+	segment := &CaptureSegment{}
+	writer := &captureTextWriter{}
+	writer.write(segment, CaptureEvent{Data: output}, nil)
+	writer.flush(segment, nil)
+	return segment.text
+}
+
+func terminalSummaryPrompt(lines []string, question string) string {
+	var prompt strings.Builder
+	prompt.WriteString(`Summarize this terminal output for a developer who doesn't want to read all of it.
+
+Start with what happened: which command ran and whether it succeeded or failed. If anything failed, give the root cause (with the file and line when the output shows them) and the most likely fix. Ignore progress bars, repeated lines and noise. Use at most 6 short bullet points.
+`)
+	if question = strings.TrimSpace(question); question != "" {
+		fmt.Fprintf(&prompt, "\nFocus on this question: %s\n", question)
+	}
+	prompt.WriteString("\n<output>\n")
+	prompt.WriteString(strings.Join(lines, "\n"))
+	prompt.WriteString("\n</output>")
+	return prompt.String()
+}
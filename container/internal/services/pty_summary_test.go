@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type recordingClaude struct {
+	requests []*models.CreateCompletionRequest
+}
+
+func (r *recordingClaude) CreateCompletion(ctx context.Context, req *models.CreateCompletionRequest) (*models.CreateCompletionResponse, error) {
+	r.requests = append(r.requests, req)
+	return &models.CreateCompletionResponse{Response: "  The build failed.\n"}, nil
+}
+
+func TestTerminalSummarizer(t *testing.T) {
+	claude := &recordingClaude{}
+	summarizer := NewTerminalSummarizer(claude)
+
+	output := "$ npm run build\r\n\x1b[31mERROR\x1b[0m src/app.ts:12 Cannot find module './db'\r\n" +
+		"Building... 10%\rBuilding... 10%\r\n"
+	summary, err := summarizer.Summarize(context.Background(), []byte(output), "/workspace/app/feature", "why did it fail?")
+	require.NoError(t, err)
+	assert.Equal(t, "The build failed.", summary.Summary)
+	assert.Equal(t, TerminalSummaryModel, summary.Model)
+	assert.Equal(t, 3, summary.Lines, "redraws of a line are sent once")
+	assert.False(t, summary.Truncated)
+
+	require.Len(t, claude.requests, 1)
+	req := claude.requests[0]
+	assert.Equal(t, TerminalSummaryModel, req.Model)
+	assert.True(t, req.DisableTools)
+	assert.Equal(t, "/workspace/app/feature", req.WorkingDirectory)
+	assert.Contains(t, req.Prompt, "<output>\n$ npm run build\nERROR src/app.ts:12 Cannot find module './db'\nBuilding... 10%\n</output>")
+	assert.Contains(t, req.Prompt, "Focus on this question: why did it fail?")
+	assert.NotContains(t, req.Prompt, "\x1b")
+
+	_, err = summarizer.Summarize(context.Background(), []byte("\x1b[2J\x1b[H\r\n"), "", "")
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err), "nothing but escape sequences")
+
+	long := strings.Repeat("noise\n", MaxTerminalSummaryKB*1024/6) + "FAIL: the last line\n"
+	summary, err = summarizer.Summarize(context.Background(), []byte(long), "", "")
+	require.NoError(t, err)
+	assert.True(t, summary.Truncated)
+	assert.True(t, strings.HasSuffix(claude.requests[len(claude.requests)-1].Prompt, "FAIL: the last line\n</output>"), "the end of long output is kept")
+}
+
+func TestTerminalOutputTail(t *testing.T) {
+	output := []byte(strings.Repeat("a", 1000) + "\n" + strings.Repeat("b", 1500) + "\n")
+	tail := TerminalOutputTail(output, 2)
+	assert.Equal(t, strings.Repeat("b", 1500)+"\n", string(tail), "starts at a line boundary")
+	assert.Equal(t, output, TerminalOutputTail(output, 4))
+}
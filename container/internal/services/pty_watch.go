@@ -0,0 +1,222 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxWatchesPerSession caps how many watches a single PTY session can have
+	MaxWatchesPerSession = 20
+	// DefaultWatchCooldown is the minimum time between notifications for one watch
+	DefaultWatchCooldown  = 10 * time.Second
+	maxWatchPatternLength = 512
+	// Longest partial line kept between reads while waiting for its newline
+	maxWatchCarry = 4096
+)
+
+var watchANSIPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]|\x1b\][^\x07]*\x07|\x1b[><]|\x1b\][^\x1b]*\x1b\\`)
+
+// SessionWatch is a pattern registered against a PTY session's output
+type SessionWatch struct {
+	ID      string `json:"id"`
+	Pattern string `json:"pattern"`
+	// Treat Pattern as a regular expression instead of a plain substring
+	Regex bool   `json:"regex"`
+	Label string `json:"label,omitempty"`
+	// Remove the watch after its first notification
+	Once bool `json:"once"`
+	// Minimum seconds between notifications (defaults to 10)
+	CooldownSeconds int        `json:"cooldown_seconds"`
+	CreatedAt       time.Time  `json:"created_at"`
+	MatchCount      int        `json:"match_count"`
+	LastMatchAt     *time.Time `json:"last_match_at,omitempty"`
+	// Matches dropped because they arrived during the cooldown
+	SuppressedCount int `json:"suppressed_count"`
+
+	re *regexp.Regexp
+}
+
+// WatchMatch is a watch hit that should be notified
+type WatchMatch struct {
+	SessionID string
+	Watch     SessionWatch
+	Match     string // Text that matched the pattern
+	Line      string // Full output line, ANSI escapes removed
+}
+
+type sessionWatchList struct {
+	watches []*SessionWatch
+	carry   string // Trailing output without a newline yet
+}
+
+// PTYWatchRegistry holds per-session output watches and matches them against
+// PTY output as it is read
+type PTYWatchRegistry struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionWatchList
+	now      func() time.Time
+}
+
+// NewPTYWatchRegistry creates an empty watch registry
+func NewPTYWatchRegistry() *PTYWatchRegistry {
+	return &PTYWatchRegistry{
+		sessions: make(map[string]*sessionWatchList),
+		now:      time.Now,
+	}
+}
+
+// Add validates and registers a watch for a session
+func (r *PTYWatchRegistry) Add(sessionID string, watch SessionWatch) (SessionWatch, error) {
+	watch.Pattern = strings.TrimSpace(watch.Pattern)
+	if watch.Pattern == "" {
+		return SessionWatch{}, fmt.Errorf("pattern is required")
+	}
+	if len(watch.Pattern) > maxWatchPatternLength {
+		return SessionWatch{}, fmt.Errorf("pattern must be at most %d characters", maxWatchPatternLength)
+	}
+	if watch.CooldownSeconds < 0 {
+		return SessionWatch{}, fmt.Errorf("cooldown_seconds must not be negative")
+	}
+	if watch.CooldownSeconds == 0 {
+		watch.CooldownSeconds = int(DefaultWatchCooldown / time.Second)
+	}
+
+	pattern := regexp.QuoteMeta(watch.Pattern)
+	if watch.Regex {
+		pattern = watch.Pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return SessionWatch{}, fmt.Errorf("invalid regex: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, exists := r.sessions[sessionID]
+	if !exists {
+		list = &sessionWatchList{}
+		r.sessions[sessionID] = list
+	}
+	if len(list.watches) >= MaxWatchesPerSession {
+		return SessionWatch{}, fmt.Errorf("session already has %d watches", MaxWatchesPerSession)
+	}
+
+	watch.ID = uuid.New().String()
+	watch.CreatedAt = r.now()
+	watch.MatchCount = 0
+	watch.LastMatchAt = nil
+	watch.SuppressedCount = 0
+	watch.re = re
+	list.watches = append(list.watches, &watch)
+	return watch, nil
+}
+
+// Remove deletes a watch, reporting whether it existed
+func (r *PTYWatchRegistry) Remove(sessionID, watchID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, exists := r.sessions[sessionID]
+	if !exists {
+		return false
+	}
+	for i, watch := range list.watches {
+		if watch.ID == watchID {
+			list.watches = append(list.watches[:i], list.watches[i+1:]...)
+			if len(list.watches) == 0 {
+				delete(r.sessions, sessionID)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// List returns a session's watches
+func (r *PTYWatchRegistry) List(sessionID string) []SessionWatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := []SessionWatch{}
+	if list, exists := r.sessions[sessionID]; exists {
+		for _, watch := range list.watches {
+			result = append(result, *watch)
+		}
+	}
+	return result
+}
+
+// Forget drops all watches for a session that has been cleaned up
+func (r *PTYWatchRegistry) Forget(sessionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, sessionID)
+}
+
+// Scan matches a chunk of PTY output against the session's watches and returns
+// the hits to notify. Output is matched a line at a time so patterns split
+// across reads still match; watches in their cooldown only count the hit.
+func (r *PTYWatchRegistry) Scan(sessionID string, data []byte) []WatchMatch {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, exists := r.sessions[sessionID]
+	if !exists || len(list.watches) == 0 {
+		return nil
+	}
+
+	text := list.carry + watchANSIPattern.ReplaceAllString(string(data), "")
+	lines := strings.FieldsFunc(text, func(c rune) bool { return c == '\n' || c == '\r' })
+	list.carry = ""
+	if len(lines) > 0 && !strings.HasSuffix(text, "\n") && !strings.HasSuffix(text, "\r") {
+		list.carry = lines[len(lines)-1]
+		lines = lines[:len(lines)-1]
+		if len(list.carry) > maxWatchCarry {
+			list.carry = list.carry[len(list.carry)-maxWatchCarry:]
+		}
+	}
+
+	var matches []WatchMatch
+	now := r.now()
+	for _, line := range lines {
+		for i := 0; i < len(list.watches); i++ {
+			watch := list.watches[i]
+			found := watch.re.FindString(line)
+			if found == "" {
+				continue
+			}
+
+			watch.MatchCount++
+			cooldown := time.Duration(watch.CooldownSeconds) * time.Second
+			if watch.LastMatchAt != nil && now.Sub(*watch.LastMatchAt) < cooldown {
+				watch.SuppressedCount++
+				continue
+			}
+			matchedAt := now
+			watch.LastMatchAt = &matchedAt
+
+			matches = append(matches, WatchMatch{
+				SessionID: sessionID,
+				Watch:     *watch,
+				Match:     found,
+				Line:      strings.TrimSpace(line),
+			})
+			if watch.Once {
+				list.watches = append(list.watches[:i], list.watches[i+1:]...)
+				i--
+			}
+		}
+	}
+
+	if len(list.watches) == 0 {
+		delete(r.sessions, sessionID)
+	}
+	return matches
+}
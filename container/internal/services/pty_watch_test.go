@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWatchRegistry() (*PTYWatchRegistry, *time.Time) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	registry := NewPTYWatchRegistry()
+	registry.now = func() time.Time { return now }
+	return registry, &now
+}
+
+func TestPTYWatchRegistryAdd(t *testing.T) {
+	registry, _ := newTestWatchRegistry()
+
+	watch, err := registry.Add("ws", SessionWatch{Pattern: "BUILD FAILED"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, watch.ID)
+	assert.Equal(t, 10, watch.CooldownSeconds)
+
+	_, err = registry.Add("ws", SessionWatch{Pattern: "  "})
+	assert.Error(t, err)
+	_, err = registry.Add("ws", SessionWatch{Pattern: "([", Regex: true})
+	assert.Error(t, err)
+
+	for i := 1; i < MaxWatchesPerSession; i++ {
+		_, err = registry.Add("ws", SessionWatch{Pattern: "x"})
+		require.NoError(t, err)
+	}
+	_, err = registry.Add("ws", SessionWatch{Pattern: "one too many"})
+	assert.Error(t, err)
+
+	assert.Len(t, registry.List("ws"), MaxWatchesPerSession)
+	assert.Empty(t, registry.List("other"))
+	assert.True(t, registry.Remove("ws", watch.ID))
+	assert.False(t, registry.Remove("ws", watch.ID))
+}
+
+func TestPTYWatchRegistryScan(t *testing.T) {
+	t.Run("matches plain strings literally and strips ANSI", func(t *testing.T) {
+		registry, _ := newTestWatchRegistry()
+		_, err := registry.Add("ws", SessionWatch{Pattern: "listening on (port)", Label: "server up"})
+		require.NoError(t, err)
+
+		assert.Empty(t, registry.Scan("ws", []byte("listening on port 3000\n")))
+		matches := registry.Scan("ws", []byte("\x1b[32mlistening on (port)\x1b[0m 3000\r\n"))
+		require.Len(t, matches, 1)
+		assert.Equal(t, "listening on (port) 3000", matches[0].Line)
+		assert.Equal(t, "server up", matches[0].Watch.Label)
+	})
+
+	t.Run("matches regexes across read boundaries", func(t *testing.T) {
+		registry, _ := newTestWatchRegistry()
+		_, err := registry.Add("ws", SessionWatch{Pattern: `listening on :\d+`, Regex: true})
+		require.NoError(t, err)
+
+		assert.Empty(t, registry.Scan("ws", []byte("server listen")))
+		matches := registry.Scan("ws", []byte("ing on :8080\n"))
+		require.Len(t, matches, 1)
+		assert.Equal(t, "listening on :8080", matches[0].Match)
+	})
+
+	t.Run("rate limits repeated matches", func(t *testing.T) {
+		registry, now := newTestWatchRegistry()
+		watch, err := registry.Add("ws", SessionWatch{Pattern: "BUILD FAILED", CooldownSeconds: 30})
+		require.NoError(t, err)
+
+		assert.Len(t, registry.Scan("ws", []byte("BUILD FAILED\nBUILD FAILED\n")), 1)
+		*now = now.Add(10 * time.Second)
+		assert.Empty(t, registry.Scan("ws", []byte("BUILD FAILED\n")))
+		*now = now.Add(30 * time.Second)
+		assert.Len(t, registry.Scan("ws", []byte("BUILD FAILED\n")), 1)
+
+		listed := registry.List("ws")
+		require.Len(t, listed, 1)
+		assert.Equal(t, watch.ID, listed[0].ID)
+		assert.Equal(t, 4, listed[0].MatchCount)
+		assert.Equal(t, 2, listed[0].SuppressedCount)
+	})
+
+	t.Run("once watches are removed after firing", func(t *testing.T) {
+		registry, _ := newTestWatchRegistry()
+		_, err := registry.Add("ws", SessionWatch{Pattern: "done", Once: true})
+		require.NoError(t, err)
+
+		assert.Len(t, registry.Scan("ws", []byte("done\ndone\n")), 1)
+		assert.Empty(t, registry.List("ws"))
+	})
+
+	t.Run("forget drops session watches", func(t *testing.T) {
+		registry, _ := newTestWatchRegistry()
+		_, err := registry.Add("ws", SessionWatch{Pattern: "done"})
+		require.NoError(t, err)
+
+		registry.Forget("ws")
+		assert.Empty(t, registry.Scan("ws", []byte("done\n")))
+	})
+}
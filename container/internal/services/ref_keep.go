@@ -0,0 +1,112 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// defaultRefCleanupGrace protects refs created or moved this recently, which
+// may belong to a worktree whose state hasn't been saved yet
+const defaultRefCleanupGrace = 15 * time.Minute
+
+// KeepCatnipRef places a keep marker on a catnip ref or legacy catnip branch
+// so cleanup never deletes it, whether or not it's tracked in state
+func (s *GitService) KeepCatnipRef(repoPath, ref string) error {
+	_, err := s.operations.ExecuteGit(repoPath, "update-ref", git.KeepRefName(ref), ref)
+	return err
+}
+
+// ReleaseCatnipRef removes a catnip ref's keep marker
+func (s *GitService) ReleaseCatnipRef(repoPath, ref string) error {
+	_, err := s.operations.ExecuteGit(repoPath, "update-ref", "-d", git.KeepRefName(ref))
+	return err
+}
+
+// refCleanupGuard decides which of a repository's untracked catnip refs
+// cleanup must still leave alone
+type refCleanupGuard struct {
+	kept      map[string]bool // Workspace names with keep markers
+	pending   map[string]bool // Workspace names of journaled operations still in flight
+	refsDir   string          // Directory holding loose refs, "" if unknown
+	grace     time.Duration
+	checkedAt time.Time
+}
+
+// newRefCleanupGuard collects the keep markers, in-flight journal entries and
+// grace period that protect refs in a repository
+func (s *GitService) newRefCleanupGuard(repoPath string) *refCleanupGuard {
+	guard := &refCleanupGuard{
+		kept:      make(map[string]bool),
+		pending:   make(map[string]bool),
+		grace:     refCleanupGraceFromEnv(),
+		checkedAt: time.Now(),
+	}
+
+	if output, err := s.operations.ExecuteGit(repoPath, "for-each-ref", "--format=%(refname)", git.CatnipKeepRefPrefix); err == nil {
+		for _, ref := range strings.Fields(string(output)) {
+			guard.kept[strings.TrimPrefix(ref, git.CatnipKeepRefPrefix)] = true
+		}
+	} else {
+		logger.Warnf("⚠️ Failed to list keep markers in %s: %v", repoPath, err)
+	}
+
+	for _, entry := range s.stateManager.PendingOperations() {
+		if entry.RepoPath != repoPath {
+			continue
+		}
+		for _, branch := range []string{entry.Branch, entry.NewBranch} {
+			if branch != "" {
+				guard.pending[git.ExtractWorkspaceName(branch)] = true
+			}
+		}
+	}
+
+	if output, err := s.operations.ExecuteGit(repoPath, "rev-parse", "--git-common-dir"); err == nil {
+		commonDir := strings.TrimSpace(string(output))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(repoPath, commonDir)
+		}
+		guard.refsDir = commonDir
+	}
+	return guard
+}
+
+// protects returns why cleanup must keep a full ref name (refs/catnip/felix
+// or refs/heads/catnip/felix), or "" if it may go. Only loose refs carry a
+// modification time. Catnip's own gc leaves refs loose; refs packed by a gc
+// run elsewhere lose their grace period.
+func (g *refCleanupGuard) protects(ref string) string {
+	name := git.ExtractWorkspaceName(strings.TrimPrefix(ref, "refs/heads/"))
+	if g.kept[name] {
+		return "keep marker"
+	}
+	if g.pending[name] {
+		return "operation in progress"
+	}
+	if g.refsDir != "" && g.grace > 0 {
+		if info, err := os.Stat(filepath.Join(g.refsDir, filepath.FromSlash(ref))); err == nil && g.checkedAt.Sub(info.ModTime()) < g.grace {
+			return "updated within the grace period"
+		}
+	}
+	return ""
+}
+
+// refCleanupGraceFromEnv reads CATNIP_REF_CLEANUP_GRACE, where 0 disables
+// the grace period
+func refCleanupGraceFromEnv() time.Duration {
+	raw := os.Getenv("CATNIP_REF_CLEANUP_GRACE")
+	if raw == "" {
+		return defaultRefCleanupGrace
+	}
+	grace, err := time.ParseDuration(raw)
+	if err != nil || grace < 0 {
+		logger.Warnf("⚠️ Ignoring invalid CATNIP_REF_CLEANUP_GRACE %q", raw)
+		return defaultRefCleanupGrace
+	}
+	return grace
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCleanupCatnipRefsHonorsProtection(t *testing.T) {
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	repoPath := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	gitCmd := func(args ...string) string {
+		output, err := service.ExecuteGit(repoPath, args...)
+		require.NoError(t, err, "git %v: %s", args, output)
+		return strings.TrimSpace(string(output))
+	}
+	gitCmd("init", "-b", "main")
+	gitCmd("config", "user.name", "Test User")
+	gitCmd("config", "user.email", "test@example.com")
+	gitCmd("commit", "--allow-empty", "-m", "initial")
+	for _, name := range []string{"orphan", "kept", "restoring", "fresh"} {
+		gitCmd("update-ref", "refs/catnip/"+name, "HEAD")
+	}
+	require.NoError(t, service.KeepCatnipRef(repoPath, "refs/catnip/kept"))
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: repoPath}))
+	journalID, err := service.stateManager.BeginOperation(JournalEntry{Op: JournalCreateWorktree, RepoPath: repoPath, Branch: "refs/catnip/restoring"})
+	require.NoError(t, err)
+
+	// Everything but "fresh" was created long before the grace period
+	old := time.Now().Add(-time.Hour)
+	for _, ref := range []string{"refs/catnip/orphan", "refs/catnip/kept", "refs/catnip/restoring", "refs/catnip-keep/kept"} {
+		require.NoError(t, os.Chtimes(filepath.Join(repoPath, ".git", filepath.FromSlash(ref)), old, old))
+	}
+
+	service.cleanupCatnipRefs()
+
+	refs := gitCmd("for-each-ref", "--format=%(refname)")
+	assert.NotContains(t, refs, "refs/catnip/orphan\n")
+	assert.Contains(t, refs, "refs/catnip/kept\n")
+	assert.Contains(t, refs, "refs/catnip-keep/kept\n", "cleanup never touches keep markers")
+	assert.Contains(t, refs, "refs/catnip/restoring\n")
+	assert.Contains(t, refs, "refs/catnip/fresh\n")
+
+	// Once released and out of the journal, untracked refs are cleaned up
+	require.NoError(t, service.ReleaseCatnipRef(repoPath, "refs/catnip/kept"))
+	service.stateManager.CompleteOperation(journalID)
+	service.cleanupCatnipRefs()
+	refs = gitCmd("for-each-ref", "--format=%(refname)")
+	assert.NotContains(t, refs, "refs/catnip/kept\n")
+	assert.NotContains(t, refs, "refs/catnip/restoring\n")
+	assert.Contains(t, refs, "refs/catnip/fresh\n")
+}
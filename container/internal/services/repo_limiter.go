@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// defaultRepoConcurrency is how many mutating operations may run at once on
+// one repository. Git worktree metadata and refs aren't safe to mutate in
+// parallel, so operations are serialized per repository by default.
+const defaultRepoConcurrency = 1
+
+// Operation names used as callers for repository slots
+const (
+	repoOpCheckout  = "checkout"
+	repoOpDelete    = "delete"
+	repoOpSync      = "sync"
+	repoOpFetch     = "fetch"
+	repoOpMerge     = "merge"
+	repoOpRemove    = "remove_repository"
+	repoOpHibernate = "hibernate"
+	repoOpSandbox   = "sandbox"
+)
+
+// RepoQueueMetrics reports queueing for one repository
+type RepoQueueMetrics struct {
+	RepoID string `json:"repo_id"`
+	Limit  int    `json:"limit"`
+	Active int    `json:"active"`
+	Queued int    `json:"queued"`
+	// Waiting operations per caller
+	QueuedByCaller map[string]int `json:"queued_by_caller,omitempty"`
+	// Operations started since startup, and how many of them had to wait
+	Acquired  int64 `json:"acquired"`
+	Waited    int64 `json:"waited"`
+	Canceled  int64 `json:"canceled"`
+	MaxWaitMs int64 `json:"max_wait_ms"`
+	AvgWaitMs int64 `json:"avg_wait_ms"`
+}
+
+type repoWaiter struct {
+	ready    chan struct{}
+	enqueued time.Time
+}
+
+type repoQueue struct {
+	limit   int
+	active  int
+	waiters map[string][]*repoWaiter
+	// Callers with waiters, in the order they'll be served next
+	callers []string
+
+	acquired    int64
+	waited      int64
+	canceled    int64
+	totalWaitMs int64
+	maxWaitMs   int64
+}
+
+func (q *repoQueue) queued() int {
+	n := 0
+	for _, waiters := range q.waiters {
+		n += len(waiters)
+	}
+	return n
+}
+
+// RepoOperationLimiter bounds concurrent mutating operations per repository.
+// Waiting operations are served round-robin between callers and FIFO within
+// a caller, so a burst from one caller can't starve the others.
+type RepoOperationLimiter struct {
+	mu    sync.Mutex
+	limit int
+	repos map[string]*repoQueue
+}
+
+// NewRepoOperationLimiter creates a limiter configured from
+// CATNIP_REPO_CONCURRENCY (default 1)
+func NewRepoOperationLimiter() *RepoOperationLimiter {
+	return NewRepoOperationLimiterWithLimit(repoConcurrencyFromEnv())
+}
+
+func repoConcurrencyFromEnv() int {
+	if raw := os.Getenv("CATNIP_REPO_CONCURRENCY"); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed >= 1 {
+			return parsed
+		}
+		logger.Warnf("⚠️ Ignoring invalid CATNIP_REPO_CONCURRENCY %q", raw)
+	}
+	return defaultRepoConcurrency
+}
+
+// NewRepoOperationLimiterWithLimit creates a limiter with an explicit limit (for testing)
+func NewRepoOperationLimiterWithLimit(limit int) *RepoOperationLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	return &RepoOperationLimiter{
+		limit: limit,
+		repos: make(map[string]*repoQueue),
+	}
+}
+
+// SetLimit changes how many operations may run at once on each repository.
+// Raising it starts queued operations; lowering it lets running ones finish.
+func (l *RepoOperationLimiter) SetLimit(limit int) {
+	if limit < 1 {
+		limit = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.limit = limit
+	for _, q := range l.repos {
+		q.limit = limit
+		q.dispatchLocked()
+	}
+}
+
+// ReloadConfig re-reads CATNIP_REPO_CONCURRENCY
+func (l *RepoOperationLimiter) ReloadConfig() {
+	l.SetLimit(repoConcurrencyFromEnv())
+}
+
+func (l *RepoOperationLimiter) queueLocked(repoID string) *repoQueue {
+	q, exists := l.repos[repoID]
+	if !exists {
+		q = &repoQueue{limit: l.limit, waiters: make(map[string][]*repoWaiter)}
+		l.repos[repoID] = q
+	}
+	return q
+}
+
+// Acquire waits for a slot on a repository. The returned release function
+// must be called when the operation finishes; it is safe to call twice.
+func (l *RepoOperationLimiter) Acquire(ctx context.Context, repoID, caller string) (func(), error) {
+	l.mu.Lock()
+	q := l.queueLocked(repoID)
+
+	if q.active < q.limit && q.queued() == 0 {
+		q.active++
+		q.acquired++
+		l.mu.Unlock()
+		return l.releaseFunc(repoID), nil
+	}
+
+	waiter := &repoWaiter{ready: make(chan struct{}), enqueued: time.Now()}
+	if len(q.waiters[caller]) == 0 {
+		q.callers = append(q.callers, caller)
+	}
+	q.waiters[caller] = append(q.waiters[caller], waiter)
+	queued := q.queued()
+	l.mu.Unlock()
+
+	logger.Debugf("⏳ Waiting for %s slot on %s (%d queued)", caller, repoID, queued)
+
+	select {
+	case <-waiter.ready:
+		return l.releaseFunc(repoID), nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		select {
+		case <-waiter.ready:
+			// Granted while canceling; hand the slot straight back
+			q.active--
+			q.dispatchLocked()
+		default:
+			q.removeWaiterLocked(caller, waiter)
+		}
+		q.canceled++
+		return nil, ctx.Err()
+	}
+}
+
+func (l *RepoOperationLimiter) releaseFunc(repoID string) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			q := l.repos[repoID]
+			q.active--
+			q.dispatchLocked()
+		})
+	}
+}
+
+// dispatchLocked hands free slots to waiters, rotating between callers
+func (q *repoQueue) dispatchLocked() {
+	for q.active < q.limit && len(q.callers) > 0 {
+		caller := q.callers[0]
+		q.callers = q.callers[1:]
+
+		waiter := q.waiters[caller][0]
+		q.waiters[caller] = q.waiters[caller][1:]
+		if len(q.waiters[caller]) > 0 {
+			q.callers = append(q.callers, caller)
+		} else {
+			delete(q.waiters, caller)
+		}
+
+		waitMs := time.Since(waiter.enqueued).Milliseconds()
+		q.active++
+		q.acquired++
+		q.waited++
+		q.totalWaitMs += waitMs
+		if waitMs > q.maxWaitMs {
+			q.maxWaitMs = waitMs
+		}
+		close(waiter.ready)
+	}
+}
+
+func (q *repoQueue) removeWaiterLocked(caller string, waiter *repoWaiter) {
+	waiters := q.waiters[caller]
+	for i, w := range waiters {
+		if w == waiter {
+			q.waiters[caller] = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(q.waiters[caller]) > 0 {
+		return
+	}
+	delete(q.waiters, caller)
+	for i, c := range q.callers {
+		if c == caller {
+			q.callers = append(q.callers[:i], q.callers[i+1:]...)
+			break
+		}
+	}
+}
+
+// Metrics returns queue metrics for every repository that has seen an operation
+func (l *RepoOperationLimiter) Metrics() []RepoQueueMetrics {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	metrics := make([]RepoQueueMetrics, 0, len(l.repos))
+	for repoID, q := range l.repos {
+		m := RepoQueueMetrics{
+			RepoID:    repoID,
+			Limit:     q.limit,
+			Active:    q.active,
+			Queued:    q.queued(),
+			Acquired:  q.acquired,
+			Waited:    q.waited,
+			Canceled:  q.canceled,
+			MaxWaitMs: q.maxWaitMs,
+		}
+		if q.waited > 0 {
+			m.AvgWaitMs = q.totalWaitMs / q.waited
+		}
+		if m.Queued > 0 {
+			m.QueuedByCaller = make(map[string]int, len(q.waiters))
+			for caller, waiters := range q.waiters {
+				m.QueuedByCaller[caller] = len(waiters)
+			}
+		}
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool { return metrics[i].RepoID < metrics[j].RepoID })
+	return metrics
+}
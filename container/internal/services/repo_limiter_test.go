@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// acquireAsync starts waiting for a slot and reports on the returned channel once granted
+func acquireAsync(t *testing.T, limiter *RepoOperationLimiter, repoID, caller string, order chan<- string) {
+	t.Helper()
+	go func() {
+		release, err := limiter.Acquire(context.Background(), repoID, caller)
+		if err != nil {
+			return
+		}
+		order <- caller
+		release()
+	}()
+}
+
+// waitQueued waits until a repository has n queued operations
+func waitQueued(t *testing.T, limiter *RepoOperationLimiter, repoID string, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		for _, m := range limiter.Metrics() {
+			if m.RepoID == repoID {
+				return m.Queued == n
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+}
+
+func TestRepoOperationLimiterSerializesPerRepo(t *testing.T) {
+	limiter := NewRepoOperationLimiterWithLimit(1)
+
+	release, err := limiter.Acquire(context.Background(), "org/a", repoOpCheckout)
+	require.NoError(t, err)
+
+	// Other repositories aren't blocked
+	other, err := limiter.Acquire(context.Background(), "org/b", repoOpCheckout)
+	require.NoError(t, err)
+	other()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx, "org/a", repoOpDelete)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release() // Releasing twice is harmless
+
+	again, err := limiter.Acquire(context.Background(), "org/a", repoOpDelete)
+	require.NoError(t, err)
+	again()
+
+	metrics := limiter.Metrics()
+	require.Len(t, metrics, 2)
+	assert.Equal(t, "org/a", metrics[0].RepoID)
+	assert.Equal(t, 0, metrics[0].Active)
+	assert.Equal(t, int64(2), metrics[0].Acquired)
+	assert.Equal(t, int64(1), metrics[0].Canceled)
+}
+
+func TestRepoOperationLimiterFairness(t *testing.T) {
+	limiter := NewRepoOperationLimiterWithLimit(1)
+	release, err := limiter.Acquire(context.Background(), "org/a", "holder")
+	require.NoError(t, err)
+
+	order := make(chan string, 4)
+	// A burst of checkouts queues ahead of a single delete
+	for i := 0; i < 3; i++ {
+		acquireAsync(t, limiter, "org/a", repoOpCheckout, order)
+		waitQueued(t, limiter, "org/a", i+1)
+	}
+	acquireAsync(t, limiter, "org/a", repoOpDelete, order)
+	waitQueued(t, limiter, "org/a", 4)
+
+	metrics := limiter.Metrics()
+	assert.Equal(t, map[string]int{repoOpCheckout: 3, repoOpDelete: 1}, metrics[0].QueuedByCaller)
+
+	release()
+	var served []string
+	for i := 0; i < 4; i++ {
+		served = append(served, <-order)
+	}
+	// The delete is served second instead of waiting behind every checkout
+	assert.Equal(t, []string{repoOpCheckout, repoOpDelete, repoOpCheckout, repoOpCheckout}, served)
+	assert.Equal(t, int64(4), limiter.Metrics()[0].Waited)
+}
+
+func TestRepoOperationLimiterSetLimit(t *testing.T) {
+	limiter := NewRepoOperationLimiterWithLimit(1)
+	release, err := limiter.Acquire(context.Background(), "org/a", repoOpSync)
+	require.NoError(t, err)
+	defer release()
+
+	order := make(chan string, 1)
+	acquireAsync(t, limiter, "org/a", repoOpFetch, order)
+	waitQueued(t, limiter, "org/a", 1)
+
+	// Raising the limit starts the queued operation without waiting for a release
+	limiter.SetLimit(2)
+	select {
+	case caller := <-order:
+		assert.Equal(t, repoOpFetch, caller)
+	case <-time.After(time.Second):
+		t.Fatal("queued operation didn't start after raising the limit")
+	}
+	assert.Equal(t, 2, limiter.Metrics()[0].Limit)
+}
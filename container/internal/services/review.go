@@ -0,0 +1,262 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// deletedFileBlobHash stands in for the blob hash of a file the diff deletes
+const deletedFileBlobHash = "deleted"
+
+// reviewMarks maps worktree ID -> reviewer -> file path -> mark
+type reviewMarks map[string]map[string]map[string]models.FileReviewMark
+
+// ReviewStore persists per-reviewer file review marks
+type ReviewStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewReviewStore creates a store backed by the given JSON file
+func NewReviewStore(path string) *ReviewStore {
+	return &ReviewStore{path: path}
+}
+
+func (s *ReviewStore) load() (reviewMarks, error) {
+	marks := make(reviewMarks)
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return marks, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &marks); err != nil {
+		return nil, fmt.Errorf("corrupt review marks: %v", err)
+	}
+	return marks, nil
+}
+
+func (s *ReviewStore) save(marks reviewMarks) error {
+	data, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// Marks returns every reviewer's marks for a worktree
+func (s *ReviewStore) Marks(worktreeID string) (map[string]map[string]models.FileReviewMark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marks, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return marks[worktreeID], nil
+}
+
+// Update marks files (path -> blob hash) as reviewed, or clears their marks
+// when blobs is nil
+func (s *ReviewStore) Update(worktreeID, reviewer string, files []string, blobs map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if marks[worktreeID] == nil {
+		marks[worktreeID] = make(map[string]map[string]models.FileReviewMark)
+	}
+	if marks[worktreeID][reviewer] == nil {
+		marks[worktreeID][reviewer] = make(map[string]models.FileReviewMark)
+	}
+
+	now := time.Now()
+	for _, file := range files {
+		if blobs == nil {
+			delete(marks[worktreeID][reviewer], file)
+			continue
+		}
+		marks[worktreeID][reviewer][file] = models.FileReviewMark{BlobHash: blobs[file], ReviewedAt: now}
+	}
+
+	if len(marks[worktreeID][reviewer]) == 0 {
+		delete(marks[worktreeID], reviewer)
+	}
+	if len(marks[worktreeID]) == 0 {
+		delete(marks, worktreeID)
+	}
+	return s.save(marks)
+}
+
+// Forget drops the marks of a deleted worktree
+func (s *ReviewStore) Forget(worktreeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marks, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, exists := marks[worktreeID]; !exists {
+		return nil
+	}
+	delete(marks, worktreeID)
+	return s.save(marks)
+}
+
+// currentBlobHashes hashes the working tree contents of the given files.
+// Files that no longer exist get deletedFileBlobHash.
+func (s *GitService) currentBlobHashes(worktreePath string, files []string) (map[string]string, error) {
+	hashes := make(map[string]string, len(files))
+	var existing []string
+	for _, file := range files {
+		if _, err := os.Lstat(filepath.Join(worktreePath, file)); os.IsNotExist(err) {
+			hashes[file] = deletedFileBlobHash
+			continue
+		}
+		existing = append(existing, file)
+	}
+	if len(existing) == 0 {
+		return hashes, nil
+	}
+
+	args := append([]string{"hash-object", "--"}, existing...)
+	output, err := s.operations.ExecuteGit(worktreePath, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash files: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != len(existing) {
+		return nil, fmt.Errorf("git hash-object returned %d hashes for %d files", len(lines), len(existing))
+	}
+	for i, file := range existing {
+		hashes[file] = strings.TrimSpace(lines[i])
+	}
+	return hashes, nil
+}
+
+// applyReviewState annotates a diff with blob hashes, who has reviewed each
+// file at its current contents, and the reviewer's overall progress
+func (s *GitService) applyReviewState(worktree *models.Worktree, diff *git.WorktreeDiffResponse, reviewer string) error {
+	if s.reviews == nil {
+		return nil
+	}
+	files := make([]string, len(diff.FileDiffs))
+	for i, fileDiff := range diff.FileDiffs {
+		files[i] = fileDiff.FilePath
+	}
+	hashes, err := s.currentBlobHashes(worktree.Path, files)
+	if err != nil {
+		return err
+	}
+	marks, err := s.reviews.Marks(worktree.ID)
+	if err != nil {
+		return err
+	}
+
+	progress := &models.ReviewProgress{Reviewer: reviewer, Total: len(diff.FileDiffs)}
+	for i := range diff.FileDiffs {
+		fileDiff := &diff.FileDiffs[i]
+		fileDiff.BlobHash = hashes[fileDiff.FilePath]
+		fileDiff.Reviewed = false
+		fileDiff.ReviewedBy = nil
+
+		for name, reviewerMarks := range marks {
+			mark, marked := reviewerMarks[fileDiff.FilePath]
+			if !marked {
+				continue
+			}
+			if mark.BlobHash == fileDiff.BlobHash {
+				fileDiff.ReviewedBy = append(fileDiff.ReviewedBy, name)
+				if name == reviewer {
+					fileDiff.Reviewed = true
+					progress.Reviewed++
+				}
+			} else if name == reviewer {
+				progress.Stale++
+			}
+		}
+		sort.Strings(fileDiff.ReviewedBy)
+	}
+	diff.Review = progress
+	return nil
+}
+
+// UpdateFileReview marks (or unmarks) files of a worktree's diff as reviewed
+// at their current contents and returns the reviewer's progress
+func (s *GitService) UpdateFileReview(worktreeID string, req models.UpdateFileReviewRequest) (*models.ReviewProgress, error) {
+	reviewer := strings.TrimSpace(req.Reviewer)
+	if reviewer == "" {
+		reviewer = models.DefaultReviewer
+	}
+	if len(req.Files) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "files is required")
+	}
+
+	// Ignored files can be marked too; they only count toward progress when requested
+	diff, err := s.GetWorktreeDiffWithOptions(worktreeID, WorktreeDiffOptions{Reviewer: reviewer, IncludeIgnored: true})
+	if err != nil {
+		return nil, err
+	}
+	if diff.Review == nil {
+		return nil, fmt.Errorf("failed to read current file contents for review")
+	}
+
+	inDiff := make(map[string]string, len(diff.FileDiffs))
+	for _, fileDiff := range diff.FileDiffs {
+		inDiff[fileDiff.FilePath] = fileDiff.BlobHash
+	}
+	for _, file := range req.Files {
+		if _, ok := inDiff[file]; !ok {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "file %q is not part of the worktree diff", file)
+		}
+	}
+
+	var blobs map[string]string
+	if req.Reviewed {
+		blobs = inDiff
+	}
+	if err := s.reviews.Update(worktreeID, reviewer, req.Files, blobs); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if !req.IncludeIgnored {
+		kept := diff.FileDiffs[:0]
+		for _, fileDiff := range diff.FileDiffs {
+			if fileDiff.IgnoredBy == "" {
+				kept = append(kept, fileDiff)
+			}
+		}
+		diff.FileDiffs = kept
+	}
+	if err := s.applyReviewState(worktree, diff, reviewer); err != nil {
+		return nil, err
+	}
+	return diff.Review, nil
+}
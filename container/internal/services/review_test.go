@@ -0,0 +1,103 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestReviewStore(t *testing.T) {
+	store := NewReviewStore(filepath.Join(t.TempDir(), "reviews.json"))
+
+	require.NoError(t, store.Update("wt-1", "alice", []string{"a.go", "b.go"}, map[string]string{"a.go": "aaa", "b.go": "bbb"}))
+	require.NoError(t, store.Update("wt-1", "bob", []string{"a.go"}, map[string]string{"a.go": "aaa"}))
+
+	marks, err := store.Marks("wt-1")
+	require.NoError(t, err)
+	assert.Len(t, marks["alice"], 2)
+	assert.Equal(t, "aaa", marks["bob"]["a.go"].BlobHash)
+
+	// Clearing marks removes empty reviewers
+	require.NoError(t, store.Update("wt-1", "bob", []string{"a.go"}, nil))
+	marks, err = store.Marks("wt-1")
+	require.NoError(t, err)
+	assert.NotContains(t, marks, "bob")
+
+	// Marks survive a new store on the same file
+	reopened := NewReviewStore(store.path)
+	marks, err = reopened.Marks("wt-1")
+	require.NoError(t, err)
+	assert.Len(t, marks["alice"], 2)
+
+	require.NoError(t, reopened.Forget("wt-1"))
+	marks, err = reopened.Marks("wt-1")
+	require.NoError(t, err)
+	assert.Empty(t, marks)
+}
+
+func TestApplyReviewStateResetsOnChange(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "util.go"), []byte("package util\n"), 0644))
+
+	worktree := &models.Worktree{ID: "wt-review", Path: dir}
+	newDiff := func() *git.WorktreeDiffResponse {
+		return &git.WorktreeDiffResponse{FileDiffs: []git.FileDiff{
+			{FilePath: "main.go", ChangeType: "modified"},
+			{FilePath: "util.go", ChangeType: "added"},
+			{FilePath: "gone.go", ChangeType: "deleted"},
+		}}
+	}
+
+	diff := newDiff()
+	require.NoError(t, service.applyReviewState(worktree, diff, "alice"))
+	assert.Equal(t, &models.ReviewProgress{Reviewer: "alice", Total: 3}, diff.Review)
+	assert.Equal(t, deletedFileBlobHash, diff.FileDiffs[2].BlobHash)
+	assert.Len(t, diff.FileDiffs[0].BlobHash, 40)
+
+	blobs := map[string]string{}
+	for _, fileDiff := range diff.FileDiffs {
+		blobs[fileDiff.FilePath] = fileDiff.BlobHash
+	}
+	require.NoError(t, service.reviews.Update(worktree.ID, "alice", []string{"main.go", "util.go", "gone.go"}, blobs))
+	require.NoError(t, service.reviews.Update(worktree.ID, "bob", []string{"main.go"}, blobs))
+
+	diff = newDiff()
+	require.NoError(t, service.applyReviewState(worktree, diff, "alice"))
+	assert.Equal(t, 3, diff.Review.Reviewed)
+	assert.Equal(t, []string{"alice", "bob"}, diff.FileDiffs[0].ReviewedBy)
+
+	// Editing a file lapses every reviewer's mark on it
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	diff = newDiff()
+	require.NoError(t, service.applyReviewState(worktree, diff, "alice"))
+	assert.False(t, diff.FileDiffs[0].Reviewed)
+	assert.Empty(t, diff.FileDiffs[0].ReviewedBy)
+	assert.True(t, diff.FileDiffs[1].Reviewed)
+	assert.Equal(t, 2, diff.Review.Reviewed)
+	assert.Equal(t, 1, diff.Review.Stale)
+
+	// Another reviewer sees their own progress
+	diff = newDiff()
+	require.NoError(t, service.applyReviewState(worktree, diff, "bob"))
+	assert.Equal(t, 0, diff.Review.Reviewed)
+	assert.Equal(t, 1, diff.Review.Stale)
+	assert.Equal(t, []string{"alice"}, diff.FileDiffs[1].ReviewedBy)
+}
+
+func TestUpdateFileReviewValidation(t *testing.T) {
+	service := createTestGitService(t)
+
+	_, err := service.UpdateFileReview("missing", models.UpdateFileReviewRequest{})
+	assert.Error(t, err)
+
+	_, err = service.UpdateFileReview("missing", models.UpdateFileReviewRequest{Files: []string{"a.go"}, Reviewed: true})
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// SecretStore keeps named secrets (private keys, tokens) as owner-only files
+// in the volume. Values are never returned by the API, only used server-side.
+type SecretStore struct {
+	dir string
+	mu  sync.RWMutex
+}
+
+// NewSecretStore creates a secret store in the volume directory
+func NewSecretStore() *SecretStore {
+	return NewSecretStoreWithPath(filepath.Join(config.Runtime.VolumeDir, "secrets"))
+}
+
+// NewSecretStoreWithPath creates a secret store in an explicit directory (for testing)
+func NewSecretStoreWithPath(dir string) *SecretStore {
+	return &SecretStore{dir: dir}
+}
+
+func (s *SecretStore) path(name string) (string, error) {
+	if !secretNamePattern.MatchString(name) {
+		return "", models.NewAPIError(models.ErrCodeInvalidRequest, "invalid secret name %q (letters, digits, '.', '_' and '-')", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// Get returns a secret's value
+func (s *SecretStore) Get(name string) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", models.NewAPIError(models.ErrCodeInvalidRequest, "secret %s is not set", name)
+	}
+	return string(data), err
+}
+
+// Has reports whether a secret is set
+func (s *SecretStore) Has(name string) bool {
+	path, err := s.path(name)
+	if err != nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// Set stores a secret, replacing any previous value
+func (s *SecretStore) Set(name, value string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(value) == "" {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "secret %s has an empty value", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(value), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Delete removes a secret; deleting a missing secret is not an error
+func (s *SecretStore) Delete(name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete secret %s: %v", name, err)
+	}
+	return nil
+}
+
+// Names lists the stored secrets
+func (s *SecretStore) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return []string{}
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && secretNamePattern.MatchString(entry.Name()) && !strings.HasSuffix(entry.Name(), ".tmp") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// DefaultWrapUpGrace is how long Claude gets to wrap up before the process is stopped
+	DefaultWrapUpGrace = 3 * time.Minute
+	// How long to wait for a hook to deliver the wrap-up prompt mid-turn before
+	// queueing it on the process's input instead
+	wrapUpHookWindow = 15 * time.Second
+)
+
+// SessionBudgetPhase is where a budgeted session is in its lifecycle
+type SessionBudgetPhase string
+
+const (
+	// SessionBudgetActive sessions are within budget
+	SessionBudgetActive SessionBudgetPhase = "active"
+	// SessionBudgetWrappingUp sessions have been asked to commit and write a handoff note
+	SessionBudgetWrappingUp SessionBudgetPhase = "wrapping_up"
+	// SessionBudgetStopped sessions were stopped by the server after wrapping up
+	SessionBudgetStopped SessionBudgetPhase = "stopped"
+	// SessionBudgetFinished sessions exited on their own
+	SessionBudgetFinished SessionBudgetPhase = "finished"
+)
+
+// SessionBudgetStatus is the budget state of a Claude task
+type SessionBudgetStatus struct {
+	WorkingDirectory string `json:"working_directory"`
+	models.SessionBudget
+	StartedAt  time.Time          `json:"started_at"`
+	TokensUsed int                `json:"tokens_used"`
+	Phase      SessionBudgetPhase `json:"phase"`
+	// Which limit was hit: "time" or "tokens"
+	Reason   string     `json:"reason,omitempty"`
+	WrapUpAt *time.Time `json:"wrap_up_at,omitempty"`
+	// How the wrap-up prompt reached Claude: "hook" or "input"
+	WrapUpDelivery string     `json:"wrap_up_delivery,omitempty"`
+	EndedAt        *time.Time `json:"ended_at,omitempty"`
+}
+
+// SessionBudgetEventsEmitter is notified when a budgeted session changes phase
+type SessionBudgetEventsEmitter interface {
+	EmitSessionBudget(status SessionBudgetStatus)
+}
+
+type sessionBudgetState struct {
+	status        SessionBudgetStatus
+	lastMessageID string
+	lastTokens    int
+}
+
+// SessionBudgetTracker tracks time/token budgets of Claude tasks, keyed by
+// working directory, and decides when they must wrap up and stop
+type SessionBudgetTracker struct {
+	mu      sync.Mutex
+	budgets map[string]*sessionBudgetState
+	grace   time.Duration
+	now     func() time.Time
+	events  SessionBudgetEventsEmitter
+}
+
+// NewSessionBudgetTracker creates an empty budget tracker
+func NewSessionBudgetTracker() *SessionBudgetTracker {
+	return &SessionBudgetTracker{
+		budgets: make(map[string]*sessionBudgetState),
+		grace:   DefaultWrapUpGrace,
+		now:     time.Now,
+	}
+}
+
+// WithEvents sets the emitter notified of phase changes
+func (t *SessionBudgetTracker) WithEvents(events SessionBudgetEventsEmitter) *SessionBudgetTracker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.events = events
+	return t
+}
+
+// Start begins tracking a budget for a working directory, replacing any previous one
+func (t *SessionBudgetTracker) Start(workingDir string, budget models.SessionBudget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budgets[workingDir] = &sessionBudgetState{status: SessionBudgetStatus{
+		WorkingDirectory: workingDir,
+		SessionBudget:    budget,
+		StartedAt:        t.now(),
+		Phase:            SessionBudgetActive,
+	}}
+	logger.Infof("⏱️ Budget for %s: %d minutes, %d tokens", workingDir, budget.MaxMinutes, budget.MaxTokens)
+}
+
+// RecordUsage adds the token usage of an assistant message. Claude repeats the
+// usage of a message for each of its content blocks, so repeats of the same
+// message ID replace the previous count instead of adding to it.
+func (t *SessionBudgetTracker) RecordUsage(workingDir, messageID string, tokens int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, exists := t.budgets[workingDir]
+	if !exists {
+		return
+	}
+	if messageID != "" && messageID == state.lastMessageID {
+		state.status.TokensUsed -= state.lastTokens
+	}
+	state.status.TokensUsed += tokens
+	state.lastMessageID = messageID
+	state.lastTokens = tokens
+}
+
+// Check moves an active session into wrap-up once a limit is exceeded. It
+// returns true only on that transition.
+func (t *SessionBudgetTracker) Check(workingDir string) bool {
+	t.mu.Lock()
+	state, exists := t.budgets[workingDir]
+	if !exists || state.status.Phase != SessionBudgetActive {
+		t.mu.Unlock()
+		return false
+	}
+	reason := t.exceededLocked(state)
+	if reason == "" {
+		t.mu.Unlock()
+		return false
+	}
+	now := t.now()
+	state.status.Phase = SessionBudgetWrappingUp
+	state.status.Reason = reason
+	state.status.WrapUpAt = &now
+	status, events := state.status, t.events
+	t.mu.Unlock()
+
+	logger.Infof("⏰ %s exceeded its %s budget, asking Claude to wrap up", workingDir, reason)
+	if events != nil {
+		events.EmitSessionBudget(status)
+	}
+	return true
+}
+
+func (t *SessionBudgetTracker) exceededLocked(state *sessionBudgetState) string {
+	budget := state.status.SessionBudget
+	if budget.MaxMinutes > 0 && t.now().Sub(state.status.StartedAt) >= time.Duration(budget.MaxMinutes)*time.Minute {
+		return "time"
+	}
+	if budget.MaxTokens > 0 && state.status.TokensUsed >= budget.MaxTokens {
+		return "tokens"
+	}
+	return ""
+}
+
+// ClaimWrapUp returns the wrap-up prompt if it still has to be delivered. Hooks
+// may claim it straight away; input delivery waits for the hook window so the
+// prompt isn't sent twice.
+func (t *SessionBudgetTracker) ClaimWrapUp(workingDir, delivery string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, exists := t.budgets[workingDir]
+	if !exists || state.status.Phase != SessionBudgetWrappingUp || state.status.WrapUpDelivery != "" {
+		return "", false
+	}
+	if delivery == "input" && t.now().Sub(*state.status.WrapUpAt) < wrapUpHookWindow {
+		return "", false
+	}
+	state.status.WrapUpDelivery = delivery
+	return wrapUpPrompt(state.status), true
+}
+
+// GraceExpired reports whether a session has had its full grace period to wrap up
+func (t *SessionBudgetTracker) GraceExpired(workingDir string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, exists := t.budgets[workingDir]
+	if !exists || state.status.Phase != SessionBudgetWrappingUp {
+		return false
+	}
+	return t.now().Sub(*state.status.WrapUpAt) >= t.grace
+}
+
+// HookResponse is called for each Claude hook event in a budgeted working
+// directory. It delivers the wrap-up prompt on the first PostToolUse after
+// the budget runs out, and reports that the session should be stopped once
+// Claude finishes the turn in which it was told to wrap up.
+func (t *SessionBudgetTracker) HookResponse(workingDir, eventType string) (*models.ClaudeHookResponse, bool) {
+	t.Check(workingDir)
+
+	switch eventType {
+	case "PostToolUse":
+		if prompt, ok := t.ClaimWrapUp(workingDir, "hook"); ok {
+			return &models.ClaudeHookResponse{Decision: "block", Reason: prompt}, false
+		}
+	case "Stop":
+		t.mu.Lock()
+		state, exists := t.budgets[workingDir]
+		stop := exists && state.status.Phase == SessionBudgetWrappingUp && state.status.WrapUpDelivery == "hook"
+		t.mu.Unlock()
+		return nil, stop
+	}
+	return nil, false
+}
+
+// End records that a budgeted session's process exited
+func (t *SessionBudgetTracker) End(workingDir string, stopped bool) {
+	t.mu.Lock()
+	state, exists := t.budgets[workingDir]
+	if !exists || state.status.EndedAt != nil {
+		t.mu.Unlock()
+		return
+	}
+	now := t.now()
+	state.status.EndedAt = &now
+	if stopped {
+		state.status.Phase = SessionBudgetStopped
+	} else {
+		state.status.Phase = SessionBudgetFinished
+	}
+	status, events := state.status, t.events
+	t.mu.Unlock()
+
+	if events != nil && status.Reason != "" {
+		events.EmitSessionBudget(status)
+	}
+}
+
+// Get returns the budget status of a working directory
+func (t *SessionBudgetTracker) Get(workingDir string) (SessionBudgetStatus, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, exists := t.budgets[workingDir]
+	if !exists {
+		return SessionBudgetStatus{}, false
+	}
+	return state.status, true
+}
+
+// List returns all tracked budgets, most recently started first
+func (t *SessionBudgetTracker) List() []SessionBudgetStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	statuses := make([]SessionBudgetStatus, 0, len(t.budgets))
+	for _, state := range t.budgets {
+		statuses = append(statuses, state.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].StartedAt.After(statuses[j].StartedAt)
+	})
+	return statuses
+}
+
+// wrapUpPrompt is injected when a session runs out of budget
+func wrapUpPrompt(status SessionBudgetStatus) string {
+	var limit string
+	if status.Reason == "tokens" {
+		limit = fmt.Sprintf("token budget (%d tokens)", status.MaxTokens)
+	} else {
+		limit = fmt.Sprintf("time budget (%d minutes)", status.MaxMinutes)
+	}
+	return strings.Join([]string{
+		fmt.Sprintf("⏰ This session has used up its %s. Wrap up now:", limit),
+		"1. Commit your work-in-progress with a commit message starting with \"WIP:\".",
+		"2. Write a short handoff note to HANDOFF.md covering what is done, what is left and anything the next person should know, and commit it.",
+		"3. Stop. Do not start any new work.",
+	}, "\n")
+}
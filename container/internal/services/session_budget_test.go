@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type recordingBudgetEvents struct {
+	phases []SessionBudgetPhase
+}
+
+func (r *recordingBudgetEvents) EmitSessionBudget(status SessionBudgetStatus) {
+	r.phases = append(r.phases, status.Phase)
+}
+
+func TestSessionBudgetTimeLimitWrapUpViaHook(t *testing.T) {
+	now := time.Unix(1000, 0)
+	events := &recordingBudgetEvents{}
+	tracker := NewSessionBudgetTracker().WithEvents(events)
+	tracker.now = func() time.Time { return now }
+
+	tracker.Start("/workspace/app/main", models.SessionBudget{MaxMinutes: 30})
+	response, stop := tracker.HookResponse("/workspace/app/main", "PostToolUse")
+	assert.Nil(t, response)
+	assert.False(t, stop)
+
+	now = now.Add(30 * time.Minute)
+	response, stop = tracker.HookResponse("/workspace/app/main", "PostToolUse")
+	require.NotNil(t, response)
+	assert.False(t, stop)
+	assert.Equal(t, "block", response.Decision)
+	assert.Contains(t, response.Reason, "time budget (30 minutes)")
+	assert.Contains(t, response.Reason, "HANDOFF.md")
+
+	// The prompt is only delivered once
+	response, _ = tracker.HookResponse("/workspace/app/main", "PostToolUse")
+	assert.Nil(t, response)
+	_, ok := tracker.ClaimWrapUp("/workspace/app/main", "input")
+	assert.False(t, ok)
+
+	// Claude finishing the turn it was told to wrap up in stops the session
+	_, stop = tracker.HookResponse("/workspace/app/main", "Stop")
+	assert.True(t, stop)
+	tracker.End("/workspace/app/main", true)
+
+	status, exists := tracker.Get("/workspace/app/main")
+	require.True(t, exists)
+	assert.Equal(t, SessionBudgetStopped, status.Phase)
+	assert.Equal(t, "time", status.Reason)
+	assert.Equal(t, "hook", status.WrapUpDelivery)
+	assert.Equal(t, []SessionBudgetPhase{SessionBudgetWrappingUp, SessionBudgetStopped}, events.phases)
+
+	// Hooks from directories without a budget are left alone
+	response, stop = tracker.HookResponse("/workspace/other", "PostToolUse")
+	assert.Nil(t, response)
+	assert.False(t, stop)
+}
+
+func TestSessionBudgetTokenLimitWrapUpViaInput(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tracker := NewSessionBudgetTracker()
+	tracker.now = func() time.Time { return now }
+	tracker.Start("/workspace/app/main", models.SessionBudget{MaxTokens: 1000})
+
+	// Repeated usage for the same message replaces the earlier count
+	tracker.RecordUsage("/workspace/app/main", "msg_1", 400)
+	tracker.RecordUsage("/workspace/app/main", "msg_1", 450)
+	assert.False(t, tracker.Check("/workspace/app/main"))
+	tracker.RecordUsage("/workspace/app/main", "msg_2", 600)
+	assert.True(t, tracker.Check("/workspace/app/main"))
+	assert.False(t, tracker.Check("/workspace/app/main"), "only the transition is reported")
+
+	status, _ := tracker.Get("/workspace/app/main")
+	assert.Equal(t, 1050, status.TokensUsed)
+	assert.Equal(t, "tokens", status.Reason)
+
+	// Input delivery waits for hooks to get a chance first
+	_, ok := tracker.ClaimWrapUp("/workspace/app/main", "input")
+	assert.False(t, ok)
+	now = now.Add(wrapUpHookWindow)
+	prompt, ok := tracker.ClaimWrapUp("/workspace/app/main", "input")
+	require.True(t, ok)
+	assert.Contains(t, prompt, "token budget (1000 tokens)")
+
+	// Stop hooks don't cut short a wrap-up that was queued on the input
+	_, stop := tracker.HookResponse("/workspace/app/main", "Stop")
+	assert.False(t, stop)
+
+	assert.False(t, tracker.GraceExpired("/workspace/app/main"))
+	now = now.Add(DefaultWrapUpGrace)
+	assert.True(t, tracker.GraceExpired("/workspace/app/main"))
+
+	tracker.End("/workspace/app/main", false)
+	status, _ = tracker.Get("/workspace/app/main")
+	assert.Equal(t, SessionBudgetFinished, status.Phase)
+	assert.NotNil(t, status.EndedAt)
+	assert.Len(t, tracker.List(), 1)
+}
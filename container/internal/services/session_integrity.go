@@ -0,0 +1,186 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/claude/parser"
+	"github.com/vanpelt/catnip/internal/claude/paths"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// sessionIdleWindow is how long a session file must go unmodified before it's
+// repaired automatically, so a line Claude is still writing isn't quarantined
+const sessionIdleWindow = 2 * time.Minute
+
+// maxRepairHistory caps how many past repairs are kept for reporting
+const maxRepairHistory = 100
+
+// SessionIntegrityScan summarizes a pass over every Claude session file
+type SessionIntegrityScan struct {
+	FilesChecked int `json:"files_checked"`
+	// Reports for files with malformed content (healthy files are omitted)
+	Problems []*parser.IntegrityReport `json:"problems"`
+	// Problem files that weren't repaired because they're in use or the repair failed
+	Skipped []string `json:"skipped,omitempty"`
+	// Repairs made since startup (on access or by request), newest first
+	Repairs []*parser.IntegrityReport `json:"repairs"`
+}
+
+// SessionIntegrityService detects malformed lines in Claude session JSONL
+// files, quarantines them and keeps a record of what was lost, so a
+// corrupted or truncated session degrades instead of breaking reads and resume
+type SessionIntegrityService struct {
+	projectsDir   string
+	quarantineDir string
+	idleWindow    time.Duration
+
+	mu      sync.Mutex
+	repairs []*parser.IntegrityReport // newest last
+}
+
+// NewSessionIntegrityService checks ~/.claude/projects and quarantines into the volume
+func NewSessionIntegrityService() *SessionIntegrityService {
+	return NewSessionIntegrityServiceWithOptions(
+		filepath.Join(config.Runtime.HomeDir, ".claude", "projects"),
+		filepath.Join(config.Runtime.VolumeDir, "claude-quarantine"),
+		sessionIdleWindow,
+	)
+}
+
+// NewSessionIntegrityServiceWithOptions creates a service with explicit paths (for tests)
+func NewSessionIntegrityServiceWithOptions(projectsDir, quarantineDir string, idleWindow time.Duration) *SessionIntegrityService {
+	return &SessionIntegrityService{
+		projectsDir:   projectsDir,
+		quarantineDir: quarantineDir,
+		idleWindow:    idleWindow,
+	}
+}
+
+// Check validates one session file, repairing it when repair is set and the
+// file has been idle long enough
+func (s *SessionIntegrityService) Check(path string, repair bool) (*parser.IntegrityReport, error) {
+	report, err := parser.CheckSessionFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if repair && !report.Healthy() {
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < s.idleWindow {
+			logger.Debugf("⏳ Not repairing %s yet: modified %v ago", filepath.Base(path), time.Since(info.ModTime()).Round(time.Second))
+		} else if repaired, err := parser.RepairSessionFile(path, s.quarantineDir); err != nil {
+			logger.Warnf("⚠️ Failed to repair session file %s: %v", path, err)
+		} else {
+			report = repaired
+			s.recordRepair(report)
+		}
+	}
+	return report, nil
+}
+
+// recordRepair logs a repair and keeps it for Repairs
+func (s *SessionIntegrityService) recordRepair(report *parser.IntegrityReport) {
+	if !report.Repaired {
+		return
+	}
+	logger.Warnf("🩹 Quarantined %d malformed lines (%d bytes) from session %s to %s",
+		len(report.Malformed), report.LostBytes, report.SessionID, report.QuarantinePath)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.repairs = append(s.repairs, report)
+	if len(s.repairs) > maxRepairHistory {
+		s.repairs = s.repairs[len(s.repairs)-maxRepairHistory:]
+	}
+}
+
+// RepairOnAccess repairs a session file whose reader skipped malformed content
+// and rebuilds the reader's cached state from the repaired file
+func (s *SessionIntegrityService) RepairOnAccess(reader *parser.SessionFileReader) {
+	if len(reader.GetMalformedLines()) == 0 && !reader.HasPartialTail() {
+		return
+	}
+	report, err := s.Check(reader.GetFilePath(), true)
+	if err != nil || !report.Repaired {
+		return
+	}
+	if err := reader.ReadFull(); err != nil {
+		logger.Warnf("⚠️ Failed to re-read repaired session %s: %v", report.SessionID, err)
+	}
+}
+
+// sessionFiles lists the session JSONL files under the projects directory
+func (s *SessionIntegrityService) sessionFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(s.projectsDir, "*", "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Scan checks every session file, optionally repairing idle ones
+func (s *SessionIntegrityService) Scan(repair bool) (*SessionIntegrityScan, error) {
+	files, err := s.sessionFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	scan := &SessionIntegrityScan{Problems: []*parser.IntegrityReport{}}
+	defer func() { scan.Repairs = s.Repairs() }()
+	for _, file := range files {
+		report, err := s.Check(file, repair)
+		if err != nil {
+			logger.Debugf("⚠️ Skipping unreadable session file %s: %v", file, err)
+			continue
+		}
+		scan.FilesChecked++
+		if report.Healthy() {
+			continue
+		}
+		scan.Problems = append(scan.Problems, report)
+		if repair && !report.Repaired {
+			scan.Skipped = append(scan.Skipped, file)
+		}
+	}
+	return scan, nil
+}
+
+// Repair checks and repairs a single session by ID, even if it was modified recently
+func (s *SessionIntegrityService) Repair(sessionID string) (*parser.IntegrityReport, error) {
+	if !paths.IsValidSessionUUID(sessionID) {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid session ID %q", sessionID)
+	}
+	matches, err := filepath.Glob(filepath.Join(s.projectsDir, "*", sessionID+".jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeSessionNotFound, "session %s not found", sessionID)
+	}
+
+	report, err := parser.RepairSessionFile(matches[0], s.quarantineDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to repair session %s: %w", sessionID, err)
+	}
+	s.recordRepair(report)
+	return report, nil
+}
+
+// Repairs returns the repairs made since startup, newest first
+func (s *SessionIntegrityService) Repairs() []*parser.IntegrityReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repairs := make([]*parser.IntegrityReport, 0, len(s.repairs))
+	for i := len(s.repairs) - 1; i >= 0; i-- {
+		repairs = append(repairs, s.repairs[i])
+	}
+	return repairs
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const testIntegritySessionID = "cf568042-7147-4fba-a2ca-c6a646581260"
+
+func writeIntegritySession(t *testing.T, projectsDir string, modTime time.Time) string {
+	t.Helper()
+	dir := filepath.Join(projectsDir, "-workspace-catnip-main")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	path := filepath.Join(dir, testIntegritySessionID+".jsonl")
+	content := `{"type":"user","uuid":"msg-001"}` + "\n" + `{"type":"assis` + "\n" + `{"type":"user","uuid":"msg-002"}` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	return path
+}
+
+func TestSessionIntegrityScanSkipsActiveSessions(t *testing.T) {
+	projectsDir := t.TempDir()
+	quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+	svc := NewSessionIntegrityServiceWithOptions(projectsDir, quarantineDir, time.Hour)
+	path := writeIntegritySession(t, projectsDir, time.Now())
+
+	scan, err := svc.Scan(true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, scan.FilesChecked)
+	require.Len(t, scan.Problems, 1)
+	assert.False(t, scan.Problems[0].Repaired)
+	assert.Equal(t, []string{path}, scan.Skipped)
+	assert.Empty(t, scan.Repairs)
+
+	// Once idle the same scan repairs it
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(path, old, old))
+	scan, err = svc.Scan(true)
+	require.NoError(t, err)
+	require.Len(t, scan.Problems, 1)
+	assert.True(t, scan.Problems[0].Repaired)
+	assert.Empty(t, scan.Skipped)
+	require.Len(t, scan.Repairs, 1)
+	assert.FileExists(t, scan.Repairs[0].QuarantinePath)
+
+	scan, err = svc.Scan(false)
+	require.NoError(t, err)
+	assert.Empty(t, scan.Problems)
+	assert.Len(t, scan.Repairs, 1, "repairs stay reported after the file is clean")
+}
+
+func TestSessionIntegrityRepairByID(t *testing.T) {
+	projectsDir := t.TempDir()
+	svc := NewSessionIntegrityServiceWithOptions(projectsDir, filepath.Join(t.TempDir(), "quarantine"), time.Hour)
+	path := writeIntegritySession(t, projectsDir, time.Now())
+
+	// An explicit repair doesn't wait for the session to go idle
+	report, err := svc.Repair(testIntegritySessionID)
+	require.NoError(t, err)
+	assert.True(t, report.Repaired)
+	assert.Len(t, report.Malformed, 1)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"type":"user","uuid":"msg-001"}`+"\n"+`{"type":"user","uuid":"msg-002"}`+"\n", string(data))
+
+	_, err = svc.Repair("../../etc/passwd")
+	var apiErr *models.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, models.ErrCodeInvalidRequest, apiErr.Code)
+
+	_, err = svc.Repair("00000000-0000-0000-0000-000000000000")
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, models.ErrCodeSessionNotFound, apiErr.Code)
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// ProfileSetting selects the settings profile applied under catnip.env
+const ProfileSetting = "CATNIP_PROFILE"
+
+// ClaudeAllowedToolsSetting is the instance-wide Claude tool allowlist
+// (comma-separated), used where neither the worktree nor the repository's
+// .catnip.yaml sets one
+const ClaudeAllowedToolsSetting = "CATNIP_CLAUDE_ALLOWED_TOOLS"
+
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// SettingsProfile is a named bundle of CATNIP_* settings. The active profile
+// is applied on top of the process environment and under catnip.env, so an
+// instance's own overrides still win.
+type SettingsProfile struct {
+	Name        string            `json:"name" example:"strict-safety"`
+	Description string            `json:"description" example:"Read and edit tools only, frequent checkpoints"`
+	Settings    map[string]string `json:"settings"`
+	// Whether the profile ships with catnip rather than coming from the
+	// profiles directory
+	BuiltIn bool `json:"built_in" example:"true"`
+}
+
+// builtinProfiles ship with catnip. A file in the profiles directory with
+// the same name replaces one.
+var builtinProfiles = []SettingsProfile{
+	{
+		Name:        "strict-safety",
+		Description: "Claude limited to reading and editing files (no shell or web), checkpoints every 15s, agent notes pushed with branches, no automatic setup re-runs, edits to locked paths blocked, nightly hygiene report notifications",
+		Settings: map[string]string{
+			ClaudeAllowedToolsSetting:               "Read,Glob,Grep,Edit,MultiEdit,Write,NotebookEdit,TodoWrite,ExitPlanMode",
+			"CATNIP_COMMIT_TIMEOUT_SECONDS":         "15",
+			PushAgentNotesSetting:                   "true",
+			"CATNIP_SETUP_AUTO_RERUN":               "0",
+			"CATNIP_HYGIENE_REPORT_NOTIFY":          "true",
+			"CATNIP_HYGIENE_STALE_DAYS":             "7",
+			"CATNIP_REF_CLEANUP_GRACE":              "168h",
+			"CATNIP_SYNC_UNDO_WINDOW":               "24h",
+			"CATNIP_CLAUDE_SESSION_TIMEOUT_SECONDS": "3600",
+			PathLockModeSetting:                     PathLockModeBlock,
+		},
+	},
+	{
+		Name:        "demo",
+		Description: "Short-lived instances: frequent checkpoints, aggressive cleanup of stale worktrees and refs, no report notifications",
+		Settings: map[string]string{
+			"CATNIP_COMMIT_TIMEOUT_SECONDS": "10",
+			"CATNIP_SETUP_AUTO_RERUN":       "1",
+			"CATNIP_HYGIENE_REPORT_NOTIFY":  "false",
+			"CATNIP_HYGIENE_STALE_DAYS":     "1",
+			"CATNIP_REF_CLEANUP_GRACE":      "1h",
+			"CATNIP_PR_COST_COMMENT":        "false",
+		},
+	},
+	{
+		Name:        "power-user",
+		Description: "All Claude tools, infrequent checkpoints, automatic setup re-runs, more concurrent repository operations, long sync undo window",
+		Settings: map[string]string{
+			"CATNIP_COMMIT_TIMEOUT_SECONDS": "120",
+			"CATNIP_SETUP_AUTO_RERUN":       "1",
+			"CATNIP_REPO_CONCURRENCY":       "4",
+			"CATNIP_SYNC_UNDO_WINDOW":       "72h",
+			"CATNIP_HYGIENE_STALE_DAYS":     "30",
+			"CATNIP_HYGIENE_REPORT_NOTIFY":  "false",
+		},
+	},
+}
+
+// ValidateProfileName checks that a profile name can name a file
+func ValidateProfileName(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "invalid profile name %q", name).
+			WithHint("Use lowercase letters, digits and dashes, e.g. strict-safety")
+	}
+	return nil
+}
+
+// ListProfiles returns the built-in profiles and those in the profiles
+// directory, sorted by name
+func (m *ConfigManager) ListProfiles() ([]SettingsProfile, error) {
+	profiles := make(map[string]SettingsProfile)
+	for _, profile := range builtinProfiles {
+		profiles[profile.Name] = profile
+	}
+
+	entries, err := os.ReadDir(m.profilesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		name, isEnv := strings.CutSuffix(entry.Name(), ".env")
+		if !isEnv || entry.IsDir() || ValidateProfileName(name) != nil {
+			continue
+		}
+		profile, err := m.readProfileFile(name)
+		if err != nil {
+			return nil, err
+		}
+		profiles[name] = *profile
+	}
+
+	list := make([]SettingsProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Profile returns a profile by name, from the profiles directory or else
+// the built-in ones
+func (m *ConfigManager) Profile(name string) (*SettingsProfile, error) {
+	if err := ValidateProfileName(name); err != nil {
+		return nil, err
+	}
+	if profile, err := m.readProfileFile(name); err == nil {
+		return profile, nil
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, profile := range builtinProfiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+	return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown settings profile %q", name).
+		WithHint(fmt.Sprintf("Add %s.env to %s or use a built-in profile", name, m.profilesDir))
+}
+
+// readProfileFile reads <name>.env from the profiles directory. It has the
+// format of catnip.env; a leading # comment is the profile's description.
+func (m *ConfigManager) readProfileFile(name string) (*SettingsProfile, error) {
+	path := filepath.Join(m.profilesDir, name+".env")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings, ignored, err := parseEnvFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	if len(ignored) > 0 {
+		return nil, fmt.Errorf("invalid profile %s: %s", path, strings.Join(ignored, "; "))
+	}
+	delete(settings, ProfileSetting)
+
+	profile := &SettingsProfile{Name: name, Settings: settings}
+	if first, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n"); strings.HasPrefix(first, "#") {
+		profile.Description = strings.TrimSpace(strings.TrimPrefix(first, "#"))
+	}
+	return profile, nil
+}
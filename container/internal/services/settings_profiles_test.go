@@ -0,0 +1,87 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unsetProfileSettings clears every setting a built-in profile touches,
+// restoring them when the test ends
+func unsetProfileSettings(t *testing.T) {
+	keys := []string{ProfileSetting}
+	for _, profile := range builtinProfiles {
+		keys = append(keys, sortedKeys(profile.Settings)...)
+	}
+	for _, key := range keys {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+}
+
+func TestConfigManagerProfiles(t *testing.T) {
+	unsetProfileSettings(t)
+	t.Setenv(ProfileSetting, "demo")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "catnip.env")
+	require.NoError(t, os.WriteFile(path, []byte("CATNIP_HYGIENE_STALE_DAYS=3\n"), 0644))
+
+	manager := NewConfigManagerWithPath(path)
+	require.NoError(t, manager.Load())
+	assert.Equal(t, "10", os.Getenv("CATNIP_COMMIT_TIMEOUT_SECONDS"), "from the profile")
+	assert.Equal(t, "3", os.Getenv("CATNIP_HYGIENE_STALE_DAYS"), "catnip.env wins over the profile")
+	status := manager.Status()
+	assert.Equal(t, "demo", status.Profile)
+	assert.Equal(t, []string{"CATNIP_HYGIENE_STALE_DAYS"}, status.Overrides)
+	assert.Contains(t, status.ProfileSettings, "CATNIP_REF_CLEANUP_GRACE")
+
+	// Switching at runtime is stored in catnip.env
+	reload, err := manager.Set(map[string]string{ProfileSetting: "strict-safety"})
+	require.NoError(t, err)
+	assert.Contains(t, reload.Changed, ClaudeAllowedToolsSetting)
+	assert.Contains(t, reload.Changed, "CATNIP_PR_COST_COMMENT", "demo's settings are dropped")
+	assert.NotContains(t, reload.RestartRequired, ClaudeAllowedToolsSetting)
+	assert.NotContains(t, reload.RestartRequired, "CATNIP_COMMIT_TIMEOUT_SECONDS")
+	assert.NotContains(t, reload.RestartRequired, ProfileSetting)
+	assert.Equal(t, "15", os.Getenv("CATNIP_COMMIT_TIMEOUT_SECONDS"))
+	assert.Empty(t, os.Getenv("CATNIP_PR_COST_COMMENT"))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `CATNIP_PROFILE="strict-safety"`)
+
+	service := createTestGitService(t)
+	defer service.Stop()
+	tools := service.ClaudeTools(t.TempDir())
+	assert.Equal(t, ClaudeToolsSourceInstance, tools.Source)
+	assert.Contains(t, tools.Disallowed, "Bash")
+
+	// A profile in the profiles directory replaces a built-in one
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "profiles"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "profiles", "strict-safety.env"),
+		[]byte("# Our team's safety settings\nCATNIP_COMMIT_TIMEOUT_SECONDS=5\n"), 0644))
+	_, err = manager.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, "5", os.Getenv("CATNIP_COMMIT_TIMEOUT_SECONDS"))
+	assert.Empty(t, os.Getenv(ClaudeAllowedToolsSetting))
+
+	profiles, err := manager.ListProfiles()
+	require.NoError(t, err)
+	require.Len(t, profiles, 3)
+	assert.Equal(t, "strict-safety", profiles[2].Name)
+	assert.Equal(t, "Our team's safety settings", profiles[2].Description)
+	assert.False(t, profiles[2].BuiltIn)
+
+	// Unknown profiles are reported and skipped
+	reload, err = manager.Set(map[string]string{ProfileSetting: "nope"})
+	require.NoError(t, err)
+	require.Len(t, reload.Ignored, 1)
+	assert.Contains(t, reload.Ignored[0], "unknown settings profile")
+	assert.Empty(t, manager.Status().Profile)
+	assert.Empty(t, os.Getenv("CATNIP_COMMIT_TIMEOUT_SECONDS"))
+
+	_, err = manager.Profile("../etc/passwd")
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// SetupConfig configures when setup.sh is re-run after a sync
+type SetupConfig struct {
+	// Re-run setup.sh automatically when a sync changes setup-relevant files.
+	// Defaults to CATNIP_SETUP_AUTO_RERUN; otherwise the UI offers a re-run.
+	AutoRerun *bool `json:"auto_rerun,omitempty" yaml:"auto_rerun"`
+	// Extra paths or globs (relative to the worktree root) that affect setup
+	Watch []string `json:"watch,omitempty" yaml:"watch"`
+}
+
+// SetupRerunner is implemented by setup executors that can replace a
+// finished (or running) setup session with a fresh run
+type SetupRerunner interface {
+	RerunSetupScript(worktreePath string) error
+}
+
+// Files whose changes mean the environment setup.sh built may be out of date.
+// Matched by base name so manifests in subdirectories count too.
+var defaultSetupWatchFiles = []string{
+	"package.json",
+	"package-lock.json",
+	"pnpm-lock.yaml",
+	"yarn.lock",
+	"bun.lock",
+	"bun.lockb",
+	"go.mod",
+	"go.sum",
+	"Cargo.lock",
+	"uv.lock",
+	"poetry.lock",
+	"Pipfile.lock",
+	"requirements*.txt",
+	"Gemfile.lock",
+	"composer.lock",
+}
+
+// setupRelevantChanges filters changed paths down to the ones that affect setup
+func setupRelevantChanges(changed []string, watch []string) []string {
+	var relevant []string
+	for _, file := range changed {
+		if matchesSetupWatch(file, watch) {
+			relevant = append(relevant, file)
+		}
+	}
+	return relevant
+}
+
+func matchesSetupWatch(file string, watch []string) bool {
+	if file == "setup.sh" || file == CatnipConfigFileName || isDevcontainerFile(file) {
+		return true
+	}
+	base := path.Base(file)
+	for _, pattern := range defaultSetupWatchFiles {
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	for _, pattern := range watch {
+		pattern = strings.TrimPrefix(pattern, "./")
+		if ok, _ := path.Match(pattern, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setupAutoRerunEnabled reports whether setup.sh should be re-run without asking
+func setupAutoRerunEnabled(cfg SetupConfig) bool {
+	if cfg.AutoRerun != nil {
+		return *cfg.AutoRerun
+	}
+	return os.Getenv("CATNIP_SETUP_AUTO_RERUN") == "1"
+}
+
+// checkSetupDrift looks for setup-relevant files changed between beforeHead and
+// the worktree's new HEAD. Matches mark the worktree and emit a setup-stale
+// event, and re-run setup.sh when auto re-run is enabled.
+func (s *GitService) checkSetupDrift(worktree *models.Worktree, beforeHead string) {
+	if beforeHead == "" {
+		return
+	}
+	if !hasSetupCommand(worktree.Path) {
+		return
+	}
+
+	output, err := s.operations.ExecuteGit(worktree.Path, "diff", "--name-only", beforeHead, "HEAD")
+	if err != nil {
+		logger.Warnf("⚠️ Failed to diff %s after sync: %v", worktree.Name, err)
+		return
+	}
+	cfg, err := LoadCatnipConfig(worktree.Path)
+	if err != nil {
+		logger.Warnf("⚠️ %v", err)
+		cfg = &CatnipConfig{}
+	}
+
+	changed := setupRelevantChanges(strings.Fields(string(output)), cfg.Setup.Watch)
+	if len(changed) == 0 {
+		return
+	}
+
+	// Keep files from earlier syncs that haven't been set up yet
+	if current, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		changed = mergeChangedFiles(current.SetupChangedFiles, changed)
+	}
+
+	autoRerun := setupAutoRerunEnabled(cfg.Setup)
+	logger.Infof("🔧 Sync of %s changed setup files %v (auto re-run: %v)", worktree.Name, changed, autoRerun)
+
+	if autoRerun {
+		err := s.RerunSetup(worktree.ID)
+		if err == nil {
+			if s.eventsEmitter != nil {
+				s.eventsEmitter.EmitWorktreeSetupStale(worktree.ID, worktree.Name, changed, true)
+			}
+			return
+		}
+		logger.Warnf("⚠️ Failed to re-run setup.sh for %s: %v", worktree.Name, err)
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{
+		"setup_changed_files": changed,
+	}); err != nil {
+		logger.Warnf("⚠️ Failed to mark setup as stale for %s: %v", worktree.Name, err)
+	}
+	if s.eventsEmitter != nil {
+		s.eventsEmitter.EmitWorktreeSetupStale(worktree.ID, worktree.Name, changed, false)
+	}
+}
+
+func mergeChangedFiles(existing, added []string) []string {
+	seen := make(map[string]bool, len(existing))
+	merged := append([]string{}, existing...)
+	for _, file := range existing {
+		seen[file] = true
+	}
+	for _, file := range added {
+		if !seen[file] {
+			seen[file] = true
+			merged = append(merged, file)
+		}
+	}
+	return merged
+}
+
+// RerunSetup re-runs setup.sh in the worktree's setup session and clears the
+// setup-stale marker
+func (s *GitService) RerunSetup(worktreeID string) error {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	executor := s.setupExecutor
+	s.mu.RUnlock()
+
+	if !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	rerunner, ok := executor.(SetupRerunner)
+	if !ok {
+		return fmt.Errorf("setup re-runs are not available")
+	}
+	if err := rerunner.RerunSetupScript(worktree.Path); err != nil {
+		return err
+	}
+
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"setup_changed_files": []string{},
+	})
+}
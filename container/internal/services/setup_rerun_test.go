@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type recordingSetupRerunner struct {
+	paths []string
+	err   error
+}
+
+func (r *recordingSetupRerunner) ExecuteSetupScript(worktreePath string) {}
+
+func (r *recordingSetupRerunner) RerunSetupScript(worktreePath string) error {
+	r.paths = append(r.paths, worktreePath)
+	return r.err
+}
+
+func TestSetupRelevantChanges(t *testing.T) {
+	changed := []string{
+		"setup.sh",
+		"README.md",
+		"web/package.json",
+		"pnpm-lock.yaml",
+		"requirements-dev.txt",
+		"src/main.go",
+		"scripts/bootstrap.sh",
+	}
+	assert.Equal(t,
+		[]string{"setup.sh", "web/package.json", "pnpm-lock.yaml", "requirements-dev.txt"},
+		setupRelevantChanges(changed, nil))
+	assert.Contains(t, setupRelevantChanges(changed, []string{"./scripts/*.sh"}), "scripts/bootstrap.sh")
+	assert.Empty(t, setupRelevantChanges([]string{"docs/setup.sh"}, nil), "only the root setup.sh counts")
+}
+
+func TestCheckSetupDrift(t *testing.T) {
+	t.Setenv("CATNIP_SETUP_AUTO_RERUN", "")
+	service := createTestGitService(t)
+	rerunner := &recordingSetupRerunner{}
+	service.SetSetupExecutor(rerunner)
+
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "setup.sh"), []byte("pnpm install\n"), 0755))
+	runTestGit(t, dir, "add", ".")
+	runTestGit(t, dir, "commit", "-q", "-m", "initial")
+
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	worktree := &models.Worktree{ID: "wt-setup", RepoID: "acme/app", Name: "feature", Path: dir}
+	require.NoError(t, service.stateManager.AddWorktree(worktree))
+
+	commit := func(file, content string) string {
+		before := strings.TrimSpace(runTestGit(t, dir, "rev-parse", "HEAD"))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+		runTestGit(t, dir, "add", ".")
+		runTestGit(t, dir, "commit", "-q", "-m", "update "+file)
+		return before
+	}
+
+	// Unrelated changes leave setup alone
+	service.checkSetupDrift(worktree, commit("README.md", "hi\n"))
+	current, _ := service.stateManager.GetWorktree(worktree.ID)
+	assert.Empty(t, current.SetupChangedFiles)
+
+	// Lockfile changes accumulate until setup is re-run
+	service.checkSetupDrift(worktree, commit("package.json", "{}\n"))
+	service.checkSetupDrift(worktree, commit("pnpm-lock.yaml", "lockfileVersion: 9\n"))
+	current, _ = service.stateManager.GetWorktree(worktree.ID)
+	assert.Equal(t, []string{"package.json", "pnpm-lock.yaml"}, current.SetupChangedFiles)
+	assert.Empty(t, rerunner.paths, "re-runs are offered, not automatic, by default")
+
+	require.NoError(t, service.RerunSetup(worktree.ID))
+	assert.Equal(t, []string{dir}, rerunner.paths)
+	current, _ = service.stateManager.GetWorktree(worktree.ID)
+	assert.Empty(t, current.SetupChangedFiles)
+
+	// Auto re-run clears the marker straight away; a failed re-run leaves it set
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".catnip.yaml"), []byte("setup:\n  auto_rerun: true\n"), 0644))
+	service.checkSetupDrift(worktree, commit("setup.sh", "pnpm install --frozen-lockfile\n"))
+	assert.Len(t, rerunner.paths, 2)
+	current, _ = service.stateManager.GetWorktree(worktree.ID)
+	assert.Empty(t, current.SetupChangedFiles)
+
+	rerunner.err = errors.New("boom")
+	service.checkSetupDrift(worktree, commit("go.mod", "module example\n"))
+	current, _ = service.stateManager.GetWorktree(worktree.ID)
+	assert.Equal(t, []string{"go.mod"}, current.SetupChangedFiles)
+}
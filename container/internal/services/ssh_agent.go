@@ -0,0 +1,384 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// ContainerSSHAgentSocket is where `catnip run` mounts the host's SSH agent
+const ContainerSSHAgentSocket = "/var/run/ssh-agent-host.sock"
+
+const (
+	// Largest agent message accepted, matching OpenSSH's limit
+	maxSSHAgentMessage = 256 * 1024
+	// Audit records kept in memory for the API
+	maxSSHAgentUsageRecords = 200
+)
+
+// SSH agent protocol message numbers (draft-miller-ssh-agent)
+const (
+	sshAgentFailure           = 5
+	sshAgentRequestIdentities = 11
+	sshAgentSignRequest       = 13
+	sshAgentSignResponse      = 14
+	sshAgentExtensionRequest  = 27
+)
+
+// SSHAgentUsage is an audit record of a workspace using a key through the
+// forwarded agent
+type SSHAgentUsage struct {
+	Time           time.Time `json:"time"`
+	WorktreeID     string    `json:"worktree_id"`
+	WorktreeName   string    `json:"worktree_name"`
+	KeyType        string    `json:"key_type,omitempty"`
+	KeyFingerprint string    `json:"key_fingerprint,omitempty"`
+	Success        bool      `json:"success"`
+}
+
+// SSHAgentStatus reports whether agent forwarding is possible
+type SSHAgentStatus struct {
+	Available bool `json:"available"`
+	// Whether workspaces forward the agent unless configured otherwise
+	DefaultEnabled bool   `json:"default_enabled"`
+	Upstream       string `json:"upstream,omitempty"`
+	// Workspaces with a live agent socket
+	ActiveWorkspaces int `json:"active_workspaces"`
+}
+
+type sshAgentListener struct {
+	worktreeID   string
+	worktreeName string
+	path         string
+	listener     net.Listener
+}
+
+// SSHAgentService gives PTY sessions access to the user's SSH agent. Each
+// workspace that forwards the agent gets its own socket, proxied to the
+// upstream agent: SSH_AUTH_SOCK in native mode, or the host agent `catnip
+// run` mounts in containerized mode. Only listing keys and signing are
+// forwarded, and every signature is written to the audit log.
+type SSHAgentService struct {
+	upstream       string
+	socketDir      string
+	auditPath      string
+	defaultEnabled bool
+
+	mu        sync.Mutex
+	listeners map[string]*sshAgentListener
+	recent    []SSHAgentUsage
+	auditMu   sync.Mutex
+	now       func() time.Time
+}
+
+// NewSSHAgentService creates an agent service for the current runtime.
+// Containerized workspaces only forward the agent by default when
+// CATNIP_SSH_AGENT_FORWARDING=true; native ones always had SSH_AUTH_SOCK.
+func NewSSHAgentService() *SSHAgentService {
+	upstream := os.Getenv("SSH_AUTH_SOCK")
+	defaultEnabled := true
+	if config.Runtime.IsContainerized() {
+		upstream = os.Getenv("CATNIP_SSH_AGENT_UPSTREAM")
+		if upstream == "" {
+			upstream = ContainerSSHAgentSocket
+		}
+		defaultEnabled = os.Getenv("CATNIP_SSH_AGENT_FORWARDING") == "true"
+	}
+	return NewSSHAgentServiceWithOptions(
+		upstream,
+		filepath.Join(config.Runtime.TempDir, "catnip-ssh-agent"),
+		filepath.Join(config.Runtime.VolumeDir, "ssh_agent_audit.jsonl"),
+		defaultEnabled,
+	)
+}
+
+// NewSSHAgentServiceWithOptions creates an agent service with explicit paths (for testing)
+func NewSSHAgentServiceWithOptions(upstream, socketDir, auditPath string, defaultEnabled bool) *SSHAgentService {
+	return &SSHAgentService{
+		upstream:       upstream,
+		socketDir:      socketDir,
+		auditPath:      auditPath,
+		defaultEnabled: defaultEnabled,
+		listeners:      make(map[string]*sshAgentListener),
+		now:            time.Now,
+	}
+}
+
+// Available reports whether there is an upstream agent to forward
+func (s *SSHAgentService) Available() bool {
+	if s.upstream == "" {
+		return false
+	}
+	info, err := os.Stat(s.upstream)
+	return err == nil && info.Mode()&os.ModeSocket != 0
+}
+
+// Enabled resolves a workspace's forwarding setting (nil means the default)
+func (s *SSHAgentService) Enabled(setting *bool) bool {
+	if setting != nil {
+		return *setting
+	}
+	return s.defaultEnabled
+}
+
+// Status reports the agent forwarding configuration
+func (s *SSHAgentService) Status() SSHAgentStatus {
+	s.mu.Lock()
+	active := len(s.listeners)
+	s.mu.Unlock()
+	return SSHAgentStatus{
+		Available:        s.Available(),
+		DefaultEnabled:   s.defaultEnabled,
+		Upstream:         s.upstream,
+		ActiveWorkspaces: active,
+	}
+}
+
+// SocketFor returns the agent socket for a workspace, starting its proxy if needed
+func (s *SSHAgentService) SocketFor(worktreeID, worktreeName string) (string, error) {
+	if !s.Available() {
+		return "", fmt.Errorf("no SSH agent available at %q", s.upstream)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l, ok := s.listeners[worktreeID]; ok {
+		l.worktreeName = worktreeName
+		return l.path, nil
+	}
+
+	if err := os.MkdirAll(s.socketDir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create agent socket directory: %w", err)
+	}
+	// Unix socket paths are limited to ~104 bytes, so don't use the raw ID
+	sum := sha256.Sum256([]byte(worktreeID))
+	path := filepath.Join(s.socketDir, hex.EncodeToString(sum[:6])+".sock")
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return "", fmt.Errorf("failed to listen on agent socket: %w", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return "", fmt.Errorf("failed to restrict agent socket: %w", err)
+	}
+
+	l := &sshAgentListener{worktreeID: worktreeID, worktreeName: worktreeName, path: path, listener: listener}
+	s.listeners[worktreeID] = l
+	go s.serve(l)
+	logger.Infof("🔑 Forwarding SSH agent to %s", worktreeName)
+	return path, nil
+}
+
+// Close stops forwarding the agent to a workspace
+func (s *SSHAgentService) Close(worktreeID string) {
+	s.mu.Lock()
+	l, ok := s.listeners[worktreeID]
+	delete(s.listeners, worktreeID)
+	s.mu.Unlock()
+	if ok {
+		l.listener.Close()
+		_ = os.Remove(l.path)
+	}
+}
+
+// Stop closes every workspace's agent socket
+func (s *SSHAgentService) Stop() {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.listeners))
+	for id := range s.listeners {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	for _, id := range ids {
+		s.Close(id)
+	}
+}
+
+// Usage returns recent key usage, newest first, optionally for one workspace
+func (s *SSHAgentService) Usage(worktreeID string) []SSHAgentUsage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := []SSHAgentUsage{}
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		if worktreeID == "" || s.recent[i].WorktreeID == worktreeID {
+			usage = append(usage, s.recent[i])
+		}
+	}
+	return usage
+}
+
+func (s *SSHAgentService) serve(l *sshAgentListener) {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.relay(l, conn)
+	}
+}
+
+// relay proxies one client connection. The agent protocol is strictly
+// request/response, so messages are relayed one exchange at a time.
+func (s *SSHAgentService) relay(l *sshAgentListener, client net.Conn) {
+	defer client.Close()
+
+	upstream, err := net.Dial("unix", s.upstream)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to connect to SSH agent for %s: %v", l.worktreeName, err)
+		return
+	}
+	defer upstream.Close()
+
+	for {
+		request, err := readSSHAgentMessage(client)
+		if err != nil {
+			return
+		}
+
+		if !sshAgentRequestAllowed(request) {
+			if err := writeSSHAgentMessage(client, []byte{sshAgentFailure}); err != nil {
+				return
+			}
+			continue
+		}
+
+		if err := writeSSHAgentMessage(upstream, request); err != nil {
+			return
+		}
+		response, err := readSSHAgentMessage(upstream)
+		if err != nil {
+			return
+		}
+
+		if request[0] == sshAgentSignRequest {
+			s.record(l, request, response)
+		}
+		if err := writeSSHAgentMessage(client, response); err != nil {
+			return
+		}
+	}
+}
+
+// sshAgentRequestAllowed lets through listing keys, signing and extensions
+// (e.g. session-bind@openssh.com); adding or removing keys and locking the
+// agent stay with the user
+func sshAgentRequestAllowed(request []byte) bool {
+	switch request[0] {
+	case sshAgentRequestIdentities, sshAgentSignRequest, sshAgentExtensionRequest:
+		return true
+	}
+	return false
+}
+
+func (s *SSHAgentService) record(l *sshAgentListener, request, response []byte) {
+	s.mu.Lock()
+	usage := SSHAgentUsage{
+		Time:         s.now(),
+		WorktreeID:   l.worktreeID,
+		WorktreeName: l.worktreeName,
+		Success:      len(response) > 0 && response[0] == sshAgentSignResponse,
+	}
+	s.mu.Unlock()
+
+	if blob, ok := readSSHString(request[1:]); ok {
+		if key, err := ssh.ParsePublicKey(blob); err == nil {
+			usage.KeyType = key.Type()
+			usage.KeyFingerprint = ssh.FingerprintSHA256(key)
+		}
+	}
+	logger.Infof("🔑 %s used SSH key %s (success: %t)", usage.WorktreeName, usage.KeyFingerprint, usage.Success)
+
+	s.mu.Lock()
+	s.recent = append(s.recent, usage)
+	if len(s.recent) > maxSSHAgentUsageRecords {
+		s.recent = s.recent[len(s.recent)-maxSSHAgentUsageRecords:]
+	}
+	s.mu.Unlock()
+
+	s.appendAudit(usage)
+}
+
+func (s *SSHAgentService) appendAudit(usage SSHAgentUsage) {
+	if s.auditPath == "" {
+		return
+	}
+	data, err := json.Marshal(usage)
+	if err != nil {
+		return
+	}
+
+	s.auditMu.Lock()
+	defer s.auditMu.Unlock()
+	f, err := os.OpenFile(s.auditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		logger.Warnf("⚠️ Failed to open SSH agent audit log: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		logger.Warnf("⚠️ Failed to write SSH agent audit log: %v", err)
+	}
+}
+
+func readSSHAgentMessage(r io.Reader) ([]byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || length > maxSSHAgentMessage {
+		return nil, errors.New("invalid SSH agent message length")
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func writeSSHAgentMessage(w io.Writer, message []byte) error {
+	frame := make([]byte, 4+len(message))
+	binary.BigEndian.PutUint32(frame, uint32(len(message)))
+	copy(frame[4:], message)
+	_, err := w.Write(frame)
+	return err
+}
+
+// readSSHString reads an SSH wire-format string (uint32 length + bytes)
+func readSSHString(data []byte) ([]byte, bool) {
+	if len(data) < 4 {
+		return nil, false
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint64(length) > uint64(len(data)-4) {
+		return nil, false
+	}
+	return data[4 : 4+length], true
+}
+
+// SetSSHAgentForwarding sets whether a worktree's terminals get the SSH agent
+// (nil restores the server default). Running sessions keep their environment.
+func (s *GitService) SetSSHAgentForwarding(worktreeID string, enabled *bool) error {
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{
+		"ssh_agent_forwarding": enabled,
+	})
+}
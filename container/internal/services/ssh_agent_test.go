@@ -0,0 +1,116 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// startTestAgent serves an in-memory keyring holding one ed25519 key
+func startTestAgent(t *testing.T, dir string) (string, ssh.PublicKey) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv}))
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	socket := filepath.Join(dir, "upstream.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+	return socket, signer.PublicKey()
+}
+
+func TestSSHAgentServiceProxy(t *testing.T) {
+	// Short paths keep us under the unix socket length limit
+	dir, err := os.MkdirTemp("", "sshag")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	upstream, pub := startTestAgent(t, dir)
+	auditPath := filepath.Join(dir, "audit.jsonl")
+	svc := NewSSHAgentServiceWithOptions(upstream, filepath.Join(dir, "s"), auditPath, false)
+	defer svc.Stop()
+
+	assert.True(t, svc.Available())
+	assert.False(t, svc.Enabled(nil))
+	enabled := true
+	assert.True(t, svc.Enabled(&enabled))
+
+	socket, err := svc.SocketFor("wt-1", "app/felix")
+	require.NoError(t, err)
+	again, err := svc.SocketFor("wt-1", "app/felix")
+	require.NoError(t, err)
+	assert.Equal(t, socket, again)
+	assert.Equal(t, 1, svc.Status().ActiveWorkspaces)
+
+	conn, err := net.Dial("unix", socket)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := agent.NewClient(conn)
+
+	keys, err := client.List()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, pub.Marshal(), keys[0].Marshal())
+
+	sig, err := client.Sign(pub, []byte("data"))
+	require.NoError(t, err)
+	assert.NoError(t, pub.Verify([]byte("data"), sig))
+
+	// Key management stays with the user
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	assert.Error(t, client.Add(agent.AddedKey{PrivateKey: priv}))
+	assert.Error(t, client.RemoveAll())
+	keys, err = client.List()
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	usage := svc.Usage("wt-1")
+	require.Len(t, usage, 1)
+	assert.Equal(t, "app/felix", usage[0].WorktreeName)
+	assert.Equal(t, ssh.FingerprintSHA256(pub), usage[0].KeyFingerprint)
+	assert.Equal(t, ssh.KeyAlgoED25519, usage[0].KeyType)
+	assert.True(t, usage[0].Success)
+	assert.Empty(t, svc.Usage("wt-2"))
+
+	data, err := os.ReadFile(auditPath)
+	require.NoError(t, err)
+	var logged SSHAgentUsage
+	require.NoError(t, json.Unmarshal(data, &logged))
+	assert.Equal(t, "wt-1", logged.WorktreeID)
+
+	svc.Close("wt-1")
+	_, err = os.Stat(socket)
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, 0, svc.Status().ActiveWorkspaces)
+}
+
+func TestSSHAgentServiceUnavailable(t *testing.T) {
+	svc := NewSSHAgentServiceWithOptions("", t.TempDir(), "", true)
+	assert.False(t, svc.Available())
+	_, err := svc.SocketFor("wt-1", "app/felix")
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// StagedCommit is the result of committing the staged subset of a worktree's changes
+type StagedCommit struct {
+	CommitHash string   `json:"commit_hash" example:"abc123def456"`
+	Files      []string `json:"files"`
+}
+
+// stagingWorktree resolves a worktree and validates a worktree-relative file path
+func (s *GitService) stagingWorktree(worktreeID, path string) (*models.Worktree, string, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, "", models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if path == "" {
+		return worktree, "", nil
+	}
+	cleaned := filepath.ToSlash(filepath.Clean(path))
+	if !filepath.IsLocal(cleaned) {
+		return nil, "", models.NewAPIError(models.ErrCodeInvalidRequest, "path %q must be relative to the worktree", path)
+	}
+	return worktree, cleaned, nil
+}
+
+// unstagedFileDiff diffs the working tree against the index for one file
+func (s *GitService) unstagedFileDiff(worktree *models.Worktree, path string) (*git.FileHunks, error) {
+	output, err := s.operations.ExecuteGit(worktree.Path, "diff", "--no-color", "--no-ext-diff",
+		"--src-prefix=a/", "--dst-prefix=b/", "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s: %v", path, err)
+	}
+	return git.ParseFileDiff(path, string(output))
+}
+
+// GetUnstagedHunks returns the unstaged hunks of a file in a worktree
+func (s *GitService) GetUnstagedHunks(worktreeID, path string) (*git.FileHunks, error) {
+	worktree, path, err := s.stagingWorktree(worktreeID, path)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "path is required")
+	}
+	return s.unstagedFileDiff(worktree, path)
+}
+
+// StageHunks stages the selected hunks of a file (like `git add -p`) and returns
+// the hunks still unstaged. Hunk IDs come from GetUnstagedHunks; staging fails
+// rather than guessing if the file changed in between.
+func (s *GitService) StageHunks(worktreeID, path string, hunkIDs []string) (*git.FileHunks, error) {
+	worktree, path, err := s.stagingWorktree(worktreeID, path)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" || len(hunkIDs) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "path and hunks are required")
+	}
+
+	diff, err := s.unstagedFileDiff(worktree, path)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := git.BuildHunkPatch(diff, hunkIDs)
+	if err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+
+	patchFile, err := os.CreateTemp("", "catnip-stage-*.patch")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(patchFile.Name())
+	if _, err := patchFile.WriteString(patch); err != nil {
+		patchFile.Close()
+		return nil, err
+	}
+	patchFile.Close()
+
+	if output, err := s.operations.ExecuteGit(worktree.Path, "apply", "--cached", "--whitespace=nowarn", patchFile.Name()); err != nil {
+		return nil, fmt.Errorf("failed to stage hunks of %s: %v\n%s", path, err, strings.TrimSpace(string(output)))
+	}
+	logger.Infof("➕ Staged %d hunks of %s in %s", len(hunkIDs), path, worktree.Name)
+
+	return s.unstagedFileDiff(worktree, path)
+}
+
+// UnstageFile removes a file's staged changes from the index, keeping the
+// working tree as is. An empty path unstages everything.
+func (s *GitService) UnstageFile(worktreeID, path string) error {
+	worktree, path, err := s.stagingWorktree(worktreeID, path)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"reset", "-q", "--"}
+	if path != "" {
+		args = append(args, path)
+	}
+	if output, err := s.operations.ExecuteGit(worktree.Path, args...); err != nil {
+		return fmt.Errorf("failed to unstage: %v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// StagedFiles lists the files with staged changes in a worktree
+func (s *GitService) StagedFiles(worktreeID string) ([]string, error) {
+	worktree, _, err := s.stagingWorktree(worktreeID, "")
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.operations.ExecuteGit(worktree.Path, "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %v", err)
+	}
+	files := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// CommitStaged commits only what is staged, leaving other changes in the working tree
+func (s *GitService) CommitStaged(worktreeID, message string) (*StagedCommit, error) {
+	if strings.TrimSpace(message) == "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "commit message is required")
+	}
+	files, err := s.StagedFiles(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "nothing is staged")
+	}
+
+	worktree, _ := s.stateManager.GetWorktree(worktreeID)
+	if err := s.operations.Commit(worktree.Path, message, git.CommitOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to commit staged changes: %v", err)
+	}
+	hash, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("✅ Committed %d staged files in %s: %s", len(files), worktree.Name, hash)
+
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, s.statusSourceRef)
+	return &StagedCommit{CommitHash: hash, Files: files}, nil
+}
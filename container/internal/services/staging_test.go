@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestStageHunksAndCommit(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+	service := createTestGitService(t)
+
+	dir := t.TempDir()
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	file := filepath.Join(dir, "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	runTestGit(t, dir, "add", ".")
+	runTestGit(t, dir, "commit", "-q", "-m", "initial")
+
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-stage", RepoID: "acme/app", Name: "stage", Path: dir, Branch: "main", SourceBranch: "main"}))
+
+	lines[1] = "line 2 changed"
+	lines[17] = "line 18 changed"
+	require.NoError(t, os.WriteFile(file, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+
+	diff, err := service.GetUnstagedHunks("wt-stage", "a.txt")
+	require.NoError(t, err)
+	require.Len(t, diff.Hunks, 2)
+
+	remaining, err := service.StageHunks("wt-stage", "a.txt", []string{diff.Hunks[1].ID})
+	require.NoError(t, err)
+	require.Len(t, remaining.Hunks, 1)
+	assert.Equal(t, diff.Hunks[0].ID, remaining.Hunks[0].ID)
+
+	_, err = service.StageHunks("wt-stage", "a.txt", []string{diff.Hunks[1].ID})
+	assert.Error(t, err, "an already staged hunk is stale")
+
+	commit, err := service.CommitStaged("wt-stage", "Change line 18")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.txt"}, commit.Files)
+	assert.Contains(t, runTestGit(t, dir, "show", "HEAD"), "+line 18 changed")
+	assert.NotContains(t, runTestGit(t, dir, "show", "HEAD"), "line 2 changed")
+	assert.Contains(t, runTestGit(t, dir, "diff"), "+line 2 changed")
+
+	_, err = service.CommitStaged("wt-stage", "Nothing")
+	assert.Error(t, err)
+	_, err = service.GetUnstagedHunks("wt-stage", "../outside.txt")
+	assert.Error(t, err)
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// JournalOperation identifies a multi-step operation recorded in the state journal
+type JournalOperation string
+
+const (
+	// JournalCreateWorktree covers `git worktree add` followed by saving the worktree to state
+	JournalCreateWorktree JournalOperation = "create_worktree"
+	// JournalRenameBranch covers creating the nice branch followed by saving the new name to state
+	JournalRenameBranch JournalOperation = "rename_branch"
+)
+
+// Recovery actions taken for interrupted operations
+const (
+	RecoveryCompleted  = "completed"   // Finished the remaining steps
+	RecoveryRolledBack = "rolled_back" // Undid the git-side steps
+	RecoveryDiscarded  = "discarded"   // Nothing had happened yet, entry dropped
+)
+
+// JournalEntry is a write-ahead record of an in-flight multi-step operation
+type JournalEntry struct {
+	ID           string           `json:"id"`
+	Op           JournalOperation `json:"op"`
+	WorktreeID   string           `json:"worktree_id,omitempty"`
+	RepoID       string           `json:"repo_id,omitempty"`
+	RepoPath     string           `json:"repo_path,omitempty"`
+	WorktreePath string           `json:"worktree_path,omitempty"`
+	Branch       string           `json:"branch,omitempty"`
+	NewBranch    string           `json:"new_branch,omitempty"`
+	StartedAt    time.Time        `json:"started_at"`
+}
+
+// RecoveryAction reports what the startup recovery pass did with an interrupted operation
+type RecoveryAction struct {
+	Entry  JournalEntry `json:"entry"`
+	Action string       `json:"action"`
+	Detail string       `json:"detail"`
+}
+
+// JournalRecoveryOperations are the git operations needed to repair interrupted operations
+type JournalRecoveryOperations interface {
+	BranchExists(repoPath, branch string, isRemote bool) bool
+	ListWorktrees(repoPath string) ([]git.WorktreeInfo, error)
+	RemoveWorktree(repoPath, worktreePath string, force bool) error
+	PruneWorktrees(repoPath string) error
+}
+
+func (wsm *WorktreeStateManager) journalPath() string {
+	return filepath.Join(wsm.stateDir, "journal.json")
+}
+
+// readJournal loads pending journal entries (must be called with journalMu held)
+func (wsm *WorktreeStateManager) readJournal() ([]JournalEntry, error) {
+	data, err := os.ReadFile(wsm.journalPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var entries []JournalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("corrupt state journal: %v", err)
+	}
+	return entries, nil
+}
+
+// writeJournal atomically replaces the journal (must be called with journalMu held)
+func (wsm *WorktreeStateManager) writeJournal(entries []JournalEntry) error {
+	if len(entries) == 0 {
+		if err := os.Remove(wsm.journalPath()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(wsm.stateDir, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temp file and rename so a crash never leaves a half-written journal
+	tmpPath := wsm.journalPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, wsm.journalPath())
+}
+
+// BeginOperation records an operation in the journal before any of its steps
+// run and returns the entry ID to pass to CompleteOperation
+func (wsm *WorktreeStateManager) BeginOperation(entry JournalEntry) (string, error) {
+	wsm.journalMu.Lock()
+	defer wsm.journalMu.Unlock()
+
+	entries, err := wsm.readJournal()
+	if err != nil {
+		return "", err
+	}
+
+	entry.ID = uuid.New().String()
+	entry.StartedAt = time.Now()
+	if err := wsm.writeJournal(append(entries, entry)); err != nil {
+		return "", fmt.Errorf("failed to write state journal: %v", err)
+	}
+	return entry.ID, nil
+}
+
+// CompleteOperation removes a finished (or cleanly failed) operation from the journal
+func (wsm *WorktreeStateManager) CompleteOperation(id string) {
+	if id == "" {
+		return
+	}
+
+	wsm.journalMu.Lock()
+	defer wsm.journalMu.Unlock()
+
+	entries, err := wsm.readJournal()
+	if err != nil {
+		logger.Warnf("⚠️ Failed to read state journal: %v", err)
+		return
+	}
+
+	remaining := entries[:0]
+	for _, entry := range entries {
+		if entry.ID != id {
+			remaining = append(remaining, entry)
+		}
+	}
+	if err := wsm.writeJournal(remaining); err != nil {
+		logger.Warnf("⚠️ Failed to update state journal: %v", err)
+	}
+}
+
+// PendingOperations returns the operations currently recorded in the journal
+func (wsm *WorktreeStateManager) PendingOperations() []JournalEntry {
+	wsm.journalMu.Lock()
+	defer wsm.journalMu.Unlock()
+
+	entries, err := wsm.readJournal()
+	if err != nil {
+		logger.Warnf("⚠️ Failed to read state journal: %v", err)
+	}
+	return entries
+}
+
+// LastRecovery returns the actions taken by the most recent recovery pass
+func (wsm *WorktreeStateManager) LastRecovery() []RecoveryAction {
+	wsm.journalMu.Lock()
+	defer wsm.journalMu.Unlock()
+	return append([]RecoveryAction(nil), wsm.lastRecovery...)
+}
+
+// RecoverJournal completes or rolls back operations interrupted by a crash so
+// that state.json and the git layer agree again. It should run on startup
+// before RestoreState.
+func (wsm *WorktreeStateManager) RecoverJournal(ops JournalRecoveryOperations) []RecoveryAction {
+	wsm.journalMu.Lock()
+	defer wsm.journalMu.Unlock()
+
+	entries, err := wsm.readJournal()
+	if err != nil {
+		// A corrupt journal can't be replayed; move it aside so startup isn't blocked forever
+		logger.Errorf("❌ %v - moving journal aside", err)
+		_ = os.Rename(wsm.journalPath(), wsm.journalPath()+".corrupt")
+		return nil
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	logger.Infof("🩹 Recovering %d interrupted operation(s) from state journal", len(entries))
+
+	wsm.mu.Lock()
+	var actions []RecoveryAction
+	for _, entry := range entries {
+		var action RecoveryAction
+		switch entry.Op {
+		case JournalCreateWorktree:
+			action = wsm.recoverCreateWorktree(entry, ops)
+		case JournalRenameBranch:
+			action = wsm.recoverRenameBranch(entry, ops)
+		default:
+			action = RecoveryAction{Entry: entry, Action: RecoveryDiscarded, Detail: fmt.Sprintf("unknown operation %q", entry.Op)}
+		}
+		logger.Infof("🩹 %s %s (%s): %s", action.Action, entry.Op, entry.Branch, action.Detail)
+		actions = append(actions, action)
+	}
+	if err := wsm.saveStateInternal(); err != nil {
+		logger.Warnf("⚠️ Failed to save state after journal recovery: %v", err)
+	}
+	wsm.mu.Unlock()
+
+	if err := wsm.writeJournal(nil); err != nil {
+		logger.Warnf("⚠️ Failed to clear state journal: %v", err)
+	}
+	wsm.lastRecovery = actions
+	return actions
+}
+
+// recoverCreateWorktree keeps a worktree that made it into state and otherwise
+// removes the git worktree so it doesn't linger unknown to catnip (must hold mu)
+func (wsm *WorktreeStateManager) recoverCreateWorktree(entry JournalEntry, ops JournalRecoveryOperations) RecoveryAction {
+	var gitPath string
+	if infos, err := ops.ListWorktrees(entry.RepoPath); err == nil {
+		for _, info := range infos {
+			if info.Branch == entry.Branch || info.Branch == "refs/heads/"+entry.Branch {
+				gitPath = info.Path
+				break
+			}
+		}
+	}
+
+	if gitPath == "" {
+		_ = ops.PruneWorktrees(entry.RepoPath)
+		return RecoveryAction{Entry: entry, Action: RecoveryDiscarded, Detail: "git worktree was never created"}
+	}
+
+	for _, worktree := range wsm.worktrees {
+		if worktree.Path == gitPath {
+			return RecoveryAction{Entry: entry, Action: RecoveryCompleted, Detail: fmt.Sprintf("worktree %s was already saved to state", worktree.Name)}
+		}
+	}
+
+	if err := ops.RemoveWorktree(entry.RepoPath, gitPath, true); err != nil {
+		// Fall back to deleting the directory and pruning the registration
+		_ = os.RemoveAll(gitPath)
+		_ = ops.PruneWorktrees(entry.RepoPath)
+	}
+	return RecoveryAction{Entry: entry, Action: RecoveryRolledBack, Detail: fmt.Sprintf("removed untracked git worktree at %s", gitPath)}
+}
+
+// recoverRenameBranch finishes a rename whose nice branch was created but never
+// recorded in state (must hold mu)
+func (wsm *WorktreeStateManager) recoverRenameBranch(entry JournalEntry, ops JournalRecoveryOperations) RecoveryAction {
+	worktree, exists := wsm.worktrees[entry.WorktreeID]
+	if !exists {
+		return RecoveryAction{Entry: entry, Action: RecoveryDiscarded, Detail: "worktree no longer exists"}
+	}
+	if worktree.HasBeenRenamed {
+		return RecoveryAction{Entry: entry, Action: RecoveryCompleted, Detail: "rename was already saved to state"}
+	}
+
+	branch := strings.TrimPrefix(entry.NewBranch, "refs/heads/")
+	if !ops.BranchExists(worktree.Path, branch, false) {
+		return RecoveryAction{Entry: entry, Action: RecoveryDiscarded, Detail: "nice branch was never created"}
+	}
+
+	worktree.Branch = entry.NewBranch
+	worktree.HasBeenRenamed = true
+	return RecoveryAction{Entry: entry, Action: RecoveryCompleted, Detail: fmt.Sprintf("recorded rename to %s", entry.NewBranch)}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// fakeRecoveryOps records the repairs the recovery pass performs
+type fakeRecoveryOps struct {
+	branches  map[string]bool
+	worktrees []git.WorktreeInfo
+	removed   []string
+}
+
+func (f *fakeRecoveryOps) BranchExists(repoPath, branch string, isRemote bool) bool {
+	return f.branches[branch]
+}
+
+func (f *fakeRecoveryOps) ListWorktrees(repoPath string) ([]git.WorktreeInfo, error) {
+	return f.worktrees, nil
+}
+
+func (f *fakeRecoveryOps) RemoveWorktree(repoPath, worktreePath string, force bool) error {
+	f.removed = append(f.removed, worktreePath)
+	return nil
+}
+
+func (f *fakeRecoveryOps) PruneWorktrees(repoPath string) error {
+	return nil
+}
+
+func newJournalTestManager(t *testing.T) *WorktreeStateManager {
+	wsm := NewWorktreeStateManager(t.TempDir(), nil)
+	t.Cleanup(wsm.Stop)
+	require.NoError(t, wsm.AddRepository(&models.Repository{ID: "test/repo", Path: "/repos/repo.git"}))
+	return wsm
+}
+
+func TestStateJournal_BeginComplete(t *testing.T) {
+	wsm := newJournalTestManager(t)
+
+	id, err := wsm.BeginOperation(JournalEntry{Op: JournalCreateWorktree, Branch: "refs/catnip/felix"})
+	require.NoError(t, err)
+	require.Len(t, wsm.PendingOperations(), 1)
+
+	wsm.CompleteOperation(id)
+	assert.Empty(t, wsm.PendingOperations())
+}
+
+func TestStateJournal_RecoverCreateWorktree(t *testing.T) {
+	wsm := newJournalTestManager(t)
+	require.NoError(t, wsm.AddWorktree(&models.Worktree{ID: "wt-saved", RepoID: "test/repo", Path: "/workspace/repo/saved"}))
+
+	_, err := wsm.BeginOperation(JournalEntry{Op: JournalCreateWorktree, RepoPath: "/repos/repo.git", Branch: "refs/catnip/saved"})
+	require.NoError(t, err)
+	_, err = wsm.BeginOperation(JournalEntry{Op: JournalCreateWorktree, RepoPath: "/repos/repo.git", Branch: "refs/catnip/orphan"})
+	require.NoError(t, err)
+	_, err = wsm.BeginOperation(JournalEntry{Op: JournalCreateWorktree, RepoPath: "/repos/repo.git", Branch: "refs/catnip/never"})
+	require.NoError(t, err)
+
+	ops := &fakeRecoveryOps{worktrees: []git.WorktreeInfo{
+		{Path: "/workspace/repo/saved", Branch: "refs/catnip/saved"},
+		{Path: "/workspace/repo/orphan", Branch: "refs/catnip/orphan"},
+	}}
+
+	actions := wsm.RecoverJournal(ops)
+	require.Len(t, actions, 3)
+	assert.Equal(t, RecoveryCompleted, actions[0].Action)
+	assert.Equal(t, RecoveryRolledBack, actions[1].Action)
+	assert.Equal(t, RecoveryDiscarded, actions[2].Action)
+	assert.Equal(t, []string{"/workspace/repo/orphan"}, ops.removed)
+
+	assert.Empty(t, wsm.PendingOperations())
+	assert.Len(t, wsm.LastRecovery(), 3)
+}
+
+func TestStateJournal_RecoverRenameBranch(t *testing.T) {
+	wsm := newJournalTestManager(t)
+	require.NoError(t, wsm.AddWorktree(&models.Worktree{ID: "wt-1", RepoID: "test/repo", Path: "/workspace/repo/felix", Branch: "refs/catnip/felix"}))
+	require.NoError(t, wsm.AddWorktree(&models.Worktree{ID: "wt-2", RepoID: "test/repo", Path: "/workspace/repo/tom", Branch: "refs/catnip/tom"}))
+
+	_, err := wsm.BeginOperation(JournalEntry{Op: JournalRenameBranch, WorktreeID: "wt-1", Branch: "refs/catnip/felix", NewBranch: "feature/login"})
+	require.NoError(t, err)
+	_, err = wsm.BeginOperation(JournalEntry{Op: JournalRenameBranch, WorktreeID: "wt-2", Branch: "refs/catnip/tom", NewBranch: "feature/never"})
+	require.NoError(t, err)
+
+	actions := wsm.RecoverJournal(&fakeRecoveryOps{branches: map[string]bool{"feature/login": true}})
+	require.Len(t, actions, 2)
+	assert.Equal(t, RecoveryCompleted, actions[0].Action)
+	assert.Equal(t, RecoveryDiscarded, actions[1].Action)
+
+	renamed, _ := wsm.GetWorktree("wt-1")
+	assert.Equal(t, "feature/login", renamed.Branch)
+	assert.True(t, renamed.HasBeenRenamed)
+
+	untouched, _ := wsm.GetWorktree("wt-2")
+	assert.Equal(t, "refs/catnip/tom", untouched.Branch)
+	assert.False(t, untouched.HasBeenRenamed)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// DefaultSyncUndoWindow is how long a sync can be undone unless
+	// CATNIP_SYNC_UNDO_WINDOW says otherwise
+	DefaultSyncUndoWindow = time.Hour
+
+	syncAutoStashMessage  = "catnip: auto-stash before sync"
+	syncUndoBackupMessage = "catnip: changes made after sync, set aside by undo"
+)
+
+// syncUndoWindow reads the undo window from CATNIP_SYNC_UNDO_WINDOW (a Go
+// duration such as "30m" or "24h")
+func syncUndoWindow() time.Duration {
+	if raw := os.Getenv("CATNIP_SYNC_UNDO_WINDOW"); raw != "" {
+		if window, err := time.ParseDuration(raw); err == nil && window > 0 {
+			return window
+		}
+		logger.Warnf("⚠️ Ignoring invalid CATNIP_SYNC_UNDO_WINDOW %q", raw)
+	}
+	return DefaultSyncUndoWindow
+}
+
+// hasTrackedChanges reports staged or unstaged changes to tracked files, the
+// changes that block a merge or rebase. Untracked files are left in place.
+func (s *GitService) hasTrackedChanges(worktreePath string) bool {
+	_, err := s.operations.ExecuteGit(worktreePath, "diff", "--quiet", "HEAD")
+	return err != nil
+}
+
+// stashTrackedChanges stashes tracked changes and returns the stash commit
+func (s *GitService) stashTrackedChanges(worktreePath, message string) (string, error) {
+	if output, err := s.operations.ExecuteGit(worktreePath, "stash", "push", "-m", message); err != nil {
+		return "", fmt.Errorf("failed to stash changes: %v\n%s", err, strings.TrimSpace(string(output)))
+	}
+	return s.operations.GetCommitHash(worktreePath, "stash@{0}")
+}
+
+// reflogLength counts the worktree's HEAD reflog entries
+func (s *GitService) reflogLength(worktreePath string) int {
+	output, err := s.operations.ExecuteGit(worktreePath, "reflog", "show", "--format=%H", "HEAD")
+	if err != nil {
+		return 0
+	}
+	return len(strings.Fields(string(output)))
+}
+
+// prepareSyncUndo records the state a sync can be rolled back to and stashes
+// tracked changes so the merge or rebase starts from a clean tree
+func (s *GitService) prepareSyncUndo(worktree *models.Worktree, strategy, sourceRef, preHead string) (*models.SyncSnapshot, error) {
+	if preHead == "" {
+		return nil, nil
+	}
+
+	now := time.Now()
+	snapshot := &models.SyncSnapshot{
+		Strategy:       strategy,
+		SourceRef:      sourceRef,
+		PreHead:        preHead,
+		ReflogPosition: s.reflogLength(worktree.Path),
+		SyncedAt:       now,
+		UndoExpiresAt:  now.Add(syncUndoWindow()),
+	}
+
+	if s.hasTrackedChanges(worktree.Path) {
+		stash, err := s.stashTrackedChanges(worktree.Path, syncAutoStashMessage)
+		if err != nil {
+			return nil, fmt.Errorf("cannot %s: %v", strategy, err)
+		}
+		snapshot.AutoStash = stash
+		logger.Infof("📦 Auto-stashed changes in %s before sync: %s", worktree.Name, stash)
+	}
+	return snapshot, nil
+}
+
+// finishSyncUndo re-applies the auto-stash after a successful sync and saves
+// the snapshot. After a failed sync the stash is left for undo to restore.
+func (s *GitService) finishSyncUndo(worktree *models.Worktree, snapshot *models.SyncSnapshot, syncErr error) {
+	if snapshot == nil {
+		return
+	}
+
+	if syncErr == nil {
+		snapshot.PostHead, _ = s.operations.GetCommitHash(worktree.Path, "HEAD")
+		if snapshot.AutoStash != "" {
+			if err := s.operations.StashPop(worktree.Path); err != nil {
+				logger.Warnf("⚠️ Failed to re-apply auto-stash %s in %s, it stays in the stash list: %v", snapshot.AutoStash, worktree.Name, err)
+			} else {
+				snapshot.AutoStashRestored = true
+			}
+		}
+	} else {
+		// A sync that failed without touching the worktree has nothing to undo
+		head, _ := s.operations.GetCommitHash(worktree.Path, "HEAD")
+		if head == snapshot.PreHead && s.syncInProgress(worktree.Path) == "" {
+			if snapshot.AutoStash != "" {
+				if err := s.operations.StashPop(worktree.Path); err != nil {
+					logger.Warnf("⚠️ Failed to re-apply auto-stash %s in %s, it stays in the stash list: %v", snapshot.AutoStash, worktree.Name, err)
+				}
+			}
+			return
+		}
+		if snapshot.AutoStash != "" {
+			logger.Warnf("⚠️ Sync of %s failed; uncommitted changes stay in stash %s until the sync is undone", worktree.Name, snapshot.AutoStash)
+		}
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"last_sync": snapshot}); err != nil {
+		logger.Warnf("⚠️ Failed to record sync of %s for undo: %v", worktree.Name, err)
+	}
+}
+
+// syncInProgress reports a merge or rebase stopped on conflicts ("merge",
+// "rebase" or "")
+func (s *GitService) syncInProgress(worktreePath string) string {
+	for name, operation := range map[string]string{
+		"rebase-merge": "rebase",
+		"rebase-apply": "rebase",
+		"MERGE_HEAD":   "merge",
+	} {
+		// Linked worktrees keep these under the main repo's .git/worktrees
+		output, err := s.operations.ExecuteCommand("git", "-C", worktreePath, "rev-parse", "--git-path", name)
+		if err != nil {
+			continue
+		}
+		gitPath := strings.TrimSpace(string(output))
+		if !filepath.IsAbs(gitPath) {
+			gitPath = filepath.Join(worktreePath, gitPath)
+		}
+		if _, err := os.Stat(gitPath); err == nil {
+			return operation
+		}
+	}
+	return ""
+}
+
+// abortSyncInProgress aborts a merge or rebase left stopped on conflicts
+func (s *GitService) abortSyncInProgress(worktreePath string) {
+	var err error
+	switch s.syncInProgress(worktreePath) {
+	case "rebase":
+		err = s.operations.AbortRebase(worktreePath)
+	case "merge":
+		_, err = s.operations.ExecuteGit(worktreePath, "merge", "--abort")
+	}
+	if err != nil {
+		logger.Warnf("⚠️ Failed to abort sync in %s: %v", worktreePath, err)
+	}
+}
+
+// dropStash removes a stash entry by its commit, if it is still listed
+func (s *GitService) dropStash(worktreePath, stash string) {
+	output, err := s.operations.ExecuteGit(worktreePath, "stash", "list", "--format=%H")
+	if err != nil {
+		return
+	}
+	for i, commit := range strings.Fields(string(output)) {
+		if commit == stash {
+			_, _ = s.operations.ExecuteGit(worktreePath, "stash", "drop", fmt.Sprintf("stash@{%d}", i))
+			return
+		}
+	}
+}
+
+// UndoSync resets a worktree to its state before the last sync: any stopped
+// merge or rebase is aborted, HEAD is reset to the pre-sync commit and the
+// auto-stash is re-applied. Changes made since the sync are stashed first.
+// Unless force is set, undo refuses when commits were made after the sync.
+func (s *GitService) UndoSync(worktreeID string, force bool) (*models.SyncUndoResult, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	snapshot := worktree.LastSync
+	if snapshot == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has no sync to undo", worktree.Name)
+	}
+	if time.Now().After(snapshot.UndoExpiresAt) {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "the last sync of %s can no longer be undone (window closed at %s)",
+			worktree.Name, snapshot.UndoExpiresAt.Format(time.RFC3339)).
+			WithHint("Use git reflog in a terminal to find the pre-sync commit")
+	}
+
+	release := s.acquireRepoSlot(worktree.RepoID, repoOpSync)
+	defer release()
+
+	head, _ := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if snapshot.PostHead != "" && head != snapshot.PostHead && !force {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "HEAD of %s moved since the sync; undoing it would drop the newer commits", worktree.Name).
+			WithHint("Undo with force to reset anyway; the newer commits stay reachable through git reflog")
+	}
+
+	s.abortSyncInProgress(worktree.Path)
+
+	result := &models.SyncUndoResult{Sync: *snapshot}
+
+	// The pre-sync entry is the newest one that existed when the sync started
+	if offset := s.reflogLength(worktree.Path) - snapshot.ReflogPosition; snapshot.ReflogPosition > 0 && offset >= 0 {
+		entry := fmt.Sprintf("HEAD@{%d}", offset)
+		if commit, err := s.operations.GetCommitHash(worktree.Path, entry); err == nil && commit == snapshot.PreHead {
+			result.ReflogEntry = entry
+		}
+	}
+
+	// Set aside changes made since the sync (including a re-applied auto-stash)
+	// so the reset cannot lose them. Without the backup there is no undo, even
+	// with force: force only covers commits made after the sync.
+	if s.hasTrackedChanges(worktree.Path) {
+		backup, err := s.stashTrackedChanges(worktree.Path, syncUndoBackupMessage)
+		if err != nil {
+			return nil, fmt.Errorf("cannot undo sync: failed to back up uncommitted changes: %v", err)
+		}
+		result.BackupStash = backup
+	}
+
+	if output, err := s.operations.ExecuteGit(worktree.Path, "reset", "--hard", snapshot.PreHead); err != nil {
+		return nil, fmt.Errorf("failed to reset to %s: %v\n%s", snapshot.PreHead, err, strings.TrimSpace(string(output)))
+	}
+
+	if snapshot.AutoStash != "" {
+		if _, err := s.operations.ExecuteGit(worktree.Path, "stash", "apply", "--index", snapshot.AutoStash); err != nil {
+			if output, err := s.operations.ExecuteGit(worktree.Path, "stash", "apply", snapshot.AutoStash); err != nil {
+				return nil, fmt.Errorf("reset to %s but failed to re-apply auto-stash %s: %v\n%s",
+					snapshot.PreHead, snapshot.AutoStash, err, strings.TrimSpace(string(output)))
+			}
+		}
+		result.RestoredAutoStash = true
+		s.dropStash(worktree.Path, snapshot.AutoStash)
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"last_sync": (*models.SyncSnapshot)(nil)}); err != nil {
+		logger.Warnf("⚠️ Failed to clear sync snapshot for %s: %v", worktree.Name, err)
+	}
+	s.gitWorktreeManager.UpdateWorktreeStatus(worktree, s.statusSourceRef)
+
+	logger.Infof("⏪ Undid %s sync of %s back to %s", snapshot.Strategy, worktree.Name, snapshot.PreHead)
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// setupSyncUndoRepo creates a repository whose feature branch is behind main
+func setupSyncUndoRepo(t *testing.T, conflicting bool) (*GitService, *models.Worktree) {
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+	service := createTestGitService(t)
+
+	dir := t.TempDir()
+	write := func(file, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+	}
+	commit := func(message string) {
+		runTestGit(t, dir, "add", ".")
+		runTestGit(t, dir, "commit", "-q", "-m", message)
+	}
+
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	write("a.txt", "base\n")
+	commit("initial")
+	runTestGit(t, dir, "checkout", "-q", "-b", "feature")
+	if conflicting {
+		write("a.txt", "feature\n")
+	} else {
+		write("b.txt", "feature\n")
+	}
+	commit("feature work")
+	runTestGit(t, dir, "checkout", "-q", "main")
+	if conflicting {
+		write("a.txt", "main\n")
+	} else {
+		write("c.txt", "main\n")
+	}
+	commit("main work")
+	runTestGit(t, dir, "checkout", "-q", "feature")
+
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "local/app", Path: dir}))
+	worktree := &models.Worktree{ID: "wt-sync", RepoID: "local/app", Name: "feature", Path: dir, Branch: "feature", SourceBranch: "main"}
+	require.NoError(t, service.stateManager.AddWorktree(worktree))
+	return service, worktree
+}
+
+func readTestFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestUndoSyncRestoresAutoStash(t *testing.T) {
+	service, worktree := setupSyncUndoRepo(t, false)
+	dir := worktree.Path
+	preHead := runTestGit(t, dir, "rev-parse", "HEAD")
+
+	_, err := service.UndoSync(worktree.ID, false)
+	assert.Error(t, err, "nothing to undo before a sync")
+
+	// Uncommitted changes are stashed for the rebase and re-applied after it
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("dirty\n"), 0644))
+	require.NoError(t, service.SyncWorktree(worktree.ID, "rebase"))
+	assert.FileExists(t, filepath.Join(dir, "c.txt"))
+	assert.Equal(t, "dirty\n", readTestFile(t, filepath.Join(dir, "a.txt")))
+
+	current, _ := service.stateManager.GetWorktree(worktree.ID)
+	require.NotNil(t, current.LastSync)
+	assert.Equal(t, preHead, current.LastSync.PreHead)
+	assert.NotEmpty(t, current.LastSync.AutoStash)
+	assert.True(t, current.LastSync.AutoStashRestored)
+	assert.WithinDuration(t, time.Now().Add(DefaultSyncUndoWindow), current.LastSync.UndoExpiresAt, time.Minute)
+
+	result, err := service.UndoSync(worktree.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, preHead, runTestGit(t, dir, "rev-parse", "HEAD"))
+	assert.Regexp(t, `^HEAD@\{\d+\}$`, result.ReflogEntry)
+	assert.True(t, result.RestoredAutoStash)
+	assert.NotEmpty(t, result.BackupStash, "the re-applied changes are backed up before the reset")
+	assert.NoFileExists(t, filepath.Join(dir, "c.txt"))
+	assert.Equal(t, "dirty\n", readTestFile(t, filepath.Join(dir, "a.txt")))
+
+	current, _ = service.stateManager.GetWorktree(worktree.ID)
+	assert.Nil(t, current.LastSync)
+	_, err = service.UndoSync(worktree.ID, false)
+	assert.Error(t, err, "a sync can only be undone once")
+}
+
+func TestUndoSyncAbortsConflictedMerge(t *testing.T) {
+	service, worktree := setupSyncUndoRepo(t, true)
+	dir := worktree.Path
+	preHead := runTestGit(t, dir, "rev-parse", "HEAD")
+
+	err := service.SyncWorktree(worktree.ID, "merge")
+	require.Error(t, err)
+	assert.Equal(t, "merge", service.syncInProgress(dir))
+
+	_, err = service.UndoSync(worktree.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, "", service.syncInProgress(dir))
+	assert.Equal(t, preHead, runTestGit(t, dir, "rev-parse", "HEAD"))
+	assert.Equal(t, "feature\n", readTestFile(t, filepath.Join(dir, "a.txt")))
+	assert.Empty(t, runTestGit(t, dir, "status", "--porcelain"))
+}
+
+func TestUndoSyncGuards(t *testing.T) {
+	service, worktree := setupSyncUndoRepo(t, false)
+	dir := worktree.Path
+	preHead := runTestGit(t, dir, "rev-parse", "HEAD")
+
+	// An unknown strategy fails before touching the worktree, leaving nothing to undo
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("dirty\n"), 0644))
+	assert.Error(t, service.SyncWorktree(worktree.ID, "squash"))
+	assert.Equal(t, "dirty\n", readTestFile(t, filepath.Join(dir, "a.txt")))
+	current, _ := service.stateManager.GetWorktree(worktree.ID)
+	assert.Nil(t, current.LastSync)
+	runTestGit(t, dir, "checkout", "--", "a.txt")
+
+	require.NoError(t, service.SyncWorktree(worktree.ID, "merge"))
+
+	// Commits made after the sync need force
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "d.txt"), []byte("later\n"), 0644))
+	runTestGit(t, dir, "add", ".")
+	runTestGit(t, dir, "commit", "-q", "-m", "later work")
+	_, err := service.UndoSync(worktree.ID, false)
+	require.Error(t, err)
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+
+	// The undo window is enforced
+	current, _ = service.stateManager.GetWorktree(worktree.ID)
+	snapshot := current.LastSync
+	expired := *snapshot
+	expired.UndoExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, service.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"last_sync": &expired}))
+	_, err = service.UndoSync(worktree.ID, true)
+	assert.Error(t, err)
+
+	require.NoError(t, service.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"last_sync": snapshot}))
+
+	// Force never skips backing up uncommitted changes: a failed stash aborts
+	laterHead := runTestGit(t, dir, "rev-parse", "HEAD")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "d.txt"), []byte("uncommitted\n"), 0644))
+	stashLock := filepath.Join(runTestGit(t, dir, "rev-parse", "--path-format=absolute", "--git-common-dir"), "refs", "stash.lock")
+	require.NoError(t, os.WriteFile(stashLock, nil, 0644))
+	_, err = service.UndoSync(worktree.ID, true)
+	require.Error(t, err)
+	assert.Equal(t, laterHead, runTestGit(t, dir, "rev-parse", "HEAD"))
+	assert.Equal(t, "uncommitted\n", readTestFile(t, filepath.Join(dir, "d.txt")))
+	require.NoError(t, os.Remove(stashLock))
+
+	_, err = service.UndoSync(worktree.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, preHead, runTestGit(t, dir, "rev-parse", "HEAD"))
+}
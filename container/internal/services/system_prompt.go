@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// System prompt layer sources, in the order they are concatenated
+const (
+	SystemPromptLayerOrg        = "org"
+	SystemPromptLayerRepository = "repository"
+	SystemPromptLayerWorkspace  = "workspace"
+	SystemPromptLayerRequest    = "request"
+)
+
+// AppendSystemPromptEnv carries the layered system prompt to the claude
+// wrapper (catnip purr), which passes it on as --append-system-prompt
+const AppendSystemPromptEnv = "CATNIP_APPEND_SYSTEM_PROMPT"
+
+// orgSystemPrompt returns the org-wide system prompt from volume settings.json
+func (s *ClaudeService) orgSystemPrompt() string {
+	settings, err := s.readVolumeSettings()
+	if err != nil {
+		return ""
+	}
+	prompt, _ := settings["systemPrompt"].(string)
+	return strings.TrimSpace(prompt)
+}
+
+// LayeredSystemPrompt builds the prompt appended to Claude's system prompt in
+// workingDir: the org-wide prompt from settings, the repository prompt from
+// .catnip.yaml, workspace context (e.g. a linked issue) and a per-request
+// addition, in that order. Empty layers are skipped.
+func (s *ClaudeService) LayeredSystemPrompt(workingDir, workspaceContext, addition string) *models.EffectiveSystemPrompt {
+	repoPrompt := ""
+	if workingDir != "" {
+		if cfg, err := LoadCatnipConfig(workingDir); err != nil {
+			logger.Warnf("⚠️ Ignoring repository system prompt in %s: %v", workingDir, err)
+		} else {
+			repoPrompt = cfg.Claude.SystemPrompt
+		}
+	}
+
+	return concatSystemPromptLayers([]models.SystemPromptLayer{
+		{Source: SystemPromptLayerOrg, Content: s.orgSystemPrompt()},
+		{Source: SystemPromptLayerRepository, Content: repoPrompt},
+		{Source: SystemPromptLayerWorkspace, Content: workspaceContext},
+		{Source: SystemPromptLayerRequest, Content: addition},
+	})
+}
+
+// completionAppendPrompt returns the layered prompt for an API completion. A
+// full system prompt override means the caller controls the whole prompt, so
+// no layers are added.
+func (s *ClaudeService) completionAppendPrompt(req *models.CreateCompletionRequest, workingDir string) string {
+	if req.SystemPrompt != "" {
+		return ""
+	}
+	return s.LayeredSystemPrompt(workingDir, "", req.AppendSystemPrompt).Prompt
+}
+
+func concatSystemPromptLayers(layers []models.SystemPromptLayer) *models.EffectiveSystemPrompt {
+	effective := &models.EffectiveSystemPrompt{Layers: []models.SystemPromptLayer{}}
+	parts := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		layer.Content = strings.TrimSpace(layer.Content)
+		if layer.Content == "" {
+			continue
+		}
+		effective.Layers = append(effective.Layers, layer)
+		parts = append(parts, layer.Content)
+	}
+	effective.Prompt = strings.Join(parts, "\n\n")
+	return effective
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestLayeredSystemPrompt(t *testing.T) {
+	dir := t.TempDir()
+	worktree := filepath.Join(dir, "worktree")
+	require.NoError(t, os.MkdirAll(worktree, 0755))
+
+	s := NewClaudeServiceWithWrapper(NewMockClaudeSubprocessWrapper())
+	s.settingsPath = filepath.Join(dir, "settings.json")
+	s.claudeConfigPath = filepath.Join(dir, ".claude.json")
+
+	// No layers configured
+	effective := s.LayeredSystemPrompt(worktree, "", "")
+	assert.Empty(t, effective.Layers)
+	assert.Equal(t, "", effective.Prompt)
+
+	orgPrompt := "  Follow CONTRIBUTING.md.\n"
+	settings, err := s.UpdateClaudeSettings(&models.ClaudeSettingsUpdateRequest{SystemPrompt: &orgPrompt})
+	require.NoError(t, err)
+	assert.Equal(t, "Follow CONTRIBUTING.md.", settings.SystemPrompt)
+	assert.True(t, settings.NotificationsEnabled)
+
+	require.NoError(t, os.WriteFile(filepath.Join(worktree, CatnipConfigFileName), []byte("claude:\n  system_prompt: Run pnpm typecheck before committing.\n"), 0644))
+
+	effective = s.LayeredSystemPrompt(worktree, "Issue #42", "Keep it short.")
+	require.Len(t, effective.Layers, 4)
+	assert.Equal(t, []string{SystemPromptLayerOrg, SystemPromptLayerRepository, SystemPromptLayerWorkspace, SystemPromptLayerRequest},
+		[]string{effective.Layers[0].Source, effective.Layers[1].Source, effective.Layers[2].Source, effective.Layers[3].Source})
+	assert.Equal(t, "Follow CONTRIBUTING.md.\n\nRun pnpm typecheck before committing.\n\nIssue #42\n\nKeep it short.", effective.Prompt)
+
+	// Completions get the org/repo layers plus the request's addition...
+	req := &models.CreateCompletionRequest{Prompt: "hi", AppendSystemPrompt: "Keep it short."}
+	assert.Equal(t, "Follow CONTRIBUTING.md.\n\nRun pnpm typecheck before committing.\n\nKeep it short.", s.completionAppendPrompt(req, worktree))
+	// ...unless they replace the whole system prompt
+	req.SystemPrompt = "You generate branch names."
+	assert.Equal(t, "", s.completionAppendPrompt(req, worktree))
+
+	// Clearing the org prompt keeps other settings
+	cleared := ""
+	settings, err = s.UpdateClaudeSettings(&models.ClaudeSettingsUpdateRequest{SystemPrompt: &cleared})
+	require.NoError(t, err)
+	assert.Equal(t, "", settings.SystemPrompt)
+	assert.Equal(t, "Run pnpm typecheck before committing.", s.LayeredSystemPrompt(worktree, "", "").Prompt)
+}
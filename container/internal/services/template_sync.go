@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/git/templates"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+// defaultTemplateSyncInterval is how often the template repository is pulled
+const defaultTemplateSyncInterval = 15 * time.Minute
+
+// TemplateSyncStatus reports the state of the template repository sync
+type TemplateSyncStatus struct {
+	Enabled       bool      `json:"enabled"`
+	RepoURL       string    `json:"repo_url,omitempty"`
+	Ref           string    `json:"ref,omitempty"`
+	LastSync      time.Time `json:"last_sync,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	TemplateCount int       `json:"template_count"`
+	Invalid       []string  `json:"invalid,omitempty"` // Templates skipped because their manifest failed validation
+}
+
+// TemplateSyncService periodically clones/pulls a git repository of project
+// templates and publishes them to the template registry
+type TemplateSyncService struct {
+	operations   git.Operations
+	repoURL      string
+	ref          string
+	dir          string
+	syncInterval time.Duration
+	mu           sync.Mutex // Serializes syncs and guards status
+	status       TemplateSyncStatus
+	stopChan     chan struct{}
+	running      bool
+}
+
+// NewTemplateSyncService creates a template sync service configured from
+// CATNIP_TEMPLATE_REPO, CATNIP_TEMPLATE_REF and CATNIP_TEMPLATE_SYNC_INTERVAL
+func NewTemplateSyncService() *TemplateSyncService {
+	interval := defaultTemplateSyncInterval
+	if raw := os.Getenv("CATNIP_TEMPLATE_SYNC_INTERVAL"); raw != "" {
+		if parsed, err := time.ParseDuration(raw); err == nil && parsed >= time.Minute {
+			interval = parsed
+		} else {
+			logger.Warnf("⚠️ Ignoring invalid CATNIP_TEMPLATE_SYNC_INTERVAL %q (minimum 1m)", raw)
+		}
+	}
+
+	return NewTemplateSyncServiceWithOptions(
+		git.NewOperations(),
+		os.Getenv("CATNIP_TEMPLATE_REPO"),
+		os.Getenv("CATNIP_TEMPLATE_REF"),
+		filepath.Join(config.Runtime.VolumeDir, "templates"),
+		interval,
+	)
+}
+
+// NewTemplateSyncServiceWithOptions creates a template sync service with explicit settings (for testing)
+func NewTemplateSyncServiceWithOptions(operations git.Operations, repoURL, ref, dir string, interval time.Duration) *TemplateSyncService {
+	return &TemplateSyncService{
+		operations:   operations,
+		repoURL:      strings.TrimSpace(repoURL),
+		ref:          strings.TrimSpace(ref),
+		dir:          dir,
+		syncInterval: interval,
+		stopChan:     make(chan struct{}),
+		status: TemplateSyncStatus{
+			Enabled: strings.TrimSpace(repoURL) != "",
+			RepoURL: strings.TrimSpace(repoURL),
+			Ref:     strings.TrimSpace(ref),
+		},
+	}
+}
+
+// Start performs an initial sync in the background and then syncs periodically.
+// It does nothing when no template repository is configured.
+func (s *TemplateSyncService) Start() {
+	if s.repoURL == "" {
+		return
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	logger.Infof("📦 Syncing project templates from %s every %v", s.repoURL, s.syncInterval)
+
+	go func() {
+		if err := s.Sync(); err != nil {
+			logger.Warnf("⚠️ Initial template sync failed: %v", err)
+		}
+
+		ticker := time.NewTicker(s.syncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stopChan:
+				return
+			case <-ticker.C:
+				if err := s.Sync(); err != nil {
+					logger.Warnf("⚠️ Template sync failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops periodic syncing
+func (s *TemplateSyncService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+}
+
+// Status returns the current sync status
+func (s *TemplateSyncService) Status() TemplateSyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Sync clones or updates the template repository and reloads its templates.
+// On failure the previously loaded templates stay registered.
+func (s *TemplateSyncService) Sync() error {
+	if s.repoURL == "" {
+		return fmt.Errorf("no template repository configured (set CATNIP_TEMPLATE_REPO)")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.pull(); err != nil {
+		s.status.LastError = err.Error()
+		return err
+	}
+
+	loaded, errs := templates.LoadTemplatesFromDir(s.dir)
+	templates.SetRemoteTemplates(loaded)
+
+	s.status.LastSync = time.Now()
+	s.status.LastError = ""
+	s.status.TemplateCount = len(loaded)
+	s.status.Invalid = nil
+	for _, err := range errs {
+		logger.Warnf("⚠️ Skipping invalid template: %v", err)
+		s.status.Invalid = append(s.status.Invalid, err.Error())
+	}
+
+	logger.Infof("✅ Loaded %d templates from %s", len(loaded), s.repoURL)
+	return nil
+}
+
+// pull clones the repository on first use and hard-resets to the remote ref afterwards
+func (s *TemplateSyncService) pull() error {
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(s.dir), 0755); err != nil {
+			return fmt.Errorf("failed to create templates directory: %v", err)
+		}
+		// Clear out any partial clone from an earlier failed attempt
+		_ = os.RemoveAll(s.dir)
+
+		args := []string{"clone", "--depth", "1"}
+		if s.ref != "" {
+			args = append(args, "--branch", s.ref)
+		}
+		args = append(args, s.repoURL, s.dir)
+		if output, err := s.operations.ExecuteGit("", args...); err != nil {
+			return fmt.Errorf("failed to clone template repository: %v\nOutput: %s", err, string(output))
+		}
+		return nil
+	}
+
+	ref := s.ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if output, err := s.operations.ExecuteGit(s.dir, "fetch", "--depth", "1", "origin", ref); err != nil {
+		return fmt.Errorf("failed to fetch template repository: %v\nOutput: %s", err, string(output))
+	}
+	if output, err := s.operations.ExecuteGit(s.dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+		return fmt.Errorf("failed to update template repository: %v\nOutput: %s", err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,649 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// testImpactMaxFiles bounds how many source files are analyzed
+	testImpactMaxFiles = 20000
+	// testImpactMaxJSFileSize skips bundles and other huge files when reading imports
+	testImpactMaxJSFileSize = 1 << 20
+)
+
+// Directories never searched for source files
+var testImpactSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"coverage":     true,
+}
+
+var jsSourceExtensions = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".cjs", ".mts", ".cts"}
+
+// jsImportPattern matches the module of import/export ... from, bare and
+// dynamic imports, require() and vi/jest.mock()
+var jsImportPattern = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*|\brequire\s*\(\s*|\b(?:vi|jest)\.mock\s*\(\s*)['"]([^'"\n]+)['"]`)
+
+// WorktreeTestImpact maps the files changed in a worktree against its source
+// branch to the tests likely affected by them
+func (s *GitService) WorktreeTestImpact(worktreeID string) (*models.TestImpact, error) {
+	diff, err := s.GetWorktreeDiffWithOptions(worktreeID, WorktreeDiffOptions{TestImpact: true})
+	if err != nil {
+		return nil, err
+	}
+	return diff.TestImpact, nil
+}
+
+// diffChangedFiles lists every file of a diff, including ignored ones:
+// generated code still changes what tests see
+func diffChangedFiles(diff *git.WorktreeDiffResponse) []string {
+	var files []string
+	for _, file := range diff.FileDiffs {
+		files = append(files, file.FilePath)
+	}
+	for _, file := range diff.IgnoredFiles {
+		files = append(files, file.FilePath)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// AnalyzeTestImpact maps changed files, relative to dir, to the test files
+// likely affected by them. Go changes propagate through the package import
+// graph of the modules in dir, JavaScript and TypeScript changes through
+// relative and tsconfig-aliased imports. Changes to go.mod or package.json
+// affect every test of that language.
+func AnalyzeTestImpact(dir string, changed []string) *models.TestImpact {
+	files, partial := listTestImpactFiles(dir)
+	impact := &models.TestImpact{
+		ChangedFiles: changed,
+		Tests:        []models.ImpactedTest{},
+		Partial:      partial,
+	}
+
+	tests := make(map[string]models.ImpactedTest)
+	add := func(test models.ImpactedTest) {
+		if existing, ok := tests[test.Path]; !ok || test.Distance < existing.Distance {
+			tests[test.Path] = test
+		}
+	}
+
+	goGraph := newGoImportGraph(dir, files)
+	jsGraph := newJSImportGraph(dir, files, changed)
+	for _, file := range changed {
+		var found []models.ImpactedTest
+		switch {
+		case strings.HasSuffix(file, ".go") || path.Base(file) == "go.mod" || path.Base(file) == "go.sum" || goGraph.owningPackage(file) != "":
+			found = goGraph.impactOf(file)
+		case isJSSource(file) || path.Base(file) == "package.json" || isTSConfig(file):
+			found = jsGraph.impactOf(file)
+		}
+		for _, test := range found {
+			add(test)
+		}
+		if len(found) == 0 && (strings.HasSuffix(file, ".go") || isJSSource(file)) && !isGoTestFile(file) && !isJSTestFile(file) {
+			impact.Unmapped = append(impact.Unmapped, file)
+		}
+	}
+
+	for _, test := range tests {
+		impact.Tests = append(impact.Tests, test)
+	}
+	sort.Slice(impact.Tests, func(i, j int) bool {
+		if impact.Tests[i].Distance != impact.Tests[j].Distance {
+			return impact.Tests[i].Distance < impact.Tests[j].Distance
+		}
+		return impact.Tests[i].Path < impact.Tests[j].Path
+	})
+
+	impact.GoPackages, impact.Commands = goGraph.commands(impact.Tests)
+	if command := jsGraph.command(dir, impact.Tests); command != "" {
+		impact.Commands = append(impact.Commands, command)
+	}
+	return impact
+}
+
+// listTestImpactFiles lists the Go and JavaScript sources and manifests in
+// dir as slash-separated relative paths
+func listTestImpactFiles(dir string) ([]string, bool) {
+	var files []string
+	partial := false
+	_ = filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		name := entry.Name()
+		if entry.IsDir() {
+			if p != dir && (strings.HasPrefix(name, ".") || testImpactSkipDirs[name]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(name, ".go") && !isJSSource(name) && name != "go.mod" && name != "package.json" && !isTSConfig(name) {
+			return nil
+		}
+		if len(files) >= testImpactMaxFiles {
+			partial = true
+			return filepath.SkipAll
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err == nil {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, partial
+}
+
+func isGoTestFile(file string) bool {
+	return strings.HasSuffix(file, "_test.go")
+}
+
+func isJSSource(file string) bool {
+	if strings.HasSuffix(file, ".d.ts") {
+		return false
+	}
+	ext := path.Ext(file)
+	for _, candidate := range jsSourceExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func isJSTestFile(file string) bool {
+	base := path.Base(file)
+	return isJSSource(file) && (strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		strings.HasPrefix(file, "__tests__/") || strings.Contains(file, "/__tests__/"))
+}
+
+func isTSConfig(file string) bool {
+	base := path.Base(file)
+	return strings.HasPrefix(base, "tsconfig") && strings.HasSuffix(base, ".json")
+}
+
+// goModule is a go.mod found in the analyzed tree
+type goModule struct {
+	dir  string // Relative to the tree, "." for the root
+	path string // Module path from the module directive
+}
+
+// goImportGraph links the Go packages of a tree by their imports
+type goImportGraph struct {
+	modules   []goModule
+	packages  map[string]bool            // Package directories
+	tests     map[string][]string        // Test files by package directory
+	rdeps     map[string][]string        // Packages whose non-test files import a package
+	testFiles map[string][]string        // Test files importing a package, from any package
+	testDeps  map[string]map[string]bool // Packages each test file imports
+}
+
+func newGoImportGraph(dir string, files []string) *goImportGraph {
+	g := &goImportGraph{
+		packages:  make(map[string]bool),
+		tests:     make(map[string][]string),
+		rdeps:     make(map[string][]string),
+		testFiles: make(map[string][]string),
+		testDeps:  make(map[string]map[string]bool),
+	}
+	for _, file := range files {
+		if path.Base(file) == "go.mod" {
+			if modulePath := readGoModulePath(filepath.Join(dir, filepath.FromSlash(file))); modulePath != "" {
+				g.modules = append(g.modules, goModule{dir: path.Dir(file), path: modulePath})
+			}
+		}
+	}
+	// Longest module paths first, so nested modules win
+	sort.Slice(g.modules, func(i, j int) bool { return len(g.modules[i].path) > len(g.modules[j].path) })
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		pkg := path.Dir(file)
+		g.packages[pkg] = true
+		isTest := isGoTestFile(file)
+		if isTest {
+			g.tests[pkg] = append(g.tests[pkg], file)
+			g.testDeps[file] = make(map[string]bool)
+		}
+
+		parsed, err := parser.ParseFile(fset, filepath.Join(dir, filepath.FromSlash(file)), nil, parser.ImportsOnly)
+		if err != nil {
+			continue
+		}
+		for _, spec := range parsed.Imports {
+			importPath, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+			dep, ok := g.packageDir(importPath)
+			if !ok {
+				continue
+			}
+			if isTest {
+				if !g.testDeps[file][dep] {
+					g.testDeps[file][dep] = true
+					g.testFiles[dep] = append(g.testFiles[dep], file)
+				}
+			} else if dep != pkg {
+				g.rdeps[dep] = appendUnique(g.rdeps[dep], pkg)
+			}
+		}
+	}
+	return g
+}
+
+func readGoModulePath(goModPath string) string {
+	file, err := os.Open(goModPath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// packageDir maps an import path to a package directory in the tree
+func (g *goImportGraph) packageDir(importPath string) (string, bool) {
+	for _, module := range g.modules {
+		if importPath == module.path {
+			return module.dir, true
+		}
+		if rest, ok := strings.CutPrefix(importPath, module.path+"/"); ok {
+			return path.Join(module.dir, rest), true
+		}
+	}
+	return "", false
+}
+
+// owningPackage returns the package a non-Go file belongs to, such as an
+// embedded template or testdata fixture, or "" if it's outside any package
+func (g *goImportGraph) owningPackage(file string) string {
+	dir := path.Dir(file)
+	if i := strings.Index("/"+dir+"/", "/testdata/"); i >= 0 {
+		dir = path.Clean(strings.TrimPrefix(("/" + dir + "/")[:i], "/"))
+		if dir == "" {
+			dir = "."
+		}
+	}
+	if g.packages[dir] {
+		return dir
+	}
+	return ""
+}
+
+// moduleOf returns the module containing a directory
+func (g *goImportGraph) moduleOf(dir string) (goModule, bool) {
+	var best goModule
+	found := false
+	for _, module := range g.modules {
+		if (module.dir == "." || dir == module.dir || strings.HasPrefix(dir, module.dir+"/")) && (!found || len(module.dir) > len(best.dir)) {
+			best, found = module, true
+		}
+	}
+	return best, found
+}
+
+// impactOf returns the Go tests a changed file affects
+func (g *goImportGraph) impactOf(file string) []models.ImpactedTest {
+	var found []models.ImpactedTest
+	if isGoTestFile(file) {
+		if g.packages[path.Dir(file)] && slices.Contains(g.tests[path.Dir(file)], file) {
+			found = append(found, models.ImpactedTest{Path: file, Language: "go", Distance: 0, Reason: "changed"})
+		}
+		return found
+	}
+
+	if base := path.Base(file); base == "go.mod" || base == "go.sum" {
+		module, ok := g.moduleOf(path.Dir(file))
+		if !ok {
+			return nil
+		}
+		for pkg, tests := range g.tests {
+			if owner, _ := g.moduleOf(pkg); owner == module {
+				for _, test := range tests {
+					found = append(found, models.ImpactedTest{Path: test, Language: "go", Distance: 1, Reason: base + " changed"})
+				}
+			}
+		}
+		return found
+	}
+
+	changedPkg := path.Dir(file)
+	if !strings.HasSuffix(file, ".go") {
+		changedPkg = g.owningPackage(file)
+	}
+	if !g.packages[changedPkg] {
+		return nil
+	}
+
+	// Walk out from the changed package through the packages importing it
+	distance := map[string]int{changedPkg: 0}
+	queue := []string{changedPkg}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.rdeps[pkg] {
+			if _, seen := distance[dependent]; !seen {
+				distance[dependent] = distance[pkg] + 1
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	for pkg, d := range distance {
+		reason := "same package as " + file
+		if d > 0 {
+			reason = "depends on " + changedPkg
+		}
+		for _, test := range g.tests[pkg] {
+			found = append(found, models.ImpactedTest{Path: test, Language: "go", Distance: d + 1, Reason: reason})
+		}
+		// Tests importing the package directly, such as external _test packages
+		for _, test := range g.testFiles[pkg] {
+			reason := "imports " + pkg
+			if d > 0 {
+				reason = "depends on " + changedPkg
+			}
+			found = append(found, models.ImpactedTest{Path: test, Language: "go", Distance: d + 1, Reason: reason})
+		}
+	}
+	return found
+}
+
+// commands lists the packages of the impacted Go tests and a go test
+// command per module running them
+func (g *goImportGraph) commands(tests []models.ImpactedTest) ([]string, []string) {
+	pkgSet := make(map[string]bool)
+	for _, test := range tests {
+		if test.Language == "go" {
+			pkgSet[path.Dir(test.Path)] = true
+		}
+	}
+	var packages []string
+	byModule := make(map[string][]string)
+	for pkg := range pkgSet {
+		packages = append(packages, goPackagePattern(pkg))
+		module, ok := g.moduleOf(pkg)
+		if !ok {
+			continue
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(pkg, module.dir), "/")
+		if module.dir == "." {
+			rel = pkg
+		}
+		byModule[module.dir] = append(byModule[module.dir], goPackagePattern(rel))
+	}
+	sort.Strings(packages)
+
+	var commands []string
+	for moduleDir, patterns := range byModule {
+		sort.Strings(patterns)
+		command := "go test " + strings.Join(patterns, " ")
+		if moduleDir != "." {
+			command = fmt.Sprintf("cd %s && %s", moduleDir, command)
+		}
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return packages, commands
+}
+
+func goPackagePattern(dir string) string {
+	if dir == "" || dir == "." {
+		return "."
+	}
+	return "./" + dir
+}
+
+// jsAlias is a tsconfig paths mapping such as "@/*": ["./src/*"]
+type jsAlias struct {
+	prefix   string // Specifier prefix, or the whole specifier when exact
+	exact    bool
+	replaces []string // Tree-relative paths the prefix stands for
+}
+
+// jsImportGraph links JavaScript and TypeScript files by their imports
+type jsImportGraph struct {
+	files   map[string]bool
+	tests   []string
+	aliases []jsAlias
+	rdeps   map[string][]string // Files importing a file
+}
+
+func newJSImportGraph(dir string, files, changed []string) *jsImportGraph {
+	g := &jsImportGraph{
+		files: make(map[string]bool),
+		rdeps: make(map[string][]string),
+	}
+	for _, file := range files {
+		if isJSSource(file) {
+			g.files[file] = true
+			if isJSTestFile(file) {
+				g.tests = append(g.tests, file)
+			}
+		} else if isTSConfig(file) && path.Dir(file) == "." {
+			g.aliases = append(g.aliases, readTSConfigAliases(dir, file)...)
+		}
+	}
+	// Imports of deleted files should still resolve to them
+	for _, file := range changed {
+		if isJSSource(file) {
+			if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file))); os.IsNotExist(err) {
+				g.files[file] = true
+			}
+		}
+	}
+
+	for file := range g.files {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil || info.Size() > testImpactMaxJSFileSize {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			continue
+		}
+		for _, match := range jsImportPattern.FindAllStringSubmatch(string(data), -1) {
+			if target := g.resolve(file, match[1]); target != "" && target != file {
+				g.rdeps[target] = appendUnique(g.rdeps[target], file)
+			}
+		}
+	}
+	return g
+}
+
+// readTSConfigAliases reads compilerOptions.paths from a tsconfig
+func readTSConfigAliases(dir, file string) []jsAlias {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil
+	}
+	var tsconfig struct {
+		CompilerOptions struct {
+			BaseURL string              `json:"baseUrl"`
+			Paths   map[string][]string `json:"paths"`
+		} `json:"compilerOptions"`
+	}
+	if err := json.Unmarshal(stripJSONC(data), &tsconfig); err != nil {
+		return nil
+	}
+
+	base := path.Join(path.Dir(file), tsconfig.CompilerOptions.BaseURL)
+	var aliases []jsAlias
+	for pattern, targets := range tsconfig.CompilerOptions.Paths {
+		alias := jsAlias{prefix: strings.TrimSuffix(pattern, "*"), exact: !strings.HasSuffix(pattern, "*")}
+		for _, target := range targets {
+			alias.replaces = append(alias.replaces, path.Join(base, strings.TrimSuffix(target, "*")))
+		}
+		aliases = append(aliases, alias)
+	}
+	return aliases
+}
+
+// resolve maps an import specifier in a file to a file in the tree, or ""
+// for packages and anything else outside it
+func (g *jsImportGraph) resolve(from, spec string) string {
+	spec, _, _ = strings.Cut(spec, "?")
+	var bases []string
+	if spec == "." || spec == ".." || strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") {
+		bases = append(bases, path.Join(path.Dir(from), spec))
+	} else {
+		for _, alias := range g.aliases {
+			if alias.exact && spec == alias.prefix {
+				bases = append(bases, alias.replaces...)
+			} else if rest, ok := strings.CutPrefix(spec, alias.prefix); ok && !alias.exact {
+				for _, replace := range alias.replaces {
+					bases = append(bases, path.Join(replace, rest))
+				}
+			}
+		}
+	}
+
+	for _, base := range bases {
+		candidates := []string{base}
+		// TypeScript ESM imports name the compiled .js file
+		if ext := path.Ext(base); ext == ".js" || ext == ".jsx" || ext == ".mjs" || ext == ".cjs" {
+			candidates = append(candidates, strings.TrimSuffix(base, ext))
+		}
+		for _, candidate := range candidates {
+			if g.files[candidate] {
+				return candidate
+			}
+			for _, ext := range jsSourceExtensions {
+				if g.files[candidate+ext] {
+					return candidate + ext
+				}
+			}
+			for _, ext := range jsSourceExtensions {
+				if g.files[candidate+"/index"+ext] {
+					return candidate + "/index" + ext
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// impactOf returns the JavaScript tests a changed file affects
+func (g *jsImportGraph) impactOf(file string) []models.ImpactedTest {
+	var found []models.ImpactedTest
+	if path.Base(file) == "package.json" || isTSConfig(file) {
+		for _, test := range g.tests {
+			found = append(found, models.ImpactedTest{Path: test, Language: "javascript", Distance: 1, Reason: path.Base(file) + " changed"})
+		}
+		return found
+	}
+	if !g.files[file] {
+		return nil
+	}
+	if isJSTestFile(file) && slices.Contains(g.tests, file) {
+		found = append(found, models.ImpactedTest{Path: file, Language: "javascript", Distance: 0, Reason: "changed"})
+	}
+
+	// Walk out from the changed file through the files importing it
+	distance := map[string]int{file: 0}
+	queue := []string{file}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, importer := range g.rdeps[current] {
+			if _, seen := distance[importer]; seen {
+				continue
+			}
+			d := distance[current] + 1
+			distance[importer] = d
+			queue = append(queue, importer)
+			if isJSTestFile(importer) {
+				reason := "imports " + file
+				if d > 1 {
+					reason = "depends on " + file
+				}
+				found = append(found, models.ImpactedTest{Path: importer, Language: "javascript", Distance: d, Reason: reason})
+			}
+		}
+	}
+	return found
+}
+
+// command returns a command running the impacted JavaScript tests with the
+// root package.json's test runner, or "" if it has none catnip knows
+func (g *jsImportGraph) command(dir string, tests []models.ImpactedTest) string {
+	var files []string
+	for _, test := range tests {
+		if test.Language == "javascript" {
+			files = append(files, test.Path)
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	sort.Strings(files)
+
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	hasDependency := func(name string) bool {
+		_, dep := pkg.Dependencies[name]
+		_, devDep := pkg.DevDependencies[name]
+		return dep || devDep
+	}
+
+	runner := ""
+	switch {
+	case hasDependency("vitest"):
+		runner = "vitest run"
+	case hasDependency("jest"):
+		runner = "jest"
+	default:
+		return ""
+	}
+	exec := "npx"
+	if _, err := os.Stat(filepath.Join(dir, "pnpm-lock.yaml")); err == nil {
+		exec = "pnpm exec"
+	} else if _, err := os.Stat(filepath.Join(dir, "yarn.lock")); err == nil {
+		exec = "yarn"
+	}
+	return fmt.Sprintf("%s %s %s", exec, runner, strings.Join(files, " "))
+}
+
+func appendUnique(items []string, item string) []string {
+	if slices.Contains(items, item) {
+		return items
+	}
+	return append(items, item)
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func writeTestImpactTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func impactedPaths(impact *models.TestImpact) map[string]int {
+	paths := make(map[string]int)
+	for _, test := range impact.Tests {
+		paths[test.Path] = test.Distance
+	}
+	return paths
+}
+
+func TestAnalyzeTestImpactGo(t *testing.T) {
+	dir := writeTestImpactTree(t, map[string]string{
+		"go.mod":                          "module example.com/app\n\ngo 1.25\n",
+		"main.go":                         "package main\n\nimport _ \"example.com/app/internal/api\"\n",
+		"internal/store/store.go":         "package store\n",
+		"internal/store/store_test.go":    "package store\n",
+		"internal/store/testdata/a.json":  "{}",
+		"internal/api/api.go":             "package api\n\nimport (\n\t\"fmt\"\n\t_ \"example.com/app/internal/store\"\n)\n\nvar _ = fmt.Sprint\n",
+		"internal/api/api_test.go":        "package api\n",
+		"internal/ui/ui.go":               "package ui\n",
+		"internal/ui/ui_test.go":          "package ui_test\n\nimport _ \"example.com/app/internal/store\"\n",
+		"internal/other/other.go":         "package other\n",
+		"internal/other/other_test.go":    "package other\n",
+		"tools/gen/go.mod":                "module example.com/gen\n",
+		"tools/gen/gen.go":                "package gen\n",
+		"tools/gen/gen_test.go":           "package gen\n",
+		"vendor/example.com/x/x_test.go":  "package x\n",
+		"internal/untested/untested.go":   "package untested\n",
+		"node_modules/pkg/index.test.js":  "",
+		".hidden/skip/skip_test.go":       "package skip\n",
+		"internal/api/README.md":          "",
+		"internal/store/testdata/b/c.txt": "",
+	})
+
+	impact := AnalyzeTestImpact(dir, []string{"internal/store/store.go", "internal/untested/untested.go", "docs/guide.md"})
+	assert.Equal(t, map[string]int{
+		"internal/store/store_test.go": 1,
+		"internal/ui/ui_test.go":       1,
+		"internal/api/api_test.go":     2,
+	}, impactedPaths(impact))
+	assert.Equal(t, []string{"internal/untested/untested.go"}, impact.Unmapped)
+	assert.Equal(t, []string{"./internal/api", "./internal/store", "./internal/ui"}, impact.GoPackages)
+	assert.Equal(t, []string{"go test ./internal/api ./internal/store ./internal/ui"}, impact.Commands)
+	assert.Equal(t, "internal/store/store_test.go", impact.Tests[0].Path)
+	assert.Equal(t, "depends on internal/store", impact.Tests[2].Reason)
+
+	// A changed test runs itself, testdata runs its package's tests
+	impact = AnalyzeTestImpact(dir, []string{"internal/other/other_test.go", "internal/store/testdata/b/c.txt"})
+	assert.Equal(t, 0, impactedPaths(impact)["internal/other/other_test.go"])
+	assert.Contains(t, impactedPaths(impact), "internal/store/store_test.go")
+	assert.Empty(t, impact.Unmapped)
+
+	// go.mod affects every test in its module, nested modules run from their directory
+	impact = AnalyzeTestImpact(dir, []string{"tools/gen/go.mod"})
+	assert.Equal(t, map[string]int{"tools/gen/gen_test.go": 1}, impactedPaths(impact))
+	assert.Equal(t, []string{"cd tools/gen && go test ."}, impact.Commands)
+	// The nested module's tests aren't affected by the root go.mod
+	assert.NotContains(t, impactedPaths(AnalyzeTestImpact(dir, []string{"go.mod"})), "tools/gen/gen_test.go")
+}
+
+func TestAnalyzeTestImpactJavaScript(t *testing.T) {
+	dir := writeTestImpactTree(t, map[string]string{
+		"package.json":   `{"devDependencies": {"vitest": "^3.0.0"}}`,
+		"pnpm-lock.yaml": "",
+		"tsconfig.json": `{
+			// Aliases
+			"compilerOptions": {"baseUrl": ".", "paths": {"@/*": ["./src/*"]}},
+		}`,
+		"src/lib/format.ts":             "export const format = () => ''\n",
+		"src/lib/index.ts":              "export * from './format'\n",
+		"src/components/Card.tsx":       "import { format } from '@/lib'\n",
+		"src/components/Card.test.tsx":  "import { Card } from './Card.js'\n",
+		"src/lib/__tests__/format.ts":   "import { format } from '../format'\n",
+		"src/lib/mocked.spec.ts":        "vi.mock('./format')\n",
+		"src/lib/lazy.test.ts":          "const mod = await import('./index')\n",
+		"src/standalone.ts":             "import React from 'react'\n",
+		"src/unrelated.test.ts":         "import { x } from './standalone'\n",
+		"node_modules/dep/dep.test.js":  "require('../../src/lib/format')\n",
+		"src/legacy/old.test.js":        "const old = require('./old')\n",
+		"src/components/Card.module.ts": "",
+	})
+
+	impact := AnalyzeTestImpact(dir, []string{"src/lib/format.ts", "src/legacy/old.js"})
+	assert.Equal(t, map[string]int{
+		"src/lib/__tests__/format.ts":  1,
+		"src/lib/mocked.spec.ts":       1,
+		"src/lib/lazy.test.ts":         2,
+		"src/components/Card.test.tsx": 3,
+		"src/legacy/old.test.js":       1,
+	}, impactedPaths(impact))
+	for _, test := range impact.Tests {
+		if test.Path == "src/components/Card.test.tsx" {
+			assert.Equal(t, "depends on src/lib/format.ts", test.Reason)
+		}
+	}
+	assert.Empty(t, impact.Unmapped, "deleted files still resolve")
+	assert.Equal(t, []string{"pnpm exec vitest run src/components/Card.test.tsx src/legacy/old.test.js src/lib/__tests__/format.ts src/lib/lazy.test.ts src/lib/mocked.spec.ts"}, impact.Commands)
+
+	impact = AnalyzeTestImpact(dir, []string{"src/components/Card.module.ts"})
+	assert.Empty(t, impact.Tests)
+	assert.Equal(t, []string{"src/components/Card.module.ts"}, impact.Unmapped)
+	assert.Empty(t, impact.Commands)
+
+	assert.Len(t, AnalyzeTestImpact(dir, []string{"package.json"}).Tests, 6)
+}
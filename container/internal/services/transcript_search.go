@@ -0,0 +1,501 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/vanpelt/catnip/internal/claude/parser"
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// DefaultTranscriptSearchLimit is how many hits a search returns by default
+	DefaultTranscriptSearchLimit = 20
+	// MaxTranscriptSearchLimit caps the hits returned by one search
+	MaxTranscriptSearchLimit = 100
+
+	// transcriptSnippetRadius is how many characters of context surround the
+	// first match in a snippet
+	transcriptSnippetRadius = 120
+	// Words outside these lengths aren't indexed
+	transcriptMinWordLength = 2
+	transcriptMaxWordLength = 64
+)
+
+// TranscriptSearchQuery selects transcript messages. Every word of Text must
+// appear in a message; a word ending in * matches any word it starts.
+type TranscriptSearchQuery struct {
+	Text string
+	// Worktree path, or its trailing path components such as "felix" or "catnip/felix"
+	Worktree string
+	// Substring of the model name, such as "opus"
+	Model string
+	// "user" or "assistant"
+	Role   string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+// TranscriptSearchHit is one message matching a transcript search
+type TranscriptSearchHit struct {
+	SessionID   string    `json:"session_id" example:"6c1b2f2e-6c1e-4b55-9a3d-0c7c4f1e8a11"`
+	MessageUUID string    `json:"message_uuid" example:"f0c7b6a4-6a55-4b4b-8f38-6a2f7f5e3c21"`
+	Worktree    string    `json:"worktree" example:"/worktrees/catnip/felix"`
+	Role        string    `json:"role" example:"assistant"`
+	Model       string    `json:"model,omitempty" example:"claude-opus-4-1"`
+	Timestamp   time.Time `json:"timestamp" example:"2024-01-15T14:30:00Z"`
+	// Text around the first match
+	Snippet string `json:"snippet" example:"…we migrate in two phases: backfill the new column, then switch reads…"`
+	// Number of query word occurrences in the message
+	Score int `json:"score" example:"3"`
+}
+
+// TranscriptSearchResult is a page of transcript search hits, best first
+type TranscriptSearchResult struct {
+	Query string                `json:"query" example:"migration strategy"`
+	Total int                   `json:"total" example:"4"`
+	Hits  []TranscriptSearchHit `json:"hits"`
+	// Size of the index the search ran against
+	IndexedSessions int `json:"indexed_sessions" example:"212"`
+	IndexedMessages int `json:"indexed_messages" example:"48113"`
+}
+
+// transcriptPosting records that a message contains a word, and how often
+type transcriptPosting struct {
+	doc   int32
+	count int32
+}
+
+// transcriptDoc locates an indexed message in its session file
+type transcriptDoc struct {
+	file      *transcriptFile
+	offset    int64
+	length    int
+	uuid      string
+	role      string
+	model     string
+	timestamp time.Time
+	removed   bool
+}
+
+// transcriptFile is how far a session file has been indexed
+type transcriptFile struct {
+	path      string
+	sessionID string
+	worktree  string
+	model     string // Latest assistant model, attributed to later prompts
+	offset    int64
+	modTime   time.Time
+	docs      []int32
+}
+
+// TranscriptIndex is a full-text index over every Claude session transcript.
+// Files are indexed incrementally: only lines appended since the last update
+// are read, and a file that shrank (a repair rewrote it) is reindexed.
+type TranscriptIndex struct {
+	projectsDir string
+
+	syncMu   sync.Mutex // Serializes updates
+	mu       sync.RWMutex
+	files    map[string]*transcriptFile
+	docs     []transcriptDoc
+	postings map[string][]transcriptPosting
+	live     int // Docs not removed
+}
+
+// NewTranscriptIndex indexes the transcripts under ~/.claude/projects
+func NewTranscriptIndex() *TranscriptIndex {
+	return NewTranscriptIndexWithPath(filepath.Join(config.Runtime.HomeDir, ".claude", "projects"))
+}
+
+// NewTranscriptIndexWithPath indexes the transcripts under projectsDir (for tests)
+func NewTranscriptIndexWithPath(projectsDir string) *TranscriptIndex {
+	return &TranscriptIndex{
+		projectsDir: projectsDir,
+		files:       make(map[string]*transcriptFile),
+		postings:    make(map[string][]transcriptPosting),
+	}
+}
+
+// Sync brings every session file's index up to date
+func (x *TranscriptIndex) Sync() {
+	files, err := filepath.Glob(filepath.Join(x.projectsDir, "*", "*.jsonl"))
+	if err != nil {
+		return
+	}
+
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+
+	present := make(map[string]bool, len(files))
+	for _, path := range files {
+		present[path] = true
+		x.updateFile(path)
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for path, file := range x.files {
+		if !present[path] {
+			x.removeDocs(file)
+			delete(x.files, path)
+		}
+	}
+}
+
+// Update indexes whatever was appended to one session file since it was
+// last indexed
+func (x *TranscriptIndex) Update(path string) {
+	x.syncMu.Lock()
+	defer x.syncMu.Unlock()
+	x.updateFile(path)
+}
+
+// updateFile must be called with syncMu held
+func (x *TranscriptIndex) updateFile(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	x.mu.RLock()
+	file := x.files[path]
+	x.mu.RUnlock()
+	if file != nil && info.Size() == file.offset && info.ModTime().Equal(file.modTime) {
+		return
+	}
+	if file == nil || info.Size() < file.offset {
+		x.mu.Lock()
+		if file != nil {
+			x.removeDocs(file)
+		}
+		file = &transcriptFile{path: path, sessionID: strings.TrimSuffix(filepath.Base(path), ".jsonl")}
+		x.files[path] = file
+		x.mu.Unlock()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if _, err := f.Seek(file.offset, io.SeekStart); err != nil {
+		return
+	}
+
+	type pending struct {
+		doc   transcriptDoc
+		words map[string]int32
+	}
+	var added []pending
+	offset, worktree, model := file.offset, file.worktree, file.model
+	reader := bufio.NewReaderSize(f, 64*1024)
+	for {
+		// Stops at EOF, leaving a line that's still being written for the
+		// next update
+		raw, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		lineOffset := offset
+		offset += int64(len(raw))
+
+		line := bytes.TrimSpace(raw)
+		var msg models.ClaudeSessionMessage
+		if len(line) == 0 || json.Unmarshal(line, &msg) != nil {
+			continue
+		}
+		if msg.Cwd != "" {
+			worktree = msg.Cwd
+		}
+		if m, ok := msg.Message["model"].(string); ok && msg.Type == "assistant" {
+			model = m
+		}
+		if (msg.Type != "user" && msg.Type != "assistant") || msg.IsMeta {
+			continue
+		}
+		text := parser.ExtractTextContent(msg)
+		if strings.TrimSpace(text) == "" || (msg.Type == "user" && parser.IsAutomatedPrompt(text)) {
+			continue
+		}
+
+		timestamp, _ := time.Parse(time.RFC3339, msg.Timestamp)
+		added = append(added, pending{
+			doc: transcriptDoc{
+				file:      file,
+				offset:    lineOffset,
+				length:    len(raw),
+				uuid:      msg.Uuid,
+				role:      msg.Type,
+				model:     model,
+				timestamp: timestamp,
+			},
+			words: transcriptWordCounts(text),
+		})
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, p := range added {
+		id := int32(len(x.docs))
+		x.docs = append(x.docs, p.doc)
+		file.docs = append(file.docs, id)
+		for word, count := range p.words {
+			x.postings[word] = append(x.postings[word], transcriptPosting{doc: id, count: count})
+		}
+	}
+	x.live += len(added)
+	file.offset, file.worktree, file.model = offset, worktree, model
+	file.modTime = info.ModTime()
+	if len(added) > 0 {
+		logger.Debugf("🔎 Indexed %d transcript messages from %s", len(added), filepath.Base(path))
+	}
+}
+
+// removeDocs drops a file's messages from search results. Must be called
+// with mu held.
+func (x *TranscriptIndex) removeDocs(file *transcriptFile) {
+	for _, id := range file.docs {
+		if !x.docs[id].removed {
+			x.docs[id].removed = true
+			x.live--
+		}
+	}
+	file.docs = nil
+}
+
+// Search returns the messages matching a query, most matches first and
+// newest first among equals. The index is synced before searching.
+func (x *TranscriptIndex) Search(query TranscriptSearchQuery) (*TranscriptSearchResult, error) {
+	terms := transcriptQueryTerms(query.Text)
+	if len(terms) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "search text must contain at least one word")
+	}
+	if query.Role != "" && query.Role != "user" && query.Role != "assistant" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "role must be user or assistant")
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultTranscriptSearchLimit
+	}
+	if query.Limit > MaxTranscriptSearchLimit {
+		query.Limit = MaxTranscriptSearchLimit
+	}
+
+	x.Sync()
+
+	x.mu.RLock()
+	scores := x.matchTerms(terms)
+	type match struct {
+		doc      transcriptDoc
+		worktree string
+		score    int
+	}
+	var matches []match
+	for id, score := range scores {
+		doc := x.docs[id]
+		if doc.removed || !query.matches(doc) {
+			continue
+		}
+		matches = append(matches, match{doc: doc, worktree: doc.file.worktree, score: score})
+	}
+	result := &TranscriptSearchResult{
+		Query:           query.Text,
+		Hits:            []TranscriptSearchHit{},
+		IndexedSessions: len(x.files),
+		IndexedMessages: x.live,
+	}
+	x.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].doc.timestamp.After(matches[j].doc.timestamp)
+	})
+	result.Total = len(matches)
+	if query.Offset < len(matches) {
+		matches = matches[max(query.Offset, 0):]
+	} else {
+		matches = nil
+	}
+	if len(matches) > query.Limit {
+		matches = matches[:query.Limit]
+	}
+
+	for _, m := range matches {
+		result.Hits = append(result.Hits, TranscriptSearchHit{
+			SessionID:   m.doc.file.sessionID,
+			MessageUUID: m.doc.uuid,
+			Worktree:    m.worktree,
+			Role:        m.doc.role,
+			Model:       m.doc.model,
+			Timestamp:   m.doc.timestamp,
+			Snippet:     transcriptSnippet(readTranscriptText(m.doc), terms),
+			Score:       m.score,
+		})
+	}
+	return result, nil
+}
+
+// matchTerms scores the documents containing every term. Must be called
+// with mu held.
+func (x *TranscriptIndex) matchTerms(terms []string) map[int32]int {
+	var scores map[int32]int
+	for _, term := range terms {
+		termScores := make(map[int32]int)
+		if prefix, ok := strings.CutSuffix(term, "*"); ok {
+			for word, postings := range x.postings {
+				if strings.HasPrefix(word, prefix) {
+					for _, p := range postings {
+						termScores[p.doc] += int(p.count)
+					}
+				}
+			}
+		} else {
+			for _, p := range x.postings[term] {
+				termScores[p.doc] += int(p.count)
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for id := range scores {
+			if count, ok := termScores[id]; ok {
+				scores[id] += count
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+	return scores
+}
+
+// matches applies the query's filters to a message
+func (q TranscriptSearchQuery) matches(doc transcriptDoc) bool {
+	if q.Role != "" && doc.role != q.Role {
+		return false
+	}
+	if q.Model != "" && !strings.Contains(strings.ToLower(doc.model), strings.ToLower(q.Model)) {
+		return false
+	}
+	if q.Worktree != "" {
+		worktree := strings.TrimSuffix(q.Worktree, "/")
+		if doc.file.worktree != worktree && !strings.HasSuffix(doc.file.worktree, "/"+worktree) {
+			return false
+		}
+	}
+	if !q.Since.IsZero() && doc.timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && doc.timestamp.After(q.Until) {
+		return false
+	}
+	return true
+}
+
+// readTranscriptText rereads an indexed message's text from its session file
+func readTranscriptText(doc transcriptDoc) string {
+	f, err := os.Open(doc.file.path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	line := make([]byte, doc.length)
+	if _, err := f.ReadAt(line, doc.offset); err != nil {
+		return ""
+	}
+	var msg models.ClaudeSessionMessage
+	if json.Unmarshal(bytes.TrimSpace(line), &msg) != nil {
+		return ""
+	}
+	return parser.ExtractTextContent(msg)
+}
+
+// transcriptWords splits text into lowercase words
+func transcriptWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// transcriptWordCounts counts the indexable words of a message
+func transcriptWordCounts(text string) map[string]int32 {
+	counts := make(map[string]int32)
+	for _, word := range transcriptWords(text) {
+		if len(word) >= transcriptMinWordLength && len(word) <= transcriptMaxWordLength {
+			counts[word]++
+		}
+	}
+	return counts
+}
+
+// transcriptQueryTerms splits search text into words, keeping a trailing *
+// on prefix terms
+func transcriptQueryTerms(text string) []string {
+	var terms []string
+	for _, field := range strings.Fields(text) {
+		prefix := strings.HasSuffix(field, "*")
+		words := transcriptWords(field)
+		for i, word := range words {
+			if prefix && i == len(words)-1 {
+				terms = append(terms, word+"*")
+			} else if len(word) >= transcriptMinWordLength {
+				terms = append(terms, word)
+			}
+		}
+	}
+	return terms
+}
+
+// transcriptSnippet returns the text around the first occurrence of a term,
+// on one line
+func transcriptSnippet(text string, terms []string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	first := -1
+	for _, term := range terms {
+		needle := []rune(strings.TrimSuffix(term, "*"))
+		for i := 0; i+len(needle) <= len(lower); i++ {
+			if slices.Equal(lower[i:i+len(needle)], needle) {
+				if first < 0 || i < first {
+					first = i
+				}
+				break
+			}
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	start := max(first-transcriptSnippetRadius, 0)
+	end := min(first+transcriptSnippetRadius, len(runes))
+	snippet := string(runes[start:end])
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
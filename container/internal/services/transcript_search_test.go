@@ -0,0 +1,112 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func transcriptLine(t *testing.T, msgType, uuid, cwd, model, text, timestamp string) string {
+	t.Helper()
+	message := map[string]any{"role": msgType, "content": []map[string]any{{"type": "text", "text": text}}}
+	if model != "" {
+		message["model"] = model
+	}
+	line, err := json.Marshal(map[string]any{
+		"type": msgType, "uuid": uuid, "cwd": cwd, "timestamp": timestamp, "message": message,
+	})
+	require.NoError(t, err)
+	return string(line) + "\n"
+}
+
+func appendTranscript(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.WriteString(strings.Join(lines, ""))
+	require.NoError(t, err)
+}
+
+func TestTranscriptIndexSearch(t *testing.T) {
+	projectsDir := t.TempDir()
+	felix := filepath.Join(projectsDir, "-worktrees-catnip-felix", "11111111-1111-1111-1111-111111111111.jsonl")
+	luna := filepath.Join(projectsDir, "-worktrees-api-luna", "22222222-2222-2222-2222-222222222222.jsonl")
+
+	appendTranscript(t, felix,
+		transcriptLine(t, "user", "u1", "/worktrees/catnip/felix", "", "How should we run the migration?", "2024-01-10T10:00:00Z"),
+		transcriptLine(t, "assistant", "a1", "/worktrees/catnip/felix", "claude-opus-4-1", "The migration strategy: backfill the column first, then switch reads. The migration is safe to rerun.", "2024-01-10T10:01:00Z"),
+		`{"type":"user","uuid":"tool","cwd":"/worktrees/catnip/felix","message":{"role":"user","content":[{"type":"tool_result","content":"migration ok"}]}}`+"\n",
+		transcriptLine(t, "user", "u2", "/worktrees/catnip/felix", "", "Create a commit message that: mentions the migration", "2024-01-10T10:02:00Z"),
+	)
+	appendTranscript(t, luna,
+		transcriptLine(t, "assistant", "a2", "/worktrees/api/luna", "claude-sonnet-4-5", "Migrations run at startup; no strategy needed.", "2024-02-01T09:00:00Z"),
+	)
+
+	index := NewTranscriptIndexWithPath(projectsDir)
+	result, err := index.Search(TranscriptSearchQuery{Text: "migration strategy"})
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Total)
+	hit := result.Hits[0]
+	assert.Equal(t, "11111111-1111-1111-1111-111111111111", hit.SessionID)
+	assert.Equal(t, "a1", hit.MessageUUID)
+	assert.Equal(t, "/worktrees/catnip/felix", hit.Worktree)
+	assert.Equal(t, "claude-opus-4-1", hit.Model)
+	assert.Equal(t, 3, hit.Score)
+	assert.Contains(t, hit.Snippet, "The migration strategy: backfill")
+	assert.Equal(t, 2, result.IndexedSessions)
+	assert.Equal(t, 3, result.IndexedMessages, "tool results and automated prompts aren't indexed")
+
+	// Prefix terms, ranking by matches and filters
+	result, err = index.Search(TranscriptSearchQuery{Text: "migrat*"})
+	require.NoError(t, err)
+	require.Equal(t, 3, result.Total)
+	assert.Equal(t, []string{"a1", "a2", "u1"}, []string{result.Hits[0].MessageUUID, result.Hits[1].MessageUUID, result.Hits[2].MessageUUID})
+
+	for query, want := range map[*TranscriptSearchQuery]int{
+		{Text: "migrat*", Worktree: "felix"}:                                     2,
+		{Text: "migrat*", Worktree: "/worktrees/api/luna"}:                       1,
+		{Text: "migrat*", Worktree: "elix"}:                                      0,
+		{Text: "migrat*", Model: "sonnet"}:                                       1,
+		{Text: "migrat*", Role: "user"}:                                          1,
+		{Text: "migrat*", Since: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}:   1,
+		{Text: "migrat*", Until: time.Date(2024, 1, 10, 10, 0, 30, 0, time.UTC)}: 1,
+		{Text: "migrat*", Limit: 1, Offset: 2}:                                   3,
+	} {
+		result, err := index.Search(*query)
+		require.NoError(t, err)
+		assert.Equal(t, want, result.Total, "%+v", *query)
+	}
+
+	// Appended lines are indexed incrementally, a partial last line waits
+	appendTranscript(t, luna,
+		transcriptLine(t, "user", "u3", "/worktrees/api/luna", "", "What about the rollback plan?", "2024-02-02T09:00:00Z"),
+		`{"type":"assistant","uuid":"a3","message":{"content":"rollback`,
+	)
+	index.Update(luna)
+	result, err = index.Search(TranscriptSearchQuery{Text: "rollback"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+	assert.Equal(t, "u3", result.Hits[0].MessageUUID)
+
+	// A rewritten (shorter) file is reindexed
+	require.NoError(t, os.WriteFile(felix, []byte(transcriptLine(t, "user", "u9", "/worktrees/catnip/felix", "", "Rollback first", "2024-03-01T09:00:00Z")), 0644))
+	result, err = index.Search(TranscriptSearchQuery{Text: "rollback"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	result, err = index.Search(TranscriptSearchQuery{Text: "strategy"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Total)
+
+	_, err = index.Search(TranscriptSearchQuery{Text: "  ?! "})
+	assert.Error(t, err)
+	_, err = index.Search(TranscriptSearchQuery{Text: "rollback", Role: "system"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/vanpelt/catnip/internal/logger"
+)
+
+const (
+	tunnelMinBackoff     = time.Second
+	tunnelMaxBackoff     = time.Minute
+	tunnelPingInterval   = 30 * time.Second
+	tunnelWriteTimeout   = 10 * time.Second
+	tunnelStreamIDLength = 4
+	tunnelReadBufferSize = 32 * 1024
+)
+
+// TunnelState is the connection state of the reverse tunnel
+type TunnelState string
+
+const (
+	// TunnelDisabled means no relay is configured
+	TunnelDisabled TunnelState = "disabled"
+	// TunnelConnecting means a connection attempt is in progress
+	TunnelConnecting TunnelState = "connecting"
+	// TunnelConnected means the relay is forwarding connections
+	TunnelConnected TunnelState = "connected"
+	// TunnelDisconnected means the tunnel is down and will be retried
+	TunnelDisconnected TunnelState = "disconnected"
+)
+
+// TunnelStatus is surfaced in the settings API
+type TunnelStatus struct {
+	Enabled  bool        `json:"enabled"`
+	State    TunnelState `json:"state"`
+	RelayURL string      `json:"relay_url,omitempty"`
+	Name     string      `json:"name,omitempty"`
+	// URL the relay serves this instance on, announced after connecting
+	PublicURL   string     `json:"public_url,omitempty"`
+	ConnectedAt *time.Time `json:"connected_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Reconnects  int        `json:"reconnects"`
+	// Connections currently forwarded through the tunnel
+	ActiveStreams int `json:"active_streams"`
+}
+
+// tunnelControl is a JSON text frame. The relay sends "hello" once after
+// the handshake, then "open" for each incoming connection; either side
+// sends "close" when its end of a stream is done. Stream data travels in
+// binary frames: a 4-byte big-endian stream ID followed by the payload.
+type tunnelControl struct {
+	Type      string `json:"type"`
+	Stream    uint32 `json:"stream,omitempty"`
+	PublicURL string `json:"public_url,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TunnelService keeps a WebSocket reverse tunnel open to a self-hosted
+// relay, so an instance behind NAT can be reached without port forwarding.
+// Each connection the relay accepts is forwarded as raw TCP to the local
+// server, which keeps SSE and PTY WebSockets working. The local API has no
+// authentication of its own, so the relay must authenticate the instance by
+// its token and only admit clients authorized for that instance; see
+// docs/TUNNEL.md for the protocol.
+type TunnelService struct {
+	relayURL  string
+	token     string
+	name      string
+	localAddr string
+
+	mu      sync.Mutex
+	status  TunnelStatus
+	conn    *websocket.Conn
+	streams map[uint32]net.Conn
+
+	writeMu  sync.Mutex
+	stopChan chan struct{}
+	running  bool
+}
+
+// NewTunnelService creates a tunnel service configured from
+// CATNIP_TUNNEL_URL (the relay), CATNIP_TUNNEL_TOKEN and CATNIP_TUNNEL_NAME
+// (defaults to the hostname). The tunnel stays disabled unless both the
+// relay and the token are set.
+func NewTunnelService(localPort string) *TunnelService {
+	relayURL := os.Getenv("CATNIP_TUNNEL_URL")
+	name := os.Getenv("CATNIP_TUNNEL_NAME")
+	if name == "" {
+		name, _ = os.Hostname()
+	}
+	return NewTunnelServiceWithOptions(relayURL, os.Getenv("CATNIP_TUNNEL_TOKEN"), name, "127.0.0.1:"+localPort)
+}
+
+// NewTunnelServiceWithOptions creates a tunnel service with explicit settings (for testing)
+func NewTunnelServiceWithOptions(relayURL, token, name, localAddr string) *TunnelService {
+	enabled := relayURL != "" && token != ""
+	state := TunnelDisabled
+	lastError := ""
+	if enabled {
+		state = TunnelDisconnected
+	} else if relayURL != "" {
+		lastError = "CATNIP_TUNNEL_TOKEN is required to connect to a relay"
+	}
+	return &TunnelService{
+		relayURL:  relayURL,
+		token:     token,
+		name:      name,
+		localAddr: localAddr,
+		streams:   make(map[uint32]net.Conn),
+		stopChan:  make(chan struct{}),
+		status: TunnelStatus{
+			Enabled:   enabled,
+			State:     state,
+			RelayURL:  relayURL,
+			Name:      name,
+			LastError: lastError,
+		},
+	}
+}
+
+// Enabled reports whether a relay and the token authenticating with it are
+// configured
+func (s *TunnelService) Enabled() bool {
+	return s.relayURL != "" && s.token != ""
+}
+
+// Status returns the current tunnel status
+func (s *TunnelService) Status() TunnelStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.ActiveStreams = len(s.streams)
+	return status
+}
+
+// Start connects to the relay in the background and reconnects with backoff
+func (s *TunnelService) Start() {
+	if !s.Enabled() {
+		if s.relayURL != "" {
+			logger.Warnf("⚠️ Tunnel to %s disabled: CATNIP_TUNNEL_TOKEN is not set", s.relayURL)
+		} else {
+			logger.Debugf("🚇 Tunnel disabled (set CATNIP_TUNNEL_URL and CATNIP_TUNNEL_TOKEN to enable)")
+		}
+		return
+	}
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	logger.Infof("🚇 Starting reverse tunnel to %s as %q", s.relayURL, s.name)
+	go s.run()
+}
+
+// Stop closes the tunnel and all forwarded connections
+func (s *TunnelService) Stop() {
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = false
+	close(s.stopChan)
+	conn := s.conn
+	s.mu.Unlock()
+
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
+
+func (s *TunnelService) run() {
+	backoff := tunnelMinBackoff
+	for {
+		s.setState(TunnelConnecting, "")
+		err := s.connectAndServe()
+
+		select {
+		case <-s.stopChan:
+			s.setState(TunnelDisconnected, "")
+			return
+		default:
+		}
+
+		if err != nil {
+			logger.Warnf("🚇 Tunnel to %s lost: %v (retrying in %v)", s.relayURL, err, backoff)
+			s.setState(TunnelDisconnected, err.Error())
+		}
+		s.mu.Lock()
+		// A connection that stayed up resets the backoff
+		if s.status.ConnectedAt != nil && time.Since(*s.status.ConnectedAt) > tunnelMaxBackoff {
+			backoff = tunnelMinBackoff
+		}
+		s.status.Reconnects++
+		s.status.ConnectedAt = nil
+		s.mu.Unlock()
+
+		select {
+		case <-s.stopChan:
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > tunnelMaxBackoff {
+			backoff = tunnelMaxBackoff
+		}
+	}
+}
+
+func (s *TunnelService) setState(state TunnelState, lastError string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.State = state
+	if lastError != "" {
+		s.status.LastError = lastError
+	}
+}
+
+// connectAndServe runs one tunnel connection until it fails or is stopped
+func (s *TunnelService) connectAndServe() error {
+	header := http.Header{}
+	if s.token != "" {
+		header.Set("Authorization", "Bearer "+s.token)
+	}
+	header.Set("X-Catnip-Tunnel-Name", s.name)
+
+	conn, resp, err := websocket.DefaultDialer.Dial(s.relayURL, header)
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("relay rejected the tunnel: %s", resp.Status)
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	if !s.running {
+		s.mu.Unlock()
+		_ = conn.Close()
+		return nil
+	}
+	s.conn = conn
+	now := time.Now()
+	s.status.State = TunnelConnected
+	s.status.ConnectedAt = &now
+	s.status.LastError = ""
+	s.mu.Unlock()
+	logger.Infof("🚇 Tunnel connected to %s", s.relayURL)
+
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		_ = conn.Close()
+		s.closeAllStreams()
+		s.mu.Lock()
+		s.conn = nil
+		s.mu.Unlock()
+	}()
+	go s.keepAlive(conn, done)
+
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		switch messageType {
+		case websocket.TextMessage:
+			s.handleControl(conn, data)
+		case websocket.BinaryMessage:
+			s.handleData(conn, data)
+		}
+	}
+}
+
+func (s *TunnelService) keepAlive(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(tunnelPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.writeMu.Lock()
+			err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(tunnelWriteTimeout))
+			s.writeMu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *TunnelService) handleControl(conn *websocket.Conn, data []byte) {
+	var msg tunnelControl
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.Debugf("🚇 Ignoring invalid tunnel control frame: %v", err)
+		return
+	}
+
+	switch msg.Type {
+	case "hello":
+		s.mu.Lock()
+		s.status.PublicURL = msg.PublicURL
+		s.mu.Unlock()
+		if msg.PublicURL != "" {
+			logger.Infof("🚇 Catnip is reachable at %s", msg.PublicURL)
+		}
+	case "open":
+		local, err := net.DialTimeout("tcp", s.localAddr, tunnelWriteTimeout)
+		if err != nil {
+			_ = s.writeControl(conn, tunnelControl{Type: "close", Stream: msg.Stream, Error: err.Error()})
+			return
+		}
+		s.mu.Lock()
+		s.streams[msg.Stream] = local
+		s.mu.Unlock()
+		go s.pumpLocal(conn, msg.Stream, local)
+	case "close":
+		s.closeStream(msg.Stream)
+	case "error":
+		logger.Warnf("🚇 Relay error: %s", msg.Error)
+	}
+}
+
+// handleData writes a binary frame's payload to its local connection
+func (s *TunnelService) handleData(conn *websocket.Conn, data []byte) {
+	if len(data) < tunnelStreamIDLength {
+		return
+	}
+	streamID := binary.BigEndian.Uint32(data[:tunnelStreamIDLength])
+	s.mu.Lock()
+	local, exists := s.streams[streamID]
+	s.mu.Unlock()
+	if !exists {
+		return
+	}
+	if _, err := local.Write(data[tunnelStreamIDLength:]); err != nil {
+		s.closeStream(streamID)
+		_ = s.writeControl(conn, tunnelControl{Type: "close", Stream: streamID})
+	}
+}
+
+// pumpLocal forwards everything the local server writes back to the relay
+func (s *TunnelService) pumpLocal(conn *websocket.Conn, streamID uint32, local net.Conn) {
+	buf := make([]byte, tunnelStreamIDLength+tunnelReadBufferSize)
+	binary.BigEndian.PutUint32(buf, streamID)
+	for {
+		n, err := local.Read(buf[tunnelStreamIDLength:])
+		if n > 0 {
+			s.writeMu.Lock()
+			_ = conn.SetWriteDeadline(time.Now().Add(tunnelWriteTimeout))
+			writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:tunnelStreamIDLength+n])
+			s.writeMu.Unlock()
+			if writeErr != nil {
+				s.closeStream(streamID)
+				return
+			}
+		}
+		if err != nil {
+			// Only report the close if the relay didn't close it first
+			if s.closeStream(streamID) {
+				_ = s.writeControl(conn, tunnelControl{Type: "close", Stream: streamID})
+			}
+			return
+		}
+	}
+}
+
+func (s *TunnelService) writeControl(conn *websocket.Conn, msg tunnelControl) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(tunnelWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// closeStream closes a forwarded connection, reporting whether it was open
+func (s *TunnelService) closeStream(streamID uint32) bool {
+	s.mu.Lock()
+	local, exists := s.streams[streamID]
+	delete(s.streams, streamID)
+	s.mu.Unlock()
+	if exists {
+		_ = local.Close()
+	}
+	return exists
+}
+
+func (s *TunnelService) closeAllStreams() {
+	s.mu.Lock()
+	streams := s.streams
+	s.streams = make(map[uint32]net.Conn)
+	s.mu.Unlock()
+	for _, local := range streams {
+		_ = local.Close()
+	}
+}
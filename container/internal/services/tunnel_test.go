@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTunnelForwardsStreams(t *testing.T) {
+	// Local "catnip server" that echoes what it receives
+	local, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer local.Close()
+	go func() {
+		for {
+			conn, err := local.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	relayConns := make(chan *websocket.Conn, 1)
+	var authHeader string
+	relay := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		require.NoError(t, err)
+		relayConns <- conn
+	}))
+	defer relay.Close()
+
+	tunnel := NewTunnelServiceWithOptions("ws"+strings.TrimPrefix(relay.URL, "http"), "secret", "home", local.Addr().String())
+	assert.Equal(t, TunnelDisconnected, tunnel.Status().State)
+	tunnel.Start()
+	defer tunnel.Stop()
+
+	var conn *websocket.Conn
+	select {
+	case conn = <-relayConns:
+	case <-time.After(5 * time.Second):
+		t.Fatal("tunnel never connected")
+	}
+	defer conn.Close()
+	assert.Equal(t, "Bearer secret", authHeader)
+
+	require.NoError(t, conn.WriteJSON(tunnelControl{Type: "hello", PublicURL: "https://home.catnip.run"}))
+	require.NoError(t, conn.WriteJSON(tunnelControl{Type: "open", Stream: 7}))
+	frame := make([]byte, tunnelStreamIDLength)
+	binary.BigEndian.PutUint32(frame, 7)
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, append(frame, []byte("GET /health")...)))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	messageType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, uint32(7), binary.BigEndian.Uint32(data))
+	assert.Equal(t, "GET /health", string(data[tunnelStreamIDLength:]))
+
+	status := tunnel.Status()
+	assert.Equal(t, TunnelConnected, status.State)
+	assert.Equal(t, "https://home.catnip.run", status.PublicURL)
+	assert.Equal(t, 1, status.ActiveStreams)
+
+	// Closing from the relay side closes the local connection
+	require.NoError(t, conn.WriteJSON(tunnelControl{Type: "close", Stream: 7}))
+	require.Eventually(t, func() bool { return tunnel.Status().ActiveStreams == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTunnelDisabledWithoutRelay(t *testing.T) {
+	tunnel := NewTunnelServiceWithOptions("", "", "home", "127.0.0.1:6369")
+	tunnel.Start()
+	defer tunnel.Stop()
+	assert.False(t, tunnel.Enabled())
+	assert.Equal(t, TunnelDisabled, tunnel.Status().State)
+
+	// A relay without a token would expose the unauthenticated API
+	tunnel = NewTunnelServiceWithOptions("wss://relay.example.com/tunnel", "", "home", "127.0.0.1:6369")
+	tunnel.Start()
+	defer tunnel.Stop()
+	assert.False(t, tunnel.Enabled())
+	status := tunnel.Status()
+	assert.Equal(t, TunnelDisabled, status.State)
+	assert.Contains(t, status.LastError, "CATNIP_TUNNEL_TOKEN")
+}
@@ -0,0 +1,568 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// MaxVoiceAudioBytes caps uploaded audio (the common provider limit)
+	MaxVoiceAudioBytes = 25 << 20
+	// MaxSpeechTextLength caps the text synthesized in one request
+	MaxSpeechTextLength = 4096
+
+	defaultSpeechProvider = "openai"
+	voiceRequestTimeout   = 2 * time.Minute
+)
+
+// voiceAudioExtensions maps accepted upload content types to the file
+// extension providers use to detect the format
+var voiceAudioExtensions = map[string]string{
+	"audio/webm":   "webm",
+	"audio/ogg":    "ogg",
+	"audio/mp4":    "m4a",
+	"audio/m4a":    "m4a",
+	"audio/x-m4a":  "m4a",
+	"audio/aac":    "aac",
+	"audio/mpeg":   "mp3",
+	"audio/mp3":    "mp3",
+	"audio/wav":    "wav",
+	"audio/x-wav":  "wav",
+	"audio/wave":   "wav",
+	"audio/flac":   "flac",
+	"audio/x-flac": "flac",
+}
+
+// VoiceAudioExtension returns the file extension for an audio content type
+func VoiceAudioExtension(contentType string) (string, bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	ext, ok := voiceAudioExtensions[strings.ToLower(mediaType)]
+	return ext, ok
+}
+
+// Transcription is the text recognized in an audio clip
+type Transcription struct {
+	Text     string `json:"text" example:"run the tests and fix what fails"`
+	Language string `json:"language,omitempty" example:"en"`
+	Provider string `json:"provider" example:"openai"`
+}
+
+// SynthesizedSpeech is audio generated from text
+type SynthesizedSpeech struct {
+	Audio       []byte
+	ContentType string
+	Provider    string
+}
+
+// TranscribeOptions are passed to speech-to-text providers
+type TranscribeOptions struct {
+	// File extension of the audio format, e.g. "m4a"
+	Format string
+	// ISO-639-1 language hint; empty to auto-detect
+	Language string
+	Model    string
+}
+
+// SynthesizeOptions are passed to text-to-speech providers
+type SynthesizeOptions struct {
+	Voice string
+	Model string
+}
+
+// SpeechProvider is a speech service. Providers implement SpeechTranscriber,
+// SpeechSynthesizer or both, and read their API key from the secret store.
+type SpeechProvider interface {
+	Name() string
+	// KeySecret is the secret store entry holding the provider's API key
+	KeySecret() string
+}
+
+// SpeechTranscriber converts speech to text
+type SpeechTranscriber interface {
+	Transcribe(ctx context.Context, key string, audio []byte, opts TranscribeOptions) (*Transcription, error)
+}
+
+// SpeechSynthesizer converts text to speech
+type SpeechSynthesizer interface {
+	Synthesize(ctx context.Context, key string, text string, opts SynthesizeOptions) (*SynthesizedSpeech, error)
+}
+
+// VoiceConfig selects the providers used for voice input and output
+type VoiceConfig struct {
+	// Provider for transcription (default openai)
+	TranscriptionProvider string `json:"transcription_provider,omitempty" example:"openai"`
+	TranscriptionModel    string `json:"transcription_model,omitempty" example:"gpt-4o-mini-transcribe"`
+	// Provider for speech (default openai)
+	SpeechProvider string `json:"speech_provider,omitempty" example:"elevenlabs"`
+	SpeechModel    string `json:"speech_model,omitempty"`
+	// Provider-specific voice name or ID
+	Voice string `json:"voice,omitempty" example:"alloy"`
+	// Default language hint for transcription
+	Language string `json:"language,omitempty" example:"en"`
+}
+
+// VoiceProviderStatus describes a registered provider
+type VoiceProviderStatus struct {
+	Name       string `json:"name" example:"openai"`
+	Transcribe bool   `json:"transcribe"`
+	Synthesize bool   `json:"synthesize"`
+	KeySecret  string `json:"key_secret" example:"openai-api-key"`
+	HasKey     bool   `json:"has_key"`
+}
+
+// VoiceStatus reports the voice configuration and available providers
+type VoiceStatus struct {
+	VoiceConfig
+	// Whether the selected providers have API keys
+	TranscriptionReady bool                  `json:"transcription_ready"`
+	SpeechReady        bool                  `json:"speech_ready"`
+	Providers          []VoiceProviderStatus `json:"providers"`
+}
+
+// VoiceService bridges voice clients to workspace agents: it transcribes
+// spoken prompts and synthesizes assistant replies through pluggable
+// providers whose keys live in the secret store
+type VoiceService struct {
+	path    string
+	secrets *SecretStore
+
+	mu        sync.Mutex
+	config    VoiceConfig
+	providers map[string]SpeechProvider
+}
+
+// NewVoiceService creates the voice service with the built-in providers,
+// reading its configuration from the volume
+func NewVoiceService(secrets *SecretStore) *VoiceService {
+	return NewVoiceServiceWithPath(filepath.Join(config.Runtime.VolumeDir, "voice.json"), secrets).
+		WithProvider(NewOpenAISpeechProvider("")).
+		WithProvider(NewElevenLabsSpeechProvider(""))
+}
+
+// NewVoiceServiceWithPath creates a voice service without providers and an
+// explicit config path (for testing)
+func NewVoiceServiceWithPath(path string, secrets *SecretStore) *VoiceService {
+	s := &VoiceService{
+		path:      path,
+		secrets:   secrets,
+		providers: make(map[string]SpeechProvider),
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &s.config); err != nil {
+			logger.Warnf("⚠️ Ignoring invalid voice config %s: %v", path, err)
+		}
+	}
+	return s
+}
+
+// WithProvider registers a speech provider, replacing one with the same name
+func (s *VoiceService) WithProvider(provider SpeechProvider) *VoiceService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.providers[provider.Name()] = provider
+	return s
+}
+
+// Status reports the configuration and which providers have keys
+func (s *VoiceService) Status() VoiceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked()
+}
+
+func (s *VoiceService) statusLocked() VoiceStatus {
+	status := VoiceStatus{VoiceConfig: s.effectiveConfigLocked(), Providers: []VoiceProviderStatus{}}
+	for _, provider := range s.providers {
+		_, transcribes := provider.(SpeechTranscriber)
+		_, synthesizes := provider.(SpeechSynthesizer)
+		entry := VoiceProviderStatus{
+			Name:       provider.Name(),
+			Transcribe: transcribes,
+			Synthesize: synthesizes,
+			KeySecret:  provider.KeySecret(),
+			HasKey:     s.secrets.Has(provider.KeySecret()),
+		}
+		status.Providers = append(status.Providers, entry)
+		if entry.Name == status.TranscriptionProvider && transcribes {
+			status.TranscriptionReady = entry.HasKey
+		}
+		if entry.Name == status.SpeechProvider && synthesizes {
+			status.SpeechReady = entry.HasKey
+		}
+	}
+	sort.Slice(status.Providers, func(i, j int) bool { return status.Providers[i].Name < status.Providers[j].Name })
+	return status
+}
+
+func (s *VoiceService) effectiveConfigLocked() VoiceConfig {
+	cfg := s.config
+	if cfg.TranscriptionProvider == "" {
+		cfg.TranscriptionProvider = defaultSpeechProvider
+	}
+	if cfg.SpeechProvider == "" {
+		cfg.SpeechProvider = defaultSpeechProvider
+	}
+	return cfg
+}
+
+// Configure selects providers and defaults and saves them to the volume
+func (s *VoiceService) Configure(cfg VoiceConfig) (VoiceStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name := cfg.TranscriptionProvider; name != "" {
+		if _, ok := s.providers[name].(SpeechTranscriber); !ok {
+			return VoiceStatus{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%q is not a transcription provider", name)
+		}
+	}
+	if name := cfg.SpeechProvider; name != "" {
+		if _, ok := s.providers[name].(SpeechSynthesizer); !ok {
+			return VoiceStatus{}, models.NewAPIError(models.ErrCodeInvalidRequest, "%q is not a speech provider", name)
+		}
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return VoiceStatus{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return VoiceStatus{}, err
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return VoiceStatus{}, err
+	}
+	s.config = cfg
+	logger.Infof("🎙️ Voice configured: transcription %s, speech %s", s.effectiveConfigLocked().TranscriptionProvider, s.effectiveConfigLocked().SpeechProvider)
+	return s.statusLocked(), nil
+}
+
+// providerKey returns a provider and its API key from the secret store
+func (s *VoiceService) providerKey(name string) (SpeechProvider, string, error) {
+	provider, ok := s.providers[name]
+	if !ok {
+		return nil, "", models.NewAPIError(models.ErrCodeInvalidRequest, "unknown voice provider %q", name)
+	}
+	if !s.secrets.Has(provider.KeySecret()) {
+		return nil, "", models.NewAPIError(models.ErrCodeInvalidRequest, "%s API key is not set", name).
+			WithHint(fmt.Sprintf("Store it with PUT /v1/secrets/%s", provider.KeySecret()))
+	}
+	key, err := s.secrets.Get(provider.KeySecret())
+	if err != nil {
+		return nil, "", err
+	}
+	return provider, strings.TrimSpace(key), nil
+}
+
+// Transcribe converts a spoken clip to text. contentType is the audio's MIME
+// type; language overrides the configured hint.
+func (s *VoiceService) Transcribe(ctx context.Context, audio []byte, contentType, language string) (*Transcription, error) {
+	format, ok := VoiceAudioExtension(contentType)
+	if !ok {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unsupported audio type %q", contentType).
+			WithHint("Send webm, ogg, m4a, mp3, wav or flac audio with a matching Content-Type")
+	}
+	if len(audio) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "audio is empty")
+	}
+	if len(audio) > MaxVoiceAudioBytes {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "audio is larger than %d MB", MaxVoiceAudioBytes>>20)
+	}
+
+	s.mu.Lock()
+	cfg := s.effectiveConfigLocked()
+	provider, key, err := s.providerKey(cfg.TranscriptionProvider)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	transcriber, ok := provider.(SpeechTranscriber)
+	if !ok {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s can't transcribe audio", provider.Name())
+	}
+	if language == "" {
+		language = cfg.Language
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, voiceRequestTimeout)
+	defer cancel()
+	result, err := transcriber.Transcribe(ctx, key, audio, TranscribeOptions{Format: format, Language: language, Model: cfg.TranscriptionModel})
+	if err != nil {
+		return nil, models.NewRetryableAPIError(models.ErrCodeInternal, "transcription failed: %v", err)
+	}
+	result.Provider = provider.Name()
+	return result, nil
+}
+
+// Synthesize converts text to speech. voice overrides the configured voice.
+func (s *VoiceService) Synthesize(ctx context.Context, text, voice string) (*SynthesizedSpeech, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "nothing to say")
+	}
+	if len(text) > MaxSpeechTextLength {
+		text = truncateSpeechText(text, MaxSpeechTextLength)
+	}
+
+	s.mu.Lock()
+	cfg := s.effectiveConfigLocked()
+	provider, key, err := s.providerKey(cfg.SpeechProvider)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	synthesizer, ok := provider.(SpeechSynthesizer)
+	if !ok {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s can't synthesize speech", provider.Name())
+	}
+	if voice == "" {
+		voice = cfg.Voice
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, voiceRequestTimeout)
+	defer cancel()
+	speech, err := synthesizer.Synthesize(ctx, key, text, SynthesizeOptions{Voice: voice, Model: cfg.SpeechModel})
+	if err != nil {
+		return nil, models.NewRetryableAPIError(models.ErrCodeInternal, "speech synthesis failed: %v", err)
+	}
+	speech.Provider = provider.Name()
+	return speech, nil
+}
+
+// truncateSpeechText cuts text at the last sentence end before limit bytes
+func truncateSpeechText(text string, limit int) string {
+	cut := text[:limit]
+	if idx := strings.LastIndexAny(cut, ".!?\n"); idx > limit/2 {
+		return cut[:idx+1]
+	}
+	// Don't split a multi-byte character
+	for len(cut) > 0 && !utf8.ValidString(cut) {
+		cut = cut[:len(cut)-1]
+	}
+	return strings.TrimSpace(cut)
+}
+
+var (
+	speechCodeBlockPattern  = regexp.MustCompile("(?s)```.*?(```|$)")
+	speechInlineCodePattern = regexp.MustCompile("`([^`]*)`")
+	speechLinkPattern       = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	speechMarkupPattern     = regexp.MustCompile(`(?m)^[ \t]{0,3}(#{1,6}\s+|>\s?|[-*+]\s+|\d+\.\s+)|[*~]{1,3}`)
+	speechBlankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// SpeakableText turns an assistant reply's markdown into text that reads
+// well aloud: code blocks are replaced by a short mention and markup is removed
+func SpeakableText(markdown string) string {
+	text := speechCodeBlockPattern.ReplaceAllString(markdown, "(code omitted)")
+	text = speechInlineCodePattern.ReplaceAllString(text, "$1")
+	text = speechLinkPattern.ReplaceAllString(text, "$1")
+	text = speechMarkupPattern.ReplaceAllString(text, "")
+	text = speechBlankLinesPattern.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// speechHTTPClient is shared by the built-in providers; requests are bounded
+// by the caller's context
+var speechHTTPClient = &http.Client{}
+
+// speechRequest sends a provider request and returns the body of a 2xx response
+func speechRequest(req *http.Request) ([]byte, string, error) {
+	resp, err := speechHTTPClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxVoiceAudioBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message := strings.TrimSpace(string(body))
+		if len(message) > 300 {
+			message = message[:300]
+		}
+		return nil, "", fmt.Errorf("%s returned %d: %s", req.URL.Host, resp.StatusCode, message)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// OpenAISpeechProvider uses OpenAI's audio transcription and speech APIs
+type OpenAISpeechProvider struct {
+	baseURL string
+}
+
+// NewOpenAISpeechProvider creates the OpenAI provider; baseURL defaults to
+// the public API and can point at a compatible server
+func NewOpenAISpeechProvider(baseURL string) *OpenAISpeechProvider {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAISpeechProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Name implements SpeechProvider
+func (p *OpenAISpeechProvider) Name() string { return "openai" }
+
+// KeySecret implements SpeechProvider
+func (p *OpenAISpeechProvider) KeySecret() string { return "openai-api-key" }
+
+// Transcribe implements SpeechTranscriber
+func (p *OpenAISpeechProvider) Transcribe(ctx context.Context, key string, audio []byte, opts TranscribeOptions) (*Transcription, error) {
+	model := opts.Model
+	if model == "" {
+		model = "gpt-4o-mini-transcribe"
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "audio."+opts.Format)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(audio); err != nil {
+		return nil, err
+	}
+	_ = form.WriteField("model", model)
+	_ = form.WriteField("response_format", "json")
+	if opts.Language != "" {
+		_ = form.WriteField("language", opts.Language)
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	data, _, err := speechRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("unexpected transcription response: %v", err)
+	}
+	language := result.Language
+	if language == "" {
+		language = opts.Language
+	}
+	return &Transcription{Text: strings.TrimSpace(result.Text), Language: language}, nil
+}
+
+// Synthesize implements SpeechSynthesizer
+func (p *OpenAISpeechProvider) Synthesize(ctx context.Context, key string, text string, opts SynthesizeOptions) (*SynthesizedSpeech, error) {
+	model := opts.Model
+	if model == "" {
+		model = "gpt-4o-mini-tts"
+	}
+	voice := opts.Voice
+	if voice == "" {
+		voice = "alloy"
+	}
+	payload, err := json.Marshal(map[string]string{
+		"model":           model,
+		"input":           text,
+		"voice":           voice,
+		"response_format": "mp3",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/audio/speech", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+key)
+	req.Header.Set("Content-Type", "application/json")
+
+	audio, contentType, err := speechRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if contentType == "" {
+		contentType = "audio/mpeg"
+	}
+	return &SynthesizedSpeech{Audio: audio, ContentType: contentType}, nil
+}
+
+// ElevenLabsSpeechProvider uses ElevenLabs text-to-speech
+type ElevenLabsSpeechProvider struct {
+	baseURL string
+}
+
+// NewElevenLabsSpeechProvider creates the ElevenLabs provider; baseURL
+// defaults to the public API
+func NewElevenLabsSpeechProvider(baseURL string) *ElevenLabsSpeechProvider {
+	if baseURL == "" {
+		baseURL = "https://api.elevenlabs.io/v1"
+	}
+	return &ElevenLabsSpeechProvider{baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Name implements SpeechProvider
+func (p *ElevenLabsSpeechProvider) Name() string { return "elevenlabs" }
+
+// KeySecret implements SpeechProvider
+func (p *ElevenLabsSpeechProvider) KeySecret() string { return "elevenlabs-api-key" }
+
+// Synthesize implements SpeechSynthesizer. The voice is an ElevenLabs voice ID.
+func (p *ElevenLabsSpeechProvider) Synthesize(ctx context.Context, key string, text string, opts SynthesizeOptions) (*SynthesizedSpeech, error) {
+	voice := opts.Voice
+	if voice == "" {
+		voice = "21m00Tcm4TlvDq8ikWAM" // "Rachel", a default voice available to every account
+	}
+	model := opts.Model
+	if model == "" {
+		model = "eleven_turbo_v2_5"
+	}
+	payload, err := json.Marshal(map[string]string{"text": text, "model_id": model})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/text-to-speech/"+voice, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("xi-api-key", key)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "audio/mpeg")
+
+	audio, contentType, err := speechRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if contentType == "" {
+		contentType = "audio/mpeg"
+	}
+	return &SynthesizedSpeech{Audio: audio, ContentType: contentType}, nil
+}
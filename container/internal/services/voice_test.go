@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func newTestVoiceService(t *testing.T, baseURL string) (*VoiceService, *SecretStore) {
+	t.Helper()
+	dir := t.TempDir()
+	secrets := NewSecretStoreWithPath(filepath.Join(dir, "secrets"))
+	service := NewVoiceServiceWithPath(filepath.Join(dir, "voice.json"), secrets).
+		WithProvider(NewOpenAISpeechProvider(baseURL)).
+		WithProvider(NewElevenLabsSpeechProvider(baseURL))
+	return service, secrets
+}
+
+func TestVoiceServiceTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		audio, _ := io.ReadAll(file)
+		assert.Equal(t, "audio.m4a", header.Filename)
+		assert.Equal(t, "fake-audio", string(audio))
+		assert.Equal(t, "de", r.FormValue("language"))
+		_ = json.NewEncoder(w).Encode(map[string]string{"text": " Führe die Tests aus "})
+	}))
+	defer server.Close()
+
+	service, secrets := newTestVoiceService(t, server.URL)
+	_, err := service.Transcribe(context.Background(), []byte("fake-audio"), "audio/mp4", "")
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err), "the API key is required")
+
+	require.NoError(t, secrets.Set("openai-api-key", "sk-test\n"))
+	_, err = service.Configure(VoiceConfig{Language: "de"})
+	require.NoError(t, err)
+
+	result, err := service.Transcribe(context.Background(), []byte("fake-audio"), "audio/mp4", "")
+	require.NoError(t, err)
+	assert.Equal(t, "Führe die Tests aus", result.Text)
+	assert.Equal(t, "de", result.Language)
+	assert.Equal(t, "openai", result.Provider)
+
+	_, err = service.Transcribe(context.Background(), []byte("fake-audio"), "video/quicktime", "")
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+}
+
+func TestVoiceServiceSynthesize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/text-to-speech/voice-123", r.URL.Path)
+		assert.Equal(t, "el-test", r.Header.Get("xi-api-key"))
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Done. The build passes.", body["text"])
+		w.Header().Set("Content-Type", "audio/mpeg")
+		_, _ = w.Write([]byte("mp3-bytes"))
+	}))
+	defer server.Close()
+
+	service, secrets := newTestVoiceService(t, server.URL)
+	require.NoError(t, secrets.Set("elevenlabs-api-key", "el-test"))
+
+	_, err := service.Configure(VoiceConfig{TranscriptionProvider: "elevenlabs"})
+	assert.Error(t, err, "elevenlabs only synthesizes speech")
+
+	status, err := service.Configure(VoiceConfig{SpeechProvider: "elevenlabs", Voice: "voice-123"})
+	require.NoError(t, err)
+	assert.True(t, status.SpeechReady)
+	assert.False(t, status.TranscriptionReady)
+
+	speech, err := service.Synthesize(context.Background(), "Done. The build passes.", "")
+	require.NoError(t, err)
+	assert.Equal(t, "mp3-bytes", string(speech.Audio))
+	assert.Equal(t, "audio/mpeg", speech.ContentType)
+
+	// Configuration survives a restart
+	reloaded := NewVoiceServiceWithPath(service.path, secrets).WithProvider(NewElevenLabsSpeechProvider(server.URL))
+	assert.Equal(t, "voice-123", reloaded.Status().Voice)
+}
+
+func TestVoiceServiceProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	service, secrets := newTestVoiceService(t, server.URL)
+	require.NoError(t, secrets.Set("openai-api-key", "sk-bad"))
+	_, err := service.Synthesize(context.Background(), "hello", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	apiErr, ok := models.AsAPIError(err)
+	require.True(t, ok)
+	assert.True(t, apiErr.Retryable)
+}
+
+func TestSpeakableText(t *testing.T) {
+	reply := "## Summary\n\nI fixed **two** bugs in `server.go`:\n\n- the [retry loop](https://example.com)\n- the timeout\n\n```go\nfunc main() {}\n```\n\nAll tests pass."
+	assert.Equal(t, "Summary\n\nI fixed two bugs in server.go:\n\nthe retry loop\nthe timeout\n\n(code omitted)\n\nAll tests pass.", SpeakableText(reply))
+}
+
+func TestTruncateSpeechText(t *testing.T) {
+	text := strings.Repeat("Sentence one. ", 10)
+	assert.Equal(t, strings.Repeat("Sentence one. ", 4)+"Sentence one.", truncateSpeechText(text, 70))
+
+	// Without a sentence break the cut falls on a character boundary
+	assert.Equal(t, "ééé", truncateSpeechText("éééé", 7))
+}
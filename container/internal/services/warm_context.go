@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	defaultWarmContextCommits     = 10
+	defaultWarmContextTestTimeout = 60 * time.Second
+	// Lines kept from each section so the block stays a summary
+	maxWarmContextLines = 40
+)
+
+// WarmContextConfig configures the branch summary given to new Claude
+// sessions in .catnip.yaml (claude.warm_context)
+type WarmContextConfig struct {
+	// Add the summary to new sessions (off by default)
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Number of recent branch commits to list (default 10)
+	Commits int `json:"commits,omitempty" yaml:"commits"`
+	// Test command whose failures are included, e.g. "go test ./..."
+	Test string `json:"test,omitempty" yaml:"test"`
+	// Seconds the test command may run before it is skipped (default 60)
+	TestTimeoutSeconds int `json:"test_timeout_seconds,omitempty" yaml:"test_timeout_seconds"`
+}
+
+// WarmContextForPath summarizes the state of the branch checked out at
+// worktreePath (recent commits, uncommitted changes, the diff against the base
+// branch and failing tests) for a new Claude session, so it doesn't spend its
+// first turns rediscovering it. Returns "" unless claude.warm_context.enabled
+// is set in the worktree's .catnip.yaml.
+func (s *GitService) WarmContextForPath(worktreePath string) string {
+	cfg, err := LoadCatnipConfig(worktreePath)
+	if err != nil || !cfg.Claude.WarmContext.Enabled {
+		return ""
+	}
+
+	s.mu.RLock()
+	var worktree *models.Worktree
+	for _, wt := range s.stateManager.GetAllWorktrees() {
+		if wt.Path == worktreePath {
+			worktree = wt
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if worktree == nil {
+		return ""
+	}
+
+	start := time.Now()
+	block := s.buildWarmContext(worktree, cfg.Claude.WarmContext)
+	logger.Debugf("🔥 Built warm context for %s in %v", worktree.Name, time.Since(start))
+	return block
+}
+
+// buildWarmContext assembles the branch summary block
+func (s *GitService) buildWarmContext(worktree *models.Worktree, cfg WarmContextConfig) string {
+	if cfg.Commits <= 0 {
+		cfg.Commits = defaultWarmContextCommits
+	}
+
+	base := ""
+	if worktree.SourceBranch != "" {
+		ref := s.statusSourceRef(worktree)
+		if _, err := s.operations.GetCommitHash(worktree.Path, ref); err == nil {
+			base = ref
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("The state of this worktree when the session started, gathered automatically. Use it instead of re-running git log, git status or the tests to orient yourself.\n\n")
+	b.WriteString("<branch_context>\n")
+	fmt.Fprintf(&b, "Branch: %s", worktree.Branch)
+	if worktree.SourceBranch != "" {
+		fmt.Fprintf(&b, " (based on %s)", worktree.SourceBranch)
+	}
+	b.WriteString("\n")
+
+	logArgs := []string{"log", "--no-color", fmt.Sprintf("-n%d", cfg.Commits), "--format=%h %s (%ar)"}
+	if base != "" {
+		logArgs = append(logArgs, base+"..HEAD")
+	}
+	if commits := s.warmContextGit(worktree.Path, logArgs...); commits != "" {
+		heading := "Recent commits (newest first)"
+		if base != "" {
+			heading = fmt.Sprintf("Commits on this branch not in %s (newest first)", worktree.SourceBranch)
+		}
+		fmt.Fprintf(&b, "\n%s:\n%s\n", heading, commits)
+	} else if base != "" {
+		fmt.Fprintf(&b, "\nNo commits on this branch yet beyond %s.\n", worktree.SourceBranch)
+	}
+
+	if base != "" {
+		if stat := s.warmContextGit(worktree.Path, "diff", "--no-color", "--stat=120", base+"...HEAD"); stat != "" {
+			fmt.Fprintf(&b, "\nCommitted changes against %s:\n%s\n", worktree.SourceBranch, stat)
+		}
+	}
+
+	if status := s.warmContextGit(worktree.Path, "status", "--porcelain"); status != "" {
+		fmt.Fprintf(&b, "\nUncommitted changes (git status --porcelain):\n%s\n", status)
+	} else {
+		b.WriteString("\nNo uncommitted changes.\n")
+	}
+
+	if cfg.Test != "" {
+		b.WriteString("\n")
+		b.WriteString(warmContextTests(worktree.Path, cfg))
+	}
+
+	b.WriteString("</branch_context>")
+	return b.String()
+}
+
+// warmContextGit runs a git command for the summary, returning its trimmed and
+// truncated output, or "" if it fails
+func (s *GitService) warmContextGit(worktreePath string, args ...string) string {
+	output, err := s.operations.ExecuteGit(worktreePath, args...)
+	if err != nil {
+		return ""
+	}
+	return truncateLines(strings.TrimRight(string(output), "\n"), maxWarmContextLines)
+}
+
+// warmContextTests runs the configured test command and describes the result
+func warmContextTests(worktreePath string, cfg WarmContextConfig) string {
+	timeout := defaultWarmContextTestTimeout
+	if cfg.TestTimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TestTimeoutSeconds) * time.Second
+	}
+
+	output, err := runDependencyCommand(worktreePath, cfg.Test, timeout)
+	switch {
+	case err == nil:
+		return fmt.Sprintf("Tests pass (`%s`).\n", cfg.Test)
+	case strings.HasPrefix(err.Error(), "timed out"):
+		return fmt.Sprintf("Tests were not checked: `%s` %v.\n", cfg.Test, err)
+	default:
+		tail := lastLines(strings.TrimRight(output, "\n"), maxWarmContextLines)
+		return fmt.Sprintf("Tests are failing (`%s`: %v). End of the output:\n%s\n", cfg.Test, err, tail)
+	}
+}
+
+// truncateLines keeps the first n lines of text
+func truncateLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= n {
+		return text
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-n)
+}
+
+// lastLines keeps the last n lines of text
+func lastLines(text string, n int) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) <= n {
+		return text
+	}
+	return "…\n" + strings.Join(lines[len(lines)-n:], "\n")
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmContextForPath(t *testing.T) {
+	service, worktree := setupSyncUndoRepo(t, false)
+	dir := worktree.Path
+
+	assert.Empty(t, service.WarmContextForPath(dir), "off unless enabled in .catnip.yaml")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, CatnipConfigFileName), []byte(`claude:
+  warm_context:
+    enabled: true
+    test: "echo 'FAIL: TestLogin'; exit 1"
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("dirty\n"), 0644))
+
+	block := service.WarmContextForPath(dir)
+	assert.Contains(t, block, "Branch: feature (based on main)")
+	assert.Contains(t, block, "Commits on this branch not in main")
+	assert.Contains(t, block, "feature work")
+	assert.NotContains(t, block, "main work", "commits already on the base branch are left out")
+	assert.Contains(t, block, "b.txt")
+	assert.Contains(t, block, " M a.txt")
+	assert.Contains(t, block, "Tests are failing")
+	assert.Contains(t, block, "FAIL: TestLogin")
+	assert.Contains(t, block, "</branch_context>")
+
+	assert.Empty(t, service.WarmContextForPath(t.TempDir()), "unknown worktrees get nothing")
+}
+
+func TestWarmContextTests(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, "Tests pass (`true`).\n", warmContextTests(dir, WarmContextConfig{Test: "true"}))
+	assert.Contains(t, warmContextTests(dir, WarmContextConfig{Test: "sleep 5", TestTimeoutSeconds: 1}), "Tests were not checked")
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// CreateStandbyWorktree creates a fresh worktree from the latest source branch
+// of a merged worktree, carrying over its labels, identity, SSH agent and
+// mDNS settings so the next task on the same service doesn't start from scratch.
+// from is a snapshot, so this works after the merged worktree is deleted.
+func (s *GitService) CreateStandbyWorktree(from *models.Worktree) (*models.Worktree, error) {
+	repo, exists := s.stateManager.GetRepository(from.RepoID)
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(from.RepoID)
+	}
+
+	// Worktrees created from a tag or commit go back to the default branch
+	branch := from.SourceBranch
+	if branch == "" || from.SourceRefType != "" {
+		branch = repo.DefaultBranch
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpCheckout)
+	_, worktree, err := s.createWorktreeForExistingRepo(repo, branch)
+	release()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create standby worktree: %v", err)
+	}
+
+	updates := map[string]interface{}{}
+	if len(from.Labels) > 0 {
+		updates["labels"] = append([]string{}, from.Labels...)
+	}
+	if from.SSHAgentForwarding != nil {
+		updates["ssh_agent_forwarding"] = from.SSHAgentForwarding
+	}
+	if from.MDNS != nil {
+		updates["mdns"] = from.MDNS
+	}
+	if from.ClaudeTools != nil {
+		updates["claude_tools"] = from.ClaudeTools
+	}
+	if from.Checkpoints != nil {
+		updates["checkpoints"] = from.Checkpoints
+	}
+	if len(updates) > 0 {
+		if err := s.stateManager.UpdateWorktree(worktree.ID, updates); err != nil {
+			logger.Warnf("⚠️ Failed to copy settings to standby worktree %s: %v", worktree.Name, err)
+		}
+	}
+	if from.Identity != nil {
+		if _, err := s.SetWorktreeIdentity(worktree.ID, from.Identity); err != nil {
+			logger.Warnf("⚠️ Failed to copy identity to standby worktree %s: %v", worktree.Name, err)
+		}
+	}
+
+	logger.Infof("🔥 Created standby worktree %s from %s to follow %s", worktree.Name, branch, from.Name)
+	if updated, exists := s.stateManager.GetWorktree(worktree.ID); exists {
+		return updated, nil
+	}
+	return worktree, nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestCreateStandbyWorktree(t *testing.T) {
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	service := createTestGitService(t)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("base\n"), 0644))
+	runTestGit(t, dir, "init", "-q", "-b", "main")
+	runTestGit(t, dir, "add", ".")
+	runTestGit(t, dir, "commit", "-q", "-m", "initial")
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "local/app", Path: dir, DefaultBranch: "main"}))
+
+	forwarding := false
+	merged := &models.Worktree{
+		ID:                 "wt-merged",
+		RepoID:             "local/app",
+		Name:               "app/merged",
+		SourceBranch:       "main",
+		Labels:             []string{"customer-x", "hotfix"},
+		SSHAgentForwarding: &forwarding,
+	}
+
+	standby, err := service.CreateStandbyWorktree(merged)
+	require.NoError(t, err)
+	assert.NotEqual(t, merged.ID, standby.ID)
+	assert.Equal(t, "main", standby.SourceBranch)
+	assert.Equal(t, []string{"customer-x", "hotfix"}, standby.Labels)
+	require.NotNil(t, standby.SSHAgentForwarding)
+	assert.False(t, *standby.SSHAgentForwarding)
+	assert.FileExists(t, filepath.Join(standby.Path, "a.txt"))
+
+	_, err = service.CreateStandbyWorktree(&models.Worktree{RepoID: "local/missing"})
+	assert.Error(t, err)
+}
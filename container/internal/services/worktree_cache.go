@@ -41,6 +41,10 @@ type CachedWorktreeStatus struct {
 	LastCommitHashChecked   string    `json:"last_commit_hash_checked"`    // CommitHash when HasCommitsAheadOfRemote was last computed
 	LastUpdated             time.Time `json:"last_updated"`
 	UpdateInProgress        bool      `json:"update_in_progress"`
+
+	// Stash, file-state and per-remote breakdown; nil = not cached yet
+	StatusDetail *models.WorktreeStatusDetail `json:"status_detail,omitempty"`
+	detailKeys   statusDetailKeys
 }
 
 // NewWorktreeStatusCache creates a new worktree status cache
@@ -104,6 +108,9 @@ func (c *WorktreeStatusCache) EnhanceWorktreeWithCache(worktree *models.Worktree
 	if cached.CommitsBehind != nil {
 		worktree.CommitsBehind = *cached.CommitsBehind
 	}
+	if cached.StatusDetail != nil {
+		worktree.StatusDetail = cached.StatusDetail
+	}
 	// Only update branch field if worktree hasn't been renamed
 	// If renamed, Branch field shows nice name for UI, don't overwrite with actual git ref
 	if cached.Branch != "" && !worktree.HasBeenRenamed {
@@ -362,6 +369,9 @@ func (c *WorktreeStatusCache) processBatchUpdates(worktreeIDs map[string]bool) {
 				if cached.Branch != "" {
 					stateUpdate["branch"] = cached.Branch
 				}
+				if cached.StatusDetail != nil {
+					stateUpdate["status_detail"] = cached.StatusDetail
+				}
 				if len(stateUpdate) > 0 {
 					stateUpdates[worktreeID] = stateUpdate
 				}
@@ -423,6 +433,9 @@ func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, ca
 	if worktreePath == "" || worktree == nil {
 		return cached // Worktree not found
 	}
+	if worktree.Hibernation != nil {
+		return cached // No files to inspect until rehydrated
+	}
 
 	// Perform the expensive git operations
 
@@ -497,6 +510,8 @@ func (c *WorktreeStatusCache) updateWorktreeStatusInternal(worktreeID string, ca
 		}
 	}
 
+	c.updateStatusDetail(worktreePath, worktree, cached)
+
 	cached.LastUpdated = time.Now()
 
 	// Removed noisy worktree update logs
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// HibernateWorktree removes a dormant worktree's files to reclaim disk. Its
+// branch stays (catnip refs under a keep marker), uncommitted changes are kept
+// as a stash commit at refs/catnip-hibernate/<id>, ignored files are archived
+// in the state directory and the worktree stays in state, so
+// RehydrateWorktree can bring it back as it was.
+func (s *GitService) HibernateWorktree(worktreeID string) (*models.Worktree, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if worktree.Hibernation != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is already hibernated", worktree.Name)
+	}
+	if worktree.Sandbox != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is sandboxed", worktree.Name).
+			WithHint("Promote or discard the sandbox before hibernating")
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
+	}
+	if worktree.Path == repo.Path {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s is the repository's main checkout and can't be hibernated", worktree.Name)
+	}
+	if worktree.ClaudeActivityState == models.ClaudeActive || worktree.ClaudeActivityState == models.ClaudeRunning {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has a Claude session running", worktree.Name).
+			WithHint("Close the worktree's terminals before hibernating it")
+	}
+	s.mu.RLock()
+	sessions := s.workspaceSessions
+	s.mu.RUnlock()
+	if sessions != nil {
+		if open := sessions.WorkspaceSessionCount(worktree.Path); open > 0 {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has %d terminal sessions open", worktree.Name, open).
+				WithHint("Close the worktree's terminals before hibernating it")
+		}
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpHibernate)
+	defer release()
+
+	if s.operations.HasConflicts(worktree.Path) {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has unresolved conflicts", worktree.Name).
+			WithHint("Resolve or abort the merge or rebase before hibernating")
+	}
+
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of %s: %v", worktree.Name, err)
+	}
+	hibernation := &models.WorktreeHibernation{HibernatedAt: time.Now(), Head: head}
+	if output, err := s.operations.ExecuteGit(worktree.Path, "symbolic-ref", "-q", "HEAD"); err == nil {
+		hibernation.Ref = strings.TrimSpace(string(output))
+	}
+
+	// Ignored files aren't part of a stash but are often relied on, so they
+	// are archived before anything is removed
+	archive, err := s.archiveIgnoredFiles(worktree)
+	if err != nil {
+		return nil, err
+	}
+	hibernation.IgnoredArchive = archive
+
+	// Untracked files are part of the work too, so they go into the stash
+	if output, err := s.operations.ExecuteGit(worktree.Path, "status", "--porcelain"); err != nil {
+		removeIgnoredArchive(archive)
+		return nil, fmt.Errorf("failed to check %s for changes: %v", worktree.Name, err)
+	} else if strings.TrimSpace(string(output)) != "" {
+		stash, err := s.stashHibernatedChanges(worktree, repo)
+		if err != nil {
+			removeIgnoredArchive(archive)
+			return nil, err
+		}
+		hibernation.Stash = stash
+	}
+
+	if catnipRef := hibernatedCatnipRef(hibernation.Ref); catnipRef != "" {
+		if err := s.KeepCatnipRef(repo.Path, catnipRef); err != nil {
+			removeIgnoredArchive(archive)
+			return nil, fmt.Errorf("failed to protect %s: %v", catnipRef, err)
+		}
+	}
+
+	if layout, err := scanDiskLayout(worktree.Path, time.Now); err == nil {
+		hibernation.ReclaimedBytes = layout.TotalBytes
+	}
+
+	s.worktreeCache.RemoveWorktree(worktree.ID, worktree.Path)
+	if err := s.operations.RemoveWorktree(repo.Path, worktree.Path, true); err != nil {
+		logger.Warnf("⚠️ Failed to remove worktree %s, removing its directory: %v", worktree.Name, err)
+	}
+	if err := os.RemoveAll(worktree.Path); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %v", worktree.Path, err)
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"hibernation": hibernation}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("💤 Hibernated %s at %s, reclaiming %d bytes", worktree.Name, head, hibernation.ReclaimedBytes)
+	updated, _ := s.stateManager.GetWorktree(worktree.ID)
+	return updated, nil
+}
+
+// RehydrateWorktree checks a hibernated worktree out again at its branch and
+// re-applies the changes it was hibernated with
+func (s *GitService) RehydrateWorktree(worktreeID string) (*models.Worktree, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	hibernation := worktree.Hibernation
+	if hibernation == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is not hibernated", worktree.Name)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, models.NewRepositoryNotFoundError(worktree.RepoID)
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpHibernate)
+	defer release()
+
+	if entries, err := os.ReadDir(worktree.Path); err == nil && len(entries) > 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%s already exists and is not empty", worktree.Path).
+			WithHint("Move the directory aside to rehydrate the worktree")
+	}
+
+	if err := s.checkoutHibernatedWorktree(worktree, repo, hibernation); err != nil {
+		return nil, err
+	}
+
+	if hibernation.IgnoredArchive != "" {
+		if err := extractFilesArchive(hibernation.IgnoredArchive, worktree.Path); err != nil {
+			logger.Warnf("⚠️ Failed to restore ignored files of %s from %s: %v", worktree.Name, hibernation.IgnoredArchive, err)
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "checked out %s but failed to restore its ignored files", worktree.Name).
+				WithHint(fmt.Sprintf("Extract %s into the worktree with tar -xzf", hibernation.IgnoredArchive))
+		}
+	}
+
+	if hibernation.Stash != "" {
+		if _, err := s.operations.ExecuteGit(worktree.Path, "stash", "apply", "--index", hibernation.Stash); err != nil {
+			if output, err := s.operations.ExecuteGit(worktree.Path, "stash", "apply", hibernation.Stash); err != nil {
+				// The worktree is back; the stash ref stays so the changes can be applied by hand
+				logger.Warnf("⚠️ Failed to re-apply hibernated changes %s in %s: %v\n%s", hibernation.Stash, worktree.Name, err, strings.TrimSpace(string(output)))
+				return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "checked out %s but failed to re-apply its uncommitted changes", worktree.Name).
+					WithHint(fmt.Sprintf("Run git stash apply %s in the worktree", git.HibernateRefName(worktree.ID)))
+			}
+		}
+		_, _ = s.operations.ExecuteGit(repo.Path, "update-ref", "-d", git.HibernateRefName(worktree.ID))
+	}
+
+	if catnipRef := hibernatedCatnipRef(hibernation.Ref); catnipRef != "" {
+		_ = s.ReleaseCatnipRef(repo.Path, catnipRef)
+	}
+	removeIgnoredArchive(hibernation.IgnoredArchive)
+
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"hibernation": (*models.WorktreeHibernation)(nil)}); err != nil {
+		return nil, err
+	}
+	s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
+
+	logger.Infof("☀️ Rehydrated %s at %s", worktree.Name, worktree.Path)
+	updated, _ := s.stateManager.GetWorktree(worktree.ID)
+	return updated, nil
+}
+
+// stashHibernatedChanges stashes a worktree's changes, untracked files
+// included, and moves the stash commit from the shared stash list to the
+// worktree's hibernate ref
+func (s *GitService) stashHibernatedChanges(worktree *models.Worktree, repo *models.Repository) (string, error) {
+	message := fmt.Sprintf("catnip: hibernate %s", worktree.Name)
+	if output, err := s.operations.ExecuteGit(worktree.Path, "stash", "push", "--include-untracked", "-m", message); err != nil {
+		return "", fmt.Errorf("failed to stash changes in %s: %v\n%s", worktree.Name, err, strings.TrimSpace(string(output)))
+	}
+	stash, err := s.operations.GetCommitHash(worktree.Path, "stash@{0}")
+	if err != nil {
+		return "", fmt.Errorf("failed to read stash in %s: %v", worktree.Name, err)
+	}
+	if _, err := s.operations.ExecuteGit(repo.Path, "update-ref", git.HibernateRefName(worktree.ID), stash); err != nil {
+		// Put the changes back rather than leave them only in the stash list
+		_ = s.operations.StashPop(worktree.Path)
+		return "", fmt.Errorf("failed to save stash of %s: %v", worktree.Name, err)
+	}
+	s.dropStash(worktree.Path, stash)
+	return stash, nil
+}
+
+// archiveIgnoredFiles writes the worktree's ignored files to a gzipped tar
+// in the state directory, returning "" if there are none
+func (s *GitService) archiveIgnoredFiles(worktree *models.Worktree) (string, error) {
+	output, err := s.operations.ExecuteGit(worktree.Path, "ls-files", "-z", "--others", "--ignored", "--exclude-standard")
+	if err != nil {
+		return "", fmt.Errorf("failed to list ignored files in %s: %v", worktree.Name, err)
+	}
+	names := strings.Split(strings.TrimRight(string(output), "\x00"), "\x00")
+	if len(names) == 1 && names[0] == "" {
+		return "", nil
+	}
+
+	dir := filepath.Join(s.stateManager.stateDir, "hibernate")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	archive := filepath.Join(dir, filepath.Base(worktree.ID)+".tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		return "", fmt.Errorf("failed to archive ignored files of %s: %v", worktree.Name, err)
+	}
+	err = writeFilesArchive(f, worktree.Path, names)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(archive)
+		return "", fmt.Errorf("failed to archive ignored files of %s: %v", worktree.Name, err)
+	}
+	return archive, nil
+}
+
+// removeIgnoredArchive deletes an archive of ignored files, if any
+func removeIgnoredArchive(archive string) {
+	if archive == "" {
+		return
+	}
+	if err := os.Remove(archive); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("⚠️ Failed to remove %s: %v", archive, err)
+	}
+}
+
+// extractFilesArchive unpacks an archive written by writeFilesArchive into
+// dir. Entries that would land outside dir are skipped.
+func extractFilesArchive(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("corrupt archive: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("corrupt archive: %v", err)
+		}
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			logger.Warnf("⚠️ Ignoring unexpected archive entry %s", header.Name)
+			continue
+		}
+		dest := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeReg:
+			out, err := os.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, header.FileInfo().Mode().Perm())
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, tr)
+			if closeErr := out.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.Symlink(header.Linkname, dest); err != nil && !os.IsExist(err) {
+				return err
+			}
+		}
+	}
+}
+
+// checkoutHibernatedWorktree adds the worktree back at the ref it was
+// hibernated on, or detached at its old HEAD if that ref is gone
+func (s *GitService) checkoutHibernatedWorktree(worktree *models.Worktree, repo *models.Repository, hibernation *models.WorktreeHibernation) error {
+	ref := hibernation.Ref
+	if ref != "" {
+		if _, err := s.operations.GetCommitHash(repo.Path, ref); err != nil {
+			logger.Warnf("⚠️ %s no longer exists, rehydrating %s detached at %s", ref, worktree.Name, hibernation.Head)
+			ref = ""
+		}
+	}
+
+	var args []string
+	switch {
+	case ref == "" || strings.HasPrefix(ref, "refs/catnip/"):
+		// Catnip refs aren't branches, so they are attached with symbolic-ref below
+		target := hibernation.Head
+		if ref != "" {
+			target = ref
+		}
+		args = []string{"worktree", "add", "--detach", worktree.Path, target}
+	default:
+		args = []string{"worktree", "add", worktree.Path, strings.TrimPrefix(ref, "refs/heads/")}
+	}
+
+	output, err := s.operations.ExecuteGit(repo.Path, args...)
+	if err != nil && strings.Contains(err.Error()+string(output), "already registered") {
+		// A directory removed without git leaves its registration behind
+		output, err = s.operations.ExecuteGit(repo.Path, append([]string{"worktree", "add", "-f"}, args[2:]...)...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check out %s: %v\n%s", worktree.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	if strings.HasPrefix(ref, "refs/catnip/") {
+		if output, err := s.operations.ExecuteGit(worktree.Path, "symbolic-ref", "HEAD", ref); err != nil {
+			return fmt.Errorf("failed to attach %s to %s: %v\n%s", worktree.Name, ref, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// hibernatedCatnipRef returns the name to keep a hibernated worktree's ref
+// under, or "" if it isn't a catnip ref or legacy catnip branch
+func hibernatedCatnipRef(ref string) string {
+	if strings.HasPrefix(ref, "refs/catnip/") {
+		return ref
+	}
+	if branch := strings.TrimPrefix(ref, "refs/heads/"); strings.HasPrefix(branch, "catnip/") {
+		return branch
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// fakeWorkspaceSessions reports a fixed number of open terminals per workspace
+type fakeWorkspaceSessions map[string]int
+
+func (f fakeWorkspaceSessions) WorkspaceSessionCount(workDir string) int {
+	return f[workDir]
+}
+
+func (f fakeWorkspaceSessions) CloseWorkspaceSessions(workDir string) int {
+	closed := f[workDir]
+	delete(f, workDir)
+	return closed
+}
+
+func TestHibernateAndRehydrateWorktree(t *testing.T) {
+	t.Setenv("CATNIP_WORKSPACE_DIR", t.TempDir())
+	service := createTestGitService(t)
+	defer service.Stop()
+
+	repoPath := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.MkdirAll(repoPath, 0755))
+	runTestGit(t, repoPath, "init", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "app.txt"), []byte("v1\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, ".gitignore"), []byte(".env\ncache/\n"), 0644))
+	runTestGit(t, repoPath, "add", ".")
+	runTestGit(t, repoPath, "commit", "-m", "base")
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: repoPath, DefaultBranch: "main"}))
+
+	// A workspace on a catnip ref with a commit and uncommitted work
+	worktreePath := filepath.Join(t.TempDir(), "felix")
+	runTestGit(t, repoPath, "worktree", "add", "--detach", worktreePath, "main")
+	runTestGit(t, repoPath, "update-ref", "refs/catnip/felix", "main")
+	runTestGit(t, worktreePath, "symbolic-ref", "HEAD", "refs/catnip/felix")
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v2\n"), 0644))
+	runTestGit(t, worktreePath, "commit", "-am", "v2")
+	head := runTestGit(t, worktreePath, "rev-parse", "HEAD")
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "app.txt"), []byte("v3\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "notes.txt"), []byte("untracked\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, ".env"), []byte("API_KEY=local\n"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(worktreePath, "cache", "build"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(worktreePath, "cache", "build", "out.bin"), []byte("built\n"), 0644))
+	require.NoError(t, os.Symlink("out.bin", filepath.Join(worktreePath, "cache", "build", "latest")))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{
+		ID: "wt-felix", RepoID: "acme/app", Name: "app/felix", Path: worktreePath, Branch: "refs/catnip/felix", SourceBranch: "main",
+	}))
+
+	// Open terminals would lose their working directory
+	sessions := fakeWorkspaceSessions{worktreePath: 1}
+	service.SetWorkspaceSessions(sessions)
+	_, err := service.HibernateWorktree("wt-felix")
+	require.Error(t, err)
+	assert.DirExists(t, worktreePath)
+	delete(sessions, worktreePath)
+
+	worktree, err := service.HibernateWorktree("wt-felix")
+	require.NoError(t, err)
+	require.NotNil(t, worktree.Hibernation)
+	assert.FileExists(t, worktree.Hibernation.IgnoredArchive)
+	assert.Equal(t, "refs/catnip/felix", worktree.Hibernation.Ref)
+	assert.Equal(t, head, worktree.Hibernation.Head)
+	assert.NotEmpty(t, worktree.Hibernation.Stash)
+	assert.Positive(t, worktree.Hibernation.ReclaimedBytes)
+	assert.NoDirExists(t, worktreePath)
+	assert.Equal(t, worktree.Hibernation.Stash, runTestGit(t, repoPath, "rev-parse", git.HibernateRefName("wt-felix")))
+	assert.Equal(t, head, runTestGit(t, repoPath, "rev-parse", git.KeepRefName("refs/catnip/felix")))
+	assert.Empty(t, runTestGit(t, repoPath, "stash", "list"), "the shared stash list is left alone")
+
+	_, err = service.HibernateWorktree("wt-felix")
+	assert.Error(t, err, "already hibernated")
+
+	worktree, err = service.RehydrateWorktree("wt-felix")
+	require.NoError(t, err)
+	assert.Nil(t, worktree.Hibernation)
+	assert.Equal(t, "refs/catnip/felix", runTestGit(t, worktreePath, "symbolic-ref", "HEAD"))
+	assert.Equal(t, head, runTestGit(t, worktreePath, "rev-parse", "HEAD"))
+	content, err := os.ReadFile(filepath.Join(worktreePath, "app.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v3\n", string(content))
+	assert.FileExists(t, filepath.Join(worktreePath, "notes.txt"))
+	// Ignored files come back from the archive, which is then removed
+	content, err = os.ReadFile(filepath.Join(worktreePath, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, "API_KEY=local\n", string(content))
+	info, err := os.Stat(filepath.Join(worktreePath, ".env"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	assert.FileExists(t, filepath.Join(worktreePath, "cache", "build", "out.bin"))
+	link, err := os.Readlink(filepath.Join(worktreePath, "cache", "build", "latest"))
+	require.NoError(t, err)
+	assert.Equal(t, "out.bin", link)
+	assert.NoFileExists(t, filepath.Join(service.stateManager.stateDir, "hibernate", "wt-felix.tar.gz"))
+	assert.Empty(t, runTestGit(t, repoPath, "for-each-ref", git.CatnipHibernateRefPrefix, git.CatnipKeepRefPrefix))
+
+	_, err = service.RehydrateWorktree("wt-felix")
+	assert.Error(t, err, "not hibernated")
+}
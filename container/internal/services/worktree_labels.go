@@ -0,0 +1,241 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// maxWorktreeLabels caps how many labels a single worktree can carry
+const maxWorktreeLabels = 20
+
+// worktreeLabelPattern allows short slug-like labels ("experiment", "customer-x", "v2.1")
+var worktreeLabelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,62}$`)
+
+// Bulk worktree actions
+const (
+	BulkActionDelete  = "delete"
+	BulkActionSync    = "sync"
+	BulkActionLabel   = "label"
+	BulkActionUnlabel = "unlabel"
+)
+
+// NormalizeWorktreeLabels trims, lowercases, validates, de-duplicates and sorts labels
+func NormalizeWorktreeLabels(labels []string) ([]string, error) {
+	seen := make(map[string]bool, len(labels))
+	normalized := make([]string, 0, len(labels))
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || seen[label] {
+			continue
+		}
+		if !worktreeLabelPattern.MatchString(label) {
+			return nil, fmt.Errorf("invalid label %q: use up to 63 lowercase letters, digits, '.', '_' or '-'", label)
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	if len(normalized) > maxWorktreeLabels {
+		return nil, fmt.Errorf("a worktree can have at most %d labels", maxWorktreeLabels)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// LabelSelector picks worktrees by label. A worktree matches when it has every
+// Include label and none of the Exclude labels; the empty selector matches all.
+type LabelSelector struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// ParseLabelSelector parses a comma-separated selector such as "experiment,!keep",
+// where a leading "!" excludes worktrees carrying that label
+func ParseLabelSelector(raw string) (LabelSelector, error) {
+	var include, exclude []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if negated := strings.TrimPrefix(part, "!"); negated != part {
+			exclude = append(exclude, negated)
+		} else if part != "" {
+			include = append(include, part)
+		}
+	}
+
+	var selector LabelSelector
+	var err error
+	if selector.Include, err = NormalizeWorktreeLabels(include); err != nil {
+		return LabelSelector{}, err
+	}
+	if selector.Exclude, err = NormalizeWorktreeLabels(exclude); err != nil {
+		return LabelSelector{}, err
+	}
+	return selector, nil
+}
+
+// Empty reports whether the selector matches every worktree
+func (s LabelSelector) Empty() bool {
+	return len(s.Include) == 0 && len(s.Exclude) == 0
+}
+
+// Matches reports whether a worktree with these labels is selected
+func (s LabelSelector) Matches(labels []string) bool {
+	has := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		has[label] = true
+	}
+	for _, label := range s.Include {
+		if !has[label] {
+			return false
+		}
+	}
+	for _, label := range s.Exclude {
+		if has[label] {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the selector in the form ParseLabelSelector accepts
+func (s LabelSelector) String() string {
+	parts := append([]string{}, s.Include...)
+	for _, label := range s.Exclude {
+		parts = append(parts, "!"+label)
+	}
+	return strings.Join(parts, ",")
+}
+
+// SetWorktreeLabels replaces a worktree's labels and returns the normalized set
+func (s *GitService) SetWorktreeLabels(worktreeID string, labels []string) ([]string, error) {
+	normalized, err := NormalizeWorktreeLabels(labels)
+	if err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+	if _, exists := s.stateManager.GetWorktree(worktreeID); !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"labels": normalized}); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// WorktreeLabels returns every label in use with the number of worktrees carrying it
+func (s *GitService) WorktreeLabels() map[string]int {
+	counts := make(map[string]int)
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		for _, label := range worktree.Labels {
+			counts[label]++
+		}
+	}
+	return counts
+}
+
+// WorktreeMatchesLabels reports whether a worktree is selected; unknown worktrees never match
+func (s *GitService) WorktreeMatchesLabels(worktreeID string, selector LabelSelector) bool {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	return exists && selector.Matches(worktree.Labels)
+}
+
+// BulkWorktreeRequest applies one action to every worktree matching a label selector
+type BulkWorktreeRequest struct {
+	// Label selector, e.g. "experiment,!keep" (required)
+	Selector string `json:"selector" example:"experiment,!keep"`
+	// Action to apply: delete, sync, label or unlabel
+	Action string `json:"action" example:"sync"`
+	// Sync strategy for the sync action (defaults to rebase)
+	Strategy string `json:"strategy,omitempty" example:"rebase"`
+	// Labels added or removed by the label/unlabel actions
+	Labels []string `json:"labels,omitempty"`
+}
+
+// BulkWorktreeResult is the outcome of a bulk action on one worktree
+type BulkWorktreeResult struct {
+	WorktreeID string `json:"worktree_id"`
+	Name       string `json:"name"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BulkWorktrees applies an action to the worktrees selected by label. A
+// selector is required so a typo can't turn into an action on every worktree.
+// Failures are reported per worktree and don't stop the rest.
+func (s *GitService) BulkWorktrees(req BulkWorktreeRequest) ([]BulkWorktreeResult, error) {
+	selector, err := ParseLabelSelector(req.Selector)
+	if err != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+	}
+	if len(selector.Include) == 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "selector must include at least one label")
+	}
+
+	var labels []string
+	switch req.Action {
+	case BulkActionDelete, BulkActionSync:
+	case BulkActionLabel, BulkActionUnlabel:
+		if labels, err = NormalizeWorktreeLabels(req.Labels); err != nil {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "%v", err)
+		}
+		if len(labels) == 0 {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "labels are required for the %s action", req.Action)
+		}
+	default:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "unknown bulk action %q", req.Action)
+	}
+
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = "rebase"
+	}
+
+	results := []BulkWorktreeResult{}
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if !selector.Matches(worktree.Labels) {
+			continue
+		}
+
+		var opErr error
+		switch req.Action {
+		case BulkActionDelete:
+			var done <-chan error
+			if done, opErr = s.DeleteWorktree(worktree.ID); opErr == nil {
+				opErr = <-done
+			}
+		case BulkActionSync:
+			opErr = s.SyncWorktree(worktree.ID, strategy)
+		case BulkActionLabel:
+			_, opErr = s.SetWorktreeLabels(worktree.ID, append(append([]string{}, worktree.Labels...), labels...))
+		case BulkActionUnlabel:
+			_, opErr = s.SetWorktreeLabels(worktree.ID, withoutLabels(worktree.Labels, labels))
+		}
+
+		result := BulkWorktreeResult{WorktreeID: worktree.ID, Name: worktree.Name}
+		if opErr != nil {
+			result.Error = opErr.Error()
+			logger.Warnf("⚠️ Bulk %s failed for %s: %v", req.Action, worktree.Name, opErr)
+		}
+		results = append(results, result)
+	}
+
+	logger.Infof("🏷️ Bulk %s applied to %d worktrees matching %q", req.Action, len(results), selector.String())
+	return results, nil
+}
+
+// withoutLabels returns labels minus the removed ones
+func withoutLabels(labels, removed []string) []string {
+	drop := make(map[string]bool, len(removed))
+	for _, label := range removed {
+		drop[label] = true
+	}
+	kept := []string{}
+	for _, label := range labels {
+		if !drop[label] {
+			kept = append(kept, label)
+		}
+	}
+	return kept
+}
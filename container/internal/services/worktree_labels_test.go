@@ -0,0 +1,68 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestLabelSelector(t *testing.T) {
+	labels, err := NormalizeWorktreeLabels([]string{" Hotfix", "experiment", "hotfix", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"experiment", "hotfix"}, labels)
+
+	_, err = NormalizeWorktreeLabels([]string{"has space"})
+	assert.Error(t, err)
+
+	selector, err := ParseLabelSelector("experiment, !keep")
+	require.NoError(t, err)
+	assert.Equal(t, LabelSelector{Include: []string{"experiment"}, Exclude: []string{"keep"}}, selector)
+	assert.Equal(t, "experiment,!keep", selector.String())
+
+	assert.True(t, selector.Matches([]string{"customer-x", "experiment"}))
+	assert.False(t, selector.Matches([]string{"experiment", "keep"}))
+	assert.False(t, selector.Matches(nil))
+
+	empty, err := ParseLabelSelector("")
+	require.NoError(t, err)
+	assert.True(t, empty.Empty())
+	assert.True(t, empty.Matches(nil))
+}
+
+func TestBulkWorktreeLabels(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-1", RepoID: "acme/app", Name: "one", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-2", RepoID: "acme/app", Name: "two", Path: dir}))
+
+	labels, err := service.SetWorktreeLabels("wt-1", []string{"Experiment"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"experiment"}, labels)
+	_, err = service.SetWorktreeLabels("wt-2", []string{"experiment", "keep"})
+	require.NoError(t, err)
+	_, err = service.SetWorktreeLabels("missing", []string{"experiment"})
+	assert.Error(t, err)
+
+	assert.Equal(t, map[string]int{"experiment": 2, "keep": 1}, service.WorktreeLabels())
+
+	results, err := service.BulkWorktrees(BulkWorktreeRequest{Selector: "experiment,!keep", Action: BulkActionLabel, Labels: []string{"customer-x"}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "wt-1", results[0].WorktreeID)
+	assert.Empty(t, results[0].Error)
+
+	worktree, _ := service.GetWorktree("wt-1")
+	assert.Equal(t, []string{"customer-x", "experiment"}, worktree.Labels)
+
+	_, err = service.BulkWorktrees(BulkWorktreeRequest{Selector: "experiment", Action: BulkActionUnlabel, Labels: []string{"experiment"}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"customer-x": 1, "keep": 1}, service.WorktreeLabels())
+
+	_, err = service.BulkWorktrees(BulkWorktreeRequest{Selector: "!keep", Action: BulkActionDelete})
+	assert.Error(t, err, "a selector without an included label would match almost everything")
+	_, err = service.BulkWorktrees(BulkWorktreeRequest{Selector: "keep", Action: "archive"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// PathLockModeSetting decides what happens when Claude edits a locked path:
+// "warn" (default) lets the edit through and tells Claude and the user about
+// the lock, "block" denies the edit
+const PathLockModeSetting = "CATNIP_PATH_LOCK_MODE"
+
+// Path lock modes
+const (
+	PathLockModeWarn  = "warn"
+	PathLockModeBlock = "block"
+)
+
+const (
+	// maxWorktreeLocks caps how many locks a worktree holds
+	maxWorktreeLocks = 20
+	// maxLockPaths caps the globs in one lock
+	maxLockPaths = 20
+	// worktreeLockMaxReason caps a lock's reason
+	worktreeLockMaxReason = 300
+)
+
+// Claude Code tools that modify the file named in their input
+var fileEditTools = map[string]bool{
+	"Edit":         true,
+	"MultiEdit":    true,
+	"Write":        true,
+	"NotebookEdit": true,
+}
+
+// LockedPath is a path covered by a worktree lock
+type LockedPath struct {
+	// Path relative to the worktree root
+	Path string              `json:"path" example:"src/auth/middleware.go"`
+	Lock models.WorktreeLock `json:"lock"`
+}
+
+// PathLockMode returns the configured path lock mode
+func PathLockMode() string {
+	if os.Getenv(PathLockModeSetting) == PathLockModeBlock {
+		return PathLockModeBlock
+	}
+	return PathLockModeWarn
+}
+
+// normalizeLockPattern cleans a lock glob into a path relative to the worktree root
+func normalizeLockPattern(pattern string) (string, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return "", fmt.Errorf("empty path")
+	}
+	if path.IsAbs(pattern) {
+		return "", fmt.Errorf("%q must be relative to the worktree root", pattern)
+	}
+	pattern = strings.TrimSuffix(path.Clean(pattern), "/")
+	if pattern == ".." || strings.HasPrefix(pattern, "../") {
+		return "", fmt.Errorf("%q is outside the worktree", pattern)
+	}
+	if pattern == "." {
+		pattern = "**"
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return "", fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+	}
+	return pattern, nil
+}
+
+// lockPatternMatches reports whether a lock glob covers a path relative to
+// the worktree root. A pattern naming a directory covers everything in it.
+func lockPatternMatches(pattern, rel string) bool {
+	patternParts := strings.Split(pattern, "/")
+	parts := strings.Split(rel, "/")
+	if matchPathSegments(patternParts, parts) {
+		return true
+	}
+	for i := len(parts) - 1; i > 0; i-- {
+		if matchPathSegments(patternParts, parts[:i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeLocks drops expired locks
+func activeLocks(locks []models.WorktreeLock, now time.Time) []models.WorktreeLock {
+	active := make([]models.WorktreeLock, 0, len(locks))
+	for _, lock := range locks {
+		if lock.ExpiresAt == nil || lock.ExpiresAt.After(now) {
+			active = append(active, lock)
+		}
+	}
+	return active
+}
+
+// AddWorktreeLock locks paths in a worktree. Claude sessions there are told
+// about the lock and warned or blocked (per CATNIP_PATH_LOCK_MODE) when they
+// edit the paths. A ttl of 0 keeps the lock until it's removed.
+func (s *GitService) AddWorktreeLock(worktreeID string, lock models.WorktreeLock, ttl time.Duration) (*models.WorktreeLock, error) {
+	lock.Reason = normalizeMemoryText(lock.Reason)
+	lock.Owner = strings.TrimSpace(lock.Owner)
+	switch {
+	case len(lock.Paths) == 0:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "at least one path is required")
+	case len(lock.Paths) > maxLockPaths:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "a lock covers at most %d paths", maxLockPaths)
+	case lock.Reason == "":
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "a reason is required").
+			WithHint("Say why the paths are locked; Claude is shown the reason")
+	case len(lock.Reason) > worktreeLockMaxReason:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "reason is %d characters; keep it under %d", len(lock.Reason), worktreeLockMaxReason)
+	case ttl < 0:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "ttl can't be negative")
+	}
+	patterns := make([]string, 0, len(lock.Paths))
+	for _, pattern := range lock.Paths {
+		normalized, err := normalizeLockPattern(pattern)
+		if err != nil {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid lock path: %v", err)
+		}
+		patterns = append(patterns, normalized)
+	}
+	lock.Paths = patterns
+
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	now := time.Now()
+	locks := activeLocks(worktree.Locks, now)
+	if len(locks) >= maxWorktreeLocks {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s already has %d locks", worktree.Name, maxWorktreeLocks).
+			WithHint("Remove locks that are no longer needed first")
+	}
+
+	lock.ID = uuid.New().String()
+	lock.CreatedAt = now
+	lock.ExpiresAt = nil
+	if ttl > 0 {
+		expires := now.Add(ttl)
+		lock.ExpiresAt = &expires
+	}
+	locks = append(locks, lock)
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"locks": locks}); err != nil {
+		return nil, err
+	}
+	logger.Infof("🔒 Locked %s in worktree %s: %s", strings.Join(lock.Paths, ", "), worktree.Name, lock.Reason)
+	return &lock, nil
+}
+
+// RemoveWorktreeLock releases a lock
+func (s *GitService) RemoveWorktreeLock(worktreeID, lockID string) error {
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	locks := make([]models.WorktreeLock, 0, len(worktree.Locks))
+	for _, lock := range worktree.Locks {
+		if lock.ID != lockID {
+			locks = append(locks, lock)
+		}
+	}
+	if len(locks) == len(worktree.Locks) {
+		return fmt.Errorf("worktree %s has no lock %s", worktree.Name, lockID)
+	}
+	logger.Infof("🔓 Released lock %s in worktree %s", lockID, worktree.Name)
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"locks": activeLocks(locks, time.Now())})
+}
+
+// WorktreeLocks returns a worktree's unexpired locks, newest first
+func (s *GitService) WorktreeLocks(worktreeID string) ([]models.WorktreeLock, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	locks := activeLocks(worktree.Locks, time.Now())
+	sort.SliceStable(locks, func(i, j int) bool {
+		return locks[i].CreatedAt.After(locks[j].CreatedAt)
+	})
+	return locks, nil
+}
+
+// worktreeContaining returns the worktree whose directory holds path, the
+// most specific one when worktrees are nested
+func (s *GitService) worktreeContaining(dir string) *models.Worktree {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var match *models.Worktree
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if dir != worktree.Path && !strings.HasPrefix(dir, worktree.Path+string(filepath.Separator)) {
+			continue
+		}
+		if match == nil || len(worktree.Path) > len(match.Path) {
+			match = worktree
+		}
+	}
+	return match
+}
+
+// FindLockedPaths checks paths (absolute, or relative to dir) against the
+// locks of the worktree containing dir
+func (s *GitService) FindLockedPaths(dir string, paths []string) []LockedPath {
+	worktree := s.worktreeContaining(dir)
+	if worktree == nil {
+		return nil
+	}
+	locks := activeLocks(worktree.Locks, time.Now())
+	if len(locks) == 0 {
+		return nil
+	}
+
+	var locked []LockedPath
+	for _, target := range paths {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		rel, err := filepath.Rel(worktree.Path, filepath.Clean(target))
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, lock := range locks {
+			if lockCovers(lock, rel) {
+				locked = append(locked, LockedPath{Path: rel, Lock: lock})
+				break
+			}
+		}
+	}
+	return locked
+}
+
+func lockCovers(lock models.WorktreeLock, rel string) bool {
+	for _, pattern := range lock.Paths {
+		if lockPatternMatches(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// PathLockHookResponse answers a Claude hook with the locks of the session's
+// worktree: the active locks as context when a prompt is submitted, and a
+// warning or denial (per CATNIP_PATH_LOCK_MODE) before a tool edits a locked
+// path. Shell commands are only checked for locked paths they mention, and
+// only warned about, since catnip can't tell whether they modify them.
+func (s *GitService) PathLockHookResponse(event *models.ClaudeHookEvent) *models.ClaudeHookResponse {
+	switch event.EventType {
+	case "UserPromptSubmit":
+		worktree := s.worktreeContaining(event.WorkingDirectory)
+		if worktree == nil {
+			return nil
+		}
+		locks := activeLocks(worktree.Locks, time.Now())
+		if len(locks) == 0 {
+			return nil
+		}
+		return &models.ClaudeHookResponse{
+			HookSpecificOutput: &models.ClaudeHookSpecificOutput{
+				HookEventName:     event.EventType,
+				AdditionalContext: lockContextPrompt(locks),
+			},
+		}
+	case "PreToolUse":
+	default:
+		return nil
+	}
+
+	toolName, _ := event.Data["tool_name"].(string)
+	input, _ := event.Data["tool_input"].(map[string]interface{})
+	var targets []string
+	shell := false
+	switch {
+	case fileEditTools[toolName]:
+		for _, key := range []string{"file_path", "notebook_path"} {
+			if target, ok := input[key].(string); ok && target != "" {
+				targets = append(targets, target)
+			}
+		}
+	case toolName == "Bash":
+		command, _ := input["command"].(string)
+		targets = commandPathArgs(command)
+		shell = true
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	locked := s.FindLockedPaths(event.WorkingDirectory, targets)
+	if len(locked) == 0 {
+		return nil
+	}
+
+	reason := lockedPathsMessage(locked)
+	if !shell && PathLockMode() == PathLockModeBlock {
+		logger.Infof("🔒 Blocked %s of locked %s", toolName, locked[0].Path)
+		return &models.ClaudeHookResponse{
+			HookSpecificOutput: &models.ClaudeHookSpecificOutput{
+				HookEventName:            event.EventType,
+				PermissionDecision:       "deny",
+				PermissionDecisionReason: reason + " Leave these files alone; ask the user if you need them changed.",
+			},
+		}
+	}
+	logger.Infof("🔒 Warned about %s of locked %s", toolName, locked[0].Path)
+	return &models.ClaudeHookResponse{
+		HookSpecificOutput: &models.ClaudeHookSpecificOutput{
+			HookEventName:     event.EventType,
+			AdditionalContext: reason + " Avoid modifying them and check with the user before overwriting their work.",
+		},
+		SystemMessage: fmt.Sprintf("⚠️ Claude is using %s on locked %s", toolName, locked[0].Path),
+	}
+}
+
+// commandPathArgs returns the words of a shell command that could be paths
+func commandPathArgs(command string) []string {
+	var args []string
+	for _, word := range strings.FieldsFunc(command, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == ';' || r == '|' || r == '&' || r == '>' || r == '<' || r == '(' || r == ')'
+	}) {
+		word = strings.Trim(word, `"'`)
+		if word == "" || strings.HasPrefix(word, "-") || strings.Contains(word, "=") || strings.Contains(word, "$") {
+			continue
+		}
+		args = append(args, word)
+	}
+	return args
+}
+
+// lockedPathsMessage explains which locks cover the paths
+func lockedPathsMessage(locked []LockedPath) string {
+	var b strings.Builder
+	b.WriteString("Locked in this worktree:")
+	seen := make(map[string]bool)
+	for _, item := range locked {
+		if seen[item.Path] {
+			continue
+		}
+		seen[item.Path] = true
+		fmt.Fprintf(&b, " %s (%s).", item.Path, describeLock(item.Lock))
+	}
+	return b.String()
+}
+
+// describeLock summarizes who holds a lock and why
+func describeLock(lock models.WorktreeLock) string {
+	description := lock.Reason
+	if lock.Owner != "" {
+		description = lock.Owner + ": " + description
+	}
+	if lock.ExpiresAt != nil {
+		description += ", until " + lock.ExpiresAt.Format(time.RFC3339)
+	}
+	return description
+}
+
+// lockContextPrompt lists a worktree's locks for Claude
+func lockContextPrompt(locks []models.WorktreeLock) string {
+	var b strings.Builder
+	b.WriteString("Someone is working on these paths in this worktree. Don't modify them, and check with the user before touching anything they cover:\n")
+	for _, lock := range locks {
+		fmt.Fprintf(&b, "- %s (%s)\n", strings.Join(lock.Paths, ", "), describeLock(lock))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
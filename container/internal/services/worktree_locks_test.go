@@ -0,0 +1,142 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestLockPatternMatches(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"go.mod", "go.mod", true},
+		{"go.mod", "sub/go.mod", false},
+		{"src/auth", "src/auth/middleware.go", true},
+		{"src/auth", "src/authz/policy.go", false},
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/auth/main.go", false},
+		{"docs/**/*.md", "docs/guide/setup/intro.md", true},
+		{"docs/**/*.md", "docs/README.md", true},
+		{"**", "anything/at/all", true},
+	} {
+		assert.Equal(t, tc.matches, lockPatternMatches(tc.pattern, tc.path), "%s vs %s", tc.pattern, tc.path)
+	}
+}
+
+func TestWorktreeLocks(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-locks", RepoID: "acme/app", Name: "feature", Path: dir}))
+
+	auth, err := service.AddWorktreeLock("wt-locks", models.WorktreeLock{
+		Paths: []string{"./src/auth/", " go.mod "}, Reason: "  Refactoring\n auth ", Owner: "alice",
+	}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/auth", "go.mod"}, auth.Paths)
+	assert.Equal(t, "Refactoring auth", auth.Reason)
+	assert.Nil(t, auth.ExpiresAt)
+
+	docs, err := service.AddWorktreeLock("wt-locks", models.WorktreeLock{Paths: []string{"docs/**"}, Reason: "Release notes"}, time.Hour)
+	require.NoError(t, err)
+	require.NotNil(t, docs.ExpiresAt)
+
+	for _, invalid := range []models.WorktreeLock{
+		{Reason: "no paths"},
+		{Paths: []string{"src"}},
+		{Paths: []string{"/etc/passwd"}, Reason: "absolute"},
+		{Paths: []string{"../other"}, Reason: "outside"},
+		{Paths: []string{"src/[a"}, Reason: "bad glob"},
+	} {
+		_, err := service.AddWorktreeLock("wt-locks", invalid, 0)
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err), "%+v", invalid)
+	}
+	_, err = service.AddWorktreeLock("missing", models.WorktreeLock{Paths: []string{"x"}, Reason: "x"}, 0)
+	assert.Equal(t, models.ErrCodeWorktreeNotFound, models.ErrorCodeOf(err))
+
+	locks, err := service.WorktreeLocks("wt-locks")
+	require.NoError(t, err)
+	require.Len(t, locks, 2)
+	assert.Equal(t, docs.ID, locks[0].ID, "newest first")
+
+	locked := service.FindLockedPaths(filepath.Join(dir, "src"), []string{
+		"auth/session.go", filepath.Join(dir, "go.mod"), "main.go", "../docs/guide.md", "/elsewhere/go.mod",
+	})
+	require.Len(t, locked, 3)
+	assert.Equal(t, "src/auth/session.go", locked[0].Path)
+	assert.Equal(t, auth.ID, locked[0].Lock.ID)
+	assert.Equal(t, "go.mod", locked[1].Path)
+	assert.Equal(t, "docs/guide.md", locked[2].Path)
+	assert.Empty(t, service.FindLockedPaths(t.TempDir(), []string{"go.mod"}), "outside any worktree")
+
+	// Expired locks are ignored and dropped on the next change
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, service.stateManager.UpdateWorktree("wt-locks", map[string]interface{}{"locks": []models.WorktreeLock{
+		*auth, {ID: "old", Paths: []string{"main.go"}, Reason: "done", ExpiresAt: &expired},
+	}}))
+	assert.Empty(t, service.FindLockedPaths(dir, []string{"main.go"}))
+	locks, _ = service.WorktreeLocks("wt-locks")
+	assert.Len(t, locks, 1)
+
+	require.NoError(t, service.RemoveWorktreeLock("wt-locks", auth.ID))
+	assert.Error(t, service.RemoveWorktreeLock("wt-locks", auth.ID))
+	worktree, _ := service.stateManager.GetWorktree("wt-locks")
+	assert.Empty(t, worktree.Locks)
+}
+
+func TestPathLockHookResponse(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-hook", RepoID: "acme/app", Name: "feature", Path: dir}))
+
+	toolUse := func(tool string, input map[string]interface{}) *models.ClaudeHookEvent {
+		return &models.ClaudeHookEvent{
+			EventType:        "PreToolUse",
+			WorkingDirectory: dir,
+			Data:             map[string]interface{}{"tool_name": tool, "tool_input": input},
+		}
+	}
+	prompt := &models.ClaudeHookEvent{EventType: "UserPromptSubmit", WorkingDirectory: dir}
+	edit := toolUse("Edit", map[string]interface{}{"file_path": filepath.Join(dir, "src/auth/session.go")})
+
+	assert.Nil(t, service.PathLockHookResponse(prompt), "nothing locked")
+	assert.Nil(t, service.PathLockHookResponse(edit))
+
+	_, err := service.AddWorktreeLock("wt-hook", models.WorktreeLock{Paths: []string{"src/auth/**"}, Reason: "Refactoring auth", Owner: "alice"}, 0)
+	require.NoError(t, err)
+
+	context := service.PathLockHookResponse(prompt)
+	require.NotNil(t, context)
+	assert.Equal(t, "UserPromptSubmit", context.HookSpecificOutput.HookEventName)
+	assert.Contains(t, context.HookSpecificOutput.AdditionalContext, "- src/auth/** (alice: Refactoring auth)")
+
+	t.Setenv(PathLockModeSetting, "")
+	warning := service.PathLockHookResponse(edit)
+	require.NotNil(t, warning)
+	assert.Empty(t, warning.HookSpecificOutput.PermissionDecision)
+	assert.Contains(t, warning.HookSpecificOutput.AdditionalContext, "src/auth/session.go (alice: Refactoring auth)")
+	assert.Contains(t, warning.SystemMessage, "Edit on locked src/auth/session.go")
+
+	t.Setenv(PathLockModeSetting, PathLockModeBlock)
+	denial := service.PathLockHookResponse(edit)
+	require.NotNil(t, denial)
+	assert.Equal(t, "deny", denial.HookSpecificOutput.PermissionDecision)
+	assert.Contains(t, denial.HookSpecificOutput.PermissionDecisionReason, "src/auth/session.go")
+
+	// Shell commands mentioning a locked path are only warned about
+	shell := service.PathLockHookResponse(toolUse("Bash", map[string]interface{}{"command": "sed -i 's/a/b/' 'src/auth/session.go' && go test ./..."}))
+	require.NotNil(t, shell)
+	assert.Empty(t, shell.HookSpecificOutput.PermissionDecision)
+	assert.Nil(t, service.PathLockHookResponse(toolUse("Bash", map[string]interface{}{"command": "go test ./..."})))
+
+	assert.Nil(t, service.PathLockHookResponse(toolUse("Read", map[string]interface{}{"file_path": filepath.Join(dir, "src/auth/session.go")})), "reads are fine")
+	assert.Nil(t, service.PathLockHookResponse(toolUse("Write", map[string]interface{}{"file_path": filepath.Join(dir, "README.md")})))
+}
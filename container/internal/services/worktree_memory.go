@@ -0,0 +1,191 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Worktree memory kinds, in the order they are given to Claude
+const (
+	MemoryKindConvention = "convention"
+	MemoryKindDecision   = "decision"
+	MemoryKindFact       = "fact"
+)
+
+// Who added a memory
+const (
+	MemorySourceClaude = "claude"
+	MemorySourceUser   = "user"
+)
+
+const (
+	// maxWorktreeMemories caps how many memories a worktree keeps
+	maxWorktreeMemories = 50
+	// worktreeMemoryMaxText caps a single memory
+	worktreeMemoryMaxText = 500
+	// memoryContextMaxChars caps the memories given to a new session; the
+	// oldest entries of each kind are left out first
+	memoryContextMaxChars = 4000
+)
+
+// memoryKindHeadings are the headings memories are grouped under in Claude's context
+var memoryKindHeadings = map[string]string{
+	MemoryKindConvention: "Conventions",
+	MemoryKindDecision:   "Decisions",
+	MemoryKindFact:       "Facts",
+}
+
+// normalizeMemoryText collapses whitespace so a memory is a single line
+func normalizeMemoryText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// AddWorktreeMemory remembers a fact, decision or convention in a worktree.
+// Every new Claude session there gets the worktree's memories as context.
+// Restating an existing memory updates its kind and moves it to the front
+// instead of adding a duplicate.
+func (s *GitService) AddWorktreeMemory(worktreeID string, memory models.WorktreeMemory) (*models.WorktreeMemory, error) {
+	memory.Text = normalizeMemoryText(memory.Text)
+	if memory.Kind == "" {
+		memory.Kind = MemoryKindFact
+	}
+	if memory.Source == "" {
+		memory.Source = MemorySourceUser
+	}
+	switch {
+	case memory.Text == "":
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "memory text is required")
+	case len(memory.Text) > worktreeMemoryMaxText:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "memory is %d characters; keep it under %d", len(memory.Text), worktreeMemoryMaxText)
+	case memoryKindHeadings[memory.Kind] == "":
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid memory kind %q: use fact, decision or convention", memory.Kind)
+	case memory.Source != MemorySourceClaude && memory.Source != MemorySourceUser:
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "invalid memory source %q: use claude or user", memory.Source)
+	}
+
+	s.memoryMu.Lock()
+	defer s.memoryMu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+
+	memories := make([]models.WorktreeMemory, 0, len(worktree.Memory)+1)
+	for _, existing := range worktree.Memory {
+		if strings.EqualFold(existing.Text, memory.Text) {
+			memory.ID = existing.ID
+			continue
+		}
+		memories = append(memories, existing)
+	}
+	if memory.ID == "" {
+		if len(memories) >= maxWorktreeMemories {
+			return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s already remembers %d things", worktree.Name, maxWorktreeMemories).
+				WithHint("Remove memories that no longer apply first")
+		}
+		memory.ID = uuid.New().String()
+	}
+	memory.UpdatedAt = time.Now()
+	memories = append(memories, memory)
+
+	if err := s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"memory": memories}); err != nil {
+		return nil, err
+	}
+	logger.Infof("🧠 Worktree %s remembers (%s, from %s): %s", worktree.Name, memory.Kind, memory.Source, memory.Text)
+	return &memory, nil
+}
+
+// RemoveWorktreeMemory forgets a memory
+func (s *GitService) RemoveWorktreeMemory(worktreeID, memoryID string) error {
+	s.memoryMu.Lock()
+	defer s.memoryMu.Unlock()
+
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return models.NewWorktreeNotFoundError(worktreeID)
+	}
+	memories := make([]models.WorktreeMemory, 0, len(worktree.Memory))
+	for _, memory := range worktree.Memory {
+		if memory.ID != memoryID {
+			memories = append(memories, memory)
+		}
+	}
+	if len(memories) == len(worktree.Memory) {
+		return fmt.Errorf("worktree %s has no memory %s", worktree.Name, memoryID)
+	}
+	return s.stateManager.UpdateWorktree(worktreeID, map[string]interface{}{"memory": memories})
+}
+
+// WorktreeMemory returns a worktree's memories, most recent first
+func (s *GitService) WorktreeMemory(worktreeID string) ([]models.WorktreeMemory, error) {
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	if !exists {
+		return nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	memories := append([]models.WorktreeMemory{}, worktree.Memory...)
+	sort.SliceStable(memories, func(i, j int) bool {
+		return memories[i].UpdatedAt.After(memories[j].UpdatedAt)
+	})
+	return memories, nil
+}
+
+// MemoryContextForPath returns the memories of the worktree at path, formatted
+// as context for a new Claude session, with how to add to them. It returns ""
+// for paths that aren't a worktree.
+func (s *GitService) MemoryContextForPath(worktreePath string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, worktree := range s.stateManager.GetAllWorktrees() {
+		if worktree.Path == worktreePath {
+			return memoryContextPrompt(worktree.Memory)
+		}
+	}
+	return ""
+}
+
+// memoryContextPrompt groups memories by kind, most recent first, and explains
+// how Claude can remember more
+func memoryContextPrompt(memories []models.WorktreeMemory) string {
+	var b strings.Builder
+	if len(memories) > 0 {
+		b.WriteString("Memory saved in this worktree by earlier sessions. Follow it unless the user says otherwise.\n")
+
+		sorted := append([]models.WorktreeMemory{}, memories...)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].UpdatedAt.After(sorted[j].UpdatedAt)
+		})
+		omitted := 0
+		for _, kind := range []string{MemoryKindConvention, MemoryKindDecision, MemoryKindFact} {
+			heading := false
+			for _, memory := range sorted {
+				if memory.Kind != kind {
+					continue
+				}
+				if b.Len()+len(memory.Text) > memoryContextMaxChars {
+					omitted++
+					continue
+				}
+				if !heading {
+					fmt.Fprintf(&b, "\n%s:\n", memoryKindHeadings[kind])
+					heading = true
+				}
+				fmt.Fprintf(&b, "- %s\n", memory.Text)
+			}
+		}
+		if omitted > 0 {
+			fmt.Fprintf(&b, "\n(%d older memories left out; run `catnip memory list` to see them)\n", omitted)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("To remember a fact, decision or convention for future sessions in this worktree " +
+		"(for example when the user corrects you), run `catnip memory add --kind convention \"Use pnpm, not npm\"`.")
+	return b.String()
+}
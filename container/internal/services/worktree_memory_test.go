@@ -0,0 +1,97 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestWorktreeMemory(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-memory", RepoID: "acme/app", Name: "feature", Path: dir}))
+
+	pnpm, err := service.AddWorktreeMemory("wt-memory", models.WorktreeMemory{Text: "  Use pnpm,\n not npm ", Kind: MemoryKindConvention, Source: MemorySourceClaude})
+	require.NoError(t, err)
+	assert.Equal(t, "Use pnpm, not npm", pnpm.Text)
+	assert.NotEmpty(t, pnpm.ID)
+
+	_, err = service.AddWorktreeMemory("wt-memory", models.WorktreeMemory{Text: "The staging database is read-only"})
+	require.NoError(t, err)
+
+	// Restating a memory updates it rather than duplicating it
+	restated, err := service.AddWorktreeMemory("wt-memory", models.WorktreeMemory{Text: "use PNPM, not npm", Kind: MemoryKindDecision})
+	require.NoError(t, err)
+	assert.Equal(t, pnpm.ID, restated.ID)
+
+	memories, err := service.WorktreeMemory("wt-memory")
+	require.NoError(t, err)
+	require.Len(t, memories, 2)
+	assert.Equal(t, "use PNPM, not npm", memories[0].Text, "most recent first")
+	assert.Equal(t, MemoryKindDecision, memories[0].Kind)
+	assert.Equal(t, MemorySourceUser, memories[1].Source)
+
+	for _, invalid := range []models.WorktreeMemory{
+		{Text: "   "},
+		{Text: "x", Kind: "rumor"},
+		{Text: "x", Source: "someone"},
+		{Text: strings.Repeat("x", worktreeMemoryMaxText+1)},
+	} {
+		_, err := service.AddWorktreeMemory("wt-memory", invalid)
+		assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+	}
+	_, err = service.AddWorktreeMemory("missing", models.WorktreeMemory{Text: "x"})
+	assert.Equal(t, models.ErrCodeWorktreeNotFound, models.ErrorCodeOf(err))
+
+	require.NoError(t, service.RemoveWorktreeMemory("wt-memory", pnpm.ID))
+	assert.Error(t, service.RemoveWorktreeMemory("wt-memory", pnpm.ID))
+	memories, _ = service.WorktreeMemory("wt-memory")
+	assert.Len(t, memories, 1)
+}
+
+func TestWorktreeMemoryFull(t *testing.T) {
+	service := createTestGitService(t)
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: t.TempDir()}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-full", RepoID: "acme/app", Name: "full", Path: t.TempDir()}))
+	for i := 0; i < maxWorktreeMemories; i++ {
+		_, err := service.AddWorktreeMemory("wt-full", models.WorktreeMemory{Text: fmt.Sprintf("fact %d", i)})
+		require.NoError(t, err)
+	}
+
+	_, err := service.AddWorktreeMemory("wt-full", models.WorktreeMemory{Text: "one too many"})
+	assert.Equal(t, models.ErrCodeInvalidRequest, models.ErrorCodeOf(err))
+	_, err = service.AddWorktreeMemory("wt-full", models.WorktreeMemory{Text: "fact 3", Kind: MemoryKindConvention})
+	assert.NoError(t, err, "restating still works when full")
+}
+
+func TestMemoryContext(t *testing.T) {
+	service := createTestGitService(t)
+	dir := t.TempDir()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: dir}))
+	require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{ID: "wt-context", RepoID: "acme/app", Name: "feature", Path: dir}))
+	assert.Empty(t, service.MemoryContextForPath(t.TempDir()))
+	assert.Contains(t, service.MemoryContextForPath(dir), "catnip memory add", "Claude learns how to remember before there's anything to remember")
+
+	now := time.Now()
+	context := memoryContextPrompt([]models.WorktreeMemory{
+		{Kind: MemoryKindFact, Text: "CI runs on Node 20", UpdatedAt: now.Add(-time.Hour)},
+		{Kind: MemoryKindConvention, Text: "Use pnpm, not npm", UpdatedAt: now.Add(-2 * time.Hour)},
+		{Kind: MemoryKindFact, Text: "The staging database is read-only", UpdatedAt: now},
+	})
+	assert.Contains(t, context, "Conventions:\n- Use pnpm, not npm\n\nFacts:\n- The staging database is read-only\n- CI runs on Node 20\n")
+	assert.NotContains(t, context, "Decisions:")
+
+	var many []models.WorktreeMemory
+	for i := 0; i < maxWorktreeMemories; i++ {
+		many = append(many, models.WorktreeMemory{Kind: MemoryKindFact, Text: strings.Repeat("x", 400), UpdatedAt: now})
+	}
+	context = memoryContextPrompt(many)
+	assert.Less(t, len(context), memoryContextMaxChars+500)
+	assert.Contains(t, context, "older memories left out")
+}
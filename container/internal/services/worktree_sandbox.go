@@ -0,0 +1,417 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// Overlay mount operations, replaced in tests
+var (
+	mountSandbox   = mountOverlay
+	unmountSandbox = unmountOverlay
+	sandboxMounted = overlayMounted
+)
+
+// WorkspaceSessions finds and closes the terminal sessions running in a
+// workspace. Open sessions keep a sandbox's overlay busy, and ones started
+// before the overlay was mounted write past it.
+type WorkspaceSessions interface {
+	WorkspaceSessionCount(workDir string) int
+	CloseWorkspaceSessions(workDir string) int
+}
+
+// SandboxStatus describes a worktree's sandbox and what changed in it
+type SandboxStatus struct {
+	Sandbox *models.WorktreeSandbox `json:"sandbox"`
+	// Whether the overlay is mounted (it isn't after a container restart until the sandbox is started again)
+	Mounted bool `json:"mounted"`
+	// Files and symlinks written in the sandbox, relative to the worktree
+	Changed []string `json:"changed"`
+	// Paths deleted in the sandbox; directories that were replaced end in a slash
+	Deleted []string `json:"deleted"`
+	// Size of the changed files
+	Bytes int64 `json:"bytes" example:"20480"`
+}
+
+// SetWorkspaceSessions connects the terminal sessions sandboxes close before
+// they are promoted or discarded
+func (s *GitService) SetWorkspaceSessions(sessions WorkspaceSessions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workspaceSessions = sessions
+}
+
+// StartSandbox mounts an overlay over a worktree so every change made in it,
+// untracked and ignored files included, lands in a separate layer until it is
+// promoted or discarded. Starting a sandbox that exists mounts it again if a
+// container restart unmounted it.
+func (s *GitService) StartSandbox(worktreeID string) (*models.Worktree, error) {
+	worktree, repo, err := s.sandboxWorktree(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Runtime.IsContainerized() {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "sandboxes are only available in containerized mode")
+	}
+	if worktree.Hibernation != nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is hibernated", worktree.Name).
+			WithHint("Rehydrate the worktree before sandboxing it")
+	}
+	if worktree.Sandbox != nil && sandboxMounted(worktree.Path) {
+		return worktree, nil
+	}
+	if count := s.workspaceSessionCount(worktree.Path); count > 0 {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s has %d open terminal sessions", worktree.Name, count).
+			WithHint("Close the worktree's terminals before starting a sandbox; they would write around it")
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpSandbox)
+	defer release()
+
+	sandbox := worktree.Sandbox
+	if sandbox == nil {
+		sandbox, err = s.newSandbox(worktree)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.worktreeCache.RemoveWorktree(worktree.ID, worktree.Path)
+	err = mountSandbox(worktree.Path, filepath.Join(sandbox.Dir, "upper"), filepath.Join(sandbox.Dir, "work"), worktree.Path)
+	s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
+	if err != nil {
+		if worktree.Sandbox == nil {
+			_ = os.RemoveAll(sandbox.Dir)
+		}
+		return nil, fmt.Errorf("failed to sandbox %s: %v", worktree.Name, err)
+	}
+
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"sandbox": sandbox}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("🧪 Sandboxed %s at %s, changes go to %s", worktree.Name, sandbox.Head, sandbox.Dir)
+	updated, _ := s.stateManager.GetWorktree(worktree.ID)
+	return updated, nil
+}
+
+// GetSandbox reports a worktree's sandbox and the paths changed in it
+func (s *GitService) GetSandbox(worktreeID string) (*SandboxStatus, error) {
+	worktree, _, err := s.sandboxWorktree(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if worktree.Sandbox == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is not sandboxed", worktree.Name)
+	}
+	status, err := sandboxChanges(filepath.Join(worktree.Sandbox.Dir, "upper"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sandbox of %s: %v", worktree.Name, err)
+	}
+	status.Sandbox = worktree.Sandbox
+	status.Mounted = sandboxMounted(worktree.Path)
+	return status, nil
+}
+
+// PromoteSandbox closes the worktree's terminals, unmounts its sandbox and
+// applies the sandbox's changes to the worktree. Applying is idempotent, so a
+// promotion that fails partway can be retried.
+func (s *GitService) PromoteSandbox(worktreeID string) (*models.Worktree, error) {
+	worktree, repo, err := s.sandboxWorktree(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	sandbox := worktree.Sandbox
+	if sandbox == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is not sandboxed", worktree.Name)
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpSandbox)
+	defer release()
+
+	if err := s.unmountWorktreeSandbox(worktree); err != nil {
+		return nil, err
+	}
+	defer s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
+
+	if err := applySandboxUpper(filepath.Join(sandbox.Dir, "upper"), worktree.Path); err != nil {
+		return nil, fmt.Errorf("failed to promote sandbox of %s, promote again to finish: %v", worktree.Name, err)
+	}
+	if err := os.RemoveAll(sandbox.Dir); err != nil {
+		logger.Warnf("⚠️ Failed to remove promoted sandbox %s: %v", sandbox.Dir, err)
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"sandbox": (*models.WorktreeSandbox)(nil)}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("🧪 Promoted sandbox of %s", worktree.Name)
+	updated, _ := s.stateManager.GetWorktree(worktree.ID)
+	return updated, nil
+}
+
+// DiscardSandbox closes the worktree's terminals, unmounts its sandbox and
+// drops its changes. Commits made in the sandbox are discarded too: the branch
+// and index go back to where they were when the sandbox started.
+func (s *GitService) DiscardSandbox(worktreeID string) (*models.Worktree, error) {
+	worktree, repo, err := s.sandboxWorktree(worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	sandbox := worktree.Sandbox
+	if sandbox == nil {
+		return nil, models.NewAPIError(models.ErrCodeInvalidRequest, "worktree %s is not sandboxed", worktree.Name)
+	}
+
+	release := s.acquireRepoSlot(repo.ID, repoOpSandbox)
+	defer release()
+
+	if err := s.unmountWorktreeSandbox(worktree); err != nil {
+		return nil, err
+	}
+	defer s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
+
+	s.restoreSandboxGitState(worktree, sandbox)
+	if err := os.RemoveAll(sandbox.Dir); err != nil {
+		return nil, fmt.Errorf("failed to remove sandbox %s: %v", sandbox.Dir, err)
+	}
+	if err := s.stateManager.UpdateWorktree(worktree.ID, map[string]interface{}{"sandbox": (*models.WorktreeSandbox)(nil)}); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("🧪 Discarded sandbox of %s", worktree.Name)
+	updated, _ := s.stateManager.GetWorktree(worktree.ID)
+	return updated, nil
+}
+
+// sandboxWorktree looks up a worktree and its repository
+func (s *GitService) sandboxWorktree(worktreeID string) (*models.Worktree, *models.Repository, error) {
+	s.mu.RLock()
+	worktree, exists := s.stateManager.GetWorktree(worktreeID)
+	s.mu.RUnlock()
+
+	if !exists {
+		return nil, nil, models.NewWorktreeNotFoundError(worktreeID)
+	}
+	repo, exists := s.stateManager.GetRepository(worktree.RepoID)
+	if !exists {
+		return nil, nil, models.NewRepositoryNotFoundError(worktree.RepoID)
+	}
+	return worktree, repo, nil
+}
+
+// newSandbox creates the overlay directories for a worktree and records the
+// git state discarding restores: HEAD, its ref and a copy of the index
+func (s *GitService) newSandbox(worktree *models.Worktree) (*models.WorktreeSandbox, error) {
+	head, err := s.operations.GetCommitHash(worktree.Path, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HEAD of %s: %v", worktree.Name, err)
+	}
+	sandbox := &models.WorktreeSandbox{
+		StartedAt: time.Now(),
+		Dir:       filepath.Join(config.Runtime.VolumeDir, "sandboxes", worktree.ID),
+		Head:      head,
+	}
+	if output, err := s.operations.ExecuteGit(worktree.Path, "symbolic-ref", "-q", "HEAD"); err == nil {
+		sandbox.Ref = strings.TrimSpace(string(output))
+	}
+
+	// Leftovers of a sandbox that was never recorded have nothing worth keeping
+	_ = os.RemoveAll(sandbox.Dir)
+	for _, dir := range []string{"upper", "work"} {
+		if err := os.MkdirAll(filepath.Join(sandbox.Dir, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create sandbox directory: %v", err)
+		}
+	}
+	if gitDir, err := s.worktreeGitDir(worktree); err == nil {
+		if err := copyFile(filepath.Join(gitDir, "index"), filepath.Join(sandbox.Dir, "index")); err != nil && !os.IsNotExist(err) {
+			logger.Warnf("⚠️ Failed to save index of %s, discarding won't restore it: %v", worktree.Name, err)
+		}
+	}
+	return sandbox, nil
+}
+
+// unmountWorktreeSandbox closes the worktree's terminals, stops watching it
+// and unmounts its overlay if it is mounted
+func (s *GitService) unmountWorktreeSandbox(worktree *models.Worktree) error {
+	s.mu.RLock()
+	sessions := s.workspaceSessions
+	s.mu.RUnlock()
+	if sessions != nil {
+		if closed := sessions.CloseWorkspaceSessions(worktree.Path); closed > 0 {
+			logger.Infof("🧪 Closed %d terminal sessions in sandboxed %s", closed, worktree.Name)
+		}
+	}
+	s.worktreeCache.RemoveWorktree(worktree.ID, worktree.Path)
+	if !sandboxMounted(worktree.Path) {
+		return nil
+	}
+	if err := unmountSandbox(worktree.Path); err != nil {
+		s.worktreeCache.AddWorktree(worktree.ID, worktree.Path)
+		return models.NewAPIError(models.ErrCodeInvalidRequest, "failed to unmount sandbox of %s: %v", worktree.Name, err).
+			WithHint("Stop processes still running in the worktree, such as dev servers, and try again")
+	}
+	return nil
+}
+
+// dropSandbox unmounts and removes a worktree's sandbox without restoring
+// anything, for worktrees being deleted
+func (s *GitService) dropSandbox(worktree *models.Worktree) {
+	if worktree.Sandbox == nil {
+		return
+	}
+	if sandboxMounted(worktree.Path) {
+		if err := unmountSandbox(worktree.Path); err != nil {
+			logger.Warnf("⚠️ Failed to unmount sandbox of %s: %v", worktree.Name, err)
+		}
+	}
+	_ = os.RemoveAll(worktree.Sandbox.Dir)
+}
+
+// restoreSandboxGitState moves the worktree's branch (or detached HEAD) back to
+// the commit the sandbox started at and restores the saved index
+func (s *GitService) restoreSandboxGitState(worktree *models.Worktree, sandbox *models.WorktreeSandbox) {
+	ref := sandbox.Ref
+	args := []string{"update-ref", "-m", "catnip: discard sandbox", ref, sandbox.Head}
+	if ref == "" {
+		ref = "HEAD"
+		args = []string{"update-ref", "--no-deref", "-m", "catnip: discard sandbox", ref, sandbox.Head}
+	}
+	if head, err := s.operations.GetCommitHash(worktree.Path, ref); err == nil && head != sandbox.Head {
+		if _, err := s.operations.ExecuteGit(worktree.Path, args...); err != nil {
+			logger.Warnf("⚠️ Failed to reset %s of %s to %s: %v", ref, worktree.Name, sandbox.Head, err)
+		} else {
+			logger.Infof("🧪 Reset %s of %s from %s to %s", ref, worktree.Name, head, sandbox.Head)
+		}
+	}
+	if sandbox.Ref != "" {
+		_, _ = s.operations.ExecuteGit(worktree.Path, "symbolic-ref", "HEAD", sandbox.Ref)
+	}
+
+	gitDir, err := s.worktreeGitDir(worktree)
+	if err != nil {
+		return
+	}
+	if err := copyFile(filepath.Join(sandbox.Dir, "index"), filepath.Join(gitDir, "index")); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("⚠️ Failed to restore index of %s: %v", worktree.Name, err)
+	}
+}
+
+// worktreeGitDir returns the worktree's git directory, which lives outside the
+// worktree and so outside its sandbox
+func (s *GitService) worktreeGitDir(worktree *models.Worktree) (string, error) {
+	output, err := s.operations.ExecuteGit(worktree.Path, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// workspaceSessionCount counts the terminal sessions open in a workspace
+func (s *GitService) workspaceSessionCount(workDir string) int {
+	s.mu.RLock()
+	sessions := s.workspaceSessions
+	s.mu.RUnlock()
+	if sessions == nil {
+		return 0
+	}
+	return sessions.WorkspaceSessionCount(workDir)
+}
+
+// sandboxChanges lists the paths an overlay's upper directory changes
+func sandboxChanges(upper string) (*SandboxStatus, error) {
+	status := &SandboxStatus{Changed: []string{}, Deleted: []string{}}
+	err := filepath.WalkDir(upper, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == upper {
+			return err
+		}
+		rel, _ := filepath.Rel(upper, path)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case isOverlayWhiteout(info):
+			status.Deleted = append(status.Deleted, rel)
+		case entry.IsDir():
+			if isOverlayOpaque(path) {
+				status.Deleted = append(status.Deleted, rel+"/")
+			}
+		default:
+			status.Changed = append(status.Changed, rel)
+			if info.Mode().IsRegular() {
+				status.Bytes += info.Size()
+			}
+		}
+		return nil
+	})
+	sort.Strings(status.Changed)
+	sort.Strings(status.Deleted)
+	return status, err
+}
+
+// applySandboxUpper applies an unmounted overlay's upper directory to its
+// lower directory: whiteouts delete, opaque directories replace the lower
+// directory, and files and symlinks overwrite. Each file is replaced by a
+// rename, so an interrupted run leaves whole files and can be repeated.
+func applySandboxUpper(upper, lower string) error {
+	return filepath.WalkDir(upper, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == upper {
+			return err
+		}
+		rel, _ := filepath.Rel(upper, path)
+		target := filepath.Join(lower, rel)
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case isOverlayWhiteout(info):
+			return os.RemoveAll(target)
+		case entry.IsDir():
+			existing, err := os.Lstat(target)
+			if err == nil && (!existing.IsDir() || isOverlayOpaque(path)) {
+				if err := os.RemoveAll(target); err != nil {
+					return err
+				}
+			}
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(target); err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			if existing, err := os.Lstat(target); err == nil && existing.IsDir() {
+				if err := os.RemoveAll(target); err != nil {
+					return err
+				}
+			}
+			tmp := filepath.Join(filepath.Dir(target), ".catnip-sandbox-"+filepath.Base(target))
+			if err := copyFile(path, tmp); err != nil {
+				_ = os.Remove(tmp)
+				return err
+			}
+			return os.Rename(tmp, target)
+		default:
+			logger.Warnf("⚠️ Skipping special file %s in sandbox", rel)
+			return nil
+		}
+	})
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"syscall"
+)
+
+// mountOverlay mounts an overlay of upper over lower at target. Redirects and
+// metacopy stay off so every change is a full file or whiteout in upper.
+func mountOverlay(lower, upper, work, target string) error {
+	options := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s,redirect_dir=off,metacopy=off", lower, upper, work)
+	if err := syscall.Mount("overlay", target, "overlay", 0, options); err != nil {
+		return fmt.Errorf("mount overlay on %s: %v", target, err)
+	}
+	return nil
+}
+
+// unmountOverlay unmounts the overlay at target
+func unmountOverlay(target string) error {
+	if err := syscall.Unmount(target, 0); err != nil {
+		if err == syscall.EBUSY {
+			return fmt.Errorf("%s is still in use by a process", target)
+		}
+		return fmt.Errorf("unmount %s: %v", target, err)
+	}
+	return nil
+}
+
+// overlayMounted reports whether an overlay is mounted at target
+func overlayMounted(target string) bool {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// ID parent major:minor root mountpoint options [optional...] - fstype source options
+		fields, rest, _ := strings.Cut(scanner.Text(), " - ")
+		parts := strings.Fields(fields)
+		if len(parts) < 5 || unescapeMountPath(parts[4]) != target {
+			continue
+		}
+		if fstype, _, _ := strings.Cut(rest, " "); fstype == "overlay" {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeMountPath decodes the octal escapes mountinfo uses for spaces and tabs
+func unescapeMountPath(path string) string {
+	for _, escape := range []struct{ from, to string }{{`\040`, " "}, {`\011`, "\t"}, {`\012`, "\n"}, {`\134`, `\`}} {
+		path = strings.ReplaceAll(path, escape.from, escape.to)
+	}
+	return path
+}
+
+// isOverlayWhiteout reports whether an upper directory entry marks a deleted
+// path: overlayfs records deletions as 0/0 character devices
+func isOverlayWhiteout(info fs.FileInfo) bool {
+	if info.Mode()&fs.ModeCharDevice == 0 {
+		return false
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && stat.Rdev == 0
+}
+
+// isOverlayOpaque reports whether an upper directory replaces the lower one
+// entirely, which overlayfs records when a directory is deleted and recreated
+func isOverlayOpaque(path string) bool {
+	value := make([]byte, 1)
+	for _, attr := range []string{"trusted.overlay.opaque", "user.overlay.opaque"} {
+		if n, err := syscall.Getxattr(path, attr, value); err == nil && n == 1 && value[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
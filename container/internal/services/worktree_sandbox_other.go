@@ -0,0 +1,30 @@
+//go:build !linux
+
+package services
+
+import (
+	"errors"
+	"io/fs"
+)
+
+var errOverlayUnsupported = errors.New("overlay sandboxes require Linux")
+
+func mountOverlay(lower, upper, work, target string) error {
+	return errOverlayUnsupported
+}
+
+func unmountOverlay(target string) error {
+	return errOverlayUnsupported
+}
+
+func overlayMounted(target string) bool {
+	return false
+}
+
+func isOverlayWhiteout(info fs.FileInfo) bool {
+	return false
+}
+
+func isOverlayOpaque(path string) bool {
+	return false
+}
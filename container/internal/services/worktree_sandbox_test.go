@@ -0,0 +1,72 @@
+//go:build linux
+
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySandboxUpper(t *testing.T) {
+	lower := t.TempDir()
+	upper := t.TempDir()
+	write := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	write(filepath.Join(lower, "main.go"), "package main\n")
+	write(filepath.Join(lower, "README.md"), "old readme\n")
+	write(filepath.Join(lower, "build", "stale.o"), "object")
+	write(filepath.Join(lower, "cache", "keep.txt"), "kept")
+
+	write(filepath.Join(upper, "README.md"), "new readme\n")
+	write(filepath.Join(upper, "node_modules", "left-pad", "index.js"), "module.exports = pad\n")
+	write(filepath.Join(upper, "cache", "new.txt"), "added")
+	require.NoError(t, os.Symlink("README.md", filepath.Join(upper, "README")))
+
+	// Whiteouts and opaque markers need privileges the test may not have
+	whiteouts := syscall.Mknod(filepath.Join(upper, "main.go"), syscall.S_IFCHR, 0) == nil
+	opaque := false
+	if whiteouts {
+		require.NoError(t, os.MkdirAll(filepath.Join(upper, "build"), 0755))
+		opaque = syscall.Setxattr(filepath.Join(upper, "build"), "trusted.overlay.opaque", []byte("y"), 0) == nil
+	}
+
+	status, err := sandboxChanges(upper)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"README", "README.md", "cache/new.txt", "node_modules/left-pad/index.js"}, status.Changed)
+	assert.Equal(t, int64(len("new readme\n")+len("added")+len("module.exports = pad\n")), status.Bytes)
+
+	// Applying twice leaves the same result, so an interrupted promotion can be retried
+	for i := 0; i < 2; i++ {
+		require.NoError(t, applySandboxUpper(upper, lower))
+	}
+
+	content, err := os.ReadFile(filepath.Join(lower, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "new readme\n", string(content))
+	assert.FileExists(t, filepath.Join(lower, "node_modules", "left-pad", "index.js"))
+	assert.FileExists(t, filepath.Join(lower, "cache", "keep.txt"))
+	assert.FileExists(t, filepath.Join(lower, "cache", "new.txt"))
+	link, err := os.Readlink(filepath.Join(lower, "README"))
+	require.NoError(t, err)
+	assert.Equal(t, "README.md", link)
+
+	if !whiteouts {
+		t.Log("skipping whiteout checks: creating device nodes is not permitted")
+		return
+	}
+	assert.Contains(t, status.Deleted, "main.go")
+	assert.NoFileExists(t, filepath.Join(lower, "main.go"))
+	if opaque {
+		assert.Contains(t, status.Deleted, "build/")
+		assert.NoFileExists(t, filepath.Join(lower, "build", "stale.o"))
+		assert.DirExists(t, filepath.Join(lower, "build"))
+	}
+}
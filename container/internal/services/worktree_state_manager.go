@@ -39,6 +39,7 @@ type WorktreeStateChange struct {
 type PRStateUpdate struct {
 	WorktreeID string
 	PRState    string
+	Feedback   *models.PullRequestFeedback
 }
 
 // WorktreeStateManager manages all worktree state persistently
@@ -59,6 +60,10 @@ type WorktreeStateManager struct {
 
 	// PR state updates from sync manager
 	prUpdateChan chan PRStateUpdate
+
+	// Write-ahead journal for multi-step operations (see state_journal.go)
+	journalMu    sync.Mutex
+	lastRecovery []RecoveryAction
 }
 
 // worktreeFieldState tracks all fields we care about for change detection
@@ -114,9 +119,13 @@ func (wsm *WorktreeStateManager) startPRUpdateProcessor() {
 		logger.Debugf("Processing PR state update for worktree %s: %s", update.WorktreeID, update.PRState)
 
 		// Use UpdateWorktree to safely update the PR state
-		err := wsm.UpdateWorktree(update.WorktreeID, map[string]interface{}{
+		updates := map[string]interface{}{
 			"pull_request_state": update.PRState,
-		})
+		}
+		if update.Feedback != nil {
+			updates["pull_request_feedback"] = update.Feedback
+		}
+		err := wsm.UpdateWorktree(update.WorktreeID, updates)
 
 		if err != nil {
 			logger.Warnf("Failed to update PR state for worktree %s: %v", update.WorktreeID, err)
@@ -128,10 +137,10 @@ func (wsm *WorktreeStateManager) startPRUpdateProcessor() {
 	logger.Debug("PR update processor goroutine stopped")
 }
 
-// SendPRStateUpdate sends a PR state update to the processing channel
-func (wsm *WorktreeStateManager) SendPRStateUpdate(worktreeID, prState string) {
+// SendPRStateUpdate sends a PR state and feedback update to the processing channel
+func (wsm *WorktreeStateManager) SendPRStateUpdate(worktreeID, prState string, feedback *models.PullRequestFeedback) {
 	select {
-	case wsm.prUpdateChan <- PRStateUpdate{WorktreeID: worktreeID, PRState: prState}:
+	case wsm.prUpdateChan <- PRStateUpdate{WorktreeID: worktreeID, PRState: prState, Feedback: feedback}:
 		// Update sent successfully
 	default:
 		logger.Warnf("PR update channel is full, dropping PR state update for worktree %s", worktreeID)
@@ -297,6 +306,10 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 			if v, ok := value.(bool); ok {
 				worktree.HasConflicts = v
 			}
+		case "status_detail":
+			if v, ok := value.(*models.WorktreeStatusDetail); ok {
+				worktree.StatusDetail = v
+			}
 		case "pull_request_url":
 			if v, ok := value.(string); ok {
 				worktree.PullRequestURL = v
@@ -349,6 +362,90 @@ func (wsm *WorktreeStateManager) UpdateWorktree(worktreeID string, updates map[s
 			if v, ok := value.(string); ok {
 				worktree.PullRequestState = v
 			}
+		case "pull_request_feedback":
+			if v, ok := value.(*models.PullRequestFeedback); ok {
+				worktree.PullRequestFeedback = v
+			}
+		case "source_ref":
+			if v, ok := value.(string); ok {
+				worktree.SourceRef = v
+			}
+		case "source_ref_type":
+			if v, ok := value.(models.SourceRefType); ok {
+				worktree.SourceRefType = v
+			}
+		case "identity":
+			if v, ok := value.(*models.GitIdentitySettings); ok {
+				worktree.Identity = v
+			}
+		case "checkpoints":
+			if v, ok := value.(*models.CheckpointSettings); ok {
+				worktree.Checkpoints = v
+			}
+		case "created_from_mirror":
+			if v, ok := value.(bool); ok {
+				worktree.CreatedFromMirror = v
+			}
+		case "mirror_synced_at":
+			if v, ok := value.(*time.Time); ok {
+				worktree.MirrorSyncedAt = v
+			}
+		case "setup_changed_files":
+			if v, ok := value.([]string); ok {
+				worktree.SetupChangedFiles = v
+			}
+		case "linked_issue":
+			if v, ok := value.(*models.LinkedIssue); ok {
+				worktree.LinkedIssue = v
+			}
+		case "imported_branch":
+			if v, ok := value.(string); ok {
+				worktree.ImportedBranch = v
+			}
+		case "source_pull_request":
+			if v, ok := value.(*models.SourcePullRequest); ok {
+				worktree.SourcePullRequest = v
+			}
+		case "ssh_agent_forwarding":
+			if v, ok := value.(*bool); ok {
+				worktree.SSHAgentForwarding = v
+			}
+		case "mdns":
+			if v, ok := value.(*bool); ok {
+				worktree.MDNS = v
+			}
+		case "last_sync":
+			if v, ok := value.(*models.SyncSnapshot); ok {
+				worktree.LastSync = v
+			}
+		case "labels":
+			if v, ok := value.([]string); ok {
+				worktree.Labels = v
+			}
+		case "memory":
+			if v, ok := value.([]models.WorktreeMemory); ok {
+				worktree.Memory = v
+			}
+		case "locks":
+			if v, ok := value.([]models.WorktreeLock); ok {
+				worktree.Locks = v
+			}
+		case "preview":
+			if v, ok := value.(*models.PreviewDeployment); ok {
+				worktree.Preview = v
+			}
+		case "claude_tools":
+			if v, ok := value.(*models.ClaudeToolPolicy); ok {
+				worktree.ClaudeTools = v
+			}
+		case "hibernation":
+			if v, ok := value.(*models.WorktreeHibernation); ok {
+				worktree.Hibernation = v
+			}
+		case "sandbox":
+			if v, ok := value.(*models.WorktreeSandbox); ok {
+				worktree.Sandbox = v
+			}
 		}
 	}
 
@@ -395,6 +492,9 @@ func (wsm *WorktreeStateManager) UpdateWorktreeStatus(worktreeID string, status
 	if status.Branch != "" {
 		updates["branch"] = status.Branch
 	}
+	if status.StatusDetail != nil {
+		updates["status_detail"] = status.StatusDetail
+	}
 
 	return wsm.UpdateWorktree(worktreeID, updates)
 }
@@ -486,6 +586,10 @@ func (wsm *WorktreeStateManager) BatchUpdateWorktrees(updates map[string]map[str
 				if v, ok := value.(bool); ok {
 					worktree.HasConflicts = v
 				}
+			case "status_detail":
+				if v, ok := value.(*models.WorktreeStatusDetail); ok {
+					worktree.StatusDetail = v
+				}
 			case "has_active_claude_session":
 				if v, ok := value.(bool); ok {
 					worktree.HasActiveClaudeSession = v
@@ -544,6 +648,10 @@ func (wsm *WorktreeStateManager) BatchUpdateWorktrees(updates map[string]map[str
 				cached.Branch = v
 				hasGitStatusUpdates = true
 			}
+			if v, ok := worktreeUpdates["status_detail"].(*models.WorktreeStatusDetail); ok {
+				cached.StatusDetail = v
+				hasGitStatusUpdates = true
+			}
 
 			cachedUpdates[worktreeID] = cached
 
@@ -701,6 +809,13 @@ func (wsm *WorktreeStateManager) RestoreState() error {
 			continue
 		}
 
+		// Hibernated worktrees stay without files until rehydrated
+		if worktree.Hibernation != nil {
+			logger.Debugf("💤 Worktree %s is hibernated, skipping", worktree.Name)
+			skippedCount++
+			continue
+		}
+
 		// Check if worktree directory still exists
 		if _, err := os.Stat(worktree.Path); err == nil {
 			logger.Debugf("✅ Worktree %s already exists at %s, no restoration needed", worktree.Name, worktree.Path)
@@ -888,6 +1003,25 @@ func (wsm *WorktreeStateManager) RenameWorktreeBranch(worktreeID, niceBranchName
 
 	logger.Debugf("🔄 Creating nice branch %s for %s", niceBranchName, originalBranch)
 
+	journalID, err := wsm.BeginOperation(JournalEntry{
+		Op:           JournalRenameBranch,
+		WorktreeID:   worktreeID,
+		RepoID:       worktree.RepoID,
+		WorktreePath: worktree.Path,
+		Branch:       originalBranch,
+		NewBranch:    niceBranchName,
+	})
+	if err != nil {
+		logger.Warnf("⚠️ Failed to journal branch rename: %v", err)
+	}
+	// Leave the entry in place if state can't be saved so startup recovery can finish the rename
+	keepJournal := false
+	defer func() {
+		if !keepJournal {
+			wsm.CompleteOperation(journalID)
+		}
+	}()
+
 	// Create the nice branch using git operations (this can be done without holding the lock)
 	currentCommit, err := gitOperations.GetCommitHash(worktree.Path, "HEAD")
 	if err != nil {
@@ -915,6 +1049,7 @@ func (wsm *WorktreeStateManager) RenameWorktreeBranch(worktreeID, niceBranchName
 
 	// Save state directly
 	if err := wsm.saveStateInternal(); err != nil {
+		keepJournal = true
 		return fmt.Errorf("failed to save worktree state: %v", err)
 	}
 
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+// statusDetailKeys remember what a worktree's status detail was computed
+// from, so unchanged parts can be reused on the next cache update
+type statusDetailKeys struct {
+	stashRef  string
+	remoteKey string
+}
+
+// parsePorcelainV2Counts counts files by state in `git status --porcelain=v2` output
+func parsePorcelainV2Counts(output string, detail *models.WorktreeStatusDetail) {
+	detail.StagedCount, detail.ModifiedCount, detail.UntrackedCount, detail.ConflictedCount = 0, 0, 0, 0
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		switch line[0] {
+		case '1', '2':
+			// "1 XY ..." ordinary or "2 XY ..." renamed/copied entry
+			if len(line) < 4 {
+				continue
+			}
+			if line[2] != '.' {
+				detail.StagedCount++
+			}
+			if line[3] != '.' {
+				detail.ModifiedCount++
+			}
+		case 'u':
+			detail.ConflictedCount++
+		case '?':
+			detail.UntrackedCount++
+		}
+	}
+}
+
+// updateStatusDetail refreshes the granular status of a worktree. Work is
+// skipped where the inputs haven't changed: clean worktrees don't run git
+// status, stashes are only recounted when refs/stash moves, and ahead/behind
+// is only recomputed when HEAD or a remote-tracking ref moves.
+func (c *WorktreeStatusCache) updateStatusDetail(worktreePath string, worktree *models.Worktree, cached *CachedWorktreeStatus) {
+	detail := &models.WorktreeStatusDetail{}
+	if cached.StatusDetail != nil {
+		*detail = *cached.StatusDetail
+	}
+
+	if cached.IsDirty != nil && !*cached.IsDirty {
+		detail.StagedCount, detail.ModifiedCount, detail.UntrackedCount, detail.ConflictedCount = 0, 0, 0, 0
+	} else if output, err := c.operations.ExecuteGit(worktreePath, "status", "--porcelain=v2"); err == nil {
+		parsePorcelainV2Counts(string(output), detail)
+	}
+
+	stashRef := ""
+	if output, err := c.operations.ExecuteGit(worktreePath, "for-each-ref", "--format=%(objectname)", "refs/stash"); err == nil {
+		stashRef = strings.TrimSpace(string(output))
+	}
+	if stashRef == "" {
+		detail.StashCount = 0
+	} else if stashRef != cached.detailKeys.stashRef || cached.StatusDetail == nil {
+		if output, err := c.operations.ExecuteGit(worktreePath, "rev-list", "--walk-reflogs", "--count", "refs/stash"); err == nil {
+			if count, err := strconv.Atoi(strings.TrimSpace(string(output))); err == nil {
+				detail.StashCount = count
+			}
+		}
+	}
+	cached.detailKeys.stashRef = stashRef
+
+	c.updateRemoteAheadBehind(worktreePath, worktree, cached, detail)
+	cached.StatusDetail = detail
+}
+
+// remoteStatusRefs are the remote-tracking refs compared against HEAD: the
+// worktree's own branch on origin, and its source branch on upstream (forks)
+func remoteStatusRefs(worktree *models.Worktree) map[string]string {
+	refs := make(map[string]string)
+	if worktree.Branch != "" {
+		refs["origin"] = "refs/remotes/origin/" + worktree.Branch
+	}
+	if worktree.SourceBranch != "" {
+		refs["upstream"] = "refs/remotes/upstream/" + strings.TrimPrefix(worktree.SourceBranch, "origin/")
+	}
+	return refs
+}
+
+func (c *WorktreeStatusCache) updateRemoteAheadBehind(worktreePath string, worktree *models.Worktree, cached *CachedWorktreeStatus, detail *models.WorktreeStatusDetail) {
+	refs := remoteStatusRefs(worktree)
+	args := []string{"for-each-ref", "--format=%(refname) %(objectname)"}
+	for _, remote := range []string{"origin", "upstream"} {
+		if ref, ok := refs[remote]; ok {
+			args = append(args, ref)
+		}
+	}
+	if len(args) == 2 {
+		detail.Remotes = nil
+		return
+	}
+
+	output, err := c.operations.ExecuteGit(worktreePath, args...)
+	if err != nil {
+		return
+	}
+	remoteKey := cached.CommitHash + "\n" + strings.TrimSpace(string(output))
+	if remoteKey == cached.detailKeys.remoteKey && cached.StatusDetail != nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			existing[fields[0]] = true
+		}
+	}
+
+	var remotes []models.RemoteAheadBehind
+	for _, remote := range []string{"origin", "upstream"} {
+		ref, ok := refs[remote]
+		if !ok || !existing[ref] {
+			continue
+		}
+		counts, err := c.operations.ExecuteGit(worktreePath, "rev-list", "--left-right", "--count", "HEAD..."+ref)
+		if err != nil {
+			continue
+		}
+		fields := strings.Fields(string(counts))
+		if len(fields) != 2 {
+			continue
+		}
+		ahead, _ := strconv.Atoi(fields[0])
+		behind, _ := strconv.Atoi(fields[1])
+		remotes = append(remotes, models.RemoteAheadBehind{
+			Remote: remote,
+			Ref:    strings.TrimPrefix(ref, "refs/remotes/"),
+			Ahead:  ahead,
+			Behind: behind,
+		})
+	}
+	detail.Remotes = remotes
+	cached.detailKeys.remoteKey = remoteKey
+}
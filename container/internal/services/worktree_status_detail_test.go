@@ -0,0 +1,90 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/git"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+func TestParsePorcelainV2Counts(t *testing.T) {
+	output := `1 M. N... 100644 100644 100644 aaa bbb staged.go
+1 .M N... 100644 100644 100644 aaa aaa modified.go
+1 MM N... 100644 100644 100644 aaa bbb both.go
+2 R. N... 100644 100644 100644 aaa aaa R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 aaa bbb ccc conflict.go
+? untracked.txt
+? other.txt
+! ignored.log
+`
+	detail := &models.WorktreeStatusDetail{StagedCount: 99}
+	parsePorcelainV2Counts(output, detail)
+	assert.Equal(t, 3, detail.StagedCount)
+	assert.Equal(t, 2, detail.ModifiedCount)
+	assert.Equal(t, 2, detail.UntrackedCount)
+	assert.Equal(t, 1, detail.ConflictedCount)
+}
+
+func TestUpdateStatusDetail(t *testing.T) {
+	dir := t.TempDir()
+	runTestGit(t, dir, "init", "-q", "-b", "feature")
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("a.txt", "a\n")
+	write("b.txt", "b\n")
+	runTestGit(t, dir, "add", ".")
+	runTestGit(t, dir, "commit", "-q", "-m", "initial")
+
+	// origin/feature is one commit behind HEAD; upstream/main is one commit ahead of the base
+	runTestGit(t, dir, "update-ref", "refs/remotes/origin/feature", "HEAD")
+	runTestGit(t, dir, "checkout", "-q", "-b", "upstream-main")
+	write("up.txt", "upstream\n")
+	runTestGit(t, dir, "add", ".")
+	runTestGit(t, dir, "commit", "-q", "-m", "upstream change")
+	runTestGit(t, dir, "update-ref", "refs/remotes/upstream/main", "HEAD")
+	runTestGit(t, dir, "checkout", "-q", "feature")
+	write("a.txt", "a2\n")
+	runTestGit(t, dir, "commit", "-q", "-am", "local change")
+
+	// One stash, then staged, modified and untracked changes
+	write("b.txt", "stashed\n")
+	runTestGit(t, dir, "stash", "-q")
+	write("a.txt", "staged\n")
+	runTestGit(t, dir, "add", "a.txt")
+	write("b.txt", "modified\n")
+	write("new.txt", "untracked\n")
+
+	cache := NewWorktreeStatusCache(git.NewOperations(), nil)
+	defer cache.Stop()
+	worktree := &models.Worktree{ID: "wt-detail", Path: dir, Branch: "feature", SourceBranch: "main"}
+	dirty := true
+	cached := &CachedWorktreeStatus{WorktreeID: worktree.ID, IsDirty: &dirty, CommitHash: runTestGit(t, dir, "rev-parse", "HEAD")}
+
+	cache.updateStatusDetail(dir, worktree, cached)
+	detail := cached.StatusDetail
+	require.NotNil(t, detail)
+	assert.Equal(t, 1, detail.StagedCount)
+	assert.Equal(t, 1, detail.ModifiedCount)
+	assert.Equal(t, 1, detail.UntrackedCount)
+	assert.Equal(t, 1, detail.StashCount)
+	assert.Equal(t, []models.RemoteAheadBehind{
+		{Remote: "origin", Ref: "origin/feature", Ahead: 1, Behind: 0},
+		{Remote: "upstream", Ref: "upstream/main", Ahead: 1, Behind: 1},
+	}, detail.Remotes)
+
+	// A clean worktree skips git status entirely and reuses unchanged remote counts
+	clean := false
+	cached.IsDirty = &clean
+	runTestGit(t, dir, "stash", "drop", "-q")
+	cache.updateStatusDetail(dir, worktree, cached)
+	assert.Zero(t, cached.StatusDetail.StagedCount)
+	assert.Zero(t, cached.StatusDetail.UntrackedCount)
+	assert.Zero(t, cached.StatusDetail.StashCount)
+	assert.Len(t, cached.StatusDetail.Remotes, 2)
+	assert.Equal(t, 1, detail.StashCount, "earlier snapshots are not mutated")
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vanpelt/catnip/internal/config"
+	"github.com/vanpelt/catnip/internal/logger"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+const (
+	// WorktreeSummaryModel writes the in-flight summaries; there can be one
+	// per worktree, so it needs to be fast and cheap
+	WorktreeSummaryModel = "claude-haiku-4-5"
+
+	// worktreeSummaryConcurrency caps the summaries generated at once
+	worktreeSummaryConcurrency = 3
+	worktreeSummaryTimeout     = 60 * time.Second
+	// Limits on what is sent per worktree
+	worktreeSummaryMaxDiff    = 12 * 1024
+	worktreeSummaryMaxStatus  = 50
+	worktreeSummaryMaxCommits = 20
+)
+
+// WorktreeSummary says what is in flight in a worktree with uncommitted changes
+type WorktreeSummary struct {
+	WorktreeID string `json:"worktree_id" example:"6f1c2b9e-3a8d-4c7f-9e21-5b0a4d3c2e1f"`
+	Name       string `json:"name" example:"feature-api-docs"`
+	RepoID     string `json:"repo_id" example:"acme/app"`
+	Branch     string `json:"branch" example:"feature/api-docs"`
+	Summary    string `json:"summary,omitempty" example:"Half-finished move of the API docs to OpenAPI 3: the generator is updated but two handlers still use the old annotations."`
+	// HEAD commit the summary describes
+	Commit string `json:"commit" example:"abc123def456"`
+	// Uncommitted files when the summary was made
+	DirtyFiles  int       `json:"dirty_files" example:"4"`
+	GeneratedAt time.Time `json:"generated_at"`
+	// Nothing changed since the summary was made, so it wasn't regenerated
+	Cached bool `json:"cached"`
+	// Why generating the summary failed; Summary is then the last one made, if any
+	Error string `json:"error,omitempty"`
+}
+
+// WorktreeSummaryReport summarizes every worktree with uncommitted changes
+type WorktreeSummaryReport struct {
+	Model       string            `json:"model" example:"claude-haiku-4-5"`
+	GeneratedAt time.Time         `json:"generated_at"`
+	Summaries   []WorktreeSummary `json:"summaries"`
+	// Worktrees without uncommitted changes, which aren't summarized
+	Clean int `json:"clean" example:"7"`
+}
+
+// worktreeSummaryCacheEntry is a summary and the state it was made from
+type worktreeSummaryCacheEntry struct {
+	Key     string          `json:"key"`
+	Summary WorktreeSummary `json:"summary"`
+}
+
+// worktreeSnapshot is what a summary is made from
+type worktreeSnapshot struct {
+	worktree *models.Worktree
+	head     string
+	status   []string
+	key      string
+}
+
+// WorktreeSummarizer writes a one-paragraph summary of the work in flight in
+// each dirty worktree. Summaries are cached by HEAD and the set of dirty
+// files, and the cache is kept in the volume so it survives restarts.
+type WorktreeSummarizer struct {
+	git    *GitService
+	claude completionCreator
+	path   string
+	now    func() time.Time
+
+	mu      sync.Mutex
+	cache   map[string]worktreeSummaryCacheEntry
+	loaded  bool
+	running chan struct{} // Closed when the summaries being generated are done
+}
+
+// NewWorktreeSummarizer caches summaries in the volume
+func NewWorktreeSummarizer(git *GitService, claude completionCreator) *WorktreeSummarizer {
+	return NewWorktreeSummarizerWithPath(git, claude, filepath.Join(config.Runtime.VolumeDir, "worktree_summaries.json"))
+}
+
+// NewWorktreeSummarizerWithPath caches summaries at path (for testing)
+func NewWorktreeSummarizerWithPath(git *GitService, claude completionCreator, path string) *WorktreeSummarizer {
+	return &WorktreeSummarizer{
+		git:    git,
+		claude: claude,
+		path:   path,
+		now:    time.Now,
+		cache:  make(map[string]worktreeSummaryCacheEntry),
+	}
+}
+
+// Summaries summarizes every worktree with uncommitted changes. Cached
+// summaries are reused unless the worktree's HEAD or dirty files changed, or
+// refresh is set. Calls run one at a time, so a caller arriving during a
+// run gets that run's fresh summaries from the cache.
+func (s *WorktreeSummarizer) Summaries(ctx context.Context, refresh bool) (*WorktreeSummaryReport, error) {
+	for {
+		s.mu.Lock()
+		if s.running == nil {
+			s.running = make(chan struct{})
+			s.mu.Unlock()
+			break
+		}
+		running := s.running
+		s.mu.Unlock()
+		select {
+		case <-running:
+			refresh = false // The run we waited for brought the cache up to date
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	defer func() {
+		s.mu.Lock()
+		close(s.running)
+		s.running = nil
+		s.mu.Unlock()
+	}()
+
+	s.loadCache()
+	report := &WorktreeSummaryReport{Model: WorktreeSummaryModel, GeneratedAt: s.now(), Summaries: []WorktreeSummary{}}
+
+	var stale []*worktreeSnapshot
+	live := make(map[string]bool)
+	for _, worktree := range s.git.ListWorktrees() {
+		if worktree.Hibernation != nil {
+			continue
+		}
+		live[worktree.ID] = true
+		snapshot, err := s.snapshot(worktree)
+		if err != nil {
+			logger.Debugf("⚠️ Skipping summary of %s: %v", worktree.Name, err)
+			continue
+		}
+		if len(snapshot.status) == 0 {
+			report.Clean++
+			continue
+		}
+		s.mu.Lock()
+		cached, ok := s.cache[worktree.ID]
+		s.mu.Unlock()
+		if ok && cached.Key == snapshot.key && !refresh {
+			summary := cached.Summary
+			summary.Cached = true
+			report.Summaries = append(report.Summaries, summary)
+			continue
+		}
+		stale = append(stale, snapshot)
+	}
+
+	results := make([]WorktreeSummary, len(stale))
+	semaphore := make(chan struct{}, worktreeSummaryConcurrency)
+	var wg sync.WaitGroup
+	for i, snapshot := range stale {
+		wg.Add(1)
+		go func(i int, snapshot *worktreeSnapshot) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = s.summarize(ctx, snapshot)
+		}(i, snapshot)
+	}
+	wg.Wait()
+	report.Summaries = append(report.Summaries, results...)
+
+	s.mu.Lock()
+	for id := range s.cache {
+		if !live[id] {
+			delete(s.cache, id)
+		}
+	}
+	s.mu.Unlock()
+	if len(stale) > 0 {
+		s.saveCache()
+	}
+
+	sort.Slice(report.Summaries, func(i, j int) bool {
+		a, b := report.Summaries[i], report.Summaries[j]
+		if a.RepoID != b.RepoID {
+			return a.RepoID < b.RepoID
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// snapshot reads a worktree's HEAD and uncommitted files
+func (s *WorktreeSummarizer) snapshot(worktree *models.Worktree) (*worktreeSnapshot, error) {
+	output, err := s.git.operations.ExecuteGit(worktree.Path, "status", "--porcelain", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &worktreeSnapshot{worktree: worktree}
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		if strings.TrimSpace(line) != "" {
+			snapshot.status = append(snapshot.status, line)
+		}
+	}
+	if len(snapshot.status) == 0 {
+		return snapshot, nil
+	}
+	if head, err := s.git.operations.ExecuteGit(worktree.Path, "rev-parse", "HEAD"); err == nil {
+		snapshot.head = strings.TrimSpace(string(head))
+	}
+
+	sorted := append([]string(nil), snapshot.status...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(snapshot.head + "\n" + strings.Join(sorted, "\n")))
+	snapshot.key = hex.EncodeToString(sum[:])
+	return snapshot, nil
+}
+
+// summarize generates and caches a worktree's summary. On failure the last
+// summary made is returned with the error.
+func (s *WorktreeSummarizer) summarize(ctx context.Context, snapshot *worktreeSnapshot) WorktreeSummary {
+	worktree := snapshot.worktree
+	summary := WorktreeSummary{
+		WorktreeID:  worktree.ID,
+		Name:        worktree.Name,
+		RepoID:      worktree.RepoID,
+		Branch:      worktree.Branch,
+		Commit:      snapshot.head,
+		DirtyFiles:  len(snapshot.status),
+		GeneratedAt: s.now(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, worktreeSummaryTimeout)
+	defer cancel()
+	response, err := s.claude.CreateCompletion(ctx, &models.CreateCompletionRequest{
+		Prompt:           s.prompt(snapshot),
+		SystemPrompt:     "You help developers pick up work they left unfinished. Be concrete and brief, and never invent details that aren't in the changes.",
+		Model:            WorktreeSummaryModel,
+		MaxTurns:         1,
+		WorkingDirectory: worktree.Path,
+		SuppressEvents:   true,
+		DisableTools:     true,
+	})
+	if err == nil {
+		summary.Summary = strings.TrimSpace(response.Response)
+		if summary.Summary == "" {
+			err = fmt.Errorf("claude returned an empty summary")
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		logger.Warnf("⚠️ Failed to summarize worktree %s: %v", worktree.Name, err)
+		previous, ok := s.cache[worktree.ID]
+		if ok && previous.Summary.Summary != "" {
+			summary = previous.Summary
+		}
+		summary.Error = err.Error()
+		return summary
+	}
+	s.cache[worktree.ID] = worktreeSummaryCacheEntry{Key: snapshot.key, Summary: summary}
+	return summary
+}
+
+// prompt describes a worktree's commits and uncommitted changes
+func (s *WorktreeSummarizer) prompt(snapshot *worktreeSnapshot) string {
+	worktree := snapshot.worktree
+	var b strings.Builder
+	b.WriteString(`Write one short paragraph (at most 4 sentences) saying what is in flight in this git worktree, for a developer triaging their open branches after time away. Say what the change is trying to do, how far along it looks, and anything that looks unfinished or broken. Don't list files one by one.
+`)
+	fmt.Fprintf(&b, "\nBranch: %s", worktree.Branch)
+	if worktree.SourceBranch != "" {
+		fmt.Fprintf(&b, " (from %s)", worktree.SourceBranch)
+	}
+	b.WriteString("\n")
+	if prompt := strings.TrimSpace(worktree.LatestUserPrompt); prompt != "" {
+		if len(prompt) > 500 {
+			prompt = prompt[:500] + "..."
+		}
+		fmt.Fprintf(&b, "Last request to Claude here: %s\n", prompt)
+	}
+
+	if worktree.CommitHash != "" {
+		if log, err := s.git.operations.ExecuteGit(worktree.Path, "log", "--oneline", "-n", fmt.Sprint(worktreeSummaryMaxCommits), worktree.CommitHash+"..HEAD"); err == nil && len(strings.TrimSpace(string(log))) > 0 {
+			fmt.Fprintf(&b, "\n<commits>\n%s\n</commits>\n", strings.TrimSpace(string(log)))
+		}
+	}
+
+	status := snapshot.status
+	if len(status) > worktreeSummaryMaxStatus {
+		status = append(append([]string(nil), status[:worktreeSummaryMaxStatus]...), fmt.Sprintf("... and %d more", len(snapshot.status)-worktreeSummaryMaxStatus))
+	}
+	fmt.Fprintf(&b, "\n<uncommitted_files>\n%s\n</uncommitted_files>\n", strings.Join(status, "\n"))
+
+	if diff, err := s.git.operations.ExecuteGit(worktree.Path, "diff", "HEAD"); err == nil && len(diff) > 0 {
+		text := string(diff)
+		if len(text) > worktreeSummaryMaxDiff {
+			text = text[:worktreeSummaryMaxDiff] + "\n[diff truncated]"
+		}
+		fmt.Fprintf(&b, "\n<diff>\n%s\n</diff>", strings.TrimRight(text, "\n"))
+	}
+	return b.String()
+}
+
+func (s *WorktreeSummarizer) loadCache() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded {
+		return
+	}
+	s.loaded = true
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.cache); err != nil {
+		logger.Warnf("⚠️ Ignoring unreadable worktree summary cache %s: %v", s.path, err)
+		s.cache = make(map[string]worktreeSummaryCacheEntry)
+	}
+}
+
+func (s *WorktreeSummarizer) saveCache() {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(s.cache, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		logger.Warnf("⚠️ Failed to save worktree summaries: %v", err)
+		return
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		logger.Warnf("⚠️ Failed to save worktree summaries: %v", err)
+		return
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		logger.Warnf("⚠️ Failed to save worktree summaries: %v", err)
+	}
+}
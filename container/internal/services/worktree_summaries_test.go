@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vanpelt/catnip/internal/models"
+)
+
+type summaryClaude struct {
+	mu       sync.Mutex
+	prompts  map[string]string
+	calls    int
+	failWith error
+}
+
+func (c *summaryClaude) CreateCompletion(ctx context.Context, req *models.CreateCompletionRequest) (*models.CreateCompletionResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.failWith != nil {
+		return nil, c.failWith
+	}
+	c.prompts[req.WorkingDirectory] = req.Prompt
+	return &models.CreateCompletionResponse{Response: " Work in " + filepath.Base(req.WorkingDirectory) + "\n"}, nil
+}
+
+func TestWorktreeSummaries(t *testing.T) {
+	service := createTestGitService(t)
+	defer service.Stop()
+	require.NoError(t, service.stateManager.AddRepository(&models.Repository{ID: "acme/app", Path: t.TempDir()}))
+
+	newWorktree := func(id string) string {
+		dir := filepath.Join(t.TempDir(), id)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		runTestGit(t, dir, "init", "-q", "-b", "main")
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "app.go"), []byte("package app\n"), 0644))
+		runTestGit(t, dir, "add", ".")
+		runTestGit(t, dir, "commit", "-q", "-m", "initial")
+		base := runTestGit(t, dir, "rev-parse", "HEAD")
+		require.NoError(t, service.stateManager.AddWorktree(&models.Worktree{
+			ID: id, RepoID: "acme/app", Name: id, Branch: "feature/" + id, SourceBranch: "main",
+			Path: dir, CommitHash: base, LatestUserPrompt: "Add retries to the client",
+		}))
+		return dir
+	}
+	dirty := newWorktree("dirty")
+	newWorktree("clean")
+	require.NoError(t, os.WriteFile(filepath.Join(dirty, "app.go"), []byte("package app\n\nfunc Retry() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dirty, "retry_test.go"), []byte("package app\n"), 0644))
+
+	claude := &summaryClaude{prompts: make(map[string]string)}
+	cachePath := filepath.Join(t.TempDir(), "worktree_summaries.json")
+	summarizer := NewWorktreeSummarizerWithPath(service, claude, cachePath)
+
+	report, err := summarizer.Summaries(context.Background(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Clean)
+	require.Len(t, report.Summaries, 1)
+	summary := report.Summaries[0]
+	assert.Equal(t, "dirty", summary.WorktreeID)
+	assert.Equal(t, "Work in dirty", summary.Summary)
+	assert.Equal(t, 2, summary.DirtyFiles)
+	assert.False(t, summary.Cached)
+	assert.NotEmpty(t, summary.Commit)
+
+	prompt := claude.prompts[dirty]
+	assert.Contains(t, prompt, "Branch: feature/dirty (from main)")
+	assert.Contains(t, prompt, "Last request to Claude here: Add retries to the client")
+	assert.Contains(t, prompt, "?? retry_test.go")
+	assert.Contains(t, prompt, "+func Retry() {}")
+
+	// Unchanged worktrees come from the cache, also after a restart
+	report, err = summarizer.Summaries(context.Background(), false)
+	require.NoError(t, err)
+	assert.True(t, report.Summaries[0].Cached)
+	restarted := NewWorktreeSummarizerWithPath(service, claude, cachePath)
+	report, err = restarted.Summaries(context.Background(), false)
+	require.NoError(t, err)
+	assert.True(t, report.Summaries[0].Cached)
+	assert.Equal(t, 1, claude.calls)
+
+	// A new dirty file invalidates the summary
+	require.NoError(t, os.WriteFile(filepath.Join(dirty, "notes.md"), []byte("todo\n"), 0644))
+	report, err = summarizer.Summaries(context.Background(), false)
+	require.NoError(t, err)
+	assert.False(t, report.Summaries[0].Cached)
+	assert.Equal(t, 3, report.Summaries[0].DirtyFiles)
+	assert.Equal(t, 2, claude.calls)
+
+	// Refreshing regenerates; failures keep the last summary
+	claude.failWith = errors.New("rate limited")
+	report, err = summarizer.Summaries(context.Background(), true)
+	require.NoError(t, err)
+	assert.Equal(t, "Work in dirty", report.Summaries[0].Summary)
+	assert.Equal(t, "rate limited", report.Summaries[0].Error)
+	assert.Equal(t, 3, claude.calls)
+}
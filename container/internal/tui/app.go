@@ -303,6 +303,9 @@ func (a *App) Run(ctx context.Context, workDir string, customPorts []string) (st
 					a.portForwarder.StopForward(cp)
 				}
 			}
+		case PortPublishRulesEvent, WorktreeCreatedEvent, WorktreeDeletedEvent:
+			// Publish rules follow the worktrees they belong to
+			a.portForwarder.SyncPublishRules()
 		}
 	}
 
@@ -331,6 +334,9 @@ func (a *App) Run(ctx context.Context, workDir string, customPorts []string) (st
 	sseClient.onConnected = func() {
 		// Load initial worktree data once we're connected to the backend
 		a.loadInitialWorktrees(m)
+		if a.sshEnabled && a.portForwarder != nil {
+			go a.portForwarder.SyncPublishRules()
+		}
 	}
 
 	// Start SSE client immediately
@@ -3,7 +3,9 @@ package tui
 import (
 	"bytes"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -11,8 +13,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/vanpelt/catnip/internal/services"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -31,12 +35,16 @@ type PortForwardManager struct {
 	forwardsMu sync.Mutex
 	forwards   map[int]*activeForward // containerPort -> forward
 
+	publishMu sync.Mutex                // serializes SyncPublishRules
+	published map[string]*activeForward // publish rule ID -> forward
+
 	httpClient *http.Client
 }
 
 type activeForward struct {
 	containerPort int
 	hostPort      int
+	hostAddress   string
 	listener      net.Listener
 	closed        chan struct{}
 }
@@ -56,6 +64,7 @@ func NewPortForwardManager(backendBaseURL string) *PortForwardManager {
 		sshAddress:     "127.0.0.1:2222",
 		keyPath:        keyPath,
 		forwards:       make(map[int]*activeForward),
+		published:      make(map[string]*activeForward),
 		httpClient:     &http.Client{Timeout: 2 * time.Second},
 	}
 }
@@ -65,6 +74,13 @@ func NewPortForwardManager(backendBaseURL string) *PortForwardManager {
 func (m *PortForwardManager) EnsureForward(containerPort int) int {
 	debugLog("PFM: EnsureForward containerPort=%d", containerPort)
 	m.forwardsMu.Lock()
+	// Ports with a publish rule are forwarded on the rule's host port
+	for _, f := range m.published {
+		if f.containerPort == containerPort {
+			m.forwardsMu.Unlock()
+			return f.hostPort
+		}
+	}
 	if f, ok := m.forwards[containerPort]; ok {
 		debugLog("PFM: already forwarding containerPort=%d hostPort=%d", containerPort, f.hostPort)
 		m.forwardsMu.Unlock()
@@ -88,6 +104,7 @@ func (m *PortForwardManager) EnsureForward(containerPort int) int {
 	fwd := &activeForward{
 		containerPort: containerPort,
 		hostPort:      hostPort,
+		hostAddress:   "127.0.0.1",
 		listener:      ln,
 		closed:        make(chan struct{}),
 	}
@@ -141,6 +158,15 @@ func (m *PortForwardManager) StopAll() {
 	for _, p := range ports {
 		m.StopForward(p)
 	}
+	m.publishMu.Lock()
+	m.forwardsMu.Lock()
+	published := m.published
+	m.published = make(map[string]*activeForward)
+	m.forwardsMu.Unlock()
+	for _, f := range published {
+		m.stopPublished(f)
+	}
+	m.publishMu.Unlock()
 	m.closeSSH()
 }
 
@@ -153,6 +179,9 @@ func (m *PortForwardManager) ReannounceMappings() {
 	for cport, f := range m.forwards {
 		snapshot[cport] = f.hostPort
 	}
+	for _, f := range m.published {
+		snapshot[f.containerPort] = f.hostPort
+	}
 	m.forwardsMu.Unlock()
 
 	for cport, hport := range snapshot {
@@ -164,6 +193,152 @@ func (m *PortForwardManager) ReannounceMappings() {
 	}
 }
 
+// SyncPublishRules fetches the backend's host publish rules and reconciles
+// listeners with them: each rule binds exactly its host address and port, so
+// a port already taken on the host is reported back as a conflict instead of
+// being moved elsewhere. Listeners for removed rules (or deleted worktrees)
+// are closed.
+func (m *PortForwardManager) SyncPublishRules() {
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+
+	rules, err := m.fetchPublishRules()
+	if err != nil {
+		debugLog("PFM: fetch publish rules failed: %v", err)
+		return
+	}
+
+	wanted := make(map[string]services.PortPublishRule, len(rules))
+	for _, rule := range rules {
+		// Rules clashing with another rule are never bound; rules whose host
+		// port was busy (a reported conflict) are retried
+		if rule.Status == services.PortPublishConflict && rule.UpdatedAt == nil {
+			continue
+		}
+		wanted[rule.ID] = rule
+	}
+
+	// Close listeners for rules that are gone or changed
+	m.forwardsMu.Lock()
+	var stale []*activeForward
+	for id, f := range m.published {
+		rule, ok := wanted[id]
+		if !ok || rule.Port != f.containerPort || rule.HostPort != f.hostPort || rule.HostAddress != f.hostAddress {
+			stale = append(stale, f)
+			delete(m.published, id)
+		}
+	}
+	m.forwardsMu.Unlock()
+	for _, f := range stale {
+		m.stopPublished(f)
+	}
+
+	for _, rule := range rules {
+		m.forwardsMu.Lock()
+		_, running := m.published[rule.ID]
+		m.forwardsMu.Unlock()
+		if _, ok := wanted[rule.ID]; !ok || running {
+			continue
+		}
+		m.publish(rule)
+	}
+}
+
+// publish binds a publish rule's host port and reports the outcome
+func (m *PortForwardManager) publish(rule services.PortPublishRule) {
+	// An automatic forward of the same port would hold the host port the rule asks for
+	m.forwardsMu.Lock()
+	auto, autoRunning := m.forwards[rule.Port]
+	m.forwardsMu.Unlock()
+	if autoRunning && (auto.hostPort == rule.HostPort || rule.HostAddress == "0.0.0.0") {
+		m.StopForward(rule.Port)
+	}
+
+	addr := net.JoinHostPort(rule.HostAddress, fmt.Sprintf("%d", rule.HostPort))
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		debugLog("PFM: publish listen failed rule=%s addr=%s err=%v", rule.ID, addr, err)
+		status := services.PortPublishError
+		if errors.Is(err, syscall.EADDRINUSE) {
+			status = services.PortPublishConflict
+			err = fmt.Errorf("host port %s is already in use", addr)
+		}
+		m.reportPublishStatus(rule.ID, status, err.Error())
+		return
+	}
+
+	fwd := &activeForward{
+		containerPort: rule.Port,
+		hostPort:      rule.HostPort,
+		hostAddress:   rule.HostAddress,
+		listener:      ln,
+		closed:        make(chan struct{}),
+	}
+	m.forwardsMu.Lock()
+	m.published[rule.ID] = fwd
+	m.forwardsMu.Unlock()
+
+	go m.acceptLoop(fwd)
+	if err := m.postMapping(rule.Port, rule.HostPort); err != nil {
+		debugLog("PFM: postMapping failed cport=%d hport=%d err=%v", rule.Port, rule.HostPort, err)
+	}
+	m.reportPublishStatus(rule.ID, services.PortPublishPublished, "")
+	debugLog("PFM: published rule=%s cport=%d -> %s", rule.ID, rule.Port, addr)
+}
+
+// stopPublished closes a published listener and clears its mapping
+func (m *PortForwardManager) stopPublished(f *activeForward) {
+	_ = f.listener.Close()
+	close(f.closed)
+
+	m.forwardsMu.Lock()
+	_, autoRunning := m.forwards[f.containerPort]
+	m.forwardsMu.Unlock()
+	if !autoRunning {
+		if err := m.deleteMapping(f.containerPort); err != nil {
+			debugLog("PFM: deleteMapping failed cport=%d err=%v", f.containerPort, err)
+		}
+	}
+	debugLog("PFM: unpublished cport=%d from %s:%d", f.containerPort, f.hostAddress, f.hostPort)
+}
+
+func (m *PortForwardManager) fetchPublishRules() ([]services.PortPublishRule, error) {
+	resp, err := m.httpClient.Get(m.backendBaseURL + "/v1/ports/publish")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	var body struct {
+		Rules []services.PortPublishRule `json:"rules"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Rules, nil
+}
+
+func (m *PortForwardManager) reportPublishStatus(id string, status services.PortPublishStatus, message string) {
+	body, err := json.Marshal(map[string]string{"status": string(status), "error": message})
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%s/v1/ports/publish/%s/status", m.backendBaseURL, id), bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		debugLog("PFM: report publish status failed rule=%s err=%v", id, err)
+		return
+	}
+	defer resp.Body.Close()
+	debugLog("PFM: report publish status rule=%s status=%s response=%s", id, status, resp.Status)
+}
+
 func (m *PortForwardManager) ensureSSH() (*ssh.Client, error) {
 	m.clientMu.Lock()
 	defer m.clientMu.Unlock()
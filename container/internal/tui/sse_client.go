@@ -54,6 +54,8 @@ const (
 	WorktreeUpdatedEvent      = "worktree:updated"
 	WorktreeBatchUpdatedEvent = "worktree:batch_updated"
 	WorktreeCreatedEvent      = "worktree:created"
+	WorktreeDeletedEvent      = "worktree:deleted"
+	PortPublishRulesEvent     = "port:publish_rules"
 )
 
 // SSE event messages are defined in messages.go
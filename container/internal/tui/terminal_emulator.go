@@ -11,11 +11,11 @@ import (
 // Attribute mode constants from vt10x internal
 // These match the mode bits used in vt10x
 const (
-	attrBold      = 1 << 0
+	attrReverse   = 1 << 0
 	attrUnderline = 1 << 1
-	attrBlink     = 1 << 2
-	attrReverse   = 1 << 3
+	attrBold      = 1 << 2
 	attrItalic    = 1 << 4
+	attrBlink     = 1 << 5
 )
 
 // TerminalEmulator wraps vt10x to provide terminal emulation for PTY output
@@ -54,6 +54,29 @@ func (te *TerminalEmulator) RenderForReconnection() string {
 	return te.renderInternal(false)
 }
 
+// Snapshot returns output that repaints the current screen on a real
+// terminal: it clears the screen, draws each line and restores the cursor
+func (te *TerminalEmulator) Snapshot() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\033[H\033[2J")
+	// Lines are drawn in full, so carriage returns keep them from drifting
+	buf.WriteString(strings.ReplaceAll(te.renderInternal(false), "\n", "\r\n"))
+
+	cursor := te.terminal.Cursor()
+	buf.WriteString(fmt.Sprintf("\033[%d;%dH", cursor.Y+1, cursor.X+1))
+	if te.terminal.CursorVisible() {
+		buf.WriteString("\033[?25h")
+	} else {
+		buf.WriteString("\033[?25l")
+	}
+	return buf.Bytes()
+}
+
+// Size returns the terminal dimensions
+func (te *TerminalEmulator) Size() (cols, rows int) {
+	return te.cols, te.rows
+}
+
 // Render returns the current terminal view as a string with ANSI color codes
 func (te *TerminalEmulator) Render() string {
 	return te.renderInternal(true)
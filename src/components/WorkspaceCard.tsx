@@ -3,8 +3,8 @@ import { Button } from "@/components/ui/button";
 import { Badge } from "@/components/ui/badge";
 import { Textarea } from "@/components/ui/textarea";
 import { Skeleton } from "@/components/ui/skeleton";
-import { GitBranch, Copy, Check, Clock, Loader2 } from "lucide-react";
-import { type Worktree } from "@/lib/git-api";
+import { GitBranch, Copy, Check, Clock, Loader2, Moon } from "lucide-react";
+import { gitApi, type Worktree } from "@/lib/git-api";
 import { getRelativeTime } from "@/lib/git-utils";
 import { useState } from "react";
 import { WorkspaceActions as SharedWorkspaceActions } from "@/components/WorkspaceActions";
@@ -17,7 +17,20 @@ interface WorkspaceCardProps {
 export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
   const [prompt, setPrompt] = useState("");
   const [isAnimating, setIsAnimating] = useState(false);
+  const [isWaking, setIsWaking] = useState(false);
   const navigate = useNavigate();
+  const hibernation = worktree.hibernation;
+
+  const wakeWorkspace = async () => {
+    setIsWaking(true);
+    try {
+      await gitApi.rehydrateWorktree(worktree.id);
+    } catch (err) {
+      console.error("Failed to rehydrate worktree:", err);
+    } finally {
+      setIsWaking(false);
+    }
+  };
 
   const navigateToClaude = (e: React.SyntheticEvent) => {
     e.preventDefault();
@@ -77,10 +90,21 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
           <Badge variant="outline" className="text-xs">
             {worktree.branch}
           </Badge>
-          <DirtyIndicator
-            isDirty={worktree.is_dirty}
-            isLoading={!worktree.cache_status?.is_cached}
-          />
+          {hibernation ? (
+            <Badge
+              variant="secondary"
+              className="text-xs"
+              title={`Hibernated ${getRelativeTime(hibernation.hibernated_at)}`}
+            >
+              <Moon className="w-3 h-3 mr-1" />
+              Hibernated
+            </Badge>
+          ) : (
+            <DirtyIndicator
+              isDirty={worktree.is_dirty}
+              isLoading={!worktree.cache_status?.is_cached}
+            />
+          )}
           {worktree.cache_status?.is_loading && (
             <Badge variant="secondary" className="text-xs">
               <Loader2 className="w-3 h-3 mr-1 animate-spin" />
@@ -104,8 +128,22 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
           </div>
         </div>
 
+        {/* Hibernated workspaces have no files until woken up */}
+        {hibernation && (
+          <Button
+            onClick={() => void wakeWorkspace()}
+            size="sm"
+            variant="outline"
+            className="w-full"
+            disabled={isWaking}
+          >
+            {isWaking && <Loader2 className="w-4 h-4 mr-2 animate-spin" />}
+            Wake up workspace
+          </Button>
+        )}
+
         {/* Prompt input form (only show if no active session) */}
-        {!worktree.has_active_claude_session && (
+        {!hibernation && !worktree.has_active_claude_session && (
           <form onSubmit={handlePromptSubmit} className="space-y-2">
             <Textarea
               placeholder="Ask Claude to help with this workspace..."
@@ -141,7 +179,7 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
       {/* Footer with Connect button and stats */}
       <div className="space-y-3">
         {/* Connect to Claude button (positioned above footer stats) */}
-        {worktree.has_active_claude_session && (
+        {!hibernation && worktree.has_active_claude_session && (
           <Button
             onClick={() => {
               setIsAnimating(true);
@@ -175,8 +213,12 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
             </div>
           </div>
 
-          {!worktree.cache_status?.is_cached &&
-          worktree.commits_behind === undefined ? (
+          {hibernation ? (
+            <div className="text-xs text-muted-foreground">
+              {formatReclaimed(hibernation.reclaimed_bytes)} reclaimed
+            </div>
+          ) : !worktree.cache_status?.is_cached &&
+            worktree.commits_behind === undefined ? (
             <Skeleton className="w-24 h-4" />
           ) : worktree.commits_behind > 0 ? (
             <div className="text-xs text-orange-600">
@@ -193,6 +235,12 @@ export function WorkspaceCard({ worktree, onDelete }: WorkspaceCardProps) {
   );
 }
 
+function formatReclaimed(bytes: number): string {
+  if (bytes >= 1 << 30) return `${(bytes / (1 << 30)).toFixed(1)} GB`;
+  if (bytes >= 1 << 20) return `${(bytes / (1 << 20)).toFixed(1)} MB`;
+  return `${Math.round(bytes / 1024)} KB`;
+}
+
 interface CommitHashDisplayProps {
   commitHash: string;
 }
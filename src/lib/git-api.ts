@@ -61,6 +61,15 @@ export interface Worktree {
   latest_claude_message_timestamp?: number;
   latest_user_prompt?: string;
   latest_session_title?: string;
+  hibernation?: WorktreeHibernation | null;
+}
+
+export interface WorktreeHibernation {
+  hibernated_at: string;
+  ref?: string;
+  head: string;
+  stash?: string;
+  reclaimed_bytes: number;
 }
 
 interface Owner {
@@ -395,6 +404,28 @@ export const gitApi = {
     }
   },
 
+  async hibernateWorktree(id: string): Promise<Worktree> {
+    const response = await fetch(`/v1/git/worktrees/${id}/hibernate`, {
+      method: "POST",
+    });
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({}));
+      throw new Error(error.message || "Failed to hibernate worktree");
+    }
+    return response.json();
+  },
+
+  async rehydrateWorktree(id: string): Promise<Worktree> {
+    const response = await fetch(`/v1/git/worktrees/${id}/rehydrate`, {
+      method: "POST",
+    });
+    if (!response.ok) {
+      const error = await response.json().catch(() => ({}));
+      throw new Error(error.message || "Failed to rehydrate worktree");
+    }
+    return response.json();
+  },
+
   async syncWorktree(id: string, errorHandler: ErrorHandler): Promise<boolean> {
     try {
       const response = await fetch(`/v1/git/worktrees/${id}/sync`, {